@@ -11,6 +11,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/supergiant/control/pkg/controlplane"
+	"github.com/supergiant/control/pkg/kubeconfig"
 	"github.com/supergiant/control/pkg/proxy"
 )
 
@@ -21,7 +22,7 @@ var (
 	insecurePort  = flag.Int("insecure-port", 8080, "tcp port to listen for incoming HTTP requests. if -port is set this flag will be ignored")
 	certFile      = flag.String("cert-file", "", "file containing server x509 certificate")
 	keyFile       = flag.String("key-file", "", "file containing x509 private key matching --cert-file")
-	storageMode   = flag.String("storage-mode", "file", "storage type either file(default), memory or etcd")
+	storageMode   = flag.String("storage-mode", "file", "storage type either file(default), memory, etcd or bolt")
 	storageURI    = flag.String("storage-uri", "supergiant.db", "uri of storage depends on selected storage type, for memory storage type this is empty")
 	templatesDir  = flag.String("templates", "", "supergiant will load script templates from the specified directory on start")
 	logDir        = flag.String("log-dir", "/tmp", "logging directory for task logs")
@@ -33,6 +34,44 @@ var (
 	ProxiesPortRangeTo   = flag.Int("proxies-port-to", 60250, "last tcp port in a range of binding reverse proxies for service apps")
 	pprofListenStr       = flag.String("pprofListenStr", "",
 		"pprof listen str host:port")
+	spotInterruptionInterval = flag.Int("spot-interruption-interval", 30,
+		"default interval in seconds between polls for spot interruption notices")
+	securityGroupCheckInterval = flag.Int("security-group-check-interval", 600,
+		"interval in seconds between checks of required security group rules")
+	importDiscoveryTimeout = flag.Int("import-discovery-timeout", 10,
+		"timeout in seconds of requests to API server of imported clusters")
+	discoveryCacheTTL = flag.Int("discovery-cache-ttl", 60,
+		"time in seconds discovered versions of clusters are cached")
+	metricsCacheTTL = flag.Int("metrics-cache-ttl", 15,
+		"time in seconds metrics of clusters are cached")
+	alertWebhookURL = flag.String("alert-webhook-url", "",
+		"url alerts of cluster node metrics are posted to, alerts are logged when it is empty")
+	alertEvalInterval = flag.Int("alert-eval-interval", 30,
+		"interval in seconds of evaluation of cluster alert rules")
+	metricsEnabled = flag.Bool("metrics-enabled", true,
+		"serve operational metrics of control in prometheus format on /metrics")
+	metricsAuth = flag.Bool("metrics-auth", false,
+		"require an auth token to get operational metrics")
+	nodeParallelism = flag.Int("node-parallelism", 5,
+		"count of worker nodes of a cluster provisioned at once")
+	maxNodeFailureRatio = flag.Float64("max-node-failure-ratio", 0.1,
+		"fraction of worker nodes that may fail without failing provisioning of the cluster")
+	taskRetention = flag.Int("task-retention", 720,
+		"age in hours of finished tasks after which their records and logs are deleted")
+	detachRetention = flag.Int("detach-retention", 720,
+		"time in hours detached clusters may be restored before their records are deleted")
+	secretKey = flag.String("secret-key", "",
+		"secret credentials of helm repositories are encrypted with, a key is generated and kept in the storage when it is empty")
+	helmRepoRefreshTTL = flag.Int("helm-repo-refresh-ttl", 3600,
+		"age in seconds of index files of helm repositories after which they are refreshed")
+	proxyMaxRequestSize = flag.Int64("proxy-max-request-size", proxy.DefaultMaxRequestSize,
+		"limit in bytes of bodies of requests proxied to services of clusters")
+	proxyMaxResponseSize = flag.Int64("proxy-max-response-size", proxy.DefaultMaxResponseSize,
+		"limit in bytes of bodies of responses of services of clusters")
+	execPlugins = flag.String("exec-plugins", strings.Join(kubeconfig.DefaultExecPlugins, ","),
+		"comma separated names of credential plugins of imported clusters that may be run on control host")
+	kubeConfigDir = flag.String("kubeconfig-dir", "",
+		"dir on control host that files referenced by imported kubeconfigs may be read from, they must be inlined when it is empty")
 )
 
 func main() {
@@ -55,6 +94,26 @@ func main() {
 		IdleTimeout:   time.Second * 120,
 		SpawnInterval: time.Second * time.Duration(*spawnInterval),
 
+		SpotInterruptionInterval:   time.Second * time.Duration(*spotInterruptionInterval),
+		SecurityGroupCheckInterval: time.Second * time.Duration(*securityGroupCheckInterval),
+		ImportDiscoveryTimeout:     time.Second * time.Duration(*importDiscoveryTimeout),
+		DiscoveryCacheTTL:          time.Second * time.Duration(*discoveryCacheTTL),
+		MetricsCacheTTL:            time.Second * time.Duration(*metricsCacheTTL),
+		AlertWebhookURL:            *alertWebhookURL,
+		AlertEvalInterval:          time.Second * time.Duration(*alertEvalInterval),
+		MetricsEnabled:             *metricsEnabled,
+		MetricsAuth:                *metricsAuth,
+		NodeParallelism:            *nodeParallelism,
+		MaxNodeFailureRatio:        *maxNodeFailureRatio,
+		TaskRetention:              time.Hour * time.Duration(*taskRetention),
+		DetachRetention:            time.Hour * time.Duration(*detachRetention),
+		SecretKey:                  *secretKey,
+		HelmRepoRefreshTTL:         time.Second * time.Duration(*helmRepoRefreshTTL),
+		ProxyMaxRequestSize:        *proxyMaxRequestSize,
+		ProxyMaxResponseSize:       *proxyMaxResponseSize,
+		ExecPlugins:                strings.Split(*execPlugins, ","),
+		KubeConfigDir:              *kubeConfigDir,
+
 		PprofListenStr: *pprofListenStr,
 
 		ProxiesPortRange: proxy.PortRange{int32(*ProxiesPortRangeFrom), int32(*ProxiesPortRangeTo)},
@@ -63,7 +122,9 @@ func main() {
 
 	server, err := controlplane.New(cfg)
 	if err != nil {
-		logrus.Infof("configuration: %+v", *cfg)
+		logged := *cfg
+		logged.SecretKey = ""
+		logrus.Infof("configuration: %+v", logged)
 		logrus.Fatalf("broken configuration: %v", err)
 	}
 
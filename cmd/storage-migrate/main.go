@@ -0,0 +1,52 @@
+// Command storage-migrate copies data of control from one storage to a
+// bolt database file, e.g. to run control on a single node without etcd.
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/controlplane"
+	"github.com/supergiant/control/pkg/storage"
+	"github.com/supergiant/control/pkg/storage/bolt"
+)
+
+var (
+	fromMode = flag.String("from-mode", "etcd", "type of storage data is copied from, either etcd or file")
+	fromURI  = flag.String("from-uri", "", "uri of storage data is copied from")
+	toFile   = flag.String("to-file", "supergiant.db", "bolt database file data is copied to")
+)
+
+func main() {
+	flag.Parse()
+
+	if *fromURI == "" {
+		logrus.Fatal("-from-uri is required")
+	}
+
+	src, err := storage.GetStorage(*fromMode, *fromURI)
+	if err != nil {
+		logrus.Fatalf("open %s storage %s: %v", *fromMode, *fromURI, err)
+	}
+
+	exporter, ok := src.(storage.Exporter)
+	if !ok {
+		logrus.Fatalf("keys of %s storage can not be listed", *fromMode)
+	}
+
+	dst, err := bolt.NewRepository(*toFile)
+	if err != nil {
+		logrus.Fatalf("open bolt storage: %v", err)
+	}
+	defer dst.Close()
+
+	count, err := storage.Migrate(context.Background(), exporter, dst, controlplane.StoragePrefixes)
+	if err != nil {
+		logrus.Errorf("migrate storage: %v", err)
+		return
+	}
+
+	logrus.Infof("copied %d keys from %s storage %s to %s", count, *fromMode, *fromURI, *toFile)
+}
@@ -1,6 +1,6 @@
 export const CLUSTER_OPTIONS = {
   archs: ['amd64'],
-  networkProviders: ['Flannel', 'Calico', 'Weave'],
+  networkProviders: ['Flannel', 'Calico', 'Weave', 'Cilium'],
   operatingSystems: ['linux'],
   networkTypes: ['vxlan'],
   ubuntuVersions: ['xenial'],
@@ -4,12 +4,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/asaskevich/govalidator.v8"
 
+	"github.com/supergiant/control/pkg/api"
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/clouds/awssdk"
 	"github.com/supergiant/control/pkg/message"
 	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/sgerrors"
@@ -21,12 +26,23 @@ import (
 type Handler struct {
 	validator util.CloudAccountValidator
 	service   *Service
+
+	getRegionDescriber func(steps.AWSConfig) (awssdk.RegionDescriber, error)
+}
+
+// Fields of listed accounts returned whatever fields are selected
+var accountKeyFields = []string{"name"}
+
+type regionValidation struct {
+	Region           string `json:"region"`
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
 }
 
 func NewHandler(service *Service) *Handler {
 	return &Handler{
-		validator: util.NewCloudAccountValidator(),
-		service:   service,
+		validator:          util.NewCloudAccountValidator(),
+		service:            service,
+		getRegionDescriber: getRegionDescriber,
 	}
 }
 
@@ -38,6 +54,8 @@ func (h *Handler) Register(r *mux.Router) {
 	r.HandleFunc("/accounts/{accountName}", h.Delete).Methods(http.MethodDelete)
 	r.HandleFunc("/accounts/{accountName}/regions", h.GetRegions).Methods(http.MethodGet)
 	r.HandleFunc("/accounts/{accountName}/regions/{region}/az", h.GetAZs).Methods(http.MethodGet)
+	r.HandleFunc("/accounts/{accountName}/regions/{region}/validate", h.ValidateRegion).Methods(http.MethodGet)
+	r.HandleFunc("/accounts/{accountName}/regions/{region}/machinetypes", h.GetMachineTypes).Methods(http.MethodGet)
 	r.HandleFunc("/accounts/{accountName}/regions/{region}/az/{az}/types", h.GetTypes).Methods(http.MethodGet)
 }
 
@@ -63,6 +81,8 @@ func (h *Handler) Create(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	hideDefaultChainKeys(account)
+
 	if err = h.service.Create(r.Context(), account); err != nil {
 		if sgerrors.IsUnsupportedProvider(err) {
 			message.SendMessage(rw, message.New(fmt.Sprintf("Unsupported provider %s", account.Provider),
@@ -81,9 +101,23 @@ func (h *Handler) Create(rw http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// ListAll retrieves all cloud accounts
+// ListAll retrieves cloud accounts matching provider and name query
+// parameters, a page of them is returned when limit or offset is set.
 func (h *Handler) ListAll(rw http.ResponseWriter, r *http.Request) {
-	accounts, err := h.service.GetAll(r.Context())
+	query := r.URL.Query()
+
+	page, err := api.ParsePage(query, 0, 0)
+	if err != nil {
+		message.SendValidationFailed(rw, err)
+		return
+	}
+
+	filter := Filter{
+		Provider: query.Get("provider"),
+		Name:     query.Get("name"),
+	}
+
+	accounts, total, err := h.service.List(r.Context(), filter, page.Offset, page.Limit)
 	if err != nil {
 		if sgerrors.IsNotFound(err) {
 			message.SendNotFound(rw, "accounts", err)
@@ -94,7 +128,22 @@ func (h *Handler) ListAll(rw http.ResponseWriter, r *http.Request) {
 		message.SendUnknownError(rw, err)
 		return
 	}
-	if err := json.NewEncoder(rw).Encode(accounts); err != nil {
+
+	for i := range accounts {
+		hideDefaultChainKeys(&accounts[i])
+	}
+
+	resp, err := api.SelectFields(accounts, api.ParseFields(query), accountKeyFields, nil)
+	if err != nil {
+		logrus.Errorf("account handler: list all %v", err)
+		message.SendUnknownError(rw, err)
+		return
+	}
+
+	// Total count of matching accounts is reported for pagination
+	rw.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	if err := json.NewEncoder(rw).Encode(resp); err != nil {
 		logrus.Errorf("account handler: list all %v", err)
 		message.SendUnknownError(rw, err)
 		return
@@ -115,6 +164,8 @@ func (h *Handler) Get(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	hideDefaultChainKeys(account)
+
 	if err := json.NewEncoder(rw).Encode(account); err != nil {
 		logrus.Errorf("account handler: get %v", err)
 		message.SendUnknownError(rw, err)
@@ -136,7 +187,18 @@ func (h *Handler) Update(rw http.ResponseWriter, r *http.Request) {
 		message.SendValidationFailed(rw, err)
 		return
 	}
+
+	hideDefaultChainKeys(account)
+
 	if err := h.service.Update(r.Context(), account); err != nil {
+		if sgerrors.IsConflict(err) {
+			current, getErr := h.service.Get(r.Context(), account.Name)
+			if getErr == nil {
+				message.SendConflict(rw, account.Name, current.Revision, err)
+				return
+			}
+		}
+
 		logrus.Errorf("account handler: update: %v", err)
 		message.SendUnknownError(rw, err)
 		return
@@ -311,3 +373,142 @@ func (h *Handler) GetTypes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// ValidateRegion checks AWS region and optional availability zone passed
+// as az query parameter with the account credentials.
+func (h *Handler) ValidateRegion(w http.ResponseWriter, r *http.Request) {
+	accountName := mux.Vars(r)["accountName"]
+	resp := regionValidation{
+		Region:           mux.Vars(r)["region"],
+		AvailabilityZone: r.URL.Query().Get("az"),
+	}
+
+	acc, err := h.service.Get(r.Context(), accountName)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, "account", err)
+			return
+		}
+
+		logrus.Errorf("clouds: validate region %s %v", accountName, err)
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if acc.Provider != clouds.AWS {
+		message.SendValidationFailed(w, errors.Wrapf(ErrUnsupportedProvider,
+			"validate region of %s", acc.Provider))
+		return
+	}
+
+	config := &steps.Config{}
+	if err := util.FillCloudAccountCredentials(acc, config); err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	awsCfg := config.AWSConfig
+	awsCfg.Region = awssdk.DefaultRegion
+	svc, err := h.getRegionDescriber(awsCfg)
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if err := awssdk.ValidateRegion(r.Context(), svc, resp.Region); err != nil {
+		sendRegionErr(w, "region", err)
+		return
+	}
+
+	if resp.AvailabilityZone != "" {
+		awsCfg.Region = resp.Region
+		svc, err = h.getRegionDescriber(awsCfg)
+		if err != nil {
+			message.SendUnknownError(w, err)
+			return
+		}
+
+		err = awssdk.ValidateZone(r.Context(), svc, resp.Region, resp.AvailabilityZone)
+		if err != nil {
+			sendRegionErr(w, "availability zone", err)
+			return
+		}
+	}
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logrus.Errorf("clouds: validate region %v", err)
+	}
+}
+
+// GetMachineTypes returns machine types of the region with cpu, memory
+// and spot support.
+func (h *Handler) GetMachineTypes(w http.ResponseWriter, r *http.Request) {
+	accountName := mux.Vars(r)["accountName"]
+	region := mux.Vars(r)["region"]
+
+	acc, err := h.service.Get(r.Context(), accountName)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, "account", err)
+			return
+		}
+
+		logrus.Errorf("clouds: get machine types %s %v", accountName, err)
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	config := &steps.Config{
+		CloudAccountName: acc.Name,
+	}
+	if err := util.FillCloudAccountCredentials(acc, config); err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	types, err := GetMachineTypes(r.Context(), config, region)
+	if err != nil {
+		logrus.Errorf("clouds: get %s machine types %v", acc.Provider, err)
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(types); err != nil {
+		logrus.Errorf("clouds: get %s machine types %v", acc.Provider, err)
+	}
+}
+
+func sendRegionErr(w http.ResponseWriter, entity string, err error) {
+	if sgerrors.IsNotFound(err) {
+		message.SendNotFound(w, entity, err)
+		return
+	}
+
+	logrus.Errorf("clouds: validate %s %v", entity, err)
+	message.SendUnknownError(w, err)
+}
+
+func getRegionDescriber(cfg steps.AWSConfig) (awssdk.RegionDescriber, error) {
+	sess, err := awssdk.NewSession(cfg.Region, util.AWSCredentials(cfg))
+	if err != nil {
+		return nil, errors.Wrap(err, "aws authentication")
+	}
+
+	return ec2.New(sess), nil
+}
+
+// hideDefaultChainKeys removes keys from AWS account that uses default
+// credential chain, so keys resolved by the SDK are never stored or returned.
+func hideDefaultChainKeys(account *model.CloudAccount) {
+	if account == nil || account.Provider != clouds.AWS {
+		return
+	}
+
+	if useDefaultChain, _ := strconv.ParseBool(account.Credentials[clouds.AWSUseDefaultChain]); !useDefaultChain {
+		return
+	}
+
+	delete(account.Credentials, clouds.AWSAccessKeyID)
+	delete(account.Credentials, clouds.AWSSecretKey)
+	delete(account.Credentials, clouds.AWSSessionToken)
+}
@@ -2,6 +2,7 @@ package account
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -9,6 +10,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/mock"
@@ -16,10 +20,13 @@ import (
 	"gopkg.in/asaskevich/govalidator.v8"
 
 	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/clouds/awssdk"
 	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/storage/memory"
 	"github.com/supergiant/control/pkg/testutils"
 	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
 )
 
 type MockValidator struct {
@@ -247,11 +254,55 @@ func TestService_Update(t *testing.T) {
 	}
 }
 
+func TestHandler_UpdateConflict(t *testing.T) {
+	h := NewHandler(NewService(DefaultStoragePrefix, memory.NewInMemoryRepository()))
+
+	stored := &model.CloudAccount{
+		Name:        "test",
+		Provider:    clouds.DigitalOcean,
+		Credentials: map[string]string{"accessToken": "token"},
+	}
+	require.NoError(t, h.service.Create(context.Background(), stored))
+	require.NoError(t, h.service.Update(context.Background(), stored))
+
+	router := mux.NewRouter()
+	h.Register(router)
+
+	for _, testCase := range []struct {
+		revision     int64
+		expectedCode int
+	}{
+		{1, http.StatusConflict},
+		{2, http.StatusOK},
+		{2, http.StatusConflict},
+	} {
+		body, _ := json.Marshal(&model.CloudAccount{
+			Name:        "test",
+			Provider:    clouds.DigitalOcean,
+			Credentials: map[string]string{"accessToken": "new"},
+			Revision:    testCase.revision,
+		})
+
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPut, "/accounts/test", bytes.NewReader(body))
+
+		router.ServeHTTP(rec, req)
+
+		require.Equal(t, testCase.expectedCode, rec.Code)
+
+		if rec.Code == http.StatusConflict {
+			current, err := h.service.Get(context.Background(), "test")
+			require.NoError(t, err)
+			require.Equal(t, fmt.Sprintf("%d", current.Revision), rec.Header().Get("X-Revision"))
+		}
+	}
+}
+
 func TestHandler_Register(t *testing.T) {
 	r := mux.NewRouter()
 	h := Handler{}
 	h.Register(r)
-	expectedRouteCount := 8
+	expectedRouteCount := 10
 	routes := []*mux.Route{}
 
 	walkFn := func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
@@ -328,6 +379,67 @@ func TestHandler_ListAll(t *testing.T) {
 	}
 }
 
+func TestHandler_ListAllFiltered(t *testing.T) {
+	stored := [][]byte{
+		[]byte(`{"name":"prod-aws","provider":"aws","credentials":{"key":"1"}}`),
+		[]byte(`{"name":"dev-aws","provider":"aws","credentials":{"key":"2"}}`),
+		[]byte(`{"name":"prod-gce","provider":"gce","credentials":{"key":"3"}}`),
+		[]byte(`{"name":"ci-aws","provider":"aws","credentials":{"key":"4"}}`),
+	}
+
+	testCases := []struct {
+		url           string
+		expectedCode  int
+		expectedBody  []map[string]interface{}
+		expectedTotal string
+	}{
+		{
+			url:          "/accounts?provider=aws&limit=1&offset=1&fields=provider",
+			expectedCode: http.StatusOK,
+			expectedBody: []map[string]interface{}{
+				{"name": "dev-aws", "provider": "aws"},
+			},
+			expectedTotal: "3",
+		},
+		{
+			url:          "/accounts?name=prod&fields=name",
+			expectedCode: http.StatusOK,
+			expectedBody: []map[string]interface{}{
+				{"name": "prod-aws"},
+				{"name": "prod-gce"},
+			},
+			expectedTotal: "2",
+		},
+		{
+			url:          "/accounts?offset=-1",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for i, testCase := range testCases {
+		e, m := fixtures()
+		m.On("GetAll", mock.Anything, mock.Anything).Return(stored, nil)
+
+		router := mux.NewRouter()
+		e.Register(router)
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, testCase.url, nil)
+
+		router.ServeHTTP(rec, req)
+
+		require.Equalf(t, testCase.expectedCode, rec.Code, "TC#%d", i+1)
+
+		if testCase.expectedBody == nil {
+			continue
+		}
+
+		var body []map[string]interface{}
+		require.NoErrorf(t, json.NewDecoder(rec.Body).Decode(&body), "TC#%d", i+1)
+		require.Equalf(t, testCase.expectedBody, body, "TC#%d", i+1)
+		require.Equalf(t, testCase.expectedTotal, rec.Header().Get("X-Total-Count"), "TC#%d", i+1)
+	}
+}
+
 func TestHandler_Get(t *testing.T) {
 	testCases := []struct {
 		accountName          string
@@ -381,6 +493,65 @@ func TestHandler_Get(t *testing.T) {
 	}
 }
 
+func TestHideDefaultChainKeys(t *testing.T) {
+	testCases := []struct {
+		description string
+		account     *model.CloudAccount
+		hidden      bool
+	}{
+		{
+			description: "default chain",
+			account: &model.CloudAccount{
+				Provider: clouds.AWS,
+				Credentials: map[string]string{
+					clouds.AWSAccessKeyID:     "key",
+					clouds.AWSSecretKey:       "secret",
+					clouds.AWSSessionToken:    "token",
+					clouds.AWSUseDefaultChain: "true",
+				},
+			},
+			hidden: true,
+		},
+		{
+			description: "static keys",
+			account: &model.CloudAccount{
+				Provider: clouds.AWS,
+				Credentials: map[string]string{
+					clouds.AWSAccessKeyID: "key",
+					clouds.AWSSecretKey:   "secret",
+				},
+			},
+		},
+		{
+			description: "other provider",
+			account: &model.CloudAccount{
+				Provider: clouds.GCE,
+				Credentials: map[string]string{
+					clouds.AWSAccessKeyID:     "key",
+					clouds.AWSUseDefaultChain: "true",
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		hideDefaultChainKeys(testCase.account)
+
+		_, hasKey := testCase.account.Credentials[clouds.AWSAccessKeyID]
+		_, hasSecret := testCase.account.Credentials[clouds.AWSSecretKey]
+		_, hasToken := testCase.account.Credentials[clouds.AWSSessionToken]
+
+		if testCase.hidden && (hasKey || hasSecret || hasToken) {
+			t.Errorf("Keys must be hidden %v", testCase.account.Credentials)
+		}
+
+		if !testCase.hidden && !hasKey {
+			t.Errorf("Keys must not be hidden %v", testCase.account.Credentials)
+		}
+	}
+}
+
 func TestHandler_GetRegions(t *testing.T) {
 	testCases := []struct {
 		accountName          string
@@ -610,3 +781,168 @@ func TestHandler_GetTypes(t *testing.T) {
 		}
 	}
 }
+
+type fakeRegionDescriber struct {
+	err error
+}
+
+func (f *fakeRegionDescriber) DescribeRegionsWithContext(aws.Context, *ec2.DescribeRegionsInput,
+	...request.Option) (*ec2.DescribeRegionsOutput, error) {
+	return &ec2.DescribeRegionsOutput{
+		Regions: []*ec2.Region{
+			{RegionName: aws.String("us-east-1")},
+			{RegionName: aws.String("eu-west-1")},
+		},
+	}, f.err
+}
+
+func (f *fakeRegionDescriber) DescribeAvailabilityZonesWithContext(aws.Context, *ec2.DescribeAvailabilityZonesInput,
+	...request.Option) (*ec2.DescribeAvailabilityZonesOutput, error) {
+	return &ec2.DescribeAvailabilityZonesOutput{
+		AvailabilityZones: []*ec2.AvailabilityZone{
+			{ZoneName: aws.String("eu-west-1a")},
+		},
+	}, f.err
+}
+
+func TestHandler_ValidateRegion(t *testing.T) {
+	awsAccount := []byte(`{"provider":"aws",
+		"credentials": {"access_key":"access", "secret_key":"key"}}`)
+
+	testCases := []struct {
+		description  string
+		url          string
+		accData      []byte
+		serviceErr   error
+		describeErr  error
+		expectedCode int
+	}{
+		{
+			description:  "account not found",
+			url:          "/accounts/test/regions/eu-west-1/validate",
+			serviceErr:   sgerrors.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "unsupported provider",
+			url:          "/accounts/test/regions/eu-west-1/validate",
+			accData:      []byte(`{"provider":"digitalocean"}`),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "describe error",
+			url:          "/accounts/test/regions/eu-west-1/validate",
+			accData:      awsAccount,
+			describeErr:  errors.New("describe error"),
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			description:  "wrong region",
+			url:          "/accounts/test/regions/eu-wset-1/validate",
+			accData:      awsAccount,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "wrong zone",
+			url:          "/accounts/test/regions/eu-west-1/validate?az=eu-west-1z",
+			accData:      awsAccount,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "success",
+			url:          "/accounts/test/regions/eu-west-1/validate?az=eu-west-1a",
+			accData:      awsAccount,
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		e, m := fixtures()
+		m.On("Get", mock.Anything,
+			mock.Anything, mock.Anything, mock.Anything).
+			Return(testCase.accData, testCase.serviceErr)
+		e.getRegionDescriber = func(steps.AWSConfig) (awssdk.RegionDescriber, error) {
+			return &fakeRegionDescriber{err: testCase.describeErr}, nil
+		}
+
+		router := mux.NewRouter()
+		e.Register(router)
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, testCase.url, nil)
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("Wrong response code expected %d actual %d",
+				testCase.expectedCode, rec.Code)
+		}
+	}
+}
+
+func TestHandler_GetMachineTypes(t *testing.T) {
+	defer withSpotTypes("m4.large")()
+
+	testCases := []struct {
+		description  string
+		region       string
+		accData      []byte
+		serviceErr   error
+		expectedCode int
+	}{
+		{
+			description:  "account not found",
+			region:       "us-east-1",
+			serviceErr:   sgerrors.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "unsupported provider",
+			region:       "us-east-1",
+			accData:      []byte(`{"name":"test","provider":"unknowncloud"}`),
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			description:  "unknown region",
+			region:       "us-east-99",
+			accData:      []byte(`{"name":"test","provider":"aws"}`),
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			description:  "success",
+			region:       "us-east-1",
+			accData:      []byte(`{"name":"test","provider":"aws"}`),
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		e, m := fixtures()
+		m.On("Get", mock.Anything,
+			mock.Anything, mock.Anything, mock.Anything).
+			Return(testCase.accData, testCase.serviceErr)
+
+		router := mux.NewRouter()
+		e.Register(router)
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet,
+			fmt.Sprintf("/accounts/test/regions/%s/machinetypes", testCase.region), nil)
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("Wrong response code expected %d actual %d",
+				testCase.expectedCode, rec.Code)
+			continue
+		}
+
+		if rec.Code == http.StatusOK {
+			var types []MachineType
+
+			if err := json.NewDecoder(rec.Body).Decode(&types); err != nil || len(types) == 0 {
+				t.Errorf("Wrong machine types %v %v", types, err)
+			}
+		}
+	}
+}
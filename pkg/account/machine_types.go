@@ -0,0 +1,434 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2017-09-01/skus"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/digitalocean/godo"
+	"github.com/pkg/errors"
+	gcecomputev1 "google.golang.org/api/compute/v1"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/clouds/awssdk"
+	"github.com/supergiant/control/pkg/clouds/digitaloceansdk"
+	"github.com/supergiant/control/pkg/clouds/gcesdk"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const machineTypesTTL = time.Hour
+
+// MachineType describes machine type available in the region.
+type MachineType struct {
+	Name      string  `json:"name"`
+	VCPU      int64   `json:"vcpu"`
+	MemoryGiB float64 `json:"memoryGiB"`
+	// Spot is true when machines of the type can be provisioned
+	// as spot or preemptible instances.
+	Spot bool `json:"spot"`
+	// GPU is count of GPUs attached to machines of the type
+	GPU int64 `json:"gpu,omitempty"`
+}
+
+var (
+	// GCE accelerator optimized types have GPUs count in the name,
+	// e.g. a2-highgpu-4g, accelerators attached to other types
+	// are not supported.
+	gceGPUType = regexp.MustCompile(`^a[23]-[a-z]+gpu-(\d+)g$`)
+	// DigitalOcean GPU droplets have GPUs count in the slug,
+	// e.g. gpu-h100x8-640gb.
+	doGPUSize = regexp.MustCompile(`^gpu-[a-z0-9]+x(\d+)-`)
+)
+
+// spotPriceLister lists spot prices of EC2 instance types.
+type spotPriceLister interface {
+	DescribeSpotPriceHistoryPagesWithContext(aws.Context, *ec2.DescribeSpotPriceHistoryInput,
+		func(*ec2.DescribeSpotPriceHistoryOutput, bool) bool, ...request.Option) error
+}
+
+var getSpotPriceLister = func(cfg steps.AWSConfig, region string) (spotPriceLister, error) {
+	sess, err := awssdk.NewSession(region, util.AWSCredentials(cfg))
+
+	if err != nil {
+		return nil, errors.Wrap(err, "aws authentication")
+	}
+
+	return ec2.New(sess), nil
+}
+
+type machineTypesEntry struct {
+	types   []MachineType
+	expires time.Time
+}
+
+// machineTypesCache keeps machine types per account and region,
+// so cloud APIs are not called on each request.
+type machineTypesCache struct {
+	m       sync.Mutex
+	entries map[string]machineTypesEntry
+	now     func() time.Time
+}
+
+var machineTypes = &machineTypesCache{
+	entries: make(map[string]machineTypesEntry),
+	now:     time.Now,
+}
+
+func (c *machineTypesCache) get(key string) ([]MachineType, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	entry, ok := c.entries[key]
+
+	if !ok || c.now().After(entry.expires) {
+		return nil, false
+	}
+
+	return entry.types, true
+}
+
+func (c *machineTypesCache) put(key string, types []MachineType) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.entries[key] = machineTypesEntry{
+		types:   types,
+		expires: c.now().Add(machineTypesTTL),
+	}
+}
+
+// GetMachineTypes returns machine types available in the region for
+// the cloud account of config, results are cached for an hour.
+func GetMachineTypes(ctx context.Context, config *steps.Config, region string) ([]MachineType, error) {
+	key := fmt.Sprintf("%s/%s/%s", config.Provider, config.CloudAccountName, region)
+
+	if types, ok := machineTypes.get(key); ok {
+		return types, nil
+	}
+
+	var (
+		types []MachineType
+		err   error
+	)
+
+	switch config.Provider {
+	case clouds.AWS:
+		types, err = awsMachineTypes(ctx, config, region)
+	case clouds.GCE:
+		types, err = gceMachineTypes(ctx, config, region)
+	case clouds.DigitalOcean:
+		types, err = doMachineTypes(ctx, config, region)
+	case clouds.Azure:
+		types, err = azureMachineTypes(ctx, config, region)
+	default:
+		return nil, errors.Wrapf(ErrUnsupportedProvider, "machine types of %s", config.Provider)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "get %s machine types in %s", config.Provider, region)
+	}
+
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].Name < types[j].Name
+	})
+
+	machineTypes.put(key, types)
+
+	return types, nil
+}
+
+// ValidateGPUMachineType checks that machines of the type available
+// in the region have GPUs.
+func ValidateGPUMachineType(ctx context.Context, config *steps.Config, region, name string) error {
+	types, err := GetMachineTypes(ctx, config, region)
+
+	if errors.Cause(err) == ErrUnsupportedProvider {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"GPU nodes are not supported by provider %s", config.Provider)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for _, machineType := range types {
+		if machineType.Name != name {
+			continue
+		}
+
+		if machineType.GPU == 0 {
+			return errors.Wrapf(sgerrors.ErrValidationFailed,
+				"machine type %s has no GPUs", name)
+		}
+
+		return nil
+	}
+
+	return errors.Wrapf(sgerrors.ErrValidationFailed,
+		"machine type %s is not available in %s", name, region)
+}
+
+// awsMachineTypes uses generated list of EC2 instance types of the
+// region. DescribeInstanceTypeOfferings is missing from the vendored
+// aws sdk, so types are marked spot when the spot price history of the
+// region has current prices for them in any availability zone.
+func awsMachineTypes(ctx context.Context, config *steps.Config, region string) ([]MachineType, error) {
+	names, err := awsMachines.RegionTypes(region)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lister, err := getSpotPriceLister(config.AWSConfig, region)
+
+	if err != nil {
+		return nil, err
+	}
+
+	spot, err := awsSpotTypes(ctx, lister)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := awsMachines.Sizes()
+	types := make([]MachineType, 0, len(names))
+
+	for _, name := range names {
+		size := sizes[name]
+		vcpu, _ := strconv.ParseInt(size.VCPU, 10, 64)
+		memory, _ := strconv.ParseFloat(size.MemoryGiB, 64)
+		gpu, _ := strconv.ParseInt(size.GPU, 10, 64)
+
+		types = append(types, MachineType{
+			Name:      name,
+			VCPU:      vcpu,
+			MemoryGiB: memory,
+			Spot:      spot[name],
+			GPU:       gpu,
+		})
+	}
+
+	return types, nil
+}
+
+// awsSpotTypes returns instance types which have current spot prices.
+func awsSpotTypes(ctx context.Context, lister spotPriceLister) (map[string]bool, error) {
+	types := make(map[string]bool)
+	input := &ec2.DescribeSpotPriceHistoryInput{
+		// Only the current price of each type and zone is returned
+		StartTime:           aws.Time(time.Now()),
+		ProductDescriptions: aws.StringSlice([]string{"Linux/UNIX"}),
+	}
+
+	err := lister.DescribeSpotPriceHistoryPagesWithContext(ctx, input,
+		func(out *ec2.DescribeSpotPriceHistoryOutput, last bool) bool {
+			for _, price := range out.SpotPriceHistory {
+				types[aws.StringValue(price.InstanceType)] = true
+			}
+
+			return true
+		})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "describe spot price history")
+	}
+
+	return types, nil
+}
+
+func gceMachineTypes(ctx context.Context, config *steps.Config, region string) ([]MachineType, error) {
+	client, err := gcesdk.GetClient(ctx, config.GCEConfig)
+
+	if err != nil {
+		return nil, err
+	}
+
+	projectID := config.GCEConfig.ServiceAccount.ProjectID
+	gceRegion, err := client.Regions.Get(projectID, region).Context(ctx).Do()
+
+	if err != nil {
+		return nil, errors.Wrap(err, "get region")
+	}
+
+	var items []*gcecomputev1.MachineType
+
+	for _, zoneLink := range gceRegion.Zones {
+		zone := zoneLink[strings.LastIndex(zoneLink, "/")+1:]
+		err := client.MachineTypes.List(projectID, zone).Pages(ctx,
+			func(list *gcecomputev1.MachineTypeList) error {
+				items = append(items, list.Items...)
+				return nil
+			})
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "list machine types in %s", zone)
+		}
+	}
+
+	return convertGCEMachineTypes(items), nil
+}
+
+// convertGCEMachineTypes merges machine types of region zones,
+// any machine type can be preemptible.
+func convertGCEMachineTypes(items []*gcecomputev1.MachineType) []MachineType {
+	seen := make(map[string]bool)
+	types := make([]MachineType, 0, len(items))
+
+	for _, item := range items {
+		if item == nil || seen[item.Name] {
+			continue
+		}
+
+		seen[item.Name] = true
+		types = append(types, MachineType{
+			Name:      item.Name,
+			VCPU:      item.GuestCpus,
+			MemoryGiB: float64(item.MemoryMb) / 1024,
+			Spot:      true,
+			GPU:       gpuCount(gceGPUType, item.Name),
+		})
+	}
+
+	return types
+}
+
+func doMachineTypes(ctx context.Context, config *steps.Config, region string) ([]MachineType, error) {
+	client := digitaloceansdk.New(config.DigitalOceanConfig.AccessToken).GetClient()
+	var sizes []godo.Size
+	opts := &godo.ListOptions{}
+
+	for {
+		page, resp, err := client.Sizes.List(ctx, opts)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sizes = append(sizes, page...)
+
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+
+		current, err := resp.Links.CurrentPage()
+
+		if err != nil {
+			return nil, err
+		}
+
+		opts.Page = current + 1
+	}
+
+	return convertDOSizes(sizes, region), nil
+}
+
+// convertDOSizes returns sizes available in region, DigitalOcean
+// has no spot droplets.
+func convertDOSizes(sizes []godo.Size, region string) []MachineType {
+	types := make([]MachineType, 0, len(sizes))
+
+	for _, size := range sizes {
+		if !size.Available || !contains(size.Regions, region) {
+			continue
+		}
+
+		types = append(types, MachineType{
+			Name:      size.Slug,
+			VCPU:      int64(size.Vcpus),
+			MemoryGiB: float64(size.Memory) / 1024,
+			GPU:       gpuCount(doGPUSize, size.Slug),
+		})
+	}
+
+	return types
+}
+
+func azureMachineTypes(ctx context.Context, config *steps.Config, region string) ([]MachineType, error) {
+	token, err := auth.NewClientCredentialsConfig(config.AzureConfig.ClientID,
+		config.AzureConfig.ClientSecret, config.AzureConfig.TenantID).Authorizer()
+
+	if err != nil {
+		return nil, errors.Wrap(err, "get authorization token")
+	}
+
+	skusClient := skus.NewResourceSkusClient(config.AzureConfig.SubscriptionID)
+	skusClient.Authorizer = token
+
+	finder := AzureFinder{
+		subscriptionID: config.AzureConfig.SubscriptionID,
+		location:       region,
+		skusClient:     skusClient,
+	}
+
+	sizes, err := finder.getVMSizes(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return convertAzureSizes(sizes), nil
+}
+
+// convertAzureSizes reads cpu and memory from SKU capabilities, spot
+// virtual machines are not provisioned on Azure.
+func convertAzureSizes(sizes []skus.ResourceSku) []MachineType {
+	seen := make(map[string]bool)
+	types := make([]MachineType, 0, len(sizes))
+
+	for _, size := range sizes {
+		name := to.String(size.Name)
+
+		if name == "" || seen[name] {
+			continue
+		}
+
+		seen[name] = true
+		machineType := MachineType{
+			Name: name,
+		}
+
+		if size.Capabilities != nil {
+			for _, capability := range *size.Capabilities {
+				switch to.String(capability.Name) {
+				case "vCPUs":
+					machineType.VCPU, _ = strconv.ParseInt(to.String(capability.Value), 10, 64)
+				case "MemoryGB":
+					machineType.MemoryGiB, _ = strconv.ParseFloat(to.String(capability.Value), 64)
+				case "GPUs":
+					machineType.GPU, _ = strconv.ParseInt(to.String(capability.Value), 10, 64)
+				}
+			}
+		}
+
+		types = append(types, machineType)
+	}
+
+	return types
+}
+
+// gpuCount reads count of GPUs from the machine type name.
+func gpuCount(pattern *regexp.Regexp, name string) int64 {
+	match := pattern.FindStringSubmatch(name)
+
+	if match == nil {
+		return 0
+	}
+
+	count, _ := strconv.ParseInt(match[1], 10, 64)
+
+	return count
+}
@@ -0,0 +1,243 @@
+package account
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2017-09-01/skus"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/digitalocean/godo"
+	"github.com/pkg/errors"
+	gcecomputev1 "google.golang.org/api/compute/v1"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type fakeSpotPriceLister struct {
+	types []string
+}
+
+func (f *fakeSpotPriceLister) DescribeSpotPriceHistoryPagesWithContext(ctx aws.Context,
+	input *ec2.DescribeSpotPriceHistoryInput, fn func(*ec2.DescribeSpotPriceHistoryOutput, bool) bool,
+	opts ...request.Option) error {
+	out := &ec2.DescribeSpotPriceHistoryOutput{}
+
+	for _, machineType := range f.types {
+		out.SpotPriceHistory = append(out.SpotPriceHistory, &ec2.SpotPrice{
+			AvailabilityZone: aws.String("us-east-1a"),
+			InstanceType:     aws.String(machineType),
+			SpotPrice:        aws.String("0.05"),
+		})
+	}
+
+	fn(out, true)
+
+	return nil
+}
+
+// withSpotTypes makes spot prices of the types listed, the returned
+// func restores the lister.
+func withSpotTypes(types ...string) func() {
+	prev := getSpotPriceLister
+	getSpotPriceLister = func(steps.AWSConfig, string) (spotPriceLister, error) {
+		return &fakeSpotPriceLister{types: types}, nil
+	}
+
+	return func() {
+		getSpotPriceLister = prev
+	}
+}
+
+func TestGetMachineTypesAWS(t *testing.T) {
+	defer withSpotTypes("m4.large")()
+
+	config := &steps.Config{
+		Provider:         clouds.AWS,
+		CloudAccountName: "aws-test",
+	}
+
+	types, err := GetMachineTypes(context.Background(), config, "us-east-1")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	var found bool
+
+	for i, machineType := range types {
+		if i > 0 && types[i-1].Name > machineType.Name {
+			t.Errorf("Machine types must be sorted %s %s", types[i-1].Name, machineType.Name)
+		}
+
+		if machineType.Name == "m4.large" {
+			found = true
+
+			if machineType.VCPU != 2 || machineType.MemoryGiB != 8 || !machineType.Spot {
+				t.Errorf("Wrong machine type %v", machineType)
+			}
+		}
+
+		// Types without spot prices are not spot
+		if machineType.Name == "m4.xlarge" && machineType.Spot {
+			t.Errorf("Machine type %s must not be spot", machineType.Name)
+		}
+	}
+
+	if !found {
+		t.Errorf("Machine type m4.large not found in %v", types)
+	}
+
+	if _, err := GetMachineTypes(context.Background(), config, "us-east-99"); err == nil {
+		t.Errorf("Error must not be nil for unknown region")
+	}
+}
+
+func TestGetMachineTypesUnsupported(t *testing.T) {
+	_, err := GetMachineTypes(context.Background(), &steps.Config{
+		Provider: clouds.Name("unknown"),
+	}, "region")
+
+	if errors.Cause(err) != ErrUnsupportedProvider {
+		t.Errorf("Wrong error expected %v actual %v", ErrUnsupportedProvider, err)
+	}
+}
+
+func TestMachineTypesCache(t *testing.T) {
+	now := time.Now()
+	cache := &machineTypesCache{
+		entries: make(map[string]machineTypesEntry),
+		now: func() time.Time {
+			return now
+		},
+	}
+
+	if _, ok := cache.get("key"); ok {
+		t.Errorf("Empty cache must not have entries")
+	}
+
+	cache.put("key", []MachineType{{Name: "m4.large"}})
+
+	if types, ok := cache.get("key"); !ok || len(types) != 1 {
+		t.Errorf("Cached types not found %v", types)
+	}
+
+	now = now.Add(machineTypesTTL + time.Second)
+
+	if _, ok := cache.get("key"); ok {
+		t.Errorf("Entry must expire after %v", machineTypesTTL)
+	}
+}
+
+func TestConvertGCEMachineTypes(t *testing.T) {
+	types := convertGCEMachineTypes([]*gcecomputev1.MachineType{
+		{Name: "n1-standard-1", GuestCpus: 1, MemoryMb: 3840},
+		{Name: "n1-standard-1", GuestCpus: 1, MemoryMb: 3840},
+		{Name: "a2-highgpu-4g", GuestCpus: 48, MemoryMb: 348160},
+		nil,
+	})
+
+	if len(types) != 2 || types[0].VCPU != 1 || types[0].MemoryGiB != 3.75 || !types[0].Spot {
+		t.Errorf("Wrong machine types %v", types)
+	}
+
+	if types[0].GPU != 0 || types[1].GPU != 4 {
+		t.Errorf("Wrong GPUs of machine types %v", types)
+	}
+}
+
+func TestConvertDOSizes(t *testing.T) {
+	types := convertDOSizes([]godo.Size{
+		{Slug: "s-1vcpu-2gb", Vcpus: 1, Memory: 2048, Available: true, Regions: []string{"fra1"}},
+		{Slug: "s-2vcpu-4gb", Vcpus: 2, Memory: 4096, Available: false, Regions: []string{"fra1"}},
+		{Slug: "s-4vcpu-8gb", Vcpus: 4, Memory: 8192, Available: true, Regions: []string{"nyc1"}},
+		{Slug: "gpu-h100x8-640gb", Vcpus: 160, Memory: 1966080, Available: true, Regions: []string{"fra1"}},
+	}, "fra1")
+
+	if len(types) != 2 || types[0].Name != "s-1vcpu-2gb" || types[0].MemoryGiB != 2 || types[0].Spot {
+		t.Errorf("Wrong machine types %v", types)
+	}
+
+	if types[0].GPU != 0 || types[1].GPU != 8 {
+		t.Errorf("Wrong GPUs of machine types %v", types)
+	}
+}
+
+func TestConvertAzureSizes(t *testing.T) {
+	types := convertAzureSizes([]skus.ResourceSku{
+		{
+			Name: to.StringPtr("Standard_A2_v2"),
+			Capabilities: &[]skus.ResourceSkuCapabilities{
+				{Name: to.StringPtr("vCPUs"), Value: to.StringPtr("2")},
+				{Name: to.StringPtr("MemoryGB"), Value: to.StringPtr("4")},
+			},
+		},
+		{
+			Name: to.StringPtr("Standard_NC6"),
+			Capabilities: &[]skus.ResourceSkuCapabilities{
+				{Name: to.StringPtr("vCPUs"), Value: to.StringPtr("6")},
+				{Name: to.StringPtr("GPUs"), Value: to.StringPtr("1")},
+			},
+		},
+		{
+			Name: to.StringPtr(""),
+		},
+	})
+
+	if len(types) != 2 || types[0].VCPU != 2 || types[0].MemoryGiB != 4 || types[0].Spot {
+		t.Errorf("Wrong machine types %v", types)
+	}
+
+	if types[0].GPU != 0 || types[1].GPU != 1 {
+		t.Errorf("Wrong GPUs of machine types %v", types)
+	}
+}
+
+func TestValidateGPUMachineType(t *testing.T) {
+	defer withSpotTypes()()
+
+	config := &steps.Config{
+		Provider:         clouds.AWS,
+		CloudAccountName: "aws-gpu",
+	}
+
+	testCases := []struct {
+		description string
+		machineType string
+		isErr       bool
+	}{
+		{
+			description: "gpu machine type",
+			machineType: "p2.xlarge",
+		},
+		{
+			description: "machine type without gpus",
+			machineType: "m4.large",
+			isErr:       true,
+		},
+		{
+			description: "unknown machine type",
+			machineType: "x9.unknown",
+			isErr:       true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		err := ValidateGPUMachineType(context.Background(), config,
+			"us-east-1", testCase.machineType)
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("Wrong error %v", err)
+		}
+
+		if err != nil && !sgerrors.IsValidationFailed(err) {
+			t.Errorf("Wrong error type %v", err)
+		}
+	}
+}
@@ -12,14 +12,13 @@ import (
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/digitalocean/godo"
 	"github.com/pkg/errors"
 	gcecomputev1 "google.golang.org/api/compute/v1"
 
 	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/clouds/awssdk"
 	"github.com/supergiant/control/pkg/clouds/digitaloceansdk"
 	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/sgerrors"
@@ -234,14 +233,8 @@ func NewAWSFinder(acc *model.CloudAccount, config *steps.Config) (*AWSFinder, er
 		return nil, errors.Wrap(err, "aws new finder")
 	}
 
-	sess, err := session.NewSessionWithOptions(session.Options{
-		Config: aws.Config{
-			Region: aws.String(config.AWSConfig.Region),
-			Credentials: credentials.NewStaticCredentials(
-				config.AWSConfig.KeyID, config.AWSConfig.Secret,
-				""),
-		},
-	})
+	sess, err := awssdk.NewSession(config.AWSConfig.Region,
+		util.AWSCredentials(config.AWSConfig))
 
 	if err != nil {
 		return nil, errors.Wrap(err, "aws authentication: ")
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -50,6 +52,47 @@ func (s *Service) GetAll(ctx context.Context) ([]model.CloudAccount, error) {
 	return accounts, nil
 }
 
+// Filter selects accounts of a list, empty fields match all accounts.
+type Filter struct {
+	Provider string
+	// Name matches accounts with names containing it
+	Name string
+}
+
+// List returns accounts matching the filter ordered by name starting at
+// the offset, all of them are returned when limit is zero. Total count
+// of matching accounts is returned along with them.
+func (s *Service) List(ctx context.Context, filter Filter, offset, limit int) ([]model.CloudAccount, int, error) {
+	accounts, err := s.GetAll(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	matches := make([]model.CloudAccount, 0, len(accounts))
+	for _, a := range accounts {
+		if (filter.Provider == "" || filter.Provider == string(a.Provider)) &&
+			strings.Contains(a.Name, filter.Name) {
+			matches = append(matches, a)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Name < matches[j].Name
+	})
+
+	total := len(matches)
+	if offset > total {
+		offset = total
+	}
+
+	matches = matches[offset:]
+	if limit > 0 && limit < len(matches) {
+		matches = matches[:limit]
+	}
+
+	return matches, total, nil
+}
+
 // Get retrieves a user by it's accountName, returns nil if not found
 func (s *Service) Get(ctx context.Context, accountName string) (*model.CloudAccount, error) {
 	res, err := s.repository.Get(ctx, s.storagePrefix, accountName)
@@ -86,30 +129,60 @@ func (s *Service) Create(ctx context.Context, account *model.CloudAccount) error
 		return sgerrors.ErrAlreadyExists
 	}
 
+	account.Revision = 1
+
 	rawJSON, err := json.Marshal(account)
 	if err != nil {
 		return err
 	}
 
-	return s.repository.Put(ctx, s.storagePrefix, account.Name, rawJSON)
+	// Account may have been created meanwhile
+	err = storage.CompareAndSwap(ctx, s.repository, s.storagePrefix, account.Name, nil, rawJSON)
+	if sgerrors.IsConflict(err) {
+		return sgerrors.ErrAlreadyExists
+	}
+
+	return err
 }
 
-// Update cloud account
+// Update cloud account when its revision is the revision of the stored
+// account, accounts of zero revision replace the stored one whatever its
+// revision is. sgerrors.ErrConflict is returned when revisions differ.
 func (s *Service) Update(ctx context.Context, account *model.CloudAccount) error {
-	rawJSON, err := json.Marshal(account)
+	old, err := s.repository.Get(ctx, s.storagePrefix, account.Name)
 	if err != nil {
-		return errors.WithStack(err)
+		return err
+	}
+	if old == nil {
+		return sgerrors.ErrNotFound
 	}
 
-	oldAcc, err := s.Get(ctx, account.Name)
-	if err != nil {
-		return err
+	oldAcc := new(model.CloudAccount)
+	if err = json.Unmarshal(old, oldAcc); err != nil {
+		return errors.WithStack(err)
 	}
 	if oldAcc.Name != account.Name || oldAcc.Provider != account.Provider {
 		return errors.New("account name or provider can't be changed")
 	}
 
-	err = s.repository.Put(ctx, s.storagePrefix, account.Name, rawJSON)
+	if account.Revision != 0 && account.Revision != oldAcc.Revision {
+		return errors.Wrapf(sgerrors.ErrConflict, "account %s revision %d is not the stored revision %d",
+			account.Name, account.Revision, oldAcc.Revision)
+	}
+
+	revision := account.Revision
+	account.Revision = oldAcc.Revision + 1
+
+	rawJSON, err := json.Marshal(account)
+	if err != nil {
+		account.Revision = revision
+		return errors.WithStack(err)
+	}
+
+	err = storage.CompareAndSwap(ctx, s.repository, s.storagePrefix, account.Name, old, rawJSON)
+	if err != nil {
+		account.Revision = revision
+	}
 
 	return err
 }
@@ -7,8 +7,10 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/supergiant/control/pkg/clouds"
 	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/storage/memory"
 	"github.com/supergiant/control/pkg/testutils"
 )
 
@@ -72,3 +74,44 @@ func TestServiceCreate(t *testing.T) {
 		}
 	}
 }
+
+func TestServiceUpdateRevision(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(DefaultStoragePrefix, memory.NewInMemoryRepository())
+
+	if err := svc.Create(ctx, &model.CloudAccount{Name: "test", Provider: clouds.AWS}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	first, _ := svc.Get(ctx, "test")
+	second, _ := svc.Get(ctx, "test")
+
+	if first.Revision != 1 {
+		t.Errorf("expected revision 1 of created account actual %d", first.Revision)
+	}
+
+	first.Credentials["key"] = "first"
+	if err := svc.Update(ctx, first); err != nil || first.Revision != 2 {
+		t.Errorf("unexpected error %v revision %d", err, first.Revision)
+	}
+
+	second.Credentials["key"] = "second"
+	if err := svc.Update(ctx, second); !sgerrors.IsConflict(err) {
+		t.Errorf("update of stale account must conflict %v", err)
+	}
+
+	stored, _ := svc.Get(ctx, "test")
+	if stored.Credentials["key"] != "first" || stored.Revision != 2 {
+		t.Errorf("stale account must not be stored %v", stored)
+	}
+
+	// Accounts without revision replace the stored one
+	second.Revision = 0
+	if err := svc.Update(ctx, second); err != nil || second.Revision != 3 {
+		t.Errorf("unexpected error %v revision %d", err, second.Revision)
+	}
+
+	if err := svc.Create(ctx, &model.CloudAccount{Name: "test", Provider: clouds.AWS}); !sgerrors.IsAlreadyExists(err) {
+		t.Errorf("expected already exists error actual %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// AllFields selects all fields of listed entities including heavy ones.
+const AllFields = "*"
+
+// Page is a page of entities list endpoints return.
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// ParsePage parses limit and offset query parameters, limit is
+// defaultLimit when it is missing. Zero limits mean no limit, limits
+// above maxLimit are rejected unless maxLimit is zero.
+func ParsePage(query url.Values, defaultLimit, maxLimit int) (Page, error) {
+	page := Page{
+		Limit: defaultLimit,
+	}
+
+	if l := query.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit <= 0 || (maxLimit > 0 && limit > maxLimit) {
+			if maxLimit > 0 {
+				return page, errors.Wrapf(sgerrors.ErrValidationFailed,
+					"limit %s must be between 1 and %d", l, maxLimit)
+			}
+
+			return page, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"limit %s must be positive", l)
+		}
+		page.Limit = limit
+	}
+
+	if o := query.Get("offset"); o != "" {
+		offset, err := strconv.Atoi(o)
+		if err != nil || offset < 0 {
+			return page, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"offset %s must not be negative", o)
+		}
+		page.Offset = offset
+	}
+
+	return page, nil
+}
+
+// Bounds returns bounds of the page within a list of total entities.
+func (p Page) Bounds(total int) (int, int) {
+	start := p.Offset
+	if start > total {
+		start = total
+	}
+
+	end := total
+	if p.Limit > 0 && start+p.Limit < total {
+		end = start + p.Limit
+	}
+
+	return start, end
+}
+
+// ParseFields returns json names of fields the comma separated
+// fields query parameter selects, nil is returned when it is missing.
+func ParseFields(query url.Values) []string {
+	var fields []string
+
+	for _, v := range query["fields"] {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	return fields
+}
+
+// SelectFields returns json objects of the items with the fields only,
+// when no fields are selected all fields but the omitted ones are
+// returned. Keys are always returned to identify items.
+func SelectFields(items interface{}, fields []string, keys []string, omitted []string) ([]map[string]json.RawMessage, error) {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal")
+	}
+
+	var objects []map[string]json.RawMessage
+	if err = json.Unmarshal(data, &objects); err != nil {
+		return nil, errors.Wrap(err, "unmarshal")
+	}
+
+	if objects == nil {
+		objects = make([]map[string]json.RawMessage, 0)
+	}
+
+	for _, f := range fields {
+		if f == AllFields {
+			return objects, nil
+		}
+	}
+
+	for _, object := range objects {
+		if len(fields) == 0 {
+			for _, f := range omitted {
+				delete(object, f)
+			}
+			continue
+		}
+
+		for f := range object {
+			if !contains(fields, f) && !contains(keys, f) {
+				delete(object, f)
+			}
+		}
+	}
+
+	return objects, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
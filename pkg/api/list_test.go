@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+func TestParsePage(t *testing.T) {
+	testCases := []struct {
+		description string
+		query       string
+		maxLimit    int
+		expected    Page
+		invalid     bool
+	}{
+		{
+			description: "default",
+			expected:    Page{Limit: 10},
+		},
+		{
+			description: "limit and offset",
+			query:       "limit=5&offset=20",
+			expected:    Page{Limit: 5, Offset: 20},
+		},
+		{
+			description: "limit above max",
+			query:       "limit=101",
+			maxLimit:    100,
+			invalid:     true,
+		},
+		{
+			description: "limit without max",
+			query:       "limit=1000",
+			expected:    Page{Limit: 1000},
+		},
+		{
+			description: "zero limit",
+			query:       "limit=0",
+			invalid:     true,
+		},
+		{
+			description: "negative offset",
+			query:       "offset=-1",
+			invalid:     true,
+		},
+		{
+			description: "offset not a number",
+			query:       "offset=first",
+			invalid:     true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		query, _ := url.ParseQuery(testCase.query)
+		page, err := ParsePage(query, 10, testCase.maxLimit)
+
+		if testCase.invalid {
+			if !sgerrors.IsValidationFailed(err) {
+				t.Errorf("Expected validation error actual %v", err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if page != testCase.expected {
+			t.Errorf("Expected page %v actual %v", testCase.expected, page)
+		}
+	}
+}
+
+func TestPageBounds(t *testing.T) {
+	testCases := []struct {
+		page  Page
+		total int
+		start int
+		end   int
+	}{
+		{Page{}, 5, 0, 5},
+		{Page{Limit: 2}, 5, 0, 2},
+		{Page{Limit: 2, Offset: 4}, 5, 4, 5},
+		{Page{Limit: 2, Offset: 10}, 5, 5, 5},
+		{Page{Offset: 3}, 5, 3, 5},
+	}
+
+	for _, testCase := range testCases {
+		start, end := testCase.page.Bounds(testCase.total)
+
+		if start != testCase.start || end != testCase.end {
+			t.Errorf("Expected bounds of %v within %d [%d:%d] actual [%d:%d]", testCase.page,
+				testCase.total, testCase.start, testCase.end, start, end)
+		}
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	query, _ := url.ParseQuery("fields=name,%20state&fields=auth,")
+
+	if fields := ParseFields(query); !reflect.DeepEqual(fields, []string{"name", "state", "auth"}) {
+		t.Errorf("Unexpected fields %v", fields)
+	}
+
+	if fields := ParseFields(url.Values{}); fields != nil {
+		t.Errorf("Expected no fields actual %v", fields)
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	type item struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		State string `json:"state"`
+		Auth  string `json:"auth"`
+	}
+
+	items := []item{{"1", "one", "operational", "cert"}}
+
+	testCases := []struct {
+		description string
+		fields      []string
+		expected    []string
+	}{
+		{
+			description: "omitted fields are dropped by default",
+			expected:    []string{"id", "name", "state"},
+		},
+		{
+			description: "selected fields with keys",
+			fields:      []string{"name", "missing"},
+			expected:    []string{"id", "name"},
+		},
+		{
+			description: "omitted field selected",
+			fields:      []string{"auth"},
+			expected:    []string{"auth", "id"},
+		},
+		{
+			description: "all fields",
+			fields:      []string{AllFields},
+			expected:    []string{"auth", "id", "name", "state"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		objects, err := SelectFields(items, testCase.fields, []string{"id"}, []string{"auth"})
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		data, _ := json.Marshal(objects)
+
+		var actual []map[string]interface{}
+		if err = json.Unmarshal(data, &actual); err != nil || len(actual) != 1 {
+			t.Errorf("Unexpected objects %s %v", data, err)
+			continue
+		}
+
+		for _, f := range testCase.expected {
+			if _, ok := actual[0][f]; !ok {
+				t.Errorf("Expected field %s in %s", f, data)
+			}
+		}
+
+		if len(actual[0]) != len(testCase.expected) {
+			t.Errorf("Expected fields %v actual %s", testCase.expected, data)
+		}
+	}
+
+	objects, err := SelectFields([]item(nil), nil, nil, nil)
+	if err != nil || objects == nil || len(objects) != 0 {
+		t.Errorf("Expected empty list for no items actual %v %v", objects, err)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
@@ -9,6 +10,14 @@ import (
 	"github.com/supergiant/control/pkg/sgerrors"
 )
 
+// Accesses user tokens grant
+const (
+	AccessView = "view"
+	AccessEdit = "edit"
+)
+
+type claimsKey struct{}
+
 type TokenValidater interface {
 	Validate(string) (jwt.MapClaims, error)
 }
@@ -53,10 +62,40 @@ func (m *Middleware) AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
 	})
 }
 
+// WithClaims returns the context carrying claims of the user token.
+func WithClaims(ctx context.Context, claims jwt.MapClaims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// HasAccess tells whether the user token of the request grants the access.
+func HasAccess(ctx context.Context, access string) bool {
+	claims, ok := ctx.Value(claimsKey{}).(jwt.MapClaims)
+	if !ok {
+		return false
+	}
+
+	switch accesses := claims["accesses"].(type) {
+	case []string:
+		for _, a := range accesses {
+			if a == access {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, a := range accesses {
+			if a == access {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func ContentTypeJSON(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -195,6 +195,42 @@ func TestAuthMiddleware(t *testing.T) {
 	}
 }
 
+func TestHasAccess(t *testing.T) {
+	ts := sgjwt.NewTokenService(60, []byte("secret"))
+	tokenString, err := ts.Issue("root")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+
+	md := Middleware{
+		TokenService: ts,
+	}
+
+	called := false
+	md.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+
+		if !HasAccess(r.Context(), AccessView) || !HasAccess(r.Context(), AccessEdit) {
+			t.Errorf("Accesses of the token must be granted")
+		}
+
+		if HasAccess(r.Context(), "admin") {
+			t.Errorf("Access missing from the token must not be granted")
+		}
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	if !called {
+		t.Errorf("Handler was not called")
+	}
+
+	if HasAccess(req.Context(), AccessView) {
+		t.Errorf("Access must not be granted without token")
+	}
+}
+
 type testHandler struct {
 	called bool
 }
@@ -0,0 +1,257 @@
+package awssdk
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/query"
+	"github.com/pkg/errors"
+)
+
+// Instances of auto scaling group being removed from it
+const (
+	LifecycleTerminating = "Terminating"
+	LifecycleTerminated  = "Terminated"
+)
+
+// AutoScalingGroup is a group of EC2 instances of the Auto Scaling API.
+type AutoScalingGroup struct {
+	AutoScalingGroupName *string                `type:"string"`
+	MinSize              *int64                 `type:"integer"`
+	MaxSize              *int64                 `type:"integer"`
+	DesiredCapacity      *int64                 `type:"integer"`
+	Instances            []*AutoScalingInstance `type:"list"`
+	Status               *string                `type:"string"`
+
+	_ struct{} `type:"structure"`
+}
+
+// AutoScalingInstance is a member instance of auto scaling group.
+type AutoScalingInstance struct {
+	InstanceId       *string `type:"string"`
+	LifecycleState   *string `type:"string"`
+	HealthStatus     *string `type:"string"`
+	AvailabilityZone *string `type:"string"`
+
+	_ struct{} `type:"structure"`
+}
+
+// AutoScalingGroupSpec is a group of instances of the launch template
+// in the subnets, tags of the group are propagated to its instances.
+type AutoScalingGroupSpec struct {
+	Name             string
+	LaunchTemplateID string
+	MinSize          int64
+	MaxSize          int64
+	DesiredCapacity  int64
+	SubnetIDs        []string
+	Tags             map[string]string
+}
+
+// AutoScalingService manages auto scaling groups of node pools.
+type AutoScalingService interface {
+	CreateAutoScalingGroup(ctx context.Context, spec AutoScalingGroupSpec) error
+	DescribeAutoScalingGroups(ctx context.Context, names []string) ([]*AutoScalingGroup, error)
+	DeleteAutoScalingGroup(ctx context.Context, name string) error
+}
+
+// AutoScaling is a client of the Auto Scaling API limited to groups of
+// node pools, the API is served over query protocol.
+type AutoScaling struct {
+	*client.Client
+}
+
+type autoScalingTag struct {
+	Key               *string `type:"string"`
+	Value             *string `type:"string"`
+	PropagateAtLaunch *bool   `type:"boolean"`
+	ResourceId        *string `type:"string"`
+	ResourceType      *string `type:"string"`
+
+	_ struct{} `type:"structure"`
+}
+
+type launchTemplateSpecification struct {
+	LaunchTemplateId *string `type:"string"`
+	Version          *string `type:"string"`
+
+	_ struct{} `type:"structure"`
+}
+
+type createAutoScalingGroupInput struct {
+	AutoScalingGroupName *string                      `type:"string"`
+	LaunchTemplate       *launchTemplateSpecification `type:"structure"`
+	MinSize              *int64                       `type:"integer"`
+	MaxSize              *int64                       `type:"integer"`
+	DesiredCapacity      *int64                       `type:"integer"`
+	VPCZoneIdentifier    *string                      `type:"string"`
+	Tags                 []*autoScalingTag            `type:"list"`
+
+	_ struct{} `type:"structure"`
+}
+
+type describeAutoScalingGroupsInput struct {
+	AutoScalingGroupNames []*string `type:"list"`
+	NextToken             *string   `type:"string"`
+
+	_ struct{} `type:"structure"`
+}
+
+type describeAutoScalingGroupsOutput struct {
+	AutoScalingGroups []*AutoScalingGroup `type:"list"`
+	NextToken         *string             `type:"string"`
+
+	_ struct{} `type:"structure"`
+}
+
+type deleteAutoScalingGroupInput struct {
+	AutoScalingGroupName *string `type:"string"`
+	ForceDelete          *bool   `type:"boolean"`
+
+	_ struct{} `type:"structure"`
+}
+
+type autoScalingOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+// NewAutoScaling creates Auto Scaling client for region of the session.
+func NewAutoScaling(p client.ConfigProvider) *AutoScaling {
+	c := p.ClientConfig("autoscaling")
+	if c.SigningNameDerived || len(c.SigningName) == 0 {
+		c.SigningName = "autoscaling"
+	}
+
+	svc := &AutoScaling{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:   "autoscaling",
+				ServiceID:     "Auto Scaling",
+				SigningName:   c.SigningName,
+				SigningRegion: c.SigningRegion,
+				Endpoint:      c.Endpoint,
+				APIVersion:    "2011-01-01",
+			},
+			c.Handlers,
+		),
+	}
+
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(query.BuildHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(query.UnmarshalHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(query.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(query.UnmarshalErrorHandler)
+
+	return svc
+}
+
+// CreateAutoScalingGroup creates the group, group that already exists
+// is not changed.
+func (c *AutoScaling) CreateAutoScalingGroup(ctx context.Context, spec AutoScalingGroupSpec) error {
+	keys := make([]string, 0, len(spec.Tags))
+
+	for key := range spec.Tags {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	tags := make([]*autoScalingTag, 0, len(keys))
+
+	for _, key := range keys {
+		tags = append(tags, &autoScalingTag{
+			Key:               aws.String(key),
+			Value:             aws.String(spec.Tags[key]),
+			PropagateAtLaunch: aws.Bool(true),
+			ResourceId:        aws.String(spec.Name),
+			ResourceType:      aws.String("auto-scaling-group"),
+		})
+	}
+
+	err := c.send(ctx, "CreateAutoScalingGroup", &createAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String(spec.Name),
+		LaunchTemplate: &launchTemplateSpecification{
+			LaunchTemplateId: aws.String(spec.LaunchTemplateID),
+			Version:          aws.String("$Latest"),
+		},
+		MinSize:           aws.Int64(spec.MinSize),
+		MaxSize:           aws.Int64(spec.MaxSize),
+		DesiredCapacity:   aws.Int64(spec.DesiredCapacity),
+		VPCZoneIdentifier: aws.String(strings.Join(spec.SubnetIDs, ",")),
+		Tags:              tags,
+	}, &autoScalingOutput{})
+
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "AlreadyExists" {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "create auto scaling group %s", spec.Name)
+	}
+
+	return nil
+}
+
+// DescribeAutoScalingGroups returns groups with the names, groups that
+// don't exist are not returned.
+func (c *AutoScaling) DescribeAutoScalingGroups(ctx context.Context, names []string) ([]*AutoScalingGroup, error) {
+	input := &describeAutoScalingGroupsInput{
+		AutoScalingGroupNames: aws.StringSlice(names),
+	}
+	groups := make([]*AutoScalingGroup, 0, len(names))
+
+	for {
+		out := &describeAutoScalingGroupsOutput{}
+
+		if err := c.send(ctx, "DescribeAutoScalingGroups", input, out); err != nil {
+			return nil, errors.Wrap(err, "describe auto scaling groups")
+		}
+
+		groups = append(groups, out.AutoScalingGroups...)
+
+		if aws.StringValue(out.NextToken) == "" {
+			return groups, nil
+		}
+
+		input.NextToken = out.NextToken
+	}
+}
+
+// DeleteAutoScalingGroup deletes the group and terminates its instances,
+// group that does not exist is considered deleted.
+func (c *AutoScaling) DeleteAutoScalingGroup(ctx context.Context, name string) error {
+	err := c.send(ctx, "DeleteAutoScalingGroup", &deleteAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String(name),
+		ForceDelete:          aws.Bool(true),
+	}, &autoScalingOutput{})
+
+	// Auto Scaling reports missing groups as validation errors
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "ValidationError" &&
+		strings.Contains(aerr.Message(), "not found") {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "delete auto scaling group %s", name)
+	}
+
+	return nil
+}
+
+func (c *AutoScaling) send(ctx context.Context, operation string, input, output interface{}) error {
+	req := c.NewRequest(&request.Operation{
+		Name:       operation,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}, input, output)
+	req.SetContext(ctx)
+
+	return req.Send()
+}
@@ -0,0 +1,186 @@
+package awssdk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestAutoScalingCreateAutoScalingGroup(t *testing.T) {
+	var form map[string][]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.Form
+
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<CreateAutoScalingGroupResponse>
+  <ResponseMetadata><RequestId>id</RequestId></ResponseMetadata>
+</CreateAutoScalingGroupResponse>`))
+	}))
+	defer server.Close()
+
+	svc := newTestAutoScaling(t, server.URL)
+	err := svc.CreateAutoScalingGroup(context.Background(), AutoScalingGroupSpec{
+		Name:             "test-workers",
+		LaunchTemplateID: "lt-1234",
+		MinSize:          1,
+		MaxSize:          5,
+		DesiredCapacity:  2,
+		SubnetIDs:        []string{"subnet-a", "subnet-b"},
+		Tags: map[string]string{
+			"k8s.io/cluster-autoscaler/enabled": "true",
+			"supergiant.io/node-pool":           "workers",
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := map[string]string{
+		"Action":                          "CreateAutoScalingGroup",
+		"AutoScalingGroupName":            "test-workers",
+		"LaunchTemplate.LaunchTemplateId": "lt-1234",
+		"MinSize":                         "1",
+		"MaxSize":                         "5",
+		"DesiredCapacity":                 "2",
+		"VPCZoneIdentifier":               "subnet-a,subnet-b",
+		"Tags.member.1.Key":               "k8s.io/cluster-autoscaler/enabled",
+		"Tags.member.1.PropagateAtLaunch": "true",
+		"Tags.member.2.Key":               "supergiant.io/node-pool",
+		"Tags.member.2.Value":             "workers",
+	}
+
+	for key, value := range expected {
+		if len(form[key]) != 1 || form[key][0] != value {
+			t.Errorf("Expected %s=%s actual %v", key, value, form[key])
+		}
+	}
+}
+
+func TestAutoScalingDescribeAutoScalingGroups(t *testing.T) {
+	var action, name string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		action = r.Form.Get("Action")
+		name = r.Form.Get("AutoScalingGroupNames.member.1")
+
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<DescribeAutoScalingGroupsResponse>
+  <DescribeAutoScalingGroupsResult>
+    <AutoScalingGroups>
+      <member>
+        <AutoScalingGroupName>test-workers</AutoScalingGroupName>
+        <MinSize>1</MinSize>
+        <MaxSize>5</MaxSize>
+        <DesiredCapacity>2</DesiredCapacity>
+        <Instances>
+          <member>
+            <InstanceId>i-1</InstanceId>
+            <LifecycleState>InService</LifecycleState>
+          </member>
+          <member>
+            <InstanceId>i-2</InstanceId>
+            <LifecycleState>Terminating</LifecycleState>
+          </member>
+        </Instances>
+      </member>
+    </AutoScalingGroups>
+  </DescribeAutoScalingGroupsResult>
+</DescribeAutoScalingGroupsResponse>`))
+	}))
+	defer server.Close()
+
+	svc := newTestAutoScaling(t, server.URL)
+	groups, err := svc.DescribeAutoScalingGroups(context.Background(), []string{"test-workers"})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if action != "DescribeAutoScalingGroups" || name != "test-workers" {
+		t.Errorf("Wrong request %s %s", action, name)
+	}
+
+	if len(groups) != 1 || aws.Int64Value(groups[0].DesiredCapacity) != 2 ||
+		len(groups[0].Instances) != 2 ||
+		aws.StringValue(groups[0].Instances[1].LifecycleState) != LifecycleTerminating {
+		t.Errorf("Wrong groups %v", groups)
+	}
+}
+
+func TestAutoScalingDeleteAutoScalingGroup(t *testing.T) {
+	testCases := []struct {
+		description string
+		code        int
+		body        string
+		expectedErr bool
+	}{
+		{
+			description: "deleted",
+			code:        http.StatusOK,
+			body:        `<DeleteAutoScalingGroupResponse></DeleteAutoScalingGroupResponse>`,
+		},
+		{
+			description: "not found",
+			code:        http.StatusBadRequest,
+			body: `<ErrorResponse><Error><Type>Sender</Type><Code>ValidationError</Code>
+<Message>AutoScalingGroup name not found - AutoScalingGroup test-workers not found</Message></Error></ErrorResponse>`,
+		},
+		{
+			description: "in use",
+			code:        http.StatusBadRequest,
+			body: `<ErrorResponse><Error><Type>Sender</Type><Code>ResourceInUse</Code>
+<Message>in use</Message></Error></ErrorResponse>`,
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		var force string
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			force = r.Form.Get("ForceDelete")
+
+			w.Header().Set("Content-Type", "text/xml")
+			w.WriteHeader(testCase.code)
+			w.Write([]byte(testCase.body))
+		}))
+
+		svc := newTestAutoScaling(t, server.URL)
+		err := svc.DeleteAutoScalingGroup(context.Background(), "test-workers")
+		server.Close()
+
+		if (err != nil) != testCase.expectedErr {
+			t.Errorf("Expected error %v actual %v", testCase.expectedErr, err)
+		}
+
+		if force != "true" {
+			t.Errorf("Group must be force deleted")
+		}
+	}
+}
+
+func newTestAutoScaling(t *testing.T, endpoint string) *AutoScaling {
+	sess, err := NewSession("us-east-1", Credentials{
+		KeyID:  "key",
+		Secret: "secret",
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	return NewAutoScaling(sess.Copy(&aws.Config{
+		Endpoint:   aws.String(endpoint),
+		MaxRetries: aws.Int(0),
+	}))
+}
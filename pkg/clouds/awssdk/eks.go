@@ -0,0 +1,38 @@
+package awssdk
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/pkg/errors"
+)
+
+const (
+	eksTokenPrefix     = "k8s-aws-v1."
+	eksClusterIDHeader = "x-k8s-aws-id"
+
+	// EKS accepts presigned requests for 15 minutes regardless of
+	// their expiration, token is refreshed a minute earlier.
+	EKSTokenTTL        = 14 * time.Minute
+	eksPresignDuration = time.Minute
+)
+
+// EKSToken returns bearer token of EKS cluster, it is a presigned
+// GetCallerIdentity request that EKS uses to authenticate the caller
+// as aws-iam-authenticator does.
+func EKSToken(sess client.ConfigProvider, clusterName string, now time.Time) (string, time.Time, error) {
+	req, _ := sts.New(sess).GetCallerIdentityRequest(&sts.GetCallerIdentityInput{})
+	req.HTTPRequest.Header.Add(eksClusterIDHeader, clusterName)
+
+	presigned, err := req.Presign(eksPresignDuration)
+
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(err, "presign get caller identity")
+	}
+
+	token := eksTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(presigned))
+
+	return token, now.Add(EKSTokenTTL), nil
+}
@@ -0,0 +1,55 @@
+package awssdk
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEKSToken(t *testing.T) {
+	sess, err := NewSession("us-east-1", Credentials{
+		KeyID:  "key",
+		Secret: "secret",
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	now := time.Now()
+	token, expires, err := EKSToken(sess, "cluster", now)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if !expires.Equal(now.Add(EKSTokenTTL)) {
+		t.Errorf("Wrong expiration %v", expires)
+	}
+
+	if !strings.HasPrefix(token, eksTokenPrefix) {
+		t.Fatalf("Wrong token prefix %s", token)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, eksTokenPrefix))
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	presigned, err := url.Parse(string(raw))
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	query := presigned.Query()
+
+	if query.Get("Action") != "GetCallerIdentity" ||
+		!strings.Contains(query.Get("X-Amz-SignedHeaders"), eksClusterIDHeader) ||
+		query.Get("X-Amz-Signature") == "" {
+		t.Errorf("Wrong presigned request %s", presigned)
+	}
+}
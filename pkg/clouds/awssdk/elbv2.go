@@ -0,0 +1,223 @@
+package awssdk
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/query"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// TargetTypeInstance targets are registered by instance ID
+const TargetTypeInstance = "instance"
+
+// NetworkLoadBalancer is a load balancer of the ELBv2 API.
+type NetworkLoadBalancer struct {
+	LoadBalancerArn  *string `type:"string"`
+	LoadBalancerName *string `type:"string"`
+	DNSName          *string `type:"string"`
+	Type             *string `type:"string"`
+	VpcId            *string `type:"string"`
+
+	_ struct{} `type:"structure"`
+}
+
+// TargetGroup routes requests of load balancer listeners to targets.
+type TargetGroup struct {
+	TargetGroupArn   *string   `type:"string"`
+	TargetGroupName  *string   `type:"string"`
+	Port             *int64    `type:"integer"`
+	Protocol         *string   `type:"string"`
+	TargetType       *string   `type:"string"`
+	VpcId            *string   `type:"string"`
+	LoadBalancerArns []*string `type:"list"`
+
+	_ struct{} `type:"structure"`
+}
+
+// TargetGroupService manages instances of existing target groups.
+type TargetGroupService interface {
+	DescribeLoadBalancer(ctx context.Context, nameOrARN string) (*NetworkLoadBalancer, error)
+	DescribeTargetGroup(ctx context.Context, nameOrARN string) (*TargetGroup, error)
+	RegisterInstance(ctx context.Context, targetGroupARN, instanceID string) error
+	DeregisterInstance(ctx context.Context, targetGroupARN, instanceID string) error
+}
+
+// ELBv2 is a client of the ELBv2 API limited to target groups of existing
+// load balancers, the SDK talks to ELBv2 over the same query protocol
+// as to classic ELB.
+type ELBv2 struct {
+	*client.Client
+}
+
+type describeLoadBalancersInput struct {
+	LoadBalancerArns []*string `type:"list"`
+	Names            []*string `type:"list"`
+
+	_ struct{} `type:"structure"`
+}
+
+type describeLoadBalancersOutput struct {
+	LoadBalancers []*NetworkLoadBalancer `type:"list"`
+
+	_ struct{} `type:"structure"`
+}
+
+type describeTargetGroupsInput struct {
+	TargetGroupArns []*string `type:"list"`
+	Names           []*string `type:"list"`
+
+	_ struct{} `type:"structure"`
+}
+
+type describeTargetGroupsOutput struct {
+	TargetGroups []*TargetGroup `type:"list"`
+
+	_ struct{} `type:"structure"`
+}
+
+type targetDescription struct {
+	Id *string `type:"string"`
+
+	_ struct{} `type:"structure"`
+}
+
+type targetsInput struct {
+	TargetGroupArn *string              `type:"string"`
+	Targets        []*targetDescription `type:"list"`
+
+	_ struct{} `type:"structure"`
+}
+
+type targetsOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+// NewELBv2 creates ELBv2 client for region of the session.
+func NewELBv2(p client.ConfigProvider) *ELBv2 {
+	c := p.ClientConfig("elasticloadbalancing")
+	if c.SigningNameDerived || len(c.SigningName) == 0 {
+		c.SigningName = "elasticloadbalancing"
+	}
+
+	svc := &ELBv2{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:   "elasticloadbalancing",
+				ServiceID:     "Elastic Load Balancing v2",
+				SigningName:   c.SigningName,
+				SigningRegion: c.SigningRegion,
+				Endpoint:      c.Endpoint,
+				APIVersion:    "2015-12-01",
+			},
+			c.Handlers,
+		),
+	}
+
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(query.BuildHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(query.UnmarshalHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(query.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(query.UnmarshalErrorHandler)
+
+	return svc
+}
+
+// DescribeLoadBalancer returns load balancer by its name or ARN.
+func (c *ELBv2) DescribeLoadBalancer(ctx context.Context, nameOrARN string) (*NetworkLoadBalancer, error) {
+	input := &describeLoadBalancersInput{}
+
+	if isARN(nameOrARN) {
+		input.LoadBalancerArns = aws.StringSlice([]string{nameOrARN})
+	} else {
+		input.Names = aws.StringSlice([]string{nameOrARN})
+	}
+
+	out := &describeLoadBalancersOutput{}
+
+	if err := c.send(ctx, "DescribeLoadBalancers", input, out); err != nil {
+		return nil, notFound(err, "LoadBalancerNotFound", "load balancer", nameOrARN)
+	}
+
+	if len(out.LoadBalancers) == 0 {
+		return nil, errors.Wrapf(sgerrors.ErrNotFound, "load balancer %s", nameOrARN)
+	}
+
+	return out.LoadBalancers[0], nil
+}
+
+// DescribeTargetGroup returns target group by its name or ARN.
+func (c *ELBv2) DescribeTargetGroup(ctx context.Context, nameOrARN string) (*TargetGroup, error) {
+	input := &describeTargetGroupsInput{}
+
+	if isARN(nameOrARN) {
+		input.TargetGroupArns = aws.StringSlice([]string{nameOrARN})
+	} else {
+		input.Names = aws.StringSlice([]string{nameOrARN})
+	}
+
+	out := &describeTargetGroupsOutput{}
+
+	if err := c.send(ctx, "DescribeTargetGroups", input, out); err != nil {
+		return nil, notFound(err, "TargetGroupNotFound", "target group", nameOrARN)
+	}
+
+	if len(out.TargetGroups) == 0 {
+		return nil, errors.Wrapf(sgerrors.ErrNotFound, "target group %s", nameOrARN)
+	}
+
+	return out.TargetGroups[0], nil
+}
+
+// RegisterInstance adds instance to the target group on port of the group.
+func (c *ELBv2) RegisterInstance(ctx context.Context, targetGroupARN, instanceID string) error {
+	return c.send(ctx, "RegisterTargets", targets(targetGroupARN, instanceID), &targetsOutput{})
+}
+
+// DeregisterInstance removes instance from the target group.
+func (c *ELBv2) DeregisterInstance(ctx context.Context, targetGroupARN, instanceID string) error {
+	return c.send(ctx, "DeregisterTargets", targets(targetGroupARN, instanceID), &targetsOutput{})
+}
+
+func (c *ELBv2) send(ctx context.Context, operation string, input, output interface{}) error {
+	req := c.NewRequest(&request.Operation{
+		Name:       operation,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}, input, output)
+	req.SetContext(ctx)
+
+	return req.Send()
+}
+
+func targets(targetGroupARN, instanceID string) *targetsInput {
+	return &targetsInput{
+		TargetGroupArn: aws.String(targetGroupARN),
+		Targets: []*targetDescription{
+			{
+				Id: aws.String(instanceID),
+			},
+		},
+	}
+}
+
+func isARN(s string) bool {
+	return strings.HasPrefix(s, "arn:")
+}
+
+func notFound(err error, code, kind, nameOrARN string) error {
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == code {
+		return errors.Wrapf(sgerrors.ErrNotFound, "%s %s", kind, nameOrARN)
+	}
+
+	return errors.Wrapf(err, "describe %s %s", kind, nameOrARN)
+}
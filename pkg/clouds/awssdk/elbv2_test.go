@@ -0,0 +1,132 @@
+package awssdk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+func TestELBv2DescribeTargetGroup(t *testing.T) {
+	var action, name string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		action = r.Form.Get("Action")
+		name = r.Form.Get("Names.member.1")
+
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<DescribeTargetGroupsResponse>
+  <DescribeTargetGroupsResult>
+    <TargetGroups>
+      <member>
+        <TargetGroupArn>arn:tg</TargetGroupArn>
+        <TargetGroupName>api</TargetGroupName>
+        <Port>6443</Port>
+        <Protocol>TCP</Protocol>
+        <TargetType>instance</TargetType>
+        <LoadBalancerArns>
+          <member>arn:lb</member>
+        </LoadBalancerArns>
+      </member>
+    </TargetGroups>
+  </DescribeTargetGroupsResult>
+</DescribeTargetGroupsResponse>`))
+	}))
+	defer server.Close()
+
+	svc := newTestELBv2(t, server.URL)
+	targetGroup, err := svc.DescribeTargetGroup(context.Background(), "api")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if action != "DescribeTargetGroups" || name != "api" {
+		t.Errorf("Wrong request %s %s", action, name)
+	}
+
+	if aws.StringValue(targetGroup.TargetGroupArn) != "arn:tg" ||
+		aws.Int64Value(targetGroup.Port) != 6443 ||
+		len(targetGroup.LoadBalancerArns) != 1 ||
+		aws.StringValue(targetGroup.LoadBalancerArns[0]) != "arn:lb" {
+		t.Errorf("Wrong target group %v", targetGroup)
+	}
+}
+
+func TestELBv2DescribeLoadBalancerNotFound(t *testing.T) {
+	var arn string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		arn = r.Form.Get("LoadBalancerArns.member.1")
+
+		w.Header().Set("Content-Type", "text/xml")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`<ErrorResponse>
+  <Error>
+    <Type>Sender</Type>
+    <Code>LoadBalancerNotFound</Code>
+    <Message>One or more load balancers not found</Message>
+  </Error>
+  <RequestId>1234</RequestId>
+</ErrorResponse>`))
+	}))
+	defer server.Close()
+
+	svc := newTestELBv2(t, server.URL)
+	_, err := svc.DescribeLoadBalancer(context.Background(), "arn:aws:elasticloadbalancing:lb")
+
+	if !sgerrors.IsNotFound(err) {
+		t.Errorf("Wrong error %v", err)
+	}
+
+	if arn != "arn:aws:elasticloadbalancing:lb" {
+		t.Errorf("Wrong load balancer arn in request %s", arn)
+	}
+}
+
+func TestELBv2RegisterInstance(t *testing.T) {
+	var action, targetGroupARN, instanceID string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		action = r.Form.Get("Action")
+		targetGroupARN = r.Form.Get("TargetGroupArn")
+		instanceID = r.Form.Get("Targets.member.1.Id")
+
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<RegisterTargetsResponse><RegisterTargetsResult/></RegisterTargetsResponse>`))
+	}))
+	defer server.Close()
+
+	svc := newTestELBv2(t, server.URL)
+
+	if err := svc.RegisterInstance(context.Background(), "arn:tg", "i-1234"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if action != "RegisterTargets" || targetGroupARN != "arn:tg" || instanceID != "i-1234" {
+		t.Errorf("Wrong request %s %s %s", action, targetGroupARN, instanceID)
+	}
+}
+
+func newTestELBv2(t *testing.T, endpoint string) *ELBv2 {
+	sess, err := NewSession("us-east-1", Credentials{
+		KeyID:  "key",
+		Secret: "secret",
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	return NewELBv2(sess.Copy(&aws.Config{
+		Endpoint:   aws.String(endpoint),
+		MaxRetries: aws.Int(0),
+	}))
+}
@@ -0,0 +1,122 @@
+package awssdk
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/jsonrpc"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+const kmsKeyStateEnabled = "Enabled"
+
+// KMSKey is metadata of the KMS key.
+type KMSKey struct {
+	Arn      *string `locationName:"Arn" type:"string"`
+	KeyID    *string `locationName:"KeyId" type:"string"`
+	KeyState *string `locationName:"KeyState" type:"string"`
+	Enabled  *bool   `locationName:"Enabled" type:"boolean"`
+
+	_ struct{} `type:"structure"`
+}
+
+// KeyDescriber gets metadata of KMS keys.
+type KeyDescriber interface {
+	DescribeKey(ctx context.Context, keyID string) (*KMSKey, error)
+}
+
+// KMS is a client of the KMS API limited to describing keys, the SDK
+// talks to KMS over the same json protocol as to the pricing API.
+type KMS struct {
+	*client.Client
+}
+
+type describeKeyInput struct {
+	KeyID *string `locationName:"KeyId" type:"string"`
+
+	_ struct{} `type:"structure"`
+}
+
+type describeKeyOutput struct {
+	KeyMetadata *KMSKey `type:"structure"`
+
+	_ struct{} `type:"structure"`
+}
+
+// NewKMS creates KMS client for region of the session.
+func NewKMS(p client.ConfigProvider) *KMS {
+	c := p.ClientConfig("kms")
+	if c.SigningNameDerived || len(c.SigningName) == 0 {
+		c.SigningName = "kms"
+	}
+
+	svc := &KMS{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:   "kms",
+				ServiceID:     "KMS",
+				SigningName:   c.SigningName,
+				SigningRegion: c.SigningRegion,
+				Endpoint:      c.Endpoint,
+				APIVersion:    "2014-11-01",
+				JSONVersion:   "1.1",
+				TargetPrefix:  "TrentService",
+			},
+			c.Handlers,
+		),
+	}
+
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(jsonrpc.BuildHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(jsonrpc.UnmarshalHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(jsonrpc.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(jsonrpc.UnmarshalErrorHandler)
+
+	return svc
+}
+
+// DescribeKey returns metadata of the key, keyID may be key id,
+// key ARN, alias name or alias ARN.
+func (c *KMS) DescribeKey(ctx context.Context, keyID string) (*KMSKey, error) {
+	out := &describeKeyOutput{}
+	req := c.NewRequest(&request.Operation{
+		Name:       "DescribeKey",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}, &describeKeyInput{KeyID: aws.String(keyID)}, out)
+	req.SetContext(ctx)
+
+	if err := req.Send(); err != nil {
+		return nil, err
+	}
+
+	if out.KeyMetadata == nil {
+		return nil, errors.Wrapf(sgerrors.ErrNotFound, "kms key %s", keyID)
+	}
+
+	return out.KeyMetadata, nil
+}
+
+// ValidateKMSKey checks that the key is visible to account credentials
+// and can be used to encrypt volumes.
+func ValidateKMSKey(ctx context.Context, svc KeyDescriber, keyID string) error {
+	key, err := svc.DescribeKey(ctx, keyID)
+
+	if err != nil {
+		return errors.Wrapf(err, "describe kms key %s", keyID)
+	}
+
+	if !aws.BoolValue(key.Enabled) || aws.StringValue(key.KeyState) != kmsKeyStateEnabled {
+		return errors.Wrapf(sgerrors.ErrValidationFailed, "kms key %s is in state %s",
+			keyID, aws.StringValue(key.KeyState))
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package awssdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+type fakeKeyDescriber struct {
+	key *KMSKey
+	err error
+}
+
+func (f *fakeKeyDescriber) DescribeKey(context.Context, string) (*KMSKey, error) {
+	return f.key, f.err
+}
+
+func TestKMSDescribeKey(t *testing.T) {
+	var target, keyID string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target = r.Header.Get("X-Amz-Target")
+
+		input := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&input)
+		keyID = input["KeyId"]
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.Write([]byte(`{"KeyMetadata":{"KeyId":"1234","Arn":"arn:aws:kms:us-east-1:1:key/1234",` +
+			`"KeyState":"Enabled","Enabled":true}}`))
+	}))
+	defer server.Close()
+
+	sess, err := NewSession("us-east-1", Credentials{
+		KeyID:  "key",
+		Secret: "secret",
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	svc := NewKMS(sess.Copy(&aws.Config{
+		Endpoint: aws.String(server.URL),
+	}))
+
+	key, err := svc.DescribeKey(context.Background(), "alias/ebs")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if target != "TrentService.DescribeKey" {
+		t.Errorf("Wrong target %s", target)
+	}
+
+	if keyID != "alias/ebs" {
+		t.Errorf("Wrong key id in request %s", keyID)
+	}
+
+	if aws.StringValue(key.KeyID) != "1234" || !aws.BoolValue(key.Enabled) ||
+		aws.StringValue(key.KeyState) != kmsKeyStateEnabled {
+		t.Errorf("Wrong key %v", key)
+	}
+}
+
+func TestValidateKMSKey(t *testing.T) {
+	testCases := []struct {
+		description  string
+		svc          *fakeKeyDescriber
+		isErr        bool
+		isValidation bool
+	}{
+		{
+			description: "enabled",
+			svc: &fakeKeyDescriber{
+				key: &KMSKey{
+					KeyState: aws.String("Enabled"),
+					Enabled:  aws.Bool(true),
+				},
+			},
+		},
+		{
+			description: "pending deletion",
+			svc: &fakeKeyDescriber{
+				key: &KMSKey{
+					KeyState: aws.String("PendingDeletion"),
+					Enabled:  aws.Bool(false),
+				},
+			},
+			isErr:        true,
+			isValidation: true,
+		},
+		{
+			description: "disabled",
+			svc: &fakeKeyDescriber{
+				key: &KMSKey{
+					KeyState: aws.String("Disabled"),
+					Enabled:  aws.Bool(false),
+				},
+			},
+			isErr:        true,
+			isValidation: true,
+		},
+		{
+			description: "access denied",
+			svc: &fakeKeyDescriber{
+				err: errors.New("AccessDeniedException"),
+			},
+			isErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		err := ValidateKMSKey(context.Background(), testCase.svc, "alias/ebs")
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("Wrong error %v", err)
+		}
+
+		if testCase.isValidation != sgerrors.IsValidationFailed(err) {
+			t.Errorf("Wrong error type %v", err)
+		}
+	}
+}
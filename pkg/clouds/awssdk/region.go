@@ -0,0 +1,84 @@
+package awssdk
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// DefaultRegion is used to describe regions, so a mistyped region
+// of the profile never becomes the API endpoint.
+const DefaultRegion = "us-east-1"
+
+// RegionDescriber lists regions and availability zones
+// enabled for the account.
+type RegionDescriber interface {
+	DescribeRegionsWithContext(aws.Context, *ec2.DescribeRegionsInput,
+		...request.Option) (*ec2.DescribeRegionsOutput, error)
+	DescribeAvailabilityZonesWithContext(aws.Context, *ec2.DescribeAvailabilityZonesInput,
+		...request.Option) (*ec2.DescribeAvailabilityZonesOutput, error)
+}
+
+// ValidateRegion returns sgerrors.ErrNotFound with the list of valid
+// regions when region is not available for the account.
+func ValidateRegion(ctx context.Context, svc RegionDescriber, region string) error {
+	out, err := svc.DescribeRegionsWithContext(ctx, &ec2.DescribeRegionsInput{})
+
+	if err != nil {
+		return errors.Wrap(err, "describe regions")
+	}
+
+	regions := make([]string, 0, len(out.Regions))
+
+	for _, r := range out.Regions {
+		regions = append(regions, aws.StringValue(r.RegionName))
+	}
+
+	return contains("region", region, regions)
+}
+
+// ValidateZone returns sgerrors.ErrNotFound with the list of valid
+// zones when zone is not available in region, svc must be bound
+// to the region.
+func ValidateZone(ctx context.Context, svc RegionDescriber, region, zone string) error {
+	out, err := svc.DescribeAvailabilityZonesWithContext(ctx, &ec2.DescribeAvailabilityZonesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("region-name"),
+				Values: aws.StringSlice([]string{region}),
+			},
+		},
+	})
+
+	if err != nil {
+		return errors.Wrapf(err, "describe availability zones of %s", region)
+	}
+
+	zones := make([]string, 0, len(out.AvailabilityZones))
+
+	for _, az := range out.AvailabilityZones {
+		zones = append(zones, aws.StringValue(az.ZoneName))
+	}
+
+	return contains("availability zone", zone, zones)
+}
+
+func contains(entity, value string, valid []string) error {
+	for _, v := range valid {
+		if v == value {
+			return nil
+		}
+	}
+
+	sort.Strings(valid)
+
+	return errors.Wrapf(sgerrors.ErrNotFound, "%s %q, valid values: %s",
+		entity, value, strings.Join(valid, ", "))
+}
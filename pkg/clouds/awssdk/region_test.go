@@ -0,0 +1,103 @@
+package awssdk
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+type fakeRegionDescriber struct {
+	regions []string
+	zones   []string
+	err     error
+}
+
+func (f *fakeRegionDescriber) DescribeRegionsWithContext(aws.Context, *ec2.DescribeRegionsInput,
+	...request.Option) (*ec2.DescribeRegionsOutput, error) {
+	out := &ec2.DescribeRegionsOutput{}
+
+	for _, region := range f.regions {
+		out.Regions = append(out.Regions, &ec2.Region{RegionName: aws.String(region)})
+	}
+
+	return out, f.err
+}
+
+func (f *fakeRegionDescriber) DescribeAvailabilityZonesWithContext(aws.Context, *ec2.DescribeAvailabilityZonesInput,
+	...request.Option) (*ec2.DescribeAvailabilityZonesOutput, error) {
+	out := &ec2.DescribeAvailabilityZonesOutput{}
+
+	for _, zone := range f.zones {
+		out.AvailabilityZones = append(out.AvailabilityZones,
+			&ec2.AvailabilityZone{ZoneName: aws.String(zone)})
+	}
+
+	return out, f.err
+}
+
+func TestValidateRegion(t *testing.T) {
+	testCases := []struct {
+		description string
+		svc         *fakeRegionDescriber
+		region      string
+		isNotFound  bool
+		errMsg      string
+	}{
+		{
+			description: "valid",
+			svc:         &fakeRegionDescriber{regions: []string{"us-east-1", "eu-west-1"}},
+			region:      "eu-west-1",
+		},
+		{
+			description: "typo",
+			svc:         &fakeRegionDescriber{regions: []string{"us-east-1", "eu-west-1"}},
+			region:      "eu-wets-1",
+			isNotFound:  true,
+			errMsg:      "eu-west-1, us-east-1",
+		},
+		{
+			description: "describe error",
+			svc:         &fakeRegionDescriber{err: errors.New("message1")},
+			region:      "eu-west-1",
+			errMsg:      "message1",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		err := ValidateRegion(context.Background(), testCase.svc, testCase.region)
+
+		if testCase.errMsg == "" && err != nil {
+			t.Errorf("Unexpected error %v", err)
+		}
+
+		if testCase.errMsg != "" && (err == nil || !strings.Contains(err.Error(), testCase.errMsg)) {
+			t.Errorf("Error %v does not contain %s", err, testCase.errMsg)
+		}
+
+		if testCase.isNotFound != sgerrors.IsNotFound(err) {
+			t.Errorf("Wrong not found error %v", err)
+		}
+	}
+}
+
+func TestValidateZone(t *testing.T) {
+	svc := &fakeRegionDescriber{zones: []string{"eu-west-1b", "eu-west-1a"}}
+
+	if err := ValidateZone(context.Background(), svc, "eu-west-1", "eu-west-1a"); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	err := ValidateZone(context.Background(), svc, "eu-west-1", "eu-west-1z")
+
+	if !sgerrors.IsNotFound(err) || !strings.Contains(err.Error(), "eu-west-1a, eu-west-1b") {
+		t.Errorf("Wrong error %v", err)
+	}
+}
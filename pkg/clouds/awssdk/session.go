@@ -0,0 +1,56 @@
+package awssdk
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// Credentials of AWS account, static keys are used to assume
+// the role when RoleARN is set.
+type Credentials struct {
+	KeyID        string
+	Secret       string
+	SessionToken string
+
+	// UseDefaultChain resolves credentials from environment, shared
+	// config or instance profile instead of static keys.
+	UseDefaultChain bool
+
+	RoleARN    string
+	ExternalID string
+}
+
+// NewSession creates session for region, credentials of assumed role
+// are refreshed by STS before they expire.
+func NewSession(region string, creds Credentials) (*session.Session, error) {
+	opts := session.Options{
+		Config: aws.Config{
+			Region: aws.String(region),
+			Credentials: credentials.NewStaticCredentials(creds.KeyID,
+				creds.Secret, creds.SessionToken),
+		},
+	}
+
+	if creds.UseDefaultChain {
+		opts.Config.Credentials = nil
+		opts.SharedConfigState = session.SharedConfigEnable
+	}
+
+	sess, err := session.NewSessionWithOptions(opts)
+
+	if err != nil || creds.RoleARN == "" {
+		return sess, err
+	}
+
+	roleCreds := stscreds.NewCredentials(sess, creds.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+		if creds.ExternalID != "" {
+			p.ExternalID = aws.String(creds.ExternalID)
+		}
+	})
+
+	return sess.Copy(&aws.Config{
+		Credentials: roleCreds,
+	}), nil
+}
@@ -0,0 +1,86 @@
+package awssdk
+
+import (
+	"os"
+	"testing"
+)
+
+func TestNewSession(t *testing.T) {
+	sess, err := NewSession("us-west-1", Credentials{
+		KeyID:        "key",
+		Secret:       "secret",
+		SessionToken: "token",
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	value, err := sess.Config.Credentials.Get()
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if value.AccessKeyID != "key" || value.SecretAccessKey != "secret" ||
+		value.SessionToken != "token" {
+		t.Errorf("Wrong static credentials %v", value)
+	}
+}
+
+func TestNewSessionAssumeRole(t *testing.T) {
+	static, err := NewSession("us-west-1", Credentials{
+		KeyID:  "key",
+		Secret: "secret",
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	sess, err := NewSession("us-west-1", Credentials{
+		KeyID:      "key",
+		Secret:     "secret",
+		RoleARN:    "arn:aws:iam::123456789012:role/control",
+		ExternalID: "external",
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if sess.Config.Credentials == nil || sess.Config.Credentials == static.Config.Credentials {
+		t.Errorf("Role credentials must be used")
+	}
+
+	if *sess.Config.Region != "us-west-1" {
+		t.Errorf("Wrong region %s", *sess.Config.Region)
+	}
+}
+
+func TestNewSessionDefaultChain(t *testing.T) {
+	os.Setenv("AWS_ACCESS_KEY_ID", "env-key")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+	defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+	defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+	sess, err := NewSession("us-west-1", Credentials{
+		KeyID:           "key",
+		Secret:          "secret",
+		UseDefaultChain: true,
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	value, err := sess.Config.Credentials.Get()
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if value.AccessKeyID != "env-key" {
+		t.Errorf("Wrong access key expected env-key actual %s", value.AccessKeyID)
+	}
+}
@@ -84,9 +84,23 @@ const (
 	TagClusterID         = "supergiant.io/cluster-id"
 	TagNodeName          = "Name"
 	TagKubernetesCluster = "KubernetesCluster"
+	TagRole              = "Role"
+	TagNodePool          = "supergiant.io/node-pool"
+
+	// Cluster autoscaler discovers auto scaling groups by these tags,
+	// cluster tag is the prefix followed by the kube id.
+	TagAutoscalerEnabled = "k8s.io/cluster-autoscaler/enabled"
+	TagAutoscalerPrefix  = "k8s.io/cluster-autoscaler/"
+
+	// GCE label and Azure tag keys must not contain slashes
+	LabelClusterID = "supergiant-cluster-id"
 
 	AWSAccessKeyID              = "access_key"
 	AWSSecretKey                = "secret_key"
+	AWSSessionToken             = "session_token"
+	AWSRoleARN                  = "role_arn"
+	AWSExternalID               = "external_id"
+	AWSUseDefaultChain          = "use_default_credential_chain"
 	AwsAZ                       = "aws_az"
 	AwsVpcCIDR                  = "aws_vpc_cidr"
 	AwsVpcID                    = "aws_vpc_id"
@@ -103,7 +117,15 @@ const (
 	AwsImageID                  = "aws_image_id"
 	AwsExternalLoadBalancerName = "AwsExternalLoadBalancerName"
 	AwsInternalLoadBalancerName = "AwsInternalLoadBalancerName"
+	AwsTargetGroupARN           = "AwsTargetGroupARN"
 	AwsVolumeSize               = "AwsVolumeSize"
+	AwsVolumeType               = "AwsVolumeType"
+	AwsVolumeIops               = "AwsVolumeIops"
+	AwsDeleteOnTermination      = "AwsDeleteOnTermination"
+	AwsEbsEncrypted             = "AwsEbsEncrypted"
+	AwsKmsKeyID                 = "AwsKmsKeyID"
+	AwsHttpTokens               = "AwsHttpTokens"
+	AwsHttpPutResponseHopLimit  = "AwsHttpPutResponseHopLimit"
 
 	// Use client credentials auth model for azure.
 	// https://github.com/Azure/azure-sdk-for-go#more-authentication-details
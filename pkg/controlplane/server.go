@@ -16,12 +16,14 @@ import (
 	"github.com/pkg/errors"
 	"github.com/rakyll/statik/fs"
 	"github.com/sirupsen/logrus"
-	"k8s.io/helm/pkg/repo"
 
 	"github.com/supergiant/control/pkg/account"
 	"github.com/supergiant/control/pkg/api"
 	"github.com/supergiant/control/pkg/jwt"
 	"github.com/supergiant/control/pkg/kube"
+	"github.com/supergiant/control/pkg/kubeconfig"
+	"github.com/supergiant/control/pkg/metrics"
+	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/profile"
 	"github.com/supergiant/control/pkg/provisioner"
 	"github.com/supergiant/control/pkg/proxy"
@@ -31,6 +33,7 @@ import (
 	"github.com/supergiant/control/pkg/storage"
 	"github.com/supergiant/control/pkg/templatemanager"
 	"github.com/supergiant/control/pkg/user"
+	"github.com/supergiant/control/pkg/watch"
 	"github.com/supergiant/control/pkg/workflows"
 	"github.com/supergiant/control/pkg/workflows/steps/amazon"
 	"github.com/supergiant/control/pkg/workflows/steps/apply"
@@ -39,22 +42,26 @@ import (
 	"github.com/supergiant/control/pkg/workflows/steps/bootstraptoken"
 	"github.com/supergiant/control/pkg/workflows/steps/certificates"
 	"github.com/supergiant/control/pkg/workflows/steps/cloudcontroller"
+	"github.com/supergiant/control/pkg/workflows/steps/clusterautoscaler"
 	"github.com/supergiant/control/pkg/workflows/steps/clustercheck"
 	"github.com/supergiant/control/pkg/workflows/steps/cni"
 	"github.com/supergiant/control/pkg/workflows/steps/configmap"
-	"github.com/supergiant/control/pkg/workflows/steps/dashboard"
 	"github.com/supergiant/control/pkg/workflows/steps/digitalocean"
 	"github.com/supergiant/control/pkg/workflows/steps/docker"
 	"github.com/supergiant/control/pkg/workflows/steps/downloadk8sbinary"
 	"github.com/supergiant/control/pkg/workflows/steps/drain"
+	"github.com/supergiant/control/pkg/workflows/steps/etcd"
 	"github.com/supergiant/control/pkg/workflows/steps/evacuate"
 	"github.com/supergiant/control/pkg/workflows/steps/gce"
+	"github.com/supergiant/control/pkg/workflows/steps/gpu"
+	"github.com/supergiant/control/pkg/workflows/steps/hooks"
 	"github.com/supergiant/control/pkg/workflows/steps/install_app"
 	"github.com/supergiant/control/pkg/workflows/steps/kubeadm"
 	"github.com/supergiant/control/pkg/workflows/steps/kubelet"
+	"github.com/supergiant/control/pkg/workflows/steps/kubeletconfig"
 	"github.com/supergiant/control/pkg/workflows/steps/network"
 	"github.com/supergiant/control/pkg/workflows/steps/poststart"
-	"github.com/supergiant/control/pkg/workflows/steps/prometheus"
+	"github.com/supergiant/control/pkg/workflows/steps/replacemaster"
 	"github.com/supergiant/control/pkg/workflows/steps/ssh"
 	"github.com/supergiant/control/pkg/workflows/steps/storageclass"
 	"github.com/supergiant/control/pkg/workflows/steps/tiller"
@@ -106,6 +113,43 @@ type Config struct {
 	LogDir       string
 
 	SpawnInterval time.Duration
+	// Count of node workflows of a cluster run at once
+	NodeParallelism int
+	// Fraction of nodes that may fail without failing the cluster
+	MaxNodeFailureRatio float64
+	// Age of finished tasks after which their history is deleted
+	TaskRetention time.Duration
+	// How long detached kubes may be restored before they are purged
+	DetachRetention time.Duration
+	// Default interval of polling for spot interruption notices
+	SpotInterruptionInterval time.Duration
+	// Interval of checks of required security group rules
+	SecurityGroupCheckInterval time.Duration
+	// Timeout of requests to API server of kubes being imported
+	ImportDiscoveryTimeout time.Duration
+	// How long discovered versions of kubes are cached
+	DiscoveryCacheTTL time.Duration
+	// How long metrics of kubes are cached
+	MetricsCacheTTL time.Duration
+	// Alerts of kubes are posted to the webhook, they are logged when it is empty
+	AlertWebhookURL string
+	// Interval of evaluation of kube alert rules
+	AlertEvalInterval time.Duration
+	// Serve operational metrics of control on /metrics
+	MetricsEnabled bool
+	// Require a token to get operational metrics
+	MetricsAuth bool
+	// Secret credentials of helm repositories are encrypted with
+	SecretKey string
+	// Age of index files of helm repositories after which they are refreshed
+	HelmRepoRefreshTTL time.Duration
+	// Limits of bodies of requests and responses proxied to services of kubes
+	ProxyMaxRequestSize  int64
+	ProxyMaxResponseSize int64
+	// Names of credential plugins of imported kubes control may run
+	ExecPlugins []string
+	// Dir files referenced by imported kubeconfigs may be read from
+	KubeConfigDir string
 
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
@@ -118,6 +162,17 @@ type Config struct {
 	Version string
 }
 
+// StoragePrefixes are prefixes services of control keep their data under,
+// storages are migrated by them.
+var StoragePrefixes = append([]string{
+	account.DefaultStoragePrefix,
+	user.DefaultStoragePrefix,
+	profile.DefaultKubeProfilePreifx,
+	kube.DefaultStoragePrefix,
+	workflows.Prefix,
+	workflows.IndexPrefix,
+}, sghelm.StoragePrefixes...)
+
 func New(cfg *Config) (*Server, error) {
 	if err := validate(cfg); err != nil {
 		return nil, err
@@ -183,6 +238,7 @@ func validate(cfg *Config) error {
 func configureApplication(cfg *Config) (*mux.Router, error) {
 	//TODO will work for now, but we should revisit ETCD configuration later
 	router := mux.NewRouter()
+	router.Use(metrics.Middleware)
 
 	protectedAPI := router.PathPrefix("/v1/api").Subrouter()
 	repository, err := storage.GetStorage(cfg.StorageMode, cfg.StorageURI)
@@ -192,8 +248,17 @@ func configureApplication(cfg *Config) (*mux.Router, error) {
 			cfg.StorageMode, cfg.StorageURI)
 	}
 
+	// Writes of kubes and tasks are published to watch clients
+	changes := watch.NewBus(watch.DefaultHistorySize)
+	repository = watch.NewStorage(repository, changes,
+		kube.DefaultStoragePrefix, workflows.Prefix)
+
 	accountService := account.NewService(account.DefaultStoragePrefix, repository)
 	accountHandler := account.NewHandler(accountService)
+	kubeconfig.SetAccountGetter(accountService)
+	if cfg.ExecPlugins != nil {
+		kubeconfig.SetAllowedPlugins(cfg.ExecPlugins)
+	}
 	accountHandler.Register(protectedAPI)
 
 	//TODO Add generation of jwt token
@@ -223,17 +288,21 @@ func configureApplication(cfg *Config) (*mux.Router, error) {
 	docker.Init()
 	downloadk8sbinary.Init()
 	kubelet.Init()
+	kubeletconfig.Init()
 	poststart.Init()
+	hooks.Init()
 	tiller.Init()
 	ssh.Init()
 	network.Init()
 	clustercheck.Init()
 	cloudcontroller.Init()
-	prometheus.Init()
-	dashboard.Init()
 	gce.Init(accountService)
 	storageclass.Init()
 	drain.Init()
+	gpu.Init()
+	clusterautoscaler.Init()
+	replacemaster.Init()
+	etcd.Init()
 	kubeadm.Init()
 	bootstraptoken.Init()
 	configmap.Init()
@@ -243,15 +312,28 @@ func configureApplication(cfg *Config) (*mux.Router, error) {
 	install_app.Init()
 	helm.Init()
 
+	amazon.InitValidateRegion(amazon.GetEC2)
+	amazon.InitValidateKMSKey(amazon.GetKMS)
+	amazon.InitValidateLoadBalancer(amazon.GetELBv2)
 	amazon.InitFindAMI(amazon.GetEC2)
 	amazon.InitImportKeyPair(amazon.GetEC2)
 	amazon.InitCreateInstanceProfiles(amazon.GetIAM)
 	amazon.InitCreateMachine(amazon.GetEC2)
+	amazon.InitCreateBastion(amazon.GetEC2)
 	amazon.InitCreateSecurityGroups(amazon.GetEC2)
 	amazon.InitCreateVPC(amazon.GetEC2)
 	amazon.InitCreateSubnet(amazon.GetEC2, accountService)
 	amazon.InitDeleteClusterMachines(amazon.GetEC2)
-	amazon.InitDeleteNode(amazon.GetEC2)
+	amazon.InitCancelSpotRequests(amazon.GetEC2)
+	amazon.InitRequestSpotInstances(amazon.GetEC2)
+	amazon.InitWaitSpotRequests(amazon.GetEC2)
+	amazon.InitCreateSpotFleet(amazon.GetEC2)
+	amazon.InitCreateAutoScalingGroup(amazon.GetEC2, amazon.GetAutoScaling)
+	amazon.InitDeleteAutoScalingGroups(amazon.GetEC2, amazon.GetAutoScaling)
+	amazon.InitTagSpotInstances(amazon.GetEC2)
+	amazon.InitSetSpotMetadataOptions(amazon.GetEC2)
+	amazon.InitRegisterSpotMachines(amazon.GetEC2)
+	amazon.InitDeleteNode(amazon.GetEC2, amazon.GetELBv2)
 	amazon.InitDeleteSecurityGroup(amazon.GetEC2)
 	amazon.InitDeleteVPC(amazon.GetEC2)
 	amazon.InitDeleteSubnets(amazon.GetEC2)
@@ -265,7 +347,7 @@ func configureApplication(cfg *Config) (*mux.Router, error) {
 	amazon.InitDeleteKeyPair(amazon.GetEC2)
 	amazon.InitCreateLoadBalancer(amazon.GetELB)
 	amazon.InitDeleteLoadBalancer(amazon.GetELB)
-	amazon.InitRegisterInstance(amazon.GetELB)
+	amazon.InitRegisterInstance(amazon.GetELB, amazon.GetELBv2)
 	amazon.InitImportClusterStep(amazon.GetEC2)
 	amazon.InitImportSubnetDescriber(amazon.GetEC2)
 	amazon.InitImportInternetGatewayStep(amazon.GetEC2)
@@ -276,10 +358,7 @@ func configureApplication(cfg *Config) (*mux.Router, error) {
 
 	workflows.Init()
 
-	taskHandler := workflows.NewTaskHandler(repository, sshRunner.NewRunner, accountService, cfg.LogDir)
-	taskHandler.Register(protectedAPI)
-
-	helmService, err := sghelm.NewService(repository)
+	helmService, err := sghelm.NewService(repository, cfg.SecretKey)
 	if err != nil {
 		return nil, errors.Wrap(err, "new helm service")
 	}
@@ -291,13 +370,20 @@ func configureApplication(cfg *Config) (*mux.Router, error) {
 
 	helmHandler := sghelm.NewHandler(helmService)
 	helmHandler.Register(protectedAPI)
+	go sghelm.NewIndexRefresher(helmService, cfg.HelmRepoRefreshTTL,
+		sghelm.DefaultRefreshInterval).Run(context.Background())
 
 	kubeService := kube.NewService(kube.DefaultStoragePrefix,
 		repository, helmService)
+	if cfg.DiscoveryCacheTTL > 0 {
+		kubeService.SetDiscoveryTTL(cfg.DiscoveryCacheTTL)
+	}
 
 	taskProvisioner := provisioner.NewProvisioner(repository,
 		kubeService,
 		cfg.SpawnInterval, cfg.LogDir)
+	taskProvisioner.SetNodeParallelism(cfg.NodeParallelism)
+	taskProvisioner.SetMaxNodeFailureRatio(cfg.MaxNodeFailureRatio)
 	provisionHandler := provisioner.NewHandler(kubeService, accountService,
 		profileService, taskProvisioner)
 	provisionHandler.Register(protectedAPI)
@@ -307,13 +393,78 @@ func configureApplication(cfg *Config) (*mux.Router, error) {
 	kubeHandler := kube.NewHandler(kubeService, accountService,
 		profileService, taskProvisioner, taskProvisioner, helmService,
 		repository, apiProxy, cfg.LogDir)
+	taskProvisioner.SetAddonInstaller(kubeHandler)
+	kubeHandler.SetReleaseAnnotator(helmService)
+	kubeHandler.SetServiceProxy(proxy.NewKubeServiceProxy(cfg.ProxyMaxRequestSize,
+		cfg.ProxyMaxResponseSize, logrus.New().WithField("component", "service-proxy")))
+	if cfg.ImportDiscoveryTimeout > 0 {
+		kubeHandler.SetDiscoveryTimeout(cfg.ImportDiscoveryTimeout)
+	}
+	if cfg.MetricsCacheTTL > 0 {
+		kubeHandler.SetMetricsCacheTTL(cfg.MetricsCacheTTL)
+	}
+	if cfg.DetachRetention > 0 {
+		kubeHandler.SetDetachRetention(cfg.DetachRetention)
+	}
+	kubeHandler.SetKubeConfigDir(cfg.KubeConfigDir)
 	kubeHandler.Register(protectedAPI)
 
+	// Restarted tasks are resumed through kube handler to save their updates
+	taskHandler := workflows.NewTaskHandler(repository, sshRunner.NewRunner,
+		accountService, kubeHandler, cfg.LogDir)
+	taskHandler.Register(protectedAPI)
+	go workflows.NewTaskPruner(repository, cfg.LogDir, cfg.TaskRetention,
+		workflows.DefaultPruneInterval).Run(context.Background())
+
+	watchHandler := watch.NewHandler(changes,
+		watch.Resource{
+			Name:          "kubes",
+			Prefix:        kube.DefaultStoragePrefix,
+			KeyFields:     []string{"id"},
+			OmittedFields: []string{"auth", "masters", "nodes", "etcd"},
+		},
+		watch.Resource{
+			Name:        "tasks",
+			Prefix:      workflows.Prefix,
+			KubeIDField: "kubeId",
+			KeyFields:   []string{"id"},
+		})
+	watchHandler.Register(protectedAPI)
+
+	go kube.NewInterruptionWatcher(kubeService, accountService,
+		cfg.SpotInterruptionInterval).Run(context.Background())
+	go kube.NewSpotReconciler(kubeService, kubeHandler.RequestSpotCapacity,
+		kube.DefaultSpotReconcileInterval).Run(context.Background())
+	go kube.NewSecurityGroupWatcher(kubeService, accountService,
+		cfg.SecurityGroupCheckInterval).Run(context.Background())
+	go kube.NewScheduler(kubeService, kubeHandler.ExecuteSchedule, kubeHandler.TaskRunning,
+		kube.DefaultScheduleInterval).Run(context.Background())
+	go kubeHandler.UpgradeComponents(context.Background())
+	go kube.NewDetachedKubeCleaner(kubeService, kubeHandler.PurgeKube,
+		kube.DefaultDetachedPurgeInterval).Run(context.Background())
+
+	var alertSink kube.AlertSink
+	if cfg.AlertWebhookURL != "" {
+		alertSink = kube.NewWebhookSink(cfg.AlertWebhookURL)
+	}
+	go kube.NewAlertEvaluator(kubeService, alertSink,
+		cfg.AlertEvalInterval).Run(context.Background())
+
 	authMiddleware := api.Middleware{
 		TokenService: jwtService,
 	}
 	protectedAPI.Use(authMiddleware.AuthMiddleware, api.ContentTypeJSON)
 
+	if cfg.MetricsEnabled {
+		metricsHandler := metrics.Handler()
+
+		if cfg.MetricsAuth {
+			metricsHandler = authMiddleware.AuthMiddleware(metricsHandler)
+		}
+
+		router.Handle("/metrics", metricsHandler).Methods(http.MethodGet)
+	}
+
 	if cfg.PprofListenStr != "" {
 		go func() {
 			logrus.Debugf("Start pprof on %s", cfg.PprofListenStr)
@@ -332,7 +483,7 @@ func ensureHelmRepositories(svc sghelm.Servicer) {
 		return
 	}
 
-	entries := []repo.Entry{
+	entries := []model.RepositoryConfig{
 		{
 			Name: "supergiant",
 			URL:  "https://supergiant.github.io/charts",
@@ -0,0 +1,280 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	clientcmddapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/supergiant/control/pkg/kubeconfig"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+const (
+	// DefaultAccessTTL is lifetime of tokens of scoped kubeconfigs
+	// when none is requested.
+	DefaultAccessTTL = time.Hour
+	// MinAccessTTL is the shortest lifetime TokenRequest API accepts.
+	MinAccessTTL = 10 * time.Minute
+
+	// accessLabel marks service accounts created for scoped kubeconfigs,
+	// only those may be revoked.
+	accessLabel = "supergiant.io/access"
+
+	legacyTokenAttempts = 10
+	legacyTokenInterval = 500 * time.Millisecond
+)
+
+// Roles of scoped kubeconfigs are bound to the default cluster roles.
+var accessRoles = map[string]bool{
+	"view":  true,
+	"edit":  true,
+	"admin": true,
+}
+
+// AccessRequest asks for kubeconfig limited to the role in the namespace.
+type AccessRequest struct {
+	Namespace  string `json:"namespace"`
+	Role       string `json:"role"`
+	TTLSeconds int64  `json:"ttlSeconds"`
+}
+
+// AccessGrant is a service account kubeconfig, legacy token clusters
+// issue tokens that do not expire until the grant is revoked.
+type AccessGrant struct {
+	Name       string     `json:"name"`
+	Namespace  string     `json:"namespace"`
+	Role       string     `json:"role"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	KubeConfig string     `json:"kubeconfig"`
+}
+
+func (r *AccessRequest) validate() error {
+	if r.Namespace == "" {
+		return errors.Wrap(sgerrors.ErrValidationFailed, "namespace is required")
+	}
+
+	if !accessRoles[r.Role] {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"role %s must be one of view, edit, admin", r.Role)
+	}
+
+	if r.TTLSeconds == 0 {
+		r.TTLSeconds = int64(DefaultAccessTTL / time.Second)
+	}
+
+	if r.TTLSeconds < int64(MinAccessTTL/time.Second) {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"ttl must be at least %d seconds", int64(MinAccessTTL/time.Second))
+	}
+
+	return nil
+}
+
+// grantAccess creates service account bound to the role and returns
+// kubeconfig with its token, service account is removed on failure.
+func grantAccess(ctx context.Context, clientSet kubernetes.Interface,
+	adminConfig clientcmddapi.Config, req AccessRequest) (*AccessGrant, error) {
+	name := fmt.Sprintf("control-%s-%s", req.Role, uuid.New()[:8])
+	labels := map[string]string{accessLabel: "true"}
+
+	_, err := clientSet.CoreV1().ServiceAccounts(req.Namespace).Create(&corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+	})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "create service account %s", name)
+	}
+
+	grant, err := bindAccess(ctx, clientSet, adminConfig, name, labels, req)
+
+	if err != nil {
+		if revokeErr := revokeAccess(clientSet, req.Namespace, name); revokeErr != nil {
+			logrus.Errorf("remove service account %s/%s: %v", req.Namespace, name, revokeErr)
+		}
+
+		return nil, err
+	}
+
+	return grant, nil
+}
+
+func bindAccess(ctx context.Context, clientSet kubernetes.Interface, adminConfig clientcmddapi.Config,
+	name string, labels map[string]string, req AccessRequest) (*AccessGrant, error) {
+	_, err := clientSet.RbacV1().RoleBindings(req.Namespace).Create(&rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     req.Role,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      name,
+				Namespace: req.Namespace,
+			},
+		},
+	})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "create role binding %s", name)
+	}
+
+	grant := &AccessGrant{
+		Name:      name,
+		Namespace: req.Namespace,
+		Role:      req.Role,
+	}
+
+	tokenRequest, err := clientSet.CoreV1().ServiceAccounts(req.Namespace).CreateToken(name,
+		&authenticationv1.TokenRequest{
+			Spec: authenticationv1.TokenRequestSpec{
+				ExpirationSeconds: &req.TTLSeconds,
+			},
+		})
+
+	var token string
+
+	switch {
+	case err == nil:
+		token = tokenRequest.Status.Token
+		expiresAt := tokenRequest.Status.ExpirationTimestamp.Time
+		grant.ExpiresAt = &expiresAt
+	case apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err):
+		// TokenRequest API is not enabled, token controller puts
+		// a non expiring token to the secret of service account
+		token, err = legacyToken(ctx, clientSet, req.Namespace, name)
+
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Wrapf(err, "request token of service account %s", name)
+	}
+
+	data, err := scopedKubeConfig(adminConfig, name, req.Namespace, token)
+
+	if err != nil {
+		return nil, err
+	}
+
+	grant.KubeConfig = string(data)
+
+	return grant, nil
+}
+
+// legacyToken waits for token controller to create the secret
+// of the service account.
+func legacyToken(ctx context.Context, clientSet kubernetes.Interface, namespace, name string) (string, error) {
+	for i := 0; i < legacyTokenAttempts; i++ {
+		secrets, err := clientSet.CoreV1().Secrets(namespace).List(metav1.ListOptions{})
+
+		if err != nil {
+			return "", errors.Wrapf(err, "list secrets of namespace %s", namespace)
+		}
+
+		for _, secret := range secrets.Items {
+			if secret.Type != corev1.SecretTypeServiceAccountToken ||
+				secret.Annotations[corev1.ServiceAccountNameKey] != name {
+				continue
+			}
+
+			if token := secret.Data[corev1.ServiceAccountTokenKey]; len(token) > 0 {
+				return string(token), nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", errors.Wrapf(ctx.Err(), "wait for token of service account %s", name)
+		case <-time.After(legacyTokenInterval):
+		}
+	}
+
+	return "", errors.Wrapf(sgerrors.ErrNotFound, "token of service account %s", name)
+}
+
+// scopedKubeConfig uses cluster of the admin kubeconfig with the token.
+func scopedKubeConfig(adminConfig clientcmddapi.Config, name, namespace, token string) ([]byte, error) {
+	adminContext := adminConfig.Contexts[adminConfig.CurrentContext]
+
+	if adminContext == nil {
+		return nil, errors.Wrapf(sgerrors.ErrNotFound, "context %s", adminConfig.CurrentContext)
+	}
+
+	cluster := adminConfig.Clusters[adminContext.Cluster]
+
+	if cluster == nil {
+		return nil, errors.Wrapf(sgerrors.ErrNotFound, "cluster %s", adminContext.Cluster)
+	}
+
+	contextName := name + "@" + adminContext.Cluster
+
+	data, err := kubeconfig.Marshal(clientcmddapi.Config{
+		Clusters: map[string]*clientcmddapi.Cluster{
+			adminContext.Cluster: cluster,
+		},
+		AuthInfos: map[string]*clientcmddapi.AuthInfo{
+			name: {Token: token},
+		},
+		Contexts: map[string]*clientcmddapi.Context{
+			contextName: {
+				Cluster:   adminContext.Cluster,
+				AuthInfo:  name,
+				Namespace: namespace,
+			},
+		},
+		CurrentContext: contextName,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.JSONToYAML(data)
+}
+
+// revokeAccess removes service account created by grantAccess, its
+// tokens are invalidated with it.
+func revokeAccess(clientSet kubernetes.Interface, namespace, name string) error {
+	sa, err := clientSet.CoreV1().ServiceAccounts(namespace).Get(name, metav1.GetOptions{})
+
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return errors.Wrapf(sgerrors.ErrNotFound, "service account %s/%s", namespace, name)
+		}
+
+		return errors.Wrapf(err, "get service account %s/%s", namespace, name)
+	}
+
+	if sa.Labels[accessLabel] != "true" {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"service account %s/%s is not managed by control", namespace, name)
+	}
+
+	err = clientSet.RbacV1().RoleBindings(namespace).Delete(name, &metav1.DeleteOptions{})
+
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "delete role binding %s/%s", namespace, name)
+	}
+
+	err = clientSet.CoreV1().ServiceAccounts(namespace).Delete(name, &metav1.DeleteOptions{})
+
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "delete service account %s/%s", namespace, name)
+	}
+
+	return nil
+}
@@ -0,0 +1,232 @@
+package kube
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmddapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+var accessAdminConfig = clientcmddapi.Config{
+	Clusters: map[string]*clientcmddapi.Cluster{
+		"kube": {Server: "https://kube:443", CertificateAuthorityData: []byte("ca")},
+	},
+	AuthInfos: map[string]*clientcmddapi.AuthInfo{
+		"admin": {ClientCertificateData: []byte("cert"), ClientKeyData: []byte("key")},
+	},
+	Contexts: map[string]*clientcmddapi.Context{
+		"admin@kube": {Cluster: "kube", AuthInfo: "admin"},
+	},
+	CurrentContext: "admin@kube",
+}
+
+func TestAccessRequestValidate(t *testing.T) {
+	testCases := []struct {
+		req         AccessRequest
+		expectedTTL int64
+		isErr       bool
+	}{
+		{
+			req:         AccessRequest{Namespace: "apps", Role: "view"},
+			expectedTTL: 3600,
+		},
+		{
+			req:         AccessRequest{Namespace: "apps", Role: "admin", TTLSeconds: 600},
+			expectedTTL: 600,
+		},
+		{
+			req:   AccessRequest{Role: "view"},
+			isErr: true,
+		},
+		{
+			req:   AccessRequest{Namespace: "apps", Role: "cluster-admin"},
+			isErr: true,
+		},
+		{
+			req:   AccessRequest{Namespace: "apps", Role: "edit", TTLSeconds: 60},
+			isErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.req.validate()
+
+		if testCase.isErr {
+			if !sgerrors.IsValidationFailed(err) {
+				t.Errorf("Expected validation error for %v actual %v", testCase.req, err)
+			}
+			continue
+		}
+
+		if err != nil || testCase.req.TTLSeconds != testCase.expectedTTL {
+			t.Errorf("Wrong ttl %d error %v", testCase.req.TTLSeconds, err)
+		}
+	}
+}
+
+func TestGrantAccessTokenRequest(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	clientSet := fake.NewSimpleClientset()
+	clientSet.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+
+		return true, &authenticationv1.TokenRequest{
+			Status: authenticationv1.TokenRequestStatus{
+				Token:               "scoped-token",
+				ExpirationTimestamp: metav1.NewTime(expiresAt),
+			},
+		}, nil
+	})
+
+	grant, err := grantAccess(context.Background(), clientSet, accessAdminConfig,
+		AccessRequest{Namespace: "apps", Role: "edit", TTLSeconds: 3600})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if grant.ExpiresAt == nil || !grant.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Wrong expiration %v", grant.ExpiresAt)
+	}
+
+	checkScopedKubeConfig(t, grant, "scoped-token")
+
+	binding, err := clientSet.RbacV1().RoleBindings("apps").Get(grant.Name, metav1.GetOptions{})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if binding.RoleRef.Name != "edit" || binding.Subjects[0].Name != grant.Name {
+		t.Errorf("Wrong role binding %v", binding)
+	}
+
+	if err := revokeAccess(clientSet, "apps", grant.Name); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	if _, err := clientSet.CoreV1().ServiceAccounts("apps").Get(grant.Name, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("Service account must be deleted %v", err)
+	}
+}
+
+func TestGrantAccessLegacyToken(t *testing.T) {
+	for _, tokenErr := range []error{
+		apierrors.NewNotFound(schema.GroupResource{Resource: "serviceaccounts/token"}, ""),
+		apierrors.NewMethodNotSupported(schema.GroupResource{Resource: "serviceaccounts/token"}, "create"),
+	} {
+		t.Logf("token request error %v", tokenErr)
+
+		var serviceAccount string
+
+		clientSet := fake.NewSimpleClientset()
+		clientSet.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if action.GetSubresource() == "token" {
+				return true, nil, tokenErr
+			}
+
+			serviceAccount = action.(k8stesting.CreateAction).GetObject().(*corev1.ServiceAccount).Name
+
+			return false, nil, nil
+		})
+		// Token controller creates secret of the service account
+		clientSet.PrependReactor("list", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, &corev1.SecretList{
+				Items: []corev1.Secret{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:        serviceAccount + "-token",
+							Annotations: map[string]string{corev1.ServiceAccountNameKey: serviceAccount},
+						},
+						Type: corev1.SecretTypeServiceAccountToken,
+						Data: map[string][]byte{corev1.ServiceAccountTokenKey: []byte("legacy-token")},
+					},
+				},
+			}, nil
+		})
+
+		grant, err := grantAccess(context.Background(), clientSet, accessAdminConfig,
+			AccessRequest{Namespace: "apps", Role: "view", TTLSeconds: 3600})
+
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if grant.ExpiresAt != nil {
+			t.Errorf("Legacy token must not have expiration %v", grant.ExpiresAt)
+		}
+
+		checkScopedKubeConfig(t, grant, "legacy-token")
+	}
+}
+
+func TestGrantAccessCleanup(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	clientSet.PrependReactor("create", "rolebindings", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+
+	if _, err := grantAccess(context.Background(), clientSet, accessAdminConfig,
+		AccessRequest{Namespace: "apps", Role: "view", TTLSeconds: 3600}); err == nil {
+		t.Fatalf("Error must not be nil")
+	}
+
+	accounts, err := clientSet.CoreV1().ServiceAccounts("apps").List(metav1.ListOptions{})
+
+	if err != nil || len(accounts.Items) != 0 {
+		t.Errorf("Service account must be removed %v %v", accounts, err)
+	}
+}
+
+func TestRevokeAccessUnmanaged(t *testing.T) {
+	clientSet := fake.NewSimpleClientset(&corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "apps"},
+	})
+
+	if err := revokeAccess(clientSet, "apps", "default"); !sgerrors.IsValidationFailed(err) {
+		t.Errorf("Expected validation error actual %v", err)
+	}
+
+	if err := revokeAccess(clientSet, "apps", "missing"); !sgerrors.IsNotFound(err) {
+		t.Errorf("Expected not found error actual %v", err)
+	}
+}
+
+func checkScopedKubeConfig(t *testing.T, grant *AccessGrant, expectedToken string) {
+	kubeConfig, err := clientcmd.Load([]byte(grant.KubeConfig))
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	ctx := kubeConfig.Contexts[kubeConfig.CurrentContext]
+
+	if ctx == nil || ctx.Namespace != grant.Namespace || ctx.Cluster != "kube" {
+		t.Fatalf("Wrong context %v", ctx)
+	}
+
+	if authInfo := kubeConfig.AuthInfos[ctx.AuthInfo]; authInfo == nil ||
+		authInfo.Token != expectedToken || len(authInfo.ClientKeyData) > 0 {
+		t.Errorf("Wrong user %v", authInfo)
+	}
+
+	if cluster := kubeConfig.Clusters["kube"]; cluster == nil || cluster.Server != "https://kube:443" {
+		t.Errorf("Wrong cluster %v", cluster)
+	}
+}
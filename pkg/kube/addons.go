@@ -0,0 +1,319 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/supergiant/control/pkg/message"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/provisioner/addons"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// installAddon installs the addon on the kube and adds it to addons of the kube.
+func (h *Handler) installAddon(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	kubeID, addonName := vars["kubeID"], vars["addonName"]
+	k, addon, ok := h.getAddon(w, r, kubeID, addonName)
+
+	if !ok {
+		return
+	}
+
+	if err := addon.Supports(k.K8SVersion); err != nil {
+		message.SendValidationFailed(w, err)
+		return
+	}
+
+	if status := k.AddonStatuses[addon.Name]; status != nil && status.Error == "" {
+		message.SendAlreadyExists(w, addon.Name, sgerrors.ErrAlreadyExists)
+		return
+	}
+
+	status, err := h.installAddonOn(r.Context(), k, addon)
+
+	if err != nil {
+		logrus.Errorf("install addon %s on kube %s %v", addon.Name, kubeID, err)
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if k.AddonStatuses == nil {
+		k.AddonStatuses = make(map[string]*model.AddonStatus)
+	}
+
+	k.AddonStatuses[addon.Name] = status
+	k.Addons = append(withoutAddon(k.Addons, addon.Name), addon.Name)
+
+	if err := h.svc.Update(r.Context(), k); err != nil {
+		if sgerrors.IsConflict(err) {
+			h.sendConflict(w, r, k.ID, err)
+			return
+		}
+
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		logrus.Errorf("encode addon status %v", err)
+	}
+}
+
+// deleteAddon removes the addon from the kube.
+func (h *Handler) deleteAddon(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	kubeID, addonName := vars["kubeID"], vars["addonName"]
+	k, addon, ok := h.getAddon(w, r, kubeID, addonName)
+
+	if !ok {
+		return
+	}
+
+	status := k.AddonStatuses[addon.Name]
+
+	if status == nil {
+		message.SendNotFound(w, addon.Name, errors.Wrapf(sgerrors.ErrNotFound,
+			"addon %s is not installed", addon.Name))
+		return
+	}
+
+	if err := h.uninstallAddon(r.Context(), k, addon, status); err != nil {
+		logrus.Errorf("delete addon %s from kube %s %v", addon.Name, kubeID, err)
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	delete(k.AddonStatuses, addon.Name)
+	k.Addons = withoutAddon(k.Addons, addon.Name)
+
+	if err := h.svc.Update(r.Context(), k); err != nil {
+		if sgerrors.IsConflict(err) {
+			h.sendConflict(w, r, k.ID, err)
+			return
+		}
+
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getAddon responds with an error when the kube or the addon is not
+// found or the kube is not operational.
+func (h *Handler) getAddon(w http.ResponseWriter, r *http.Request,
+	kubeID, addonName string) (*model.Kube, *addons.Addon, bool) {
+	k, err := h.svc.Get(r.Context(), kubeID)
+
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return nil, nil, false
+		}
+
+		message.SendUnknownError(w, err)
+		return nil, nil, false
+	}
+
+	addon, err := addons.Get(addonName)
+
+	if err != nil {
+		message.SendNotFound(w, addonName, err)
+		return nil, nil, false
+	}
+
+	if k.State != model.StateOperational {
+		message.SendValidationFailed(w, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"kube %s is not operational", kubeID))
+		return nil, nil, false
+	}
+
+	return k, addon, true
+}
+
+// InstallAddons installs addons of the kube that have not been installed
+// or have failed, failures are saved to statuses of the addons. System
+// components addons may rely on are ensured first.
+func (h *Handler) InstallAddons(ctx context.Context, kubeID string) {
+	h.EnsureComponents(ctx, kubeID)
+
+	k, err := h.svc.Get(ctx, kubeID)
+
+	if err != nil {
+		logrus.Errorf("install addons: get kube %s %v", kubeID, err)
+		return
+	}
+
+	installed := make(map[string]*model.AddonStatus)
+
+	for _, name := range k.Addons {
+		addon, err := addons.Get(name)
+
+		if err != nil {
+			logrus.Errorf("install addons of kube %s %v", kubeID, err)
+			continue
+		}
+
+		if status := k.AddonStatuses[addon.Name]; status != nil && status.Error == "" {
+			continue
+		}
+
+		status, err := h.installAddonOn(ctx, k, addon)
+
+		if err != nil {
+			logrus.Errorf("install addon %s on kube %s %v", addon.Name, kubeID, err)
+			status = &model.AddonStatus{
+				Error: err.Error(),
+			}
+		}
+
+		installed[addon.Name] = status
+	}
+
+	if len(installed) == 0 {
+		return
+	}
+
+	h.updateKube(kubeID, func(k *model.Kube) {
+		if k.AddonStatuses == nil {
+			k.AddonStatuses = make(map[string]*model.AddonStatus)
+		}
+
+		for name, status := range installed {
+			k.AddonStatuses[name] = status
+		}
+	})
+}
+
+// installAddonOn installs chart of the addon by helm, manifests of the
+// addon are applied when it has no chart or there is no helm client.
+func (h *Handler) installAddonOn(ctx context.Context, k *model.Kube,
+	addon *addons.Addon) (*model.AddonStatus, error) {
+	if addon.Chart != nil {
+		rls, err := h.svc.InstallRelease(ctx, k.ID, &ReleaseInput{
+			Name:         addon.Chart.Name,
+			Namespace:    addon.Namespace,
+			ChartName:    addon.Chart.Name,
+			ChartVersion: addon.Chart.Version,
+			RepoName:     addon.Chart.RepoName,
+			Values:       addon.Values,
+		})
+
+		if err == nil {
+			status := &model.AddonStatus{
+				Version:     rls.GetChart().GetMetadata().GetVersion(),
+				Release:     rls.GetName(),
+				InstalledAt: time.Now().Unix(),
+			}
+
+			if status.Version == "" {
+				status.Version = addon.Chart.Version
+			}
+
+			if status.Release == "" {
+				status.Release = addon.Chart.Name
+			}
+
+			return status, nil
+		}
+
+		if errors.Cause(err) != ErrNoHelmProxy || addon.Manifests == "" {
+			return nil, errors.Wrapf(err, "install chart of addon %s", addon.Name)
+		}
+
+		logrus.Infof("Apply manifests of addon %s to kube %s: %v", addon.Name, k.ID, err)
+	}
+
+	client, err := h.dynamicClientFor(k)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "build kubernetes client")
+	}
+
+	if err := addons.Apply(client, addon.Manifests); err != nil {
+		return nil, errors.Wrapf(err, "apply manifests of addon %s", addon.Name)
+	}
+
+	return &model.AddonStatus{
+		Version:     addon.Version,
+		InstalledAt: time.Now().Unix(),
+	}, nil
+}
+
+// uninstallAddon deletes release of the addon or objects of its manifests.
+func (h *Handler) uninstallAddon(ctx context.Context, k *model.Kube,
+	addon *addons.Addon, status *model.AddonStatus) error {
+	if status.Release != "" {
+		if _, err := h.svc.DeleteRelease(ctx, k.ID, status.Release, true); err != nil {
+			return errors.Wrapf(err, "delete release %s", status.Release)
+		}
+
+		return nil
+	}
+
+	// Addon that has failed to install may have no objects
+	if addon.Manifests == "" {
+		return nil
+	}
+
+	client, err := h.dynamicClientFor(k)
+
+	if err != nil {
+		return errors.Wrap(err, "build kubernetes client")
+	}
+
+	return addons.Delete(client, addon.Manifests)
+}
+
+// setAddonStatuses sets health of installed addons by their deployments.
+func (h *Handler) setAddonStatuses(ctx context.Context, k *model.Kube) {
+	if len(k.AddonStatuses) == 0 {
+		return
+	}
+
+	client, err := h.clientSetFor(k)
+
+	if err != nil {
+		logrus.Debugf("build kubernetes client of kube %s: %v", k.ID, err)
+		return
+	}
+
+	for name, status := range k.AddonStatuses {
+		addon, err := addons.Get(name)
+
+		if err != nil || status == nil {
+			continue
+		}
+
+		d, err := client.AppsV1().Deployments(addon.Namespace).Get(addon.Deployment,
+			metav1.GetOptions{})
+
+		status.Healthy = err == nil && d.Status.AvailableReplicas > 0 &&
+			d.Status.UnavailableReplicas == 0
+	}
+}
+
+// withoutAddon returns addons of the kube except the addon, legacy
+// names of the addon are removed as well.
+func withoutAddon(names []string, addonName string) []string {
+	out := make([]string, 0, len(names))
+
+	for _, name := range names {
+		if addon, err := addons.Get(name); err == nil && addon.Name == addonName {
+			continue
+		}
+
+		out = append(out, name)
+	}
+
+	return out
+}
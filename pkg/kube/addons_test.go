@@ -0,0 +1,349 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+type addonDynamic struct {
+	created []string
+	deleted []string
+}
+
+func (d *addonDynamic) Resource(resource schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &addonResource{client: d}
+}
+
+type addonResource struct {
+	dynamic.NamespaceableResourceInterface
+	client *addonDynamic
+}
+
+func (r *addonResource) Namespace(string) dynamic.ResourceInterface {
+	return r
+}
+
+func (r *addonResource) Create(obj *unstructured.Unstructured, options metav1.CreateOptions,
+	subresources ...string) (*unstructured.Unstructured, error) {
+	r.client.created = append(r.client.created, obj.GetName())
+	return obj, nil
+}
+
+func (r *addonResource) Delete(name string, options *metav1.DeleteOptions, subresources ...string) error {
+	r.client.deleted = append(r.client.deleted, name)
+	return nil
+}
+
+func addonKube() *model.Kube {
+	return &model.Kube{
+		ID:         "test",
+		State:      model.StateOperational,
+		K8SVersion: "1.15.3",
+		Addons:     []string{"dashboard"},
+		AddonStatuses: map[string]*model.AddonStatus{
+			"kubernetes-dashboard": {Version: "1.10.1", Release: "kubernetes-dashboard"},
+		},
+	}
+}
+
+func addonHandler(k *model.Kube, kubeErr error, rls *release.Release, rlsErr error) (*Handler, *addonDynamic) {
+	svc := &kubeServiceMock{
+		rls:     rls,
+		rlsInfo: &model.ReleaseInfo{},
+		rlsErr:  rlsErr,
+	}
+	svc.On(serviceGet, mock.Anything, mock.Anything).Return(k, kubeErr)
+	svc.On(serviceUpdate, mock.Anything, mock.Anything).Return(nil)
+
+	client := &addonDynamic{}
+
+	return &Handler{
+		svc: svc,
+		dynamicClientFor: func(*model.Kube) (dynamic.Interface, error) {
+			return client, nil
+		},
+	}, client
+}
+
+func TestInstallAddon(t *testing.T) {
+	ingressRelease := &release.Release{
+		Name: "nginx-ingress",
+		Chart: &chart.Chart{
+			Metadata: &chart.Metadata{Name: "nginx-ingress", Version: "1.24.4"},
+		},
+	}
+
+	testCases := []struct {
+		description string
+		addon       string
+		kubeErr     error
+		state       model.KubeState
+		k8sVersion  string
+		rlsErr      error
+
+		expectedCode    int
+		expectedStatus  model.AddonStatus
+		expectedApplied bool
+	}{
+		{
+			description:  "kube not found",
+			addon:        "nginx-ingress",
+			kubeErr:      sgerrors.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "unknown addon",
+			addon:        "istio",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "kube is not operational",
+			addon:        "nginx-ingress",
+			state:        model.StateProvisioning,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "unsupported K8S version",
+			addon:        "metrics-server",
+			k8sVersion:   "1.10.0",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "already installed",
+			addon:        "kubernetes-dashboard",
+			expectedCode: http.StatusConflict,
+		},
+		{
+			description:  "chart",
+			addon:        "nginx-ingress",
+			expectedCode: http.StatusCreated,
+			expectedStatus: model.AddonStatus{
+				Version: "1.24.4",
+				Release: "nginx-ingress",
+			},
+		},
+		{
+			description:  "manifests without helm client",
+			addon:        "nginx-ingress",
+			rlsErr:       errors.Wrap(ErrNoHelmProxy, "build helm proxy"),
+			expectedCode: http.StatusCreated,
+			expectedStatus: model.AddonStatus{
+				Version: "0.26.1",
+			},
+			expectedApplied: true,
+		},
+		{
+			description:  "chart error",
+			addon:        "nginx-ingress",
+			rlsErr:       errFake,
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			description:  "manifests",
+			addon:        "metrics-server",
+			expectedCode: http.StatusCreated,
+			expectedStatus: model.AddonStatus{
+				Version: "0.3.6",
+			},
+			expectedApplied: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		k := addonKube()
+
+		if testCase.state != "" {
+			k.State = testCase.state
+		}
+
+		if testCase.k8sVersion != "" {
+			k.K8SVersion = testCase.k8sVersion
+		}
+
+		h, client := addonHandler(k, testCase.kubeErr, ingressRelease, testCase.rlsErr)
+
+		req, _ := http.NewRequest(http.MethodPost, "/kubes/test/addons/"+testCase.addon, nil)
+		rec := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/kubes/{kubeID}/addons/{addonName}", h.installAddon)
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("Expected code %d actual %d %s", testCase.expectedCode,
+				rec.Code, rec.Body.String())
+			continue
+		}
+
+		if rec.Code != http.StatusCreated {
+			continue
+		}
+
+		status := k.AddonStatuses[testCase.addon]
+
+		if status == nil || status.Version != testCase.expectedStatus.Version ||
+			status.Release != testCase.expectedStatus.Release || status.InstalledAt == 0 {
+			t.Errorf("Expected status %v actual %v", testCase.expectedStatus, status)
+		}
+
+		if len(k.Addons) != 2 || k.Addons[1] != testCase.addon {
+			t.Errorf("Addon must be added to the kube %v", k.Addons)
+		}
+
+		if testCase.expectedApplied != (len(client.created) > 0) {
+			t.Errorf("Expected manifests applied %v actual %v", testCase.expectedApplied, client.created)
+		}
+	}
+}
+
+func TestDeleteAddon(t *testing.T) {
+	testCases := []struct {
+		description     string
+		addon           string
+		status          *model.AddonStatus
+		expectedCode    int
+		expectedDeleted bool
+	}{
+		{
+			description:  "not installed",
+			addon:        "metrics-server",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "release",
+			addon:        "dashboard",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			description:     "manifests",
+			addon:           "metrics-server",
+			status:          &model.AddonStatus{Version: "0.3.6"},
+			expectedCode:    http.StatusNoContent,
+			expectedDeleted: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		k := addonKube()
+
+		if testCase.status != nil {
+			k.Addons = append(k.Addons, testCase.addon)
+			k.AddonStatuses[testCase.addon] = testCase.status
+		}
+
+		h, client := addonHandler(k, nil, nil, nil)
+
+		req, _ := http.NewRequest(http.MethodDelete, "/kubes/test/addons/"+testCase.addon, nil)
+		rec := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/kubes/{kubeID}/addons/{addonName}", h.deleteAddon)
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("Expected code %d actual %d %s", testCase.expectedCode,
+				rec.Code, rec.Body.String())
+			continue
+		}
+
+		if rec.Code != http.StatusNoContent {
+			continue
+		}
+
+		if testCase.expectedDeleted != (len(client.deleted) > 0) {
+			t.Errorf("Expected objects deleted %v actual %v", testCase.expectedDeleted, client.deleted)
+		}
+
+		if testCase.status != nil {
+			if _, ok := k.AddonStatuses[testCase.addon]; ok || len(k.Addons) != 1 {
+				t.Errorf("Addon must be removed from the kube %v %v", k.Addons, k.AddonStatuses)
+			}
+		} else if len(k.AddonStatuses) != 0 || len(k.Addons) != 0 {
+			t.Errorf("Legacy addon must be removed from the kube %v %v", k.Addons, k.AddonStatuses)
+		}
+	}
+}
+
+func TestInstallAddons(t *testing.T) {
+	k := addonKube()
+	k.Addons = []string{"dashboard", "metrics-server", "nginx-ingress", "unknown"}
+	k.AddonStatuses["nginx-ingress"] = &model.AddonStatus{Error: "timeout"}
+
+	// Dashboard is installed already, ingress is retried
+	h, client := addonHandler(k, nil, nil, errFake)
+	h.InstallAddons(context.Background(), k.ID)
+
+	if status := k.AddonStatuses["kubernetes-dashboard"]; status.Release != "kubernetes-dashboard" {
+		t.Errorf("Installed addon must not be changed %v", status)
+	}
+
+	if status := k.AddonStatuses["metrics-server"]; status == nil || status.Version != "0.3.6" ||
+		status.Error != "" || len(client.created) == 0 {
+		t.Errorf("Manifests of metrics server must be applied %v %v", status, client.created)
+	}
+
+	if status := k.AddonStatuses["nginx-ingress"]; status == nil || status.Error == "" ||
+		status.Error == "timeout" {
+		t.Errorf("Error of chart install must be saved %v", status)
+	}
+}
+
+func TestSetAddonStatuses(t *testing.T) {
+	k := addonKube()
+	k.AddonStatuses["metrics-server"] = &model.AddonStatus{Version: "0.3.6"}
+
+	client := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "kubernetes-dashboard", Namespace: "kube-system"},
+			Status:     appsv1.DeploymentStatus{AvailableReplicas: 1},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "metrics-server", Namespace: "kube-system"},
+			Status:     appsv1.DeploymentStatus{AvailableReplicas: 1, UnavailableReplicas: 1},
+		},
+	)
+	h := &Handler{
+		clientSetFor: func(*model.Kube) (kubernetes.Interface, error) {
+			return client, nil
+		},
+	}
+
+	h.setAddonStatuses(context.Background(), k)
+
+	if !k.AddonStatuses["kubernetes-dashboard"].Healthy {
+		t.Errorf("Available addon must be healthy")
+	}
+
+	if k.AddonStatuses["metrics-server"].Healthy {
+		t.Errorf("Addon with unavailable replicas must not be healthy")
+	}
+
+	data, _ := json.Marshal(k)
+	decoded := &model.Kube{}
+
+	if err := json.Unmarshal(data, decoded); err != nil ||
+		!decoded.AddonStatuses["kubernetes-dashboard"].Healthy {
+		t.Errorf("Addon statuses must be returned with the kube %s", data)
+	}
+}
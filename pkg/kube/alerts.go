@@ -0,0 +1,372 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+const (
+	DefaultAlertEvalInterval = 30 * time.Second
+
+	// Timeout of notification requests to the webhook
+	webhookTimeout = 10 * time.Second
+)
+
+type AlertStatus string
+
+const (
+	AlertFiring   AlertStatus = "firing"
+	AlertResolved AlertStatus = "resolved"
+)
+
+// Alert is the notification of node metric that meets or no
+// longer meets condition of the rule.
+type Alert struct {
+	Status    AlertStatus         `json:"status"`
+	KubeID    string              `json:"kubeId"`
+	KubeName  string              `json:"kubeName"`
+	Node      string              `json:"node"`
+	RuleID    string              `json:"ruleId"`
+	Metric    string              `json:"metric"`
+	Operator  model.AlertOperator `json:"operator"`
+	Threshold float64             `json:"threshold"`
+	Value     float64             `json:"value"`
+	// Since is unix time the condition has been met from
+	Since int64 `json:"since"`
+}
+
+// AlertSink delivers alert notifications.
+type AlertSink interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// WebhookSink posts alerts as json to the url.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink constructs WebhookSink.
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+func (s *WebhookSink) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+
+	if err != nil {
+		return errors.Wrap(err, "marshal alert")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+
+	if err != nil {
+		return errors.Wrap(err, "create request")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req.WithContext(ctx))
+
+	if err != nil {
+		return errors.Wrapf(err, "post alert to %s", s.url)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("post alert to %s: unexpected status %s", s.url, resp.Status)
+	}
+
+	return nil
+}
+
+// logSink logs alerts when no webhook is configured.
+type logSink struct{}
+
+func (logSink) Notify(ctx context.Context, alert Alert) error {
+	logrus.Warnf("Kube %s alert %s %s: node %s %s %v %s %v", alert.KubeID, alert.RuleID,
+		alert.Status, alert.Node, alert.Metric, alert.Value, alert.Operator, alert.Threshold)
+	return nil
+}
+
+// alertKey identifies condition of the rule on the node.
+type alertKey struct {
+	ruleID string
+	node   string
+}
+
+type alertState struct {
+	since  time.Time
+	firing bool
+}
+
+// AlertEvaluator periodically evaluates alert rules of operational kubes
+// against node metrics of their prometheus.
+type AlertEvaluator struct {
+	svc      Interface
+	sink     AlertSink
+	interval time.Duration
+
+	queryMetrics func(context.Context, string, *model.Kube) ([]byte, error)
+	now          func() time.Time
+
+	// Conditions being met by kube id
+	states map[string]map[alertKey]*alertState
+}
+
+// NewAlertEvaluator constructs AlertEvaluator, alerts are logged
+// when sink is nil.
+func NewAlertEvaluator(svc Interface, sink AlertSink, interval time.Duration) *AlertEvaluator {
+	if interval <= 0 {
+		interval = DefaultAlertEvalInterval
+	}
+
+	if sink == nil {
+		sink = logSink{}
+	}
+
+	return &AlertEvaluator{
+		svc:          svc,
+		sink:         sink,
+		interval:     interval,
+		queryMetrics: queryMetrics,
+		now:          time.Now,
+		states:       make(map[string]map[alertKey]*alertState),
+	}
+}
+
+// Run evaluates rules until context is done.
+func (e *AlertEvaluator) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.poll(ctx)
+		}
+	}
+}
+
+func (e *AlertEvaluator) poll(ctx context.Context) {
+	kubes, err := e.svc.ListAll(ctx)
+
+	if err != nil {
+		logrus.Errorf("alert evaluator: list kubes %v", err)
+		return
+	}
+
+	evaluated := make(map[string]bool, len(kubes))
+
+	for i := range kubes {
+		k := &kubes[i]
+
+		if k.State != model.StateOperational || len(k.AlertRules) == 0 {
+			continue
+		}
+
+		evaluated[k.ID] = true
+		e.evaluateKube(ctx, k)
+	}
+
+	// Kubes that have been deleted or have no rules are not evaluated anymore
+	for kubeID := range e.states {
+		if !evaluated[kubeID] {
+			delete(e.states, kubeID)
+		}
+	}
+}
+
+func (e *AlertEvaluator) evaluateKube(ctx context.Context, k *model.Kube) {
+	states := e.states[k.ID]
+
+	if states == nil {
+		states = make(map[alertKey]*alertState)
+		e.states[k.ID] = states
+	}
+
+	// Conditions of removed rules are dropped
+	for key := range states {
+		if _, ok := k.AlertRules[key.ruleID]; !ok {
+			delete(states, key)
+		}
+	}
+
+	values := make(map[string]map[string]float64)
+
+	for _, rule := range k.AlertRules {
+		nodeValues, ok := values[rule.Metric]
+
+		if !ok {
+			var err error
+			nodeValues, err = nodeMetricValues(ctx, k, rule.Metric, e.queryMetrics)
+
+			if err != nil {
+				logrus.Errorf("alert evaluator: kube %s metric %s %v", k.ID, rule.Metric, err)
+				continue
+			}
+
+			values[rule.Metric] = nodeValues
+		}
+
+		e.evaluateRule(ctx, k, rule, nodeValues, states)
+	}
+}
+
+// evaluateRule fires alerts of nodes that meet condition of the rule for
+// its duration and resolves alerts of nodes that do not meet it anymore.
+// Pending conditions of nodes that do not report the metric are dropped.
+func (e *AlertEvaluator) evaluateRule(ctx context.Context, k *model.Kube, rule *model.AlertRule,
+	nodeValues map[string]float64, states map[alertKey]*alertState) {
+	now := e.now()
+
+	for key, state := range states {
+		if _, ok := nodeValues[key.node]; key.ruleID == rule.ID && !ok && !state.firing {
+			delete(states, key)
+		}
+	}
+
+	for node, value := range nodeValues {
+		key := alertKey{ruleID: rule.ID, node: node}
+		state := states[key]
+
+		if !rule.Matches(value) {
+			if state != nil && state.firing {
+				if err := e.notify(ctx, k, rule, node, value, AlertResolved, state.since); err != nil {
+					continue
+				}
+			}
+
+			delete(states, key)
+			continue
+		}
+
+		if state == nil {
+			state = &alertState{since: now}
+			states[key] = state
+		}
+
+		if state.firing || now.Sub(state.since) < time.Duration(rule.Duration)*time.Second {
+			continue
+		}
+
+		if err := e.notify(ctx, k, rule, node, value, AlertFiring, state.since); err == nil {
+			state.firing = true
+		}
+	}
+}
+
+func (e *AlertEvaluator) notify(ctx context.Context, k *model.Kube, rule *model.AlertRule,
+	node string, value float64, status AlertStatus, since time.Time) error {
+	err := e.sink.Notify(ctx, Alert{
+		Status:    status,
+		KubeID:    k.ID,
+		KubeName:  k.Name,
+		Node:      node,
+		RuleID:    rule.ID,
+		Metric:    rule.Metric,
+		Operator:  rule.Operator,
+		Threshold: rule.Threshold,
+		Value:     value,
+		Since:     since.Unix(),
+	})
+
+	if err != nil {
+		logrus.Errorf("alert evaluator: kube %s notify %s alert %s of node %s %v",
+			k.ID, status, rule.ID, node, err)
+	}
+
+	return err
+}
+
+// nodeMetricValues queries the node metric of the kube, values are keyed
+// by machine name like in nodes metrics.
+func nodeMetricValues(ctx context.Context, k *model.Kube, metric string,
+	query func(context.Context, string, *model.Kube) ([]byte, error)) (map[string]float64, error) {
+	uri := fmt.Sprintf("/%s/api/v1/query?query=%s", prometheusProxyURL,
+		url.QueryEscape(nodeMetricsQueries[metric]))
+
+	raw, err := query(ctx, uri, k)
+
+	if err != nil {
+		return nil, err
+	}
+
+	metricResponse := &MetricResponse{}
+
+	if err := json.Unmarshal(raw, metricResponse); err != nil {
+		return nil, errors.Wrap(err, "unmarshal")
+	}
+
+	metrics := make(map[string]map[string]interface{})
+
+	for _, result := range metricResponse.Data.Result {
+		nodeName, ok := result.Metric["node"]
+
+		if !ok || len(result.Value) < 2 {
+			continue
+		}
+
+		if v, ok := metricValue(result.Value[1]); ok && !math.IsNaN(v) {
+			metrics[nodeName] = map[string]interface{}{metric: v}
+		}
+	}
+
+	values := make(map[string]float64, len(metrics))
+
+	for nodeName, nodeMetrics := range processMetrics(k, metrics) {
+		values[nodeName] = nodeMetrics[metric].(float64)
+	}
+
+	return values, nil
+}
+
+// newAlertRule validates the rule and assigns its id.
+func newAlertRule(rule model.AlertRule) (*model.AlertRule, error) {
+	if _, ok := nodeMetricsQueries[rule.Metric]; !ok {
+		return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"unknown metric %s", rule.Metric)
+	}
+
+	switch rule.Operator {
+	case model.AlertAbove, model.AlertAboveOrEqual, model.AlertBelow, model.AlertBelowOrEqual:
+	default:
+		return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"operator %s must be one of >, >=, <, <=", rule.Operator)
+	}
+
+	if rule.Duration < 0 {
+		return nil, errors.Wrap(sgerrors.ErrValidationFailed,
+			"duration must not be negative")
+	}
+
+	if math.IsNaN(rule.Threshold) || math.IsInf(rule.Threshold, 0) {
+		return nil, errors.Wrap(sgerrors.ErrValidationFailed,
+			"threshold must be a number")
+	}
+
+	if rule.ID == "" {
+		rule.ID = uuid.New()[:8]
+	}
+
+	return &rule, nil
+}
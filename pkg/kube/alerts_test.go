@@ -0,0 +1,267 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+type sinkMock struct {
+	alerts []Alert
+	err    error
+}
+
+func (s *sinkMock) Notify(ctx context.Context, alert Alert) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.alerts = append(s.alerts, alert)
+	return nil
+}
+
+func alertKube() *model.Kube {
+	return &model.Kube{
+		ID:    "kube-1",
+		Name:  "test",
+		State: model.StateOperational,
+		AlertRules: map[string]*model.AlertRule{
+			"memory": {
+				ID:        "memory",
+				Metric:    "memory",
+				Operator:  model.AlertAbove,
+				Threshold: 0.9,
+				Duration:  300,
+			},
+		},
+	}
+}
+
+func TestAlertEvaluatorPoll(t *testing.T) {
+	k := alertKube()
+	svc := new(kubeServiceMock)
+	svc.On(serviceListAll, mock.Anything).Return([]model.Kube{*k}, nil)
+
+	sink := &sinkMock{}
+	now := time.Unix(1000, 0)
+	memory := "0.95"
+	var queryErr error
+
+	e := NewAlertEvaluator(svc, sink, 0)
+	e.now = func() time.Time {
+		return now
+	}
+	e.queryMetrics = func(ctx context.Context, uri string, k *model.Kube) ([]byte, error) {
+		return []byte(fmt.Sprintf(`{"status":"success","data":{"resultType":"vector",`+
+			`"result":[{"metric":{"node":"node-1"},"value":[1000,"%s"]}]}}`, memory)), queryErr
+	}
+
+	steps := []struct {
+		description string
+		after       time.Duration
+		memory      string
+		queryErr    error
+		sinkErr     error
+		expected    []AlertStatus
+	}{
+		{
+			description: "condition is pending",
+			memory:      "0.95",
+		},
+		{
+			description: "prometheus fails",
+			after:       time.Minute,
+			queryErr:    errors.New("prometheus is down"),
+		},
+		{
+			description: "condition holds for less than duration",
+			after:       3 * time.Minute,
+			memory:      "0.92",
+		},
+		{
+			description: "sink fails",
+			after:       2 * time.Minute,
+			memory:      "0.92",
+			sinkErr:     errors.New("connection refused"),
+		},
+		{
+			description: "condition holds for duration",
+			after:       time.Minute,
+			memory:      "0.93",
+			expected:    []AlertStatus{AlertFiring},
+		},
+		{
+			description: "firing alert is not sent again",
+			after:       time.Minute,
+			memory:      "0.97",
+			expected:    []AlertStatus{AlertFiring},
+		},
+		{
+			description: "condition is not met",
+			after:       time.Minute,
+			memory:      "0.5",
+			expected:    []AlertStatus{AlertFiring, AlertResolved},
+		},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.after)
+		memory = step.memory
+		queryErr = step.queryErr
+		sink.err = step.sinkErr
+
+		e.poll(context.Background())
+
+		if len(sink.alerts) != len(step.expected) {
+			t.Errorf("%s: expected alerts %v actual %+v", step.description, step.expected, sink.alerts)
+			continue
+		}
+
+		for i, status := range step.expected {
+			if sink.alerts[i].Status != status {
+				t.Errorf("%s: expected alert %s actual %s", step.description, status, sink.alerts[i].Status)
+			}
+		}
+	}
+
+	alert := sink.alerts[0]
+
+	if alert.KubeID != k.ID || alert.Node != "node-1" || alert.Metric != "memory" ||
+		alert.Value != 0.93 || alert.Since != 1000 {
+		t.Errorf("Wrong alert %+v", alert)
+	}
+
+	// Kube has been deleted
+	svc = new(kubeServiceMock)
+	svc.On(serviceListAll, mock.Anything).Return([]model.Kube{}, nil)
+	e.svc = svc
+
+	e.poll(context.Background())
+
+	if len(e.states) != 0 {
+		t.Errorf("Conditions of deleted kube must be dropped %v", e.states)
+	}
+}
+
+func TestAlertEvaluatorSkipsKubes(t *testing.T) {
+	provisioning := alertKube()
+	provisioning.State = model.StateProvisioning
+
+	noRules := alertKube()
+	noRules.ID = "kube-2"
+	noRules.AlertRules = nil
+
+	svc := new(kubeServiceMock)
+	svc.On(serviceListAll, mock.Anything).
+		Return([]model.Kube{*provisioning, *noRules}, nil)
+
+	e := NewAlertEvaluator(svc, &sinkMock{}, 0)
+	e.queryMetrics = func(ctx context.Context, uri string, k *model.Kube) ([]byte, error) {
+		t.Errorf("Kube %s must not be evaluated", k.ID)
+		return nil, errors.New("unexpected query")
+	}
+
+	e.poll(context.Background())
+}
+
+func TestNewAlertRule(t *testing.T) {
+	testCases := []struct {
+		description string
+		rule        model.AlertRule
+		expectedErr error
+	}{
+		{
+			description: "valid",
+			rule: model.AlertRule{
+				Metric:    "memory",
+				Operator:  model.AlertAboveOrEqual,
+				Threshold: 0.9,
+				Duration:  300,
+			},
+		},
+		{
+			description: "unknown metric",
+			rule: model.AlertRule{
+				Metric:   "swap",
+				Operator: model.AlertAbove,
+			},
+			expectedErr: sgerrors.ErrValidationFailed,
+		},
+		{
+			description: "unknown operator",
+			rule: model.AlertRule{
+				Metric:   "cpu",
+				Operator: "==",
+			},
+			expectedErr: sgerrors.ErrValidationFailed,
+		},
+		{
+			description: "negative duration",
+			rule: model.AlertRule{
+				Metric:   "cpu",
+				Operator: model.AlertBelow,
+				Duration: -1,
+			},
+			expectedErr: sgerrors.ErrValidationFailed,
+		},
+	}
+
+	for _, testCase := range testCases {
+		rule, err := newAlertRule(testCase.rule)
+
+		if errors.Cause(err) != testCase.expectedErr {
+			t.Errorf("%s: expected error %v actual %v", testCase.description, testCase.expectedErr, err)
+			continue
+		}
+
+		if err == nil && rule.ID == "" {
+			t.Errorf("%s: rule id must be assigned", testCase.description)
+		}
+	}
+}
+
+func TestWebhookSinkNotify(t *testing.T) {
+	var received Alert
+	status := http.StatusOK
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Unexpected error %v", err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL)
+	alert := Alert{
+		Status: AlertFiring,
+		KubeID: "kube-1",
+		Node:   "node-1",
+		Metric: "memory",
+		Value:  0.95,
+	}
+
+	if err := sink.Notify(context.Background(), alert); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	if received != alert {
+		t.Errorf("Expected alert %+v actual %+v", alert, received)
+	}
+
+	status = http.StatusInternalServerError
+
+	if err := sink.Notify(context.Background(), alert); err == nil {
+		t.Errorf("Error status must fail notification")
+	}
+}
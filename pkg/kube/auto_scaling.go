@@ -0,0 +1,216 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/clouds/awssdk"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+// createAutoScalingGroup starts the task that creates auto scaling group
+// of the autoscaled pool with count instances, cluster autoscaler resizes
+// the group afterwards.
+func (h *Handler) createAutoScalingGroup(ctx context.Context, k *model.Kube, pool *model.NodePool,
+	config *steps.Config, count int64) (string, error) {
+	if pool.AutoScalingGroup == "" {
+		pool.AutoScalingGroup = fmt.Sprintf("%s-%s", k.ID, pool.Name)
+	}
+
+	config.AWSConfig.InstanceType = pool.MachineType
+
+	if pool.Image != "" {
+		config.AWSConfig.ImageID = pool.Image
+	}
+
+	// Instances of the group join the kube the way spot instances do
+	config.AWSConfig.UserData = fmt.Sprintf("#!/bin/sh\n%s", config.ConfigMap.Data)
+	config.AutoScalingConfig = steps.AutoScalingConfig{
+		GroupName:       pool.AutoScalingGroup,
+		MinSize:         pool.MinCount,
+		MaxSize:         pool.MaxCount,
+		DesiredCapacity: count,
+	}
+
+	return h.startAutoScalingTask(ctx, k, pool, config, workflows.AutoScalingGroup)
+}
+
+// deleteAutoScalingGroup starts the task that deletes auto scaling group
+// of the pool along with its instances, the pool and its nodes are
+// removed from the kube when the group is gone.
+func (h *Handler) deleteAutoScalingGroup(ctx context.Context, k *model.Kube,
+	pool *model.NodePool) (string, error) {
+	config, _, err := h.spotConfig(ctx, k)
+
+	if err != nil {
+		return "", err
+	}
+
+	config.Pool = pool.Name
+
+	return h.startAutoScalingTask(ctx, k, pool, config, workflows.DeleteAutoScalingGroup)
+}
+
+func (h *Handler) startAutoScalingTask(ctx context.Context, k *model.Kube, pool *model.NodePool,
+	config *steps.Config, workflow string) (string, error) {
+	t, err := workflows.NewTask(config, workflow, h.repo)
+
+	if err != nil {
+		return "", errors.Wrap(err, "new task")
+	}
+
+	config.TaskID = t.ID
+	writer, err := h.getWriter(util.MakeFileName(t.ID))
+
+	if err != nil {
+		return "", errors.Wrap(err, "get writer")
+	}
+
+	deletePool := workflow == workflows.DeleteAutoScalingGroup
+	pool.TaskID = t.ID
+	err = h.savePool(ctx, k.ID, pool, func(k *model.Kube) {
+		appendTasks(k, workflows.NodePoolTask, t.ID)
+
+		if !deletePool {
+			return
+		}
+
+		for _, n := range k.Nodes {
+			if n != nil && n.Pool == pool.Name {
+				n.State = model.MachineStateDeleting
+			}
+		}
+	})
+
+	if err != nil {
+		return "", errors.Wrapf(err, "update kube %s", k.ID)
+	}
+
+	go h.runAutoScalingTask(k.ID, pool.Name, t, config, writer, deletePool)
+
+	return t.ID, nil
+}
+
+func (h *Handler) runAutoScalingTask(kubeID, poolName string, t *workflows.Task,
+	config *steps.Config, out io.WriteCloser, deletePool bool) {
+	if err := <-t.Run(context.Background(), config.Clone(), out); err != nil {
+		logrus.Errorf("auto scaling task %s of pool %s of kube %s caused %v",
+			t.ID, poolName, kubeID, err)
+		return
+	}
+
+	if !deletePool {
+		return
+	}
+
+	h.updateKube(kubeID, func(k *model.Kube) {
+		for name, n := range k.Nodes {
+			if n != nil && n.Pool == poolName {
+				delete(k.Nodes, name)
+			}
+		}
+
+		delete(k.NodePools, poolName)
+	})
+}
+
+// validateAutoscaledPool checks that the pool may be backed by auto
+// scaling group that cluster autoscaler of the kube resizes.
+func validateAutoscaledPool(k *model.Kube, pool *model.NodePool) error {
+	if k.Provider != clouds.AWS {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"autoscaled node pools are not supported by provider %s", k.Provider)
+	}
+
+	if !k.ClusterAutoscaler {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"cluster autoscaler is not enabled for kube %s", k.Name)
+	}
+
+	// Instances of the group join by user data like spot ones
+	if pool.Spot || pool.GPU || len(pool.Taints) > 0 || len(pool.Labels) > 0 {
+		return errors.Wrap(sgerrors.ErrValidationFailed,
+			"spot, GPUs, taints and labels of autoscaled node pools are not supported")
+	}
+
+	if pool.MaxCount <= 0 || pool.MinCount < 0 || pool.MinCount > pool.Count ||
+		pool.Count > pool.MaxCount {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"node count %d must be between min %d and max %d counts, max must be positive",
+			pool.Count, pool.MinCount, pool.MaxCount)
+	}
+
+	return nil
+}
+
+// errAutoscaledPool is returned on resize of the pool by user or schedule.
+func errAutoscaledPool(poolName string) error {
+	return errors.Wrapf(sgerrors.ErrValidationFailed,
+		"size of node pool %s is managed by cluster autoscaler", poolName)
+}
+
+// syncAutoScalingGroups sets counts of autoscaled pools to desired
+// capacity of their groups, nodes of the pools that have left their
+// groups are marked deleting.
+func syncAutoScalingGroups(ctx context.Context, svc awssdk.AutoScalingService, k *model.Kube) error {
+	pools := make(map[string]*model.NodePool)
+	names := make([]string, 0)
+
+	for _, name := range sortedPoolNames(k) {
+		pool := k.NodePools[name]
+
+		if pool.AutoScalingGroup != "" {
+			pools[pool.AutoScalingGroup] = pool
+			names = append(names, pool.AutoScalingGroup)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	groups, err := svc.DescribeAutoScalingGroups(ctx, names)
+
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		pool := pools[aws.StringValue(group.AutoScalingGroupName)]
+
+		if pool == nil {
+			continue
+		}
+
+		pool.Count = aws.Int64Value(group.DesiredCapacity)
+		members := make(map[string]bool, len(group.Instances))
+
+		for _, instance := range group.Instances {
+			switch aws.StringValue(instance.LifecycleState) {
+			case awssdk.LifecycleTerminating, awssdk.LifecycleTerminated:
+			default:
+				members[aws.StringValue(instance.InstanceId)] = true
+			}
+		}
+
+		for _, n := range poolNodes(k, pool.Name) {
+			if !members[n.ID] {
+				logrus.Infof("Node %s of kube %s has left auto scaling group %s",
+					n.Name, k.ID, pool.AutoScalingGroup)
+				n.State = model.MachineStateDeleting
+			}
+		}
+	}
+
+	return nil
+}
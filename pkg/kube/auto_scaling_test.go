@@ -0,0 +1,295 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/gorilla/mux"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/clouds/awssdk"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type autoScalingStep struct {
+	drainStep
+	configs chan steps.AutoScalingConfig
+}
+
+func (s autoScalingStep) Run(ctx context.Context, w io.Writer, config *steps.Config) error {
+	s.configs <- config.AutoScalingConfig
+	return nil
+}
+
+type fakeAutoScaling struct {
+	awssdk.AutoScalingService
+	groups []*awssdk.AutoScalingGroup
+	err    error
+}
+
+func (f *fakeAutoScaling) DescribeAutoScalingGroups(ctx context.Context,
+	names []string) ([]*awssdk.AutoScalingGroup, error) {
+	return f.groups, f.err
+}
+
+func autoscaledPoolKube() *model.Kube {
+	k := poolKube()
+	k.Provider = clouds.AWS
+	k.ClusterAutoscaler = true
+	k.NodePools["asg"] = &model.NodePool{
+		NodePool: profile.NodePool{
+			Name:        "asg",
+			MachineType: "m5.large",
+			Count:       2,
+		},
+		Autoscale:        true,
+		MinCount:         1,
+		MaxCount:         5,
+		AutoScalingGroup: "test-asg",
+	}
+	k.Nodes["asg-1"] = &model.Machine{ID: "i-1", Name: "asg-1", Pool: "asg"}
+	k.Nodes["asg-2"] = &model.Machine{ID: "i-2", Name: "asg-2", Pool: "asg"}
+
+	return k
+}
+
+func TestValidateAutoscaledPool(t *testing.T) {
+	testCases := []struct {
+		description string
+		provider    clouds.Name
+		disabled    bool
+		pool        model.NodePool
+		expectedErr bool
+	}{
+		{
+			description: "success",
+			provider:    clouds.AWS,
+			pool:        model.NodePool{NodePool: profile.NodePool{Count: 1}, MaxCount: 3},
+		},
+		{
+			description: "unsupported provider",
+			provider:    clouds.GCE,
+			pool:        model.NodePool{NodePool: profile.NodePool{Count: 1}, MaxCount: 3},
+			expectedErr: true,
+		},
+		{
+			description: "cluster autoscaler is disabled",
+			provider:    clouds.AWS,
+			disabled:    true,
+			pool:        model.NodePool{NodePool: profile.NodePool{Count: 1}, MaxCount: 3},
+			expectedErr: true,
+		},
+		{
+			description: "spot",
+			provider:    clouds.AWS,
+			pool:        model.NodePool{Spot: true, MaxCount: 3},
+			expectedErr: true,
+		},
+		{
+			description: "labels",
+			provider:    clouds.AWS,
+			pool: model.NodePool{
+				NodePool: profile.NodePool{Labels: map[string]string{"team": "ml"}},
+				MaxCount: 3,
+			},
+			expectedErr: true,
+		},
+		{
+			description: "count above max",
+			provider:    clouds.AWS,
+			pool:        model.NodePool{NodePool: profile.NodePool{Count: 4}, MaxCount: 3},
+			expectedErr: true,
+		},
+		{
+			description: "count below min",
+			provider:    clouds.AWS,
+			pool:        model.NodePool{NodePool: profile.NodePool{Count: 1}, MinCount: 2, MaxCount: 3},
+			expectedErr: true,
+		},
+		{
+			description: "no max",
+			provider:    clouds.AWS,
+			pool:        model.NodePool{},
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		k := &model.Kube{
+			Provider:          testCase.provider,
+			ClusterAutoscaler: !testCase.disabled,
+		}
+		err := validateAutoscaledPool(k, &testCase.pool)
+
+		if testCase.expectedErr != (err != nil) {
+			t.Errorf("Expected error %v actual %v", testCase.expectedErr, err)
+		}
+
+		if err != nil && !sgerrors.IsValidationFailed(err) {
+			t.Errorf("Expected validation error actual %v", err)
+		}
+	}
+}
+
+func TestCreateAutoscaledNodePool(t *testing.T) {
+	configs := make(chan steps.AutoScalingConfig, 1)
+
+	workflows.Init()
+	workflows.RegisterWorkFlow(workflows.AutoScalingGroup,
+		[]steps.Step{autoScalingStep{configs: configs}})
+
+	k := autoscaledPoolKube()
+	h := poolHandler(k, nil, new(mockNodeProvisioner))
+
+	data, _ := json.Marshal(model.NodePool{
+		NodePool:  profile.NodePool{Name: "scaled", MachineType: "m5.large", Count: 1},
+		Autoscale: true,
+		MaxCount:  4,
+	})
+	req, _ := http.NewRequest(http.MethodPost, "/kubes/test/pools", bytes.NewReader(data))
+	rec := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/kubes/{kubeID}/pools", h.createNodePool)
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected code %d actual %d %s", http.StatusAccepted, rec.Code, rec.Body.String())
+	}
+
+	pool := k.NodePools["scaled"]
+
+	if pool == nil || pool.AutoScalingGroup != "test-scaled" || pool.TaskID == "" {
+		t.Fatalf("Pool must be saved with its group and task %v", pool)
+	}
+
+	select {
+	case config := <-configs:
+		if config.GroupName != "test-scaled" || config.MinSize != 0 ||
+			config.MaxSize != 4 || config.DesiredCapacity != 1 {
+			t.Errorf("Unexpected auto scaling config %v", config)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Auto scaling group task has not been run")
+	}
+}
+
+func TestResizeAutoscaledNodePool(t *testing.T) {
+	k := autoscaledPoolKube()
+	h := poolHandler(k, nil, new(mockNodeProvisioner))
+
+	data, _ := json.Marshal(NodePoolResize{Count: 3})
+	req, _ := http.NewRequest(http.MethodPatch, "/kubes/test/pools/asg", bytes.NewReader(data))
+	rec := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/kubes/{kubeID}/pools/{poolName}", h.resizeNodePool)
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected code %d actual %d %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+
+	if k.NodePools["asg"].Count != 2 {
+		t.Errorf("Count of autoscaled pool must not be changed %v", k.NodePools["asg"])
+	}
+}
+
+func TestDeleteAutoscaledNodePool(t *testing.T) {
+	workflows.Init()
+	workflows.RegisterWorkFlow(workflows.DeleteAutoScalingGroup, []steps.Step{drainStep{}})
+
+	k := autoscaledPoolKube()
+	h := poolHandler(k, nil, new(mockNodeProvisioner))
+
+	req, _ := http.NewRequest(http.MethodDelete, "/kubes/test/pools/asg", nil)
+	rec := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/kubes/{kubeID}/pools/{poolName}", h.deleteNodePool)
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected code %d actual %d %s", http.StatusAccepted, rec.Code, rec.Body.String())
+	}
+
+	resp := nodePoolResponse{}
+
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if err := waitPoolTask(h, resp.TaskID); err != nil {
+		t.Fatal(err)
+	}
+
+	poolExists := func() bool {
+		kube, _ := h.svc.Get(context.Background(), "test")
+		_, ok := kube.NodePools["asg"]
+		return ok
+	}
+	deadline := time.Now().Add(time.Second * 5)
+
+	for poolExists() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	if poolExists() {
+		t.Errorf("Pool must be removed from the kube")
+	}
+
+	if len(k.Nodes) != 4 || k.Nodes["asg-1"] != nil || k.Nodes["asg-2"] != nil {
+		t.Errorf("Only nodes of the pool must be removed %v", k.Nodes)
+	}
+}
+
+func TestSyncAutoScalingGroups(t *testing.T) {
+	k := autoscaledPoolKube()
+	svc := &fakeAutoScaling{
+		groups: []*awssdk.AutoScalingGroup{
+			{
+				AutoScalingGroupName: aws.String("test-asg"),
+				DesiredCapacity:      aws.Int64(3),
+				Instances: []*awssdk.AutoScalingInstance{
+					{InstanceId: aws.String("i-1"), LifecycleState: aws.String("InService")},
+					{InstanceId: aws.String("i-2"), LifecycleState: aws.String(awssdk.LifecycleTerminating)},
+					{InstanceId: aws.String("i-3"), LifecycleState: aws.String("Pending")},
+				},
+			},
+		},
+	}
+
+	if err := syncAutoScalingGroups(context.Background(), svc, k); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if k.NodePools["asg"].Count != 3 {
+		t.Errorf("Expected pool count 3 actual %d", k.NodePools["asg"].Count)
+	}
+
+	if k.Nodes["asg-1"].State == model.MachineStateDeleting {
+		t.Errorf("Node in service must not be deleted %v", k.Nodes["asg-1"])
+	}
+
+	if k.Nodes["asg-2"].State != model.MachineStateDeleting {
+		t.Errorf("Terminating node must be deleted %v", k.Nodes["asg-2"])
+	}
+
+	svc.err = sgerrors.ErrNotFound
+
+	if err := syncAutoScalingGroups(context.Background(), svc, k); err == nil ||
+		!strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected describe error actual %v", err)
+	}
+}
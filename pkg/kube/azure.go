@@ -0,0 +1,342 @@
+package kube
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-11-01/network"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+	azuresteps "github.com/supergiant/control/pkg/workflows/steps/azure"
+)
+
+const (
+	azureSyncWorkers = 8
+	azureSyncTimeout = time.Minute * 2
+
+	azureProvisioningSucceeded = "Succeeded"
+)
+
+type azureMachineClient interface {
+	ListVMs(ctx context.Context, groupName string) ([]compute.VirtualMachine, error)
+	GetNIC(ctx context.Context, groupName, nicName string) (network.Interface, error)
+	GetPublicIP(ctx context.Context, groupName, ipName string) (network.PublicIPAddress, error)
+}
+
+type azureClient struct {
+	vms compute.VirtualMachinesClient
+	nic network.InterfacesClient
+	ips network.PublicIPAddressesClient
+}
+
+func newAzureClient(a autorest.Authorizer, subscriptionID string) *azureClient {
+	c := &azureClient{
+		vms: compute.NewVirtualMachinesClient(subscriptionID),
+		nic: network.NewInterfacesClient(subscriptionID),
+		ips: network.NewPublicIPAddressesClient(subscriptionID),
+	}
+
+	c.vms.Authorizer = a
+	c.nic.Authorizer = a
+	c.ips.Authorizer = a
+
+	return c
+}
+
+func (c *azureClient) ListVMs(ctx context.Context, groupName string) ([]compute.VirtualMachine, error) {
+	vms := make([]compute.VirtualMachine, 0)
+	it, err := c.vms.ListComplete(ctx, groupName)
+
+	for ; err == nil && it.NotDone(); err = it.NextWithContext(ctx) {
+		vms = append(vms, it.Value())
+	}
+
+	return vms, err
+}
+
+func (c *azureClient) GetNIC(ctx context.Context, groupName, nicName string) (network.Interface, error) {
+	return c.nic.Get(ctx, groupName, nicName, "")
+}
+
+func (c *azureClient) GetPublicIP(ctx context.Context, groupName, ipName string) (network.PublicIPAddress, error) {
+	return c.ips.Get(ctx, groupName, ipName, "")
+}
+
+func getAzureClient(config *steps.Config) (azureMachineClient, error) {
+	a, err := auth.NewClientCredentialsConfig(
+		config.AzureConfig.ClientID,
+		config.AzureConfig.ClientSecret,
+		config.AzureConfig.TenantID,
+	).Authorizer()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newAzureClient(a, config.AzureConfig.SubscriptionID), nil
+}
+
+// syncAzureMachines reconciles kube machines with VMs of the cluster
+// resource group, IPs of VMs are resolved by bounded pool of workers.
+func syncAzureMachines(ctx context.Context, client azureMachineClient, k *model.Kube) error {
+	ctx, cancel := context.WithTimeout(ctx, azureSyncTimeout)
+	defer cancel()
+
+	groupName := azuresteps.ResourceGroupName(k.ID, k.Name)
+	vms, err := client.ListVMs(ctx, groupName)
+
+	if err != nil {
+		return errors.Wrapf(err, "list vms of group %s", groupName)
+	}
+
+	machines, err := resolveAzureMachines(ctx, client, k, vms)
+
+	if err != nil {
+		return errors.Wrap(err, "resolve vm addresses")
+	}
+
+	// VMs of the cluster by private ip
+	byIP := make(map[string]*model.Machine, len(machines))
+
+	for _, machine := range machines {
+		if machine.PrivateIp != "" {
+			byIP[machine.PrivateIp] = machine
+		}
+	}
+
+	if k.Masters == nil {
+		k.Masters = make(map[string]*model.Machine)
+	}
+
+	if k.Nodes == nil {
+		k.Nodes = make(map[string]*model.Machine)
+	}
+
+	for privateIP, machine := range byIP {
+		if machine.State != model.MachineStateActive {
+			continue
+		}
+
+		if isKnownMachine(k.Masters, privateIP) || isKnownMachine(k.Nodes, privateIP) {
+			continue
+		}
+
+		if machine.Role == model.RoleMaster {
+			logrus.Debugf("Add new master %v", machine)
+			k.Masters[machine.Name] = machine
+			continue
+		}
+
+		logrus.Debugf("Add new node %v", machine)
+		k.Nodes[machine.Name] = machine
+	}
+
+	for name, machine := range k.Nodes {
+		if !isSyncable(machine) || byIP[machine.PrivateIp] != nil {
+			continue
+		}
+
+		// Give node one more sync before removing it from the model
+		if machine.State == model.MachineStateDeleting {
+			logrus.Infof("Remove node %s gone from Azure from kube %s", name, k.ID)
+			delete(k.Nodes, name)
+			continue
+		}
+
+		logrus.Infof("Node %s of kube %s is gone from Azure", name, k.ID)
+		machine.State = model.MachineStateDeleting
+	}
+
+	for name, machine := range k.Masters {
+		if !isSyncable(machine) || byIP[machine.PrivateIp] != nil {
+			continue
+		}
+
+		logrus.Errorf("Master %s of kube %s is gone from Azure", name, k.ID)
+		machine.State = model.MachineStateError
+	}
+
+	return nil
+}
+
+// resolveAzureMachines builds machines of cluster VMs, NIC and public
+// IP of each VM are fetched by one of azureSyncWorkers.
+func resolveAzureMachines(ctx context.Context, client azureMachineClient,
+	k *model.Kube, vms []compute.VirtualMachine) ([]*model.Machine, error) {
+	var (
+		m        sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+
+	machines := make([]*model.Machine, 0, len(vms))
+	jobs := make(chan compute.VirtualMachine)
+
+	for i := 0; i < azureSyncWorkers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for vm := range jobs {
+				machine, err := azureMachine(ctx, client, k, vm)
+
+				m.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = errors.Wrapf(err, "vm %s", to.String(vm.Name))
+				}
+
+				if err == nil && machine != nil {
+					machines = append(machines, machine)
+				}
+				m.Unlock()
+			}
+		}()
+	}
+
+	for _, vm := range vms {
+		select {
+		case jobs <- vm:
+		case <-ctx.Done():
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return machines, ctx.Err()
+}
+
+// azureMachine returns machine of VM, nil is returned for VMs
+// that belong to another cluster.
+func azureMachine(ctx context.Context, client azureMachineClient,
+	k *model.Kube, vm compute.VirtualMachine) (*model.Machine, error) {
+	// VMs created before tagging have no cluster id tag
+	if clusterID := to.String(vm.Tags[clouds.LabelClusterID]); clusterID != "" && clusterID != k.ID {
+		return nil, nil
+	}
+
+	machine := &model.Machine{
+		ID:       to.String(vm.ID),
+		Name:     to.String(vm.Name),
+		Region:   to.String(vm.Location),
+		Role:     model.RoleNode,
+		Provider: clouds.Azure,
+		State:    model.MachineStateActive,
+	}
+
+	if name := to.String(vm.Tags[clouds.TagNodeName]); name != "" {
+		machine.Name = name
+	}
+
+	switch role := to.String(vm.Tags[clouds.TagRole]); {
+	case role == util.MakeRole(true):
+		machine.Role = model.RoleMaster
+	case role == "" && k.Masters[machine.Name] != nil:
+		// Fallback for VMs created without role tag
+		machine.Role = model.RoleMaster
+	}
+
+	props := vm.VirtualMachineProperties
+
+	if props == nil {
+		return machine, nil
+	}
+
+	if to.String(props.ProvisioningState) != azureProvisioningSucceeded {
+		machine.State = model.MachineStateBuilding
+	}
+
+	if props.HardwareProfile != nil {
+		machine.Size = string(props.HardwareProfile.VMSize)
+	}
+
+	nicID := primaryNICID(props.NetworkProfile)
+
+	if nicID == "" {
+		return machine, nil
+	}
+
+	nicResource, err := azure.ParseResourceID(nicID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	nic, err := client.GetNIC(ctx, nicResource.ResourceGroup, nicResource.ResourceName)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "get nic %s", nicResource.ResourceName)
+	}
+
+	if nic.InterfacePropertiesFormat == nil || nic.IPConfigurations == nil ||
+		len(*nic.IPConfigurations) == 0 {
+		return machine, nil
+	}
+
+	ipConfig := (*nic.IPConfigurations)[0]
+
+	if ipConfig.InterfaceIPConfigurationPropertiesFormat == nil {
+		return machine, nil
+	}
+
+	machine.PrivateIp = to.String(ipConfig.PrivateIPAddress)
+
+	if ipConfig.PublicIPAddress == nil || ipConfig.PublicIPAddress.ID == nil {
+		return machine, nil
+	}
+
+	ipResource, err := azure.ParseResourceID(*ipConfig.PublicIPAddress.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ip, err := client.GetPublicIP(ctx, ipResource.ResourceGroup, ipResource.ResourceName)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "get public ip %s", ipResource.ResourceName)
+	}
+
+	if ip.PublicIPAddressPropertiesFormat != nil {
+		machine.PublicIp = to.String(ip.IPAddress)
+	}
+
+	return machine, nil
+}
+
+func primaryNICID(profile *compute.NetworkProfile) string {
+	if profile == nil || profile.NetworkInterfaces == nil {
+		return ""
+	}
+
+	nicID := ""
+
+	for _, ref := range *profile.NetworkInterfaces {
+		if nicID == "" {
+			nicID = to.String(ref.ID)
+		}
+
+		if ref.NetworkInterfaceReferenceProperties != nil &&
+			to.Bool(ref.Primary) {
+			return to.String(ref.ID)
+		}
+	}
+
+	return nicID
+}
@@ -0,0 +1,222 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-11-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+)
+
+const azureResourcePrefix = "/subscriptions/sub/resourceGroups/sg-test-kube-id/providers/Microsoft.Network"
+
+type fakeAzureClient struct {
+	m sync.Mutex
+
+	vms       []compute.VirtualMachine
+	listErr   error
+	nicErr    error
+	privateIP map[string]string
+
+	groups []string
+}
+
+func (f *fakeAzureClient) ListVMs(ctx context.Context, groupName string) ([]compute.VirtualMachine, error) {
+	f.groups = append(f.groups, groupName)
+	return f.vms, f.listErr
+}
+
+func (f *fakeAzureClient) GetNIC(ctx context.Context, groupName, nicName string) (network.Interface, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if f.nicErr != nil {
+		return network.Interface{}, f.nicErr
+	}
+
+	return network.Interface{
+		InterfacePropertiesFormat: &network.InterfacePropertiesFormat{
+			IPConfigurations: &[]network.InterfaceIPConfiguration{
+				{
+					InterfaceIPConfigurationPropertiesFormat: &network.InterfaceIPConfigurationPropertiesFormat{
+						PrivateIPAddress: to.StringPtr(f.privateIP[nicName]),
+						PublicIPAddress: &network.PublicIPAddress{
+							ID: to.StringPtr(azureResourcePrefix + "/publicIPAddresses/ip0-" + nicName),
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+func (f *fakeAzureClient) GetPublicIP(ctx context.Context, groupName, ipName string) (network.PublicIPAddress, error) {
+	return network.PublicIPAddress{
+		PublicIPAddressPropertiesFormat: &network.PublicIPAddressPropertiesFormat{
+			IPAddress: to.StringPtr("40.0.0.1"),
+		},
+	}, nil
+}
+
+func azureVM(name, state string, tags map[string]*string) compute.VirtualMachine {
+	return compute.VirtualMachine{
+		ID:       to.StringPtr(fmt.Sprintf("/vms/%s", name)),
+		Name:     to.StringPtr(name),
+		Location: to.StringPtr("westeurope"),
+		Tags:     tags,
+		VirtualMachineProperties: &compute.VirtualMachineProperties{
+			ProvisioningState: to.StringPtr(state),
+			HardwareProfile: &compute.HardwareProfile{
+				VMSize: compute.VirtualMachineSizeTypesStandardA2V2,
+			},
+			NetworkProfile: &compute.NetworkProfile{
+				NetworkInterfaces: &[]compute.NetworkInterfaceReference{
+					{
+						ID: to.StringPtr(azureResourcePrefix + "/networkInterfaces/" + name),
+						NetworkInterfaceReferenceProperties: &compute.NetworkInterfaceReferenceProperties{
+							Primary: to.BoolPtr(true),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func azureTags(clusterID, role string) map[string]*string {
+	return map[string]*string{
+		clouds.LabelClusterID: to.StringPtr(clusterID),
+		clouds.TagRole:        to.StringPtr(role),
+	}
+}
+
+func TestSyncAzureMachines(t *testing.T) {
+	client := &fakeAzureClient{
+		vms: []compute.VirtualMachine{
+			azureVM("master-1", azureProvisioningSucceeded, azureTags("kube-id", "master")),
+			azureVM("node-1", azureProvisioningSucceeded, azureTags("kube-id", "node")),
+			azureVM("node-2", "Creating", azureTags("kube-id", "node")),
+			azureVM("other", azureProvisioningSucceeded, azureTags("other-id", "node")),
+			// VM created before tagging
+			azureVM("untagged", azureProvisioningSucceeded, nil),
+		},
+		privateIP: map[string]string{
+			"master-1": "10.0.1.1",
+			"node-1":   "10.0.0.1",
+			"node-2":   "10.0.0.2",
+			"other":    "10.0.0.3",
+			"untagged": "10.0.0.4",
+		},
+	}
+
+	k := &model.Kube{
+		ID:   "kube-id",
+		Name: "test",
+		Masters: map[string]*model.Machine{
+			"master-2": {
+				Name:      "master-2",
+				PrivateIp: "10.0.1.2",
+				State:     model.MachineStateActive,
+			},
+		},
+		Nodes: map[string]*model.Machine{
+			"missing": {
+				Name:      "missing",
+				PrivateIp: "10.0.0.5",
+				State:     model.MachineStateActive,
+			},
+		},
+	}
+
+	if err := syncAzureMachines(context.Background(), client, k); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(client.groups) != 1 || client.groups[0] != "sg-test-kube-id" {
+		t.Errorf("Wrong resource group listed %v", client.groups)
+	}
+
+	if master := k.Masters["master-1"]; master == nil || master.Role != model.RoleMaster {
+		t.Errorf("Master must be synced %v", k.Masters)
+	}
+
+	for _, name := range []string{"node-1", "untagged"} {
+		if k.Nodes[name] == nil {
+			t.Errorf("Node %s must be synced", name)
+		}
+	}
+
+	for _, name := range []string{"node-2", "other"} {
+		if k.Nodes[name] != nil {
+			t.Errorf("Node %s must not be synced", name)
+		}
+	}
+
+	node := k.Nodes["node-1"]
+
+	if node != nil && (node.PrivateIp != "10.0.0.1" || node.PublicIp != "40.0.0.1" ||
+		node.Size != string(compute.VirtualMachineSizeTypesStandardA2V2) ||
+		node.Region != "westeurope" || node.Provider != clouds.Azure) {
+		t.Errorf("Wrong node %v", node)
+	}
+
+	if k.Nodes["missing"] == nil || k.Nodes["missing"].State != model.MachineStateDeleting {
+		t.Errorf("Missing node must be deleting %v", k.Nodes["missing"])
+	}
+
+	if k.Masters["master-2"].State != model.MachineStateError {
+		t.Errorf("Wrong state of master expected %s actual %s",
+			model.MachineStateError, k.Masters["master-2"].State)
+	}
+
+	// Nodes are removed on the next sync
+	if err := syncAzureMachines(context.Background(), client, k); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if k.Nodes["missing"] != nil {
+		t.Errorf("Node missing must be removed")
+	}
+}
+
+func TestSyncAzureMachinesError(t *testing.T) {
+	testCases := []struct {
+		description string
+		client      *fakeAzureClient
+	}{
+		{
+			description: "list vms",
+			client: &fakeAzureClient{
+				listErr: errors.New("list error"),
+			},
+		},
+		{
+			description: "get nic",
+			client: &fakeAzureClient{
+				vms: []compute.VirtualMachine{
+					azureVM("node-1", azureProvisioningSucceeded, azureTags("kube-id", "node")),
+				},
+				nicErr: errors.New("nic error"),
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		k := &model.Kube{
+			ID: "kube-id",
+		}
+
+		if err := syncAzureMachines(context.Background(), testCase.client, k); err == nil {
+			t.Errorf("Error must not be nil")
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package kube
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/provisioner/addons"
+)
+
+// UpgradeComponents brings system components of operational kubes to
+// versions pinned by control, it is run on start of control so the
+// components are upgraded along with it.
+func (h *Handler) UpgradeComponents(ctx context.Context) {
+	kubes, err := h.svc.ListAll(ctx)
+
+	if err != nil {
+		logrus.Errorf("upgrade components: list kubes %v", err)
+		return
+	}
+
+	for _, k := range kubes {
+		if k.State != model.StateOperational {
+			continue
+		}
+
+		h.EnsureComponents(ctx, k.ID)
+	}
+}
+
+// EnsureComponents installs system components missing from the kube and
+// upgrades the ones older than pinned versions. Releases of components
+// installed before control managed them are adopted as they are, objects
+// of components without release are taken over by the install in place.
+func (h *Handler) EnsureComponents(ctx context.Context, kubeID string) {
+	k, err := h.svc.Get(ctx, kubeID)
+
+	if err != nil {
+		logrus.Errorf("ensure components: get kube %s %v", kubeID, err)
+		return
+	}
+
+	releases, err := h.svc.ListReleases(ctx, k.ID, "", "", 0)
+
+	if err != nil {
+		logrus.Errorf("ensure components: list releases of kube %s %v", kubeID, err)
+		return
+	}
+
+	updated := make(map[string]*model.ComponentStatus)
+
+	for _, c := range addons.Components() {
+		status, err := h.ensureComponent(ctx, k, c, findRelease(releases, c))
+
+		if err != nil {
+			logrus.Errorf("ensure component %s of kube %s %v", c.Name, kubeID, err)
+			status = &model.ComponentStatus{
+				Release:   c.Chart.Name,
+				UpdatedAt: time.Now().Unix(),
+				Error:     err.Error(),
+			}
+
+			if current := k.Components[c.Name]; current != nil {
+				status.Version, status.Adopted = current.Version, current.Adopted
+			}
+		}
+
+		if status != nil {
+			updated[c.Name] = status
+		}
+	}
+
+	if len(updated) == 0 {
+		return
+	}
+
+	h.updateKube(kubeID, func(k *model.Kube) {
+		if k.Components == nil {
+			k.Components = make(map[string]*model.ComponentStatus)
+		}
+
+		for name, status := range updated {
+			k.Components[name] = status
+		}
+	})
+}
+
+// ensureComponent returns new status of the component or nil when
+// it is up to date.
+func (h *Handler) ensureComponent(ctx context.Context, k *model.Kube,
+	c *addons.Component, rls *model.ReleaseInfo) (*model.ComponentStatus, error) {
+	current := k.Components[c.Name]
+
+	if rls != nil && current != nil && current.Error == "" &&
+		current.Version == rls.ChartVersion && !olderVersion(rls.ChartVersion, c.Chart.Version) {
+		return nil, nil
+	}
+
+	status := &model.ComponentStatus{
+		Version:   c.Chart.Version,
+		Release:   c.Chart.Name,
+		UpdatedAt: time.Now().Unix(),
+		Adopted:   current != nil && current.Adopted || current == nil && rls != nil,
+	}
+
+	input := &ReleaseInput{
+		Name:         c.Chart.Name,
+		Namespace:    c.Namespace,
+		ChartName:    c.Chart.Name,
+		ChartVersion: c.Chart.Version,
+		RepoName:     c.Chart.RepoName,
+		Values:       c.Values,
+	}
+
+	if c.Set != nil {
+		input.Set = c.Set(k)
+	}
+
+	switch {
+	case rls == nil:
+		if _, err := h.svc.InstallRelease(ctx, k.ID, input); err != nil {
+			return nil, errors.Wrapf(err, "install release %s", input.Name)
+		}
+	case olderVersion(rls.ChartVersion, c.Chart.Version):
+		if _, err := h.svc.UpgradeRelease(ctx, k.ID, rls.Name, input); err != nil {
+			return nil, errors.Wrapf(err, "upgrade release %s", rls.Name)
+		}
+	default:
+		// Releases newer than pinned are kept
+		status.Version = rls.ChartVersion
+	}
+
+	return status, nil
+}
+
+// markSystemReleases flags releases of system components.
+func markSystemReleases(releases []*model.ReleaseInfo) {
+	for _, rls := range releases {
+		if rls == nil {
+			continue
+		}
+
+		if c, ok := addons.SystemComponent(rls.Name); ok && rls.Namespace == c.Namespace {
+			rls.System = true
+		}
+	}
+}
+
+func isSystemRelease(name string) bool {
+	_, ok := addons.SystemComponent(name)
+	return ok
+}
+
+func findRelease(releases []*model.ReleaseInfo, c *addons.Component) *model.ReleaseInfo {
+	for _, rls := range releases {
+		if rls != nil && rls.Name == c.Chart.Name && rls.Namespace == c.Namespace {
+			return rls
+		}
+	}
+
+	return nil
+}
+
+// olderVersion tells whether version is older than the pinned one,
+// versions that do not parse are upgraded.
+func olderVersion(version, pinned string) bool {
+	v, err := semver.NewVersion(version)
+
+	if err != nil {
+		return true
+	}
+
+	p, err := semver.NewVersion(pinned)
+
+	if err != nil {
+		return false
+	}
+
+	return v.LessThan(p)
+}
@@ -0,0 +1,188 @@
+package kube
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	"github.com/supergiant/control/pkg/model"
+)
+
+type componentsServiceMock struct {
+	*kubeServiceMock
+	installed  []*ReleaseInput
+	upgraded   []*ReleaseInput
+	installErr error
+}
+
+func (m *componentsServiceMock) InstallRelease(ctx context.Context,
+	kname string, rls *ReleaseInput) (*release.Release, error) {
+	m.installed = append(m.installed, rls)
+	return nil, m.installErr
+}
+
+func (m *componentsServiceMock) UpgradeRelease(ctx context.Context,
+	kname, rlsName string, rls *ReleaseInput) (*release.Release, error) {
+	m.upgraded = append(m.upgraded, rls)
+	return m.kubeServiceMock.UpgradeRelease(ctx, kname, rlsName, rls)
+}
+
+func TestEnsureComponents(t *testing.T) {
+	testCases := []struct {
+		description string
+		components  map[string]*model.ComponentStatus
+		releases    []*model.ReleaseInfo
+		listErr     error
+		installErr  error
+
+		expectInstall bool
+		expectUpgrade bool
+		expected      *model.ComponentStatus
+	}{
+		{
+			description:   "fresh install",
+			expectInstall: true,
+			expected:      &model.ComponentStatus{Version: "5.0.4", Release: "prometheus-operator"},
+		},
+		{
+			description: "adopt existing release",
+			releases: []*model.ReleaseInfo{
+				{Name: "prometheus-operator", Namespace: "kube-system", ChartVersion: "5.0.4"},
+			},
+			expected: &model.ComponentStatus{Version: "5.0.4", Release: "prometheus-operator", Adopted: true},
+		},
+		{
+			description: "release of other namespace",
+			releases: []*model.ReleaseInfo{
+				{Name: "prometheus-operator", Namespace: "monitoring", ChartVersion: "5.0.4"},
+			},
+			expectInstall: true,
+			expected:      &model.ComponentStatus{Version: "5.0.4", Release: "prometheus-operator"},
+		},
+		{
+			description: "upgrade older release",
+			components: map[string]*model.ComponentStatus{
+				"prometheus-operator": {Version: "5.0.0", Release: "prometheus-operator", Adopted: true},
+			},
+			releases: []*model.ReleaseInfo{
+				{Name: "prometheus-operator", Namespace: "kube-system", ChartVersion: "5.0.0"},
+			},
+			expectUpgrade: true,
+			expected:      &model.ComponentStatus{Version: "5.0.4", Release: "prometheus-operator", Adopted: true},
+		},
+		{
+			description: "keep newer release",
+			releases: []*model.ReleaseInfo{
+				{Name: "prometheus-operator", Namespace: "kube-system", ChartVersion: "6.0.0"},
+			},
+			expected: &model.ComponentStatus{Version: "6.0.0", Release: "prometheus-operator", Adopted: true},
+		},
+		{
+			description: "up to date",
+			components: map[string]*model.ComponentStatus{
+				"prometheus-operator": {Version: "5.0.4", Release: "prometheus-operator", UpdatedAt: 1},
+			},
+			releases: []*model.ReleaseInfo{
+				{Name: "prometheus-operator", Namespace: "kube-system", ChartVersion: "5.0.4"},
+			},
+			expected: &model.ComponentStatus{Version: "5.0.4", Release: "prometheus-operator", UpdatedAt: 1},
+		},
+		{
+			description: "install error",
+			components: map[string]*model.ComponentStatus{
+				"prometheus-operator": {Version: "5.0.0", Release: "prometheus-operator"},
+			},
+			installErr:    errFake,
+			expectInstall: true,
+			expected: &model.ComponentStatus{Version: "5.0.0", Release: "prometheus-operator",
+				Error: "install release prometheus-operator: " + errFake.Error()},
+		},
+		{
+			description: "list releases error",
+			listErr:     errFake,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		k := &model.Kube{
+			ID:         "test",
+			State:      model.StateOperational,
+			Components: testCase.components,
+		}
+
+		svc := &componentsServiceMock{
+			kubeServiceMock: &kubeServiceMock{
+				rlsInfoList: testCase.releases,
+				rlsErr:      testCase.listErr,
+			},
+			installErr: testCase.installErr,
+		}
+		svc.On(serviceGet, mock.Anything, mock.Anything).Return(k, nil)
+		svc.On(serviceUpdate, mock.Anything, mock.Anything).Return(nil)
+
+		h := &Handler{svc: svc}
+		h.EnsureComponents(context.Background(), k.ID)
+
+		if testCase.expectInstall != (len(svc.installed) == 1) {
+			t.Errorf("Expected install %v actual %v", testCase.expectInstall, svc.installed)
+		}
+
+		if testCase.expectUpgrade != (len(svc.upgraded) == 1) {
+			t.Errorf("Expected upgrade %v actual %v", testCase.expectUpgrade, svc.upgraded)
+		}
+
+		for _, rls := range append(svc.installed, svc.upgraded...) {
+			if rls.Namespace != "kube-system" || rls.ChartVersion != "5.0.4" || len(rls.Set) == 0 {
+				t.Errorf("Release of pinned chart expected %v", rls)
+			}
+		}
+
+		status := k.Components["prometheus-operator"]
+
+		if testCase.expected == nil {
+			if status != nil {
+				t.Errorf("Status must not be recorded %v", status)
+			}
+			continue
+		}
+
+		if status == nil {
+			t.Errorf("Status of component must be recorded")
+			continue
+		}
+
+		if testCase.expected.UpdatedAt == 0 && status.UpdatedAt == 0 {
+			t.Errorf("Update time must be recorded")
+		}
+
+		status.UpdatedAt = testCase.expected.UpdatedAt
+		if *status != *testCase.expected {
+			t.Errorf("Expected status %v actual %v", testCase.expected, status)
+		}
+	}
+}
+
+func TestOlderVersion(t *testing.T) {
+	testCases := []struct {
+		version  string
+		pinned   string
+		expected bool
+	}{
+		{"5.0.0", "5.0.4", true},
+		{"5.0.4", "5.0.4", false},
+		{"6.0.0", "5.0.4", false},
+		{"unknown", "5.0.4", true},
+		{"5.0.0", "unknown", false},
+	}
+
+	for _, testCase := range testCases {
+		if actual := olderVersion(testCase.version, testCase.pinned); actual != testCase.expected {
+			t.Errorf("Expected %s older than %s %v actual %v",
+				testCase.version, testCase.pinned, testCase.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/message"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows/statuses"
+)
+
+// Modes of kube deletion
+const (
+	// DeleteModeDestroy deletes machines and cloud resources of the kube
+	DeleteModeDestroy = "destroy"
+	// DeleteModeDetach keeps machines of the kube, control forgets it
+	DeleteModeDetach = "detach"
+)
+
+const (
+	DefaultDetachRetention       = 30 * 24 * time.Hour
+	DefaultDetachedPurgeInterval = time.Hour
+)
+
+var (
+	errDetached    = errors.New("kube is detached")
+	errNotDetached = errors.New("kube is not detached")
+)
+
+// KubePurger deletes record and tasks of the kube.
+type KubePurger func(ctx context.Context, kubeID string) error
+
+// SetDetachRetention sets how long detached kubes may be restored.
+func (h *Handler) SetDetachRetention(retention time.Duration) {
+	h.detachRetention = retention
+}
+
+// detachKube makes control forget the kube without deleting its machines,
+// the kube is kept for the retention period passed with the retention
+// parameter or the default one, it may be restored meanwhile.
+func (h *Handler) detachKube(w http.ResponseWriter, r *http.Request) {
+	kubeID := mux.Vars(r)["kubeID"]
+	retention := h.detachRetention
+
+	if s := r.URL.Query().Get("retention"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			message.SendValidationFailed(w, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"retention %s must be a positive duration", s))
+			return
+		}
+		retention = d
+	}
+
+	if !h.checkNoRunningTasks(w, r, kubeID) {
+		return
+	}
+
+	now := time.Now()
+	k, err := retryUpdate(r.Context(), h.svc, kubeID, func(k *model.Kube) error {
+		switch k.State {
+		case model.StateDetached:
+			return errDetached
+		case model.StateDeleting:
+			return errors.Wrapf(sgerrors.ErrValidationFailed, "kube %s is being deleted", k.ID)
+		}
+
+		k.Detachment = &model.Detachment{
+			State:      k.State,
+			DetachedAt: now.Unix(),
+			PurgeAt:    now.Add(retention).Unix(),
+		}
+		k.State = model.StateDetached
+
+		return nil
+	})
+
+	if err != nil {
+		switch {
+		case sgerrors.IsNotFound(err):
+			message.SendNotFound(w, kubeID, err)
+		case err == errDetached:
+			message.SendMessage(w, message.New(fmt.Sprintf("Kube %s is detached already", kubeID),
+				err.Error(), sgerrors.ValidationFailed, ""), http.StatusConflict)
+		case sgerrors.IsValidationFailed(err):
+			message.SendMessage(w, message.New(fmt.Sprintf("Kube %s can not be detached", kubeID),
+				err.Error(), sgerrors.ValidationFailed, ""), http.StatusConflict)
+		case sgerrors.IsConflict(err):
+			h.sendConflict(w, r, kubeID, err)
+		default:
+			message.SendUnknownError(w, errors.Wrapf(err, "detach kube %s", kubeID))
+		}
+		return
+	}
+
+	logrus.Infof("kube %s has been detached until %s", kubeID,
+		time.Unix(k.Detachment.PurgeAt, 0).Format(time.RFC3339))
+
+	if h.serviceProxy != nil {
+		h.serviceProxy.Close(kubeID)
+	}
+	h.metricsCache.forget(kubeID)
+
+	if err = json.NewEncoder(w).Encode(k); err != nil {
+		message.SendUnknownError(w, err)
+	}
+}
+
+// restoreKube makes control manage the detached kube again, its machines
+// are synced with the cloud as they may have changed while it was detached.
+func (h *Handler) restoreKube(w http.ResponseWriter, r *http.Request) {
+	kubeID := mux.Vars(r)["kubeID"]
+
+	k, err := h.svc.Get(r.Context(), kubeID)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if k.State != model.StateDetached {
+		message.SendMessage(w, message.New(fmt.Sprintf("Kube %s is not detached", kubeID),
+			errNotDetached.Error(), sgerrors.ValidationFailed, ""), http.StatusConflict)
+		return
+	}
+
+	var acc *model.CloudAccount
+
+	if k.AccountName != "" {
+		acc, err = h.accountService.Get(r.Context(), k.AccountName)
+		if err != nil {
+			if sgerrors.IsNotFound(err) {
+				message.SendNotFound(w, k.AccountName, err)
+				return
+			}
+
+			message.SendUnknownError(w, err)
+			return
+		}
+	}
+
+	k, err = retryUpdate(r.Context(), h.svc, kubeID, func(k *model.Kube) error {
+		if k.State != model.StateDetached {
+			return errNotDetached
+		}
+
+		switch {
+		case acc == nil:
+			if err := h.syncNodes(r.Context(), k); err != nil {
+				return err
+			}
+		case isSyncSupported(k.Provider):
+			if err := h.syncMachines(r.Context(), k, acc); err != nil {
+				return err
+			}
+		default:
+			logrus.Warnf("machines of kube %s of provider %s are restored without sync",
+				k.ID, k.Provider)
+		}
+
+		k.State = model.StateOperational
+		if k.Detachment != nil && k.Detachment.State != "" {
+			k.State = k.Detachment.State
+		}
+		k.Detachment = nil
+
+		return nil
+	})
+
+	if err != nil {
+		switch {
+		case err == errNotDetached:
+			message.SendMessage(w, message.New(fmt.Sprintf("Kube %s is not detached", kubeID),
+				err.Error(), sgerrors.ValidationFailed, ""), http.StatusConflict)
+		case sgerrors.IsInvalidCredentials(err):
+			message.SendInvalidCredentials(w, err)
+		case sgerrors.IsConflict(err):
+			h.sendConflict(w, r, kubeID, err)
+		default:
+			message.SendUnknownError(w, errors.Wrapf(err, "sync machines of kube %s", kubeID))
+		}
+		return
+	}
+
+	logrus.Infof("kube %s has been restored", kubeID)
+
+	if err = json.NewEncoder(w).Encode(k); err != nil {
+		message.SendUnknownError(w, err)
+	}
+}
+
+// checkNoRunningTasks responds with conflict and returns false
+// when tasks of the kube are running.
+func (h *Handler) checkNoRunningTasks(w http.ResponseWriter, r *http.Request, kubeID string) bool {
+	tasks, err := h.getKubeTasks(r.Context(), kubeID)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return false
+		}
+
+		message.SendUnknownError(w, err)
+		return false
+	}
+
+	for _, task := range tasks {
+		if task.Status == statuses.Executing {
+			message.SendMessage(w, message.New(
+				fmt.Sprintf("Kube %s has running tasks", kubeID),
+				fmt.Sprintf("task %s of type %s is running", task.ID, task.Type),
+				sgerrors.ValidationFailed, ""), http.StatusConflict)
+			return false
+		}
+	}
+
+	return true
+}
+
+// PurgeKube deletes record and tasks of the detached kube.
+func (h *Handler) PurgeKube(ctx context.Context, kubeID string) error {
+	return h.cleanUpKube(kubeID, false)
+}
+
+// DetachedKubeCleaner purges detached kubes once their retention
+// period is over.
+type DetachedKubeCleaner struct {
+	svc      Interface
+	purge    KubePurger
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewDetachedKubeCleaner constructs DetachedKubeCleaner.
+func NewDetachedKubeCleaner(svc Interface, purge KubePurger,
+	interval time.Duration) *DetachedKubeCleaner {
+	if interval <= 0 {
+		interval = DefaultDetachedPurgeInterval
+	}
+
+	return &DetachedKubeCleaner{
+		svc:      svc,
+		purge:    purge,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Run purges detached kubes until context is done.
+func (c *DetachedKubeCleaner) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.poll(ctx)
+		}
+	}
+}
+
+func (c *DetachedKubeCleaner) poll(ctx context.Context) {
+	kubes, err := c.svc.ListAll(ctx)
+
+	if err != nil {
+		logrus.Errorf("detached kube cleaner: list kubes %v", err)
+		return
+	}
+
+	for i := range kubes {
+		k := &kubes[i]
+
+		if k.State != model.StateDetached || k.Detachment == nil ||
+			c.now().Unix() < k.Detachment.PurgeAt {
+			continue
+		}
+
+		if err := c.purge(ctx, k.ID); err != nil {
+			logrus.Errorf("detached kube cleaner: purge kube %s %v", k.ID, err)
+			continue
+		}
+
+		logrus.Infof("detached kube %s has been purged", k.ID)
+	}
+}
+
+// deleteDetachedKube purges record of the detached kube at once,
+// its machines are not deleted.
+func (h *Handler) deleteDetachedKube(w http.ResponseWriter, kubeID string, keepTasks bool) {
+	if err := h.cleanUpKube(kubeID, keepTasks); err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
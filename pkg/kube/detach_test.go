@@ -0,0 +1,389 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/testutils"
+	"github.com/supergiant/control/pkg/workflows"
+)
+
+func TestHandler_detachKube(t *testing.T) {
+	testCases := []struct {
+		description string
+		query       string
+
+		kube     *model.Kube
+		kubeErr  error
+		repoData []byte
+
+		expectedCode  int
+		expectedState model.KubeState
+		retention     time.Duration
+	}{
+		{
+			description:  "kube not found",
+			query:        "?mode=detach",
+			kubeErr:      sgerrors.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "wrong mode",
+			query:        "?mode=forget",
+			kube:         &model.Kube{State: model.StateOperational},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "wrong retention",
+			query:        "?mode=detach&retention=week",
+			kube:         &model.Kube{State: model.StateOperational},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description: "task is running",
+			query:       "?mode=detach",
+			kube: &model.Kube{
+				State: model.StateOperational,
+				Tasks: map[string][]string{
+					workflows.NodeTask: {"1234"},
+				},
+			},
+			repoData:     []byte(`{"id":"1234","status":"executing"}`),
+			expectedCode: http.StatusConflict,
+		},
+		{
+			description:  "detached already",
+			query:        "?mode=detach",
+			kube:         &model.Kube{State: model.StateDetached},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			description:  "kube is being deleted",
+			query:        "?mode=detach",
+			kube:         &model.Kube{State: model.StateDeleting},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			description:   "default retention",
+			query:         "?mode=detach",
+			kube:          &model.Kube{State: model.StateOperational},
+			expectedCode:  http.StatusOK,
+			expectedState: model.StateOperational,
+			retention:     DefaultDetachRetention,
+		},
+		{
+			description:   "retention of the request",
+			query:         "?mode=detach&retention=72h",
+			kube:          &model.Kube{State: model.StateFailed},
+			expectedCode:  http.StatusOK,
+			expectedState: model.StateFailed,
+			retention:     72 * time.Hour,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		svc := new(kubeServiceMock)
+		svc.On(serviceGet, mock.Anything, mock.Anything).
+			Return(testCase.kube, testCase.kubeErr)
+		svc.On(serviceUpdate, mock.Anything, mock.Anything).
+			Return(nil)
+
+		repo := &testutils.MockStorage{}
+		repo.On("GetAll", mock.Anything, mock.Anything).
+			Return([][]byte{}, nil)
+		repo.On("Get", mock.Anything, mock.Anything, mock.Anything).
+			Return(testCase.repoData, nil)
+
+		h := Handler{
+			svc:             svc,
+			repo:            repo,
+			metricsCache:    newMetricsCache(DefaultMetricsCacheTTL),
+			detachRetention: DefaultDetachRetention,
+		}
+
+		req, _ := http.NewRequest(http.MethodDelete, "/kubes/test"+testCase.query, nil)
+		rec := httptest.NewRecorder()
+		router := mux.NewRouter()
+
+		router.HandleFunc("/kubes/{kubeID}", h.deleteKube)
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("Wrong status code expected %d actual %d %s",
+				testCase.expectedCode, rec.Code, rec.Body.String())
+			continue
+		}
+
+		if testCase.expectedCode != http.StatusOK {
+			continue
+		}
+
+		k := &model.Kube{}
+		if err := json.NewDecoder(rec.Body).Decode(k); err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if k.State != model.StateDetached || k.Detachment == nil {
+			t.Errorf("Kube must be detached %v", k)
+			continue
+		}
+
+		if k.Detachment.State != testCase.expectedState {
+			t.Errorf("Wrong state to restore expected %s actual %s",
+				testCase.expectedState, k.Detachment.State)
+		}
+
+		if retention := time.Duration(k.Detachment.PurgeAt-k.Detachment.DetachedAt) *
+			time.Second; retention != testCase.retention {
+			t.Errorf("Wrong retention expected %v actual %v", testCase.retention, retention)
+		}
+	}
+}
+
+func TestHandler_deleteDetachedKube(t *testing.T) {
+	svc := new(kubeServiceMock)
+	svc.On(serviceGet, mock.Anything, mock.Anything).
+		Return(&model.Kube{ID: "test", State: model.StateDetached}, nil)
+	svc.On(serviceDelete, mock.Anything, "test").Return(nil)
+
+	provisioner := new(mockNodeProvisioner)
+	provisioner.On("Cancel", mock.Anything).Return(nil)
+
+	h := Handler{
+		svc:             svc,
+		nodeProvisioner: provisioner,
+		metricsCache:    newMetricsCache(DefaultMetricsCacheTTL),
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, "/kubes/test?keepTasks=true", nil)
+	rec := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	router.HandleFunc("/kubes/{kubeID}", h.deleteKube)
+	router.ServeHTTP(rec, req)
+
+	// Record is deleted without running the delete task
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Wrong status code expected %d actual %d", http.StatusNoContent, rec.Code)
+	}
+
+	svc.AssertCalled(t, serviceDelete, mock.Anything, "test")
+}
+
+func TestHandler_restoreKube(t *testing.T) {
+	detached := func(provider clouds.Name, account string) *model.Kube {
+		return &model.Kube{
+			Provider:    provider,
+			AccountName: account,
+			State:       model.StateDetached,
+			Nodes:       map[string]*model.Machine{},
+			Detachment: &model.Detachment{
+				State: model.StateFailed,
+			},
+		}
+	}
+
+	testCases := []struct {
+		description string
+
+		kube    *model.Kube
+		kubeErr error
+
+		account    *model.CloudAccount
+		accountErr error
+
+		syncErr error
+
+		expectedCode  int
+		expectedState model.KubeState
+		expectedNode  string
+	}{
+		{
+			description:  "kube not found",
+			kubeErr:      sgerrors.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "kube is not detached",
+			kube:         &model.Kube{State: model.StateOperational},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			description:  "account not found",
+			kube:         detached(clouds.AWS, "aws"),
+			accountErr:   sgerrors.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "invalid credentials",
+			kube:         detached(clouds.AWS, "aws"),
+			account:      &model.CloudAccount{},
+			syncErr:      errors.Wrap(sgerrors.ErrInvalidCredentials, "sync"),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:   "machines synced",
+			kube:          detached(clouds.AWS, "aws"),
+			account:       &model.CloudAccount{},
+			expectedCode:  http.StatusOK,
+			expectedState: model.StateFailed,
+			expectedNode:  "cloud-node",
+		},
+		{
+			description:   "nodes synced through k8s API without cloud account",
+			kube:          detached(clouds.OpenStack, ""),
+			expectedCode:  http.StatusOK,
+			expectedState: model.StateFailed,
+			expectedNode:  "k8s-node",
+		},
+		{
+			description:   "provider without sync",
+			kube:          detached(clouds.OpenStack, "openstack"),
+			account:       &model.CloudAccount{},
+			expectedCode:  http.StatusOK,
+			expectedState: model.StateFailed,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		svc := new(kubeServiceMock)
+		svc.On(serviceGet, mock.Anything, mock.Anything).
+			Return(testCase.kube, testCase.kubeErr)
+		svc.On(serviceUpdate, mock.Anything, mock.Anything).
+			Return(nil)
+
+		accService := new(accServiceMock)
+		accService.On("Get", mock.Anything, mock.Anything).
+			Return(testCase.account, testCase.accountErr)
+
+		addNode := func(k *model.Kube, name string) error {
+			if testCase.syncErr != nil {
+				return testCase.syncErr
+			}
+
+			k.Nodes[name] = &model.Machine{
+				Name:  name,
+				State: model.MachineStateActive,
+			}
+
+			return nil
+		}
+
+		h := Handler{
+			svc:            svc,
+			accountService: accService,
+			syncMachines: func(ctx context.Context, k *model.Kube, acc *model.CloudAccount) error {
+				return addNode(k, "cloud-node")
+			},
+			syncNodes: func(ctx context.Context, k *model.Kube) error {
+				return addNode(k, "k8s-node")
+			},
+		}
+
+		req, _ := http.NewRequest(http.MethodPost, "/kubes/test/restore", nil)
+		rec := httptest.NewRecorder()
+		router := mux.NewRouter()
+
+		router.HandleFunc("/kubes/{kubeID}/restore", h.restoreKube)
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("Wrong status code expected %d actual %d %s",
+				testCase.expectedCode, rec.Code, rec.Body.String())
+			continue
+		}
+
+		if testCase.expectedCode != http.StatusOK {
+			continue
+		}
+
+		k := &model.Kube{}
+		if err := json.NewDecoder(rec.Body).Decode(k); err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if k.State != testCase.expectedState || k.Detachment != nil {
+			t.Errorf("Kube must be restored to %s actual %s %v",
+				testCase.expectedState, k.State, k.Detachment)
+		}
+
+		if testCase.expectedNode != "" && k.Nodes[testCase.expectedNode] == nil {
+			t.Errorf("Node %s must be synced %v", testCase.expectedNode, k.Nodes)
+		}
+	}
+}
+
+func TestDetachedKubeCleanerPoll(t *testing.T) {
+	now := time.Now()
+
+	svc := new(kubeServiceMock)
+	svc.On(serviceListAll, mock.Anything).Return([]model.Kube{
+		{
+			ID:    "operational",
+			State: model.StateOperational,
+		},
+		{
+			ID:    "retained",
+			State: model.StateDetached,
+			Detachment: &model.Detachment{
+				PurgeAt: now.Add(time.Hour).Unix(),
+			},
+		},
+		{
+			ID:    "expired",
+			State: model.StateDetached,
+			Detachment: &model.Detachment{
+				PurgeAt: now.Add(-time.Hour).Unix(),
+			},
+		},
+		{
+			ID:    "failed",
+			State: model.StateDetached,
+			Detachment: &model.Detachment{
+				PurgeAt: now.Unix(),
+			},
+		},
+	}, nil)
+
+	var purged []string
+
+	c := NewDetachedKubeCleaner(svc, func(ctx context.Context, kubeID string) error {
+		purged = append(purged, kubeID)
+
+		if kubeID == "failed" {
+			return errors.New("purge error")
+		}
+
+		return nil
+	}, 0)
+	c.now = func() time.Time {
+		return now
+	}
+
+	c.poll(context.Background())
+
+	if len(purged) != 2 || purged[0] != "expired" || purged[1] != "failed" {
+		t.Errorf("Expected expired kubes to be purged actual %v", purged)
+	}
+
+	if c.interval != DefaultDetachedPurgeInterval {
+		t.Errorf("Wrong default interval %v", c.interval)
+	}
+}
@@ -0,0 +1,251 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/model"
+)
+
+const (
+	dropletStatusNew     = "new"
+	dropletStatusActive  = "active"
+	dropletStatusOff     = "off"
+	dropletStatusArchive = "archive"
+
+	dropletsPerPage        = 200
+	dropletRetryAttempts   = 5
+	dropletRetryDelay      = time.Second
+	dropletMaxRetryDelay   = time.Second * 30
+	dropletMasterTagFormat = "master-%s"
+)
+
+type dropletLister interface {
+	ListByTag(context.Context, string, *godo.ListOptions) ([]godo.Droplet, *godo.Response, error)
+}
+
+// syncDOMachines reconciles kube machines with droplets tagged
+// with kube id at provision time.
+func syncDOMachines(ctx context.Context, svc dropletLister, k *model.Kube) error {
+	droplets, err := listDroplets(ctx, svc, k.ID, dropletRetryDelay)
+
+	if err != nil {
+		return errors.Wrap(err, "list droplets")
+	}
+
+	masterTag := fmt.Sprintf(dropletMasterTagFormat, k.ID)
+	// Droplets of the cluster by private ip
+	machines := make(map[string]*model.Machine)
+
+	for _, droplet := range droplets {
+		machine := dropletMachine(k, droplet, masterTag)
+
+		if machine.PrivateIp == "" {
+			continue
+		}
+
+		// Archived droplet may share private ip with the one
+		// that has replaced it.
+		if prev := machines[machine.PrivateIp]; prev != nil &&
+			prev.State == model.MachineStateActive {
+			continue
+		}
+
+		machines[machine.PrivateIp] = machine
+	}
+
+	if k.Masters == nil {
+		k.Masters = make(map[string]*model.Machine)
+	}
+
+	if k.Nodes == nil {
+		k.Nodes = make(map[string]*model.Machine)
+	}
+
+	for _, machine := range machines {
+		if machine.State != model.MachineStateActive {
+			continue
+		}
+
+		if isKnownMachine(k.Masters, machine.PrivateIp) || isKnownMachine(k.Nodes, machine.PrivateIp) {
+			continue
+		}
+
+		if machine.Role == model.RoleMaster {
+			logrus.Debugf("Add new master %v", machine)
+			k.Masters[machine.Name] = machine
+			continue
+		}
+
+		logrus.Debugf("Add new node %v", machine)
+		k.Nodes[machine.Name] = machine
+	}
+
+	for name, machine := range k.Nodes {
+		if !isSyncable(machine) {
+			continue
+		}
+
+		droplet := machines[machine.PrivateIp]
+
+		if droplet != nil && droplet.State != model.MachineStateDeleting {
+			syncDropletState(machine, droplet)
+			continue
+		}
+
+		// Give node one more sync before removing it from the model
+		if machine.State == model.MachineStateDeleting {
+			logrus.Infof("Remove node %s gone from DigitalOcean from kube %s", name, k.ID)
+			delete(k.Nodes, name)
+			continue
+		}
+
+		logrus.Infof("Node %s of kube %s is gone from DigitalOcean", name, k.ID)
+		machine.State = model.MachineStateDeleting
+	}
+
+	for name, machine := range k.Masters {
+		if !isSyncable(machine) {
+			continue
+		}
+
+		droplet := machines[machine.PrivateIp]
+
+		if droplet != nil && droplet.State != model.MachineStateDeleting {
+			syncDropletState(machine, droplet)
+			continue
+		}
+
+		logrus.Errorf("Master %s of kube %s is gone from DigitalOcean", name, k.ID)
+		machine.State = model.MachineStateError
+	}
+
+	return nil
+}
+
+// listDroplets returns droplets with the tag from all pages, requests
+// are retried with exponential backoff when API rate limit is exceeded.
+func listDroplets(ctx context.Context, svc dropletLister, tag string,
+	retryDelay time.Duration) ([]godo.Droplet, error) {
+	droplets := make([]godo.Droplet, 0)
+	opts := &godo.ListOptions{
+		Page:    1,
+		PerPage: dropletsPerPage,
+	}
+
+	for {
+		page, resp, err := listDropletsPage(ctx, svc, tag, opts, retryDelay)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "list page %d", opts.Page)
+		}
+
+		droplets = append(droplets, page...)
+
+		if resp == nil || resp.Links == nil || resp.Links.IsLastPage() {
+			return droplets, nil
+		}
+
+		current, err := resp.Links.CurrentPage()
+
+		if err != nil {
+			return nil, errors.Wrap(err, "current page")
+		}
+
+		opts.Page = current + 1
+	}
+}
+
+func listDropletsPage(ctx context.Context, svc dropletLister, tag string,
+	opts *godo.ListOptions, retryDelay time.Duration) ([]godo.Droplet, *godo.Response, error) {
+	for attempt := 1; ; attempt++ {
+		droplets, resp, err := svc.ListByTag(ctx, tag, opts)
+
+		if err == nil || !isRateLimited(resp) || attempt >= dropletRetryAttempts {
+			return droplets, resp, err
+		}
+
+		logrus.Debugf("attempt #%d list droplets is rate limited, retry in %v",
+			attempt, retryDelay)
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(retryDelay):
+		}
+
+		if retryDelay *= 2; retryDelay > dropletMaxRetryDelay {
+			retryDelay = dropletMaxRetryDelay
+		}
+	}
+}
+
+func isRateLimited(resp *godo.Response) bool {
+	return resp != nil && resp.Response != nil &&
+		resp.StatusCode == http.StatusTooManyRequests
+}
+
+func dropletMachine(k *model.Kube, droplet godo.Droplet, masterTag string) *model.Machine {
+	machine := &model.Machine{
+		ID:     strconv.Itoa(droplet.ID),
+		Name:   droplet.Name,
+		Role:   model.RoleNode,
+		State:  dropletMachineState(droplet.Status),
+		Region: k.Region,
+	}
+
+	if droplet.Size != nil {
+		machine.Size = droplet.Size.Slug
+	}
+
+	machine.PrivateIp, _ = droplet.PrivateIPv4()
+	machine.PublicIp, _ = droplet.PublicIPv4()
+
+	for _, tag := range droplet.Tags {
+		if tag == masterTag {
+			machine.Role = model.RoleMaster
+		}
+	}
+
+	return machine
+}
+
+func dropletMachineState(status string) model.MachineState {
+	switch status {
+	case dropletStatusNew:
+		return model.MachineStateBuilding
+	case dropletStatusActive:
+		return model.MachineStateActive
+	case dropletStatusOff:
+		return model.MachineStateError
+	case dropletStatusArchive:
+		return model.MachineStateDeleting
+	}
+
+	return model.MachineStateError
+}
+
+// syncDropletState updates state of active or turned off machine,
+// states of other machines are maintained by workflows.
+func syncDropletState(machine, droplet *model.Machine) {
+	switch machine.State {
+	case model.MachineStateActive, model.MachineStateError:
+	default:
+		return
+	}
+
+	if droplet.State == model.MachineStateBuilding || machine.State == droplet.State {
+		return
+	}
+
+	logrus.Infof("Machine %s state has changed from %s to %s",
+		machine.Name, machine.State, droplet.State)
+	machine.State = droplet.State
+}
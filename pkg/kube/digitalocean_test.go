@@ -0,0 +1,271 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/model"
+)
+
+type fakeDropletLister struct {
+	pages     [][]godo.Droplet
+	responses []*godo.Response
+	errs      []error
+	calls     int
+}
+
+func (f *fakeDropletLister) ListByTag(ctx context.Context, tag string,
+	opts *godo.ListOptions) ([]godo.Droplet, *godo.Response, error) {
+	call := f.calls
+	f.calls++
+
+	if call < len(f.errs) && f.errs[call] != nil {
+		return nil, f.responses[call], f.errs[call]
+	}
+
+	page := opts.Page - 1
+
+	if page >= len(f.pages) {
+		return nil, nil, errors.New("unexpected page")
+	}
+
+	resp := &godo.Response{
+		Links: &godo.Links{},
+	}
+
+	if page > 0 {
+		resp.Links.Pages = &godo.Pages{
+			Prev: dropletsPageURL(page),
+		}
+	}
+
+	if page < len(f.pages)-1 {
+		if resp.Links.Pages == nil {
+			resp.Links.Pages = &godo.Pages{}
+		}
+
+		resp.Links.Pages.Next = dropletsPageURL(page + 2)
+		resp.Links.Pages.Last = dropletsPageURL(len(f.pages))
+	}
+
+	return f.pages[page], resp, nil
+}
+
+func dropletsPageURL(page int) string {
+	return fmt.Sprintf("https://api.digitalocean.com/v2/droplets?page=%d", page)
+}
+
+func droplet(name, privateIP, status string, tags ...string) godo.Droplet {
+	return godo.Droplet{
+		Name:   name,
+		Status: status,
+		Tags:   tags,
+		Networks: &godo.Networks{
+			V4: []godo.NetworkV4{
+				{
+					IPAddress: privateIP,
+					Type:      "private",
+				},
+			},
+		},
+	}
+}
+
+func TestSyncDOMachinesPages(t *testing.T) {
+	lister := &fakeDropletLister{
+		pages: [][]godo.Droplet{
+			{
+				droplet("node-1", "10.0.0.1", dropletStatusActive),
+				droplet("node-2", "10.0.0.2", dropletStatusActive),
+			},
+			{
+				droplet("node-3", "10.0.0.3", dropletStatusActive),
+				droplet("master-1", "10.0.1.1", dropletStatusActive, "master-kube-id"),
+			},
+		},
+	}
+
+	k := &model.Kube{
+		ID: "kube-id",
+	}
+
+	if err := syncDOMachines(context.Background(), lister, k); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	for _, name := range []string{"node-1", "node-2", "node-3"} {
+		if k.Nodes[name] == nil {
+			t.Errorf("Node %s must be synced", name)
+		}
+	}
+
+	if k.Masters["master-1"] == nil {
+		t.Errorf("Master must be synced")
+	}
+}
+
+func TestSyncDOMachinesGoneDroplets(t *testing.T) {
+	lister := &fakeDropletLister{
+		pages: [][]godo.Droplet{
+			{
+				droplet("running", "10.0.0.1", dropletStatusActive),
+				droplet("off", "10.0.0.2", dropletStatusOff),
+				droplet("archived", "10.0.0.3", dropletStatusArchive),
+				droplet("new", "10.0.0.5", dropletStatusNew),
+			},
+		},
+	}
+
+	k := &model.Kube{
+		Masters: map[string]*model.Machine{
+			"master": {
+				Name:      "master",
+				PrivateIp: "10.0.1.1",
+				State:     model.MachineStateActive,
+			},
+		},
+		Nodes: map[string]*model.Machine{
+			"running": {
+				Name:      "running",
+				PrivateIp: "10.0.0.1",
+				State:     model.MachineStateError,
+			},
+			"off": {
+				Name:      "off",
+				PrivateIp: "10.0.0.2",
+				State:     model.MachineStateActive,
+			},
+			"archived": {
+				Name:      "archived",
+				PrivateIp: "10.0.0.3",
+				State:     model.MachineStateActive,
+			},
+			"missing": {
+				Name:      "missing",
+				PrivateIp: "10.0.0.4",
+				State:     model.MachineStateActive,
+			},
+			"provisioning": {
+				Name:  "provisioning",
+				State: model.MachineStateProvisioning,
+			},
+		},
+	}
+
+	if err := syncDOMachines(context.Background(), lister, k); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := map[string]model.MachineState{
+		"running":      model.MachineStateActive,
+		"off":          model.MachineStateError,
+		"archived":     model.MachineStateDeleting,
+		"missing":      model.MachineStateDeleting,
+		"provisioning": model.MachineStateProvisioning,
+	}
+
+	for name, state := range expected {
+		if k.Nodes[name] == nil || k.Nodes[name].State != state {
+			t.Errorf("Wrong state of node %s expected %s actual %v",
+				name, state, k.Nodes[name])
+		}
+	}
+
+	if k.Nodes["new"] != nil {
+		t.Errorf("Droplet that is being created must not be synced")
+	}
+
+	if k.Masters["master"].State != model.MachineStateError {
+		t.Errorf("Wrong state of master expected %s actual %s",
+			model.MachineStateError, k.Masters["master"].State)
+	}
+
+	// Nodes are removed on the next sync
+	lister.calls = 0
+
+	if err := syncDOMachines(context.Background(), lister, k); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	for _, name := range []string{"archived", "missing"} {
+		if k.Nodes[name] != nil {
+			t.Errorf("Node %s must be removed", name)
+		}
+	}
+}
+
+func TestListDropletsRateLimit(t *testing.T) {
+	rateLimited := &godo.Response{
+		Response: &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+		},
+	}
+
+	testCases := []struct {
+		description string
+
+		responses []*godo.Response
+		errs      []error
+
+		calls int
+		isErr bool
+	}{
+		{
+			description: "retry rate limited",
+			responses:   []*godo.Response{rateLimited, rateLimited},
+			errs:        []error{errors.New("limit"), errors.New("limit")},
+			calls:       3,
+		},
+		{
+			description: "retries exceeded",
+			responses: []*godo.Response{rateLimited, rateLimited, rateLimited,
+				rateLimited, rateLimited},
+			errs: []error{errors.New("limit"), errors.New("limit"), errors.New("limit"),
+				errors.New("limit"), errors.New("limit")},
+			calls: dropletRetryAttempts,
+			isErr: true,
+		},
+		{
+			description: "not rate limited",
+			responses:   []*godo.Response{nil},
+			errs:        []error{errors.New("unauthorized")},
+			calls:       1,
+			isErr:       true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		lister := &fakeDropletLister{
+			pages: [][]godo.Droplet{
+				{
+					droplet("node-1", "10.0.0.1", dropletStatusActive),
+				},
+			},
+			responses: testCase.responses,
+			errs:      testCase.errs,
+		}
+
+		droplets, err := listDroplets(context.Background(), lister,
+			"kube-id", time.Millisecond)
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("Unexpected error %v", err)
+		}
+
+		if !testCase.isErr && len(droplets) != 1 {
+			t.Errorf("Wrong droplets count expected 1 actual %d", len(droplets))
+		}
+
+		if lister.calls != testCase.calls {
+			t.Errorf("Wrong count of calls expected %d actual %d",
+				testCase.calls, lister.calls)
+		}
+	}
+}
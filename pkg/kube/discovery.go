@@ -0,0 +1,150 @@
+package kube
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	clientcmddapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/supergiant/control/pkg/model"
+)
+
+// DefaultDiscoveryTTL is how long discovered versions of a kube are
+// served from cache before API server of the kube is asked again.
+const DefaultDiscoveryTTL = time.Minute
+
+// DiscoveryResult holds versions and monitoring status discovered from
+// API server of a kube, results of the last successful discovery are
+// kept on error.
+type DiscoveryResult struct {
+	ServerVersion string                  `json:"serverVersion"`
+	HelmVersion   string                  `json:"helmVersion"`
+	Monitoring    *model.MonitoringStatus `json:"monitoring,omitempty"`
+	LastSeen      time.Time               `json:"lastSeen"`
+	CheckedAt     time.Time               `json:"checkedAt"`
+	Err           error                   `json:"-"`
+}
+
+// discoveryCall is a discovery in progress, concurrent discoveries
+// of the same kube wait for it instead of asking API server again.
+type discoveryCall struct {
+	done   chan struct{}
+	result DiscoveryResult
+}
+
+// discoveryCache caches discovery results per kube, errors are cached
+// too so unreachable kubes are not asked on every request.
+type discoveryCache struct {
+	m       sync.Mutex
+	ttl     time.Duration
+	results map[string]DiscoveryResult
+	calls   map[string]*discoveryCall
+
+	discoverK8SVersion  func(ctx context.Context, kubeConfig *clientcmddapi.Config) (string, error)
+	discoverHelmVersion func(ctx context.Context, kubeConfig *clientcmddapi.Config) (string, error)
+	discoverMonitoring  func(ctx context.Context, kubeConfig *clientcmddapi.Config) (model.MonitoringStatus, error)
+}
+
+func newDiscoveryCache(ttl time.Duration) *discoveryCache {
+	return &discoveryCache{
+		ttl:                 ttl,
+		results:             make(map[string]DiscoveryResult),
+		calls:               make(map[string]*discoveryCall),
+		discoverK8SVersion:  discoverK8SVersion,
+		discoverHelmVersion: discoverHelmVersion,
+		discoverMonitoring:  discoverMonitoring,
+	}
+}
+
+func (c *discoveryCache) setTTL(ttl time.Duration) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.ttl = ttl
+}
+
+// get returns cached result of the kube unless it is expired or force
+// is set, waiting callers give up when their context is done.
+func (c *discoveryCache) get(ctx context.Context, kubeID string,
+	kubeConfig *clientcmddapi.Config, force bool) DiscoveryResult {
+	c.m.Lock()
+
+	prev, ok := c.results[kubeID]
+
+	if ok && !force && time.Since(prev.CheckedAt) < c.ttl {
+		c.m.Unlock()
+		return prev
+	}
+
+	if call, ok := c.calls[kubeID]; ok {
+		c.m.Unlock()
+
+		select {
+		case <-call.done:
+			return call.result
+		case <-ctx.Done():
+			prev.Err = ctx.Err()
+			return prev
+		}
+	}
+
+	call := &discoveryCall{done: make(chan struct{})}
+	c.calls[kubeID] = call
+	c.m.Unlock()
+
+	call.result = c.discover(ctx, kubeID, kubeConfig, prev)
+
+	c.m.Lock()
+	c.results[kubeID] = call.result
+	delete(c.calls, kubeID)
+	c.m.Unlock()
+
+	close(call.done)
+
+	return call.result
+}
+
+func (c *discoveryCache) discover(ctx context.Context, kubeID string,
+	kubeConfig *clientcmddapi.Config, prev DiscoveryResult) DiscoveryResult {
+	result := prev
+	result.CheckedAt = time.Now()
+	result.Err = nil
+
+	serverVersion, err := c.discoverK8SVersion(ctx, kubeConfig)
+
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.ServerVersion = serverVersion
+	result.LastSeen = result.CheckedAt
+
+	helmVersion, err := c.discoverHelmVersion(ctx, kubeConfig)
+
+	if err != nil {
+		logrus.Warnf("discover helm version of kube %s: %v", kubeID, err)
+	} else {
+		result.HelmVersion = helmVersion
+	}
+
+	monitoring, err := c.discoverMonitoring(ctx, kubeConfig)
+
+	if err != nil {
+		logrus.Warnf("discover monitoring of kube %s: %v", kubeID, err)
+	} else {
+		result.Monitoring = &monitoring
+	}
+
+	return result
+}
+
+// forget removes result of the kube, discovery in progress is not affected.
+func (c *discoveryCache) forget(kubeID string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	delete(c.results, kubeID)
+}
@@ -0,0 +1,110 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	clientcmddapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/supergiant/control/pkg/model"
+)
+
+func TestDiscoveryCacheGet(t *testing.T) {
+	var calls int32
+	var discoverErr error
+
+	c := newDiscoveryCache(time.Hour)
+	c.discoverK8SVersion = func(ctx context.Context, kubeConfig *clientcmddapi.Config) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "1.15.1", discoverErr
+	}
+	c.discoverHelmVersion = func(ctx context.Context, kubeConfig *clientcmddapi.Config) (string, error) {
+		return Helm3, nil
+	}
+	c.discoverMonitoring = func(ctx context.Context, kubeConfig *clientcmddapi.Config) (model.MonitoringStatus, error) {
+		return model.MonitoringStatus{State: model.MonitoringInstalled}, nil
+	}
+
+	result := c.get(context.Background(), "kube", &clientcmddapi.Config{}, false)
+
+	if result.Err != nil || result.ServerVersion != "1.15.1" || result.HelmVersion != Helm3 {
+		t.Errorf("Wrong result %+v", result)
+	}
+
+	if result.Monitoring == nil || result.Monitoring.State != model.MonitoringInstalled {
+		t.Errorf("Wrong monitoring status %+v", result.Monitoring)
+	}
+
+	c.get(context.Background(), "kube", &clientcmddapi.Config{}, false)
+
+	if calls != 1 {
+		t.Errorf("Cached result must be used, discovery calls %d", calls)
+	}
+
+	discoverErr = errors.New("unreachable")
+	result = c.get(context.Background(), "kube", &clientcmddapi.Config{}, true)
+
+	if calls != 2 {
+		t.Errorf("Forced refresh must discover, discovery calls %d", calls)
+	}
+
+	if result.Err == nil || result.ServerVersion != "1.15.1" || result.LastSeen.IsZero() {
+		t.Errorf("Error must keep previous versions %+v", result)
+	}
+
+	c.forget("kube")
+	c.setTTL(0)
+	discoverErr = nil
+	result = c.get(context.Background(), "kube", &clientcmddapi.Config{}, false)
+
+	if calls != 3 || result.Err != nil {
+		t.Errorf("Expired result must be discovered, discovery calls %d error %v",
+			calls, result.Err)
+	}
+}
+
+func TestDiscoveryCacheConcurrent(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+
+	c := newDiscoveryCache(time.Hour)
+	c.discoverK8SVersion = func(ctx context.Context, kubeConfig *clientcmddapi.Config) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "1.15.1", nil
+	}
+	c.discoverHelmVersion = func(ctx context.Context, kubeConfig *clientcmddapi.Config) (string, error) {
+		return HelmNotInstalled, nil
+	}
+	c.discoverMonitoring = func(ctx context.Context, kubeConfig *clientcmddapi.Config) (model.MonitoringStatus, error) {
+		return model.MonitoringStatus{State: model.MonitoringMissing}, nil
+	}
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result := c.get(context.Background(), "kube", &clientcmddapi.Config{}, true)
+
+			if result.ServerVersion != "1.15.1" {
+				t.Errorf("Wrong server version %s", result.ServerVersion)
+			}
+		}()
+	}
+
+	// Let callers join the discovery in progress
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("Concurrent discoveries must be deduplicated, discovery calls %d", calls)
+	}
+}
@@ -0,0 +1,382 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows/steps"
+	"github.com/supergiant/control/pkg/workflows/steps/amazon"
+)
+
+const (
+	hoursPerMonth = 730
+	priceCacheTTL = 24 * time.Hour
+
+	CostComponentMaster = "master"
+	CostComponentNode   = "node"
+	CostComponentVolume = "volume"
+)
+
+// Static price tables are rough, GCE prices are list prices of us-central1
+// and EBS prices are list prices of us-east-1.
+var (
+	gceHourlyPrices = map[string]float64{
+		"f1-micro":       0.0076,
+		"g1-small":       0.0257,
+		"n1-standard-1":  0.0475,
+		"n1-standard-2":  0.095,
+		"n1-standard-4":  0.19,
+		"n1-standard-8":  0.38,
+		"n1-standard-16": 0.76,
+		"n1-highmem-2":   0.1184,
+		"n1-highmem-4":   0.2368,
+		"n1-highmem-8":   0.4736,
+		"n1-highcpu-2":   0.0709,
+		"n1-highcpu-4":   0.1418,
+		"n1-highcpu-8":   0.2836,
+	}
+
+	doMonthlyPrices = map[string]float64{
+		"s-1vcpu-1gb":  5,
+		"s-1vcpu-2gb":  10,
+		"s-1vcpu-3gb":  15,
+		"s-2vcpu-2gb":  15,
+		"s-2vcpu-4gb":  20,
+		"s-3vcpu-1gb":  15,
+		"s-4vcpu-8gb":  40,
+		"s-6vcpu-16gb": 80,
+		"s-8vcpu-32gb": 160,
+		"1gb":          5,
+		"2gb":          10,
+		"4gb":          20,
+		"8gb":          40,
+		"16gb":         80,
+	}
+
+	// Price of GB-month
+	ebsMonthlyPrices = map[string]float64{
+		ec2.VolumeTypeStandard: 0.05,
+		ec2.VolumeTypeGp2:      0.1,
+		amazon.VolumeTypeGp3:   0.08,
+		ec2.VolumeTypeIo1:      0.125,
+		amazon.VolumeTypeIo2:   0.125,
+		ec2.VolumeTypeSt1:      0.045,
+		ec2.VolumeTypeSc1:      0.025,
+	}
+)
+
+// EstimateRequest is a profile to estimate, account is used
+// to get prices from AWS.
+type EstimateRequest struct {
+	CloudAccountName string          `json:"cloudAccountName"`
+	Profile          profile.Profile `json:"profile"`
+	// Spot adds estimate of worker nodes launched as spot instances.
+	Spot bool `json:"spot"`
+}
+
+// CostItem is monthly cost of machines or volumes of the same type,
+// unknown items are not included in totals.
+type CostItem struct {
+	Component string `json:"component"`
+	Type      string `json:"type"`
+	Count     int64  `json:"count"`
+	// Size of a volume in GB
+	Size            int64   `json:"size,omitempty"`
+	MonthlyCost     float64 `json:"monthlyCost"`
+	SpotMonthlyCost float64 `json:"spotMonthlyCost,omitempty"`
+	Unknown         bool    `json:"unknown,omitempty"`
+	Reason          string  `json:"reason,omitempty"`
+}
+
+// CostEstimate is estimated monthly cost of the cluster in USD.
+type CostEstimate struct {
+	Items        []*CostItem `json:"items"`
+	MonthlyTotal float64     `json:"monthlyTotal"`
+	// SpotMonthlyTotal is the total when worker nodes are spot instances.
+	SpotMonthlyTotal float64 `json:"spotMonthlyTotal,omitempty"`
+	HasUnknown       bool    `json:"hasUnknown"`
+}
+
+type cachedPrice struct {
+	price   float64
+	expires time.Time
+}
+
+// CostEstimator estimates cost of profiles, prices obtained
+// from cloud APIs are cached.
+type CostEstimator struct {
+	onDemandPrice func(machineType string, config *steps.Config) (float64, error)
+	spotPrices    func(ctx context.Context, machineType, az string,
+		config *steps.Config) (map[string][]SpotPricePoint, error)
+	now func() time.Time
+
+	m     sync.Mutex
+	cache map[string]cachedPrice
+}
+
+func NewCostEstimator() *CostEstimator {
+	return &CostEstimator{
+		onDemandPrice: getAwsOnDemandPrice,
+		spotPrices:    getSpotPrices,
+		now:           time.Now,
+		cache:         make(map[string]cachedPrice),
+	}
+}
+
+// Estimate returns monthly cost of masters, nodes and node pools of the
+// profile, config must have region and credentials of AWS account.
+func (e *CostEstimator) Estimate(ctx context.Context, p *profile.Profile, spot bool,
+	config *steps.Config) (*CostEstimate, error) {
+	switch p.Provider {
+	case clouds.AWS, clouds.GCE, clouds.DigitalOcean:
+	default:
+		return nil, errors.Wrapf(sgerrors.ErrUnsupportedProvider,
+			"estimate cost of %s", p.Provider)
+	}
+
+	nodeProfiles := append(append([]profile.NodeProfile{}, p.NodesProfiles...),
+		profile.PoolNodeProfiles(p.NodePools)...)
+
+	items := make([]*CostItem, 0)
+	items = append(items, groupMachines(CostComponentMaster, p.MasterProfiles)...)
+	items = append(items, groupMachines(CostComponentNode, nodeProfiles)...)
+
+	for _, item := range items {
+		e.priceMachines(ctx, item, p.Provider, spot, config)
+	}
+
+	if p.Provider == clouds.AWS {
+		volumes := groupVolumes(append(append([]profile.NodeProfile{},
+			p.MasterProfiles...), nodeProfiles...))
+
+		for _, item := range volumes {
+			priceVolumes(item)
+		}
+
+		items = append(items, volumes...)
+	}
+
+	estimate := &CostEstimate{
+		Items: items,
+	}
+
+	for _, item := range items {
+		if item.Unknown {
+			estimate.HasUnknown = true
+			continue
+		}
+
+		estimate.MonthlyTotal += item.MonthlyCost
+
+		if spot {
+			if item.SpotMonthlyCost > 0 {
+				estimate.SpotMonthlyTotal += item.SpotMonthlyCost
+			} else {
+				estimate.SpotMonthlyTotal += item.MonthlyCost
+			}
+		}
+	}
+
+	estimate.MonthlyTotal = roundCost(estimate.MonthlyTotal)
+	estimate.SpotMonthlyTotal = roundCost(estimate.SpotMonthlyTotal)
+
+	return estimate, nil
+}
+
+func (e *CostEstimator) priceMachines(ctx context.Context, item *CostItem,
+	provider clouds.Name, spot bool, config *steps.Config) {
+	if item.Type == "" {
+		item.Unknown = true
+		item.Reason = "machine type is not set"
+		return
+	}
+
+	hourly, err := e.machinePrice(provider, item.Type, config)
+
+	if err != nil {
+		item.Unknown = true
+		item.Reason = err.Error()
+		return
+	}
+
+	item.MonthlyCost = roundCost(hourly * hoursPerMonth * float64(item.Count))
+
+	if !spot || item.Component != CostComponentNode || provider != clouds.AWS {
+		return
+	}
+
+	spotHourly, err := e.spotPrice(ctx, item.Type, config)
+
+	if err != nil {
+		item.Reason = fmt.Sprintf("on-demand price is used for spot: %v", err)
+		item.SpotMonthlyCost = item.MonthlyCost
+		return
+	}
+
+	item.SpotMonthlyCost = roundCost(spotHourly * hoursPerMonth * float64(item.Count))
+}
+
+// machinePrice returns hourly on-demand price of the machine type.
+func (e *CostEstimator) machinePrice(provider clouds.Name, machineType string,
+	config *steps.Config) (float64, error) {
+	switch provider {
+	case clouds.AWS:
+		key := fmt.Sprintf("%s/%s/%s", provider, config.AWSConfig.Region, machineType)
+
+		return e.cached(key, func() (float64, error) {
+			return e.onDemandPrice(machineType, config)
+		})
+	case clouds.GCE:
+		if price, ok := gceHourlyPrices[machineType]; ok {
+			return price, nil
+		}
+	case clouds.DigitalOcean:
+		if price, ok := doMonthlyPrices[machineType]; ok {
+			return price / hoursPerMonth, nil
+		}
+	}
+
+	return 0, errors.Wrapf(sgerrors.ErrNotFound, "price of %s", machineType)
+}
+
+// spotPrice returns median of spot price history of the machine
+// type in all zones of the region.
+func (e *CostEstimator) spotPrice(ctx context.Context, machineType string,
+	config *steps.Config) (float64, error) {
+	key := fmt.Sprintf("spot/%s/%s", config.AWSConfig.Region, machineType)
+
+	return e.cached(key, func() (float64, error) {
+		prices, err := e.spotPrices(ctx, machineType, "", config)
+
+		if err != nil {
+			return 0, err
+		}
+
+		recommendation, err := recommendFromPrices(prices)
+
+		if err != nil {
+			return 0, err
+		}
+
+		return recommendation.P50, nil
+	})
+}
+
+// cached returns price by key, failed lookups are not cached.
+func (e *CostEstimator) cached(key string, fn func() (float64, error)) (float64, error) {
+	e.m.Lock()
+	entry, ok := e.cache[key]
+	e.m.Unlock()
+
+	if ok && e.now().Before(entry.expires) {
+		return entry.price, nil
+	}
+
+	price, err := fn()
+
+	if err != nil {
+		return 0, err
+	}
+
+	e.m.Lock()
+	e.cache[key] = cachedPrice{
+		price:   price,
+		expires: e.now().Add(priceCacheTTL),
+	}
+	e.m.Unlock()
+
+	return price, nil
+}
+
+// groupMachines counts node profiles of the same machine type.
+func groupMachines(component string, nodeProfiles []profile.NodeProfile) []*CostItem {
+	items := make([]*CostItem, 0)
+	byType := make(map[string]*CostItem)
+
+	for _, nodeProfile := range nodeProfiles {
+		machineType := nodeProfile["size"]
+		item, ok := byType[machineType]
+
+		if !ok {
+			item = &CostItem{
+				Component: component,
+				Type:      machineType,
+			}
+			byType[machineType] = item
+			items = append(items, item)
+		}
+
+		item.Count++
+	}
+
+	return items
+}
+
+// groupVolumes counts root volumes of the same type and size.
+func groupVolumes(nodeProfiles []profile.NodeProfile) []*CostItem {
+	items := make([]*CostItem, 0)
+	byKey := make(map[string]*CostItem)
+
+	for _, nodeProfile := range nodeProfiles {
+		volumeType := nodeProfile["volumeType"]
+
+		if volumeType == "" {
+			volumeType = ec2.VolumeTypeGp2
+		}
+
+		key := volumeType + "/" + nodeProfile["volumeSize"]
+		item, ok := byKey[key]
+
+		if !ok {
+			item = &CostItem{
+				Component: CostComponentVolume,
+				Type:      volumeType,
+			}
+
+			size, err := strconv.ParseInt(nodeProfile["volumeSize"], 10, 64)
+
+			if err != nil || size <= 0 {
+				item.Unknown = true
+				item.Reason = fmt.Sprintf("volume size %q is not known", nodeProfile["volumeSize"])
+			}
+
+			item.Size = size
+			byKey[key] = item
+			items = append(items, item)
+		}
+
+		item.Count++
+	}
+
+	return items
+}
+
+func priceVolumes(item *CostItem) {
+	if item.Unknown {
+		return
+	}
+
+	price, ok := ebsMonthlyPrices[item.Type]
+
+	if !ok {
+		item.Unknown = true
+		item.Reason = fmt.Sprintf("price of %s volumes is not known", item.Type)
+		return
+	}
+
+	item.MonthlyCost = roundCost(price * float64(item.Size*item.Count))
+}
+
+func roundCost(cost float64) float64 {
+	return math.Round(cost*100) / 100
+}
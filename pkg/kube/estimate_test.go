@@ -0,0 +1,334 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+func testEstimator(onDemandCalls, spotCalls *int) *CostEstimator {
+	e := NewCostEstimator()
+	e.onDemandPrice = func(machineType string, config *steps.Config) (float64, error) {
+		*onDemandCalls++
+
+		switch machineType {
+		case "m4.large":
+			return 0.1, nil
+		case "c5.xlarge":
+			return 0.2, nil
+		}
+
+		return 0, errors.Wrapf(sgerrors.ErrNotFound, "on-demand price for %s", machineType)
+	}
+	e.spotPrices = func(ctx context.Context, machineType, az string,
+		config *steps.Config) (map[string][]SpotPricePoint, error) {
+		*spotCalls++
+
+		if machineType != "m4.large" {
+			return nil, nil
+		}
+
+		return map[string][]SpotPricePoint{
+			"us-east-1a": {{Price: 0.03}, {Price: 0.04}, {Price: 0.05}},
+		}, nil
+	}
+
+	return e
+}
+
+func findCostItem(estimate *CostEstimate, component, itemType string) *CostItem {
+	for _, item := range estimate.Items {
+		if item.Component == component && item.Type == itemType {
+			return item
+		}
+	}
+
+	return nil
+}
+
+func TestCostEstimatorEstimateAWS(t *testing.T) {
+	var onDemandCalls, spotCalls int
+	e := testEstimator(&onDemandCalls, &spotCalls)
+
+	p := &profile.Profile{
+		Provider: clouds.AWS,
+		Region:   "us-east-1",
+		MasterProfiles: []profile.NodeProfile{
+			{"size": "m4.large", "volumeSize": "50"},
+		},
+		NodesProfiles: []profile.NodeProfile{
+			{"size": "m4.large", "volumeSize": "50"},
+			{"size": "m4.large", "volumeSize": "50"},
+			{"size": "x9.unknown", "volumeSize": "50"},
+		},
+		NodePools: []profile.NodePool{
+			{Name: "compute", MachineType: "c5.xlarge", Count: 1},
+		},
+	}
+
+	estimate, err := e.Estimate(context.Background(), p, true, &steps.Config{})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	master := findCostItem(estimate, CostComponentMaster, "m4.large")
+
+	if master == nil || master.Count != 1 || master.MonthlyCost != 73 || master.SpotMonthlyCost != 0 {
+		t.Errorf("Wrong master item %v", master)
+	}
+
+	node := findCostItem(estimate, CostComponentNode, "m4.large")
+
+	if node == nil || node.Count != 2 || node.MonthlyCost != 146 || node.SpotMonthlyCost != 58.4 {
+		t.Errorf("Wrong node item %v", node)
+	}
+
+	compute := findCostItem(estimate, CostComponentNode, "c5.xlarge")
+
+	if compute == nil || compute.MonthlyCost != 146 || compute.SpotMonthlyCost != 146 || compute.Reason == "" {
+		t.Errorf("Spot estimate of machine without spot history must use on-demand price %v", compute)
+	}
+
+	unknown := findCostItem(estimate, CostComponentNode, "x9.unknown")
+
+	if unknown == nil || !unknown.Unknown || unknown.Reason == "" {
+		t.Errorf("Wrong unknown item %v", unknown)
+	}
+
+	volume := findCostItem(estimate, CostComponentVolume, "gp2")
+
+	if volume == nil || volume.Count != 4 || volume.Size != 50 || volume.MonthlyCost != 20 {
+		t.Errorf("Wrong volume item %v", volume)
+	}
+
+	// Volume of the pool node has no size, it is unknown
+	if !estimate.HasUnknown {
+		t.Errorf("Estimate must have unknown items")
+	}
+
+	if estimate.MonthlyTotal != 385 {
+		t.Errorf("Wrong monthly total %v", estimate.MonthlyTotal)
+	}
+
+	if estimate.SpotMonthlyTotal != 297.4 {
+		t.Errorf("Wrong spot monthly total %v", estimate.SpotMonthlyTotal)
+	}
+}
+
+func TestCostEstimatorEstimateStaticPrices(t *testing.T) {
+	testCases := []struct {
+		description string
+		profile     *profile.Profile
+
+		expectedTotal float64
+		expectedErr   error
+	}{
+		{
+			description: "gce",
+			profile: &profile.Profile{
+				Provider: clouds.GCE,
+				MasterProfiles: []profile.NodeProfile{
+					{"size": "n1-standard-2"},
+				},
+				NodesProfiles: []profile.NodeProfile{
+					{"size": "n1-standard-1"},
+				},
+			},
+			expectedTotal: 104.02,
+		},
+		{
+			description: "digitalocean",
+			profile: &profile.Profile{
+				Provider: clouds.DigitalOcean,
+				MasterProfiles: []profile.NodeProfile{
+					{"size": "s-2vcpu-4gb"},
+				},
+				NodesProfiles: []profile.NodeProfile{
+					{"size": "s-1vcpu-2gb"},
+					{"size": "s-1vcpu-2gb"},
+				},
+			},
+			expectedTotal: 40,
+		},
+		{
+			description: "unsupported provider",
+			profile: &profile.Profile{
+				Provider: clouds.OpenStack,
+			},
+			expectedErr: sgerrors.ErrUnsupportedProvider,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		var onDemandCalls, spotCalls int
+		e := testEstimator(&onDemandCalls, &spotCalls)
+
+		estimate, err := e.Estimate(context.Background(), testCase.profile, true, &steps.Config{})
+
+		if errors.Cause(err) != testCase.expectedErr {
+			t.Errorf("Wrong error expected %v actual %v", testCase.expectedErr, err)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if estimate.MonthlyTotal != testCase.expectedTotal {
+			t.Errorf("Wrong monthly total expected %v actual %v",
+				testCase.expectedTotal, estimate.MonthlyTotal)
+		}
+
+		if onDemandCalls != 0 || spotCalls != 0 {
+			t.Errorf("Static prices must not call AWS")
+		}
+	}
+}
+
+func TestCostEstimatorCache(t *testing.T) {
+	var onDemandCalls, spotCalls int
+	now := time.Now()
+
+	e := testEstimator(&onDemandCalls, &spotCalls)
+	e.now = func() time.Time {
+		return now
+	}
+
+	config := &steps.Config{
+		AWSConfig: steps.AWSConfig{Region: "us-east-1"},
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := e.machinePrice(clouds.AWS, "m4.large", config); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	if onDemandCalls != 1 {
+		t.Errorf("Price must be cached, calls %d", onDemandCalls)
+	}
+
+	// Failed lookups are not cached
+	for i := 0; i < 2; i++ {
+		e.machinePrice(clouds.AWS, "x9.unknown", config)
+	}
+
+	if onDemandCalls != 3 {
+		t.Errorf("Failed lookup must not be cached, calls %d", onDemandCalls)
+	}
+
+	now = now.Add(priceCacheTTL)
+	e.machinePrice(clouds.AWS, "m4.large", config)
+
+	if onDemandCalls != 4 {
+		t.Errorf("Expired price must be looked up again, calls %d", onDemandCalls)
+	}
+
+	config.AWSConfig.Region = "eu-west-1"
+	e.machinePrice(clouds.AWS, "m4.large", config)
+
+	if onDemandCalls != 5 {
+		t.Errorf("Price must be cached per region, calls %d", onDemandCalls)
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	testCases := []struct {
+		description string
+		body        string
+		accountErr  error
+
+		expectedCode  int
+		expectedTotal float64
+	}{
+		{
+			description:  "invalid json",
+			body:         "{",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "account not found",
+			body:         `{"cloudAccountName":"aws","profile":{"provider":"aws"}}`,
+			accountErr:   sgerrors.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "unsupported provider",
+			body:         `{"profile":{"provider":"openstack"}}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description: "success",
+			body: `{"cloudAccountName":"aws","profile":{"provider":"aws","region":"us-east-1",` +
+				`"masterProfiles":[{"size":"m4.large"}]}}`,
+			expectedCode:  http.StatusOK,
+			expectedTotal: 73,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		accService := new(accServiceMock)
+		accService.On("Get", mock.Anything, mock.Anything).
+			Return(&model.CloudAccount{
+				Name:     "aws",
+				Provider: clouds.AWS,
+				Credentials: map[string]string{
+					"access_key": "key",
+					"secret_key": "secret",
+				},
+			}, testCase.accountErr)
+
+		var onDemandCalls, spotCalls int
+		h := Handler{
+			accountService: accService,
+			costEstimator:  testEstimator(&onDemandCalls, &spotCalls),
+		}
+
+		req, _ := http.NewRequest(http.MethodPost, "/kubeprofiles/estimate",
+			bytes.NewBufferString(testCase.body))
+		rec := httptest.NewRecorder()
+		router := mux.NewRouter()
+
+		router.HandleFunc("/kubeprofiles/estimate", h.estimateCost)
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("Wrong status code expected %d actual %d",
+				testCase.expectedCode, rec.Code)
+			continue
+		}
+
+		if testCase.expectedCode != http.StatusOK {
+			continue
+		}
+
+		estimate := &CostEstimate{}
+
+		if err := json.NewDecoder(rec.Body).Decode(estimate); err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		// Volume of the image size is unknown
+		if estimate.MonthlyTotal != testCase.expectedTotal || !estimate.HasUnknown {
+			t.Errorf("Wrong estimate %v", estimate)
+		}
+	}
+}
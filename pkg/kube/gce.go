@@ -0,0 +1,201 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/api/compute/v1"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/util"
+)
+
+const (
+	gceStatusRunning = "RUNNING"
+	gceRoleMetadata  = "Role"
+)
+
+type gceInstanceLister interface {
+	ListInstancesPages(ctx context.Context, zone, filter string,
+		fn func(*compute.InstanceList) error) error
+}
+
+type gceInstances struct {
+	svc       *compute.Service
+	projectID string
+}
+
+func (g *gceInstances) ListInstancesPages(ctx context.Context, zone, filter string,
+	fn func(*compute.InstanceList) error) error {
+	return g.svc.Instances.List(g.projectID, zone).Filter(filter).Pages(ctx, fn)
+}
+
+// syncGCEMachines reconciles kube machines with instances labeled
+// with kube id in all zones of the kube.
+func syncGCEMachines(ctx context.Context, svc gceInstanceLister, k *model.Kube) error {
+	filter := fmt.Sprintf("labels.%s = %s", clouds.LabelClusterID, k.ID)
+	// Instances of the cluster by private ip
+	instances := make(map[string]*compute.Instance)
+
+	for _, zone := range gceZones(k) {
+		err := svc.ListInstancesPages(ctx, zone, filter, func(page *compute.InstanceList) error {
+			for _, instance := range page.Items {
+				privateIP := gceInstancePrivateIP(instance)
+
+				if privateIP == "" {
+					continue
+				}
+
+				if isGCEInstanceRunning(instances[privateIP]) {
+					continue
+				}
+
+				instances[privateIP] = instance
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return errors.Wrapf(err, "list instances in zone %s", zone)
+		}
+	}
+
+	if k.Masters == nil {
+		k.Masters = make(map[string]*model.Machine)
+	}
+
+	if k.Nodes == nil {
+		k.Nodes = make(map[string]*model.Machine)
+	}
+
+	for privateIP, instance := range instances {
+		// Terminated and stopping instances are not added
+		if !isGCEInstanceRunning(instance) {
+			continue
+		}
+
+		if isKnownMachine(k.Masters, privateIP) || isKnownMachine(k.Nodes, privateIP) {
+			continue
+		}
+
+		machine := gceMachine(instance)
+
+		if machine.Role == model.RoleMaster {
+			logrus.Debugf("Add new master %v", machine)
+			k.Masters[machine.Name] = machine
+			continue
+		}
+
+		logrus.Debugf("Add new node %v", machine)
+		k.Nodes[machine.Name] = machine
+	}
+
+	// Machines of terminated and stopping instances are gone as well
+	for name, machine := range k.Nodes {
+		if !isSyncable(machine) || isGCEInstanceRunning(instances[machine.PrivateIp]) {
+			continue
+		}
+
+		// Give node one more sync before removing it from the model
+		if machine.State == model.MachineStateDeleting {
+			logrus.Infof("Remove node %s gone from GCE from kube %s", name, k.ID)
+			delete(k.Nodes, name)
+			continue
+		}
+
+		logrus.Infof("Node %s of kube %s is gone from GCE", name, k.ID)
+		machine.State = model.MachineStateDeleting
+	}
+
+	for name, machine := range k.Masters {
+		if !isSyncable(machine) || isGCEInstanceRunning(instances[machine.PrivateIp]) {
+			continue
+		}
+
+		logrus.Errorf("Master %s of kube %s is gone from GCE", name, k.ID)
+		machine.State = model.MachineStateError
+	}
+
+	return nil
+}
+
+func isGCEInstanceRunning(instance *compute.Instance) bool {
+	return instance != nil && instance.Status == gceStatusRunning
+}
+
+// gceZones returns zones of the kube, machine region
+// of GCE machine is its zone.
+func gceZones(k *model.Kube) []string {
+	zones := make(map[string]struct{})
+
+	for zone := range k.Subnets {
+		zones[zone] = struct{}{}
+	}
+
+	for _, machines := range []map[string]*model.Machine{k.Masters, k.Nodes} {
+		for _, machine := range machines {
+			if machine != nil && machine.Region != "" {
+				zones[machine.Region] = struct{}{}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(zones))
+
+	for zone := range zones {
+		names = append(names, zone)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func gceMachine(instance *compute.Instance) *model.Machine {
+	machine := &model.Machine{
+		ID:        strconv.FormatUint(instance.Id, 10),
+		Name:      instance.Name,
+		Size:      lastURLSegment(instance.MachineType),
+		Region:    lastURLSegment(instance.Zone),
+		Role:      model.RoleNode,
+		State:     model.MachineStateActive,
+		Provider:  clouds.GCE,
+		PrivateIp: gceInstancePrivateIP(instance),
+	}
+
+	if len(instance.NetworkInterfaces) > 0 &&
+		len(instance.NetworkInterfaces[0].AccessConfigs) > 0 {
+		machine.PublicIp = instance.NetworkInterfaces[0].AccessConfigs[0].NatIP
+	}
+
+	if instance.Metadata != nil {
+		for _, item := range instance.Metadata.Items {
+			if item != nil && item.Key == gceRoleMetadata && item.Value != nil &&
+				*item.Value == util.MakeRole(true) {
+				machine.Role = model.RoleMaster
+			}
+		}
+	}
+
+	return machine
+}
+
+func gceInstancePrivateIP(instance *compute.Instance) string {
+	if len(instance.NetworkInterfaces) == 0 {
+		return ""
+	}
+
+	return instance.NetworkInterfaces[0].NetworkIP
+}
+
+// lastURLSegment returns name of resource from its link
+func lastURLSegment(link string) string {
+	return link[strings.LastIndex(link, "/")+1:]
+}
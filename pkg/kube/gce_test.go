@@ -0,0 +1,245 @@
+package kube
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/compute/v1"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+)
+
+type fakeGCEInstanceLister struct {
+	// Pages of instances by zone
+	zones   map[string][]*compute.InstanceList
+	filters []string
+	err     error
+}
+
+func (f *fakeGCEInstanceLister) ListInstancesPages(ctx context.Context, zone, filter string,
+	fn func(*compute.InstanceList) error) error {
+	f.filters = append(f.filters, filter)
+
+	if f.err != nil {
+		return f.err
+	}
+
+	for _, page := range f.zones[zone] {
+		if err := fn(page); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func gceInstance(name, zone, privateIP, status, role string) *compute.Instance {
+	return &compute.Instance{
+		Name:        name,
+		Status:      status,
+		Zone:        "https://www.googleapis.com/compute/v1/projects/test/zones/" + zone,
+		MachineType: "https://www.googleapis.com/compute/v1/projects/test/zones/" + zone + "/machineTypes/n1-standard-2",
+		Labels: map[string]string{
+			clouds.LabelClusterID: "kube-id",
+		},
+		Metadata: &compute.Metadata{
+			Items: []*compute.MetadataItems{
+				{
+					Key:   "Role",
+					Value: &role,
+				},
+			},
+		},
+		NetworkInterfaces: []*compute.NetworkInterface{
+			{
+				NetworkIP: privateIP,
+				AccessConfigs: []*compute.AccessConfig{
+					{
+						NatIP: "35.0.0.1",
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestSyncGCEMachinesMultiZone(t *testing.T) {
+	lister := &fakeGCEInstanceLister{
+		zones: map[string][]*compute.InstanceList{
+			"us-central1-a": {
+				{
+					Items: []*compute.Instance{
+						gceInstance("master-1", "us-central1-a", "10.0.1.1", "RUNNING", "master"),
+						gceInstance("node-1", "us-central1-a", "10.0.0.1", "RUNNING", "node"),
+					},
+				},
+				{
+					Items: []*compute.Instance{
+						gceInstance("node-2", "us-central1-a", "10.0.0.2", "TERMINATED", "node"),
+					},
+				},
+			},
+			"us-central1-b": {
+				{
+					Items: []*compute.Instance{
+						gceInstance("node-3", "us-central1-b", "10.0.0.3", "RUNNING", "node"),
+						gceInstance("node-4", "us-central1-b", "10.0.0.4", "STOPPING", "node"),
+					},
+				},
+			},
+		},
+	}
+
+	k := &model.Kube{
+		ID: "kube-id",
+		Subnets: map[string]string{
+			"us-central1-a": "dummy",
+			"us-central1-b": "dummy",
+		},
+	}
+
+	if err := syncGCEMachines(context.Background(), lister, k); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(lister.filters) != 2 {
+		t.Errorf("Wrong count of zones listed expected 2 actual %d", len(lister.filters))
+	}
+
+	for _, filter := range lister.filters {
+		if !strings.Contains(filter, clouds.LabelClusterID) || !strings.Contains(filter, k.ID) {
+			t.Errorf("Filter %s must contain cluster id label", filter)
+		}
+	}
+
+	if master := k.Masters["master-1"]; master == nil || master.Role != model.RoleMaster {
+		t.Errorf("Master must be synced %v", k.Masters)
+	}
+
+	for _, name := range []string{"node-1", "node-3"} {
+		if k.Nodes[name] == nil {
+			t.Errorf("Node %s must be synced", name)
+		}
+	}
+
+	for _, name := range []string{"node-2", "node-4"} {
+		if k.Nodes[name] != nil {
+			t.Errorf("Node %s must not be synced", name)
+		}
+	}
+
+	node := k.Nodes["node-3"]
+
+	if node != nil && (node.Size != "n1-standard-2" || node.Region != "us-central1-b" ||
+		node.PrivateIp != "10.0.0.3" || node.PublicIp != "35.0.0.1") {
+		t.Errorf("Wrong node %v", node)
+	}
+}
+
+func TestSyncGCEMachinesGoneInstances(t *testing.T) {
+	lister := &fakeGCEInstanceLister{
+		zones: map[string][]*compute.InstanceList{
+			"us-central1-a": {
+				{
+					Items: []*compute.Instance{
+						gceInstance("gce-name", "us-central1-a", "10.0.0.1", "RUNNING", "node"),
+						gceInstance("stopped", "us-central1-a", "10.0.0.2", "TERMINATED", "node"),
+					},
+				},
+			},
+		},
+	}
+
+	k := &model.Kube{
+		ID: "kube-id",
+		Masters: map[string]*model.Machine{
+			"master": {
+				Name:      "master",
+				PrivateIp: "10.0.1.1",
+				Region:    "us-central1-a",
+				State:     model.MachineStateActive,
+			},
+		},
+		Nodes: map[string]*model.Machine{
+			// Machines are matched by ip rather than by name
+			"running": {
+				Name:      "running",
+				PrivateIp: "10.0.0.1",
+				Region:    "us-central1-a",
+				State:     model.MachineStateActive,
+			},
+			"stopped": {
+				Name:      "stopped",
+				PrivateIp: "10.0.0.2",
+				Region:    "us-central1-a",
+				State:     model.MachineStateActive,
+			},
+			"missing": {
+				Name:      "missing",
+				PrivateIp: "10.0.0.3",
+				Region:    "us-central1-a",
+				State:     model.MachineStateActive,
+			},
+		},
+	}
+
+	if err := syncGCEMachines(context.Background(), lister, k); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := map[string]model.MachineState{
+		"running": model.MachineStateActive,
+		"stopped": model.MachineStateDeleting,
+		"missing": model.MachineStateDeleting,
+	}
+
+	for name, state := range expected {
+		if k.Nodes[name] == nil || k.Nodes[name].State != state {
+			t.Errorf("Wrong state of node %s expected %s actual %v",
+				name, state, k.Nodes[name])
+		}
+	}
+
+	if k.Nodes["gce-name"] != nil {
+		t.Errorf("Known instance must not be added under another name")
+	}
+
+	if k.Masters["master"].State != model.MachineStateError {
+		t.Errorf("Wrong state of master expected %s actual %s",
+			model.MachineStateError, k.Masters["master"].State)
+	}
+
+	// Nodes are removed on the next sync
+	if err := syncGCEMachines(context.Background(), lister, k); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	for _, name := range []string{"stopped", "missing"} {
+		if k.Nodes[name] != nil {
+			t.Errorf("Node %s must be removed", name)
+		}
+	}
+
+	if k.Nodes["running"] == nil {
+		t.Errorf("Node running must be kept")
+	}
+}
+
+func TestSyncGCEMachinesError(t *testing.T) {
+	lister := &fakeGCEInstanceLister{
+		err: errors.New("list error"),
+	}
+
+	k := &model.Kube{
+		Subnets: map[string]string{
+			"us-central1-a": "dummy",
+		},
+	}
+
+	if err := syncGCEMachines(context.Background(), lister, k); err == nil {
+		t.Errorf("Error must not be nil")
+	}
+}
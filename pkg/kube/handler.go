@@ -6,22 +6,28 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"time"
 
+	"github.com/ghodss/yaml"
 	"github.com/gorilla/mux"
-	"github.com/pborman/uuid"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/asaskevich/govalidator.v8"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	clientcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
-	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmddapi "k8s.io/client-go/tools/clientcmd/api"
 
+	"github.com/supergiant/control/pkg/api"
 	"github.com/supergiant/control/pkg/clouds"
 	"github.com/supergiant/control/pkg/kubeconfig"
 	"github.com/supergiant/control/pkg/message"
@@ -34,17 +40,40 @@ import (
 	"github.com/supergiant/control/pkg/workflows"
 	"github.com/supergiant/control/pkg/workflows/statuses"
 	"github.com/supergiant/control/pkg/workflows/steps"
+	"github.com/supergiant/control/pkg/workflows/steps/amazon"
+	"github.com/supergiant/control/pkg/workflows/steps/gpu"
 )
 
 const (
 	clusterService = "kubernetes.io/cluster-service"
 	nodeLabelRole  = "kubernetes.io/role"
+
+	// DefaultTasksLimit is count of kube tasks returned.
+	DefaultTasksLimit = 50
+	// MaxTasksLimit limits count of kube tasks.
+	MaxTasksLimit = 500
+)
+
+var (
+	// Fields of listed kubes returned whatever fields are selected
+	kubeKeyFields = []string{"id"}
+	// Fields of listed kubes returned only when selected, they hold
+	// certificates and machines of kubes.
+	heavyKubeFields = []string{"auth", "masters", "nodes", "etcd"}
+
+	// Fields of listed tasks returned whatever fields are selected
+	taskKeyFields = []string{"id"}
 )
 
 type ChartRefGetter interface {
 	GetChartRef(context.Context, string, string, string) (string, error)
 }
 
+// releaseAnnotator sets chart versions releases may be upgraded to.
+type releaseAnnotator interface {
+	AnnotateReleases(ctx context.Context, releases []*model.ReleaseInfo) error
+}
+
 type accountGetter interface {
 	Get(context.Context, string) (*model.CloudAccount, error)
 }
@@ -57,6 +86,12 @@ type profileSvc interface {
 type nodeProvisioner interface {
 	ProvisionNodes(context.Context, []profile.NodeProfile, *model.Kube,
 		*steps.Config) ([]string, error)
+	// ProvisionNodePool provisions nodes of the pool by the single pool task
+	ProvisionNodePool(context.Context, profile.NodePool, int, *model.Kube,
+		*steps.Config) (*workflows.Task, []string, error)
+	// ReplaceMaster provisions master in place of the old one
+	ReplaceMaster(context.Context, profile.NodeProfile, *model.Kube,
+		*model.Machine, *steps.Config) (*workflows.Task, <-chan error, error)
 	// Method that cancels newly added nodes to working cluster
 	Cancel(string) error
 }
@@ -68,6 +103,7 @@ type kubeProvisioner interface {
 		taskIdMap map[string][]string) error
 	UpgradeCluster(context.Context, string, *model.Kube,
 		map[string][]*workflows.Task, *steps.Config)
+	RestartTask(context.Context, string, *workflows.Task, io.WriteCloser) error
 }
 
 type ServiceInfo struct {
@@ -90,10 +126,71 @@ type MetricResponse struct {
 }
 
 type SpotRequest struct {
-	SpotPrice        string `json:"spotPrice"`
-	MachineType      string `json:"machineType"`
-	MachineCount     int64  `json:"machineCount"`
+	SpotPrice    string `json:"spotPrice"`
+	MachineType  string `json:"machineType"`
+	MachineCount int64  `json:"machineCount"`
+	// DEPRECATED: use AvailabilityZones instead.
 	AvailabilityZone string `json:"availabilityZone"`
+	// AvailabilityZones machines are spread across, "any" means
+	// all zones of the kube.
+	AvailabilityZones []string `json:"availabilityZones"`
+	// InstanceTypes of spot fleet, spot fleet is used instead of
+	// spot requests of MachineType when they are set.
+	InstanceTypes []steps.SpotInstanceType `json:"instanceTypes"`
+	// AllocationStrategy of spot fleet lowestPrice or capacityOptimized
+	AllocationStrategy string `json:"allocationStrategy"`
+	// ValidUntil is the time when spot request expires, one year
+	// from now is used when it is omitted.
+	ValidUntil *time.Time `json:"validUntil,omitempty"`
+	// FallbackOnDemand launches on-demand instances instead of spot
+	// requests that were not fulfilled within FulfillmentTimeout.
+	FallbackOnDemand bool `json:"fallbackOnDemand"`
+	// FulfillmentTimeout in seconds, ten minutes are used when it is omitted.
+	FulfillmentTimeout int64 `json:"fulfillmentTimeout"`
+	// DryRun checks permissions and launch specification of spot
+	// requests without submitting them.
+	DryRun bool `json:"dryRun"`
+	// MaintainCount requests spot instances again when nodes
+	// of the request are interrupted.
+	MaintainCount bool `json:"maintainCount"`
+	// Pool of the kube profile spot nodes are added to, machine type
+	// and image of the pool are used when they are not set.
+	Pool string `json:"pool,omitempty"`
+}
+
+// SpotPricePoint is a spot price of machine type at the point of time.
+type SpotPricePoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Price     float64   `json:"price"`
+}
+
+type spotResponse struct {
+	// DEPRECATED: use TaskIDs instead.
+	TaskID  string   `json:"taskId,omitempty"`
+	TaskIDs []string `json:"taskIds"`
+	// Zones where spot instances were requested
+	AvailabilityZones []string `json:"availabilityZones"`
+	// SpotGroupID of the group which spot count is maintained
+	SpotGroupID string `json:"spotGroupId,omitempty"`
+}
+
+type spotDryRunResponse struct {
+	DryRun               bool                     `json:"dryRun"`
+	LaunchSpecifications []steps.SpotDryRunResult `json:"launchSpecifications"`
+}
+
+type syncResponse struct {
+	Added   int `json:"added"`
+	Updated int `json:"updated"`
+	Removed int `json:"removed"`
+
+	SecurityGroupDrift *model.SecurityGroupDrift `json:"securityGroupDrift,omitempty"`
+}
+
+type spotPricesResponse struct {
+	// DEPRECATED: prices in kube availability zone, use Zones instead.
+	Prices []string                    `json:"Prices"`
+	Zones  map[string][]SpotPricePoint `json:"zones"`
 }
 
 // Handler is a http controller for a kube entity.
@@ -104,17 +201,44 @@ type Handler struct {
 	kubeProvisioner kubeProvisioner
 	profileSvc      profileSvc
 	chartGetter     ChartRefGetter
+	rlsAnnotator    releaseAnnotator
+	serviceProxy    serviceProxy
 
 	repo    storage.Interface
 	proxies proxy.Container
+	// Logs of tasks are deleted along with their history
+	logDir string
 
 	getWriter  func(string) (io.WriteCloser, error)
 	getMetrics func(string, *model.Kube) (*MetricResponse, error)
+	// queryMetrics returns raw prometheus response of the query uri
+	queryMetrics func(context.Context, string, *model.Kube) ([]byte, error)
+	// getRangeMetrics returns series of range query uri
+	getRangeMetrics func(context.Context, string, *model.Kube) (*MetricRangeResponse, error)
+	metricsCache    *metricsCache
 
-	discoverK8SVersion  func(kubeConfig *clientcmddapi.Config) (string, error)
-	discoverHelmVersion func(kubeConfig *clientcmddapi.Config) (string, error)
+	discoverK8SVersion  func(ctx context.Context, kubeConfig *clientcmddapi.Config) (string, error)
+	discoverHelmVersion func(ctx context.Context, kubeConfig *clientcmddapi.Config) (string, error)
+	// Timeout of requests to API server of kube being imported
+	discoveryTimeout time.Duration
 
 	listK8sServices func(*model.Kube, string) (*corev1.ServiceList, error)
+	listEtcdPods    func(*model.Kube) ([]corev1.Pod, error)
+	clientSetFor    func(*model.Kube) (kubernetes.Interface, error)
+	// dynamicClientFor builds client that applies manifests of addons
+	dynamicClientFor func(*model.Kube) (dynamic.Interface, error)
+	syncMachines     func(context.Context, *model.Kube, *model.CloudAccount) error
+	// syncNodes syncs machines of kubes without cloud account by k8s nodes
+	syncNodes func(context.Context, *model.Kube) error
+
+	checkSecurityGroups SecurityGroupCheckFn
+
+	costEstimator *CostEstimator
+	// How long detached kubes are kept by default
+	detachRetention time.Duration
+	// Files referenced by imported kubeconfigs are read only within the
+	// dir, kubeconfigs must inline them when it is empty
+	kubeConfigDir string
 }
 
 // NewHandler constructs a Handler for kubes.
@@ -137,18 +261,10 @@ func NewHandler(
 		profileSvc:      profileSvc,
 		chartGetter:     charGetter,
 		repo:            repo,
+		logDir:          logDir,
 		getWriter:       util.GetWriterFunc(logDir),
 		getMetrics: func(metricURI string, k *model.Kube) (*MetricResponse, error) {
-			cfg, err := kubeconfig.NewConfigFor(k)
-			if err != nil {
-				return nil, errors.Wrap(err, "build kubernetes rest config")
-			}
-			kclient, err := rest.UnversionedRESTClientFor(cfg)
-			if err != nil {
-				return nil, errors.Wrap(err, "build kubernetes client")
-			}
-
-			raw, err := kclient.Get().RequestURI(metricURI).Do().Raw()
+			raw, err := queryMetrics(context.Background(), metricURI, k)
 			if err != nil {
 				return nil, errors.Wrap(err, "retrieve metrics")
 			}
@@ -161,6 +277,9 @@ func NewHandler(
 
 			return metricResponse, nil
 		},
+		queryMetrics:    queryMetrics,
+		getRangeMetrics: getRangeMetrics,
+		metricsCache:    newMetricsCache(DefaultMetricsCacheTTL),
 		listK8sServices: func(k *model.Kube, selector string) (*corev1.ServiceList, error) {
 			cfg, err := kubeconfig.NewConfigFor(k)
 			if err != nil {
@@ -174,9 +293,56 @@ func NewHandler(
 				LabelSelector: selector,
 			})
 		},
+		listEtcdPods: func(k *model.Kube) ([]corev1.Pod, error) {
+			cfg, err := kubeconfig.NewConfigFor(k)
+			if err != nil {
+				return nil, errors.Wrap(err, "build kubernetes rest config")
+			}
+			c, err := clientcorev1.NewForConfig(cfg)
+			if err != nil {
+				return nil, errors.Wrapf(err, "build kubernetes client")
+			}
+			podList, err := c.Pods(metav1.NamespaceSystem).List(metav1.ListOptions{
+				LabelSelector: "component=etcd",
+			})
+			if err != nil {
+				return nil, err
+			}
+			return podList.Items, nil
+		},
+		clientSetFor: func(k *model.Kube) (kubernetes.Interface, error) {
+			cfg, err := kubeconfig.NewConfigFor(k)
+			if err != nil {
+				return nil, errors.Wrap(err, "build kubernetes rest config")
+			}
+			return kubernetes.NewForConfig(cfg)
+		},
+		dynamicClientFor: func(k *model.Kube) (dynamic.Interface, error) {
+			cfg, err := kubeconfig.NewConfigFor(k)
+			if err != nil {
+				return nil, errors.Wrap(err, "build kubernetes rest config")
+			}
+			return dynamic.NewForConfig(cfg)
+		},
 		discoverK8SVersion:  discoverK8SVersion,
 		discoverHelmVersion: discoverHelmVersion,
+		discoveryTimeout:    DefaultDiscoveryTimeout,
+		syncMachines:        syncMachines,
+		syncNodes: func(ctx context.Context, k *model.Kube) error {
+			nodes, err := svc.ListNodes(ctx, k, "")
+
+			if err != nil {
+				return errors.Wrap(err, "list nodes")
+			}
+
+			syncK8SNodes(k, nodes)
+
+			return nil
+		},
+		checkSecurityGroups: checkSecurityGroups,
 		proxies:             proxies,
+		costEstimator:       NewCostEstimator(),
+		detachRetention:     DefaultDetachRetention,
 	}
 }
 
@@ -185,10 +351,14 @@ func (h *Handler) Register(r *mux.Router) {
 	r.HandleFunc("/kubes", h.createKube).Methods(http.MethodPost)
 	r.HandleFunc("/kubes", h.listKubes).Methods(http.MethodGet)
 	r.HandleFunc("/kubes/import", h.importKube).Methods(http.MethodPost)
+	r.HandleFunc("/kubes/import/contexts", h.importContexts).Methods(http.MethodPost)
 	r.HandleFunc("/kubes/{kubeID}", h.getKube).Methods(http.MethodGet)
 	r.HandleFunc("/kubes/{kubeID}", h.deleteKube).Methods(http.MethodDelete)
 
 	r.HandleFunc("/kubes/{kubeID}/users/{uname}/kubeconfig", h.getKubeconfig).Methods(http.MethodGet)
+	r.HandleFunc("/kubes/{kubeID}/kubeconfig", h.downloadKubeconfig).Methods(http.MethodGet)
+	r.HandleFunc("/kubes/{kubeID}/access", h.grantAccess).Methods(http.MethodPost)
+	r.HandleFunc("/kubes/{kubeID}/access/{namespace}/{name}", h.revokeAccess).Methods(http.MethodDelete)
 
 	r.HandleFunc("/kubes/{kubeID}/resources", h.listResources).Methods(http.MethodGet)
 	r.HandleFunc("/kubes/{kubeID}/resources/{resource}", h.getResource).Methods(http.MethodGet)
@@ -196,31 +366,66 @@ func (h *Handler) Register(r *mux.Router) {
 	r.HandleFunc("/kubes/{kubeID}/releases", h.installRelease).Methods(http.MethodPost)
 	r.HandleFunc("/kubes/{kubeID}/releases", h.listReleases).Methods(http.MethodGet)
 	r.HandleFunc("/kubes/{kubeID}/releases/{releaseName}", h.getRelease).Methods(http.MethodGet)
+	r.HandleFunc("/kubes/{kubeID}/releases/{releaseName}", h.upgradeRelease).Methods(http.MethodPut)
 	r.HandleFunc("/kubes/{kubeID}/releases/{releaseName}", h.deleteReleases).Methods(http.MethodDelete)
+	r.HandleFunc("/kubes/{kubeID}/releases/{releaseName}/history", h.releaseHistory).Methods(http.MethodGet)
+	r.HandleFunc("/kubes/{kubeID}/releases/{releaseName}/rollback", h.rollbackRelease).Methods(http.MethodPost)
+	r.HandleFunc("/kubes/{kubeID}/releases/{releaseName}/diff", h.diffRelease).Methods(http.MethodPost)
 
 	r.HandleFunc("/kubes/{kubeID}/certs/{cname}", h.getCerts).Methods(http.MethodGet)
 	r.HandleFunc("/kubes/{kubeID}/tasks", h.getTasks).Methods(http.MethodGet)
+	r.HandleFunc("/kubes/{kubeID}/sync", h.syncKube).Methods(http.MethodPost)
+	r.HandleFunc("/kubes/{kubeID}/restore", h.restoreKube).Methods(http.MethodPost)
+	r.HandleFunc("/kubes/{kubeID}/reconfigure", h.reconfigureNodes).Methods(http.MethodPost)
+	r.HandleFunc("/kubes/{kubeID}/profile-export", h.exportProfile).Methods(http.MethodGet)
+	r.HandleFunc("/kubes/{kubeID}/addons/{addonName}", h.installAddon).Methods(http.MethodPost)
+	r.HandleFunc("/kubes/{kubeID}/addons/{addonName}", h.deleteAddon).Methods(http.MethodDelete)
 
 	// DEPRECATED: has been moved to /kubes/{kubeID}/machines
 	r.HandleFunc("/kubes/{kubeID}/nodes", h.addMachine).Methods(http.MethodPost)
 
 	// DEPRECATED: has been moved to /kubes/{kubeID}/machines
 	r.HandleFunc("/kubes/{kubeID}/nodes/{nodename}", h.deleteMachine).Methods(http.MethodDelete)
+	r.HandleFunc("/kubes/{kubeID}/masters/{masterName}/replace", h.replaceMaster).Methods(http.MethodPost)
 
 	r.HandleFunc("/kubes/{kubeID}/nodes", h.listNodes).Methods(http.MethodGet)
 
 	r.HandleFunc("/kubes/{kubeID}/machines", h.addMachine).Methods(http.MethodPost)
 	r.HandleFunc("/kubes/{kubeID}/machines/{nodename}", h.deleteMachine).Methods(http.MethodDelete)
 
+	r.HandleFunc("/kubes/{kubeID}/pools", h.listNodePools).Methods(http.MethodGet)
+	r.HandleFunc("/kubes/{kubeID}/pools", h.createNodePool).Methods(http.MethodPost)
+	r.HandleFunc("/kubes/{kubeID}/pools/{poolName}", h.resizeNodePool).Methods(http.MethodPatch)
+	r.HandleFunc("/kubes/{kubeID}/pools/{poolName}", h.deleteNodePool).Methods(http.MethodDelete)
+
 	r.HandleFunc("/kubes/{kubeID}/spot", h.addSpotMachine).Methods(http.MethodPost)
 	r.HandleFunc("/kubes/{kubeID}/spot/{machineType}/price", h.spotMachinePrice).Methods(http.MethodGet)
+	r.HandleFunc("/kubes/{kubeID}/spot/{machineType}/recommendation", h.spotPriceRecommendation).Methods(http.MethodGet)
 
 	r.HandleFunc("/kubes/{kubeID}/nodes/metrics", h.getNodesMetrics).Methods(http.MethodGet)
 	r.HandleFunc("/kubes/{kubeID}/metrics", h.getClusterMetrics).Methods(http.MethodGet)
+	r.HandleFunc("/kubes/{kubeID}/metrics/query", h.queryClusterMetrics).Methods(http.MethodPost)
+	r.HandleFunc("/kubes/{kubeID}/metrics/{groupBy:namespaces|pods}", h.getWorkloadMetrics).Methods(http.MethodGet)
+	r.HandleFunc("/kubes/{kubeID}/alerts", h.listAlertRules).Methods(http.MethodGet)
+	r.HandleFunc("/kubes/{kubeID}/alerts", h.createAlertRule).Methods(http.MethodPost)
+	r.HandleFunc("/kubes/{kubeID}/alerts/{ruleID}", h.getAlertRule).Methods(http.MethodGet)
+	r.HandleFunc("/kubes/{kubeID}/alerts/{ruleID}", h.createAlertRule).Methods(http.MethodPut)
+	r.HandleFunc("/kubes/{kubeID}/alerts/{ruleID}", h.deleteAlertRule).Methods(http.MethodDelete)
+	r.HandleFunc("/kubes/{kubeID}/schedules", h.listSchedules).Methods(http.MethodGet)
+	r.HandleFunc("/kubes/{kubeID}/schedules", h.createSchedule).Methods(http.MethodPost)
+	r.HandleFunc("/kubes/{kubeID}/schedules/preview", h.previewSchedule).Methods(http.MethodPost)
+	r.HandleFunc("/kubes/{kubeID}/schedules/{scheduleID}", h.getSchedule).Methods(http.MethodGet)
+	r.HandleFunc("/kubes/{kubeID}/schedules/{scheduleID}", h.createSchedule).Methods(http.MethodPut)
+	r.HandleFunc("/kubes/{kubeID}/schedules/{scheduleID}", h.deleteSchedule).Methods(http.MethodDelete)
 	r.HandleFunc("/kubes/{kubeID}/services", h.getServices).Methods(http.MethodGet)
+	r.PathPrefix("/kubes/{kubeID}/proxy/{namespace}/{service}/{port}").HandlerFunc(h.proxyService)
 	r.HandleFunc("/kubes/{kubeID}/restart", h.restartKubeProvisioning).Methods(http.MethodPost)
 	r.HandleFunc("/kubes/{kubeID}", h.upgradeKube).Methods(http.MethodPatch)
+	r.HandleFunc("/kubes/{kubeID}/upgrade-targets", h.getUpgradeTargets).Methods(http.MethodGet)
+	r.HandleFunc("/kubes/{kubeID}/upgrade-check", h.getUpgradeCheck).Methods(http.MethodGet)
 	r.HandleFunc("/kubes/{kubeID}/apply", h.applyToKube).Methods(http.MethodPost)
+
+	r.HandleFunc("/kubeprofiles/estimate", h.estimateCost).Methods(http.MethodPost)
 }
 
 func (h *Handler) getTasks(w http.ResponseWriter, r *http.Request) {
@@ -232,6 +437,14 @@ func (h *Handler) getTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	query := r.URL.Query()
+
+	page, err := api.ParsePage(query, DefaultTasksLimit, MaxTasksLimit)
+	if err != nil {
+		message.SendValidationFailed(w, err)
+		return
+	}
+
 	tasks, err := h.getKubeTasks(r.Context(), id)
 
 	if err != nil {
@@ -254,19 +467,40 @@ func (h *Handler) getTasks(w http.ResponseWriter, r *http.Request) {
 		Type         string                 `json:"type"`
 		Status       statuses.Status        `json:"status"`
 		StepStatuses []workflows.StepStatus `json:"stepsStatuses"`
+		CreatedAt    int64                  `json:"createdAt,omitempty"`
+		UpdatedAt    int64                  `json:"updatedAt,omitempty"`
 	}
 
+	status, taskType := query.Get("status"), query.Get("type")
 	resp := make([]taskDTO, 0, len(tasks))
 
 	for _, task := range tasks {
+		if (status != "" && string(task.Status) != status) || (taskType != "" && task.Type != taskType) {
+			continue
+		}
+
 		resp = append(resp, taskDTO{
 			ID:           task.ID,
 			Type:         task.Type,
 			Status:       task.Status,
 			StepStatuses: task.StepStatuses,
+			CreatedAt:    task.CreatedAt,
+			UpdatedAt:    task.UpdatedAt,
 		})
 	}
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
+
+	start, end := page.Bounds(len(resp))
+
+	selected, err := api.SelectFields(resp[start:end], api.ParseFields(query), taskKeyFields, nil)
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	// Total count of matching tasks is reported for pagination
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(resp)))
+
+	if err = json.NewEncoder(w).Encode(selected); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
@@ -321,7 +555,7 @@ func (h *Handler) getKube(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Sync only after cluster becomes operational
-	if k.Provider == clouds.AWS && k.State == model.StateOperational {
+	if isSyncSupported(k.Provider) && k.State == model.StateOperational {
 		logrus.Debugf("Get cloud account %s", k.AccountName)
 		acc, err := h.accountService.Get(r.Context(), k.AccountName)
 
@@ -335,31 +569,217 @@ func (h *Handler) getKube(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if err := syncMachines(r.Context(), k, acc); err != nil {
-			logrus.Errorf("error syncing machines for %s %v", k.ID, err)
-		}
-
 		// Update cluster with new nodes
-		err = h.svc.Create(context.Background(), k)
+		synced, err := retryUpdate(context.Background(), h.svc, kubeID, func(k *model.Kube) error {
+			if err := h.syncMachines(r.Context(), k, acc); err != nil {
+				logrus.Errorf("error syncing machines for %s %v", k.ID, err)
+			}
+
+			return nil
+		})
 
 		if err != nil {
 			logrus.Errorf("update cluster %s caused %v", kubeID, err)
+		} else {
+			k = synced
 		}
 	}
 
+	if k.State == model.StateOperational {
+		h.setMonitoringStatus(r.Context(), k)
+		h.setAllocatableGPUs(r.Context(), k)
+		h.setAddonStatuses(r.Context(), k)
+	}
+
 	if err = json.NewEncoder(w).Encode(k); err != nil {
 		message.SendUnknownError(w, err)
 	}
 }
 
+// setMonitoringStatus sets prometheus status of the kube from discovery
+// cache, status of the last successful discovery is kept on error.
+func (h *Handler) setMonitoringStatus(ctx context.Context, k *model.Kube) {
+	ctx, cancel := context.WithTimeout(ctx, h.discoveryTimeout)
+	defer cancel()
+
+	result, err := h.svc.Discover(ctx, k, false)
+
+	if err != nil {
+		logrus.Debugf("discover monitoring of kube %s: %v", k.ID, err)
+	}
+
+	if result != nil && result.Monitoring != nil {
+		k.MonitoringStatus = result.Monitoring
+	}
+}
+
+// setAllocatableGPUs sums GPUs allocatable by GPU nodes of the kube,
+// the last known count is kept when nodes can't be listed.
+func (h *Handler) setAllocatableGPUs(ctx context.Context, k *model.Kube) {
+	if !hasGPUNodes(k) {
+		return
+	}
+
+	nodes, err := h.svc.ListNodes(ctx, k, "")
+
+	if err != nil {
+		logrus.Debugf("list nodes of kube %s: %v", k.ID, err)
+		return
+	}
+
+	k.AllocatableGPUs = gpu.TotalAllocatable(nodes)
+}
+
+func hasGPUNodes(k *model.Kube) bool {
+	for _, node := range k.Nodes {
+		if node != nil && node.GPU {
+			return true
+		}
+	}
+
+	return false
+}
+
+// syncKube syncs kube machines with cloud provider on demand, security
+// groups of AWS kubes are checked for missing rules.
+func (h *Handler) syncKube(w http.ResponseWriter, r *http.Request) {
+	kubeID := mux.Vars(r)["kubeID"]
+
+	k, err := h.svc.Get(r.Context(), kubeID)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	// Kubes without cloud account are synced through their API
+	if k.AccountName != "" && !isSyncSupported(k.Provider) {
+		message.SendValidationFailed(w, errors.Wrapf(sgerrors.ErrUnsupportedProvider,
+			"sync machines of provider %s", k.Provider))
+		return
+	}
+
+	if !h.checkNoRunningTasks(w, r, kubeID) {
+		return
+	}
+
+	var acc *model.CloudAccount
+
+	if k.AccountName != "" {
+		logrus.Debugf("Get cloud account %s", k.AccountName)
+		acc, err = h.accountService.Get(r.Context(), k.AccountName)
+
+		if err != nil {
+			if sgerrors.IsNotFound(err) {
+				message.SendNotFound(w, k.AccountName, err)
+				return
+			}
+
+			message.SendUnknownError(w, err)
+			return
+		}
+	}
+
+	var before map[string]model.Machine
+
+	// Machines are synced again with the reloaded kube when it has been
+	// modified while they were synced
+	k, err = retryUpdate(r.Context(), h.svc, kubeID, func(k *model.Kube) error {
+		before = snapshotMachines(k)
+
+		if acc != nil {
+			if err := h.syncMachines(r.Context(), k, acc); err != nil {
+				return err
+			}
+		} else if err := h.syncNodes(r.Context(), k); err != nil {
+			return err
+		}
+
+		if acc != nil && hasSecurityGroups(k) {
+			enforce, _ := strconv.ParseBool(r.URL.Query().Get("enforce"))
+			drift, err := h.checkSecurityGroups(r.Context(), k, acc,
+				enforce || k.SecurityGroups.Enforce)
+
+			if err != nil {
+				logrus.Errorf("error checking security groups of %s %v", k.ID, err)
+			} else {
+				setSecurityGroupDrift(k, drift)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		switch {
+		case sgerrors.IsInvalidCredentials(err):
+			message.SendInvalidCredentials(w, err)
+		case sgerrors.IsConflict(err):
+			h.sendConflict(w, r, kubeID, err)
+		default:
+			message.SendUnknownError(w, errors.Wrapf(err, "sync machines of kube %s", kubeID))
+		}
+		return
+	}
+
+	added, updated, removed := diffMachines(before, snapshotMachines(k))
+
+	if err := json.NewEncoder(w).Encode(syncResponse{
+		Added:              added,
+		Updated:            updated,
+		Removed:            removed,
+		SecurityGroupDrift: k.SecurityGroupDrift,
+	}); err != nil {
+		message.SendUnknownError(w, err)
+	}
+}
+
+// sendConflict responds the kube has been modified along with its
+// current revision.
+func (h *Handler) sendConflict(w http.ResponseWriter, r *http.Request, kubeID string, err error) {
+	k, getErr := h.svc.Get(r.Context(), kubeID)
+	if getErr != nil {
+		message.SendUnknownError(w, errors.Wrapf(getErr, "get kube %s", kubeID))
+		return
+	}
+
+	message.SendConflict(w, kubeID, k.Revision, err)
+}
+
 func (h *Handler) listKubes(w http.ResponseWriter, r *http.Request) {
-	kubes, err := h.svc.ListAll(r.Context())
+	query := r.URL.Query()
+
+	page, err := api.ParsePage(query, 0, 0)
+	if err != nil {
+		message.SendValidationFailed(w, err)
+		return
+	}
+
+	filter := Filter{
+		Provider: query.Get("provider"),
+		State:    query.Get("state"),
+		Name:     query.Get("name"),
+	}
+
+	kubes, total, err := h.svc.List(r.Context(), filter, page.Offset, page.Limit)
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	resp, err := api.SelectFields(kubes, api.ParseFields(query), kubeKeyFields, heavyKubeFields)
 	if err != nil {
 		message.SendUnknownError(w, err)
 		return
 	}
 
-	if err = json.NewEncoder(w).Encode(kubes); err != nil {
+	// Total count of matching kubes is reported for pagination
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	if err = json.NewEncoder(w).Encode(resp); err != nil {
 		message.SendUnknownError(w, err)
 	}
 }
@@ -369,6 +789,17 @@ func (h *Handler) deleteKube(w http.ResponseWriter, r *http.Request) {
 	kubeID := vars["kubeID"]
 	forceDelete := false
 
+	switch mode := r.URL.Query().Get("mode"); mode {
+	case "", DeleteModeDestroy:
+	case DeleteModeDetach:
+		h.detachKube(w, r)
+		return
+	default:
+		message.SendValidationFailed(w, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"unknown delete mode %s", mode))
+		return
+	}
+
 	logrus.Debugf("Delete kube %s", kubeID)
 
 	forceString := r.URL.Query().Get("force")
@@ -377,6 +808,9 @@ func (h *Handler) deleteKube(w http.ResponseWriter, r *http.Request) {
 		forceDelete, _ = strconv.ParseBool(forceString)
 	}
 
+	// History of tasks of the kube is deleted along with it by default
+	keepTasks, _ := strconv.ParseBool(r.URL.Query().Get("keepTasks"))
+
 	if err := h.nodeProvisioner.Cancel(kubeID); err != nil {
 		logrus.Debugf("cancel kube tasks error %v", err)
 	}
@@ -391,6 +825,12 @@ func (h *Handler) deleteKube(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Machines of detached kubes are not managed by control anymore
+	if k.State == model.StateDetached {
+		h.deleteDetachedKube(w, kubeID, keepTasks)
+		return
+	}
+
 	acc, err := h.accountService.Get(r.Context(), k.AccountName)
 
 	if err != nil {
@@ -422,6 +862,8 @@ func (h *Handler) deleteKube(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	t.KubeID = kubeID
+
 	// Load things specific to cloud provider
 	err = util.LoadCloudSpecificDataFromKube(k, config)
 
@@ -450,18 +892,18 @@ func (h *Handler) deleteKube(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx, _ := context.WithTimeout(context.Background(), time.Minute*10)
-	errChan := t.Run(ctx, *config, writer)
+	errChan := t.Run(ctx, config.Clone(), writer)
 
 	go func(t *workflows.Task) {
-		// Update kube with deleting state
-		k.State = model.StateDeleting
-		// Append delete task ID to kube tasks so that task can be deleted too.
-		k.Tasks[workflows.DeleteTask] = []string{t.ID}
-
-		err = h.svc.Create(context.Background(), k)
+		h.updateKube(kubeID, func(k *model.Kube) {
+			// Update kube with deleting state
+			k.State = model.StateDeleting
+			// Append delete task ID to kube tasks so that task can be deleted too.
+			k.Tasks[workflows.DeleteTask] = []string{t.ID}
+		})
 
-		if err != nil {
-			logrus.Errorf("update cluster %s caused %v", kubeID, err)
+		if h.serviceProxy != nil {
+			h.serviceProxy.Close(kubeID)
 		}
 
 		err = <-errChan
@@ -470,7 +912,7 @@ func (h *Handler) deleteKube(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Clean up tasks in storage
-		if err := h.cleanUpKube(kubeID); err != nil {
+		if err := h.cleanUpKube(kubeID, keepTasks); err != nil {
 			logrus.Errorf("clean up kube %s caused %v", kubeID, err)
 		}
 	}(t)
@@ -501,11 +943,12 @@ func (h *Handler) getKubeconfig(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *Handler) listResources(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
+// downloadKubeconfig returns admin kubeconfig of the kube as a yaml file,
+// kubes imported with token auth get the token instead of client certificate.
+func (h *Handler) downloadKubeconfig(w http.ResponseWriter, r *http.Request) {
+	kubeID := mux.Vars(r)["kubeID"]
 
-	kubeID := vars["kubeID"]
-	rawResources, err := h.svc.ListKubeResources(r.Context(), kubeID)
+	k, err := h.svc.Get(r.Context(), kubeID)
 	if err != nil {
 		if sgerrors.IsNotFound(err) {
 			message.SendNotFound(w, kubeID, err)
@@ -515,20 +958,56 @@ func (h *Handler) listResources(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err = w.Write(rawResources); err != nil {
+	kubeConfig, err := kubeconfig.AdminKubeConfig(k)
+	if err != nil {
+		logrus.Errorf("kubes: %s cluster: build kubeconfig: %v", kubeID, err)
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	data, err := kubeconfig.Marshal(kubeConfig)
+	if err != nil {
 		message.SendUnknownError(w, err)
+		return
+	}
+
+	data, err = yaml.JSONToYAML(data)
+	if err != nil {
+		message.SendUnknownError(w, errors.Wrap(err, "convert kubeconfig to yaml"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-yaml")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=%q", kubeconfigFileName(k)))
+
+	if _, err = w.Write(data); err != nil {
+		logrus.Errorf("kubes: %s cluster: download kubeconfig: write response: %v", kubeID, err)
 	}
 }
 
-func (h *Handler) getResource(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
+// grantAccess returns kubeconfig of a service account bound to the
+// requested role in the namespace, its token expires after the ttl.
+func (h *Handler) grantAccess(w http.ResponseWriter, r *http.Request) {
+	kubeID := mux.Vars(r)["kubeID"]
 
-	kubeID := vars["kubeID"]
-	rs := vars["resource"]
-	ns := r.URL.Query().Get("namespace")
-	name := r.URL.Query().Get("name")
+	req := AccessRequest{}
 
-	rawResources, err := h.svc.GetKubeResources(r.Context(), kubeID, rs, ns, name)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		message.SendInvalidJSON(w, err)
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		message.SendValidationFailed(w, err)
+		return
+	}
+
+	k, err := h.svc.Get(r.Context(), kubeID)
 	if err != nil {
 		if sgerrors.IsNotFound(err) {
 			message.SendNotFound(w, kubeID, err)
@@ -538,36 +1017,43 @@ func (h *Handler) getResource(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err = w.Write(rawResources); err != nil {
+	adminConfig, err := kubeconfig.AdminKubeConfig(k)
+	if err != nil {
 		message.SendUnknownError(w, err)
+		return
 	}
-}
-
-func (h *Handler) getCerts(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
 
-	kubeID := vars["kubeID"]
-	cname := vars["cname"]
+	clientSet, err := h.clientSetFor(k)
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
 
-	b, err := h.svc.GetCerts(r.Context(), kubeID, cname)
+	grant, err := grantAccess(r.Context(), clientSet, adminConfig, req)
 	if err != nil {
-		if sgerrors.IsNotFound(err) {
-			message.SendNotFound(w, kubeID, err)
+		logrus.Errorf("kubes: %s cluster: grant %s access to namespace %s: %v",
+			kubeID, req.Role, req.Namespace, err)
+		if sgerrors.IsValidationFailed(err) {
+			message.SendValidationFailed(w, err)
 			return
 		}
 		message.SendUnknownError(w, err)
 		return
 	}
 
-	if err = json.NewEncoder(w).Encode(b); err != nil {
-		message.SendUnknownError(w, err)
+	logrus.Infof("Kube %s: granted %s access to namespace %s as %s",
+		kubeID, req.Role, req.Namespace, grant.Name)
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(grant); err != nil {
+		logrus.Errorf("kubes: %s cluster: encode access grant: %v", kubeID, err)
 	}
 }
 
-func (h *Handler) listNodes(w http.ResponseWriter, r *http.Request) {
+// revokeAccess removes service account of a scoped kubeconfig.
+func (h *Handler) revokeAccess(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	kubeID := vars["kubeID"]
-	role := r.URL.Query().Get("role")
 
 	k, err := h.svc.Get(r.Context(), kubeID)
 	if err != nil {
@@ -579,15 +1065,125 @@ func (h *Handler) listNodes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	nodes, err := h.svc.ListNodes(r.Context(), k, role)
+	clientSet, err := h.clientSetFor(k)
 	if err != nil {
 		message.SendUnknownError(w, err)
 		return
 	}
 
-	if err = json.NewEncoder(w).Encode(nodes); err != nil {
-		message.SendUnknownError(w, err)
-	}
+	if err := revokeAccess(clientSet, vars["namespace"], vars["name"]); err != nil {
+		switch {
+		case sgerrors.IsNotFound(err):
+			message.SendNotFound(w, vars["name"], err)
+		case sgerrors.IsValidationFailed(err):
+			message.SendValidationFailed(w, err)
+		default:
+			message.SendUnknownError(w, err)
+		}
+		return
+	}
+
+	logrus.Infof("Kube %s: revoked access of %s/%s", kubeID, vars["namespace"], vars["name"])
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func kubeconfigFileName(k *model.Kube) string {
+	name := k.Name
+
+	if name == "" {
+		name = k.ID
+	}
+
+	return name + "-kubeconfig.yaml"
+}
+
+func (h *Handler) listResources(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	kubeID := vars["kubeID"]
+	rawResources, err := h.svc.ListKubeResources(r.Context(), kubeID)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if _, err = w.Write(rawResources); err != nil {
+		message.SendUnknownError(w, err)
+	}
+}
+
+func (h *Handler) getResource(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	kubeID := vars["kubeID"]
+	rs := vars["resource"]
+	ns := r.URL.Query().Get("namespace")
+	name := r.URL.Query().Get("name")
+
+	rawResources, err := h.svc.GetKubeResources(r.Context(), kubeID, rs, ns, name)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if _, err = w.Write(rawResources); err != nil {
+		message.SendUnknownError(w, err)
+	}
+}
+
+func (h *Handler) getCerts(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	kubeID := vars["kubeID"]
+	cname := vars["cname"]
+
+	b, err := h.svc.GetCerts(r.Context(), kubeID, cname)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(b); err != nil {
+		message.SendUnknownError(w, err)
+	}
+}
+
+func (h *Handler) listNodes(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	kubeID := vars["kubeID"]
+	role := r.URL.Query().Get("role")
+
+	k, err := h.svc.Get(r.Context(), kubeID)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	nodes, err := h.svc.ListNodes(r.Context(), k, role)
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(nodes); err != nil {
+		message.SendUnknownError(w, err)
+	}
 }
 
 // Add node to working kube
@@ -629,6 +1225,15 @@ func (h *Handler) addMachine(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	for i := range nodeProfiles {
+		nodeProfiles[i], err = profile.ResolveNodeProfile(kubeProfile.NodePools, nodeProfiles[i])
+
+		if err != nil {
+			message.SendValidationFailed(w, err)
+			return
+		}
+	}
+
 	acc, err := h.accountService.Get(r.Context(), k.AccountName)
 
 	if sgerrors.IsNotFound(err) {
@@ -665,9 +1270,12 @@ func (h *Handler) addMachine(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Add tasks ids to kube object
-	k.Tasks[workflows.NodeTask] = append(k.Tasks[workflows.NodeTask], tasks...)
+	_, err = retryUpdate(ctx, h.svc, kubeID, func(k *model.Kube) error {
+		k.Tasks[workflows.NodeTask] = append(k.Tasks[workflows.NodeTask], tasks...)
+		return nil
+	})
 
-	if err := h.svc.Create(ctx, k); err != nil {
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -714,7 +1322,31 @@ func (h *Handler) deleteMachine(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	acc, err := h.accountService.Get(r.Context(), k.AccountName)
+	drainCfg, err := drainConfigFromQuery(r.URL.Query())
+
+	if err != nil {
+		message.SendValidationFailed(w, err)
+		return
+	}
+
+	var acc *model.CloudAccount
+
+	// Imported kube without cloud account has its node only removed from k8s
+	if k.AccountName != "" {
+		acc, err = h.accountService.Get(r.Context(), k.AccountName)
+
+		if err != nil {
+			if sgerrors.IsNotFound(err) {
+				http.NotFound(w, r)
+				return
+			}
+
+			message.SendUnknownError(w, err)
+			return
+		}
+	}
+
+	t, config, writer, err := h.newDeleteNodeTask(k, n, acc, drainCfg)
 
 	if err != nil {
 		if sgerrors.IsNotFound(err) {
@@ -726,87 +1358,133 @@ func (h *Handler) deleteMachine(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Update cluster state when deletion completes
+	go func() {
+		prevState := n.State
+
+		// Set node to deleting state
+		h.updateKube(kubeID, func(k *model.Kube) {
+			nodeToDelete, ok := k.Nodes[nodeName]
+
+			if !ok {
+				logrus.Errorf("Node %s not found", nodeName)
+				return
+			}
+			nodeToDelete.State = model.MachineStateDeleting
+			// Deleted node must not be requested again
+			releaseSpotNode(k, nodeToDelete)
+			releasePoolNode(k, nodeToDelete)
+		})
+
+		err := <-t.Run(context.Background(), config.Clone(), writer)
+
+		// Node stays in the cluster when its drain has not been forced
+		if sgerrors.IsTimeoutExceeded(err) {
+			logrus.Errorf("delete node %s from cluster %s aborted %v", nodeName, kubeID, err)
+			h.updateKube(kubeID, func(k *model.Kube) {
+				if nodeToDelete, ok := k.Nodes[nodeName]; ok {
+					nodeToDelete.State = prevState
+				}
+			})
+
+			return
+		}
+
+		if err != nil {
+			logrus.Errorf("delete node %s from cluster %s caused %v", nodeName, kubeID, err)
+		}
+
+		// Delete node from cluster object
+		logrus.Infof("delete node %s from cluster %s", nodeName, kubeID)
+		h.updateKube(kubeID, func(k *model.Kube) {
+			delete(k.Nodes, nodeName)
+		})
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// newDeleteNodeTask creates task that drains the node and deletes
+// its machine, task log is written to the writer. Node is only
+// drained and removed from k8s when cloud account is nil.
+func (h *Handler) newDeleteNodeTask(k *model.Kube, n *model.Machine, acc *model.CloudAccount,
+	drainCfg steps.DrainConfig) (*workflows.Task, *steps.Config, io.WriteCloser, error) {
+	drainCfg.PrivateIP = n.PrivateIp
+	drainCfg.NodeName = n.Name
+
 	config := &steps.Config{
-		Kube:     *k,
-		Provider: k.Provider,
-		DrainConfig: steps.DrainConfig{
-			PrivateIP: n.PrivateIp,
-		},
+		Kube:             *k,
+		Provider:         k.Provider,
+		DrainConfig:      drainCfg,
 		CloudAccountName: k.AccountName,
 		Node:             *n,
 		Masters:          steps.NewMap(k.Masters),
 	}
 
-	t, err := workflows.NewTask(config, workflows.DeleteNode, h.repo)
-	if err != nil {
-		if sgerrors.IsNotFound(err) {
-			http.NotFound(w, r)
-			return
-		}
+	workflow := workflows.DeleteNode
 
-		message.SendUnknownError(w, err)
-		return
+	if acc == nil {
+		workflow = workflows.DrainNode
 	}
 
-	err = util.FillCloudAccountCredentials(acc, config)
+	t, err := workflows.NewTask(config, workflow, h.repo)
 
 	if err != nil {
-		if sgerrors.IsNotFound(err) {
-			http.NotFound(w, r)
-			return
-		}
-		message.SendUnknownError(w, err)
-		return
+		return nil, nil, nil, errors.Wrap(err, "new task")
 	}
 
-	err = util.LoadCloudSpecificDataFromKube(k, config)
+	if acc != nil {
+		if err := util.FillCloudAccountCredentials(acc, config); err != nil {
+			return nil, nil, nil, errors.Wrap(err, "fill cloud account credentials")
+		}
 
-	if err != nil {
-		message.SendUnknownError(w, err)
-		return
+		if err := util.LoadCloudSpecificDataFromKube(k, config); err != nil {
+			return nil, nil, nil, errors.Wrap(err, "load cloud specific data")
+		}
 	}
 
 	writer, err := h.getWriter(util.MakeFileName(t.ID))
 
 	if err != nil {
-		message.SendUnknownError(w, err)
-		return
+		return nil, nil, nil, errors.Wrap(err, "get writer")
 	}
 
-	// Update cluster state when deletion completes
-	go func() {
-		// Set node to deleting state
-		nodeToDelete, ok := k.Nodes[nodeName]
+	return t, config, writer, nil
+}
 
-		if !ok {
-			logrus.Errorf("Node %s not found", nodeName)
-			return
-		}
-		nodeToDelete.State = model.MachineStateDeleting
-		k.Nodes[nodeName] = nodeToDelete
-		err := h.svc.Create(context.Background(), k)
+// drainConfigFromQuery reads drain options of node deletion, grace
+// period and timeout are in seconds.
+func drainConfigFromQuery(query url.Values) (steps.DrainConfig, error) {
+	cfg := steps.DrainConfig{}
 
-		if err != nil {
-			logrus.Errorf("update cluster %s caused %v", kubeID, err)
+	for name, value := range map[string]*int64{
+		"gracePeriod": &cfg.GracePeriod,
+		"timeout":     &cfg.Timeout,
+	} {
+		param := query.Get(name)
+
+		if param == "" {
+			continue
 		}
 
-		err = <-t.Run(context.Background(), *config, writer)
+		n, err := strconv.ParseInt(param, 10, 64)
 
-		if err != nil {
-			logrus.Errorf("delete node %s from cluster %s caused %v", nodeName, kubeID, err)
+		if err != nil || n < 0 {
+			return cfg, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"%s %s must be a non-negative number of seconds", name, param)
 		}
 
-		// Delete node from cluster object
-		delete(k.Nodes, nodeName)
-		// Save cluster object to etcd
-		logrus.Infof("delete node %s from cluster %s", nodeName, kubeID)
-		err = h.svc.Create(context.Background(), k)
+		*value = n
+	}
 
-		if err != nil {
-			logrus.Errorf("update cluster %s caused %v", kubeID, err)
+	if force := query.Get("force"); force != "" {
+		var err error
+
+		if cfg.Force, err = strconv.ParseBool(force); err != nil {
+			return cfg, errors.Wrapf(sgerrors.ErrValidationFailed, "force %s must be a boolean", force)
 		}
-	}()
-	w.WriteHeader(http.StatusAccepted)
+	}
+
+	return cfg, nil
 }
 
 // TODO(stgleb): Create separte task service to manage task object lifecycle
@@ -817,10 +1495,25 @@ func (h *Handler) getKubeTasks(ctx context.Context, kubeID string) ([]*workflows
 		return nil, err
 	}
 
-	tasks := make([]*workflows.Task, 0, len(k.Tasks))
+	// Tasks of the kube record may have been created before tasks were indexed
+	tasks, err := workflows.GetKubeTasks(ctx, h.repo, kubeID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(tasks))
+
+	for _, task := range tasks {
+		found[task.ID] = true
+	}
 
 	for _, taskSet := range k.Tasks {
 		for _, taskID := range taskSet {
+			if found[taskID] {
+				continue
+			}
+
 			t, err := h.repo.Get(ctx, workflows.Prefix, taskID)
 
 			// If one of tasks not found we dont care, because
@@ -838,10 +1531,16 @@ func (h *Handler) getKubeTasks(ctx context.Context, kubeID string) ([]*workflows
 					"get task %s", taskID)
 			}
 
+			found[taskID] = true
 			tasks = append(tasks, task)
 		}
 	}
 
+	// Newest tasks go first
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].CreatedAt > tasks[j].CreatedAt
+	})
+
 	return tasks, nil
 }
 
@@ -853,7 +1552,7 @@ func (h *Handler) deleteClusterTasks(ctx context.Context, kubeID string) error {
 	}
 
 	for _, task := range tasks {
-		if err := h.repo.Delete(ctx, workflows.Prefix, task.ID); err != nil {
+		if err := workflows.RemoveTask(ctx, h.repo, h.logDir, task); err != nil {
 			logrus.Warnf("delete task %s: %v", task.ID, err)
 			return err
 		}
@@ -862,12 +1561,13 @@ func (h *Handler) deleteClusterTasks(ctx context.Context, kubeID string) error {
 	return nil
 }
 
-func (h *Handler) cleanUpKube(kubeID string) error {
-	// Clean up tasks in storage
-	err := h.deleteClusterTasks(context.Background(), kubeID)
-
-	if err != nil {
-		logrus.Errorf("error while cleanup kube tasks %s", err)
+// cleanUpKube deletes the kube record, history of its tasks is
+// deleted unless it is kept.
+func (h *Handler) cleanUpKube(kubeID string, keepTasks bool) error {
+	if !keepTasks {
+		if err := h.deleteClusterTasks(context.Background(), kubeID); err != nil {
+			logrus.Errorf("error while cleanup kube tasks %s", err)
+		}
 	}
 
 	// Finally delete cluster record from etcd
@@ -875,6 +1575,8 @@ func (h *Handler) cleanUpKube(kubeID string) error {
 		return errors.Wrap(err, "cleanup kube %s caused %v")
 	}
 
+	h.metricsCache.forget(kubeID)
+
 	return nil
 }
 
@@ -904,6 +1606,12 @@ func (h *Handler) installRelease(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Helm 3 releases are installed by the driver, there is no tiller to run helm against
+	if k.HelmMajorVersion == 3 {
+		h.installHelm3Release(w, r, kubeID, inp)
+		return
+	}
+
 	logrus.Debugf("Get cloud profile %s", k.ProfileID)
 	kubeProfile, err := h.profileSvc.Get(r.Context(), k.ProfileID)
 
@@ -924,6 +1632,28 @@ func (h *Handler) installRelease(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// helm merges only a single document of values on the master
+	values, err := h.svc.PrepareRelease(r.Context(), kubeID, releaseInput(inp))
+	if err != nil {
+		logrus.Errorf("helm: install release: %s cluster: %s/%s: %s",
+			kubeID, inp.RepoName, inp.ChartName, err)
+		if sgerrors.IsValidationFailed(err) {
+			message.SendValidationFailed(w, err)
+			return
+		}
+		if sgerrors.IsNotSupported(err) {
+			message.SendNotSupported(w, err)
+			return
+		}
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, inp.ChartName, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+	inp.Values = values
+
 	ref, err := h.chartGetter.GetChartRef(r.Context(), inp.RepoName, inp.ChartName, inp.ChartVersion)
 	if err != nil {
 		if sgerrors.IsNotFound(err) {
@@ -961,13 +1691,9 @@ func (h *Handler) installRelease(w http.ResponseWriter, r *http.Request) {
 
 	go func() {
 		// Save install task to kube
-		k.Tasks[workflows.InstallApp] = []string{installAppTask.ID}
-
-		err = h.svc.Create(context.Background(), k)
-
-		if err != nil {
-			logrus.Errorf("update cluster %s caused %v", kubeID, err)
-		}
+		h.updateKube(kubeID, func(k *model.Kube) {
+			k.Tasks[workflows.InstallApp] = []string{installAppTask.ID}
+		})
 
 		fileName := util.MakeFileName(installAppTask.ID)
 		writer, err := h.getWriter(fileName)
@@ -976,7 +1702,7 @@ func (h *Handler) installRelease(w http.ResponseWriter, r *http.Request) {
 			logrus.Errorf("error getting writer %v", err)
 		}
 
-		errCh := installAppTask.Run(context.Background(), *installAppTask.Config, writer)
+		errCh := installAppTask.Run(context.Background(), installAppTask.Config.Clone(), writer)
 		err = <-errCh
 
 		if err != nil {
@@ -996,21 +1722,216 @@ func (h *Handler) installRelease(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *Handler) getRelease(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-
-	kubeID := vars["kubeID"]
-	rlsName := vars["releaseName"]
-
-	rls, err := h.svc.ReleaseDetails(r.Context(), kubeID, rlsName)
+func (h *Handler) installHelm3Release(w http.ResponseWriter, r *http.Request,
+	kubeID string, inp *steps.InstallAppConfig) {
+	rls, err := h.svc.InstallRelease(r.Context(), kubeID, releaseInput(inp))
 	if err != nil {
-		logrus.Errorf("helm: get %s release: %s cluster: %s", rlsName, kubeID, err)
-		message.SendUnknownError(w, err)
-		return
-	}
-
-	if err = json.NewEncoder(w).Encode(rls); err != nil {
-		logrus.Errorf("helm: get %s release: %s cluster: write response: %s", rlsName, kubeID, err)
+		logrus.Errorf("helm: install release: %s cluster: %s/%s: %s",
+			kubeID, inp.RepoName, inp.ChartName, err)
+		if sgerrors.IsValidationFailed(err) {
+			message.SendValidationFailed(w, err)
+			return
+		}
+		if sgerrors.IsNotSupported(err) {
+			message.SendNotSupported(w, err)
+			return
+		}
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, inp.ChartName, err)
+			return
+		}
+		if sgerrors.IsAlreadyExists(err) {
+			message.SendAlreadyExists(w, inp.Name, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(rls); err != nil {
+		logrus.Errorf("helm: install release: %s cluster: write response: %s", kubeID, err)
+		message.SendUnknownError(w, err)
+	}
+}
+
+func releaseInput(inp *steps.InstallAppConfig) *ReleaseInput {
+	return &ReleaseInput{
+		Name:            inp.Name,
+		Namespace:       inp.Namespace,
+		ChartName:       inp.ChartName,
+		ChartVersion:    inp.ChartVersion,
+		RepoName:        inp.RepoName,
+		Values:          inp.Values,
+		ValuesFiles:     inp.ValuesFiles,
+		Set:             inp.Set,
+		CreateNamespace: inp.CreateNamespace,
+	}
+}
+
+func (h *Handler) upgradeRelease(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	kubeID := vars["kubeID"]
+	rlsName := vars["releaseName"]
+
+	inp := &ReleaseInput{}
+	if err := json.NewDecoder(r.Body).Decode(inp); err != nil {
+		logrus.Errorf("helm: upgrade release: decode: %s", err)
+		message.SendInvalidJSON(w, err)
+		return
+	}
+
+	if ok, err := govalidator.ValidateStruct(inp); !ok {
+		logrus.Errorf("helm: upgrade release: validation: %s", err)
+		message.SendValidationFailed(w, err)
+		return
+	}
+
+	rls, err := h.svc.UpgradeRelease(r.Context(), kubeID, rlsName, inp)
+	if err != nil {
+		logrus.Errorf("helm: upgrade release: %s cluster: release %s: %s", kubeID, rlsName, err)
+		if sgerrors.IsValidationFailed(err) {
+			message.SendValidationFailed(w, err)
+			return
+		}
+		if sgerrors.IsNotSupported(err) {
+			message.SendNotSupported(w, err)
+			return
+		}
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, rlsName, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(rls); err != nil {
+		logrus.Errorf("helm: upgrade release: %s cluster: write response: %s", kubeID, err)
+		message.SendUnknownError(w, err)
+	}
+}
+
+// diffRelease shows how the upgrade of the input changes values and
+// objects of the release, nothing is changed in the kube.
+func (h *Handler) diffRelease(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	kubeID := vars["kubeID"]
+	rlsName := vars["releaseName"]
+
+	inp := &ReleaseInput{}
+	if err := json.NewDecoder(r.Body).Decode(inp); err != nil {
+		logrus.Errorf("helm: diff release: decode: %s", err)
+		message.SendInvalidJSON(w, err)
+		return
+	}
+
+	if ok, err := govalidator.ValidateStruct(inp); !ok {
+		logrus.Errorf("helm: diff release: validation: %s", err)
+		message.SendValidationFailed(w, err)
+		return
+	}
+
+	diff, err := h.svc.DiffRelease(r.Context(), kubeID, rlsName, inp)
+	if err != nil {
+		logrus.Errorf("helm: diff release: %s cluster: release %s: %s", kubeID, rlsName, err)
+		if sgerrors.IsValidationFailed(err) {
+			message.SendValidationFailed(w, err)
+			return
+		}
+		if sgerrors.IsNotSupported(err) {
+			message.SendNotSupported(w, err)
+			return
+		}
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, rlsName, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(diff); err != nil {
+		logrus.Errorf("helm: diff release: %s cluster: write response: %s", kubeID, err)
+		message.SendUnknownError(w, err)
+	}
+}
+
+func (h *Handler) releaseHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	kubeID := vars["kubeID"]
+	rlsName := vars["releaseName"]
+
+	revisions, err := h.svc.ReleaseHistory(r.Context(), kubeID, rlsName)
+	if err != nil {
+		logrus.Errorf("helm: %s release history: %s cluster: %s", rlsName, kubeID, err)
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, rlsName, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(revisions); err != nil {
+		logrus.Errorf("helm: %s release history: %s cluster: write response: %s", rlsName, kubeID, err)
+		message.SendUnknownError(w, err)
+	}
+}
+
+func (h *Handler) rollbackRelease(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	kubeID := vars["kubeID"]
+	rlsName := vars["releaseName"]
+
+	inp := &RollbackInput{}
+	if err := json.NewDecoder(r.Body).Decode(inp); err != nil {
+		logrus.Errorf("helm: rollback release: decode: %s", err)
+		message.SendInvalidJSON(w, err)
+		return
+	}
+
+	if ok, err := govalidator.ValidateStruct(inp); !ok {
+		logrus.Errorf("helm: rollback release: validation: %s", err)
+		message.SendValidationFailed(w, err)
+		return
+	}
+
+	rlsInfo, err := h.svc.RollbackRelease(r.Context(), kubeID, rlsName, inp.Revision)
+	if err != nil {
+		logrus.Errorf("helm: rollback release: %s cluster: release %s: %s", kubeID, rlsName, err)
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, rlsName, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(rlsInfo); err != nil {
+		logrus.Errorf("helm: rollback release: %s cluster: write response: %s", kubeID, err)
+		message.SendUnknownError(w, err)
+	}
+}
+
+func (h *Handler) getRelease(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	kubeID := vars["kubeID"]
+	rlsName := vars["releaseName"]
+
+	rls, err := h.svc.ReleaseDetails(r.Context(), kubeID, rlsName)
+	if err != nil {
+		logrus.Errorf("helm: get %s release: %s cluster: %s", rlsName, kubeID, err)
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(rls); err != nil {
+		logrus.Errorf("helm: get %s release: %s cluster: write response: %s", rlsName, kubeID, err)
 		message.SendUnknownError(w, err)
 	}
 }
@@ -1043,6 +1964,15 @@ func (h *Handler) listReleases(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	markSystemReleases(rlsList)
+
+	// releases are listed without upgrades if repositories can not be read
+	if h.rlsAnnotator != nil {
+		if err = h.rlsAnnotator.AnnotateReleases(r.Context(), rlsList); err != nil {
+			logrus.Warnf("helm: list releases: %s cluster: annotate upgrades: %s", kubeID, err)
+		}
+	}
+
 	if err = json.NewEncoder(w).Encode(rlsList); err != nil {
 		logrus.Errorf("helm: list releases: %s cluster: write response: %s", kubeID, err)
 		message.SendUnknownError(w, err)
@@ -1055,6 +1985,14 @@ func (h *Handler) deleteReleases(w http.ResponseWriter, r *http.Request) {
 	kubeID := vars["kubeID"]
 	rlsName := vars["releaseName"]
 	purge, _ := strconv.ParseBool(r.URL.Query().Get("purge"))
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+
+	// control stops working without its components
+	if isSystemRelease(rlsName) && !force {
+		message.SendValidationFailed(w, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"release %s is a system component, set force to delete it", rlsName))
+		return
+	}
 
 	rls, err := h.svc.DeleteRelease(r.Context(), kubeID, rlsName, purge)
 	if err != nil {
@@ -1070,15 +2008,6 @@ func (h *Handler) deleteReleases(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) getClusterMetrics(w http.ResponseWriter, r *http.Request) {
-	var (
-		metricsRelUrls = map[string]string{
-			"cpu":    "api/v1/query?query=:node_cpu_utilisation:avg1m",
-			"memory": "api/v1/query?query=:node_memory_utilisation:",
-		}
-		response = map[string]interface{}{}
-		baseUrl  = "api/v1/namespaces/kube-system/services/prometheus-operated:9090/proxy"
-	)
-
 	vars := mux.Vars(r)
 	kubeID := vars["kubeID"]
 
@@ -1092,13 +2021,27 @@ func (h *Handler) getClusterMetrics(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.serveMetrics(w, r, kubeID, func() (interface{}, error) {
+		return h.clusterMetrics(k)
+	})
+}
+
+func (h *Handler) clusterMetrics(k *model.Kube) (map[string]interface{}, error) {
+	var (
+		metricsRelUrls = map[string]string{
+			"cpu":    "api/v1/query?query=:node_cpu_utilisation:avg1m",
+			"memory": "api/v1/query?query=:node_memory_utilisation:",
+		}
+		response = map[string]interface{}{}
+		baseUrl  = "api/v1/namespaces/kube-system/services/prometheus-operated:9090/proxy"
+	)
+
 	for metricType, relUrl := range metricsRelUrls {
 		url := fmt.Sprintf("/%s/%s", baseUrl, relUrl)
 		metricResponse, err := h.getMetrics(url, k)
 
 		if err != nil {
-			message.SendUnknownError(w, err)
-			return
+			return nil, err
 		}
 
 		if len(metricResponse.Data.Result) > 0 && len(metricResponse.Data.Result[0].Value) > 1 {
@@ -1106,24 +2049,10 @@ func (h *Handler) getClusterMetrics(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	err = json.NewEncoder(w).Encode(response)
-
-	if err != nil {
-		message.SendUnknownError(w, err)
-		return
-	}
+	return response, nil
 }
 
 func (h *Handler) getNodesMetrics(w http.ResponseWriter, r *http.Request) {
-	var (
-		metricsRelUrls = map[string]string{
-			"cpu":    "api/v1/query?query=node:node_cpu_utilisation:avg1m",
-			"memory": "api/v1/query?query=node:node_memory_utilisation:",
-		}
-		response = map[string]map[string]interface{}{}
-		baseUrl  = "api/v1/namespaces/kube-system/services/prometheus-operated:9090/proxy"
-	)
-
 	vars := mux.Vars(r)
 	kubeID := vars["kubeID"]
 
@@ -1137,20 +2066,45 @@ func (h *Handler) getNodesMetrics(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	for metricType, relUrl := range metricsRelUrls {
-		url := fmt.Sprintf("/%s/%s", baseUrl, relUrl)
-		metricResponse, err := h.getMetrics(url, k)
+	if duration := r.URL.Query().Get("duration"); duration != "" {
+		step := r.URL.Query().Get("step")
+
+		h.serveMetrics(w, r, kubeID, func() (interface{}, error) {
+			return h.nodesRangeMetrics(r.Context(), k, duration, step)
+		})
+		return
+	}
+
+	h.serveMetrics(w, r, kubeID, func() (interface{}, error) {
+		return h.nodesMetrics(k)
+	})
+}
+
+func (h *Handler) nodesMetrics(k *model.Kube) (map[string]interface{}, error) {
+	var (
+		response = map[string]map[string]interface{}{}
+		baseUrl  = "api/v1/namespaces/kube-system/services/prometheus-operated:9090/proxy"
+	)
+
+	for metricType, query := range nodeMetricsQueries {
+		uri := fmt.Sprintf("/%s/api/v1/query?query=%s", baseUrl, url.QueryEscape(query))
+		metricResponse, err := h.getMetrics(uri, k)
 
 		if err != nil {
-			message.SendUnknownError(w, err)
-			return
+			return nil, err
 		}
 
 		for _, result := range metricResponse.Data.Result {
 			// Get node name of the metric
 			nodeName, ok := result.Metric["node"]
 
-			if !ok {
+			if !ok || len(result.Value) < 2 {
+				continue
+			}
+
+			// Metrics missing on node are omitted, e.g. root filesystem
+			// of nodes that are not reported by node-exporter
+			if v, ok := metricValue(result.Value[1]); !ok || math.IsNaN(v) {
 				continue
 			}
 			// If dict for this node is empty - fill it with empty map
@@ -1162,19 +2116,49 @@ func (h *Handler) getNodesMetrics(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if k.Provider == clouds.AWS {
-		processAWSMetrics(k, response)
+	response = processMetrics(k, response)
+
+	// Cluster totals are returned along with nodes
+	result := make(map[string]interface{}, len(response)+1)
+
+	for nodeName, nodeMetrics := range response {
+		result[nodeName] = nodeMetrics
 	}
 
-	err = json.NewEncoder(w).Encode(response)
+	result[ClusterMetricsKey] = aggregateMetrics(k, response)
+
+	return result, nil
+}
+
+func (h *Handler) listAlertRules(w http.ResponseWriter, r *http.Request) {
+	kubeID := mux.Vars(r)["kubeID"]
 
+	k, err := h.svc.Get(r.Context(), kubeID)
 	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
 		message.SendUnknownError(w, err)
 		return
 	}
+
+	rules := make([]*model.AlertRule, 0, len(k.AlertRules))
+
+	for _, rule := range k.AlertRules {
+		rules = append(rules, rule)
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+
+	if err := json.NewEncoder(w).Encode(rules); err != nil {
+		message.SendUnknownError(w, err)
+	}
 }
 
-func (h *Handler) getServices(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) getAlertRule(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	kubeID := vars["kubeID"]
 
@@ -1188,90 +2172,85 @@ func (h *Handler) getServices(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO: use sg specific label
-	selector := fmt.Sprintf("%s=%s", clusterService, "true")
-	svcList, err := h.listK8sServices(k, selector)
-	if err != nil {
-		message.SendUnknownError(w, err)
+	rule, ok := k.AlertRules[vars["ruleID"]]
+
+	if !ok {
+		message.SendNotFound(w, vars["ruleID"], sgerrors.ErrNotFound)
 		return
 	}
 
-	// TODO(stgleb): Figure out which ports are worth to be proxy. These are name aliases for ports!
-	webPorts := map[string]struct{}{
-		"web":     {},
-		"http":    {},
-		"https":   {},
-		"service": {},
+	if err := json.NewEncoder(w).Encode(rule); err != nil {
+		message.SendUnknownError(w, err)
 	}
+}
 
-	var serviceInfos = make([]*ServiceInfo, 0)
-	var targetServices = make([]proxy.Target, 0)
+// createAlertRule adds the rule to the kube, PUT to the rule
+// path replaces the existing rule.
+func (h *Handler) createAlertRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	kubeID := vars["kubeID"]
+	ruleID, update := vars["ruleID"]
 
-	cfg, err := kubeconfig.NewConfigFor(k)
-	if err != nil {
-		message.SendUnknownError(w, err)
+	req := model.AlertRule{}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		message.SendInvalidJSON(w, err)
 		return
 	}
 
-	for _, service := range svcList.Items {
-		for _, port := range service.Spec.Ports {
-			if _, ok := webPorts[port.Name]; !ok && port.Protocol != "TCP" {
-				continue
-			}
+	req.ID = ruleID
 
-			var serviceInfo = &ServiceInfo{
-				ID:        string(service.UID),
-				Name:      service.Name,
-				Namespace: service.Namespace,
-				Type:      string(service.Spec.Type),
-			}
-			serviceInfos = append(serviceInfos, serviceInfo)
-
-			targetServices = append(targetServices, proxy.Target{
-				ProxyID: kubeID + string(service.ObjectMeta.UID),
-				// TargetPort is ignored here
-				TargetURL:  fmt.Sprintf("%s%s:%d/proxy", cfg.Host, service.ObjectMeta.SelfLink, port.Port),
-				KubeConfig: cfg,
-			})
-		}
+	rule, err := newAlertRule(req)
+	if err != nil {
+		message.SendValidationFailed(w, err)
+		return
 	}
 
-	// TODO implement proxy removing logic under separate ticket
-	err = h.proxies.RegisterProxies(targetServices)
+	k, err := h.svc.Get(r.Context(), kubeID)
 	if err != nil {
-		logrus.Error(err)
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
 		message.SendUnknownError(w, err)
 		return
 	}
 
-	proxies := h.proxies.GetProxies(kubeID)
-	for _, service := range serviceInfos {
-		if proxies[kubeID+service.ID] == nil {
-			continue
-		}
-		service.ProxyPort = proxies[kubeID+service.ID].Port()
+	if _, ok := k.AlertRules[ruleID]; update && !ok {
+		message.SendNotFound(w, ruleID, sgerrors.ErrNotFound)
+		return
 	}
 
-	err = json.NewEncoder(w).Encode(serviceInfos)
-	if err != nil {
+	if k.AlertRules == nil {
+		k.AlertRules = make(map[string]*model.AlertRule)
+	}
+
+	k.AlertRules[rule.ID] = rule
+
+	if err := h.svc.Update(r.Context(), k); err != nil {
+		if sgerrors.IsConflict(err) {
+			h.sendConflict(w, r, k.ID, err)
+			return
+		}
+
 		message.SendUnknownError(w, err)
+		return
 	}
-}
 
-func contains(name, value string, labels map[string]string) bool {
-	v, exists := labels[name]
-	if exists && v == value {
-		return true
+	if !update {
+		w.WriteHeader(http.StatusCreated)
 	}
 
-	return false
+	if err := json.NewEncoder(w).Encode(rule); err != nil {
+		logrus.Errorf("kubes: %s cluster: encode alert rule %v", kubeID, err)
+	}
 }
 
-func (h *Handler) restartKubeProvisioning(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) deleteAlertRule(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	kubeID := vars["kubeID"]
+	ruleID := vars["ruleID"]
 
-	logrus.Debugf("Get kube %s", kubeID)
 	k, err := h.svc.Get(r.Context(), kubeID)
 	if err != nil {
 		if sgerrors.IsNotFound(err) {
@@ -1282,12 +2261,16 @@ func (h *Handler) restartKubeProvisioning(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	logrus.Debugf("Get cloud profile %s", k.ProfileID)
-	kubeProfile, err := h.profileSvc.Get(r.Context(), k.ProfileID)
+	if _, ok := k.AlertRules[ruleID]; !ok {
+		message.SendNotFound(w, ruleID, sgerrors.ErrNotFound)
+		return
+	}
 
-	if err != nil {
-		if sgerrors.IsNotFound(err) {
-			message.SendNotFound(w, k.ProfileID, err)
+	delete(k.AlertRules, ruleID)
+
+	if err := h.svc.Update(r.Context(), k); err != nil {
+		if sgerrors.IsConflict(err) {
+			h.sendConflict(w, r, k.ID, err)
 			return
 		}
 
@@ -1295,255 +2278,367 @@ func (h *Handler) restartKubeProvisioning(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	config, err := steps.NewConfigFromKube(kubeProfile, k)
-	if err != nil {
-		logrus.Errorf("New config %v", err.Error())
-		message.SendUnknownError(w, err)
-		return
-	}
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	logrus.Debugf("load clout specific data from kube %s", k.ID)
-	// Load things specific to cloud provider
-	err = util.LoadCloudSpecificDataFromKube(k, config)
+// getWorkloadMetrics returns top namespaces or pods by usage,
+// e.g. /metrics/namespaces?sortBy=cpu&limit=5.
+func (h *Handler) getWorkloadMetrics(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	kubeID := vars["kubeID"]
+	groupBy := vars["groupBy"]
 
-	if err != nil {
-		message.SendUnknownError(w, err)
+	sortBy := r.URL.Query().Get("sortBy")
+
+	if sortBy == "" {
+		sortBy = "memory"
+	}
+
+	if sortBy != "memory" && sortBy != "cpu" {
+		message.SendValidationFailed(w, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"sortBy %s must be one of memory, cpu", sortBy))
 		return
 	}
 
-	logrus.Debugf("Get cloud account %s", k.AccountName)
-	acc, err := h.accountService.Get(r.Context(), k.AccountName)
+	limit := DefaultWorkloadsLimit
 
-	if err != nil {
-		if sgerrors.IsNotFound(err) {
-			http.NotFound(w, r)
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+
+		if limit, err = strconv.Atoi(l); err != nil || limit <= 0 || limit > MaxWorkloadsLimit {
+			message.SendValidationFailed(w, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"limit %s must be between 1 and %d", l, MaxWorkloadsLimit))
 			return
 		}
-
-		message.SendUnknownError(w, err)
-		return
 	}
 
-	logrus.Debug("Fill config with cloud account credentials")
-	err = util.FillCloudAccountCredentials(acc, config)
-
+	k, err := h.svc.Get(r.Context(), kubeID)
 	if err != nil {
 		if sgerrors.IsNotFound(err) {
-			http.NotFound(w, r)
+			message.SendNotFound(w, kubeID, err)
 			return
 		}
 		message.SendUnknownError(w, err)
 		return
 	}
 
-	logrus.Debugf("Restart cluster %s provisioning", k.ID)
-	err = h.kubeProvisioner.RestartClusterProvisioning(r.Context(),
-		kubeProfile, config, k.Tasks)
+	h.serveMetrics(w, r, kubeID, func() (interface{}, error) {
+		return h.workloadMetrics(k, groupBy, sortBy, limit)
+	})
+}
+
+func (h *Handler) workloadMetrics(k *model.Kube, groupBy, sortBy string, limit int) ([]WorkloadMetrics, error) {
+	baseUrl := "api/v1/namespaces/kube-system/services/prometheus-operated:9090/proxy"
+
+	// Requests are missing rather than zero without kube-state-metrics
+	metricResponse, err := h.getMetrics(fmt.Sprintf("/%s/api/v1/query?query=%s",
+		baseUrl, url.QueryEscape(kubeStateMetricsQuery)), k)
 
 	if err != nil {
-		message.SendUnknownError(w, err)
-		return
+		return nil, err
 	}
 
-	w.WriteHeader(http.StatusAccepted)
-}
+	if len(metricResponse.Data.Result) == 0 {
+		return nil, errors.Wrapf(sgerrors.ErrKubeStateMetricsNotFound, "kube %s", k.ID)
+	}
 
-func (h *Handler) importKube(w http.ResponseWriter, r *http.Request) {
-	type importRequest struct {
-		KubeConfig       string          `json:"kubeconfig"`
-		ClusterName      string          `json:"clusterName"`
-		CloudAccountName string          `json:"cloudAccountName"`
-		PublicKey        string          `json:"publicKey"`
-		PrivateKey       string          `json:"privateKey"`
-		Profile          profile.Profile `json:"profile" valid:"-"`
+	workloads := map[[2]string]*WorkloadMetrics{}
+
+	for metricType, query := range workloadQueries(groupBy) {
+		uri := fmt.Sprintf("/%s/api/v1/query?query=%s", baseUrl, url.QueryEscape(query))
+		metricResponse, err := h.getMetrics(uri, k)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range metricResponse.Data.Result {
+			if len(result.Value) < 2 {
+				continue
+			}
+
+			v, ok := metricValue(result.Value[1])
+
+			if !ok || math.IsNaN(v) {
+				continue
+			}
+
+			namespace, pod := workloadKey(result.Metric)
+
+			if namespace == "" || (groupBy == "pods" && pod == "") {
+				continue
+			}
+
+			key := [2]string{namespace, pod}
+
+			if workloads[key] == nil {
+				workloads[key] = &WorkloadMetrics{Namespace: namespace, Pod: pod}
+			}
+
+			switch metricType {
+			case "cpu":
+				workloads[key].CPU = v
+			case "memory":
+				workloads[key].Memory = v
+			case "cpuRequests":
+				workloads[key].CPURequests = v
+			case "memoryRequests":
+				workloads[key].MemoryRequests = v
+			}
+		}
 	}
 
-	var req importRequest
-	var err error
+	result := make([]WorkloadMetrics, 0, len(workloads))
 
-	err = json.NewDecoder(r.Body).Decode(&req)
+	for _, workload := range workloads {
+		result = append(result, *workload)
+	}
+
+	return topWorkloads(result, sortBy, limit), nil
+}
+
+// nodesRangeMetrics returns series of node metrics for the duration
+// until now, e.g. duration=1h&step=30s.
+func (h *Handler) nodesRangeMetrics(ctx context.Context, k *model.Kube,
+	duration, step string) (map[string]map[string]interface{}, error) {
+	d, err := time.ParseDuration(duration)
 
 	if err != nil {
-		logrus.Error(err)
-		message.SendInvalidJSON(w, err)
-		return
+		return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"duration %s: %v", duration, err)
 	}
 
-	kubeConfig, err := clientcmd.Load([]byte(req.KubeConfig))
+	end := time.Now()
+	start := end.Add(-d)
+	response := map[string]map[string]interface{}{}
+
+	for metricType, query := range nodeMetricsQueries {
+		uri, err := MetricsQuery{
+			Query: query,
+			Start: &start,
+			End:   &end,
+			Step:  step,
+		}.uri()
+
+		if err != nil {
+			return nil, err
+		}
+
+		metricResponse, err := h.getRangeMetrics(ctx, uri, k)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range metricResponse.Data.Result {
+			nodeName, ok := result.Metric["node"]
+
+			if !ok {
+				continue
+			}
+
+			samples := metricSamples(result.Values)
+
+			if len(samples) == 0 {
+				continue
+			}
+
+			if response[nodeName] == nil {
+				response[nodeName] = map[string]interface{}{}
+			}
+
+			response[nodeName][metricType] = downsample(samples, MaxSeriesPoints)
+		}
+	}
+
+	return processMetrics(k, response), nil
+}
+
+// serveMetrics writes metrics of the kube through cache, refresh=true
+// bypasses it. Age header tells how old the metrics are, stale metrics
+// served when prometheus fails have Warning header.
+func (h *Handler) serveMetrics(w http.ResponseWriter, r *http.Request, kubeID string,
+	fetch func() (interface{}, error)) {
+	params := r.URL.Query()
+	refresh, _ := strconv.ParseBool(params.Get("refresh"))
+	params.Del("refresh")
+
+	entry, stale, err := h.metricsCache.get(r.Context(), kubeID,
+		r.URL.Path+"?"+params.Encode(), refresh, fetch)
 
 	if err != nil {
-		logrus.Error(err)
-		message.SendInvalidJSON(w, err)
+		sendQueryError(w, err)
 		return
 	}
 
-	k8sVersion, err := h.discoverK8SVersion(kubeConfig)
+	w.Header().Set("Age", strconv.Itoa(int(time.Since(entry.fetchedAt)/time.Second)))
+
+	if stale {
+		w.Header().Set("Warning", `110 - "Response is Stale"`)
+	}
+
+	err = json.NewEncoder(w).Encode(entry.value)
 
 	if err != nil {
 		message.SendUnknownError(w, err)
 		return
 	}
+}
+
+func (h *Handler) queryClusterMetrics(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	kubeID := vars["kubeID"]
 
-	helmVersion, err := h.discoverK8SVersion(kubeConfig)
+	query := MetricsQuery{}
 
-	if err != nil {
-		message.SendUnknownError(w, err)
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		message.SendInvalidJSON(w, err)
 		return
 	}
 
-	req.Profile.HelmVersion = helmVersion
-	config, err := steps.NewConfig(req.ClusterName, req.CloudAccountName, req.Profile)
+	uri, err := query.uri()
 
 	if err != nil {
-		logrus.Errorf("build provisioning config: %s", err)
-		message.SendUnknownError(w, err)
+		message.SendValidationFailed(w, err)
 		return
 	}
 
-	importTask, err := workflows.NewTask(config, workflows.ImportCluster, h.repo)
-
+	k, err := h.svc.Get(r.Context(), kubeID)
 	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
 		message.SendUnknownError(w, err)
 		return
 	}
 
-	kube, err := kubeFromKubeConfig(*kubeConfig)
+	ctx, cancel := context.WithTimeout(r.Context(), QueryTimeout)
+	defer cancel()
+
+	raw, err := h.queryMetrics(ctx, uri, k)
 
 	if err != nil {
-		message.SendInvalidCredentials(w, err)
+		sendQueryError(w, err)
 		return
 	}
-	// Grab all k8s nodes from kube-apiserver
-	nodes, err := h.svc.ListNodes(r.Context(), kube, "")
-	if err != nil {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(raw)
+}
+
+func sendQueryError(w http.ResponseWriter, err error) {
+	switch {
+	case sgerrors.IsPrometheusNotFound(err):
+		message.SendMessage(w, message.New("Prometheus is not deployed to the kube",
+			err.Error(), sgerrors.PrometheusNotFound, ""), http.StatusNotFound)
+	case sgerrors.IsKubeStateMetricsNotFound(err):
+		message.SendMessage(w, message.New("kube-state-metrics is not deployed to the kube",
+			err.Error(), sgerrors.KubeStateMetricsNotFound, ""), http.StatusNotFound)
+	case sgerrors.IsValidationFailed(err):
+		message.SendValidationFailed(w, err)
+	case sgerrors.IsTimeoutExceeded(err):
+		message.SendMessage(w, message.New("Query timed out",
+			err.Error(), sgerrors.TimeoutExceeded, ""), http.StatusGatewayTimeout)
+	default:
 		message.SendUnknownError(w, err)
-		return
 	}
+}
 
-	cloudAccount, err := h.accountService.Get(r.Context(), req.CloudAccountName)
+func (h *Handler) getServices(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	kubeID := vars["kubeID"]
 
+	k, err := h.svc.Get(r.Context(), kubeID)
 	if err != nil {
 		if sgerrors.IsNotFound(err) {
-			message.SendNotFound(w, req.CloudAccountName, err)
+			message.SendNotFound(w, kubeID, err)
 			return
 		}
 		message.SendUnknownError(w, err)
 		return
 	}
 
-	err = util.FillCloudAccountCredentials(cloudAccount, config)
-
+	// TODO: use sg specific label
+	selector := fmt.Sprintf("%s=%s", clusterService, "true")
+	svcList, err := h.listK8sServices(k, selector)
 	if err != nil {
 		message.SendUnknownError(w, err)
 		return
 	}
 
-	var clusterID string
-
-	if len(importTask.ID) > 8 {
-		clusterID = importTask.ID[:8]
-	} else {
-		message.SendValidationFailed(w, errors.New("import task id is too short"))
-		return
-	}
-
-	config.Kube.ID = clusterID
-	config.IsBootstrap = true
-	config.Kube.SSHConfig.BootstrapPrivateKey = req.PrivateKey
-	config.Kube.SSHConfig.PublicKey = req.PublicKey
-	config.Kube.Auth = kube.Auth
-	config.Kube.ExternalDNSName = kube.ExternalDNSName
-	config.Kube.K8SVersion = k8sVersion
-	config.IsImport = true
-
-	if err := createKube(config, model.StateImporting, req.Profile, importTask.ID, h); err != nil {
-		message.SendUnknownError(w, errors.Wrapf(err, "create importing kube"))
-	}
-
-	if err := h.profileSvc.Create(r.Context(), &req.Profile); err != nil {
-		message.SendUnknownError(w, errors.Wrap(err, "save profile"))
+	// TODO(stgleb): Figure out which ports are worth to be proxy. These are name aliases for ports!
+	webPorts := map[string]struct{}{
+		"web":     {},
+		"http":    {},
+		"https":   {},
+		"service": {},
 	}
 
-	w.WriteHeader(http.StatusAccepted)
-	err = json.NewEncoder(w).Encode(struct {
-		ClusterID string `json:"clusterId"`
-	}{
-		ClusterID: clusterID,
-	})
+	var serviceInfos = make([]*ServiceInfo, 0)
+	var targetServices = make([]proxy.Target, 0)
 
+	cfg, err := kubeconfig.NewConfigFor(k)
 	if err != nil {
-		message.SendInvalidJSON(w, err)
+		message.SendUnknownError(w, err)
 		return
 	}
 
-	go func() {
-		fileName := util.MakeFileName(importTask.ID)
-		writer, err := h.getWriter(fileName)
-
-		if err != nil {
-			message.SendUnknownError(w, err)
-			return
-		}
-
-		masters := make(map[string]*model.Machine)
-		workers := make(map[string]*model.Machine)
-
-		for _, node := range nodes {
-			machine := model.Machine{}
-
-			for _, address := range node.Status.Addresses {
-				if address.Type == "ExternalIP" {
-					machine.PublicIp = address.Address
-				} else if address.Type == "InternalIP" {
-					machine.PrivateIp = address.Address
-				}
+	for _, service := range svcList.Items {
+		for _, port := range service.Spec.Ports {
+			if _, ok := webPorts[port.Name]; !ok && port.Protocol != "TCP" {
+				continue
 			}
 
-			machine.Role = model.RoleNode
-
-			for key := range node.Labels {
-				if key == "node-role.kubernetes.io/master" {
-					machine.Role = model.RoleMaster
-				}
+			var serviceInfo = &ServiceInfo{
+				ID:        string(service.UID),
+				Name:      service.Name,
+				Namespace: service.Namespace,
+				Type:      string(service.Spec.Type),
 			}
+			serviceInfos = append(serviceInfos, serviceInfo)
 
-			machine.Name = fmt.Sprintf("%s-%s-%s", config.Kube.Name, machine.Role, uuid.New()[:4])
-
-			if machine.Role == model.RoleMaster {
-				masters[machine.Name] = &machine
-			} else {
-				workers[machine.Name] = &machine
-			}
+			targetServices = append(targetServices, proxy.Target{
+				ProxyID: kubeID + string(service.ObjectMeta.UID),
+				// TargetPort is ignored here
+				TargetURL:  fmt.Sprintf("%s%s:%d/proxy", cfg.Host, service.ObjectMeta.SelfLink, port.Port),
+				KubeConfig: cfg,
+			})
 		}
+	}
 
-		config.Masters = steps.NewMap(masters)
-		config.Nodes = steps.NewMap(workers)
-
-		importTask.Config = config
-		resultChan := importTask.Run(context.Background(), *importTask.Config, writer)
-		err = <-resultChan
+	// TODO implement proxy removing logic under separate ticket
+	err = h.proxies.RegisterProxies(targetServices)
+	if err != nil {
+		logrus.Error(err)
+		message.SendUnknownError(w, err)
+		return
+	}
 
-		if err != nil {
-			logrus.Errorf("task %s has finished with error %v", importTask.ID, err)
+	proxies := h.proxies.GetProxies(kubeID)
+	for _, service := range serviceInfos {
+		if proxies[kubeID+service.ID] == nil {
+			continue
 		}
+		service.ProxyPort = proxies[kubeID+service.ID].Port()
+	}
 
-		state := model.StateOperational
-
-		if err != nil {
-			state = model.StateFailed
-		}
+	err = json.NewEncoder(w).Encode(serviceInfos)
+	if err != nil {
+		message.SendUnknownError(w, err)
+	}
+}
 
-		if err := createKube(importTask.Config, state, req.Profile, importTask.ID, h); err != nil {
-			logrus.Errorf("error creating kube %v", err)
-		}
+func contains(name, value string, labels map[string]string) bool {
+	v, exists := labels[name]
+	if exists && v == value {
+		return true
+	}
 
-		logrus.Infof("Import task %s has successfully finished", importTask.ID)
-	}()
+	return false
 }
 
-func (h *Handler) upgradeKube(w http.ResponseWriter, r *http.Request) {
-	var err error
-
+func (h *Handler) restartKubeProvisioning(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	kubeID := vars["kubeID"]
 
@@ -1558,150 +2653,1212 @@ func (h *Handler) upgradeKube(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if k.State != model.StateOperational {
-		w.WriteHeader(http.StatusNoContent)
-		logrus.Infof("Cluster %s is not operational", k.ID)
+	if err := h.restartKube(r.Context(), k); err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, k.ID, err)
+			return
+		}
+		if sgerrors.IsValidationFailed(err) {
+			message.SendValidationFailed(w, err)
+			return
+		}
+		message.SendUnknownError(w, err)
 		return
 	}
 
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// restartKube restarts the provisioning orchestration of the kube from
+// the tasks it has already.
+func (h *Handler) restartKube(ctx context.Context, k *model.Kube) error {
 	logrus.Debugf("Get cloud profile %s", k.ProfileID)
-	kubeProfile, err := h.profileSvc.Get(r.Context(), k.ProfileID)
+	kubeProfile, err := h.profileSvc.Get(ctx, k.ProfileID)
+	if err != nil {
+		return errors.Wrapf(err, "get profile %s", k.ProfileID)
+	}
 
+	config, err := steps.NewConfigFromKube(kubeProfile, k)
 	if err != nil {
-		if sgerrors.IsNotFound(err) {
-			message.SendNotFound(w, k.ProfileID, err)
-			return
+		return errors.Wrap(err, "new config")
+	}
+
+	logrus.Debugf("load clout specific data from kube %s", k.ID)
+	// Load things specific to cloud provider
+	if err := util.LoadCloudSpecificDataFromKube(k, config); err != nil {
+		return errors.Wrap(err, "load cloud specific data")
+	}
+
+	logrus.Debugf("Get cloud account %s", k.AccountName)
+	acc, err := h.accountService.Get(ctx, k.AccountName)
+	if err != nil {
+		return errors.Wrapf(err, "get cloud account %s", k.AccountName)
+	}
+
+	logrus.Debug("Fill config with cloud account credentials")
+	if err := util.FillCloudAccountCredentials(acc, config); err != nil {
+		return errors.Wrap(err, "fill cloud account credentials")
+	}
+
+	logrus.Debugf("Restart cluster %s provisioning", k.ID)
+	return h.kubeProvisioner.RestartClusterProvisioning(ctx,
+		kubeProfile, config, k.Tasks)
+}
+
+// RestartTask resumes the task of the kube. When the task is one of the
+// provisioning tasks of a kube that is not running yet, the whole cluster
+// provisioning is restarted, otherwise the task alone is resumed and
+// its updates are saved to the kube.
+func (h *Handler) RestartTask(ctx context.Context, task *workflows.Task, out io.WriteCloser) error {
+	if task.KubeID == "" {
+		out.Close()
+		return errors.Wrapf(sgerrors.ErrValidationFailed, "task %s has no kube", task.ID)
+	}
+
+	k, err := h.svc.Get(ctx, task.KubeID)
+	if err != nil {
+		out.Close()
+		return errors.Wrapf(err, "get kube %s", task.KubeID)
+	}
+
+	if k.State != model.StateOperational && isProvisioningTask(k, task.ID) {
+		// Provisioning tasks write their own logs
+		out.Close()
+		return h.restartKube(ctx, k)
+	}
+
+	switch task.Type {
+	case workflows.SpotInstance, workflows.SpotFleet:
+		if task.Config == nil {
+			out.Close()
+			return errors.Wrapf(sgerrors.ErrNilEntity, "config of task %s", task.ID)
 		}
 
-		message.SendUnknownError(w, err)
+		// Spot machines and requests are saved to the kube as spot task does
+		go h.runSpotTask(k.ID, task, task.Config, out)
+		return nil
+	}
+
+	return h.kubeProvisioner.RestartTask(ctx, k.ID, task, out)
+}
+
+func isProvisioningTask(k *model.Kube, taskID string) bool {
+	for _, taskType := range []string{workflows.PreProvisionTask,
+		workflows.EtcdTask, workflows.MasterTask,
+		workflows.NodeTask, workflows.ClusterTask} {
+		for _, id := range k.Tasks[taskType] {
+			if id == taskID {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ImportWarning tells that kube has been imported but something went wrong.
+type ImportWarning struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+func unreachableWarning(err error) *ImportWarning {
+	return &ImportWarning{
+		Reason:  "Unreachable",
+		Message: fmt.Sprintf("cluster imported but unreachable: %v", err),
+	}
+}
+
+// SetDiscoveryTimeout sets timeout of requests to kubes being imported.
+func (h *Handler) SetDiscoveryTimeout(timeout time.Duration) {
+	h.discoveryTimeout = timeout
+}
+
+// SetReleaseAnnotator sets the annotator of listed releases with
+// chart upgrades available to them.
+func (h *Handler) SetReleaseAnnotator(a releaseAnnotator) {
+	h.rlsAnnotator = a
+}
+
+// SetKubeConfigDir sets the dir files referenced by imported
+// kubeconfigs may be read from.
+func (h *Handler) SetKubeConfigDir(dir string) {
+	h.kubeConfigDir = dir
+}
+
+// SetMetricsCacheTTL sets how long metrics of kubes are cached.
+func (h *Handler) SetMetricsCacheTTL(ttl time.Duration) {
+	h.metricsCache.setTTL(ttl)
+}
+
+// discoverNodes lists nodes of imported kube with timeout.
+func (h *Handler) discoverNodes(ctx context.Context, k *model.Kube) ([]corev1.Node, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.discoveryTimeout)
+	defer cancel()
+
+	type result struct {
+		nodes []corev1.Node
+		err   error
+	}
+
+	resultChan := make(chan result, 1)
+
+	go func() {
+		nodes, err := h.svc.ListNodes(ctx, k, "")
+		resultChan <- result{nodes: nodes, err: err}
+	}()
+
+	select {
+	case res := <-resultChan:
+		return res.nodes, res.err
+	case <-ctx.Done():
+		return nil, errors.Wrap(ctx.Err(), "list nodes")
+	}
+}
+
+// importContexts lists contexts of kubeconfig so one of them can be imported.
+func (h *Handler) importContexts(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		KubeConfig string `json:"kubeconfig"`
+	}{}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		message.SendInvalidJSON(w, err)
 		return
 	}
 
-	config, err := steps.NewConfigFromKube(kubeProfile, k)
+	kubeConfig, err := clientcmd.Load([]byte(req.KubeConfig))
 
 	if err != nil {
-		logrus.Errorf("New config %v", err.Error())
-		message.SendUnknownError(w, err)
+		message.SendInvalidJSON(w, err)
 		return
 	}
 
-	// Load things specific to cloud provider
-	err = util.LoadCloudSpecificDataFromKube(k, config)
+	if err := json.NewEncoder(w).Encode(kubeConfigContexts(*kubeConfig)); err != nil {
+		message.SendUnknownError(w, err)
+	}
+}
+
+func (h *Handler) importKube(w http.ResponseWriter, r *http.Request) {
+	type importRequest struct {
+		KubeConfig       string          `json:"kubeconfig"`
+		ClusterName      string          `json:"clusterName"`
+		CloudAccountName string          `json:"cloudAccountName"`
+		PublicKey        string          `json:"publicKey"`
+		PrivateKey       string          `json:"privateKey"`
+		Profile          profile.Profile `json:"profile" valid:"-"`
+
+		// Location of kubeconfig on control host that relative
+		// certificate and key paths are resolved against, files are
+		// read only within the dir configured by the operator
+		KubeConfigPath string `json:"kubeconfigPath"`
+		// Context to import, current context is used by default
+		ContextName string `json:"contextName"`
+	}
+
+	var req importRequest
+	var err error
+
+	err = json.NewDecoder(r.Body).Decode(&req)
 
 	if err != nil {
-		message.SendUnknownError(w, err)
+		logrus.Error(err)
+		message.SendInvalidJSON(w, err)
 		return
 	}
 
-	nextVersion := findNextMinorVersion(k.K8SVersion, clouds.GetVersions())
-
-	if nextVersion == "" {
-		http.Error(w, fmt.Sprintf("can't upgrade from version %s", k.K8SVersion), http.StatusBadRequest)
+	if err := profile.ValidateTags(req.Profile.Provider, req.Profile.Tags); err != nil {
+		message.SendValidationFailed(w, err)
 		return
 	}
 
-	if nextVersion == k.K8SVersion {
-		w.WriteHeader(http.StatusNoContent)
+	kubeConfig, err := clientcmd.Load([]byte(req.KubeConfig))
+
+	if err != nil {
+		logrus.Error(err)
+		message.SendInvalidJSON(w, err)
 		return
 	}
 
-	config.Kube.K8SVersion = nextVersion
-	tasks := h.makeUpgradeTasks(config, k)
+	// Kube is discovered with current context of the kubeconfig
+	if req.ContextName != "" {
+		if _, ok := kubeConfig.Contexts[req.ContextName]; !ok {
+			message.SendValidationFailed(w, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"context %s not found in kubeconfig", req.ContextName))
+			return
+		}
 
-	go h.kubeProvisioner.UpgradeCluster(context.Background(), nextVersion, k, tasks, config)
-	node2TaskMap := mapNode2Task(tasks)
+		kubeConfig.CurrentContext = req.ContextName
+	}
 
-	// here we are ready for async part
-	w.WriteHeader(http.StatusAccepted)
-	if err := json.NewEncoder(w).Encode(node2TaskMap); err != nil {
-		logrus.Errorf("Error encoding task map %v", err)
+	var baseDir string
+
+	if req.KubeConfigPath != "" {
+		baseDir = filepath.Dir(req.KubeConfigPath)
 	}
-}
 
-func (h *Handler) makeUpgradeTasks(config *steps.Config, k *model.Kube) map[string][]*workflows.Task {
-	masterTasks := make([]*workflows.Task, 0, len(k.Masters))
-	nodeTasks := make([]*workflows.Task, 0, len(k.Nodes))
-	//some clouds (e.g. AWS) requires running tasks before provisioning nodes (creating a VPC, Subnets, SecGroups, etc)
+	if err := resolveKubeConfigFiles(kubeConfig, baseDir, h.kubeConfigDir); err != nil {
+		if sgerrors.IsValidationFailed(err) {
+			message.SendValidationFailed(w, err)
+			return
+		}
 
-	for _, masterMachine := range k.Masters {
-		masterTask, err := workflows.NewTask(config, workflows.Upgrade, h.repo)
-		if err != nil {
-			logrus.Errorf("Failed to set up task for %s workflow", workflows.ProvisionMaster)
-			continue
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	// Token of credential plugin is cached before kube is discovered,
+	// EKS tokens of kubes linked to AWS account are got without plugin.
+	if kube, err := kubeFromKubeConfig(*kubeConfig, req.ContextName); err == nil && kubeconfig.HasPluginAuth(kube.Auth) {
+		kube.AccountName = req.CloudAccountName
+		kube.Region = req.Profile.Region
+
+		if err := kubeconfig.CheckPluginAuth(kube); err != nil {
+			message.SendValidationFailed(w, err)
+			return
 		}
 
-		cfg := *config
-		cfg.Node = *masterMachine
-		cfg.IsMaster = true
-		cfg.IsBootstrap = false
-		masterTask.Config = &cfg
-		// Note(stgleb): Reuse task ID for machine provisioning that will allow to browse
-		// logs of machine upgrade without changes on the UI
-		masterTask.ID = masterMachine.TaskID
-		masterTasks = append(masterTasks, masterTask)
+		if _, err := kubeconfig.PluginToken(r.Context(), kube); err != nil {
+			logrus.Errorf("get token of credential plugin %v", err)
+			message.SendInvalidCredentials(w, err)
+			return
+		}
 	}
 
-	for _, nodeMachine := range k.Nodes {
-		nodeTask, err := workflows.NewTask(config, workflows.Upgrade, h.repo)
+	// Unreachable kube is imported with a warning and unknown versions
+	var warning *ImportWarning
+	var helmVersion string
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.discoveryTimeout)
+	k8sVersion, err := h.discoverK8SVersion(ctx, kubeConfig)
+
+	if err != nil {
+		logrus.Warnf("discover imported kube %s: %v", req.ClusterName, err)
+		warning = unreachableWarning(err)
+	} else {
+		helmVersion, err = h.discoverHelmVersion(ctx, kubeConfig)
+
 		if err != nil {
-			logrus.Errorf("Failed to set up task for %s workflow", workflows.ProvisionNode)
-			continue
+			logrus.Warnf("discover helm version of imported kube %s: %v", req.ClusterName, err)
 		}
-		cfg := *config
-		cfg.Node = *nodeMachine
-		cfg.IsMaster = false
-		cfg.IsBootstrap = false
-		nodeTask.Config = &cfg
-		// Note(stgleb): Reuse task ID for machine provisioning that will allow to browse
-		// logs of machine upgrade without changes on the UI
-		nodeTask.ID = nodeMachine.ID
-		nodeTasks = append(nodeTasks, nodeTask)
+	}
+	cancel()
+
+	req.Profile.HelmVersion = helmVersion
+	config, err := steps.NewConfig(req.ClusterName, req.CloudAccountName, req.Profile)
+
+	if err != nil {
+		logrus.Errorf("build provisioning config: %s", err)
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	importTask, err := workflows.NewTask(config, workflows.ImportCluster, h.repo)
+
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	kube, err := kubeFromKubeConfig(*kubeConfig, req.ContextName)
+
+	if err != nil {
+		if sgerrors.IsValidationFailed(err) {
+			message.SendValidationFailed(w, err)
+			return
+		}
+
+		message.SendInvalidCredentials(w, err)
+		return
+	}
+	// Grab all k8s nodes from kube-apiserver
+	var nodes []corev1.Node
+
+	if warning == nil {
+		nodes, err = h.discoverNodes(r.Context(), kube)
+
+		if err != nil {
+			logrus.Warnf("list nodes of imported kube %s: %v", req.ClusterName, err)
+			warning = unreachableWarning(err)
+		}
+	}
+
+	cloudAccount, err := h.accountService.Get(r.Context(), req.CloudAccountName)
+
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, req.CloudAccountName, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	err = util.FillCloudAccountCredentials(cloudAccount, config)
+
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	var clusterID string
+
+	if len(importTask.ID) > 8 {
+		clusterID = importTask.ID[:8]
+	} else {
+		message.SendValidationFailed(w, errors.New("import task id is too short"))
+		return
+	}
+
+	config.Kube.ID = clusterID
+	config.IsBootstrap = true
+	config.Kube.SSHConfig.BootstrapPrivateKey = req.PrivateKey
+	config.Kube.SSHConfig.PublicKey = req.PublicKey
+	config.Kube.Auth = kube.Auth
+	config.Kube.ExternalDNSName = kube.ExternalDNSName
+	config.Kube.K8SVersion = k8sVersion
+	config.IsImport = true
+
+	masters, workers := machinesFromNodes(nodes, config.Provider, req.Profile.Region)
+	config.Masters = steps.NewMap(masters)
+	config.Nodes = steps.NewMap(workers)
+
+	if err := createKube(config, model.StateImporting, req.Profile, importTask.ID, h); err != nil {
+		message.SendUnknownError(w, errors.Wrapf(err, "create importing kube"))
+	}
+
+	if err := h.profileSvc.Create(r.Context(), &req.Profile); err != nil {
+		message.SendUnknownError(w, errors.Wrap(err, "save profile"))
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	err = json.NewEncoder(w).Encode(struct {
+		ClusterID string         `json:"clusterId"`
+		Warning   *ImportWarning `json:"warning,omitempty"`
+	}{
+		ClusterID: clusterID,
+		Warning:   warning,
+	})
+
+	if err != nil {
+		message.SendInvalidJSON(w, err)
+		return
+	}
+
+	go func() {
+		fileName := util.MakeFileName(importTask.ID)
+		writer, err := h.getWriter(fileName)
+
+		if err != nil {
+			message.SendUnknownError(w, err)
+			return
+		}
+
+		importTask.Config = config
+		resultChan := importTask.Run(context.Background(), importTask.Config.Clone(), writer)
+		err = <-resultChan
+
+		if err != nil {
+			logrus.Errorf("task %s has finished with error %v", importTask.ID, err)
+		}
+
+		state := model.StateOperational
+
+		if err != nil {
+			state = model.StateFailed
+		}
+
+		// Importing kube is replaced with the imported one, unless
+		// it has been deleted meanwhile
+		_, err = retryUpdate(context.Background(), h.svc, clusterID, func(k *model.Kube) error {
+			imported := importedKube(importTask.Config, state, req.Profile, importTask.ID)
+			imported.Revision = k.Revision
+			*k = *imported
+
+			return nil
+		})
+
+		if err != nil {
+			logrus.Errorf("error updating imported kube %v", err)
+		}
+
+		logrus.Infof("Import task %s has successfully finished", importTask.ID)
+	}()
+}
+
+// upgradeKube upgrades provisioned kube to the next minor version, batchSize
+// query parameter sets the count of workers upgraded at the same time.
+// Upgrade that has stopped is resumed by the same request.
+func (h *Handler) upgradeKube(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	vars := mux.Vars(r)
+	kubeID := vars["kubeID"]
+	batchSize := 1
+
+	if value := r.URL.Query().Get("batchSize"); value != "" {
+		batchSize, err = strconv.Atoi(value)
+
+		if err != nil || batchSize < 1 {
+			http.Error(w, fmt.Sprintf("invalid batch size %s", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	logrus.Debugf("Get kube %s", kubeID)
+	k, err := h.svc.Get(r.Context(), kubeID)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if k.State != model.StateOperational {
+		w.WriteHeader(http.StatusNoContent)
+		logrus.Infof("Cluster %s is not operational", k.ID)
+		return
+	}
+
+	if len(k.Tasks[workflows.ImportTask]) > 0 {
+		message.SendValidationFailed(w, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"imported kube %s can't be upgraded", k.ID))
+		return
+	}
+
+	logrus.Debugf("Get cloud profile %s", k.ProfileID)
+	kubeProfile, err := h.profileSvc.Get(r.Context(), k.ProfileID)
+
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, k.ProfileID, err)
+			return
+		}
+
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	config, err := steps.NewConfigFromKube(kubeProfile, k)
+
+	if err != nil {
+		logrus.Errorf("New config %v", err.Error())
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	// Load things specific to cloud provider
+	err = util.LoadCloudSpecificDataFromKube(k, config)
+
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	nextVersion := findNextMinorVersion(k.K8SVersion, clouds.GetVersions())
+
+	if nextVersion == "" {
+		http.Error(w, fmt.Sprintf("can't upgrade from version %s", k.K8SVersion), http.StatusBadRequest)
+		return
+	}
+
+	if nextVersion == k.K8SVersion {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	check, nodes, err := h.checkUpgrade(r.Context(), k, nextVersion)
+
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if err := check.Err(); err != nil {
+		logrus.Infof("Kube %s can't be upgraded to %s: %v", k.ID, nextVersion, err)
+		message.SendValidationFailed(w, err)
+		return
+	}
+
+	config.Kube.K8SVersion = nextVersion
+	config.UpgradeConfig.BatchSize = batchSize
+	tasks := h.makeUpgradeTasks(config, k, nodes)
+
+	go h.kubeProvisioner.UpgradeCluster(context.Background(), nextVersion, k, tasks, config)
+	node2TaskMap := mapNode2Task(tasks)
+
+	// here we are ready for async part
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(node2TaskMap); err != nil {
+		logrus.Errorf("Error encoding task map %v", err)
+	}
+}
+
+// getUpgradeTargets returns versions kube may be upgraded to, version of
+// imported kube is discovered since it may be upgraded outside of control,
+// refresh query parameter bypasses cached discovery.
+func (h *Handler) getUpgradeTargets(w http.ResponseWriter, r *http.Request) {
+	kubeID := mux.Vars(r)["kubeID"]
+
+	k, err := h.svc.Get(r.Context(), kubeID)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	current := k.K8SVersion
+
+	if len(k.Tasks[workflows.ImportTask]) > 0 {
+		refresh, _ := strconv.ParseBool(r.URL.Query().Get("refresh"))
+
+		if version, err := h.discoverImportedVersion(r.Context(), k, refresh); err != nil {
+			logrus.Warnf("discover version of imported kube %s: %v", k.ID, err)
+		} else {
+			current = version
+		}
+	}
+
+	if err := json.NewEncoder(w).Encode(upgradeTargets(current, clouds.GetVersions())); err != nil {
+		message.SendUnknownError(w, err)
+	}
+}
+
+// getUpgradeCheck runs validation done before upgrade of the kube
+// to the next version without upgrading it.
+func (h *Handler) getUpgradeCheck(w http.ResponseWriter, r *http.Request) {
+	kubeID := mux.Vars(r)["kubeID"]
+
+	k, err := h.svc.Get(r.Context(), kubeID)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	nextVersion := findNextMinorVersion(k.K8SVersion, clouds.GetVersions())
+
+	if nextVersion == "" {
+		http.Error(w, fmt.Sprintf("can't upgrade from version %s", k.K8SVersion), http.StatusBadRequest)
+		return
+	}
+
+	check, _, err := h.checkUpgrade(r.Context(), k, nextVersion)
+
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(check); err != nil {
+		message.SendUnknownError(w, err)
+	}
+}
+
+// checkUpgrade gathers nodes and etcd version of the kube to validate
+// version skew against the target version, nodes are returned along with
+// the check.
+func (h *Handler) checkUpgrade(ctx context.Context, k *model.Kube, target string) (*UpgradeCheck, []corev1.Node, error) {
+	nodes, err := h.svc.ListNodes(ctx, k, "")
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "list nodes")
+	}
+
+	pods, err := h.listEtcdPods(k)
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "list etcd pods")
+	}
+
+	check := checkUpgrade(k.K8SVersion, target, nodes, etcdVersionFromPods(pods))
+
+	return &check, nodes, nil
+}
+
+func (h *Handler) discoverImportedVersion(ctx context.Context, k *model.Kube, refresh bool) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.discoveryTimeout)
+	defer cancel()
+
+	result, err := h.svc.Discover(ctx, k, refresh)
+
+	if err != nil {
+		return "", err
+	}
+
+	return result.ServerVersion, nil
+}
+
+// makeUpgradeTasks makes tasks for machines that don't run the version of
+// the config yet. Masters are upgraded in order of their names, the first
+// one applies the upgrade unless another master has already done it.
+func (h *Handler) makeUpgradeTasks(config *steps.Config, k *model.Kube,
+	nodes []corev1.Node) map[string][]*workflows.Task {
+	masterTasks := make([]*workflows.Task, 0, len(k.Masters))
+	nodeTasks := make([]*workflows.Task, 0, len(k.Nodes))
+	upgraded := upgradedMachines(k, nodes, config.Kube.K8SVersion)
+	isBootstrap := true
+
+	for name := range k.Masters {
+		if upgraded[name] {
+			isBootstrap = false
+		}
+	}
+
+	for _, name := range sortedMachineNames(k.Masters) {
+		masterMachine := k.Masters[name]
+
+		if upgraded[name] {
+			logrus.Infof("Master %s has already been upgraded to %s", name, config.Kube.K8SVersion)
+			continue
+		}
+
+		masterTask, err := workflows.NewTask(config, workflows.Upgrade, h.repo)
+		if err != nil {
+			logrus.Errorf("Failed to set up task for %s workflow", workflows.ProvisionMaster)
+			continue
+		}
+
+		cfg := *config
+		cfg.Node = *masterMachine
+		cfg.IsMaster = true
+		cfg.IsBootstrap = isBootstrap
+		isBootstrap = false
+		masterTask.Config = &cfg
+		// Note(stgleb): Reuse task ID for machine provisioning that will allow to browse
+		// logs of machine upgrade without changes on the UI
+		masterTask.ID = masterMachine.TaskID
+		masterTasks = append(masterTasks, masterTask)
+	}
+
+	for _, name := range sortedMachineNames(k.Nodes) {
+		nodeMachine := k.Nodes[name]
+
+		if upgraded[name] {
+			logrus.Infof("Node %s has already been upgraded to %s", name, config.Kube.K8SVersion)
+			continue
+		}
+
+		nodeTask, err := workflows.NewTask(config, workflows.Upgrade, h.repo)
+		if err != nil {
+			logrus.Errorf("Failed to set up task for %s workflow", workflows.ProvisionNode)
+			continue
+		}
+		cfg := *config
+		cfg.Node = *nodeMachine
+		cfg.IsMaster = false
+		cfg.IsBootstrap = false
+		nodeTask.Config = &cfg
+		// Note(stgleb): Reuse task ID for machine provisioning that will allow to browse
+		// logs of machine upgrade without changes on the UI
+		nodeTask.ID = nodeMachine.ID
+		nodeTasks = append(nodeTasks, nodeTask)
+	}
+
+	taskMap := map[string][]*workflows.Task{
+		workflows.MasterTask: masterTasks,
+		workflows.NodeTask:   nodeTasks,
+	}
+
+	return taskMap
+}
+
+func (h *Handler) applyToKube(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	vars := mux.Vars(r)
+	kubeID := vars["kubeID"]
+
+	data, err := ioutil.ReadAll(r.Body)
+
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	logrus.Debugf("Get kube %s", kubeID)
+	k, err := h.svc.Get(r.Context(), kubeID)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if k.State != model.StateOperational {
+		w.WriteHeader(http.StatusNoContent)
+		logrus.Infof("Cluster %s is not operational", k.ID)
+		return
+	}
+
+	if len(k.Tasks[workflows.ImportTask]) > 0 {
+		message.SendValidationFailed(w, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"imported kube %s can't be upgraded", k.ID))
+		return
+	}
+
+	logrus.Debugf("Get cloud profile %s", k.ProfileID)
+	kubeProfile, err := h.profileSvc.Get(r.Context(), k.ProfileID)
+
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, k.ProfileID, err)
+			return
+		}
+
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	config, err := steps.NewConfigFromKube(kubeProfile, k)
+
+	if err != nil {
+		logrus.Errorf("New config %v", err.Error())
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	// Load things specific to cloud provider
+	err = util.LoadCloudSpecificDataFromKube(k, config)
+
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if master := config.GetMaster(); master != nil {
+		config.Node = *master
+	} else {
+		message.SendNotFound(w, "master node", err)
+		return
+	}
+
+	config.ApplyConfig.Data = string(data)
+	applyTask, err := workflows.NewTask(config, workflows.ApplyYaml, h.repo)
+
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	fileName := util.MakeFileName(applyTask.ID)
+	writer, err := h.getWriter(fileName)
+
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	applyTask.Config = config
+	go func() {
+		err := <-applyTask.Run(context.Background(), config.Clone(), writer)
+
+		if err != nil {
+			logrus.Errorf("Error executing apply task %v", err)
+		}
+	}()
+
+	// here we are ready for async part
+	w.WriteHeader(http.StatusAccepted)
+	err = json.NewEncoder(w).Encode(struct {
+		TaskID string `json:"taskId"`
+	}{
+		TaskID: applyTask.ID,
+	})
+
+	if err != nil {
+		logrus.Errorf("Error encoding task id %v", err)
+	}
+}
+
+func mapNode2Task(taskMap map[string][]*workflows.Task) map[string]string {
+	node2Task := make(map[string]string)
+
+	for _, taskSet := range taskMap {
+		for _, task := range taskSet {
+			node2Task[task.Config.Node.Name] = task.ID
+		}
+	}
+
+	return node2Task
+}
+
+func createKube(config *steps.Config, state model.KubeState, profile profile.Profile, taskID string, h *Handler) error {
+	err := h.svc.Create(context.Background(), importedKube(config, state, profile, taskID))
+	if err != nil {
+		logrus.Infof("Error creating the cluster")
+	}
+
+	return err
+}
+
+// importedKube builds kube of the import task from its config.
+func importedKube(config *steps.Config, state model.KubeState, profile profile.Profile, taskID string) *model.Kube {
+	cluster := &model.Kube{
+		ID:                     config.Kube.ID,
+		State:                  state,
+		Name:                   config.Kube.Name,
+		Provider:               config.Provider,
+		AccountName:            config.CloudAccountName,
+		BootstrapToken:         config.Kube.BootstrapToken,
+		Region:                 profile.Region,
+		Arch:                   profile.Arch,
+		OperatingSystem:        profile.OperatingSystem,
+		OperatingSystemVersion: profile.UbuntuVersion,
+		K8SVersion:             profile.K8SVersion,
+		DockerVersion:          profile.DockerVersion,
+		HelmVersion:            profile.HelmVersion,
+		HelmMajorVersion:       helmMajorVersion(profile.HelmVersion),
+		RBACEnabled:            profile.RBACEnabled,
+		ExternalDNSName:        config.Kube.ExternalDNSName,
+		InternalDNSName:        config.Kube.ExternalDNSName,
+		ProfileID:              profile.ID,
+		Auth:                   config.Kube.Auth,
+		Masters:                config.GetMasters(),
+		Nodes:                  config.GetNodes(),
+		Tasks: map[string][]string{
+			workflows.ImportTask:       {taskID},
+			workflows.PreProvisionTask: {},
+			workflows.MasterTask:       {},
+			workflows.NodeTask:         {},
+			workflows.ClusterTask:      {},
+		},
+
+		SSHConfig: config.Kube.SSHConfig,
+	}
+	util.UpdateKubeWithCloudSpecificData(cluster, config)
+
+	return cluster
+}
+
+// Add spot instance machine to k8s cluster
+func (h *Handler) addSpotMachine(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	kubeID := vars["kubeID"]
+	k, err := h.svc.Get(r.Context(), kubeID)
+
+	if sgerrors.IsNotFound(err) {
+		http.NotFound(w, r)
+		return
+	}
+
+	// TODO(stgleb): Add machine count here
+	req := &SpotRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		message.SendInvalidJSON(w, err)
+		return
+	}
+
+	if err := validateSpotRequest(req, time.Now()); err != nil {
+		message.SendValidationFailed(w, err)
+		return
+	}
+
+	config, kubeProfile, err := h.spotConfig(r.Context(), k)
+
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if config.Provider == clouds.GCE {
+		if req.MaintainCount || req.Pool != "" {
+			message.SendValidationFailed(w, errors.Wrap(sgerrors.ErrValidationFailed,
+				"spot count and node pools of spot requests are supported only on AWS"))
+			return
+		}
+
+		h.addPreemptibleMachines(w, k, config, req)
+		return
+	}
+
+	if config.Provider != clouds.AWS {
+		message.SendUnknownError(w, sgerrors.ErrUnsupportedProvider)
+		return
+	}
+
+	if err := setSpotConfig(config, kubeProfile.NodePools, req); err != nil {
+		message.SendValidationFailed(w, err)
+		return
+	}
+
+	if req.DryRun {
+		h.dryRunSpotRequest(w, r, config)
+		return
+	}
+
+	groupID := ""
+
+	if req.MaintainCount {
+		group, err := newSpotGroup(req, time.Now())
+
+		if err != nil {
+			message.SendUnknownError(w, err)
+			return
+		}
+
+		if k.SpotGroups == nil {
+			k.SpotGroups = make(map[string]*model.SpotGroup)
+		}
+
+		k.SpotGroups[group.ID] = group
+		groupID = group.ID
+	}
+
+	t, err := h.startSpotTask(r.Context(), k, config, groupID)
+
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	resp := spotResponse{
+		TaskID:            t.ID,
+		TaskIDs:           []string{t.ID},
+		AvailabilityZones: sortedZones(config.SpotConfig.Zones),
+		SpotGroupID:       groupID,
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logrus.Errorf("encode spot response %v", err)
+	}
+}
+
+// spotConfig builds config of the kube filled with cloud account
+// credentials for spot requests.
+func (h *Handler) spotConfig(ctx context.Context, k *model.Kube) (*steps.Config, *profile.Profile, error) {
+	logrus.Debugf("Get cloud profile %s", k.ProfileID)
+	kubeProfile, err := h.profileSvc.Get(ctx, k.ProfileID)
+
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "get profile %s", k.ProfileID)
+	}
+
+	config, err := steps.NewConfigFromKube(kubeProfile, k)
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "new config")
+	}
+
+	acc, err := h.accountService.Get(ctx, k.AccountName)
+
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "get cloud account %s", k.AccountName)
+	}
+
+	// Get cloud account fill appropriate config structure
+	// with cloud account credentials
+	if err := util.FillCloudAccountCredentials(acc, config); err != nil {
+		return nil, nil, errors.Wrap(err, "fill cloud account credentials")
+	}
+
+	if err := util.LoadCloudSpecificDataFromKube(k, config); err != nil {
+		return nil, nil, errors.Wrap(err, "load cloud specific data")
+	}
+
+	return config, kubeProfile, nil
+}
+
+// setSpotConfig sets AWS spot request parameters to config, image of
+// the node pool is used instead of cluster one when it is set.
+func setSpotConfig(config *steps.Config, pools []profile.NodePool, req *SpotRequest) error {
+	if req.Pool != "" {
+		pool, err := profile.FindNodePool(pools, req.Pool)
+
+		if err != nil {
+			return errors.Wrap(sgerrors.ErrValidationFailed, err.Error())
+		}
+
+		if req.MachineType == "" {
+			req.MachineType = pool.MachineType
+		}
+
+		if pool.Image != "" {
+			config.AWSConfig.ImageID = pool.Image
+		}
+
+		config.Pool = pool.Name
+	}
+
+	zones := spotZones(req, config.AWSConfig.Subnets)
+
+	if len(zones) == 0 {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"no subnets in availability zones %v", req.AvailabilityZones)
+	}
+
+	config.AWSConfig.InstanceType = req.MachineType
+	config.AWSConfig.UserData = fmt.Sprintf("#!/bin/sh\n%s", config.ConfigMap.Data)
+	config.SpotConfig = steps.SpotConfig{
+		SpotPrice:          req.SpotPrice,
+		MachineCount:       req.MachineCount,
+		Zones:              zones,
+		InstanceTypes:      req.InstanceTypes,
+		AllocationStrategy: req.AllocationStrategy,
+		ValidUntil:         req.ValidUntil,
+		FallbackOnDemand:   req.FallbackOnDemand,
+		FulfillmentTimeout: req.FulfillmentTimeout,
+	}
+
+	return nil
+}
+
+// startSpotTask saves spot task to the kube and runs it, machines of
+// the task are added to spot group when groupID is not empty.
+func (h *Handler) startSpotTask(ctx context.Context, k *model.Kube, config *steps.Config,
+	groupID string) (*workflows.Task, error) {
+	workflow := workflows.SpotInstance
+
+	if len(config.SpotConfig.InstanceTypes) > 0 {
+		workflow = workflows.SpotFleet
+	}
+
+	t, err := workflows.NewTask(config, workflow, h.repo)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "new task")
+	}
+
+	config.TaskID = t.ID
+	config.SpotConfig.GroupID = groupID
+	writer, err := h.getWriter(util.MakeFileName(t.ID))
+
+	if err != nil {
+		return nil, errors.Wrap(err, "get writer")
+	}
+
+	_, err = retryUpdate(ctx, h.svc, k.ID, func(latest *model.Kube) error {
+		// Group is saved along with its task, it is either new
+		// or its capacity request has been started
+		if group := k.SpotGroups[groupID]; group != nil {
+			if latest.SpotGroups == nil {
+				latest.SpotGroups = make(map[string]*model.SpotGroup)
+			}
+
+			latest.SpotGroups[groupID] = group
+		}
+
+		if latest.Tasks == nil {
+			latest.Tasks = make(map[string][]string)
+		}
+
+		latest.Tasks[workflows.SpotTask] = append(latest.Tasks[workflows.SpotTask], t.ID)
+		return nil
+	})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "update kube %s", k.ID)
+	}
+
+	go h.runSpotTask(k.ID, t, config, writer)
+
+	return t, nil
+}
+
+// RequestSpotCapacity submits spot request of the group for count nodes
+// with parameters the group has been created with.
+func (h *Handler) RequestSpotCapacity(ctx context.Context, k *model.Kube,
+	group *model.SpotGroup, count int64) error {
+	req := &SpotRequest{}
+
+	if err := json.Unmarshal(group.Request, req); err != nil {
+		return errors.Wrap(err, "unmarshal spot request")
+	}
+
+	req.MachineCount = count
+	config, kubeProfile, err := h.spotConfig(ctx, k)
+
+	if err != nil {
+		return err
 	}
 
-	taskMap := map[string][]*workflows.Task{
-		workflows.MasterTask: masterTasks,
-		workflows.NodeTask:   nodeTasks,
+	if err := setSpotConfig(config, kubeProfile.NodePools, req); err != nil {
+		return err
 	}
 
-	return taskMap
+	_, err = h.startSpotTask(ctx, k, config, group.ID)
+
+	return err
 }
 
-func (h *Handler) applyToKube(w http.ResponseWriter, r *http.Request) {
-	var err error
+// dryRunSpotRequest validates spot requests with AWS without submitting
+// them and responds with launch specifications that would be used.
+func (h *Handler) dryRunSpotRequest(w http.ResponseWriter, r *http.Request, config *steps.Config) {
+	step := steps.GetStep(amazon.RequestSpotInstancesStepName)
 
-	vars := mux.Vars(r)
-	kubeID := vars["kubeID"]
+	if step == nil {
+		message.SendUnknownError(w, errors.Errorf("step %s not found",
+			amazon.RequestSpotInstancesStepName))
+		return
+	}
 
-	data, err := ioutil.ReadAll(r.Body)
+	config.DryRun = true
 
-	if err != nil {
+	if err := step.Run(r.Context(), ioutil.Discard, config); err != nil {
+		logrus.Errorf("dry run of spot request %v", err)
 		message.SendUnknownError(w, err)
 		return
 	}
 
-	logrus.Debugf("Get kube %s", kubeID)
-	k, err := h.svc.Get(r.Context(), kubeID)
-	if err != nil {
-		if sgerrors.IsNotFound(err) {
-			message.SendNotFound(w, kubeID, err)
-			return
-		}
-		message.SendUnknownError(w, err)
-		return
+	resp := spotDryRunResponse{
+		DryRun:               true,
+		LaunchSpecifications: config.SpotConfig.DryRunResults,
 	}
 
-	if k.State != model.StateOperational {
-		w.WriteHeader(http.StatusNoContent)
-		logrus.Infof("Cluster %s is not operational", k.ID)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logrus.Errorf("encode spot dry run response %v", err)
+	}
+}
+
+// addPreemptibleMachines provisions GCE preemptible instances which
+// are GCE counterpart of AWS spot instances.
+func (h *Handler) addPreemptibleMachines(w http.ResponseWriter, k *model.Kube,
+	config *steps.Config, req *SpotRequest) {
+	zones := spotZones(req, config.GCEConfig.AZs)
+
+	if len(zones) == 0 {
+		message.SendValidationFailed(w, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"no availability zones %v in region %s", req.AvailabilityZones,
+			config.GCEConfig.Region))
 		return
 	}
 
-	logrus.Debugf("Get cloud profile %s", k.ProfileID)
-	kubeProfile, err := h.profileSvc.Get(r.Context(), k.ProfileID)
+	availabilityZones := sortedZones(zones)
+	nodeProfiles := make([]profile.NodeProfile, 0, req.MachineCount)
+
+	for _, zone := range availabilityZones {
+		for i := int64(0); i < zones[zone]; i++ {
+			nodeProfiles = append(nodeProfiles, profile.NodeProfile{
+				"size":             req.MachineType,
+				"availabilityZone": zone,
+				"preemptible":      "true",
+			})
+		}
+	}
+
+	tasks, err := h.nodeProvisioner.ProvisionNodes(context.Background(),
+		nodeProfiles, k, config)
 
 	if err != nil {
 		if sgerrors.IsNotFound(err) {
-			message.SendNotFound(w, k.ProfileID, err)
+			message.SendNotFound(w, k.ID, err)
 			return
 		}
 
@@ -1709,124 +3866,134 @@ func (h *Handler) applyToKube(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	config, err := steps.NewConfigFromKube(kubeProfile, k)
+	_, err = retryUpdate(context.Background(), h.svc, k.ID, func(k *model.Kube) error {
+		k.Tasks[workflows.NodeTask] = append(k.Tasks[workflows.NodeTask], tasks...)
+		return nil
+	})
 
 	if err != nil {
-		logrus.Errorf("New config %v", err.Error())
 		message.SendUnknownError(w, err)
 		return
 	}
 
-	// Load things specific to cloud provider
-	err = util.LoadCloudSpecificDataFromKube(k, config)
-
-	if err != nil {
-		message.SendUnknownError(w, err)
-		return
+	resp := spotResponse{
+		TaskIDs:           tasks,
+		AvailabilityZones: availabilityZones,
 	}
 
-	if master := config.GetMaster(); master != nil {
-		config.Node = *master
-	} else {
-		message.SendNotFound(w, "master node", err)
-		return
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logrus.Errorf("encode preemptible response %v", err)
 	}
+}
 
-	config.ApplyConfig.Data = string(data)
-	applyTask, err := workflows.NewTask(config, workflows.ApplyYaml, h.repo)
+// runSpotTask runs spot instance workflow and saves spot requests and
+// machines of the task to the kube.
+func (h *Handler) runSpotTask(kubeID string, t *workflows.Task, config *steps.Config,
+	out io.WriteCloser) {
+	groupID := config.SpotConfig.GroupID
+	nodeChan := make(chan model.Machine, config.SpotConfig.MachineCount)
+	spotRequestsChan := make(chan []string)
+	config.SetNodeChan(nodeChan)
+	config.SetSpotRequestsChan(spotRequestsChan)
+	done := make(chan struct{})
+	launched := 0
+
+	// Updates are saved one at a time, so they do not overwrite each other
+	go func(nodes <-chan model.Machine, spotRequests <-chan []string) {
+		for nodes != nil || spotRequests != nil {
+			select {
+			case n, ok := <-nodes:
+				if !ok {
+					nodes = nil
+					continue
+				}
 
-	if err != nil {
-		message.SendUnknownError(w, err)
-		return
-	}
+				n.SpotGroupID = groupID
+				h.saveSpotMachine(kubeID, n)
+				launched++
+			case requestIDs, ok := <-spotRequests:
+				if !ok {
+					spotRequests = nil
+					continue
+				}
 
-	fileName := util.MakeFileName(applyTask.ID)
-	writer, err := h.getWriter(fileName)
+				h.setSpotRequestsState(kubeID, requestIDs, model.SpotRequestOpen)
+			}
+		}
+		close(done)
+	}(nodeChan, spotRequestsChan)
+
+	err := <-t.Run(context.Background(), config.Clone(), out)
+	close(nodeChan)
+	close(spotRequestsChan)
+	<-done
 
 	if err != nil {
-		message.SendUnknownError(w, err)
-		return
+		logrus.Errorf("spot task %s of kube %s caused %v", t.ID, kubeID, err)
 	}
 
-	applyTask.Config = config
-	go func() {
-		err := <-applyTask.Run(context.Background(), *config, writer)
-
-		if err != nil {
-			logrus.Errorf("Error executing apply task %v", err)
-		}
-	}()
+	spotCfg := t.Config.SpotConfig
 
-	// here we are ready for async part
-	w.WriteHeader(http.StatusAccepted)
-	err = json.NewEncoder(w).Encode(struct {
-		TaskID string `json:"taskId"`
-	}{
-		TaskID: applyTask.ID,
-	})
+	if len(spotCfg.RequestIDs) > 0 {
+		h.setSpotRequestsState(kubeID, spotCfg.RequestIDs, spotCfg.State)
+	}
 
-	if err != nil {
-		logrus.Errorf("Error encoding task id %v", err)
+	if groupID != "" {
+		h.setSpotGroupResult(kubeID, groupID, launched == 0)
 	}
 }
 
-func mapNode2Task(taskMap map[string][]*workflows.Task) map[string]string {
-	node2Task := make(map[string]string)
+// setSpotGroupResult completes capacity request of the spot group,
+// next request is delayed when no machines were launched.
+func (h *Handler) setSpotGroupResult(kubeID, groupID string, failed bool) {
+	now := time.Now()
 
-	for _, taskSet := range taskMap {
-		for _, task := range taskSet {
-			node2Task[task.Config.Node.Name] = task.ID
+	h.updateKube(kubeID, func(k *model.Kube) {
+		if group := k.SpotGroups[groupID]; group != nil {
+			completeSpotRequest(group, failed, now)
 		}
-	}
+	})
+}
 
-	return node2Task
+func (h *Handler) saveSpotMachine(kubeID string, n model.Machine) {
+	h.updateKube(kubeID, func(k *model.Kube) {
+		putSpotMachine(k, &n)
+	})
 }
 
-func createKube(config *steps.Config, state model.KubeState, profile profile.Profile, taskID string, h *Handler) error {
-	cluster := &model.Kube{
-		ID:                     config.Kube.ID,
-		State:                  state,
-		Name:                   config.Kube.Name,
-		Provider:               config.Provider,
-		AccountName:            config.CloudAccountName,
-		BootstrapToken:         config.Kube.BootstrapToken,
-		Region:                 profile.Region,
-		Arch:                   profile.Arch,
-		OperatingSystem:        profile.OperatingSystem,
-		OperatingSystemVersion: profile.UbuntuVersion,
-		K8SVersion:             profile.K8SVersion,
-		DockerVersion:          profile.DockerVersion,
-		HelmVersion:            profile.HelmVersion,
-		RBACEnabled:            profile.RBACEnabled,
-		ExternalDNSName:        config.Kube.ExternalDNSName,
-		InternalDNSName:        config.Kube.ExternalDNSName,
-		ProfileID:              profile.ID,
-		Auth:                   config.Kube.Auth,
-		Masters:                config.GetMasters(),
-		Nodes:                  config.GetNodes(),
-		Tasks: map[string][]string{
-			workflows.ImportTask:       {taskID},
-			workflows.PreProvisionTask: {},
-			workflows.MasterTask:       {},
-			workflows.NodeTask:         {},
-			workflows.ClusterTask:      {},
-		},
+// setSpotRequestsState saves spot requests to the kube, so they
+// are cancelled when kube is deleted.
+func (h *Handler) setSpotRequestsState(kubeID string, requestIDs []string, state model.SpotRequestState) {
+	logrus.Infof("Spot requests %v of kube %s are %s", requestIDs, kubeID, state)
+	h.updateKube(kubeID, func(k *model.Kube) {
+		if k.SpotRequestStates == nil {
+			k.SpotRequestStates = make(map[string]model.SpotRequestState)
+		}
 
-		SSHConfig: config.Kube.SSHConfig,
-	}
-	util.UpdateKubeWithCloudSpecificData(cluster, config)
-	err := h.svc.Create(context.Background(), cluster)
-	if err != nil {
-		logrus.Infof("Error creating the cluster")
-	}
+		known := make(map[string]bool, len(k.SpotRequests))
 
-	return err
+		for _, requestID := range k.SpotRequests {
+			known[requestID] = true
+		}
+
+		for _, requestID := range requestIDs {
+			if !known[requestID] {
+				k.SpotRequests = append(k.SpotRequests, requestID)
+			}
+
+			k.SpotRequestStates[requestID] = state
+		}
+	})
 }
 
 // Add spot instance machine to k8s cluster
-func (h *Handler) addSpotMachine(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) spotMachinePrice(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+
+	machineType := vars["machineType"]
 	kubeID := vars["kubeID"]
+
 	k, err := h.svc.Get(r.Context(), kubeID)
 
 	if sgerrors.IsNotFound(err) {
@@ -1834,11 +4001,15 @@ func (h *Handler) addSpotMachine(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO(stgleb): Add machine count here
-	req := &SpotRequest{}
+	acc, err := h.accountService.Get(r.Context(), k.AccountName)
 
-	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
-		message.SendInvalidJSON(w, err)
+	if sgerrors.IsNotFound(err) {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -1862,18 +4033,6 @@ func (h *Handler) addSpotMachine(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	acc, err := h.accountService.Get(r.Context(), k.AccountName)
-
-	if sgerrors.IsNotFound(err) {
-		http.NotFound(w, r)
-		return
-	}
-
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
 	// Get cloud account fill appropriate config structure
 	// with cloud account credentials
 	err = util.FillCloudAccountCredentials(acc, config)
@@ -1883,19 +4042,34 @@ func (h *Handler) addSpotMachine(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = util.LoadCloudSpecificDataFromKube(k, config); err != nil {
+	// Prices for all zones of the region are returned unless zone is specified
+	az := r.URL.Query().Get("availabilityZone")
+	prices, err := getSpotPrices(r.Context(), machineType, az, config)
+
+	if err != nil {
 		message.SendUnknownError(w, err)
 		return
 	}
 
-	if err := createSpotInstance(req, config); err != nil {
-		message.SendUnknownError(w, err)
-		return
+	resp := &spotPricesResponse{
+		Prices: make([]string, 0),
+		Zones:  prices,
+	}
+
+	for _, point := range prices[config.AWSConfig.AvailabilityZone] {
+		resp.Prices = append(resp.Prices,
+			strconv.FormatFloat(point.Price, 'f', -1, 64))
+	}
+
+	err = json.NewEncoder(w).Encode(resp)
+
+	if err != nil {
+		message.SendInvalidJSON(w, err)
 	}
 }
 
-// Add spot instance machine to k8s cluster
-func (h *Handler) spotMachinePrice(w http.ResponseWriter, r *http.Request) {
+// Recommend spot price for machine type based on price history
+func (h *Handler) spotPriceRecommendation(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 
 	machineType := vars["machineType"]
@@ -1903,24 +4077,28 @@ func (h *Handler) spotMachinePrice(w http.ResponseWriter, r *http.Request) {
 
 	k, err := h.svc.Get(r.Context(), kubeID)
 
-	if sgerrors.IsNotFound(err) {
-		http.NotFound(w, r)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+
+		message.SendUnknownError(w, err)
 		return
 	}
 
 	acc, err := h.accountService.Get(r.Context(), k.AccountName)
 
-	if sgerrors.IsNotFound(err) {
-		http.NotFound(w, r)
-		return
-	}
-
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, k.AccountName, err)
+			return
+		}
+
+		message.SendUnknownError(w, err)
 		return
 	}
 
-	logrus.Debugf("Get cloud profile %s", k.ProfileID)
 	kubeProfile, err := h.profileSvc.Get(r.Context(), k.ProfileID)
 
 	if err != nil {
@@ -1934,31 +4112,83 @@ func (h *Handler) spotMachinePrice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	config, err := steps.NewConfigFromKube(kubeProfile, k)
+
 	if err != nil {
-		logrus.Errorf("New config %v", err.Error())
 		message.SendUnknownError(w, err)
 		return
 	}
 
-	// Get cloud account fill appropriate config structure
-	// with cloud account credentials
-	err = util.FillCloudAccountCredentials(acc, config)
-
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err = util.FillCloudAccountCredentials(acc, config); err != nil {
+		message.SendUnknownError(w, err)
 		return
 	}
 
-	prices, err := getSpotPrices(machineType, config)
+	az := r.URL.Query().Get("availabilityZone")
+	recommendation, err := RecommendSpotPrice(r.Context(), machineType, az, config)
 
 	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, machineType, err)
+			return
+		}
+
 		message.SendUnknownError(w, err)
 		return
 	}
 
-	err = json.NewEncoder(w).Encode(&struct{ Prices []string }{prices})
+	if err = json.NewEncoder(w).Encode(recommendation); err != nil {
+		message.SendUnknownError(w, err)
+	}
+}
+
+// estimateCost returns estimated monthly cost of the profile, account
+// is required for AWS profiles to query prices.
+func (h *Handler) estimateCost(w http.ResponseWriter, r *http.Request) {
+	req := &EstimateRequest{}
 
-	if err != nil {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
 		message.SendInvalidJSON(w, err)
+		return
+	}
+
+	config := &steps.Config{
+		Provider: req.Profile.Provider,
+	}
+
+	if req.Profile.Provider == clouds.AWS {
+		acc, err := h.accountService.Get(r.Context(), req.CloudAccountName)
+
+		if err != nil {
+			if sgerrors.IsNotFound(err) {
+				message.SendNotFound(w, req.CloudAccountName, err)
+				return
+			}
+
+			message.SendUnknownError(w, err)
+			return
+		}
+
+		if err := util.FillCloudAccountCredentials(acc, config); err != nil {
+			message.SendUnknownError(w, err)
+			return
+		}
+
+		config.AWSConfig.Region = req.Profile.Region
+	}
+
+	estimate, err := h.costEstimator.Estimate(r.Context(), &req.Profile, req.Spot, config)
+
+	if err != nil {
+		if sgerrors.IsUnsupportedProvider(err) {
+			message.SendValidationFailed(w, err)
+			return
+		}
+
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(estimate); err != nil {
+		message.SendUnknownError(w, err)
 	}
 }
@@ -3,10 +3,13 @@ package kube
 import (
 	"github.com/pkg/errors"
 	"github.com/supergiant/control/pkg/kubeconfig"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
 	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/sghelm/helm3"
 	"github.com/supergiant/control/pkg/sghelm/proxy"
 )
 
@@ -27,3 +30,26 @@ func helmProxyFrom(kube *model.Kube) (proxy.Interface, error) {
 
 	return proxy.New(coreV1Client, restConf, "")
 }
+
+func helm3DriverFrom(kube *model.Kube) (proxy.Interface, error) {
+	if kube == nil {
+		return nil, errors.Wrap(sgerrors.ErrNilEntity, "kube model")
+	}
+
+	restConf, err := kubeconfig.NewConfigFor(kube)
+	if err != nil {
+		return nil, err
+	}
+
+	clientSet, err := kubernetes.NewForConfig(restConf)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(restConf)
+	if err != nil {
+		return nil, err
+	}
+
+	return helm3.New(clientSet, dynamicClient, kube.K8SVersion), nil
+}
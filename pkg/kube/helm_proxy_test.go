@@ -6,6 +6,7 @@ import (
 
 	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/sghelm/proxy"
 )
 
 func TestHelmProxyFrom(t *testing.T) {
@@ -41,14 +42,20 @@ func TestHelmProxyFrom(t *testing.T) {
 
 	for _, testCase := range testCases {
 		t.Log(testCase.description)
-		_, err := helmProxyFrom(testCase.k)
 
-		if err == nil && testCase.errMsg != "" {
-			t.Error("err must not be nil")
-		}
+		for _, newClientFn := range []func(*model.Kube) (proxy.Interface, error){
+			helmProxyFrom,
+			helm3DriverFrom,
+		} {
+			_, err := newClientFn(testCase.k)
+
+			if err == nil && testCase.errMsg != "" {
+				t.Error("err must not be nil")
+			}
 
-		if err != nil && !strings.Contains(err.Error(), testCase.errMsg) {
-			t.Errorf("Error %v must contain %s", err, testCase.errMsg)
+			if err != nil && !strings.Contains(err.Error(), testCase.errMsg) {
+				t.Errorf("Error %v must contain %s", err, testCase.errMsg)
+			}
 		}
 	}
 }
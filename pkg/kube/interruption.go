@@ -0,0 +1,368 @@
+package kube
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/kubeconfig"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+	"github.com/supergiant/control/pkg/workflows/steps/amazon"
+)
+
+const (
+	DefaultInterruptionPollInterval = 30 * time.Second
+
+	// Spot request status code of instance that gets interruption notice
+	spotStatusMarkedForTermination = "marked-for-termination"
+
+	// Watcher checks which kubes are due to be polled at this period
+	interruptionTick = 5 * time.Second
+)
+
+// errNotInterrupted is returned by update of the kube which nodes are
+// marked interrupting already.
+var errNotInterrupted = errors.New("no nodes are interrupted")
+
+type spotRequestDescriber interface {
+	DescribeSpotInstanceRequestsWithContext(aws.Context, *ec2.DescribeSpotInstanceRequestsInput,
+		...request.Option) (*ec2.DescribeSpotInstanceRequestsOutput, error)
+}
+
+// InterruptionWatcher polls spot requests of AWS kubes and marks nodes
+// which instances are going to be interrupted.
+type InterruptionWatcher struct {
+	svc            Interface
+	accountService accountGetter
+	interval       time.Duration
+
+	getSvc         func(steps.AWSConfig) (spotRequestDescriber, error)
+	corev1ClientFn func(*model.Kube) (corev1client.CoreV1Interface, error)
+	now            func() time.Time
+
+	// Time of the last poll by kube id
+	lastPoll map[string]time.Time
+}
+
+// NewInterruptionWatcher constructs InterruptionWatcher, interval is used
+// for kubes that do not set their own poll interval.
+func NewInterruptionWatcher(svc Interface, accountService accountGetter,
+	interval time.Duration) *InterruptionWatcher {
+	if interval <= 0 {
+		interval = DefaultInterruptionPollInterval
+	}
+
+	return &InterruptionWatcher{
+		svc:            svc,
+		accountService: accountService,
+		interval:       interval,
+		getSvc: func(cfg steps.AWSConfig) (spotRequestDescriber, error) {
+			return amazon.GetEC2(cfg)
+		},
+		corev1ClientFn: kubeconfig.CoreV1Client,
+		now:            time.Now,
+		lastPoll:       make(map[string]time.Time),
+	}
+}
+
+// Run polls kubes until context is done.
+func (w *InterruptionWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(interruptionTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.poll(ctx)
+		}
+	}
+}
+
+func (w *InterruptionWatcher) poll(ctx context.Context) {
+	kubes, err := w.svc.ListAll(ctx)
+
+	if err != nil {
+		logrus.Errorf("interruption watcher: list kubes %v", err)
+		return
+	}
+
+	// Poll times of deleted kubes are forgotten
+	listed := make(map[string]bool, len(kubes))
+
+	for i := range kubes {
+		listed[kubes[i].ID] = true
+	}
+
+	for id := range w.lastPoll {
+		if !listed[id] {
+			delete(w.lastPoll, id)
+		}
+	}
+
+	for i := range kubes {
+		k := &kubes[i]
+
+		if !w.isDue(k) {
+			continue
+		}
+
+		w.lastPoll[k.ID] = w.now()
+
+		if err := w.checkKube(ctx, k); err != nil {
+			logrus.Errorf("interruption watcher: check kube %s %v", k.ID, err)
+		}
+	}
+}
+
+// isDue returns true when AWS kube with spot nodes has not been polled
+// for its poll interval.
+func (w *InterruptionWatcher) isDue(k *model.Kube) bool {
+	if k.Provider != clouds.AWS || k.State == model.StateDetached ||
+		k.SpotInterruption.Disabled || len(spotRequestIDs(k)) == 0 {
+		return false
+	}
+
+	interval := w.interval
+
+	if k.SpotInterruption.PollInterval > 0 {
+		interval = time.Duration(k.SpotInterruption.PollInterval) * time.Second
+	}
+
+	last, ok := w.lastPoll[k.ID]
+
+	return !ok || w.now().Sub(last) >= interval
+}
+
+func (w *InterruptionWatcher) checkKube(ctx context.Context, k *model.Kube) error {
+	acc, err := w.accountService.Get(ctx, k.AccountName)
+
+	if err != nil {
+		return errors.Wrapf(err, "get cloud account %s", k.AccountName)
+	}
+
+	config := &steps.Config{}
+	if err := util.FillCloudAccountCredentials(acc, config); err != nil {
+		return errors.Wrap(err, "fill cloud account credentials")
+	}
+
+	config.AWSConfig.Region = k.Region
+	svc, err := w.getSvc(config.AWSConfig)
+
+	if err != nil {
+		return errors.Wrap(err, "get EC2 client")
+	}
+
+	marked, err := describeInterruptions(ctx, svc, spotRequestIDs(k))
+
+	if err != nil {
+		return err
+	}
+
+	if len(marked) == 0 {
+		return nil
+	}
+
+	var machines []*model.Machine
+
+	// Kube may have been changed while spot requests were described
+	k, err = retryUpdate(ctx, w.svc, k.ID, func(k *model.Kube) error {
+		if machines = markInterrupting(k, marked); len(machines) == 0 {
+			return errNotInterrupted
+		}
+
+		return nil
+	})
+
+	if err == errNotInterrupted {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "update kube")
+	}
+
+	for _, machine := range machines {
+		logrus.Warnf("Spot instance %s of node %s of kube %s is marked for termination",
+			machine.ID, machine.Name, k.ID)
+
+		if !k.SpotInterruption.Drain {
+			continue
+		}
+
+		if err := w.drain(k, machine); err != nil {
+			logrus.Errorf("Drain node %s of kube %s %v", machine.Name, k.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// spotRequestIDs returns spot requests of active kube nodes.
+func spotRequestIDs(k *model.Kube) []string {
+	ids := make([]string, 0)
+
+	for _, machine := range k.Nodes {
+		if machine == nil || machine.State != model.MachineStateActive ||
+			machine.SpotInfo == nil || machine.SpotInfo.RequestID == "" {
+			continue
+		}
+
+		ids = append(ids, machine.SpotInfo.RequestID)
+	}
+
+	return ids
+}
+
+// describeInterruptions returns ids of spot requests which instances are
+// marked for termination.
+func describeInterruptions(ctx context.Context, svc spotRequestDescriber,
+	requestIDs []string) (map[string]bool, error) {
+	// Filter does not fail on requests that have expired
+	input := &ec2.DescribeSpotInstanceRequestsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("spot-instance-request-id"),
+				Values: aws.StringSlice(requestIDs),
+			},
+			{
+				Name:   aws.String("status-code"),
+				Values: aws.StringSlice([]string{spotStatusMarkedForTermination}),
+			},
+		},
+	}
+
+	var out *ec2.DescribeSpotInstanceRequestsOutput
+
+	err := util.Retry(ctx, amazon.RetryPolicy, amazon.IsRetryableErr, "describe spot requests", func() error {
+		var err error
+		out, err = svc.DescribeSpotInstanceRequestsWithContext(ctx, input)
+		return err
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "describe spot requests")
+	}
+
+	marked := make(map[string]bool)
+
+	for _, req := range out.SpotInstanceRequests {
+		if req.Status == nil || aws.StringValue(req.Status.Code) != spotStatusMarkedForTermination {
+			continue
+		}
+
+		marked[aws.StringValue(req.SpotInstanceRequestId)] = true
+	}
+
+	return marked, nil
+}
+
+// markInterrupting sets interrupting state of active nodes which spot
+// requests are marked and returns them.
+func markInterrupting(k *model.Kube, marked map[string]bool) []*model.Machine {
+	machines := make([]*model.Machine, 0)
+
+	for _, machine := range k.Nodes {
+		if machine == nil || machine.State != model.MachineStateActive ||
+			machine.SpotInfo == nil || !marked[machine.SpotInfo.RequestID] {
+			continue
+		}
+
+		machine.State = model.MachineStateInterrupting
+		machines = append(machines, machine)
+	}
+
+	return machines
+}
+
+// drain cordons the node and evicts its pods, pods of daemon sets
+// are left since they are not rescheduled to other nodes.
+func (w *InterruptionWatcher) drain(k *model.Kube, machine *model.Machine) error {
+	client, err := w.corev1ClientFn(k)
+
+	if err != nil {
+		return errors.Wrap(err, "build kubernetes client")
+	}
+
+	node, err := findNode(client, machine.PrivateIp)
+
+	if err != nil {
+		return err
+	}
+
+	node.Spec.Unschedulable = true
+
+	if _, err := client.Nodes().Update(node); err != nil {
+		return errors.Wrapf(err, "cordon node %s", node.Name)
+	}
+
+	pods, err := client.Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node.Name).String(),
+	})
+
+	if err != nil {
+		return errors.Wrapf(err, "list pods of node %s", node.Name)
+	}
+
+	for _, pod := range pods.Items {
+		if isDaemonSetPod(pod) {
+			continue
+		}
+
+		err := client.Pods(pod.Namespace).Evict(&policy.Eviction{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+			},
+		})
+
+		if err != nil {
+			logrus.Warnf("Evict pod %s/%s from node %s %v", pod.Namespace, pod.Name, node.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func findNode(client corev1client.CoreV1Interface, privateIP string) (*corev1.Node, error) {
+	nodes, err := client.Nodes().List(metav1.ListOptions{})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "list nodes")
+	}
+
+	for i := range nodes.Items {
+		for _, addr := range nodes.Items[i].Status.Addresses {
+			if addr.Type == corev1.NodeInternalIP && addr.Address == privateIP {
+				return &nodes.Items[i], nil
+			}
+		}
+	}
+
+	return nil, errors.Wrapf(sgerrors.ErrNotFound, "node with ip %s", privateIP)
+}
+
+func isDaemonSetPod(pod corev1.Pod) bool {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "DaemonSet" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,375 @@
+package kube
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	fakev1client "k8s.io/client-go/kubernetes/typed/core/v1/fake"
+	kubetesting "k8s.io/client-go/testing"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type fakeSpotRequestDescriber struct {
+	input *ec2.DescribeSpotInstanceRequestsInput
+	out   *ec2.DescribeSpotInstanceRequestsOutput
+	err   error
+}
+
+func (f *fakeSpotRequestDescriber) DescribeSpotInstanceRequestsWithContext(ctx aws.Context,
+	input *ec2.DescribeSpotInstanceRequestsInput, opts ...request.Option) (*ec2.DescribeSpotInstanceRequestsOutput, error) {
+	f.input = input
+	return f.out, f.err
+}
+
+func spotRequest(id, statusCode string) *ec2.SpotInstanceRequest {
+	return &ec2.SpotInstanceRequest{
+		SpotInstanceRequestId: aws.String(id),
+		Status: &ec2.SpotInstanceStatus{
+			Code: aws.String(statusCode),
+		},
+	}
+}
+
+func spotKube() *model.Kube {
+	return &model.Kube{
+		ID:          "kube-1",
+		Provider:    clouds.AWS,
+		AccountName: "aws",
+		Region:      "us-east-1",
+		Nodes: map[string]*model.Machine{
+			"node-1": {
+				ID:        "i-1",
+				Name:      "node-1",
+				PrivateIp: "10.0.0.1",
+				State:     model.MachineStateActive,
+				SpotInfo: &model.SpotInfo{
+					RequestID: "sir-1",
+				},
+			},
+			"node-2": {
+				ID:        "i-2",
+				Name:      "node-2",
+				PrivateIp: "10.0.0.2",
+				State:     model.MachineStateActive,
+				SpotInfo: &model.SpotInfo{
+					RequestID: "sir-2",
+				},
+			},
+			"node-3": {
+				ID:        "i-3",
+				Name:      "node-3",
+				PrivateIp: "10.0.0.3",
+				State:     model.MachineStateActive,
+			},
+		},
+	}
+}
+
+func TestDescribeInterruptions(t *testing.T) {
+	testCases := []struct {
+		description string
+		out         *ec2.DescribeSpotInstanceRequestsOutput
+		err         error
+		expected    map[string]bool
+		expectedErr bool
+	}{
+		{
+			description: "error",
+			err:         errors.New("error"),
+			expectedErr: true,
+		},
+		{
+			description: "marked for termination",
+			out: &ec2.DescribeSpotInstanceRequestsOutput{
+				SpotInstanceRequests: []*ec2.SpotInstanceRequest{
+					spotRequest("sir-1", spotStatusMarkedForTermination),
+					spotRequest("sir-2", "fulfilled"),
+					{
+						SpotInstanceRequestId: aws.String("sir-3"),
+					},
+				},
+			},
+			expected: map[string]bool{
+				"sir-1": true,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		svc := &fakeSpotRequestDescriber{
+			out: testCase.out,
+			err: testCase.err,
+		}
+
+		marked, err := describeInterruptions(context.Background(), svc, []string{"sir-1", "sir-2", "sir-3"})
+
+		if testCase.expectedErr {
+			if err == nil {
+				t.Errorf("Error must not be nil")
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if len(marked) != len(testCase.expected) {
+			t.Errorf("Wrong marked requests expected %v actual %v", testCase.expected, marked)
+		}
+
+		for id := range testCase.expected {
+			if !marked[id] {
+				t.Errorf("Request %s must be marked", id)
+			}
+		}
+
+		if len(svc.input.Filters) != 2 {
+			t.Errorf("Wrong count of filters %d", len(svc.input.Filters))
+		}
+	}
+}
+
+func TestMarkInterrupting(t *testing.T) {
+	k := spotKube()
+
+	machines := markInterrupting(k, map[string]bool{"sir-2": true})
+
+	if len(machines) != 1 || machines[0].Name != "node-2" {
+		t.Fatalf("Wrong interrupting machines %v", machines)
+	}
+
+	if k.Nodes["node-2"].State != model.MachineStateInterrupting {
+		t.Errorf("Wrong state of node-2 %s", k.Nodes["node-2"].State)
+	}
+
+	if k.Nodes["node-1"].State != model.MachineStateActive {
+		t.Errorf("Wrong state of node-1 %s", k.Nodes["node-1"].State)
+	}
+
+	if ids := spotRequestIDs(k); len(ids) != 1 || ids[0] != "sir-1" {
+		t.Errorf("Interrupting node must not be polled %v", ids)
+	}
+}
+
+func TestInterruptionWatcherIsDue(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		description string
+		kube        func() *model.Kube
+		lastPoll    time.Time
+		expected    bool
+	}{
+		{
+			description: "never polled",
+			kube:        spotKube,
+			expected:    true,
+		},
+		{
+			description: "polled recently",
+			kube:        spotKube,
+			lastPoll:    now.Add(-time.Second),
+		},
+		{
+			description: "default interval passed",
+			kube:        spotKube,
+			lastPoll:    now.Add(-time.Minute),
+			expected:    true,
+		},
+		{
+			description: "kube interval has not passed",
+			kube: func() *model.Kube {
+				k := spotKube()
+				k.SpotInterruption.PollInterval = 120
+				return k
+			},
+			lastPoll: now.Add(-time.Minute),
+		},
+		{
+			description: "disabled",
+			kube: func() *model.Kube {
+				k := spotKube()
+				k.SpotInterruption.Disabled = true
+				return k
+			},
+		},
+		{
+			description: "not aws",
+			kube: func() *model.Kube {
+				k := spotKube()
+				k.Provider = clouds.GCE
+				return k
+			},
+		},
+		{
+			description: "no spot nodes",
+			kube: func() *model.Kube {
+				k := spotKube()
+				delete(k.Nodes, "node-1")
+				delete(k.Nodes, "node-2")
+				return k
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		w := NewInterruptionWatcher(nil, nil, 0)
+		w.now = func() time.Time {
+			return now
+		}
+
+		k := testCase.kube()
+
+		if !testCase.lastPoll.IsZero() {
+			w.lastPoll[k.ID] = testCase.lastPoll
+		}
+
+		if due := w.isDue(k); due != testCase.expected {
+			t.Errorf("Wrong due expected %v actual %v", testCase.expected, due)
+		}
+	}
+}
+
+func TestInterruptionWatcherPollForgetsDeletedKubes(t *testing.T) {
+	k := spotKube()
+	k.Provider = clouds.GCE
+
+	svc := new(kubeServiceMock)
+	svc.On(serviceListAll, mock.Anything).Return([]model.Kube{*k}, nil)
+
+	w := NewInterruptionWatcher(svc, nil, 0)
+	w.lastPoll[k.ID] = time.Now()
+	w.lastPoll["deleted"] = time.Now()
+
+	w.poll(context.Background())
+
+	if _, ok := w.lastPoll["deleted"]; ok {
+		t.Errorf("Poll time of the deleted kube must be forgotten")
+	}
+
+	if _, ok := w.lastPoll[k.ID]; !ok {
+		t.Errorf("Poll time of the listed kube must be kept")
+	}
+}
+
+func TestInterruptionWatcherCheckKube(t *testing.T) {
+	k := spotKube()
+	// Kube is read again before update, its drain setting is used
+	stored := spotKube()
+	stored.SpotInterruption.Drain = true
+
+	svc := new(kubeServiceMock)
+	svc.On("Get", mock.Anything, k.ID).Return(stored, nil)
+	svc.On(serviceUpdate, mock.Anything, mock.MatchedBy(func(k *model.Kube) bool {
+		return k.Nodes["node-1"].State == model.MachineStateInterrupting &&
+			k.Nodes["node-2"].State == model.MachineStateActive
+	})).Return(nil)
+
+	accSvc := new(accServiceMock)
+	accSvc.On("Get", mock.Anything, k.AccountName).Return(&model.CloudAccount{
+		Name:     k.AccountName,
+		Provider: clouds.AWS,
+	}, nil)
+
+	fake := &fakev1client.FakeCoreV1{
+		Fake: &kubetesting.Fake{},
+	}
+
+	fake.AddReactor("list", "nodes",
+		func(action kubetesting.Action) (bool, runtime.Object, error) {
+			return true, &corev1.NodeList{
+				Items: []corev1.Node{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "ip-10-0-0-1"},
+						Status: corev1.NodeStatus{
+							Addresses: []corev1.NodeAddress{
+								{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+							},
+						},
+					},
+				},
+			}, nil
+		})
+
+	cordoned := false
+	fake.AddReactor("update", "nodes",
+		func(action kubetesting.Action) (bool, runtime.Object, error) {
+			node := action.(kubetesting.UpdateAction).GetObject().(*corev1.Node)
+			cordoned = node.Name == "ip-10-0-0-1" && node.Spec.Unschedulable
+			return true, node, nil
+		})
+
+	fake.AddReactor("list", "pods",
+		func(action kubetesting.Action) (bool, runtime.Object, error) {
+			return true, &corev1.PodList{
+				Items: []corev1.Pod{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "proxy",
+							Namespace: "kube-system",
+							OwnerReferences: []metav1.OwnerReference{
+								{Kind: "DaemonSet"},
+							},
+						},
+					},
+				},
+			}, nil
+		})
+
+	evicted := make([]string, 0)
+	fake.AddReactor("create", "pods",
+		func(action kubetesting.Action) (bool, runtime.Object, error) {
+			if action.GetSubresource() == "eviction" {
+				evicted = append(evicted, action.GetNamespace())
+			}
+			return true, nil, nil
+		})
+
+	w := NewInterruptionWatcher(svc, accSvc, 0)
+	w.getSvc = func(steps.AWSConfig) (spotRequestDescriber, error) {
+		return &fakeSpotRequestDescriber{
+			out: &ec2.DescribeSpotInstanceRequestsOutput{
+				SpotInstanceRequests: []*ec2.SpotInstanceRequest{
+					spotRequest("sir-1", spotStatusMarkedForTermination),
+				},
+			},
+		}, nil
+	}
+	w.corev1ClientFn = func(*model.Kube) (corev1client.CoreV1Interface, error) {
+		return fake, nil
+	}
+
+	if err := w.checkKube(context.Background(), k); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	svc.AssertExpectations(t)
+
+	if !cordoned {
+		t.Errorf("Node must be cordoned")
+	}
+
+	if len(evicted) != 1 || evicted[0] != "default" {
+		t.Errorf("Wrong evicted pods %v", evicted)
+	}
+}
@@ -0,0 +1,303 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/model"
+)
+
+// ClusterMetricsKey is the key of cluster totals in nodes metrics.
+const ClusterMetricsKey = "cluster"
+
+// nodeMetricsQueries are queries of node metrics by key in response,
+// node-exporter series are labeled with node through its pod.
+var nodeMetricsQueries = map[string]string{
+	"cpu":             "node:node_cpu_utilisation:avg1m",
+	"memory":          "node:node_memory_utilisation:",
+	"memoryTotal":     "node:node_memory_bytes_total:sum",
+	"memoryAvailable": "node:node_memory_bytes_available:sum",
+	"diskSaturation":  "node:node_disk_saturation:avg_irate",
+	// Used ratio of root filesystem
+	"filesystem": `max by (node) ((1 - node_filesystem_avail_bytes{mountpoint="/",fstype!="rootfs"} / ` +
+		`node_filesystem_size_bytes{mountpoint="/",fstype!="rootfs"}) ` +
+		`* on (namespace, pod) group_left(node) node_namespace_pod:kube_pod_info:)`,
+	// Bytes per second of physical interfaces
+	"networkReceive": `sum by (node) (irate(node_network_receive_bytes_total{` + networkDevices + `}[1m]) ` +
+		`* on (namespace, pod) group_left(node) node_namespace_pod:kube_pod_info:)`,
+	"networkTransmit": `sum by (node) (irate(node_network_transmit_bytes_total{` + networkDevices + `}[1m]) ` +
+		`* on (namespace, pod) group_left(node) node_namespace_pod:kube_pod_info:)`,
+}
+
+// networkDevices excludes loopback and virtual interfaces of containers.
+const networkDevices = `device!~"lo|veth.+|docker.+|flannel.+|cali.+|cbr.+|cni.+|br.+"`
+
+// ClusterMetrics aggregates node metrics, utilisation is averaged over
+// nodes that report it and memory is summed in bytes.
+type ClusterMetrics struct {
+	CPU         float64 `json:"cpu"`
+	Memory      float64 `json:"memory"`
+	MemoryUsed  float64 `json:"memoryUsed"`
+	MemoryTotal float64 `json:"memoryTotal"`
+
+	// Machines of the kube by state and presence of metrics
+	NodesTotal     int      `json:"nodesTotal"`
+	NodesReady     int      `json:"nodesReady"`
+	NodesReporting int      `json:"nodesReporting"`
+	NodesMissing   []string `json:"nodesMissing"`
+}
+
+// aggregateMetrics computes cluster metrics of processed node metrics,
+// nodes are counted by machines of the kube.
+func aggregateMetrics(k *model.Kube, metrics map[string]map[string]interface{}) ClusterMetrics {
+	cluster := ClusterMetrics{
+		NodesMissing: []string{},
+	}
+
+	var cpuCount, memoryCount int
+
+	for _, machines := range []map[string]*model.Machine{k.Masters, k.Nodes} {
+		for _, machine := range machines {
+			cluster.NodesTotal++
+
+			if machine.State == model.MachineStateActive {
+				cluster.NodesReady++
+			}
+
+			if _, ok := metrics[strings.ToLower(machine.Name)]; ok {
+				cluster.NodesReporting++
+			} else {
+				cluster.NodesMissing = append(cluster.NodesMissing, machine.Name)
+			}
+		}
+	}
+
+	for _, nodeMetrics := range metrics {
+		if cpu, ok := metricValue(nodeMetrics["cpu"]); ok {
+			cluster.CPU += cpu
+			cpuCount++
+		}
+
+		if memory, ok := metricValue(nodeMetrics["memory"]); ok {
+			cluster.Memory += memory
+			memoryCount++
+		}
+
+		total, hasTotal := metricValue(nodeMetrics["memoryTotal"])
+		available, hasAvailable := metricValue(nodeMetrics["memoryAvailable"])
+
+		if hasTotal && hasAvailable {
+			cluster.MemoryTotal += total
+			cluster.MemoryUsed += total - available
+		}
+	}
+
+	if cpuCount > 0 {
+		cluster.CPU /= float64(cpuCount)
+	}
+
+	if memoryCount > 0 {
+		cluster.Memory /= float64(memoryCount)
+	}
+
+	sort.Strings(cluster.NodesMissing)
+
+	return cluster
+}
+
+// metricValue parses value of prometheus sample, prometheus
+// returns values as strings.
+func metricValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// MaxSeriesPoints is the limit of points of node metrics series,
+// longer series are downsampled.
+const MaxSeriesPoints = 300
+
+// MetricRangeResponse is prometheus response of range query.
+type MetricRangeResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Values [][]interface{}   `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// MetricSample is a point of metric series, it is encoded
+// as [timestamp, value] the way prometheus does.
+type MetricSample [2]float64
+
+// getRangeMetrics makes the range query uri through prometheus proxy.
+func getRangeMetrics(ctx context.Context, uri string, k *model.Kube) (*MetricRangeResponse, error) {
+	raw, err := queryMetrics(ctx, uri, k)
+	if err != nil {
+		return nil, err
+	}
+
+	metricResponse := &MetricRangeResponse{}
+	if err := json.Unmarshal(raw, metricResponse); err != nil {
+		return nil, errors.Wrap(err, "unmarshal")
+	}
+
+	return metricResponse, nil
+}
+
+// metricSamples parses prometheus values of the series, values
+// that are not finite numbers are skipped as json can't encode them.
+func metricSamples(values [][]interface{}) []MetricSample {
+	samples := make([]MetricSample, 0, len(values))
+
+	for _, value := range values {
+		if len(value) < 2 {
+			continue
+		}
+
+		ts, ok := metricValue(value[0])
+		if !ok {
+			continue
+		}
+
+		v, ok := metricValue(value[1])
+		if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+
+		samples = append(samples, MetricSample{ts, v})
+	}
+
+	return samples
+}
+
+// downsample averages consecutive samples so that series has at most
+// limit points, each point has timestamp of the first sample it averages.
+func downsample(samples []MetricSample, limit int) []MetricSample {
+	if limit <= 0 || len(samples) <= limit {
+		return samples
+	}
+
+	bucketSize := (len(samples) + limit - 1) / limit
+	result := make([]MetricSample, 0, limit)
+
+	for i := 0; i < len(samples); i += bucketSize {
+		end := i + bucketSize
+
+		if end > len(samples) {
+			end = len(samples)
+		}
+
+		var sum float64
+
+		for _, sample := range samples[i:end] {
+			sum += sample[1]
+		}
+
+		result = append(result, MetricSample{samples[i][0], sum / float64(end-i)})
+	}
+
+	return result
+}
+
+const (
+	// DefaultWorkloadsLimit is count of top namespaces or pods returned.
+	DefaultWorkloadsLimit = 10
+	// MaxWorkloadsLimit limits count of top namespaces or pods.
+	MaxWorkloadsLimit = 100
+
+	// containerSelector excludes pause containers and cgroup totals of pods
+	containerSelector = `{image!="",container!="POD",container_name!="POD"}`
+)
+
+// WorkloadMetrics is resource usage of a namespace or a pod, cpu is in
+// cores and memory in bytes. Requests come from kube-state-metrics.
+type WorkloadMetrics struct {
+	Namespace      string  `json:"namespace"`
+	Pod            string  `json:"pod,omitempty"`
+	CPU            float64 `json:"cpu"`
+	Memory         float64 `json:"memory"`
+	CPURequests    float64 `json:"cpuRequests"`
+	MemoryRequests float64 `json:"memoryRequests"`
+}
+
+// workloadGroups are labels series are summed by for namespaces
+// and pods, pods are labeled with pod_name before k8s 1.16.
+var workloadGroups = map[string]string{
+	"namespaces": "namespace",
+	"pods":       "namespace, pod, pod_name",
+}
+
+// kubeStateMetricsQuery returns no series without kube-state-metrics.
+const kubeStateMetricsQuery = "count(kube_pod_info)"
+
+// workloadQueries returns queries of workload metrics by key of the sum.
+func workloadQueries(groupBy string) map[string]string {
+	by := workloadGroups[groupBy]
+
+	return map[string]string{
+		"cpu": fmt.Sprintf("sum by (%s) (rate(container_cpu_usage_seconds_total%s[5m]))",
+			by, containerSelector),
+		"memory": fmt.Sprintf("sum by (%s) (container_memory_working_set_bytes%s)",
+			by, containerSelector),
+		"cpuRequests": fmt.Sprintf("sum by (%s) (kube_pod_container_resource_requests_cpu_cores)", by),
+		"memoryRequests": fmt.Sprintf("sum by (%s) (kube_pod_container_resource_requests_memory_bytes)",
+			by),
+	}
+}
+
+// workloadKey returns namespace and pod of series labels.
+func workloadKey(labels map[string]string) (string, string) {
+	pod := labels["pod"]
+
+	if pod == "" {
+		pod = labels["pod_name"]
+	}
+
+	return labels["namespace"], pod
+}
+
+// topWorkloads sorts workloads by the metric in descending order
+// and returns first limit of them.
+func topWorkloads(workloads []WorkloadMetrics, sortBy string, limit int) []WorkloadMetrics {
+	value := func(w WorkloadMetrics) float64 {
+		if sortBy == "cpu" {
+			return w.CPU
+		}
+		return w.Memory
+	}
+
+	sort.SliceStable(workloads, func(i, j int) bool {
+		if value(workloads[i]) != value(workloads[j]) {
+			return value(workloads[i]) > value(workloads[j])
+		}
+
+		if workloads[i].Namespace != workloads[j].Namespace {
+			return workloads[i].Namespace < workloads[j].Namespace
+		}
+
+		return workloads[i].Pod < workloads[j].Pod
+	})
+
+	if len(workloads) > limit {
+		workloads = workloads[:limit]
+	}
+
+	return workloads
+}
@@ -0,0 +1,146 @@
+package kube
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultMetricsCacheTTL is how long metrics of a kube are served
+	// from cache before prometheus of the kube is asked again.
+	DefaultMetricsCacheTTL = 15 * time.Second
+
+	// maxMetricsStaleness limits age of cached metrics served
+	// when prometheus of the kube fails.
+	maxMetricsStaleness = 5 * time.Minute
+)
+
+type metricsEntry struct {
+	value     interface{}
+	fetchedAt time.Time
+}
+
+// metricsCall is a fetch in progress, concurrent requests of the
+// same metrics wait for it instead of asking prometheus again.
+type metricsCall struct {
+	done  chan struct{}
+	entry metricsEntry
+	stale bool
+	err   error
+}
+
+// metricsCache caches metrics per kube keyed by the query set,
+// nil cache fetches metrics on every call.
+type metricsCache struct {
+	m       sync.Mutex
+	ttl     time.Duration
+	entries map[string]map[string]metricsEntry
+	calls   map[string]*metricsCall
+}
+
+func newMetricsCache(ttl time.Duration) *metricsCache {
+	return &metricsCache{
+		ttl:     ttl,
+		entries: make(map[string]map[string]metricsEntry),
+		calls:   make(map[string]*metricsCall),
+	}
+}
+
+func (c *metricsCache) setTTL(ttl time.Duration) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.ttl = ttl
+}
+
+// get returns cached metrics of the key unless they are expired or force
+// is set. Previous metrics are returned as stale when fetch fails.
+func (c *metricsCache) get(ctx context.Context, kubeID, key string, force bool,
+	fetch func() (interface{}, error)) (metricsEntry, bool, error) {
+	if c == nil {
+		value, err := fetch()
+		return metricsEntry{value: value, fetchedAt: time.Now()}, false, err
+	}
+
+	c.m.Lock()
+
+	prev, ok := c.entries[kubeID][key]
+
+	if ok && !force && time.Since(prev.fetchedAt) < c.ttl {
+		c.m.Unlock()
+		return prev, false, nil
+	}
+
+	callKey := kubeID + "/" + key
+
+	if call, ok := c.calls[callKey]; ok {
+		c.m.Unlock()
+
+		select {
+		case <-call.done:
+			return call.entry, call.stale, call.err
+		case <-ctx.Done():
+			return metricsEntry{}, false, ctx.Err()
+		}
+	}
+
+	call := &metricsCall{done: make(chan struct{})}
+	c.calls[callKey] = call
+	c.m.Unlock()
+
+	value, err := fetch()
+
+	c.m.Lock()
+
+	switch {
+	case err == nil:
+		call.entry = metricsEntry{value: value, fetchedAt: time.Now()}
+		c.store(kubeID, key, call.entry)
+	case ok && time.Since(prev.fetchedAt) < maxMetricsStaleness:
+		logrus.Warnf("fetch metrics %s of kube %s, serve cached metrics: %v", key, kubeID, err)
+		call.entry, call.stale = prev, true
+	default:
+		call.err = err
+	}
+
+	delete(c.calls, callKey)
+	c.m.Unlock()
+
+	close(call.done)
+
+	return call.entry, call.stale, call.err
+}
+
+// store puts the entry and removes entries of the kube that are
+// too old to be served, must be called with the lock held.
+func (c *metricsCache) store(kubeID, key string, entry metricsEntry) {
+	entries := c.entries[kubeID]
+
+	if entries == nil {
+		entries = make(map[string]metricsEntry)
+		c.entries[kubeID] = entries
+	}
+
+	for k, e := range entries {
+		if time.Since(e.fetchedAt) >= maxMetricsStaleness {
+			delete(entries, k)
+		}
+	}
+
+	entries[key] = entry
+}
+
+// forget removes metrics of the kube, fetches in progress are not affected.
+func (c *metricsCache) forget(kubeID string) {
+	if c == nil {
+		return
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	delete(c.entries, kubeID)
+}
@@ -0,0 +1,117 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMetricsCacheGet(t *testing.T) {
+	var calls int32
+	var fetchErr error
+
+	fetch := func() (interface{}, error) {
+		n := atomic.AddInt32(&calls, 1)
+		return n, fetchErr
+	}
+
+	c := newMetricsCache(time.Hour)
+
+	entry, stale, err := c.get(context.Background(), "kube", "metrics", false, fetch)
+
+	if err != nil || stale || entry.value != int32(1) {
+		t.Errorf("Wrong entry %+v stale %v error %v", entry, stale, err)
+	}
+
+	entry, _, _ = c.get(context.Background(), "kube", "metrics", false, fetch)
+
+	if calls != 1 || entry.value != int32(1) {
+		t.Errorf("Cached metrics must be used, fetch calls %d", calls)
+	}
+
+	entry, _, _ = c.get(context.Background(), "kube", "nodes", false, fetch)
+
+	if calls != 2 || entry.value != int32(2) {
+		t.Errorf("Metrics must be cached by key, fetch calls %d", calls)
+	}
+
+	fetchErr = errors.New("prometheus is down")
+	entry, stale, err = c.get(context.Background(), "kube", "metrics", true, fetch)
+
+	if calls != 3 {
+		t.Errorf("Forced refresh must fetch, fetch calls %d", calls)
+	}
+
+	if err != nil || !stale || entry.value != int32(1) {
+		t.Errorf("Stale metrics must be served on error %+v stale %v error %v", entry, stale, err)
+	}
+
+	c.forget("kube")
+
+	if _, _, err = c.get(context.Background(), "kube", "metrics", false, fetch); err != fetchErr {
+		t.Errorf("Expected error %v actual %v", fetchErr, err)
+	}
+
+	fetchErr = nil
+	c.setTTL(0)
+	c.get(context.Background(), "kube", "metrics", false, fetch)
+	c.get(context.Background(), "kube", "metrics", false, fetch)
+
+	if calls != 6 {
+		t.Errorf("Expired metrics must be fetched, fetch calls %d", calls)
+	}
+}
+
+func TestMetricsCacheConcurrent(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+
+	fetch := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "metrics", nil
+	}
+
+	c := newMetricsCache(time.Hour)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			entry, _, err := c.get(context.Background(), "kube", "metrics", true, fetch)
+
+			if err != nil || entry.value != "metrics" {
+				t.Errorf("Wrong entry %+v error %v", entry, err)
+			}
+		}()
+	}
+
+	// Let callers join the fetch in progress
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("Concurrent fetches must be deduplicated, fetch calls %d", calls)
+	}
+}
+
+func TestMetricsCacheNil(t *testing.T) {
+	var c *metricsCache
+
+	entry, stale, err := c.get(context.Background(), "kube", "metrics", false,
+		func() (interface{}, error) {
+			return "metrics", nil
+		})
+
+	if err != nil || stale || entry.value != "metrics" {
+		t.Errorf("Wrong entry %+v stale %v error %v", entry, stale, err)
+	}
+
+	c.forget("kube")
+}
@@ -0,0 +1,134 @@
+package kube
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/supergiant/control/pkg/model"
+)
+
+func TestAggregateMetrics(t *testing.T) {
+	k := &model.Kube{
+		Masters: map[string]*model.Machine{
+			"master-1": {Name: "Master-1", State: model.MachineStateActive},
+		},
+		Nodes: map[string]*model.Machine{
+			"node-1": {Name: "node-1", State: model.MachineStateActive},
+			"node-2": {Name: "node-2", State: model.MachineStateActive},
+			"node-3": {Name: "node-3", State: model.MachineStateProvisioning},
+			"node-4": {Name: "node-4", State: model.MachineStateError},
+		},
+	}
+
+	testCases := []struct {
+		description string
+		metrics     map[string]map[string]interface{}
+
+		expected ClusterMetrics
+	}{
+		{
+			description: "no metrics",
+			metrics:     map[string]map[string]interface{}{},
+			expected: ClusterMetrics{
+				NodesTotal:   5,
+				NodesReady:   3,
+				NodesMissing: []string{"Master-1", "node-1", "node-2", "node-3", "node-4"},
+			},
+		},
+		{
+			description: "partial metrics",
+			metrics: map[string]map[string]interface{}{
+				"master-1": {
+					"cpu":             "0.5",
+					"memory":          "0.25",
+					"memoryTotal":     "4000",
+					"memoryAvailable": "3000",
+				},
+				"node-1": {
+					"cpu":             0.1,
+					"memory":          0.75,
+					"memoryTotal":     8000.0,
+					"memoryAvailable": 2000.0,
+				},
+				"node-2": {
+					"cpu": "NaN-value",
+				},
+			},
+			expected: ClusterMetrics{
+				CPU:            0.3,
+				Memory:         0.5,
+				MemoryUsed:     7000,
+				MemoryTotal:    12000,
+				NodesTotal:     5,
+				NodesReady:     3,
+				NodesReporting: 3,
+				NodesMissing:   []string{"node-3", "node-4"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual := aggregateMetrics(k, testCase.metrics)
+
+		if math.Abs(actual.CPU-testCase.expected.CPU) < 1e-9 {
+			actual.CPU = testCase.expected.CPU
+		}
+
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("TC: %s expected %+v actual %+v",
+				testCase.description, testCase.expected, actual)
+		}
+	}
+}
+
+func TestMetricSamples(t *testing.T) {
+	values := [][]interface{}{
+		{float64(1564617600), "0.5"},
+		{float64(1564617630), "NaN"},
+		{float64(1564617660), "bad"},
+		{float64(1564617690)},
+		{float64(1564617720), "0.25"},
+	}
+
+	samples := metricSamples(values)
+
+	if !reflect.DeepEqual(samples, []MetricSample{{1564617600, 0.5}, {1564617720, 0.25}}) {
+		t.Errorf("Wrong samples %v", samples)
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	samples := make([]MetricSample, 0, 10)
+
+	for i := 0; i < 10; i++ {
+		samples = append(samples, MetricSample{float64(i * 30), float64(i)})
+	}
+
+	testCases := []struct {
+		limit    int
+		expected []MetricSample
+	}{
+		{
+			limit:    10,
+			expected: samples,
+		},
+		{
+			limit:    5,
+			expected: []MetricSample{{0, 0.5}, {60, 2.5}, {120, 4.5}, {180, 6.5}, {240, 8.5}},
+		},
+		{
+			limit:    3,
+			expected: []MetricSample{{0, 1.5}, {120, 5.5}, {240, 8.5}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual := downsample(samples, testCase.limit)
+
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("Wrong samples for limit %d expected %v actual %v",
+				testCase.limit, testCase.expected, actual)
+		}
+	}
+}
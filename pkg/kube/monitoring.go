@@ -0,0 +1,144 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	clientcmddapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/supergiant/control/pkg/model"
+)
+
+// prometheusReadyPath is the readiness endpoint of prometheus
+// behind the service metrics are queried through.
+const prometheusReadyPath = "/" + prometheusProxyURL + "/-/ready"
+
+// discoverMonitoring checks readiness of prometheus workloads in
+// kube-system and probes the query endpoint.
+func discoverMonitoring(ctx context.Context, kubeConfig *clientcmddapi.Config) (model.MonitoringStatus, error) {
+	restConf, err := restConfigFor(ctx, kubeConfig)
+
+	if err != nil {
+		return model.MonitoringStatus{}, errors.Wrapf(err, "create rest config")
+	}
+
+	clientSet, err := kubernetes.NewForConfig(restConf)
+
+	if err != nil {
+		return model.MonitoringStatus{}, errors.Wrapf(err, "get client set")
+	}
+
+	statefulSetList := &appsv1.StatefulSetList{}
+	err = clientSet.AppsV1().RESTClient().Get().
+		Namespace("kube-system").
+		Resource("statefulsets").
+		VersionedParams(&v1.ListOptions{}, scheme.ParameterCodec).
+		Context(ctx).
+		Do().
+		Into(statefulSetList)
+
+	if err != nil {
+		return model.MonitoringStatus{}, errors.Wrapf(err, "list statefulsets")
+	}
+
+	deploymentList := &appsv1.DeploymentList{}
+	err = clientSet.AppsV1().RESTClient().Get().
+		Namespace("kube-system").
+		Resource("deployments").
+		VersionedParams(&v1.ListOptions{}, scheme.ParameterCodec).
+		Context(ctx).
+		Do().
+		Into(deploymentList)
+
+	if err != nil {
+		return model.MonitoringStatus{}, errors.Wrapf(err, "list deployments")
+	}
+
+	probeErr := clientSet.CoreV1().RESTClient().Get().
+		AbsPath(prometheusReadyPath).
+		Context(ctx).
+		Do().
+		Error()
+
+	return monitoringStatus(statefulSetList.Items, deploymentList.Items, probeErr), nil
+}
+
+// monitoringStatus tells state of prometheus by its workloads and
+// result of the probe of query endpoint.
+func monitoringStatus(statefulSets []appsv1.StatefulSet, deployments []appsv1.Deployment,
+	probeErr error) model.MonitoringStatus {
+	status := model.MonitoringStatus{
+		State:     model.MonitoringInstalled,
+		CheckedAt: time.Now().Unix(),
+	}
+
+	var found bool
+	var notReady []string
+
+	for _, sts := range statefulSets {
+		if !isPrometheus(sts.Spec.Template.Labels) {
+			continue
+		}
+
+		found = true
+
+		if replicas(sts.Spec.Replicas) > sts.Status.ReadyReplicas {
+			notReady = append(notReady, fmt.Sprintf("statefulset %s: %d/%d replicas ready",
+				sts.Name, sts.Status.ReadyReplicas, replicas(sts.Spec.Replicas)))
+		}
+	}
+
+	for _, deployment := range deployments {
+		if !isPrometheus(deployment.Spec.Template.Labels) {
+			continue
+		}
+
+		found = true
+
+		if replicas(deployment.Spec.Replicas) > deployment.Status.ReadyReplicas {
+			notReady = append(notReady, fmt.Sprintf("deployment %s: %d/%d replicas ready",
+				deployment.Name, deployment.Status.ReadyReplicas, replicas(deployment.Spec.Replicas)))
+		}
+	}
+
+	switch {
+	case !found:
+		status.State = model.MonitoringMissing
+		status.Reason = "prometheus is not deployed to kube-system"
+	case len(notReady) > 0:
+		status.State = model.MonitoringDegraded
+		status.Reason = strings.Join(notReady, ", ")
+	case probeErr != nil:
+		status.State = model.MonitoringDegraded
+		status.Reason = fmt.Sprintf("query endpoint is not ready: %v", probeErr)
+	}
+
+	return status
+}
+
+// isPrometheus matches pods of prometheus server by labels of prometheus
+// operator and helm chart, alertmanager and exporters are not matched.
+func isPrometheus(podLabels map[string]string) bool {
+	if podLabels["app.kubernetes.io/name"] == "prometheus" {
+		return true
+	}
+
+	return podLabels["app"] == "prometheus" &&
+		(podLabels["component"] == "" || podLabels["component"] == "server")
+}
+
+// replicas returns desired replicas, nil means one.
+func replicas(r *int32) int32 {
+	if r == nil {
+		return 1
+	}
+
+	return *r
+}
@@ -0,0 +1,107 @@
+package kube
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/supergiant/control/pkg/model"
+)
+
+func TestMonitoringStatus(t *testing.T) {
+	two := int32(2)
+
+	prometheus := appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "prometheus-k8s"},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &two,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"app": "prometheus", "prometheus": "k8s"},
+				},
+			},
+		},
+		Status: appsv1.StatefulSetStatus{ReadyReplicas: 2},
+	}
+
+	degraded := *prometheus.DeepCopy()
+	degraded.Status.ReadyReplicas = 1
+
+	alertmanager := appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "prometheus-alertmanager"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"app": "prometheus", "component": "alertmanager"},
+				},
+			},
+		},
+	}
+
+	server := *alertmanager.DeepCopy()
+	server.Name = "prometheus-server"
+	server.Spec.Template.Labels["component"] = "server"
+	server.Status.ReadyReplicas = 1
+
+	testCases := []struct {
+		description  string
+		statefulSets []appsv1.StatefulSet
+		deployments  []appsv1.Deployment
+		probeErr     error
+
+		expectedState  model.MonitoringState
+		expectedReason string
+	}{
+		{
+			description:   "prometheus operator",
+			statefulSets:  []appsv1.StatefulSet{prometheus},
+			expectedState: model.MonitoringInstalled,
+		},
+		{
+			description:   "helm chart",
+			deployments:   []appsv1.Deployment{alertmanager, server},
+			expectedState: model.MonitoringInstalled,
+		},
+		{
+			description:    "not deployed",
+			deployments:    []appsv1.Deployment{alertmanager},
+			expectedState:  model.MonitoringMissing,
+			expectedReason: "not deployed",
+		},
+		{
+			description:    "replicas not ready",
+			statefulSets:   []appsv1.StatefulSet{degraded},
+			expectedState:  model.MonitoringDegraded,
+			expectedReason: "prometheus-k8s: 1/2",
+		},
+		{
+			description:    "probe failed",
+			statefulSets:   []appsv1.StatefulSet{prometheus},
+			probeErr:       errors.New("no endpoints available"),
+			expectedState:  model.MonitoringDegraded,
+			expectedReason: "no endpoints available",
+		},
+	}
+
+	for _, testCase := range testCases {
+		status := monitoringStatus(testCase.statefulSets, testCase.deployments, testCase.probeErr)
+
+		if status.State != testCase.expectedState {
+			t.Errorf("%s: wrong state expected %s actual %s",
+				testCase.description, testCase.expectedState, status.State)
+		}
+
+		if !strings.Contains(status.Reason, testCase.expectedReason) {
+			t.Errorf("%s: reason %s must contain %s",
+				testCase.description, status.Reason, testCase.expectedReason)
+		}
+
+		if status.CheckedAt == 0 {
+			t.Errorf("%s: check time must be set", testCase.description)
+		}
+	}
+}
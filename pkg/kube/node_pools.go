@@ -0,0 +1,652 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/account"
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/message"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows"
+	"github.com/supergiant/control/pkg/workflows/statuses"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+// poolRemovalParallelism is count of pool nodes drained and deleted at once.
+const poolRemovalParallelism = 5
+
+// NodePoolResize changes count of nodes of the pool.
+type NodePoolResize struct {
+	Count int64 `json:"count"`
+}
+
+type nodePoolView struct {
+	*model.NodePool
+
+	Nodes []*model.Machine `json:"nodes"`
+}
+
+type nodePoolResponse struct {
+	Pool *model.NodePool `json:"pool"`
+	// TaskID of the task adding or removing nodes of the pool
+	TaskID string `json:"taskId,omitempty"`
+	// TaskIDs of node tasks run by the pool task
+	TaskIDs []string `json:"taskIds,omitempty"`
+}
+
+func (h *Handler) listNodePools(w http.ResponseWriter, r *http.Request) {
+	kubeID := mux.Vars(r)["kubeID"]
+	k, err := h.svc.Get(r.Context(), kubeID)
+
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	pools := make([]nodePoolView, 0, len(k.NodePools))
+
+	for _, pool := range k.NodePools {
+		pools = append(pools, nodePoolView{
+			NodePool: pool,
+			Nodes:    poolNodes(k, pool.Name),
+		})
+	}
+
+	sort.Slice(pools, func(i, j int) bool {
+		return pools[i].Name < pools[j].Name
+	})
+
+	if err := json.NewEncoder(w).Encode(pools); err != nil {
+		logrus.Errorf("encode node pools %v", err)
+	}
+}
+
+// createNodePool adds the pool to the kube and provisions its nodes.
+func (h *Handler) createNodePool(w http.ResponseWriter, r *http.Request) {
+	kubeID := mux.Vars(r)["kubeID"]
+	k, err := h.svc.Get(r.Context(), kubeID)
+
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	pool := &model.NodePool{}
+
+	if err := json.NewDecoder(r.Body).Decode(pool); err != nil {
+		message.SendInvalidJSON(w, err)
+		return
+	}
+
+	if err := validateNodePool(k, pool); err != nil {
+		message.SendValidationFailed(w, err)
+		return
+	}
+
+	if _, ok := k.NodePools[pool.Name]; ok {
+		message.SendAlreadyExists(w, pool.Name, sgerrors.ErrAlreadyExists)
+		return
+	}
+
+	kubeProfile, err := h.profileSvc.Get(r.Context(), k.ProfileID)
+
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	// Nodes of profile pools can not be told apart from nodes of the pool
+	if _, err := profile.FindNodePool(kubeProfile.NodePools, pool.Name); err == nil {
+		message.SendValidationFailed(w, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"node pool %s is defined by profile %s", pool.Name, kubeProfile.ID))
+		return
+	}
+
+	if k.NodePools == nil {
+		k.NodePools = make(map[string]*model.NodePool)
+	}
+
+	k.NodePools[pool.Name] = pool
+	resp := nodePoolResponse{
+		Pool: pool,
+	}
+
+	if pool.Count > 0 || pool.Autoscale {
+		resp.TaskID, resp.TaskIDs, err = h.addPoolNodes(r.Context(), k, pool, pool.Count)
+	} else {
+		err = h.savePool(r.Context(), kubeID, pool, nil)
+	}
+
+	if err != nil {
+		sendNodePoolError(w, pool.Name, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logrus.Errorf("encode node pool %v", err)
+	}
+}
+
+// resizeNodePool adds or removes nodes of the pool to match the count.
+func (h *Handler) resizeNodePool(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	kubeID, poolName := vars["kubeID"], vars["poolName"]
+	k, pool, ok := h.getNodePool(w, r, kubeID, poolName)
+
+	if !ok {
+		return
+	}
+
+	req := &NodePoolResize{}
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		message.SendInvalidJSON(w, err)
+		return
+	}
+
+	if req.Count < 0 {
+		message.SendValidationFailed(w, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"node count must not be negative, got %d", req.Count))
+		return
+	}
+
+	if pool.Autoscale {
+		message.SendValidationFailed(w, errAutoscaledPool(poolName))
+		return
+	}
+
+	nodes := poolNodes(k, poolName)
+	delta := req.Count - int64(len(nodes))
+	pool.Count = req.Count
+	resp := nodePoolResponse{
+		Pool: pool,
+	}
+
+	var err error
+
+	switch {
+	case delta > 0:
+		resp.TaskID, resp.TaskIDs, err = h.addPoolNodes(r.Context(), k, pool, delta)
+	case delta < 0:
+		resp.TaskID, resp.TaskIDs, err = h.removePoolNodes(r.Context(), k, pool,
+			nodesToRemove(nodes, int(-delta)), false)
+	default:
+		err = h.savePool(r.Context(), kubeID, pool, nil)
+	}
+
+	if err != nil {
+		sendNodePoolError(w, pool.Name, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logrus.Errorf("encode node pool %v", err)
+	}
+}
+
+// deleteNodePool drains and deletes all nodes of the pool, the pool
+// is removed from the kube when they are deleted.
+func (h *Handler) deleteNodePool(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	kubeID, poolName := vars["kubeID"], vars["poolName"]
+	k, pool, ok := h.getNodePool(w, r, kubeID, poolName)
+
+	if !ok {
+		return
+	}
+
+	var (
+		taskID  string
+		taskIDs []string
+		err     error
+	)
+
+	pool.Count = 0
+
+	// Instances of the group are terminated with it, they are not drained
+	if pool.AutoScalingGroup != "" {
+		taskID, err = h.deleteAutoScalingGroup(r.Context(), k, pool)
+	} else {
+		taskID, taskIDs, err = h.removePoolNodes(r.Context(), k, pool, poolNodes(k, poolName), true)
+	}
+
+	if err != nil {
+		sendNodePoolError(w, pool.Name, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(nodePoolResponse{
+		Pool:    pool,
+		TaskID:  taskID,
+		TaskIDs: taskIDs,
+	}); err != nil {
+		logrus.Errorf("encode node pool %v", err)
+	}
+}
+
+// getNodePool responds with an error when the pool is not found
+// or nodes of the pool are still being added or removed.
+func (h *Handler) getNodePool(w http.ResponseWriter, r *http.Request,
+	kubeID, poolName string) (*model.Kube, *model.NodePool, bool) {
+	k, err := h.svc.Get(r.Context(), kubeID)
+
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return nil, nil, false
+		}
+
+		message.SendUnknownError(w, err)
+		return nil, nil, false
+	}
+
+	pool := k.NodePools[poolName]
+
+	if pool == nil {
+		message.SendNotFound(w, poolName, sgerrors.ErrNotFound)
+		return nil, nil, false
+	}
+
+	running, err := h.TaskRunning(r.Context(), pool.TaskID)
+
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return nil, nil, false
+	}
+
+	if running {
+		message.SendMessage(w, message.New(
+			fmt.Sprintf("Node pool %s is being changed", poolName),
+			fmt.Sprintf("task %s of node pool is running", pool.TaskID),
+			sgerrors.ValidationFailed, ""), http.StatusConflict)
+		return nil, nil, false
+	}
+
+	return k, pool, true
+}
+
+// TaskRunning returns true while the task is waiting or being executed,
+// tasks that are not found are not running.
+func (h *Handler) TaskRunning(ctx context.Context, taskID string) (bool, error) {
+	if taskID == "" {
+		return false, nil
+	}
+
+	data, err := h.repo.Get(ctx, workflows.Prefix, taskID)
+
+	if err != nil && !sgerrors.IsNotFound(err) {
+		return false, errors.Wrapf(err, "get task %s", taskID)
+	}
+
+	task := &workflows.Task{}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, task); err != nil {
+			return false, errors.Wrapf(err, "unmarshal task %s", taskID)
+		}
+	}
+
+	return task.Status == statuses.Todo || task.Status == statuses.Executing, nil
+}
+
+// addPoolNodes provisions count nodes of the pool, the kube is saved
+// with ids of tasks that provision them.
+func (h *Handler) addPoolNodes(ctx context.Context, k *model.Kube, pool *model.NodePool,
+	count int64) (string, []string, error) {
+	config, _, err := h.spotConfig(ctx, k)
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	config.Pool = pool.Name
+
+	if pool.Autoscale {
+		taskID, err := h.createAutoScalingGroup(ctx, k, pool, config, count)
+		return taskID, nil, err
+	}
+
+	if pool.GPU {
+		err := account.ValidateGPUMachineType(ctx, config, k.Region, pool.MachineType)
+
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "node pool %s", pool.Name)
+		}
+	}
+
+	if pool.Spot {
+		req := &SpotRequest{
+			MachineType:  pool.MachineType,
+			MachineCount: count,
+			SpotPrice:    pool.SpotPrice,
+			Pool:         pool.Name,
+		}
+
+		if err := validateSpotRequest(req, time.Now()); err != nil {
+			return "", nil, err
+		}
+
+		if err := setSpotConfig(config, []profile.NodePool{pool.NodePool}, req); err != nil {
+			return "", nil, err
+		}
+
+		t, err := h.startSpotTask(ctx, k, config, "")
+
+		if err != nil {
+			return "", nil, err
+		}
+
+		// Spot task provisions all nodes of the pool
+		pool.TaskID = t.ID
+
+		return t.ID, nil, h.savePool(ctx, k.ID, pool, nil)
+	}
+
+	t, taskIDs, err := h.nodeProvisioner.ProvisionNodePool(context.Background(),
+		pool.NodePool, int(count), k, config)
+
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "provision node pool %s", pool.Name)
+	}
+
+	pool.TaskID = t.ID
+	err = h.savePool(ctx, k.ID, pool, func(k *model.Kube) {
+		appendTasks(k, workflows.NodePoolTask, t.ID)
+		appendTasks(k, workflows.NodeTask, taskIDs...)
+	})
+
+	return t.ID, taskIDs, err
+}
+
+// removePoolNodes drains and deletes nodes of the pool by the pool task,
+// the pool is removed from the kube afterwards when deletePool is set.
+func (h *Handler) removePoolNodes(ctx context.Context, k *model.Kube, pool *model.NodePool,
+	nodes []*model.Machine, deletePool bool) (string, []string, error) {
+	acc, err := h.accountService.Get(ctx, k.AccountName)
+
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "get cloud account %s", k.AccountName)
+	}
+
+	poolTask, err := workflows.NewTask(&steps.Config{
+		Kube:             *k,
+		Provider:         k.Provider,
+		CloudAccountName: k.AccountName,
+		Pool:             pool.Name,
+	}, workflows.NodePool, h.repo)
+
+	if err != nil {
+		return "", nil, errors.Wrap(err, "new pool task")
+	}
+
+	removals := make([]poolNodeRemoval, 0, len(nodes))
+	taskIDs := make([]string, 0, len(nodes))
+
+	for _, n := range nodes {
+		// Removed nodes leave the pool regardless of the result of their drain
+		t, config, writer, err := h.newDeleteNodeTask(k, n, acc, steps.DrainConfig{Force: true})
+
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "delete node %s", n.Name)
+		}
+
+		removals = append(removals, poolNodeRemoval{
+			node:   n.Name,
+			task:   t,
+			config: config,
+			out:    writer,
+		})
+		taskIDs = append(taskIDs, t.ID)
+	}
+
+	pool.TaskID = poolTask.ID
+	err = h.savePool(ctx, k.ID, pool, func(k *model.Kube) {
+		for _, n := range nodes {
+			if node := k.Nodes[n.Name]; node != nil {
+				node.State = model.MachineStateDeleting
+			}
+		}
+
+		appendTasks(k, workflows.NodePoolTask, poolTask.ID)
+	})
+
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "update kube %s", k.ID)
+	}
+
+	go h.runPoolRemoval(k.ID, pool.Name, poolTask, removals, deletePool)
+
+	return poolTask.ID, taskIDs, nil
+}
+
+type poolNodeRemoval struct {
+	node   string
+	task   *workflows.Task
+	config *steps.Config
+	out    io.WriteCloser
+}
+
+func (h *Handler) runPoolRemoval(kubeID, poolName string, poolTask *workflows.Task,
+	removals []poolNodeRemoval, deletePool bool) {
+	var (
+		wg     sync.WaitGroup
+		m      sync.Mutex
+		failed int
+	)
+
+	if err := poolTask.SetStatus(context.Background(), statuses.Executing); err != nil {
+		logrus.Errorf("save pool task %s %v", poolTask.ID, err)
+	}
+
+	sem := make(chan struct{}, poolRemovalParallelism)
+
+	for _, removal := range removals {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(removal poolNodeRemoval) {
+			defer wg.Done()
+			defer func() {
+				<-sem
+			}()
+
+			err := <-removal.task.Run(context.Background(), removal.config, removal.out)
+
+			if err != nil {
+				logrus.Errorf("delete node %s of pool %s caused %v", removal.node, poolName, err)
+			}
+
+			// Pool task is shared by removals, the kube record is updated
+			// outside of the lock as retryUpdate handles concurrent writes.
+			m.Lock()
+			if err != nil {
+				failed++
+			}
+
+			if err := poolTask.SetSubtaskStatus(context.Background(), removal.task.ID,
+				removal.task.Status); err != nil {
+				logrus.Errorf("save pool task %s %v", poolTask.ID, err)
+			}
+			m.Unlock()
+
+			h.updateKube(kubeID, func(k *model.Kube) {
+				delete(k.Nodes, removal.node)
+			})
+		}(removal)
+	}
+
+	wg.Wait()
+
+	if deletePool {
+		h.updateKube(kubeID, func(k *model.Kube) {
+			delete(k.NodePools, poolName)
+		})
+	}
+
+	status := statuses.Success
+
+	if failed > 0 {
+		status = statuses.Error
+	}
+
+	if err := poolTask.SetStatus(context.Background(), status); err != nil {
+		logrus.Errorf("save pool task %s %v", poolTask.ID, err)
+	}
+}
+
+// savePool saves the pool to the latest kube record along with changes
+// of update, machines saved by running tasks meanwhile are kept.
+func (h *Handler) savePool(ctx context.Context, kubeID string, pool *model.NodePool,
+	update func(*model.Kube)) error {
+	_, err := retryUpdate(ctx, h.svc, kubeID, func(k *model.Kube) error {
+		if k.NodePools == nil {
+			k.NodePools = make(map[string]*model.NodePool)
+		}
+
+		k.NodePools[pool.Name] = pool
+
+		if update != nil {
+			update(k)
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// updateKube applies update to the latest kube record and saves it.
+func (h *Handler) updateKube(kubeID string, update func(*model.Kube)) {
+	_, err := retryUpdate(context.Background(), h.svc, kubeID, func(k *model.Kube) error {
+		update(k)
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("update kube %s %v", kubeID, err)
+	}
+}
+
+func sendNodePoolError(w http.ResponseWriter, poolName string, err error) {
+	switch {
+	case sgerrors.IsValidationFailed(err):
+		message.SendValidationFailed(w, err)
+	case sgerrors.IsNotFound(err):
+		message.SendNotFound(w, poolName, err)
+	default:
+		message.SendUnknownError(w, err)
+	}
+}
+
+// validateNodePool checks pool settings, spot and autoscaled pools are
+// supported only on AWS and can not set taints, labels or GPUs of their nodes.
+func validateNodePool(k *model.Kube, pool *model.NodePool) error {
+	if err := profile.ValidateNodePools([]profile.NodePool{pool.NodePool}); err != nil {
+		return err
+	}
+
+	if pool.Autoscale {
+		return validateAutoscaledPool(k, pool)
+	}
+
+	if !pool.Spot {
+		return nil
+	}
+
+	// Spot nodes join by user data, GPU step is never run for them
+	if pool.GPU {
+		return errors.Wrap(sgerrors.ErrValidationFailed,
+			"GPU spot node pools are not supported")
+	}
+
+	if k.Provider != clouds.AWS {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"spot node pools are not supported by provider %s", k.Provider)
+	}
+
+	if len(pool.Taints) > 0 || len(pool.Labels) > 0 {
+		return errors.Wrap(sgerrors.ErrValidationFailed,
+			"taints and labels of spot node pools are not supported")
+	}
+
+	return nil
+}
+
+// poolNodes returns nodes of the pool that are not being deleted.
+func poolNodes(k *model.Kube, poolName string) []*model.Machine {
+	nodes := make([]*model.Machine, 0)
+
+	for _, n := range k.Nodes {
+		if n != nil && n.Pool == poolName && n.State != model.MachineStateDeleting {
+			nodes = append(nodes, n)
+		}
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+
+	return nodes
+}
+
+// nodesToRemove picks failed nodes first and then the newest ones.
+func nodesToRemove(nodes []*model.Machine, count int) []*model.Machine {
+	candidates := append([]*model.Machine(nil), nodes...)
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		iFailed := candidates[i].State == model.MachineStateError
+		jFailed := candidates[j].State == model.MachineStateError
+
+		if iFailed != jFailed {
+			return iFailed
+		}
+
+		return candidates[i].CreatedAt > candidates[j].CreatedAt
+	})
+
+	if count > len(candidates) {
+		count = len(candidates)
+	}
+
+	return candidates[:count]
+}
+
+// releasePoolNode decreases count of the pool node belongs to,
+// so nodes deleted by user are not counted as missing.
+func releasePoolNode(k *model.Kube, machine *model.Machine) {
+	if machine == nil || machine.Pool == "" {
+		return
+	}
+
+	pool := k.NodePools[machine.Pool]
+
+	if pool != nil && pool.Count > 0 {
+		pool.Count--
+	}
+}
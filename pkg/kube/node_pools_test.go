@@ -0,0 +1,411 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/storage/memory"
+	"github.com/supergiant/control/pkg/workflows"
+	"github.com/supergiant/control/pkg/workflows/statuses"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type drainStep struct{}
+
+func (drainStep) Run(context.Context, io.Writer, *steps.Config) error      { return nil }
+func (drainStep) Name() string                                             { return "drain" }
+func (drainStep) Description() string                                      { return "" }
+func (drainStep) Depends() []string                                        { return nil }
+func (drainStep) Rollback(context.Context, io.Writer, *steps.Config) error { return nil }
+
+func poolKube() *model.Kube {
+	return &model.Kube{
+		ID:          "test",
+		AccountName: "test",
+		Provider:    clouds.DigitalOcean,
+		Masters: map[string]*model.Machine{
+			"master": {Name: "master"},
+		},
+		Nodes: map[string]*model.Machine{
+			"gpu-1": {Name: "gpu-1", Pool: "gpu", CreatedAt: 1},
+			"gpu-2": {Name: "gpu-2", Pool: "gpu", CreatedAt: 2},
+			"gpu-3": {Name: "gpu-3", Pool: "gpu", CreatedAt: 3, State: model.MachineStateError},
+			"other": {Name: "other"},
+		},
+		NodePools: map[string]*model.NodePool{
+			"gpu": {
+				NodePool: profile.NodePool{
+					Name:        "gpu",
+					MachineType: "s-2vcpu-4gb",
+					Count:       3,
+				},
+			},
+		},
+		Tasks: make(map[string][]string),
+	}
+}
+
+func poolHandler(k *model.Kube, kubeErr error, provisioner *mockNodeProvisioner) *Handler {
+	svc := new(kubeServiceMock)
+	svc.On(serviceGet, mock.Anything, mock.Anything).Return(k, kubeErr)
+	svc.On(serviceUpdate, mock.Anything, mock.Anything).Return(nil)
+
+	profileSvc := new(mockProfileService)
+	profileSvc.On("Get", mock.Anything, mock.Anything).Return(&profile.Profile{
+		ID: "profile",
+		NodePools: []profile.NodePool{
+			{Name: "general", MachineType: "s-2vcpu-4gb"},
+		},
+	}, nil)
+
+	accService := new(accServiceMock)
+	accService.On("Get", mock.Anything, mock.Anything).Return(&model.CloudAccount{
+		Name:     "test",
+		Provider: clouds.DigitalOcean,
+	}, nil)
+
+	return &Handler{
+		svc:             svc,
+		accountService:  accService,
+		profileSvc:      profileSvc,
+		nodeProvisioner: provisioner,
+		repo:            memory.NewInMemoryRepository(),
+		getWriter: func(string) (io.WriteCloser, error) {
+			return &bufferCloser{}, nil
+		},
+	}
+}
+
+// poolService stores the kube in memory storage, so concurrent removals
+// of pool nodes get their own copies of the record.
+func poolService(t *testing.T, k *model.Kube) *Service {
+	svc := NewService(DefaultStoragePrefix, memory.NewInMemoryRepository(), nil)
+
+	if err := svc.Create(context.Background(), k); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	return svc
+}
+
+func TestCreateNodePool(t *testing.T) {
+	testCases := []struct {
+		description   string
+		kubeErr       error
+		pool          model.NodePool
+		expectedCode  int
+		expectedCount int
+	}{
+		{
+			description: "kube not found",
+			kubeErr:     sgerrors.ErrNotFound,
+			pool: model.NodePool{
+				NodePool: profile.NodePool{Name: "cpu", MachineType: "s-2vcpu-4gb"},
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description: "no machine type",
+			pool: model.NodePool{
+				NodePool: profile.NodePool{Name: "cpu"},
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description: "pool exists",
+			pool: model.NodePool{
+				NodePool: profile.NodePool{Name: "gpu", MachineType: "s-2vcpu-4gb"},
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			description: "pool of profile",
+			pool: model.NodePool{
+				NodePool: profile.NodePool{Name: "general", MachineType: "s-2vcpu-4gb"},
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description: "spot pool is not supported",
+			pool: model.NodePool{
+				NodePool: profile.NodePool{Name: "cpu", MachineType: "s-2vcpu-4gb"},
+				Spot:     true,
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description: "gpu spot pool",
+			pool: model.NodePool{
+				NodePool: profile.NodePool{Name: "cpu", MachineType: "s-2vcpu-4gb", GPU: true},
+				Spot:     true,
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description: "success",
+			pool: model.NodePool{
+				NodePool: profile.NodePool{
+					Name:        "cpu",
+					MachineType: "s-2vcpu-4gb",
+					Count:       2,
+					Labels:      map[string]string{"team": "ml"},
+				},
+			},
+			expectedCode:  http.StatusAccepted,
+			expectedCount: 2,
+		},
+	}
+
+	workflows.Init()
+
+	for _, testCase := range testCases {
+		k := poolKube()
+		poolTask := &workflows.Task{ID: "pooltask"}
+
+		provisioner := new(mockNodeProvisioner)
+		provisioner.On("ProvisionNodePool", mock.Anything, testCase.pool.NodePool,
+			testCase.expectedCount, k, mock.Anything).
+			Return(poolTask, []string{"node1", "node2"}, nil)
+
+		h := poolHandler(k, testCase.kubeErr, provisioner)
+
+		data, _ := json.Marshal(testCase.pool)
+		req, _ := http.NewRequest(http.MethodPost, "/kubes/test/pools", bytes.NewReader(data))
+		rec := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/kubes/{kubeID}/pools", h.createNodePool)
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("%s: expected code %d actual %d %s", testCase.description,
+				testCase.expectedCode, rec.Code, rec.Body.String())
+			continue
+		}
+
+		if rec.Code != http.StatusAccepted {
+			continue
+		}
+
+		provisioner.AssertExpectations(t)
+		pool := k.NodePools[testCase.pool.Name]
+
+		if pool == nil || pool.TaskID != poolTask.ID {
+			t.Errorf("%s: pool must be saved with its task %v", testCase.description, pool)
+		}
+
+		if len(k.Tasks[workflows.NodePoolTask]) != 1 || len(k.Tasks[workflows.NodeTask]) != 2 {
+			t.Errorf("%s: tasks must be saved to the kube %v", testCase.description, k.Tasks)
+		}
+	}
+}
+
+func TestResizeNodePool(t *testing.T) {
+	testCases := []struct {
+		description  string
+		count        int64
+		taskStatus   statuses.Status
+		expectedCode int
+		added        int
+		removed      []string
+	}{
+		{
+			description:  "negative count",
+			count:        -1,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "pool is being changed",
+			count:        5,
+			taskStatus:   statuses.Executing,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			description:  "grow",
+			count:        5,
+			taskStatus:   statuses.Success,
+			expectedCode: http.StatusAccepted,
+			added:        2,
+		},
+		{
+			description:  "shrink",
+			count:        1,
+			expectedCode: http.StatusAccepted,
+			removed:      []string{"gpu-3", "gpu-2"},
+		},
+		{
+			description:  "same size",
+			count:        3,
+			expectedCode: http.StatusAccepted,
+		},
+	}
+
+	workflows.Init()
+	workflows.RegisterWorkFlow(workflows.DeleteNode, []steps.Step{drainStep{}})
+
+	for _, testCase := range testCases {
+		k := poolKube()
+
+		if testCase.taskStatus != "" {
+			k.NodePools["gpu"].TaskID = "last"
+		}
+
+		provisioner := new(mockNodeProvisioner)
+		provisioner.On("ProvisionNodePool", mock.Anything, mock.Anything,
+			testCase.added, mock.Anything, mock.Anything).
+			Return(&workflows.Task{ID: "pooltask"}, []string{}, nil)
+
+		svc := poolService(t, k)
+		h := poolHandler(nil, nil, provisioner)
+		h.svc = svc
+
+		if testCase.taskStatus != "" {
+			data, _ := json.Marshal(&workflows.Task{ID: "last", Status: testCase.taskStatus})
+			h.repo.Put(context.Background(), workflows.Prefix, "last", data)
+		}
+
+		data, _ := json.Marshal(NodePoolResize{Count: testCase.count})
+		req, _ := http.NewRequest(http.MethodPatch, "/kubes/test/pools/gpu", bytes.NewReader(data))
+		rec := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/kubes/{kubeID}/pools/{poolName}", h.resizeNodePool)
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("%s: expected code %d actual %d %s", testCase.description,
+				testCase.expectedCode, rec.Code, rec.Body.String())
+			continue
+		}
+
+		if rec.Code != http.StatusAccepted {
+			continue
+		}
+
+		k, _ = svc.Get(context.Background(), k.ID)
+
+		if k.NodePools["gpu"].Count != testCase.count {
+			t.Errorf("%s: expected pool count %d actual %d", testCase.description,
+				testCase.count, k.NodePools["gpu"].Count)
+		}
+
+		if testCase.added > 0 {
+			provisioner.AssertExpectations(t)
+		}
+
+		if len(testCase.removed) == 0 {
+			continue
+		}
+
+		if err := waitPoolTask(h, k.NodePools["gpu"].TaskID); err != nil {
+			t.Errorf("%s: %v", testCase.description, err)
+			continue
+		}
+
+		k, _ = svc.Get(context.Background(), k.ID)
+
+		for _, name := range testCase.removed {
+			if _, ok := k.Nodes[name]; ok {
+				t.Errorf("%s: node %s must be removed", testCase.description, name)
+			}
+		}
+
+		if len(k.Nodes) != 2 {
+			t.Errorf("%s: expected 2 nodes left actual %v", testCase.description, k.Nodes)
+		}
+	}
+}
+
+func TestDeleteNodePool(t *testing.T) {
+	workflows.Init()
+	workflows.RegisterWorkFlow(workflows.DeleteNode, []steps.Step{drainStep{}})
+
+	svc := poolService(t, poolKube())
+	h := poolHandler(nil, nil, new(mockNodeProvisioner))
+	h.svc = svc
+
+	req, _ := http.NewRequest(http.MethodDelete, "/kubes/test/pools/gpu", nil)
+	rec := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/kubes/{kubeID}/pools/{poolName}", h.deleteNodePool)
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected code %d actual %d %s", http.StatusAccepted, rec.Code, rec.Body.String())
+	}
+
+	resp := nodePoolResponse{}
+
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(resp.TaskIDs) != 3 {
+		t.Errorf("Expected 3 delete tasks actual %v", resp.TaskIDs)
+	}
+
+	if err := waitPoolTask(h, resp.TaskID); err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := svc.Get(context.Background(), "test")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if _, ok := k.NodePools["gpu"]; ok {
+		t.Errorf("Pool must be removed from the kube")
+	}
+
+	if len(k.Nodes) != 1 || k.Nodes["other"] == nil {
+		t.Errorf("Only nodes of the pool must be removed %v", k.Nodes)
+	}
+}
+
+func TestNodesToRemove(t *testing.T) {
+	nodes := poolNodes(poolKube(), "gpu")
+
+	if len(nodes) != 3 {
+		t.Fatalf("Expected 3 pool nodes actual %d", len(nodes))
+	}
+
+	removed := nodesToRemove(nodes, 2)
+
+	if len(removed) != 2 || removed[0].Name != "gpu-3" || removed[1].Name != "gpu-2" {
+		t.Errorf("Failed and newest nodes must be removed first %v", removed)
+	}
+
+	if removed = nodesToRemove(nodes, 5); len(removed) != 3 {
+		t.Errorf("Expected all nodes to be removed %v", removed)
+	}
+}
+
+// waitPoolTask waits until the pool task has finished.
+func waitPoolTask(h *Handler, taskID string) error {
+	deadline := time.Now().Add(time.Second * 5)
+
+	for time.Now().Before(deadline) {
+		data, _ := h.repo.Get(context.Background(), workflows.Prefix, taskID)
+		task := &workflows.Task{}
+
+		if json.Unmarshal(data, task) == nil && task.Status == statuses.Success {
+			return nil
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	return fmt.Errorf("pool task %s has not finished", taskID)
+}
@@ -0,0 +1,241 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/message"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// portableSettings are cloud specific settings that don't refer to cloud
+// resources, so they apply to a kube in any region.
+var portableSettings = map[string]bool{
+	clouds.AwsVpcCIDR:                 true,
+	clouds.AwsVolumeSize:              true,
+	clouds.AwsVolumeType:              true,
+	clouds.AwsVolumeIops:              true,
+	clouds.AwsDeleteOnTermination:     true,
+	clouds.AwsEbsEncrypted:            true,
+	clouds.AwsHttpTokens:              true,
+	clouds.AwsHttpPutResponseHopLimit: true,
+	clouds.AzureVolumeSize:            true,
+	clouds.AzureVNetCIDR:              true,
+	clouds.GCEImageFamily:             true,
+}
+
+func (h *Handler) exportProfile(w http.ResponseWriter, r *http.Request) {
+	kubeID := mux.Vars(r)["kubeID"]
+	k, err := h.svc.Get(r.Context(), kubeID)
+
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	// Imported kubes have no profile
+	stored, err := h.profileSvc.Get(r.Context(), k.ProfileID)
+
+	if err != nil && !sgerrors.IsNotFound(err) {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(ExportProfile(k, stored)); err != nil {
+		logrus.Errorf("encode profile export of kube %s %v", kubeID, err)
+	}
+}
+
+// ExportProfile reconstructs a creatable profile from the kube and the profile
+// it has been provisioned with, stored profile may be nil. Masters, nodes and
+// versions are taken from the kube as they may have changed since provisioning.
+// Region, secrets and identifiers of user provided cloud resources are
+// removed from the profile.
+func ExportProfile(k *model.Kube, stored *profile.Profile) profile.Export {
+	e := profile.Export{
+		SourceKubeID: k.ID,
+		Unresolved:   make([]string, 0),
+	}
+
+	if stored == nil {
+		stored = &profile.Profile{}
+	}
+
+	p := profile.Profile{
+		Provider:           k.Provider,
+		Arch:               k.Arch,
+		OperatingSystem:    k.OperatingSystem,
+		UbuntuVersion:      stored.UbuntuVersion,
+		DockerVersion:      k.DockerVersion,
+		K8SVersion:         k.K8SVersion,
+		K8SServicesCIDR:    k.ServicesCIDR,
+		K8SAPIPort:         k.APIServerPort,
+		NetworkProvider:    k.Networking.Provider,
+		FlannelVersion:     stored.FlannelVersion,
+		NetworkType:        k.Networking.Type,
+		CIDR:               k.Networking.CIDR,
+		HelmVersion:        k.HelmVersion,
+		RBACEnabled:        k.RBACEnabled,
+		PublicKey:          k.SSHConfig.PublicKey,
+		ExposedAddresses:   k.ExposedAddresses,
+		Addons:             k.Addons,
+		Tags:               stored.Tags,
+		StepTimeouts:       stored.StepTimeouts,
+		MasterHooks:        stored.MasterHooks,
+		NodeHooks:          stored.NodeHooks,
+		Etcd:               stored.Etcd,
+		PrivateNetworking:  k.PrivateNetworking,
+		KubeletExtraArgs:   k.KubeletExtraArgs,
+		APIServerExtraArgs: k.APIServerExtraArgs,
+		ClusterAutoscaler:  k.ClusterAutoscaler,
+	}
+
+	// Tokens and passwords of the kube are not exported
+	if len(stored.StaticAuth.BasicAuth) > 0 || len(stored.StaticAuth.Tokens) > 0 {
+		e.Unresolved = append(e.Unresolved, "staticAuth")
+	}
+
+	if stored.BastionHost != "" {
+		e.Unresolved = append(e.Unresolved, "bastionHost")
+	}
+
+	if len(stored.Subnets) > 0 {
+		e.Unresolved = append(e.Unresolved, "subnets")
+	}
+
+	if stored.ExternalLoadBalancer != nil {
+		e.Unresolved = append(e.Unresolved, "externalLoadBalancer")
+	}
+
+	p.CloudSpecificSettings, e.Unresolved = exportCloudSettings(k, stored, e.Unresolved)
+	p.MasterProfiles, e.Unresolved = exportNodeProfiles(k, k.Masters, "masterProfiles",
+		stored.MasterProfiles, e.Unresolved)
+	p.NodesProfiles, e.Unresolved = exportNodeProfiles(k, k.Nodes, "nodesProfiles",
+		stored.NodesProfiles, e.Unresolved)
+	p.NodePools, e.Unresolved = exportNodePools(k, e.Unresolved)
+
+	e.Profile = p
+
+	return e
+}
+
+// exportCloudSettings keeps portable settings of the kube, settings that
+// refer to resources provided by user are unresolved.
+func exportCloudSettings(k *model.Kube, stored *profile.Profile,
+	unresolved []string) (profile.CloudSpecificSettings, []string) {
+	settings := make(profile.CloudSpecificSettings)
+	source := stored.CloudSpecificSettings
+
+	if source == nil {
+		source = k.CloudSpec
+	}
+
+	for _, key := range sortedKeys(source) {
+		value := source[key]
+
+		switch {
+		case portableSettings[key]:
+			settings[key] = value
+		case stored.CloudSpecificSettings != nil || k.IsUserResource(value):
+			unresolved = append(unresolved, "cloudSpecificSettings."+key)
+		}
+	}
+
+	return settings, unresolved
+}
+
+// exportNodeProfiles returns profile per machine of the kube that is not
+// a node of a pool or a spot group, the first stored profile provides
+// settings machines don't have. AMIs are region specific.
+func exportNodeProfiles(k *model.Kube, machines map[string]*model.Machine, field string,
+	stored []profile.NodeProfile, unresolved []string) ([]profile.NodeProfile, []string) {
+	base := profile.NodeProfile{}
+
+	if len(stored) > 0 {
+		base = stored[0]
+	}
+
+	key := profile.MachineTypeKey(k.Provider)
+	nodeProfiles := make([]profile.NodeProfile, 0, len(machines))
+
+	for _, name := range sortedMachineNames(machines) {
+		machine := machines[name]
+
+		if machine.Pool != "" || machine.SpotGroupID != "" {
+			continue
+		}
+
+		nodeProfile := make(profile.NodeProfile, len(base))
+
+		for setting, value := range base {
+			nodeProfile[setting] = value
+		}
+
+		if machine.Size != "" {
+			nodeProfile[key] = machine.Size
+		}
+
+		if k.Provider == clouds.AWS && nodeProfile[profile.ImageKey] != "" {
+			delete(nodeProfile, profile.ImageKey)
+			unresolved = append(unresolved, fmt.Sprintf("%s[%d].%s",
+				field, len(nodeProfiles), profile.ImageKey))
+		}
+
+		nodeProfiles = append(nodeProfiles, nodeProfile)
+	}
+
+	return nodeProfiles, unresolved
+}
+
+// exportNodePools returns pools of the kube with their desired size,
+// spot pools can't be provisioned with the profile.
+func exportNodePools(k *model.Kube, unresolved []string) ([]profile.NodePool, []string) {
+	names := make([]string, 0, len(k.NodePools))
+
+	for name, pool := range k.NodePools {
+		if !pool.Spot {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	pools := make([]profile.NodePool, 0, len(names))
+
+	for _, name := range names {
+		pool := k.NodePools[name].NodePool
+
+		if k.Provider == clouds.AWS && pool.Image != "" {
+			pool.Image = ""
+			unresolved = append(unresolved, fmt.Sprintf("nodePools[%d].image", len(pools)))
+		}
+
+		pools = append(pools, pool)
+	}
+
+	return pools, unresolved
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,207 @@
+package kube
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+func exportKube() *model.Kube {
+	return &model.Kube{
+		ID:           "test",
+		Provider:     clouds.AWS,
+		Region:       "us-west-1",
+		K8SVersion:   "1.15.1",
+		ServicesCIDR: "10.3.0.0/16",
+		Networking: model.Networking{
+			Provider: profile.Calico,
+			CIDR:     "10.0.0.0/16",
+		},
+		CloudSpec: profile.CloudSpecificSettings{
+			clouds.AwsVpcID:      "vpc-created",
+			clouds.AwsVolumeSize: "100",
+		},
+		Masters: map[string]*model.Machine{
+			"master-1": {Name: "master-1", Size: "m5.large"},
+		},
+		Nodes: map[string]*model.Machine{
+			"node-1": {Name: "node-1", Size: "m5.xlarge"},
+			"node-2": {Name: "node-2", Size: "m5.large"},
+			"gpu-1":  {Name: "gpu-1", Size: "p3.2xlarge", Pool: "gpu"},
+		},
+		NodePools: map[string]*model.NodePool{
+			"gpu": {
+				NodePool: profile.NodePool{
+					Name:        "gpu",
+					MachineType: "p3.2xlarge",
+					Image:       "ami-gpu",
+					Count:       1,
+					GPU:         true,
+				},
+			},
+			"spot": {
+				NodePool: profile.NodePool{Name: "spot", MachineType: "m5.large"},
+				Spot:     true,
+			},
+		},
+	}
+}
+
+func TestExportProfile(t *testing.T) {
+	stored := &profile.Profile{
+		ID:     "profile",
+		Region: "us-west-1",
+		MasterProfiles: []profile.NodeProfile{
+			{"size": "t2.medium", "image": "ami-1", "volumeSize": "50"},
+		},
+		NodesProfiles: []profile.NodeProfile{
+			{"size": "t2.medium", "image": "ami-1"},
+		},
+		Subnets: map[string]string{"us-west-1a": "subnet-1"},
+		CloudSpecificSettings: profile.CloudSpecificSettings{
+			clouds.AwsVpcID:      "vpc-user",
+			clouds.AwsVpcCIDR:    "172.16.0.0/16",
+			clouds.AwsVolumeSize: "100",
+		},
+		StaticAuth: profile.StaticAuth{
+			Tokens: []profile.TokenAuthUser{{Token: "secret"}},
+		},
+		Tags: map[string]string{"team": "data"},
+	}
+
+	e := ExportProfile(exportKube(), stored)
+	p := e.Profile
+
+	if p.ID != "" || p.Region != "" || len(p.Subnets) > 0 || len(p.StaticAuth.Tokens) > 0 {
+		t.Errorf("Identifiers of the kube must not be exported %v", p)
+	}
+
+	expectedMasters := []profile.NodeProfile{
+		{"size": "m5.large", "volumeSize": "50"},
+	}
+
+	if !reflect.DeepEqual(p.MasterProfiles, expectedMasters) {
+		t.Errorf("Wrong masters expected %v actual %v", expectedMasters, p.MasterProfiles)
+	}
+
+	expectedNodes := []profile.NodeProfile{
+		{"size": "m5.xlarge"},
+		{"size": "m5.large"},
+	}
+
+	if !reflect.DeepEqual(p.NodesProfiles, expectedNodes) {
+		t.Errorf("Wrong nodes expected %v actual %v", expectedNodes, p.NodesProfiles)
+	}
+
+	if len(p.NodePools) != 1 || p.NodePools[0].Name != "gpu" || p.NodePools[0].Image != "" {
+		t.Errorf("Wrong node pools %v", p.NodePools)
+	}
+
+	expectedSettings := profile.CloudSpecificSettings{
+		clouds.AwsVpcCIDR:    "172.16.0.0/16",
+		clouds.AwsVolumeSize: "100",
+	}
+
+	if !reflect.DeepEqual(p.CloudSpecificSettings, expectedSettings) {
+		t.Errorf("Wrong cloud settings expected %v actual %v", expectedSettings, p.CloudSpecificSettings)
+	}
+
+	if p.K8SVersion != "1.15.1" || p.CIDR != "10.0.0.0/16" || p.Tags["team"] != "data" {
+		t.Errorf("Wrong profile %v", p)
+	}
+
+	expectedUnresolved := []string{
+		"staticAuth",
+		"subnets",
+		"cloudSpecificSettings." + clouds.AwsVpcID,
+		"masterProfiles[0].image",
+		"nodesProfiles[0].image",
+		"nodesProfiles[1].image",
+		"nodePools[0].image",
+	}
+
+	if !reflect.DeepEqual(e.Unresolved, expectedUnresolved) {
+		t.Errorf("Wrong unresolved expected %v actual %v", expectedUnresolved, e.Unresolved)
+	}
+}
+
+func TestExportProfileHandler(t *testing.T) {
+	testCases := []struct {
+		description  string
+		kubeErr      error
+		profileErr   error
+		expectedCode int
+	}{
+		{
+			description:  "kube not found",
+			kubeErr:      sgerrors.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "profile of imported kube",
+			profileErr:   sgerrors.ErrNotFound,
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:  "success",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		svc := new(kubeServiceMock)
+		svc.On(serviceGet, mock.Anything, mock.Anything).Return(exportKube(), testCase.kubeErr)
+
+		var stored *profile.Profile
+
+		if testCase.profileErr == nil {
+			stored = &profile.Profile{ID: "profile"}
+		}
+
+		profileSvc := new(mockProfileService)
+		profileSvc.On("Get", mock.Anything, mock.Anything).Return(stored, testCase.profileErr)
+
+		h := &Handler{
+			svc:        svc,
+			profileSvc: profileSvc,
+		}
+
+		req, _ := http.NewRequest(http.MethodGet, "/kubes/test/profile-export", nil)
+		rec := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/kubes/{kubeID}/profile-export", h.exportProfile)
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("Expected code %d actual %d", testCase.expectedCode, rec.Code)
+			continue
+		}
+
+		if testCase.expectedCode != http.StatusOK {
+			continue
+		}
+
+		e := profile.Export{}
+
+		if err := json.NewDecoder(rec.Body).Decode(&e); err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if e.SourceKubeID != "test" || len(e.Profile.NodesProfiles) != 2 {
+			t.Errorf("Wrong export %v", e)
+		}
+	}
+}
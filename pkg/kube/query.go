@@ -0,0 +1,144 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+
+	"github.com/supergiant/control/pkg/kubeconfig"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+const (
+	// QueryTimeout limits evaluation of PromQL queries by prometheus
+	// and the request to the proxy.
+	QueryTimeout = 30 * time.Second
+	// MaxQueryRange is the longest range of range queries.
+	MaxQueryRange = 7 * 24 * time.Hour
+	// MaxQueryPoints is the limit of points per series prometheus accepts.
+	MaxQueryPoints = 11000
+
+	// defaultQueryPoints is used to derive step of range queries without one.
+	defaultQueryPoints = 250
+
+	prometheusProxyURL = "api/v1/namespaces/kube-system/services/prometheus-operated:9090/proxy"
+)
+
+// MetricsQuery is a PromQL query, range query is made when start
+// and end are set, instant query otherwise.
+type MetricsQuery struct {
+	Query string     `json:"query"`
+	Start *time.Time `json:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty"`
+	// Step of range query in duration format, e.g. 30s, 5m
+	Step string `json:"step,omitempty"`
+}
+
+// uri returns uri of the query for prometheus proxy of the kube.
+func (q MetricsQuery) uri() (string, error) {
+	if q.Query == "" {
+		return "", errors.Wrap(sgerrors.ErrValidationFailed, "query is required")
+	}
+
+	params := url.Values{}
+	params.Set("query", q.Query)
+	params.Set("timeout", QueryTimeout.String())
+
+	if q.Start == nil && q.End == nil {
+		if q.Step != "" {
+			return "", errors.Wrap(sgerrors.ErrValidationFailed, "step requires start and end")
+		}
+
+		return fmt.Sprintf("/%s/api/v1/query?%s", prometheusProxyURL, params.Encode()), nil
+	}
+
+	if q.Start == nil || q.End == nil {
+		return "", errors.Wrap(sgerrors.ErrValidationFailed, "range query requires both start and end")
+	}
+
+	queryRange := q.End.Sub(*q.Start)
+
+	if queryRange <= 0 {
+		return "", errors.Wrap(sgerrors.ErrValidationFailed, "end must be after start")
+	}
+
+	if queryRange > MaxQueryRange {
+		return "", errors.Wrapf(sgerrors.ErrValidationFailed,
+			"range %s exceeds maximum %s", queryRange, MaxQueryRange)
+	}
+
+	step := (queryRange / defaultQueryPoints).Truncate(time.Second)
+
+	if q.Step != "" {
+		var err error
+
+		if step, err = time.ParseDuration(q.Step); err != nil {
+			return "", errors.Wrapf(sgerrors.ErrValidationFailed, "step %s: %v", q.Step, err)
+		}
+	}
+
+	if step < time.Second {
+		step = time.Second
+	}
+
+	if queryRange/step > MaxQueryPoints {
+		return "", errors.Wrapf(sgerrors.ErrValidationFailed,
+			"step %s is too small for range %s, maximum %d points",
+			step, queryRange, MaxQueryPoints)
+	}
+
+	params.Set("start", strconv.FormatInt(q.Start.Unix(), 10))
+	params.Set("end", strconv.FormatInt(q.End.Unix(), 10))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+
+	return fmt.Sprintf("/%s/api/v1/query_range?%s", prometheusProxyURL, params.Encode()), nil
+}
+
+// queryMetrics makes the query through prometheus service proxy of the kube.
+func queryMetrics(ctx context.Context, uri string, k *model.Kube) ([]byte, error) {
+	cfg, err := kubeconfig.NewConfigFor(k)
+	if err != nil {
+		return nil, errors.Wrap(err, "build kubernetes rest config")
+	}
+	kclient, err := rest.UnversionedRESTClientFor(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "build kubernetes client")
+	}
+
+	raw, err := kclient.Get().Context(ctx).RequestURI(uri).Do().Raw()
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	return raw, nil
+}
+
+// queryError tells missing prometheus and bad queries from other
+// errors of the proxy.
+func queryError(ctx context.Context, err error) error {
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return errors.Wrap(sgerrors.ErrTimeoutExceeded, err.Error())
+	case apierrors.IsNotFound(err):
+		return errors.Wrap(sgerrors.ErrPrometheusNotFound, err.Error())
+	case apierrors.IsServiceUnavailable(err):
+		// Proxy responds so when service has no pods, prometheus
+		// when evaluation of the query times out
+		if strings.Contains(err.Error(), "no endpoints available") {
+			return errors.Wrap(sgerrors.ErrPrometheusNotFound, err.Error())
+		}
+		return errors.Wrap(sgerrors.ErrTimeoutExceeded, err.Error())
+	case apierrors.IsBadRequest(err) || apierrors.IsInvalid(err):
+		return errors.Wrap(sgerrors.ErrValidationFailed, err.Error())
+	default:
+		return errors.Wrap(err, "query metrics")
+	}
+}
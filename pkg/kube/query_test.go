@@ -0,0 +1,164 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+func TestMetricsQueryURI(t *testing.T) {
+	start := time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC)
+	hourLater := start.Add(time.Hour)
+	fiveHoursLater := start.Add(5 * time.Hour)
+	weekLater := start.Add(8 * 24 * time.Hour)
+
+	testCases := []struct {
+		query        MetricsQuery
+		expectedPath string
+		expectedStep string
+		isErr        bool
+	}{
+		{
+			query:        MetricsQuery{Query: "up"},
+			expectedPath: "/api/v1/query",
+		},
+		{
+			query:        MetricsQuery{Query: "up", Start: &start, End: &hourLater, Step: "30s"},
+			expectedPath: "/api/v1/query_range",
+			expectedStep: "30",
+		},
+		{
+			// Step is derived from the range
+			query:        MetricsQuery{Query: "up", Start: &start, End: &hourLater},
+			expectedPath: "/api/v1/query_range",
+			expectedStep: "14",
+		},
+		{
+			query: MetricsQuery{},
+			isErr: true,
+		},
+		{
+			query: MetricsQuery{Query: "up", Step: "30s"},
+			isErr: true,
+		},
+		{
+			query: MetricsQuery{Query: "up", Start: &start},
+			isErr: true,
+		},
+		{
+			query: MetricsQuery{Query: "up", Start: &hourLater, End: &start},
+			isErr: true,
+		},
+		{
+			query: MetricsQuery{Query: "up", Start: &start, End: &weekLater},
+			isErr: true,
+		},
+		{
+			// Step is rounded up to a second
+			query:        MetricsQuery{Query: "up", Start: &start, End: &hourLater, Step: "100ms"},
+			expectedPath: "/api/v1/query_range",
+			expectedStep: "1",
+		},
+		{
+			query: MetricsQuery{Query: "up", Start: &start, End: &fiveHoursLater, Step: "1s"},
+			isErr: true,
+		},
+		{
+			query: MetricsQuery{Query: "up", Start: &start, End: &hourLater, Step: "minute"},
+			isErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		uri, err := testCase.query.uri()
+
+		if testCase.isErr {
+			if !sgerrors.IsValidationFailed(err) {
+				t.Errorf("Expected validation error for %+v actual %v", testCase.query, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		u, err := url.Parse(uri)
+
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if !strings.HasPrefix(u.Path, "/"+prometheusProxyURL) ||
+			!strings.HasSuffix(u.Path, testCase.expectedPath) {
+			t.Errorf("Wrong path %s", u.Path)
+		}
+
+		params := u.Query()
+
+		if params.Get("query") != testCase.query.Query || params.Get("timeout") != QueryTimeout.String() {
+			t.Errorf("Wrong params %v", params)
+		}
+
+		if params.Get("step") != testCase.expectedStep {
+			t.Errorf("Wrong step expected %s actual %s", testCase.expectedStep, params.Get("step"))
+		}
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	gr := schema.GroupResource{Resource: "services"}
+
+	testCases := []struct {
+		err   error
+		check func(error) bool
+	}{
+		{
+			err:   apierrors.NewNotFound(gr, "prometheus-operated"),
+			check: sgerrors.IsPrometheusNotFound,
+		},
+		{
+			err: apierrors.NewServiceUnavailable(
+				`no endpoints available for service "prometheus-operated:9090"`),
+			check: sgerrors.IsPrometheusNotFound,
+		},
+		{
+			err:   apierrors.NewServiceUnavailable("query timed out in expression evaluation"),
+			check: sgerrors.IsTimeoutExceeded,
+		},
+		{
+			err:   apierrors.NewBadRequest("parse error at char 4"),
+			check: sgerrors.IsValidationFailed,
+		},
+		{
+			err: errors.New("connection refused"),
+			check: func(err error) bool {
+				return err != nil && !sgerrors.IsPrometheusNotFound(err)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		if err := queryError(context.Background(), testCase.err); !testCase.check(err) {
+			t.Errorf("Wrong error for %v: %v", testCase.err, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-ctx.Done()
+
+	if err := queryError(ctx, errors.New("context deadline exceeded")); !sgerrors.IsTimeoutExceeded(err) {
+		t.Errorf("Expected timeout error actual %v", err)
+	}
+}
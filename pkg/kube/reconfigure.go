@@ -0,0 +1,206 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/message"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows"
+	"github.com/supergiant/control/pkg/workflows/statuses"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+// ReconfigureRequest replaces extra flags of kubelets of the kube.
+type ReconfigureRequest struct {
+	KubeletExtraArgs map[string]string `json:"kubeletExtraArgs"`
+}
+
+type reconfigureResponse struct {
+	// TaskID of the task tracking reconfiguration of machines
+	TaskID string `json:"taskId"`
+	// TaskIDs of machine tasks in order they are run
+	TaskIDs []string `json:"taskIds"`
+}
+
+type machineReconfiguration struct {
+	machine string
+	task    *workflows.Task
+	config  *steps.Config
+	out     io.WriteCloser
+}
+
+// reconfigureNodes rewrites kubelet flags of active machines and restarts
+// kubelet one machine at a time, masters go first.
+func (h *Handler) reconfigureNodes(w http.ResponseWriter, r *http.Request) {
+	kubeID := mux.Vars(r)["kubeID"]
+	k, err := h.svc.Get(r.Context(), kubeID)
+
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	req := &ReconfigureRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		message.SendInvalidJSON(w, err)
+		return
+	}
+
+	if err := validateReconfigure(k, req); err != nil {
+		message.SendValidationFailed(w, err)
+		return
+	}
+
+	taskID, taskIDs, err := h.reconfigureMachines(r.Context(), k, req.KubeletExtraArgs)
+
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+
+	if err := json.NewEncoder(w).Encode(reconfigureResponse{
+		TaskID:  taskID,
+		TaskIDs: taskIDs,
+	}); err != nil {
+		logrus.Errorf("encode reconfigure response %v", err)
+	}
+}
+
+func validateReconfigure(k *model.Kube, req *ReconfigureRequest) error {
+	if k.State != model.StateOperational {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"kube %s is %s", k.ID, k.State)
+	}
+
+	// Machines of imported kubes are not reachable over ssh
+	if len(k.Tasks[workflows.ImportTask]) > 0 {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"kube %s has been imported", k.ID)
+	}
+
+	return profile.ValidateExtraArgs(req.KubeletExtraArgs, nil)
+}
+
+// reconfigureMachines saves new kubelet flags of the kube and starts
+// the task that applies them to active machines.
+func (h *Handler) reconfigureMachines(ctx context.Context, k *model.Kube,
+	kubeletArgs map[string]string) (string, []string, error) {
+	k.KubeletExtraArgs = kubeletArgs
+
+	parentTask, err := workflows.NewTask(&steps.Config{
+		Kube:             *k,
+		Provider:         k.Provider,
+		CloudAccountName: k.AccountName,
+	}, workflows.ReconfigureNodes, h.repo)
+
+	if err != nil {
+		return "", nil, errors.Wrap(err, "new reconfigure task")
+	}
+
+	reconfigurations := make([]machineReconfiguration, 0, len(k.Masters)+len(k.Nodes))
+	taskIDs := make([]string, 0, len(k.Masters)+len(k.Nodes))
+
+	for _, machines := range []map[string]*model.Machine{k.Masters, k.Nodes} {
+		for _, name := range sortedMachineNames(machines) {
+			machine := machines[name]
+
+			if machine.State != model.MachineStateActive {
+				continue
+			}
+
+			config := &steps.Config{
+				Kube:             *k,
+				Provider:         k.Provider,
+				CloudAccountName: k.AccountName,
+				Node:             *machine,
+				IsMaster:         machine.Role == model.RoleMaster,
+			}
+
+			t, err := workflows.NewTask(config, workflows.ReconfigureNode, h.repo)
+
+			if err != nil {
+				return "", nil, errors.Wrapf(err, "new task of machine %s", name)
+			}
+
+			writer, err := h.getWriter(util.MakeFileName(t.ID))
+
+			if err != nil {
+				return "", nil, errors.Wrap(err, "get writer")
+			}
+
+			reconfigurations = append(reconfigurations, machineReconfiguration{
+				machine: name,
+				task:    t,
+				config:  config,
+				out:     writer,
+			})
+			taskIDs = append(taskIDs, t.ID)
+		}
+	}
+
+	_, err = retryUpdate(ctx, h.svc, k.ID, func(k *model.Kube) error {
+		k.KubeletExtraArgs = kubeletArgs
+		appendTasks(k, workflows.ReconfigureTask, parentTask.ID)
+		return nil
+	})
+
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "update kube %s", k.ID)
+	}
+
+	go h.runReconfiguration(parentTask, reconfigurations)
+
+	return parentTask.ID, taskIDs, nil
+}
+
+// runReconfiguration runs machine tasks one by one so that kubelets of the
+// kube are not restarted at once, it stops at the first failed machine.
+func (h *Handler) runReconfiguration(parentTask *workflows.Task, reconfigurations []machineReconfiguration) {
+	if err := parentTask.SetStatus(context.Background(), statuses.Executing); err != nil {
+		logrus.Errorf("save reconfigure task %s %v", parentTask.ID, err)
+	}
+
+	status := statuses.Success
+
+	for i, reconfiguration := range reconfigurations {
+		err := <-reconfiguration.task.Run(context.Background(), reconfiguration.config, reconfiguration.out)
+
+		if err := parentTask.SetSubtaskStatus(context.Background(), reconfiguration.task.ID,
+			reconfiguration.task.Status); err != nil {
+			logrus.Errorf("save reconfigure task %s %v", parentTask.ID, err)
+		}
+
+		if err != nil {
+			logrus.Errorf("reconfigure machine %s caused %v", reconfiguration.machine, err)
+			status = statuses.Error
+
+			for _, skipped := range reconfigurations[i+1:] {
+				skipped.out.Close()
+			}
+
+			break
+		}
+	}
+
+	if err := parentTask.SetStatus(context.Background(), status); err != nil {
+		logrus.Errorf("save reconfigure task %s %v", parentTask.ID, err)
+	}
+}
@@ -0,0 +1,138 @@
+package kube
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+func reconfigureKube() *model.Kube {
+	k := poolKube()
+	k.State = model.StateOperational
+
+	for _, machines := range []map[string]*model.Machine{k.Masters, k.Nodes} {
+		for _, m := range machines {
+			if m.State == "" {
+				m.State = model.MachineStateActive
+			}
+		}
+	}
+
+	k.Masters["master"].Role = model.RoleMaster
+
+	return k
+}
+
+func TestReconfigureNodes(t *testing.T) {
+	workflows.Init()
+	workflows.RegisterWorkFlow(workflows.ReconfigureNode, []steps.Step{drainStep{}})
+
+	testCases := []struct {
+		description  string
+		kubeErr      error
+		state        model.KubeState
+		imported     bool
+		body         string
+		expectedCode int
+	}{
+		{
+			description:  "kube not found",
+			kubeErr:      sgerrors.ErrNotFound,
+			body:         `{}`,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "invalid json",
+			body:         `{`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "kube is provisioning",
+			state:        model.StateProvisioning,
+			body:         `{"kubeletExtraArgs":{"max-pods":"200"}}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "imported kube",
+			imported:     true,
+			body:         `{"kubeletExtraArgs":{"max-pods":"200"}}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "managed flag",
+			body:         `{"kubeletExtraArgs":{"cloud-provider":"aws"}}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "success",
+			body:         `{"kubeletExtraArgs":{"max-pods":"200"}}`,
+			expectedCode: http.StatusAccepted,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		k := reconfigureKube()
+
+		if testCase.state != "" {
+			k.State = testCase.state
+		}
+
+		if testCase.imported {
+			k.Tasks[workflows.ImportTask] = []string{"import"}
+		}
+
+		h := poolHandler(k, testCase.kubeErr, new(mockNodeProvisioner))
+
+		req, _ := http.NewRequest(http.MethodPost, "/kubes/test/reconfigure",
+			bytes.NewBufferString(testCase.body))
+		rec := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/kubes/{kubeID}/reconfigure", h.reconfigureNodes)
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("Expected code %d actual %d %s", testCase.expectedCode,
+				rec.Code, rec.Body.String())
+			continue
+		}
+
+		if testCase.expectedCode != http.StatusAccepted {
+			continue
+		}
+
+		resp := reconfigureResponse{}
+
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		// Machine in error state is skipped
+		if len(resp.TaskIDs) != 4 {
+			t.Errorf("Expected 4 machine tasks actual %v", resp.TaskIDs)
+		}
+
+		if err := waitPoolTask(h, resp.TaskID); err != nil {
+			t.Error(err)
+		}
+
+		if k.KubeletExtraArgs["max-pods"] != "200" {
+			t.Errorf("Kubelet args must be saved %v", k.KubeletExtraArgs)
+		}
+
+		if len(k.Tasks[workflows.ReconfigureTask]) != 1 {
+			t.Errorf("Reconfigure task must be saved %v", k.Tasks)
+		}
+	}
+}
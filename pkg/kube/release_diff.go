@@ -0,0 +1,340 @@
+package kube
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/helm"
+	"k8s.io/helm/pkg/releaseutil"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+	changeChanged = "changed"
+
+	// Lines of a resource diff returned before it is truncated
+	maxResourceDiffLines = 200
+	// Lines of context around changes in resource diffs
+	resourceDiffContext = 3
+)
+
+// Values and fields of objects which names match are masked in diffs.
+var secretKeyPattern = regexp.MustCompile(`(?i)(password|passwd|secret|token|credential|private.?key|api.?key)`)
+
+// ReleaseDiff is the difference between the deployed release and its upgrade.
+type ReleaseDiff struct {
+	Values    []ValueChange  `json:"values"`
+	Resources []ResourceDiff `json:"resources"`
+}
+
+// ValueChange is a change of the user supplied value at the path,
+// secret values are masked.
+type ValueChange struct {
+	Path   string      `json:"path"`
+	Change string      `json:"change"`
+	Old    interface{} `json:"old,omitempty"`
+	New    interface{} `json:"new,omitempty"`
+}
+
+// ResourceDiff is a unified diff of a rendered object of the release,
+// data of secrets is masked.
+type ResourceDiff struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Change    string `json:"change"`
+	Diff      string `json:"diff"`
+	Truncated bool   `json:"truncated"`
+}
+
+type manifestHead struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+type renderedResource struct {
+	kind      string
+	namespace string
+	name      string
+	content   string
+}
+
+// DiffRelease compares the deployed release with the upgrade to the chart
+// and values of the input, the upgrade is rendered with a dry run.
+func (s Service) DiffRelease(ctx context.Context, kubeID, rlsName string, rls *ReleaseInput) (*ReleaseDiff, error) {
+	if rls == nil {
+		return nil, errors.Wrap(sgerrors.ErrNilEntity, "release input")
+	}
+
+	chrt, err := s.chrtGetter.GetChart(ctx, rls.RepoName, rls.ChartName, rls.ChartVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "get chart")
+	}
+
+	proposed, err := releaseValues(chrt, rls)
+	if err != nil {
+		return nil, err
+	}
+
+	kprx, err := s.kubeHelmClient(ctx, kubeID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := kprx.ReleaseContent(rlsName)
+	if err != nil {
+		return nil, errors.Wrap(err, "get release details")
+	}
+
+	upgrade, err := kprx.UpdateReleaseFromChart(
+		rlsName,
+		chrt,
+		helm.UpdateValueOverrides([]byte(proposed)),
+		helm.UpgradeDryRun(true),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "render upgrade")
+	}
+
+	values, err := diffValues(current.GetRelease().GetConfig().GetRaw(), proposed)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := diffManifests(current.GetRelease().GetManifest(),
+		upgrade.GetRelease().GetManifest())
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReleaseDiff{
+		Values:    values,
+		Resources: resources,
+	}, nil
+}
+
+// diffValues compares values by their paths, lists are compared as a whole.
+func diffValues(deployed, proposed string) ([]ValueChange, error) {
+	oldValues, err := chartutil.ReadValues([]byte(deployed))
+	if err != nil {
+		return nil, errors.Wrap(err, "read deployed values")
+	}
+
+	newValues, err := chartutil.ReadValues([]byte(proposed))
+	if err != nil {
+		return nil, errors.Wrap(err, "read proposed values")
+	}
+
+	oldPaths, newPaths := map[string]interface{}{}, map[string]interface{}{}
+	flattenValues("", oldValues.AsMap(), oldPaths)
+	flattenValues("", newValues.AsMap(), newPaths)
+
+	changes := make([]ValueChange, 0)
+
+	for path, old := range oldPaths {
+		value, ok := newPaths[path]
+
+		switch {
+		case !ok:
+			changes = append(changes, ValueChange{Path: path, Change: changeRemoved, Old: old})
+		case !reflect.DeepEqual(old, value):
+			changes = append(changes, ValueChange{Path: path, Change: changeChanged, Old: old, New: value})
+		}
+	}
+
+	for path, value := range newPaths {
+		if _, ok := oldPaths[path]; !ok {
+			changes = append(changes, ValueChange{Path: path, Change: changeAdded, New: value})
+		}
+	}
+
+	for i := range changes {
+		if secretKeyPattern.MatchString(changes[i].Path) {
+			changes[i].Old = maskValue(changes[i].Old)
+			changes[i].New = maskValue(changes[i].New)
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
+
+func flattenValues(prefix string, values map[string]interface{}, out map[string]interface{}) {
+	for k, v := range values {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+
+		if nested, ok := v.(map[string]interface{}); ok && len(nested) > 0 {
+			flattenValues(path, nested, out)
+			continue
+		}
+
+		out[path] = v
+	}
+}
+
+// diffManifests compares objects of manifests by kind, namespace and name.
+func diffManifests(deployed, proposed string) ([]ResourceDiff, error) {
+	oldResources, err := splitResources(deployed)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse deployed manifest")
+	}
+
+	newResources, err := splitResources(proposed)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse proposed manifest")
+	}
+
+	keys := make([]string, 0, len(oldResources)+len(newResources))
+	for key := range oldResources {
+		keys = append(keys, key)
+	}
+	for key := range newResources {
+		if _, ok := oldResources[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	diffs := make([]ResourceDiff, 0)
+
+	for _, key := range keys {
+		old, oldOK := oldResources[key]
+		updated, newOK := newResources[key]
+
+		change, ref := changeChanged, updated
+		switch {
+		case !oldOK:
+			change, old = changeAdded, &renderedResource{}
+		case !newOK:
+			change, ref, updated = changeRemoved, old, &renderedResource{}
+		case old.content == updated.content:
+			continue
+		}
+
+		diff, truncated, err := unifiedDiff(old.content, updated.content)
+		if err != nil {
+			return nil, errors.Wrapf(err, "diff %s", key)
+		}
+
+		diffs = append(diffs, ResourceDiff{
+			Kind:      ref.kind,
+			Namespace: ref.namespace,
+			Name:      ref.name,
+			Change:    change,
+			Diff:      diff,
+			Truncated: truncated,
+		})
+	}
+
+	return diffs, nil
+}
+
+// splitResources parses objects of the manifest, objects are normalized
+// so formatting and comments of templates do not show up in diffs.
+func splitResources(manifest string) (map[string]*renderedResource, error) {
+	out := make(map[string]*renderedResource)
+
+	for _, doc := range releaseutil.SplitManifests(manifest) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+
+		head := &manifestHead{}
+		if err := yaml.Unmarshal([]byte(doc), head); err != nil {
+			return nil, err
+		}
+		if head.Kind == "" {
+			continue
+		}
+
+		obj := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			return nil, err
+		}
+
+		if head.Kind == "Secret" {
+			maskSecretData(obj)
+		}
+
+		content, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		r := &renderedResource{
+			kind:      head.Kind,
+			namespace: head.Metadata.Namespace,
+			name:      head.Metadata.Name,
+			content:   string(content),
+		}
+		out[strings.Join([]string{r.kind, r.namespace, r.name}, "/")] = r
+	}
+
+	return out, nil
+}
+
+func maskSecretData(obj map[string]interface{}) {
+	for _, field := range []string{"data", "stringData"} {
+		data, ok := obj[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		for k, v := range data {
+			data[k] = maskValue(v)
+		}
+	}
+}
+
+// maskValue hides the value, digest of it is kept so changes are still seen.
+func maskValue(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	digest := sha256.Sum256([]byte(fmt.Sprint(v)))
+	return "****** (sha256:" + hex.EncodeToString(digest[:4]) + ")"
+}
+
+func unifiedDiff(old, updated string) (string, bool, error) {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(old),
+		B:        difflib.SplitLines(updated),
+		FromFile: "deployed",
+		ToFile:   "upgrade",
+		Context:  resourceDiffContext,
+	})
+	if err != nil {
+		return "", false, err
+	}
+
+	if strings.Count(diff, "\n") <= maxResourceDiffLines {
+		return diff, false, nil
+	}
+
+	lines := strings.SplitAfter(diff, "\n")
+
+	return strings.Join(lines[:maxResourceDiffLines], ""), true, nil
+}
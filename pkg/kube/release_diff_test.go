@@ -0,0 +1,312 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+	"k8s.io/helm/pkg/proto/hapi/services"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/sghelm/proxy"
+	"github.com/supergiant/control/pkg/testutils/storage"
+)
+
+const (
+	deployedManifest = `---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: apps
+spec:
+  replicas: 3
+---
+# Source: web/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web
+  namespace: apps
+data:
+  password: b2xk
+---
+# Source: web/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+  namespace: apps
+data:
+  mode: production
+`
+	upgradeManifest = `---
+# Source: web/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web
+  namespace: apps
+data:
+  password: bmV3
+---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  namespace: apps
+  name: web
+spec:
+  replicas: 5
+---
+# Source: web/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: apps
+`
+)
+
+func TestDiffValues(t *testing.T) {
+	changes, err := diffValues(
+		"replicas: 3\nimage:\n  tag: 1.0\nauth:\n  password: old\nhosts: [a]\n",
+		"replicas: 5\nimage:\n  tag: 1.0\n  pullPolicy: Always\nauth:\n  password: new\n")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []struct {
+		path   string
+		change string
+	}{
+		{"auth.password", changeChanged},
+		{"hosts", changeRemoved},
+		{"image.pullPolicy", changeAdded},
+		{"replicas", changeChanged},
+	}
+
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected changes %v actual %v", expected, changes)
+	}
+
+	for i, e := range expected {
+		if changes[i].Path != e.path || changes[i].Change != e.change {
+			t.Errorf("Expected change %v actual %v", e, changes[i])
+		}
+	}
+
+	password := changes[0]
+	if password.Old == password.New || strings.Contains(fmt.Sprint(password.Old, password.New), "new") {
+		t.Errorf("Secret values must be masked %v", password)
+	}
+
+	if changes[3].Old != float64(3) || changes[3].New != float64(5) {
+		t.Errorf("Unexpected replicas change %v", changes[3])
+	}
+
+	if _, err := diffValues("", "{{"); err == nil {
+		t.Errorf("Invalid values must fail")
+	}
+}
+
+func TestDiffManifests(t *testing.T) {
+	diffs, err := diffManifests(deployedManifest, upgradeManifest)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []struct {
+		kind   string
+		change string
+	}{
+		{"ConfigMap", changeRemoved},
+		{"Deployment", changeChanged},
+		{"Secret", changeChanged},
+		{"Service", changeAdded},
+	}
+
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected diffs %v actual %v", expected, diffs)
+	}
+
+	for i, e := range expected {
+		if diffs[i].Kind != e.kind || diffs[i].Change != e.change || diffs[i].Name != "web" ||
+			diffs[i].Namespace != "apps" || diffs[i].Truncated {
+			t.Errorf("Expected diff %v actual %v", e, diffs[i])
+		}
+	}
+
+	if deployment := diffs[1].Diff; !strings.Contains(deployment, "-  replicas: 3") ||
+		!strings.Contains(deployment, "+  replicas: 5") || strings.Contains(deployment, "name: web\n-") {
+		t.Errorf("Unexpected deployment diff %s", deployment)
+	}
+
+	if secret := diffs[2].Diff; strings.Contains(secret, "b2xk") || strings.Contains(secret, "bmV3") ||
+		!strings.Contains(secret, "-  password: '******") {
+		t.Errorf("Data of secrets must be masked %s", secret)
+	}
+
+	large := &strings.Builder{}
+	large.WriteString("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: large\ndata:\n")
+
+	for i := 0; i < maxResourceDiffLines; i++ {
+		fmt.Fprintf(large, "  key%d: value%d\n", i, i)
+	}
+
+	diffs, err = diffManifests("", large.String())
+
+	if err != nil || len(diffs) != 1 || !diffs[0].Truncated ||
+		strings.Count(diffs[0].Diff, "\n") != maxResourceDiffLines {
+		t.Errorf("Large diffs must be truncated %v %v", diffs, err)
+	}
+}
+
+func TestService_DiffRelease(t *testing.T) {
+	deployed := &release.Release{
+		Name:     "web",
+		Manifest: deployedManifest,
+		Config:   &chart.Config{Raw: "replicas: 3"},
+	}
+
+	testCases := []struct {
+		description string
+		rls         *ReleaseInput
+		proxy       *fakeHelmProxy
+
+		expectedErr error
+	}{
+		{
+			description: "nil input",
+			expectedErr: sgerrors.ErrNilEntity,
+		},
+		{
+			description: "release not found",
+			rls:         &ReleaseInput{},
+			proxy: &fakeHelmProxy{
+				err: errors.Wrap(sgerrors.ErrNotFound, "release"),
+			},
+			expectedErr: sgerrors.ErrNotFound,
+		},
+		{
+			description: "diff",
+			rls:         &ReleaseInput{Values: "replicas: 5"},
+			proxy: &fakeHelmProxy{
+				getReleaseResp: &services.GetReleaseContentResponse{Release: deployed},
+				updateRlsResp: &services.UpdateReleaseResponse{
+					Release: &release.Release{Name: "web", Manifest: upgradeManifest},
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		svc := Service{
+			chrtGetter: &fakeChartGetter{},
+			storage: &storage.Fake{
+				Item: []byte("{}"),
+			},
+			newHelmProxyFn: func(kube *model.Kube) (proxy.Interface, error) {
+				return testCase.proxy, nil
+			},
+		}
+
+		diff, err := svc.DiffRelease(context.Background(), "fake", "web", testCase.rls)
+
+		if errors.Cause(err) != testCase.expectedErr {
+			t.Errorf("Expected error %v actual %v", testCase.expectedErr, err)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if len(diff.Values) != 1 || diff.Values[0].Path != "replicas" || len(diff.Resources) != 4 {
+			t.Errorf("Unexpected diff %v", diff)
+		}
+	}
+}
+
+func TestHandler_diffRelease(t *testing.T) {
+	testCases := []struct {
+		description string
+		body        string
+		svc         *kubeServiceMock
+
+		expectedCode int
+	}{
+		{
+			description:  "invalid json",
+			body:         "{{}",
+			svc:          &kubeServiceMock{},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "validation",
+			body:         "{}",
+			svc:          &kubeServiceMock{},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description: "not found",
+			body:        deployedReleaseInput,
+			svc: &kubeServiceMock{
+				rlsErr: errors.Wrap(sgerrors.ErrNotFound, "release"),
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description: "diff",
+			body:        deployedReleaseInput,
+			svc: &kubeServiceMock{
+				rlsDiff: &ReleaseDiff{
+					Values: []ValueChange{{Path: "replicas", Change: changeChanged}},
+				},
+			},
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		h := &Handler{svc: testCase.svc}
+		router := mux.NewRouter()
+		h.Register(router)
+
+		req, _ := http.NewRequest(http.MethodPost, "/kubes/fake/releases/web/diff",
+			strings.NewReader(testCase.body))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("Expected code %d actual %d %s", testCase.expectedCode, rec.Code, rec.Body.String())
+			continue
+		}
+
+		if rec.Code != http.StatusOK {
+			continue
+		}
+
+		diff := &ReleaseDiff{}
+
+		if err := json.NewDecoder(rec.Body).Decode(diff); err != nil || len(diff.Values) != 1 {
+			t.Errorf("Unexpected diff %v %v", diff, err)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// Schema helm validates values of charts against
+const valuesSchemaFile = "values.schema.json"
+
+// PrepareRelease returns values of the release merged from the input and
+// validated against schema of the chart, the namespace of the release is
+// created when it is missing and the input asks for it.
+func (s Service) PrepareRelease(ctx context.Context, kubeID string, rls *ReleaseInput) (string, error) {
+	if rls == nil {
+		return "", errors.Wrap(sgerrors.ErrNilEntity, "release input")
+	}
+
+	chrt, err := s.chrtGetter.GetChart(ctx, rls.RepoName, rls.ChartName, rls.ChartVersion)
+	if err != nil {
+		return "", errors.Wrap(err, "get chart")
+	}
+
+	kube, err := s.Get(ctx, kubeID)
+	if err != nil {
+		return "", errors.Wrap(err, "get kube")
+	}
+
+	return s.prepareRelease(kube, chrt, rls)
+}
+
+func (s Service) prepareRelease(kube *model.Kube, chrt *chart.Chart, rls *ReleaseInput) (string, error) {
+	values, err := releaseValues(chrt, rls)
+	if err != nil {
+		return "", err
+	}
+
+	if err = s.ensureNamespace(kube, rls.Namespace, rls.CreateNamespace); err != nil {
+		return "", err
+	}
+
+	return values, nil
+}
+
+// ensureNamespace checks the namespace exists, it is created if missing
+// when create is set. Namespace of the release defaults to the existing one.
+func (s Service) ensureNamespace(kube *model.Kube, namespace string, create bool) error {
+	if namespace == "" || namespace == metav1.NamespaceDefault {
+		return nil
+	}
+
+	client, err := s.corev1ClientFn(kube)
+	if err != nil {
+		return errors.Wrap(err, "build kubernetes client")
+	}
+
+	_, err = client.Namespaces().Get(namespace, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !k8serrors.IsNotFound(err) {
+		return errors.Wrapf(err, "get %s namespace", namespace)
+	}
+
+	if !create {
+		return errors.Wrapf(sgerrors.ErrNotFound, "%s namespace", namespace)
+	}
+
+	_, err = client.Namespaces().Create(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: namespace},
+	})
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return errors.Wrapf(err, "create %s namespace", namespace)
+	}
+
+	return nil
+}
+
+// releaseValues merges values documents of the release in order, values
+// and overrides go last. Merged values are validated against schema of the
+// chart when it has one.
+func releaseValues(chrt *chart.Chart, rls *ReleaseInput) (string, error) {
+	merged := map[string]interface{}{}
+
+	docs := append(append([]string{}, rls.ValuesFiles...), rls.Values)
+	for i, doc := range docs {
+		values, err := chartutil.ReadValues([]byte(doc))
+		if err != nil {
+			return "", errors.Wrapf(sgerrors.ErrValidationFailed, "read values document %d: %v", i+1, err)
+		}
+		mergeValues(merged, values.AsMap())
+	}
+
+	for _, o := range rls.Set {
+		if err := setValue(merged, o); err != nil {
+			return "", errors.Wrapf(sgerrors.ErrValidationFailed, "%v", err)
+		}
+	}
+
+	out, err := yaml.Marshal(merged)
+	if err != nil {
+		return "", errors.Wrap(err, "marshal values")
+	}
+
+	if len(merged) == 0 {
+		out = nil
+	}
+
+	if err = validateValues(chrt, string(out)); err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
+
+// mergeValues merges src into dest, maps are merged while
+// scalars and lists are replaced.
+func mergeValues(dest, src map[string]interface{}) {
+	for k, v := range src {
+		next, ok := v.(map[string]interface{})
+		if !ok {
+			dest[k] = v
+			continue
+		}
+
+		current, ok := dest[k].(map[string]interface{})
+		if !ok {
+			current = map[string]interface{}{}
+			dest[k] = current
+		}
+		mergeValues(current, next)
+	}
+}
+
+func setValue(values map[string]interface{}, o model.ValueOverride) error {
+	if o.Path == "" {
+		return errors.New("path of value override is empty")
+	}
+
+	var value interface{}
+	switch o.Type {
+	case model.ValueTypeString:
+		value = o.Value
+	case "", model.ValueTypeYAML:
+		if err := yaml.Unmarshal([]byte(o.Value), &value); err != nil {
+			return errors.Wrapf(err, "parse %s value", o.Path)
+		}
+	default:
+		return errors.Errorf("unknown type %s of %s value", o.Type, o.Path)
+	}
+
+	keys := strings.Split(o.Path, ".")
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := values[k].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			values[k] = next
+		}
+		values = next
+	}
+	values[keys[len(keys)-1]] = value
+
+	return nil
+}
+
+// validateValues checks values coalesced with defaults of the chart
+// against the values schema of the chart.
+func validateValues(chrt *chart.Chart, values string) error {
+	var rawSchema []byte
+	for _, f := range chrt.GetFiles() {
+		if f.GetTypeUrl() == valuesSchemaFile {
+			rawSchema = f.GetValue()
+		}
+	}
+
+	if rawSchema == nil {
+		return nil
+	}
+
+	schema := map[string]interface{}{}
+	if err := json.Unmarshal(rawSchema, &schema); err != nil {
+		return errors.Wrapf(sgerrors.ErrValidationFailed, "read %s of the chart: %v", valuesSchemaFile, err)
+	}
+
+	coalesced, err := chartutil.CoalesceValues(chrt, &chart.Config{Raw: values})
+	if err != nil {
+		return errors.Wrapf(sgerrors.ErrValidationFailed, "coalesce values: %v", err)
+	}
+
+	// schema works with json types
+	raw, err := json.Marshal(coalesced.AsMap())
+	if err != nil {
+		return errors.Wrap(err, "marshal values")
+	}
+	var doc interface{}
+	if err = json.Unmarshal(raw, &doc); err != nil {
+		return errors.Wrap(err, "unmarshal values")
+	}
+
+	if violations := validateSchema(schema, doc, ""); len(violations) > 0 {
+		return errors.Wrapf(sgerrors.ErrValidationFailed, "values do not match schema of the chart: %s",
+			strings.Join(violations, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,263 @@
+package kube
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/testutils/storage"
+)
+
+const testValuesSchema = `{
+  "type": "object",
+  "required": ["image"],
+  "properties": {
+    "replicas": {"type": "integer", "minimum": 1},
+    "image": {
+      "type": "object",
+      "required": ["tag"],
+      "properties": {
+        "tag": {"type": "string", "pattern": "^[a-z0-9.]+$"},
+        "pullPolicy": {"enum": ["Always", "IfNotPresent"]}
+      }
+    },
+    "hosts": {"type": "array", "items": {"type": "string"}, "maxItems": 2}
+  },
+  "additionalProperties": false
+}`
+
+func schemaChart() *chart.Chart {
+	return &chart.Chart{
+		Metadata: &chart.Metadata{Name: "web"},
+		Values:   &chart.Config{Raw: "replicas: 1\nimage:\n  tag: latest\n"},
+		Files: []*any.Any{
+			{TypeUrl: valuesSchemaFile, Value: []byte(testValuesSchema)},
+		},
+	}
+}
+
+func TestReleaseValues(t *testing.T) {
+	testCases := []struct {
+		description string
+		chrt        *chart.Chart
+		rls         *ReleaseInput
+
+		expected  map[string]interface{}
+		violation string
+	}{
+		{
+			description: "no values",
+			chrt:        &chart.Chart{},
+			rls:         &ReleaseInput{},
+			expected:    map[string]interface{}{},
+		},
+		{
+			description: "layered documents",
+			chrt:        &chart.Chart{},
+			rls: &ReleaseInput{
+				ValuesFiles: []string{
+					"replicas: 1\nimage:\n  tag: \"1.0\"\n  pullPolicy: Always\nhosts: [a, b]\n",
+					"replicas: 2\nimage:\n  tag: \"1.1\"\nhosts: [c]\n",
+				},
+				Values: "replicas: 3",
+			},
+			expected: map[string]interface{}{
+				"replicas": float64(3),
+				"image":    map[string]interface{}{"tag": "1.1", "pullPolicy": "Always"},
+				"hosts":    []interface{}{"c"},
+			},
+		},
+		{
+			description: "typed overrides",
+			chrt:        &chart.Chart{},
+			rls: &ReleaseInput{
+				Values: "image: latest",
+				Set: []model.ValueOverride{
+					{Path: "image.tag", Value: "1.10", Type: model.ValueTypeString},
+					{Path: "replicas", Value: "2"},
+					{Path: "ingress.hosts", Value: "[a, b]", Type: model.ValueTypeYAML},
+				},
+			},
+			expected: map[string]interface{}{
+				"replicas": float64(2),
+				"image":    map[string]interface{}{"tag": "1.10"},
+				"ingress":  map[string]interface{}{"hosts": []interface{}{"a", "b"}},
+			},
+		},
+		{
+			description: "unknown override type",
+			chrt:        &chart.Chart{},
+			rls: &ReleaseInput{
+				Set: []model.ValueOverride{{Path: "replicas", Value: "2", Type: "int"}},
+			},
+			violation: "unknown type",
+		},
+		{
+			description: "invalid document",
+			chrt:        &chart.Chart{},
+			rls: &ReleaseInput{
+				ValuesFiles: []string{"{{"},
+			},
+			violation: "document 1",
+		},
+		{
+			description: "values match schema",
+			chrt:        schemaChart(),
+			rls: &ReleaseInput{
+				Values: "replicas: 2\nhosts: [a]",
+			},
+			expected: map[string]interface{}{
+				"replicas": float64(2),
+				"hosts":    []interface{}{"a"},
+			},
+		},
+		{
+			description: "string override of integer",
+			chrt:        schemaChart(),
+			rls: &ReleaseInput{
+				Set: []model.ValueOverride{{Path: "replicas", Value: "2", Type: model.ValueTypeString}},
+			},
+			violation: "replicas: invalid type, expected integer, given string",
+		},
+		{
+			description: "schema violations",
+			chrt:        schemaChart(),
+			rls: &ReleaseInput{
+				Values: "replicas: 0\nimage:\n  tag: Latest\n  pullPolicy: Never\nhosts: [a, b, 1]\nextra: true\n",
+			},
+			violation: "(root): additional property extra is not allowed; " +
+				"hosts: must have at most 2 items; hosts[2]: invalid type, expected string, given integer; " +
+				"image.pullPolicy: must be one of [Always IfNotPresent]; image.tag: must match ^[a-z0-9.]+$; " +
+				"replicas: must be greater than or equal to 1",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		values, err := releaseValues(testCase.chrt, testCase.rls)
+
+		if testCase.violation != "" {
+			if !sgerrors.IsValidationFailed(err) || !strings.Contains(err.Error(), testCase.violation) {
+				t.Errorf("Expected violation %s actual %v", testCase.violation, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		actual, err := chartutil.ReadValues([]byte(values))
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if !reflect.DeepEqual(testCase.expected, actual.AsMap()) {
+			t.Errorf("Expected values %v actual %v", testCase.expected, actual)
+		}
+	}
+}
+
+func TestService_PrepareRelease(t *testing.T) {
+	testCases := []struct {
+		description string
+		rls         *ReleaseInput
+		existing    []string
+
+		expectedErr        error
+		expectedNamespaces []string
+	}{
+		{
+			description: "nil input",
+			expectedErr: sgerrors.ErrNilEntity,
+		},
+		{
+			description:        "default namespace",
+			rls:                &ReleaseInput{},
+			expectedNamespaces: []string{},
+		},
+		{
+			description:        "existing namespace",
+			rls:                &ReleaseInput{Namespace: "apps"},
+			existing:           []string{"apps"},
+			expectedNamespaces: []string{"apps"},
+		},
+		{
+			description: "missing namespace",
+			rls:         &ReleaseInput{Namespace: "apps"},
+			expectedErr: sgerrors.ErrNotFound,
+		},
+		{
+			description:        "create namespace",
+			rls:                &ReleaseInput{Namespace: "apps", CreateNamespace: true},
+			expectedNamespaces: []string{"apps"},
+		},
+		{
+			description: "invalid values",
+			rls: &ReleaseInput{
+				Namespace:       "apps",
+				CreateNamespace: true,
+				Values:          "{{",
+			},
+			expectedErr:        sgerrors.ErrValidationFailed,
+			expectedNamespaces: []string{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		objects := make([]runtime.Object, 0)
+		for _, name := range testCase.existing {
+			objects = append(objects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		}
+		client := fake.NewSimpleClientset(objects...)
+
+		svc := Service{
+			chrtGetter: &fakeChartGetter{chrt: &chart.Chart{}},
+			storage: &storage.Fake{
+				Item: []byte("{}"),
+			},
+			corev1ClientFn: func(*model.Kube) (corev1client.CoreV1Interface, error) {
+				return client.CoreV1(), nil
+			},
+		}
+
+		_, err := svc.PrepareRelease(context.Background(), "fake", testCase.rls)
+
+		if errors.Cause(err) != testCase.expectedErr {
+			t.Errorf("Expected error %v actual %v", testCase.expectedErr, err)
+			continue
+		}
+
+		if testCase.expectedNamespaces == nil {
+			continue
+		}
+
+		list, err := client.CoreV1().Namespaces().List(metav1.ListOptions{})
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if len(list.Items) != len(testCase.expectedNamespaces) {
+			t.Errorf("Expected namespaces %v actual %v", testCase.expectedNamespaces, list.Items)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/supergiant/control/pkg/message"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type replaceMasterResponse struct {
+	TaskID string `json:"taskId"`
+}
+
+// replaceMaster provisions new master in place of the old one, the old
+// master is removed from etcd, the kube and the cloud once new one joins.
+func (h *Handler) replaceMaster(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	kubeID := vars["kubeID"]
+	masterName := vars["masterName"]
+
+	// Unreachable master is replaced only on demand
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+
+	k, err := h.svc.Get(r.Context(), kubeID)
+
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	old := k.Masters[masterName]
+
+	if old == nil {
+		message.SendNotFound(w, masterName, sgerrors.ErrNotFound)
+		return
+	}
+
+	clientSet, err := h.clientSetFor(k)
+
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	nodes, err := clientSet.CoreV1().Nodes().List(metav1.ListOptions{})
+
+	if err != nil {
+		message.SendUnknownError(w, errors.Wrap(err, "list nodes"))
+		return
+	}
+
+	healthy, err := checkMasterReplacement(k, old, nodes.Items, force)
+
+	if err != nil {
+		message.SendMessage(w, message.New(
+			fmt.Sprintf("Master %s can't be replaced", masterName),
+			err.Error(), sgerrors.ValidationFailed, ""), http.StatusConflict)
+		return
+	}
+
+	config, kubeProfile, err := h.spotConfig(r.Context(), k)
+
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if len(kubeProfile.MasterProfiles) == 0 {
+		message.SendValidationFailed(w, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"profile %s has no master profiles", kubeProfile.ID))
+		return
+	}
+
+	config.ReplaceMasterConfig = steps.ReplaceMasterConfig{
+		HealthyMasterIP: k.SSHConfig.Host(*healthy),
+	}
+
+	t, done, err := h.nodeProvisioner.ReplaceMaster(context.Background(),
+		kubeProfile.MasterProfiles[0], k, old, config)
+
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	prevState := old.State
+	_, err = retryUpdate(r.Context(), h.svc, kubeID, func(k *model.Kube) error {
+		if m := k.Masters[masterName]; m != nil {
+			m.State = model.MachineStateDeleting
+		}
+
+		appendTasks(k, workflows.MasterTask, t.ID)
+		return nil
+	})
+
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	logrus.Infof("Replace master %s of kube %s by task %s", masterName, kubeID, t.ID)
+	go h.removeReplacedMaster(kubeID, old, prevState, done)
+
+	w.WriteHeader(http.StatusAccepted)
+
+	if err := json.NewEncoder(w).Encode(replaceMasterResponse{TaskID: t.ID}); err != nil {
+		logrus.Errorf("kubes: %s cluster: encode replace master response: %v", kubeID, err)
+	}
+}
+
+// removeReplacedMaster waits for the replacement, then removes the old
+// master from the kube and deletes its machine. Master stays in the
+// kube with its previous state when replacement has failed.
+func (h *Handler) removeReplacedMaster(kubeID string, old *model.Machine,
+	prevState model.MachineState, done <-chan error) {
+	if err := <-done; err != nil {
+		logrus.Errorf("replace master %s of kube %s caused %v", old.Name, kubeID, err)
+		h.updateKube(kubeID, func(k *model.Kube) {
+			if m := k.Masters[old.Name]; m != nil {
+				m.State = prevState
+			}
+		})
+		return
+	}
+
+	h.updateKube(kubeID, func(k *model.Kube) {
+		delete(k.Masters, old.Name)
+	})
+
+	k, err := h.svc.Get(context.Background(), kubeID)
+
+	if err != nil {
+		logrus.Errorf("get kube %s %v", kubeID, err)
+		return
+	}
+
+	acc, err := h.accountService.Get(context.Background(), k.AccountName)
+
+	if err != nil {
+		logrus.Errorf("delete replaced master %s: get cloud account %s %v",
+			old.Name, k.AccountName, err)
+		return
+	}
+
+	// Pods of unreachable master are never evicted
+	t, config, writer, err := h.newDeleteNodeTask(k, old, acc, steps.DrainConfig{Force: true})
+
+	if err != nil {
+		logrus.Errorf("delete replaced master %s of kube %s %v", old.Name, kubeID, err)
+		return
+	}
+
+	h.updateKube(kubeID, func(k *model.Kube) {
+		k.Tasks[workflows.NodeTask] = append(k.Tasks[workflows.NodeTask], t.ID)
+	})
+
+	if err := <-t.Run(context.Background(), config, writer); err != nil {
+		logrus.Errorf("delete replaced master %s of kube %s caused %v", old.Name, kubeID, err)
+	}
+}
+
+// checkMasterReplacement returns healthy master that prepares the cluster
+// for replacement. Replacement must keep etcd quorum when the new member
+// is added while the old one may be down, the new member does not count
+// as it is not live until it has joined, unreachable master is replaced
+// only when it is forced.
+func checkMasterReplacement(k *model.Kube, old *model.Machine, nodes []corev1.Node,
+	force bool) (*model.Machine, error) {
+	ready := readyMasters(k, nodes)
+
+	if !ready[old.Name] && !force {
+		return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"master %s is unreachable, replacement must be forced", old.Name)
+	}
+
+	others := make([]string, 0, len(ready))
+
+	for name := range ready {
+		if name != old.Name && k.SSHConfig.Host(*k.Masters[name]) != "" {
+			others = append(others, name)
+		}
+	}
+
+	members := len(k.Masters) + 1
+	live := len(others)
+
+	if ready[old.Name] {
+		live++
+	}
+
+	if quorum := members/2 + 1; live < quorum {
+		return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"%d healthy masters can't keep quorum of %d etcd members while %s is replaced",
+			live, members, old.Name)
+	}
+
+	sort.Strings(others)
+
+	return k.Masters[others[0]], nil
+}
+
+// readyMasters returns names of kube masters which nodes are ready.
+func readyMasters(k *model.Kube, nodes []corev1.Node) map[string]bool {
+	masters, workers := machinesFromNodes(nodes, k.Provider, k.Region)
+	ready := make(map[string]bool)
+
+	for _, synced := range []map[string]*model.Machine{masters, workers} {
+		for _, node := range synced {
+			if node.State != model.MachineStateActive {
+				continue
+			}
+
+			for name, master := range k.Masters {
+				if master == nil {
+					continue
+				}
+
+				if node.Name == master.Name || (master.PrivateIp != "" && node.PrivateIp == master.PrivateIp) {
+					ready[name] = true
+				}
+			}
+		}
+	}
+
+	return ready
+}
@@ -0,0 +1,195 @@
+package kube
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+func masterNode(name, ip string, ready bool) *corev1.Node {
+	status := corev1.ConditionTrue
+
+	if !ready {
+		status = corev1.ConditionUnknown
+	}
+
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				"node-role.kubernetes.io/master": "",
+			},
+		},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: ip},
+			},
+			Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: status},
+			},
+		},
+	}
+}
+
+func mastersKube() *model.Kube {
+	return &model.Kube{
+		ID:          "test",
+		AccountName: "test",
+		Provider:    clouds.DigitalOcean,
+		Masters: map[string]*model.Machine{
+			"master-1": {Name: "master-1", PrivateIp: "10.0.0.1", PublicIp: "1.1.1.1"},
+			"master-2": {Name: "master-2", PrivateIp: "10.0.0.2", PublicIp: "1.1.1.2"},
+			"master-3": {Name: "master-3", PrivateIp: "10.0.0.3", PublicIp: "1.1.1.3"},
+		},
+		Nodes: make(map[string]*model.Machine),
+		Tasks: make(map[string][]string),
+	}
+}
+
+func TestReplaceMaster(t *testing.T) {
+	testCases := []struct {
+		description  string
+		kubeErr      error
+		master       string
+		force        bool
+		ready        map[string]bool
+		expectedCode int
+	}{
+		{
+			description:  "kube not found",
+			kubeErr:      sgerrors.ErrNotFound,
+			master:       "master-1",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "master not found",
+			master:       "master-4",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "unreachable master",
+			master:       "master-1",
+			ready:        map[string]bool{"master-2": true, "master-3": true},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			description:  "quorum is lost",
+			master:       "master-1",
+			force:        true,
+			ready:        map[string]bool{"master-1": true, "master-2": true},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			description:  "new member does not count for quorum",
+			master:       "master-1",
+			force:        true,
+			ready:        map[string]bool{"master-2": true, "master-3": true},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			description:  "replacement of healthy master",
+			master:       "master-1",
+			ready:        map[string]bool{"master-1": true, "master-2": true, "master-3": true},
+			expectedCode: http.StatusAccepted,
+		},
+	}
+
+	workflows.Init()
+	configs := make(chan steps.DrainConfig, 1)
+	workflows.RegisterWorkFlow(workflows.DeleteNode, []steps.Step{
+		drainConfigStep{configs: configs},
+	})
+
+	for _, testCase := range testCases {
+		k := mastersKube()
+		done := make(chan error, 1)
+		done <- nil
+
+		provisioner := new(mockNodeProvisioner)
+		provisioner.On("ReplaceMaster", mock.Anything, mock.Anything, k,
+			k.Masters[testCase.master], mock.Anything).
+			Return(&workflows.Task{ID: "replace"}, done, nil)
+
+		h := poolHandler(k, testCase.kubeErr, provisioner)
+		h.profileSvc = new(mockProfileService)
+		h.profileSvc.(*mockProfileService).On("Get", mock.Anything, mock.Anything).
+			Return(&profile.Profile{
+				MasterProfiles: []profile.NodeProfile{{"size": "s-2vcpu-4gb"}},
+			}, nil)
+		h.clientSetFor = func(*model.Kube) (kubernetes.Interface, error) {
+			client := fake.NewSimpleClientset()
+
+			for _, m := range mastersKube().Masters {
+				client.CoreV1().Nodes().Create(masterNode(m.Name, m.PrivateIp, testCase.ready[m.Name]))
+			}
+
+			return client, nil
+		}
+
+		url := "/kubes/test/masters/" + testCase.master + "/replace"
+
+		if testCase.force {
+			url += "?force=true"
+		}
+
+		req, _ := http.NewRequest(http.MethodPost, url, nil)
+		rec := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/kubes/{kubeID}/masters/{masterName}/replace", h.replaceMaster)
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("%s: expected code %d actual %d %s", testCase.description,
+				testCase.expectedCode, rec.Code, rec.Body.String())
+			continue
+		}
+
+		if rec.Code != http.StatusAccepted {
+			continue
+		}
+
+		resp := replaceMasterResponse{}
+
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.TaskID != "replace" {
+			t.Errorf("%s: wrong response %v %v", testCase.description, resp, err)
+		}
+
+		config := provisioner.Calls[0].Arguments.Get(4).(*steps.Config)
+
+		if config.ReplaceMasterConfig.HealthyMasterIP != "1.1.1.2" {
+			t.Errorf("%s: commands must be run on healthy master %+v", testCase.description,
+				config.ReplaceMasterConfig)
+		}
+
+		// Machine of the old master is deleted once it leaves the kube
+		select {
+		case cfg := <-configs:
+			if cfg.NodeName != testCase.master || !cfg.Force {
+				t.Errorf("%s: wrong drain config %+v", testCase.description, cfg)
+			}
+		case <-time.After(time.Second * 5):
+			t.Errorf("%s: replaced master has not been deleted", testCase.description)
+			continue
+		}
+
+		if _, ok := k.Masters[testCase.master]; ok {
+			t.Errorf("%s: replaced master must be removed from the kube", testCase.description)
+		}
+	}
+}
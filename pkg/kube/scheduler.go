@@ -0,0 +1,204 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/util/cron"
+)
+
+const DefaultScheduleInterval = 30 * time.Second
+
+// errScheduleDeleted stops update of a schedule deleted while it was run
+var errScheduleDeleted = errors.New("schedule has been deleted")
+
+// ScheduleExecutor runs action of the schedule on the kube and returns
+// ids of tasks it has started.
+type ScheduleExecutor func(ctx context.Context, k *model.Kube, schedule *model.Schedule) ([]string, error)
+
+// TaskChecker returns true while the task is running.
+type TaskChecker func(ctx context.Context, taskID string) (bool, error)
+
+// Scheduler executes schedules of kubes that are due. Schedule of the kube
+// is skipped while tasks of its previous executions are running, schedules
+// missed while control was down are executed once.
+type Scheduler struct {
+	svc       Interface
+	execute   ScheduleExecutor
+	isRunning TaskChecker
+	interval  time.Duration
+	now       func() time.Time
+}
+
+// NewScheduler constructs Scheduler.
+func NewScheduler(svc Interface, execute ScheduleExecutor, isRunning TaskChecker,
+	interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = DefaultScheduleInterval
+	}
+
+	return &Scheduler{
+		svc:       svc,
+		execute:   execute,
+		isRunning: isRunning,
+		interval:  interval,
+		now:       time.Now,
+	}
+}
+
+// Run executes schedules until context is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.poll(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) poll(ctx context.Context) {
+	kubes, err := s.svc.ListAll(ctx)
+
+	if err != nil {
+		logrus.Errorf("scheduler: list kubes %v", err)
+		return
+	}
+
+	now := s.now()
+
+	for i := range kubes {
+		k := &kubes[i]
+
+		for _, schedule := range dueSchedules(k, now) {
+			s.run(ctx, k, schedule, now)
+		}
+	}
+}
+
+// run executes the schedule unless the kube is busy, the result is saved
+// to the schedule of the latest kube record.
+func (s *Scheduler) run(ctx context.Context, k *model.Kube, schedule *model.Schedule, now time.Time) {
+	var (
+		taskIDs []string
+		err     error
+	)
+
+	reason := s.skipReason(ctx, k)
+
+	if reason != "" {
+		logrus.Infof("scheduler: skip schedule %s of kube %s: %s", schedule.ID, k.ID, reason)
+	} else {
+		logrus.Infof("scheduler: execute schedule %s of kube %s: %s", schedule.ID, k.ID, schedule.Action)
+		taskIDs, err = s.execute(ctx, k, schedule)
+
+		if err != nil {
+			logrus.Errorf("scheduler: execute schedule %s of kube %s %v", schedule.ID, k.ID, err)
+			reason = err.Error()
+		}
+	}
+
+	update := func(schedule *model.Schedule) {
+		schedule.LastRun = now.Unix()
+		schedule.LastError = reason
+
+		// Tasks of the previous run are kept when none have been started
+		if reason == "" || len(taskIDs) > 0 {
+			schedule.TaskIDs = taskIDs
+		}
+	}
+
+	// Other schedules of the kube due now see tasks of this run
+	update(schedule)
+
+	_, err = retryUpdate(ctx, s.svc, k.ID, func(latest *model.Kube) error {
+		// The schedule may have been deleted meanwhile
+		if latest.Schedules[schedule.ID] == nil {
+			return errScheduleDeleted
+		}
+
+		update(latest.Schedules[schedule.ID])
+		return nil
+	})
+
+	if err != nil && err != errScheduleDeleted {
+		logrus.Errorf("scheduler: update kube %s %v", k.ID, err)
+	}
+}
+
+// skipReason returns why no schedule of the kube may run now,
+// it is empty when it may.
+func (s *Scheduler) skipReason(ctx context.Context, k *model.Kube) string {
+	if k.State != model.StateOperational {
+		return fmt.Sprintf("kube is %s", k.State)
+	}
+
+	for _, schedule := range sortedSchedules(k) {
+		for _, taskID := range schedule.TaskIDs {
+			running, err := s.isRunning(ctx, taskID)
+
+			if err != nil {
+				return fmt.Sprintf("check task %s of schedule %s: %v", taskID, schedule.ID, err)
+			}
+
+			if running {
+				return fmt.Sprintf("task %s of schedule %s is running", taskID, schedule.ID)
+			}
+		}
+	}
+
+	return ""
+}
+
+// dueSchedules returns schedules of the kube whose next time after
+// their last run has passed.
+func dueSchedules(k *model.Kube, now time.Time) []*model.Schedule {
+	due := make([]*model.Schedule, 0)
+
+	for _, schedule := range sortedSchedules(k) {
+		spec, err := cron.Parse(schedule.Cron)
+
+		if err != nil {
+			logrus.Errorf("scheduler: schedule %s of kube %s %v", schedule.ID, k.ID, err)
+			continue
+		}
+
+		last := schedule.CreatedAt
+
+		if schedule.LastRun > last {
+			last = schedule.LastRun
+		}
+
+		next := spec.Next(time.Unix(last, 0))
+
+		if !next.IsZero() && !next.After(now) {
+			due = append(due, schedule)
+		}
+	}
+
+	return due
+}
+
+func sortedSchedules(k *model.Kube) []*model.Schedule {
+	schedules := make([]*model.Schedule, 0, len(k.Schedules))
+
+	for _, schedule := range k.Schedules {
+		schedules = append(schedules, schedule)
+	}
+
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].ID < schedules[j].ID
+	})
+
+	return schedules
+}
@@ -0,0 +1,130 @@
+package kube
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/model"
+)
+
+func TestSchedulerPoll(t *testing.T) {
+	now := time.Date(2019, 7, 1, 20, 0, 30, 0, time.UTC)
+	created := now.Add(-time.Hour).Unix()
+
+	testCases := []struct {
+		description string
+		state       model.KubeState
+		schedules   map[string]*model.Schedule
+		running     bool
+
+		expectedExecuted []string
+		expectedSkipped  []string
+	}{
+		{
+			description: "due",
+			schedules: map[string]*model.Schedule{
+				"night": {ID: "night", Cron: "0 20 * * *", CreatedAt: created},
+			},
+			expectedExecuted: []string{"night"},
+		},
+		{
+			description: "not due",
+			schedules: map[string]*model.Schedule{
+				"morning": {ID: "morning", Cron: "0 8 * * *", CreatedAt: created},
+			},
+		},
+		{
+			description: "already run",
+			schedules: map[string]*model.Schedule{
+				"night": {ID: "night", Cron: "0 20 * * *", CreatedAt: created,
+					LastRun: now.Add(-time.Second * 10).Unix()},
+			},
+		},
+		{
+			description: "kube is not operational",
+			state:       model.StateProvisioning,
+			schedules: map[string]*model.Schedule{
+				"night": {ID: "night", Cron: "0 20 * * *", CreatedAt: created},
+			},
+			expectedSkipped: []string{"night"},
+		},
+		{
+			description: "task of previous run is running",
+			running:     true,
+			schedules: map[string]*model.Schedule{
+				"night": {ID: "night", Cron: "0 20 * * *", CreatedAt: created},
+				"scale": {ID: "scale", Cron: "0 * * * *", CreatedAt: created,
+					LastRun: now.Add(-time.Hour).Unix(), TaskIDs: []string{"task"}},
+			},
+			expectedSkipped: []string{"night", "scale"},
+		},
+		{
+			description: "overlapping schedules",
+			schedules: map[string]*model.Schedule{
+				"night": {ID: "night", Cron: "0 20 * * *", CreatedAt: created},
+				"scale": {ID: "scale", Cron: "0 * * * *", CreatedAt: created},
+			},
+			expectedExecuted: []string{"night"},
+			expectedSkipped:  []string{"scale"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		state := testCase.state
+		if state == "" {
+			state = model.StateOperational
+		}
+
+		k := model.Kube{
+			ID:        "test",
+			State:     state,
+			Schedules: testCase.schedules,
+		}
+
+		svc := new(kubeServiceMock)
+		svc.On(serviceListAll, mock.Anything).Return([]model.Kube{k}, nil)
+		svc.On(serviceGet, mock.Anything, mock.Anything).Return(&k, nil)
+		svc.On(serviceUpdate, mock.Anything, mock.Anything).Return(nil)
+
+		executed := make([]string, 0)
+
+		s := NewScheduler(svc, func(ctx context.Context, k *model.Kube, schedule *model.Schedule) ([]string, error) {
+			executed = append(executed, schedule.ID)
+			return []string{"task"}, nil
+		}, func(ctx context.Context, taskID string) (bool, error) {
+			// Tasks started by the poll are running
+			return testCase.running || len(executed) > 0, nil
+		}, 0)
+		s.now = func() time.Time {
+			return now
+		}
+
+		s.poll(context.Background())
+
+		if strings.Join(executed, ",") != strings.Join(testCase.expectedExecuted, ",") {
+			t.Errorf("Expected executed %v actual %v", testCase.expectedExecuted, executed)
+		}
+
+		for _, id := range testCase.expectedExecuted {
+			schedule := k.Schedules[id]
+
+			if schedule.LastRun != now.Unix() || schedule.LastError != "" || len(schedule.TaskIDs) != 1 {
+				t.Errorf("Run of schedule %s must be saved %v", id, schedule)
+			}
+		}
+
+		for _, id := range testCase.expectedSkipped {
+			schedule := k.Schedules[id]
+
+			if schedule.LastRun != now.Unix() || schedule.LastError == "" {
+				t.Errorf("Skip of schedule %s must be saved %v", id, schedule)
+			}
+		}
+	}
+}
@@ -0,0 +1,419 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/message"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/util/cron"
+)
+
+// schedulePreviewRuns is count of next execution times of the preview.
+const schedulePreviewRuns = 5
+
+type schedulePreview struct {
+	Schedule *model.Schedule `json:"schedule"`
+	NextRuns []time.Time     `json:"nextRuns"`
+}
+
+func (h *Handler) listSchedules(w http.ResponseWriter, r *http.Request) {
+	kubeID := mux.Vars(r)["kubeID"]
+
+	k, err := h.svc.Get(r.Context(), kubeID)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(sortedSchedules(k)); err != nil {
+		message.SendUnknownError(w, err)
+	}
+}
+
+func (h *Handler) getSchedule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	kubeID := vars["kubeID"]
+
+	k, err := h.svc.Get(r.Context(), kubeID)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	schedule, ok := k.Schedules[vars["scheduleID"]]
+
+	if !ok {
+		message.SendNotFound(w, vars["scheduleID"], sgerrors.ErrNotFound)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(schedule); err != nil {
+		message.SendUnknownError(w, err)
+	}
+}
+
+// createSchedule adds the schedule to the kube, PUT to the schedule
+// path replaces the existing schedule and keeps results of its last run.
+func (h *Handler) createSchedule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	kubeID := vars["kubeID"]
+	scheduleID, update := vars["scheduleID"]
+
+	req := model.Schedule{}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		message.SendInvalidJSON(w, err)
+		return
+	}
+
+	k, err := h.svc.Get(r.Context(), kubeID)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	existing, ok := k.Schedules[scheduleID]
+
+	if update && !ok {
+		message.SendNotFound(w, scheduleID, sgerrors.ErrNotFound)
+		return
+	}
+
+	req.ID = scheduleID
+
+	schedule, err := newSchedule(k, req, time.Now())
+	if err != nil {
+		message.SendValidationFailed(w, err)
+		return
+	}
+
+	if existing != nil {
+		schedule.LastRun = existing.LastRun
+		schedule.TaskIDs = existing.TaskIDs
+		schedule.LastError = existing.LastError
+	}
+
+	if k.Schedules == nil {
+		k.Schedules = make(map[string]*model.Schedule)
+	}
+
+	k.Schedules[schedule.ID] = schedule
+
+	if err := h.svc.Update(r.Context(), k); err != nil {
+		if sgerrors.IsConflict(err) {
+			h.sendConflict(w, r, k.ID, err)
+			return
+		}
+
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if !update {
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	if err := json.NewEncoder(w).Encode(schedule); err != nil {
+		logrus.Errorf("kubes: %s cluster: encode schedule %v", kubeID, err)
+	}
+}
+
+func (h *Handler) deleteSchedule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	kubeID := vars["kubeID"]
+	scheduleID := vars["scheduleID"]
+
+	k, err := h.svc.Get(r.Context(), kubeID)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if _, ok := k.Schedules[scheduleID]; !ok {
+		message.SendNotFound(w, scheduleID, sgerrors.ErrNotFound)
+		return
+	}
+
+	delete(k.Schedules, scheduleID)
+
+	if err := h.svc.Update(r.Context(), k); err != nil {
+		if sgerrors.IsConflict(err) {
+			h.sendConflict(w, r, k.ID, err)
+			return
+		}
+
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// previewSchedule validates the schedule without saving it and returns
+// next times it would be executed at.
+func (h *Handler) previewSchedule(w http.ResponseWriter, r *http.Request) {
+	kubeID := mux.Vars(r)["kubeID"]
+
+	req := model.Schedule{}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		message.SendInvalidJSON(w, err)
+		return
+	}
+
+	k, err := h.svc.Get(r.Context(), kubeID)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	now := time.Now()
+
+	schedule, err := newSchedule(k, req, now)
+	if err != nil {
+		message.SendValidationFailed(w, err)
+		return
+	}
+
+	// Cron spec has been validated by newSchedule
+	spec, _ := cron.Parse(schedule.Cron)
+
+	if err := json.NewEncoder(w).Encode(schedulePreview{
+		Schedule: schedule,
+		NextRuns: spec.NextN(now, schedulePreviewRuns),
+	}); err != nil {
+		logrus.Errorf("kubes: %s cluster: encode schedule preview %v", kubeID, err)
+	}
+}
+
+// ExecuteSchedule runs action of the schedule on the kube and returns
+// ids of pool tasks it has started, nodes are drained before removal.
+func (h *Handler) ExecuteSchedule(ctx context.Context, k *model.Kube,
+	schedule *model.Schedule) ([]string, error) {
+	switch schedule.Action {
+	case model.ScheduleScale:
+		return h.scalePool(ctx, k, schedule.Pool, schedule.Count)
+	case model.SchedulePause:
+		return h.pausePools(ctx, k)
+	case model.ScheduleResume:
+		return h.resumePools(ctx, k)
+	}
+
+	return nil, errors.Wrapf(sgerrors.ErrValidationFailed, "unknown action %s", schedule.Action)
+}
+
+// scalePool adds or removes nodes of the pool to match the count.
+func (h *Handler) scalePool(ctx context.Context, k *model.Kube,
+	poolName string, count int64) ([]string, error) {
+	pool := k.NodePools[poolName]
+
+	if pool == nil {
+		return nil, errors.Wrapf(sgerrors.ErrNotFound, "node pool %s", poolName)
+	}
+
+	if pool.Autoscale {
+		return nil, errAutoscaledPool(poolName)
+	}
+
+	running, err := h.TaskRunning(ctx, pool.TaskID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if running {
+		return nil, errors.Errorf("task %s of node pool %s is running", pool.TaskID, poolName)
+	}
+
+	nodes := poolNodes(k, poolName)
+	delta := count - int64(len(nodes))
+	pool.Count = count
+
+	var taskID string
+
+	switch {
+	case delta > 0:
+		taskID, _, err = h.addPoolNodes(ctx, k, pool, delta)
+	case delta < 0:
+		taskID, _, err = h.removePoolNodes(ctx, k, pool, nodesToRemove(nodes, int(-delta)), false)
+	default:
+		return nil, h.savePool(ctx, k.ID, pool, nil)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{taskID}, nil
+}
+
+// pausePools scales all node pools of the kube to zero, their counts
+// are saved to resume them. Pools that have been paused already
+// keep the saved count.
+func (h *Handler) pausePools(ctx context.Context, k *model.Kube) ([]string, error) {
+	if k.PausedPools == nil {
+		k.PausedPools = make(map[string]int64)
+	}
+
+	taskIDs := make([]string, 0)
+
+	for _, name := range sortedPoolNames(k) {
+		pool := k.NodePools[name]
+
+		// Cluster autoscaler scales idle autoscaled pools down by itself
+		if pool.Autoscale {
+			continue
+		}
+
+		if _, ok := k.PausedPools[name]; !ok && pool.Count > 0 {
+			k.PausedPools[name] = pool.Count
+		}
+
+		if len(poolNodes(k, name)) == 0 {
+			pool.Count = 0
+			continue
+		}
+
+		ids, err := h.scalePool(ctx, k, name, 0)
+
+		if err != nil {
+			return taskIDs, errors.Wrapf(err, "pause node pool %s", name)
+		}
+
+		taskIDs = append(taskIDs, ids...)
+	}
+
+	return taskIDs, h.savePausedPools(ctx, k)
+}
+
+// resumePools scales paused pools back to their saved counts.
+func (h *Handler) resumePools(ctx context.Context, k *model.Kube) ([]string, error) {
+	taskIDs := make([]string, 0)
+
+	for _, name := range sortedPoolNames(k) {
+		count, ok := k.PausedPools[name]
+
+		if !ok {
+			continue
+		}
+
+		delete(k.PausedPools, name)
+
+		ids, err := h.scalePool(ctx, k, name, count)
+
+		if err != nil {
+			return taskIDs, errors.Wrapf(err, "resume node pool %s", name)
+		}
+
+		taskIDs = append(taskIDs, ids...)
+	}
+
+	// Pools that have been deleted while paused are not resumed
+	k.PausedPools = nil
+
+	return taskIDs, h.savePausedPools(ctx, k)
+}
+
+// savePausedPools saves paused pools and pool counts of the kube to the
+// latest kube record, pools have been saved with their tasks already.
+func (h *Handler) savePausedPools(ctx context.Context, k *model.Kube) error {
+	_, err := retryUpdate(ctx, h.svc, k.ID, func(latest *model.Kube) error {
+		latest.PausedPools = k.PausedPools
+
+		for name, pool := range latest.NodePools {
+			if scaled := k.NodePools[name]; scaled != nil {
+				pool.Count = scaled.Count
+			}
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// newSchedule validates the schedule and assigns its id.
+func newSchedule(k *model.Kube, schedule model.Schedule, now time.Time) (*model.Schedule, error) {
+	if _, err := cron.Parse(schedule.Cron); err != nil {
+		return nil, errors.Wrapf(sgerrors.ErrValidationFailed, "cron %s: %v",
+			schedule.Cron, err)
+	}
+
+	switch schedule.Action {
+	case model.ScheduleScale:
+		pool, ok := k.NodePools[schedule.Pool]
+
+		if !ok {
+			return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"node pool %s not found", schedule.Pool)
+		}
+
+		if pool.Autoscale {
+			return nil, errAutoscaledPool(schedule.Pool)
+		}
+
+		if schedule.Count < 0 {
+			return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"node count must not be negative, got %d", schedule.Count)
+		}
+	case model.SchedulePause, model.ScheduleResume:
+		schedule.Pool = ""
+		schedule.Count = 0
+	default:
+		return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"action %s must be one of scale, pause, resume", schedule.Action)
+	}
+
+	if schedule.ID == "" {
+		schedule.ID = uuid.New()[:8]
+	}
+
+	schedule.CreatedAt = now.Unix()
+	schedule.LastRun = 0
+	schedule.TaskIDs = nil
+	schedule.LastError = ""
+
+	return &schedule, nil
+}
+
+func sortedPoolNames(k *model.Kube) []string {
+	names := make([]string, 0, len(k.NodePools))
+
+	for name := range k.NodePools {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,267 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/workflows"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+func TestNewSchedule(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		description string
+		schedule    model.Schedule
+		expectedErr bool
+	}{
+		{
+			description: "scale",
+			schedule: model.Schedule{
+				Cron:   "CRON_TZ=Europe/Berlin 0 20 * * mon-fri",
+				Action: model.ScheduleScale,
+				Pool:   "gpu",
+				Count:  0,
+			},
+		},
+		{
+			description: "pause",
+			schedule: model.Schedule{
+				Cron:   "0 20 * * *",
+				Action: model.SchedulePause,
+			},
+		},
+		{
+			description: "invalid cron",
+			schedule: model.Schedule{
+				Cron:   "0 25 * * *",
+				Action: model.SchedulePause,
+			},
+			expectedErr: true,
+		},
+		{
+			description: "unknown action",
+			schedule: model.Schedule{
+				Cron:   "0 20 * * *",
+				Action: "stop",
+			},
+			expectedErr: true,
+		},
+		{
+			description: "unknown pool",
+			schedule: model.Schedule{
+				Cron:   "0 20 * * *",
+				Action: model.ScheduleScale,
+				Pool:   "cpu",
+			},
+			expectedErr: true,
+		},
+		{
+			description: "negative count",
+			schedule: model.Schedule{
+				Cron:   "0 20 * * *",
+				Action: model.ScheduleScale,
+				Pool:   "gpu",
+				Count:  -1,
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		schedule, err := newSchedule(poolKube(), testCase.schedule, now)
+
+		if (err != nil) != testCase.expectedErr {
+			t.Errorf("Expected error %v actual %v", testCase.expectedErr, err)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if schedule.ID == "" || schedule.CreatedAt != now.Unix() {
+			t.Errorf("Schedule must have id and creation time %v", schedule)
+		}
+	}
+}
+
+func TestCreateSchedule(t *testing.T) {
+	k := poolKube()
+	k.Schedules = map[string]*model.Schedule{
+		"night": {
+			ID:      "night",
+			Cron:    "0 20 * * *",
+			Action:  model.SchedulePause,
+			LastRun: 100,
+			TaskIDs: []string{"task"},
+		},
+	}
+	h := poolHandler(k, nil, new(mockNodeProvisioner))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/kubes/{kubeID}/schedules", h.createSchedule).Methods(http.MethodPost)
+	router.HandleFunc("/kubes/{kubeID}/schedules/{scheduleID}", h.createSchedule).Methods(http.MethodPut)
+
+	testCases := []struct {
+		description  string
+		method       string
+		path         string
+		body         string
+		expectedCode int
+	}{
+		{
+			description:  "create",
+			method:       http.MethodPost,
+			path:         "/kubes/test/schedules",
+			body:         `{"cron":"0 8 * * *","action":"resume"}`,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			description:  "invalid",
+			method:       http.MethodPost,
+			path:         "/kubes/test/schedules",
+			body:         `{"cron":"0 8 * *","action":"resume"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "update",
+			method:       http.MethodPut,
+			path:         "/kubes/test/schedules/night",
+			body:         `{"cron":"0 21 * * *","action":"pause"}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:  "update not found",
+			method:       http.MethodPut,
+			path:         "/kubes/test/schedules/day",
+			body:         `{"cron":"0 8 * * *","action":"resume"}`,
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		req, _ := http.NewRequest(testCase.method, testCase.path,
+			bytes.NewBufferString(testCase.body))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("Expected code %d actual %d %s", testCase.expectedCode,
+				rec.Code, rec.Body.String())
+		}
+	}
+
+	if len(k.Schedules) != 2 {
+		t.Errorf("Expected 2 schedules actual %v", k.Schedules)
+	}
+
+	night := k.Schedules["night"]
+
+	if night.Cron != "0 21 * * *" || night.LastRun != 100 || len(night.TaskIDs) != 1 {
+		t.Errorf("Updated schedule must keep its last run %v", night)
+	}
+}
+
+func TestPreviewSchedule(t *testing.T) {
+	k := poolKube()
+	h := poolHandler(k, nil, new(mockNodeProvisioner))
+
+	req, _ := http.NewRequest(http.MethodPost, "/kubes/test/schedules/preview",
+		bytes.NewBufferString(`{"cron":"CRON_TZ=Europe/Berlin 0 20 * * *","action":"pause"}`))
+	rec := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/kubes/{kubeID}/schedules/preview", h.previewSchedule)
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected code %d actual %d %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	preview := schedulePreview{}
+
+	if err := json.NewDecoder(rec.Body).Decode(&preview); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(preview.NextRuns) != schedulePreviewRuns {
+		t.Fatalf("Expected %d runs actual %v", schedulePreviewRuns, preview.NextRuns)
+	}
+
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+
+	for i, next := range preview.NextRuns {
+		if next.In(berlin).Hour() != 20 || (i > 0 && next.Sub(preview.NextRuns[i-1]) != 24*time.Hour) {
+			t.Errorf("Wrong run %d %v", i, next)
+		}
+	}
+
+	if len(k.Schedules) != 0 {
+		t.Errorf("Preview must not save the schedule %v", k.Schedules)
+	}
+}
+
+func TestExecuteSchedulePauseResume(t *testing.T) {
+	workflows.Init()
+	workflows.RegisterWorkFlow(workflows.DeleteNode, []steps.Step{drainStep{}})
+
+	k := poolKube()
+	provisioner := new(mockNodeProvisioner)
+	provisioner.On("ProvisionNodePool", mock.Anything, mock.Anything, 3, k, mock.Anything).
+		Return(&workflows.Task{ID: "pooltask"}, []string{}, nil)
+	h := poolHandler(k, nil, provisioner)
+
+	taskIDs, err := h.ExecuteSchedule(context.Background(), k,
+		&model.Schedule{Action: model.SchedulePause})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(taskIDs) != 1 {
+		t.Fatalf("Expected pool task actual %v", taskIDs)
+	}
+
+	if err := waitPoolTask(h, taskIDs[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if k.PausedPools["gpu"] != 3 || k.NodePools["gpu"].Count != 0 {
+		t.Errorf("Pool count must be saved %v %d", k.PausedPools, k.NodePools["gpu"].Count)
+	}
+
+	if len(poolNodes(k, "gpu")) != 0 {
+		t.Errorf("Pool nodes must be removed %v", k.Nodes)
+	}
+
+	taskIDs, err = h.ExecuteSchedule(context.Background(), k,
+		&model.Schedule{Action: model.ScheduleResume})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	provisioner.AssertExpectations(t)
+
+	if len(taskIDs) != 1 || taskIDs[0] != "pooltask" {
+		t.Errorf("Expected pool task actual %v", taskIDs)
+	}
+
+	if len(k.PausedPools) != 0 || k.NodePools["gpu"].Count != 3 {
+		t.Errorf("Pool must be resumed %v %d", k.PausedPools, k.NodePools["gpu"].Count)
+	}
+}
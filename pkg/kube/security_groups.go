@@ -0,0 +1,178 @@
+package kube
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+	"github.com/supergiant/control/pkg/workflows/steps/amazon"
+)
+
+const DefaultSecurityGroupCheckInterval = 10 * time.Minute
+
+// SecurityGroupCheckFn checks required rules of kube security groups,
+// missing rules are authorized again when enforce is set.
+type SecurityGroupCheckFn func(ctx context.Context, k *model.Kube,
+	account *model.CloudAccount, enforce bool) (*model.SecurityGroupDrift, error)
+
+// SecurityGroupWatcher periodically checks security groups of AWS kubes
+// for rules that have been removed outside of control.
+type SecurityGroupWatcher struct {
+	svc            Interface
+	accountService accountGetter
+	interval       time.Duration
+	check          SecurityGroupCheckFn
+}
+
+// NewSecurityGroupWatcher constructs SecurityGroupWatcher.
+func NewSecurityGroupWatcher(svc Interface, accountService accountGetter,
+	interval time.Duration) *SecurityGroupWatcher {
+	if interval <= 0 {
+		interval = DefaultSecurityGroupCheckInterval
+	}
+
+	return &SecurityGroupWatcher{
+		svc:            svc,
+		accountService: accountService,
+		interval:       interval,
+		check:          checkSecurityGroups,
+	}
+}
+
+// Run checks kubes until context is done.
+func (w *SecurityGroupWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.poll(ctx)
+		}
+	}
+}
+
+func (w *SecurityGroupWatcher) poll(ctx context.Context) {
+	kubes, err := w.svc.ListAll(ctx)
+
+	if err != nil {
+		logrus.Errorf("security group watcher: list kubes %v", err)
+		return
+	}
+
+	for i := range kubes {
+		k := &kubes[i]
+
+		if k.State != model.StateOperational || !hasSecurityGroups(k) {
+			continue
+		}
+
+		if err := w.checkKube(ctx, k); err != nil {
+			logrus.Errorf("security group watcher: check kube %s %v", k.ID, err)
+		}
+	}
+}
+
+func (w *SecurityGroupWatcher) checkKube(ctx context.Context, k *model.Kube) error {
+	acc, err := w.accountService.Get(ctx, k.AccountName)
+
+	if err != nil {
+		return errors.Wrapf(err, "get cloud account %s", k.AccountName)
+	}
+
+	drift, err := w.check(ctx, k, acc, k.SecurityGroups.Enforce)
+
+	if err != nil {
+		return err
+	}
+
+	// Kube may have been changed while security groups were checked
+	_, err = retryUpdate(ctx, w.svc, k.ID, func(k *model.Kube) error {
+		setSecurityGroupDrift(k, drift)
+		return nil
+	})
+
+	return errors.Wrap(err, "update kube")
+}
+
+// setSecurityGroupDrift saves result of the check to the kube.
+func setSecurityGroupDrift(k *model.Kube, drift *model.SecurityGroupDrift) {
+	if drift.HasDrift() {
+		logrus.Warnf("Kube %s misses %d required security group rules",
+			k.ID, len(drift.MissingRules))
+	}
+
+	if drift != nil && len(drift.RestoredRules) > 0 {
+		logrus.Infof("%d security group rules of kube %s have been restored",
+			len(drift.RestoredRules), k.ID)
+	}
+
+	k.SecurityGroupDrift = drift
+}
+
+// hasSecurityGroups returns true for AWS kubes which security groups are known.
+func hasSecurityGroups(k *model.Kube) bool {
+	return k.Provider == clouds.AWS &&
+		k.CloudSpec[clouds.AwsMastersSecGroupID] != "" &&
+		k.CloudSpec[clouds.AwsNodesSecgroupID] != ""
+}
+
+func checkSecurityGroups(ctx context.Context, k *model.Kube,
+	account *model.CloudAccount, enforce bool) (*model.SecurityGroupDrift, error) {
+	config := &steps.Config{}
+	if err := util.FillCloudAccountCredentials(account, config); err != nil {
+		return nil, errors.Wrap(err, "error fill cloud account credentials")
+	}
+
+	config.AWSConfig.Region = k.Region
+	EC2, err := amazon.GetEC2(config.AWSConfig)
+
+	if err != nil {
+		return nil, errors.Wrap(sgerrors.ErrInvalidCredentials, err.Error())
+	}
+
+	return checkSecurityGroupRules(ctx, EC2, k, enforce, time.Now())
+}
+
+// checkSecurityGroupRules compares rules of kube security groups with rules
+// authorized during provisioning, rules added by users are left alone.
+func checkSecurityGroupRules(ctx context.Context, svc amazon.SecurityGroupService,
+	k *model.Kube, enforce bool, now time.Time) (*model.SecurityGroupDrift, error) {
+	required := amazon.RequiredSecurityGroupRules(steps.AWSConfig{
+		MastersSecurityGroupID: k.CloudSpec[clouds.AwsMastersSecGroupID],
+		NodesSecurityGroupID:   k.CloudSpec[clouds.AwsNodesSecgroupID],
+	}, k.ExposedAddresses, k.APIServerPort)
+
+	missing, err := amazon.MissingSecurityGroupRules(ctx, svc, required)
+
+	if err != nil {
+		return nil, err
+	}
+
+	drift := &model.SecurityGroupDrift{
+		CheckedAt:    now.Unix(),
+		MissingRules: missing,
+	}
+
+	if !enforce || len(missing) == 0 {
+		return drift, nil
+	}
+
+	if err := amazon.AuthorizeSecurityGroupRules(ctx, svc, missing); err != nil {
+		return nil, errors.Wrap(err, "restore security group rules")
+	}
+
+	drift.RestoredRules = missing
+	drift.MissingRules = make([]model.SecurityGroupRule, 0)
+
+	return drift, nil
+}
@@ -0,0 +1,286 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/testutils"
+)
+
+type fakeSecurityGroupService struct {
+	groups       []*ec2.SecurityGroup
+	authorized   []*ec2.AuthorizeSecurityGroupIngressInput
+	authorizeErr error
+}
+
+func (f *fakeSecurityGroupService) DescribeSecurityGroupsWithContext(aws.Context,
+	*ec2.DescribeSecurityGroupsInput, ...request.Option) (*ec2.DescribeSecurityGroupsOutput, error) {
+	return &ec2.DescribeSecurityGroupsOutput{
+		SecurityGroups: f.groups,
+	}, nil
+}
+
+func (f *fakeSecurityGroupService) AuthorizeSecurityGroupIngressWithContext(ctx aws.Context,
+	input *ec2.AuthorizeSecurityGroupIngressInput, opts ...request.Option) (*ec2.AuthorizeSecurityGroupIngressOutput, error) {
+	f.authorized = append(f.authorized, input)
+	return &ec2.AuthorizeSecurityGroupIngressOutput{}, f.authorizeErr
+}
+
+func securityGroupKube() *model.Kube {
+	return &model.Kube{
+		ID:          "kube-1",
+		Provider:    clouds.AWS,
+		State:       model.StateOperational,
+		AccountName: "aws",
+		CloudSpec: map[string]string{
+			clouds.AwsMastersSecGroupID: "sg-masters",
+			clouds.AwsNodesSecgroupID:   "sg-nodes",
+		},
+	}
+}
+
+// securityGroups returns groups with rules authorized during provisioning,
+// ssh of nodes has been removed.
+func securityGroups() []*ec2.SecurityGroup {
+	ssh := &ec2.IpPermission{
+		IpProtocol: aws.String("tcp"),
+		FromPort:   aws.Int64(22),
+		ToPort:     aws.Int64(22),
+		IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+	}
+	all := &ec2.IpPermission{
+		IpProtocol: aws.String("-1"),
+		UserIdGroupPairs: []*ec2.UserIdGroupPair{
+			{GroupId: aws.String("sg-masters")},
+			{GroupId: aws.String("sg-nodes")},
+		},
+	}
+
+	return []*ec2.SecurityGroup{
+		{GroupId: aws.String("sg-masters"), IpPermissions: []*ec2.IpPermission{ssh, all}},
+		{GroupId: aws.String("sg-nodes"), IpPermissions: []*ec2.IpPermission{all}},
+	}
+}
+
+func TestCheckSecurityGroupRules(t *testing.T) {
+	testCases := []struct {
+		description  string
+		enforce      bool
+		authorizeErr error
+
+		expectedMissing    int
+		expectedRestored   int
+		expectedAuthorized int
+		isErr              bool
+	}{
+		{
+			description:     "report drift",
+			expectedMissing: 1,
+		},
+		{
+			description:        "enforce",
+			enforce:            true,
+			expectedRestored:   1,
+			expectedAuthorized: 1,
+		},
+		{
+			description:        "enforce error",
+			enforce:            true,
+			authorizeErr:       errors.New("error"),
+			expectedAuthorized: 1,
+			isErr:              true,
+		},
+	}
+
+	now := time.Now()
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		svc := &fakeSecurityGroupService{
+			groups:       securityGroups(),
+			authorizeErr: testCase.authorizeErr,
+		}
+
+		drift, err := checkSecurityGroupRules(context.Background(), svc,
+			securityGroupKube(), testCase.enforce, now)
+
+		if len(svc.authorized) != testCase.expectedAuthorized {
+			t.Errorf("Wrong count of authorized rules expected %d actual %d",
+				testCase.expectedAuthorized, len(svc.authorized))
+		}
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if len(drift.MissingRules) != testCase.expectedMissing ||
+			len(drift.RestoredRules) != testCase.expectedRestored ||
+			drift.CheckedAt != now.Unix() {
+			t.Errorf("Wrong drift %v", drift)
+		}
+	}
+}
+
+func TestSecurityGroupWatcherPoll(t *testing.T) {
+	k := securityGroupKube()
+	k.SecurityGroups.Enforce = true
+
+	notOperational := securityGroupKube()
+	notOperational.ID = "kube-2"
+	notOperational.State = model.StateProvisioning
+
+	svc := new(kubeServiceMock)
+	svc.On("ListAll", mock.Anything).
+		Return([]model.Kube{*k, *notOperational}, nil)
+	svc.On(serviceGet, mock.Anything, k.ID).Return(k, nil)
+	svc.On(serviceUpdate, mock.Anything, mock.Anything).Return(nil)
+
+	accService := new(accServiceMock)
+	accService.On("Get", mock.Anything, mock.Anything).
+		Return(&model.CloudAccount{}, nil)
+
+	checked := make([]string, 0)
+	drift := &model.SecurityGroupDrift{
+		MissingRules: []model.SecurityGroupRule{{GroupID: "sg-nodes"}},
+	}
+
+	w := NewSecurityGroupWatcher(svc, accService, 0)
+	w.check = func(ctx context.Context, k *model.Kube,
+		acc *model.CloudAccount, enforce bool) (*model.SecurityGroupDrift, error) {
+		if !enforce {
+			t.Errorf("Enforce of kube %s must be used", k.ID)
+		}
+		checked = append(checked, k.ID)
+		return drift, nil
+	}
+
+	w.poll(context.Background())
+
+	if len(checked) != 1 || checked[0] != k.ID {
+		t.Errorf("Only operational kube must be checked %v", checked)
+	}
+
+	if k.SecurityGroupDrift != drift {
+		t.Errorf("Drift must be saved to kube")
+	}
+
+	svc.AssertCalled(t, serviceUpdate, mock.Anything, k)
+}
+
+func TestSyncKubeSecurityGroups(t *testing.T) {
+	testCases := []struct {
+		description string
+		query       string
+		enforce     bool
+		checkErr    error
+
+		expectedEnforce bool
+		expectedDrift   bool
+	}{
+		{
+			description:   "report drift",
+			expectedDrift: true,
+		},
+		{
+			description:     "enforce by query",
+			query:           "?enforce=true",
+			expectedEnforce: true,
+			expectedDrift:   true,
+		},
+		{
+			description:     "enforce by kube",
+			enforce:         true,
+			expectedEnforce: true,
+			expectedDrift:   true,
+		},
+		{
+			description: "check error does not fail sync",
+			checkErr:    errors.New("error"),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		k := securityGroupKube()
+		k.SecurityGroups.Enforce = testCase.enforce
+
+		svc := new(kubeServiceMock)
+		svc.On(serviceGet, mock.Anything, mock.Anything).Return(k, nil)
+		svc.On(serviceUpdate, mock.Anything, mock.Anything).Return(nil)
+
+		repo := &testutils.MockStorage{}
+		repo.On("GetAll", mock.Anything, mock.Anything).
+			Return([][]byte{}, nil)
+		repo.On("Get", mock.Anything, mock.Anything, mock.Anything).
+			Return(nil, nil)
+
+		accService := new(accServiceMock)
+		accService.On("Get", mock.Anything, mock.Anything).
+			Return(&model.CloudAccount{}, nil)
+
+		var enforced bool
+		h := Handler{
+			svc:            svc,
+			accountService: accService,
+			repo:           repo,
+			syncMachines: func(context.Context, *model.Kube, *model.CloudAccount) error {
+				return nil
+			},
+			checkSecurityGroups: func(ctx context.Context, k *model.Kube,
+				acc *model.CloudAccount, enforce bool) (*model.SecurityGroupDrift, error) {
+				enforced = enforce
+				return &model.SecurityGroupDrift{
+					MissingRules: []model.SecurityGroupRule{{GroupID: "sg-nodes"}},
+				}, testCase.checkErr
+			},
+		}
+
+		req, _ := http.NewRequest(http.MethodPost, "/kubes/kube-1/sync"+testCase.query, nil)
+		rec := httptest.NewRecorder()
+		router := mux.NewRouter()
+
+		router.HandleFunc("/kubes/{kubeID}/sync", h.syncKube)
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Wrong status code %d", rec.Code)
+			continue
+		}
+
+		if enforced != testCase.expectedEnforce {
+			t.Errorf("Wrong enforce expected %v actual %v",
+				testCase.expectedEnforce, enforced)
+		}
+
+		resp := syncResponse{}
+
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if resp.SecurityGroupDrift.HasDrift() != testCase.expectedDrift ||
+			k.SecurityGroupDrift.HasDrift() != testCase.expectedDrift {
+			t.Errorf("Wrong drift expected %v actual %v",
+				testCase.expectedDrift, resp.SecurityGroupDrift)
+		}
+	}
+}
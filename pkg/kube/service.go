@@ -2,9 +2,13 @@ package kube
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/pborman/uuid"
 	"github.com/pkg/errors"
@@ -38,6 +42,10 @@ const (
 	DefaultStoragePrefix = "/supergiant/kubes/"
 
 	releaseInstallTimeout = 300
+	// Revisions of a release tiller returns history of
+	releaseHistoryMax = 256
+	// Attempts to write a kube modified meanwhile
+	maxWriteAttempts = 5
 )
 
 var (
@@ -46,21 +54,56 @@ var (
 	_ Interface = &Service{}
 )
 
+// Filter selects kubes of a list, empty fields match all kubes.
+type Filter struct {
+	Provider string
+	// Detached kubes are matched only when their state is filtered
+	State string
+	// Name matches kubes with names containing it
+	Name string
+}
+
+// kubeHeader is the part of a kube filters are matched against.
+type kubeHeader struct {
+	ID       string          `json:"id"`
+	Name     string          `json:"name"`
+	Provider string          `json:"provider"`
+	State    model.KubeState `json:"state"`
+}
+
+func (f Filter) match(k kubeHeader) bool {
+	if f.State == "" && k.State == model.StateDetached {
+		return false
+	}
+
+	return (f.Provider == "" || f.Provider == k.Provider) &&
+		(f.State == "" || f.State == string(k.State)) &&
+		strings.Contains(k.Name, f.Name)
+}
+
 // Interface represents an interface for a kube service.
 type Interface interface {
 	Create(ctx context.Context, k *model.Kube) error
 	Get(ctx context.Context, name string) (*model.Kube, error)
+	Update(ctx context.Context, k *model.Kube) error
 	ListAll(ctx context.Context) ([]model.Kube, error)
+	List(ctx context.Context, filter Filter, offset, limit int) ([]model.Kube, int, error)
 	Delete(ctx context.Context, name string) error
 	KubeConfigFor(ctx context.Context, kname, user string) ([]byte, error)
 	ListKubeResources(ctx context.Context, kname string) ([]byte, error)
 	GetKubeResources(ctx context.Context, kname, resource, ns, name string) ([]byte, error)
 	ListNodes(ctx context.Context, k *model.Kube, role string) ([]corev1.Node, error)
 	GetCerts(ctx context.Context, kname, cname string) (*Bundle, error)
+	PrepareRelease(ctx context.Context, kname string, rls *ReleaseInput) (string, error)
 	InstallRelease(ctx context.Context, kname string, rls *ReleaseInput) (*release.Release, error)
 	ListReleases(ctx context.Context, kname, ns, offset string, limit int) ([]*model.ReleaseInfo, error)
 	ReleaseDetails(ctx context.Context, kname, rlsName string) (*release.Release, error)
+	UpgradeRelease(ctx context.Context, kname, rlsName string, rls *ReleaseInput) (*release.Release, error)
 	DeleteRelease(ctx context.Context, kname, rlsName string, purge bool) (*model.ReleaseInfo, error)
+	ReleaseHistory(ctx context.Context, kname, rlsName string) ([]*model.ReleaseRevision, error)
+	RollbackRelease(ctx context.Context, kname, rlsName string, revision int32) (*model.ReleaseInfo, error)
+	DiffRelease(ctx context.Context, kname, rlsName string, rls *ReleaseInput) (*ReleaseDiff, error)
+	Discover(ctx context.Context, k *model.Kube, force bool) (*DiscoveryResult, error)
 }
 
 // ChartGetter interface is a wrapper for GetChart function.
@@ -81,8 +124,11 @@ type Service struct {
 	prefix  string
 	storage storage.Interface
 
-	newHelmProxyFn func(kube *model.Kube) (proxy.Interface, error)
-	chrtGetter     ChartGetter
+	newHelmProxyFn   func(kube *model.Kube) (proxy.Interface, error)
+	newHelm3DriverFn func(kube *model.Kube) (proxy.Interface, error)
+	chrtGetter       ChartGetter
+
+	discovery *discoveryCache
 }
 
 // NewService constructs a Service.
@@ -91,25 +137,130 @@ func NewService(prefix string, s storage.Interface, chrtGetter ChartGetter) *Ser
 		clientForGroupFn: kubeconfig.RestClientForGroupVersion,
 		corev1ClientFn:   kubeconfig.CoreV1Client,
 		newHelmProxyFn:   helmProxyFrom,
+		newHelm3DriverFn: helm3DriverFrom,
 		chrtGetter:       chrtGetter,
 		prefix:           prefix,
 		storage:          s,
+		discovery:        newDiscoveryCache(DefaultDiscoveryTTL),
+	}
+}
+
+// SetDiscoveryTTL sets how long discovered versions of kubes are cached.
+func (s Service) SetDiscoveryTTL(ttl time.Duration) {
+	if s.discovery != nil {
+		s.discovery.setTTL(ttl)
 	}
 }
 
-// Create and stores a kube in the provided storage.
+// Create and stores a kube in the provided storage. Kube that has been
+// read from the storage is stored only when it has not been modified
+// meanwhile, sgerrors.ErrConflict is returned otherwise.
 func (s Service) Create(ctx context.Context, k *model.Kube) error {
 	if k.ID == "" {
 		k.ID = uuid.New()[:8]
 	}
 
+	if k.Revision != 0 {
+		return s.put(ctx, k, true)
+	}
+
+	var err error
+
+	// Conflicts are caused by kubes written between get and put
+	for i := 0; i < maxWriteAttempts; i++ {
+		if err = s.put(ctx, k, false); !sgerrors.IsConflict(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// Update stores the kube when its revision is the revision of the stored
+// kube, sgerrors.ErrConflict is returned when the kube has been modified.
+func (s Service) Update(ctx context.Context, k *model.Kube) error {
+	return s.put(ctx, k, true)
+}
+
+// retryUpdate gets the kube, applies update to it and stores it, the kube
+// is got and update is applied again when it has been modified meanwhile.
+func retryUpdate(ctx context.Context, svc Interface, kubeID string, update func(*model.Kube) error) (*model.Kube, error) {
+	var err error
+
+	for i := 0; i < maxWriteAttempts; i++ {
+		var k *model.Kube
+
+		if k, err = svc.Get(ctx, kubeID); err != nil {
+			return nil, err
+		}
+
+		if err = update(k); err != nil {
+			return nil, err
+		}
+
+		if err = svc.Update(ctx, k); err == nil {
+			return k, nil
+		}
+
+		if !sgerrors.IsConflict(err) {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
+
+// appendTasks adds ids of tasks of the type to the kube.
+func appendTasks(k *model.Kube, taskType string, taskIDs ...string) {
+	if k.Tasks == nil {
+		k.Tasks = make(map[string][]string)
+	}
+
+	k.Tasks[taskType] = append(k.Tasks[taskType], taskIDs...)
+}
+
+// put writes the kube comparing it to the stored one, revision of the kube
+// is set to the next revision of the stored kube.
+func (s Service) put(ctx context.Context, k *model.Kube, checkRevision bool) error {
+	old, err := s.storage.Get(ctx, s.prefix, k.ID)
+	if err != nil && !sgerrors.IsNotFound(err) {
+		return errors.Wrap(err, "storage: get")
+	}
+
+	if sgerrors.IsNotFound(err) {
+		old = nil
+	}
+
+	if checkRevision && old == nil {
+		return errors.Wrapf(sgerrors.ErrNotFound, "kube %s", k.ID)
+	}
+
+	stored := struct {
+		Revision int64 `json:"revision"`
+	}{}
+
+	if old != nil {
+		if err = json.Unmarshal(old, &stored); err != nil {
+			return errors.Wrap(err, "unmarshal")
+		}
+	}
+
+	if checkRevision && stored.Revision != k.Revision {
+		return errors.Wrapf(sgerrors.ErrConflict, "kube %s revision %d is not the stored revision %d",
+			k.ID, k.Revision, stored.Revision)
+	}
+
+	revision := k.Revision
+	k.Revision = stored.Revision + 1
+
 	raw, err := json.Marshal(k)
 	if err != nil {
+		k.Revision = revision
 		return errors.Wrap(err, "marshal")
 	}
 
-	err = s.storage.Put(ctx, s.prefix, k.ID, raw)
-	if err != nil {
+	if err = storage.CompareAndSwap(ctx, s.storage, s.prefix, k.ID, old, raw); err != nil {
+		k.Revision = revision
 		return errors.Wrap(err, "storage: put")
 	}
 
@@ -153,11 +304,92 @@ func (s Service) ListAll(ctx context.Context) ([]model.Kube, error) {
 	return kubes, nil
 }
 
+// List returns kubes matching the filter ordered by name starting
+// at the offset, all of them are returned when limit is zero. Total
+// count of matching kubes is returned along with them.
+func (s Service) List(ctx context.Context, filter Filter, offset, limit int) ([]model.Kube, int, error) {
+	rawKubes, err := s.storage.GetAll(ctx, s.prefix)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "storage: getAll")
+	}
+
+	type match struct {
+		header kubeHeader
+		raw    []byte
+	}
+
+	// Only the header is decoded to filter kubes, the rest is decoded
+	// for kubes of the page
+	matches := make([]match, 0, len(rawKubes))
+	for _, v := range rawKubes {
+		h := kubeHeader{}
+		if err = json.Unmarshal(v, &h); err != nil {
+			return nil, 0, errors.Wrap(err, "unmarshal")
+		}
+
+		if filter.match(h) {
+			matches = append(matches, match{h, v})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].header.Name != matches[j].header.Name {
+			return matches[i].header.Name < matches[j].header.Name
+		}
+		return matches[i].header.ID < matches[j].header.ID
+	})
+
+	total := len(matches)
+	if offset > total {
+		offset = total
+	}
+
+	matches = matches[offset:]
+	if limit > 0 && limit < len(matches) {
+		matches = matches[:limit]
+	}
+
+	kubes := make([]model.Kube, len(matches))
+	for i, m := range matches {
+		if err = json.Unmarshal(m.raw, &kubes[i]); err != nil {
+			return nil, 0, errors.Wrap(err, "unmarshal")
+		}
+	}
+
+	return kubes, total, nil
+}
+
 // Delete deletes a kube with a specified name.
 func (s Service) Delete(ctx context.Context, kubeID string) error {
+	if s.discovery != nil {
+		s.discovery.forget(kubeID)
+	}
+
 	return s.storage.Delete(ctx, s.prefix, kubeID)
 }
 
+// Discover returns versions of the kube discovered from its API server,
+// results are cached per kube unless refresh is forced.
+func (s Service) Discover(ctx context.Context, k *model.Kube, force bool) (*DiscoveryResult, error) {
+	if s.discovery == nil {
+		return nil, errors.Wrap(sgerrors.ErrNilEntity, "discovery cache")
+	}
+
+	kubeConfig, err := kubeconfig.AdminKubeConfig(k)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "build kubeconfig")
+	}
+
+	result := s.discovery.get(ctx, k.ID, &kubeConfig, force)
+
+	if result.Err != nil {
+		return &result, errors.Wrapf(result.Err, "discover kube %s", k.ID)
+	}
+
+	return &result, nil
+}
+
 // ListKubeResources returns raw representation of the supported kubernetes resources.
 func (s Service) ListKubeResources(ctx context.Context, kubeID string) ([]byte, error) {
 	kube, err := s.Get(ctx, kubeID)
@@ -300,7 +532,13 @@ func (s Service) InstallRelease(ctx context.Context, kubeID string, rls *Release
 	if err != nil {
 		return nil, errors.Wrap(err, "get kube")
 	}
-	kprx, err := s.helmClient(kube)
+
+	values, err := s.prepareRelease(kube, chrt, rls)
+	if err != nil {
+		return nil, err
+	}
+
+	kprx, err := s.helmClient(ctx, kube)
 	if err != nil {
 		return nil, errors.Wrap(err, "build helm proxy")
 	}
@@ -309,7 +547,7 @@ func (s Service) InstallRelease(ctx context.Context, kubeID string, rls *Release
 		chrt,
 		rls.Namespace,
 		helm.ReleaseName(ensureReleaseName(rls.Name)),
-		helm.ValueOverrides([]byte(rls.Values)),
+		helm.ValueOverrides([]byte(values)),
 		helm.InstallWait(false),
 		helm.InstallTimeout(releaseInstallTimeout),
 	)
@@ -322,7 +560,7 @@ func (s Service) ReleaseDetails(ctx context.Context, kubeID, rlsName string) (*r
 	if err != nil {
 		return nil, errors.Wrap(err, "get kube")
 	}
-	kprx, err := s.helmClient(kube)
+	kprx, err := s.helmClient(ctx, kube)
 	if err != nil {
 		return nil, errors.Wrap(err, "build helm proxy")
 	}
@@ -340,7 +578,7 @@ func (s Service) ListReleases(ctx context.Context, kubeID, namespace, offset str
 	if err != nil {
 		return nil, errors.Wrap(err, "get kube")
 	}
-	kprx, err := s.helmClient(kube)
+	kprx, err := s.helmClient(ctx, kube)
 	if err != nil {
 		return nil, errors.Wrap(err, "build helm proxy")
 	}
@@ -364,12 +602,50 @@ func (s Service) ListReleases(ctx context.Context, kubeID, namespace, offset str
 	return out, nil
 }
 
+func (s Service) UpgradeRelease(ctx context.Context, kubeID, rlsName string, rls *ReleaseInput) (*release.Release, error) {
+	if rls == nil {
+		return nil, errors.Wrap(sgerrors.ErrNilEntity, "release input")
+	}
+
+	chrt, err := s.chrtGetter.GetChart(ctx, rls.RepoName, rls.ChartName, rls.ChartVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "get chart")
+	}
+
+	values, err := releaseValues(chrt, rls)
+	if err != nil {
+		return nil, err
+	}
+
+	kube, err := s.Get(ctx, kubeID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get kube")
+	}
+	kprx, err := s.helmClient(ctx, kube)
+	if err != nil {
+		return nil, errors.Wrap(err, "build helm proxy")
+	}
+
+	rr, err := kprx.UpdateReleaseFromChart(
+		rlsName,
+		chrt,
+		helm.UpdateValueOverrides([]byte(values)),
+		helm.UpgradeWait(false),
+		helm.UpgradeTimeout(releaseInstallTimeout),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "upgrade release")
+	}
+
+	return rr.GetRelease(), nil
+}
+
 func (s Service) DeleteRelease(ctx context.Context, kubeID, rlsName string, purge bool) (*model.ReleaseInfo, error) {
 	kube, err := s.Get(ctx, kubeID)
 	if err != nil {
 		return nil, errors.Wrap(err, "get kube")
 	}
-	kprx, err := s.helmClient(kube)
+	kprx, err := s.helmClient(ctx, kube)
 	if err != nil {
 		return nil, errors.Wrap(err, "build helm proxy")
 	}
@@ -385,11 +661,119 @@ func (s Service) DeleteRelease(ctx context.Context, kubeID, rlsName string, purg
 	return toReleaseInfo(res.GetRelease()), nil
 }
 
-func (s Service) helmClient(k *model.Kube) (proxy.Interface, error) {
-	if s.newHelmProxyFn == nil {
+func (s Service) ReleaseHistory(ctx context.Context, kubeID, rlsName string) ([]*model.ReleaseRevision, error) {
+	kprx, err := s.kubeHelmClient(ctx, kubeID)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.releaseHistory(kprx, rlsName)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*model.ReleaseRevision, 0, len(revisions))
+	for _, rls := range revisions {
+		out = append(out, toReleaseRevision(rls))
+	}
+
+	return out, nil
+}
+
+// RollbackRelease rolls the release back to the revision, the new revision
+// created by the rollback is returned.
+func (s Service) RollbackRelease(ctx context.Context, kubeID, rlsName string, revision int32) (*model.ReleaseInfo, error) {
+	kprx, err := s.kubeHelmClient(ctx, kubeID)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.releaseHistory(kprx, rlsName)
+	if err != nil {
+		return nil, err
+	}
+
+	// tiller fails with an unknown error for revisions that do not exist
+	found := false
+	for _, rls := range revisions {
+		if rls.GetVersion() == revision {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, errors.Wrapf(sgerrors.ErrNotFound, "release %s revision %d", rlsName, revision)
+	}
+
+	res, err := kprx.RollbackRelease(
+		rlsName,
+		helm.RollbackVersion(revision),
+		helm.RollbackWait(false),
+		helm.RollbackTimeout(releaseInstallTimeout),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "rollback release")
+	}
+
+	return toReleaseInfo(res.GetRelease()), nil
+}
+
+func (s Service) kubeHelmClient(ctx context.Context, kubeID string) (proxy.Interface, error) {
+	kube, err := s.Get(ctx, kubeID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get kube")
+	}
+	kprx, err := s.helmClient(ctx, kube)
+	if err != nil {
+		return nil, errors.Wrap(err, "build helm proxy")
+	}
+
+	return kprx, nil
+}
+
+// releaseHistory returns revisions of the release, the latest go first.
+func (s Service) releaseHistory(kprx proxy.Interface, rlsName string) ([]*release.Release, error) {
+	res, err := kprx.ReleaseHistory(rlsName, helm.WithMaxHistory(releaseHistoryMax))
+	if err != nil {
+		return nil, errors.Wrap(err, "get release history")
+	}
+	if len(res.GetReleases()) == 0 {
+		return nil, errors.Wrapf(sgerrors.ErrNotFound, "release %s", rlsName)
+	}
+
+	return res.GetReleases(), nil
+}
+
+// helmClient returns helm 3 driver or tiller proxy by helm version of the kube.
+func (s Service) helmClient(ctx context.Context, k *model.Kube) (proxy.Interface, error) {
+	newClientFn := s.newHelmProxyFn
+
+	// Helm 3 has no tiller, releases are stored in secrets
+	if s.helmMajorVersion(ctx, k) == 3 {
+		newClientFn = s.newHelm3DriverFn
+	}
+
+	if newClientFn == nil {
 		return nil, ErrNoHelmProxy
 	}
-	return s.newHelmProxyFn(k)
+
+	return newClientFn(k)
+}
+
+// helmMajorVersion returns helm version of the kube, versions of kubes that
+// were unreachable on import are taken from cached discovery.
+func (s Service) helmMajorVersion(ctx context.Context, k *model.Kube) int {
+	if k.HelmMajorVersion != 0 || s.discovery == nil {
+		return k.HelmMajorVersion
+	}
+
+	result, err := s.Discover(ctx, k, false)
+
+	if err != nil {
+		return 0
+	}
+
+	return helmMajorVersion(result.HelmVersion)
 }
 
 func (s Service) resourcesGroupInfo(kube *model.Kube) (map[string]schema.GroupVersion, error) {
@@ -443,6 +827,21 @@ func toReleaseInfo(rls *release.Release) *model.ReleaseInfo {
 	}
 }
 
+func toReleaseRevision(rls *release.Release) *model.ReleaseRevision {
+	digest := sha256.Sum256([]byte(rls.GetConfig().GetRaw()))
+
+	return &model.ReleaseRevision{
+		Revision:     rls.GetVersion(),
+		Updated:      timeconv.String(rls.GetInfo().GetLastDeployed()),
+		Chart:        rls.GetChart().GetMetadata().GetName(),
+		ChartVersion: rls.GetChart().GetMetadata().GetVersion(),
+		AppVersion:   rls.GetChart().GetMetadata().GetAppVersion(),
+		Status:       rls.GetInfo().GetStatus().GetCode().String(),
+		Description:  rls.GetInfo().GetDescription(),
+		ValuesDigest: hex.EncodeToString(digest[:]),
+	}
+}
+
 func releaseStatuses() []release.Status_Code {
 	// TODO: filter releases by statuses on the UI side?
 	return []release.Status_Code{
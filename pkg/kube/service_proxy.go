@@ -0,0 +1,127 @@
+package kube
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/supergiant/control/pkg/api"
+	"github.com/supergiant/control/pkg/kubeconfig"
+	"github.com/supergiant/control/pkg/message"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/proxy"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// serviceProxy tunnels requests to services of kubes.
+type serviceProxy interface {
+	Serve(w http.ResponseWriter, r *http.Request, t proxy.ServiceTarget)
+	Close(kubeID string)
+}
+
+// SetServiceProxy sets proxy of in-cluster services.
+func (h *Handler) SetServiceProxy(p serviceProxy) {
+	h.serviceProxy = p
+}
+
+// proxyService proxies the request to the port of the service in the kube
+// through API server of the kube. Reading requests need view access of the
+// user, other requests need edit access.
+func (h *Handler) proxyService(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	kubeID := vars["kubeID"]
+	target := proxy.ServiceTarget{
+		KubeID:    kubeID,
+		Namespace: vars["namespace"],
+		Service:   vars["service"],
+		Port:      vars["port"],
+	}
+
+	if h.serviceProxy == nil {
+		message.SendUnknownError(w, errors.New("service proxy is not configured"))
+		return
+	}
+
+	access := api.AccessEdit
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		access = api.AccessView
+	}
+
+	if !api.HasAccess(r.Context(), access) {
+		message.SendMessage(w, message.New("Access denied",
+			fmt.Sprintf("%s access is required", access), sgerrors.Unauthorized, ""),
+			http.StatusForbidden)
+		return
+	}
+
+	if err := validateServiceTarget(target); err != nil {
+		message.SendValidationFailed(w, err)
+		return
+	}
+
+	k, err := h.svc.Get(r.Context(), kubeID)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, kubeID, err)
+			return
+		}
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if k.State == model.StateDeleting {
+		message.SendValidationFailed(w, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"kube %s is being deleted", kubeID))
+		return
+	}
+
+	target.KubeConfig, err = kubeconfig.NewConfigFor(k)
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	prefix := fmt.Sprintf("/kubes/%s/proxy/%s/%s/%s", kubeID, target.Namespace, target.Service, target.Port)
+	if i := strings.Index(r.URL.Path, prefix); i >= 0 {
+		target.Path = r.URL.Path[i+len(prefix):]
+	}
+
+	// Content type is set by the service
+	w.Header().Del("Content-Type")
+
+	h.serviceProxy.Serve(w, r, target)
+}
+
+func validateServiceTarget(t proxy.ServiceTarget) error {
+	var violations []string
+
+	for _, msg := range validation.IsDNS1123Label(t.Namespace) {
+		violations = append(violations, "namespace: "+msg)
+	}
+
+	for _, msg := range validation.IsDNS1123Label(t.Service) {
+		violations = append(violations, "service: "+msg)
+	}
+
+	if port, err := strconv.Atoi(t.Port); err == nil {
+		for _, msg := range validation.IsValidPortNum(port) {
+			violations = append(violations, "port: "+msg)
+		}
+	} else {
+		for _, msg := range validation.IsValidPortName(t.Port) {
+			violations = append(violations, "port: "+msg)
+		}
+	}
+
+	if len(violations) > 0 {
+		return errors.Wrap(sgerrors.ErrValidationFailed, strings.Join(violations, "; "))
+	}
+
+	return nil
+}
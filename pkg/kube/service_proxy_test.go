@@ -0,0 +1,165 @@
+package kube
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/api"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/proxy"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+type fakeServiceProxy struct {
+	targets []proxy.ServiceTarget
+	closed  []string
+}
+
+func (p *fakeServiceProxy) Serve(w http.ResponseWriter, r *http.Request, t proxy.ServiceTarget) {
+	p.targets = append(p.targets, t)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (p *fakeServiceProxy) Close(kubeID string) {
+	p.closed = append(p.closed, kubeID)
+}
+
+func TestHandler_proxyService(t *testing.T) {
+	testCases := []struct {
+		description string
+		method      string
+		url         string
+		accesses    []string
+		kube        *model.Kube
+		kubeErr     error
+
+		expectedCode int
+		expectedPath string
+	}{
+		{
+			description:  "no token",
+			method:       http.MethodGet,
+			url:          "/kubes/test/proxy/apps/web/80/",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			description:  "view page",
+			method:       http.MethodGet,
+			url:          "/kubes/test/proxy/apps/web/80/ui/index.html?tab=nodes",
+			accesses:     []string{api.AccessView},
+			expectedCode: http.StatusOK,
+			expectedPath: "/ui/index.html",
+		},
+		{
+			description:  "post without edit access",
+			method:       http.MethodPost,
+			url:          "/kubes/test/proxy/apps/web/80/api",
+			accesses:     []string{api.AccessView},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			description:  "post with edit access",
+			method:       http.MethodPost,
+			url:          "/kubes/test/proxy/apps/web/http/api",
+			accesses:     []string{api.AccessView, api.AccessEdit},
+			expectedCode: http.StatusOK,
+			expectedPath: "/api",
+		},
+		{
+			description:  "invalid namespace",
+			method:       http.MethodGet,
+			url:          "/kubes/test/proxy/Apps/web/80/",
+			accesses:     []string{api.AccessView},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "invalid port",
+			method:       http.MethodGet,
+			url:          "/kubes/test/proxy/apps/web/70000/",
+			accesses:     []string{api.AccessView},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "kube not found",
+			method:       http.MethodGet,
+			url:          "/kubes/test/proxy/apps/web/80/",
+			accesses:     []string{api.AccessView},
+			kubeErr:      sgerrors.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "kube is being deleted",
+			method:       http.MethodGet,
+			url:          "/kubes/test/proxy/apps/web/80/",
+			accesses:     []string{api.AccessView},
+			kube:         &model.Kube{ID: "test", State: model.StateDeleting},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		k := testCase.kube
+		if k == nil {
+			k = &model.Kube{
+				ID:    "test",
+				State: model.StateOperational,
+				Masters: map[string]*model.Machine{
+					"master": {PublicIp: "10.0.0.1", State: model.MachineStateActive},
+				},
+				APIServerPort: 443,
+			}
+		}
+
+		svc := new(kubeServiceMock)
+		svc.On("Get", mock.Anything, mock.Anything).Return(k, testCase.kubeErr)
+
+		serviceProxy := &fakeServiceProxy{}
+		h := &Handler{svc: svc}
+		h.SetServiceProxy(serviceProxy)
+
+		router := mux.NewRouter()
+		h.Register(router)
+
+		req := httptest.NewRequest(testCase.method, testCase.url, nil)
+		if testCase.accesses != nil {
+			req = req.WithContext(api.WithClaims(req.Context(), jwt.MapClaims{
+				"user_id":  "root",
+				"accesses": testCase.accesses,
+			}))
+		}
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("Wrong status code expected %d actual %d %s",
+				testCase.expectedCode, rec.Code, rec.Body.String())
+			continue
+		}
+
+		if testCase.expectedCode != http.StatusOK {
+			if len(serviceProxy.targets) != 0 {
+				t.Errorf("Request must not be proxied %v", serviceProxy.targets)
+			}
+			continue
+		}
+
+		if len(serviceProxy.targets) != 1 {
+			t.Errorf("Request must be proxied")
+			continue
+		}
+
+		target := serviceProxy.targets[0]
+		if target.KubeID != "test" || target.Namespace != "apps" || target.Service != "web" ||
+			target.Path != testCase.expectedPath || target.KubeConfig == nil {
+			t.Errorf("Wrong target %+v", target)
+		}
+	}
+}
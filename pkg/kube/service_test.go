@@ -2,7 +2,12 @@ package kube
 
 import (
 	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/pkg/errors"
@@ -24,6 +29,8 @@ import (
 	"github.com/supergiant/control/pkg/runner/ssh"
 	"github.com/supergiant/control/pkg/sgerrors"
 	"github.com/supergiant/control/pkg/sghelm/proxy"
+	"github.com/supergiant/control/pkg/storage/bolt"
+	"github.com/supergiant/control/pkg/storage/memory"
 	"github.com/supergiant/control/pkg/testutils"
 	"github.com/supergiant/control/pkg/testutils/storage"
 )
@@ -61,6 +68,9 @@ type fakeHelmProxy struct {
 	getReleaseResp    *services.GetReleaseContentResponse
 	listReleaseResp   *services.ListReleasesResponse
 	uninstReleaseResp *services.UninstallReleaseResponse
+	updateRlsResp     *services.UpdateReleaseResponse
+	historyResp       *services.GetHistoryResponse
+	rollbackResp      *services.RollbackReleaseResponse
 }
 
 func (p *fakeHelmProxy) InstallReleaseFromChart(chart *chart.Chart, namespace string, opts ...helm.InstallOption) (*services.InstallReleaseResponse, error) {
@@ -75,6 +85,15 @@ func (p *fakeHelmProxy) ReleaseContent(rlsName string, opts ...helm.ContentOptio
 func (p *fakeHelmProxy) DeleteRelease(rlsName string, opts ...helm.DeleteOption) (*services.UninstallReleaseResponse, error) {
 	return p.uninstReleaseResp, p.err
 }
+func (p *fakeHelmProxy) UpdateReleaseFromChart(rlsName string, chart *chart.Chart, opts ...helm.UpdateOption) (*services.UpdateReleaseResponse, error) {
+	return p.updateRlsResp, p.err
+}
+func (p *fakeHelmProxy) ReleaseHistory(rlsName string, opts ...helm.HistoryOption) (*services.GetHistoryResponse, error) {
+	return p.historyResp, p.err
+}
+func (p *fakeHelmProxy) RollbackRelease(rlsName string, opts ...helm.RollbackOption) (*services.RollbackReleaseResponse, error) {
+	return p.rollbackResp, p.err
+}
 
 type mockServerResourceGetter struct {
 	resources []*metav1.APIResourceList
@@ -144,6 +163,8 @@ func TestKubeServiceCreate(t *testing.T) {
 	for _, testCase := range testCases {
 		m := new(testutils.MockStorage)
 
+		m.On("Get", context.Background(), prefix, mock.Anything).
+			Return([]byte(nil), sgerrors.ErrNotFound)
 		m.On("Put",
 			context.Background(),
 			prefix,
@@ -196,6 +217,192 @@ func TestKubeServiceGetAll(t *testing.T) {
 	}
 }
 
+func TestService_Update(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubes")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	boltRepo, err := bolt.NewRepository(filepath.Join(dir, "kubes.db"))
+	require.NoError(t, err)
+	defer boltRepo.Close()
+
+	for _, repo := range []storage.Repository{memory.NewInMemoryRepository(), boltRepo} {
+		t.Logf("%T", repo)
+
+		ctx := context.Background()
+		svc := NewService(DefaultStoragePrefix, repo, nil)
+
+		require.True(t, sgerrors.IsNotFound(svc.Update(ctx, &model.Kube{ID: "kube"})),
+			"missing kube must not be updated")
+
+		require.NoError(t, svc.Create(ctx, &model.Kube{ID: "kube", Name: "kube"}))
+
+		first, err := svc.Get(ctx, "kube")
+		require.NoError(t, err)
+		second, err := svc.Get(ctx, "kube")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), first.Revision)
+
+		first.Nodes = map[string]*model.Machine{"node": {ID: "node"}}
+		require.NoError(t, svc.Update(ctx, first))
+		require.Equal(t, int64(2), first.Revision)
+
+		// Write of the stale kube would lose the added node
+		second.Name = "renamed"
+		err = svc.Update(ctx, second)
+		require.True(t, sgerrors.IsConflict(err), "stale kube must conflict %v", err)
+		require.Equal(t, int64(1), second.Revision)
+
+		stored, err := svc.Get(ctx, "kube")
+		require.NoError(t, err)
+		require.Equal(t, "kube", stored.Name)
+		require.NotNil(t, stored.Nodes["node"])
+
+		// Stale kube written by Create loses to the added node as well
+		err = svc.Create(ctx, second)
+		require.True(t, sgerrors.IsConflict(err), "stale kube must conflict %v", err)
+
+		stored, err = svc.Get(ctx, "kube")
+		require.NoError(t, err)
+		require.NotNil(t, stored.Nodes["node"])
+
+		// Kube that has not been read replaces the stored one
+		require.NoError(t, svc.Create(ctx, &model.Kube{ID: "kube", Name: "new"}))
+		require.True(t, sgerrors.IsConflict(svc.Update(ctx, first)))
+	}
+}
+
+func TestRetryUpdate(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(DefaultStoragePrefix, memory.NewInMemoryRepository(), nil)
+
+	require.NoError(t, svc.Create(ctx, &model.Kube{ID: "kube"}))
+
+	attempts := 0
+	k, err := retryUpdate(ctx, svc, "kube", func(k *model.Kube) error {
+		attempts++
+
+		if attempts == 1 {
+			// Machines are synced while the kube is renamed
+			renamed, err := svc.Get(ctx, "kube")
+			require.NoError(t, err)
+			renamed.Name = "renamed"
+			require.NoError(t, svc.Update(ctx, renamed))
+		}
+
+		k.Nodes = map[string]*model.Machine{"node": {ID: "node"}}
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 2, attempts, "kube must be reloaded on conflict")
+	require.Equal(t, "renamed", k.Name)
+
+	stored, err := svc.Get(ctx, "kube")
+	require.NoError(t, err)
+	require.Equal(t, "renamed", stored.Name)
+	require.NotNil(t, stored.Nodes["node"])
+
+	// Kube modified by each attempt is not updated
+	attempts = 0
+	_, err = retryUpdate(ctx, svc, "kube", func(k *model.Kube) error {
+		attempts++
+		require.NoError(t, svc.Create(ctx, &model.Kube{ID: "kube"}))
+		return nil
+	})
+	require.True(t, sgerrors.IsConflict(err))
+	require.Equal(t, maxWriteAttempts, attempts)
+}
+
+func TestService_List(t *testing.T) {
+	data := [][]byte{
+		[]byte(`{"id":"3","name":"prod-b","provider":"aws","state":"operational"}`),
+		[]byte(`{"id":"1","name":"dev","provider":"aws","state":"operational"}`),
+		[]byte(`{"id":"2","name":"prod-a","provider":"gce","state":"provisioning"}`),
+		[]byte(`{"id":"4","name":"prod-a","provider":"aws","state":"failed"}`),
+		[]byte(`{"id":"5","name":"handed-over","provider":"aws","state":"detached"}`),
+	}
+
+	testCases := []struct {
+		description string
+		filter      Filter
+		offset      int
+		limit       int
+		expectedIDs []string
+		total       int
+	}{
+		{
+			description: "all kubes ordered by name",
+			expectedIDs: []string{"1", "2", "4", "3"},
+			total:       4,
+		},
+		{
+			description: "provider",
+			filter:      Filter{Provider: "aws"},
+			expectedIDs: []string{"1", "4", "3"},
+			total:       3,
+		},
+		{
+			description: "state and name",
+			filter:      Filter{State: "operational", Name: "prod"},
+			expectedIDs: []string{"3"},
+			total:       1,
+		},
+		{
+			description: "page",
+			filter:      Filter{Name: "prod"},
+			offset:      1,
+			limit:       1,
+			expectedIDs: []string{"4"},
+			total:       3,
+		},
+		{
+			description: "detached kubes",
+			filter:      Filter{State: "detached"},
+			expectedIDs: []string{"5"},
+			total:       1,
+		},
+		{
+			description: "offset past the end",
+			offset:      10,
+			expectedIDs: []string{},
+			total:       4,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		m := new(testutils.MockStorage)
+		m.On("GetAll", context.Background(), DefaultStoragePrefix).Return(data, nil)
+
+		service := NewService(DefaultStoragePrefix, m, nil)
+
+		kubes, total, err := service.List(context.Background(), testCase.filter, testCase.offset, testCase.limit)
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		ids := make([]string, 0, len(kubes))
+		for _, k := range kubes {
+			ids = append(ids, k.ID)
+		}
+
+		if !reflect.DeepEqual(testCase.expectedIDs, ids) || total != testCase.total {
+			t.Errorf("Expected kubes %v of %d actual %v of %d",
+				testCase.expectedIDs, testCase.total, ids, total)
+		}
+	}
+
+	m := new(testutils.MockStorage)
+	m.On("GetAll", context.Background(), DefaultStoragePrefix).Return([][]byte(nil), errors.New("test err"))
+
+	if _, _, err := NewService(DefaultStoragePrefix, m, nil).List(context.Background(), Filter{}, 0, 0); err == nil {
+		t.Errorf("Expected storage error")
+	}
+}
+
 func TestService_InstallRelease(t *testing.T) {
 	tcs := []struct {
 		svc Service
@@ -513,6 +720,286 @@ func TestService_DeleteRelease(t *testing.T) {
 	}
 }
 
+func TestService_UpgradeRelease(t *testing.T) {
+	tcs := []struct {
+		rlsInput *ReleaseInput
+		svc      Service
+
+		expectedRes *release.Release
+		expectedErr error
+	}{
+		{ // TC#1
+			svc:         Service{},
+			expectedErr: sgerrors.ErrNilEntity,
+		},
+		{ // TC#2
+			rlsInput: &ReleaseInput{},
+			svc: Service{
+				chrtGetter: &fakeChartGetter{
+					err: errFake,
+				},
+			},
+			expectedErr: errFake,
+		},
+		{ // TC#3
+			rlsInput: &ReleaseInput{},
+			svc: Service{
+				chrtGetter: &fakeChartGetter{},
+				storage: &storage.Fake{
+					Item: []byte("{}"),
+				},
+				newHelmProxyFn: func(kube *model.Kube) (proxy.Interface, error) {
+					return &fakeHelmProxy{
+						err: errFake,
+					}, nil
+				},
+			},
+			expectedErr: errFake,
+		},
+		{ // TC#4
+			rlsInput: &ReleaseInput{},
+			svc: Service{
+				chrtGetter: &fakeChartGetter{},
+				storage: &storage.Fake{
+					Item: []byte("{}"),
+				},
+				newHelmProxyFn: func(kube *model.Kube) (proxy.Interface, error) {
+					return &fakeHelmProxy{
+						updateRlsResp: &services.UpdateReleaseResponse{
+							Release: fakeRls,
+						},
+					}, nil
+				},
+			},
+			expectedRes: fakeRls,
+		},
+	}
+
+	for i, tc := range tcs {
+		rls, err := tc.svc.UpgradeRelease(context.Background(), "fake", "fakeRelease", tc.rlsInput)
+		require.Equalf(t, tc.expectedErr, errors.Cause(err), "TC#%d: check errors", i+1)
+
+		if err == nil {
+			require.Equalf(t, tc.expectedRes, rls, "TC#%d: check results", i+1)
+		}
+	}
+}
+
+func TestService_ReleaseHistory(t *testing.T) {
+	revision := &release.Release{
+		Name:    "fakeRelease",
+		Version: 2,
+		Config:  &chart.Config{Raw: "replicas: 3"},
+		Info: &release.Info{
+			LastDeployed: &timestamp.Timestamp{},
+			Status:       &release.Status{Code: release.Status_DEPLOYED},
+			Description:  "Upgrade complete",
+		},
+		Chart: &chart.Chart{
+			Metadata: &chart.Metadata{Name: "nginx", Version: "1.0.0", AppVersion: "1.17"},
+		},
+	}
+
+	tcs := []struct {
+		proxy *fakeHelmProxy
+
+		expectedRes []*model.ReleaseRevision
+		expectedErr error
+	}{
+		{ // TC#1
+			proxy:       &fakeHelmProxy{err: errFake},
+			expectedErr: errFake,
+		},
+		{ // TC#2
+			proxy:       &fakeHelmProxy{historyResp: &services.GetHistoryResponse{}},
+			expectedErr: sgerrors.ErrNotFound,
+		},
+		{ // TC#3
+			proxy: &fakeHelmProxy{
+				historyResp: &services.GetHistoryResponse{
+					Releases: []*release.Release{revision},
+				},
+			},
+			expectedRes: []*model.ReleaseRevision{
+				{
+					Revision:     2,
+					Updated:      timeconv.String(&timestamp.Timestamp{}),
+					Chart:        "nginx",
+					ChartVersion: "1.0.0",
+					AppVersion:   "1.17",
+					Status:       "DEPLOYED",
+					Description:  "Upgrade complete",
+					ValuesDigest: "776530bb9f97f489746afe927013f7b659ac108e425ca071bb13efe63c130f98",
+				},
+			},
+		},
+	}
+
+	for i, tc := range tcs {
+		svc := Service{
+			storage: &storage.Fake{
+				Item: []byte("{}"),
+			},
+			newHelmProxyFn: func(kube *model.Kube) (proxy.Interface, error) {
+				return tc.proxy, nil
+			},
+		}
+
+		revisions, err := svc.ReleaseHistory(context.Background(), "fake", "fakeRelease")
+		require.Equalf(t, tc.expectedErr, errors.Cause(err), "TC#%d: check errors", i+1)
+
+		if err == nil {
+			require.Equalf(t, tc.expectedRes, revisions, "TC#%d: check results", i+1)
+		}
+	}
+}
+
+func TestService_RollbackRelease(t *testing.T) {
+	history := &services.GetHistoryResponse{
+		Releases: []*release.Release{
+			{Name: "fakeRelease", Version: 2},
+			{Name: "fakeRelease", Version: 1},
+		},
+	}
+	rolledBack := &release.Release{
+		Name:    "fakeRelease",
+		Version: 3,
+		Info: &release.Info{
+			FirstDeployed: &timestamp.Timestamp{},
+			LastDeployed:  &timestamp.Timestamp{},
+			Status:        &release.Status{Code: release.Status_DEPLOYED},
+		},
+		Chart: &chart.Chart{
+			Metadata: &chart.Metadata{Name: "nginx"},
+		},
+	}
+
+	tcs := []struct {
+		revision int32
+		proxy    *fakeHelmProxy
+
+		expectedVersion int32
+		expectedErr     error
+	}{
+		{ // TC#1
+			revision:    1,
+			proxy:       &fakeHelmProxy{err: errFake},
+			expectedErr: errFake,
+		},
+		{ // TC#2
+			revision:    5,
+			proxy:       &fakeHelmProxy{historyResp: history},
+			expectedErr: sgerrors.ErrNotFound,
+		},
+		{ // TC#3
+			revision: 1,
+			proxy: &fakeHelmProxy{
+				historyResp: history,
+				rollbackResp: &services.RollbackReleaseResponse{
+					Release: rolledBack,
+				},
+			},
+			expectedVersion: 3,
+		},
+	}
+
+	for i, tc := range tcs {
+		svc := Service{
+			storage: &storage.Fake{
+				Item: []byte("{}"),
+			},
+			newHelmProxyFn: func(kube *model.Kube) (proxy.Interface, error) {
+				return tc.proxy, nil
+			},
+		}
+
+		rlsInfo, err := svc.RollbackRelease(context.Background(), "fake", "fakeRelease", tc.revision)
+		require.Equalf(t, tc.expectedErr, errors.Cause(err), "TC#%d: check errors", i+1)
+
+		if err == nil {
+			require.Equalf(t, tc.expectedVersion, rlsInfo.Version, "TC#%d: check new revision", i+1)
+		}
+	}
+}
+
+func TestService_HelmClient(t *testing.T) {
+	tiller, helm3 := &fakeHelmProxy{}, &fakeHelmProxy{}
+	discovered := newDiscoveryCache(time.Hour)
+	discovered.results["fake"] = DiscoveryResult{
+		HelmVersion: Helm3,
+		CheckedAt:   time.Now(),
+	}
+
+	tcs := []struct {
+		description string
+		kube        *model.Kube
+		discovery   *discoveryCache
+		helm3Fn     bool
+
+		expected    proxy.Interface
+		expectedErr error
+	}{
+		{
+			description: "tiller",
+			kube:        &model.Kube{ID: "fake", HelmMajorVersion: 2},
+			discovery:   discovered,
+			helm3Fn:     true,
+			expected:    tiller,
+		},
+		{
+			description: "helm 3",
+			kube:        &model.Kube{ID: "fake", HelmMajorVersion: 3},
+			helm3Fn:     true,
+			expected:    helm3,
+		},
+		{
+			description: "discovered helm 3",
+			kube:        &model.Kube{ID: "fake", ExternalDNSName: "fake.test"},
+			discovery:   discovered,
+			helm3Fn:     true,
+			expected:    helm3,
+		},
+		{
+			description: "unknown helm version",
+			kube:        &model.Kube{ID: "fake"},
+			helm3Fn:     true,
+			expected:    tiller,
+		},
+		{
+			description: "no helm 3 driver",
+			kube:        &model.Kube{ID: "fake", HelmMajorVersion: 3},
+			expectedErr: ErrNoHelmProxy,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Log(tc.description)
+
+		svc := Service{
+			discovery: tc.discovery,
+			newHelmProxyFn: func(kube *model.Kube) (proxy.Interface, error) {
+				return tiller, nil
+			},
+		}
+
+		if tc.helm3Fn {
+			svc.newHelm3DriverFn = func(kube *model.Kube) (proxy.Interface, error) {
+				return helm3, nil
+			}
+		}
+
+		client, err := svc.helmClient(context.Background(), tc.kube)
+
+		if errors.Cause(err) != tc.expectedErr {
+			t.Errorf("Expected error %v actual %v", tc.expectedErr, err)
+		}
+
+		if client != tc.expected {
+			t.Errorf("Wrong helm client %v", client)
+		}
+	}
+}
+
 func TestService_Delete(t *testing.T) {
 	testCases := []struct {
 		repoErr error
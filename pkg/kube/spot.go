@@ -0,0 +1,205 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/service/pricing"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/clouds/awssdk"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+// Suggested bid is p90 of the price history plus the margin.
+const spotBidMargin = 0.1
+
+// SpotPriceRecommendation contains spot price statistics for the last week
+// and the bid suggested for the spot request.
+type SpotPriceRecommendation struct {
+	MachineType      string  `json:"machineType"`
+	AvailabilityZone string  `json:"availabilityZone,omitempty"`
+	P50              float64 `json:"p50"`
+	P90              float64 `json:"p90"`
+	Max              float64 `json:"max"`
+	Bid              float64 `json:"bid"`
+	// OnDemandPrice is zero when it can not be obtained from pricing API.
+	OnDemandPrice float64 `json:"onDemandPrice,omitempty"`
+}
+
+type productsGetter interface {
+	GetProducts(*pricing.GetProductsInput) (*pricing.GetProductsOutput, error)
+}
+
+// RecommendSpotPrice suggests the bid for machine type based on the spot
+// price history, history of all zones of the region is used when az is empty.
+func RecommendSpotPrice(ctx context.Context, machineType, az string,
+	config *steps.Config) (*SpotPriceRecommendation, error) {
+	prices, err := getSpotPrices(ctx, machineType, az, config)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "get spot prices")
+	}
+
+	recommendation, err := recommendFromPrices(prices)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "recommend spot price for %s", machineType)
+	}
+
+	recommendation.MachineType = machineType
+	recommendation.AvailabilityZone = az
+
+	if config.Provider == clouds.AWS {
+		onDemandPrice, err := getAwsOnDemandPrice(machineType, config)
+
+		if err != nil {
+			logrus.Debugf("get on-demand price for %s %v", machineType, err)
+		}
+
+		recommendation.OnDemandPrice = onDemandPrice
+	}
+
+	return recommendation, nil
+}
+
+func recommendFromPrices(prices map[string][]SpotPricePoint) (*SpotPriceRecommendation, error) {
+	values := make([]float64, 0)
+
+	for _, points := range prices {
+		for _, point := range points {
+			values = append(values, point.Price)
+		}
+	}
+
+	if len(values) == 0 {
+		return nil, errors.Wrap(sgerrors.ErrNotFound, "spot price history is empty")
+	}
+
+	sort.Float64s(values)
+	p90 := percentile(values, 90)
+
+	return &SpotPriceRecommendation{
+		P50: percentile(values, 50),
+		P90: p90,
+		Max: values[len(values)-1],
+		Bid: roundPrice(p90 * (1 + spotBidMargin)),
+	}, nil
+}
+
+// percentile uses nearest rank method, values must be sorted.
+func percentile(values []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(values))))
+
+	if rank < 1 {
+		rank = 1
+	}
+
+	return values[rank-1]
+}
+
+// AWS accepts spot price with up to 5 decimal digits
+func roundPrice(price float64) float64 {
+	return math.Round(price*1e5) / 1e5
+}
+
+func getAwsOnDemandPrice(machineType string, config *steps.Config) (float64, error) {
+	// Pricing API is available only in a few regions
+	sess, err := awssdk.NewSession(endpoints.UsEast1RegionID,
+		util.AWSCredentials(config.AWSConfig))
+
+	if err != nil {
+		return 0, errors.Wrap(err, "create session")
+	}
+
+	return describeOnDemandPrice(pricing.New(sess), machineType, config.AWSConfig.Region)
+}
+
+func describeOnDemandPrice(svc productsGetter, machineType, region string) (float64, error) {
+	location, ok := endpoints.AwsPartition().Regions()[region]
+
+	if !ok {
+		return 0, errors.Wrapf(sgerrors.ErrNotFound, "region %s", region)
+	}
+
+	filter := func(field, value string) *pricing.Filter {
+		return &pricing.Filter{
+			Field: aws.String(field),
+			Type:  aws.String(pricing.FilterTypeTermMatch),
+			Value: aws.String(value),
+		}
+	}
+
+	out, err := svc.GetProducts(&pricing.GetProductsInput{
+		ServiceCode:   aws.String("AmazonEC2"),
+		FormatVersion: aws.String("aws_v1"),
+		Filters: []*pricing.Filter{
+			filter("instanceType", machineType),
+			filter("location", location.Description()),
+			filter("operatingSystem", "Linux"),
+			filter("tenancy", "Shared"),
+			filter("preInstalledSw", "NA"),
+			filter("capacitystatus", "Used"),
+		},
+		MaxResults: aws.Int64(1),
+	})
+
+	if err != nil {
+		return 0, errors.Wrap(err, "get products")
+	}
+
+	for _, product := range out.PriceList {
+		price, err := onDemandPriceFromProduct(product)
+
+		if err != nil {
+			return 0, err
+		}
+
+		return price, nil
+	}
+
+	return 0, errors.Wrapf(sgerrors.ErrNotFound, "on-demand price for %s", machineType)
+}
+
+type priceListItem struct {
+	Terms struct {
+		OnDemand map[string]struct {
+			PriceDimensions map[string]struct {
+				PricePerUnit map[string]string `json:"pricePerUnit"`
+			} `json:"priceDimensions"`
+		} `json:"OnDemand"`
+	} `json:"terms"`
+}
+
+func onDemandPriceFromProduct(product aws.JSONValue) (float64, error) {
+	data, err := json.Marshal(product)
+
+	if err != nil {
+		return 0, errors.Wrap(err, "marshal product")
+	}
+
+	item := &priceListItem{}
+
+	if err := json.Unmarshal(data, item); err != nil {
+		return 0, errors.Wrap(err, "unmarshal product")
+	}
+
+	for _, term := range item.Terms.OnDemand {
+		for _, dimension := range term.PriceDimensions {
+			if usd, ok := dimension.PricePerUnit["USD"]; ok {
+				return strconv.ParseFloat(usd, 64)
+			}
+		}
+	}
+
+	return 0, errors.Wrap(sgerrors.ErrNotFound, "on-demand price")
+}
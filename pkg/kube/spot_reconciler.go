@@ -0,0 +1,230 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+)
+
+const (
+	DefaultSpotReconcileInterval = time.Minute
+
+	// Capacity request that has not completed within the timeout is
+	// considered lost, e.g. when control has been restarted.
+	spotCapacityRequestTimeout = 30 * time.Minute
+
+	spotGroupMinBackoff = time.Minute
+	spotGroupMaxBackoff = time.Hour
+)
+
+// SpotCapacityRequester submits spot request of the group for count nodes.
+type SpotCapacityRequester func(ctx context.Context, k *model.Kube, group *model.SpotGroup, count int64) error
+
+// SpotReconciler requests spot instances again for spot groups which
+// nodes have been interrupted.
+type SpotReconciler struct {
+	svc      Interface
+	request  SpotCapacityRequester
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewSpotReconciler constructs SpotReconciler.
+func NewSpotReconciler(svc Interface, request SpotCapacityRequester,
+	interval time.Duration) *SpotReconciler {
+	if interval <= 0 {
+		interval = DefaultSpotReconcileInterval
+	}
+
+	return &SpotReconciler{
+		svc:      svc,
+		request:  request,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Run reconciles spot groups until context is done.
+func (r *SpotReconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.reconcile(ctx)
+		}
+	}
+}
+
+func (r *SpotReconciler) reconcile(ctx context.Context) {
+	kubes, err := r.svc.ListAll(ctx)
+
+	if err != nil {
+		logrus.Errorf("spot reconciler: list kubes %v", err)
+		return
+	}
+
+	for i := range kubes {
+		k := &kubes[i]
+
+		if k.Provider != clouds.AWS || k.State != model.StateOperational {
+			continue
+		}
+
+		for _, group := range k.SpotGroups {
+			if err := r.reconcileGroup(ctx, k, group); err != nil {
+				logrus.Errorf("spot reconciler: group %s of kube %s %v", group.ID, k.ID, err)
+			}
+		}
+	}
+}
+
+func (r *SpotReconciler) reconcileGroup(ctx context.Context, k *model.Kube, group *model.SpotGroup) error {
+	now := r.now()
+
+	if group == nil || !group.Maintain || isSpotRequestInProgress(group, now) ||
+		now.Unix() < group.RetryAt {
+		return nil
+	}
+
+	if expired, err := isSpotGroupExpired(group, now); err != nil || expired {
+		return err
+	}
+
+	missing := group.DesiredCount - countSpotGroupNodes(k, group.ID)
+
+	if missing <= 0 {
+		return nil
+	}
+
+	logrus.Infof("Request %d spot nodes of group %s of kube %s", missing, group.ID, k.ID)
+
+	// Request is saved as in progress along with its task, so it is not
+	// submitted again after restart.
+	group.RequestedAt = now.Unix()
+
+	if err := r.request(ctx, k, group, missing); err != nil {
+		completeSpotRequest(group, true, now)
+
+		_, updateErr := retryUpdate(ctx, r.svc, k.ID, func(k *model.Kube) error {
+			if latest := k.SpotGroups[group.ID]; latest != nil {
+				*latest = *group
+			}
+
+			return nil
+		})
+
+		if updateErr != nil {
+			logrus.Errorf("update kube %s %v", k.ID, updateErr)
+		}
+
+		return errors.Wrapf(err, "request %d spot nodes", missing)
+	}
+
+	return nil
+}
+
+// newSpotGroup creates group maintaining machine count of spot request,
+// the request task started along with the group is in progress, so the
+// reconciler does not submit it again before the task completes.
+func newSpotGroup(req *SpotRequest, now time.Time) (*model.SpotGroup, error) {
+	raw, err := json.Marshal(req)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal spot request")
+	}
+
+	return &model.SpotGroup{
+		ID:           uuid.New()[:8],
+		DesiredCount: req.MachineCount,
+		Maintain:     true,
+		Request:      raw,
+		RequestedAt:  now.Unix(),
+	}, nil
+}
+
+// completeSpotRequest finishes capacity request of the group, failed
+// requests are retried with exponential backoff.
+func completeSpotRequest(group *model.SpotGroup, failed bool, now time.Time) {
+	group.RequestedAt = 0
+
+	if !failed {
+		group.Failures = 0
+		group.RetryAt = 0
+		return
+	}
+
+	group.Failures++
+	backoff := spotGroupMinBackoff
+
+	for i := 1; i < group.Failures && backoff < spotGroupMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > spotGroupMaxBackoff {
+		backoff = spotGroupMaxBackoff
+	}
+
+	group.RetryAt = now.Add(backoff).Unix()
+}
+
+// releaseSpotNode decreases desired count of the group node belongs
+// to, so nodes deleted by user are not requested again.
+func releaseSpotNode(k *model.Kube, machine *model.Machine) {
+	if machine == nil || machine.SpotGroupID == "" {
+		return
+	}
+
+	group := k.SpotGroups[machine.SpotGroupID]
+
+	if group != nil && group.DesiredCount > 0 {
+		group.DesiredCount--
+	}
+}
+
+func isSpotRequestInProgress(group *model.SpotGroup, now time.Time) bool {
+	return group.RequestedAt > 0 &&
+		now.Sub(time.Unix(group.RequestedAt, 0)) < spotCapacityRequestTimeout
+}
+
+// isSpotGroupExpired returns true when spot request of the group
+// is no longer valid.
+func isSpotGroupExpired(group *model.SpotGroup, now time.Time) (bool, error) {
+	req := &SpotRequest{}
+
+	if err := json.Unmarshal(group.Request, req); err != nil {
+		return false, errors.Wrap(err, "unmarshal spot request")
+	}
+
+	return req.ValidUntil != nil && req.ValidUntil.Sub(now) < minSpotRequestDuration, nil
+}
+
+// countSpotGroupNodes counts nodes of the group that are running
+// or being provisioned.
+func countSpotGroupNodes(k *model.Kube, groupID string) int64 {
+	var count int64
+
+	for _, machine := range k.Nodes {
+		if machine == nil || machine.SpotGroupID != groupID {
+			continue
+		}
+
+		switch machine.State {
+		case model.MachineStateInterrupting, model.MachineStateDeleting, model.MachineStateError:
+		default:
+			count++
+		}
+	}
+
+	return count
+}
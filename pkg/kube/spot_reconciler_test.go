@@ -0,0 +1,296 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+)
+
+func spotGroupKube(t *testing.T, validUntil time.Time) *model.Kube {
+	group, err := newSpotGroup(&SpotRequest{
+		MachineType:  "m4.large",
+		SpotPrice:    "0.05",
+		MachineCount: 3,
+		ValidUntil:   aws.Time(validUntil),
+	}, time.Now())
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	group.ID = "group-1"
+	// Task of the spot request has completed
+	completeSpotRequest(group, false, time.Now())
+
+	return &model.Kube{
+		ID:       "kube-1",
+		State:    model.StateOperational,
+		Provider: clouds.AWS,
+		Nodes: map[string]*model.Machine{
+			"node-1": {
+				Name:        "node-1",
+				State:       model.MachineStateActive,
+				SpotGroupID: group.ID,
+			},
+			"node-2": {
+				Name:        "node-2",
+				State:       model.MachineStateInterrupting,
+				SpotGroupID: group.ID,
+			},
+			"node-3": {
+				Name:  "node-3",
+				State: model.MachineStateActive,
+			},
+		},
+		SpotGroups: map[string]*model.SpotGroup{
+			group.ID: group,
+		},
+	}
+}
+
+func TestSpotReconcilerReconcileGroup(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		description string
+		update      func(*model.Kube)
+		requestErr  error
+
+		expectedCount    int64
+		expectedErr      bool
+		expectedFailures int
+	}{
+		{
+			description:   "request missing nodes",
+			expectedCount: 2,
+		},
+		{
+			description: "not maintained",
+			update: func(k *model.Kube) {
+				k.SpotGroups["group-1"].Maintain = false
+			},
+		},
+		{
+			description: "request in progress",
+			update: func(k *model.Kube) {
+				k.SpotGroups["group-1"].RequestedAt = now.Add(-time.Minute).Unix()
+			},
+		},
+		{
+			description: "lost request",
+			update: func(k *model.Kube) {
+				k.SpotGroups["group-1"].RequestedAt = now.Add(-time.Hour).Unix()
+			},
+			expectedCount: 2,
+		},
+		{
+			description: "backoff",
+			update: func(k *model.Kube) {
+				k.SpotGroups["group-1"].RetryAt = now.Add(time.Minute).Unix()
+			},
+		},
+		{
+			description: "nodes deleted by user",
+			update: func(k *model.Kube) {
+				releaseSpotNode(k, k.Nodes["node-2"])
+				releaseSpotNode(k, k.Nodes["node-3"])
+				delete(k.Nodes, "node-2")
+			},
+			expectedCount: 1,
+		},
+		{
+			description: "request error",
+			requestErr:  errors.New("error"),
+			expectedErr: true,
+
+			expectedCount:    2,
+			expectedFailures: 1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		k := spotGroupKube(t, now.Add(time.Hour))
+
+		if testCase.update != nil {
+			testCase.update(k)
+		}
+
+		svc := new(kubeServiceMock)
+		svc.On(serviceGet, mock.Anything, k.ID).Return(k, nil)
+		svc.On(serviceUpdate, mock.Anything, mock.Anything).Return(nil)
+
+		var requested int64
+		r := NewSpotReconciler(svc, func(ctx context.Context, k *model.Kube,
+			group *model.SpotGroup, count int64) error {
+			if group.RequestedAt == 0 {
+				t.Errorf("Request must be marked in progress")
+			}
+
+			requested = count
+			return testCase.requestErr
+		}, 0)
+		r.now = func() time.Time {
+			return now
+		}
+
+		group := k.SpotGroups["group-1"]
+		err := r.reconcileGroup(context.Background(), k, group)
+
+		if testCase.expectedErr != (err != nil) {
+			t.Errorf("Wrong error %v", err)
+		}
+
+		if requested != testCase.expectedCount {
+			t.Errorf("Wrong count of requested nodes expected %d actual %d",
+				testCase.expectedCount, requested)
+		}
+
+		if group.Failures != testCase.expectedFailures {
+			t.Errorf("Wrong failures expected %d actual %d",
+				testCase.expectedFailures, group.Failures)
+		}
+
+		if testCase.expectedFailures > 0 {
+			if group.RequestedAt != 0 || group.RetryAt <= now.Unix() {
+				t.Errorf("Failed request must be retried later %v", group)
+			}
+
+			svc.AssertCalled(t, serviceUpdate, mock.Anything, k)
+		}
+	}
+}
+
+func TestSpotReconcilerExpiredGroup(t *testing.T) {
+	now := time.Now()
+	k := spotGroupKube(t, now.Add(time.Second))
+	called := false
+
+	r := NewSpotReconciler(nil, func(context.Context, *model.Kube, *model.SpotGroup, int64) error {
+		called = true
+		return nil
+	}, 0)
+	r.now = func() time.Time {
+		return now
+	}
+
+	if err := r.reconcileGroup(context.Background(), k, k.SpotGroups["group-1"]); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	if called {
+		t.Errorf("Expired spot group must not be requested")
+	}
+}
+
+func TestSpotReconcilerNewGroup(t *testing.T) {
+	now := time.Now()
+	group, err := newSpotGroup(&SpotRequest{
+		MachineType:  "m4.large",
+		SpotPrice:    "0.05",
+		MachineCount: 3,
+		ValidUntil:   aws.Time(now.Add(time.Hour)),
+	}, now)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	k := &model.Kube{
+		ID:         "kube-1",
+		State:      model.StateOperational,
+		Provider:   clouds.AWS,
+		SpotGroups: map[string]*model.SpotGroup{group.ID: group},
+	}
+
+	svc := new(kubeServiceMock)
+	svc.On(serviceListAll, mock.Anything).Return([]model.Kube{*k}, nil)
+
+	called := false
+	r := NewSpotReconciler(svc, func(context.Context, *model.Kube, *model.SpotGroup, int64) error {
+		called = true
+		return nil
+	}, 0)
+	r.now = func() time.Time {
+		return now.Add(DefaultSpotReconcileInterval)
+	}
+
+	r.reconcile(context.Background())
+
+	if called {
+		t.Errorf("Group must not be requested again while its task is running")
+	}
+}
+
+func TestCompleteSpotRequest(t *testing.T) {
+	now := time.Now()
+	group := &model.SpotGroup{
+		RequestedAt: now.Unix(),
+	}
+
+	expected := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute}
+
+	for _, backoff := range expected {
+		completeSpotRequest(group, true, now)
+
+		if group.RetryAt != now.Add(backoff).Unix() {
+			t.Errorf("Wrong backoff expected %v actual %v", backoff,
+				time.Unix(group.RetryAt, 0).Sub(now))
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		completeSpotRequest(group, true, now)
+	}
+
+	if group.RetryAt != now.Add(spotGroupMaxBackoff).Unix() {
+		t.Errorf("Backoff must not exceed %v", spotGroupMaxBackoff)
+	}
+
+	completeSpotRequest(group, false, now)
+
+	if group.Failures != 0 || group.RetryAt != 0 || group.RequestedAt != 0 {
+		t.Errorf("Successful request must reset backoff %v", group)
+	}
+}
+
+func TestNewSpotGroup(t *testing.T) {
+	req := &SpotRequest{
+		MachineType:   "m4.large",
+		MachineCount:  2,
+		MaintainCount: true,
+	}
+
+	now := time.Now()
+	group, err := newSpotGroup(req, now)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if group.ID == "" || group.DesiredCount != 2 || !group.Maintain {
+		t.Errorf("Wrong spot group %v", group)
+	}
+
+	if group.RequestedAt != now.Unix() {
+		t.Errorf("Request of the new group must be in progress %v", group)
+	}
+
+	stored := &SpotRequest{}
+
+	if err := json.Unmarshal(group.Request, stored); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if stored.MachineType != req.MachineType {
+		t.Errorf("Wrong stored request %v", stored)
+	}
+}
@@ -0,0 +1,184 @@
+package kube
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/pricing"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+func pricePoints(prices ...float64) []SpotPricePoint {
+	points := make([]SpotPricePoint, 0, len(prices))
+
+	for i, price := range prices {
+		points = append(points, SpotPricePoint{
+			Timestamp: time.Unix(int64(i), 0),
+			Price:     price,
+		})
+	}
+
+	return points
+}
+
+func TestRecommendFromPrices(t *testing.T) {
+	testCases := []struct {
+		description string
+		prices      map[string][]SpotPricePoint
+		expected    SpotPriceRecommendation
+		isNotFound  bool
+	}{
+		{
+			description: "empty history",
+			prices:      map[string][]SpotPricePoint{},
+			isNotFound:  true,
+		},
+		{
+			description: "empty zone",
+			prices: map[string][]SpotPricePoint{
+				"us-east-1a": {},
+			},
+			isNotFound: true,
+		},
+		{
+			description: "single price",
+			prices: map[string][]SpotPricePoint{
+				"us-east-1a": pricePoints(0.1),
+			},
+			expected: SpotPriceRecommendation{
+				P50: 0.1,
+				P90: 0.1,
+				Max: 0.1,
+				Bid: 0.11,
+			},
+		},
+		{
+			description: "multiple zones",
+			prices: map[string][]SpotPricePoint{
+				"us-east-1a": pricePoints(0.01, 0.02, 0.03, 0.04, 0.05),
+				"us-east-1b": pricePoints(0.10, 0.09, 0.08, 0.07, 0.06),
+			},
+			expected: SpotPriceRecommendation{
+				P50: 0.05,
+				P90: 0.09,
+				Max: 0.1,
+				Bid: 0.099,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		recommendation, err := recommendFromPrices(testCase.prices)
+
+		if testCase.isNotFound {
+			if !sgerrors.IsNotFound(err) {
+				t.Errorf("Expected not found error actual %v", err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if *recommendation != testCase.expected {
+			t.Errorf("Wrong recommendation expected %v actual %v",
+				testCase.expected, *recommendation)
+		}
+	}
+}
+
+type fakeProductsGetter struct {
+	input  *pricing.GetProductsInput
+	output *pricing.GetProductsOutput
+	err    error
+}
+
+func (f *fakeProductsGetter) GetProducts(input *pricing.GetProductsInput) (*pricing.GetProductsOutput, error) {
+	f.input = input
+	return f.output, f.err
+}
+
+func TestDescribeOnDemandPrice(t *testing.T) {
+	product := aws.JSONValue{
+		"terms": map[string]interface{}{
+			"OnDemand": map[string]interface{}{
+				"term": map[string]interface{}{
+					"priceDimensions": map[string]interface{}{
+						"dimension": map[string]interface{}{
+							"pricePerUnit": map[string]interface{}{
+								"USD": "0.1000000000",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		description string
+		region      string
+		output      *pricing.GetProductsOutput
+		err         error
+		expected    float64
+		isErr       bool
+	}{
+		{
+			description: "unknown region",
+			region:      "unknown",
+			isErr:       true,
+		},
+		{
+			description: "get products error",
+			region:      "us-east-1",
+			err:         errors.New("error"),
+			isErr:       true,
+		},
+		{
+			description: "no products",
+			region:      "us-east-1",
+			output:      &pricing.GetProductsOutput{},
+			isErr:       true,
+		},
+		{
+			description: "success",
+			region:      "us-east-1",
+			output: &pricing.GetProductsOutput{
+				PriceList: []aws.JSONValue{product},
+			},
+			expected: 0.1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		svc := &fakeProductsGetter{
+			output: testCase.output,
+			err:    testCase.err,
+		}
+
+		price, err := describeOnDemandPrice(svc, "m4.large", testCase.region)
+
+		if testCase.isErr {
+			if err == nil {
+				t.Errorf("Error must not be nil")
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if price != testCase.expected {
+			t.Errorf("Wrong price expected %f actual %f", testCase.expected, price)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package kube
 
+import "github.com/supergiant/control/pkg/model"
+
 type ReleaseInput struct {
 	Name         string `json:"name"`
 	Namespace    string `json:"namespace"`
@@ -7,4 +9,13 @@ type ReleaseInput struct {
 	ChartVersion string `json:"chartVersion"`
 	RepoName     string `json:"repoName" valid:"required"`
 	Values       string `json:"values"`
+	// Values documents merged in order before values and overrides
+	ValuesFiles []string              `json:"valuesFiles"`
+	Set         []model.ValueOverride `json:"set"`
+	// Create the namespace of the release when it is missing
+	CreateNamespace bool `json:"createNamespace"`
+}
+
+type RollbackInput struct {
+	Revision int32 `json:"revision" valid:"required"`
 }
@@ -1,21 +1,33 @@
 package kube
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Masterminds/semver"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/pborman/uuid"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -24,6 +36,9 @@ import (
 	clientcmddapi "k8s.io/client-go/tools/clientcmd/api"
 
 	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/clouds/digitaloceansdk"
+	"github.com/supergiant/control/pkg/clouds/gcesdk"
+	"github.com/supergiant/control/pkg/kubeconfig"
 	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/sgerrors"
 	"github.com/supergiant/control/pkg/util"
@@ -31,330 +46,1470 @@ import (
 	"github.com/supergiant/control/pkg/workflows/steps/amazon"
 )
 
-func processAWSMetrics(k *model.Kube, metrics map[string]map[string]interface{}) {
-	for _, masterNode := range k.Masters {
-		// After some amount of time prometheus start using region in metric name
-		prefix := ip2Host(masterNode.PrivateIp)
-		for metricKey := range metrics {
-			if strings.Contains(metricKey, prefix) {
-				value := metrics[metricKey]
-				delete(metrics, metricKey)
-				metrics[strings.ToLower(masterNode.Name)] = value
+const (
+	defaultSpotRequestDuration = time.Hour * 24 * 365
+	minSpotRequestDuration     = time.Minute
+	spotAnyZone                = "any"
+
+	linuxProductDescription = "Linux/UNIX"
+
+	// DefaultDiscoveryTimeout limits requests to API server of kube
+	// being imported, kube is imported with a warning when it is exceeded
+	DefaultDiscoveryTimeout = 10 * time.Second
+
+	// HelmNotInstalled is discovered when kube has neither tiller
+	// nor helm 3 releases.
+	HelmNotInstalled = "not installed"
+	// Helm3 is discovered when kube has helm 3 releases.
+	Helm3 = "3"
+
+	helm3ReleaseType = "helm.sh/release.v1"
+)
+
+// EC2 instance state codes
+const (
+	instanceStateRunning      int64 = 16
+	instanceStateShuttingDown int64 = 32
+	instanceStateTerminated   int64 = 48
+	instanceStateStopped      int64 = 80
+)
+
+// hostMatcher tells whether metric key of prometheus instance is
+// the host of the machine.
+type hostMatcher func(metricKey string, machine *model.Machine) bool
+
+// hostMatchers by provider, IP addresses are matched for the rest.
+var hostMatchers = map[clouds.Name]hostMatcher{
+	// Hostnames look like ip-10-0-0-1, prometheus adds region domain
+	// to them after some amount of time
+	clouds.AWS: func(metricKey string, machine *model.Machine) bool {
+		return machine.PrivateIp != "" && isHost(metricKey, ip2Host(machine.PrivateIp))
+	},
+	// Hostnames are instance names with optional internal domain
+	clouds.GCE: func(metricKey string, machine *model.Machine) bool {
+		return machine.Name != "" && isHost(metricKey, machine.Name)
+	},
+	// Hostnames are droplet names
+	clouds.DigitalOcean: func(metricKey string, machine *model.Machine) bool {
+		return machine.Name != "" && isHost(metricKey, machine.Name)
+	},
+}
+
+// processMetrics returns metrics keyed by names of the kube machines,
+// keys that don't match any machine are left intact.
+func processMetrics(k *model.Kube, metrics map[string]map[string]interface{}) map[string]map[string]interface{} {
+	machines := make([]*model.Machine, 0, len(k.Masters)+len(k.Nodes))
+
+	for _, machine := range k.Masters {
+		machines = append(machines, machine)
+	}
+
+	for _, machine := range k.Nodes {
+		machines = append(machines, machine)
+	}
+
+	matchers := []hostMatcher{matchIPs}
+
+	if matcher, ok := hostMatchers[k.Provider]; ok {
+		matchers = []hostMatcher{matcher, matchIPs}
+	}
+
+	processed := make(map[string]map[string]interface{}, len(metrics))
+
+	for metricKey, value := range metrics {
+		if machine := findMetricMachine(metricKey, machines, matchers); machine != nil {
+			processed[strings.ToLower(machine.Name)] = value
+		} else {
+			processed[metricKey] = value
+		}
+	}
+
+	return processed
+}
+
+func findMetricMachine(metricKey string, machines []*model.Machine, matchers []hostMatcher) *model.Machine {
+	for _, matches := range matchers {
+		for _, machine := range machines {
+			if matches(metricKey, machine) {
+				return machine
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchIPs matches keys with private or public ip of the machine
+// as a host, e.g. 10.0.0.1:9100.
+func matchIPs(metricKey string, machine *model.Machine) bool {
+	host := metricHost(metricKey)
+
+	return host != "" && (host == machine.PrivateIp || host == machine.PublicIp)
+}
+
+// isHost is true when host of the key is the host or the host
+// in some domain, hosts are compared as whole tokens.
+func isHost(metricKey, host string) bool {
+	keyHost := strings.ToLower(metricHost(metricKey))
+	host = strings.ToLower(host)
+
+	return keyHost == host || strings.SplitN(keyHost, ".", 2)[0] == host
+}
+
+// metricHost strips port of prometheus instance label.
+func metricHost(metricKey string) string {
+	return strings.SplitN(metricKey, ":", 2)[0]
+}
+
+func ip2Host(ip string) string {
+	return fmt.Sprintf("ip-%s", strings.Join(strings.Split(ip, "."), "-"))
+}
+
+// resolveKubeConfigFiles reads certificates, keys and tokens that current
+// context of the kubeconfig references by path. Files are read only within
+// allowedDir configured by the operator, references are rejected when it is
+// empty. Relative paths are resolved against baseDir that is the kubeconfig
+// location.
+func resolveKubeConfigFiles(kubeConfig *clientcmddapi.Config, baseDir, allowedDir string) error {
+	currentContext := kubeConfig.Contexts[kubeConfig.CurrentContext]
+
+	if currentContext == nil {
+		return nil
+	}
+
+	if cluster := kubeConfig.Clusters[currentContext.Cluster]; cluster != nil {
+		if len(cluster.CertificateAuthorityData) == 0 && cluster.CertificateAuthority != "" {
+			data, err := readKubeConfigFile(cluster.CertificateAuthority, baseDir, allowedDir)
+
+			if err != nil {
+				return errors.Wrapf(err, "certificate-authority of cluster %s", currentContext.Cluster)
+			}
+
+			cluster.CertificateAuthorityData = data
+		}
+		cluster.CertificateAuthority = ""
+	}
+
+	if authInfo := kubeConfig.AuthInfos[currentContext.AuthInfo]; authInfo != nil {
+		if len(authInfo.ClientCertificateData) == 0 && authInfo.ClientCertificate != "" {
+			data, err := readKubeConfigFile(authInfo.ClientCertificate, baseDir, allowedDir)
+
+			if err != nil {
+				return errors.Wrapf(err, "client-certificate of user %s", currentContext.AuthInfo)
+			}
+
+			authInfo.ClientCertificateData = data
+		}
+		authInfo.ClientCertificate = ""
+
+		if len(authInfo.ClientKeyData) == 0 && authInfo.ClientKey != "" {
+			data, err := readKubeConfigFile(authInfo.ClientKey, baseDir, allowedDir)
+
+			if err != nil {
+				return errors.Wrapf(err, "client-key of user %s", currentContext.AuthInfo)
+			}
+
+			authInfo.ClientKeyData = data
+		}
+		authInfo.ClientKey = ""
+
+		// Token file would be read by client-go and sent to the API server
+		if authInfo.Token == "" && authInfo.TokenFile != "" {
+			data, err := readKubeConfigFile(authInfo.TokenFile, baseDir, allowedDir)
+
+			if err != nil {
+				return errors.Wrapf(err, "tokenFile of user %s", currentContext.AuthInfo)
+			}
+
+			authInfo.Token = string(bytes.TrimSpace(data))
+		}
+		authInfo.TokenFile = ""
+	}
+
+	return nil
+}
+
+// sanitizeKubeConfig returns copy of the kubeconfig that is stored with
+// imported kube, references to files are dropped since they can't be read
+// after import, files of the imported context are resolved beforehand.
+func sanitizeKubeConfig(kubeConfig clientcmddapi.Config, contextName string) clientcmddapi.Config {
+	sanitized := *kubeConfig.DeepCopy()
+	sanitized.CurrentContext = contextName
+	sanitized.Preferences = clientcmddapi.Preferences{}
+	sanitized.Extensions = nil
+
+	for _, cluster := range sanitized.Clusters {
+		cluster.LocationOfOrigin = ""
+		cluster.CertificateAuthority = ""
+	}
+
+	for _, authInfo := range sanitized.AuthInfos {
+		authInfo.LocationOfOrigin = ""
+		authInfo.ClientCertificate = ""
+		authInfo.ClientKey = ""
+		authInfo.TokenFile = ""
+	}
+
+	for _, ctx := range sanitized.Contexts {
+		ctx.LocationOfOrigin = ""
+	}
+
+	return sanitized
+}
+
+// readKubeConfigFile returns PEM data of the file within allowedDir, files
+// that hold base64 encoded PEM like *-data fields of kubeconfig are decoded.
+func readKubeConfigFile(path, baseDir, allowedDir string) ([]byte, error) {
+	if allowedDir == "" {
+		return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"file %s can not be read, certificates, keys and tokens must be inlined", path)
+	}
+
+	if !filepath.IsAbs(path) {
+		if baseDir == "" {
+			return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"relative path %s requires kubeconfig location", path)
+		}
+
+		path = filepath.Join(baseDir, path)
+	}
+
+	path, err := confinePath(filepath.Clean(path), allowedDir)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+
+	if os.IsNotExist(err) {
+		return nil, errors.Wrapf(sgerrors.ErrValidationFailed, "file %s does not exist", path)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "read file %s", path)
+	}
+
+	pemBegin := []byte("-----BEGIN")
+
+	if !bytes.Contains(data, pemBegin) {
+		decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+
+		if err == nil && bytes.Contains(decoded, pemBegin) {
+			return decoded, nil
+		}
+	}
+
+	return data, nil
+}
+
+// confinePath returns the path with symlinks resolved, validation error
+// is returned when it is outside of the dir.
+func confinePath(path, dir string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if os.IsNotExist(err) {
+		return "", errors.Wrapf(sgerrors.ErrValidationFailed, "file %s does not exist", path)
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "resolve file %s", path)
+	}
+
+	resolvedDir, err := filepath.EvalSymlinks(filepath.Clean(dir))
+	if err != nil {
+		return "", errors.Wrapf(err, "resolve dir %s", dir)
+	}
+
+	rel, err := filepath.Rel(resolvedDir, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Wrapf(sgerrors.ErrValidationFailed,
+			"file %s is outside of %s", path, dir)
+	}
+
+	return resolved, nil
+}
+
+// machinesFromNodes returns masters and nodes of imported kube by
+// k8s nodes, machines are named after nodes.
+func machinesFromNodes(nodes []corev1.Node, provider clouds.Name,
+	region string) (map[string]*model.Machine, map[string]*model.Machine) {
+	masters := make(map[string]*model.Machine)
+	workers := make(map[string]*model.Machine)
+
+	for _, node := range nodes {
+		machine := &model.Machine{
+			Name:           node.Name,
+			Role:           model.RoleNode,
+			Provider:       provider,
+			Region:         region,
+			CreatedAt:      node.CreationTimestamp.Unix(),
+			State:          nodeMachineState(node),
+			KubeletVersion: node.Status.NodeInfo.KubeletVersion,
+		}
+
+		for _, address := range node.Status.Addresses {
+			if address.Type == corev1.NodeExternalIP {
+				machine.PublicIp = address.Address
+			} else if address.Type == corev1.NodeInternalIP {
+				machine.PrivateIp = address.Address
 			}
 		}
+
+		if isMasterNode(node) {
+			machine.Role = model.RoleMaster
+			masters[machine.Name] = machine
+		} else {
+			workers[machine.Name] = machine
+		}
+	}
+
+	return masters, workers
+}
+
+// syncK8SNodes syncs machines of the kube with its k8s nodes like
+// syncAWSMachines does with EC2 instances.
+func syncK8SNodes(k *model.Kube, nodes []corev1.Node) {
+	masters, workers := machinesFromNodes(nodes, k.Provider, k.Region)
+
+	if k.Masters == nil {
+		k.Masters = make(map[string]*model.Machine)
+	}
+
+	if k.Nodes == nil {
+		k.Nodes = make(map[string]*model.Machine)
+	}
+
+	// Machines of nodes that are present by name of the machine
+	present := make(map[string]bool)
+
+	syncNodeMachines(k, masters, k.Masters, present)
+	syncNodeMachines(k, workers, k.Nodes, present)
+
+	for name, machine := range k.Nodes {
+		if !isSyncable(machine) || present[name] {
+			continue
+		}
+
+		// Give node one more sync before removing it from the model
+		if machine.State == model.MachineStateDeleting {
+			logrus.Infof("Remove node %s gone from k8s from kube %s", name, k.ID)
+			delete(k.Nodes, name)
+			continue
+		}
+
+		logrus.Infof("Node %s of kube %s is gone from k8s", name, k.ID)
+		machine.State = model.MachineStateDeleting
+	}
+
+	for name, machine := range k.Masters {
+		if !isSyncable(machine) || present[name] {
+			continue
+		}
+
+		logrus.Errorf("Master %s of kube %s is gone from k8s", name, k.ID)
+		machine.State = model.MachineStateError
+	}
+}
+
+// syncNodeMachines updates known machines by name or private ip
+// and adds the rest to the machines of the role.
+func syncNodeMachines(k *model.Kube, synced map[string]*model.Machine,
+	machines map[string]*model.Machine, present map[string]bool) {
+	for name, machine := range synced {
+		known := k.Masters[name]
+
+		if known == nil {
+			known = k.Nodes[name]
+		}
+
+		if known == nil {
+			known = findMachine(k.Masters, machine.PrivateIp)
+		}
+
+		if known == nil {
+			known = findMachine(k.Nodes, machine.PrivateIp)
+		}
+
+		if known == nil {
+			logrus.Debugf("Add new %s %v", machine.Role, machine)
+			machines[name] = machine
+			present[name] = true
+			continue
+		}
+
+		present[known.Name] = true
+
+		if known.PrivateIp == "" {
+			known.PrivateIp = machine.PrivateIp
+		}
+
+		if machine.PublicIp != "" {
+			known.PublicIp = machine.PublicIp
+		}
+
+		known.KubeletVersion = machine.KubeletVersion
+
+		if isSyncable(known) {
+			known.State = machine.State
+		}
+	}
+}
+
+// isMasterNode returns true for nodes labeled with master or control plane role.
+func isMasterNode(node corev1.Node) bool {
+	for _, label := range []string{
+		"node-role.kubernetes.io/master",
+		"node-role.kubernetes.io/control-plane",
+	} {
+		if _, ok := node.Labels[label]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nodeMachineState maps Ready condition of the node to machine state.
+func nodeMachineState(node corev1.Node) model.MachineState {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type != corev1.NodeReady {
+			continue
+		}
+
+		if condition.Status == corev1.ConditionTrue {
+			return model.MachineStateActive
+		}
+
+		return model.MachineStateError
+	}
+
+	return model.MachineStateProvisioning
+}
+
+// KubeConfigContext is a context of kubeconfig that may be imported.
+type KubeConfigContext struct {
+	Name    string `json:"name"`
+	Cluster string `json:"cluster"`
+	Server  string `json:"server"`
+	Current bool   `json:"current"`
+}
+
+// kubeConfigContexts returns contexts of the kubeconfig sorted by name.
+func kubeConfigContexts(kubeConfig clientcmddapi.Config) []KubeConfigContext {
+	contexts := make([]KubeConfigContext, 0, len(kubeConfig.Contexts))
+
+	for name, kubeContext := range kubeConfig.Contexts {
+		kubeCtx := KubeConfigContext{
+			Name:    name,
+			Cluster: kubeContext.Cluster,
+			Current: name == kubeConfig.CurrentContext,
+		}
+
+		if cluster := kubeConfig.Clusters[kubeContext.Cluster]; cluster != nil {
+			kubeCtx.Server = cluster.Server
+		}
+
+		contexts = append(contexts, kubeCtx)
+	}
+
+	sort.Slice(contexts, func(i, j int) bool {
+		return contexts[i].Name < contexts[j].Name
+	})
+
+	return contexts
+}
+
+// kubeFromKubeConfig builds kube of the context, current context
+// is used when contextName is empty.
+func kubeFromKubeConfig(kubeConfig clientcmddapi.Config, contextName string) (*model.Kube, error) {
+	if contextName != "" {
+		if _, ok := kubeConfig.Contexts[contextName]; !ok {
+			return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"context %s not found in kubeconfig", contextName)
+		}
+	} else {
+		contextName = kubeConfig.CurrentContext
+	}
+
+	currentCtxName := contextName
+	currentContext := kubeConfig.Contexts[currentCtxName]
+
+	if currentContext == nil {
+		return nil, errors.Wrapf(sgerrors.ErrNilEntity, "current context %s not found in context map %v",
+			currentCtxName, kubeConfig.Contexts)
+	}
+
+	authInfoName := currentContext.AuthInfo
+	authInfo := kubeConfig.AuthInfos[authInfoName]
+
+	if authInfo == nil {
+		return nil, errors.Wrapf(sgerrors.ErrNilEntity, "authInfo %s not found in auth into auth map %v",
+			authInfoName, kubeConfig.AuthInfos)
+	}
+
+	clusterName := currentContext.Cluster
+	cluster := kubeConfig.Clusters[clusterName]
+
+	if cluster == nil {
+		return nil, errors.Wrapf(sgerrors.ErrNilEntity, "cluster %s not found in cluster map %v",
+			clusterName, kubeConfig.Clusters)
+	}
+
+	hasCert := len(authInfo.ClientCertificateData) > 0 && len(authInfo.ClientKeyData) > 0
+	hasBasicAuth := authInfo.Username != "" && authInfo.Password != ""
+	hasPlugin := authInfo.Exec != nil || authInfo.AuthProvider != nil
+
+	if !hasCert && authInfo.Token == "" && !hasBasicAuth && !hasPlugin {
+		return nil, errors.Wrapf(sgerrors.ErrValidationFailed, "authInfo %s has neither "+
+			"client certificate, token, basic auth credentials nor credential plugin, "+
+			"credential files are not supported", authInfoName)
+	}
+
+	raw, err := kubeconfig.Marshal(sanitizeKubeConfig(kubeConfig, contextName))
+
+	if err != nil {
+		return nil, errors.Wrap(err, "serialize kubeconfig")
+	}
+
+	kube := &model.Kube{
+		Name:            currentContext.Cluster,
+		ExternalDNSName: cluster.Server,
+		Auth: model.Auth{
+			CACert:        string(cluster.CertificateAuthorityData),
+			AdminCert:     string(authInfo.ClientCertificateData),
+			AdminKey:      string(authInfo.ClientKeyData),
+			AdminToken:    authInfo.Token,
+			AdminUsername: authInfo.Username,
+			AdminPassword: authInfo.Password,
+
+			InsecureSkipTLSVerify: cluster.InsecureSkipTLSVerify,
+
+			KubeConfig: string(raw),
+		},
+	}
+
+	if exec := authInfo.Exec; exec != nil {
+		kube.Auth.AdminExec = &model.ExecAuth{
+			Command:    exec.Command,
+			Args:       exec.Args,
+			APIVersion: exec.APIVersion,
+		}
+
+		if len(exec.Env) > 0 {
+			kube.Auth.AdminExec.Env = make(map[string]string, len(exec.Env))
+		}
+
+		for _, env := range exec.Env {
+			kube.Auth.AdminExec.Env[env.Name] = env.Value
+		}
+	} else if provider := authInfo.AuthProvider; provider != nil {
+		kube.Auth.AdminAuthProvider = &model.AuthProvider{
+			Name:   provider.Name,
+			Config: provider.Config,
+		}
+	}
+
+	return kube, nil
+}
+
+func syncMachines(ctx context.Context, k *model.Kube, account *model.CloudAccount) error {
+	config := &steps.Config{}
+	if err := util.FillCloudAccountCredentials(account, config); err != nil {
+		return errors.Wrap(err, "error fill cloud account credentials")
+	}
+
+	switch k.Provider {
+	case clouds.AWS:
+		config.AWSConfig.Region = k.Region
+		EC2, err := amazon.GetEC2(config.AWSConfig)
+
+		if err != nil {
+			return errors.Wrap(sgerrors.ErrInvalidCredentials, err.Error())
+		}
+
+		if err := syncAWSMachines(ctx, EC2, k); err != nil {
+			return err
+		}
+
+		autoScaling, err := amazon.GetAutoScaling(config.AWSConfig)
+
+		if err != nil {
+			return errors.Wrap(sgerrors.ErrInvalidCredentials, err.Error())
+		}
+
+		return syncAutoScalingGroups(ctx, autoScaling, k)
+	case clouds.DigitalOcean:
+		client := digitaloceansdk.New(config.DigitalOceanConfig.AccessToken).GetClient()
+
+		return syncDOMachines(ctx, client.Droplets, k)
+	case clouds.GCE:
+		client, err := gcesdk.GetClient(ctx, config.GCEConfig)
+
+		if err != nil {
+			return errors.Wrap(sgerrors.ErrInvalidCredentials, err.Error())
+		}
+
+		return syncGCEMachines(ctx, &gceInstances{
+			svc:       client,
+			projectID: config.GCEConfig.ServiceAccount.ProjectID,
+		}, k)
+	case clouds.Azure:
+		client, err := getAzureClient(config)
+
+		if err != nil {
+			return errors.Wrap(sgerrors.ErrInvalidCredentials, err.Error())
+		}
+
+		return syncAzureMachines(ctx, client, k)
+	}
+
+	return sgerrors.ErrUnsupportedProvider
+}
+
+// isSyncSupported returns true for providers machines are synced with.
+func isSyncSupported(provider clouds.Name) bool {
+	switch provider {
+	case clouds.AWS, clouds.DigitalOcean, clouds.GCE, clouds.Azure:
+		return true
+	}
+
+	return false
+}
+
+type instanceDescriber interface {
+	DescribeInstancesPagesWithContext(aws.Context, *ec2.DescribeInstancesInput,
+		func(*ec2.DescribeInstancesOutput, bool) bool, ...request.Option) error
+}
+
+func syncAWSMachines(ctx context.Context, EC2 instanceDescriber, k *model.Kube) error {
+	input := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String(fmt.Sprintf("tag:%s", clouds.TagClusterID)),
+				Values: aws.StringSlice([]string{k.ID}),
+			},
+		},
+	}
+
+	// Instances of the cluster by private ip
+	var instances map[string]*ec2.Instance
+
+	err := util.Retry(ctx, amazon.RetryPolicy, amazon.IsRetryableErr, "describe instances", func() error {
+		instances = make(map[string]*ec2.Instance)
+
+		return EC2.DescribeInstancesPagesWithContext(ctx, input,
+			func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+				for _, res := range page.Reservations {
+					for _, instance := range res.Instances {
+						if instance.PrivateIpAddress == nil {
+							continue
+						}
+
+						// Terminated instance may share private ip
+						// with the one that has replaced it.
+						if prev := instances[*instance.PrivateIpAddress]; prev != nil &&
+							instanceState(prev) == instanceStateRunning {
+							continue
+						}
+
+						instances[*instance.PrivateIpAddress] = instance
+					}
+				}
+
+				return true
+			})
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "describe instances")
+	}
+
+	if k.Masters == nil {
+		k.Masters = make(map[string]*model.Machine)
+	}
+
+	if k.Nodes == nil {
+		k.Nodes = make(map[string]*model.Machine)
+	}
+
+	for _, instance := range instances {
+		syncInstance(k, instance)
+	}
+
+	for name, machine := range k.Nodes {
+		if !isSyncable(machine) {
+			continue
+		}
+
+		instance := instances[machine.PrivateIp]
+
+		if instance != nil && !isInstanceGone(instance) {
+			continue
+		}
+
+		// Give node one more sync before removing it from the model
+		if machine.State == model.MachineStateDeleting {
+			logrus.Infof("Remove node %s gone from EC2 from kube %s", name, k.ID)
+			delete(k.Nodes, name)
+			continue
+		}
+
+		logrus.Infof("Node %s of kube %s is gone from EC2", name, k.ID)
+		machine.State = model.MachineStateDeleting
+	}
+
+	for name, machine := range k.Masters {
+		if !isSyncable(machine) {
+			continue
+		}
+
+		instance := instances[machine.PrivateIp]
+
+		if instance != nil && !isInstanceGone(instance) {
+			continue
+		}
+
+		logrus.Errorf("Master %s of kube %s is gone from EC2", name, k.ID)
+		machine.State = model.MachineStateError
+	}
+
+	return nil
+}
+
+func syncInstance(k *model.Kube, instance *ec2.Instance) {
+	machine := &model.Machine{
+		ID:     aws.StringValue(instance.InstanceId),
+		Size:   aws.StringValue(instance.InstanceType),
+		State:  model.MachineStateActive,
+		Role:   model.RoleNode,
+		Region: k.Region,
+	}
+
+	if instance.PublicIpAddress != nil {
+		machine.PublicIp = *instance.PublicIpAddress
+	}
+
+	if instance.PrivateIpAddress != nil {
+		machine.PrivateIp = *instance.PrivateIpAddress
+	}
+
+	roleTag := ""
+
+	for _, tag := range instance.Tags {
+		if tag.Key == nil || tag.Value == nil {
+			continue
+		}
+
+		switch *tag.Key {
+		case clouds.TagNodeName:
+			machine.Name = *tag.Value
+		case clouds.TagRole:
+			roleTag = *tag.Value
+		case clouds.TagNodePool:
+			machine.Pool = *tag.Value
+		}
+	}
+
+	// Bastion of private kube is not a machine of it
+	if roleTag == amazon.BastionRole {
+		return
+	}
+
+	switch {
+	case roleTag == util.MakeRole(true):
+		machine.Role = model.RoleMaster
+	case roleTag == "" && machine.Name != "" && k.Masters[machine.Name] != nil:
+		// Fallback for instances created without role tag
+		machine.Role = model.RoleMaster
+	}
+
+	if known := findMachine(k.Masters, machine.PrivateIp); known != nil {
+		updateSpotInfo(known, instance)
+		return
+	}
+
+	if known := findMachine(k.Nodes, machine.PrivateIp); known != nil {
+		updateSpotInfo(known, instance)
+		return
+	}
+
+	if instanceState(instance) != instanceStateRunning {
+		return
+	}
+
+	machine.SpotInfo = amazon.SpotInfo(instance, "")
+
+	// Instances launched without name tag are named after their id
+	if machine.Name == "" && machine.ID != "" {
+		machine.Name = fmt.Sprintf("%s-%s", machine.Role, machine.ID)
+	}
+
+	if machine.Name == "" {
+		logrus.Warnf("Skip instance %s of kube %s without name",
+			aws.StringValue(instance.InstanceId), k.ID)
+		return
+	}
+
+	if machine.Role == model.RoleMaster {
+		logrus.Debugf("Add new master %v", machine)
+		k.Masters[machine.Name] = machine
+		return
+	}
+
+	logrus.Debugf("Add new node %v", machine)
+	k.Nodes[machine.Name] = machine
+}
+
+func isKnownMachine(machines map[string]*model.Machine, privateIP string) bool {
+	return findMachine(machines, privateIP) != nil
+}
+
+func findMachine(machines map[string]*model.Machine, privateIP string) *model.Machine {
+	for _, machine := range machines {
+		if privateIP != "" && machine != nil && machine.PrivateIp == privateIP {
+			return machine
+		}
+	}
+
+	return nil
+}
+
+// updateSpotInfo keeps spot info saved by spot workflow, sync only
+// fills it for machines it has not been saved for and marks
+// interrupted instances.
+func updateSpotInfo(machine *model.Machine, instance *ec2.Instance) {
+	if machine.ID != "" && machine.ID != aws.StringValue(instance.InstanceId) {
+		return
+	}
+
+	info := amazon.SpotInfo(instance, "")
+
+	switch {
+	case info == nil:
+	case machine.SpotInfo == nil:
+		machine.SpotInfo = info
+	case info.Interrupted:
+		machine.SpotInfo.Interrupted = true
+	}
+}
+
+// putSpotMachine adds machine to kube nodes replacing the same
+// instance if it has been added by sync under another name.
+func putSpotMachine(k *model.Kube, n *model.Machine) {
+	if k.Nodes == nil {
+		k.Nodes = make(map[string]*model.Machine)
 	}
 
-	for _, workerNode := range k.Nodes {
-		prefix := ip2Host(workerNode.PrivateIp)
+	for name, machine := range k.Nodes {
+		if name == n.Name || machine == nil {
+			continue
+		}
 
-		for metricKey := range metrics {
-			if strings.Contains(metricKey, prefix) {
-				value := metrics[metricKey]
-				delete(metrics, metricKey)
-				metrics[strings.ToLower(workerNode.Name)] = value
-			}
+		if (n.ID != "" && machine.ID == n.ID) ||
+			(n.PrivateIp != "" && machine.PrivateIp == n.PrivateIp) {
+			logrus.Debugf("Replace machine %s with spot machine %s", name, n.Name)
+			delete(k.Nodes, name)
 		}
 	}
+
+	k.Nodes[n.Name] = n
 }
 
-func ip2Host(ip string) string {
-	return fmt.Sprintf("ip-%s", strings.Join(strings.Split(ip, "."), "-"))
+func instanceState(instance *ec2.Instance) int64 {
+	if instance.State != nil && instance.State.Code != nil {
+		return *instance.State.Code
+	}
+
+	return -1
 }
 
-func kubeFromKubeConfig(kubeConfig clientcmddapi.Config) (*model.Kube, error) {
-	currentCtxName := kubeConfig.CurrentContext
-	currentContext := kubeConfig.Contexts[currentCtxName]
+// isInstanceGone returns true for instances that are terminated or being
+// terminated, stopped instances are kept since they may be started again.
+func isInstanceGone(instance *ec2.Instance) bool {
+	state := instanceState(instance)
 
-	if currentContext == nil {
-		return nil, errors.Wrapf(sgerrors.ErrNilEntity, "current context %s not found in context map %v",
-			currentCtxName, kubeConfig.Contexts)
+	return state == instanceStateShuttingDown || state == instanceStateTerminated
+}
+
+// isSyncable returns true for machines that are expected to have EC2 instance.
+func isSyncable(machine *model.Machine) bool {
+	if machine == nil || machine.PrivateIp == "" {
+		return false
 	}
 
-	authInfoName := currentContext.AuthInfo
-	authInfo := kubeConfig.AuthInfos[authInfoName]
+	switch machine.State {
+	case model.MachineStatePlanned, model.MachineStateBuilding, model.MachineStateProvisioning:
+		return false
+	}
 
-	if authInfo == nil {
-		return nil, errors.Wrapf(sgerrors.ErrNilEntity, "authInfo %s not found in auth into auth map %v",
-			authInfoName, kubeConfig.AuthInfos)
+	return true
+}
+
+// snapshotMachines copies kube machines by role and name.
+func snapshotMachines(k *model.Kube) map[string]model.Machine {
+	machines := make(map[string]model.Machine, len(k.Masters)+len(k.Nodes))
+
+	for name, machine := range k.Masters {
+		if machine != nil {
+			machines[string(model.RoleMaster)+"/"+name] = copyMachine(machine)
+		}
 	}
 
-	clusterName := currentContext.Cluster
-	cluster := kubeConfig.Clusters[clusterName]
+	for name, machine := range k.Nodes {
+		if machine != nil {
+			machines[string(model.RoleNode)+"/"+name] = copyMachine(machine)
+		}
+	}
 
-	if cluster == nil {
-		return nil, errors.Wrapf(sgerrors.ErrNilEntity, "cluster %s not found in cluster map %v",
-			clusterName, kubeConfig.Clusters)
+	return machines
+}
+
+// copyMachine copies machine along with its spot info, so sync
+// does not change the copy.
+func copyMachine(machine *model.Machine) model.Machine {
+	m := *machine
+
+	if m.SpotInfo != nil {
+		info := *m.SpotInfo
+		m.SpotInfo = &info
 	}
 
-	return &model.Kube{
-		Name:            currentContext.Cluster,
-		ExternalDNSName: cluster.Server,
-		Auth: model.Auth{
-			CACert:    string(cluster.CertificateAuthorityData),
-			AdminCert: string(authInfo.ClientCertificateData),
-			AdminKey:  string(authInfo.ClientKeyData),
-		},
-	}, nil
+	return m
 }
 
-func syncMachines(ctx context.Context, k *model.Kube, account *model.CloudAccount) error {
-	config := &steps.Config{}
-	if err := util.FillCloudAccountCredentials(account, config); err != nil {
-		return errors.Wrap(err, "error fill cloud account credentials")
+// diffMachines counts machines added, updated and removed between snapshots.
+func diffMachines(before, after map[string]model.Machine) (added, updated, removed int) {
+	for key, machine := range after {
+		prev, ok := before[key]
+
+		switch {
+		case !ok:
+			added++
+		case prev.State != machine.State || prev.PrivateIp != machine.PrivateIp ||
+			prev.PublicIp != machine.PublicIp ||
+			!reflect.DeepEqual(prev.SpotInfo, machine.SpotInfo):
+			updated++
+		}
+	}
+
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			removed++
+		}
 	}
 
-	config.AWSConfig.Region = k.Region
-	EC2, err := amazon.GetEC2(config.AWSConfig)
+	return added, updated, removed
+}
 
-	if err != nil {
-		return errors.Wrap(sgerrors.ErrInvalidCredentials, err.Error())
+// validateSpotRequest checks spot request expiration time and sets
+// the default one when it is omitted.
+func validateSpotRequest(req *SpotRequest, now time.Time) error {
+	// Count is split across zones of the request, none are used for zero
+	if req.MachineCount <= 0 {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"machine count must be positive, got %d", req.MachineCount)
 	}
 
-	describeInstanceOutput, err := EC2.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{
-		Filters: []*ec2.Filter{
-			{
-				Name:   aws.String(fmt.Sprintf("tag:%s", clouds.TagClusterID)),
-				Values: aws.StringSlice([]string{k.ID}),
-			},
-		},
-	})
+	if req.FulfillmentTimeout < 0 {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"fulfillment timeout must not be negative, got %d", req.FulfillmentTimeout)
+	}
 
-	if err != nil {
-		return errors.Wrap(err, "describe instances")
+	for _, instanceType := range req.InstanceTypes {
+		if instanceType.InstanceType == "" || instanceType.Weight < 0 {
+			return errors.Wrapf(sgerrors.ErrValidationFailed,
+				"wrong spot fleet instance type %v", instanceType)
+		}
 	}
 
-	for _, res := range describeInstanceOutput.Reservations {
-		for _, instance := range res.Instances {
-			node := &model.Machine{
-				Size:   *instance.InstanceType,
-				State:  model.MachineStateActive,
-				Role:   model.RoleNode,
-				Region: k.Region,
-			}
+	// Spot fleet requests do not support dry run
+	if req.DryRun && len(req.InstanceTypes) > 0 {
+		return errors.Wrap(sgerrors.ErrValidationFailed,
+			"dry run is not supported for spot fleet")
+	}
 
-			if instance.PublicIpAddress != nil {
-				node.PublicIp = *instance.PublicIpAddress
-			}
+	switch req.AllocationStrategy {
+	case "", amazon.AllocationStrategyLowestPrice, amazon.AllocationStrategyCapacityOptimized:
+	default:
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"unknown allocation strategy %s", req.AllocationStrategy)
+	}
 
-			if instance.PrivateIpAddress != nil {
-				node.PrivateIp = *instance.PrivateIpAddress
-			}
+	if req.ValidUntil == nil {
+		req.ValidUntil = aws.Time(now.Add(defaultSpotRequestDuration))
+		return nil
+	}
 
-			for _, tag := range instance.Tags {
-				if tag.Key != nil && *tag.Key == clouds.TagNodeName {
-					node.Name = *tag.Value
-				}
-			}
+	if req.ValidUntil.Sub(now) < minSpotRequestDuration {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"spot request must be valid for at least %v, validUntil %v",
+			minSpotRequestDuration, req.ValidUntil.Format(time.RFC3339))
+	}
 
-			isFound := false
+	return nil
+}
 
-			for _, machine := range k.Nodes {
-				if instance.PrivateIpAddress != nil && machine.PrivateIp == *instance.PrivateIpAddress {
-					isFound = true
-				}
-			}
+// spotZones splits machine count of spot request across requested
+// availability zones round-robin, zones without subnet are skipped.
+func spotZones(req *SpotRequest, subnets map[string]string) map[string]int64 {
+	requested := req.AvailabilityZones
 
-			var state int64
+	if len(requested) == 0 && req.AvailabilityZone != "" {
+		requested = []string{req.AvailabilityZone}
+	}
 
-			if instance.State != nil && instance.State.Code != nil {
-				state = *instance.State.Code
-			}
+	zones := make([]string, 0, len(requested))
+	seen := make(map[string]bool)
 
-			// If node is new in workers and it is not a master
-			if !isFound && k.Masters[node.Name] == nil && state == 16 {
-				logrus.Debugf("Add new node %v", node)
-				k.Nodes[node.Name] = node
+	for _, zone := range requested {
+		if strings.EqualFold(zone, spotAnyZone) {
+			zones = zones[:0]
+
+			for subnetZone := range subnets {
+				zones = append(zones, subnetZone)
 			}
+
+			break
 		}
+
+		if subnets[zone] == "" || seen[zone] {
+			logrus.Debugf("Skip availability zone %s for spot request", zone)
+			continue
+		}
+
+		seen[zone] = true
+		zones = append(zones, zone)
 	}
 
-	return nil
+	sort.Strings(zones)
+	counts := make(map[string]int64, len(zones))
+
+	for i := int64(0); i < req.MachineCount && len(zones) > 0; i++ {
+		counts[zones[i%int64(len(zones))]]++
+	}
+
+	return counts
 }
 
-func createSpotInstance(req *SpotRequest, config *steps.Config) error {
-	switch config.Provider {
-	case clouds.AWS:
-		return createAwsSpotInstance(req, config)
+func sortedZones(zones map[string]int64) []string {
+	names := make([]string, 0, len(zones))
+
+	for zone := range zones {
+		names = append(names, zone)
 	}
 
-	return sgerrors.ErrUnsupportedProvider
+	sort.Strings(names)
+
+	return names
 }
 
-func getSpotPrices(machineType string, config *steps.Config) ([]string, error) {
+// getSpotPrices returns spot price history for machine type keyed by
+// availability zone, all zones of the region are queried when az is empty.
+func getSpotPrices(ctx context.Context, machineType, az string, config *steps.Config) (map[string][]SpotPricePoint, error) {
 	switch config.Provider {
 	case clouds.AWS:
-		return getAwsSpotPrices(machineType, config)
+		return getAwsSpotPrices(ctx, machineType, az, config)
+	case clouds.GCE:
+		// Preemptible instances have fixed price, so there is no history
+		return nil, errors.Wrap(sgerrors.ErrUnsupportedProvider,
+			"GCE preemptible instances have fixed price, see GCE pricing catalog")
 	}
 
 	return nil, sgerrors.ErrUnsupportedProvider
 }
 
-func createAwsSpotInstance(req *SpotRequest, config *steps.Config) error {
+type spotPriceDescriber interface {
+	DescribeSpotPriceHistoryPagesWithContext(aws.Context, *ec2.DescribeSpotPriceHistoryInput,
+		func(*ec2.DescribeSpotPriceHistoryOutput, bool) bool, ...request.Option) error
+}
+
+func getAwsSpotPrices(ctx context.Context, machineType, az string, config *steps.Config) (map[string][]SpotPricePoint, error) {
 	svc, err := amazon.GetEC2(config.AWSConfig)
 
 	if err != nil {
-		return errors.Wrap(err, "get EC2 client")
+		return nil, errors.Wrap(err, "get EC2 client")
+	}
+
+	return describeSpotPrices(ctx, svc, machineType, az, time.Now())
+}
+
+func describeSpotPrices(ctx context.Context, svc spotPriceDescriber, machineType, az string,
+	now time.Time) (map[string][]SpotPricePoint, error) {
+	spotPriceReq := &ec2.DescribeSpotPriceHistoryInput{
+		EndTime:             aws.Time(now),
+		StartTime:           aws.Time(now.Add(time.Hour * -24 * 7)),
+		InstanceTypes:       []*string{aws.String(machineType)},
+		ProductDescriptions: []*string{aws.String(linuxProductDescription)},
 	}
 
-	config.AWSConfig.InstanceType = req.MachineType
-	volumeSize, err := strconv.ParseInt(config.AWSConfig.VolumeSize, 10, 64)
+	if az != "" {
+		spotPriceReq.AvailabilityZone = aws.String(az)
+	}
+
+	var spotPrices map[string][]SpotPricePoint
+
+	err := util.Retry(ctx, amazon.RetryPolicy, amazon.IsRetryableErr, "describe spot price history", func() error {
+		spotPrices = make(map[string][]SpotPricePoint)
+
+		return svc.DescribeSpotPriceHistoryPagesWithContext(ctx, spotPriceReq,
+			func(page *ec2.DescribeSpotPriceHistoryOutput, lastPage bool) bool {
+				for _, spotPrice := range page.SpotPriceHistory {
+					if spotPrice.AvailabilityZone == nil || spotPrice.SpotPrice == nil ||
+						spotPrice.Timestamp == nil {
+						continue
+					}
+
+					if !strings.EqualFold(aws.StringValue(spotPrice.ProductDescription),
+						linuxProductDescription) {
+						continue
+					}
+
+					price, err := strconv.ParseFloat(*spotPrice.SpotPrice, 64)
+
+					if err != nil {
+						logrus.Debugf("parse spot price %s %v", *spotPrice.SpotPrice, err)
+						continue
+					}
+
+					zone := *spotPrice.AvailabilityZone
+					spotPrices[zone] = append(spotPrices[zone], SpotPricePoint{
+						Timestamp: *spotPrice.Timestamp,
+						Price:     price,
+					})
+				}
+
+				return true
+			})
+	})
 
 	if err != nil {
-		return errors.Wrapf(err, "parse volume size %s", config.AWSConfig.VolumeSize)
+		return nil, errors.Wrap(err, "describe spot price history")
 	}
 
-	input := &ec2.RequestSpotInstancesInput{
-		Type: aws.String("persistent"),
-		LaunchSpecification: &ec2.RequestSpotLaunchSpecification{
-			IamInstanceProfile: &ec2.IamInstanceProfileSpecification{
-				Name: aws.String(config.AWSConfig.NodesInstanceProfile),
-			},
-			SubnetId:         aws.String(config.AWSConfig.Subnets[req.AvailabilityZone]),
-			SecurityGroupIds: []*string{aws.String(config.AWSConfig.NodesSecurityGroupID)},
-			ImageId:          aws.String(config.AWSConfig.ImageID),
-			InstanceType:     aws.String(config.AWSConfig.InstanceType),
-			KeyName:          aws.String(config.AWSConfig.KeyPairName),
-			BlockDeviceMappings: []*ec2.BlockDeviceMapping{
-				{
-					DeviceName: aws.String("/dev/sda1"),
-					Ebs: &ec2.EbsBlockDevice{
-						DeleteOnTermination: aws.Bool(false),
-						VolumeType:          aws.String("gp2"),
-						VolumeSize:          aws.Int64(volumeSize),
-					},
-				},
-			},
-			UserData: aws.String(base64.StdEncoding.EncodeToString([]byte(
-				fmt.Sprintf("#!/bin/sh\n%s", config.ConfigMap.Data)))),
-		},
-		SpotPrice:     aws.String(req.SpotPrice),
-		ClientToken:   aws.String(uuid.New()),
-		InstanceCount: aws.Int64(req.MachineCount),
-		DryRun:        aws.Bool(config.DryRun),
-		ValidFrom:     aws.Time(time.Now().Add(time.Second * 10)),
-		// TODO(stgleb): pass this as a parameter
-		ValidUntil: aws.Time(time.Now().Add(time.Duration(24*365) * time.Hour)),
+	for zone := range spotPrices {
+		points := spotPrices[zone]
+		sort.Slice(points, func(i, j int) bool {
+			return points[i].Timestamp.Before(points[j].Timestamp)
+		})
 	}
 
-	result, err := svc.RequestSpotInstances(input)
+	return spotPrices, nil
+}
+
+// findNextMinorVersion returns the lowest released version of the minor
+// following the current one, pre-releases are not offered for upgrade.
+func findNextMinorVersion(current string, versions []string) string {
+	currentVersion, err := semver.NewVersion(current)
+
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			logrus.Errorf("request spot instance caused %s", aerr.Message())
-		} else {
-			logrus.Errorf("Error %v", err)
-		}
-		return errors.Wrap(err, "request spot instance")
+		return ""
 	}
 
-	go func() {
-		requestIds := make([]*string, 0)
+	var next *semver.Version
 
-		for _, spot := range result.SpotInstanceRequests {
-			requestIds = append(requestIds, spot.SpotInstanceRequestId)
+	for _, v := range versions {
+		candidate, err := semver.NewVersion(v)
+
+		if err != nil || candidate.Prerelease() != "" {
+			continue
 		}
 
-		describeReq := &ec2.DescribeSpotInstanceRequestsInput{
-			DryRun:                 aws.Bool(false),
-			SpotInstanceRequestIds: requestIds,
+		if candidate.Major() != currentVersion.Major() ||
+			candidate.Minor() != currentVersion.Minor()+1 {
+			continue
 		}
 
-		err = svc.WaitUntilSpotInstanceRequestFulfilled(describeReq)
+		if next == nil || candidate.LessThan(next) {
+			next = candidate
+		}
+	}
 
-		if err != nil {
-			logrus.Errorf("wait until request full filled %v", err)
+	if next == nil {
+		return ""
+	}
+
+	return next.Original()
+}
+
+// UpgradeTargets lists versions kube may be upgraded to, supported versions
+// that can't be upgraded to are excluded with the reason.
+type UpgradeTargets struct {
+	CurrentVersion string            `json:"currentVersion"`
+	Targets        []string          `json:"targets"`
+	Excluded       []ExcludedVersion `json:"excluded"`
+}
+
+// ExcludedVersion is a supported version kube can't be upgraded to.
+type ExcludedVersion struct {
+	Version string `json:"version"`
+	Reason  string `json:"reason"`
+}
+
+// upgradeTargets applies the one minor at a time rule of findNextMinorVersion
+// to the supported versions.
+func upgradeTargets(current string, versions []string) UpgradeTargets {
+	targets := UpgradeTargets{
+		CurrentVersion: current,
+		Targets:        []string{},
+		Excluded:       []ExcludedVersion{},
+	}
+
+	currentVersion, err := semver.NewVersion(current)
+	next := findNextMinorVersion(current, versions)
+
+	for _, v := range versions {
+		if v == next {
+			targets.Targets = append(targets.Targets, v)
+			continue
 		}
 
-		spotRequests, err := svc.DescribeSpotInstanceRequests(describeReq)
+		targets.Excluded = append(targets.Excluded, ExcludedVersion{
+			Version: v,
+			Reason:  excludeReason(currentVersion, err, v, next),
+		})
+	}
 
-		if err != nil {
-			logrus.Errorf("describe spot instance requests %v", err)
-		}
-
-		logrus.Debugf("Tag spot instance requests and spot instances")
-		for _, instance := range spotRequests.SpotInstanceRequests {
-
-			ec2Tags := []*ec2.Tag{
-				{
-					Key:   aws.String("KubernetesCluster"),
-					Value: aws.String(config.Kube.Name),
-				},
-				{
-					Key:   aws.String(clouds.TagClusterID),
-					Value: aws.String(config.Kube.ID),
-				},
-				{
-					Key: aws.String("Name"),
-					Value: aws.String(util.MakeNodeName(config.Kube.Name,
-						uuid.New()[:4], config.IsMaster)),
-				},
-				{
-					Key:   aws.String("Role"),
-					Value: aws.String(util.MakeRole(config.IsMaster)),
-				},
-			}
+	return targets
+}
 
-			tagInput := &ec2.CreateTagsInput{
-				Resources: []*string{},
-				Tags:      ec2Tags,
-			}
+func excludeReason(current *semver.Version, currentErr error, v, next string) string {
+	if currentErr != nil {
+		return "current version is unknown"
+	}
 
-			logrus.Infof("Tag instance %s and request id %s",
-				*instance.InstanceId, *instance.SpotInstanceRequestId)
-			tagInput.Resources = append(tagInput.Resources, instance.InstanceId)
-			tagInput.Resources = append(tagInput.Resources, instance.SpotInstanceRequestId)
+	candidate, err := semver.NewVersion(v)
+
+	switch {
+	case err != nil:
+		return "malformed version"
+	case candidate.Prerelease() != "":
+		return "pre-release version"
+	case !candidate.GreaterThan(current):
+		return "version is not newer than current"
+	case candidate.Major() != current.Major():
+		return "major version upgrade is not supported"
+	case candidate.Minor() > current.Minor()+1:
+		return fmt.Sprintf("minor versions are upgraded one at a time, upgrade to %d.%d first",
+			current.Major(), current.Minor()+1)
+	case candidate.Minor() == current.Minor():
+		return "patch upgrade is not supported"
+	default:
+		return fmt.Sprintf("upgrade goes to %s", next)
+	}
+}
 
-			_, err = svc.CreateTags(tagInput)
+// minEtcdVersions are the lowest etcd versions supported by k8s minors,
+// minors absent here have no requirement.
+var minEtcdVersions = map[string]string{
+	"1.11": "3.2.18",
+	"1.12": "3.2.24",
+	"1.13": "3.2.24",
+	"1.14": "3.3.10",
+	"1.15": "3.3.10",
+	"1.16": "3.3.15",
+}
 
-			if err != nil {
-				logrus.Errorf("tagging spot instances %v", err)
-			}
+// UpgradeCheck is a result of validation of kube before upgrade to
+// the target version, upgrade is refused while there are violations.
+type UpgradeCheck struct {
+	CurrentVersion string             `json:"currentVersion"`
+	TargetVersion  string             `json:"targetVersion"`
+	EtcdVersion    string             `json:"etcdVersion"`
+	Violations     []UpgradeViolation `json:"violations"`
+}
+
+// UpgradeViolation is a reason node or etcd prevents upgrade.
+type UpgradeViolation struct {
+	Node   string `json:"node,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func (v UpgradeViolation) Error() string {
+	if v.Node == "" {
+		return v.Reason
+	}
+
+	return fmt.Sprintf("node %s: %s", v.Node, v.Reason)
+}
+
+// Err returns all violations as a single error, nil means kube
+// may be upgraded.
+func (c UpgradeCheck) Err() error {
+	errs := make([]error, 0, len(c.Violations))
+
+	for _, v := range c.Violations {
+		errs = append(errs, v)
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// checkUpgrade validates version skew of kubelets and etcd against the
+// target version and readiness of nodes, empty etcd version is not checked
+// since etcd may be external to the kube.
+func checkUpgrade(current, target string, nodes []corev1.Node, etcdVersion string) UpgradeCheck {
+	check := UpgradeCheck{
+		CurrentVersion: current,
+		TargetVersion:  target,
+		EtcdVersion:    etcdVersion,
+		Violations:     []UpgradeViolation{},
+	}
+
+	targetVersion, err := semver.NewVersion(target)
+
+	if err != nil {
+		check.Violations = append(check.Violations, UpgradeViolation{
+			Reason: fmt.Sprintf("malformed target version %s", target),
+		})
+		return check
+	}
+
+	for _, node := range nodes {
+		if nodeMachineState(node) != model.MachineStateActive {
+			check.Violations = append(check.Violations, UpgradeViolation{
+				Node:   node.Name,
+				Reason: "node is not ready",
+			})
 		}
-	}()
 
-	return nil
+		if reason := kubeletSkew(node.Status.NodeInfo.KubeletVersion, targetVersion); reason != "" {
+			check.Violations = append(check.Violations, UpgradeViolation{
+				Node:   node.Name,
+				Reason: reason,
+			})
+		}
+	}
+
+	if reason := etcdSkew(etcdVersion, targetVersion); reason != "" {
+		check.Violations = append(check.Violations, UpgradeViolation{
+			Reason: reason,
+		})
+	}
+
+	return check
 }
 
-func getAwsSpotPrices(machineType string, config *steps.Config) ([]string, error) {
-	svc, err := amazon.GetEC2(config.AWSConfig)
+// kubeletSkew returns why kubelet can't work with API server of
+// the target version, kubelet may be at most one minor older.
+func kubeletSkew(kubeletVersion string, target *semver.Version) string {
+	kubelet, err := semver.NewVersion(kubeletVersion)
 
 	if err != nil {
-		return nil, errors.Wrap(err, "get EC2 client")
+		return fmt.Sprintf("unknown kubelet version %q", kubeletVersion)
 	}
 
-	spotPriceReq := &ec2.DescribeSpotPriceHistoryInput{
-		AvailabilityZone: aws.String(config.AWSConfig.AvailabilityZone),
-		EndTime:          aws.Time(time.Now()),
-		StartTime:        aws.Time(time.Now().Add(time.Hour * -24 * 7)),
-		InstanceTypes:    []*string{aws.String(machineType)},
+	switch {
+	case kubelet.Major() != target.Major():
+		return fmt.Sprintf("kubelet %s major version differs from %s",
+			kubelet.Original(), target.Original())
+	case kubelet.Minor() > target.Minor():
+		return fmt.Sprintf("kubelet %s is newer than %s",
+			kubelet.Original(), target.Original())
+	case kubelet.Minor()+1 < target.Minor():
+		return fmt.Sprintf("kubelet %s is more than one minor older than %s",
+			kubelet.Original(), target.Original())
+	}
+
+	return ""
+}
+
+func etcdSkew(etcdVersion string, target *semver.Version) string {
+	if etcdVersion == "" {
+		return ""
+	}
+
+	minVersion, ok := minEtcdVersions[fmt.Sprintf("%d.%d", target.Major(), target.Minor())]
+
+	if !ok {
+		return ""
+	}
+
+	etcd, err := semver.NewVersion(etcdVersion)
+
+	if err != nil {
+		return fmt.Sprintf("unknown etcd version %q", etcdVersion)
+	}
+
+	if etcd.LessThan(semver.MustParse(minVersion)) {
+		return fmt.Sprintf("etcd %s is older than %s required by %s",
+			etcdVersion, minVersion, target.Original())
+	}
+
+	return ""
+}
+
+// upgradedMachines returns names of kube machines which kubelets already
+// run the target version, these machines are skipped when upgrade is resumed.
+func upgradedMachines(k *model.Kube, nodes []corev1.Node, target string) map[string]bool {
+	upgraded := make(map[string]bool)
+	targetVersion, err := semver.NewVersion(target)
+
+	if err != nil {
+		return upgraded
 	}
 
-	prices, _ := svc.DescribeSpotPriceHistory(spotPriceReq)
-	spotPrices := make([]string, 0)
+	masters, workers := machinesFromNodes(nodes, k.Provider, k.Region)
 
-	for _, spotPrice := range prices.SpotPriceHistory {
-		if strings.EqualFold(*spotPrice.ProductDescription, "Linux/UNIX") {
-			spotPrices = append(spotPrices, *spotPrice.SpotPrice)
+	for _, synced := range []map[string]*model.Machine{masters, workers} {
+		for _, node := range synced {
+			kubelet, err := semver.NewVersion(node.KubeletVersion)
+
+			if err != nil || !kubelet.Equal(targetVersion) {
+				continue
+			}
+
+			for _, machines := range []map[string]*model.Machine{k.Masters, k.Nodes} {
+				for name, machine := range machines {
+					if machine == nil {
+						continue
+					}
+
+					if node.Name == machine.Name || (machine.PrivateIp != "" && node.PrivateIp == machine.PrivateIp) {
+						upgraded[name] = true
+					}
+				}
+			}
 		}
 	}
 
-	return spotPrices, nil
+	return upgraded
 }
 
-func findNextMinorVersion(current string, versions []string) string {
-	if len(versions) == 0 {
-		return ""
+func sortedMachineNames(machines map[string]*model.Machine) []string {
+	names := make([]string, 0, len(machines))
+
+	for name := range machines {
+		names = append(names, name)
 	}
 
-	for i := 0; i < len(versions)-1; i++ {
-		if (len(versions[i]) > 3 && len(current) > 3) && strings.EqualFold(versions[i][:4], current[:4]) {
-			return versions[i+1]
+	sort.Strings(names)
+
+	return names
+}
+
+// etcdVersionFromPods returns version of etcd by image of its pods,
+// kubeadm runs etcd as static pods labeled component=etcd.
+func etcdVersionFromPods(pods []corev1.Pod) string {
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			if container.Name != "etcd" {
+				continue
+			}
+
+			slice := strings.Split(container.Image, ":")
+
+			if len(slice) > 1 {
+				// Image tags look like 3.3.10 or 3.3.10-0
+				return strings.SplitN(strings.TrimPrefix(slice[len(slice)-1], "v"), "-", 2)[0]
+			}
 		}
 	}
 
 	return ""
 }
 
-func discoverK8SVersion(kubeConfig *clientcmddapi.Config) (string, error) {
+// restConfigFor builds rest config of the kubeconfig being imported,
+// credential plugins are run by control like for other kubes. Timeout
+// of requests is taken from the context deadline.
+func restConfigFor(ctx context.Context, kubeConfig *clientcmddapi.Config) (*rest.Config, error) {
 	restConf, err := clientcmd.NewNonInteractiveClientConfig(
 		*kubeConfig,
 		kubeConfig.CurrentContext,
@@ -363,7 +1518,17 @@ func discoverK8SVersion(kubeConfig *clientcmddapi.Config) (string, error) {
 	).ClientConfig()
 
 	if err != nil {
-		return "", errors.Wrapf(err, "create rest config")
+		return nil, err
+	}
+
+	if kube, err := kubeFromKubeConfig(*kubeConfig, ""); err == nil && kubeconfig.HasPluginAuth(kube.Auth) {
+		kubeconfig.UsePluginAuth(restConf, kube)
+	}
+
+	restConf.Timeout = DefaultDiscoveryTimeout
+
+	if deadline, ok := ctx.Deadline(); ok {
+		restConf.Timeout = time.Until(deadline)
 	}
 
 	restConf.NegotiatedSerializer = serializer.DirectCodecFactory{CodecFactory: scheme.Codecs}
@@ -371,45 +1536,59 @@ func discoverK8SVersion(kubeConfig *clientcmddapi.Config) (string, error) {
 		restConf.UserAgent = rest.DefaultKubernetesUserAgent()
 	}
 
+	return restConf, nil
+}
+
+func discoverK8SVersion(ctx context.Context, kubeConfig *clientcmddapi.Config) (string, error) {
+	restConf, err := restConfigFor(ctx, kubeConfig)
+
+	if err != nil {
+		return "", errors.Wrapf(err, "create rest config")
+	}
+
 	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConf)
 
 	if err != nil {
 		return "", errors.Wrapf(err, "error create discovery client")
 	}
 
-	serverVersion, err := discoveryClient.ServerVersion()
+	body, err := discoveryClient.RESTClient().Get().AbsPath("/version").Context(ctx).Do().Raw()
 
 	if err != nil {
 		return "", errors.Wrapf(err, "error getting server version")
 	}
 
+	serverVersion := &version.Info{}
+
+	if err := json.Unmarshal(body, serverVersion); err != nil {
+		return "", errors.Wrapf(err, "decode server version")
+	}
+
 	return strings.TrimPrefix(serverVersion.GitVersion, "v"), nil
 }
 
-func discoverHelmVersion(kubeConfig *clientcmddapi.Config) (string, error) {
-	restConf, err := clientcmd.NewNonInteractiveClientConfig(
-		*kubeConfig,
-		kubeConfig.CurrentContext,
-		&clientcmd.ConfigOverrides{},
-		nil,
-	).ClientConfig()
+// discoverHelmVersion returns tiller version, Helm3 or HelmNotInstalled.
+func discoverHelmVersion(ctx context.Context, kubeConfig *clientcmddapi.Config) (string, error) {
+	restConf, err := restConfigFor(ctx, kubeConfig)
 
 	if err != nil {
 		return "", errors.Wrapf(err, "create rest config")
 	}
 
-	restConf.NegotiatedSerializer = serializer.DirectCodecFactory{CodecFactory: scheme.Codecs}
-	if len(restConf.UserAgent) == 0 {
-		restConf.UserAgent = rest.DefaultKubernetesUserAgent()
-	}
-
 	clientSet, err := kubernetes.NewForConfig(restConf)
 
 	if err != nil {
 		return "", errors.Wrapf(err, "get client set")
 	}
 
-	deploymentList, err := clientSet.AppsV1().Deployments("kube-system").List(v1.ListOptions{})
+	deploymentList := &appsv1.DeploymentList{}
+	err = clientSet.AppsV1().RESTClient().Get().
+		Namespace("kube-system").
+		Resource("deployments").
+		VersionedParams(&v1.ListOptions{}, scheme.ParameterCodec).
+		Context(ctx).
+		Do().
+		Into(deploymentList)
 
 	if err != nil {
 		return "", errors.Wrapf(err, "list deployments")
@@ -427,5 +1606,36 @@ func discoverHelmVersion(kubeConfig *clientcmddapi.Config) (string, error) {
 		}
 	}
 
-	return "", nil
+	// Helm 3 has no tiller, it stores releases in secrets of any namespace
+	secretList := &corev1.SecretList{}
+	err = clientSet.CoreV1().RESTClient().Get().
+		Resource("secrets").
+		VersionedParams(&v1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("type", helm3ReleaseType).String(),
+			Limit:         1,
+		}, scheme.ParameterCodec).
+		Context(ctx).
+		Do().
+		Into(secretList)
+
+	if err != nil {
+		return "", errors.Wrapf(err, "list helm 3 releases")
+	}
+
+	if len(secretList.Items) > 0 {
+		return Helm3, nil
+	}
+
+	return HelmNotInstalled, nil
+}
+
+// helmMajorVersion returns 0 when helm is not installed or unknown.
+func helmMajorVersion(helmVersion string) int {
+	v, err := semver.NewVersion(helmVersion)
+
+	if err != nil {
+		return 0
+	}
+
+	return int(v.Major())
 }
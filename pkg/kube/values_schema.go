@@ -0,0 +1,216 @@
+package kube
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// validateSchema checks the value against the json schema and returns
+// violations by path. Keywords charts use for values are supported,
+// references and combinations of schemas are not and they are ignored.
+func validateSchema(schema map[string]interface{}, value interface{}, path string) []string {
+	var violations []string
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !matchesType(types, value) {
+		return []string{violation(path, "invalid type, expected %s, given %s",
+			strings.Join(types, " or "), jsonType(value))}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && !containsValue(enum, value) {
+		violations = append(violations, violation(path, "must be one of %v", enum))
+	}
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, value) {
+		violations = append(violations, violation(path, "must be %v", c))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		violations = append(violations, validateObject(schema, v, path)...)
+	case []interface{}:
+		violations = append(violations, validateArray(schema, v, path)...)
+	case string:
+		violations = append(violations, validateString(schema, v, path)...)
+	case float64:
+		violations = append(violations, validateNumber(schema, v, path)...)
+	}
+
+	return violations
+}
+
+func validateObject(schema map[string]interface{}, obj map[string]interface{}, path string) []string {
+	var violations []string
+
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, ok := obj[name]; !ok {
+					violations = append(violations, violation(path, "%s is required", name))
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if property, ok := properties[k].(map[string]interface{}); ok {
+			violations = append(violations, validateSchema(property, obj[k], joinPath(path, k))...)
+			continue
+		}
+		if _, ok := properties[k]; ok {
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				violations = append(violations, violation(path, "additional property %s is not allowed", k))
+			}
+		case map[string]interface{}:
+			violations = append(violations, validateSchema(additional, obj[k], joinPath(path, k))...)
+		}
+	}
+
+	return violations
+}
+
+func validateArray(schema map[string]interface{}, items []interface{}, path string) []string {
+	var violations []string
+
+	if min, ok := schema["minItems"].(float64); ok && float64(len(items)) < min {
+		violations = append(violations, violation(path, "must have at least %v items", min))
+	}
+	if max, ok := schema["maxItems"].(float64); ok && float64(len(items)) > max {
+		violations = append(violations, violation(path, "must have at most %v items", max))
+	}
+
+	if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+		for i, item := range items {
+			violations = append(violations, validateSchema(itemSchema, item,
+				path+"["+strconv.Itoa(i)+"]")...)
+		}
+	}
+
+	return violations
+}
+
+func validateString(schema map[string]interface{}, s string, path string) []string {
+	var violations []string
+	length := float64(utf8.RuneCountInString(s))
+
+	if min, ok := schema["minLength"].(float64); ok && length < min {
+		violations = append(violations, violation(path, "must be at least %v characters long", min))
+	}
+	if max, ok := schema["maxLength"].(float64); ok && length > max {
+		violations = append(violations, violation(path, "must be at most %v characters long", max))
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(s) {
+			violations = append(violations, violation(path, "must match %s", pattern))
+		}
+	}
+
+	return violations
+}
+
+func validateNumber(schema map[string]interface{}, n float64, path string) []string {
+	var violations []string
+
+	if min, ok := schema["minimum"].(float64); ok && n < min {
+		violations = append(violations, violation(path, "must be greater than or equal to %v", min))
+	}
+	if max, ok := schema["maximum"].(float64); ok && n > max {
+		violations = append(violations, violation(path, "must be less than or equal to %v", max))
+	}
+	if min, ok := schema["exclusiveMinimum"].(float64); ok && n <= min {
+		violations = append(violations, violation(path, "must be greater than %v", min))
+	}
+	if max, ok := schema["exclusiveMaximum"].(float64); ok && n >= max {
+		violations = append(violations, violation(path, "must be less than %v", max))
+	}
+
+	return violations
+}
+
+func schemaTypes(t interface{}) []string {
+	switch v := t.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		types := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesType(types []string, value interface{}) bool {
+	actual := jsonType(value)
+
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return reflect.TypeOf(value).String()
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func violation(path, format string, args ...interface{}) string {
+	if path == "" {
+		path = "(root)"
+	}
+	return path + ": " + fmt.Sprintf(format, args...)
+}
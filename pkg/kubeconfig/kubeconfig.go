@@ -1,6 +1,7 @@
 package kubeconfig
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -16,6 +17,8 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmddapi "k8s.io/client-go/tools/clientcmd/api"
+	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
+	clientcmdapiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
 )
 
 func NewConfigFor(k *model.Kube) (*rest.Config, error) {
@@ -31,6 +34,10 @@ func NewConfigFor(k *model.Kube) (*rest.Config, error) {
 		nil,
 	).ClientConfig()
 
+	if err == nil && HasPluginAuth(k.Auth) {
+		UsePluginAuth(restConf, k)
+	}
+
 	restConf.NegotiatedSerializer = serializer.DirectCodecFactory{CodecFactory: scheme.Codecs}
 	if len(restConf.UserAgent) == 0 {
 		restConf.UserAgent = rest.DefaultKubernetesUserAgent()
@@ -65,8 +72,13 @@ func CoreV1Client(k *model.Kube) (corev1client.CoreV1Interface, error) {
 	return corev1client.NewForConfig(cfg)
 }
 
-// adminKubeConfig returns a cluster-admin kubeconfig for provided cluster.
+// adminKubeConfig returns a cluster-admin kubeconfig for provided cluster,
+// kubeconfig stored with imported cluster is used as is.
 func AdminKubeConfig(k *model.Kube) (clientcmddapi.Config, error) {
+	if k != nil && k.Auth.KubeConfig != "" {
+		return StoredKubeConfig(k)
+	}
+
 	// TODO: this should be an address of the master load balancer
 	if k == nil || (k.ExternalDNSName == "" && len(k.Masters) == 0) {
 		// TODO: use another base error, not ErrNotFound
@@ -83,22 +95,33 @@ func AdminKubeConfig(k *model.Kube) (clientcmddapi.Config, error) {
 	} else {
 		// Use public IP in case if DNS name is absent
 		m := util.GetRandomNode(k.Masters)
-		apiAddr = fmt.Sprintf("https://%s:%d", m.PublicIp, k.APIServerPort)
+		addr := m.PublicIp
+
+		if addr == "" {
+			addr = m.PrivateIp
+		}
+
+		apiAddr = fmt.Sprintf("https://%s:%d", addr, k.APIServerPort)
+	}
+
+	cluster := &clientcmddapi.Cluster{
+		Server:                   apiAddr,
+		CertificateAuthorityData: []byte(k.Auth.CACert),
+	}
+
+	// client-go does not allow CA together with insecure flag
+	if k.Auth.InsecureSkipTLSVerify {
+		cluster.CertificateAuthorityData = nil
+		cluster.InsecureSkipTLSVerify = true
 	}
 
 	// TODO: add validation
 	return clientcmddapi.Config{
 		AuthInfos: map[string]*clientcmddapi.AuthInfo{
-			adminContext(k.Name): {
-				ClientCertificateData: []byte(k.Auth.AdminCert),
-				ClientKeyData:         []byte(k.Auth.AdminKey),
-			},
+			adminContext(k.Name): adminAuthInfo(k.Auth),
 		},
 		Clusters: map[string]*clientcmddapi.Cluster{
-			k.Name: {
-				Server:                   apiAddr,
-				CertificateAuthorityData: []byte(k.Auth.CACert),
-			},
+			k.Name: cluster,
 		},
 		Contexts: map[string]*clientcmddapi.Context{
 			adminContext(k.Name): {
@@ -110,6 +133,88 @@ func AdminKubeConfig(k *model.Kube) (clientcmddapi.Config, error) {
 	}, nil
 }
 
+// StoredKubeConfig rebuilds kubeconfig stored with imported kube, kubes
+// imported before kubeconfig was stored have none.
+func StoredKubeConfig(k *model.Kube) (clientcmddapi.Config, error) {
+	if k == nil || k.Auth.KubeConfig == "" {
+		return clientcmddapi.Config{}, errors.Wrap(sgerrors.ErrNotFound, "stored kubeconfig")
+	}
+
+	kubeConfig, err := clientcmd.Load([]byte(k.Auth.KubeConfig))
+
+	if err != nil {
+		return clientcmddapi.Config{}, errors.Wrap(err, "load stored kubeconfig")
+	}
+
+	if kubeConfig.Contexts[kubeConfig.CurrentContext] == nil {
+		return clientcmddapi.Config{}, errors.Wrapf(sgerrors.ErrNotFound,
+			"context %s of stored kubeconfig", kubeConfig.CurrentContext)
+	}
+
+	return *kubeConfig, nil
+}
+
+// Marshal serializes kubeconfig to be stored with imported kube, it is
+// converted to v1 and encoded with encoding/json that kubeconfig loading
+// accepts as well as yaml.
+func Marshal(kubeConfig clientcmddapi.Config) ([]byte, error) {
+	v1Config := &clientcmdapiv1.Config{}
+
+	if err := clientcmdlatest.Scheme.Convert(&kubeConfig, v1Config, nil); err != nil {
+		return nil, errors.Wrap(err, "convert kubeconfig")
+	}
+
+	v1Config.APIVersion = clientcmdlatest.Version
+	v1Config.Kind = "Config"
+
+	return json.Marshal(v1Config)
+}
+
+// adminAuthInfo uses credentials that are present, client-go does not
+// allow both token and basic auth, so token takes precedence. Credential
+// plugins are kept for kubeconfig users, control runs them by itself.
+func adminAuthInfo(auth model.Auth) *clientcmddapi.AuthInfo {
+	authInfo := &clientcmddapi.AuthInfo{}
+
+	if auth.AdminCert != "" && auth.AdminKey != "" {
+		authInfo.ClientCertificateData = []byte(auth.AdminCert)
+		authInfo.ClientKeyData = []byte(auth.AdminKey)
+	}
+
+	if auth.AdminToken != "" {
+		authInfo.Token = auth.AdminToken
+	} else if auth.AdminUsername != "" {
+		authInfo.Username = auth.AdminUsername
+		authInfo.Password = auth.AdminPassword
+	}
+
+	if exec := auth.AdminExec; exec != nil {
+		authInfo.Exec = &clientcmddapi.ExecConfig{
+			Command:    exec.Command,
+			Args:       exec.Args,
+			APIVersion: exec.APIVersion,
+		}
+
+		if authInfo.Exec.APIVersion == "" {
+			authInfo.Exec.APIVersion = defaultExecAPIVersion
+		}
+
+		for name, value := range exec.Env {
+			authInfo.Exec.Env = append(authInfo.Exec.Env, clientcmddapi.ExecEnvVar{
+				Name:  name,
+				Value: value,
+			})
+		}
+	} else if provider := auth.AdminAuthProvider; provider != nil {
+		authInfo.AuthProvider = &clientcmddapi.AuthProviderConfig{
+			Name:   provider.Name,
+			Config: provider.Config,
+		}
+	}
+
+	return authInfo
+}
+
 func setGroupDefaults(config *rest.Config, gv schema.GroupVersion) {
 	config.GroupVersion = &gv
 	if len(gv.Group) == 0 {
@@ -83,3 +83,153 @@ func TestDiscoveryClient(t *testing.T) {
 		}
 	}
 }
+
+func TestAdminAuthInfo(t *testing.T) {
+	testCases := []struct {
+		description string
+		auth        model.Auth
+
+		expectedCert     string
+		expectedToken    string
+		expectedUsername string
+	}{
+		{
+			description: "client certificate",
+			auth: model.Auth{
+				AdminCert: "cert",
+				AdminKey:  "key",
+			},
+			expectedCert: "cert",
+		},
+		{
+			description: "token",
+			auth: model.Auth{
+				AdminToken: "token",
+			},
+			expectedToken: "token",
+		},
+		{
+			description: "basic auth",
+			auth: model.Auth{
+				AdminUsername: "admin",
+				AdminPassword: "password",
+			},
+			expectedUsername: "admin",
+		},
+		{
+			description: "token takes precedence over basic auth",
+			auth: model.Auth{
+				AdminToken:    "token",
+				AdminUsername: "admin",
+				AdminPassword: "password",
+			},
+			expectedToken: "token",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		authInfo := adminAuthInfo(testCase.auth)
+
+		if string(authInfo.ClientCertificateData) != testCase.expectedCert ||
+			authInfo.Token != testCase.expectedToken ||
+			authInfo.Username != testCase.expectedUsername {
+			t.Errorf("Wrong auth info %v", authInfo)
+		}
+	}
+
+	k := &model.Kube{
+		Name:            "kube",
+		ExternalDNSName: "10.20.30.40",
+		Auth: model.Auth{
+			AdminToken: "token",
+		},
+	}
+
+	if _, err := NewConfigFor(k); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	k.Auth.CACert = "ca cert"
+	k.Auth.InsecureSkipTLSVerify = true
+	cfg, err := NewConfigFor(k)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if !cfg.Insecure || len(cfg.CAData) != 0 {
+		t.Errorf("Insecure config must not have CA %v", cfg.TLSClientConfig)
+	}
+
+	k.Auth = model.Auth{
+		AdminExec: &model.ExecAuth{
+			Command: "aws-iam-authenticator",
+			Args:    []string{"token", "-i", "kube"},
+			Env:     map[string]string{"AWS_PROFILE": "kube"},
+		},
+	}
+	authInfo := adminAuthInfo(k.Auth)
+
+	if authInfo.Exec == nil || authInfo.Exec.APIVersion != defaultExecAPIVersion ||
+		len(authInfo.Exec.Env) != 1 {
+		t.Errorf("Wrong exec config %v", authInfo.Exec)
+	}
+
+	cfg, err = NewConfigFor(k)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if cfg.ExecProvider != nil || cfg.WrapTransport == nil {
+		t.Errorf("Plugin must be run by control")
+	}
+}
+
+func TestAdminKubeConfigStored(t *testing.T) {
+	kube := &model.Kube{
+		Name:            "kube",
+		ExternalDNSName: "https://kube",
+		Auth: model.Auth{
+			KubeConfig: `apiVersion: v1
+kind: Config
+clusters:
+- name: kube
+  cluster:
+    server: https://kube:6443
+contexts:
+- name: admin
+  context:
+    cluster: kube
+    user: admin
+    namespace: apps
+users:
+- name: admin
+  user:
+    token: token
+current-context: admin
+`,
+		},
+	}
+
+	kubeConfig, err := AdminKubeConfig(kube)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if kubeConfig.CurrentContext != "admin" || kubeConfig.Contexts["admin"].Namespace != "apps" {
+		t.Errorf("Stored kubeconfig must be used %v", kubeConfig)
+	}
+
+	kube.Auth.KubeConfig = "current-context: missing"
+
+	if _, err := AdminKubeConfig(kube); !sgerrors.IsNotFound(errors.Cause(err)) {
+		t.Errorf("Expected not found error actual %v", err)
+	}
+
+	if _, err := StoredKubeConfig(&model.Kube{}); !sgerrors.IsNotFound(errors.Cause(err)) {
+		t.Errorf("Expected not found error actual %v", err)
+	}
+}
@@ -0,0 +1,519 @@
+package kubeconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/rest"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/clouds/awssdk"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+// Credential plugins are run on control host, binaries of plugins used by
+// imported kubes must be in its PATH:
+//   - aws-iam-authenticator or aws for EKS kubes that are not linked to
+//     AWS cloud account, tokens of linked kubes are generated by control;
+//   - gke-gcloud-auth-plugin for GKE kubes, or gcloud for kubeconfigs
+//     with gcp auth-provider;
+//   - kubelogin for AKS and OIDC kubes.
+//
+// Only plugins allowed by the operator are run, they are named without
+// path in kubeconfigs and looked up in PATH of control.
+const (
+	ExecPluginTimeout = 30 * time.Second
+
+	defaultExecAPIVersion = "client.authentication.k8s.io/v1alpha1"
+
+	// Token is refreshed when it expires within the delta
+	tokenExpiryDelta = time.Minute
+	// Token is reused for this period when plugin does not tell its expiration
+	defaultPluginTokenTTL = 5 * time.Minute
+
+	awsIAMAuthenticator = "aws-iam-authenticator"
+	awsCLI              = "aws"
+
+	gcpTokenKey  = "{.credential.access_token}"
+	gcpExpiryKey = "{.credential.token_expiry}"
+)
+
+// AccountGetter gets cloud accounts that kubes are linked to.
+type AccountGetter interface {
+	Get(context.Context, string) (*model.CloudAccount, error)
+}
+
+// DefaultExecPlugins are the credential plugins control runs unless
+// the operator allows other ones.
+var DefaultExecPlugins = []string{
+	awsIAMAuthenticator,
+	awsCLI,
+	"gke-gcloud-auth-plugin",
+	"gcloud",
+	"kubelogin",
+}
+
+var (
+	accountGetter  AccountGetter
+	allowedPlugins = pluginSet(DefaultExecPlugins)
+	tokens         = &tokenCache{tokens: make(map[string]cachedToken)}
+
+	lookPath   = exec.LookPath
+	runCommand = runPluginCommand
+	now        = time.Now
+)
+
+// SetAccountGetter sets accounts used to generate tokens of EKS kubes.
+func SetAccountGetter(getter AccountGetter) {
+	accountGetter = getter
+}
+
+// SetAllowedPlugins sets names of credential plugins control may run,
+// commands of other plugins are rejected.
+func SetAllowedPlugins(names []string) {
+	allowedPlugins = pluginSet(names)
+}
+
+func pluginSet(names []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			set[name] = struct{}{}
+		}
+	}
+
+	return set
+}
+
+type cachedToken struct {
+	token   string
+	expires time.Time
+}
+
+type tokenCache struct {
+	m      sync.Mutex
+	tokens map[string]cachedToken
+}
+
+func (c *tokenCache) get(key string) (string, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	entry, ok := c.tokens[key]
+
+	if !ok || now().Add(tokenExpiryDelta).After(entry.expires) {
+		return "", false
+	}
+
+	return entry.token, true
+}
+
+func (c *tokenCache) set(key, token string, expires time.Time) {
+	c.m.Lock()
+	c.tokens[key] = cachedToken{token: token, expires: expires}
+	c.m.Unlock()
+}
+
+// HasPluginAuth returns true when kube credentials are obtained from
+// an exec plugin or auth provider.
+func HasPluginAuth(auth model.Auth) bool {
+	return auth.AdminExec != nil || auth.AdminAuthProvider != nil
+}
+
+// UsePluginAuth makes the config authenticate requests with tokens of the
+// kube credential plugin, tokens are refreshed before they expire.
+func UsePluginAuth(cfg *rest.Config, k *model.Kube) {
+	kube := *k
+
+	// Plugins are run by control instead of client-go
+	cfg.ExecProvider = nil
+	cfg.AuthProvider = nil
+	cfg.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		return &pluginRoundTripper{
+			kube: &kube,
+			rt:   rt,
+		}
+	}
+}
+
+// CheckPluginAuth returns validation error when plugin that kube
+// credentials are obtained from is not allowed or not installed
+// on control host.
+func CheckPluginAuth(k *model.Kube) error {
+	if exec := k.Auth.AdminExec; exec != nil {
+		if EKSClusterName(exec) != "" && k.AccountName != "" {
+			return nil
+		}
+
+		return checkPluginInstalled(exec.Command)
+	}
+
+	if provider := k.Auth.AdminAuthProvider; provider != nil {
+		if cmdPath := provider.Config["cmd-path"]; cmdPath != "" {
+			return checkPluginInstalled(cmdPath)
+		}
+
+		if provider.Config["access-token"] == "" && provider.Config["id-token"] == "" {
+			return errors.Wrapf(sgerrors.ErrValidationFailed,
+				"auth provider %s has neither command nor token", provider.Name)
+		}
+	}
+
+	return nil
+}
+
+// EKSClusterName returns name of EKS cluster which token is printed
+// by aws-iam-authenticator or aws cli.
+func EKSClusterName(exec *model.ExecAuth) string {
+	var flags []string
+
+	switch filepath.Base(exec.Command) {
+	case awsIAMAuthenticator:
+		flags = []string{"-i", "--cluster-id"}
+	case awsCLI:
+		flags = []string{"--cluster-name"}
+	default:
+		return ""
+	}
+
+	return argValue(exec.Args, flags...)
+}
+
+type pluginRoundTripper struct {
+	kube *model.Kube
+	rt   http.RoundTripper
+}
+
+func (p *pluginRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := PluginToken(req.Context(), p.kube)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "get token of kube %s", p.kube.Name)
+	}
+
+	req = req.WithContext(req.Context())
+	req.Header = cloneHeader(req.Header)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return p.rt.RoundTrip(req)
+}
+
+// PluginToken returns cached token of the kube or gets a new one from
+// its credential plugin, kubes being imported are cached by address.
+func PluginToken(ctx context.Context, k *model.Kube) (string, error) {
+	key := k.ID
+
+	if key == "" {
+		key = k.ExternalDNSName
+	}
+
+	if token, ok := tokens.get(key); ok {
+		return token, nil
+	}
+
+	token, expires, err := fetchPluginToken(ctx, k)
+
+	if err != nil {
+		return "", err
+	}
+
+	tokens.set(key, token, expires)
+
+	return token, nil
+}
+
+func fetchPluginToken(ctx context.Context, k *model.Kube) (string, time.Time, error) {
+	if exec := k.Auth.AdminExec; exec != nil {
+		if clusterName := EKSClusterName(exec); clusterName != "" &&
+			k.AccountName != "" && accountGetter != nil {
+			return eksToken(ctx, k, clusterName)
+		}
+
+		return execToken(ctx, exec)
+	}
+
+	if provider := k.Auth.AdminAuthProvider; provider != nil {
+		return authProviderToken(ctx, provider)
+	}
+
+	return "", time.Time{}, errors.Wrap(sgerrors.ErrNilEntity, "credential plugin")
+}
+
+func eksToken(ctx context.Context, k *model.Kube, clusterName string) (string, time.Time, error) {
+	acc, err := accountGetter.Get(ctx, k.AccountName)
+
+	if err != nil {
+		return "", time.Time{}, errors.Wrapf(err, "get cloud account %s", k.AccountName)
+	}
+
+	if acc.Provider != clouds.AWS {
+		return "", time.Time{}, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"EKS kube is linked to %s account %s", acc.Provider, acc.Name)
+	}
+
+	config := &steps.Config{}
+	if err := util.FillCloudAccountCredentials(acc, config); err != nil {
+		return "", time.Time{}, errors.Wrap(err, "fill cloud account credentials")
+	}
+
+	region := argValue(k.Auth.AdminExec.Args, "--region")
+
+	if region == "" {
+		region = k.Region
+	}
+
+	sess, err := awssdk.NewSession(region, util.AWSCredentials(config.AWSConfig))
+
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(sgerrors.ErrInvalidCredentials, err.Error())
+	}
+
+	return awssdk.EKSToken(sess, clusterName, now())
+}
+
+type execCredential struct {
+	Status *struct {
+		Token               string     `json:"token"`
+		ExpirationTimestamp *time.Time `json:"expirationTimestamp"`
+	} `json:"status"`
+}
+
+func execToken(ctx context.Context, exec *model.ExecAuth) (string, time.Time, error) {
+	env := make(map[string]string)
+
+	for name, value := range exec.Env {
+		env[name] = value
+	}
+
+	if exec.APIVersion != "" {
+		env["KUBERNETES_EXEC_INFO"] = fmt.Sprintf(`{"apiVersion":%q,"kind":"ExecCredential",`+
+			`"spec":{"interactive":false}}`, exec.APIVersion)
+	}
+
+	out, err := runCommand(ctx, exec.Command, exec.Args, env)
+
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	cred := &execCredential{}
+
+	if err := json.Unmarshal(out, cred); err != nil {
+		return "", time.Time{}, errors.Wrapf(err, "decode credential of plugin %s", exec.Command)
+	}
+
+	if cred.Status == nil || cred.Status.Token == "" {
+		return "", time.Time{}, errors.Errorf("plugin %s has not printed a token", exec.Command)
+	}
+
+	expires := now().Add(defaultPluginTokenTTL)
+
+	if cred.Status.ExpirationTimestamp != nil {
+		expires = *cred.Status.ExpirationTimestamp
+	}
+
+	return cred.Status.Token, expires, nil
+}
+
+// authProviderToken runs command of the gcp auth provider, tokens of other
+// providers are used until they expire since they can not be refreshed.
+func authProviderToken(ctx context.Context, provider *model.AuthProvider) (string, time.Time, error) {
+	cmdPath := provider.Config["cmd-path"]
+
+	if cmdPath == "" {
+		token := provider.Config["access-token"]
+
+		if token == "" {
+			token = provider.Config["id-token"]
+		}
+
+		if token == "" {
+			return "", time.Time{}, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"auth provider %s has neither command nor token", provider.Name)
+		}
+
+		expires, err := time.Parse(time.RFC3339, provider.Config["expiry"])
+
+		if err != nil {
+			expires = now().Add(defaultPluginTokenTTL)
+		}
+
+		return token, expires, nil
+	}
+
+	out, err := runCommand(ctx, cmdPath, strings.Fields(provider.Config["cmd-args"]), nil)
+
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	var data interface{}
+
+	if err := json.Unmarshal(out, &data); err != nil {
+		return "", time.Time{}, errors.Wrapf(err, "decode output of %s", cmdPath)
+	}
+
+	tokenKey := provider.Config["token-key"]
+
+	if tokenKey == "" {
+		tokenKey = gcpTokenKey
+	}
+
+	token, _ := jsonField(data, tokenKey).(string)
+
+	if token == "" {
+		return "", time.Time{}, errors.Errorf("%s has not printed %s", cmdPath, tokenKey)
+	}
+
+	expiryKey := provider.Config["expiry-key"]
+
+	if expiryKey == "" {
+		expiryKey = gcpExpiryKey
+	}
+
+	expiry, _ := jsonField(data, expiryKey).(string)
+	expires, err := time.Parse(time.RFC3339, expiry)
+
+	if err != nil {
+		expires = now().Add(defaultPluginTokenTTL)
+	}
+
+	return token, expires, nil
+}
+
+func checkPluginInstalled(command string) error {
+	if err := checkPluginAllowed(command); err != nil {
+		return err
+	}
+
+	if _, err := lookPath(command); err != nil {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"credential plugin %s must be installed on control host", command)
+	}
+
+	return nil
+}
+
+// checkPluginAllowed returns validation error unless the command is
+// a name of plugin allowed by the operator, paths are never allowed.
+func checkPluginAllowed(command string) error {
+	if strings.ContainsAny(command, `/\`) {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"credential plugin %s must be named without path", command)
+	}
+
+	if _, ok := allowedPlugins[command]; !ok {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"credential plugin %s is not allowed on control host", command)
+	}
+
+	return nil
+}
+
+// runPluginCommand runs the allowed plugin with timeout, plugin does not
+// get stdin and environment of control except of PATH and HOME.
+func runPluginCommand(ctx context.Context, command string, args []string,
+	env map[string]string) ([]byte, error) {
+	// Kubes imported before plugins were restricted are checked too
+	if err := checkPluginAllowed(command); err != nil {
+		return nil, err
+	}
+
+	path, err := lookPath(command)
+
+	if err != nil {
+		return nil, errors.Wrapf(sgerrors.ErrNotFound,
+			"credential plugin %s is not installed on control host", command)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ExecPluginTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + os.Getenv("HOME"),
+	}
+
+	for name, value := range env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	logrus.Debugf("run credential plugin %s", command)
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, errors.Errorf("credential plugin %s has not finished in %v",
+				command, ExecPluginTimeout)
+		}
+
+		return nil, errors.Wrapf(err, "run credential plugin %s: %s",
+			command, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// argValue returns value of the first flag found in args, both
+// "--flag value" and "--flag=value" forms are supported.
+func argValue(args []string, flags ...string) string {
+	for i, arg := range args {
+		for _, flag := range flags {
+			if arg == flag && i+1 < len(args) {
+				return args[i+1]
+			}
+
+			if strings.HasPrefix(arg, flag+"=") {
+				return strings.TrimPrefix(arg, flag+"=")
+			}
+		}
+	}
+
+	return ""
+}
+
+// jsonField returns field of decoded json by simple jsonpath
+// like {.credential.access_token}.
+func jsonField(data interface{}, path string) interface{} {
+	path = strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+
+	for _, key := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		fields, ok := data.(map[string]interface{})
+
+		if !ok {
+			return nil
+		}
+
+		data = fields[key]
+	}
+
+	return data
+}
+
+func cloneHeader(header http.Header) http.Header {
+	clone := make(http.Header, len(header))
+
+	for key, values := range header {
+		clone[key] = append([]string(nil), values...)
+	}
+
+	return clone
+}
@@ -0,0 +1,368 @@
+package kubeconfig
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+func TestEKSClusterName(t *testing.T) {
+	testCases := []struct {
+		exec     *model.ExecAuth
+		expected string
+	}{
+		{
+			exec: &model.ExecAuth{
+				Command: "aws-iam-authenticator",
+				Args:    []string{"token", "-i", "eks-1"},
+			},
+			expected: "eks-1",
+		},
+		{
+			exec: &model.ExecAuth{
+				Command: "/usr/local/bin/aws",
+				Args:    []string{"eks", "get-token", "--cluster-name=eks-2"},
+			},
+			expected: "eks-2",
+		},
+		{
+			exec: &model.ExecAuth{
+				Command: "gke-gcloud-auth-plugin",
+				Args:    []string{"--cluster-name", "gke"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		if name := EKSClusterName(testCase.exec); name != testCase.expected {
+			t.Errorf("Wrong cluster name expected %s actual %s", testCase.expected, name)
+		}
+	}
+}
+
+func TestPluginToken(t *testing.T) {
+	defer func() {
+		runCommand = runPluginCommand
+		now = time.Now
+	}()
+
+	current := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+
+	calls := 0
+	runCommand = func(ctx context.Context, command string, args []string,
+		env map[string]string) ([]byte, error) {
+		calls++
+
+		if !strings.Contains(env["KUBERNETES_EXEC_INFO"], "v1beta1") {
+			t.Errorf("Wrong exec info %v", env)
+		}
+
+		return []byte(`{"kind":"ExecCredential","status":{"token":"token",` +
+			`"expirationTimestamp":"2019-05-01T12:10:00Z"}}`), nil
+	}
+
+	k := &model.Kube{
+		ID: "token-kube",
+		Auth: model.Auth{
+			AdminExec: &model.ExecAuth{
+				Command:    "plugin",
+				APIVersion: "client.authentication.k8s.io/v1beta1",
+			},
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		token, err := PluginToken(context.Background(), k)
+
+		if err != nil || token != "token" {
+			t.Errorf("Wrong token %s error %v", token, err)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("Token must be cached, plugin was run %d times", calls)
+	}
+
+	// Token expires within a minute
+	current = current.Add(9*time.Minute + 30*time.Second)
+
+	if _, err := PluginToken(context.Background(), k); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("Token must be refreshed, plugin was run %d times", calls)
+	}
+}
+
+func TestAuthProviderToken(t *testing.T) {
+	defer func() {
+		runCommand = runPluginCommand
+	}()
+
+	runCommand = func(ctx context.Context, command string, args []string,
+		env map[string]string) ([]byte, error) {
+		if command != "gcloud" || len(args) != 2 {
+			t.Errorf("Wrong command %s %v", command, args)
+		}
+
+		return []byte(`{"credential":{"access_token":"gcp-token",` +
+			`"token_expiry":"2019-05-01T12:10:00Z"}}`), nil
+	}
+
+	testCases := []struct {
+		description string
+		config      map[string]string
+
+		expectedToken   string
+		expectedExpires time.Time
+		isErr           bool
+	}{
+		{
+			description: "gcp command",
+			config: map[string]string{
+				"cmd-path": "gcloud",
+				"cmd-args": "config config-helper",
+			},
+			expectedToken:   "gcp-token",
+			expectedExpires: time.Date(2019, 5, 1, 12, 10, 0, 0, time.UTC),
+		},
+		{
+			description: "static token",
+			config: map[string]string{
+				"id-token": "oidc-token",
+				"expiry":   "2019-05-01T12:20:00Z",
+			},
+			expectedToken:   "oidc-token",
+			expectedExpires: time.Date(2019, 5, 1, 12, 20, 0, 0, time.UTC),
+		},
+		{
+			description: "no token",
+			config:      map[string]string{},
+			isErr:       true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		token, expires, err := authProviderToken(context.Background(),
+			&model.AuthProvider{Name: "gcp", Config: testCase.config})
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if token != testCase.expectedToken || !expires.Equal(testCase.expectedExpires) {
+			t.Errorf("Wrong token %s expires %v", token, expires)
+		}
+	}
+}
+
+func TestRunPluginCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugins")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "plugin")
+	err = ioutil.WriteFile(script, []byte("#!/bin/sh\n"+
+		"if [ -n \"$SECRET\" ]; then echo leaked >&2; exit 1; fi\n"+
+		"if [ \"$1\" = fail ]; then echo denied >&2; exit 1; fi\n"+
+		"echo \"$TOKEN\"\n"), 0755)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	os.Setenv("SECRET", "secret")
+	defer os.Unsetenv("SECRET")
+
+	defer func() {
+		lookPath = exec.LookPath
+		SetAllowedPlugins(DefaultExecPlugins)
+	}()
+
+	lookPath = func(command string) (string, error) {
+		return exec.LookPath(filepath.Join(dir, command))
+	}
+	SetAllowedPlugins([]string{"plugin", "missing"})
+
+	out, err := runPluginCommand(context.Background(), "plugin", nil,
+		map[string]string{"TOKEN": "token"})
+
+	if err != nil || strings.TrimSpace(string(out)) != "token" {
+		t.Errorf("Wrong output %s error %v", out, err)
+	}
+
+	_, err = runPluginCommand(context.Background(), "plugin", []string{"fail"}, nil)
+
+	if err == nil || !strings.Contains(err.Error(), "denied") {
+		t.Errorf("Error must contain stderr %v", err)
+	}
+
+	_, err = runPluginCommand(context.Background(), "missing", nil, nil)
+
+	if !sgerrors.IsNotFound(errors.Cause(err)) {
+		t.Errorf("Wrong error of missing plugin %v", err)
+	}
+
+	// Plugins are run only when they are allowed and named without path
+	for _, command := range []string{script, "sh"} {
+		_, err = runPluginCommand(context.Background(), command, nil, nil)
+
+		if !sgerrors.IsValidationFailed(errors.Cause(err)) {
+			t.Errorf("Plugin %s must not be run %v", command, err)
+		}
+	}
+}
+
+func TestCheckPluginAuth(t *testing.T) {
+	defer func() {
+		lookPath = exec.LookPath
+		SetAllowedPlugins(DefaultExecPlugins)
+	}()
+
+	lookPath = func(command string) (string, error) {
+		if command == "installed" {
+			return command, nil
+		}
+
+		return "", errors.New("not found")
+	}
+	SetAllowedPlugins([]string{"installed", "missing"})
+
+	testCases := []struct {
+		description string
+		kube        *model.Kube
+		isErr       bool
+	}{
+		{
+			description: "installed plugin",
+			kube: &model.Kube{Auth: model.Auth{
+				AdminExec: &model.ExecAuth{Command: "installed"},
+			}},
+		},
+		{
+			description: "missing plugin",
+			kube: &model.Kube{Auth: model.Auth{
+				AdminExec: &model.ExecAuth{Command: "missing"},
+			}},
+			isErr: true,
+		},
+		{
+			description: "plugin not allowed",
+			kube: &model.Kube{Auth: model.Auth{
+				AdminExec: &model.ExecAuth{Command: "/bin/sh", Args: []string{"-c", "id"}},
+			}},
+			isErr: true,
+		},
+		{
+			description: "auth provider command not allowed",
+			kube: &model.Kube{Auth: model.Auth{
+				AdminAuthProvider: &model.AuthProvider{
+					Name:   "gcp",
+					Config: map[string]string{"cmd-path": "/tmp/gcloud"},
+				},
+			}},
+			isErr: true,
+		},
+		{
+			description: "EKS kube linked to account",
+			kube: &model.Kube{
+				AccountName: "aws",
+				Auth: model.Auth{
+					AdminExec: &model.ExecAuth{
+						Command: "aws-iam-authenticator",
+						Args:    []string{"token", "-i", "eks"},
+					},
+				},
+			},
+		},
+		{
+			description: "auth provider without command and token",
+			kube: &model.Kube{Auth: model.Auth{
+				AdminAuthProvider: &model.AuthProvider{Name: "oidc"},
+			}},
+			isErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		err := CheckPluginAuth(testCase.kube)
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("Unexpected error %v", err)
+		}
+
+		if err != nil && !sgerrors.IsValidationFailed(errors.Cause(err)) {
+			t.Errorf("Wrong error %v", err)
+		}
+	}
+}
+
+func TestUsePluginAuth(t *testing.T) {
+	defer func() {
+		runCommand = runPluginCommand
+	}()
+
+	runCommand = func(ctx context.Context, command string, args []string,
+		env map[string]string) ([]byte, error) {
+		return []byte(`{"status":{"token":"plugin-token"}}`), nil
+	}
+
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	cfg := &rest.Config{
+		Host:         server.URL,
+		ExecProvider: &clientcmdapi.ExecConfig{Command: "plugin"},
+	}
+	UsePluginAuth(cfg, &model.Kube{
+		ID: "round-trip-kube",
+		Auth: model.Auth{
+			AdminExec: &model.ExecAuth{Command: "plugin"},
+		},
+	})
+
+	if cfg.ExecProvider != nil {
+		t.Errorf("Exec provider must be removed")
+	}
+
+	transport, err := rest.TransportFor(cfg)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if auth != "Bearer plugin-token" {
+		t.Errorf("Wrong authorization header %s", auth)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/sirupsen/logrus"
 
@@ -111,6 +112,24 @@ func SendAlreadyExists(w http.ResponseWriter, entityName string, err error) {
 	w.Write(data)
 }
 
+// SendConflict is sent when the entity has been modified since it was read,
+// its current revision is set to X-Revision header.
+func SendConflict(w http.ResponseWriter, entityName string, revision int64, err error) {
+	msg := New(fmt.Sprintf("%s has been modified, its current revision is %d", entityName, revision),
+		err.Error(), sgerrors.Conflict, "")
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		logrus.Errorf("failed to marshall message: %v", err)
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Revision", strconv.FormatInt(revision, 10))
+	w.WriteHeader(http.StatusConflict)
+	w.Write(data)
+}
+
 func SendInvalidCredentials(w http.ResponseWriter, err error) {
 	msg := New("Credentials are bad for cloud provider",
 		err.Error(), sgerrors.InvalidCredentials, "")
@@ -125,3 +144,18 @@ func SendInvalidCredentials(w http.ResponseWriter, err error) {
 	w.WriteHeader(http.StatusBadRequest)
 	w.Write(data)
 }
+
+// SendNotSupported is sent when the request needs a feature that is not supported.
+func SendNotSupported(w http.ResponseWriter, err error) {
+	msg := New("Request is not supported", err.Error(), sgerrors.NotSupported, "")
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		logrus.Errorf("failed to marshall message: %v", err)
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write(data)
+}
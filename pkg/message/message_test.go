@@ -231,6 +231,31 @@ func TestSendAlreadyExists(t *testing.T) {
 	}
 }
 
+func TestSendConflict(t *testing.T) {
+	errMsg := "expected error dev message"
+	rec := httptest.NewRecorder()
+
+	SendConflict(rec, "kube", 7, errors.New(errMsg))
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Wrong code expected %d actual %d",
+			http.StatusConflict, rec.Code)
+	}
+
+	if h := rec.Header().Get("X-Revision"); h != "7" {
+		t.Errorf("Wrong revision header expected 7 actual %s", h)
+	}
+
+	msg := &Message{}
+	if err := json.Unmarshal(rec.Body.Bytes(), msg); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if msg.ErrorCode != sgerrors.Conflict || msg.DevMessage != errMsg {
+		t.Errorf("Wrong message %v", msg)
+	}
+}
+
 func TestSendInvalidCredentials(t *testing.T) {
 	header := "Content-Type"
 	headerValue := "application/json"
@@ -261,3 +286,25 @@ func TestSendInvalidCredentials(t *testing.T) {
 			errMsg, msg2.DevMessage)
 	}
 }
+
+func TestSendNotSupported(t *testing.T) {
+	errMsg := "hooks are not supported"
+	rec := httptest.NewRecorder()
+
+	SendNotSupported(rec, errors.New(errMsg))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Wrong code expected %d actual %d",
+			http.StatusBadRequest, rec.Code)
+	}
+
+	msg := &Message{}
+
+	if err := json.Unmarshal(rec.Body.Bytes(), msg); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if msg.ErrorCode != sgerrors.NotSupported || msg.DevMessage != errMsg {
+		t.Errorf("Wrong message %v", msg)
+	}
+}
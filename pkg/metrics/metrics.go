@@ -0,0 +1,179 @@
+// Package metrics exposes operational metrics of control in
+// prometheus format.
+package metrics
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "control"
+
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+var (
+	stepDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "workflow",
+		Name:      "step_duration_seconds",
+		Help:      "Duration of workflow steps by step name, provider and outcome.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800},
+	}, []string{"step", "provider", "outcome"})
+
+	tasksRunning = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "workflow",
+		Name:      "tasks_running",
+		Help:      "Count of workflow tasks being executed.",
+	})
+
+	cloudErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cloud",
+		Name:      "errors_total",
+		Help:      "Errors of cloud provider API calls by service, operation and error code.",
+	}, []string{"provider", "service", "operation", "code"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of API requests by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+)
+
+func init() {
+	prometheus.MustRegister(stepDuration, tasksRunning, cloudErrors, httpDuration)
+}
+
+// Handler serves metrics of the default registry.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveStep records duration of the workflow step started at start.
+func ObserveStep(step, provider string, start time.Time, err error) {
+	outcome := OutcomeSuccess
+
+	if err != nil {
+		outcome = OutcomeFailure
+	}
+
+	stepDuration.WithLabelValues(step, provider, outcome).
+		Observe(time.Since(start).Seconds())
+}
+
+// TaskStarted increments count of running tasks, TaskFinished
+// must be called when the task is done.
+func TaskStarted() {
+	tasksRunning.Inc()
+}
+
+// TaskFinished decrements count of running tasks.
+func TaskFinished() {
+	tasksRunning.Dec()
+}
+
+// CloudError counts failed call of cloud provider API, aws errors
+// are counted by their code.
+func CloudError(provider, service, operation string, err error) {
+	if err == nil {
+		return
+	}
+
+	code := "unknown"
+
+	if aerr, ok := errors.Cause(err).(awserr.Error); ok {
+		code = aerr.Code()
+	}
+
+	cloudErrors.WithLabelValues(provider, service, operation, code).Inc()
+}
+
+// InstrumentAWSSession counts errors of requests made by clients
+// of the session.
+func InstrumentAWSSession(sess *session.Session) {
+	if sess == nil {
+		return
+	}
+
+	sess.Handlers.Complete.PushBackNamed(request.NamedHandler{
+		Name: "control.metrics.CloudErrors",
+		Fn: func(r *request.Request) {
+			var operation string
+
+			if r.Operation != nil {
+				operation = r.Operation.Name
+			}
+
+			CloudError("aws", r.ClientInfo.ServiceName, operation, r.Error)
+		},
+	})
+}
+
+// Middleware measures latency of requests, requests are labeled by
+// route template to keep cardinality of labels low.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		route := "unknown"
+
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+
+		httpDuration.WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).
+			Observe(time.Since(start).Seconds())
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush keeps streaming of wrapped writers, e.g. task logs.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets websockets of task logs upgrade the connection.
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+
+	r.status = http.StatusSwitchingProtocols
+
+	return h.Hijack()
+}
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveStep(t *testing.T) {
+	ObserveStep("test_step", "aws", time.Now(), nil)
+	ObserveStep("test_step", "aws", time.Now(), errors.New("error"))
+
+	body := scrape(t)
+
+	for _, expected := range []string{
+		`control_workflow_step_duration_seconds_count{outcome="success",provider="aws",step="test_step"} 1`,
+		`control_workflow_step_duration_seconds_count{outcome="failure",provider="aws",step="test_step"} 1`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Metric %s not found in %s", expected, body)
+		}
+	}
+}
+
+func TestCloudError(t *testing.T) {
+	testCases := []struct {
+		description string
+		err         error
+		code        string
+		expected    float64
+	}{
+		{
+			description: "no error",
+			code:        "unknown",
+		},
+		{
+			description: "aws error",
+			err:         errors.Wrap(awserr.New("Throttling", "rate exceeded", nil), "describe"),
+			code:        "Throttling",
+			expected:    1,
+		},
+		{
+			description: "other error",
+			err:         errors.New("connection refused"),
+			code:        "unknown",
+			expected:    1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		operation := strings.Replace(testCase.description, " ", "_", -1)
+		CloudError("aws", "ec2", operation, testCase.err)
+
+		actual := testutil.ToFloat64(cloudErrors.WithLabelValues("aws", "ec2", operation, testCase.code))
+
+		if actual != testCase.expected {
+			t.Errorf("Expected errors %v actual %v", testCase.expected, actual)
+		}
+	}
+}
+
+func TestTasksRunning(t *testing.T) {
+	before := testutil.ToFloat64(tasksRunning)
+
+	TaskStarted()
+
+	if actual := testutil.ToFloat64(tasksRunning); actual != before+1 {
+		t.Errorf("Expected running tasks %v actual %v", before+1, actual)
+	}
+
+	TaskFinished()
+
+	if actual := testutil.ToFloat64(tasksRunning); actual != before {
+		t.Errorf("Expected running tasks %v actual %v", before, actual)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(Middleware)
+	router.HandleFunc("/kubes/{kubeID}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	for _, kubeID := range []string{"a", "b"} {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/kubes/"+kubeID, nil)
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected code %d actual %d", http.StatusNotFound, rec.Code)
+		}
+	}
+
+	expected := `control_http_request_duration_seconds_count{code="404",method="GET",route="/kubes/{kubeID}"} 2`
+
+	if body := scrape(t); !strings.Contains(body, expected) {
+		t.Errorf("Metric %s not found in %s", expected, body)
+	}
+}
+
+func scrape(t *testing.T) string {
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected code %d actual %d", http.StatusOK, rec.Code)
+	}
+
+	body, _ := ioutil.ReadAll(rec.Body)
+
+	return string(body)
+}
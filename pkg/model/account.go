@@ -10,4 +10,6 @@ type CloudAccount struct {
 	Name        string            `json:"name" valid:"required, length(1|32)"`
 	Provider    clouds.Name       `json:"provider" valid:"in(aws|digitalocean|gce|azure)"`
 	Credentials map[string]string `json:"credentials" valid:"optional"`
+	// Revision is incremented by each write of the account
+	Revision int64 `json:"revision" valid:"-"`
 }
@@ -0,0 +1,28 @@
+package model
+
+// AddonStatus is state of the addon installed on the kube.
+type AddonStatus struct {
+	// Version of the chart or of the manifests that have been installed
+	Version string `json:"version,omitempty"`
+	// Release of the chart, addons installed by manifests have none
+	Release     string `json:"release,omitempty"`
+	InstalledAt int64  `json:"installedAt,omitempty"`
+	// Healthy is set when deployment of the addon is available,
+	// it is refreshed on get of the kube.
+	Healthy bool `json:"healthy"`
+	// Error of the last installation
+	Error string `json:"error,omitempty"`
+}
+
+// ComponentStatus is state of the system component of the kube.
+type ComponentStatus struct {
+	// Version of the chart of the component release
+	Version   string `json:"version,omitempty"`
+	Release   string `json:"release,omitempty"`
+	UpdatedAt int64  `json:"updatedAt,omitempty"`
+	// Adopted is set when the component was installed before control
+	// managed it and its release has been taken over.
+	Adopted bool `json:"adopted,omitempty"`
+	// Error of the last installation or upgrade
+	Error string `json:"error,omitempty"`
+}
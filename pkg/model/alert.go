@@ -0,0 +1,39 @@
+package model
+
+type AlertOperator string
+
+const (
+	AlertAbove        AlertOperator = ">"
+	AlertAboveOrEqual AlertOperator = ">="
+	AlertBelow        AlertOperator = "<"
+	AlertBelowOrEqual AlertOperator = "<="
+)
+
+// AlertRule fires when a node metric of the kube matches the condition
+// for the duration.
+type AlertRule struct {
+	ID string `json:"id"`
+	// Metric is a key of node metrics, e.g. memory or cpu
+	Metric   string        `json:"metric"`
+	Operator AlertOperator `json:"operator"`
+	// Threshold in units of the metric, utilisation is a ratio
+	Threshold float64 `json:"threshold"`
+	// Duration in seconds the condition must hold before alert fires
+	Duration int64 `json:"duration"`
+}
+
+// Matches returns true when value meets condition of the rule.
+func (r *AlertRule) Matches(value float64) bool {
+	switch r.Operator {
+	case AlertAbove:
+		return value > r.Threshold
+	case AlertAboveOrEqual:
+		return value >= r.Threshold
+	case AlertBelow:
+		return value < r.Threshold
+	case AlertBelowOrEqual:
+		return value <= r.Threshold
+	}
+
+	return false
+}
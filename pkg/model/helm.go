@@ -4,7 +4,6 @@ import (
 	"time"
 
 	"k8s.io/helm/pkg/proto/hapi/chart"
-	"k8s.io/helm/pkg/repo"
 )
 
 // ChartData is a simplified representation of the helm chart.
@@ -32,10 +31,29 @@ type ChartVersion struct {
 	URLs       []string  `json:"urls"`
 }
 
+// RepositoryConfig holds the location of a helm repository and settings
+// to access private ones.
+type RepositoryConfig struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Bearer token is sent instead of basic auth credentials when it is set
+	Token    string `json:"token,omitempty"`
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+	CAFile   string `json:"caFile"`
+	// PEM encoded CA bundle certificates of the repository are verified with
+	CAData                string `json:"caData,omitempty"`
+	InsecureSkipTLSVerify bool   `json:"insecureSkipTLSVerify,omitempty"`
+}
+
 // RepositoryInfo holds authorization details and shortened charts info.
 type RepositoryInfo struct {
-	Config repo.Entry  `json:"config"`
-	Charts []ChartInfo `json:"charts"`
+	Config RepositoryConfig `json:"config"`
+	Charts []ChartInfo      `json:"charts"`
+	// Time the index file of the repository was downloaded
+	RefreshedAt time.Time `json:"refreshedAt"`
 }
 
 // ReleaseInfo is a simplified representations of the helm release.
@@ -48,4 +66,38 @@ type ReleaseInfo struct {
 	Chart        string `json:"chart"`
 	ChartVersion string `json:"chartVersion"`
 	Status       string `json:"status"`
+	// Latest chart version found in repositories, upgrade is available
+	// when it is newer than the chart version of the release
+	LatestVersion    string `json:"latestVersion,omitempty"`
+	UpgradeAvailable bool   `json:"upgradeAvailable"`
+	// System releases are components control relies on
+	System bool `json:"system"`
+}
+
+// Types of values of overrides.
+const (
+	ValueTypeYAML   = "yaml"
+	ValueTypeString = "string"
+)
+
+// ValueOverride sets the value at the dotted path of release values,
+// the value is parsed as yaml unless its type is string.
+type ValueOverride struct {
+	Path  string `json:"path" valid:"required"`
+	Value string `json:"value"`
+	Type  string `json:"type"`
+}
+
+// ReleaseRevision is a revision in the history of a helm release.
+type ReleaseRevision struct {
+	Revision     int32  `json:"revision"`
+	Updated      string `json:"updated"`
+	Chart        string `json:"chart"`
+	ChartVersion string `json:"chartVersion"`
+	AppVersion   string `json:"appVersion"`
+	Status       string `json:"status"`
+	Description  string `json:"description"`
+	// Digest of values the revision is configured with, revisions
+	// with equal digests have the same values
+	ValuesDigest string `json:"valuesDigest"`
 }
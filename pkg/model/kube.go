@@ -14,11 +14,16 @@ const (
 	StateDeleting     KubeState = "deleting"
 	StateImporting    KubeState = "importing"
 	StateUpgrading    KubeState = "upgrading"
+	// Detached kubes are forgotten by control, their machines are kept
+	StateDetached KubeState = "detached"
 )
 
 // Kube represents a kubernetes cluster.
 type Kube struct {
-	ID           string      `json:"id" valid:"-"`
+	ID string `json:"id" valid:"-"`
+	// Revision is incremented by each write of the kube, updates of
+	// kubes of other revisions than the stored one are rejected.
+	Revision     int64       `json:"revision" valid:"-"`
 	State        KubeState   `json:"state"`
 	Name         string      `json:"name" valid:"required"`
 	Provider     clouds.Name `json:"provider" valid:"in(aws|digitalocean|packet|gce|openstack)"`
@@ -42,12 +47,20 @@ type Kube struct {
 	DockerVersion          string            `json:"dockerVersion"`
 	K8SVersion             string            `json:"K8SVersion"`
 	HelmVersion            string            `json:"helmVersion"`
+	HelmMajorVersion       int               `json:"helmMajorVersion,omitempty"`
 	Networking             Networking        `json:"networking"`
 	Subnets                map[string]string `json:"subnets"`
 
 	ExternalDNSName string `json:"externalDNSName"`
 	InternalDNSName string `json:"internalDNSName"`
 	BootstrapToken  string `json:"bootstrapToken"`
+	// Machines of private kube have no public IPs
+	PrivateNetworking bool `json:"privateNetworking"`
+	// Extra flags of kubelet and API server by flag name
+	KubeletExtraArgs   map[string]string `json:"kubeletExtraArgs,omitempty"`
+	APIServerExtraArgs map[string]string `json:"apiServerExtraArgs,omitempty"`
+	// Cluster autoscaler resizes autoscaled node pools of the kube
+	ClusterAutoscaler bool `json:"clusterAutoscaler,omitempty"`
 
 	CloudSpec profile.CloudSpecificSettings `json:"cloudSpec" valid:"-"`
 
@@ -55,6 +68,8 @@ type Kube struct {
 
 	Masters map[string]*Machine `json:"masters"`
 	Nodes   map[string]*Machine `json:"nodes"`
+	// Etcd members of the kube, etcd is stacked on masters when it is empty
+	Etcd map[string]*Machine `json:"etcd,omitempty"`
 	// Store taskIds of tasks that are made to provision this kube
 	Tasks map[string][]string `json:"tasks"`
 
@@ -63,6 +78,69 @@ type Kube struct {
 	UserData         string              `json:"userData"`
 	ExposedAddresses []profile.Addresses `json:"exposedAddresses"`
 	Addons           []string            `json:"addons,omitempty"`
+	// Installed addons by addon name
+	AddonStatuses map[string]*AddonStatus `json:"addonStatuses,omitempty"`
+	// System components control relies on by component name
+	Components map[string]*ComponentStatus `json:"components,omitempty"`
+	// Spot instance request ids submitted for this kube, they must be
+	// cancelled when the kube is deleted.
+	SpotRequests []string `json:"spotRequests,omitempty"`
+	// Outcome of spot requests by spot request id
+	SpotRequestStates map[string]SpotRequestState `json:"spotRequestStates,omitempty"`
+	SpotInterruption  SpotInterruptionConfig      `json:"spotInterruption"`
+	// Spot node groups by group id
+	SpotGroups map[string]*SpotGroup `json:"spotGroups,omitempty"`
+	// Node pools added to the kube by pool name
+	NodePools map[string]*NodePool `json:"nodePools,omitempty"`
+
+	SecurityGroups SecurityGroupsConfig `json:"securityGroups"`
+	// Required security group rules missing from the AWS security groups
+	SecurityGroupDrift *SecurityGroupDrift `json:"securityGroupDrift,omitempty"`
+	// State of prometheus of the kube as of the last discovery
+	MonitoringStatus *MonitoringStatus `json:"monitoringStatus,omitempty"`
+	// GPUs nodes of the kube may allocate to pods as of the last get
+	AllocatableGPUs int64 `json:"allocatableGPUs,omitempty"`
+	// Alert rules of node metrics by rule id
+	AlertRules map[string]*AlertRule `json:"alertRules,omitempty"`
+	// Schedules of actions on the kube by schedule id
+	Schedules map[string]*Schedule `json:"schedules,omitempty"`
+	// Node counts of pools paused by a schedule by pool name
+	PausedPools map[string]int64 `json:"pausedPools,omitempty"`
+	// Owners of cloud resources by resource id, resources missing from
+	// the map have been created for the kube.
+	ResourceOwners map[string]ResourceOwner `json:"resourceOwners,omitempty"`
+	// Detachment of the kube, it is nil unless the kube is detached
+	Detachment *Detachment `json:"detachment,omitempty"`
+}
+
+// Detachment keeps record of detached kube until it is purged.
+type Detachment struct {
+	// State of the kube is restored along with it
+	State KubeState `json:"state"`
+	// Unix times the kube has been detached and is purged at
+	DetachedAt int64 `json:"detachedAt"`
+	PurgeAt    int64 `json:"purgeAt"`
+}
+
+// ResourceOwner manages lifecycle of cloud resource used by the kube
+type ResourceOwner string
+
+// OwnerUser resources are provided by user, they are never deleted
+// with the kube.
+const OwnerUser ResourceOwner = "user"
+
+// SetOwner records owner of the cloud resource.
+func (k *Kube) SetOwner(resourceID string, owner ResourceOwner) {
+	if k.ResourceOwners == nil {
+		k.ResourceOwners = make(map[string]ResourceOwner)
+	}
+
+	k.ResourceOwners[resourceID] = owner
+}
+
+// IsUserResource returns true for resources provided by user.
+func (k *Kube) IsUserResource(resourceID string) bool {
+	return resourceID != "" && k.ResourceOwners[resourceID] == OwnerUser
 }
 
 type SSHConfig struct {
@@ -72,6 +150,19 @@ type SSHConfig struct {
 	BootstrapPublicKey  string `json:"bootstrapPublicKey"`
 	PublicKey           string `json:"publicKey"`
 	Timeout             int    `json:"timeout"`
+	// BastionHost is host:port of the ssh jump host, machines are
+	// dialed directly when it is empty.
+	BastionHost string `json:"bastionHost,omitempty"`
+}
+
+// Host is address of the machine to dial over ssh, machines behind
+// the bastion are reached by private IP.
+func (c SSHConfig) Host(m Machine) string {
+	if c.BastionHost != "" {
+		return m.PrivateIp
+	}
+
+	return m.PublicIp
 }
 
 // Auth holds all possible auth parameters.
@@ -88,6 +179,36 @@ type Auth struct {
 	AdminKey       string             `json:"adminKey"`
 	CertificateKey string             `json:"certificateKey"`
 	StaticAuth     profile.StaticAuth `json:"staticAuth"`
+	// CA of dedicated etcd and client certificate of API servers
+	EtcdCACert     string `json:"etcdCaCert,omitempty"`
+	EtcdCAKey      string `json:"etcdCaKey,omitempty"`
+	EtcdClientCert string `json:"etcdClientCert,omitempty"`
+	EtcdClientKey  string `json:"etcdClientKey,omitempty"`
+	// Credentials of imported kubes that do not use client certificates
+	AdminToken    string `json:"adminToken"`
+	AdminUsername string `json:"adminUsername"`
+	AdminPassword string `json:"adminPassword"`
+	// Credential plugins of imported kubes, e.g. EKS or GKE
+	AdminExec         *ExecAuth     `json:"adminExec,omitempty"`
+	AdminAuthProvider *AuthProvider `json:"adminAuthProvider,omitempty"`
+	// API server certificate of imported kube is not verified
+	InsecureSkipTLSVerify bool `json:"insecureSkipTLSVerify,omitempty"`
+	// Kubeconfig of imported kube with credential files inlined
+	KubeConfig string `json:"kubeConfig,omitempty"`
+}
+
+// ExecAuth is a command that prints ExecCredential with a token.
+type ExecAuth struct {
+	Command    string            `json:"command"`
+	Args       []string          `json:"args,omitempty"`
+	Env        map[string]string `json:"env,omitempty"`
+	APIVersion string            `json:"apiVersion,omitempty"`
+}
+
+// AuthProvider is auth-provider of kubeconfig, e.g. gcp or oidc.
+type AuthProvider struct {
+	Name   string            `json:"name"`
+	Config map[string]string `json:"config,omitempty"`
 }
 
 type Networking struct {
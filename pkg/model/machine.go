@@ -8,6 +8,8 @@ import (
 
 type MachineState string
 
+type HookResult string
+
 type Role string
 
 func (r Role) String() string {
@@ -22,9 +24,18 @@ const (
 	MachineStateActive       MachineState = "active"
 	MachineStateDeleting     MachineState = "deleting"
 	MachineStateUpgrading    MachineState = "upgrading"
+	// Spot instance of the machine is marked for termination by the cloud
+	MachineStateInterrupting MachineState = "interrupting"
+
+	HookSuccess HookResult = "success"
+	HookFailed  HookResult = "failed"
+	// Script of warn only hook has failed but machine has been provisioned
+	HookWarning HookResult = "warning"
 
 	RoleMaster Role = "master"
 	RoleNode   Role = "node"
+	// RoleEtcd is a member of etcd cluster dedicated to the kube
+	RoleEtcd Role = "etcd"
 )
 
 type Machine struct {
@@ -41,6 +52,17 @@ type Machine struct {
 	State            MachineState `json:"state"`
 	Name             string       `json:"name"`
 	SelfLink         string       `json:"selfLink"`
+	SpotInfo         *SpotInfo    `json:"spotInfo,omitempty" valid:"-"`
+	// Pool is the node pool of the profile machine belongs to
+	Pool string `json:"pool,omitempty" valid:"-"`
+	// GPU is set once the node is labeled for GPU workloads
+	GPU bool `json:"gpu,omitempty" valid:"-"`
+	// SpotGroupID is set for nodes of maintained spot group
+	SpotGroupID string `json:"spotGroupId,omitempty" valid:"-"`
+	// KubeletVersion is reported by nodes of imported kubes
+	KubeletVersion string `json:"kubeletVersion,omitempty" valid:"-"`
+	// Hooks are results of hook scripts run on the machine by hook name
+	Hooks map[string]HookResult `json:"hooks,omitempty" valid:"-"`
 }
 
 func (m Machine) String() string {
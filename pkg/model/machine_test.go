@@ -30,3 +30,18 @@ func TestNode_String(t *testing.T) {
 		t.Errorf("id %s not found in %s", id, n.String())
 	}
 }
+
+func TestSSHConfigHost(t *testing.T) {
+	m := Machine{
+		PublicIp:  "54.0.0.1",
+		PrivateIp: "10.0.0.1",
+	}
+
+	if host := (SSHConfig{}).Host(m); host != m.PublicIp {
+		t.Errorf("Wrong host %s expected %s", host, m.PublicIp)
+	}
+
+	if host := (SSHConfig{BastionHost: "54.0.0.2"}).Host(m); host != m.PrivateIp {
+		t.Errorf("Wrong host %s expected %s", host, m.PrivateIp)
+	}
+}
@@ -0,0 +1,23 @@
+package model
+
+type MonitoringState string
+
+const (
+	// MonitoringInstalled means prometheus is ready and answers queries.
+	MonitoringInstalled MonitoringState = "installed"
+	// MonitoringDegraded means prometheus is deployed but not ready
+	// or its query endpoint fails.
+	MonitoringDegraded MonitoringState = "degraded"
+	// MonitoringMissing means prometheus is not deployed.
+	MonitoringMissing MonitoringState = "missing"
+)
+
+// MonitoringStatus is the state of in-cluster prometheus metrics
+// of the kube are queried from.
+type MonitoringStatus struct {
+	State MonitoringState `json:"state"`
+	// Reason explains degraded or missing state
+	Reason string `json:"reason,omitempty"`
+	// CheckedAt is unix time of the check
+	CheckedAt int64 `json:"checkedAt"`
+}
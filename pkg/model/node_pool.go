@@ -0,0 +1,22 @@
+package model
+
+import "github.com/supergiant/control/pkg/profile"
+
+// NodePool is a group of identical worker nodes added to the kube at once,
+// nodes of the pool have the pool name set. Count is the desired size.
+type NodePool struct {
+	profile.NodePool
+
+	// Spot nodes of the pool are requested on AWS spot market
+	Spot      bool   `json:"spot"`
+	SpotPrice string `json:"spotPrice,omitempty"`
+	// Autoscale pools are AWS auto scaling groups resized by cluster
+	// autoscaler between min and max counts, count is the initial size.
+	Autoscale bool  `json:"autoscale,omitempty"`
+	MinCount  int64 `json:"minCount,omitempty"`
+	MaxCount  int64 `json:"maxCount,omitempty"`
+	// AutoScalingGroup is name of AWS auto scaling group of the pool
+	AutoScalingGroup string `json:"autoScalingGroup,omitempty"`
+	// TaskID of the last provisioning or removal of pool nodes
+	TaskID string `json:"taskId,omitempty"`
+}
@@ -0,0 +1,32 @@
+package model
+
+type ScheduleAction string
+
+const (
+	// ScheduleScale changes count of nodes of the pool
+	ScheduleScale ScheduleAction = "scale"
+	// SchedulePause removes all nodes of node pools of the kube
+	SchedulePause ScheduleAction = "pause"
+	// ScheduleResume adds back nodes removed by the pause
+	ScheduleResume ScheduleAction = "resume"
+)
+
+// Schedule runs the action on the kube at times of the cron expression.
+type Schedule struct {
+	ID string `json:"id"`
+	// Cron is five field cron expression evaluated in UTC unless it has
+	// CRON_TZ prefix, e.g. CRON_TZ=Europe/Berlin 0 20 * * mon-fri
+	Cron   string         `json:"cron"`
+	Action ScheduleAction `json:"action"`
+	// Pool and count of its nodes of scale action
+	Pool  string `json:"pool,omitempty"`
+	Count int64  `json:"count"`
+	// CreatedAt is unix time the schedule has been created or changed
+	CreatedAt int64 `json:"createdAt"`
+	// LastRun is unix time of the last execution or skip of the action
+	LastRun int64 `json:"lastRun,omitempty"`
+	// TaskIDs of tasks started by the last execution
+	TaskIDs []string `json:"taskIds,omitempty"`
+	// LastError is why the last execution has failed or has been skipped
+	LastError string `json:"lastError,omitempty"`
+}
@@ -0,0 +1,34 @@
+package model
+
+// SecurityGroupsConfig configures checks of security group rules
+// required by the cluster.
+type SecurityGroupsConfig struct {
+	// Enforce authorizes required rules again when they are missing.
+	Enforce bool `json:"enforce"`
+}
+
+// SecurityGroupRule is an ingress rule of a security group, source of
+// the traffic is either a cidr or another security group.
+type SecurityGroupRule struct {
+	GroupID       string `json:"groupId"`
+	Protocol      string `json:"protocol"`
+	FromPort      int64  `json:"fromPort"`
+	ToPort        int64  `json:"toPort"`
+	CIDR          string `json:"cidr,omitempty"`
+	SourceGroupID string `json:"sourceGroupId,omitempty"`
+}
+
+// SecurityGroupDrift is the result of the last check of required rules.
+type SecurityGroupDrift struct {
+	// CheckedAt is unix time of the check
+	CheckedAt int64 `json:"checkedAt"`
+	// MissingRules are required rules that are not in security groups.
+	MissingRules []SecurityGroupRule `json:"missingRules"`
+	// RestoredRules have been authorized again by the check.
+	RestoredRules []SecurityGroupRule `json:"restoredRules,omitempty"`
+}
+
+// HasDrift returns true when required rules are missing.
+func (d *SecurityGroupDrift) HasDrift() bool {
+	return d != nil && len(d.MissingRules) > 0
+}
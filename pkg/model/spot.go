@@ -0,0 +1,53 @@
+package model
+
+import "encoding/json"
+
+type SpotRequestState string
+
+const (
+	SpotRequestOpen      SpotRequestState = "open"
+	SpotRequestFulfilled SpotRequestState = "fulfilled"
+	// Spot request was not fulfilled in time and on-demand
+	// instances were launched instead.
+	SpotRequestFallback SpotRequestState = "fallback"
+	SpotRequestFailed   SpotRequestState = "failed"
+)
+
+// SpotInfo describes spot request machine has been launched for.
+type SpotInfo struct {
+	RequestID        string `json:"requestId,omitempty"`
+	BidPrice         string `json:"bidPrice,omitempty"`
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+	// FulfilledAt is unix time when instance was launched
+	FulfilledAt int64 `json:"fulfilledAt,omitempty"`
+	// Interrupted is set when instance was terminated by the cloud
+	Interrupted bool `json:"interrupted"`
+}
+
+// SpotInterruptionConfig controls polling for spot interruption notices
+// of kube nodes.
+type SpotInterruptionConfig struct {
+	Disabled bool `json:"disabled"`
+	// PollInterval in seconds, default interval is used when it is zero.
+	PollInterval int64 `json:"pollInterval,omitempty"`
+	// Drain nodes that are marked for termination.
+	Drain bool `json:"drain"`
+}
+
+// SpotGroup is a group of spot nodes requested at once, nodes of the group
+// that are interrupted are requested again when Maintain is set.
+type SpotGroup struct {
+	ID string `json:"id"`
+	// DesiredCount of group nodes, it is decreased when user deletes
+	// a node of the group.
+	DesiredCount int64 `json:"desiredCount"`
+	Maintain     bool  `json:"maintain"`
+	// Request is the spot request the group has been created with.
+	Request json.RawMessage `json:"request"`
+	// RequestedAt is unix time of the capacity request in progress.
+	RequestedAt int64 `json:"requestedAt,omitempty"`
+	// Failures of capacity requests in a row
+	Failures int `json:"failures,omitempty"`
+	// RetryAt is unix time capacity is not requested before.
+	RetryAt int64 `json:"retryAt,omitempty"`
+}
@@ -0,0 +1,52 @@
+package pki
+
+import (
+	"crypto/x509"
+	"net"
+
+	"github.com/pkg/errors"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+// EtcdClientName is the common name of API server certificate used
+// to access dedicated etcd.
+const EtcdClientName = "kube-apiserver-etcd-client"
+
+// NewEtcdMemberPair creates certificates of the etcd member that serves
+// clients and peers on its ip and localhost, the certificate is used as
+// a client one by etcdctl of the member as well.
+func NewEtcdMemberPair(name string, ip string, caEncoded *PairPEM) (*PairPEM, error) {
+	memberIP := net.ParseIP(ip)
+
+	if memberIP == nil {
+		return nil, errors.Errorf("wrong ip %q of etcd member %s", ip, name)
+	}
+
+	ca, err := Decode(caEncoded)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode ca cert/key")
+	}
+
+	key, err := newPrivateKey()
+	if err != nil {
+		return nil, errors.Wrap(err, "create private key")
+	}
+
+	cfg := certutil.Config{
+		CommonName: name,
+		AltNames: certutil.AltNames{
+			DNSNames: []string{name, "localhost"},
+			IPs:      []net.IP{memberIP, net.IPv4(127, 0, 0, 1)},
+		},
+		Usages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	cert, err := newSignedCert(cfg, key, ca.Cert, ca.Key)
+	if err != nil {
+		return nil, errors.Wrap(err, "sign certificate")
+	}
+
+	return Encode(&Pair{
+		Cert: cert,
+		Key:  key,
+	})
+}
@@ -0,0 +1,53 @@
+package pki
+
+import (
+	"crypto/x509"
+	"testing"
+)
+
+func TestNewEtcdMemberPair(t *testing.T) {
+	cert, key, _ := newCertificateAuthority()
+
+	caPEMPair, _ := Encode(&Pair{
+		Cert: cert,
+		Key:  key,
+	})
+
+	if _, err := NewEtcdMemberPair("etcd-1", "10.0.0", caPEMPair); err == nil {
+		t.Errorf("error expected for wrong ip")
+	}
+
+	pairPem, err := NewEtcdMemberPair("etcd-1", "10.0.0.1", caPEMPair)
+
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	pair, err := Decode(pairPem)
+
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := pair.Cert.VerifyHostname("10.0.0.1"); err != nil {
+		t.Errorf("member ip must be in certificate %v", err)
+	}
+
+	if err := pair.Cert.VerifyHostname("127.0.0.1"); err != nil {
+		t.Errorf("localhost must be in certificate %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	for _, usage := range []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth} {
+		_, err := pair.Cert.Verify(x509.VerifyOptions{
+			Roots:     roots,
+			KeyUsages: []x509.ExtKeyUsage{usage},
+		})
+
+		if err != nil {
+			t.Errorf("certificate must be valid for usage %v %v", usage, err)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package profile
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// ValidateClusterAutoscaler checks that cluster autoscaler is enabled
+// only for AWS kube, it resizes AWS auto scaling groups of node pools.
+func ValidateClusterAutoscaler(p *Profile) error {
+	if p.ClusterAutoscaler && p.Provider != clouds.AWS {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"cluster autoscaler is not supported on %s", p.Provider)
+	}
+
+	return nil
+}
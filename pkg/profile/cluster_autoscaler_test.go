@@ -0,0 +1,51 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+func TestValidateClusterAutoscaler(t *testing.T) {
+	testCases := []struct {
+		description string
+		profile     Profile
+		isErr       bool
+	}{
+		{
+			description: "disabled",
+			profile: Profile{
+				Provider: clouds.GCE,
+			},
+		},
+		{
+			description: "aws",
+			profile: Profile{
+				Provider:          clouds.AWS,
+				ClusterAutoscaler: true,
+			},
+		},
+		{
+			description: "other provider",
+			profile: Profile{
+				Provider:          clouds.GCE,
+				ClusterAutoscaler: true,
+			},
+			isErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		err := ValidateClusterAutoscaler(&testCase.profile)
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("Wrong error %v", err)
+		}
+
+		if err != nil && !sgerrors.IsValidationFailed(err) {
+			t.Errorf("Expected validation error actual %v", err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package profile
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// Node profile keys of etcd machines
+const (
+	volumeTypeKey = "volumeType"
+	volumeSizeKey = "volumeSize"
+)
+
+// EtcdProfile describes machines of etcd cluster dedicated to the kube,
+// etcd is stacked on masters when profile has no etcd.
+type EtcdProfile struct {
+	// Count of etcd members must be odd to tolerate failures
+	Count       int    `json:"count"`
+	MachineType string `json:"machineType"`
+	// VolumeType and VolumeSize in GB of the data volume, cloud
+	// defaults are used when they are not set.
+	VolumeType string `json:"volumeType,omitempty"`
+	VolumeSize int64  `json:"volumeSize,omitempty"`
+}
+
+// NodeProfile returns node profile of the etcd machine.
+func (p EtcdProfile) NodeProfile() NodeProfile {
+	nodeProfile := NodeProfile{
+		sizeKey: p.MachineType,
+	}
+
+	if p.VolumeType != "" {
+		nodeProfile[volumeTypeKey] = p.VolumeType
+	}
+
+	if p.VolumeSize > 0 {
+		nodeProfile[volumeSizeKey] = strconv.FormatInt(p.VolumeSize, 10)
+	}
+
+	return nodeProfile
+}
+
+// EtcdNodeProfiles returns node profiles of all etcd machines, there are
+// none when etcd is stacked on masters.
+func EtcdNodeProfiles(p *EtcdProfile) []NodeProfile {
+	if p == nil {
+		return nil
+	}
+
+	nodeProfiles := make([]NodeProfile, 0, p.Count)
+
+	for i := 0; i < p.Count; i++ {
+		nodeProfiles = append(nodeProfiles, p.NodeProfile())
+	}
+
+	return nodeProfiles
+}
+
+// ValidateEtcd checks that etcd has odd count of members of
+// the machine type.
+func ValidateEtcd(p *EtcdProfile) error {
+	if p == nil {
+		return nil
+	}
+
+	if p.Count < 1 || p.Count%2 == 0 {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"etcd count %d must be odd", p.Count)
+	}
+
+	if p.MachineType == "" {
+		return errors.Wrap(sgerrors.ErrValidationFailed, "etcd machine type is not set")
+	}
+
+	if p.VolumeSize < 0 {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"etcd volume size %d is negative", p.VolumeSize)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package profile
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+func TestValidateEtcd(t *testing.T) {
+	testCases := []struct {
+		description string
+		etcd        *EtcdProfile
+		isErr       bool
+	}{
+		{
+			description: "stacked etcd",
+		},
+		{
+			description: "valid",
+			etcd: &EtcdProfile{
+				Count:       3,
+				MachineType: "i3.large",
+				VolumeType:  "io1",
+				VolumeSize:  100,
+			},
+		},
+		{
+			description: "even count",
+			etcd:        &EtcdProfile{Count: 2, MachineType: "i3.large"},
+			isErr:       true,
+		},
+		{
+			description: "no members",
+			etcd:        &EtcdProfile{MachineType: "i3.large"},
+			isErr:       true,
+		},
+		{
+			description: "empty machine type",
+			etcd:        &EtcdProfile{Count: 3},
+			isErr:       true,
+		},
+		{
+			description: "negative volume size",
+			etcd:        &EtcdProfile{Count: 1, MachineType: "i3.large", VolumeSize: -1},
+			isErr:       true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		err := ValidateEtcd(testCase.etcd)
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("Wrong error %v", err)
+		}
+
+		if err != nil && !sgerrors.IsValidationFailed(err) {
+			t.Errorf("Wrong error type %v", err)
+		}
+	}
+}
+
+func TestEtcdNodeProfiles(t *testing.T) {
+	if nodeProfiles := EtcdNodeProfiles(nil); len(nodeProfiles) != 0 {
+		t.Errorf("Stacked etcd must have no machines %v", nodeProfiles)
+	}
+
+	nodeProfiles := EtcdNodeProfiles(&EtcdProfile{
+		Count:       3,
+		MachineType: "i3.large",
+		VolumeType:  "io1",
+		VolumeSize:  100,
+	})
+
+	if len(nodeProfiles) != 3 {
+		t.Fatalf("Wrong count of etcd machines %d", len(nodeProfiles))
+	}
+
+	expected := NodeProfile{
+		"size":       "i3.large",
+		"volumeType": "io1",
+		"volumeSize": "100",
+	}
+
+	if !reflect.DeepEqual(nodeProfiles[0], expected) {
+		t.Errorf("Wrong etcd node profile expected %v actual %v", expected, nodeProfiles[0])
+	}
+}
@@ -0,0 +1,40 @@
+package profile
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// Export is a profile reconstructed from a kube. Fields of the profile that
+// refer to cloud resources of the kube region are left empty and listed as
+// unresolved, they must be set again before the profile is provisioned.
+type Export struct {
+	Profile Profile `json:"profile"`
+	// Unresolved are JSON paths of fields removed from the profile
+	Unresolved []string `json:"unresolved"`
+	// SourceKubeID is the kube the profile has been exported from
+	SourceKubeID string `json:"sourceKubeId"`
+}
+
+// ImportRequest creates profile from the export with name and region
+// of the new profile.
+type ImportRequest struct {
+	Export Export `json:"export"`
+	Name   string `json:"name"`
+	Region string `json:"region"`
+}
+
+// Import returns profile of the export in the region, region of the source
+// kube is never exported so it must be provided.
+func (e Export) Import(name, region string) (*Profile, error) {
+	if region == "" {
+		return nil, errors.Wrap(sgerrors.ErrValidationFailed, "region must be provided")
+	}
+
+	p := e.Profile
+	p.Name = name
+	p.Region = region
+
+	return &p, nil
+}
@@ -0,0 +1,93 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/testutils"
+)
+
+func TestImportProfile(t *testing.T) {
+	export := Export{
+		Profile: Profile{
+			Provider: clouds.AWS,
+			MasterProfiles: []NodeProfile{
+				{"size": "m5.large"},
+			},
+		},
+		Unresolved:   []string{"masterProfiles[0].image"},
+		SourceKubeID: "kube",
+	}
+
+	invalid := export
+	invalid.Profile.CIDR = "10.0.0"
+
+	testCases := []struct {
+		description  string
+		req          ImportRequest
+		expectedCode int
+	}{
+		{
+			description:  "region is missing",
+			req:          ImportRequest{Export: export, Name: "copy"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "invalid profile",
+			req:          ImportRequest{Export: invalid, Name: "copy", Region: "eu-west-1"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "success",
+			req:          ImportRequest{Export: export, Name: "copy", Region: "eu-west-1"},
+			expectedCode: http.StatusCreated,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		mockRepo := &testutils.MockStorage{}
+		mockRepo.On("Put", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil)
+		h := Handler{
+			service: NewService("prefix", mockRepo),
+		}
+
+		body, _ := json.Marshal(testCase.req)
+		req, _ := http.NewRequest(http.MethodPost, "/kubeprofiles/import", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.ImportProfile(rec, req)
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("Wrong response code expected %d actual %d %s",
+				testCase.expectedCode, rec.Code, rec.Body.String())
+			continue
+		}
+
+		if testCase.expectedCode != http.StatusCreated {
+			continue
+		}
+
+		resp := Export{}
+
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if resp.Profile.ID == "" || resp.Profile.Name != "copy" || resp.Profile.Region != "eu-west-1" {
+			t.Errorf("Wrong profile %v", resp.Profile)
+		}
+
+		if len(resp.Unresolved) != 1 {
+			t.Errorf("Unresolved fields must be returned %v", resp.Unresolved)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package profile
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+var (
+	flagName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+	// Flags of kubelet set by control when node joins the cluster
+	managedKubeletFlags = []string{
+		"cloud-provider",
+		"provider-id",
+		"node-ip",
+		"node-labels",
+		"register-with-taints",
+		"hostname-override",
+		"kubeconfig",
+		"bootstrap-kubeconfig",
+		"config",
+		"tls-cert-file",
+		"tls-private-key-file",
+		"rotate-certificates",
+	}
+
+	// Flags of API server set by control in kubeadm config
+	managedAPIServerFlags = []string{
+		"cloud-provider",
+		"authorization-mode",
+		"kubelet-preferred-address-types",
+		"advertise-address",
+		"secure-port",
+		"service-cluster-ip-range",
+		"client-ca-file",
+		"tls-cert-file",
+		"tls-private-key-file",
+		"etcd-servers",
+		"etcd-cafile",
+		"etcd-certfile",
+		"etcd-keyfile",
+	}
+)
+
+// ValidateExtraArgs checks that extra args of kubelet and API server are
+// well formed flags that are not managed by control. Flags are named
+// without leading dashes, e.g. max-pods.
+func ValidateExtraArgs(kubeletArgs, apiServerArgs map[string]string) error {
+	if err := validateArgs("kubelet", kubeletArgs, managedKubeletFlags); err != nil {
+		return err
+	}
+
+	return validateArgs("API server", apiServerArgs, managedAPIServerFlags)
+}
+
+func validateArgs(component string, args map[string]string, managed []string) error {
+	for name, value := range args {
+		if !flagName.MatchString(name) {
+			return errors.Wrapf(sgerrors.ErrValidationFailed,
+				"wrong %s flag %q, flags are named without leading dashes", component, name)
+		}
+
+		for _, managedName := range managed {
+			if name == managedName {
+				return errors.Wrapf(sgerrors.ErrValidationFailed,
+					"%s flag %s is managed by control", component, name)
+			}
+		}
+
+		// Values are written to kubeadm config and kubelet defaults by shell
+		if value == "" || strings.ContainsAny(value, "\"'`$\\\n ") {
+			return errors.Wrapf(sgerrors.ErrValidationFailed,
+				"wrong value %q of %s flag %s", value, component, name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+func TestValidateExtraArgs(t *testing.T) {
+	testCases := []struct {
+		description   string
+		kubeletArgs   map[string]string
+		apiServerArgs map[string]string
+		isErr         bool
+	}{
+		{
+			description: "empty",
+		},
+		{
+			description: "valid",
+			kubeletArgs: map[string]string{
+				"max-pods":      "200",
+				"feature-gates": "RotateKubeletServerCertificate=true",
+			},
+			apiServerArgs: map[string]string{
+				"enable-admission-plugins": "NodeRestriction,PodSecurity",
+			},
+		},
+		{
+			description: "leading dashes",
+			kubeletArgs: map[string]string{"--max-pods": "200"},
+			isErr:       true,
+		},
+		{
+			description: "managed kubelet flag",
+			kubeletArgs: map[string]string{"cloud-provider": "aws"},
+			isErr:       true,
+		},
+		{
+			description:   "managed API server flag",
+			apiServerArgs: map[string]string{"authorization-mode": "AlwaysAllow"},
+			isErr:         true,
+		},
+		{
+			description: "empty value",
+			kubeletArgs: map[string]string{"max-pods": ""},
+			isErr:       true,
+		},
+		{
+			description:   "value with quote",
+			apiServerArgs: map[string]string{"audit-log-path": "/var/log/'audit"},
+			isErr:         true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		err := ValidateExtraArgs(testCase.kubeletArgs, testCase.apiServerArgs)
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("Wrong error %v", err)
+		}
+
+		if err != nil && !sgerrors.IsValidationFailed(err) {
+			t.Errorf("Wrong error type %v", err)
+		}
+	}
+}
@@ -26,6 +26,7 @@ func (h *Handler) Register(r *mux.Router) {
 	r.HandleFunc("/kubeprofiles/{id}", h.GetProfile).Methods(http.MethodGet)
 	r.HandleFunc("/kubeprofiles", h.CreateProfile).Methods(http.MethodPost)
 	r.HandleFunc("/kubeprofiles", h.GetProfiles).Methods(http.MethodGet)
+	r.HandleFunc("/kubeprofiles/import", h.ImportProfile).Methods(http.MethodPost)
 }
 
 func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
@@ -64,6 +65,51 @@ func (h *Handler) CreateProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := ValidateTags(profile.Provider, profile.Tags); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := ValidateNodePools(profile.NodePools); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := ValidateStepTimeouts(profile.StepTimeouts); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := ValidateHooks(profile.MasterHooks, profile.NodeHooks); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := ValidateEtcd(profile.Etcd); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := ValidateNetworking(profile); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := ValidateExternalLoadBalancer(profile); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := ValidateExtraArgs(profile.KubeletExtraArgs, profile.APIServerExtraArgs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := ValidateClusterAutoscaler(profile); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	if err := h.service.Create(r.Context(), profile); err != nil {
 		logrus.Error(err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -86,3 +132,44 @@ func (h *Handler) GetProfiles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// ImportProfile creates profile from profile exported from a kube, fields
+// that have not been resolved are returned along with the new profile.
+func (h *Handler) ImportProfile(w http.ResponseWriter, r *http.Request) {
+	req := &ImportRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	profile, err := req.Export.Import(req.Name, req.Region)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if report := Validate(profile); !report.Valid() {
+		http.Error(w, report.Err().Error(), http.StatusBadRequest)
+		return
+	}
+
+	profile.ID = uuid.NewUUID().String()
+
+	if err := h.service.Create(r.Context(), profile); err != nil {
+		logrus.Error(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(Export{
+		Profile:      *profile,
+		Unresolved:   req.Export.Unresolved,
+		SourceKubeID: req.Export.SourceKubeID,
+	}); err != nil {
+		logrus.Error(err)
+	}
+}
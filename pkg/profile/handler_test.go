@@ -164,6 +164,38 @@ func TestKubeProfileEndpointCreateProfileInternalError(t *testing.T) {
 	}
 }
 
+func TestKubeProfileEndpointCreateProfileReservedTag(t *testing.T) {
+	kubeProfile := &Profile{
+		ID:       "key",
+		Provider: clouds.AWS,
+		Tags: map[string]string{
+			"team":         "core",
+			clouds.TagRole: "master",
+		},
+	}
+
+	mockRepo := &testutils.MockStorage{}
+	data, _ := json.Marshal(kubeProfile)
+	endpoint := &Handler{
+		service: NewService("prefix", mockRepo),
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost,
+		"/kubeprofile", bytes.NewReader(data))
+
+	handler := http.HandlerFunc(endpoint.CreateProfile)
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Wrong response code, expected %d actual %d",
+			http.StatusBadRequest, rr.Code)
+	}
+
+	mockRepo.AssertNotCalled(t, "Put", mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything)
+}
+
 func TestNewKubeProfileHandler(t *testing.T) {
 	svc := &Service{}
 	h := NewHandler(svc)
@@ -177,7 +209,7 @@ func TestHandler_Register(t *testing.T) {
 	r := mux.NewRouter()
 	h := Handler{}
 	h.Register(r)
-	expectedRouteCount := 3
+	expectedRouteCount := 4
 	routes := []*mux.Route{}
 
 	walkFn := func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
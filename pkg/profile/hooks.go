@@ -0,0 +1,37 @@
+package profile
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// MaxHookScriptSize limits size of hook scripts stored with the profile.
+const MaxHookScriptSize = 16 * 1024
+
+// Hooks are scripts run on machines over ssh, the pre provision script
+// runs before kubernetes is installed and the post provision one
+// after kubelet has started.
+type Hooks struct {
+	PreProvision  string `json:"preProvision,omitempty"`
+	PostProvision string `json:"postProvision,omitempty"`
+	// WarnOnly hooks log failures of scripts instead of failing machines
+	WarnOnly bool `json:"warnOnly,omitempty"`
+}
+
+// ValidateHooks checks that hook scripts do not exceed the size limit.
+func ValidateHooks(hooks ...Hooks) error {
+	for _, h := range hooks {
+		for name, script := range map[string]string{
+			"preProvision":  h.PreProvision,
+			"postProvision": h.PostProvision,
+		} {
+			if len(script) > MaxHookScriptSize {
+				return errors.Wrapf(sgerrors.ErrValidationFailed,
+					"%s script size %d exceeds %d bytes", name, len(script), MaxHookScriptSize)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package profile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+func TestValidateHooks(t *testing.T) {
+	testCases := []struct {
+		description string
+		hooks       []Hooks
+		isErr       bool
+	}{
+		{
+			description: "empty",
+			hooks:       []Hooks{{}, {}},
+		},
+		{
+			description: "valid",
+			hooks: []Hooks{
+				{PreProvision: "echo pre", PostProvision: "echo post"},
+			},
+		},
+		{
+			description: "pre provision script is too large",
+			hooks: []Hooks{
+				{},
+				{PreProvision: strings.Repeat("a", MaxHookScriptSize+1)},
+			},
+			isErr: true,
+		},
+		{
+			description: "post provision script is too large",
+			hooks: []Hooks{
+				{PostProvision: strings.Repeat("a", MaxHookScriptSize+1)},
+			},
+			isErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := ValidateHooks(testCase.hooks...)
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("%s: unexpected error %v", testCase.description, err)
+		}
+
+		if err != nil && errors.Cause(err) != sgerrors.ErrValidationFailed {
+			t.Errorf("%s: wrong error %v", testCase.description, err)
+		}
+	}
+}
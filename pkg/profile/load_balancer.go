@@ -0,0 +1,37 @@
+package profile
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// ExternalLoadBalancer is a centrally managed AWS network load balancer,
+// masters are registered to its target group instead of load balancers
+// created for the kube.
+type ExternalLoadBalancer struct {
+	// LoadBalancer and TargetGroup are names or ARNs
+	LoadBalancer string `json:"loadBalancer"`
+	TargetGroup  string `json:"targetGroup"`
+}
+
+// ValidateExternalLoadBalancer checks that load balancer and its target
+// group are both set for AWS kube.
+func ValidateExternalLoadBalancer(p *Profile) error {
+	if p.ExternalLoadBalancer == nil {
+		return nil
+	}
+
+	if p.Provider != clouds.AWS {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"external load balancer is not supported on %s", p.Provider)
+	}
+
+	if p.ExternalLoadBalancer.LoadBalancer == "" || p.ExternalLoadBalancer.TargetGroup == "" {
+		return errors.Wrap(sgerrors.ErrValidationFailed,
+			"load balancer and target group of external load balancer must be set")
+	}
+
+	return nil
+}
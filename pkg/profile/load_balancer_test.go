@@ -0,0 +1,64 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+func TestValidateExternalLoadBalancer(t *testing.T) {
+	testCases := []struct {
+		description string
+		profile     Profile
+		isErr       bool
+	}{
+		{
+			description: "empty",
+		},
+		{
+			description: "valid",
+			profile: Profile{
+				Provider: clouds.AWS,
+				ExternalLoadBalancer: &ExternalLoadBalancer{
+					LoadBalancer: "central",
+					TargetGroup:  "api",
+				},
+			},
+		},
+		{
+			description: "other provider",
+			profile: Profile{
+				Provider: clouds.GCE,
+				ExternalLoadBalancer: &ExternalLoadBalancer{
+					LoadBalancer: "central",
+					TargetGroup:  "api",
+				},
+			},
+			isErr: true,
+		},
+		{
+			description: "no target group",
+			profile: Profile{
+				Provider: clouds.AWS,
+				ExternalLoadBalancer: &ExternalLoadBalancer{
+					LoadBalancer: "central",
+				},
+			},
+			isErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		err := ValidateExternalLoadBalancer(&testCase.profile)
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("Wrong error %v", err)
+		}
+
+		if err != nil && !sgerrors.IsValidationFailed(err) {
+			t.Errorf("Wrong error type %v", err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package profile
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// CNI providers whose manifests are installed after control plane is up
+const (
+	Flannel = "Flannel"
+	Calico  = "Calico"
+	Weave   = "Weave"
+	Cilium  = "Cilium"
+)
+
+var networkProviders = []string{Flannel, Calico, Weave, Cilium}
+
+type namedCIDR struct {
+	name  string
+	field string
+	cidr  string
+}
+
+// ValidateNetworking checks that network provider is supported,
+// pod, service and AWS VPC CIDRs are valid and do not overlap and
+// private networking is supported by the cloud.
+func ValidateNetworking(p *Profile) error {
+	if issues := networkingIssues(p); len(issues) > 0 {
+		return errors.Wrap(sgerrors.ErrValidationFailed, issues[0].Message)
+	}
+
+	return nil
+}
+
+// networkingIssues returns all problems of networking settings
+// of the profile by field.
+func networkingIssues(p *Profile) []Issue {
+	issues := make([]Issue, 0)
+
+	if p.NetworkProvider != "" && !isNetworkProvider(p.NetworkProvider) {
+		issues = append(issues, Issue{
+			Field: "networkProvider",
+			Message: fmt.Sprintf("network provider %s must be one of %s",
+				p.NetworkProvider, strings.Join(networkProviders, ", ")),
+		})
+	}
+
+	if p.PrivateNetworking && p.Provider != clouds.AWS {
+		issues = append(issues, Issue{
+			Field:   "privateNetworking",
+			Message: fmt.Sprintf("private networking is not supported on %s", p.Provider),
+		})
+	}
+
+	// Bastion is dialed on ssh port of the kube
+	if p.BastionHost != "" && net.ParseIP(p.BastionHost) == nil &&
+		strings.Contains(p.BastionHost, ":") {
+		issues = append(issues, Issue{
+			Field:   "bastionHost",
+			Message: fmt.Sprintf("bastion host %s must not contain port", p.BastionHost),
+		})
+	}
+
+	if p.Provider == clouds.AWS {
+		issues = append(issues, awsResourcesIssues(p)...)
+	}
+
+	cidrs := []namedCIDR{
+		{"pod", "cidr", p.CIDR},
+		{"service", "k8sServicesCIDR", p.K8SServicesCIDR},
+	}
+
+	if p.Provider == clouds.AWS {
+		cidrs = append(cidrs, namedCIDR{"VPC", "cloudSpecificSettings." + clouds.AwsVpcCIDR,
+			p.CloudSpecificSettings[clouds.AwsVpcCIDR]})
+	}
+
+	nets := make([]*net.IPNet, len(cidrs))
+
+	for i, c := range cidrs {
+		if c.cidr == "" {
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(c.cidr)
+
+		if err != nil {
+			issues = append(issues, Issue{
+				Field:   c.field,
+				Message: fmt.Sprintf("%s CIDR %s is invalid", c.name, c.cidr),
+			})
+			continue
+		}
+
+		for j := 0; j < i; j++ {
+			if nets[j] != nil && overlap(nets[j], ipNet) {
+				issues = append(issues, Issue{
+					Field: c.field,
+					Message: fmt.Sprintf("%s CIDR %s overlaps %s CIDR %s", c.name, c.cidr,
+						cidrs[j].name, cidrs[j].cidr),
+				})
+			}
+		}
+
+		nets[i] = ipNet
+	}
+
+	return issues
+}
+
+// awsResourcesIssues checks that subnets and security groups provided
+// by user belong to VPC provided by user as well.
+func awsResourcesIssues(p *Profile) []Issue {
+	issues := make([]Issue, 0)
+	vpcID := p.CloudSpecificSettings[clouds.AwsVpcID]
+	userVPC := vpcID != "" && vpcID != "default"
+
+	// Resources of VPC created for the kube can't exist yet
+	if !userVPC && len(p.Subnets) > 0 {
+		issues = append(issues, Issue{
+			Field:   "subnets",
+			Message: "vpc of subnets must be provided",
+		})
+	}
+
+	mastersGroupID := p.CloudSpecificSettings[clouds.AwsMastersSecGroupID]
+	nodesGroupID := p.CloudSpecificSettings[clouds.AwsNodesSecgroupID]
+
+	if (mastersGroupID == "") != (nodesGroupID == "") {
+		issues = append(issues, Issue{
+			Field:   "cloudSpecificSettings",
+			Message: "both masters and nodes security groups must be provided",
+		})
+	}
+
+	if mastersGroupID != "" && !userVPC {
+		issues = append(issues, Issue{
+			Field:   "cloudSpecificSettings." + clouds.AwsVpcID,
+			Message: "vpc of security groups must be provided",
+		})
+	}
+
+	return issues
+}
+
+func isNetworkProvider(name string) bool {
+	for _, provider := range networkProviders {
+		if provider == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// overlap reports whether networks share any address, one of
+// them contains the other then.
+func overlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
@@ -0,0 +1,155 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+func TestValidateNetworking(t *testing.T) {
+	testCases := []struct {
+		description string
+		profile     Profile
+		isErr       bool
+	}{
+		{
+			description: "empty",
+		},
+		{
+			description: "valid",
+			profile: Profile{
+				Provider:        clouds.AWS,
+				NetworkProvider: Cilium,
+				CIDR:            "10.0.0.0/16",
+				K8SServicesCIDR: "10.3.0.0/16",
+				CloudSpecificSettings: CloudSpecificSettings{
+					clouds.AwsVpcCIDR: "172.16.0.0/16",
+				},
+			},
+		},
+		{
+			description: "unknown network provider",
+			profile:     Profile{NetworkProvider: "kube-router"},
+			isErr:       true,
+		},
+		{
+			description: "invalid pod cidr",
+			profile:     Profile{CIDR: "10.0.0.0"},
+			isErr:       true,
+		},
+		{
+			description: "invalid service cidr",
+			profile:     Profile{K8SServicesCIDR: "10.3.0.0/33"},
+			isErr:       true,
+		},
+		{
+			description: "service cidr inside pod cidr",
+			profile: Profile{
+				CIDR:            "10.0.0.0/8",
+				K8SServicesCIDR: "10.3.0.0/16",
+			},
+			isErr: true,
+		},
+		{
+			description: "vpc cidr overlaps pod cidr",
+			profile: Profile{
+				Provider: clouds.AWS,
+				CIDR:     "10.0.0.0/16",
+				CloudSpecificSettings: CloudSpecificSettings{
+					clouds.AwsVpcCIDR: "10.0.128.0/17",
+				},
+			},
+			isErr: true,
+		},
+		{
+			description: "private networking",
+			profile: Profile{
+				Provider:          clouds.AWS,
+				PrivateNetworking: true,
+				BastionHost:       "10.0.0.10",
+			},
+		},
+		{
+			description: "private networking on other provider",
+			profile: Profile{
+				Provider:          clouds.GCE,
+				PrivateNetworking: true,
+			},
+			isErr: true,
+		},
+		{
+			description: "bastion host with port",
+			profile:     Profile{BastionHost: "bastion.example.com:2222"},
+			isErr:       true,
+		},
+		{
+			description: "resources of user",
+			profile: Profile{
+				Provider: clouds.AWS,
+				Subnets:  map[string]string{"us-west-1a": "subnet-1"},
+				CloudSpecificSettings: CloudSpecificSettings{
+					clouds.AwsVpcID:             "vpc-1",
+					clouds.AwsMastersSecGroupID: "sg-masters",
+					clouds.AwsNodesSecgroupID:   "sg-nodes",
+				},
+			},
+		},
+		{
+			description: "subnets without vpc",
+			profile: Profile{
+				Provider: clouds.AWS,
+				Subnets:  map[string]string{"us-west-1a": "subnet-1"},
+				CloudSpecificSettings: CloudSpecificSettings{
+					clouds.AwsVpcID: "default",
+				},
+			},
+			isErr: true,
+		},
+		{
+			description: "security groups without vpc",
+			profile: Profile{
+				Provider: clouds.AWS,
+				CloudSpecificSettings: CloudSpecificSettings{
+					clouds.AwsMastersSecGroupID: "sg-masters",
+					clouds.AwsNodesSecgroupID:   "sg-nodes",
+				},
+			},
+			isErr: true,
+		},
+		{
+			description: "one security group",
+			profile: Profile{
+				Provider: clouds.AWS,
+				CloudSpecificSettings: CloudSpecificSettings{
+					clouds.AwsVpcID:             "vpc-1",
+					clouds.AwsMastersSecGroupID: "sg-masters",
+				},
+			},
+			isErr: true,
+		},
+		{
+			description: "vpc cidr of other provider",
+			profile: Profile{
+				Provider: clouds.GCE,
+				CIDR:     "10.0.0.0/16",
+				CloudSpecificSettings: CloudSpecificSettings{
+					clouds.AwsVpcCIDR: "10.0.128.0/17",
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		err := ValidateNetworking(&testCase.profile)
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("Wrong error %v", err)
+		}
+
+		if err != nil && !sgerrors.IsValidationFailed(err) {
+			t.Errorf("Wrong error type %v", err)
+		}
+	}
+}
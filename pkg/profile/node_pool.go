@@ -0,0 +1,196 @@
+package profile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// Node profile keys set for nodes of a pool
+const (
+	NodePoolKey = "pool"
+	TaintsKey   = "taints"
+	LabelsKey   = "labels"
+	GPUKey      = "gpu"
+	sizeKey     = "size"
+	ImageKey    = "image"
+)
+
+var taintEffects = []string{"NoSchedule", "PreferNoSchedule", "NoExecute"}
+
+// NodePool is a group of worker nodes of the same machine type and image.
+type NodePool struct {
+	Name        string `json:"name"`
+	MachineType string `json:"machineType"`
+	// Image is AWS AMI id of pool nodes, image of the cluster
+	// is used when it is empty.
+	Image  string  `json:"image,omitempty"`
+	Count  int64   `json:"count"`
+	Taints []Taint `json:"taints,omitempty"`
+	// Labels are registered for nodes of the pool along with the pool label.
+	Labels map[string]string `json:"labels,omitempty"`
+	// GPU nodes get NVIDIA device plugin and are tainted, so only
+	// pods that request GPUs are scheduled to them.
+	GPU bool `json:"gpu,omitempty"`
+}
+
+// Taint is registered for nodes of the pool when they join the cluster.
+type Taint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"`
+}
+
+func (t Taint) String() string {
+	if t.Value == "" {
+		return fmt.Sprintf("%s:%s", t.Key, t.Effect)
+	}
+
+	return fmt.Sprintf("%s=%s:%s", t.Key, t.Value, t.Effect)
+}
+
+// NodeProfile returns node profile of the pool node.
+func (p NodePool) NodeProfile() NodeProfile {
+	nodeProfile := NodeProfile{
+		NodePoolKey: p.Name,
+		sizeKey:     p.MachineType,
+	}
+
+	if p.Image != "" {
+		nodeProfile[ImageKey] = p.Image
+	}
+
+	if p.GPU {
+		nodeProfile[GPUKey] = "true"
+	}
+
+	if len(p.Taints) > 0 {
+		taints := make([]string, 0, len(p.Taints))
+
+		for _, taint := range p.Taints {
+			taints = append(taints, taint.String())
+		}
+
+		nodeProfile[TaintsKey] = strings.Join(taints, ",")
+	}
+
+	if len(p.Labels) > 0 {
+		labels := make([]string, 0, len(p.Labels))
+
+		for key, value := range p.Labels {
+			labels = append(labels, fmt.Sprintf("%s=%s", key, value))
+		}
+
+		sort.Strings(labels)
+		nodeProfile[LabelsKey] = strings.Join(labels, ",")
+	}
+
+	return nodeProfile
+}
+
+// PoolNodeProfiles returns node profiles of all nodes of the pools.
+func PoolNodeProfiles(pools []NodePool) []NodeProfile {
+	nodeProfiles := make([]NodeProfile, 0)
+
+	for _, pool := range pools {
+		for i := int64(0); i < pool.Count; i++ {
+			nodeProfiles = append(nodeProfiles, pool.NodeProfile())
+		}
+	}
+
+	return nodeProfiles
+}
+
+// FindNodePool returns the pool with the name.
+func FindNodePool(pools []NodePool, name string) (*NodePool, error) {
+	for i := range pools {
+		if pools[i].Name == name {
+			return &pools[i], nil
+		}
+	}
+
+	return nil, errors.Wrapf(sgerrors.ErrNotFound, "node pool %s", name)
+}
+
+// ResolveNodeProfile fills node profile that refers to the pool with
+// machine type, image and taints of the pool, values set in node
+// profile take precedence.
+func ResolveNodeProfile(pools []NodePool, nodeProfile NodeProfile) (NodeProfile, error) {
+	name := nodeProfile[NodePoolKey]
+
+	if name == "" {
+		return nodeProfile, nil
+	}
+
+	pool, err := FindNodePool(pools, name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := pool.NodeProfile()
+
+	for key, value := range nodeProfile {
+		resolved[key] = value
+	}
+
+	return resolved, nil
+}
+
+// ValidateNodePools checks that pools have unique names, machine types,
+// taints with known effects and well formed labels.
+func ValidateNodePools(pools []NodePool) error {
+	names := make(map[string]bool, len(pools))
+
+	for _, pool := range pools {
+		if pool.Name == "" {
+			return errors.Wrap(sgerrors.ErrValidationFailed, "node pool name must not be empty")
+		}
+
+		if names[pool.Name] {
+			return errors.Wrapf(sgerrors.ErrValidationFailed, "duplicate node pool %s", pool.Name)
+		}
+
+		names[pool.Name] = true
+
+		if pool.MachineType == "" {
+			return errors.Wrapf(sgerrors.ErrValidationFailed,
+				"machine type of node pool %s must not be empty", pool.Name)
+		}
+
+		if pool.Count < 0 {
+			return errors.Wrapf(sgerrors.ErrValidationFailed,
+				"node count of pool %s must not be negative, got %d", pool.Name, pool.Count)
+		}
+
+		for _, taint := range pool.Taints {
+			if taint.Key == "" || !isTaintEffect(taint.Effect) {
+				return errors.Wrapf(sgerrors.ErrValidationFailed,
+					"wrong taint %s of node pool %s", taint, pool.Name)
+			}
+		}
+
+		for key, value := range pool.Labels {
+			if key == "" || strings.ContainsAny(key+value, ",=") {
+				return errors.Wrapf(sgerrors.ErrValidationFailed,
+					"wrong label %s=%s of node pool %s", key, value, pool.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func isTaintEffect(effect string) bool {
+	for _, known := range taintEffects {
+		if effect == known {
+			return true
+		}
+	}
+
+	return false
+}
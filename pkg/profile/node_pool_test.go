@@ -0,0 +1,244 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+func TestValidateNodePools(t *testing.T) {
+	testCases := []struct {
+		description string
+		pools       []NodePool
+		isErr       bool
+	}{
+		{
+			description: "empty",
+		},
+		{
+			description: "valid",
+			pools: []NodePool{
+				{
+					Name:        "gpu",
+					MachineType: "p2.xlarge",
+					Image:       "ami-123",
+					Count:       2,
+					Taints: []Taint{
+						{Key: "gpu", Value: "true", Effect: "NoSchedule"},
+					},
+					Labels: map[string]string{
+						"accelerator": "nvidia",
+					},
+				},
+				{
+					Name:        "general",
+					MachineType: "m4.large",
+				},
+			},
+		},
+		{
+			description: "empty name",
+			pools: []NodePool{
+				{MachineType: "m4.large"},
+			},
+			isErr: true,
+		},
+		{
+			description: "duplicate name",
+			pools: []NodePool{
+				{Name: "general", MachineType: "m4.large"},
+				{Name: "general", MachineType: "m4.xlarge"},
+			},
+			isErr: true,
+		},
+		{
+			description: "empty machine type",
+			pools: []NodePool{
+				{Name: "general"},
+			},
+			isErr: true,
+		},
+		{
+			description: "negative count",
+			pools: []NodePool{
+				{Name: "general", MachineType: "m4.large", Count: -1},
+			},
+			isErr: true,
+		},
+		{
+			description: "wrong taint effect",
+			pools: []NodePool{
+				{
+					Name:        "gpu",
+					MachineType: "p2.xlarge",
+					Taints: []Taint{
+						{Key: "gpu", Effect: "Evict"},
+					},
+				},
+			},
+			isErr: true,
+		},
+		{
+			description: "wrong label",
+			pools: []NodePool{
+				{
+					Name:        "gpu",
+					MachineType: "p2.xlarge",
+					Labels: map[string]string{
+						"accelerator": "nvidia,amd",
+					},
+				},
+			},
+			isErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		err := ValidateNodePools(testCase.pools)
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("Wrong error %v", err)
+		}
+
+		if err != nil && !sgerrors.IsValidationFailed(err) {
+			t.Errorf("Wrong error type %v", err)
+		}
+	}
+}
+
+func TestPoolNodeProfiles(t *testing.T) {
+	pools := []NodePool{
+		{
+			Name:        "gpu",
+			MachineType: "p2.xlarge",
+			Image:       "ami-123",
+			Count:       2,
+			Taints: []Taint{
+				{Key: "gpu", Value: "true", Effect: "NoSchedule"},
+				{Key: "dedicated", Effect: "NoExecute"},
+			},
+			Labels: map[string]string{
+				"team":        "ml",
+				"accelerator": "nvidia",
+			},
+			GPU: true,
+		},
+		{
+			Name:        "general",
+			MachineType: "m4.large",
+			Count:       1,
+		},
+	}
+
+	nodeProfiles := PoolNodeProfiles(pools)
+
+	if len(nodeProfiles) != 3 {
+		t.Fatalf("Wrong count of node profiles %d", len(nodeProfiles))
+	}
+
+	gpu := nodeProfiles[0]
+
+	if gpu[NodePoolKey] != "gpu" || gpu[sizeKey] != "p2.xlarge" || gpu[ImageKey] != "ami-123" {
+		t.Errorf("Wrong node profile %v", gpu)
+	}
+
+	if gpu[TaintsKey] != "gpu=true:NoSchedule,dedicated:NoExecute" {
+		t.Errorf("Wrong taints %s", gpu[TaintsKey])
+	}
+
+	if gpu[LabelsKey] != "accelerator=nvidia,team=ml" {
+		t.Errorf("Wrong labels %s", gpu[LabelsKey])
+	}
+
+	if gpu[GPUKey] != "true" {
+		t.Errorf("Wrong gpu flag %s", gpu[GPUKey])
+	}
+
+	general := nodeProfiles[2]
+
+	if _, ok := general[ImageKey]; ok {
+		t.Errorf("Image must not be set for pool without image %v", general)
+	}
+
+	if _, ok := general[TaintsKey]; ok {
+		t.Errorf("Taints must not be set for pool without taints %v", general)
+	}
+
+	if _, ok := general[GPUKey]; ok {
+		t.Errorf("GPU must not be set for pool without GPUs %v", general)
+	}
+}
+
+func TestResolveNodeProfile(t *testing.T) {
+	pools := []NodePool{
+		{
+			Name:        "gpu",
+			MachineType: "p2.xlarge",
+			Image:       "ami-123",
+		},
+	}
+
+	testCases := []struct {
+		description string
+		nodeProfile NodeProfile
+		expected    NodeProfile
+		isErr       bool
+	}{
+		{
+			description: "no pool",
+			nodeProfile: NodeProfile{sizeKey: "m4.large"},
+			expected:    NodeProfile{sizeKey: "m4.large"},
+		},
+		{
+			description: "pool",
+			nodeProfile: NodeProfile{NodePoolKey: "gpu"},
+			expected: NodeProfile{
+				NodePoolKey: "gpu",
+				sizeKey:     "p2.xlarge",
+				ImageKey:    "ami-123",
+			},
+		},
+		{
+			description: "node profile overrides pool",
+			nodeProfile: NodeProfile{NodePoolKey: "gpu", sizeKey: "p2.8xlarge"},
+			expected: NodeProfile{
+				NodePoolKey: "gpu",
+				sizeKey:     "p2.8xlarge",
+				ImageKey:    "ami-123",
+			},
+		},
+		{
+			description: "unknown pool",
+			nodeProfile: NodeProfile{NodePoolKey: "unknown"},
+			isErr:       true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		resolved, err := ResolveNodeProfile(pools, testCase.nodeProfile)
+
+		if testCase.isErr {
+			if !sgerrors.IsNotFound(err) {
+				t.Errorf("Wrong error %v", err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if len(resolved) != len(testCase.expected) {
+			t.Errorf("Wrong node profile expected %v actual %v", testCase.expected, resolved)
+		}
+
+		for key, value := range testCase.expected {
+			if resolved[key] != value {
+				t.Errorf("Wrong %s expected %s actual %s", key, value, resolved[key])
+			}
+		}
+	}
+}
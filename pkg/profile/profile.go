@@ -4,9 +4,13 @@ import "github.com/supergiant/control/pkg/clouds"
 
 type Profile struct {
 	ID string `json:"id" valid:"required"`
+	// Name is optional, it tells profiles apart in the UI
+	Name string `json:"name,omitempty" valid:"-"`
 
 	MasterProfiles []NodeProfile `json:"masterProfiles" valid:"-"`
 	NodesProfiles  []NodeProfile `json:"nodesProfiles" valid:"-"`
+	// NodePools are added to nodes profiles when cluster is provisioned.
+	NodePools []NodePool `json:"nodePools,omitempty" valid:"-"`
 
 	// StaticAuth represents tokens and basic authentication credentials that
 	// would be set to kube-apiserver on start.
@@ -45,6 +49,30 @@ type Profile struct {
 	// by cloud provider security groups.
 	ExposedAddresses []Addresses `json:"exposedAddresses" valid:"-"`
 	Addons           []string    `json:"addons,omitempty" valid:"-"`
+	// Tags are added to all cloud resources of the cluster
+	Tags map[string]string `json:"tags,omitempty" valid:"-"`
+	// StepTimeouts override default timeouts of workflow steps,
+	// timeouts are in seconds by step name.
+	StepTimeouts map[string]int64 `json:"stepTimeouts,omitempty" valid:"-"`
+	// Hooks are scripts run on masters and nodes of the cluster
+	MasterHooks Hooks `json:"masterHooks" valid:"-"`
+	NodeHooks   Hooks `json:"nodeHooks" valid:"-"`
+	// Etcd runs on dedicated machines when it is set
+	Etcd *EtcdProfile `json:"etcd,omitempty" valid:"-"`
+	// PrivateNetworking launches machines without public IPs, they are
+	// reached over ssh through the bastion host.
+	PrivateNetworking bool `json:"privateNetworking" valid:"-"`
+	// BastionHost is address of existing bastion, bastion is provisioned
+	// for private kube when it is empty.
+	BastionHost string `json:"bastionHost,omitempty" valid:"-"`
+	// ExternalLoadBalancer replaces load balancers of AWS kube
+	ExternalLoadBalancer *ExternalLoadBalancer `json:"externalLoadBalancer,omitempty" valid:"-"`
+	// Extra flags of kubelet and API server by flag name, e.g. max-pods
+	KubeletExtraArgs   map[string]string `json:"kubeletExtraArgs,omitempty" valid:"-"`
+	APIServerExtraArgs map[string]string `json:"apiServerExtraArgs,omitempty" valid:"-"`
+	// ClusterAutoscaler deploys cluster autoscaler that resizes node
+	// pools created with autoscaling, it is supported only on AWS.
+	ClusterAutoscaler bool `json:"clusterAutoscaler,omitempty" valid:"-"`
 }
 
 type NodeProfile map[string]string
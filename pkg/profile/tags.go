@@ -0,0 +1,75 @@
+package profile
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// reservedTags are set by control to find resources of the cluster,
+// so they can not be overridden by custom tags.
+var reservedTags = []string{
+	clouds.TagNodeName,
+	clouds.TagRole,
+	clouds.TagClusterID,
+	clouds.TagKubernetesCluster,
+	clouds.LabelClusterID,
+}
+
+var (
+	gceLabelKey   = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,62}$`)
+	gceLabelValue = regexp.MustCompile(`^[a-z0-9_-]{0,63}$`)
+	// DigitalOcean tags are plain strings, so key and value are
+	// joined with colon.
+	doTag = regexp.MustCompile(`^[a-zA-Z0-9_:-]{1,255}$`)
+)
+
+// ValidateTags checks that custom tags do not override reserved ones
+// and that they are accepted by the cloud provider.
+func ValidateTags(provider clouds.Name, tags map[string]string) error {
+	for key, value := range tags {
+		if key == "" {
+			return errors.Wrap(sgerrors.ErrValidationFailed, "tag key must not be empty")
+		}
+
+		for _, reserved := range reservedTags {
+			if strings.EqualFold(key, reserved) {
+				return errors.Wrapf(sgerrors.ErrValidationFailed,
+					"tag %s is reserved", key)
+			}
+		}
+
+		switch provider {
+		case clouds.GCE:
+			if !gceLabelKey.MatchString(key) || !gceLabelValue.MatchString(value) {
+				return errors.Wrapf(sgerrors.ErrValidationFailed,
+					"wrong gce label %s=%s", key, value)
+			}
+		case clouds.DigitalOcean:
+			if !doTag.MatchString(DOTag(key, value)) {
+				return errors.Wrapf(sgerrors.ErrValidationFailed,
+					"wrong digitalocean tag %s", DOTag(key, value))
+			}
+		case clouds.Azure:
+			if strings.ContainsAny(key, `<>%&\?/`) {
+				return errors.Wrapf(sgerrors.ErrValidationFailed,
+					"wrong azure tag %s", key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// DOTag joins key and value of custom tag to DigitalOcean tag.
+func DOTag(key, value string) string {
+	if value == "" {
+		return key
+	}
+
+	return key + ":" + value
+}
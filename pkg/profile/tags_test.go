@@ -0,0 +1,102 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+func TestValidateTags(t *testing.T) {
+	testCases := []struct {
+		description string
+		provider    clouds.Name
+		tags        map[string]string
+		isErr       bool
+	}{
+		{
+			description: "empty",
+			provider:    clouds.AWS,
+		},
+		{
+			description: "aws",
+			provider:    clouds.AWS,
+			tags:        map[string]string{"team": "core", "cost-center": "CC 42"},
+		},
+		{
+			description: "empty key",
+			provider:    clouds.AWS,
+			tags:        map[string]string{"": "core"},
+			isErr:       true,
+		},
+		{
+			description: "name",
+			provider:    clouds.AWS,
+			tags:        map[string]string{"name": "node"},
+			isErr:       true,
+		},
+		{
+			description: "role",
+			provider:    clouds.Azure,
+			tags:        map[string]string{clouds.TagRole: "master"},
+			isErr:       true,
+		},
+		{
+			description: "cluster id",
+			provider:    clouds.AWS,
+			tags:        map[string]string{clouds.TagClusterID: "id"},
+			isErr:       true,
+		},
+		{
+			description: "gce",
+			provider:    clouds.GCE,
+			tags:        map[string]string{"cost-center": "cc_42"},
+		},
+		{
+			description: "gce upper case",
+			provider:    clouds.GCE,
+			tags:        map[string]string{"Team": "core"},
+			isErr:       true,
+		},
+		{
+			description: "digitalocean",
+			provider:    clouds.DigitalOcean,
+			tags:        map[string]string{"env": "prod"},
+		},
+		{
+			description: "digitalocean space",
+			provider:    clouds.DigitalOcean,
+			tags:        map[string]string{"env": "prod 1"},
+			isErr:       true,
+		},
+		{
+			description: "azure slash",
+			provider:    clouds.Azure,
+			tags:        map[string]string{"team/env": "prod"},
+			isErr:       true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		err := ValidateTags(testCase.provider, testCase.tags)
+
+		if testCase.isErr && !sgerrors.IsValidationFailed(err) {
+			t.Errorf("Expected validation error actual %v", err)
+		}
+
+		if !testCase.isErr && err != nil {
+			t.Errorf("Unexpected error %v", err)
+		}
+	}
+}
+
+func TestDOTag(t *testing.T) {
+	if tag := DOTag("env", "prod"); tag != "env:prod" {
+		t.Errorf("Wrong tag expected env:prod actual %s", tag)
+	}
+
+	if tag := DOTag("env", ""); tag != "env" {
+		t.Errorf("Wrong tag expected env actual %s", tag)
+	}
+}
@@ -0,0 +1,23 @@
+package profile
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// ValidateStepTimeouts checks that timeouts of steps are positive.
+func ValidateStepTimeouts(timeouts map[string]int64) error {
+	for stepName, seconds := range timeouts {
+		if stepName == "" {
+			return errors.Wrap(sgerrors.ErrValidationFailed, "step name must not be empty")
+		}
+
+		if seconds <= 0 {
+			return errors.Wrapf(sgerrors.ErrValidationFailed,
+				"timeout of step %s must be positive", stepName)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+func TestValidateStepTimeouts(t *testing.T) {
+	testCases := []struct {
+		description string
+		timeouts    map[string]int64
+		isErr       bool
+	}{
+		{
+			description: "empty",
+		},
+		{
+			description: "valid",
+			timeouts:    map[string]int64{"aws_create_instance": 1200, "poststart": 900},
+		},
+		{
+			description: "empty step name",
+			timeouts:    map[string]int64{"": 1200},
+			isErr:       true,
+		},
+		{
+			description: "zero timeout",
+			timeouts:    map[string]int64{"poststart": 0},
+			isErr:       true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := ValidateStepTimeouts(testCase.timeouts)
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("%s: unexpected error %v", testCase.description, err)
+		}
+
+		if err != nil && errors.Cause(err) != sgerrors.ErrValidationFailed {
+			t.Errorf("%s: wrong error %v", testCase.description, err)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package profile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// Node profile key of machine type of Azure machines
+const vmSizeKey = "vmSize"
+
+type volumeSizeLimit struct {
+	min int64
+	max int64
+}
+
+// Root and etcd volume sizes in GB accepted by clouds, root volume
+// can't be smaller than the image.
+var volumeSizeLimits = map[clouds.Name]volumeSizeLimit{
+	clouds.AWS:   {min: 8, max: 16384},
+	clouds.Azure: {min: 30, max: 4095},
+}
+
+// Issue is a problem of the profile field, field is a path of JSON
+// names of the field, e.g. masterProfiles[0].size.
+type Issue struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Report lists errors that fail provisioning of the profile and warnings
+// that don't. Checks may add issues to the report concurrently.
+type Report struct {
+	m sync.Mutex
+
+	Errors   []Issue `json:"errors"`
+	Warnings []Issue `json:"warnings"`
+}
+
+// NewReport returns report without issues.
+func NewReport() *Report {
+	return &Report{
+		Errors:   make([]Issue, 0),
+		Warnings: make([]Issue, 0),
+	}
+}
+
+func (r *Report) AddError(field string, err error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.Errors = append(r.Errors, Issue{Field: field, Message: issueMessage(err)})
+}
+
+func (r *Report) AddWarning(field, message string) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.Warnings = append(r.Warnings, Issue{Field: field, Message: message})
+}
+
+// Valid is true when the report has no errors.
+func (r *Report) Valid() bool {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	return len(r.Errors) == 0
+}
+
+// Err returns validation error that lists all errors of the report,
+// it is nil when the report is valid.
+func (r *Report) Err() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if len(r.Errors) == 0 {
+		return nil
+	}
+
+	messages := make([]string, 0, len(r.Errors))
+
+	for _, issue := range r.Errors {
+		messages = append(messages, fmt.Sprintf("%s: %s", issue.Field, issue.Message))
+	}
+
+	return errors.Wrap(sgerrors.ErrValidationFailed, strings.Join(messages, "; "))
+}
+
+// Validate runs checks of the profile that don't call cloud APIs, unlike
+// Validate* functions it reports all problems rather than the first one.
+func Validate(p *Profile) *Report {
+	r := NewReport()
+
+	for _, check := range []struct {
+		field string
+		err   error
+	}{
+		{"tags", ValidateTags(p.Provider, p.Tags)},
+		{"nodePools", ValidateNodePools(p.NodePools)},
+		{"stepTimeouts", ValidateStepTimeouts(p.StepTimeouts)},
+		{"masterHooks", ValidateHooks(p.MasterHooks)},
+		{"nodeHooks", ValidateHooks(p.NodeHooks)},
+		{"etcd", ValidateEtcd(p.Etcd)},
+		{"externalLoadBalancer", ValidateExternalLoadBalancer(p)},
+		{"kubeletExtraArgs", ValidateExtraArgs(p.KubeletExtraArgs, nil)},
+		{"apiServerExtraArgs", ValidateExtraArgs(nil, p.APIServerExtraArgs)},
+		{"clusterAutoscaler", ValidateClusterAutoscaler(p)},
+	} {
+		if check.err != nil {
+			r.AddError(check.field, check.err)
+		}
+	}
+
+	for _, issue := range networkingIssues(p) {
+		r.AddError(issue.Field, errors.New(issue.Message))
+	}
+
+	if p.K8SVersion != "" && !isSupportedVersion(p.K8SVersion) {
+		r.AddError("K8SVersion", errors.Errorf("version %s is not supported, supported versions are %s",
+			p.K8SVersion, strings.Join(clouds.GetVersions(), ", ")))
+	}
+
+	if p.PublicKey != "" {
+		if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(p.PublicKey)); err != nil {
+			r.AddError("publicKey", errors.Wrap(err, "parse ssh public key"))
+		}
+	}
+
+	validateVolumeSizes(p, r)
+
+	switch masters := len(p.MasterProfiles); {
+	case masters == 1:
+		r.AddWarning("masterProfiles", "kube with a single master is not highly available")
+	case masters > 1 && masters%2 == 0 && p.Etcd == nil:
+		r.AddWarning("masterProfiles", fmt.Sprintf("etcd of %d masters tolerates as many failures as of %d",
+			masters, masters-1))
+	}
+
+	return r
+}
+
+// MachineTypeKey returns node profile key of machine type on the cloud.
+func MachineTypeKey(provider clouds.Name) string {
+	if provider == clouds.Azure {
+		return vmSizeKey
+	}
+
+	return sizeKey
+}
+
+func validateVolumeSizes(p *Profile, r *Report) {
+	limit, ok := volumeSizeLimits[p.Provider]
+
+	if !ok {
+		return
+	}
+
+	check := func(field string, size int64) {
+		if size < limit.min || size > limit.max {
+			r.AddError(field, errors.Errorf("volume size %d GB must be between %d and %d GB on %s",
+				size, limit.min, limit.max, p.Provider))
+		}
+	}
+
+	for _, group := range []struct {
+		name     string
+		profiles []NodeProfile
+	}{
+		{"masterProfiles", p.MasterProfiles},
+		{"nodesProfiles", p.NodesProfiles},
+	} {
+		for i, nodeProfile := range group.profiles {
+			value, ok := nodeProfile[volumeSizeKey]
+
+			if !ok {
+				continue
+			}
+
+			field := fmt.Sprintf("%s[%d].%s", group.name, i, volumeSizeKey)
+			size, err := strconv.ParseInt(value, 10, 64)
+
+			if err != nil {
+				r.AddError(field, errors.Errorf("volume size %s is not a number", value))
+				continue
+			}
+
+			check(field, size)
+		}
+	}
+
+	if p.Etcd != nil && p.Etcd.VolumeSize > 0 {
+		check("etcd.volumeSize", p.Etcd.VolumeSize)
+	}
+}
+
+// isSupportedVersion is true when minor version of kubernetes is one of
+// supported versions, patch releases of the minor version are installed
+// the same way.
+func isSupportedVersion(version string) bool {
+	for _, supported := range clouds.GetVersions() {
+		if minorVersion(supported) == minorVersion(version) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func minorVersion(version string) string {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+
+	if len(parts) < 2 {
+		return version
+	}
+
+	return parts[0] + "." + parts[1]
+}
+
+// issueMessage drops validation failed suffix of errors of Validate*
+// functions, all issues of the report are validation failures.
+func issueMessage(err error) string {
+	return strings.TrimSuffix(err.Error(), ": "+sgerrors.ErrValidationFailed.Error())
+}
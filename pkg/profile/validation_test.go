@@ -0,0 +1,99 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		description      string
+		profile          Profile
+		expectedErrors   []string
+		expectedWarnings []string
+	}{
+		{
+			description: "empty",
+		},
+		{
+			description: "all problems are reported",
+			profile: Profile{
+				Provider:        clouds.AWS,
+				NetworkProvider: "kube-router",
+				CIDR:            "10.0.0",
+				K8SServicesCIDR: "10.3.0.0/33",
+				K8SVersion:      "2.0.0",
+				StepTimeouts:    map[string]int64{"": 10},
+			},
+			expectedErrors: []string{
+				"stepTimeouts",
+				"networkProvider",
+				"cidr",
+				"k8sServicesCIDR",
+				"K8SVersion",
+			},
+		},
+		{
+			description: "patch version of supported version",
+			profile: Profile{
+				K8SVersion: "1.14.10",
+				PublicKey: "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQDB2ckfv5rVySSq7p9ziEt+waU28aFGo9VNGr9g" +
+					"ottC7dew2N+ggLj7DzUUAEI2809qPBxNFN9C/rC2aP+brS8jcvInbcMxOHK/QzxzOSDjQQOfq5tQ451Hs" +
+					"hkCqRFtz5cIRgrn/yLaPZ+4dr+gspsgu8qvTGZIb8zCyjVPZsfhg70Z8Ql+1kn+1KTljOlvQ6jlxZvZX3o" +
+					"68kMb8wRvkFc8ps4xTyeCfHaCqz6OHWnV9DCtvQYmMmADzezJKOvwAeR6Uf1A1Lwe+B8eUvxtfaeYUZ5pW" +
+					"tHFFfOykmd03Xk0pRYAwtSC9ZWeje6WooyTMf56ErpIUK4qgXmJzG2oHHjD test",
+			},
+		},
+		{
+			description: "volume sizes",
+			profile: Profile{
+				Provider: clouds.Azure,
+				MasterProfiles: []NodeProfile{
+					{volumeSizeKey: "10"},
+					{volumeSizeKey: "size"},
+				},
+				NodesProfiles: []NodeProfile{
+					{volumeSizeKey: "100"},
+				},
+			},
+			expectedErrors: []string{
+				"masterProfiles[0].volumeSize",
+				"masterProfiles[1].volumeSize",
+			},
+			expectedWarnings: []string{"masterProfiles"},
+		},
+		{
+			description: "single master",
+			profile: Profile{
+				MasterProfiles: []NodeProfile{{}},
+			},
+			expectedWarnings: []string{"masterProfiles"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		r := Validate(&testCase.profile)
+
+		if len(r.Errors) != len(testCase.expectedErrors) {
+			t.Errorf("Wrong errors expected %v actual %v", testCase.expectedErrors, r.Errors)
+			continue
+		}
+
+		for i, field := range testCase.expectedErrors {
+			if r.Errors[i].Field != field {
+				t.Errorf("Wrong error field expected %s actual %v", field, r.Errors[i])
+			}
+		}
+
+		if len(r.Warnings) != len(testCase.expectedWarnings) {
+			t.Errorf("Wrong warnings expected %v actual %v", testCase.expectedWarnings, r.Warnings)
+		}
+
+		if err := r.Err(); r.Valid() != (err == nil) || err != nil && !sgerrors.IsValidationFailed(err) {
+			t.Errorf("Wrong error %v", err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Package addons is the registry of optional components that are
+// installed on kubes after provisioning or later on demand.
+package addons
+
+import (
+	"sort"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// Chart is helm chart of the addon, name of the chart is used as release name.
+type Chart struct {
+	RepoName string
+	Name     string
+	Version  string
+}
+
+// Addon is installed by helm when it has a chart and kube has tiller,
+// its manifests are applied otherwise.
+type Addon struct {
+	Name        string
+	Description string
+	Namespace   string
+	Chart       *Chart
+	// Values are default values of the chart
+	Values string
+	// Manifests are YAML documents of objects of the addon
+	Manifests string
+	// Version of the addon installed by manifests
+	Version string
+	// Deployment reports health of the addon
+	Deployment string
+	// K8SVersions is constraint of supported K8S versions like ">= 1.11"
+	K8SVersions string
+}
+
+// Profiles created before the registry select the dashboard by its step name
+const legacyDashboard = "dashboard"
+
+var registry = map[string]*Addon{
+	"nginx-ingress": {
+		Name:        "nginx-ingress",
+		Description: "NGINX ingress controller exposed by load balancer service",
+		Namespace:   "ingress-nginx",
+		Chart: &Chart{
+			RepoName: "stable",
+			Name:     "nginx-ingress",
+			Version:  "1.24.4",
+		},
+		Values:      "controller:\n  publishService:\n    enabled: true\n",
+		Manifests:   nginxIngressManifests,
+		Version:     "0.26.1",
+		Deployment:  "nginx-ingress-controller",
+		K8SVersions: ">= 1.11",
+	},
+	"metrics-server": {
+		Name:        "metrics-server",
+		Description: "Resource metrics of nodes and pods for autoscaling and kubectl top",
+		Namespace:   "kube-system",
+		Manifests:   metricsServerManifests,
+		Version:     "0.3.6",
+		Deployment:  "metrics-server",
+		K8SVersions: ">= 1.11",
+	},
+	"kubernetes-dashboard": {
+		Name:        "kubernetes-dashboard",
+		Description: "Web UI of the kube",
+		Namespace:   "kube-system",
+		Chart: &Chart{
+			RepoName: "stable",
+			Name:     "kubernetes-dashboard",
+			Version:  "1.10.1",
+		},
+		Values: "enableSkipLogin: true\nenableInsecureLogin: true\n" +
+			"rbac:\n  clusterAdminRole: true\n",
+		Deployment:  "kubernetes-dashboard",
+		K8SVersions: ">= 1.8, < 1.16",
+	},
+}
+
+// Get returns the addon registered under the name.
+func Get(name string) (*Addon, error) {
+	if name == legacyDashboard {
+		name = "kubernetes-dashboard"
+	}
+
+	addon, ok := registry[name]
+
+	if !ok {
+		return nil, errors.Wrapf(sgerrors.ErrNotFound, "addon %s", name)
+	}
+
+	return addon, nil
+}
+
+// Names returns names of registered addons in order.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+
+	for name := range registry {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Validate checks that the addons are registered and support the K8S version.
+func Validate(names []string, k8sVersion string) error {
+	for _, name := range names {
+		addon, err := Get(name)
+
+		if err != nil {
+			return errors.Wrapf(sgerrors.ErrValidationFailed,
+				"unknown addon %s, must be one of %v", name, Names())
+		}
+
+		if err := addon.Supports(k8sVersion); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Supports returns validation error when the addon can not be installed
+// on K8S of the version, empty version is not checked.
+func (a *Addon) Supports(k8sVersion string) error {
+	if k8sVersion == "" || a.K8SVersions == "" {
+		return nil
+	}
+
+	constraint, err := semver.NewConstraint(a.K8SVersions)
+
+	if err != nil {
+		return errors.Wrapf(err, "K8S versions of addon %s", a.Name)
+	}
+
+	version, err := semver.NewVersion(k8sVersion)
+
+	if err != nil {
+		return errors.Wrapf(sgerrors.ErrValidationFailed, "K8S version %s: %v", k8sVersion, err)
+	}
+
+	if !constraint.Check(version) {
+		return errors.Wrapf(sgerrors.ErrValidationFailed, "addon %s requires K8S %s, got %s",
+			a.Name, a.K8SVersions, k8sVersion)
+	}
+
+	return nil
+}
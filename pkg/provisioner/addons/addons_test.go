@@ -0,0 +1,137 @@
+package addons
+
+import (
+	"testing"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+func TestGet(t *testing.T) {
+	testCases := []struct {
+		description  string
+		name         string
+		expectedName string
+		expectedErr  error
+	}{
+		{
+			description:  "registered",
+			name:         "metrics-server",
+			expectedName: "metrics-server",
+		},
+		{
+			description:  "legacy dashboard",
+			name:         "dashboard",
+			expectedName: "kubernetes-dashboard",
+		},
+		{
+			description: "unknown",
+			name:        "istio",
+			expectedErr: sgerrors.ErrNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		addon, err := Get(testCase.name)
+
+		if testCase.expectedErr != nil {
+			if !sgerrors.IsNotFound(err) {
+				t.Errorf("Expected not found error actual %v", err)
+			}
+			continue
+		}
+
+		if err != nil || addon.Name != testCase.expectedName {
+			t.Errorf("Expected addon %s actual %v %v", testCase.expectedName, addon, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		description string
+		names       []string
+		k8sVersion  string
+		expectedErr bool
+	}{
+		{
+			description: "supported",
+			names:       []string{"nginx-ingress", "metrics-server", "dashboard"},
+			k8sVersion:  "1.15.3",
+		},
+		{
+			description: "no version",
+			names:       []string{"kubernetes-dashboard"},
+		},
+		{
+			description: "unknown",
+			names:       []string{"metrics-server", "istio"},
+			k8sVersion:  "1.15.3",
+			expectedErr: true,
+		},
+		{
+			description: "unsupported version",
+			names:       []string{"kubernetes-dashboard"},
+			k8sVersion:  "1.16.0",
+			expectedErr: true,
+		},
+		{
+			description: "invalid version",
+			names:       []string{"metrics-server"},
+			k8sVersion:  "latest",
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		err := Validate(testCase.names, testCase.k8sVersion)
+
+		if testCase.expectedErr != (err != nil) {
+			t.Errorf("Expected error %v actual %v", testCase.expectedErr, err)
+		}
+
+		if err != nil && !sgerrors.IsValidationFailed(err) {
+			t.Errorf("Expected validation error actual %v", err)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	names := Names()
+
+	if len(names) != 3 || names[0] != "kubernetes-dashboard" {
+		t.Errorf("Unexpected addons %v", names)
+	}
+
+	for _, name := range names {
+		addon, _ := Get(name)
+
+		if addon.Chart == nil && addon.Manifests == "" {
+			t.Errorf("Addon %s has neither chart nor manifests", name)
+		}
+
+		if addon.Deployment == "" || addon.Namespace == "" {
+			t.Errorf("Health of addon %s can not be checked", name)
+		}
+
+		if addon.Manifests == "" {
+			continue
+		}
+
+		objects, err := decode(addon.Manifests)
+
+		if err != nil {
+			t.Errorf("Manifests of addon %s: %v", name, err)
+			continue
+		}
+
+		for _, obj := range objects {
+			if _, ok := resources[obj.GetKind()]; !ok {
+				t.Errorf("Unsupported kind %s of addon %s", obj.GetKind(), name)
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package addons
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/supergiant/control/pkg/model"
+)
+
+// Component is a part of the kube control relies on, it is installed by
+// helm as a system release of the pinned chart version and upgraded along
+// with control. Name of the chart is used as release name.
+type Component struct {
+	Name      string
+	Namespace string
+	Chart     Chart
+	// Values are default values of the chart
+	Values string
+	// Set returns overrides of values for the kube
+	Set func(k *model.Kube) []model.ValueOverride
+}
+
+var components = map[string]*Component{
+	// node-exporter and kube-state-metrics are subcharts of the operator
+	"prometheus-operator": {
+		Name:      "prometheus-operator",
+		Namespace: "kube-system",
+		Chart: Chart{
+			RepoName: "stable",
+			Name:     "prometheus-operator",
+			Version:  "5.0.4",
+		},
+		Values: "exporter-kubelets:\n  https: true\n",
+		Set: func(k *model.Kube) []model.ValueOverride {
+			rbac := strconv.FormatBool(k.RBACEnabled)
+
+			return []model.ValueOverride{
+				{Path: "global.rbac.create", Value: rbac},
+				{Path: "grafana.rbac.create", Value: rbac},
+				{Path: "kube-state-metrics.rbac.create", Value: rbac},
+				{Path: "prometheus-node-exporter.rbac.create", Value: rbac},
+			}
+		},
+	},
+}
+
+// Components returns system components in order of their names.
+func Components() []*Component {
+	out := make([]*Component, 0, len(components))
+
+	for _, c := range components {
+		out = append(out, c)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+
+	return out
+}
+
+// SystemComponent returns the component the release belongs to.
+func SystemComponent(releaseName string) (*Component, bool) {
+	for _, c := range components {
+		if c.Chart.Name == releaseName {
+			return c, true
+		}
+	}
+
+	return nil, false
+}
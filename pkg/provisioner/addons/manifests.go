@@ -0,0 +1,137 @@
+package addons
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+)
+
+type resource struct {
+	name       string
+	namespaced bool
+}
+
+// Kinds of objects addon manifests may contain
+var resources = map[string]resource{
+	"Namespace":          {"namespaces", false},
+	"ServiceAccount":     {"serviceaccounts", true},
+	"ConfigMap":          {"configmaps", true},
+	"Service":            {"services", true},
+	"Deployment":         {"deployments", true},
+	"DaemonSet":          {"daemonsets", true},
+	"Role":               {"roles", true},
+	"RoleBinding":        {"rolebindings", true},
+	"ClusterRole":        {"clusterroles", false},
+	"ClusterRoleBinding": {"clusterrolebindings", false},
+	"APIService":         {"apiservices", false},
+}
+
+// Apply creates objects of the manifests in order, objects that
+// exist are left as is.
+func Apply(client dynamic.Interface, manifests string) error {
+	objects, err := decode(manifests)
+
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objects {
+		c, err := resourceFor(client, obj)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = c.Create(obj, metav1.CreateOptions{})
+
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			return errors.Wrapf(err, "create %s %s", obj.GetKind(), obj.GetName())
+		}
+	}
+
+	return nil
+}
+
+// Delete deletes objects of the manifests in reverse order, objects
+// that don't exist are skipped.
+func Delete(client dynamic.Interface, manifests string) error {
+	objects, err := decode(manifests)
+
+	if err != nil {
+		return err
+	}
+
+	propagation := metav1.DeletePropagationForeground
+
+	for i := len(objects) - 1; i >= 0; i-- {
+		obj := objects[i]
+		c, err := resourceFor(client, obj)
+
+		if err != nil {
+			return err
+		}
+
+		err = c.Delete(obj.GetName(), &metav1.DeleteOptions{
+			PropagationPolicy: &propagation,
+		})
+
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "delete %s %s", obj.GetKind(), obj.GetName())
+		}
+	}
+
+	return nil
+}
+
+func decode(manifests string) ([]*unstructured.Unstructured, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewBufferString(manifests), 4096)
+	objects := make([]*unstructured.Unstructured, 0)
+
+	for {
+		obj := &unstructured.Unstructured{}
+		err := decoder.Decode(&obj.Object)
+
+		if err == io.EOF {
+			return objects, nil
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "decode manifests")
+		}
+
+		// Documents with comments only
+		if len(obj.Object) == 0 {
+			continue
+		}
+
+		objects = append(objects, obj)
+	}
+}
+
+func resourceFor(client dynamic.Interface, obj *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	gvk := obj.GroupVersionKind()
+	r, ok := resources[gvk.Kind]
+
+	if !ok {
+		return nil, errors.Errorf("unsupported kind %s of %s", gvk.Kind, obj.GetName())
+	}
+
+	objects := client.Resource(schema.GroupVersionResource{
+		Group:    gvk.Group,
+		Version:  gvk.Version,
+		Resource: r.name,
+	})
+
+	if r.namespaced {
+		return objects.Namespace(obj.GetNamespace()), nil
+	}
+
+	return objects, nil
+}
@@ -0,0 +1,138 @@
+package addons
+
+import (
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const testManifests = `
+# comment only document
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: test
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: test
+`
+
+type fakeDynamic struct {
+	calls     []string
+	createErr error
+	deleteErr error
+}
+
+func (f *fakeDynamic) Resource(resource schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &fakeResource{fake: f, resource: resource}
+}
+
+type fakeResource struct {
+	dynamic.NamespaceableResourceInterface
+	fake      *fakeDynamic
+	resource  schema.GroupVersionResource
+	namespace string
+}
+
+func (r *fakeResource) Namespace(ns string) dynamic.ResourceInterface {
+	return &fakeResource{fake: r.fake, resource: r.resource, namespace: ns}
+}
+
+func (r *fakeResource) Create(obj *unstructured.Unstructured, options metav1.CreateOptions,
+	subresources ...string) (*unstructured.Unstructured, error) {
+	r.fake.calls = append(r.fake.calls, strings.Join([]string{"create",
+		r.resource.Group, r.resource.Version, r.resource.Resource, r.namespace, obj.GetName()}, " "))
+	return obj, r.fake.createErr
+}
+
+func (r *fakeResource) Delete(name string, options *metav1.DeleteOptions, subresources ...string) error {
+	r.fake.calls = append(r.fake.calls, strings.Join([]string{"delete",
+		r.resource.Resource, r.namespace, name}, " "))
+	return r.fake.deleteErr
+}
+
+func TestApply(t *testing.T) {
+	gr := schema.GroupResource{Resource: "namespaces"}
+	testCases := []struct {
+		description   string
+		manifests     string
+		createErr     error
+		expectedCalls []string
+		expectedErr   bool
+	}{
+		{
+			description: "created",
+			manifests:   testManifests,
+			expectedCalls: []string{
+				"create  v1 namespaces  test",
+				"create apps v1 deployments test app",
+			},
+		},
+		{
+			description: "already exists",
+			manifests:   testManifests,
+			createErr:   apierrors.NewAlreadyExists(gr, "test"),
+			expectedCalls: []string{
+				"create  v1 namespaces  test",
+				"create apps v1 deployments test app",
+			},
+		},
+		{
+			description:   "create error",
+			manifests:     testManifests,
+			createErr:     apierrors.NewForbidden(gr, "test", nil),
+			expectedCalls: []string{"create  v1 namespaces  test"},
+			expectedErr:   true,
+		},
+		{
+			description: "unsupported kind",
+			manifests:   "apiVersion: v1\nkind: Pod\nmetadata:\n  name: pod\n",
+			expectedErr: true,
+		},
+		{
+			description: "invalid manifests",
+			manifests:   "kind: [",
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		client := &fakeDynamic{createErr: testCase.createErr}
+		err := Apply(client, testCase.manifests)
+
+		if testCase.expectedErr != (err != nil) {
+			t.Errorf("Expected error %v actual %v", testCase.expectedErr, err)
+		}
+
+		if strings.Join(client.calls, ",") != strings.Join(testCase.expectedCalls, ",") {
+			t.Errorf("Expected calls %v actual %v", testCase.expectedCalls, client.calls)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	client := &fakeDynamic{
+		deleteErr: apierrors.NewNotFound(schema.GroupResource{Resource: "deployments"}, "app"),
+	}
+
+	if err := Delete(client, testManifests); err != nil {
+		t.Fatalf("Objects that are not found must be skipped %v", err)
+	}
+
+	expected := "delete deployments test app,delete namespaces  test"
+
+	if strings.Join(client.calls, ",") != expected {
+		t.Errorf("Objects must be deleted in reverse order %v", client.calls)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/supergiant/control/pkg/util"
 	"github.com/supergiant/control/pkg/workflows"
 	"github.com/supergiant/control/pkg/workflows/steps"
+	"github.com/supergiant/control/pkg/workflows/steps/amazon"
 )
 
 const (
@@ -43,12 +44,17 @@ type Handler struct {
 	profileService ProfileCreater
 	kubeGetter     KubeGetter
 	provisioner    ClusterProvisioner
+
+	getMachineTypes func(context.Context, *steps.Config, string) ([]account.MachineType, error)
+	getImageFinder  func(steps.AWSConfig) (amazon.ImageFinder, error)
 }
 
 type ProvisionRequest struct {
 	ClusterName      string          `json:"clusterName" valid:"matches(^[A-Za-z0-9-]+$)"`
 	Profile          profile.Profile `json:"profile" valid:"-"`
 	CloudAccountName string          `json:"cloudAccountName" valid:"-"`
+	// Delete cloud resources created by failed provisioning
+	RollbackOnFailure bool `json:"rollbackOnFailure" valid:"-"`
 }
 
 type ProvisionResponse struct {
@@ -69,11 +75,15 @@ func NewHandler(kubeService KubeGetter,
 		profileService: profileSvc,
 		accountGetter:  cloudAccountService,
 		provisioner:    provisioner,
+
+		getMachineTypes: account.GetMachineTypes,
+		getImageFinder:  getImageFinder,
 	}
 }
 
 func (h *Handler) Register(m *mux.Router) {
 	m.HandleFunc("/provision", h.Provision).Methods(http.MethodPost)
+	m.HandleFunc("/kubeprofiles/validate", h.ValidateProfile).Methods(http.MethodPost)
 }
 
 // TODO(stgleb): Move this to KubeHandler create kube
@@ -98,6 +108,20 @@ func (h *Handler) Provision(w http.ResponseWriter, r *http.Request) {
 		req.Profile.K8SServicesCIDR = DefaultK8SServicesCIDR
 	}
 
+	report := profile.Validate(&req.Profile)
+
+	if !report.Valid() {
+		message.SendValidationFailed(w, report.Err())
+		return
+	}
+
+	// Fields of nodes profiles are checked before pool nodes are added
+	validated := req.Profile
+
+	// Nodes of pools are provisioned along with nodes profiles
+	req.Profile.NodesProfiles = append(req.Profile.NodesProfiles,
+		profile.PoolNodeProfiles(req.Profile.NodePools)...)
+
 	config, err := steps.NewConfig(req.ClusterName, req.CloudAccountName, req.Profile)
 
 	if err != nil {
@@ -106,6 +130,8 @@ func (h *Handler) Provision(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	config.RollbackOnFailure = req.RollbackOnFailure
+
 	acc, err := h.accountGetter.Get(r.Context(), req.CloudAccountName)
 
 	if err != nil {
@@ -127,6 +153,13 @@ func (h *Handler) Provision(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.validateCloud(r.Context(), config, &validated, report)
+
+	if !report.Valid() {
+		message.SendValidationFailed(w, report.Err())
+		return
+	}
+
 	// Assign ID to profile
 	id := uuid.New()
 
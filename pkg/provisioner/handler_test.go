@@ -87,10 +87,16 @@ func TestProvisionHandler(t *testing.T) {
 		"test",
 		profile.Profile{},
 		"1234",
+		false,
 	}
 
 	validBody, _ := json.Marshal(p)
 
+	// Pod CIDR contains default services CIDR
+	overlapping := *p
+	overlapping.Profile.CIDR = "10.0.0.0/8"
+	overlappingBody, _ := json.Marshal(overlapping)
+
 	testCases := []struct {
 		description string
 
@@ -107,6 +113,11 @@ func TestProvisionHandler(t *testing.T) {
 			body:         []byte(`{`),
 			expectedCode: http.StatusBadRequest,
 		},
+		{
+			description:  "overlapping cidrs",
+			body:         overlappingBody,
+			expectedCode: http.StatusBadRequest,
+		},
 		{
 			description:  "account not found",
 			body:         validBody,
@@ -242,7 +253,7 @@ func TestHandler_Register(t *testing.T) {
 	r := mux.NewRouter()
 	h.Register(r)
 
-	expectedRouteCount := 1
+	expectedRouteCount := 2
 	actualRouteCount := 0
 	err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
 		if router != r {
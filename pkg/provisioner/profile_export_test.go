@@ -0,0 +1,146 @@
+package provisioner
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/kube"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/testutils"
+	"github.com/supergiant/control/pkg/workflows"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+func TestProvisionExportedProfile(t *testing.T) {
+	repository := &testutils.MockStorage{}
+	repository.On("Put", mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	svc := &mockKubeService{
+		data: make(map[string]model.Kube),
+	}
+
+	provisioner := TaskProvisioner{
+		svc,
+		repository,
+		func(string) (io.WriteCloser, error) {
+			return &bufferCloser{ioutil.Discard, nil}, nil
+		},
+		NewRateLimiter(time.Nanosecond * 1),
+		make(map[string]func()),
+		DefaultNodeParallelism,
+		DefaultMaxNodeFailureRatio,
+		nil,
+	}
+
+	workflows.Init()
+
+	for _, name := range []string{workflows.ProvisionMaster, workflows.ProvisionNode,
+		workflows.PostProvision, workflows.AwsInfra} {
+		workflows.RegisterWorkFlow(name, []steps.Step{&mockStep{}})
+	}
+
+	source := &model.Kube{
+		ID:            "source",
+		Provider:      clouds.AWS,
+		Region:        "us-west-1",
+		K8SVersion:    "1.15.1",
+		DockerVersion: "18.06.3",
+		HelmVersion:   "2.14.3",
+		ServicesCIDR:  "10.3.0.0/16",
+		RBACEnabled:   true,
+		Networking: model.Networking{
+			Provider: profile.Flannel,
+			Type:     "vxlan",
+			CIDR:     "10.0.0.0/16",
+		},
+		CloudSpec: profile.CloudSpecificSettings{
+			clouds.AwsVpcID:      "vpc-created",
+			clouds.AwsVolumeSize: "100",
+		},
+		Masters: map[string]*model.Machine{
+			"master-1": {Name: "master-1", Size: "m5.large"},
+		},
+		Nodes: map[string]*model.Machine{
+			"node-1": {Name: "node-1", Size: "m5.large"},
+			"node-2": {Name: "node-2", Size: "m5.large"},
+			"gpu-1":  {Name: "gpu-1", Size: "p3.2xlarge", Pool: "gpu"},
+		},
+		NodePools: map[string]*model.NodePool{
+			"gpu": {
+				NodePool: profile.NodePool{
+					Name:        "gpu",
+					MachineType: "p3.2xlarge",
+					Image:       "ami-gpu",
+					Count:       1,
+					GPU:         true,
+				},
+			},
+		},
+	}
+
+	stored := &profile.Profile{
+		Region: "us-west-1",
+		MasterProfiles: []profile.NodeProfile{
+			{"size": "m5.large", "image": "ami-1"},
+		},
+		NodesProfiles: []profile.NodeProfile{
+			{"size": "m5.large", "image": "ami-1"},
+		},
+	}
+
+	p, err := kube.ExportProfile(source, stored).Import("copy", "eu-west-1")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if report := profile.Validate(p); !report.Valid() {
+		t.Fatalf("Imported profile must be valid %v", report.Err())
+	}
+
+	p.NodesProfiles = append(p.NodesProfiles, profile.PoolNodeProfiles(p.NodePools)...)
+
+	cfg, err := steps.NewConfig("copy", "", *p)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	cfg.DryRun = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// Cancel context to shut down cluster state monitoring
+	defer cancel()
+
+	taskMap, err := provisioner.ProvisionCluster(ctx, p, cfg)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(taskMap[workflows.MasterTask]) != len(source.Masters) {
+		t.Errorf("Expected %d master tasks actual %d", len(source.Masters),
+			len(taskMap[workflows.MasterTask]))
+	}
+
+	if len(taskMap[workflows.NodeTask]) != len(source.Nodes) {
+		t.Errorf("Expected %d node tasks actual %d", len(source.Nodes),
+			len(taskMap[workflows.NodeTask]))
+	}
+
+	if cfg.Kube.Region != "eu-west-1" {
+		t.Errorf("Expected region eu-west-1 actual %s", cfg.Kube.Region)
+	}
+
+	if cfg.Kube.Networking.CIDR != source.Networking.CIDR {
+		t.Errorf("Expected CIDR %s actual %s", source.Networking.CIDR, cfg.Kube.Networking.CIDR)
+	}
+}
@@ -25,11 +25,35 @@ import (
 	"github.com/supergiant/control/pkg/workflows/statuses"
 	"github.com/supergiant/control/pkg/workflows/steps"
 	"github.com/supergiant/control/pkg/workflows/steps/configmap"
+	"github.com/supergiant/control/pkg/workflows/steps/etcd"
+)
+
+const (
+	// DefaultNodeParallelism is the count of node workflows run at once
+	DefaultNodeParallelism = 5
+	// DefaultMaxNodeFailureRatio is the fraction of nodes that may fail
+	// without failing the cluster
+	DefaultMaxNodeFailureRatio = 0.1
+	// NodePoolTimeout limits provisioning of nodes of the pool
+	NodePoolTimeout = time.Hour
+	// ReplaceMasterTimeout limits provisioning of the new master
+	ReplaceMasterTimeout = time.Hour
+
+	// Kube modified meanwhile is got and updated again up to this count
+	maxKubeUpdateAttempts = 5
 )
 
 type KubeService interface {
 	Create(ctx context.Context, k *model.Kube) error
 	Get(ctx context.Context, name string) (*model.Kube, error)
+	// Update stores the kube unless it has been modified since it was got,
+	// sgerrors.ErrConflict is returned otherwise.
+	Update(ctx context.Context, k *model.Kube) error
+}
+
+// AddonInstaller installs addons of the kube that are not installed yet.
+type AddonInstaller interface {
+	InstallAddons(ctx context.Context, kubeID string)
 }
 
 type TaskProvisioner struct {
@@ -45,6 +69,11 @@ type TaskProvisioner struct {
 	// Cancel map - map of KubeID -> cancel function
 	// that cancels
 	cancelMap map[string]func()
+
+	nodeParallelism     int
+	maxNodeFailureRatio float64
+
+	addonInstaller AddonInstaller
 }
 
 func NewProvisioner(repository storage.Interface, kubeService KubeService,
@@ -55,9 +84,33 @@ func NewProvisioner(repository storage.Interface, kubeService KubeService,
 		getWriter:   util.GetWriterFunc(logDir),
 		rateLimiter: NewRateLimiter(spawnInterval),
 		cancelMap:   make(map[string]func()),
+
+		nodeParallelism:     DefaultNodeParallelism,
+		maxNodeFailureRatio: DefaultMaxNodeFailureRatio,
 	}
 }
 
+// SetNodeParallelism sets count of node workflows run at once.
+func (tp *TaskProvisioner) SetNodeParallelism(n int) {
+	if n > 0 {
+		tp.nodeParallelism = n
+	}
+}
+
+// SetMaxNodeFailureRatio sets fraction of nodes that may fail without
+// failing the cluster, it must be in [0, 1].
+func (tp *TaskProvisioner) SetMaxNodeFailureRatio(ratio float64) {
+	if ratio >= 0 && ratio <= 1 {
+		tp.maxNodeFailureRatio = ratio
+	}
+}
+
+// SetAddonInstaller sets installer of addons selected by profile, they
+// are installed once the kube becomes operational.
+func (tp *TaskProvisioner) SetAddonInstaller(installer AddonInstaller) {
+	tp.addonInstaller = installer
+}
+
 type bufferCloser struct {
 	io.Writer
 	err error
@@ -79,7 +132,15 @@ func (tp *TaskProvisioner) ProvisionCluster(parentContext context.Context,
 		return nil, errors.Wrap(err, "bootstrap certs")
 	}
 
-	taskMap := tp.prepare(config, len(clusterProfile.MasterProfiles), len(clusterProfile.NodesProfiles))
+	if clusterProfile.Etcd != nil {
+		if err := bootstrapEtcdCerts(config); err != nil {
+			return nil, errors.Wrap(err, "bootstrap etcd certs")
+		}
+	}
+
+	etcdProfiles := profile.EtcdNodeProfiles(clusterProfile.Etcd)
+	taskMap := tp.prepare(config, len(clusterProfile.MasterProfiles),
+		len(clusterProfile.NodesProfiles), len(etcdProfiles))
 	clusterTask := taskMap[workflows.ClusterTask][0]
 
 	// Get clusterID from taskID
@@ -99,6 +160,9 @@ func (tp *TaskProvisioner) ProvisionCluster(parentContext context.Context,
 		taskMap[workflows.MasterTask], taskMap[workflows.NodeTask],
 		clusterProfile)
 
+	config.Kube.Etcd = etcdFromProfile(config.Kube.Name,
+		taskMap[workflows.EtcdTask], clusterProfile)
+
 	// Gather all task ids
 	taskIds := grabTaskIds(taskMap)
 	// Save cluster before provisioning
@@ -141,6 +205,8 @@ func (tp *TaskProvisioner) ProvisionNodes(parentContext context.Context, nodePro
 		config.NodeChan(), config.KubeStateChan(), config.ConfigChan())
 
 	tasks := make([]string, 0, len(nodeProfiles))
+	// Node pools may override image of the cluster
+	clusterImageID := config.AWSConfig.ImageID
 
 	// TODO(stgleb): do this in async to avoid blocking the UI
 	for _, nodeProfile := range nodeProfiles {
@@ -162,6 +228,7 @@ func (tp *TaskProvisioner) ProvisionNodes(parentContext context.Context, nodePro
 			return nil, errors.Wrap(err, "get writer")
 		}
 
+		config.AWSConfig.ImageID = clusterImageID
 		err = FillNodeCloudSpecificData(config.Provider, nodeProfile, config)
 
 		if err != nil {
@@ -170,7 +237,7 @@ func (tp *TaskProvisioner) ProvisionNodes(parentContext context.Context, nodePro
 
 		// Put task id to config so that create instance step can use this id when generate node name
 		config.TaskID = t.ID
-		errChan := t.Run(ctx, *config, writer)
+		errChan := t.Run(ctx, config.Clone(), writer)
 
 		go func(task *workflows.Task, cfg *steps.Config, errChan chan error) {
 			err = <-errChan
@@ -188,6 +255,121 @@ func (tp *TaskProvisioner) ProvisionNodes(parentContext context.Context, nodePro
 	return tasks, nil
 }
 
+// ProvisionNodePool provisions count nodes of the pool by the pool task,
+// node tasks are run concurrently and tracked as subtasks of the pool task.
+func (tp *TaskProvisioner) ProvisionNodePool(parentContext context.Context, pool profile.NodePool, count int,
+	kube *model.Kube, config *steps.Config) (*workflows.Task, []string, error) {
+	if len(kube.Masters) == 0 {
+		return nil, nil, errors.Wrap(sgerrors.ErrNotFound, "master node")
+	}
+
+	for key := range kube.Masters {
+		config.AddMaster(kube.Masters[key])
+	}
+
+	ctx, cancel := context.WithTimeout(parentContext, NodePoolTimeout)
+	tp.cancelMap[config.Kube.ID] = cancel
+
+	if err := tp.loadCloudSpecificData(ctx, config); err != nil {
+		return nil, nil, errors.Wrap(err, "load cloud specific config")
+	}
+
+	poolTask, err := workflows.NewTask(config, workflows.NodePool, tp.repository)
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "new pool task")
+	}
+
+	poolProfile := &profile.Profile{
+		Provider: config.Provider,
+	}
+	nodeTasks := make([]*workflows.Task, 0, count)
+	taskIDs := make([]string, 0, count)
+
+	for i := 0; i < count; i++ {
+		t, err := workflows.NewTask(config, workflows.ProvisionNode, tp.repository)
+
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "new node task")
+		}
+
+		poolProfile.NodesProfiles = append(poolProfile.NodesProfiles, pool.NodeProfile())
+		nodeTasks = append(nodeTasks, t)
+		taskIDs = append(taskIDs, t.ID)
+	}
+
+	go tp.monitorClusterState(ctx, config.Kube.ID,
+		config.NodeChan(), config.KubeStateChan(), config.ConfigChan())
+
+	go func() {
+		status := statuses.Success
+
+		if err := poolTask.SetStatus(ctx, statuses.Executing); err != nil {
+			logrus.Errorf("save pool task %s %v", poolTask.ID, err)
+		}
+
+		if err := tp.provisionNodes(ctx, poolProfile, config, nodeTasks, poolTask); err == context.Canceled {
+			status = statuses.Cancelled
+		} else if err != nil {
+			status = statuses.Error
+			logrus.Errorf("node pool %s of cluster %s has failed %v", pool.Name, kube.ID, err)
+		}
+
+		if err := poolTask.SetStatus(context.Background(), status); err != nil {
+			logrus.Errorf("save pool task %s %v", poolTask.ID, err)
+		}
+	}()
+
+	return poolTask, taskIDs, nil
+}
+
+// ReplaceMaster provisions the master that takes place of the old one,
+// error of the replacement task is sent to the channel when it is done.
+func (tp *TaskProvisioner) ReplaceMaster(parentContext context.Context, masterProfile profile.NodeProfile,
+	kube *model.Kube, old *model.Machine, config *steps.Config) (*workflows.Task, <-chan error, error) {
+	for key := range kube.Masters {
+		if key != old.Name {
+			config.AddMaster(kube.Masters[key])
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(parentContext, ReplaceMasterTimeout)
+	tp.cancelMap[config.Kube.ID] = cancel
+
+	if err := tp.loadCloudSpecificData(ctx, config); err != nil {
+		return nil, nil, errors.Wrap(err, "load cloud specific config")
+	}
+
+	if err := FillNodeCloudSpecificData(config.Provider, masterProfile, config); err != nil {
+		return nil, nil, errors.Wrap(err, "fill master profile data to config")
+	}
+
+	config.IsMaster = true
+	config.IsBootstrap = false
+	config.ReplaceMasterConfig.Name = old.Name
+	config.ReplaceMasterConfig.PrivateIP = old.PrivateIp
+
+	t, err := workflows.NewTask(config, workflows.ReplaceMaster, tp.repository)
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "new replace master task")
+	}
+
+	writer, err := tp.getWriter(util.MakeFileName(t.ID))
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "get writer")
+	}
+
+	go tp.monitorClusterState(ctx, config.Kube.ID,
+		config.NodeChan(), config.KubeStateChan(), config.ConfigChan())
+
+	// Put task id to config so that create instance step can use this id when generate node name
+	config.TaskID = t.ID
+
+	return t, t.Run(ctx, config.Clone(), writer), nil
+}
+
 func (tp *TaskProvisioner) Cancel(clusterID string) error {
 	if cancelFunc := tp.cancelMap[clusterID]; cancelFunc != nil {
 		cancelFunc()
@@ -227,60 +409,108 @@ func (tp *TaskProvisioner) RestartClusterProvisioning(parentCtx context.Context,
 	return nil
 }
 
+// RestartTask resumes the task run against the kube, machines and states
+// the task sends are saved to the kube until the task is done.
+func (tp *TaskProvisioner) RestartTask(parentCtx context.Context, kubeID string,
+	task *workflows.Task, out io.WriteCloser) error {
+	if task.Config == nil {
+		return errors.Wrapf(sgerrors.ErrNilEntity, "config of task %s", task.ID)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Channels of config are not persisted with the task
+	task.Config.SetNodeChan(make(chan model.Machine))
+	task.Config.SetKubeStateChan(make(chan model.KubeState))
+	task.Config.SetConfigChan(make(chan *steps.Config))
+
+	go tp.monitorClusterState(ctx, kubeID, task.Config.NodeChan(),
+		task.Config.KubeStateChan(), task.Config.ConfigChan())
+
+	errChan := task.Run(parentCtx, task.Config, out)
+
+	go func() {
+		defer cancel()
+
+		if err := <-errChan; err != nil {
+			logrus.Errorf("restarted task %s of kube %s has failed %v", task.ID, kubeID, err)
+
+			if task.Config.Node.Name != "" {
+				task.Config.Node.State = model.MachineStateError
+				task.Config.NodeChan() <- task.Config.Node
+			}
+		}
+	}()
+
+	return nil
+}
+
+// UpgradeCluster upgrades masters one at a time, then workers in batches of
+// configured size. Upgrade stops on the first failed machine, version of the
+// kube is updated once all machines have been upgraded, so rerun of the
+// upgrade resumes from machines that have not been upgraded yet.
 func (tp *TaskProvisioner) UpgradeCluster(parentCtx context.Context, nextVersion string, k *model.Kube,
 	tasks map[string][]*workflows.Task, config *steps.Config) {
-	bootstrapTask := tasks[workflows.MasterTask][0]
-	masterTasks := tasks[workflows.MasterTask][1:]
-	nodeTasks := tasks[workflows.NodeTask]
-
 	go tp.monitorClusterState(parentCtx, k.ID, config.NodeChan(),
 		config.KubeStateChan(), config.ConfigChan())
 
 	// TODO(stgleb): uncomment this once UI handle Upgrading state of the cluster
 	//config.KubeStateChan() <- model.StateUpgrading
 	logrus.Infof("Upgrade from %s to %s", k.K8SVersion, nextVersion)
-	bootstrapTask.Config.IsBootstrap = true
-	fileName := util.MakeFileName(bootstrapTask.ID)
-	writer, err := tp.getWriter(fileName)
-
-	if err != nil {
-		logrus.Errorf("error creating writer %v", err)
-		return
-	}
 
-	logrus.Infof("upgrade bootstrap node %v", bootstrapTask.Config.Node)
-	tp.upgradeMachine(bootstrapTask, writer)
-
-	for i := 0; i < len(masterTasks); i++ {
-		masterTask := masterTasks[i]
-		fileName := util.MakeFileName(masterTask.ID)
-		writer, err := tp.getWriter(fileName)
+	for _, masterTask := range tasks[workflows.MasterTask] {
+		logrus.Infof("Upgrade master node %v", masterTask.Config.Node)
 
-		if err != nil {
-			logrus.Errorf("error creating writer %v", err)
+		if err := tp.upgradeMachine(masterTask); err != nil {
+			logrus.Errorf("Upgrade of kube %s stopped on master %s: %v",
+				k.ID, masterTask.Config.Node.Name, err)
 			return
 		}
+	}
 
-		logrus.Infof("Upgrade master node %v", masterTask.Config.Node)
-		go tp.upgradeMachine(masterTask, writer)
+	nodeTasks := tasks[workflows.NodeTask]
+	batchSize := config.UpgradeConfig.BatchSize
+
+	if batchSize < 1 {
+		batchSize = 1
 	}
 
-	for i := 0; i < len(nodeTasks); i++ {
-		nodeTask := nodeTasks[i]
+	for i := 0; i < len(nodeTasks); i += batchSize {
+		end := i + batchSize
 
-		fileName := util.MakeFileName(nodeTask.ID)
-		writer, err := tp.getWriter(fileName)
+		if end > len(nodeTasks) {
+			end = len(nodeTasks)
+		}
 
-		if err != nil {
-			logrus.Errorf("error creating writer %v", err)
-			return
+		batch := nodeTasks[i:end]
+		errs := make([]error, len(batch))
+		wg := sync.WaitGroup{}
+
+		for j, nodeTask := range batch {
+			logrus.Infof("Upgrade worker node %v", nodeTask.Config.Node)
+			wg.Add(1)
+
+			go func(j int, nodeTask *workflows.Task) {
+				defer wg.Done()
+				errs[j] = tp.upgradeMachine(nodeTask)
+			}(j, nodeTask)
 		}
 
-		logrus.Infof("Upgrade worker node %v", nodeTask.Config.Node)
-		tp.upgradeMachine(nodeTask, writer)
-		config.KubeStateChan() <- model.StateOperational
-		config.ConfigChan() <- config
+		wg.Wait()
+
+		for j, err := range errs {
+			if err != nil {
+				logrus.Errorf("Upgrade of kube %s stopped on node %s: %v",
+					k.ID, batch[j].Config.Node.Name, err)
+				return
+			}
+		}
 	}
+
+	config.Kube.K8SVersion = nextVersion
+	config.ConfigChan() <- config
+	config.KubeStateChan() <- model.StateOperational
+	logrus.Infof("Kube %s has been upgraded to %s", k.ID, nextVersion)
 }
 
 // provision do actual provisioning of master and worker nodes
@@ -306,6 +536,24 @@ func (tp *TaskProvisioner) provision(ctx context.Context,
 		config.SetConfigChan(configChan)
 	}
 
+	if etcdTasks := taskMap[workflows.EtcdTask]; len(etcdTasks) > 0 {
+		logrus.Debug("Provision etcd")
+		endpoints, err := tp.provisionEtcd(ctx, clusterProfile, config, etcdTasks)
+
+		if err != nil {
+			config.KubeStateChan() <- model.StateFailed
+			logrus.Errorf("etcd provisioning has failed with %v", err)
+			return
+		}
+
+		// Masters use dedicated etcd instead of the stacked one
+		config.EtcdConfig.Endpoints = endpoints
+
+		for _, masterTask := range taskMap[workflows.MasterTask] {
+			masterTask.Config.EtcdConfig.Endpoints = endpoints
+		}
+	}
+
 	if len(taskMap[workflows.MasterTask]) == 0 {
 		return
 	}
@@ -342,10 +590,16 @@ func (tp *TaskProvisioner) provision(ctx context.Context,
 		"%s has finished successfully",
 		config.Kube.ID)
 
+	var clusterTask *workflows.Task
+
+	if len(taskMap[workflows.ClusterTask]) != 0 {
+		clusterTask = taskMap[workflows.ClusterTask][0]
+	}
+
 	config.IsBootstrap = false
 	config.IsMaster = false
 	err = tp.provisionNodes(ctx, clusterProfile, config,
-		taskMap[workflows.NodeTask])
+		taskMap[workflows.NodeTask], clusterTask)
 
 	if err != nil {
 		config.KubeStateChan() <- model.StateFailed
@@ -353,9 +607,8 @@ func (tp *TaskProvisioner) provision(ctx context.Context,
 		return
 	}
 
-	if len(taskMap[workflows.ClusterTask]) != 0 {
+	if clusterTask != nil {
 		// Wait for cluster checks are finished
-		clusterTask := taskMap[workflows.ClusterTask][0]
 		err = tp.waitCluster(ctx, clusterTask, config)
 
 		if err != nil {
@@ -371,7 +624,7 @@ func (tp *TaskProvisioner) provision(ctx context.Context,
 }
 
 // prepare creates all tasks for provisioning according to cloud provider
-func (tp *TaskProvisioner) prepare(config *steps.Config, masterCount, nodeCount int) map[string][]*workflows.Task {
+func (tp *TaskProvisioner) prepare(config *steps.Config, masterCount, nodeCount, etcdCount int) map[string][]*workflows.Task {
 	var (
 		infraTask   *workflows.Task
 		clusterTask *workflows.Task
@@ -414,6 +667,17 @@ func (tp *TaskProvisioner) prepare(config *steps.Config, masterCount, nodeCount
 		return nil
 	}
 
+	etcdTasks := make([]*workflows.Task, 0, etcdCount)
+
+	for i := 0; i < etcdCount; i++ {
+		t, err := workflows.NewTask(config, workflows.ProvisionEtcd, tp.repository)
+		if err != nil {
+			logrus.Errorf("Failed to set up task for %s workflow", workflows.ProvisionEtcd)
+			continue
+		}
+		etcdTasks = append(etcdTasks, t)
+	}
+
 	taskMap := map[string][]*workflows.Task{
 		workflows.MasterTask:  masterTasks,
 		workflows.NodeTask:    nodeTasks,
@@ -424,6 +688,10 @@ func (tp *TaskProvisioner) prepare(config *steps.Config, masterCount, nodeCount
 		taskMap[workflows.PreProvisionTask] = []*workflows.Task{infraTask}
 	}
 
+	if len(etcdTasks) > 0 {
+		taskMap[workflows.EtcdTask] = etcdTasks
+	}
+
 	return taskMap
 }
 
@@ -438,7 +706,7 @@ func (tp *TaskProvisioner) preProvision(ctx context.Context, preProvisionTask *w
 		return err
 	}
 
-	result := preProvisionTask.Run(ctx, *config, out)
+	result := preProvisionTask.Run(ctx, config.Clone(), out)
 	err = <-result
 	config.ConfigChan() <- preProvisionTask.Config
 
@@ -453,6 +721,58 @@ func (tp *TaskProvisioner) preProvision(ctx context.Context, preProvisionTask *w
 	return err
 }
 
+// provisionEtcd provisions etcd members one at a time, each member joins
+// the members provisioned before it. Client urls of the members are returned.
+func (tp *TaskProvisioner) provisionEtcd(ctx context.Context,
+	clusterProfile *profile.Profile, rootConfig *steps.Config,
+	tasks []*workflows.Task) ([]string, error) {
+	nodeProfiles := profile.EtcdNodeProfiles(clusterProfile.Etcd)
+	endpoints := make([]string, 0, len(tasks))
+
+	for index, etcdTask := range tasks {
+		// Members provisioned before restart are already in the cluster
+		if etcdTask.Status == statuses.Success {
+			endpoints = append(endpoints, etcd.ClientURL(etcdTask.Config.Node.PrivateIp))
+			continue
+		}
+
+		if index >= len(nodeProfiles) {
+			return nil, errors.Wrapf(sgerrors.ErrNotFound, "profile of etcd member %d", index)
+		}
+
+		config := rootConfig.Clone()
+
+		if err := FillNodeCloudSpecificData(clusterProfile.Provider, nodeProfiles[index], config); err != nil {
+			return nil, errors.Wrap(err, "fill etcd profile data to config")
+		}
+
+		config.TaskID = etcdTask.ID
+		config.IsEtcd = true
+		config.IsMaster = false
+		config.IsBootstrap = false
+		config.EtcdConfig.Endpoints = append([]string(nil), endpoints...)
+
+		fileName := util.MakeFileName(etcdTask.ID)
+		out, err := tp.getWriter(fileName)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error getting writer for %s", fileName)
+		}
+
+		// Take token that allows perform action with Cloud Provider API
+		tp.rateLimiter.Take()
+
+		if err := <-etcdTask.Run(ctx, config, out); err != nil {
+			return nil, errors.Wrapf(err, "etcd task %s", etcdTask.ID)
+		}
+
+		logrus.Infof("etcd task %s has finished", etcdTask.ID)
+		endpoints = append(endpoints, etcd.ClientURL(etcdTask.Config.Node.PrivateIp))
+	}
+
+	return endpoints, nil
+}
+
 func (tp *TaskProvisioner) bootstrapMaster(ctx context.Context,
 	profile *profile.Profile, rootConfig *steps.Config,
 	bootstrapTask *workflows.Task) error {
@@ -482,7 +802,7 @@ func (tp *TaskProvisioner) bootstrapMaster(ctx context.Context,
 	bootstrapTask.Config.IsBootstrap = true
 	bootstrapTask.Config.IsMaster = true
 
-	err = <-bootstrapTask.Run(ctx, *bootstrapTask.Config, out)
+	err = <-bootstrapTask.Run(ctx, bootstrapTask.Config.Clone(), out)
 	rootConfig.ConfigChan() <- bootstrapTask.Config
 
 	if err != nil {
@@ -555,7 +875,7 @@ func (tp *TaskProvisioner) provisionMasters(ctx context.Context,
 			t.Config.IsMaster = true
 			t.Config.IsBootstrap = false
 
-			result := t.Run(ctx, *t.Config, out)
+			result := t.Run(ctx, t.Config.Clone(), out)
 			err = <-result
 			errChan <- err
 
@@ -575,9 +895,43 @@ func (tp *TaskProvisioner) provisionMasters(ctx context.Context,
 	return <-errChan
 }
 
-func (tp *TaskProvisioner) provisionNodes(ctx context.Context, profile *profile.Profile, rootConfig *steps.Config, tasks []*workflows.Task) error {
-	wg := sync.WaitGroup{}
-	wg.Add(len(tasks))
+// provisionNodes runs node workflows concurrently, no more than nodeParallelism
+// of them at once. Statuses of node tasks are aggregated by the cluster task,
+// provisioning fails when more than maxNodeFailureRatio of nodes have failed.
+func (tp *TaskProvisioner) provisionNodes(ctx context.Context, profile *profile.Profile,
+	rootConfig *steps.Config, tasks []*workflows.Task, clusterTask *workflows.Task) error {
+	var (
+		wg     sync.WaitGroup
+		m      sync.Mutex
+		failed int
+	)
+
+	setStatus := func(t *workflows.Task) {
+		if clusterTask == nil {
+			return
+		}
+
+		m.Lock()
+		defer m.Unlock()
+
+		// Node task may be cancelled along with provisioning
+		if err := clusterTask.SetSubtaskStatus(context.Background(), t.ID, t.Status); err != nil {
+			logrus.Errorf("save status of node task %s to cluster task %s %v", t.ID, clusterTask.ID, err)
+		}
+	}
+
+	for _, nodeTask := range tasks {
+		setStatus(nodeTask)
+	}
+
+	parallelism := tp.nodeParallelism
+
+	if parallelism < 1 {
+		parallelism = DefaultNodeParallelism
+	}
+
+	// Semaphore limits count of node workflows running at once
+	sem := make(chan struct{}, parallelism)
 
 	// ProvisionCluster nodes
 	for index, nodeTask := range tasks {
@@ -586,6 +940,13 @@ func (tp *TaskProvisioner) provisionNodes(ctx context.Context, profile *profile.
 			continue
 		}
 
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+
 		// Take token that allows perform action with Cloud Provider API
 		tp.rateLimiter.Take()
 
@@ -594,9 +955,13 @@ func (tp *TaskProvisioner) provisionNodes(ctx context.Context, profile *profile.
 
 		if err != nil {
 			logrus.Errorf("Error getting writer for %s", fileName)
+			wg.Wait()
 			return errors.Wrapf(err, "Error getting writer for %s", fileName)
 		}
 
+		// Node tasks run in parallel, each of them gets its own config
+		nodeTask.Config = rootConfig.Clone()
+
 		// Fulfill task config with data about provider specific node configuration
 		p := profile.NodesProfiles[index]
 		if err := MergeConfig(rootConfig, nodeTask.Config); err != nil {
@@ -604,17 +969,24 @@ func (tp *TaskProvisioner) provisionNodes(ctx context.Context, profile *profile.
 		}
 
 		if err := FillNodeCloudSpecificData(profile.Provider, p, nodeTask.Config); err != nil {
+			wg.Wait()
 			return errors.Wrapf(err, "fill nodes profile caused")
 		}
 
 		// Put task id to config so that create instance step can use this id when generate node name
 		nodeTask.Config.TaskID = nodeTask.ID
 
-		go func(t *workflows.Task) {
+		wg.Add(1)
+		go func(t *workflows.Task, out io.WriteCloser) {
+			defer wg.Done()
+			defer func() {
+				<-sem
+			}()
+
 			t.Config.IsMaster = false
 			t.Config.IsBootstrap = false
-			result := t.Run(ctx, *t.Config, out)
-			err = <-result
+			result := t.Run(ctx, t.Config.Clone(), out)
+			err := <-result
 
 			if err != nil {
 				// Put node to error state
@@ -622,19 +994,45 @@ func (tp *TaskProvisioner) provisionNodes(ctx context.Context, profile *profile.
 				t.Config.AddNode(&t.Config.Node)
 				t.Config.NodeChan() <- t.Config.Node
 
+				m.Lock()
+				failed++
+				m.Unlock()
+
 				logrus.Errorf("node task %s has finished with error %v", t.ID, err)
 			} else {
 				logrus.Infof("node-task %s has finished", t.ID)
 			}
 
-			wg.Done()
-		}(nodeTask)
+			setStatus(t)
+		}(nodeTask, out)
 	}
 
 	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if tooManyFailures(failed, len(tasks), tp.maxNodeFailureRatio) {
+		return errors.Errorf("%d of %d nodes have failed", failed, len(tasks))
+	}
+
+	if failed > 0 {
+		logrus.Warnf("%d of %d nodes have failed", failed, len(tasks))
+	}
+
 	return nil
 }
 
+// tooManyFailures returns true when more than ratio of total have failed.
+func tooManyFailures(failed, total int, ratio float64) bool {
+	if total == 0 {
+		return false
+	}
+
+	return float64(failed) > ratio*float64(total)
+}
+
 func (tp *TaskProvisioner) waitCluster(ctx context.Context, clusterTask *workflows.Task, config *steps.Config) error {
 	fileName := util.MakeFileName(clusterTask.ID)
 	out, err := tp.getWriter(fileName)
@@ -656,7 +1054,7 @@ func (tp *TaskProvisioner) waitCluster(ctx context.Context, clusterTask *workflo
 		return errors.New("No master found, cluster deployment failed")
 	}
 	clusterTask.Config = &cfg
-	result := clusterTask.Run(ctx, *clusterTask.Config, out)
+	result := clusterTask.Run(ctx, clusterTask.Config.Clone(), out)
 	err = <-result
 
 	if err != nil {
@@ -672,7 +1070,7 @@ func buildNodeProvisionScript(ctx context.Context, config *steps.Config) {
 	dryRunner := dry.NewDryRunner()
 	repository := memory.NewInMemoryRepository()
 
-	dryConfig := *config
+	dryConfig := config.Clone()
 	// These values must still be templated
 	dryConfig.Node.PublicIp = "{{ .PublicIp }}"
 	dryConfig.Node.PrivateIp = "{{ .PrivateIp }}"
@@ -684,15 +1082,20 @@ func buildNodeProvisionScript(ctx context.Context, config *steps.Config) {
 		// https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/using-instance-addressing.html#using-instance-addressing-common
 		dryConfig.Node.PublicIp = "$(curl http://169.254.169.254/latest/meta-data/public-ipv4)"
 		dryConfig.Node.PrivateIp = "$(curl http://169.254.169.254/latest/meta-data/local-ipv4)"
+
+		// Instances of private kube have no public ip
+		if config.Kube.PrivateNetworking {
+			dryConfig.Node.PublicIp = ""
+		}
 	}
 
-	task, err := workflows.NewTask(&dryConfig, workflows.ProvisionNode, repository)
+	task, err := workflows.NewTask(dryConfig, workflows.ProvisionNode, repository)
 
 	if err != nil {
 		return
 	}
 
-	task.Config = &dryConfig
+	task.Config = dryConfig
 	resultChan := task.Run(ctx, dryConfig, &bufferCloser{
 		Writer: &bytes.Buffer{},
 	})
@@ -757,6 +1160,26 @@ func bootstrapCerts(config *steps.Config) error {
 	return nil
 }
 
+// bootstrapEtcdCerts creates CA of dedicated etcd and client certificate
+// that API servers use to access it.
+func bootstrapEtcdCerts(config *steps.Config) error {
+	ca, err := pki.NewCAPair(nil)
+	if err != nil {
+		return errors.Wrap(err, "create etcd CA")
+	}
+	config.Kube.Auth.EtcdCACert = string(ca.Cert)
+	config.Kube.Auth.EtcdCAKey = string(ca.Key)
+
+	client, err := pki.NewUserPair(pki.EtcdClientName, []string{pki.MastersGroup}, ca)
+	if err != nil {
+		return errors.Wrap(err, "create etcd client certificates")
+	}
+	config.Kube.Auth.EtcdClientCert = string(client.Cert)
+	config.Kube.Auth.EtcdClientKey = string(client.Key)
+
+	return nil
+}
+
 // TODO(stgleb): move it out of the provisioner
 // All cluster state changes during provisioning must be made in this function
 func (tp *TaskProvisioner) monitorClusterState(ctx context.Context,
@@ -765,65 +1188,76 @@ func (tp *TaskProvisioner) monitorClusterState(ctx context.Context,
 	for {
 		select {
 		case n := <-nodeChan:
-			k, err := tp.kubeService.Get(ctx, clusterID)
-
-			if err != nil {
-				logrus.Errorf("cluster monitor: update kube state caused %v", err)
-				continue
-			}
-
-			if n.Role == model.RoleMaster {
-				k.Masters[n.Name] = &n
-			} else {
-				k.Nodes[n.Name] = &n
-			}
-
-			err = tp.kubeService.Create(ctx, k)
+			err := tp.updateKube(ctx, clusterID, func(k *model.Kube) {
+				switch n.Role {
+				case model.RoleMaster:
+					k.Masters[n.Name] = &n
+				case model.RoleEtcd:
+					if k.Etcd == nil {
+						k.Etcd = make(map[string]*model.Machine)
+					}
+
+					k.Etcd[n.Name] = &n
+				default:
+					k.Nodes[n.Name] = &n
+				}
+			})
 
 			if err != nil {
 				logrus.Errorf("cluster monitor: update kube state caused %v", err)
 				continue
 			}
 		case state := <-kubeStateChan:
-			logrus.Debugf("monitor: get kube %s", clusterID)
-			k, err := tp.kubeService.Get(ctx, clusterID)
+			logrus.Debugf("monitor: update kube %s with state %s",
+				clusterID, state)
+			err := tp.updateKube(ctx, clusterID, func(k *model.Kube) {
+				k.State = state
+			})
 
 			if err != nil {
 				logrus.Errorf("cluster monitor: update kube state caused %v", err)
 				continue
 			}
 
-			k.State = state
-			logrus.Debugf("monitor: update kube %s with state %s",
-				k.ID, state)
-			err = tp.kubeService.Create(ctx, k)
-
-			if err != nil {
-				logrus.Errorf("cluster monitor: update kube state caused %v", err)
-				continue
+			if state == model.StateOperational && tp.addonInstaller != nil {
+				tp.addonInstaller.InstallAddons(ctx, clusterID)
 			}
 		case config := <-configChan:
 			logrus.Debugf("update kube %s with config", clusterID)
-			k, err := tp.kubeService.Get(ctx, clusterID)
+			err := tp.updateKube(ctx, clusterID, func(k *model.Kube) {
+				util.UpdateKubeWithCloudSpecificData(k, config)
+			})
 
 			if err != nil {
 				logrus.Errorf("cluster monitor: update kube state caused %v", err)
 				continue
 			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
 
-			logrus.Debugf("update kube %s with config", k.ID)
-			util.UpdateKubeWithCloudSpecificData(k, config)
+// updateKube gets the kube, applies update to it and stores it, the kube
+// is got and update is applied again when it has been modified meanwhile.
+func (tp *TaskProvisioner) updateKube(ctx context.Context, kubeID string, update func(*model.Kube)) error {
+	var err error
 
-			err = tp.kubeService.Create(ctx, k)
+	for i := 0; i < maxKubeUpdateAttempts; i++ {
+		var k *model.Kube
 
-			if err != nil {
-				logrus.Errorf("cluster monitor: update kube state caused %v", err)
-				continue
-			}
-		case <-ctx.Done():
-			return
+		if k, err = tp.kubeService.Get(ctx, kubeID); err != nil {
+			return err
+		}
+
+		update(k)
+
+		if err = tp.kubeService.Update(ctx, k); !sgerrors.IsConflict(err) {
+			return err
 		}
 	}
+
+	return err
 }
 
 func (tp *TaskProvisioner) deserializeClusterTasks(ctx context.Context, kubeConfig *steps.Config, taskIdMap map[string][]string) (map[string][]*workflows.Task, error) {
@@ -859,19 +1293,27 @@ func (tp *TaskProvisioner) deserializeClusterTasks(ctx context.Context, kubeConf
 	return taskMap, nil
 }
 
-func (tp *TaskProvisioner) upgradeMachine(task *workflows.Task, writer io.WriteCloser) {
+func (tp *TaskProvisioner) upgradeMachine(task *workflows.Task) error {
+	writer, err := tp.getWriter(util.MakeFileName(task.ID))
+
+	if err != nil {
+		return errors.Wrapf(err, "create writer for task %s", task.ID)
+	}
+
 	task.Config.Node.State = model.MachineStateUpgrading
 	task.Config.NodeChan() <- task.Config.Node
 
-	resultChan := task.Run(context.Background(), *task.Config, writer)
-	err := <-resultChan
+	resultChan := task.Run(context.Background(), task.Config.Clone(), writer)
 
-	if err != nil {
+	if err := <-resultChan; err != nil {
 		task.Config.Node.State = model.MachineStateError
 		task.Config.NodeChan() <- task.Config.Node
 		logrus.Errorf("task %s has finished with error %v", task.ID, err)
+		return err
 	}
 
 	task.Config.Node.State = model.MachineStateActive
 	task.Config.NodeChan() <- task.Config.Node
+
+	return nil
 }
@@ -2,8 +2,11 @@ package provisioner
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -16,8 +19,10 @@ import (
 	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/profile"
 	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/storage/memory"
 	"github.com/supergiant/control/pkg/testutils"
 	"github.com/supergiant/control/pkg/workflows"
+	"github.com/supergiant/control/pkg/workflows/statuses"
 	"github.com/supergiant/control/pkg/workflows/steps"
 )
 
@@ -38,6 +43,10 @@ func (m *mockKubeService) Create(ctx context.Context, k *model.Kube) error {
 	return m.createErr
 }
 
+func (m *mockKubeService) Update(ctx context.Context, k *model.Kube) error {
+	return m.Create(ctx, k)
+}
+
 func (m *mockKubeService) Get(ctx context.Context, kname string) (*model.Kube, error) {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
@@ -113,6 +122,9 @@ func TestProvisionCluster(t *testing.T) {
 		},
 		NewRateLimiter(time.Nanosecond * 1),
 		make(map[string]func()),
+		DefaultNodeParallelism,
+		DefaultMaxNodeFailureRatio,
+		nil,
 	}
 
 	workflows.Init()
@@ -217,6 +229,9 @@ func TestProvisionNodes(t *testing.T) {
 		},
 		NewRateLimiter(time.Nanosecond * 1),
 		make(map[string]func()),
+		DefaultNodeParallelism,
+		DefaultMaxNodeFailureRatio,
+		nil,
 	}
 
 	workflows.Init()
@@ -273,6 +288,665 @@ func TestProvisionNodes(t *testing.T) {
 
 }
 
+type nodeStep struct {
+	mockStep
+
+	m        sync.Mutex
+	running  int
+	max      int
+	runs     int
+	failures int
+	delay    time.Duration
+	configs  []*steps.Config
+}
+
+func (s *nodeStep) Run(ctx context.Context, out io.Writer, cfg *steps.Config) error {
+	s.m.Lock()
+	s.running++
+	s.runs++
+	s.configs = append(s.configs, cfg)
+	if s.running > s.max {
+		s.max = s.running
+	}
+	fail := s.runs <= s.failures
+	s.m.Unlock()
+
+	defer func() {
+		s.m.Lock()
+		s.running--
+		s.m.Unlock()
+	}()
+
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if fail {
+		return errors.New("node has failed")
+	}
+
+	return nil
+}
+
+func nodeTasks(t *testing.T, count int) (*profile.Profile, *steps.Config, []*workflows.Task, *workflows.Task) {
+	repository := &testutils.MockStorage{}
+	repository.On("Put", mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	p := &profile.Profile{
+		Provider: clouds.DigitalOcean,
+	}
+
+	for i := 0; i < count; i++ {
+		p.NodesProfiles = append(p.NodesProfiles, profile.NodeProfile{
+			"size":  "s-2vcpu-4gb",
+			"image": "ubuntu-18-04-x64",
+		})
+	}
+
+	cfg, err := steps.NewConfig("test", "", *p)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	tasks := make([]*workflows.Task, 0, count)
+
+	for i := 0; i < count; i++ {
+		task, err := workflows.NewTask(cfg, workflows.ProvisionNode, repository)
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	clusterTask, err := workflows.NewTask(cfg, workflows.PostProvision, repository)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	return p, cfg, tasks, clusterTask
+}
+
+func TestProvisionNodesParallel(t *testing.T) {
+	testCases := []struct {
+		description string
+		nodes       int
+		parallelism int
+		ratio       float64
+		failures    int
+		expectedErr bool
+	}{
+		{
+			description: "parallelism is bounded",
+			nodes:       6,
+			parallelism: 2,
+		},
+		{
+			description: "failures within ratio",
+			nodes:       10,
+			parallelism: 5,
+			ratio:       0.1,
+			failures:    1,
+		},
+		{
+			description: "too many failures",
+			nodes:       10,
+			parallelism: 5,
+			ratio:       0.1,
+			failures:    2,
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		step := &nodeStep{
+			failures: testCase.failures,
+			delay:    time.Millisecond * 10,
+		}
+
+		workflows.Init()
+		workflows.RegisterWorkFlow(workflows.ProvisionNode, []steps.Step{step})
+		workflows.RegisterWorkFlow(workflows.PostProvision, []steps.Step{&mockStep{}})
+
+		p, cfg, tasks, clusterTask := nodeTasks(t, testCase.nodes)
+
+		provisioner := NewProvisioner(nil, nil, time.Nanosecond, "")
+		provisioner.getWriter = func(string) (io.WriteCloser, error) {
+			return &bufferCloser{ioutil.Discard, nil}, nil
+		}
+		provisioner.SetNodeParallelism(testCase.parallelism)
+		provisioner.SetMaxNodeFailureRatio(testCase.ratio)
+
+		err := provisioner.provisionNodes(context.Background(), p, cfg, tasks, clusterTask)
+
+		if testCase.expectedErr != (err != nil) {
+			t.Errorf("%s: unexpected error %v", testCase.description, err)
+		}
+
+		if step.runs != testCase.nodes {
+			t.Errorf("%s: expected node runs %d actual %d", testCase.description,
+				testCase.nodes, step.runs)
+		}
+
+		if step.max > testCase.parallelism {
+			t.Errorf("%s: expected at most %d nodes at once actual %d", testCase.description,
+				testCase.parallelism, step.max)
+		}
+
+		if len(clusterTask.Subtasks) != testCase.nodes {
+			t.Errorf("%s: expected statuses of %d node tasks actual %v", testCase.description,
+				testCase.nodes, clusterTask.Subtasks)
+		}
+
+		failed := 0
+
+		for _, status := range clusterTask.Subtasks {
+			if status == statuses.Error {
+				failed++
+			}
+		}
+
+		if failed != testCase.failures {
+			t.Errorf("%s: expected failed node tasks %d actual %d", testCase.description,
+				testCase.failures, failed)
+		}
+	}
+}
+
+func TestProvisionNodePool(t *testing.T) {
+	step := &nodeStep{}
+
+	workflows.Init()
+	workflows.RegisterWorkFlow(workflows.ProvisionNode, []steps.Step{step})
+
+	k := &model.Kube{
+		ID:       "1234",
+		Provider: clouds.DigitalOcean,
+		Masters: map[string]*model.Machine{
+			"master": {Name: "master"},
+		},
+		CloudSpec: make(map[string]string),
+	}
+
+	repo := memory.NewInMemoryRepository()
+	provisioner := NewProvisioner(repo, &mockKubeService{
+		data: map[string]model.Kube{
+			k.ID: *k,
+		},
+	}, time.Nanosecond, "")
+	provisioner.getWriter = func(string) (io.WriteCloser, error) {
+		return &bufferCloser{ioutil.Discard, nil}, nil
+	}
+
+	config, err := steps.NewConfig("test", "", profile.Profile{
+		Provider: clouds.DigitalOcean,
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	config.Kube.ID = k.ID
+	pool := profile.NodePool{
+		Name:        "gpu",
+		MachineType: "s-2vcpu-4gb",
+		Labels:      map[string]string{"team": "ml"},
+	}
+
+	poolTask, taskIDs, err := provisioner.ProvisionNodePool(context.Background(), pool, 3, k, config)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(taskIDs) != 3 {
+		t.Errorf("Expected 3 node tasks actual %v", taskIDs)
+	}
+
+	// Pool task is read as it is saved, it is being run meanwhile
+	stored := &workflows.Task{}
+	deadline := time.Now().Add(time.Second * 5)
+
+	for time.Now().Before(deadline) && stored.Status != statuses.Success {
+		time.Sleep(time.Millisecond * 10)
+
+		data, err := repo.Get(context.Background(), workflows.Prefix, poolTask.ID)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		if err := json.Unmarshal(data, stored); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	if stored.Status != statuses.Success {
+		t.Fatalf("Expected pool task status %s actual %s", statuses.Success, stored.Status)
+	}
+
+	if len(stored.Subtasks) != 3 {
+		t.Errorf("Expected statuses of 3 node tasks actual %v", stored.Subtasks)
+	}
+
+	step.m.Lock()
+	defer step.m.Unlock()
+
+	if len(step.configs) != 3 {
+		t.Fatalf("Expected 3 node runs actual %d", len(step.configs))
+	}
+
+	for _, cfg := range step.configs {
+		if cfg.Pool != pool.Name || cfg.Labels != "team=ml" {
+			t.Errorf("Pool of nodes must be set to config %s %s", cfg.Pool, cfg.Labels)
+		}
+	}
+}
+
+type etcdStep struct {
+	mockStep
+
+	configs []*steps.Config
+}
+
+func (s *etcdStep) Run(ctx context.Context, out io.Writer, cfg *steps.Config) error {
+	cfg.Node.PrivateIp = fmt.Sprintf("10.0.0.%d", len(s.configs)+1)
+	s.configs = append(s.configs, cfg)
+	return nil
+}
+
+func TestProvisionEtcd(t *testing.T) {
+	step := &etcdStep{}
+
+	workflows.Init()
+	workflows.RegisterWorkFlow(workflows.ProvisionEtcd, []steps.Step{step})
+
+	provisioner := NewProvisioner(memory.NewInMemoryRepository(), &mockKubeService{
+		data: make(map[string]model.Kube),
+	}, time.Nanosecond, "")
+	provisioner.getWriter = func(string) (io.WriteCloser, error) {
+		return &bufferCloser{ioutil.Discard, nil}, nil
+	}
+
+	p := &profile.Profile{
+		Provider: clouds.DigitalOcean,
+		Etcd: &profile.EtcdProfile{
+			Count:       3,
+			MachineType: "s-2vcpu-4gb",
+		},
+	}
+
+	config, err := steps.NewConfig("test", "", *p)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	tasks := make([]*workflows.Task, 0, p.Etcd.Count)
+
+	for i := 0; i < p.Etcd.Count; i++ {
+		task, err := workflows.NewTask(config, workflows.ProvisionEtcd, provisioner.repository)
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	endpoints, err := provisioner.provisionEtcd(context.Background(), p, config, tasks)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{
+		"https://10.0.0.1:2379",
+		"https://10.0.0.2:2379",
+		"https://10.0.0.3:2379",
+	}
+
+	if !reflect.DeepEqual(endpoints, expected) {
+		t.Errorf("Wrong endpoints expected %v actual %v", expected, endpoints)
+	}
+
+	if len(step.configs) != 3 {
+		t.Fatalf("Expected 3 etcd members actual %d", len(step.configs))
+	}
+
+	// Each member joins the members provisioned before it
+	for i, cfg := range step.configs {
+		if !cfg.IsEtcd || cfg.IsMaster || cfg.DigitalOceanConfig.Size != "s-2vcpu-4gb" {
+			t.Errorf("Wrong config of etcd member %d etcd %v master %v size %s", i,
+				cfg.IsEtcd, cfg.IsMaster, cfg.DigitalOceanConfig.Size)
+		}
+
+		if fmt.Sprint(cfg.EtcdConfig.Endpoints) != fmt.Sprint(expected[:i]) {
+			t.Errorf("Wrong endpoints of etcd member %d %v", i, cfg.EtcdConfig.Endpoints)
+		}
+	}
+
+	if config.IsEtcd {
+		t.Errorf("Config of the cluster must not be changed")
+	}
+}
+
+type machineStep struct {
+	mockStep
+}
+
+func (s *machineStep) Run(ctx context.Context, out io.Writer, cfg *steps.Config) error {
+	cfg.Node.State = model.MachineStateActive
+	cfg.NodeChan() <- cfg.Node
+	return nil
+}
+
+// nodesRecorder sends nodes of every saved kube
+type nodesRecorder struct {
+	*mockKubeService
+	nodes chan map[string]model.Machine
+}
+
+func (r *nodesRecorder) Update(ctx context.Context, k *model.Kube) error {
+	nodes := make(map[string]model.Machine, len(k.Nodes))
+	for name, n := range k.Nodes {
+		nodes[name] = *n
+	}
+	r.nodes <- nodes
+	return r.mockKubeService.Update(ctx, k)
+}
+
+func TestRestartTask(t *testing.T) {
+	workflows.Init()
+	workflows.RegisterWorkFlow("restart", []steps.Step{&machineStep{}})
+
+	svc := &nodesRecorder{
+		mockKubeService: &mockKubeService{
+			data: map[string]model.Kube{
+				"1234": {
+					ID:      "1234",
+					Masters: make(map[string]*model.Machine),
+					Nodes:   make(map[string]*model.Machine),
+				},
+			},
+		},
+		nodes: make(chan map[string]model.Machine, 1),
+	}
+	provisioner := NewProvisioner(memory.NewInMemoryRepository(), svc,
+		time.Nanosecond, "")
+
+	if err := provisioner.RestartTask(context.Background(), "1234",
+		&workflows.Task{ID: "task"}, &bufferCloser{ioutil.Discard, nil}); errors.Cause(err) != sgerrors.ErrNilEntity {
+		t.Errorf("Task without config must not be restarted %v", err)
+	}
+
+	task, err := workflows.NewTask(&steps.Config{
+		Node: model.Machine{
+			Name: "node-1",
+			Role: model.RoleNode,
+		},
+	}, "restart", provisioner.repository)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	err = provisioner.RestartTask(context.Background(), "1234", task,
+		&bufferCloser{ioutil.Discard, nil})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// Machine of the resumed task is saved to the kube
+	select {
+	case nodes := <-svc.nodes:
+		if nodes["node-1"].State != model.MachineStateActive {
+			t.Errorf("Wrong machine saved %v", nodes)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Machine of the task has not been saved")
+	}
+}
+
+func TestReplaceMaster(t *testing.T) {
+	workflows.Init()
+	workflows.RegisterWorkFlow(workflows.ReplaceMaster, []steps.Step{&nodeStep{}})
+
+	k := &model.Kube{
+		ID:       "1234",
+		Provider: clouds.DigitalOcean,
+		Masters: map[string]*model.Machine{
+			"master-1": {Name: "master-1", PrivateIp: "10.0.0.1"},
+			"master-2": {Name: "master-2", PrivateIp: "10.0.0.2"},
+		},
+		CloudSpec: make(map[string]string),
+	}
+
+	provisioner := NewProvisioner(memory.NewInMemoryRepository(), &mockKubeService{
+		data: map[string]model.Kube{
+			k.ID: *k,
+		},
+	}, time.Nanosecond, "")
+	provisioner.getWriter = func(string) (io.WriteCloser, error) {
+		return &bufferCloser{ioutil.Discard, nil}, nil
+	}
+
+	config, err := steps.NewConfig("test", "", profile.Profile{
+		Provider: clouds.DigitalOcean,
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	config.Kube.ID = k.ID
+	task, done, err := provisioner.ReplaceMaster(context.Background(),
+		profile.NodeProfile{"size": "s-2vcpu-4gb"}, k, k.Masters["master-1"], config)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Replace master task has not finished")
+	}
+
+	if task.Type != workflows.ReplaceMaster || task.Status != statuses.Success {
+		t.Errorf("Wrong task %s %s", task.Type, task.Status)
+	}
+
+	if !config.IsMaster || config.IsBootstrap {
+		t.Errorf("New master must join existing cluster")
+	}
+
+	if config.ReplaceMasterConfig.Name != "master-1" ||
+		config.ReplaceMasterConfig.PrivateIP != "10.0.0.1" {
+		t.Errorf("Replaced master must be set to config %+v", config.ReplaceMasterConfig)
+	}
+
+	if masters := config.GetMasters(); len(masters) != 1 || masters["master-2"] == nil {
+		t.Errorf("Replaced master must not be used by the task %v", masters)
+	}
+}
+
+func TestUpgradeCluster(t *testing.T) {
+	testCases := []struct {
+		description     string
+		batchSize       int
+		failures        int
+		expectedRuns    int
+		expectedMax     int
+		expectedVersion string
+	}{
+		{
+			description:     "one at a time",
+			expectedRuns:    5,
+			expectedMax:     1,
+			expectedVersion: "1.14.3",
+		},
+		{
+			description:     "batches of workers",
+			batchSize:       2,
+			expectedRuns:    5,
+			expectedMax:     2,
+			expectedVersion: "1.14.3",
+		},
+		{
+			description:     "master has failed",
+			batchSize:       2,
+			failures:        1,
+			expectedRuns:    1,
+			expectedMax:     1,
+			expectedVersion: "1.13.7",
+		},
+	}
+
+	for _, testCase := range testCases {
+		step := &nodeStep{
+			delay:    time.Millisecond * 20,
+			failures: testCase.failures,
+		}
+
+		workflows.Init()
+		workflows.RegisterWorkFlow(workflows.Upgrade, []steps.Step{step})
+
+		k := &model.Kube{
+			ID:         "1234",
+			K8SVersion: "1.13.7",
+			Masters: map[string]*model.Machine{
+				"master-1": {Name: "master-1", Role: model.RoleMaster},
+				"master-2": {Name: "master-2", Role: model.RoleMaster},
+			},
+			Nodes: map[string]*model.Machine{
+				"node-1": {Name: "node-1", Role: model.RoleNode},
+				"node-2": {Name: "node-2", Role: model.RoleNode},
+				"node-3": {Name: "node-3", Role: model.RoleNode},
+			},
+			CloudSpec: make(map[string]string),
+		}
+
+		svc := &mockKubeService{
+			data: map[string]model.Kube{
+				k.ID: *k,
+			},
+		}
+		repository := memory.NewInMemoryRepository()
+		provisioner := NewProvisioner(repository, svc, time.Nanosecond, "")
+		provisioner.getWriter = func(string) (io.WriteCloser, error) {
+			return &bufferCloser{ioutil.Discard, nil}, nil
+		}
+
+		config, err := steps.NewConfig("test", "", profile.Profile{
+			Provider: clouds.DigitalOcean,
+		})
+
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", testCase.description, err)
+		}
+
+		config.Kube.ID = k.ID
+		config.Kube.K8SVersion = "1.14.3"
+		config.UpgradeConfig.BatchSize = testCase.batchSize
+		tasks := make(map[string][]*workflows.Task)
+
+		for taskSet, machines := range map[string]map[string]*model.Machine{
+			workflows.MasterTask: k.Masters,
+			workflows.NodeTask:   k.Nodes,
+		} {
+			for _, machine := range machines {
+				task, err := workflows.NewTask(config, workflows.Upgrade, repository)
+
+				if err != nil {
+					t.Fatalf("%s: unexpected error %v", testCase.description, err)
+				}
+
+				task.Config = config.Clone()
+				task.Config.Node = *machine
+				tasks[taskSet] = append(tasks[taskSet], task)
+			}
+		}
+
+		provisioner.UpgradeCluster(context.Background(), "1.14.3", k, tasks, config)
+
+		if step.runs != testCase.expectedRuns {
+			t.Errorf("%s: expected runs %d actual %d", testCase.description,
+				testCase.expectedRuns, step.runs)
+		}
+
+		if step.max != testCase.expectedMax {
+			t.Errorf("%s: expected upgraded at the same time %d actual %d",
+				testCase.description, testCase.expectedMax, step.max)
+		}
+
+		// Version is saved by cluster monitor
+		deadline := time.Now().Add(time.Second * 5)
+
+		for {
+			kube, _ := svc.Get(context.Background(), k.ID)
+
+			if kube.K8SVersion == testCase.expectedVersion {
+				break
+			}
+
+			if time.Now().After(deadline) {
+				t.Errorf("%s: expected version %s actual %s", testCase.description,
+					testCase.expectedVersion, kube.K8SVersion)
+				break
+			}
+
+			time.Sleep(time.Millisecond * 10)
+		}
+	}
+}
+
+func TestProvisionNodesCancel(t *testing.T) {
+	step := &nodeStep{
+		delay: time.Minute,
+	}
+
+	workflows.Init()
+	workflows.RegisterWorkFlow(workflows.ProvisionNode, []steps.Step{step})
+	workflows.RegisterWorkFlow(workflows.PostProvision, []steps.Step{&mockStep{}})
+
+	p, cfg, tasks, clusterTask := nodeTasks(t, 4)
+
+	provisioner := NewProvisioner(nil, nil, time.Nanosecond, "")
+	provisioner.getWriter = func(string) (io.WriteCloser, error) {
+		return &bufferCloser{ioutil.Discard, nil}, nil
+	}
+	provisioner.SetNodeParallelism(2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond*50, cancel)
+
+	start := time.Now()
+	err := provisioner.provisionNodes(ctx, p, cfg, tasks, clusterTask)
+
+	if err != context.Canceled {
+		t.Errorf("Expected error %v actual %v", context.Canceled, err)
+	}
+
+	if time.Since(start) > time.Second*5 {
+		t.Errorf("In-flight node workflows must be stopped promptly")
+	}
+
+	if step.runs != 2 {
+		t.Errorf("Expected node runs %d actual %d", 2, step.runs)
+	}
+}
+
 func TestRestartProvisionClusterSuccess(t *testing.T) {
 	repository := &testutils.MockStorage{}
 	repository.On("Put", mock.Anything,
@@ -311,6 +985,9 @@ func TestRestartProvisionClusterSuccess(t *testing.T) {
 		},
 		NewRateLimiter(time.Nanosecond * 1),
 		make(map[string]func()),
+		DefaultNodeParallelism,
+		DefaultMaxNodeFailureRatio,
+		nil,
 	}
 
 	workflows.Init()
@@ -384,6 +1061,9 @@ func TestRestartProvisionClusterError(t *testing.T) {
 		},
 		NewRateLimiter(time.Nanosecond * 1),
 		make(map[string]func()),
+		DefaultNodeParallelism,
+		DefaultMaxNodeFailureRatio,
+		nil,
 	}
 
 	workflows.Init()
@@ -652,6 +1332,58 @@ func TestMonitorCluster(t *testing.T) {
 	}
 }
 
+type addonInstallerMock struct {
+	installed chan string
+}
+
+func (m *addonInstallerMock) InstallAddons(ctx context.Context, kubeID string) {
+	m.installed <- kubeID
+}
+
+func TestMonitorClusterStateInstallsAddons(t *testing.T) {
+	svc := &mockKubeService{
+		data: map[string]model.Kube{
+			"1234": {ID: "1234"},
+		},
+	}
+	installer := &addonInstallerMock{
+		installed: make(chan string, 2),
+	}
+
+	p := &TaskProvisioner{
+		kubeService: svc,
+	}
+	p.SetAddonInstaller(installer)
+
+	cfg, err := steps.NewConfig("test", "test", profile.Profile{})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go p.monitorClusterState(ctx, "1234", cfg.NodeChan(),
+		cfg.KubeStateChan(), cfg.ConfigChan())
+
+	cfg.KubeStateChan() <- model.StateProvisioning
+	cfg.KubeStateChan() <- model.StateOperational
+
+	select {
+	case kubeID := <-installer.installed:
+		if kubeID != "1234" {
+			t.Errorf("Expected addons of kube 1234 actual %s", kubeID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Addons must be installed when kube is operational")
+	}
+
+	if len(installer.installed) != 0 {
+		t.Errorf("Addons must be installed only once")
+	}
+}
+
 func TestTaskProvisioner_Cancel(t *testing.T) {
 	clusterID := "1234"
 	called := false
@@ -39,6 +39,12 @@ func FillNodeCloudSpecificData(provider clouds.Name, nodeProfile profile.NodePro
 		config.IsMaster, _ = strconv.ParseBool(nodeProfile["isMaster"])
 	}
 
+	// Config may be shared by nodes of different pools
+	config.Pool = nodeProfile[profile.NodePoolKey]
+	config.Taints = nodeProfile[profile.TaintsKey]
+	config.Labels = nodeProfile[profile.LabelsKey]
+	config.GPU, _ = strconv.ParseBool(nodeProfile[profile.GPUKey])
+
 	switch provider {
 	case clouds.AWS:
 		return util.BindParams(nodeProfile, &config.AWSConfig)
@@ -182,6 +188,38 @@ func nodesFromProfile(clusterName string, masterTasks, nodeTasks []*workflows.Ta
 	return masters, nodes
 }
 
+// etcdFromProfile returns planned machines of dedicated etcd.
+func etcdFromProfile(clusterName string, etcdTasks []*workflows.Task, clusterProfile *profile.Profile) map[string]*model.Machine {
+	members := make(map[string]*model.Machine)
+
+	for index, p := range profile.EtcdNodeProfiles(clusterProfile.Etcd) {
+		if index >= len(etcdTasks) {
+			break
+		}
+
+		taskId := etcdTasks[index].ID
+		name := util.MakeNodeName(clusterName, taskId, false)
+
+		if clusterProfile.Provider == clouds.GCE {
+			name = strings.ToLower(name)
+		}
+
+		n := &model.Machine{
+			TaskID:   taskId,
+			Name:     name,
+			Role:     model.RoleEtcd,
+			Provider: clusterProfile.Provider,
+			Region:   clusterProfile.Region,
+			State:    model.MachineStatePlanned,
+		}
+
+		util.BindParams(p, n)
+		members[n.Name] = n
+	}
+
+	return members
+}
+
 func grabTaskIds(taskMap map[string][]*workflows.Task) map[string][]string {
 	taskIds := make(map[string][]string, 0)
 
@@ -0,0 +1,306 @@
+package provisioner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/account"
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/message"
+	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+	"github.com/supergiant/control/pkg/workflows/steps/amazon"
+)
+
+// CloudValidationTimeout is shared by cloud API calls of profile validation.
+const CloudValidationTimeout = 30 * time.Second
+
+// ValidationRequest is a profile checked against the cloud of the account,
+// only checks that don't call cloud APIs are run without the account.
+type ValidationRequest struct {
+	Profile          profile.Profile `json:"profile"`
+	CloudAccountName string          `json:"cloudAccountName"`
+}
+
+type ValidationResponse struct {
+	Valid bool `json:"valid"`
+	*profile.Report
+}
+
+// machineTypeField is a field of the profile that refers to machine type.
+type machineTypeField struct {
+	field       string
+	machineType string
+	gpu         bool
+}
+
+func (h *Handler) ValidateProfile(w http.ResponseWriter, r *http.Request) {
+	req := &ValidationRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		message.SendInvalidJSON(w, err)
+		return
+	}
+
+	report, err := h.validateProfile(r.Context(), req)
+
+	if err != nil {
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(ValidationResponse{
+		Valid:  report.Valid(),
+		Report: report,
+	}); err != nil {
+		logrus.Errorf("encode validation report %v", err)
+	}
+}
+
+func (h *Handler) validateProfile(ctx context.Context, req *ValidationRequest) (*profile.Report, error) {
+	report := profile.Validate(&req.Profile)
+
+	if req.CloudAccountName == "" {
+		report.AddWarning("cloudAccountName",
+			"cloud resources are not checked without cloud account")
+		return report, nil
+	}
+
+	acc, err := h.accountGetter.Get(ctx, req.CloudAccountName)
+
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			report.AddError("cloudAccountName",
+				errors.Errorf("account %s not found", req.CloudAccountName))
+			return report, nil
+		}
+
+		return nil, errors.Wrapf(err, "get cloud account %s", req.CloudAccountName)
+	}
+
+	if acc.Provider != req.Profile.Provider {
+		report.AddError("provider", errors.Errorf("cloud account %s is of provider %s",
+			acc.Name, acc.Provider))
+		return report, nil
+	}
+
+	config, err := steps.NewConfig("", req.CloudAccountName, req.Profile)
+
+	if err != nil {
+		report.AddError("addons", err)
+		return report, nil
+	}
+
+	if err := util.FillCloudAccountCredentials(acc, config); err != nil {
+		return nil, errors.Wrap(err, "fill cloud account credentials")
+	}
+
+	h.validateCloud(ctx, config, &req.Profile, report)
+
+	return report, nil
+}
+
+// validateCloud checks that machine types and AWS images of the profile
+// exist in its region, cloud APIs are called in parallel and share
+// the deadline. Checks that could not be done are reported as warnings.
+func (h *Handler) validateCloud(ctx context.Context, config *steps.Config,
+	p *profile.Profile, report *profile.Report) {
+	ctx, cancel := context.WithTimeout(ctx, CloudValidationTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	if fields := machineTypeFields(p); len(fields) > 0 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			h.validateMachineTypes(ctx, config, p.Region, fields, report)
+		}()
+	}
+
+	if p.Provider == clouds.AWS {
+		for imageID, fields := range awsImageFields(p) {
+			wg.Add(1)
+
+			go func(imageID string, fields []string) {
+				defer wg.Done()
+				h.validateImage(ctx, config, imageID, fields, report)
+			}(imageID, fields)
+		}
+	}
+
+	wg.Wait()
+}
+
+func (h *Handler) validateMachineTypes(ctx context.Context, config *steps.Config,
+	region string, fields []machineTypeField, report *profile.Report) {
+	types, err := h.getMachineTypes(ctx, config, region)
+
+	if errors.Cause(err) == account.ErrUnsupportedProvider {
+		for _, f := range fields {
+			if f.gpu {
+				report.AddError(f.field, errors.Errorf("GPU nodes are not supported by provider %s",
+					config.Provider))
+			}
+		}
+
+		report.AddWarning("provider", fmt.Sprintf("machine types are not checked on %s", config.Provider))
+		return
+	}
+
+	if err != nil {
+		report.AddWarning("region", fmt.Sprintf("machine types are not checked: %v", err))
+		return
+	}
+
+	available := make(map[string]account.MachineType, len(types))
+
+	for _, machineType := range types {
+		available[machineType.Name] = machineType
+	}
+
+	for _, f := range fields {
+		machineType, ok := available[f.machineType]
+
+		switch {
+		case !ok:
+			report.AddError(f.field, errors.Errorf("machine type %s is not available in %s",
+				f.machineType, region))
+		case f.gpu && machineType.GPU == 0:
+			report.AddError(f.field, errors.Errorf("machine type %s has no GPUs", f.machineType))
+		}
+	}
+}
+
+func (h *Handler) validateImage(ctx context.Context, config *steps.Config,
+	imageID string, fields []string, report *profile.Report) {
+	finder, err := h.getImageFinder(config.AWSConfig)
+
+	if err != nil {
+		report.AddWarning(fields[0], fmt.Sprintf("image %s is not checked: %v", imageID, err))
+		return
+	}
+
+	out, err := finder.DescribeImagesWithContext(ctx, &ec2.DescribeImagesInput{
+		ImageIds: aws.StringSlice([]string{imageID}),
+	})
+
+	if aerr, ok := err.(awserr.Error); ok && isImageNotFound(aerr.Code()) {
+		err = nil
+		out = &ec2.DescribeImagesOutput{}
+	}
+
+	if err != nil {
+		report.AddWarning(fields[0], fmt.Sprintf("image %s is not checked: %v", imageID, err))
+		return
+	}
+
+	var imageErr error
+
+	switch {
+	case len(out.Images) == 0:
+		imageErr = errors.Errorf("image %s does not exist in %s or is not accessible",
+			imageID, config.AWSConfig.Region)
+	case aws.StringValue(out.Images[0].State) != ec2.ImageStateAvailable:
+		imageErr = errors.Errorf("image %s is %s", imageID, aws.StringValue(out.Images[0].State))
+	}
+
+	if imageErr != nil {
+		for _, field := range fields {
+			report.AddError(field, imageErr)
+		}
+	}
+}
+
+// machineTypeFields returns fields of machine types of masters, nodes,
+// node pools and etcd.
+func machineTypeFields(p *profile.Profile) []machineTypeField {
+	fields := make([]machineTypeField, 0)
+	key := profile.MachineTypeKey(p.Provider)
+
+	for name, nodeProfiles := range map[string][]profile.NodeProfile{
+		"masterProfiles": p.MasterProfiles,
+		"nodesProfiles":  p.NodesProfiles,
+	} {
+		for i, nodeProfile := range nodeProfiles {
+			if nodeProfile[key] == "" {
+				continue
+			}
+
+			fields = append(fields, machineTypeField{
+				field:       fmt.Sprintf("%s[%d].%s", name, i, key),
+				machineType: nodeProfile[key],
+			})
+		}
+	}
+
+	for i, pool := range p.NodePools {
+		fields = append(fields, machineTypeField{
+			field:       fmt.Sprintf("nodePools[%d].machineType", i),
+			machineType: pool.MachineType,
+			gpu:         pool.GPU,
+		})
+	}
+
+	if p.Etcd != nil {
+		fields = append(fields, machineTypeField{
+			field:       "etcd.machineType",
+			machineType: p.Etcd.MachineType,
+		})
+	}
+
+	return fields
+}
+
+// awsImageFields returns fields of the profile by AMI they refer to.
+func awsImageFields(p *profile.Profile) map[string][]string {
+	images := make(map[string][]string)
+
+	for name, nodeProfiles := range map[string][]profile.NodeProfile{
+		"masterProfiles": p.MasterProfiles,
+		"nodesProfiles":  p.NodesProfiles,
+	} {
+		for i, nodeProfile := range nodeProfiles {
+			if imageID := nodeProfile[profile.ImageKey]; imageID != "" {
+				images[imageID] = append(images[imageID],
+					fmt.Sprintf("%s[%d].%s", name, i, profile.ImageKey))
+			}
+		}
+	}
+
+	for i, pool := range p.NodePools {
+		if pool.Image != "" {
+			images[pool.Image] = append(images[pool.Image], fmt.Sprintf("nodePools[%d].image", i))
+		}
+	}
+
+	return images
+}
+
+// isImageNotFound is true for EC2 errors of images that don't exist
+// or are not shared with the account.
+func isImageNotFound(code string) bool {
+	switch code {
+	case "InvalidAMIID.NotFound", "InvalidAMIID.Malformed", "InvalidAMIID.Unavailable":
+		return true
+	}
+
+	return false
+}
+
+func getImageFinder(config steps.AWSConfig) (amazon.ImageFinder, error) {
+	return amazon.GetEC2(config)
+}
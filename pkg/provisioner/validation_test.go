@@ -0,0 +1,239 @@
+package provisioner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/supergiant/control/pkg/account"
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows/steps"
+	"github.com/supergiant/control/pkg/workflows/steps/amazon"
+)
+
+type fakeImageFinder struct {
+	images map[string]string
+}
+
+func (f *fakeImageFinder) DescribeImagesWithContext(ctx aws.Context, input *ec2.DescribeImagesInput,
+	opts ...request.Option) (*ec2.DescribeImagesOutput, error) {
+	imageID := aws.StringValue(input.ImageIds[0])
+	state, ok := f.images[imageID]
+
+	if !ok {
+		return nil, awserr.New("InvalidAMIID.NotFound", "not found", nil)
+	}
+
+	return &ec2.DescribeImagesOutput{
+		Images: []*ec2.Image{
+			{
+				ImageId: aws.String(imageID),
+				State:   aws.String(state),
+			},
+		},
+	}, nil
+}
+
+func validationHandler(provider clouds.Name, machineTypesErr error) *Handler {
+	return &Handler{
+		accountGetter: &mockAccountGetter{
+			get: func(_ context.Context, name string) (*model.CloudAccount, error) {
+				if name != "test" {
+					return nil, sgerrors.ErrNotFound
+				}
+
+				return &model.CloudAccount{Name: name, Provider: provider}, nil
+			},
+		},
+		getMachineTypes: func(context.Context, *steps.Config, string) ([]account.MachineType, error) {
+			return []account.MachineType{
+				{Name: "m5.large"},
+				{Name: "p3.2xlarge", GPU: 1},
+			}, machineTypesErr
+		},
+		getImageFinder: func(steps.AWSConfig) (amazon.ImageFinder, error) {
+			return &fakeImageFinder{
+				images: map[string]string{
+					"ami-available": ec2.ImageStateAvailable,
+					"ami-pending":   ec2.ImageStatePending,
+				},
+			}, nil
+		},
+	}
+}
+
+func TestValidateProfile(t *testing.T) {
+	testCases := []struct {
+		description      string
+		provider         clouds.Name
+		machineTypesErr  error
+		req              ValidationRequest
+		expectedErrors   []string
+		expectedWarnings []string
+	}{
+		{
+			description: "valid",
+			provider:    clouds.AWS,
+			req: ValidationRequest{
+				CloudAccountName: "test",
+				Profile: profile.Profile{
+					Provider: clouds.AWS,
+					Region:   "us-west-1",
+					MasterProfiles: []profile.NodeProfile{
+						{"size": "m5.large", "image": "ami-available"},
+					},
+					NodePools: []profile.NodePool{
+						{Name: "gpu", MachineType: "p3.2xlarge", Count: 1, GPU: true},
+					},
+				},
+			},
+			expectedWarnings: []string{"masterProfiles"},
+		},
+		{
+			description: "static checks without account",
+			req: ValidationRequest{
+				Profile: profile.Profile{
+					K8SVersion: "1.5.0",
+					CIDR:       "10.0.0.0",
+					PublicKey:  "ssh-rsa",
+					MasterProfiles: []profile.NodeProfile{
+						{}, {}, {},
+					},
+				},
+			},
+			expectedErrors:   []string{"K8SVersion", "cidr", "publicKey"},
+			expectedWarnings: []string{"cloudAccountName"},
+		},
+		{
+			description: "account not found",
+			req: ValidationRequest{
+				CloudAccountName: "unknown",
+			},
+			expectedErrors: []string{"cloudAccountName"},
+		},
+		{
+			description: "cloud resources",
+			provider:    clouds.AWS,
+			req: ValidationRequest{
+				CloudAccountName: "test",
+				Profile: profile.Profile{
+					Provider: clouds.AWS,
+					Region:   "us-west-1",
+					MasterProfiles: []profile.NodeProfile{
+						{"size": "m5.large", "image": "ami-pending", "volumeSize": "4"},
+					},
+					NodesProfiles: []profile.NodeProfile{
+						{"size": "m5.huge", "image": "ami-unknown"},
+					},
+					NodePools: []profile.NodePool{
+						{Name: "gpu", MachineType: "m5.large", Count: 1, GPU: true},
+					},
+				},
+			},
+			expectedErrors: []string{
+				"masterProfiles[0].volumeSize",
+				"masterProfiles[0].image",
+				"nodesProfiles[0].size",
+				"nodesProfiles[0].image",
+				"nodePools[0].machineType",
+			},
+			expectedWarnings: []string{"masterProfiles"},
+		},
+		{
+			description: "account of other provider",
+			provider:    clouds.GCE,
+			req: ValidationRequest{
+				CloudAccountName: "test",
+				Profile: profile.Profile{
+					Provider: clouds.AWS,
+				},
+			},
+			expectedErrors: []string{"provider"},
+		},
+		{
+			description:     "machine types are not available",
+			provider:        clouds.DigitalOcean,
+			machineTypesErr: sgerrors.ErrTimeoutExceeded,
+			req: ValidationRequest{
+				CloudAccountName: "test",
+				Profile: profile.Profile{
+					Provider: clouds.DigitalOcean,
+					NodesProfiles: []profile.NodeProfile{
+						{"size": "s-2vcpu-4gb"},
+					},
+				},
+			},
+			expectedWarnings: []string{"region"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		h := validationHandler(testCase.provider, testCase.machineTypesErr)
+		body, _ := json.Marshal(testCase.req)
+		req, _ := http.NewRequest(http.MethodPost, "/kubeprofiles/validate", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		h.ValidateProfile(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Wrong status code expected %d actual %d", http.StatusOK, rec.Code)
+			continue
+		}
+
+		resp := struct {
+			Valid    bool            `json:"valid"`
+			Errors   []profile.Issue `json:"errors"`
+			Warnings []profile.Issue `json:"warnings"`
+		}{}
+
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if resp.Valid != (len(testCase.expectedErrors) == 0) {
+			t.Errorf("Wrong valid %v", resp.Valid)
+		}
+
+		if !sameFields(resp.Errors, testCase.expectedErrors) {
+			t.Errorf("Wrong errors expected %v actual %v", testCase.expectedErrors, resp.Errors)
+		}
+
+		if !sameFields(resp.Warnings, testCase.expectedWarnings) {
+			t.Errorf("Wrong warnings expected %v actual %v", testCase.expectedWarnings, resp.Warnings)
+		}
+	}
+}
+
+func sameFields(issues []profile.Issue, fields []string) bool {
+	if len(issues) != len(fields) {
+		return false
+	}
+
+	expected := make(map[string]bool, len(fields))
+
+	for _, field := range fields {
+		expected[field] = true
+	}
+
+	for _, issue := range issues {
+		if !expected[issue.Field] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,209 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// DefaultMaxRequestSize limits bodies of requests proxied to services.
+	DefaultMaxRequestSize int64 = 10 << 20
+	// DefaultMaxResponseSize limits bodies of responses of services.
+	DefaultMaxResponseSize int64 = 100 << 20
+)
+
+// ErrResponseTooLarge is returned when response of the service exceeds the limit.
+var ErrResponseTooLarge = errors.New("response is too large")
+
+// ServiceTarget is a port of the service in the kube,
+// Path is requested from the service.
+type ServiceTarget struct {
+	KubeID     string
+	Namespace  string
+	Service    string
+	Port       string
+	Path       string
+	KubeConfig *rest.Config
+}
+
+// KubeServiceProxy tunnels requests to services of kubes through the
+// service proxy of kube API servers. Connections to the kube, upgraded
+// ones too, are closed on close of the kube.
+type KubeServiceProxy struct {
+	MaxRequestSize  int64
+	MaxResponseSize int64
+
+	logger logrus.FieldLogger
+
+	kubesMux sync.Mutex
+	// map[kubeID] channel closed on close of the kube
+	kubes map[string]chan struct{}
+}
+
+func NewKubeServiceProxy(maxRequestSize, maxResponseSize int64, logger logrus.FieldLogger) *KubeServiceProxy {
+	return &KubeServiceProxy{
+		MaxRequestSize:  maxRequestSize,
+		MaxResponseSize: maxResponseSize,
+		logger:          logger,
+		kubes:           make(map[string]chan struct{}),
+	}
+}
+
+// Serve proxies the request to the service target.
+func (p *KubeServiceProxy) Serve(w http.ResponseWriter, r *http.Request, t ServiceTarget) {
+	if t.KubeConfig == nil {
+		http.Error(w, "rest config should be provided", http.StatusInternalServerError)
+		return
+	}
+
+	if p.MaxRequestSize > 0 {
+		if r.ContentLength > p.MaxRequestSize {
+			http.Error(w, fmt.Sprintf("request is larger than %d bytes", p.MaxRequestSize),
+				http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, p.MaxRequestSize)
+	}
+
+	apiServer, err := url.Parse(t.KubeConfig.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tr, err := rest.TransportFor(t.KubeConfig)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	servicePath := fmt.Sprintf("/api/v1/namespaces/%s/services/%s:%s/proxy", t.Namespace, t.Service, t.Port)
+	// Path the service is served under by control
+	publicPath := strings.TrimSuffix(r.URL.Path, t.Path)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	closed := p.closed(t.KubeID)
+	go func() {
+		select {
+		case <-closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	reverseProxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = apiServer.Scheme
+			req.URL.Host = apiServer.Host
+			req.URL.Path = strings.TrimSuffix(apiServer.Path, "/") + servicePath + t.Path
+			req.URL.RawPath = ""
+			req.Host = apiServer.Host
+
+			// Token of control is not passed to services,
+			// API server authenticates control by credentials of the kube
+			query := req.URL.Query()
+			query.Del("token")
+			req.URL.RawQuery = query.Encode()
+			req.Header.Del("Authorization")
+		},
+		Transport: tr,
+		ModifyResponse: func(res *http.Response) error {
+			if location := res.Header.Get("Location"); location != "" {
+				res.Header.Set("Location", rewriteLocation(location, servicePath, publicPath))
+			}
+
+			if res.StatusCode == http.StatusSwitchingProtocols || p.MaxResponseSize <= 0 {
+				return nil
+			}
+
+			if res.ContentLength > p.MaxResponseSize {
+				return ErrResponseTooLarge
+			}
+			res.Body = &limitedBody{ReadCloser: res.Body, remaining: p.MaxResponseSize}
+
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			p.logger.Errorf("proxy %s to service %s/%s:%s of kube %s: %v",
+				t.Path, t.Namespace, t.Service, t.Port, t.KubeID, err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		},
+	}
+
+	reverseProxy.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// Close tears down connections to services of the kube.
+func (p *KubeServiceProxy) Close(kubeID string) {
+	p.kubesMux.Lock()
+	defer p.kubesMux.Unlock()
+
+	if closed, ok := p.kubes[kubeID]; ok {
+		close(closed)
+		delete(p.kubes, kubeID)
+	}
+}
+
+func (p *KubeServiceProxy) closed(kubeID string) <-chan struct{} {
+	p.kubesMux.Lock()
+	defer p.kubesMux.Unlock()
+
+	closed, ok := p.kubes[kubeID]
+	if !ok {
+		closed = make(chan struct{})
+		p.kubes[kubeID] = closed
+	}
+
+	return closed
+}
+
+// rewriteLocation points redirects of the service to control.
+func rewriteLocation(location, servicePath, publicPath string) string {
+	u, err := url.Parse(location)
+	if err != nil || !strings.HasPrefix(u.Path, servicePath) {
+		return location
+	}
+
+	u.Scheme, u.Host = "", ""
+	u.Path = publicPath + strings.TrimPrefix(u.Path, servicePath)
+
+	return u.String()
+}
+
+// limitedBody fails reads past the limit.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// Body of exactly the limit size is not an error
+		n, err := b.ReadCloser.Read(make([]byte, 1))
+		if n > 0 {
+			return 0, ErrResponseTooLarge
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+
+	return n, err
+}
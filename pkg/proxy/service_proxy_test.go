@@ -0,0 +1,236 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/rest"
+)
+
+const testServicePath = "/api/v1/namespaces/apps/services/web:http/proxy"
+
+func serviceTarget(apiServer *httptest.Server, path string) ServiceTarget {
+	return ServiceTarget{
+		KubeID:     "test",
+		Namespace:  "apps",
+		Service:    "web",
+		Port:       "http",
+		Path:       path,
+		KubeConfig: &rest.Config{Host: apiServer.URL},
+	}
+}
+
+func TestKubeServiceProxy_Serve(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case testServicePath + "/ui":
+			if r.Header.Get("Authorization") != "" || r.URL.Query().Get("token") != "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("dashboard " + r.URL.Query().Get("tab")))
+		case testServicePath + "/redirect":
+			http.Redirect(w, r, testServicePath+"/ui", http.StatusFound)
+		case testServicePath + "/upload":
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write(body)
+		case testServicePath + "/large":
+			w.Write(bytes.Repeat([]byte("a"), 256))
+		case testServicePath + "/streamed":
+			w.Write(bytes.Repeat([]byte("a"), 100))
+			w.(http.Flusher).Flush()
+			w.Write(bytes.Repeat([]byte("a"), 100))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer apiServer.Close()
+
+	testCases := []struct {
+		description string
+		method      string
+		path        string
+		body        string
+
+		expectedCode     int
+		expectedBody     string
+		expectedLocation string
+		expectedErr      bool
+	}{
+		{
+			description:  "get page",
+			method:       http.MethodGet,
+			path:         "/ui?token=secret&tab=nodes",
+			expectedCode: http.StatusOK,
+			expectedBody: "dashboard nodes",
+		},
+		{
+			description:      "redirect",
+			method:           http.MethodGet,
+			path:             "/redirect",
+			expectedCode:     http.StatusFound,
+			expectedLocation: "/v1/api/kubes/test/proxy/apps/web/http/ui",
+		},
+		{
+			description:  "post body",
+			method:       http.MethodPost,
+			path:         "/upload",
+			body:         "data",
+			expectedCode: http.StatusOK,
+			expectedBody: "data",
+		},
+		{
+			description:  "request too large",
+			method:       http.MethodPost,
+			path:         "/upload",
+			body:         strings.Repeat("a", 64),
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			description:  "response too large",
+			method:       http.MethodGet,
+			path:         "/large",
+			expectedCode: http.StatusBadGateway,
+		},
+		{
+			description: "streamed response too large",
+			method:      http.MethodGet,
+			path:        "/streamed",
+			expectedErr: true,
+		},
+	}
+
+	p := NewKubeServiceProxy(32, 128, logrus.New())
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		path := strings.SplitN(testCase.path, "?", 2)[0]
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p.Serve(w, r, serviceTarget(apiServer, path))
+		}))
+
+		req, err := http.NewRequest(testCase.method,
+			srv.URL+"/v1/api/kubes/test/proxy/apps/web/http"+testCase.path,
+			strings.NewReader(testCase.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer token")
+
+		client := &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+
+		resp, err := client.Do(req)
+		if err == nil {
+			var body []byte
+			body, err = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			if err == nil && !testCase.expectedErr {
+				if resp.StatusCode != testCase.expectedCode {
+					t.Errorf("Wrong status code expected %d actual %d",
+						testCase.expectedCode, resp.StatusCode)
+				}
+
+				if testCase.expectedBody != "" && string(body) != testCase.expectedBody {
+					t.Errorf("Wrong body expected %s actual %s", testCase.expectedBody, body)
+				}
+
+				if location := resp.Header.Get("Location"); location != testCase.expectedLocation {
+					t.Errorf("Wrong location expected %s actual %s",
+						testCase.expectedLocation, location)
+				}
+			}
+		}
+
+		if testCase.expectedErr != (err != nil) {
+			t.Errorf("Expected error %v actual %v", testCase.expectedErr, err)
+		}
+
+		srv.Close()
+	}
+}
+
+func TestKubeServiceProxy_Upgrade(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != testServicePath+"/ws" || r.Header.Get("Upgrade") != "websocket" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+
+		// echo lines until the connection is closed
+		for {
+			line, err := rw.ReadString('\n')
+			if err != nil {
+				return
+			}
+			rw.WriteString(line)
+			rw.Flush()
+		}
+	}))
+	defer apiServer.Close()
+
+	p := NewKubeServiceProxy(DefaultMaxRequestSize, DefaultMaxResponseSize, logrus.New())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.Serve(w, r, serviceTarget(apiServer, "/ws"))
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: control\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Wrong status code expected %d actual %d",
+			http.StatusSwitchingProtocols, resp.StatusCode)
+	}
+
+	conn.Write([]byte("ping\n"))
+	if line, err := r.ReadString('\n'); err != nil || line != "ping\n" {
+		t.Errorf("Upgraded connection must be proxied %s %v", line, err)
+	}
+
+	// Connection is torn down on close of the kube
+	p.Close("test")
+
+	if _, err := r.ReadString('\n'); err == nil {
+		t.Errorf("Connection must be closed along with the kube")
+	}
+}
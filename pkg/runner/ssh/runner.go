@@ -22,12 +22,16 @@ type Config struct {
 	User    string `json:"user"`
 	Timeout int    `json:"timeout"`
 	Key     []byte `json:"key"`
+	// BastionHost is connected first when it is set, host is reached
+	// through it with the same user and key.
+	BastionHost string `json:"bastionHost,omitempty"`
 }
 
 // Runner is implementation of runner interface for ssh
 type Runner struct {
 	host    string
 	port    string
+	bastion string
 	sshConf *ssh.ClientConfig
 }
 
@@ -43,7 +47,12 @@ func NewRunner(config Config) (runner.Runner, error) {
 		return nil, err
 	}
 
-	r := &Runner{host: config.Host, port: config.Port, sshConf: sshConfig}
+	r := &Runner{
+		host:    config.Host,
+		port:    config.Port,
+		bastion: config.BastionHost,
+		sshConf: sshConfig,
+	}
 	if r.port == "" {
 		r.port = DefaultPort
 	}
@@ -62,8 +71,8 @@ func (r *Runner) Run(cmd *runner.Command) (err error) {
 		return nil
 	}
 
-	c, err := connectionWithBackOff(cmd.Ctx, r.host, r.port, r.sshConf,
-		time.Second*10, 5)
+	c, err := connectionWithBackOff(cmd.Ctx, r.host, r.port, r.bastion,
+		r.sshConf, time.Second*10, 5)
 
 	if err != nil {
 		return errors.Wrap(err, "ssh: establishing connection")
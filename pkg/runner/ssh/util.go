@@ -47,7 +47,7 @@ func getSshConfig(config Config) (*ssh.ClientConfig, error) {
 	}, nil
 }
 
-func connectionWithBackOff(ctx context.Context, host, port string, config *ssh.ClientConfig, timeout time.Duration, attemptCount int) (*ssh.Client, error) {
+func connectionWithBackOff(ctx context.Context, host, port, bastion string, config *ssh.ClientConfig, timeout time.Duration, attemptCount int) (*ssh.Client, error) {
 	var (
 		counter = 0
 		c       *ssh.Client
@@ -59,7 +59,7 @@ func connectionWithBackOff(ctx context.Context, host, port string, config *ssh.C
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		default:
-			c, err = ssh.Dial("tcp", fmt.Sprintf("%s:%s", host, port), config)
+			c, err = dial(host, port, bastion, config)
 
 			if err != nil {
 				logrus.Debugf("connect to %s failed, try again in %v seconds, reason: %v",
@@ -76,3 +76,44 @@ func connectionWithBackOff(ctx context.Context, host, port string, config *ssh.C
 
 	return nil, err
 }
+
+// dial connects to the host directly or through the bastion like
+// ssh ProxyJump does, bastion is listening on the same port.
+func dial(host, port, bastion string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	addr := net.JoinHostPort(host, port)
+
+	if bastion == "" {
+		return ssh.Dial("tcp", addr, config)
+	}
+
+	bastionClient, err := ssh.Dial("tcp", net.JoinHostPort(bastion, port), config)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "connect to bastion %s", bastion)
+	}
+
+	conn, err := bastionClient.Dial("tcp", addr)
+
+	if err != nil {
+		bastionClient.Close()
+		return nil, errors.Wrapf(err, "connect to %s through bastion %s", addr, bastion)
+	}
+
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+
+	if err != nil {
+		conn.Close()
+		bastionClient.Close()
+		return nil, errors.Wrapf(err, "connect to %s through bastion %s", addr, bastion)
+	}
+
+	c := ssh.NewClient(clientConn, chans, reqs)
+
+	// Connection to bastion is not needed when the host is disconnected
+	go func() {
+		c.Wait()
+		bastionClient.Close()
+	}()
+
+	return c, nil
+}
@@ -1,8 +1,12 @@
 package ssh
 
 import (
+	"net"
+	"strings"
 	"testing"
 	"time"
+
+	"golang.org/x/crypto/ssh"
 )
 
 func TestGetSshConfig(t *testing.T) {
@@ -85,3 +89,39 @@ dZM6MSCYh9kcT0pi2FPmY9iXba9kx4XAnf+0YB5xCz9QSMk4W5xSTBs=
 		}
 	}
 }
+
+func TestDialBastion(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer l.Close()
+
+	// Bastion is connected first, target host is not reachable directly
+	accepted := make(chan struct{}, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			accepted <- struct{}{}
+			conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	_, err = dial("10.255.255.1", port, "127.0.0.1", &ssh.ClientConfig{
+		User:            "root",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         time.Second,
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "bastion 127.0.0.1") {
+		t.Errorf("Wrong error %v", err)
+	}
+
+	select {
+	case <-accepted:
+	case <-time.After(time.Second):
+		t.Errorf("Bastion has not been connected")
+	}
+}
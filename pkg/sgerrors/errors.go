@@ -21,17 +21,23 @@ func New(msg string, code ErrorCode) error {
 }
 
 var (
-	ErrInvalidCredentials  = New("invalid credentials", InvalidCredentials)
-	ErrNotFound            = New("entity not found", NotFound)
-	ErrAlreadyExists       = New("entity already exists", EntityAlreadyExists)
-	ErrUnknownProvider     = New("unknown provider type", UnknownProvider)
-	ErrUnsupportedProvider = New("unsupported provider", UnsupportedProvider)
-	ErrInvalidJson         = New("invalid json", InvalidJSON)
-	ErrNilValue            = New("nil value", NilValue)
-	ErrTokenExpired        = New("token has been expire", TokenExpired)
-	ErrNilEntity           = New("nil entity", NilEntity)
-	ErrTimeoutExceeded     = New("timeout exceeded", TimeoutExceeded)
-	ErrRawError            = New("error", RawError)
+	ErrInvalidCredentials       = New("invalid credentials", InvalidCredentials)
+	ErrNotFound                 = New("entity not found", NotFound)
+	ErrAlreadyExists            = New("entity already exists", EntityAlreadyExists)
+	ErrUnknownProvider          = New("unknown provider type", UnknownProvider)
+	ErrUnsupportedProvider      = New("unsupported provider", UnsupportedProvider)
+	ErrInvalidJson              = New("invalid json", InvalidJSON)
+	ErrNilValue                 = New("nil value", NilValue)
+	ErrTokenExpired             = New("token has been expire", TokenExpired)
+	ErrNilEntity                = New("nil entity", NilEntity)
+	ErrTimeoutExceeded          = New("timeout exceeded", TimeoutExceeded)
+	ErrRawError                 = New("error", RawError)
+	ErrValidationFailed         = New("validation failed", ValidationFailed)
+	ErrPrometheusNotFound       = New("prometheus is not deployed", PrometheusNotFound)
+	ErrKubeStateMetricsNotFound = New("kube-state-metrics is not deployed", KubeStateMetricsNotFound)
+	ErrUnauthorized             = New("unauthorized", Unauthorized)
+	ErrConflict                 = New("entity has been modified", Conflict)
+	ErrNotSupported             = New("not supported", NotSupported)
 )
 
 func IsNotFound(err error) bool {
@@ -57,3 +63,27 @@ func IsUnknownProvider(err error) bool {
 func IsUnsupportedProvider(err error) bool {
 	return errors.Cause(err) == ErrUnsupportedProvider
 }
+
+func IsValidationFailed(err error) bool {
+	return errors.Cause(err) == ErrValidationFailed
+}
+
+func IsPrometheusNotFound(err error) bool {
+	return errors.Cause(err) == ErrPrometheusNotFound
+}
+
+func IsKubeStateMetricsNotFound(err error) bool {
+	return errors.Cause(err) == ErrKubeStateMetricsNotFound
+}
+
+func IsUnauthorized(err error) bool {
+	return errors.Cause(err) == ErrUnauthorized
+}
+
+func IsConflict(err error) bool {
+	return errors.Cause(err) == ErrConflict
+}
+
+func IsNotSupported(err error) bool {
+	return errors.Cause(err) == ErrNotSupported
+}
@@ -122,6 +122,30 @@ func TestIsUnsupportedProvider(t *testing.T) {
 	}
 }
 
+func TestIsNotSupported(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected bool
+	}{
+		{
+			ErrUnsupportedProvider,
+			false,
+		},
+		{
+			ErrNotSupported,
+			true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual := IsNotSupported(testCase.err)
+
+		if testCase.expected != actual {
+			t.Errorf("Wrong result expected %v actual %v", testCase.expected, actual)
+		}
+	}
+}
+
 func TestError_Error(t *testing.T) {
 	var (
 		code    ErrorCode = 1
@@ -0,0 +1,138 @@
+package sghelm
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/storage"
+)
+
+const (
+	keyPrefix = "/helm/keys/"
+	keyName   = "repositories"
+)
+
+// credentials are secrets of a repository, they are kept encrypted.
+type credentials struct {
+	Password string `json:"password,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
+
+// storedRepo is a repository as it is kept in the storage.
+type storedRepo struct {
+	model.RepositoryInfo
+	// Credentials sealed with the key of the service
+	Credentials string `json:"credentials,omitempty"`
+}
+
+// loadKey derives the key credentials are encrypted with from the secret,
+// a random one is generated and kept in the storage when it is empty.
+func loadKey(ctx context.Context, s storage.Interface, secret string) ([]byte, error) {
+	if secret == "" {
+		data, err := s.Get(ctx, keyPrefix, keyName)
+		if err != nil && !sgerrors.IsNotFound(err) {
+			return nil, errors.Wrap(err, "get key")
+		}
+
+		if data == nil {
+			data = make([]byte, 32)
+			if _, err = io.ReadFull(rand.Reader, data); err != nil {
+				return nil, errors.Wrap(err, "generate key")
+			}
+			data = []byte(hex.EncodeToString(data))
+
+			if err = s.Put(ctx, keyPrefix, keyName, data); err != nil {
+				return nil, errors.Wrap(err, "store key")
+			}
+			logrus.Warn("helm: credentials of repositories are encrypted with a generated key")
+		}
+
+		secret = string(data)
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	return key[:], nil
+}
+
+func seal(key []byte, creds credentials) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return "", errors.Wrap(err, "marshal credentials")
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", errors.Wrap(err, "generate nonce")
+	}
+
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, data, nil)), nil
+}
+
+func unseal(key []byte, sealed string) (credentials, error) {
+	creds := credentials{}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return creds, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return creds, errors.Wrap(err, "decode credentials")
+	}
+	if len(data) < gcm.NonceSize() {
+		return creds, errors.New("credentials are malformed")
+	}
+
+	nonce, data := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	data, err = gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return creds, errors.Wrap(err, "decrypt credentials")
+	}
+
+	if err = json.Unmarshal(data, &creds); err != nil {
+		return creds, errors.Wrap(err, "unmarshal credentials")
+	}
+	return creds, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) == 0 {
+		return nil, errors.New("no key to encrypt credentials with")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "build cipher")
+	}
+	return cipher.NewGCM(block)
+}
+
+// redact returns a copy of the repository without its secrets.
+func redact(r *model.RepositoryInfo) *model.RepositoryInfo {
+	if r == nil {
+		return nil
+	}
+
+	out := *r
+	out.Config.Password = ""
+	out.Config.Token = ""
+	return &out
+}
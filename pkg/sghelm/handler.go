@@ -2,6 +2,7 @@ package sghelm
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
@@ -9,9 +10,9 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
-	"k8s.io/helm/pkg/repo"
 
 	"github.com/supergiant/control/pkg/message"
+	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/sgerrors"
 )
 
@@ -34,13 +35,15 @@ func (h *Handler) Register(r *mux.Router) {
 	r.HandleFunc("/helm/repositories/{repoName}", h.updateRepo).Methods(http.MethodPatch)
 	r.HandleFunc("/helm/repositories", h.listRepos).Methods(http.MethodGet)
 	r.HandleFunc("/helm/repositories/{repoName}", h.deleteRepo).Methods(http.MethodDelete)
+	r.HandleFunc("/helm/repositories/{repoName}/refresh", h.refreshRepo).Methods(http.MethodPost)
 
 	r.HandleFunc("/helm/repositories/{repoName}/charts", h.listCharts).Methods(http.MethodGet)
 	r.HandleFunc("/helm/repositories/{repoName}/charts/{chartName}", h.getChartData).Methods(http.MethodGet)
+	r.HandleFunc("/charts/{repoName}/{chartName}/versions", h.listChartVersions).Methods(http.MethodGet)
 }
 
 func (h *Handler) createRepo(w http.ResponseWriter, r *http.Request) {
-	repoConf := &repo.Entry{}
+	repoConf := &model.RepositoryConfig{}
 	if err := json.NewDecoder(r.Body).Decode(repoConf); err != nil {
 		log.Errorf("helm: create repository: decode: %s", err)
 		message.SendValidationFailed(w, err)
@@ -60,6 +63,10 @@ func (h *Handler) createRepo(w http.ResponseWriter, r *http.Request) {
 			message.SendAlreadyExists(w, repoConf.Name, err)
 			return
 		}
+		if sgerrors.IsUnauthorized(err) {
+			sendUnauthorized(w, repoConf.Name, err)
+			return
+		}
 		log.Errorf("helm: create repository: %s: %s", repoConf.Name, err)
 		message.SendUnknownError(w, err)
 		return
@@ -75,7 +82,7 @@ func (h *Handler) createRepo(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) updateRepo(w http.ResponseWriter, r *http.Request) {
 	name := mux.Vars(r)["repoName"]
 
-	opts := &repo.Entry{}
+	opts := &model.RepositoryConfig{}
 	err := json.NewDecoder(r.Body).Decode(opts)
 	if err != nil {
 		// ignore io.EOF error (request.Body is empty), update only the repo index
@@ -88,6 +95,10 @@ func (h *Handler) updateRepo(w http.ResponseWriter, r *http.Request) {
 
 	hrepo, err := h.svc.UpdateRepo(r.Context(), name, opts)
 	if err != nil {
+		if sgerrors.IsUnauthorized(err) {
+			sendUnauthorized(w, name, err)
+			return
+		}
 		log.Errorf("helm: update repository: %s: %s", opts.Name, err)
 		message.SendUnknownError(w, err)
 		return
@@ -157,6 +168,31 @@ func (h *Handler) deleteRepo(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *Handler) refreshRepo(w http.ResponseWriter, r *http.Request) {
+	repoName := mux.Vars(r)["repoName"]
+
+	hrepo, err := h.svc.UpdateRepo(r.Context(), repoName, nil)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, repoName, err)
+			return
+		}
+		if sgerrors.IsUnauthorized(err) {
+			sendUnauthorized(w, repoName, err)
+			return
+		}
+		log.Errorf("helm: refresh repository: %s: %s", repoName, err)
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(hrepo); err != nil {
+		log.Errorf("helm: refresh repository: %s: encode: %s", repoName, err)
+		message.SendUnknownError(w, err)
+		return
+	}
+}
+
 func (h *Handler) getChartData(w http.ResponseWriter, r *http.Request) {
 	repoName := mux.Vars(r)["repoName"]
 	chartName := mux.Vars(r)["chartName"]
@@ -168,6 +204,10 @@ func (h *Handler) getChartData(w http.ResponseWriter, r *http.Request) {
 			message.SendNotFound(w, repoName+"/"+chartName, err)
 			return
 		}
+		if sgerrors.IsUnauthorized(err) {
+			sendUnauthorized(w, repoName, err)
+			return
+		}
 		log.Errorf("helm: get %s/%s chart: %s", repoName, chartName, err)
 		message.SendUnknownError(w, err)
 		return
@@ -196,3 +236,32 @@ func (h *Handler) listCharts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func (h *Handler) listChartVersions(w http.ResponseWriter, r *http.Request) {
+	repoName := mux.Vars(r)["repoName"]
+	chartName := mux.Vars(r)["chartName"]
+
+	versions, err := h.svc.ListChartVersions(r.Context(), repoName, chartName)
+	if err != nil {
+		if sgerrors.IsNotFound(err) {
+			message.SendNotFound(w, repoName+"/"+chartName, err)
+			return
+		}
+		log.Errorf("helm: list %s/%s chart versions: %s", repoName, chartName, err)
+		message.SendUnknownError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(versions); err != nil {
+		log.Errorf("helm: list chart versions: %s/%s: encode: %s", repoName, chartName, err)
+		message.SendUnknownError(w, err)
+		return
+	}
+}
+
+// sendUnauthorized responds to requests which failed because the repository
+// rejected its credentials, 401 is not used since it ends sessions of users.
+func sendUnauthorized(w http.ResponseWriter, repoName string, err error) {
+	message.SendMessage(w, message.New(fmt.Sprintf("%s repository rejected credentials", repoName),
+		err.Error(), sgerrors.Unauthorized, ""), http.StatusBadRequest)
+}
@@ -14,7 +14,6 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	"k8s.io/helm/pkg/proto/hapi/chart"
-	"k8s.io/helm/pkg/repo"
 
 	"github.com/supergiant/control/pkg/message"
 	"github.com/supergiant/control/pkg/model"
@@ -33,13 +32,14 @@ type fakeService struct {
 	chrt     *chart.Chart
 	chrtData *model.ChartData
 	chrtList []model.ChartInfo
+	versions []model.ChartVersion
 	err      error
 }
 
-func (fs fakeService) CreateRepo(ctx context.Context, e *repo.Entry) (*model.RepositoryInfo, error) {
+func (fs fakeService) CreateRepo(ctx context.Context, e *model.RepositoryConfig) (*model.RepositoryInfo, error) {
 	return fs.repo, fs.err
 }
-func (fs fakeService) UpdateRepo(ctx context.Context, name string, opts *repo.Entry) (*model.RepositoryInfo, error) {
+func (fs fakeService) UpdateRepo(ctx context.Context, name string, opts *model.RepositoryConfig) (*model.RepositoryInfo, error) {
 	return fs.repo, fs.err
 }
 func (fs fakeService) GetRepo(ctx context.Context, repoName string) (*model.RepositoryInfo, error) {
@@ -60,6 +60,12 @@ func (fs fakeService) ListCharts(ctx context.Context, repoName string) ([]model.
 func (fs fakeService) GetChart(ctx context.Context, repoName, chartName, chartVersion string) (*chart.Chart, error) {
 	return fs.chrt, fs.err
 }
+func (fs fakeService) ListChartVersions(ctx context.Context, repoName, chartName string) ([]model.ChartVersion, error) {
+	return fs.versions, fs.err
+}
+func (fs fakeService) AnnotateReleases(ctx context.Context, releases []*model.ReleaseInfo) error {
+	return fs.err
+}
 
 func TestHandler_createRepo(t *testing.T) {
 	loggerWriter := logrus.StandardLogger().Out
@@ -104,17 +110,25 @@ func TestHandler_createRepo(t *testing.T) {
 			expectedErrCode: sgerrors.UnknownError,
 		},
 		{ // TC#5
+			inpRepo: []byte(`{"name":"unauthorized","url":"url","password":"wrong"}`),
+			svc: &fakeService{
+				err: errors.Wrap(sgerrors.ErrUnauthorized, "get index"),
+			},
+			expectedStatus:  http.StatusBadRequest,
+			expectedErrCode: sgerrors.Unauthorized,
+		},
+		{ // TC#6
 			inpRepo: []byte(`{"name":"sgRepo","url":"url"}`),
 			svc: &fakeService{
 				repo: &model.RepositoryInfo{
-					Config: repo.Entry{
+					Config: model.RepositoryConfig{
 						Name: "sgRepo",
 					},
 				},
 			},
 			expectedStatus: http.StatusOK,
 			expectedRepo: &model.RepositoryInfo{
-				Config: repo.Entry{
+				Config: model.RepositoryConfig{
 					Name: "sgRepo",
 				},
 			},
@@ -157,7 +171,7 @@ func TestHandler_updateRepo(t *testing.T) {
 	defer logrus.SetOutput(loggerWriter)
 
 	trepo := &model.RepositoryInfo{
-		Config: repo.Entry{
+		Config: model.RepositoryConfig{
 			Name: "sgRepo",
 			URL:  "sgRepo",
 		},
@@ -271,14 +285,14 @@ func TestHandler_getRepo(t *testing.T) {
 			repoName: "sgRepo",
 			svc: &fakeService{
 				repo: &model.RepositoryInfo{
-					Config: repo.Entry{
+					Config: model.RepositoryConfig{
 						Name: "sgRepo",
 					},
 				},
 			},
 			expectedStatus: http.StatusOK,
 			expectedRepo: &model.RepositoryInfo{
-				Config: repo.Entry{
+				Config: model.RepositoryConfig{
 					Name: "sgRepo",
 				},
 			},
@@ -341,7 +355,7 @@ func TestHandler_listRepos(t *testing.T) {
 			svc: &fakeService{
 				repoList: []model.RepositoryInfo{
 					{
-						Config: repo.Entry{
+						Config: model.RepositoryConfig{
 							Name: "sgRepo",
 						},
 					},
@@ -350,7 +364,7 @@ func TestHandler_listRepos(t *testing.T) {
 			expectedStatus: http.StatusOK,
 			expectedRepos: []model.RepositoryInfo{
 				{
-					Config: repo.Entry{
+					Config: model.RepositoryConfig{
 						Name: "sgRepo",
 					},
 				},
@@ -422,14 +436,14 @@ func TestHandler_deleteRepo(t *testing.T) {
 			repoName: "sgRepo",
 			svc: &fakeService{
 				repo: &model.RepositoryInfo{
-					Config: repo.Entry{
+					Config: model.RepositoryConfig{
 						Name: "sgRepo",
 					},
 				},
 			},
 			expectedStatus: http.StatusOK,
 			expectedRepo: &model.RepositoryInfo{
-				Config: repo.Entry{
+				Config: model.RepositoryConfig{
 					Name: "sgRepo",
 				},
 			},
@@ -623,3 +637,137 @@ func TestHandler_listCharts(t *testing.T) {
 		}
 	}
 }
+
+func TestHandler_refreshRepo(t *testing.T) {
+	loggerWriter := logrus.StandardLogger().Out
+	logrus.SetOutput(ioutil.Discard)
+	defer logrus.SetOutput(loggerWriter)
+
+	tcs := []struct {
+		svc *fakeService
+
+		expectedStatus  int
+		expectedErrCode sgerrors.ErrorCode
+	}{
+		{ // TC#1
+			svc: &fakeService{
+				err: errors.Wrap(sgerrors.ErrNotFound, "get repo"),
+			},
+			expectedStatus:  http.StatusNotFound,
+			expectedErrCode: sgerrors.NotFound,
+		},
+		{ // TC#2
+			svc: &fakeService{
+				err: errors.Wrap(sgerrors.ErrUnauthorized, "get index"),
+			},
+			expectedStatus:  http.StatusBadRequest,
+			expectedErrCode: sgerrors.Unauthorized,
+		},
+		{ // TC#3
+			svc: &fakeService{
+				err: errFake,
+			},
+			expectedStatus:  http.StatusInternalServerError,
+			expectedErrCode: sgerrors.UnknownError,
+		},
+		{ // TC#4
+			svc: &fakeService{
+				repo: &model.RepositoryInfo{
+					Config: model.RepositoryConfig{
+						Name: "sgRepo",
+					},
+				},
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for i, tc := range tcs {
+		h := &Handler{svc: tc.svc}
+		router := mux.NewRouter()
+		h.Register(router)
+
+		req, err := http.NewRequest(http.MethodPost, "/helm/repositories/sgRepo/refresh", nil)
+		require.Equalf(t, nil, err, "TC#%d: create request: %v", i+1, err)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equalf(t, tc.expectedStatus, w.Code, "TC#%d: check status code", i+1)
+
+		if w.Code == http.StatusOK {
+			hrepo := &model.RepositoryInfo{}
+			require.Nilf(t, json.NewDecoder(w.Body).Decode(hrepo), "TC#%d: decode repo", i+1)
+
+			require.Equalf(t, tc.svc.repo, hrepo, "TC#%d: check repo", i+1)
+		} else {
+			apiErr := &message.Message{}
+			require.Nilf(t, json.NewDecoder(w.Body).Decode(apiErr), "TC#%d: decode message", i+1)
+
+			require.Equalf(t, tc.expectedErrCode, apiErr.ErrorCode, "TC#%d: check error code", i+1)
+		}
+	}
+}
+
+func TestHandler_listChartVersions(t *testing.T) {
+	loggerWriter := logrus.StandardLogger().Out
+	logrus.SetOutput(ioutil.Discard)
+	defer logrus.SetOutput(loggerWriter)
+
+	tcs := []struct {
+		svc *fakeService
+
+		expectedStatus  int
+		expectedErrCode sgerrors.ErrorCode
+	}{
+		{ // TC#1
+			svc: &fakeService{
+				err: errors.Wrap(sgerrors.ErrNotFound, "chart"),
+			},
+			expectedStatus:  http.StatusNotFound,
+			expectedErrCode: sgerrors.NotFound,
+		},
+		{ // TC#2
+			svc: &fakeService{
+				err: errFake,
+			},
+			expectedStatus:  http.StatusInternalServerError,
+			expectedErrCode: sgerrors.UnknownError,
+		},
+		{ // TC#3
+			svc: &fakeService{
+				versions: []model.ChartVersion{
+					{Version: "1.1.0", AppVersion: "1.17"},
+					{Version: "1.0.0", AppVersion: "1.16"},
+				},
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for i, tc := range tcs {
+		h := &Handler{svc: tc.svc}
+		router := mux.NewRouter()
+		h.Register(router)
+
+		req, err := http.NewRequest(http.MethodGet, "/charts/stable/nginx/versions", nil)
+		require.Equalf(t, nil, err, "TC#%d: create request: %v", i+1, err)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equalf(t, tc.expectedStatus, w.Code, "TC#%d: check status code", i+1)
+
+		if w.Code == http.StatusOK {
+			versions := []model.ChartVersion{}
+			require.Nilf(t, json.NewDecoder(w.Body).Decode(&versions), "TC#%d: decode versions", i+1)
+
+			require.Equalf(t, tc.svc.versions, versions, "TC#%d: check versions", i+1)
+		} else {
+			apiErr := &message.Message{}
+			require.Nilf(t, json.NewDecoder(w.Body).Decode(apiErr), "TC#%d: decode message", i+1)
+
+			require.Equalf(t, tc.expectedErrCode, apiErr.ErrorCode, "TC#%d: check error code", i+1)
+		}
+	}
+}
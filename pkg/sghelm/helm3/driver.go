@@ -0,0 +1,646 @@
+// Package helm3 manages helm 3 releases of kubes without tiller. It is not
+// the helm 3 client: releases are rendered with helm 2 libraries and stored
+// in secrets in a hand-rolled implementation of the format helm 3 uses.
+// Hooks are not run, charts with hooks are not supported.
+package helm3
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/helm"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+	rls "k8s.io/helm/pkg/proto/hapi/services"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/sghelm/proxy"
+)
+
+const defaultNamespace = "default"
+
+var (
+	ErrNotSupported = errors.New("not supported by helm 3")
+
+	// errCaptured stops helm client before a request is sent to tiller
+	errCaptured = errors.New("request captured")
+
+	_ proxy.Interface = &Driver{}
+)
+
+// Driver implements tiller proxy interface for helm 3 releases.
+type Driver struct {
+	secrets     corev1.SecretsGetter
+	discovery   discovery.ServerResourcesInterface
+	dynamic     dynamic.Interface
+	kubeVersion string
+}
+
+// New creates a driver for kube of the clients, charts are rendered
+// for the kubernetes version.
+func New(clientSet kubernetes.Interface, dynamicClient dynamic.Interface, kubeVersion string) *Driver {
+	return &Driver{
+		secrets:     clientSet.CoreV1(),
+		discovery:   clientSet.Discovery(),
+		dynamic:     dynamicClient,
+		kubeVersion: kubeVersion,
+	}
+}
+
+// capture returns request helm client sends to tiller for the call,
+// so options of calls are handled the same way for both drivers.
+func capture(call func(c *helm.Client) error) (proto.Message, error) {
+	var req proto.Message
+
+	c := helm.NewClient(helm.BeforeCall(func(_ context.Context, msg proto.Message) error {
+		req = msg
+		return errCaptured
+	}))
+
+	if err := call(c); err != errCaptured {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func (d *Driver) ListReleases(opts ...helm.ReleaseListOption) (*rls.ListReleasesResponse, error) {
+	msg, err := capture(func(c *helm.Client) error {
+		_, err := c.ListReleases(opts...)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	req := msg.(*rls.ListReleasesRequest)
+	statuses := req.StatusCodes
+
+	// Tiller lists deployed releases by default
+	if len(statuses) == 0 {
+		statuses = []release.Status_Code{release.Status_DEPLOYED}
+	}
+
+	var filter *regexp.Regexp
+
+	if req.Filter != "" {
+		if filter, err = regexp.Compile(req.Filter); err != nil {
+			return nil, errors.Wrapf(sgerrors.ErrValidationFailed, "filter %v", err)
+		}
+	}
+
+	revisions, err := d.list(req.Namespace, "")
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Revisions are sorted, the last one of a release is its latest
+	latest := make([]*helmRelease, 0, len(revisions))
+
+	for i, r := range revisions {
+		if i+1 < len(revisions) && revisions[i+1].Name == r.Name &&
+			revisions[i+1].Namespace == r.Namespace {
+			continue
+		}
+
+		if filter != nil && !filter.MatchString(r.Name) {
+			continue
+		}
+
+		if hasStatus(r, statuses) {
+			latest = append(latest, r)
+		}
+	}
+
+	sort.SliceStable(latest, func(i, j int) bool {
+		if req.SortBy == rls.ListSort_LAST_RELEASED {
+			return latest[i].Info.LastDeployed.Before(latest[j].Info.LastDeployed.Time)
+		}
+
+		return latest[i].Name < latest[j].Name
+	})
+
+	if req.SortOrder == rls.ListSort_DESC {
+		for i, j := 0, len(latest)-1; i < j; i, j = i+1, j-1 {
+			latest[i], latest[j] = latest[j], latest[i]
+		}
+	}
+
+	resp := &rls.ListReleasesResponse{
+		Total: int64(len(latest)),
+	}
+
+	if req.Offset != "" {
+		for i, r := range latest {
+			if r.Name == req.Offset {
+				latest = latest[i:]
+				break
+			}
+		}
+	}
+
+	if req.Limit > 0 && int64(len(latest)) > req.Limit {
+		resp.Next = latest[req.Limit].Name
+		latest = latest[:req.Limit]
+	}
+
+	for _, r := range latest {
+		resp.Releases = append(resp.Releases, r.toRelease())
+	}
+
+	resp.Count = int64(len(resp.Releases))
+
+	return resp, nil
+}
+
+func (d *Driver) InstallRelease(chStr, namespace string,
+	opts ...helm.InstallOption) (*rls.InstallReleaseResponse, error) {
+	chrt, err := chartutil.Load(chStr)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "load chart %s", chStr)
+	}
+
+	return d.InstallReleaseFromChart(chrt, namespace, opts...)
+}
+
+func (d *Driver) InstallReleaseFromChart(chrt *chart.Chart, namespace string,
+	opts ...helm.InstallOption) (*rls.InstallReleaseResponse, error) {
+	msg, err := capture(func(c *helm.Client) error {
+		_, err := c.InstallReleaseFromChart(chrt, namespace, opts...)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	req := msg.(*rls.InstallReleaseRequest)
+
+	if req.Name == "" {
+		return nil, errors.Wrap(sgerrors.ErrValidationFailed, "release name is required")
+	}
+
+	if req.Namespace == "" {
+		req.Namespace = defaultNamespace
+	}
+
+	revisions, err := d.list(req.Namespace, req.Name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	version := 1
+
+	if len(revisions) > 0 {
+		last := revisions[len(revisions)-1]
+
+		if !req.ReuseName || last.Info.Status != statusUninstalled {
+			return nil, errors.Wrapf(sgerrors.ErrAlreadyExists, "release %s", req.Name)
+		}
+
+		version = last.Version + 1
+	}
+
+	r, err := d.render(req.Chart, req.Values, req.Name, req.Namespace, version, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := helmTime{time.Now()}
+	r.Info.FirstDeployed = now
+	r.Info.LastDeployed = now
+
+	if req.DryRun {
+		r.Info.Status = statusPendingInstall
+		r.Info.Description = "Dry run complete"
+		return &rls.InstallReleaseResponse{Release: r.toRelease()}, nil
+	}
+
+	r.Info.Status = statusPendingInstall
+	r.Info.Description = "Initial install underway"
+
+	if err := d.create(r); err != nil {
+		return nil, err
+	}
+
+	if _, err := d.apply(r); err != nil {
+		d.fail(r, err)
+		return &rls.InstallReleaseResponse{Release: r.toRelease()},
+			errors.Wrapf(err, "install release %s", r.Name)
+	}
+
+	r.Info.Status = statusDeployed
+	r.Info.Description = "Install complete"
+
+	if err := d.update(r); err != nil {
+		return nil, err
+	}
+
+	return &rls.InstallReleaseResponse{Release: r.toRelease()}, nil
+}
+
+func (d *Driver) DeleteRelease(rlsName string,
+	opts ...helm.DeleteOption) (*rls.UninstallReleaseResponse, error) {
+	msg, err := capture(func(c *helm.Client) error {
+		_, err := c.DeleteRelease(rlsName, opts...)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Dry run only gets content of the release
+	if _, ok := msg.(*rls.GetReleaseContentRequest); ok {
+		r, err := d.revision(rlsName, 0)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &rls.UninstallReleaseResponse{Release: r.toRelease()}, nil
+	}
+
+	req := msg.(*rls.UninstallReleaseRequest)
+	revisions, err := d.history(req.Name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	last := revisions[len(revisions)-1]
+
+	if last.Info.Status == statusUninstalled && !req.Purge {
+		return nil, errors.Errorf("release %s is already deleted", req.Name)
+	}
+
+	if last.Info.Status != statusUninstalled {
+		last.Info.Status = statusUninstalling
+
+		if err := d.update(last); err != nil {
+			return nil, err
+		}
+
+		if err := d.deleteObjects(last, nil); err != nil {
+			return nil, errors.Wrapf(err, "delete objects of release %s", req.Name)
+		}
+
+		last.Info.Status = statusUninstalled
+		last.Info.Deleted = helmTime{time.Now()}
+		last.Info.Description = "Uninstallation complete"
+	}
+
+	if !req.Purge {
+		if err := d.update(last); err != nil {
+			return nil, err
+		}
+
+		return &rls.UninstallReleaseResponse{Release: last.toRelease()}, nil
+	}
+
+	for _, r := range revisions {
+		if err := d.remove(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return &rls.UninstallReleaseResponse{Release: last.toRelease()}, nil
+}
+
+func (d *Driver) ReleaseStatus(rlsName string,
+	opts ...helm.StatusOption) (*rls.GetReleaseStatusResponse, error) {
+	msg, err := capture(func(c *helm.Client) error {
+		_, err := c.ReleaseStatus(rlsName, opts...)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	req := msg.(*rls.GetReleaseStatusRequest)
+	r, err := d.revision(req.Name, req.Version)
+
+	if err != nil {
+		return nil, err
+	}
+
+	out := r.toRelease()
+
+	return &rls.GetReleaseStatusResponse{
+		Name:      out.Name,
+		Info:      out.Info,
+		Namespace: out.Namespace,
+	}, nil
+}
+
+func (d *Driver) UpdateRelease(rlsName, chStr string,
+	opts ...helm.UpdateOption) (*rls.UpdateReleaseResponse, error) {
+	chrt, err := chartutil.Load(chStr)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "load chart %s", chStr)
+	}
+
+	return d.UpdateReleaseFromChart(rlsName, chrt, opts...)
+}
+
+func (d *Driver) UpdateReleaseFromChart(rlsName string, chrt *chart.Chart,
+	opts ...helm.UpdateOption) (*rls.UpdateReleaseResponse, error) {
+	msg, err := capture(func(c *helm.Client) error {
+		_, err := c.UpdateReleaseFromChart(rlsName, chrt, opts...)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	req := msg.(*rls.UpdateReleaseRequest)
+	current, err := d.revision(req.Name, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if current.Info.Status == statusUninstalled {
+		return nil, errors.Wrapf(sgerrors.ErrNotFound, "release %s is deleted", req.Name)
+	}
+
+	values := req.Values
+
+	if req.ReuseValues && !req.ResetValues {
+		overrides, err := chartutil.ReadValues([]byte(req.Values.GetRaw()))
+
+		if err != nil {
+			return nil, errors.Wrap(err, "read values")
+		}
+
+		values = &chart.Config{
+			Raw: toYAML(mergeValues(overrides.AsMap(), current.Config)),
+		}
+	}
+
+	r, err := d.render(req.Chart, values, current.Name, current.Namespace,
+		current.Version+1, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r.Info.FirstDeployed = current.Info.FirstDeployed
+	r.Info.LastDeployed = helmTime{time.Now()}
+	r.Info.Status = statusPendingUpgrade
+
+	if req.DryRun {
+		r.Info.Description = "Dry run complete"
+		return &rls.UpdateReleaseResponse{Release: r.toRelease()}, nil
+	}
+
+	r.Info.Description = "Preparing upgrade"
+
+	if err := d.create(r); err != nil {
+		return nil, err
+	}
+
+	applied, err := d.apply(r)
+
+	if err == nil {
+		// Objects removed from the chart are deleted
+		err = d.deleteObjects(current, applied)
+	}
+
+	if err != nil {
+		d.fail(r, err)
+		return &rls.UpdateReleaseResponse{Release: r.toRelease()},
+			errors.Wrapf(err, "upgrade release %s", r.Name)
+	}
+
+	current.Info.Status = statusSuperseded
+
+	if err := d.update(current); err != nil {
+		return nil, err
+	}
+
+	r.Info.Status = statusDeployed
+	r.Info.Description = "Upgrade complete"
+
+	if err := d.update(r); err != nil {
+		return nil, err
+	}
+
+	return &rls.UpdateReleaseResponse{Release: r.toRelease()}, nil
+}
+
+func (d *Driver) RollbackRelease(rlsName string,
+	opts ...helm.RollbackOption) (*rls.RollbackReleaseResponse, error) {
+	msg, err := capture(func(c *helm.Client) error {
+		_, err := c.RollbackRelease(rlsName, opts...)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	req := msg.(*rls.RollbackReleaseRequest)
+	current, err := d.revision(req.Name, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Like helm 3 the previous revision is the target by default
+	version := req.Version
+
+	if version == 0 {
+		version = int32(current.Version - 1)
+	}
+
+	if version < 1 {
+		return nil, errors.Wrapf(sgerrors.ErrNotFound, "release %s has no previous revision", req.Name)
+	}
+
+	target, err := d.revision(req.Name, version)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &helmRelease{
+		Name:      current.Name,
+		Namespace: current.Namespace,
+		Version:   current.Version + 1,
+		Chart:     target.Chart,
+		Config:    target.Config,
+		Manifest:  target.Manifest,
+		Hooks:     target.Hooks,
+		Info: &info{
+			FirstDeployed: current.Info.FirstDeployed,
+			LastDeployed:  helmTime{time.Now()},
+			Status:        statusPendingRollback,
+			Notes:         target.Info.Notes,
+			Description:   fmt.Sprintf("Rollback to %d", target.Version),
+		},
+	}
+
+	if req.DryRun {
+		return &rls.RollbackReleaseResponse{Release: r.toRelease()}, nil
+	}
+
+	if err := d.create(r); err != nil {
+		return nil, err
+	}
+
+	applied, err := d.apply(r)
+
+	if err == nil && current.Info.Status != statusUninstalled {
+		// Objects added after the target revision are deleted
+		err = d.deleteObjects(current, applied)
+	}
+
+	if err != nil {
+		d.fail(r, err)
+		return &rls.RollbackReleaseResponse{Release: r.toRelease()},
+			errors.Wrapf(err, "rollback release %s", r.Name)
+	}
+
+	if current.Info.Status == statusDeployed {
+		current.Info.Status = statusSuperseded
+
+		if err := d.update(current); err != nil {
+			return nil, err
+		}
+	}
+
+	r.Info.Status = statusDeployed
+
+	if err := d.update(r); err != nil {
+		return nil, err
+	}
+
+	return &rls.RollbackReleaseResponse{Release: r.toRelease()}, nil
+}
+
+func (d *Driver) ReleaseContent(rlsName string,
+	opts ...helm.ContentOption) (*rls.GetReleaseContentResponse, error) {
+	msg, err := capture(func(c *helm.Client) error {
+		_, err := c.ReleaseContent(rlsName, opts...)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	req := msg.(*rls.GetReleaseContentRequest)
+	r, err := d.revision(req.Name, req.Version)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &rls.GetReleaseContentResponse{Release: r.toRelease()}, nil
+}
+
+func (d *Driver) ReleaseHistory(rlsName string,
+	opts ...helm.HistoryOption) (*rls.GetHistoryResponse, error) {
+	msg, err := capture(func(c *helm.Client) error {
+		_, err := c.ReleaseHistory(rlsName, opts...)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	req := msg.(*rls.GetHistoryRequest)
+	revisions, err := d.history(req.Name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &rls.GetHistoryResponse{}
+
+	// Latest revisions go first like tiller returns them
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if req.Max > 0 && int32(len(resp.Releases)) >= req.Max {
+			break
+		}
+
+		resp.Releases = append(resp.Releases, revisions[i].toRelease())
+	}
+
+	return resp, nil
+}
+
+func (d *Driver) GetVersion(opts ...helm.VersionOption) (*rls.GetVersionResponse, error) {
+	return nil, errors.Wrap(ErrNotSupported, "tiller version")
+}
+
+func (d *Driver) PingTiller() error {
+	return errors.Wrap(ErrNotSupported, "ping tiller")
+}
+
+// fail saves the release as failed, the error is in its description.
+func (d *Driver) fail(r *helmRelease, err error) {
+	r.Info.Status = statusFailed
+	r.Info.Description = fmt.Sprintf("Release %q failed: %v", r.Name, err)
+
+	if err := d.update(r); err != nil {
+		logrus.Errorf("helm 3: save failed release %s: %v", r.Name, err)
+	}
+}
+
+func hasStatus(r *helmRelease, statuses []release.Status_Code) bool {
+	code := statusCodes[r.Info.Status]
+
+	for _, status := range statuses {
+		if status == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mergeValues merges values of the previous release into overrides,
+// overrides win.
+func mergeValues(overrides, previous map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(previous)+len(overrides))
+
+	for k, v := range previous {
+		out[k] = v
+	}
+
+	for k, v := range overrides {
+		override, ok := v.(map[string]interface{})
+		prev, isMap := out[k].(map[string]interface{})
+
+		if ok && isMap {
+			out[k] = mergeValues(override, prev)
+			continue
+		}
+
+		out[k] = v
+	}
+
+	return out
+}
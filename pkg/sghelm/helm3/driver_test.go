@@ -0,0 +1,491 @@
+package helm3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/helm/pkg/helm"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+const (
+	configMapTemplate = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}-config
+data:
+  greeting: {{ .Values.greeting }}
+`
+	deploymentTemplate = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Release.Name }}
+spec:
+  replicas: {{ .Values.replicas }}
+`
+	hookTemplate = `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{ .Release.Name }}-migrate
+  annotations:
+    "helm.sh/hook": pre-install
+`
+)
+
+// A release secret written by helm 3
+const helmRelease3 = `{"name":"web","info":{"first_deployed":"2019-11-20T10:00:00Z",
+"last_deployed":"2019-11-20T10:00:00Z","deleted":"","description":"Install complete",
+"status":"deployed","notes":"Visit web"},"chart":{"metadata":{"name":"web","version":"1.2.0",
+"appVersion":"2.0","apiVersion":"v2","type":"application","dependencies":[{"name":"redis"}]},
+"lock":null,"templates":[{"name":"templates/service.yaml","data":"a2luZDogU2VydmljZQ=="}],
+"values":{"replicas":1},"schema":null,"files":[]},"config":{"replicas":2},
+"manifest":"---\n# Source: web/templates/service.yaml\nkind: Service\n",
+"hooks":[{"name":"web-test","kind":"Pod"}],"version":3,"namespace":"apps"}`
+
+type fakeDynamic struct {
+	objects map[string]*unstructured.Unstructured
+}
+
+func (f *fakeDynamic) Resource(resource schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &fakeResource{fake: f, resource: resource}
+}
+
+type fakeResource struct {
+	dynamic.NamespaceableResourceInterface
+	fake      *fakeDynamic
+	resource  schema.GroupVersionResource
+	namespace string
+}
+
+func (r *fakeResource) Namespace(ns string) dynamic.ResourceInterface {
+	return &fakeResource{fake: r.fake, resource: r.resource, namespace: ns}
+}
+
+func (r *fakeResource) key(name string) string {
+	return strings.Join([]string{r.resource.Resource, r.namespace, name}, "/")
+}
+
+func (r *fakeResource) Create(obj *unstructured.Unstructured, options metav1.CreateOptions,
+	subresources ...string) (*unstructured.Unstructured, error) {
+	if _, ok := r.fake.objects[r.key(obj.GetName())]; ok {
+		return nil, apierrors.NewAlreadyExists(r.resource.GroupResource(), obj.GetName())
+	}
+
+	r.fake.objects[r.key(obj.GetName())] = obj.DeepCopy()
+
+	return obj, nil
+}
+
+func (r *fakeResource) Get(name string, options metav1.GetOptions,
+	subresources ...string) (*unstructured.Unstructured, error) {
+	obj, ok := r.fake.objects[r.key(name)]
+
+	if !ok {
+		return nil, apierrors.NewNotFound(r.resource.GroupResource(), name)
+	}
+
+	return obj.DeepCopy(), nil
+}
+
+func (r *fakeResource) Update(obj *unstructured.Unstructured, options metav1.UpdateOptions,
+	subresources ...string) (*unstructured.Unstructured, error) {
+	r.fake.objects[r.key(obj.GetName())] = obj.DeepCopy()
+
+	return obj, nil
+}
+
+func (r *fakeResource) Delete(name string, options *metav1.DeleteOptions, subresources ...string) error {
+	if _, ok := r.fake.objects[r.key(name)]; !ok {
+		return apierrors.NewNotFound(r.resource.GroupResource(), name)
+	}
+
+	delete(r.fake.objects, r.key(name))
+
+	return nil
+}
+
+func newDriver() (*Driver, *fake.Clientset, *fakeDynamic) {
+	clientSet := fake.NewSimpleClientset()
+	clientSet.Fake.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Kind: "Deployment", Namespaced: true},
+				{Name: "deployments/scale", Kind: "Scale", Namespaced: true},
+			},
+		},
+	}
+	dynamicClient := &fakeDynamic{
+		objects: make(map[string]*unstructured.Unstructured),
+	}
+
+	return New(clientSet, dynamicClient, "1.15.3"), clientSet, dynamicClient
+}
+
+func testChart(version string, templates ...string) *chart.Chart {
+	chrt := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:    "app",
+			Version: version,
+		},
+		Values: &chart.Config{
+			Raw: "greeting: hello\nreplicas: 1\n",
+		},
+	}
+
+	files := map[string]string{
+		"configmap":  configMapTemplate,
+		"deployment": deploymentTemplate,
+		"hook":       hookTemplate,
+		"NOTES":      "Release {{ .Release.Name }} is installed",
+		"_helpers":   `{{- define "app.name" -}}app{{- end -}}`,
+	}
+
+	for _, name := range templates {
+		ext := ".yaml"
+
+		if name == "NOTES" {
+			ext = ".txt"
+		} else if name == "_helpers" {
+			ext = ".tpl"
+		}
+
+		chrt.Templates = append(chrt.Templates, &chart.Template{
+			Name: "templates/" + name + ext,
+			Data: []byte(files[name]),
+		})
+	}
+
+	return chrt
+}
+
+func TestDriverLifecycle(t *testing.T) {
+	d, clientSet, objects := newDriver()
+	chrt := testChart("0.1.0", "configmap", "deployment", "NOTES", "_helpers")
+
+	resp, err := d.InstallReleaseFromChart(chrt, "apps",
+		helm.ReleaseName("web"),
+		helm.ValueOverrides([]byte("replicas: 3")))
+
+	if err != nil {
+		t.Fatalf("Install release %v", err)
+	}
+
+	rls := resp.GetRelease()
+
+	if rls.Name != "web" || rls.Namespace != "apps" || rls.Version != 1 ||
+		rls.Info.Status.Code != release.Status_DEPLOYED ||
+		rls.Info.Status.Notes != "Release web is installed" ||
+		rls.Chart.Metadata.Version != "0.1.0" ||
+		!strings.Contains(rls.Config.Raw, "replicas: 3") {
+		t.Errorf("Unexpected release %v", rls)
+	}
+
+	deployment := objects.objects["deployments/apps/web"]
+
+	if deployment == nil || len(objects.objects) != 2 {
+		t.Fatalf("Objects of the release must be created %v", objects.objects)
+	}
+
+	if replicas, _, _ := unstructured.NestedInt64(deployment.Object, "spec", "replicas"); replicas != 3 {
+		t.Errorf("Values must be overridden, replicas %d", replicas)
+	}
+
+	if deployment.GetAnnotations()[releaseNameAnnotation] != "web" ||
+		deployment.GetLabels()[managedByLabel] != managedBy {
+		t.Errorf("Objects must be owned by the release %v", deployment)
+	}
+
+	secret, err := clientSet.CoreV1().Secrets("apps").Get("sh.helm.release.v1.web.v1", metav1.GetOptions{})
+
+	if err != nil || secret.Type != secretType || secret.Labels[labelStatus] != statusDeployed ||
+		secret.Labels[labelOwner] != owner {
+		t.Fatalf("Release must be stored like helm 3 stores it %v %v", secret, err)
+	}
+
+	_, err = d.InstallReleaseFromChart(chrt, "apps", helm.ReleaseName("web"))
+
+	if !sgerrors.IsAlreadyExists(errors.Cause(err)) {
+		t.Errorf("Expected already exists error actual %v", err)
+	}
+
+	upgrade := testChart("0.2.0", "deployment")
+	updateResp, err := d.UpdateReleaseFromChart("web", upgrade,
+		helm.UpdateValueOverrides([]byte("replicas: 5")))
+
+	if err != nil {
+		t.Fatalf("Upgrade release %v", err)
+	}
+
+	if rls := updateResp.GetRelease(); rls.Version != 2 ||
+		rls.Info.Status.Code != release.Status_DEPLOYED || rls.Chart.Metadata.Version != "0.2.0" {
+		t.Errorf("Unexpected upgraded release %v", rls)
+	}
+
+	if _, ok := objects.objects["configmaps/apps/web-config"]; ok || len(objects.objects) != 1 {
+		t.Errorf("Objects removed from the chart must be deleted %v", objects.objects)
+	}
+
+	deployment = objects.objects["deployments/apps/web"]
+
+	if replicas, _, _ := unstructured.NestedInt64(deployment.Object, "spec", "replicas"); replicas != 5 {
+		t.Errorf("Objects must be updated, replicas %d", replicas)
+	}
+
+	history, err := d.ReleaseHistory("web")
+
+	if err != nil || len(history.Releases) != 2 ||
+		history.Releases[0].Version != 2 ||
+		history.Releases[1].Info.Status.Code != release.Status_SUPERSEDED {
+		t.Errorf("Unexpected history %v %v", history, err)
+	}
+
+	content, err := d.ReleaseContent("web", helm.ContentReleaseVersion(1))
+
+	if err != nil || content.Release.Chart.Metadata.Version != "0.1.0" {
+		t.Errorf("Unexpected content of revision %v %v", content, err)
+	}
+
+	status, err := d.ReleaseStatus("web")
+
+	if err != nil || status.Namespace != "apps" || status.Info.Status.Code != release.Status_DEPLOYED {
+		t.Errorf("Unexpected status %v %v", status, err)
+	}
+
+	if _, err = d.RollbackRelease("web", helm.RollbackVersion(9)); !sgerrors.IsNotFound(errors.Cause(err)) {
+		t.Errorf("Rollback to unknown revision must not be found %v", err)
+	}
+
+	rollbackResp, err := d.RollbackRelease("web", helm.RollbackVersion(1))
+
+	if err != nil {
+		t.Fatalf("Rollback release %v", err)
+	}
+
+	if rls := rollbackResp.GetRelease(); rls.Version != 3 ||
+		rls.Info.Status.Code != release.Status_DEPLOYED || rls.Chart.Metadata.Version != "0.1.0" ||
+		rls.Info.Description != "Rollback to 1" {
+		t.Errorf("Unexpected rolled back release %v", rls)
+	}
+
+	deployment = objects.objects["deployments/apps/web"]
+
+	if replicas, _, _ := unstructured.NestedInt64(deployment.Object, "spec", "replicas"); replicas != 3 ||
+		len(objects.objects) != 2 {
+		t.Errorf("Objects of the revision must be restored, replicas %d %v", replicas, objects.objects)
+	}
+
+	content, err = d.ReleaseContent("web", helm.ContentReleaseVersion(2))
+
+	if err != nil || content.Release.Info.Status.Code != release.Status_SUPERSEDED {
+		t.Errorf("Rolled back revision must be superseded %v %v", content, err)
+	}
+
+	uninstResp, err := d.DeleteRelease("web")
+
+	if err != nil || uninstResp.Release.Info.Status.Code != release.Status_DELETED {
+		t.Fatalf("Delete release %v %v", uninstResp, err)
+	}
+
+	if len(objects.objects) != 0 {
+		t.Errorf("Objects of the release must be deleted %v", objects.objects)
+	}
+
+	list, err := d.ListReleases()
+
+	if err != nil || len(list.Releases) != 0 {
+		t.Errorf("Deleted releases must not be listed by default %v %v", list, err)
+	}
+
+	list, err = d.ListReleases(helm.ReleaseListStatuses([]release.Status_Code{release.Status_DELETED}))
+
+	if err != nil || len(list.Releases) != 1 {
+		t.Errorf("Deleted release must be listed by status %v %v", list, err)
+	}
+
+	if _, err = d.DeleteRelease("web", helm.DeletePurge(true)); err != nil {
+		t.Fatalf("Purge release %v", err)
+	}
+
+	if _, err = d.ReleaseContent("web"); !sgerrors.IsNotFound(errors.Cause(err)) {
+		t.Errorf("Purged release must not be found %v", err)
+	}
+}
+
+func TestDriverFailedInstall(t *testing.T) {
+	d, _, _ := newDriver()
+	chrt := testChart("0.1.0", "hook")
+	chrt.Templates[0].Data = []byte("apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: job\n")
+
+	resp, err := d.InstallReleaseFromChart(chrt, "", helm.ReleaseName("job"))
+
+	if err == nil {
+		t.Fatal("Objects that are not served must fail the release")
+	}
+
+	if rls := resp.GetRelease(); rls.Namespace != defaultNamespace ||
+		rls.Info.Status.Code != release.Status_FAILED {
+		t.Errorf("Unexpected failed release %v", rls)
+	}
+
+	if _, err := d.InstallReleaseFromChart(chrt, "", helm.ReleaseName("")); !sgerrors.IsValidationFailed(errors.Cause(err)) {
+		t.Errorf("Release without name must not be installed %v", err)
+	}
+}
+
+func TestDriverListReleases(t *testing.T) {
+	d, _, _ := newDriver()
+
+	for _, name := range []string{"c", "a", "b"} {
+		_, err := d.InstallReleaseFromChart(testChart("0.1.0", "configmap"), name,
+			helm.ReleaseName(name))
+
+		if err != nil {
+			t.Fatalf("Install release %s %v", name, err)
+		}
+	}
+
+	testCases := []struct {
+		description  string
+		opts         []helm.ReleaseListOption
+		expected     []string
+		expectedNext string
+	}{
+		{
+			description: "all",
+			expected:    []string{"a", "b", "c"},
+		},
+		{
+			description: "namespace",
+			opts:        []helm.ReleaseListOption{helm.ReleaseListNamespace("b")},
+			expected:    []string{"b"},
+		},
+		{
+			description:  "offset and limit",
+			opts:         []helm.ReleaseListOption{helm.ReleaseListOffset("b"), helm.ReleaseListLimit(1)},
+			expected:     []string{"b"},
+			expectedNext: "c",
+		},
+		{
+			description: "filter",
+			opts:        []helm.ReleaseListOption{helm.ReleaseListFilter("^[ab]$")},
+			expected:    []string{"a", "b"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		resp, err := d.ListReleases(testCase.opts...)
+
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		var names []string
+
+		for _, rls := range resp.Releases {
+			names = append(names, rls.Name)
+		}
+
+		if strings.Join(names, ",") != strings.Join(testCase.expected, ",") ||
+			resp.Next != testCase.expectedNext {
+			t.Errorf("Expected releases %v next %s actual %v next %s", testCase.expected,
+				testCase.expectedNext, names, resp.Next)
+		}
+	}
+}
+
+func TestDecodeRelease(t *testing.T) {
+	gzipped := &bytes.Buffer{}
+	w := gzip.NewWriter(gzipped)
+	w.Write([]byte(helmRelease3))
+	w.Close()
+
+	for _, data := range [][]byte{gzipped.Bytes(), []byte(helmRelease3)} {
+		r, err := decodeRelease([]byte(base64.StdEncoding.EncodeToString(data)))
+
+		if err != nil {
+			t.Fatalf("Decode release %v", err)
+		}
+
+		rls := r.toRelease()
+
+		if rls.Name != "web" || rls.Namespace != "apps" || rls.Version != 3 ||
+			rls.Info.Status.Code != release.Status_DEPLOYED || rls.Info.Deleted != nil ||
+			rls.Chart.Metadata.AppVersion != "2.0" || rls.Config.Raw != "replicas: 2\n" ||
+			string(rls.Chart.Templates[0].Data) != "kind: Service" {
+			t.Errorf("Unexpected release %v", rls)
+		}
+
+		// Fields tiller releases do not have must be kept
+		raw, err := encodeRelease(r)
+
+		if err != nil {
+			t.Fatalf("Encode release %v", err)
+		}
+
+		decoded, err := decodeRelease(raw)
+
+		if err != nil || decoded.Chart.Metadata.Type != "application" ||
+			len(decoded.Chart.Metadata.Dependencies) == 0 || len(decoded.Hooks) != 1 {
+			t.Errorf("Unexpected release %v %v", decoded, err)
+		}
+	}
+}
+
+func TestBuildManifest(t *testing.T) {
+	manifest, notes, err := buildManifest(map[string]string{
+		"app/templates/deployment.yaml":        "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app",
+		"app/templates/config.yaml":            "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\n# empty\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b",
+		"app/templates/NOTES.txt":              "notes",
+		"app/templates/_helpers.tpl":           "",
+		"app/charts/redis/templates/NOTES.txt": "redis notes",
+	}, "app")
+
+	if err != nil {
+		t.Fatalf("Build manifest %v", err)
+	}
+
+	expected := "---\n# Source: app/templates/config.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n" +
+		"---\n# Source: app/templates/config.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b\n" +
+		"---\n# Source: app/templates/deployment.yaml\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\n"
+
+	if manifest != expected {
+		t.Errorf("Expected manifest\n%s\nactual\n%s", expected, manifest)
+	}
+
+	if notes != "notes" {
+		t.Errorf("Expected notes of the chart actual %s", notes)
+	}
+}
+
+func TestBuildManifestHooks(t *testing.T) {
+	_, _, err := buildManifest(map[string]string{
+		"app/templates/job.yaml": strings.Replace(hookTemplate, "{{ .Release.Name }}", "app", 1),
+	}, "app")
+
+	if !sgerrors.IsNotSupported(err) {
+		t.Errorf("Charts with hooks must not be supported %v", err)
+	}
+}
@@ -0,0 +1,433 @@
+package helm3
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/releaseutil"
+	"k8s.io/helm/pkg/renderutil"
+	"k8s.io/helm/pkg/timeconv"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+const (
+	hookAnnotation = "helm.sh/hook"
+	notesFile      = "NOTES.txt"
+
+	// Ownership metadata helm 3 uses to adopt objects of releases
+	releaseNameAnnotation      = "meta.helm.sh/release-name"
+	releaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+	managedByLabel             = "app.kubernetes.io/managed-by"
+	managedBy                  = "Helm"
+)
+
+// installOrder is the order helm installs objects of a release in,
+// objects of other kinds go last.
+var installOrder = []string{
+	"Namespace",
+	"NetworkPolicy",
+	"ResourceQuota",
+	"LimitRange",
+	"PodSecurityPolicy",
+	"PodDisruptionBudget",
+	"Secret",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"ServiceAccount",
+	"CustomResourceDefinition",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicationController",
+	"ReplicaSet",
+	"Deployment",
+	"HorizontalPodAutoscaler",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"Ingress",
+	"APIService",
+}
+
+type document struct {
+	source  string
+	kind    string
+	content string
+}
+
+// render renders templates of the chart into a release, hooks of
+// the chart are left out since the driver does not run them.
+func (d *Driver) render(chrt *chart.Chart, values *chart.Config, name, namespace string,
+	version int, install bool) (*helmRelease, error) {
+	if values == nil {
+		values = &chart.Config{}
+	}
+
+	// Empty values would make helm ignore defaults of the chart
+	if strings.TrimSpace(values.Raw) == "" {
+		values = &chart.Config{Raw: "{}"}
+	}
+
+	templates, err := renderutil.Render(chrt, values, renderutil.Options{
+		ReleaseOptions: chartutil.ReleaseOptions{
+			Name:      name,
+			Namespace: namespace,
+			Time:      timeconv.Now(),
+			IsInstall: install,
+			IsUpgrade: !install,
+			Revision:  version,
+		},
+		KubeVersion: d.kubeVersion,
+	})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "render chart %s", chrt.GetMetadata().GetName())
+	}
+
+	config, err := chartutil.ReadValues([]byte(values.Raw))
+
+	if err != nil {
+		return nil, errors.Wrap(err, "read values")
+	}
+
+	c, err := fromChart(chrt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, notes, err := buildManifest(templates, chrt.GetMetadata().GetName())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &helmRelease{
+		Name:      name,
+		Namespace: namespace,
+		Version:   version,
+		Chart:     c,
+		Config:    config.AsMap(),
+		Manifest:  manifest,
+		Info: &info{
+			Notes: notes,
+		},
+	}, nil
+}
+
+// buildManifest joins rendered templates in install order the way
+// helm 3 does, notes of the top level chart are returned separately.
+func buildManifest(templates map[string]string, chartName string) (string, string, error) {
+	var (
+		notes string
+		docs  []document
+	)
+
+	names := make([]string, 0, len(templates))
+
+	for name := range templates {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		content := templates[name]
+
+		if path.Base(name) == notesFile {
+			if name == path.Join(chartName, "templates", notesFile) {
+				notes = content
+			}
+
+			continue
+		}
+
+		if strings.HasPrefix(path.Base(name), "_") {
+			continue
+		}
+
+		parts := releaseutil.SplitManifests(content)
+
+		for i := 0; i < len(parts); i++ {
+			part := strings.TrimSpace(parts[fmt.Sprintf("manifest-%d", i)])
+
+			if part == "" {
+				continue
+			}
+
+			head := &releaseutil.SimpleHead{}
+
+			if err := yaml.Unmarshal([]byte(part), head); err != nil {
+				return "", "", errors.Wrapf(err, "parse template %s", name)
+			}
+
+			if head.Kind == "" {
+				continue
+			}
+
+			// Hooks are not run, charts that rely on them are rejected
+			// rather than installed partially.
+			if head.Metadata != nil && head.Metadata.Annotations[hookAnnotation] != "" {
+				return "", "", errors.Wrapf(sgerrors.ErrNotSupported, "hook %s of template %s",
+					head.Metadata.Name, name)
+			}
+
+			docs = append(docs, document{
+				source:  name,
+				kind:    head.Kind,
+				content: part,
+			})
+		}
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		return kindOrder(docs[i].kind) < kindOrder(docs[j].kind)
+	})
+
+	buf := &bytes.Buffer{}
+
+	for _, doc := range docs {
+		fmt.Fprintf(buf, "---\n# Source: %s\n%s\n", doc.source, doc.content)
+	}
+
+	return buf.String(), notes, nil
+}
+
+func kindOrder(kind string) int {
+	for i, k := range installOrder {
+		if k == kind {
+			return i
+		}
+	}
+
+	return len(installOrder)
+}
+
+// decodeObjects decodes objects of the manifest keeping their order.
+func decodeObjects(manifest string) ([]*unstructured.Unstructured, error) {
+	decoder := yamlutil.NewYAMLOrJSONDecoder(strings.NewReader(manifest), 4096)
+	var objects []*unstructured.Unstructured
+
+	for {
+		var raw json.RawMessage
+
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return objects, nil
+			}
+
+			return nil, errors.Wrap(err, "decode manifest")
+		}
+
+		if len(raw) == 0 || string(raw) == "null" {
+			continue
+		}
+
+		// Unstructured decoding keeps integers of objects
+		obj := &unstructured.Unstructured{}
+
+		if err := obj.UnmarshalJSON(raw); err != nil {
+			return nil, errors.Wrap(err, "decode manifest")
+		}
+
+		objects = append(objects, obj)
+	}
+}
+
+// resources maps kinds to API resources served by the kube.
+func (d *Driver) resources() (map[schema.GroupVersionKind]metav1.APIResource, error) {
+	lists, err := d.discovery.ServerResources()
+
+	// Resources of groups that are discovered are still usable
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, errors.Wrap(err, "discover resources")
+	}
+
+	out := make(map[schema.GroupVersionKind]metav1.APIResource)
+
+	for _, list := range lists {
+		if list == nil {
+			continue
+		}
+
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+
+		if err != nil {
+			continue
+		}
+
+		for _, r := range list.APIResources {
+			// Subresources share kinds of their resources
+			if strings.Contains(r.Name, "/") {
+				continue
+			}
+
+			out[gv.WithKind(r.Kind)] = r
+		}
+	}
+
+	return out, nil
+}
+
+func resourceFor(client dynamic.Interface, resources map[schema.GroupVersionKind]metav1.APIResource,
+	obj *unstructured.Unstructured, namespace string) (dynamic.ResourceInterface, error) {
+	gvk := obj.GroupVersionKind()
+	r, ok := resources[gvk]
+
+	if !ok {
+		return nil, errors.Errorf("resource of %s %s is not served", gvk, obj.GetName())
+	}
+
+	resource := client.Resource(gvk.GroupVersion().WithResource(r.Name))
+
+	if !r.Namespaced {
+		obj.SetNamespace("")
+		return resource, nil
+	}
+
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(namespace)
+	}
+
+	return resource.Namespace(obj.GetNamespace()), nil
+}
+
+func objectKey(obj *unstructured.Unstructured) string {
+	return strings.Join([]string{obj.GetAPIVersion(), obj.GetKind(),
+		obj.GetNamespace(), obj.GetName()}, "/")
+}
+
+// apply creates objects of the release, objects that exist are updated,
+// keys of applied objects are returned.
+func (d *Driver) apply(rls *helmRelease) (map[string]bool, error) {
+	objects, err := decodeObjects(rls.Manifest)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := d.resources()
+
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]bool, len(objects))
+
+	for _, obj := range objects {
+		resource, err := resourceFor(d.dynamic, resources, obj, rls.Namespace)
+
+		if err != nil {
+			return applied, err
+		}
+
+		setOwner(obj, rls)
+
+		if _, err := resource.Create(obj, metav1.CreateOptions{}); err != nil {
+			if !apierrors.IsAlreadyExists(err) {
+				return applied, errors.Wrapf(err, "create %s %s", obj.GetKind(), obj.GetName())
+			}
+
+			existing, err := resource.Get(obj.GetName(), metav1.GetOptions{})
+
+			if err != nil {
+				return applied, errors.Wrapf(err, "get %s %s", obj.GetKind(), obj.GetName())
+			}
+
+			obj.SetResourceVersion(existing.GetResourceVersion())
+
+			if _, err := resource.Update(obj, metav1.UpdateOptions{}); err != nil {
+				return applied, errors.Wrapf(err, "update %s %s", obj.GetKind(), obj.GetName())
+			}
+		}
+
+		applied[objectKey(obj)] = true
+	}
+
+	return applied, nil
+}
+
+// deleteObjects deletes objects of the release in reverse install order
+// except the kept ones, objects that are not found are skipped.
+func (d *Driver) deleteObjects(rls *helmRelease, keep map[string]bool) error {
+	objects, err := decodeObjects(rls.Manifest)
+
+	if err != nil {
+		return err
+	}
+
+	resources, err := d.resources()
+
+	if err != nil {
+		return err
+	}
+
+	propagation := metav1.DeletePropagationBackground
+
+	for i := len(objects) - 1; i >= 0; i-- {
+		obj := objects[i]
+		resource, err := resourceFor(d.dynamic, resources, obj, rls.Namespace)
+
+		if err != nil {
+			return err
+		}
+
+		if keep[objectKey(obj)] {
+			continue
+		}
+
+		err = resource.Delete(obj.GetName(), &metav1.DeleteOptions{
+			PropagationPolicy: &propagation,
+		})
+
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "delete %s %s", obj.GetKind(), obj.GetName())
+		}
+	}
+
+	return nil
+}
+
+func setOwner(obj *unstructured.Unstructured, rls *helmRelease) {
+	annotations := obj.GetAnnotations()
+
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	annotations[releaseNameAnnotation] = rls.Name
+	annotations[releaseNamespaceAnnotation] = rls.Namespace
+	obj.SetAnnotations(annotations)
+
+	objLabels := obj.GetLabels()
+
+	if objLabels == nil {
+		objLabels = make(map[string]string)
+	}
+
+	objLabels[managedByLabel] = managedBy
+	obj.SetLabels(objLabels)
+}
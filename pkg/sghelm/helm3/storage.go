@@ -0,0 +1,470 @@
+package helm3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+	"k8s.io/helm/pkg/timeconv"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// Helm 3 keeps every revision of a release in a secret of the release
+// namespace, the release is gzipped json encoded with base64.
+const (
+	secretType = "helm.sh/release.v1"
+	secretKey  = "release"
+
+	labelName    = "name"
+	labelOwner   = "owner"
+	labelStatus  = "status"
+	labelVersion = "version"
+	owner        = "helm"
+)
+
+const (
+	statusUnknown         = "unknown"
+	statusDeployed        = "deployed"
+	statusUninstalled     = "uninstalled"
+	statusSuperseded      = "superseded"
+	statusFailed          = "failed"
+	statusUninstalling    = "uninstalling"
+	statusPendingInstall  = "pending-install"
+	statusPendingUpgrade  = "pending-upgrade"
+	statusPendingRollback = "pending-rollback"
+)
+
+var statusCodes = map[string]release.Status_Code{
+	statusUnknown:         release.Status_UNKNOWN,
+	statusDeployed:        release.Status_DEPLOYED,
+	statusUninstalled:     release.Status_DELETED,
+	statusSuperseded:      release.Status_SUPERSEDED,
+	statusFailed:          release.Status_FAILED,
+	statusUninstalling:    release.Status_DELETING,
+	statusPendingInstall:  release.Status_PENDING_INSTALL,
+	statusPendingUpgrade:  release.Status_PENDING_UPGRADE,
+	statusPendingRollback: release.Status_PENDING_ROLLBACK,
+}
+
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
+
+// helmRelease is a release in the format helm 3 stores it,
+// hooks are kept as is since the driver does not run them.
+type helmRelease struct {
+	Name      string                 `json:"name,omitempty"`
+	Info      *info                  `json:"info,omitempty"`
+	Chart     *helmChart             `json:"chart,omitempty"`
+	Config    map[string]interface{} `json:"config,omitempty"`
+	Manifest  string                 `json:"manifest,omitempty"`
+	Hooks     []json.RawMessage      `json:"hooks,omitempty"`
+	Version   int                    `json:"version,omitempty"`
+	Namespace string                 `json:"namespace,omitempty"`
+}
+
+type info struct {
+	FirstDeployed helmTime `json:"first_deployed,omitempty"`
+	LastDeployed  helmTime `json:"last_deployed,omitempty"`
+	Deleted       helmTime `json:"deleted"`
+	Description   string   `json:"description,omitempty"`
+	Status        string   `json:"status,omitempty"`
+	Notes         string   `json:"notes,omitempty"`
+}
+
+// helmTime is encoded as helm 3 encodes time, zero time is an empty string.
+type helmTime struct {
+	time.Time
+}
+
+func (t helmTime) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte(`""`), nil
+	}
+
+	return t.Time.MarshalJSON()
+}
+
+func (t *helmTime) UnmarshalJSON(b []byte) error {
+	if string(b) == `""` || string(b) == "null" {
+		t.Time = time.Time{}
+		return nil
+	}
+
+	return t.Time.UnmarshalJSON(b)
+}
+
+type helmChart struct {
+	Metadata  *metadata              `json:"metadata"`
+	Lock      json.RawMessage        `json:"lock,omitempty"`
+	Templates []*file                `json:"templates"`
+	Values    map[string]interface{} `json:"values"`
+	Schema    []byte                 `json:"schema"`
+	Files     []*file                `json:"files"`
+}
+
+// metadata of helm 3 charts extends metadata of helm 2 charts
+// with dependencies and type.
+type metadata struct {
+	chart.Metadata
+	Dependencies json.RawMessage `json:"dependencies,omitempty"`
+	Type         string          `json:"type,omitempty"`
+}
+
+type file struct {
+	Name string `json:"name"`
+	Data []byte `json:"data"`
+}
+
+func secretName(name string, version int) string {
+	return fmt.Sprintf("sh.helm.release.v1.%s.v%d", name, version)
+}
+
+func releaseSelector(name string) string {
+	set := labels.Set{labelOwner: owner}
+
+	if name != "" {
+		set[labelName] = name
+	}
+
+	return labels.SelectorFromSet(set).String()
+}
+
+func encodeRelease(rls *helmRelease) ([]byte, error) {
+	raw, err := json.Marshal(rls)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal")
+	}
+
+	buf := &bytes.Buffer{}
+	w, err := gzip.NewWriterLevel(buf, gzip.BestCompression)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "gzip")
+	}
+
+	if _, err := w.Write(raw); err != nil {
+		return nil, errors.Wrap(err, "gzip")
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "gzip")
+	}
+
+	return []byte(base64.StdEncoding.EncodeToString(buf.Bytes())), nil
+}
+
+func decodeRelease(data []byte) (*helmRelease, error) {
+	raw, err := base64.StdEncoding.DecodeString(string(data))
+
+	if err != nil {
+		return nil, errors.Wrap(err, "base64")
+	}
+
+	if bytes.HasPrefix(raw, gzipMagic) {
+		r, err := gzip.NewReader(bytes.NewReader(raw))
+
+		if err != nil {
+			return nil, errors.Wrap(err, "gunzip")
+		}
+
+		defer r.Close()
+
+		if raw, err = ioutil.ReadAll(r); err != nil {
+			return nil, errors.Wrap(err, "gunzip")
+		}
+	}
+
+	rls := &helmRelease{}
+
+	if err := json.Unmarshal(raw, rls); err != nil {
+		return nil, errors.Wrap(err, "unmarshal")
+	}
+
+	if rls.Info == nil {
+		rls.Info = &info{Status: statusUnknown}
+	}
+
+	return rls, nil
+}
+
+func toSecret(rls *helmRelease) (*corev1.Secret, error) {
+	data, err := encodeRelease(rls)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "encode release %s", rls.Name)
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName(rls.Name, rls.Version),
+			Namespace: rls.Namespace,
+			Labels: map[string]string{
+				labelName:    rls.Name,
+				labelOwner:   owner,
+				labelStatus:  rls.Info.Status,
+				labelVersion: strconv.Itoa(rls.Version),
+			},
+		},
+		Type: secretType,
+		Data: map[string][]byte{
+			secretKey: data,
+		},
+	}, nil
+}
+
+// list returns revisions of releases sorted by name and version,
+// all releases of the namespace are returned when name is empty.
+func (d *Driver) list(namespace, name string) ([]*helmRelease, error) {
+	secrets, err := d.secrets.Secrets(namespace).List(metav1.ListOptions{
+		LabelSelector: releaseSelector(name),
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "list release secrets")
+	}
+
+	out := make([]*helmRelease, 0, len(secrets.Items))
+
+	for _, secret := range secrets.Items {
+		if secret.Type != secretType {
+			continue
+		}
+
+		rls, err := decodeRelease(secret.Data[secretKey])
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode release secret %s/%s",
+				secret.Namespace, secret.Name)
+		}
+
+		out = append(out, rls)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+
+		return out[i].Version < out[j].Version
+	})
+
+	return out, nil
+}
+
+// history returns revisions of the release, the release is looked up in
+// all namespaces and the one deployed last wins when names are reused.
+func (d *Driver) history(name string) ([]*helmRelease, error) {
+	revisions, err := d.list(metav1.NamespaceAll, name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(revisions) == 0 {
+		return nil, errors.Wrapf(sgerrors.ErrNotFound, "release %s", name)
+	}
+
+	var last *helmRelease
+
+	for _, rls := range revisions {
+		if last == nil || rls.Info.LastDeployed.After(last.Info.LastDeployed.Time) {
+			last = rls
+		}
+	}
+
+	out := make([]*helmRelease, 0, len(revisions))
+
+	for _, rls := range revisions {
+		if rls.Namespace == last.Namespace {
+			out = append(out, rls)
+		}
+	}
+
+	return out, nil
+}
+
+// revision returns revision of the release, the latest one for 0.
+func (d *Driver) revision(name string, version int32) (*helmRelease, error) {
+	revisions, err := d.history(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if version == 0 {
+		return revisions[len(revisions)-1], nil
+	}
+
+	for _, rls := range revisions {
+		if rls.Version == int(version) {
+			return rls, nil
+		}
+	}
+
+	return nil, errors.Wrapf(sgerrors.ErrNotFound, "release %s revision %d", name, version)
+}
+
+func (d *Driver) create(rls *helmRelease) error {
+	secret, err := toSecret(rls)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = d.secrets.Secrets(rls.Namespace).Create(secret)
+
+	return errors.Wrapf(err, "create release secret %s", secret.Name)
+}
+
+func (d *Driver) update(rls *helmRelease) error {
+	secret, err := toSecret(rls)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = d.secrets.Secrets(rls.Namespace).Update(secret)
+
+	return errors.Wrapf(err, "update release secret %s", secret.Name)
+}
+
+func (d *Driver) remove(rls *helmRelease) error {
+	name := secretName(rls.Name, rls.Version)
+	err := d.secrets.Secrets(rls.Namespace).Delete(name, &metav1.DeleteOptions{})
+
+	return errors.Wrapf(err, "delete release secret %s", name)
+}
+
+// toRelease converts the release to a tiller release, so the API
+// returns releases of the same shape for both helm versions.
+func (r *helmRelease) toRelease() *release.Release {
+	rls := &release.Release{
+		Name:      r.Name,
+		Namespace: r.Namespace,
+		Version:   int32(r.Version),
+		Manifest:  r.Manifest,
+		Info: &release.Info{
+			Status: &release.Status{
+				Code:  statusCodes[r.Info.Status],
+				Notes: r.Info.Notes,
+			},
+			FirstDeployed: toTimestamp(r.Info.FirstDeployed.Time),
+			LastDeployed:  toTimestamp(r.Info.LastDeployed.Time),
+			Deleted:       toTimestamp(r.Info.Deleted.Time),
+			Description:   r.Info.Description,
+		},
+		Config: &chart.Config{
+			Raw: toYAML(r.Config),
+		},
+	}
+
+	if r.Chart != nil {
+		rls.Chart = r.Chart.toChart()
+	}
+
+	return rls
+}
+
+func (c *helmChart) toChart() *chart.Chart {
+	chrt := &chart.Chart{
+		Values: &chart.Config{
+			Raw: toYAML(c.Values),
+		},
+	}
+
+	if c.Metadata != nil {
+		m := c.Metadata.Metadata
+		chrt.Metadata = &m
+	}
+
+	for _, f := range c.Templates {
+		chrt.Templates = append(chrt.Templates, &chart.Template{
+			Name: f.Name,
+			Data: f.Data,
+		})
+	}
+
+	for _, f := range c.Files {
+		chrt.Files = append(chrt.Files, &any.Any{
+			TypeUrl: f.Name,
+			Value:   f.Data,
+		})
+	}
+
+	return chrt
+}
+
+func fromChart(chrt *chart.Chart) (*helmChart, error) {
+	values, err := chartutil.ReadValues([]byte(chrt.GetValues().GetRaw()))
+
+	if err != nil {
+		return nil, errors.Wrap(err, "read chart values")
+	}
+
+	c := &helmChart{
+		Values: values.AsMap(),
+	}
+
+	if chrt.Metadata != nil {
+		c.Metadata = &metadata{Metadata: *chrt.Metadata}
+	}
+
+	for _, t := range chrt.Templates {
+		c.Templates = append(c.Templates, &file{
+			Name: t.Name,
+			Data: t.Data,
+		})
+	}
+
+	for _, f := range chrt.Files {
+		c.Files = append(c.Files, &file{
+			Name: f.TypeUrl,
+			Data: f.Value,
+		})
+	}
+
+	return c, nil
+}
+
+func toTimestamp(t time.Time) *timestamp.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+
+	return timeconv.Timestamp(t)
+}
+
+func toYAML(values map[string]interface{}) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	raw, err := yaml.Marshal(values)
+
+	if err != nil {
+		return ""
+	}
+
+	return string(raw)
+}
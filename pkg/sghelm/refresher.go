@@ -0,0 +1,57 @@
+package sghelm
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	DefaultIndexTTL        = time.Hour
+	DefaultRefreshInterval = 5 * time.Minute
+)
+
+// IndexRefresher periodically downloads index files of repositories
+// which are older than the ttl.
+type IndexRefresher struct {
+	svc      *Service
+	ttl      time.Duration
+	interval time.Duration
+}
+
+// NewIndexRefresher constructs IndexRefresher, defaults are used for
+// zero ttl and interval.
+func NewIndexRefresher(svc *Service, ttl, interval time.Duration) *IndexRefresher {
+	if ttl <= 0 {
+		ttl = DefaultIndexTTL
+	}
+
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+
+	// index files should not outlive the ttl by much
+	if interval > ttl {
+		interval = ttl
+	}
+
+	return &IndexRefresher{
+		svc:      svc,
+		ttl:      ttl,
+		interval: interval,
+	}
+}
+
+// Run refreshes repositories until context is done.
+func (r *IndexRefresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.svc.RefreshRepos(ctx, r.ttl)
+		}
+	}
+}
@@ -1,20 +1,32 @@
 package repositories
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"k8s.io/helm/pkg/chartutil"
-	"k8s.io/helm/pkg/getter"
-	"k8s.io/helm/pkg/helm/environment"
 	"k8s.io/helm/pkg/helm/helmpath"
 	"k8s.io/helm/pkg/proto/hapi/chart"
 	"k8s.io/helm/pkg/repo"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+const (
+	indexFileName = "index.yaml"
+	fetchTimeout  = time.Minute
 )
 
 var (
@@ -25,8 +37,8 @@ var _ Interface = &Manager{}
 
 // Interface represents an interface for the repositories manager.
 type Interface interface {
-	GetIndexFile(e *repo.Entry) (*repo.IndexFile, error)
-	GetChart(conf repo.Entry, ref string) (*chart.Chart, error)
+	GetIndexFile(conf *model.RepositoryConfig) (*repo.IndexFile, error)
+	GetChart(conf model.RepositoryConfig, ref string) (*chart.Chart, error)
 }
 
 // Manager is responsible for dealing with helm repositories.
@@ -46,19 +58,27 @@ func New(homePath string) (*Manager, error) {
 }
 
 // GetIndexFile retrieves IndexFile for the provided repository.
-func (m Manager) GetIndexFile(conf *repo.Entry) (*repo.IndexFile, error) {
+func (m Manager) GetIndexFile(conf *model.RepositoryConfig) (*repo.IndexFile, error) {
 	if err := m.ensureCacheDir(); err != nil {
 		return nil, err
 	}
 
-	cr, err := repo.NewChartRepository(conf, getter.All(environment.EnvSettings{}))
+	indexURL, err := url.Parse(conf.URL)
 	if err != nil {
-		return nil, errors.Wrap(err, "build chart repository")
+		return nil, errors.Wrapf(err, "parse %s url", conf.URL)
 	}
-	if err = cr.DownloadIndexFile(m.helmHome.CacheIndex(conf.Name)); err != nil {
+	indexURL.Path = path.Join(indexURL.Path, indexFileName)
+
+	data, err := m.fetch(conf, indexURL.String())
+	if err != nil {
 		return nil, errors.Wrap(err, "download index file")
 	}
-	ind, err := repo.LoadIndexFile(m.helmHome.CacheIndex(conf.Name))
+
+	indexPath := m.helmHome.CacheIndex(conf.Name)
+	if err = ioutil.WriteFile(indexPath, data, 0644); err != nil {
+		return nil, errors.Wrapf(err, "write %s index file", indexPath)
+	}
+	ind, err := repo.LoadIndexFile(indexPath)
 	if err != nil {
 		return nil, errors.Wrap(err, "load index file")
 	}
@@ -68,7 +88,7 @@ func (m Manager) GetIndexFile(conf *repo.Entry) (*repo.IndexFile, error) {
 // GetChart retrieves a chart to from the remote repository and
 // stores it to local cache. If chart exists locally it will be
 // read from the cache.
-func (m Manager) GetChart(conf repo.Entry, ref string) (*chart.Chart, error) {
+func (m Manager) GetChart(conf model.RepositoryConfig, ref string) (*chart.Chart, error) {
 	if err := m.ensureCacheDir(); err != nil {
 		return nil, err
 	}
@@ -79,18 +99,18 @@ func (m Manager) GetChart(conf repo.Entry, ref string) (*chart.Chart, error) {
 		return chrt, nil
 	}
 
-	g, err := getter.NewHTTPGetter(ref, conf.CertFile, conf.KeyFile, conf.CAFile)
+	// chart museum and harbor serve urls relative to the repository
+	chrtURL, err := resolveURL(conf.URL, ref)
 	if err != nil {
-		return nil, errors.Wrap(err, "build a http client")
+		return nil, err
 	}
-	g.SetCredentials(conf.Username, conf.Password)
 
-	r, err := g.Get(ref)
+	data, err := m.fetch(&conf, chrtURL)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := ioutil.WriteFile(chrtPath, r.Bytes(), 0644); err != nil {
+	if err := ioutil.WriteFile(chrtPath, data, 0644); err != nil {
 		return nil, errors.Wrapf(err, "write %s chart", chrtPath)
 	}
 
@@ -98,6 +118,113 @@ func (m Manager) GetChart(conf repo.Entry, ref string) (*chart.Chart, error) {
 	return chartutil.LoadFile(chrtPath)
 }
 
+// fetch downloads a file of the repository, credentials are sent only
+// to the host of the repository.
+func (m Manager) fetch(conf *model.RepositoryConfig, rawURL string) ([]byte, error) {
+	client, err := httpClient(conf)
+	if err != nil {
+		return nil, errors.Wrap(err, "build a http client")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "build %s request", rawURL)
+	}
+	if sameHost(conf.URL, rawURL) {
+		if conf.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+conf.Token)
+		} else if conf.Username != "" || conf.Password != "" {
+			req.SetBasicAuth(conf.Username, conf.Password)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get %s", rawURL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, errors.Wrapf(sgerrors.ErrUnauthorized, "get %s: %s", rawURL, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("get %s: %s", rawURL, resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+func httpClient(conf *model.RepositoryConfig) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: conf.InsecureSkipTLSVerify,
+	}
+
+	if conf.CAData != "" || conf.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		caData := []byte(conf.CAData)
+		if conf.CAFile != "" {
+			data, err := ioutil.ReadFile(conf.CAFile)
+			if err != nil {
+				return nil, errors.Wrapf(err, "read %s ca file", conf.CAFile)
+			}
+			caData = append(append(caData, '\n'), data...)
+		}
+
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, errors.New("no certificates found in ca bundle")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if conf.CertFile != "" && conf.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "load client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return &http.Client{
+		Timeout: fetchTimeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}, nil
+}
+
+func resolveURL(baseURL, ref string) (string, error) {
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return "", errors.Wrapf(err, "parse %s url", ref)
+	}
+	if refURL.IsAbs() {
+		return ref, nil
+	}
+
+	base, err := url.Parse(strings.TrimSuffix(baseURL, "/") + "/")
+	if err != nil {
+		return "", errors.Wrapf(err, "parse %s url", baseURL)
+	}
+	return base.ResolveReference(refURL).String(), nil
+}
+
+func sameHost(a, b string) bool {
+	aURL, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	bURL, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return aURL.Host == bURL.Host
+}
+
 // ensureCacheDir creates a filesystem tree like helm does if it
 // doesn't exist. This is used for compatibility with helm libraries.
 func (m Manager) ensureCacheDir() error {
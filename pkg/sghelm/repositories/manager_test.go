@@ -0,0 +1,201 @@
+package repositories
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+const testIndex = `apiVersion: v1
+entries:
+  private:
+  - name: private
+    version: 0.1.0
+    urls:
+    - charts/private-0.1.0.tgz
+`
+
+func privateRepo(t *testing.T, authorized func(*http.Request) bool) *httptest.Server {
+	dir, err := ioutil.TempDir("", "charts")
+	if err != nil {
+		t.Fatalf("create temp dir %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	archive, err := chartutil.Save(&chart.Chart{
+		Metadata: &chart.Metadata{Name: "private", Version: "0.1.0"},
+	}, dir)
+	if err != nil {
+		t.Fatalf("save chart %v", err)
+	}
+	archiveData, err := ioutil.ReadFile(archive)
+	if err != nil {
+		t.Fatalf("read chart %v", err)
+	}
+
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/index.yaml":
+			w.Write([]byte(testIndex))
+		case "/charts/private-0.1.0.tgz":
+			w.Write(archiveData)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestManager_PrivateRepo(t *testing.T) {
+	basicAuth := func(r *http.Request) bool {
+		user, password, ok := r.BasicAuth()
+		return ok && user == "admin" && password == "secret"
+	}
+	tokenAuth := func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer token"
+	}
+
+	testCases := []struct {
+		description string
+		authorized  func(*http.Request) bool
+		conf        model.RepositoryConfig
+		trustServer bool
+
+		expectedErr error
+	}{
+		{
+			description: "basic auth",
+			authorized:  basicAuth,
+			conf:        model.RepositoryConfig{Username: "admin", Password: "secret"},
+			trustServer: true,
+		},
+		{
+			description: "bearer token",
+			authorized:  tokenAuth,
+			conf:        model.RepositoryConfig{Token: "token"},
+			trustServer: true,
+		},
+		{
+			description: "insecure",
+			authorized:  basicAuth,
+			conf: model.RepositoryConfig{Username: "admin", Password: "secret",
+				InsecureSkipTLSVerify: true},
+		},
+		{
+			description: "wrong credentials",
+			authorized:  basicAuth,
+			conf:        model.RepositoryConfig{Username: "admin", Password: "wrong"},
+			trustServer: true,
+			expectedErr: sgerrors.ErrUnauthorized,
+		},
+		{
+			description: "untrusted certificate",
+			authorized:  basicAuth,
+			conf:        model.RepositoryConfig{Username: "admin", Password: "secret"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		home, err := ioutil.TempDir("", "helm")
+		if err != nil {
+			t.Fatalf("create temp dir %v", err)
+		}
+
+		m, err := New(home)
+		if err != nil {
+			t.Fatalf("new manager %v", err)
+		}
+
+		srv := privateRepo(t, testCase.authorized)
+
+		conf := testCase.conf
+		conf.Name, conf.URL = "private", srv.URL
+		if testCase.trustServer {
+			conf.CAData = string(pem.EncodeToMemory(&pem.Block{
+				Type:  "CERTIFICATE",
+				Bytes: srv.Certificate().Raw,
+			}))
+		}
+
+		ind, err := m.GetIndexFile(&conf)
+
+		switch {
+		case testCase.expectedErr != nil:
+			if errors.Cause(err) != testCase.expectedErr {
+				t.Errorf("Expected error %v actual %v", testCase.expectedErr, err)
+			}
+		case !testCase.trustServer && !conf.InsecureSkipTLSVerify:
+			if err == nil {
+				t.Errorf("Certificate of the repository must be verified")
+			}
+		case err != nil:
+			t.Errorf("Unexpected error %v", err)
+		default:
+			versions := ind.Entries["private"]
+			if len(versions) != 1 {
+				t.Errorf("Expected chart in the index %v", ind.Entries)
+				break
+			}
+
+			chrt, err := m.GetChart(conf, versions[0].URLs[0])
+			if err != nil || chrt.GetMetadata().GetName() != "private" {
+				t.Errorf("Expected private chart actual %v %v", chrt, err)
+			}
+
+			if _, err := os.Stat(filepath.Join(home, "cache", "archive", "private-0.1.0.tgz")); err != nil {
+				t.Errorf("Chart must be cached %v", err)
+			}
+		}
+
+		srv.Close()
+		os.RemoveAll(home)
+	}
+}
+
+func TestResolveURL(t *testing.T) {
+	testCases := []struct {
+		base     string
+		ref      string
+		expected string
+	}{
+		{
+			base:     "https://harbor.example.com/chartrepo/library",
+			ref:      "charts/nginx-0.1.0.tgz",
+			expected: "https://harbor.example.com/chartrepo/library/charts/nginx-0.1.0.tgz",
+		},
+		{
+			base:     "https://charts.example.com/",
+			ref:      "nginx-0.1.0.tgz",
+			expected: "https://charts.example.com/nginx-0.1.0.tgz",
+		},
+		{
+			base:     "https://charts.example.com",
+			ref:      "https://storage.example.com/nginx-0.1.0.tgz",
+			expected: "https://storage.example.com/nginx-0.1.0.tgz",
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := resolveURL(testCase.base, testCase.ref)
+		if err != nil || actual != testCase.expected {
+			t.Errorf("Expected %s actual %s %v", testCase.expected, actual, err)
+		}
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/Masterminds/semver"
 	"github.com/imdario/mergo"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	"k8s.io/helm/pkg/proto/hapi/chart"
 	"k8s.io/helm/pkg/repo"
 
@@ -23,46 +26,60 @@ const (
 	repoPrefix = "/helm/repositories/"
 )
 
+// StoragePrefixes are prefixes the service keeps its data under.
+var StoragePrefixes = []string{repoPrefix, keyPrefix}
+
 var _ Servicer = &Service{}
 
 // Servicer is an interface for the helm service.
 type Servicer interface {
-	CreateRepo(ctx context.Context, e *repo.Entry) (*model.RepositoryInfo, error)
-	UpdateRepo(ctx context.Context, name string, opts *repo.Entry) (*model.RepositoryInfo, error)
+	CreateRepo(ctx context.Context, e *model.RepositoryConfig) (*model.RepositoryInfo, error)
+	UpdateRepo(ctx context.Context, name string, opts *model.RepositoryConfig) (*model.RepositoryInfo, error)
 	GetRepo(ctx context.Context, repoName string) (*model.RepositoryInfo, error)
 	ListRepos(ctx context.Context) ([]model.RepositoryInfo, error)
 	DeleteRepo(ctx context.Context, repoName string) (*model.RepositoryInfo, error)
 	GetChartData(ctx context.Context, repoName, chartName, chartVersion string) (*model.ChartData, error)
 	ListCharts(ctx context.Context, repoName string) ([]model.ChartInfo, error)
 	GetChart(ctx context.Context, repoName, chartName, chartVersion string) (*chart.Chart, error)
+	ListChartVersions(ctx context.Context, repoName, chartName string) ([]model.ChartVersion, error)
+	AnnotateReleases(ctx context.Context, releases []*model.ReleaseInfo) error
 }
 
 // Service manages helm repositories.
 type Service struct {
 	storage storage.Interface
 	repos   repositories.Interface
+	// Key credentials of repositories are encrypted with
+	key []byte
 }
 
-// NewService constructs a Service for helm repository.
-func NewService(s storage.Interface) (*Service, error) {
+// NewService constructs a Service for helm repository, credentials of
+// repositories are encrypted with a key derived from the secret.
+func NewService(s storage.Interface, secret string) (*Service, error) {
 	repos, err := repositories.New(repositories.DefaultHome)
 	if err != nil {
 		return nil, errors.Wrap(err, "setup repositories manager")
 	}
 
+	key, err := loadKey(context.Background(), s, secret)
+	if err != nil {
+		return nil, errors.Wrap(err, "load credentials key")
+	}
+
 	return &Service{
 		storage: s,
 		repos:   repos,
+		key:     key,
 	}, nil
 }
 
 // CreateRepo stores a helm repository in the provided storage.
-func (s Service) CreateRepo(ctx context.Context, e *repo.Entry) (*model.RepositoryInfo, error) {
+func (s Service) CreateRepo(ctx context.Context, e *model.RepositoryConfig) (*model.RepositoryInfo, error) {
 	if e == nil {
 		return nil, sgerrors.ErrNilEntity
 	}
 
-	r, err := s.GetRepo(ctx, e.Name)
+	r, err := s.getRepo(ctx, e.Name)
 	if err != nil && !sgerrors.IsNotFound(err) {
 		return nil, err
 	}
@@ -77,27 +94,24 @@ func (s Service) CreateRepo(ctx context.Context, e *repo.Entry) (*model.Reposito
 
 	// store the index file
 	r = toRepoInfo(e, ind)
-	rawJSON, err := json.Marshal(r)
-	if err != nil {
-		return nil, errors.Wrap(err, "marshal index file")
-	}
-	if err = s.storage.Put(ctx, repoPrefix, e.Name, rawJSON); err != nil {
-		return nil, errors.Wrap(err, "storage")
+	r.RefreshedAt = time.Now()
+	if err = s.putRepo(ctx, r); err != nil {
+		return nil, err
 	}
 
-	return r, nil
+	return redact(r), nil
 }
 
 // UpdateRepo downloads the latest index file and update a helm repository in the provided storage.
-func (s Service) UpdateRepo(ctx context.Context, name string, opts *repo.Entry) (*model.RepositoryInfo, error) {
-	r, err := s.GetRepo(ctx, name)
+func (s Service) UpdateRepo(ctx context.Context, name string, opts *model.RepositoryConfig) (*model.RepositoryInfo, error) {
+	r, err := s.getRepo(ctx, name)
 	if err != nil {
 		return nil, err
 	}
 
 	// mergo panics on nil entry
 	if opts == nil {
-		opts = &repo.Entry{}
+		opts = &model.RepositoryConfig{}
 	}
 	opts.Name = "" // prevent updating the repo name
 	// merge configs
@@ -112,34 +126,23 @@ func (s Service) UpdateRepo(ctx context.Context, name string, opts *repo.Entry)
 
 	// store the index file
 	r = toRepoInfo(&r.Config, ind)
-	rawJSON, err := json.Marshal(r)
-	if err != nil {
-		return nil, errors.Wrap(err, "marshal index file")
-	}
-	if err = s.storage.Put(ctx, repoPrefix, name, rawJSON); err != nil {
-		return nil, errors.Wrap(err, "storage")
+	r.RefreshedAt = time.Now()
+	if err = s.putRepo(ctx, r); err != nil {
+		return nil, err
 	}
 
-	return r, nil
+	return redact(r), nil
 }
 
-// GetRepo retrieves the repository index file for provided nam.
+// GetRepo retrieves the repository index file for provided nam,
+// credentials of the repository are redacted.
 func (s Service) GetRepo(ctx context.Context, repoName string) (*model.RepositoryInfo, error) {
-	res, err := s.storage.Get(ctx, repoPrefix, repoName)
+	r, err := s.getRepo(ctx, repoName)
 	if err != nil {
-		return nil, errors.Wrap(err, "storage")
-	}
-	// not found
-	if res == nil {
-		return nil, errors.Wrap(sgerrors.ErrNotFound, "repo not found")
-	}
-
-	r := &model.RepositoryInfo{}
-	if err = json.Unmarshal(res, r); err != nil {
-		return nil, errors.Wrap(err, "unmarshal")
+		return nil, err
 	}
 
-	return r, nil
+	return redact(r), nil
 }
 
 // ListRepos retrieves all helm repositories from the storage.
@@ -151,12 +154,12 @@ func (s Service) ListRepos(ctx context.Context) ([]model.RepositoryInfo, error)
 
 	repos := make([]model.RepositoryInfo, len(rawRepos))
 	for i, raw := range rawRepos {
-		r := &model.RepositoryInfo{}
+		r := &storedRepo{}
 		err = json.Unmarshal(raw, r)
 		if err != nil {
 			return nil, errors.Wrap(err, "unmarshal")
 		}
-		repos[i] = *r
+		repos[i] = *redact(&r.RepositoryInfo)
 	}
 
 	return repos, nil
@@ -171,6 +174,84 @@ func (s Service) DeleteRepo(ctx context.Context, repoName string) (*model.Reposi
 	return hrepo, s.storage.Delete(ctx, repoPrefix, repoName)
 }
 
+// RefreshRepos downloads index files of repositories refreshed longer than
+// ttl ago, repositories that fail to refresh are skipped.
+func (s Service) RefreshRepos(ctx context.Context, ttl time.Duration) {
+	repos, err := s.ListRepos(ctx)
+	if err != nil {
+		logrus.Errorf("helm: refresh repositories: %v", err)
+		return
+	}
+
+	for _, r := range repos {
+		if time.Since(r.RefreshedAt) < ttl {
+			continue
+		}
+
+		if _, err := s.UpdateRepo(ctx, r.Config.Name, nil); err != nil {
+			logrus.Errorf("helm: refresh %s repository: %v", r.Config.Name, err)
+			continue
+		}
+		logrus.Debugf("helm: %s repository has been refreshed", r.Config.Name)
+	}
+}
+
+// getRepo retrieves the repository with its decrypted credentials.
+func (s Service) getRepo(ctx context.Context, repoName string) (*model.RepositoryInfo, error) {
+	res, err := s.storage.Get(ctx, repoPrefix, repoName)
+	if err != nil {
+		return nil, errors.Wrap(err, "storage")
+	}
+	// not found
+	if res == nil {
+		return nil, errors.Wrap(sgerrors.ErrNotFound, "repo not found")
+	}
+
+	r := &storedRepo{}
+	if err = json.Unmarshal(res, r); err != nil {
+		return nil, errors.Wrap(err, "unmarshal")
+	}
+
+	// repositories stored before encryption keep plain credentials
+	if r.Credentials != "" {
+		creds, err := unseal(s.key, r.Credentials)
+		if err != nil {
+			return nil, errors.Wrapf(err, "credentials of %s repository", repoName)
+		}
+		r.Config.Password, r.Config.Token = creds.Password, creds.Token
+	}
+
+	return &r.RepositoryInfo, nil
+}
+
+// putRepo stores the repository, its credentials are encrypted.
+func (s Service) putRepo(ctx context.Context, r *model.RepositoryInfo) error {
+	stored := &storedRepo{
+		RepositoryInfo: *redact(r),
+	}
+
+	if r.Config.Password != "" || r.Config.Token != "" {
+		sealed, err := seal(s.key, credentials{
+			Password: r.Config.Password,
+			Token:    r.Config.Token,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "credentials of %s repository", r.Config.Name)
+		}
+		stored.Credentials = sealed
+	}
+
+	rawJSON, err := json.Marshal(stored)
+	if err != nil {
+		return errors.Wrap(err, "marshal index file")
+	}
+	if err = s.storage.Put(ctx, repoPrefix, r.Config.Name, rawJSON); err != nil {
+		return errors.Wrap(err, "storage")
+	}
+
+	return nil
+}
+
 func (s Service) GetChartData(ctx context.Context, repoName, chartName, chartVersion string) (*model.ChartData, error) {
 	chrt, err := s.GetChart(ctx, repoName, chartName, chartVersion)
 	if err != nil {
@@ -180,7 +261,7 @@ func (s Service) GetChartData(ctx context.Context, repoName, chartName, chartVer
 }
 
 func (s Service) ListCharts(ctx context.Context, repoName string) ([]model.ChartInfo, error) {
-	hrepo, err := s.GetRepo(ctx, repoName)
+	hrepo, err := s.getRepo(ctx, repoName)
 	if err != nil {
 		return nil, errors.Wrapf(err, "get %s repository info", repoName)
 	}
@@ -188,11 +269,56 @@ func (s Service) ListCharts(ctx context.Context, repoName string) ([]model.Chart
 	return hrepo.Charts, nil
 }
 
-func (s Service) GetChart(ctx context.Context, repoName, chartName, chartVersion string) (*chart.Chart, error) {
+// ListChartVersions returns versions of the chart from the cached index
+// of the repository, the latest go first.
+func (s Service) ListChartVersions(ctx context.Context, repoName, chartName string) ([]model.ChartVersion, error) {
 	hrepo, err := s.GetRepo(ctx, repoName)
 	if err != nil {
 		return nil, errors.Wrapf(err, "get %s repository info", repoName)
 	}
+
+	for _, chrt := range hrepo.Charts {
+		if chrt.Name == chartName {
+			return chrt.Versions, nil
+		}
+	}
+
+	return nil, errors.Wrapf(sgerrors.ErrNotFound, "%s chart", chartName)
+}
+
+// AnnotateReleases sets the latest version of charts of the releases found
+// in cached indexes of repositories and whether releases can be upgraded
+// to it. Charts are matched by name since releases do not keep repositories.
+func (s Service) AnnotateReleases(ctx context.Context, releases []*model.ReleaseInfo) error {
+	repos, err := s.ListRepos(ctx)
+	if err != nil {
+		return errors.Wrap(err, "list repositories")
+	}
+
+	latest := latestVersions(repos)
+	for _, rls := range releases {
+		if rls == nil {
+			continue
+		}
+
+		v, ok := latest[rls.Chart]
+		if !ok {
+			continue
+		}
+
+		rls.LatestVersion = v.Original()
+		current, err := semver.NewVersion(rls.ChartVersion)
+		rls.UpgradeAvailable = err == nil && v.GreaterThan(current)
+	}
+
+	return nil
+}
+
+func (s Service) GetChart(ctx context.Context, repoName, chartName, chartVersion string) (*chart.Chart, error) {
+	hrepo, err := s.getRepo(ctx, repoName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get %s repository info", repoName)
+	}
 	ref, err := findChartURL(hrepo.Charts, chartName, chartVersion)
 	if err != nil {
 		return nil, errors.Wrapf(err, "get %s(%s) chart", chartName, chartVersion)
@@ -272,7 +398,7 @@ func findChartVersion(chrtVers []model.ChartVersion, version string) model.Chart
 	return model.ChartVersion{}
 }
 
-func toRepoInfo(e *repo.Entry, index *repo.IndexFile) *model.RepositoryInfo {
+func toRepoInfo(e *model.RepositoryConfig, index *repo.IndexFile) *model.RepositoryInfo {
 	r := &model.RepositoryInfo{
 		Config: *e,
 	}
@@ -313,6 +439,29 @@ func toRepoInfo(e *repo.Entry, index *repo.IndexFile) *model.RepositoryInfo {
 	return r
 }
 
+// latestVersions returns the latest stable version of every chart
+// of the repositories.
+func latestVersions(repos []model.RepositoryInfo) map[string]*semver.Version {
+	latest := make(map[string]*semver.Version)
+
+	for _, r := range repos {
+		for _, chrt := range r.Charts {
+			for _, cv := range chrt.Versions {
+				v, err := semver.NewVersion(cv.Version)
+				if err != nil || v.Prerelease() != "" {
+					continue
+				}
+
+				if current, ok := latest[chrt.Name]; !ok || v.GreaterThan(current) {
+					latest[chrt.Name] = v
+				}
+			}
+		}
+	}
+
+	return latest
+}
+
 func iconFrom(cvs repo.ChartVersions) string {
 	// chartVersions are sorted, use the latest one
 	if len(cvs) > 0 {
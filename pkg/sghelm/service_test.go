@@ -2,8 +2,10 @@ package sghelm
 
 import (
 	"context"
+	"encoding/json"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -13,6 +15,7 @@ import (
 
 	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/storage/memory"
 )
 
 type fakeRepoManager struct {
@@ -21,10 +24,10 @@ type fakeRepoManager struct {
 	err   error
 }
 
-func (m fakeRepoManager) GetIndexFile(e *repo.Entry) (*repo.IndexFile, error) {
+func (m fakeRepoManager) GetIndexFile(e *model.RepositoryConfig) (*repo.IndexFile, error) {
 	return m.index, m.err
 }
-func (m fakeRepoManager) GetChart(conf repo.Entry, ref string) (*chart.Chart, error) {
+func (m fakeRepoManager) GetChart(conf model.RepositoryConfig, ref string) (*chart.Chart, error) {
 	return m.chrt, m.err
 }
 
@@ -59,7 +62,7 @@ func TestService_CreateRepo(t *testing.T) {
 	defer logrus.SetOutput(loggerWriter)
 
 	tcs := []struct {
-		repoConf *repo.Entry
+		repoConf *model.RepositoryConfig
 
 		storage fakeStorage
 		repos   fakeRepoManager
@@ -72,7 +75,7 @@ func TestService_CreateRepo(t *testing.T) {
 			expectedErr: sgerrors.ErrNilEntity,
 		},
 		{ // TC#2
-			repoConf: &repo.Entry{
+			repoConf: &model.RepositoryConfig{
 				Name: "storageError",
 			},
 			storage: fakeStorage{
@@ -81,7 +84,7 @@ func TestService_CreateRepo(t *testing.T) {
 			expectedErr: errFake,
 		},
 		{ // TC#3
-			repoConf: &repo.Entry{
+			repoConf: &model.RepositoryConfig{
 				Name: "alreadyExists",
 			},
 			storage: fakeStorage{
@@ -90,7 +93,7 @@ func TestService_CreateRepo(t *testing.T) {
 			expectedErr: sgerrors.ErrAlreadyExists,
 		},
 		{ // TC#4
-			repoConf: &repo.Entry{
+			repoConf: &model.RepositoryConfig{
 				Name: "getIndexFileError",
 			},
 			repos: fakeRepoManager{
@@ -99,7 +102,7 @@ func TestService_CreateRepo(t *testing.T) {
 			expectedErr: errFake,
 		},
 		{ // TC#5
-			repoConf: &repo.Entry{
+			repoConf: &model.RepositoryConfig{
 				Name: "putError",
 			},
 			storage: fakeStorage{
@@ -108,20 +111,20 @@ func TestService_CreateRepo(t *testing.T) {
 			expectedErr: errFake,
 		},
 		{ // TC#6
-			repoConf: &repo.Entry{
+			repoConf: &model.RepositoryConfig{
 				Name: "emptyIndex",
 			},
 			repos: fakeRepoManager{
 				index: &repo.IndexFile{},
 			},
 			expectedRepo: &model.RepositoryInfo{
-				Config: repo.Entry{
+				Config: model.RepositoryConfig{
 					Name: "emptyIndex",
 				},
 			},
 		},
 		{ // TC#7
-			repoConf: &repo.Entry{
+			repoConf: &model.RepositoryConfig{
 				Name: "success",
 			},
 			repos: fakeRepoManager{
@@ -166,7 +169,7 @@ func TestService_CreateRepo(t *testing.T) {
 				},
 			},
 			expectedRepo: &model.RepositoryInfo{
-				Config: repo.Entry{
+				Config: model.RepositoryConfig{
 					Name: "success",
 				},
 				Charts: []model.ChartInfo{
@@ -209,6 +212,8 @@ func TestService_CreateRepo(t *testing.T) {
 		require.Equalf(t, tc.expectedErr, errors.Cause(err), "TC#%d: check errors", i+1)
 
 		if err == nil {
+			require.Falsef(t, hrepo.RefreshedAt.IsZero(), "TC#%d: check refresh time", i+1)
+			hrepo.RefreshedAt = time.Time{}
 			require.Equalf(t, tc.expectedRepo, hrepo, "TC#%d: check results", i+1)
 		}
 	}
@@ -263,7 +268,7 @@ func TestService_UpdateRepo(t *testing.T) {
 		name string
 
 		repoName string
-		repoConf *repo.Entry
+		repoConf *model.RepositoryConfig
 
 		storage fakeStorage
 		repos   fakeRepoManager
@@ -313,7 +318,7 @@ func TestService_UpdateRepo(t *testing.T) {
 				index: &repoIndexFile,
 			},
 			expectedRepo: &model.RepositoryInfo{
-				Config: repo.Entry{
+				Config: model.RepositoryConfig{
 					Name: "updateRepoIndex",
 				},
 				Charts: []model.ChartInfo{
@@ -347,7 +352,7 @@ func TestService_UpdateRepo(t *testing.T) {
 		{
 			name:     "update_repo",
 			repoName: "updateRepoURL",
-			repoConf: &repo.Entry{
+			repoConf: &model.RepositoryConfig{
 				Name: "ignoreNewRepoName",
 				URL:  "url",
 			},
@@ -355,7 +360,7 @@ func TestService_UpdateRepo(t *testing.T) {
 				item: []byte(`{"config":{"name":"updateRepoURL"}}`),
 			},
 			expectedRepo: &model.RepositoryInfo{
-				Config: repo.Entry{
+				Config: model.RepositoryConfig{
 					Name: "updateRepoURL",
 					URL:  "url",
 				},
@@ -373,6 +378,8 @@ func TestService_UpdateRepo(t *testing.T) {
 		require.Equalf(t, tc.expectedErr, errors.Cause(err), "TC#%s: check errors", tc.name)
 
 		if err == nil {
+			require.Falsef(t, hrepo.RefreshedAt.IsZero(), "TC#%s: check refresh time", tc.name)
+			hrepo.RefreshedAt = time.Time{}
 			require.Equalf(t, tc.expectedRepo, hrepo, "TC#%s: check results", tc.name)
 		}
 	}
@@ -413,7 +420,7 @@ func TestService_GetRepo(t *testing.T) {
 				item: []byte(`{"config":{"name":"success"}}`),
 			},
 			expectedRepo: &model.RepositoryInfo{
-				Config: repo.Entry{
+				Config: model.RepositoryConfig{
 					Name: "success",
 				},
 			},
@@ -466,7 +473,7 @@ func TestService_ListRepo(t *testing.T) {
 			},
 			expectedRepos: []model.RepositoryInfo{
 				{
-					Config: repo.Entry{
+					Config: model.RepositoryConfig{
 						Name: "success",
 					},
 				},
@@ -525,7 +532,7 @@ func TestService_DeleteRepo(t *testing.T) {
 				item: []byte(`{"config":{"name":"success"}}`),
 			},
 			expectedRepo: &model.RepositoryInfo{
-				Config: repo.Entry{
+				Config: model.RepositoryConfig{
 					Name: "success",
 				},
 			},
@@ -550,6 +557,233 @@ func TestService_DeleteRepo(t *testing.T) {
 	}
 }
 
+func TestService_Credentials(t *testing.T) {
+	loggerWriter := logrus.StandardLogger().Out
+	logrus.SetOutput(ioutil.Discard)
+	defer logrus.SetOutput(loggerWriter)
+
+	st := memory.NewInMemoryRepository()
+	key, err := loadKey(context.Background(), st, "")
+	require.Nil(t, err, "generate key")
+
+	again, err := loadKey(context.Background(), st, "")
+	require.Nil(t, err, "load key")
+	require.Equal(t, key, again, "generated key must be kept")
+
+	svc := Service{
+		storage: st,
+		repos:   &fakeRepoManager{index: &repo.IndexFile{}},
+		key:     key,
+	}
+
+	hrepo, err := svc.CreateRepo(context.Background(), &model.RepositoryConfig{
+		Name:     "private",
+		URL:      "https://charts.example.com",
+		Username: "admin",
+		Password: "secret",
+		Token:    "token",
+	})
+	require.Nil(t, err, "create repo")
+	require.Equal(t, "", hrepo.Config.Password, "password must be redacted")
+	require.Equal(t, "", hrepo.Config.Token, "token must be redacted")
+
+	raw, err := st.Get(context.Background(), repoPrefix, "private")
+	require.Nil(t, err, "get stored repo")
+	require.NotContains(t, string(raw), "secret", "password must be encrypted")
+	require.NotContains(t, string(raw), `"token"`, "token must be encrypted")
+
+	hrepo, err = svc.GetRepo(context.Background(), "private")
+	require.Nil(t, err, "get repo")
+	require.Equal(t, "admin", hrepo.Config.Username)
+	require.Equal(t, "", hrepo.Config.Password, "password must be redacted")
+
+	repos, err := Service{storage: &fakeStorage{items: [][]byte{raw}}}.ListRepos(context.Background())
+	require.Nil(t, err, "list repos")
+	require.Len(t, repos, 1)
+	require.Equal(t, "", repos[0].Config.Password, "password must be redacted")
+
+	hrepo, err = svc.getRepo(context.Background(), "private")
+	require.Nil(t, err, "get repo with credentials")
+	require.Equal(t, "secret", hrepo.Config.Password)
+	require.Equal(t, "token", hrepo.Config.Token)
+
+	// credentials are kept on updates without them
+	_, err = svc.UpdateRepo(context.Background(), "private", &model.RepositoryConfig{Username: "user"})
+	require.Nil(t, err, "update repo")
+	hrepo, err = svc.getRepo(context.Background(), "private")
+	require.Nil(t, err, "get updated repo")
+	require.Equal(t, "user", hrepo.Config.Username)
+	require.Equal(t, "secret", hrepo.Config.Password)
+
+	svc.key, err = loadKey(context.Background(), st, "other")
+	require.Nil(t, err, "derive key")
+	_, err = svc.getRepo(context.Background(), "private")
+	require.NotNil(t, err, "credentials must not be decrypted with other key")
+}
+
+type countingRepoManager struct {
+	fakeRepoManager
+	calls *int
+}
+
+func (m countingRepoManager) GetIndexFile(e *model.RepositoryConfig) (*repo.IndexFile, error) {
+	*m.calls++
+	return m.fakeRepoManager.GetIndexFile(e)
+}
+
+func repoItems(t *testing.T, repos ...model.RepositoryInfo) [][]byte {
+	items := make([][]byte, 0, len(repos))
+	for _, r := range repos {
+		raw, err := json.Marshal(r)
+		require.Nil(t, err, "marshal repo")
+		items = append(items, raw)
+	}
+	return items
+}
+
+func TestService_ListChartVersions(t *testing.T) {
+	items := repoItems(t, model.RepositoryInfo{
+		Config: model.RepositoryConfig{Name: "stable"},
+		Charts: []model.ChartInfo{
+			{
+				Name: "nginx",
+				Versions: []model.ChartVersion{
+					{Version: "1.1.0", AppVersion: "1.17"},
+					{Version: "1.0.0", AppVersion: "1.16"},
+				},
+			},
+		},
+	})
+
+	tcs := []struct {
+		storage   fakeStorage
+		chartName string
+
+		expectedVersions []string
+		expectedErr      error
+	}{
+		{ // TC#1
+			storage:     fakeStorage{getErr: sgerrors.ErrNotFound},
+			chartName:   "nginx",
+			expectedErr: sgerrors.ErrNotFound,
+		},
+		{ // TC#2
+			storage:     fakeStorage{item: items[0]},
+			chartName:   "redis",
+			expectedErr: sgerrors.ErrNotFound,
+		},
+		{ // TC#3
+			storage:          fakeStorage{item: items[0]},
+			chartName:        "nginx",
+			expectedVersions: []string{"1.1.0", "1.0.0"},
+		},
+	}
+
+	for i, tc := range tcs {
+		svc := Service{
+			storage: &tc.storage,
+		}
+
+		versions, err := svc.ListChartVersions(context.Background(), "stable", tc.chartName)
+		require.Equalf(t, tc.expectedErr, errors.Cause(err), "TC#%d: check errors", i+1)
+
+		if err == nil {
+			actual := make([]string, 0, len(versions))
+			for _, v := range versions {
+				actual = append(actual, v.Version)
+			}
+			require.Equalf(t, tc.expectedVersions, actual, "TC#%d: check versions", i+1)
+			require.Equalf(t, "1.17", versions[0].AppVersion, "TC#%d: check app version", i+1)
+		}
+	}
+}
+
+func TestService_AnnotateReleases(t *testing.T) {
+	items := repoItems(t,
+		model.RepositoryInfo{
+			Config: model.RepositoryConfig{Name: "stable"},
+			Charts: []model.ChartInfo{
+				{
+					Name: "nginx",
+					Versions: []model.ChartVersion{
+						{Version: "2.0.0-rc.1"},
+						{Version: "1.2.0"},
+						{Version: "1.0.0"},
+					},
+				},
+				{
+					Name:     "redis",
+					Versions: []model.ChartVersion{{Version: "3.0.0"}},
+				},
+			},
+		},
+		model.RepositoryInfo{
+			Config: model.RepositoryConfig{Name: "incubator"},
+			Charts: []model.ChartInfo{
+				{
+					Name:     "nginx",
+					Versions: []model.ChartVersion{{Version: "1.10.0"}, {Version: "invalid"}},
+				},
+			},
+		},
+	)
+
+	releases := []*model.ReleaseInfo{
+		{Name: "outdated", Chart: "nginx", ChartVersion: "1.2.0"},
+		{Name: "latest", Chart: "redis", ChartVersion: "3.0.0"},
+		{Name: "unknown", Chart: "mysql", ChartVersion: "1.0.0"},
+		nil,
+	}
+
+	svc := Service{
+		storage: &fakeStorage{items: items},
+	}
+
+	require.Nil(t, svc.AnnotateReleases(context.Background(), releases), "annotate releases")
+
+	require.Equal(t, "1.10.0", releases[0].LatestVersion, "latest stable version across repositories")
+	require.True(t, releases[0].UpgradeAvailable, "outdated release must be upgradable")
+	require.Equal(t, "3.0.0", releases[1].LatestVersion)
+	require.False(t, releases[1].UpgradeAvailable, "latest release must not be upgradable")
+	require.Equal(t, "", releases[2].LatestVersion, "unknown chart must not be annotated")
+	require.False(t, releases[2].UpgradeAvailable)
+
+	svc.storage = &fakeStorage{listErr: errFake}
+	require.Equal(t, errFake, errors.Cause(svc.AnnotateReleases(context.Background(), releases)))
+}
+
+func TestService_RefreshRepos(t *testing.T) {
+	loggerWriter := logrus.StandardLogger().Out
+	logrus.SetOutput(ioutil.Discard)
+	defer logrus.SetOutput(loggerWriter)
+
+	items := repoItems(t,
+		model.RepositoryInfo{
+			Config:      model.RepositoryConfig{Name: "stale"},
+			RefreshedAt: time.Now().Add(-2 * time.Hour),
+		},
+		model.RepositoryInfo{
+			Config:      model.RepositoryConfig{Name: "fresh"},
+			RefreshedAt: time.Now(),
+		},
+	)
+
+	calls := 0
+	svc := Service{
+		storage: &fakeStorage{item: items[0], items: items},
+		repos: countingRepoManager{
+			fakeRepoManager: fakeRepoManager{index: &repo.IndexFile{}},
+			calls:           &calls,
+		},
+	}
+
+	svc.RefreshRepos(context.Background(), time.Hour)
+	require.Equal(t, 1, calls, "only stale repositories must be refreshed")
+
+	svc.RefreshRepos(context.Background(), 0)
+	require.Equal(t, 3, calls, "repositories older than ttl must be refreshed")
+}
+
 func Test_iconFrom(t *testing.T) {
 	tcs := []struct {
 		in       repo.ChartVersions
@@ -0,0 +1,218 @@
+// Package bolt keeps values in a single bolt database file, it is meant
+// for installs of control running on a single node.
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/etcd-io/bbolt"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// Bucket of keys stored without prefix
+const rootBucket = "\x00"
+
+// How long to wait for the lock of a database file used by another process
+const openTimeout = 5 * time.Second
+
+// Repository keeps keys of each prefix in a bucket named after the prefix,
+// keys of nested prefixes are kept in buckets of their own.
+type Repository struct {
+	db *bbolt.DB
+}
+
+func NewRepository(fileName string) (*Repository, error) {
+	db, err := bbolt.Open(fileName, 0600, &bbolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, errors.Wrapf(err, "open %s", fileName)
+	}
+
+	return &Repository{
+		db: db,
+	}, nil
+}
+
+// Close releases the database file.
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+func (r *Repository) Get(ctx context.Context, prefix string, key string) ([]byte, error) {
+	var value []byte
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketName(prefix))
+		if bucket == nil {
+			return sgerrors.ErrNotFound
+		}
+
+		v := bucket.Get([]byte(key))
+		if v == nil {
+			return sgerrors.ErrNotFound
+		}
+
+		// values are valid only during the transaction
+		value = append([]byte{}, v...)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func (r *Repository) Put(ctx context.Context, prefix string, key string, value []byte) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		return put(tx, prefix, key, value)
+	})
+}
+
+// PutAll writes values by keys by prefixes in one transaction.
+func (r *Repository) PutAll(ctx context.Context, values map[string]map[string][]byte) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		for prefix, kv := range values {
+			for key, value := range kv {
+				if err := put(tx, prefix, key, value); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// CompareAndSwap puts value of the key when its stored value equals to old.
+func (r *Repository) CompareAndSwap(ctx context.Context, prefix string, key string, old, value []byte) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		var current []byte
+		if bucket := tx.Bucket(bucketName(prefix)); bucket != nil {
+			current = bucket.Get([]byte(key))
+		}
+
+		if (current != nil) != (old != nil) || !bytes.Equal(current, old) {
+			return errors.Wrapf(sgerrors.ErrConflict, "%s%s", prefix, key)
+		}
+
+		return put(tx, prefix, key, value)
+	})
+}
+
+func (r *Repository) Delete(ctx context.Context, prefix string, key string) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		name := bucketName(prefix)
+
+		bucket := tx.Bucket(name)
+		if bucket == nil {
+			return nil
+		}
+
+		if err := bucket.Delete([]byte(key)); err != nil {
+			return errors.Wrapf(err, "delete %s%s", prefix, key)
+		}
+
+		// Buckets of prefixes like task index of a kube are dropped
+		// along with the last key
+		if k, _ := bucket.Cursor().First(); k == nil {
+			return tx.DeleteBucket(name)
+		}
+
+		return nil
+	})
+}
+
+func (r *Repository) GetAll(ctx context.Context, prefix string) ([][]byte, error) {
+	values := make([][]byte, 0)
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		return forEach(tx, prefix, func(bucketPrefix string, k, v []byte) {
+			values = append(values, append([]byte{}, v...))
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// Export returns values by keys starting with the prefix,
+// keys are the rest of the whole key following the prefix.
+func (r *Repository) Export(ctx context.Context, prefix string) (map[string][]byte, error) {
+	values := make(map[string][]byte)
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		return forEach(tx, prefix, func(bucketPrefix string, k, v []byte) {
+			values[strings.TrimPrefix(bucketPrefix+string(k), prefix)] = append([]byte{}, v...)
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+func put(tx *bbolt.Tx, prefix, key string, value []byte) error {
+	bucket, err := tx.CreateBucketIfNotExists(bucketName(prefix))
+	if err != nil {
+		return errors.Wrapf(err, "create bucket %s", prefix)
+	}
+
+	if err = bucket.Put([]byte(key), value); err != nil {
+		return errors.Wrapf(err, "put %s%s", prefix, key)
+	}
+
+	return nil
+}
+
+// forEach calls fn for keys starting with the prefix, they are either
+// keys of buckets of nested prefixes or keys of the bucket of the prefix
+// starting with the rest of it.
+func forEach(tx *bbolt.Tx, prefix string, fn func(bucketPrefix string, k, v []byte)) error {
+	return tx.ForEach(func(name []byte, bucket *bbolt.Bucket) error {
+		bucketPrefix := prefixOf(name)
+
+		switch {
+		case strings.HasPrefix(bucketPrefix, prefix):
+			return bucket.ForEach(func(k, v []byte) error {
+				fn(bucketPrefix, k, v)
+				return nil
+			})
+		case strings.HasPrefix(prefix, bucketPrefix):
+			keyPrefix := []byte(strings.TrimPrefix(prefix, bucketPrefix))
+			c := bucket.Cursor()
+
+			for k, v := c.Seek(keyPrefix); k != nil && bytes.HasPrefix(k, keyPrefix); k, v = c.Next() {
+				fn(bucketPrefix, k, v)
+			}
+		}
+
+		return nil
+	})
+}
+
+func bucketName(prefix string) []byte {
+	if prefix == "" {
+		return []byte(rootBucket)
+	}
+
+	return []byte(prefix)
+}
+
+func prefixOf(bucketName []byte) string {
+	if string(bucketName) == rootBucket {
+		return ""
+	}
+
+	return string(bucketName)
+}
@@ -0,0 +1,71 @@
+package bolt
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/etcd-io/bbolt"
+
+	"github.com/supergiant/control/pkg/testutils/storage"
+)
+
+func newTestRepository(t *testing.T) (*Repository, func()) {
+	dir, err := ioutil.TempDir("", "bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewRepository(filepath.Join(dir, "control.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r, func() {
+		r.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestRepository_Conformance(t *testing.T) {
+	storage.Conformance(t, func(t *testing.T) (storage.Repository, func()) {
+		return newTestRepository(t)
+	})
+}
+
+func TestRepository_Buckets(t *testing.T) {
+	r, release := newTestRepository(t)
+	defer release()
+	ctx := context.Background()
+
+	for _, prefix := range []string{"/supergiant/kubes/", "/supergiant/kube_task_index/kube/", "tasks", ""} {
+		if err := r.Put(ctx, prefix, "key", []byte("value")); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	if err := r.Delete(ctx, "/supergiant/kube_task_index/kube/", "key"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	buckets := make([]string, 0)
+	r.db.View(func(tx *bbolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bbolt.Bucket) error {
+			buckets = append(buckets, string(name))
+			return nil
+		})
+	})
+
+	expected := []string{rootBucket, "/supergiant/kubes/", "tasks"}
+	if !reflect.DeepEqual(expected, buckets) {
+		t.Errorf("Expected buckets %q actual %q", expected, buckets)
+	}
+
+	values, err := r.GetAll(ctx, "")
+	if err != nil || len(values) != 3 {
+		t.Errorf("Expected all values actual %s %v", values, err)
+	}
+}
@@ -2,6 +2,7 @@ package etcd
 
 import (
 	"context"
+	"strings"
 
 	"github.com/coreos/etcd/clientv3"
 	"github.com/pkg/errors"
@@ -51,13 +52,44 @@ func (e *ETCDRepository) Put(ctx context.Context, prefix string, key string, val
 	return errors.Wrap(err, "failed to write to the etcd")
 }
 
+// CompareAndSwap puts value of the key in a transaction comparing
+// its stored value to old, or its create revision to zero for nil old.
+func (e *ETCDRepository) CompareAndSwap(ctx context.Context, prefix string, key string, old, value []byte) error {
+	cl, err := e.GetClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to the etcd")
+	}
+	defer cl.Close()
+
+	cmp := clientv3.Compare(clientv3.Value(prefix+key), "=", string(old))
+	if old == nil {
+		cmp = clientv3.Compare(clientv3.CreateRevision(prefix+key), "=", 0)
+	}
+
+	res, err := cl.Txn(ctx).
+		If(cmp).
+		Then(clientv3.OpPut(prefix+key, string(value))).
+		Commit()
+	if err != nil {
+		return errors.Wrap(err, "failed to write to the etcd")
+	}
+
+	if !res.Succeeded {
+		return errors.Wrapf(sgerrors.ErrConflict, "%s%s", prefix, key)
+	}
+
+	return nil
+}
+
 func (e *ETCDRepository) Delete(ctx context.Context, prefix string, key string) error {
 	cl, err := e.GetClient()
 	if err != nil {
 		return errors.Wrap(err, "failed to connect to the etcd")
 	}
-	_, err = cl.Delete(ctx, prefix+key, clientv3.WithPrefix())
-	return errors.Wrap(err, "failed to read from the etcd")
+	defer cl.Close()
+
+	_, err = cl.Delete(ctx, prefix+key)
+	return errors.Wrap(err, "failed to delete from the etcd")
 }
 
 func (e *ETCDRepository) GetClient() (*clientv3.Client, error) {
@@ -87,3 +119,23 @@ func (e *ETCDRepository) GetAll(ctx context.Context, prefix string) ([][]byte, e
 	}
 	return result, nil
 }
+
+func (e *ETCDRepository) Export(ctx context.Context, prefix string) (map[string][]byte, error) {
+	cl, err := e.GetClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to the etcd")
+	}
+	defer cl.Close()
+	kv := clientv3.NewKV(cl)
+
+	r, err := kv.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read from the etcd")
+	}
+
+	values := make(map[string][]byte, len(r.Kvs))
+	for _, v := range r.Kvs {
+		values[strings.TrimPrefix(string(v.Key), prefix)] = v.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,40 @@
+package etcd
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/coreos/etcd/clientv3"
+
+	"github.com/supergiant/control/pkg/testutils/storage"
+)
+
+// Endpoint of etcd the conformance suite is run against, keys
+// of the suite are deleted before each test
+const testEndpointEnv = "SG_TEST_ETCD_ENDPOINT"
+
+func TestETCDRepository_Conformance(t *testing.T) {
+	endpoint := os.Getenv(testEndpointEnv)
+	if endpoint == "" {
+		t.Skipf("%s is not set", testEndpointEnv)
+	}
+
+	storage.Conformance(t, func(t *testing.T) (storage.Repository, func()) {
+		r := NewETCDRepository(endpoint)
+
+		cl, err := r.GetClient()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cl.Close()
+
+		for _, prefix := range []string{"/test/", "/other/", "tasks"} {
+			if _, err := cl.Delete(context.Background(), prefix, clientv3.WithPrefix()); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return r, func() {}
+	})
+}
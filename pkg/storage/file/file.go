@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/etcd-io/bbolt"
+	"github.com/pkg/errors"
 
 	"github.com/supergiant/control/pkg/sgerrors"
 )
@@ -75,6 +76,24 @@ func (i *FileRepository) Put(ctx context.Context, prefix string, key string, val
 	return err
 }
 
+// CompareAndSwap puts value of the key when its stored value equals to old.
+func (i *FileRepository) CompareAndSwap(ctx context.Context, prefix string, key string, old, value []byte) error {
+	return i.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(bucketName))
+
+		if err != nil {
+			return fmt.Errorf("create bucket: %s", err)
+		}
+
+		current := bucket.Get([]byte(prefix + key))
+		if (current != nil) != (old != nil) || !bytes.Equal(current, old) {
+			return errors.Wrapf(sgerrors.ErrConflict, "%s%s", prefix, key)
+		}
+
+		return bucket.Put([]byte(prefix+key), value)
+	})
+}
+
 func (i *FileRepository) Delete(ctx context.Context, prefix string, key string) error {
 	err := i.db.Update(func(tx *bbolt.Tx) error {
 		bucket, err := tx.CreateBucketIfNotExists([]byte(bucketName))
@@ -110,3 +129,24 @@ func (i *FileRepository) GetAll(ctx context.Context, prefix string) ([][]byte, e
 
 	return values, nil
 }
+
+func (i *FileRepository) Export(ctx context.Context, prefix string) (map[string][]byte, error) {
+	values := make(map[string][]byte)
+
+	err := i.db.View(func(tx *bbolt.Tx) error {
+		cursor := tx.Bucket([]byte(bucketName)).Cursor()
+		prefixBytes := []byte(prefix)
+
+		for k, v := cursor.Seek(prefixBytes); k != nil && bytes.HasPrefix(k, prefixBytes); k, v = cursor.Next() {
+			values[string(k[len(prefixBytes):])] = append([]byte{}, v...)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
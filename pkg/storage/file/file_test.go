@@ -0,0 +1,29 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/supergiant/control/pkg/testutils/storage"
+)
+
+func TestFileRepository_Conformance(t *testing.T) {
+	storage.Conformance(t, func(t *testing.T) (storage.Repository, func()) {
+		dir, err := ioutil.TempDir("", "file")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := NewFileRepository(filepath.Join(dir, "control.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return r, func() {
+			r.db.Close()
+			os.RemoveAll(dir)
+		}
+	})
+}
@@ -1,10 +1,13 @@
 package memory
 
 import (
+	"bytes"
 	"context"
 	"strings"
 	"sync"
 
+	"github.com/pkg/errors"
+
 	"github.com/supergiant/control/pkg/sgerrors"
 )
 
@@ -40,6 +43,20 @@ func (i *InMemoryRepository) Put(ctx context.Context, prefix string, key string,
 	return nil
 }
 
+// CompareAndSwap puts value of the key when its stored value equals to old.
+func (i *InMemoryRepository) CompareAndSwap(ctx context.Context, prefix string, key string, old, value []byte) error {
+	i.m.Lock()
+	defer i.m.Unlock()
+
+	current, ok := i.data[prefix+key]
+	if ok != (old != nil) || !bytes.Equal(current, old) {
+		return errors.Wrapf(sgerrors.ErrConflict, "%s%s", prefix, key)
+	}
+
+	i.data[prefix+key] = value
+	return nil
+}
+
 func (i *InMemoryRepository) Delete(ctx context.Context, prefix string, key string) error {
 	i.m.Lock()
 	defer i.m.Unlock()
@@ -52,13 +69,28 @@ func (i *InMemoryRepository) GetAll(ctx context.Context, prefix string) ([][]byt
 	i.m.RLock()
 	defer i.m.RUnlock()
 
-	allKeys := make([][]byte, len(i.data))
+	allKeys := make([][]byte, 0)
 
 	for key := range i.data {
-		if strings.Contains(key, prefix) {
+		if strings.HasPrefix(key, prefix) {
 			allKeys = append(allKeys, i.data[key])
 		}
 	}
 
 	return allKeys, nil
 }
+
+func (i *InMemoryRepository) Export(ctx context.Context, prefix string) (map[string][]byte, error) {
+	i.m.RLock()
+	defer i.m.RUnlock()
+
+	values := make(map[string][]byte)
+
+	for key, value := range i.data {
+		if strings.HasPrefix(key, prefix) {
+			values[strings.TrimPrefix(key, prefix)] = value
+		}
+	}
+
+	return values, nil
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/testutils/storage"
 )
 
 func TestNewInMemoryRepository(t *testing.T) {
@@ -84,3 +85,9 @@ func TestInMemoryRepository_GetAll(t *testing.T) {
 		}
 	}
 }
+
+func TestInMemoryRepository_Conformance(t *testing.T) {
+	storage.Conformance(t, func(t *testing.T) (storage.Repository, func()) {
+		return NewInMemoryRepository(), func() {}
+	})
+}
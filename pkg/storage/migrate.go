@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Migrate copies values of keys starting with the prefixes from src to
+// dst and returns count of copied keys. Keys are put within the prefix
+// followed by the rest of the key up to its last slash, so the key
+// /supergiant/kube_task_index/kube/task of the /supergiant/kube_task_index/
+// prefix is put within /supergiant/kube_task_index/kube/ as services do.
+func Migrate(ctx context.Context, src Exporter, dst Interface, prefixes []string) (int, error) {
+	// Keys belong to the longest of overlapping prefixes
+	prefixes = append([]string{}, prefixes...)
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) > len(prefixes[j])
+	})
+
+	values := make(map[string]map[string][]byte)
+	copied := make(map[string]struct{})
+
+	for _, root := range prefixes {
+		exported, err := src.Export(ctx, root)
+		if err != nil {
+			return 0, errors.Wrapf(err, "export %s", root)
+		}
+
+		for rest, value := range exported {
+			if _, ok := copied[root+rest]; ok {
+				continue
+			}
+			copied[root+rest] = struct{}{}
+
+			prefix, key := root, rest
+			if i := strings.LastIndex(rest, "/"); i >= 0 {
+				prefix, key = root+rest[:i+1], rest[i+1:]
+			}
+
+			if values[prefix] == nil {
+				values[prefix] = make(map[string][]byte)
+			}
+			values[prefix][key] = value
+		}
+	}
+
+	if batcher, ok := dst.(Batcher); ok {
+		if err := batcher.PutAll(ctx, values); err != nil {
+			return 0, errors.Wrap(err, "put values")
+		}
+
+		return len(copied), nil
+	}
+
+	for prefix, kv := range values {
+		for key, value := range kv {
+			if err := dst.Put(ctx, prefix, key, value); err != nil {
+				return 0, errors.Wrapf(err, "put %s%s", prefix, key)
+			}
+		}
+	}
+
+	return len(copied), nil
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/supergiant/control/pkg/storage/bolt"
+	"github.com/supergiant/control/pkg/storage/memory"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	src := memory.NewInMemoryRepository()
+
+	for _, kv := range [][3]string{
+		{"/supergiant/kubes/", "kube", "kube"},
+		{"/supergiant/kube_task_index/kube/", "task", "task"},
+		{"tasks", "task", "task"},
+		{"/supergiant/profile", "profile", "profile"},
+		{"/unknown/", "key", "value"},
+	} {
+		if err := src.Put(ctx, kv[0], kv[1], []byte(kv[2])); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dst, err := bolt.NewRepository(filepath.Join(dir, "control.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	count, err := Migrate(ctx, src, dst, []string{
+		"/supergiant/", "/supergiant/kubes/", "/supergiant/kube_task_index/", "tasks", "/supergiant/profile",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if count != 4 {
+		t.Errorf("Wrong count of keys expected 4 actual %d", count)
+	}
+
+	for _, kv := range [][2]string{
+		{"/supergiant/kubes/", "kube"},
+		{"/supergiant/kube_task_index/kube/", "task"},
+		{"tasks", "task"},
+		{"/supergiant/profile", "profile"},
+	} {
+		if _, err := dst.Get(ctx, kv[0], kv[1]); err != nil {
+			t.Errorf("Key %s must be migrated within %s prefix %v", kv[1], kv[0], err)
+		}
+	}
+
+	if _, err := dst.Get(ctx, "/unknown/", "key"); err == nil {
+		t.Errorf("Key of unknown prefix must not be migrated")
+	}
+}
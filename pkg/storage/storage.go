@@ -1,10 +1,13 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 
 	"github.com/pkg/errors"
 
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/storage/bolt"
 	"github.com/supergiant/control/pkg/storage/etcd"
 	"github.com/supergiant/control/pkg/storage/file"
 	"github.com/supergiant/control/pkg/storage/memory"
@@ -14,17 +17,80 @@ const (
 	memoryStorageType = "memory"
 	fileStorageType   = "file"
 	etcdStorageType   = "etcd"
+	boltStorageType   = "bolt"
 )
 
 // Interface is an abstraction over key value storage, gets and returns values serialized as byte slices
 // It is up to the services to do data conversion from
+//
+// Values are stored under the key within the prefix, services keep their
+// entities under a prefix of their own, e.g. /supergiant/kubes/.
 type Interface interface {
+	// GetAll returns values of all keys starting with the prefix,
+	// keys of nested prefixes are included. Order of values is not defined.
 	GetAll(ctx context.Context, prefix string) ([][]byte, error)
+	// Get returns value of the key, sgerrors.ErrNotFound is
+	// returned when the key is missing.
 	Get(ctx context.Context, prefix string, key string) ([]byte, error)
+	// Put creates the key or replaces its value.
 	Put(ctx context.Context, prefix string, key string, value []byte) error
+	// Delete removes the key, missing keys are not an error.
 	Delete(ctx context.Context, prefix string, key string) error
 }
 
+// Exporter is implemented by storages able to list their keys,
+// they may be migrated to another storage.
+type Exporter interface {
+	// Export returns values by keys starting with the prefix,
+	// keys are the rest of the whole key following the prefix.
+	Export(ctx context.Context, prefix string) (map[string][]byte, error)
+}
+
+// Batcher is implemented by storages writing several values at once.
+type Batcher interface {
+	// PutAll writes values by keys by prefixes in one transaction,
+	// nothing is written when it fails.
+	PutAll(ctx context.Context, values map[string]map[string][]byte) error
+}
+
+// Swapper is implemented by storages writing a value only when the
+// stored one has not changed since it was read.
+type Swapper interface {
+	// CompareAndSwap puts value of the key when its stored value equals
+	// to old, nil old means the key must be missing. sgerrors.ErrConflict
+	// is returned when the stored value differs.
+	CompareAndSwap(ctx context.Context, prefix string, key string, old, value []byte) error
+}
+
+// CompareAndSwap puts value of the key when its stored value equals to old,
+// storages not implementing Swapper compare values before the put.
+func CompareAndSwap(ctx context.Context, s Interface, prefix string, key string, old, value []byte) error {
+	if swapper, ok := s.(Swapper); ok {
+		return swapper.CompareAndSwap(ctx, prefix, key, old, value)
+	}
+
+	current, err := s.Get(ctx, prefix, key)
+	if err != nil && !sgerrors.IsNotFound(err) {
+		return err
+	}
+
+	if !Equal(current, old) {
+		return errors.Wrapf(sgerrors.ErrConflict, "%s%s", prefix, key)
+	}
+
+	return s.Put(ctx, prefix, key, value)
+}
+
+// Equal reports whether the stored value equals to the expected one,
+// nil values are of missing keys.
+func Equal(value, expected []byte) bool {
+	if value == nil || expected == nil {
+		return value == nil && expected == nil
+	}
+
+	return bytes.Equal(value, expected)
+}
+
 func GetStorage(storageType, uri string) (Interface, error) {
 	switch storageType {
 	case memoryStorageType:
@@ -33,6 +99,8 @@ func GetStorage(storageType, uri string) (Interface, error) {
 		return file.NewFileRepository(uri)
 	case etcdStorageType:
 		return etcd.NewETCDRepository(uri), nil
+	case boltStorageType:
+		return bolt.NewRepository(uri)
 	}
 
 	return nil, errors.New("wrong storage type" + storageType)
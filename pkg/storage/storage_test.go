@@ -1,9 +1,12 @@
 package storage
 
 import (
+	"context"
 	"reflect"
 	"testing"
 
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/storage/bolt"
 	"github.com/supergiant/control/pkg/storage/etcd"
 	"github.com/supergiant/control/pkg/storage/file"
 	"github.com/supergiant/control/pkg/storage/memory"
@@ -30,6 +33,11 @@ func TestGetStorage(t *testing.T) {
 			etcdStorageType,
 			reflect.TypeOf(&etcd.ETCDRepository{}),
 		},
+		{
+			"/tmp/bolt.db",
+			boltStorageType,
+			reflect.TypeOf(&bolt.Repository{}),
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -46,3 +54,30 @@ func TestGetStorage(t *testing.T) {
 
 	}
 }
+
+// plainStorage hides CompareAndSwap of the wrapped storage.
+type plainStorage struct {
+	Interface
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	for _, s := range []Interface{memory.NewInMemoryRepository(), plainStorage{memory.NewInMemoryRepository()}} {
+		ctx := context.Background()
+
+		if err := CompareAndSwap(ctx, s, "/kubes/", "kube", nil, []byte("1")); err != nil {
+			t.Errorf("%T: unexpected error %v", s, err)
+		}
+
+		if err := CompareAndSwap(ctx, s, "/kubes/", "kube", nil, []byte("2")); !sgerrors.IsConflict(err) {
+			t.Errorf("%T: expected conflict actual %v", s, err)
+		}
+
+		if err := CompareAndSwap(ctx, s, "/kubes/", "kube", []byte("1"), []byte("2")); err != nil {
+			t.Errorf("%T: unexpected error %v", s, err)
+		}
+
+		if value, _ := s.Get(ctx, "/kubes/", "kube"); string(value) != "2" {
+			t.Errorf("%T: expected value 2 actual %s", s, value)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// Repository is the storage interface backends conform to.
+type Repository interface {
+	GetAll(ctx context.Context, prefix string) ([][]byte, error)
+	Get(ctx context.Context, prefix string, key string) ([]byte, error)
+	Put(ctx context.Context, prefix string, key string, value []byte) error
+	Delete(ctx context.Context, prefix string, key string) error
+}
+
+type exporter interface {
+	Export(ctx context.Context, prefix string) (map[string][]byte, error)
+}
+
+type swapper interface {
+	CompareAndSwap(ctx context.Context, prefix string, key string, old, value []byte) error
+}
+
+type batcher interface {
+	PutAll(ctx context.Context, values map[string]map[string][]byte) error
+}
+
+// Conformance runs the suite every storage backend passes, newRepository
+// returns an empty repository for each test and func releasing it.
+func Conformance(t *testing.T, newRepository func(t *testing.T) (Repository, func())) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, r Repository)
+	}{
+		{"GetMissing", testGetMissing},
+		{"PutGet", testPutGet},
+		{"PutReplaces", testPutReplaces},
+		{"Prefixes", testPrefixes},
+		{"GetAll", testGetAll},
+		{"Delete", testDelete},
+		{"Export", testExport},
+		{"PutAll", testPutAll},
+		{"CompareAndSwap", testCompareAndSwap},
+		{"CompareAndSwapConcurrent", testCompareAndSwapConcurrent},
+	}
+
+	for _, tc := range tests {
+		test := tc.test
+		t.Run(tc.name, func(t *testing.T) {
+			r, release := newRepository(t)
+			defer release()
+
+			test(t, r)
+		})
+	}
+}
+
+func testGetMissing(t *testing.T, r Repository) {
+	if _, err := r.Get(context.Background(), "/test/kubes/", "missing"); !sgerrors.IsNotFound(err) {
+		t.Errorf("Expected not found error actual %v", err)
+	}
+}
+
+func testPutGet(t *testing.T, r Repository) {
+	ctx := context.Background()
+	mustPut(t, r, "/test/kubes/", "kube", "value")
+
+	value, err := r.Get(ctx, "/test/kubes/", "kube")
+	if err != nil || string(value) != "value" {
+		t.Errorf("Expected value actual %s %v", value, err)
+	}
+}
+
+func testPutReplaces(t *testing.T, r Repository) {
+	ctx := context.Background()
+	mustPut(t, r, "/test/kubes/", "kube", "old")
+	mustPut(t, r, "/test/kubes/", "kube", "new")
+
+	value, err := r.Get(ctx, "/test/kubes/", "kube")
+	if err != nil || string(value) != "new" {
+		t.Errorf("Expected new actual %s %v", value, err)
+	}
+
+	if values := mustGetAll(t, r, "/test/kubes/"); len(values) != 1 {
+		t.Errorf("Replaced key must be kept once %s", values)
+	}
+}
+
+func testPrefixes(t *testing.T, r Repository) {
+	ctx := context.Background()
+	mustPut(t, r, "/test/kubes/", "id", "kube")
+	mustPut(t, r, "/test/accounts/", "id", "account")
+	mustPut(t, r, "tasks", "id", "task")
+
+	for prefix, expected := range map[string]string{
+		"/test/kubes/":    "kube",
+		"/test/accounts/": "account",
+		"tasks":           "task",
+	} {
+		value, err := r.Get(ctx, prefix, "id")
+		if err != nil || string(value) != expected {
+			t.Errorf("Expected %s within %s actual %s %v", expected, prefix, value, err)
+		}
+	}
+
+	if _, err := r.Get(ctx, "/test/", "id"); !sgerrors.IsNotFound(err) {
+		t.Errorf("Key of other prefix must not be found %v", err)
+	}
+}
+
+func testGetAll(t *testing.T, r Repository) {
+	mustPut(t, r, "/test/kubes/", "one", "1")
+	mustPut(t, r, "/test/kubes/", "two", "2")
+	mustPut(t, r, "/test/kubesets/", "three", "3")
+	mustPut(t, r, "/test/index/one/", "task", "4")
+	mustPut(t, r, "/test/index/two/", "task", "5")
+	mustPut(t, r, "tasks", "one", "6")
+
+	testCases := []struct {
+		prefix   string
+		expected []string
+	}{
+		{"/test/kubes/", []string{"1", "2"}},
+		{"/test/kubes", []string{"1", "2", "3"}},
+		{"/test/index/one/", []string{"4"}},
+		{"/test/index/", []string{"4", "5"}},
+		{"tasks", []string{"6"}},
+		{"/test/kubes/o", []string{"1"}},
+		{"/test/missing/", []string{}},
+	}
+
+	for _, testCase := range testCases {
+		values := mustGetAll(t, r, testCase.prefix)
+
+		if !reflect.DeepEqual(testCase.expected, values) {
+			t.Errorf("Expected values of %s %v actual %v", testCase.prefix, testCase.expected, values)
+		}
+	}
+}
+
+func testDelete(t *testing.T, r Repository) {
+	ctx := context.Background()
+	mustPut(t, r, "/test/kubes/", "kube", "1")
+	mustPut(t, r, "/test/kubes/", "kube2", "2")
+
+	if err := r.Delete(ctx, "/test/kubes/", "kube"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if _, err := r.Get(ctx, "/test/kubes/", "kube"); !sgerrors.IsNotFound(err) {
+		t.Errorf("Deleted key must not be found %v", err)
+	}
+
+	if values := mustGetAll(t, r, "/test/kubes/"); !reflect.DeepEqual(values, []string{"2"}) {
+		t.Errorf("Keys sharing the deleted key must be kept %v", values)
+	}
+
+	if err := r.Delete(ctx, "/test/kubes/", "missing"); err != nil {
+		t.Errorf("Delete of missing key must not fail %v", err)
+	}
+
+	if err := r.Delete(ctx, "/test/kubes/", "kube2"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	mustPut(t, r, "/test/kubes/", "kube", "3")
+	if values := mustGetAll(t, r, "/test/kubes/"); !reflect.DeepEqual(values, []string{"3"}) {
+		t.Errorf("Prefix must be usable after delete of all keys %v", values)
+	}
+}
+
+func testExport(t *testing.T, r Repository) {
+	e, ok := r.(exporter)
+	if !ok {
+		t.Skip("repository does not export keys")
+	}
+
+	mustPut(t, r, "/test/kubes/", "kube", "1")
+	mustPut(t, r, "/test/index/kube/", "task", "2")
+	mustPut(t, r, "/other/", "key", "3")
+
+	values, err := e.Export(context.Background(), "/test/")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := map[string][]byte{
+		"kubes/kube":      []byte("1"),
+		"index/kube/task": []byte("2"),
+	}
+	if !reflect.DeepEqual(expected, values) {
+		t.Errorf("Expected export %s actual %s", expected, values)
+	}
+}
+
+func testPutAll(t *testing.T, r Repository) {
+	b, ok := r.(batcher)
+	if !ok {
+		t.Skip("repository does not write batches")
+	}
+
+	err := b.PutAll(context.Background(), map[string]map[string][]byte{
+		"/test/kubes/":      {"one": []byte("1"), "two": []byte("2")},
+		"/test/index/kube/": {"task": []byte("3")},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if values := mustGetAll(t, r, "/test/"); !reflect.DeepEqual(values, []string{"1", "2", "3"}) {
+		t.Errorf("Expected all values written actual %v", values)
+	}
+}
+
+func testCompareAndSwap(t *testing.T, r Repository) {
+	s, ok := r.(swapper)
+	if !ok {
+		t.Skip("repository does not compare and swap values")
+	}
+
+	ctx := context.Background()
+
+	if err := s.CompareAndSwap(ctx, "/test/kubes/", "kube", nil, []byte("1")); err != nil {
+		t.Fatalf("Create of missing key must not fail %v", err)
+	}
+
+	if err := s.CompareAndSwap(ctx, "/test/kubes/", "kube", nil, []byte("2")); !sgerrors.IsConflict(err) {
+		t.Errorf("Create of existing key must conflict %v", err)
+	}
+
+	if err := s.CompareAndSwap(ctx, "/test/kubes/", "kube", []byte("0"), []byte("2")); !sgerrors.IsConflict(err) {
+		t.Errorf("Swap of changed value must conflict %v", err)
+	}
+
+	if err := s.CompareAndSwap(ctx, "/test/kubes/", "missing", []byte("1"), []byte("2")); !sgerrors.IsConflict(err) {
+		t.Errorf("Swap of missing key must conflict %v", err)
+	}
+
+	if err := s.CompareAndSwap(ctx, "/test/kubes/", "kube", []byte("1"), []byte("2")); err != nil {
+		t.Fatalf("Swap of unchanged value must not fail %v", err)
+	}
+
+	value, err := r.Get(ctx, "/test/kubes/", "kube")
+	if err != nil || string(value) != "2" {
+		t.Errorf("Expected swapped value 2 actual %s %v", value, err)
+	}
+
+	if _, err := r.Get(ctx, "/test/kubes/", "missing"); !sgerrors.IsNotFound(err) {
+		t.Errorf("Conflicting swap must not put the key %v", err)
+	}
+}
+
+// testCompareAndSwapConcurrent swaps the same value concurrently,
+// exactly one of swaps succeeds.
+func testCompareAndSwapConcurrent(t *testing.T, r Repository) {
+	s, ok := r.(swapper)
+	if !ok {
+		t.Skip("repository does not compare and swap values")
+	}
+
+	mustPut(t, r, "/test/kubes/", "kube", "0")
+
+	const writers = 8
+	errs := make(chan error, writers)
+
+	for i := 0; i < writers; i++ {
+		go func(i int) {
+			errs <- s.CompareAndSwap(context.Background(), "/test/kubes/", "kube",
+				[]byte("0"), []byte{byte('1' + i)})
+		}(i)
+	}
+
+	succeeded := 0
+	for i := 0; i < writers; i++ {
+		err := <-errs
+
+		switch {
+		case err == nil:
+			succeeded++
+		case !sgerrors.IsConflict(err):
+			t.Errorf("Unexpected error %v", err)
+		}
+	}
+
+	if succeeded != 1 {
+		t.Errorf("Expected one successful swap actual %d", succeeded)
+	}
+}
+
+func mustPut(t *testing.T, r Repository, prefix, key, value string) {
+	if err := r.Put(context.Background(), prefix, key, []byte(value)); err != nil {
+		t.Fatalf("put %s%s: %v", prefix, key, errors.Cause(err))
+	}
+}
+
+// mustGetAll returns sorted values of the prefix.
+func mustGetAll(t *testing.T, r Repository, prefix string) []string {
+	raw, err := r.GetAll(context.Background(), prefix)
+	if err != nil {
+		t.Fatalf("get all %s: %v", prefix, err)
+	}
+
+	values := make([]string, 0, len(raw))
+	for _, v := range raw {
+		values = append(values, string(v))
+	}
+	sort.Strings(values)
+
+	return values
+}
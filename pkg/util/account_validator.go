@@ -2,12 +2,12 @@ package util
 
 import (
 	"context"
+	"strconv"
 	"strings"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/digitalocean/godo"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -17,6 +17,7 @@ import (
 	"google.golang.org/api/dns/v1"
 
 	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/clouds/awssdk"
 	"github.com/supergiant/control/pkg/clouds/digitaloceansdk"
 	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/sgerrors"
@@ -89,19 +90,36 @@ func validateAWSCredentials(creds map[string]string) error {
 		return err
 	}
 
-	awsCfg := aws.Config{
-		Region:      aws.String("us-east-1"),
-		Credentials: credentials.NewStaticCredentials(config.KeyID, config.Secret, ""),
+	if config.UseDefaultCredentialChain != "" {
+		if _, err := strconv.ParseBool(config.UseDefaultCredentialChain); err != nil {
+			return errors.Wrapf(sgerrors.ErrValidationFailed, "parse %s %s",
+				clouds.AWSUseDefaultChain, config.UseDefaultCredentialChain)
+		}
 	}
 
-	sess, err := session.NewSessionWithOptions(session.Options{
-		Config: awsCfg,
-	})
+	awsCreds := AWSCredentials(*config)
+	sess, err := awssdk.NewSession(endpoints.UsEast1RegionID, awsCreds)
 
 	if err != nil {
 		return err
 	}
 
+	// Check that default chain resolves to working credentials
+	if awsCreds.UseDefaultChain {
+		if _, err := sts.New(sess).GetCallerIdentity(&sts.GetCallerIdentityInput{}); err != nil {
+			return errors.Wrapf(sgerrors.ErrInvalidCredentials,
+				"get caller identity: %v", err)
+		}
+	}
+
+	// Check that role can be assumed before using it
+	if config.RoleARN != "" {
+		if _, err := sess.Config.Credentials.Get(); err != nil {
+			return errors.Wrapf(sgerrors.ErrInvalidCredentials,
+				"assume role %s: %v", config.RoleARN, err)
+		}
+	}
+
 	ec2Client := ec2.New(sess)
 
 	_, err = ec2Client.DescribeKeyPairs(new(ec2.DescribeKeyPairsInput))
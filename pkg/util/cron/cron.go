@@ -0,0 +1,284 @@
+package cron
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Years searched for the next time before the schedule is considered
+// to never run, e.g. for 30th of February.
+const searchYears = 5
+
+// Prefixes of time zone of the spec, e.g. CRON_TZ=Europe/Berlin
+var zonePrefixes = []string{"CRON_TZ=", "TZ="}
+
+type bounds struct {
+	min   uint
+	max   uint
+	names map[string]uint
+}
+
+var (
+	minutes = bounds{min: 0, max: 59}
+	hours   = bounds{min: 0, max: 23}
+	days    = bounds{min: 1, max: 31}
+	months  = bounds{min: 1, max: 12, names: map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Both 0 and 7 are Sunday
+	weekdays = bounds{min: 0, max: 7, names: map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// Schedule is a parsed five field cron expression, fields are minute,
+// hour, day of month, month and day of week. Bits of the fields are set
+// for values that match.
+type Schedule struct {
+	minute  uint64
+	hour    uint64
+	day     uint64
+	month   uint64
+	weekday uint64
+
+	// Day matches when any of day of month or day of week matches
+	// unless one of them is *.
+	anyDay bool
+
+	location *time.Location
+}
+
+// Parse parses the spec in UTC or in the time zone of its CRON_TZ prefix,
+// e.g. "CRON_TZ=Europe/Berlin 0 20 * * mon-fri".
+func Parse(spec string) (*Schedule, error) {
+	location := time.UTC
+	spec = strings.TrimSpace(spec)
+
+	for _, prefix := range zonePrefixes {
+		if !strings.HasPrefix(spec, prefix) {
+			continue
+		}
+
+		parts := strings.SplitN(spec, " ", 2)
+
+		var err error
+		location, err = time.LoadLocation(strings.TrimPrefix(parts[0], prefix))
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "time zone of %s", spec)
+		}
+
+		spec = ""
+		if len(parts) == 2 {
+			spec = parts[1]
+		}
+
+		break
+	}
+
+	fields := strings.Fields(spec)
+
+	if len(fields) != 5 {
+		return nil, errors.Errorf("cron spec %q must have 5 fields, got %d", spec, len(fields))
+	}
+
+	s := &Schedule{
+		location: location,
+		anyDay:   !strings.HasPrefix(fields[2], "*") && !strings.HasPrefix(fields[4], "*"),
+	}
+
+	for _, f := range []struct {
+		name   string
+		value  string
+		bounds bounds
+		bits   *uint64
+	}{
+		{"minute", fields[0], minutes, &s.minute},
+		{"hour", fields[1], hours, &s.hour},
+		{"day of month", fields[2], days, &s.day},
+		{"month", fields[3], months, &s.month},
+		{"day of week", fields[4], weekdays, &s.weekday},
+	} {
+		bits, err := parseField(f.value, f.bounds)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s", f.name)
+		}
+
+		*f.bits = bits
+	}
+
+	// Sunday may be set as 7
+	if s.weekday&(1<<7) != 0 {
+		s.weekday |= 1
+	}
+
+	return s, nil
+}
+
+// Location returns time zone the schedule is evaluated in.
+func (s *Schedule) Location() *time.Location {
+	return s.location
+}
+
+// Next returns the first time of the schedule after t in the time zone
+// of the schedule, it is zero when the schedule never runs.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + searchYears
+
+	for t.Year() <= limit {
+		if !has(s.month, uint(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+			continue
+		}
+
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+			continue
+		}
+
+		if !has(s.hour, uint(t.Hour())) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, s.location).Add(time.Hour)
+			continue
+		}
+
+		if !has(s.minute, uint(t.Minute())) {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// NextN returns n next times of the schedule after t.
+func (s *Schedule) NextN(t time.Time, n int) []time.Time {
+	times := make([]time.Time, 0, n)
+
+	for len(times) < n {
+		t = s.Next(t)
+
+		if t.IsZero() {
+			break
+		}
+
+		times = append(times, t)
+	}
+
+	return times
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	day := has(s.day, uint(t.Day()))
+	weekday := has(s.weekday, uint(t.Weekday()))
+
+	if s.anyDay {
+		return day || weekday
+	}
+
+	return day && weekday
+}
+
+// parseField parses comma separated list of *, values, ranges
+// and their steps, e.g. 1-5,*/15.
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+
+	for _, item := range strings.Split(field, ",") {
+		itemBits, err := parseItem(item, b)
+
+		if err != nil {
+			return 0, err
+		}
+
+		bits |= itemBits
+	}
+
+	return bits, nil
+}
+
+func parseItem(item string, b bounds) (uint64, error) {
+	rangePart, step := item, uint(1)
+
+	if i := strings.Index(item, "/"); i >= 0 {
+		rangePart = item[:i]
+		value, err := strconv.ParseUint(item[i+1:], 10, 8)
+
+		if err != nil || value == 0 {
+			return 0, errors.Errorf("invalid step of %q", item)
+		}
+
+		step = uint(value)
+	}
+
+	var start, end uint
+
+	switch {
+	case rangePart == "*":
+		start, end = b.min, b.max
+	case strings.Contains(rangePart, "-"):
+		parts := strings.SplitN(rangePart, "-", 2)
+
+		var err error
+		if start, err = parseValue(parts[0], b); err != nil {
+			return 0, err
+		}
+
+		if end, err = parseValue(parts[1], b); err != nil {
+			return 0, err
+		}
+	default:
+		value, err := parseValue(rangePart, b)
+
+		if err != nil {
+			return 0, err
+		}
+
+		// Value with step runs to the end of the range, e.g. 5/15
+		start, end = value, value
+		if step > 1 {
+			end = b.max
+		}
+	}
+
+	if start > end {
+		return 0, errors.Errorf("start of range %q is after its end", item)
+	}
+
+	var bits uint64
+
+	for value := start; value <= end; value += step {
+		bits |= 1 << value
+	}
+
+	return bits, nil
+}
+
+func parseValue(value string, b bounds) (uint, error) {
+	if named, ok := b.names[strings.ToLower(value)]; ok {
+		return named, nil
+	}
+
+	parsed, err := strconv.ParseUint(value, 10, 8)
+
+	if err != nil {
+		return 0, errors.Errorf("invalid value %q", value)
+	}
+
+	if uint(parsed) < b.min || uint(parsed) > b.max {
+		return 0, errors.Errorf("value %d must be between %d and %d", parsed, b.min, b.max)
+	}
+
+	return uint(parsed), nil
+}
+
+func has(bits uint64, value uint) bool {
+	return bits&(1<<value) != 0
+}
@@ -0,0 +1,168 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		description string
+		spec        string
+		expectedErr bool
+	}{
+		{
+			description: "every minute",
+			spec:        "* * * * *",
+		},
+		{
+			description: "ranges, lists, steps and names",
+			spec:        "*/15 8-18/2 1,15 jan-jun mon-fri",
+		},
+		{
+			description: "time zone",
+			spec:        "CRON_TZ=Europe/Berlin 0 20 * * *",
+		},
+		{
+			description: "unknown time zone",
+			spec:        "CRON_TZ=Mars/Olympus 0 20 * * *",
+			expectedErr: true,
+		},
+		{
+			description: "missing field",
+			spec:        "0 20 * *",
+			expectedErr: true,
+		},
+		{
+			description: "value out of range",
+			spec:        "60 20 * * *",
+			expectedErr: true,
+		},
+		{
+			description: "reversed range",
+			spec:        "0 20-8 * * *",
+			expectedErr: true,
+		},
+		{
+			description: "zero step",
+			spec:        "*/0 * * * *",
+			expectedErr: true,
+		},
+		{
+			description: "unknown name",
+			spec:        "0 0 * * monday",
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		_, err := Parse(testCase.spec)
+
+		if (err != nil) != testCase.expectedErr {
+			t.Errorf("Expected error %v actual %v", testCase.expectedErr, err)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	testCases := []struct {
+		description string
+		spec        string
+		from        time.Time
+		expected    time.Time
+	}{
+		{
+			description: "later the same day",
+			spec:        "0 20 * * *",
+			from:        time.Date(2019, 7, 1, 10, 30, 15, 0, time.UTC),
+			expected:    time.Date(2019, 7, 1, 20, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "time of the spec is excluded",
+			spec:        "0 20 * * *",
+			from:        time.Date(2019, 7, 1, 20, 0, 0, 0, time.UTC),
+			expected:    time.Date(2019, 7, 2, 20, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "next working day",
+			spec:        "30 7 * * mon-fri",
+			from:        time.Date(2019, 7, 5, 8, 0, 0, 0, time.UTC),
+			expected:    time.Date(2019, 7, 8, 7, 30, 0, 0, time.UTC),
+		},
+		{
+			description: "sunday as 7",
+			spec:        "0 0 * * 7",
+			from:        time.Date(2019, 7, 1, 0, 0, 0, 0, time.UTC),
+			expected:    time.Date(2019, 7, 7, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "day of month or day of week",
+			spec:        "0 0 15 * fri",
+			from:        time.Date(2019, 7, 6, 0, 0, 0, 0, time.UTC),
+			expected:    time.Date(2019, 7, 12, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "next year",
+			spec:        "0 0 1 jan *",
+			from:        time.Date(2019, 7, 1, 0, 0, 0, 0, time.UTC),
+			expected:    time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "time zone",
+			spec:        "CRON_TZ=Europe/Berlin 0 20 * * *",
+			from:        time.Date(2019, 7, 1, 10, 0, 0, 0, time.UTC),
+			expected:    time.Date(2019, 7, 1, 20, 0, 0, 0, berlin),
+		},
+		{
+			description: "never",
+			spec:        "0 0 30 feb *",
+			from:        time.Date(2019, 7, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		s, err := Parse(testCase.spec)
+
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if next := s.Next(testCase.from); !next.Equal(testCase.expected) {
+			t.Errorf("Expected next time %v actual %v", testCase.expected, next)
+		}
+	}
+}
+
+func TestScheduleNextN(t *testing.T) {
+	s, err := Parse("0 */6 * * *")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	from := time.Date(2019, 7, 1, 1, 0, 0, 0, time.UTC)
+	times := s.NextN(from, 5)
+
+	if len(times) != 5 {
+		t.Fatalf("Expected 5 times actual %d", len(times))
+	}
+
+	for i, next := range times {
+		expected := time.Date(2019, 7, 1, 6, 0, 0, 0, time.UTC).Add(time.Duration(i) * 6 * time.Hour)
+
+		if !next.Equal(expected) {
+			t.Errorf("Expected time %d %v actual %v", i, expected, next)
+		}
+	}
+}
@@ -22,12 +22,16 @@ func UpdateKubeWithCloudSpecificData(k *model.Kube, config *steps.Config) {
 	k.Auth.CACertHash = config.Kube.Auth.CACertHash
 	k.Auth.CertificateKey = config.Kube.Auth.CertificateKey
 	k.Auth.CACertHash = config.Kube.Auth.CACertHash
+	// Bastion of private kube is provisioned with its infrastructure
+	k.SSHConfig.BastionHost = config.Kube.SSHConfig.BastionHost
 
 	// Save cloudSpecificData in kube
 	switch config.Provider {
 	case clouds.AWS:
 		// Save az to subnets mapping for this cluster
 		k.Subnets = config.AWSConfig.Subnets
+		// Resources of user are kept when kube is deleted
+		k.ResourceOwners = config.Kube.ResourceOwners
 
 		// Copy data got from pre provision step to cloud specific settings of kube
 		cloudSpecificSettings[clouds.AwsAZ] = config.AWSConfig.AvailabilityZone
@@ -57,8 +61,24 @@ func UpdateKubeWithCloudSpecificData(k *model.Kube, config *steps.Config) {
 			config.AWSConfig.ExternalLoadBalancerName
 		cloudSpecificSettings[clouds.AwsInternalLoadBalancerName] =
 			config.AWSConfig.InternalLoadBalancerName
+		cloudSpecificSettings[clouds.AwsTargetGroupARN] =
+			config.AWSConfig.TargetGroupARN
 		cloudSpecificSettings[clouds.AwsVolumeSize] =
 			config.AWSConfig.VolumeSize
+		cloudSpecificSettings[clouds.AwsVolumeType] =
+			config.AWSConfig.VolumeType
+		cloudSpecificSettings[clouds.AwsVolumeIops] =
+			config.AWSConfig.Iops
+		cloudSpecificSettings[clouds.AwsDeleteOnTermination] =
+			config.AWSConfig.DeleteOnTermination
+		cloudSpecificSettings[clouds.AwsEbsEncrypted] =
+			config.AWSConfig.EbsEncrypted
+		cloudSpecificSettings[clouds.AwsKmsKeyID] =
+			config.AWSConfig.KmsKeyID
+		cloudSpecificSettings[clouds.AwsHttpTokens] =
+			config.AWSConfig.HttpTokens
+		cloudSpecificSettings[clouds.AwsHttpPutResponseHopLimit] =
+			config.AWSConfig.HttpPutResponseHopLimit
 	case clouds.GCE:
 		k.Subnets = config.GCEConfig.AZs
 		cloudSpecificSettings[clouds.GCETargetPoolName] = config.GCEConfig.TargetPoolName
@@ -0,0 +1,63 @@
+package util
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RetryPolicy configures retries with jittered exponential backoff.
+type RetryPolicy struct {
+	// Attempts is the total count of calls including the first one
+	Attempts int
+	// Delay before the first retry, it doubles after each attempt
+	Delay time.Duration
+	// MaxDelay caps delay between attempts
+	MaxDelay time.Duration
+}
+
+// Retry calls fn until it succeeds, fails with error that is not retryable
+// or attempts are exhausted. Retry gives up when the next attempt would
+// not fit in the context deadline.
+func Retry(ctx context.Context, policy RetryPolicy, isRetryable func(error) bool,
+	name string, fn func() error) error {
+	delay := policy.Delay
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+
+		if err == nil || !isRetryable(err) || attempt >= policy.Attempts {
+			return err
+		}
+
+		sleep := jitter(delay)
+
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(sleep).After(deadline) {
+			return err
+		}
+
+		logrus.Debugf("attempt #%d %s failed with %v, retry in %v",
+			attempt, name, err, sleep)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(sleep):
+		}
+
+		if delay *= 2; policy.MaxDelay > 0 && delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+	}
+}
+
+// jitter returns random duration in range [d/2, d)
+func jitter(d time.Duration) time.Duration {
+	if d < 2 {
+		return d
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
@@ -0,0 +1,101 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errRetryable = errors.New("retryable")
+
+func TestRetry(t *testing.T) {
+	testCases := []struct {
+		description string
+
+		policy  RetryPolicy
+		timeout time.Duration
+		errs    []error
+
+		calls int
+		err   error
+	}{
+		{
+			description: "success",
+			policy:      RetryPolicy{Attempts: 3, Delay: time.Millisecond},
+			calls:       1,
+		},
+		{
+			description: "success after retry",
+			policy:      RetryPolicy{Attempts: 3, Delay: time.Millisecond},
+			errs:        []error{errRetryable, errRetryable},
+			calls:       3,
+		},
+		{
+			description: "attempts exhausted",
+			policy:      RetryPolicy{Attempts: 3, Delay: time.Millisecond, MaxDelay: time.Millisecond},
+			errs:        []error{errRetryable, errRetryable, errRetryable, errRetryable},
+			calls:       3,
+			err:         errRetryable,
+		},
+		{
+			description: "not retryable",
+			policy:      RetryPolicy{Attempts: 3, Delay: time.Millisecond},
+			errs:        []error{errors.New("fatal")},
+			calls:       1,
+		},
+		{
+			description: "deadline",
+			policy:      RetryPolicy{Attempts: 3, Delay: time.Minute},
+			timeout:     time.Second,
+			errs:        []error{errRetryable, errRetryable},
+			calls:       1,
+			err:         errRetryable,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		ctx := context.Background()
+
+		if testCase.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, testCase.timeout)
+			defer cancel()
+		}
+
+		calls := 0
+		err := Retry(ctx, testCase.policy, func(err error) bool {
+			return err == errRetryable
+		}, "test", func() error {
+			calls++
+
+			if calls <= len(testCase.errs) {
+				return testCase.errs[calls-1]
+			}
+
+			return nil
+		})
+
+		if calls != testCase.calls {
+			t.Errorf("Wrong count of calls expected %d actual %d",
+				testCase.calls, calls)
+		}
+
+		if testCase.err != nil && err != testCase.err {
+			t.Errorf("Wrong error expected %v actual %v", testCase.err, err)
+		}
+
+		if testCase.err == nil && len(testCase.errs) < testCase.calls && err != nil {
+			t.Errorf("Unexpected error %v", err)
+		}
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		if d := jitter(time.Second); d < time.Second/2 || d >= time.Second {
+			t.Errorf("Jitter %v is out of range", d)
+		}
+	}
+}
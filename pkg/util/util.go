@@ -7,6 +7,7 @@ import (
 	"math/rand"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/clouds/awssdk"
 	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/sgerrors"
 	"github.com/supergiant/control/pkg/workflows/steps"
@@ -111,7 +113,18 @@ func FillCloudAccountCredentials(cloudAccount *model.CloudAccount, config *steps
 	// TODO(stgleb):  Add support for other cloud providers
 	switch cloudAccount.Provider {
 	case clouds.AWS:
-		return BindParams(cloudAccount.Credentials, &config.AWSConfig)
+		if err := BindParams(cloudAccount.Credentials, &config.AWSConfig); err != nil {
+			return err
+		}
+
+		// Keys are resolved by the SDK, so they are never kept in config
+		if AWSCredentials(config.AWSConfig).UseDefaultChain {
+			config.AWSConfig.KeyID = ""
+			config.AWSConfig.Secret = ""
+			config.AWSConfig.SessionToken = ""
+		}
+
+		return nil
 	case clouds.DigitalOcean:
 		return BindParams(cloudAccount.Credentials, &config.DigitalOceanConfig)
 	case clouds.GCE:
@@ -123,6 +136,20 @@ func FillCloudAccountCredentials(cloudAccount *model.CloudAccount, config *steps
 	}
 }
 
+// AWSCredentials returns credentials of AWS account bound to config.
+func AWSCredentials(cfg steps.AWSConfig) awssdk.Credentials {
+	useDefaultChain, _ := strconv.ParseBool(cfg.UseDefaultCredentialChain)
+
+	return awssdk.Credentials{
+		KeyID:           cfg.KeyID,
+		Secret:          cfg.Secret,
+		SessionToken:    cfg.SessionToken,
+		UseDefaultChain: useDefaultChain,
+		RoleARN:         cfg.RoleARN,
+		ExternalID:      cfg.ExternalID,
+	}
+}
+
 func GetRandomNode(nodeMap map[string]*model.Machine) *model.Machine {
 	for key := range nodeMap {
 		return nodeMap[key]
@@ -171,6 +198,13 @@ func LoadCloudSpecificDataFromKube(k *model.Kube, config *steps.Config) error {
 		config.AWSConfig.ExternalLoadBalancerName = k.CloudSpec[clouds.AwsExternalLoadBalancerName]
 		config.AWSConfig.InternalLoadBalancerName = k.CloudSpec[clouds.AwsInternalLoadBalancerName]
 		config.AWSConfig.VolumeSize = k.CloudSpec[clouds.AwsVolumeSize]
+		config.AWSConfig.VolumeType = k.CloudSpec[clouds.AwsVolumeType]
+		config.AWSConfig.Iops = k.CloudSpec[clouds.AwsVolumeIops]
+		config.AWSConfig.DeleteOnTermination = k.CloudSpec[clouds.AwsDeleteOnTermination]
+		config.AWSConfig.EbsEncrypted = k.CloudSpec[clouds.AwsEbsEncrypted]
+		config.AWSConfig.KmsKeyID = k.CloudSpec[clouds.AwsKmsKeyID]
+		config.AWSConfig.HttpTokens = k.CloudSpec[clouds.AwsHttpTokens]
+		config.AWSConfig.HttpPutResponseHopLimit = k.CloudSpec[clouds.AwsHttpPutResponseHopLimit]
 	case clouds.GCE:
 		config.GCEConfig.Region = k.Region
 		config.GCEConfig.TargetPoolName = k.CloudSpec[clouds.GCETargetPoolName]
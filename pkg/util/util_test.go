@@ -207,6 +207,33 @@ func TestFillCloudAccountCredentials(t *testing.T) {
 	}
 }
 
+func TestFillCloudAccountCredentialsDefaultChain(t *testing.T) {
+	account := &model.CloudAccount{
+		Name:     "testName",
+		Provider: clouds.AWS,
+		Credentials: map[string]string{
+			clouds.AWSAccessKeyID:     "1",
+			clouds.AWSSecretKey:       "secret-key",
+			clouds.AWSSessionToken:    "token",
+			clouds.AWSUseDefaultChain: "true",
+		},
+	}
+	config := &steps.Config{}
+
+	if err := FillCloudAccountCredentials(account, config); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if config.AWSConfig.KeyID != "" || config.AWSConfig.Secret != "" ||
+		config.AWSConfig.SessionToken != "" {
+		t.Errorf("Keys must be empty %v", config.AWSConfig)
+	}
+
+	if !AWSCredentials(config.AWSConfig).UseDefaultChain {
+		t.Errorf("Default credential chain must be used")
+	}
+}
+
 func TestGetLogger(t *testing.T) {
 	writer := &bytes.Buffer{}
 	logger := GetLogger(writer)
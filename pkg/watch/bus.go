@@ -0,0 +1,186 @@
+// Package watch streams changes of entities kept in the storage, writes
+// to the storage are published to an in-process bus clients subscribe to.
+package watch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Types of changes
+const (
+	Created = "created"
+	Updated = "updated"
+	Deleted = "deleted"
+)
+
+const (
+	// DefaultHistorySize is count of the last events subscriptions
+	// may be resumed from.
+	DefaultHistorySize = 256
+	// Events pending delivery to a subscriber, subscribers falling
+	// behind are dropped and resume from the last event they have got.
+	subscriberBuffer = 64
+)
+
+// ErrExpired is returned when events following the token are not kept
+// anymore, entities must be listed again.
+var ErrExpired = errors.New("events following the token have expired")
+
+// Event is a change of the value of the key within the prefix.
+type Event struct {
+	// Token identifies the event, subscriptions resume from it
+	Token string
+	Type  string
+	// Prefix and key the value is stored under
+	Prefix string
+	Key    string
+	// Value is the value written, or the last value of deleted keys
+	Value []byte
+
+	seq uint64
+}
+
+// Bus delivers events published to it to subscribers and keeps
+// the last events for subscriptions resumed from their tokens.
+type Bus struct {
+	m sync.Mutex
+	// Tokens of events published before restart are not accepted
+	epoch       string
+	seq         uint64
+	history     []Event
+	size        int
+	subscribers map[*Subscription]struct{}
+}
+
+// NewBus creates a bus keeping size last events.
+func NewBus(size int) *Bus {
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+
+	return &Bus{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		history:     make([]Event, 0, size),
+		size:        size,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish sends the event to subscribers.
+func (b *Bus) Publish(e Event) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.seq++
+	e.seq = b.seq
+	e.Token = fmt.Sprintf("%s.%d", b.epoch, b.seq)
+
+	if len(b.history) == b.size {
+		b.history = append(b.history[:0], b.history[1:]...)
+	}
+	b.history = append(b.history, e)
+
+	for s := range b.subscribers {
+		select {
+		case s.events <- e:
+		default:
+			b.drop(s)
+		}
+	}
+}
+
+// Subscribe returns events following the token along with a subscription
+// to the ones published later, empty token subscribes to later events only.
+// ErrExpired is returned when events following the token are not kept.
+func (b *Bus) Subscribe(token string) ([]Event, *Subscription, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	var backlog []Event
+
+	if token != "" {
+		seq, err := b.parseToken(token)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(b.history) > 0 && seq+1 < b.history[0].seq {
+			return nil, nil, ErrExpired
+		}
+
+		for _, e := range b.history {
+			if e.seq > seq {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+
+	s := &Subscription{
+		bus:    b,
+		events: make(chan Event, subscriberBuffer),
+		token:  fmt.Sprintf("%s.%d", b.epoch, b.seq),
+	}
+	b.subscribers[s] = struct{}{}
+
+	return backlog, s, nil
+}
+
+func (b *Bus) parseToken(token string) (uint64, error) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return 0, errors.Errorf("malformed token %s", token)
+	}
+
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, errors.Errorf("malformed token %s", token)
+	}
+
+	// Sequence of events is restarted along with control
+	if parts[0] != b.epoch || seq > b.seq {
+		return 0, ErrExpired
+	}
+
+	return seq, nil
+}
+
+func (b *Bus) drop(s *Subscription) {
+	if _, ok := b.subscribers[s]; ok {
+		delete(b.subscribers, s)
+		close(s.events)
+	}
+}
+
+// Subscription receives events published to the bus.
+type Subscription struct {
+	bus    *Bus
+	events chan Event
+	// Token of the last event published before the subscription
+	token string
+}
+
+// Token returns the token of the last event published before the
+// subscription, events following it are delivered to the subscription.
+func (s *Subscription) Token() string {
+	return s.token
+}
+
+// Events returns channel of events, it is closed when the subscription
+// is closed or has fallen behind.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close stops delivery of events.
+func (s *Subscription) Close() {
+	s.bus.m.Lock()
+	defer s.bus.m.Unlock()
+
+	s.bus.drop(s)
+}
@@ -0,0 +1,158 @@
+package watch
+
+import (
+	"fmt"
+	"testing"
+)
+
+func publish(b *Bus, keys ...string) {
+	for _, key := range keys {
+		b.Publish(Event{
+			Type:   Created,
+			Prefix: "/test/",
+			Key:    key,
+		})
+	}
+}
+
+func TestBusSubscribe(t *testing.T) {
+	b := NewBus(0)
+
+	_, sub, err := b.Subscribe("")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer sub.Close()
+
+	publish(b, "1", "2")
+
+	for _, key := range []string{"1", "2"} {
+		e := <-sub.Events()
+
+		if e.Key != key {
+			t.Errorf("Expected event of %s actual %s", key, e.Key)
+		}
+	}
+
+	sub.Close()
+
+	if _, ok := <-sub.Events(); ok {
+		t.Errorf("Events must be closed along with the subscription")
+	}
+
+	// Closing twice is allowed
+	sub.Close()
+}
+
+func TestBusResume(t *testing.T) {
+	b := NewBus(3)
+	publish(b, "1", "2", "3")
+
+	_, sub, _ := b.Subscribe("")
+	token := sub.Token()
+	sub.Close()
+
+	if token != b.history[2].Token {
+		t.Errorf("Expected token of the last event %s actual %s", b.history[2].Token, token)
+	}
+
+	publish(b, "4", "5")
+
+	testCases := []struct {
+		description string
+		token       string
+		expected    []string
+		err         error
+	}{
+		{
+			description: "resume from the last event seen",
+			token:       token,
+			expected:    []string{"4", "5"},
+		},
+		{
+			description: "resume from the oldest event kept",
+			token:       fmt.Sprintf("%s.%d", b.epoch, 2),
+			expected:    []string{"3", "4", "5"},
+		},
+		{
+			description: "up to date",
+			token:       fmt.Sprintf("%s.%d", b.epoch, 5),
+		},
+		{
+			description: "events following the token have expired",
+			token:       fmt.Sprintf("%s.%d", b.epoch, 1),
+			err:         ErrExpired,
+		},
+		{
+			description: "token of events before restart",
+			token:       "previous.4",
+			err:         ErrExpired,
+		},
+		{
+			description: "token of events not published yet",
+			token:       fmt.Sprintf("%s.%d", b.epoch, 6),
+			err:         ErrExpired,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		backlog, sub, err := b.Subscribe(testCase.token)
+		if err != testCase.err {
+			t.Errorf("Expected error %v actual %v", testCase.err, err)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+		sub.Close()
+
+		if len(backlog) != len(testCase.expected) {
+			t.Errorf("Expected backlog %v actual %v", testCase.expected, backlog)
+			continue
+		}
+
+		for i, key := range testCase.expected {
+			if backlog[i].Key != key {
+				t.Errorf("Expected event of %s actual %s", key, backlog[i].Key)
+			}
+		}
+	}
+}
+
+func TestBusMalformedToken(t *testing.T) {
+	b := NewBus(0)
+
+	for _, token := range []string{"token", "epoch.seq"} {
+		if _, _, err := b.Subscribe(token); err == nil || err == ErrExpired {
+			t.Errorf("Expected malformed token error for %s actual %v", token, err)
+		}
+	}
+}
+
+func TestBusDropsSlowSubscribers(t *testing.T) {
+	b := NewBus(0)
+
+	_, slow, _ := b.Subscribe("")
+	defer slow.Close()
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		publish(b, fmt.Sprintf("%d", i))
+	}
+
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+
+	if received != subscriberBuffer {
+		t.Errorf("Expected %d events before the subscriber is dropped actual %d",
+			subscriberBuffer, received)
+	}
+
+	if len(b.subscribers) != 0 {
+		t.Errorf("Slow subscriber must be dropped")
+	}
+}
@@ -0,0 +1,225 @@
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/api"
+	"github.com/supergiant/control/pkg/message"
+	"github.com/supergiant/control/pkg/sgerrors"
+)
+
+// Comments are sent while no events are published to keep streams open
+const heartbeatInterval = 30 * time.Second
+
+// Resource is a kind of entities clients watch.
+type Resource struct {
+	// Name of the resource in requests and events, e.g. kubes
+	Name   string
+	Prefix string
+	// KubeIDField is json name of the field of entities holding id of
+	// their kube, keys of entities are ids of kubes when it is empty.
+	KubeIDField string
+	// Fields of entities sent whatever fields are selected
+	KeyFields []string
+	// Fields of entities sent only when they are selected
+	OmittedFields []string
+}
+
+// Handler streams events of resources as server-sent events.
+type Handler struct {
+	bus       *Bus
+	resources []Resource
+	heartbeat time.Duration
+}
+
+// change is data of events sent to clients.
+type change struct {
+	Type     string                     `json:"type"`
+	Resource string                     `json:"resource"`
+	ID       string                     `json:"id"`
+	Object   map[string]json.RawMessage `json:"object,omitempty"`
+}
+
+func NewHandler(bus *Bus, resources ...Resource) *Handler {
+	return &Handler{
+		bus:       bus,
+		resources: resources,
+		heartbeat: heartbeatInterval,
+	}
+}
+
+func (h *Handler) Register(r *mux.Router) {
+	r.HandleFunc("/watch", h.Watch).Methods(http.MethodGet)
+}
+
+// Watch streams changes of resources selected by the resources parameter,
+// all of them by default, as events named after type of the change.
+// Changes of entities of the kube passed with kubeID parameter are sent
+// only when it is set. Clients resume from the token of the last event
+// passed with the since parameter or Last-Event-ID header, 410 is sent
+// when events following it have expired and entities must be listed again.
+func (h *Handler) Watch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	resources, err := h.selectResources(query.Get("resources"))
+	if err != nil {
+		message.SendValidationFailed(w, err)
+		return
+	}
+
+	token := query.Get("since")
+	if token == "" {
+		token = r.Header.Get("Last-Event-ID")
+	}
+
+	backlog, sub, err := h.bus.Subscribe(token)
+	if err != nil {
+		if err == ErrExpired {
+			message.SendMessage(w, message.New("Changes have expired, list entities again",
+				err.Error(), sgerrors.NotFound, ""), http.StatusGone)
+			return
+		}
+
+		message.SendValidationFailed(w, errors.Wrap(sgerrors.ErrValidationFailed, err.Error()))
+		return
+	}
+	defer sub.Close()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	kubeID, fields := query.Get("kubeID"), api.ParseFields(query)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	// Clients resume from the bookmark until they get the first change
+	if len(backlog) == 0 {
+		if _, err := fmt.Fprintf(w, "id: %s\nevent: bookmark\ndata: {}\n\n", sub.Token()); err != nil {
+			return
+		}
+	}
+
+	for _, e := range backlog {
+		if err := h.send(w, e, resources, kubeID, fields); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.Events():
+			// Subscribers falling behind resume from the last event
+			if !ok {
+				return
+			}
+
+			if err := h.send(w, e, resources, kubeID, fields); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+
+		flusher.Flush()
+	}
+}
+
+func (h *Handler) selectResources(names string) ([]Resource, error) {
+	if names == "" {
+		return h.resources, nil
+	}
+
+	selected := make([]Resource, 0)
+
+	for _, name := range strings.Split(names, ",") {
+		found := false
+
+		for _, resource := range h.resources {
+			if resource.Name == strings.TrimSpace(name) {
+				selected = append(selected, resource)
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, errors.Wrapf(sgerrors.ErrValidationFailed, "unknown resource %s", name)
+		}
+	}
+
+	return selected, nil
+}
+
+// send writes the event when it is a change of the resources
+// and of the kube when it is set.
+func (h *Handler) send(w io.Writer, e Event, resources []Resource, kubeID string, fields []string) error {
+	for _, resource := range resources {
+		if resource.Prefix != e.Prefix {
+			continue
+		}
+
+		if kubeID != "" && kubeIDOf(resource, e) != kubeID {
+			return nil
+		}
+
+		objects, err := api.SelectFields([]json.RawMessage{e.Value}, fields,
+			resource.KeyFields, resource.OmittedFields)
+		if err != nil {
+			logrus.Errorf("watch: %s %s is not an object %v", resource.Name, e.Key, err)
+			return nil
+		}
+
+		data, err := json.Marshal(change{
+			Type:     e.Type,
+			Resource: resource.Name,
+			ID:       e.Key,
+			Object:   objects[0],
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.Token, e.Type, data)
+		return err
+	}
+
+	return nil
+}
+
+func kubeIDOf(resource Resource, e Event) string {
+	if resource.KubeIDField == "" {
+		return e.Key
+	}
+
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(e.Value, &fields); err != nil {
+		return ""
+	}
+
+	var kubeID string
+	json.Unmarshal(fields[resource.KubeIDField], &kubeID)
+
+	return kubeID
+}
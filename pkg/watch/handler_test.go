@@ -0,0 +1,200 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestHandlerWatch(t *testing.T) {
+	b := NewBus(4)
+
+	events := []Event{
+		{Type: Created, Prefix: "/kubes/", Key: "1", Value: []byte(`{"id":"1","name":"one","auth":{}}`)},
+		{Type: Created, Prefix: "/kubes/", Key: "2", Value: []byte(`{"id":"2","name":"two","auth":{}}`)},
+		{Type: Updated, Prefix: "tasks", Key: "a", Value: []byte(`{"id":"a","kubeId":"1","status":"executing"}`)},
+		{Type: Deleted, Prefix: "/kubes/", Key: "2", Value: []byte(`{"id":"2","name":"two","auth":{}}`)},
+		{Type: Created, Prefix: "/accounts/", Key: "aws", Value: []byte(`{"name":"aws"}`)},
+	}
+	for _, e := range events {
+		b.Publish(e)
+	}
+
+	token := func(seq int) string {
+		return fmt.Sprintf("%s.%d", b.epoch, seq)
+	}
+
+	testCases := []struct {
+		description  string
+		query        string
+		lastEventID  string
+		expectedCode int
+		expected     []string
+	}{
+		{
+			description:  "unknown resource",
+			query:        "?resources=kubes,nodes",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "malformed token",
+			query:        "?since=abc",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "expired token",
+			query:        "?since=" + token(0),
+			expectedCode: http.StatusGone,
+		},
+		{
+			description:  "up to date",
+			query:        "?since=" + token(5),
+			expectedCode: http.StatusOK,
+			expected: []string{
+				"id: " + token(5) + "\nevent: bookmark\n",
+			},
+		},
+		{
+			description:  "all resources",
+			query:        "?since=" + token(1),
+			expectedCode: http.StatusOK,
+			expected: []string{
+				"id: " + token(2) + "\nevent: created\ndata: " +
+					`{"type":"created","resource":"kubes","id":"2","object":{"id":"2","name":"two"}}`,
+				"id: " + token(3) + "\nevent: updated\ndata: " +
+					`{"type":"updated","resource":"tasks","id":"a","object":{"id":"a","kubeId":"1","status":"executing"}}`,
+				"id: " + token(4) + "\nevent: deleted\ndata: " +
+					`{"type":"deleted","resource":"kubes","id":"2","object":{"id":"2","name":"two"}}`,
+			},
+		},
+		{
+			description:  "changes of the kube",
+			query:        "?kubeID=1&fields=status",
+			lastEventID:  token(1),
+			expectedCode: http.StatusOK,
+			expected: []string{
+				"id: " + token(3) + "\nevent: updated\ndata: " +
+					`{"type":"updated","resource":"tasks","id":"a","object":{"id":"a","status":"executing"}}`,
+			},
+		},
+		{
+			description:  "selected resources with all fields",
+			query:        "?resources=kubes&fields=*&since=" + token(3),
+			expectedCode: http.StatusOK,
+			expected: []string{
+				"id: " + token(4) + "\nevent: deleted\ndata: " +
+					`{"type":"deleted","resource":"kubes","id":"2","object":{"auth":{},"id":"2","name":"two"}}`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		h := NewHandler(b,
+			Resource{
+				Name:          "kubes",
+				Prefix:        "/kubes/",
+				KeyFields:     []string{"id"},
+				OmittedFields: []string{"auth"},
+			},
+			Resource{
+				Name:        "tasks",
+				Prefix:      "tasks",
+				KubeIDField: "kubeId",
+				KeyFields:   []string{"id"},
+			})
+
+		router := mux.NewRouter()
+		h.Register(router)
+
+		// Streams end along with requests
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		req, _ := http.NewRequest(http.MethodGet, "/watch"+testCase.query, nil)
+		req = req.WithContext(ctx)
+
+		if testCase.lastEventID != "" {
+			req.Header.Set("Last-Event-ID", testCase.lastEventID)
+		}
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		cancel()
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("Expected code %d actual %d", testCase.expectedCode, rec.Code)
+			continue
+		}
+
+		body := rec.Body.String()
+
+		for _, event := range testCase.expected {
+			if !strings.Contains(body, event) {
+				t.Errorf("Event %q not found in %s", event, body)
+			}
+		}
+
+		if strings.Count(body, "event: ") != len(testCase.expected) {
+			t.Errorf("Wrong number of events %s", body)
+		}
+	}
+
+	if len(b.subscribers) != 0 {
+		t.Errorf("Subscriptions must be closed along with streams")
+	}
+}
+
+func TestHandlerWatchLive(t *testing.T) {
+	b := NewBus(0)
+	h := NewHandler(b, Resource{
+		Name:   "kubes",
+		Prefix: "/kubes/",
+	})
+	h.heartbeat = time.Millisecond
+
+	router := mux.NewRouter()
+	h.Register(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/watch")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Wrong content type %s", ct)
+	}
+
+	buf := make([]byte, 1024)
+	readUntil := func(expected string) {
+		received := ""
+		for !strings.Contains(received, expected) {
+			n, err := resp.Body.Read(buf)
+			if err != nil {
+				t.Fatalf("%s not received %s %v", expected, received, err)
+			}
+			received += string(buf[:n])
+		}
+	}
+
+	// Heartbeats follow the bookmark while nothing is published
+	readUntil(": heartbeat")
+
+	b.Publish(Event{
+		Type:   Created,
+		Prefix: "/kubes/",
+		Key:    "1",
+		Value:  []byte(`{"id":"1"}`),
+	})
+
+	readUntil("event: created")
+}
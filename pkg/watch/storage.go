@@ -0,0 +1,130 @@
+package watch
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/storage"
+)
+
+// Storage publishes writes of keys within the prefixes to the bus,
+// keys of other prefixes are written without events.
+type Storage struct {
+	storage.Interface
+
+	bus      *Bus
+	prefixes map[string]struct{}
+}
+
+// NewStorage wraps the storage publishing writes within the prefixes.
+func NewStorage(s storage.Interface, bus *Bus, prefixes ...string) *Storage {
+	watched := make(map[string]struct{}, len(prefixes))
+	for _, prefix := range prefixes {
+		watched[prefix] = struct{}{}
+	}
+
+	return &Storage{
+		Interface: s,
+		bus:       bus,
+		prefixes:  watched,
+	}
+}
+
+func (s *Storage) Put(ctx context.Context, prefix string, key string, value []byte) error {
+	if !s.watched(prefix) {
+		return s.Interface.Put(ctx, prefix, key, value)
+	}
+
+	old, err := s.get(ctx, prefix, key)
+	if err != nil {
+		return err
+	}
+
+	if err = s.Interface.Put(ctx, prefix, key, value); err != nil {
+		return err
+	}
+
+	s.publish(prefix, key, old, value)
+	return nil
+}
+
+// CompareAndSwap puts value of the key when its stored value equals to old.
+func (s *Storage) CompareAndSwap(ctx context.Context, prefix string, key string, old, value []byte) error {
+	if err := storage.CompareAndSwap(ctx, s.Interface, prefix, key, old, value); err != nil {
+		return err
+	}
+
+	if s.watched(prefix) {
+		s.publish(prefix, key, old, value)
+	}
+
+	return nil
+}
+
+func (s *Storage) Delete(ctx context.Context, prefix string, key string) error {
+	if !s.watched(prefix) {
+		return s.Interface.Delete(ctx, prefix, key)
+	}
+
+	old, err := s.get(ctx, prefix, key)
+	if err != nil {
+		return err
+	}
+
+	if err = s.Interface.Delete(ctx, prefix, key); err != nil {
+		return err
+	}
+
+	if old != nil {
+		s.bus.Publish(Event{
+			Type:   Deleted,
+			Prefix: prefix,
+			Key:    key,
+			Value:  old,
+		})
+	}
+
+	return nil
+}
+
+// Export returns values by keys starting with the prefix
+// when the wrapped storage is able to list its keys.
+func (s *Storage) Export(ctx context.Context, prefix string) (map[string][]byte, error) {
+	exporter, ok := s.Interface.(storage.Exporter)
+	if !ok {
+		return nil, errors.New("keys of the storage can not be listed")
+	}
+
+	return exporter.Export(ctx, prefix)
+}
+
+func (s *Storage) watched(prefix string) bool {
+	_, ok := s.prefixes[prefix]
+	return ok
+}
+
+// get returns value of the key, nil is returned for missing keys.
+func (s *Storage) get(ctx context.Context, prefix, key string) ([]byte, error) {
+	value, err := s.Interface.Get(ctx, prefix, key)
+	if sgerrors.IsNotFound(err) {
+		return nil, nil
+	}
+
+	return value, err
+}
+
+func (s *Storage) publish(prefix, key string, old, value []byte) {
+	eventType := Updated
+	if old == nil {
+		eventType = Created
+	}
+
+	s.bus.Publish(Event{
+		Type:   eventType,
+		Prefix: prefix,
+		Key:    key,
+		Value:  value,
+	})
+}
@@ -0,0 +1,74 @@
+package watch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/storage/memory"
+	"github.com/supergiant/control/pkg/testutils/storage"
+)
+
+func TestStorage_Conformance(t *testing.T) {
+	storage.Conformance(t, func(t *testing.T) (storage.Repository, func()) {
+		return NewStorage(memory.NewInMemoryRepository(), NewBus(0), "/test/kubes/"), func() {}
+	})
+}
+
+func TestStoragePublishes(t *testing.T) {
+	ctx := context.Background()
+	b := NewBus(0)
+	s := NewStorage(memory.NewInMemoryRepository(), b, "/kubes/")
+
+	_, sub, _ := b.Subscribe("")
+	defer sub.Close()
+
+	if err := s.Put(ctx, "/kubes/", "1", []byte("one")); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if err := s.Put(ctx, "/kubes/", "1", []byte("two")); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if err := s.CompareAndSwap(ctx, "/kubes/", "1", []byte("one"), []byte("three")); !sgerrors.IsConflict(err) {
+		t.Fatalf("Expected conflict actual %v", err)
+	}
+	if err := s.CompareAndSwap(ctx, "/kubes/", "1", []byte("two"), []byte("three")); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if err := s.CompareAndSwap(ctx, "/kubes/", "2", nil, []byte("other")); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if err := s.Delete(ctx, "/kubes/", "1"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	// Keys of other prefixes are not watched
+	if err := s.Put(ctx, "/accounts/", "1", []byte("account")); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if err := s.Delete(ctx, "/accounts/", "1"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []Event{
+		{Type: Created, Prefix: "/kubes/", Key: "1", Value: []byte("one")},
+		{Type: Updated, Prefix: "/kubes/", Key: "1", Value: []byte("two")},
+		{Type: Updated, Prefix: "/kubes/", Key: "1", Value: []byte("three")},
+		{Type: Created, Prefix: "/kubes/", Key: "2", Value: []byte("other")},
+		{Type: Deleted, Prefix: "/kubes/", Key: "1", Value: []byte("three")},
+	}
+
+	for _, e := range expected {
+		actual := <-sub.Events()
+
+		if actual.Type != e.Type || actual.Prefix != e.Prefix ||
+			actual.Key != e.Key || string(actual.Value) != string(e.Value) {
+			t.Errorf("Expected event %s %s%s %s actual %s %s%s %s",
+				e.Type, e.Prefix, e.Key, e.Value,
+				actual.Type, actual.Prefix, actual.Key, actual.Value)
+		}
+	}
+
+	if len(sub.Events()) != 0 {
+		t.Errorf("Unexpected events of unwatched prefix")
+	}
+}
@@ -19,8 +19,10 @@ import (
 	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/runner"
 	"github.com/supergiant/control/pkg/runner/ssh"
+	"github.com/supergiant/control/pkg/sgerrors"
 	"github.com/supergiant/control/pkg/storage"
 	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/statuses"
 	"github.com/supergiant/control/pkg/workflows/steps"
 )
 
@@ -28,13 +30,21 @@ type cloudAccountGetter interface {
 	Get(context.Context, string) (*model.CloudAccount, error)
 }
 
+// TaskRestarter resumes the task against the kube it belongs to
+// and saves updates of the task to the kube.
+type TaskRestarter interface {
+	RestartTask(context.Context, *Task, io.WriteCloser) error
+}
+
 type TaskHandler struct {
 	runnerFactory func(config ssh.Config) (runner.Runner, error)
 	getTail       func(string) (*tail.Tail, error)
 
 	cloudAccGetter cloudAccountGetter
+	restarter      TaskRestarter
 	repository     storage.Interface
 	getWriter      func(string) (io.WriteCloser, error)
+	openLog        func(string) (*os.File, error)
 }
 
 type RunTaskRequest struct {
@@ -52,12 +62,16 @@ type TaskResponse struct {
 	ID string `json:"id"`
 }
 
-func NewTaskHandler(repository storage.Interface, runnerFactory func(config ssh.Config) (runner.Runner, error), getter cloudAccountGetter, logDir string) *TaskHandler {
+func NewTaskHandler(repository storage.Interface, runnerFactory func(config ssh.Config) (runner.Runner, error), getter cloudAccountGetter, restarter TaskRestarter, logDir string) *TaskHandler {
 	return &TaskHandler{
 		runnerFactory:  runnerFactory,
 		repository:     repository,
 		cloudAccGetter: getter,
+		restarter:      restarter,
 		getWriter:      util.GetWriterFunc(logDir),
+		openLog: func(id string) (*os.File, error) {
+			return os.Open(path.Join(logDir, util.MakeFileName(id)))
+		},
 		getTail: func(id string) (*tail.Tail, error) {
 			t, err := tail.TailFile(path.Join(logDir, util.MakeFileName(id)),
 				tail.Config{
@@ -84,8 +98,11 @@ func (h *TaskHandler) Register(m *mux.Router) {
 	m.HandleFunc("/tasks/{id}", h.GetTask).Methods(http.MethodGet)
 	m.HandleFunc("/tasks/{id}/restart",
 		h.RestartTask).Methods(http.MethodPost)
+	m.HandleFunc("/tasks/{id}/cancel",
+		h.CancelTask).Methods(http.MethodPost)
 	m.HandleFunc("/tasks/{id}/logs", h.StreamLogs).Methods(http.MethodGet)
 	m.HandleFunc("/tasks/{id}/logs/ws", h.GetLogs).Methods(http.MethodGet)
+	m.HandleFunc("/tasks/{id}/logs/stream", h.StreamTaskLogs).Methods(http.MethodGet)
 }
 
 func (h *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
@@ -133,6 +150,22 @@ func (h *TaskHandler) RestartTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if task.Status == statuses.Success {
+		http.Error(w, fmt.Sprintf("task %s has already finished", id), http.StatusConflict)
+		return
+	}
+
+	// Task run twice at once would repeat its steps concurrently
+	if isRunning(id) {
+		http.Error(w, fmt.Sprintf("task %s is running", id), http.StatusConflict)
+		return
+	}
+
+	if task.Config == nil {
+		http.Error(w, fmt.Sprintf("task %s has no config", id), http.StatusInternalServerError)
+		return
+	}
+
 	fileName := util.MakeFileName(id)
 	writer, err := h.getWriter(fileName)
 
@@ -142,7 +175,61 @@ func (h *TaskHandler) RestartTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task.Run(context.Background(), *task.Config, writer)
+	if err := h.restarter.RestartTask(context.Background(), task, writer); err != nil {
+		logrus.Errorf("restart task %s %v", id, err)
+		switch {
+		case sgerrors.IsValidationFailed(err):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case sgerrors.IsNotFound(err):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// CancelTask interrupts the running task, the task and its interrupted
+// step are saved as cancelled.
+func (h *TaskHandler) CancelTask(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	data, err := h.repository.Get(r.Context(), Prefix, id)
+
+	if err != nil {
+		logrus.Debugf("task %s not found", id)
+		http.NotFound(w, r)
+		return
+	}
+
+	task, err := DeserializeTask(data, h.repository)
+
+	if err != nil {
+		logrus.Debugf("error deserializing task %s %v", id, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	switch task.Status {
+	case statuses.Success, statuses.Error, statuses.Cancelled:
+		http.Error(w, fmt.Sprintf("task %s has already finished", id), http.StatusConflict)
+		return
+	}
+
+	err = CancelTask(id)
+
+	if sgerrors.IsNotFound(err) {
+		// Task has been left in executing state by previous run of control
+		if err := task.cancelled(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusAccepted)
 }
 
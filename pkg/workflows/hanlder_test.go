@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/hpcloud/tail"
@@ -18,7 +19,9 @@ import (
 	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/runner"
 	"github.com/supergiant/control/pkg/runner/ssh"
+	"github.com/supergiant/control/pkg/sgerrors"
 	"github.com/supergiant/control/pkg/testutils"
+	"github.com/supergiant/control/pkg/workflows/statuses"
 	"github.com/supergiant/control/pkg/workflows/steps"
 )
 
@@ -99,6 +102,7 @@ func TestTaskHandlerRestartTask(t *testing.T) {
 			},
 			err: nil,
 		},
+		restarter: &mockRestarter{},
 		getWriter: func(id string) (io.WriteCloser, error) {
 			return &bufferCloser{}, nil
 		},
@@ -254,9 +258,254 @@ func TestTaskHandler_StreamLogs(t *testing.T) {
 
 func TestNewTaskHandler(t *testing.T) {
 	r := &testutils.MockStorage{}
-	h := NewTaskHandler(r, nil, nil, "")
+	h := NewTaskHandler(r, nil, nil, nil, "")
 
 	if h == nil {
 		t.Errorf("Handler must not be nil")
 	}
 }
+
+// mockRestarter resumes the task in place and drains its updates
+type mockRestarter struct {
+	err error
+	// Results of restarted tasks
+	done chan error
+}
+
+func (m *mockRestarter) RestartTask(ctx context.Context, task *Task, out io.WriteCloser) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	nodeChan := make(chan model.Machine)
+	task.Config.SetNodeChan(nodeChan)
+	task.Config.SetKubeStateChan(make(chan model.KubeState))
+	task.Config.SetConfigChan(make(chan *steps.Config))
+
+	errChan := task.Run(ctx, task.Config, out)
+	go func() {
+		for {
+			select {
+			case <-nodeChan:
+			case err := <-errChan:
+				if m.done != nil {
+					m.done <- err
+				}
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+type configStep struct {
+	MockStep
+	configs chan *steps.Config
+}
+
+func (s *configStep) Run(ctx context.Context, out io.Writer, config *steps.Config) error {
+	s.counter++
+	config.NodeChan() <- config.Node
+	s.configs <- config
+	return nil
+}
+
+func TestTaskHandlerRestartResumes(t *testing.T) {
+	repository := &MockRepository{
+		make(map[string][]byte),
+	}
+	restarter := &mockRestarter{
+		done: make(chan error, 1),
+	}
+	h := TaskHandler{
+		repository: repository,
+		restarter:  restarter,
+		getWriter: func(id string) (io.WriteCloser, error) {
+			return &bufferCloser{}, nil
+		},
+	}
+
+	completed := &MockStep{name: "completed"}
+	failed := &configStep{
+		MockStep: MockStep{name: "failed"},
+		configs:  make(chan *steps.Config, 1),
+	}
+
+	workflowMap = make(map[string]Workflow)
+	RegisterWorkFlow("resume", []steps.Step{completed, failed})
+
+	task := &Task{
+		ID:     "1234",
+		Type:   "resume",
+		Status: statuses.Error,
+		Config: &steps.Config{
+			TaskID: "1234",
+			AWSConfig: steps.AWSConfig{
+				Subnets: map[string]string{"us-west-1a": "subnet-1"},
+			},
+		},
+		StepStatuses: []StepStatus{
+			{StepName: "completed", Status: statuses.Success},
+			{StepName: "failed", Status: statuses.Error},
+		},
+	}
+
+	data, _ := json.Marshal(task)
+	repository.Put(context.Background(), Prefix, task.ID, data)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/tasks/{id}/restart", h.RestartTask)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/tasks/1234/restart", nil)
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Wrong response code expected %d received %d", http.StatusAccepted, rec.Code)
+	}
+
+	select {
+	case cfg := <-failed.configs:
+		if cfg.AWSConfig.Subnets["us-west-1a"] != "subnet-1" {
+			t.Errorf("Config of the task must be restored %+v", cfg.AWSConfig)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Failed step has not been resumed")
+	}
+
+	// Task is saved to the repository until it finishes
+	select {
+	case err := <-restarter.done:
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Restarted task has not finished")
+	}
+
+	if completed.counter != 0 {
+		t.Errorf("Completed step must be skipped")
+	}
+
+	// Running task is not restarted
+	data, _ = json.Marshal(task)
+	repository.Put(context.Background(), Prefix, task.ID, data)
+
+	runningMux.Lock()
+	running[task.ID] = &taskRun{cancel: func() {}}
+	runningMux.Unlock()
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/tasks/1234/restart", nil)
+	router.ServeHTTP(rec, req)
+
+	runningMux.Lock()
+	delete(running, task.ID)
+	runningMux.Unlock()
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Wrong response code expected %d received %d", http.StatusConflict, rec.Code)
+	}
+
+	// Finished task is not restarted
+	task.Status = statuses.Success
+	data, _ = json.Marshal(task)
+	repository.Put(context.Background(), Prefix, task.ID, data)
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/tasks/1234/restart", nil)
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Wrong response code expected %d received %d", http.StatusConflict, rec.Code)
+	}
+
+	// Task that cannot be resumed against its kube
+	task.Status = statuses.Error
+	data, _ = json.Marshal(task)
+	repository.Put(context.Background(), Prefix, task.ID, data)
+	restarter.err = errors.Wrap(sgerrors.ErrValidationFailed, "no kube")
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/tasks/1234/restart", nil)
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Wrong response code expected %d received %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestTaskHandlerCancelTask(t *testing.T) {
+	testCases := []struct {
+		description    string
+		task           *Task
+		expectedCode   int
+		expectedStatus statuses.Status
+	}{
+		{
+			description: "finished",
+			task: &Task{
+				ID:     "1234",
+				Status: statuses.Success,
+			},
+			expectedCode:   http.StatusConflict,
+			expectedStatus: statuses.Success,
+		},
+		{
+			description: "left executing",
+			task: &Task{
+				ID:     "1234",
+				Status: statuses.Executing,
+				StepStatuses: []StepStatus{
+					{StepName: "step1", Status: statuses.Success},
+					{StepName: "step2", Status: statuses.Executing},
+				},
+			},
+			expectedCode:   http.StatusAccepted,
+			expectedStatus: statuses.Cancelled,
+		},
+	}
+
+	for _, testCase := range testCases {
+		repository := &MockRepository{
+			make(map[string][]byte),
+		}
+		h := TaskHandler{
+			repository: repository,
+		}
+
+		data, _ := json.Marshal(testCase.task)
+		repository.Put(context.Background(), Prefix, testCase.task.ID, data)
+
+		router := mux.NewRouter()
+		router.HandleFunc("/tasks/{id}/cancel", h.CancelTask)
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/tasks/1234/cancel", nil)
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("%s: wrong response code expected %d received %d",
+				testCase.description, testCase.expectedCode, rec.Code)
+			continue
+		}
+
+		task := &Task{}
+		if err := json.Unmarshal(repository.storage[Prefix+"1234"], task); err != nil {
+			t.Errorf("%s: unexpected error %v", testCase.description, err)
+			continue
+		}
+
+		if task.Status != testCase.expectedStatus {
+			t.Errorf("%s: wrong task status expected %s actual %s",
+				testCase.description, testCase.expectedStatus, task.Status)
+		}
+
+		if testCase.expectedStatus == statuses.Cancelled &&
+			task.StepStatuses[1].Status != statuses.Cancelled {
+			t.Errorf("%s: interrupted step must be cancelled %v",
+				testCase.description, task.StepStatuses)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/storage"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/statuses"
+)
+
+const (
+	// IndexPrefix keeps ids of tasks run against kubes by kube id
+	IndexPrefix = "/supergiant/kube_task_index/"
+
+	DefaultTaskRetention = 30 * 24 * time.Hour
+	DefaultPruneInterval = time.Hour
+)
+
+func kubeIndexPrefix(kubeID string) string {
+	return IndexPrefix + kubeID + "/"
+}
+
+// GetKubeTasks returns tasks run against the kube.
+func GetKubeTasks(ctx context.Context, repository storage.Interface, kubeID string) ([]*Task, error) {
+	ids, err := repository.GetAll(ctx, kubeIndexPrefix(kubeID))
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "get task index of kube %s", kubeID)
+	}
+
+	tasks := make([]*Task, 0, len(ids))
+
+	for _, id := range ids {
+		if len(id) == 0 {
+			continue
+		}
+
+		data, err := repository.Get(ctx, Prefix, string(id))
+
+		// Task may have been deleted after it was indexed
+		if sgerrors.IsNotFound(err) {
+			continue
+		}
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "get task %s", id)
+		}
+
+		if len(data) == 0 {
+			continue
+		}
+
+		task := &Task{}
+
+		if err := json.Unmarshal(data, task); err != nil {
+			return nil, errors.Wrapf(err, "unmarshal task %s", id)
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	return tasks, nil
+}
+
+// RemoveTask deletes the task record, its index entry and log,
+// the log is kept when log dir is empty.
+func RemoveTask(ctx context.Context, repository storage.Interface, logDir string, task *Task) error {
+	if err := repository.Delete(ctx, Prefix, task.ID); err != nil {
+		return errors.Wrapf(err, "delete task %s", task.ID)
+	}
+
+	if task.KubeID != "" {
+		if err := repository.Delete(ctx, kubeIndexPrefix(task.KubeID), task.ID); err != nil {
+			return errors.Wrapf(err, "delete index of task %s", task.ID)
+		}
+	}
+
+	if logDir == "" {
+		return nil
+	}
+
+	err := os.Remove(path.Join(logDir, util.MakeFileName(task.ID)))
+
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "delete log of task %s", task.ID)
+	}
+
+	return nil
+}
+
+// TaskPruner periodically deletes records and logs of finished
+// tasks that have not been updated for the retention period.
+type TaskPruner struct {
+	repository storage.Interface
+	logDir     string
+	retention  time.Duration
+	interval   time.Duration
+
+	now func() time.Time
+}
+
+// NewTaskPruner constructs TaskPruner, defaults are used for
+// zero retention and interval.
+func NewTaskPruner(repository storage.Interface, logDir string, retention, interval time.Duration) *TaskPruner {
+	if retention <= 0 {
+		retention = DefaultTaskRetention
+	}
+
+	if interval <= 0 {
+		interval = DefaultPruneInterval
+	}
+
+	return &TaskPruner{
+		repository: repository,
+		logDir:     logDir,
+		retention:  retention,
+		interval:   interval,
+		now:        time.Now,
+	}
+}
+
+// Run prunes tasks until context is done.
+func (p *TaskPruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.poll(ctx)
+		}
+	}
+}
+
+func (p *TaskPruner) poll(ctx context.Context) {
+	records, err := p.repository.GetAll(ctx, Prefix)
+
+	if err != nil {
+		logrus.Errorf("task pruner: list tasks %v", err)
+		return
+	}
+
+	deadline := p.now().Add(-p.retention)
+
+	for _, data := range records {
+		if len(data) == 0 {
+			continue
+		}
+
+		task := &Task{}
+
+		if err := json.Unmarshal(data, task); err != nil || task.ID == "" {
+			continue
+		}
+
+		if !p.expired(task, deadline) {
+			continue
+		}
+
+		if err := RemoveTask(ctx, p.repository, p.logDir, task); err != nil {
+			logrus.Errorf("task pruner: %v", err)
+			continue
+		}
+
+		logrus.Debugf("task pruner: deleted task %s", task.ID)
+	}
+}
+
+// expired returns true for finished tasks last updated before the
+// deadline, tasks saved without update time are aged by their logs.
+func (p *TaskPruner) expired(task *Task, deadline time.Time) bool {
+	switch task.Status {
+	case statuses.Success, statuses.Error, statuses.Cancelled:
+	default:
+		return false
+	}
+
+	if isRunning(task.ID) {
+		return false
+	}
+
+	if task.UpdatedAt != 0 {
+		return time.Unix(task.UpdatedAt, 0).Before(deadline)
+	}
+
+	info, err := os.Stat(path.Join(p.logDir, util.MakeFileName(task.ID)))
+
+	if err != nil {
+		return false
+	}
+
+	return info.ModTime().Before(deadline)
+}
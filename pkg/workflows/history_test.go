@@ -0,0 +1,155 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/storage/memory"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/statuses"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+func TestGetKubeTasks(t *testing.T) {
+	repo := memory.NewInMemoryRepository()
+
+	for _, kubeID := range []string{"kube", "kube", "other"} {
+		task := newTask(MasterTask, nil, repo)
+		task.Config = &steps.Config{
+			Kube: model.Kube{
+				ID: kubeID,
+			},
+		}
+
+		if err := task.sync(context.Background()); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	tasks, err := GetKubeTasks(context.Background(), repo, "kube")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(tasks) != 2 {
+		t.Fatalf("Expected 2 tasks actual %d", len(tasks))
+	}
+
+	for _, task := range tasks {
+		if task.KubeID != "kube" || task.CreatedAt == 0 || task.UpdatedAt == 0 {
+			t.Errorf("Wrong task %+v", task)
+		}
+	}
+
+	if err := RemoveTask(context.Background(), repo, "", tasks[0]); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if tasks, _ = GetKubeTasks(context.Background(), repo, "kube"); len(tasks) != 1 {
+		t.Errorf("Expected 1 task after removal actual %d", len(tasks))
+	}
+}
+
+func TestTaskPrunerPoll(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-2 * time.Hour).Unix()
+
+	testCases := []struct {
+		description string
+		status      statuses.Status
+		updatedAt   int64
+		running     bool
+		removed     bool
+	}{
+		{
+			description: "old finished task",
+			status:      statuses.Success,
+			updatedAt:   old,
+			removed:     true,
+		},
+		{
+			description: "old failed task",
+			status:      statuses.Error,
+			updatedAt:   old,
+			removed:     true,
+		},
+		{
+			description: "recent task",
+			status:      statuses.Success,
+			updatedAt:   now.Unix(),
+		},
+		{
+			description: "unfinished task",
+			status:      statuses.Executing,
+			updatedAt:   old,
+		},
+		{
+			description: "running task",
+			status:      statuses.Error,
+			updatedAt:   old,
+			running:     true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		logDir, err := ioutil.TempDir("", "tasks")
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		repo := memory.NewInMemoryRepository()
+		task := &Task{
+			ID:        "1234",
+			KubeID:    "kube",
+			Status:    testCase.status,
+			UpdatedAt: testCase.updatedAt,
+		}
+		data, _ := json.Marshal(task)
+		repo.Put(context.Background(), Prefix, task.ID, data)
+		repo.Put(context.Background(), kubeIndexPrefix(task.KubeID), task.ID, []byte(task.ID))
+
+		logFile := path.Join(logDir, util.MakeFileName(task.ID))
+		ioutil.WriteFile(logFile, []byte("log"), 0644)
+
+		if testCase.running {
+			runningMux.Lock()
+			running[task.ID] = &taskRun{cancel: func() {}}
+			runningMux.Unlock()
+		}
+
+		pruner := NewTaskPruner(repo, logDir, time.Hour, 0)
+		pruner.now = func() time.Time {
+			return now
+		}
+		pruner.poll(context.Background())
+
+		_, err = repo.Get(context.Background(), Prefix, task.ID)
+
+		if removed := sgerrors.IsNotFound(err); removed != testCase.removed {
+			t.Errorf("%s: expected removed %v actual %v", testCase.description,
+				testCase.removed, removed)
+		}
+
+		if _, err := os.Stat(logFile); os.IsNotExist(err) != testCase.removed {
+			t.Errorf("%s: log must be removed along with the task", testCase.description)
+		}
+
+		if tasks, _ := GetKubeTasks(context.Background(), repo, "kube"); (len(tasks) == 0) != testCase.removed {
+			t.Errorf("%s: index must be removed along with the task", testCase.description)
+		}
+
+		runningMux.Lock()
+		delete(running, task.ID)
+		runningMux.Unlock()
+		os.RemoveAll(logDir)
+	}
+}
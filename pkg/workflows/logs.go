@@ -0,0 +1,312 @@
+package workflows
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/statuses"
+)
+
+const (
+	logPollInterval = time.Millisecond * 500
+	logReadSize     = 32 * 1024
+)
+
+// Runner logs "[step] - started" when the step starts
+var stepStartedRe = regexp.MustCompile(`\[([^\]\s]+)\] - started`)
+
+// LogChunk is the part of the task log written by the step, offset
+// is the position in the log right after the chunk.
+type LogChunk struct {
+	Step   string `json:"step"`
+	Offset int64  `json:"offset"`
+	Text   string `json:"text"`
+}
+
+// logStream reads complete lines of the log being written and splits
+// them to chunks by steps that have written them.
+type logStream struct {
+	r       io.ReadSeeker
+	offset  int64
+	step    string
+	partial []byte
+}
+
+func newLogStream(r io.ReadSeeker, offset int64) (*logStream, error) {
+	s := &logStream{
+		r: r,
+	}
+
+	// Find the step the offset belongs to
+	scanner := bufio.NewScanner(io.LimitReader(r, offset))
+	scanner.Buffer(make([]byte, logReadSize), bufio.MaxScanTokenSize*16)
+
+	for scanner.Scan() {
+		if m := stepStartedRe.FindSubmatch(scanner.Bytes()); m != nil {
+			s.step = string(m[1])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "scan log")
+	}
+
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return nil, errors.Wrapf(err, "seek log to %d", offset)
+	}
+
+	s.offset = offset
+
+	return s, nil
+}
+
+// next returns chunks of lines written since the last call.
+func (s *logStream) next() ([]LogChunk, error) {
+	buf := make([]byte, logReadSize)
+
+	for {
+		n, err := s.r.Read(buf)
+		s.partial = append(s.partial, buf[:n]...)
+
+		if err == io.EOF || n == 0 {
+			break
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "read log")
+		}
+	}
+
+	end := bytes.LastIndexByte(s.partial, '\n')
+
+	if end < 0 {
+		return nil, nil
+	}
+
+	lines := s.partial[:end+1]
+	s.partial = append([]byte(nil), s.partial[end+1:]...)
+
+	var (
+		chunks []LogChunk
+		text   bytes.Buffer
+	)
+
+	flush := func() {
+		if text.Len() == 0 {
+			return
+		}
+
+		chunks = append(chunks, LogChunk{
+			Step:   s.step,
+			Offset: s.offset,
+			Text:   text.String(),
+		})
+		text.Reset()
+	}
+
+	for len(lines) > 0 {
+		i := bytes.IndexByte(lines, '\n')
+		line := lines[:i+1]
+		lines = lines[i+1:]
+
+		if m := stepStartedRe.FindSubmatch(line); m != nil && string(m[1]) != s.step {
+			flush()
+			s.step = string(m[1])
+		}
+
+		text.Write(line)
+		s.offset += int64(len(line))
+	}
+
+	flush()
+
+	return chunks, nil
+}
+
+// tail returns the incomplete last line of the log as a chunk,
+// it is sent once the log is not written anymore.
+func (s *logStream) tail() []LogChunk {
+	if len(s.partial) == 0 {
+		return nil
+	}
+
+	s.offset += int64(len(s.partial))
+	chunk := LogChunk{
+		Step:   s.step,
+		Offset: s.offset,
+		Text:   string(s.partial),
+	}
+	s.partial = nil
+
+	return []LogChunk{chunk}
+}
+
+// size returns count of bytes of the log read so far.
+func (s *logStream) size() int64 {
+	return s.offset + int64(len(s.partial))
+}
+
+// StreamTaskLogs sends the task log as server-sent events while the task
+// is running, the stream ends when the task is finished and its log has
+// stopped growing. Clients resume
+// from the offset of the last event passed with the offset parameter
+// or Last-Event-ID header.
+func (h *TaskHandler) StreamTaskLogs(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	offset, err := logOffset(r)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f, err := h.openLog(id)
+
+	if os.IsNotExist(err) {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logrus.Errorf("Open file %s %v", util.MakeFileName(id), err)
+		return
+	}
+
+	defer f.Close()
+
+	flusher, ok := w.(http.Flusher)
+
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	stream, err := newLogStream(f, offset)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(logPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Status is checked before reading, so lines written
+		// before the task has finished are not lost
+		status, finished := h.taskFinished(r.Context(), id)
+		size := stream.size()
+		chunks, err := stream.next()
+
+		if err != nil {
+			logrus.Errorf("stream log of task %s %v", id, err)
+			return
+		}
+
+		// Log may be written after the status has been saved,
+		// it is read until nothing new is found
+		finished = finished && stream.size() == size
+
+		if finished {
+			chunks = append(chunks, stream.tail()...)
+		}
+
+		for _, chunk := range chunks {
+			if err := writeEvent(w, "log", chunk.Offset, chunk); err != nil {
+				return
+			}
+		}
+
+		if finished {
+			writeEvent(w, "end", stream.offset, map[string]statuses.Status{
+				"status": status,
+			})
+			flusher.Flush()
+			return
+		}
+
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// taskFinished returns status of the task that is not running anymore,
+// failed task is running while its steps are rolled back.
+func (h *TaskHandler) taskFinished(ctx context.Context, id string) (statuses.Status, bool) {
+	data, err := h.repository.Get(ctx, Prefix, id)
+
+	if err != nil {
+		return "", false
+	}
+
+	task := &Task{}
+
+	if err := json.Unmarshal(data, task); err != nil {
+		return "", false
+	}
+
+	switch task.Status {
+	case statuses.Success, statuses.Error, statuses.Cancelled:
+		return task.Status, !isRunning(id)
+	}
+
+	return task.Status, false
+}
+
+func logOffset(r *http.Request) (int64, error) {
+	value := r.URL.Query().Get("offset")
+
+	if value == "" {
+		value = r.Header.Get("Last-Event-ID")
+	}
+
+	if value == "" {
+		return 0, nil
+	}
+
+	offset, err := strconv.ParseInt(value, 10, 64)
+
+	if err != nil || offset < 0 {
+		return 0, errors.Errorf("wrong offset %s", value)
+	}
+
+	return offset, nil
+}
+
+func writeEvent(w io.Writer, event string, id int64, v interface{}) error {
+	data, err := json.Marshal(v)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, event, data)
+
+	return err
+}
@@ -0,0 +1,218 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/supergiant/control/pkg/workflows/statuses"
+)
+
+const testLog = "level=info msg=\"[ssh] - started\"\n" +
+	"connected\n" +
+	"level=info msg=\"[ssh] - success\"\n" +
+	"level=info msg=\"[docker] - started\"\n" +
+	"installed\n" +
+	"partial"
+
+func TestLogStreamNext(t *testing.T) {
+	testCases := []struct {
+		description string
+		offset      int64
+		expected    []LogChunk
+	}{
+		{
+			description: "from the beginning",
+			expected: []LogChunk{
+				{
+					Step:   "ssh",
+					Offset: 76,
+					Text:   "level=info msg=\"[ssh] - started\"\nconnected\nlevel=info msg=\"[ssh] - success\"\n",
+				},
+				{
+					Step:   "docker",
+					Offset: 122,
+					Text:   "level=info msg=\"[docker] - started\"\ninstalled\n",
+				},
+			},
+		},
+		{
+			description: "resume in the middle of the step",
+			offset:      112,
+			expected: []LogChunk{
+				{
+					Step:   "docker",
+					Offset: 122,
+					Text:   "installed\n",
+				},
+			},
+		},
+		{
+			description: "resume at the end",
+			offset:      122,
+		},
+	}
+
+	for _, testCase := range testCases {
+		stream, err := newLogStream(strings.NewReader(testLog), testCase.offset)
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", testCase.description, err)
+			continue
+		}
+
+		chunks, err := stream.next()
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", testCase.description, err)
+			continue
+		}
+
+		if len(chunks) != len(testCase.expected) {
+			t.Errorf("%s: expected chunks %v actual %v", testCase.description,
+				testCase.expected, chunks)
+			continue
+		}
+
+		for i := range chunks {
+			if chunks[i] != testCase.expected[i] {
+				t.Errorf("%s: expected chunk %v actual %v", testCase.description,
+					testCase.expected[i], chunks[i])
+			}
+		}
+	}
+}
+
+func TestTaskHandlerStreamTaskLogs(t *testing.T) {
+	id := "abcd"
+	f, err := ioutil.TempFile("", "task")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	defer os.Remove(f.Name())
+	fmt.Fprint(f, testLog)
+	f.Close()
+
+	testCases := []struct {
+		description  string
+		query        string
+		lastEventID  string
+		status       statuses.Status
+		running      bool
+		expectedCode int
+		expected     []string
+	}{
+		{
+			description:  "wrong offset",
+			query:        "?offset=abc",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "whole log",
+			status:       statuses.Success,
+			expectedCode: http.StatusOK,
+			expected: []string{
+				"id: 76\nevent: log\ndata: {\"step\":\"ssh\"",
+				"id: 122\nevent: log\ndata: {\"step\":\"docker\"",
+				"id: 129\nevent: log\ndata: {\"step\":\"docker\",\"offset\":129,\"text\":\"partial\"}",
+				"id: 129\nevent: end\ndata: {\"status\":\"success\"}",
+			},
+		},
+		{
+			description:  "reconnect",
+			lastEventID:  "76",
+			status:       statuses.Error,
+			expectedCode: http.StatusOK,
+			expected: []string{
+				"id: 122\nevent: log\ndata: {\"step\":\"docker\"",
+				"id: 129\nevent: log\ndata: {\"step\":\"docker\"",
+				"id: 129\nevent: end\ndata: {\"status\":\"error\"}",
+			},
+		},
+		{
+			description:  "failed task is rolled back",
+			status:       statuses.Error,
+			running:      true,
+			expectedCode: http.StatusOK,
+			expected: []string{
+				"id: 76\nevent: log\ndata: {\"step\":\"ssh\"",
+				"id: 122\nevent: log\ndata: {\"step\":\"docker\"",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		data, _ := json.Marshal(&Task{
+			ID:     id,
+			Status: testCase.status,
+		})
+
+		h := &TaskHandler{
+			repository: &MockRepository{
+				storage: map[string][]byte{
+					Prefix + id: data,
+				},
+			},
+			openLog: func(string) (*os.File, error) {
+				return os.Open(f.Name())
+			},
+		}
+
+		req, _ := http.NewRequest(http.MethodGet, "/tasks/"+id+"/logs/stream"+testCase.query, nil)
+
+		if testCase.lastEventID != "" {
+			req.Header.Set("Last-Event-ID", testCase.lastEventID)
+		}
+
+		// Stream of the running task ends along with the request
+		ctx, cancel := context.WithTimeout(context.Background(), logPollInterval*3)
+		req = req.WithContext(ctx)
+
+		var run *taskRun
+		if testCase.running {
+			run = setRunning(id, func() {})
+		}
+
+		rec := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/tasks/{id}/logs/stream", h.StreamTaskLogs)
+		router.ServeHTTP(rec, req)
+		cancel()
+
+		if run != nil {
+			unsetRunning(id, run)
+		}
+
+		if rec.Code != testCase.expectedCode {
+			t.Errorf("%s: expected code %d actual %d", testCase.description,
+				testCase.expectedCode, rec.Code)
+			continue
+		}
+
+		body := rec.Body.String()
+
+		if testCase.running && strings.Contains(body, "partial") {
+			t.Errorf("%s: incomplete line must not be sent %s", testCase.description, body)
+		}
+
+		for _, event := range testCase.expected {
+			if !strings.Contains(body, event) {
+				t.Errorf("%s: event %q not found in %s", testCase.description, event, body)
+			}
+		}
+
+		if strings.Count(body, "event: ") != len(testCase.expected) {
+			t.Errorf("%s: wrong number of events %s", testCase.description, body)
+		}
+	}
+}
@@ -8,4 +8,6 @@ const (
 	Success   Status = "success"
 	Error     Status = "error"
 	Cancelled Status = "cancelled"
+	// RolledBack step has been undone after the task has failed
+	RolledBack Status = "rolled_back"
 )
@@ -1,34 +0,0 @@
-package addons
-
-import (
-	"context"
-	"io/ioutil"
-	"testing"
-
-	"github.com/supergiant/control/pkg/workflows/steps"
-)
-
-func TestStepName(t *testing.T) {
-	s := Step{}
-
-	if s.Name() != StepName {
-		t.Errorf("Unexpected step name expected %s actual %s", StepName, s.Name())
-	}
-}
-
-func TestDepends(t *testing.T) {
-	s := Step{}
-
-	if len(s.Depends()) != 0 {
-		t.Errorf("Wrong dependency list %v expected %v", s.Depends(), []string{})
-	}
-}
-
-func TestStep_Rollback(t *testing.T) {
-	s := Step{}
-	err := s.Rollback(context.Background(), ioutil.Discard, &steps.Config{})
-
-	if err != nil {
-		t.Errorf("unexpected error while rollback %v", err)
-	}
-}
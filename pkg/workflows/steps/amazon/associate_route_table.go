@@ -46,6 +46,11 @@ func NewAssociateRouteTableStep(ec2fn GetEC2Fn) *AssociateRouteTableStep {
 func (s *AssociateRouteTableStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config) error {
 	logrus.Debugf(StepAssociateRouteTable)
 
+	// Subnets of user keep their route tables
+	if hasUserSubnets(cfg) {
+		return nil
+	}
+
 	if cfg.AWSConfig.RouteTableAssociationIDs == nil {
 		cfg.AWSConfig.RouteTableAssociationIDs = make(map[string]string)
 	}
@@ -0,0 +1,142 @@
+package amazon
+
+import (
+	"context"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const CancelSpotRequestsStepName = "aws_cancel_spot_requests"
+
+type spotRequestCanceler interface {
+	DescribeSpotInstanceRequestsWithContext(aws.Context, *ec2.DescribeSpotInstanceRequestsInput, ...request.Option) (*ec2.DescribeSpotInstanceRequestsOutput, error)
+	CancelSpotInstanceRequestsWithContext(aws.Context, *ec2.CancelSpotInstanceRequestsInput, ...request.Option) (*ec2.CancelSpotInstanceRequestsOutput, error)
+	TerminateInstancesWithContext(aws.Context, *ec2.TerminateInstancesInput, ...request.Option) (*ec2.TerminateInstancesOutput, error)
+}
+
+// CancelSpotRequestsStep cancels persistent spot instance requests
+// of the kube and terminates instances launched by them.
+type CancelSpotRequestsStep struct {
+	getSvc func(steps.AWSConfig) (spotRequestCanceler, error)
+}
+
+func InitCancelSpotRequests(fn GetEC2Fn) {
+	steps.RegisterStep(CancelSpotRequestsStepName, NewCancelSpotRequests(fn))
+}
+
+func NewCancelSpotRequests(fn GetEC2Fn) *CancelSpotRequestsStep {
+	return &CancelSpotRequestsStep{
+		getSvc: func(cfg steps.AWSConfig) (spotRequestCanceler, error) {
+			EC2, err := fn(cfg)
+
+			if err != nil {
+				return nil, errors.Wrap(ErrAuthorization, err.Error())
+			}
+
+			return EC2, nil
+		},
+	}
+}
+
+func (s *CancelSpotRequestsStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config) error {
+	log := util.GetLogger(w)
+
+	if len(cfg.Kube.SpotRequests) == 0 {
+		log.Infof("[%s] - no spot requests in kube %s", s.Name(), cfg.Kube.Name)
+		return nil
+	}
+
+	svc, err := s.getSvc(cfg.AWSConfig)
+
+	if err != nil {
+		logrus.Errorf("[%s] - error getting service %v", s.Name(), err)
+		return errors.Wrapf(err, "%s error getting service", s.Name())
+	}
+
+	requestIDs := make([]string, 0, len(cfg.Kube.SpotRequests))
+	instanceIDs := make([]string, 0)
+
+	// Describe requests one by one, so requests that were cancelled
+	// manually do not prevent the rest of them from being cancelled.
+	for _, requestID := range cfg.Kube.SpotRequests {
+		out, err := svc.DescribeSpotInstanceRequestsWithContext(ctx,
+			&ec2.DescribeSpotInstanceRequestsInput{
+				SpotInstanceRequestIds: aws.StringSlice([]string{requestID}),
+			})
+
+		if err != nil {
+			log.Infof("[%s] - describe spot request %s: %v", s.Name(), requestID, err)
+			requestIDs = append(requestIDs, requestID)
+			continue
+		}
+
+		for _, spotRequest := range out.SpotInstanceRequests {
+			if spotRequest.InstanceId != nil {
+				instanceIDs = append(instanceIDs, *spotRequest.InstanceId)
+			}
+
+			if spotRequest.State != nil && isSpotRequestFinished(*spotRequest.State) {
+				logrus.Debugf("[%s] - spot request %s is already %s",
+					s.Name(), requestID, *spotRequest.State)
+				continue
+			}
+
+			requestIDs = append(requestIDs, requestID)
+		}
+	}
+
+	for _, requestID := range requestIDs {
+		_, err := svc.CancelSpotInstanceRequestsWithContext(ctx, &ec2.CancelSpotInstanceRequestsInput{
+			SpotInstanceRequestIds: aws.StringSlice([]string{requestID}),
+		})
+
+		if err != nil {
+			log.Infof("[%s] - cancel spot request %s: %v", s.Name(), requestID, err)
+			continue
+		}
+
+		log.Infof("[%s] - spot request %s has been cancelled", s.Name(), requestID)
+	}
+
+	if len(instanceIDs) > 0 {
+		_, err = svc.TerminateInstancesWithContext(ctx, &ec2.TerminateInstancesInput{
+			InstanceIds: aws.StringSlice(instanceIDs),
+		})
+
+		if err != nil {
+			log.Infof("[%s] - terminate spot instances %v: %v", s.Name(), instanceIDs, err)
+		}
+	}
+
+	log.Infof("[%s] - completed", s.Name())
+	return nil
+}
+
+func (*CancelSpotRequestsStep) Name() string {
+	return CancelSpotRequestsStepName
+}
+
+func (*CancelSpotRequestsStep) Depends() []string {
+	return nil
+}
+
+func (*CancelSpotRequestsStep) Description() string {
+	return "Cancel spot instance requests of the cluster"
+}
+
+func (*CancelSpotRequestsStep) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
+
+func isSpotRequestFinished(state string) bool {
+	return state == ec2.SpotInstanceStateCancelled ||
+		state == ec2.SpotInstanceStateClosed
+}
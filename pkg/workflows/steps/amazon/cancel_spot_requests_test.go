@@ -0,0 +1,202 @@
+package amazon
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type mockSpotRequestCanceler struct {
+	mock.Mock
+}
+
+func (m *mockSpotRequestCanceler) DescribeSpotInstanceRequestsWithContext(ctx aws.Context,
+	req *ec2.DescribeSpotInstanceRequestsInput, opts ...request.Option) (*ec2.DescribeSpotInstanceRequestsOutput, error) {
+	args := m.Called(ctx, req, opts)
+	val, ok := args.Get(0).(*ec2.DescribeSpotInstanceRequestsOutput)
+	if !ok {
+		return nil, args.Error(1)
+	}
+	return val, args.Error(1)
+}
+
+func (m *mockSpotRequestCanceler) CancelSpotInstanceRequestsWithContext(ctx aws.Context,
+	req *ec2.CancelSpotInstanceRequestsInput, opts ...request.Option) (*ec2.CancelSpotInstanceRequestsOutput, error) {
+	args := m.Called(ctx, req, opts)
+	val, ok := args.Get(0).(*ec2.CancelSpotInstanceRequestsOutput)
+	if !ok {
+		return nil, args.Error(1)
+	}
+	return val, args.Error(1)
+}
+
+func (m *mockSpotRequestCanceler) TerminateInstancesWithContext(ctx aws.Context,
+	req *ec2.TerminateInstancesInput, opts ...request.Option) (*ec2.TerminateInstancesOutput, error) {
+	args := m.Called(ctx, req, opts)
+	val, ok := args.Get(0).(*ec2.TerminateInstancesOutput)
+	if !ok {
+		return nil, args.Error(1)
+	}
+	return val, args.Error(1)
+}
+
+func TestCancelSpotRequestsStep_Run(t *testing.T) {
+	testCases := []struct {
+		description string
+
+		spotRequests []string
+		getSvcErr    error
+
+		describeOutput *ec2.DescribeSpotInstanceRequestsOutput
+		describeErr    error
+		cancelErr      error
+		terminateErr   error
+
+		cancelCalls    int
+		terminateCalls int
+		errMsg         string
+	}{
+		{
+			description: "no spot requests",
+		},
+		{
+			description:  "get service error",
+			spotRequests: []string{"sir-1"},
+			getSvcErr:    errors.New("message1"),
+			errMsg:       "message1",
+		},
+		{
+			description:  "describe error",
+			spotRequests: []string{"sir-1"},
+			describeErr:  errors.New("message2"),
+			cancelCalls:  1,
+		},
+		{
+			description:  "already cancelled",
+			spotRequests: []string{"sir-1"},
+			describeOutput: &ec2.DescribeSpotInstanceRequestsOutput{
+				SpotInstanceRequests: []*ec2.SpotInstanceRequest{
+					{
+						SpotInstanceRequestId: aws.String("sir-1"),
+						State:                 aws.String(ec2.SpotInstanceStateCancelled),
+					},
+				},
+			},
+		},
+		{
+			description:  "cancel error",
+			spotRequests: []string{"sir-1", "sir-2"},
+			describeOutput: &ec2.DescribeSpotInstanceRequestsOutput{
+				SpotInstanceRequests: []*ec2.SpotInstanceRequest{
+					{
+						State:      aws.String(ec2.SpotInstanceStateActive),
+						InstanceId: aws.String("i-1"),
+					},
+				},
+			},
+			cancelErr:      errors.New("message3"),
+			cancelCalls:    2,
+			terminateCalls: 1,
+		},
+		{
+			description:  "success",
+			spotRequests: []string{"sir-1"},
+			describeOutput: &ec2.DescribeSpotInstanceRequestsOutput{
+				SpotInstanceRequests: []*ec2.SpotInstanceRequest{
+					{
+						State:      aws.String(ec2.SpotInstanceStateActive),
+						InstanceId: aws.String("i-1"),
+					},
+				},
+			},
+			terminateErr:   errors.New("message4"),
+			cancelCalls:    1,
+			terminateCalls: 1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		svc := &mockSpotRequestCanceler{}
+		svc.On("DescribeSpotInstanceRequestsWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(testCase.describeOutput,
+			testCase.describeErr)
+		svc.On("CancelSpotInstanceRequestsWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(nil, testCase.cancelErr)
+		svc.On("TerminateInstancesWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(nil, testCase.terminateErr)
+
+		config := &steps.Config{
+			Kube: model.Kube{
+				SpotRequests: testCase.spotRequests,
+			},
+		}
+		step := CancelSpotRequestsStep{
+			getSvc: func(steps.AWSConfig) (spotRequestCanceler, error) {
+				return svc, testCase.getSvcErr
+			},
+		}
+
+		err := step.Run(context.Background(), &bytes.Buffer{}, config)
+
+		if err == nil && testCase.errMsg != "" {
+			t.Errorf("Error must not be nil")
+		}
+
+		if err != nil && !strings.Contains(err.Error(), testCase.errMsg) {
+			t.Errorf("Error message %s does not contain %s",
+				err.Error(), testCase.errMsg)
+		}
+
+		svc.AssertNumberOfCalls(t, "CancelSpotInstanceRequestsWithContext",
+			testCase.cancelCalls)
+		svc.AssertNumberOfCalls(t, "TerminateInstancesWithContext",
+			testCase.terminateCalls)
+	}
+}
+
+func TestNewCancelSpotRequestsErr(t *testing.T) {
+	fn := func(steps.AWSConfig) (ec2iface.EC2API, error) {
+		return nil, errors.New("errorMessage")
+	}
+
+	s := NewCancelSpotRequests(fn)
+
+	if s == nil {
+		t.Error("Step must not be nil")
+	}
+
+	if api, err := s.getSvc(steps.AWSConfig{}); err == nil || api != nil {
+		t.Errorf("Unexpected values %v %v", api, err)
+	}
+}
+
+func TestInitCancelSpotRequests(t *testing.T) {
+	InitCancelSpotRequests(GetEC2)
+
+	s := steps.GetStep(CancelSpotRequestsStepName)
+
+	if s == nil {
+		t.Errorf("Step must not be nil")
+	}
+}
+
+func TestCancelSpotRequestsStep_Name(t *testing.T) {
+	s := &CancelSpotRequestsStep{}
+
+	if name := s.Name(); name != CancelSpotRequestsStepName {
+		t.Errorf("Wrong name expected %s actual %s",
+			CancelSpotRequestsStepName, name)
+	}
+}
@@ -1,29 +1,70 @@
 package amazon
 
 import (
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/aws/aws-sdk-go/service/elb"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
+	"github.com/supergiant/control/pkg/clouds/awssdk"
+	"github.com/supergiant/control/pkg/metrics"
+	"github.com/supergiant/control/pkg/util"
 	"github.com/supergiant/control/pkg/workflows/steps"
 )
 
+// RetryPolicy is used for EC2 calls that are throttled
+// when many clusters are managed in one account.
+var RetryPolicy = util.RetryPolicy{
+	Attempts: 5,
+	Delay:    time.Second,
+	MaxDelay: time.Second * 20,
+}
+
+// IsRetryableErr returns true for throttling and server side errors of AWS API.
+func IsRetryableErr(err error) bool {
+	if reqErr, ok := errors.Cause(err).(awserr.RequestFailure); ok &&
+		reqErr.StatusCode() >= http.StatusInternalServerError {
+		return true
+	}
+
+	if aerr, ok := errors.Cause(err).(awserr.Error); ok {
+		switch aerr.Code() {
+		case "RequestLimitExceeded", "Throttling", "ThrottlingException",
+			"InternalError", "ServiceUnavailable", "Unavailable":
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewSession creates session for region of config, the role is
+// assumed when config has role ARN.
+func NewSession(cfg steps.AWSConfig) (*session.Session, error) {
+	sess, err := awssdk.NewSession(cfg.Region, util.AWSCredentials(cfg))
+
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.InstrumentAWSSession(sess)
+
+	return sess, nil
+}
+
 type GetEC2Fn func(steps.AWSConfig) (ec2iface.EC2API, error)
 
 func GetEC2(cfg steps.AWSConfig) (ec2iface.EC2API, error) {
 	logrus.Debug("get EC2 client")
-	sess, err := session.NewSessionWithOptions(session.Options{
-		Config: aws.Config{
-			Region:      aws.String(cfg.Region),
-			Credentials: credentials.NewStaticCredentials(cfg.KeyID, cfg.Secret, ""),
-		},
-	})
+	sess, err := NewSession(cfg)
 
 	if err != nil {
 		return nil, err
@@ -34,12 +75,7 @@ func GetEC2(cfg steps.AWSConfig) (ec2iface.EC2API, error) {
 type GetIAMFn func(steps.AWSConfig) (iamiface.IAMAPI, error)
 
 func GetIAM(cfg steps.AWSConfig) (iamiface.IAMAPI, error) {
-	sess, err := session.NewSessionWithOptions(session.Options{
-		Config: aws.Config{
-			Region:      aws.String(cfg.Region),
-			Credentials: credentials.NewStaticCredentials(cfg.KeyID, cfg.Secret, ""),
-		},
-	})
+	sess, err := NewSession(cfg)
 
 	if err != nil {
 		return nil, err
@@ -51,15 +87,43 @@ func GetIAM(cfg steps.AWSConfig) (iamiface.IAMAPI, error) {
 type GetELBFn func(steps.AWSConfig) (*elb.ELB, error)
 
 func GetELB(cfg steps.AWSConfig) (*elb.ELB, error) {
-	sess, err := session.NewSessionWithOptions(session.Options{
-		Config: aws.Config{
-			Region:      aws.String(cfg.Region),
-			Credentials: credentials.NewStaticCredentials(cfg.KeyID, cfg.Secret, ""),
-		},
-	})
+	sess, err := NewSession(cfg)
 
 	if err != nil {
 		return nil, err
 	}
 	return elb.New(sess), nil
 }
+
+type GetKMSFn func(steps.AWSConfig) (awssdk.KeyDescriber, error)
+
+func GetKMS(cfg steps.AWSConfig) (awssdk.KeyDescriber, error) {
+	sess, err := NewSession(cfg)
+
+	if err != nil {
+		return nil, err
+	}
+	return awssdk.NewKMS(sess), nil
+}
+
+type GetELBv2Fn func(steps.AWSConfig) (awssdk.TargetGroupService, error)
+
+func GetELBv2(cfg steps.AWSConfig) (awssdk.TargetGroupService, error) {
+	sess, err := NewSession(cfg)
+
+	if err != nil {
+		return nil, err
+	}
+	return awssdk.NewELBv2(sess), nil
+}
+
+type GetAutoScalingFn func(steps.AWSConfig) (awssdk.AutoScalingService, error)
+
+func GetAutoScaling(cfg steps.AWSConfig) (awssdk.AutoScalingService, error) {
+	sess, err := NewSession(cfg)
+
+	if err != nil {
+		return nil, err
+	}
+	return awssdk.NewAutoScaling(sess), nil
+}
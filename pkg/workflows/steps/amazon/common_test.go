@@ -1,8 +1,12 @@
 package amazon
 
 import (
+	"net/http"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/pkg/errors"
+
 	"github.com/supergiant/control/pkg/workflows/steps"
 )
 
@@ -29,3 +33,38 @@ func TestGetIAM(t *testing.T) {
 		t.Errorf("Api must not be nil")
 	}
 }
+
+func TestIsRetryableErr(t *testing.T) {
+	testCases := []struct {
+		err       error
+		retryable bool
+	}{
+		{
+			err:       awserr.New("RequestLimitExceeded", "limit", nil),
+			retryable: true,
+		},
+		{
+			err:       errors.Wrap(awserr.New("Throttling", "rate", nil), "describe"),
+			retryable: true,
+		},
+		{
+			err: awserr.NewRequestFailure(awserr.New("Unknown", "unknown", nil),
+				http.StatusBadGateway, "id"),
+			retryable: true,
+		},
+		{
+			err: awserr.NewRequestFailure(awserr.New("InvalidParameterValue", "invalid", nil),
+				http.StatusBadRequest, "id"),
+		},
+		{
+			err: errors.New("error"),
+		},
+	}
+
+	for _, testCase := range testCases {
+		if retryable := IsRetryableErr(testCase.err); retryable != testCase.retryable {
+			t.Errorf("Wrong retryable for %v expected %v actual %v",
+				testCase.err, testCase.retryable, retryable)
+		}
+	}
+}
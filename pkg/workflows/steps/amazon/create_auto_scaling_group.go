@@ -0,0 +1,150 @@
+package amazon
+
+import (
+	"context"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/clouds/awssdk"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const CreateAutoScalingGroupStepName = "aws_create_auto_scaling_group"
+
+// CreateAutoScalingGroupStep creates auto scaling group of the autoscaled
+// node pool, instances of the group join the kube by user data and are
+// discovered by cluster autoscaler by tags of the group.
+type CreateAutoScalingGroupStep struct {
+	getEC2         func(steps.AWSConfig) (launchTemplateCreator, error)
+	getAutoScaling GetAutoScalingFn
+}
+
+func InitCreateAutoScalingGroup(ec2Fn GetEC2Fn, autoScalingFn GetAutoScalingFn) {
+	steps.RegisterStep(CreateAutoScalingGroupStepName,
+		NewCreateAutoScalingGroup(ec2Fn, autoScalingFn))
+}
+
+func NewCreateAutoScalingGroup(ec2Fn GetEC2Fn, autoScalingFn GetAutoScalingFn) *CreateAutoScalingGroupStep {
+	return &CreateAutoScalingGroupStep{
+		getEC2: func(cfg steps.AWSConfig) (launchTemplateCreator, error) {
+			EC2, err := ec2Fn(cfg)
+
+			if err != nil {
+				return nil, errors.Wrap(ErrAuthorization, err.Error())
+			}
+
+			return EC2, nil
+		},
+		getAutoScaling: autoScalingFn,
+	}
+}
+
+func (s *CreateAutoScalingGroupStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config) error {
+	log := util.GetLogger(w)
+	groupCfg := &cfg.AutoScalingConfig
+
+	if groupCfg.GroupName == "" {
+		return errors.New("auto scaling group name must not be empty")
+	}
+
+	subnets := make([]string, 0, len(cfg.AWSConfig.Subnets))
+
+	for _, subnetID := range cfg.AWSConfig.Subnets {
+		subnets = append(subnets, subnetID)
+	}
+
+	if len(subnets) == 0 {
+		return errors.Errorf("kube %s has no subnets", cfg.Kube.Name)
+	}
+
+	sort.Strings(subnets)
+
+	// Template is saved with the task, so re-run does not create another
+	if groupCfg.LaunchTemplateID == "" {
+		EC2, err := s.getEC2(cfg.AWSConfig)
+
+		if err != nil {
+			logrus.Errorf("[%s] - error getting service %v", s.Name(), err)
+			return errors.Wrapf(err, "%s error getting service", s.Name())
+		}
+
+		groupCfg.LaunchTemplateID, err = createLaunchTemplate(ctx, EC2, cfg,
+			groupCfg.GroupName, cfg.AWSConfig.InstanceType)
+
+		if err != nil {
+			return errors.Wrap(err, "create launch template")
+		}
+
+		log.Infof("[%s] - launch template %s has been created", s.Name(),
+			groupCfg.LaunchTemplateID)
+	}
+
+	svc, err := s.getAutoScaling(cfg.AWSConfig)
+
+	if err != nil {
+		logrus.Errorf("[%s] - error getting service %v", s.Name(), err)
+		return errors.Wrapf(err, "%s error getting service", s.Name())
+	}
+
+	err = svc.CreateAutoScalingGroup(ctx, awssdk.AutoScalingGroupSpec{
+		Name:             groupCfg.GroupName,
+		LaunchTemplateID: groupCfg.LaunchTemplateID,
+		MinSize:          groupCfg.MinSize,
+		MaxSize:          groupCfg.MaxSize,
+		DesiredCapacity:  groupCfg.DesiredCapacity,
+		SubnetIDs:        subnets,
+		Tags:             autoScalingGroupTags(cfg),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	log.Infof("[%s] - auto scaling group %s of %d-%d nodes has been created", s.Name(),
+		groupCfg.GroupName, groupCfg.MinSize, groupCfg.MaxSize)
+
+	return nil
+}
+
+// autoScalingGroupTags returns tags of the group propagated to its
+// instances, instances are found by them in sync. Tags of the profile
+// can not override them.
+func autoScalingGroupTags(cfg *steps.Config) map[string]string {
+	tags := make(map[string]string, len(cfg.Tags)+6)
+
+	for key, value := range cfg.Tags {
+		tags[key] = value
+	}
+
+	tags[clouds.TagKubernetesCluster] = cfg.Kube.Name
+	tags[clouds.TagClusterID] = cfg.Kube.ID
+	tags[clouds.TagRole] = util.MakeRole(false)
+	tags[clouds.TagNodePool] = cfg.Pool
+	tags[clouds.TagAutoscalerEnabled] = "true"
+	tags[clouds.TagAutoscalerPrefix+cfg.Kube.ID] = "owned"
+
+	return tags
+}
+
+func (*CreateAutoScalingGroupStep) Name() string {
+	return CreateAutoScalingGroupStepName
+}
+
+func (*CreateAutoScalingGroupStep) Depends() []string {
+	return nil
+}
+
+func (*CreateAutoScalingGroupStep) Description() string {
+	return "Create auto scaling group of node pool"
+}
+
+// Rollback leaves the group to deletion of the node pool, which
+// deletes its launch template as well.
+func (*CreateAutoScalingGroupStep) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
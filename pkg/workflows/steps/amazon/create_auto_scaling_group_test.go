@@ -0,0 +1,204 @@
+package amazon
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/clouds/awssdk"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type fakeAutoScaling struct {
+	specs     []awssdk.AutoScalingGroupSpec
+	createErr error
+
+	deleted   []string
+	deleteErr error
+	// Describe returns groups until it has been called this many times
+	describeCalls int
+	described     int
+}
+
+func (f *fakeAutoScaling) CreateAutoScalingGroup(ctx context.Context, spec awssdk.AutoScalingGroupSpec) error {
+	f.specs = append(f.specs, spec)
+	return f.createErr
+}
+
+func (f *fakeAutoScaling) DescribeAutoScalingGroups(ctx context.Context, names []string) ([]*awssdk.AutoScalingGroup, error) {
+	f.described++
+
+	if f.described >= f.describeCalls {
+		return nil, nil
+	}
+
+	groups := make([]*awssdk.AutoScalingGroup, 0, len(names))
+
+	for _, name := range names {
+		groups = append(groups, &awssdk.AutoScalingGroup{
+			AutoScalingGroupName: aws.String(name),
+		})
+	}
+
+	return groups, nil
+}
+
+func (f *fakeAutoScaling) DeleteAutoScalingGroup(ctx context.Context, name string) error {
+	f.deleted = append(f.deleted, name)
+	return f.deleteErr
+}
+
+func TestCreateAutoScalingGroupStep_Run(t *testing.T) {
+	template := &ec2.CreateLaunchTemplateOutput{
+		LaunchTemplate: &ec2.LaunchTemplate{
+			LaunchTemplateId: aws.String("lt-1"),
+		},
+	}
+
+	testCases := []struct {
+		description string
+
+		templateID     string
+		templateOutput *ec2.CreateLaunchTemplateOutput
+		templateErr    error
+		createErr      error
+
+		templateCalls int
+		groupCalls    int
+		errMsg        string
+	}{
+		{
+			description:    "success",
+			templateOutput: template,
+			templateCalls:  1,
+			groupCalls:     1,
+		},
+		{
+			description: "template has been created",
+			templateID:  "lt-1",
+			groupCalls:  1,
+		},
+		{
+			description:   "launch template error",
+			templateErr:   errors.New("message1"),
+			templateCalls: 1,
+			errMsg:        "message1",
+		},
+		{
+			description:    "group error",
+			templateOutput: template,
+			createErr:      errors.New("message2"),
+			templateCalls:  1,
+			groupCalls:     1,
+			errMsg:         "message2",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		EC2 := &mockSpotFleetCreator{}
+		EC2.On("CreateLaunchTemplateWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(testCase.templateOutput,
+			testCase.templateErr)
+		svc := &fakeAutoScaling{createErr: testCase.createErr}
+
+		config := &steps.Config{
+			TaskID: "task-id",
+			Kube: model.Kube{
+				ID:   "kube-id",
+				Name: "test",
+			},
+			Pool: "workers",
+			Tags: map[string]string{
+				"team":         "core",
+				clouds.TagRole: "custom",
+			},
+			AWSConfig: steps.AWSConfig{
+				InstanceType: "m5.large",
+				Subnets: map[string]string{
+					"us-east-1b": "subnet-b",
+					"us-east-1a": "subnet-a",
+				},
+			},
+			AutoScalingConfig: steps.AutoScalingConfig{
+				GroupName:        "kube-id-workers",
+				MinSize:          1,
+				MaxSize:          5,
+				DesiredCapacity:  2,
+				LaunchTemplateID: testCase.templateID,
+			},
+		}
+		step := CreateAutoScalingGroupStep{
+			getEC2: func(steps.AWSConfig) (launchTemplateCreator, error) {
+				return EC2, nil
+			},
+			getAutoScaling: func(steps.AWSConfig) (awssdk.AutoScalingService, error) {
+				return svc, nil
+			},
+		}
+
+		err := step.Run(context.Background(), &bytes.Buffer{}, config)
+
+		if err == nil && testCase.errMsg != "" {
+			t.Errorf("Error must not be nil")
+		}
+
+		if err != nil && !strings.Contains(err.Error(), testCase.errMsg) {
+			t.Errorf("Error message %s does not contain %s",
+				err.Error(), testCase.errMsg)
+		}
+
+		EC2.AssertNumberOfCalls(t, "CreateLaunchTemplateWithContext", testCase.templateCalls)
+
+		if len(svc.specs) != testCase.groupCalls {
+			t.Errorf("Expected %d group calls actual %d", testCase.groupCalls, len(svc.specs))
+		}
+
+		if testCase.templateCalls > 0 {
+			input := EC2.Calls[0].Arguments.Get(1).(*ec2.CreateLaunchTemplateInput)
+
+			if aws.StringValue(input.LaunchTemplateName) != "kube-id-workers" ||
+				aws.StringValue(input.LaunchTemplateData.InstanceType) != "m5.large" {
+				t.Errorf("Wrong launch template %v", input)
+			}
+		}
+
+		if len(svc.specs) == 0 {
+			continue
+		}
+
+		spec := svc.specs[0]
+
+		if spec.LaunchTemplateID != "lt-1" || config.AutoScalingConfig.LaunchTemplateID != "lt-1" {
+			t.Errorf("Wrong launch template %s", spec.LaunchTemplateID)
+		}
+
+		if strings.Join(spec.SubnetIDs, ",") != "subnet-a,subnet-b" {
+			t.Errorf("Wrong subnets %v", spec.SubnetIDs)
+		}
+
+		if spec.Tags[clouds.TagRole] != "node" || spec.Tags[clouds.TagNodePool] != "workers" ||
+			spec.Tags["team"] != "core" || spec.Tags[clouds.TagAutoscalerEnabled] != "true" ||
+			spec.Tags[clouds.TagAutoscalerPrefix+"kube-id"] != "owned" {
+			t.Errorf("Wrong tags %v", spec.Tags)
+		}
+	}
+}
+
+func TestInitCreateAutoScalingGroup(t *testing.T) {
+	InitCreateAutoScalingGroup(GetEC2, GetAutoScaling)
+
+	s := steps.GetStep(CreateAutoScalingGroupStepName)
+
+	if s == nil {
+		t.Errorf("Step %s not found", CreateAutoScalingGroupStepName)
+	}
+}
@@ -0,0 +1,233 @@
+package amazon
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const (
+	StepCreateBastion = "aws_create_bastion"
+
+	// BastionRole is the role tag of bastion instance, bastion is not
+	// a machine of the kube.
+	BastionRole = "bastion"
+
+	bastionInstanceType    = "t3.micro"
+	bastionArmInstanceType = "t4g.micro"
+)
+
+// CreateBastionStep launches an instance with public ip that ssh
+// connections to machines of private kube go through.
+type CreateBastionStep struct {
+	getSvc func(steps.AWSConfig) (instanceService, error)
+}
+
+// InitCreateBastion adds the step to the registry
+func InitCreateBastion(ec2fn GetEC2Fn) {
+	steps.RegisterStep(StepCreateBastion, NewCreateBastionStep(ec2fn))
+}
+
+func NewCreateBastionStep(ec2fn GetEC2Fn) *CreateBastionStep {
+	return &CreateBastionStep{
+		getSvc: func(config steps.AWSConfig) (instanceService, error) {
+			EC2, err := ec2fn(config)
+
+			if err != nil {
+				return nil, errors.Wrap(ErrAuthorization, err.Error())
+			}
+
+			return EC2, nil
+		},
+	}
+}
+
+func (s *CreateBastionStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config) error {
+	log := util.GetLogger(w)
+
+	// Machines of public kube are dialed directly, bastion of the
+	// profile is used as is.
+	if !cfg.Kube.PrivateNetworking || cfg.Kube.SSHConfig.BastionHost != "" {
+		return nil
+	}
+
+	svc, err := s.getSvc(cfg.AWSConfig)
+
+	if err != nil {
+		logrus.Errorf("[%s] - failed to authorize in AWS: %v", s.Name(), err)
+		return errors.Wrap(err, "get ec2 service")
+	}
+
+	lookup := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String(fmt.Sprintf("tag:%s", clouds.TagClusterID)),
+				Values: aws.StringSlice([]string{cfg.Kube.ID}),
+			},
+			{
+				Name:   aws.String(fmt.Sprintf("tag:%s", clouds.TagRole)),
+				Values: aws.StringSlice([]string{BastionRole}),
+			},
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: aws.StringSlice([]string{ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning}),
+			},
+		},
+	}
+
+	// Bastion launched by the previous run of the step is reused
+	out, err := svc.DescribeInstancesWithContext(ctx, lookup)
+
+	if err != nil {
+		return errors.Wrap(err, "describe bastion")
+	}
+
+	if len(out.Reservations) == 0 {
+		if err := s.runBastion(ctx, svc, cfg); err != nil {
+			return err
+		}
+	}
+
+	log.Infof("[%s] - waiting for bastion of kube %s to run", s.Name(), cfg.Kube.Name)
+	timeout := steps.WaitTimeout(ctx, instanceRunningTimeout)
+	err = svc.WaitUntilInstanceRunningWithContext(ctx, lookup,
+		request.WithWaiterDelay(request.ConstantWaiterDelay(instanceWaiterDelay)),
+		request.WithWaiterMaxAttempts(int(timeout/instanceWaiterDelay)+1))
+
+	if err != nil {
+		return errors.Wrap(err, "wait for bastion")
+	}
+
+	out, err = svc.DescribeInstancesWithContext(ctx, lookup)
+
+	if err != nil {
+		return errors.Wrap(err, "describe bastion")
+	}
+
+	instance := findInstanceWithAddr(out.Reservations, true)
+
+	if instance == nil {
+		return errors.Wrap(ErrNoPublicIP, "bastion")
+	}
+
+	cfg.Kube.SSHConfig.BastionHost = aws.StringValue(instance.PublicIpAddress)
+	log.Infof("[%s] - bastion %s is running at %s", s.Name(),
+		aws.StringValue(instance.InstanceId), cfg.Kube.SSHConfig.BastionHost)
+
+	return nil
+}
+
+func (s *CreateBastionStep) runBastion(ctx context.Context, svc instanceService, cfg *steps.Config) error {
+	subnetID := bastionSubnet(cfg.AWSConfig)
+
+	if subnetID == "" {
+		return errors.Wrap(ErrCreateInstance, "no subnet for bastion")
+	}
+
+	tags := ec2Tags(cfg.Tags,
+		&ec2.Tag{
+			Key:   aws.String("Name"),
+			Value: aws.String(fmt.Sprintf("%s-%s", cfg.Kube.Name, BastionRole)),
+		},
+		&ec2.Tag{
+			Key:   aws.String(clouds.TagRole),
+			Value: aws.String(BastionRole),
+		},
+		&ec2.Tag{
+			Key:   aws.String(clouds.TagClusterID),
+			Value: aws.String(cfg.Kube.ID),
+		},
+	)
+
+	instanceType := bastionInstanceType
+
+	if cfg.Kube.Arch == "arm64" {
+		instanceType = bastionArmInstanceType
+	}
+
+	// Bastion shares security group with nodes, so ssh is open to it and
+	// it is allowed to reach all machines of the kube.
+	_, err := svc.RunInstancesWithContext(ctx, &ec2.RunInstancesInput{
+		ImageId:      aws.String(cfg.AWSConfig.ImageID),
+		InstanceType: aws.String(instanceType),
+		KeyName:      aws.String(cfg.AWSConfig.KeyPairName),
+		MaxCount:     aws.Int64(1),
+		MinCount:     aws.Int64(1),
+		NetworkInterfaces: []*ec2.InstanceNetworkInterfaceSpecification{
+			{
+				DeviceIndex:              aws.Int64(0),
+				AssociatePublicIpAddress: aws.Bool(true),
+				DeleteOnTermination:      aws.Bool(true),
+				SubnetId:                 aws.String(subnetID),
+				Groups:                   aws.StringSlice([]string{cfg.AWSConfig.NodesSecurityGroupID}),
+			},
+		},
+		TagSpecifications: []*ec2.TagSpecification{
+			{
+				ResourceType: aws.String(ec2.ResourceTypeInstance),
+				Tags:         tags,
+			},
+		},
+	})
+
+	if err != nil {
+		return errors.Wrap(ErrCreateInstance, err.Error())
+	}
+
+	return nil
+}
+
+// bastionSubnet returns subnet of the availability zone of the kube
+// or the first one when zone has no subnet.
+func bastionSubnet(cfg steps.AWSConfig) string {
+	if subnetID := cfg.Subnets[cfg.AvailabilityZone]; subnetID != "" {
+		return subnetID
+	}
+
+	zones := make([]string, 0, len(cfg.Subnets))
+
+	for az := range cfg.Subnets {
+		zones = append(zones, az)
+	}
+
+	if len(zones) == 0 {
+		return ""
+	}
+
+	sort.Strings(zones)
+
+	return cfg.Subnets[zones[0]]
+}
+
+func (*CreateBastionStep) Name() string {
+	return StepCreateBastion
+}
+
+// DefaultTimeout is how long bastion is waited to be running.
+func (*CreateBastionStep) DefaultTimeout() time.Duration {
+	return instanceRunningTimeout
+}
+
+func (*CreateBastionStep) Description() string {
+	return "Create bastion host for ssh access to private cluster"
+}
+
+func (*CreateBastionStep) Depends() []string {
+	return []string{StepCreateSubnets, StepCreateSecurityGroups}
+}
+
+func (*CreateBastionStep) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
@@ -0,0 +1,175 @@
+package amazon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+func TestCreateBastionStep_Run(t *testing.T) {
+	bastion := &ec2.DescribeInstancesOutput{
+		Reservations: []*ec2.Reservation{
+			{
+				Instances: []*ec2.Instance{
+					{
+						InstanceId:      aws.String("i-1234"),
+						PublicIpAddress: aws.String("54.0.0.1"),
+					},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		description string
+
+		privateNetworking bool
+		bastionHost       string
+
+		existing    *ec2.DescribeInstancesOutput
+		describeErr error
+		runErr      error
+
+		launched    bool
+		expectedErr string
+		expected    string
+	}{
+		{
+			description: "public kube",
+		},
+		{
+			description:       "bastion of profile",
+			privateNetworking: true,
+			bastionHost:       "10.0.0.10",
+			expected:          "10.0.0.10",
+		},
+		{
+			description:       "describe error",
+			privateNetworking: true,
+			describeErr:       errors.New("describe"),
+			expectedErr:       "describe",
+		},
+		{
+			description:       "run error",
+			privateNetworking: true,
+			existing:          &ec2.DescribeInstancesOutput{},
+			runErr:            errors.New("run"),
+			launched:          true,
+			expectedErr:       "run",
+		},
+		{
+			description:       "launch bastion",
+			privateNetworking: true,
+			existing:          &ec2.DescribeInstancesOutput{},
+			launched:          true,
+			expected:          "54.0.0.1",
+		},
+		{
+			description:       "reuse bastion",
+			privateNetworking: true,
+			existing:          bastion,
+			expected:          "54.0.0.1",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		svc := &mockEC2{}
+		svc.On("DescribeInstancesWithContext", mock.Anything,
+			mock.Anything, mock.Anything).
+			Return(testCase.existing, testCase.describeErr).Once()
+		svc.On("DescribeInstancesWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(bastion, nil).Once()
+		svc.On("RunInstancesWithContext", mock.Anything,
+			mock.MatchedBy(func(input *ec2.RunInstancesInput) bool {
+				return aws.StringValue(input.NetworkInterfaces[0].SubnetId) == "subnet-1" &&
+					aws.BoolValue(input.NetworkInterfaces[0].AssociatePublicIpAddress)
+			}), mock.Anything).Return(&ec2.Reservation{}, testCase.runErr)
+		svc.On("WaitUntilInstanceRunningWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(nil)
+
+		step := &CreateBastionStep{
+			getSvc: func(steps.AWSConfig) (instanceService, error) {
+				return svc, nil
+			},
+		}
+
+		config := &steps.Config{
+			Kube: model.Kube{
+				ID:                "1234",
+				Name:              "test",
+				PrivateNetworking: testCase.privateNetworking,
+				SSHConfig: model.SSHConfig{
+					BastionHost: testCase.bastionHost,
+				},
+			},
+			AWSConfig: steps.AWSConfig{
+				AvailabilityZone: "us-west-1b",
+				Subnets: map[string]string{
+					"us-west-1a": "subnet-0",
+					"us-west-1b": "subnet-1",
+				},
+			},
+		}
+
+		err := step.Run(context.Background(), &bytes.Buffer{}, config)
+
+		if err != nil && (testCase.expectedErr == "" ||
+			!strings.Contains(err.Error(), testCase.expectedErr)) {
+			t.Errorf("Wrong error %v", err)
+			continue
+		}
+
+		if err == nil && testCase.expectedErr != "" {
+			t.Errorf("Error %s expected", testCase.expectedErr)
+			continue
+		}
+
+		if launched := isCalled(svc, "RunInstancesWithContext"); launched != testCase.launched {
+			t.Errorf("Bastion launched %v expected %v", launched, testCase.launched)
+		}
+
+		if config.Kube.SSHConfig.BastionHost != testCase.expected {
+			t.Errorf("Wrong bastion host %s expected %s",
+				config.Kube.SSHConfig.BastionHost, testCase.expected)
+		}
+	}
+}
+
+func TestBastionSubnet(t *testing.T) {
+	subnet := bastionSubnet(steps.AWSConfig{
+		AvailabilityZone: "us-west-1c",
+		Subnets: map[string]string{
+			"us-west-1b": "subnet-1",
+			"us-west-1a": "subnet-0",
+		},
+	})
+
+	if subnet != "subnet-0" {
+		t.Errorf("Wrong subnet %s", subnet)
+	}
+
+	if subnet := bastionSubnet(steps.AWSConfig{}); subnet != "" {
+		t.Errorf("Wrong subnet %s", subnet)
+	}
+}
+
+func isCalled(m *mockEC2, method string) bool {
+	for _, call := range m.Calls {
+		if call.Method == method {
+			return true
+		}
+	}
+
+	return false
+}
@@ -119,6 +119,38 @@ const (
           } 
       ]
 }`
+
+	// Cluster autoscaler scales only groups tagged for autoscaling,
+	// https://github.com/kubernetes/autoscaler/tree/master/cluster-autoscaler/cloudprovider/aws
+	clusterAutoscalerIAMPolicy = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": [
+        "autoscaling:DescribeAutoScalingGroups",
+        "autoscaling:DescribeAutoScalingInstances",
+        "autoscaling:DescribeLaunchConfigurations",
+        "autoscaling:DescribeTags",
+        "ec2:DescribeLaunchTemplateVersions"
+      ],
+      "Resource": "*"
+    },
+    {
+      "Effect": "Allow",
+      "Action": [
+        "autoscaling:SetDesiredCapacity",
+        "autoscaling:TerminateInstanceInAutoScalingGroup"
+      ],
+      "Resource": "*",
+      "Condition": {
+        "StringEquals": {
+          "autoscaling:ResourceTag/k8s.io/cluster-autoscaler/enabled": "true"
+        }
+      }
+    }
+  ]
+}`
 )
 
 var (
@@ -162,6 +194,16 @@ func (s StepCreateInstanceProfiles) Run(ctx context.Context, w io.Writer, cfg *s
 	}
 	logrus.Infof("%s: set up %s instance profile", s.Name(), cfg.AWSConfig.NodesInstanceProfile)
 
+	// Cluster autoscaler runs on masters
+	if cfg.Kube.ClusterAutoscaler {
+		role := cfg.AWSConfig.MastersInstanceProfile
+		err = createIAMRoleInlinePolicy(ctx, iamS, role, role+"-autoscaler", clusterAutoscalerIAMPolicy)
+
+		if err != nil {
+			return errors.Wrapf(err, "%s: ensure cluster autoscaler policy of %s", s.Name(), role)
+		}
+	}
+
 	return nil
 }
 
@@ -268,9 +310,16 @@ func createIAMRole(ctx context.Context, iamS iamiface.IAMAPI, name string, polic
 }
 
 func createIAMRolePolicy(ctx context.Context, iamS iamiface.IAMAPI, name string, policy string) error {
+	return createIAMRoleInlinePolicy(ctx, iamS, name, name, policy)
+}
+
+// createIAMRoleInlinePolicy puts the policy to the role unless
+// the role has a policy with the name.
+func createIAMRoleInlinePolicy(ctx context.Context, iamS iamiface.IAMAPI,
+	roleName, policyName, policy string) error {
 	getInput := &iam.GetRolePolicyInput{
-		RoleName:   aws.String(name),
-		PolicyName: aws.String(name),
+		RoleName:   aws.String(roleName),
+		PolicyName: aws.String(policyName),
 	}
 	_, err := iamS.GetRolePolicyWithContext(ctx, getInput)
 	if err == nil {
@@ -280,8 +329,8 @@ func createIAMRolePolicy(ctx context.Context, iamS iamiface.IAMAPI, name string,
 		return err
 	}
 	putRoleInput := &iam.PutRolePolicyInput{
-		RoleName:       aws.String(name),
-		PolicyName:     aws.String(name),
+		RoleName:       aws.String(roleName),
+		PolicyName:     aws.String(policyName),
 		PolicyDocument: aws.String(policy),
 	}
 	_, err = iamS.PutRolePolicyWithContext(ctx, putRoleInput)
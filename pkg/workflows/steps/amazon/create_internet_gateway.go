@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -22,9 +23,9 @@ type CreateInternetGatewayStep struct {
 }
 
 type InternetGatewayCreater interface {
-	CreateInternetGateway(*ec2.CreateInternetGatewayInput) (*ec2.CreateInternetGatewayOutput, error)
-	CreateTags(*ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error)
-	AttachInternetGateway(*ec2.AttachInternetGatewayInput) (*ec2.AttachInternetGatewayOutput, error)
+	CreateInternetGatewayWithContext(aws.Context, *ec2.CreateInternetGatewayInput, ...request.Option) (*ec2.CreateInternetGatewayOutput, error)
+	CreateTagsWithContext(aws.Context, *ec2.CreateTagsInput, ...request.Option) (*ec2.CreateTagsOutput, error)
+	AttachInternetGatewayWithContext(aws.Context, *ec2.AttachInternetGatewayInput, ...request.Option) (*ec2.AttachInternetGatewayOutput, error)
 }
 
 //InitCreateMachine adds the step to the registry
@@ -51,6 +52,12 @@ func NewCreateInternetGatewayStep(ec2fn GetEC2Fn) *CreateInternetGatewayStep {
 func (s *CreateInternetGatewayStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config) error {
 	logrus.Debugf(StepCreateInternetGateway)
 
+	// Subnets of user are routed by user
+	if hasUserSubnets(cfg) {
+		logrus.Debugf("skip internet gateway of VPC %s", cfg.AWSConfig.VPCID)
+		return nil
+	}
+
 	// Internet gateway already exists
 	if cfg.AWSConfig.InternetGatewayID != "" {
 		logrus.Debugf("use internet gateway %s",
@@ -65,7 +72,7 @@ func (s *CreateInternetGatewayStep) Run(ctx context.Context, w io.Writer, cfg *s
 		}
 
 		// Use default gateway for VPC
-		resp, err := svc.CreateInternetGateway(new(ec2.CreateInternetGatewayInput))
+		resp, err := svc.CreateInternetGatewayWithContext(ctx, new(ec2.CreateInternetGatewayInput))
 		if err != nil {
 			return err
 		}
@@ -73,7 +80,7 @@ func (s *CreateInternetGatewayStep) Run(ctx context.Context, w io.Writer, cfg *s
 		cfg.AWSConfig.InternetGatewayID = *resp.InternetGateway.InternetGatewayId
 
 		// Tag gateway
-		ec2Tags := []*ec2.Tag{
+		tags := []*ec2.Tag{
 			{
 				Key:   aws.String("KubernetesCluster"),
 				Value: aws.String(cfg.Kube.Name),
@@ -91,9 +98,9 @@ func (s *CreateInternetGatewayStep) Run(ctx context.Context, w io.Writer, cfg *s
 
 		tagInput := &ec2.CreateTagsInput{
 			Resources: []*string{aws.String(cfg.AWSConfig.InternetGatewayID)},
-			Tags:      ec2Tags,
+			Tags:      ec2Tags(cfg.Tags, tags...),
 		}
-		_, err = svc.CreateTags(tagInput)
+		_, err = svc.CreateTagsWithContext(ctx, tagInput)
 
 		if err != nil {
 			logrus.Errorf("Error tagging route table %s %v",
@@ -108,7 +115,7 @@ func (s *CreateInternetGatewayStep) Run(ctx context.Context, w io.Writer, cfg *s
 			VpcId:             aws.String(cfg.AWSConfig.VPCID),
 			InternetGatewayId: aws.String(cfg.AWSConfig.InternetGatewayID),
 		}
-		if _, err := svc.AttachInternetGateway(attachGw); err != nil && !strings.Contains(err.Error(), "already has an internet gateway attached") {
+		if _, err := svc.AttachInternetGatewayWithContext(ctx, attachGw); err != nil && !strings.Contains(err.Error(), "already has an internet gateway attached") {
 			logrus.Errorf("Error attaching GW %s to VPC %s", cfg.AWSConfig.InternetGatewayID, cfg.AWSConfig.VPCID)
 			return err
 		}
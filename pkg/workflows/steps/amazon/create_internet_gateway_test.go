@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/pkg/errors"
@@ -19,9 +20,9 @@ type mockIGWService struct {
 	mock.Mock
 }
 
-func (m *mockIGWService) CreateInternetGateway(
-	input *ec2.CreateInternetGatewayInput) (*ec2.CreateInternetGatewayOutput, error) {
-	args := m.Called(input)
+func (m *mockIGWService) CreateInternetGatewayWithContext(ctx aws.Context,
+	input *ec2.CreateInternetGatewayInput, opts ...request.Option) (*ec2.CreateInternetGatewayOutput, error) {
+	args := m.Called(ctx, input, opts)
 	val, ok := args.Get(0).(*ec2.CreateInternetGatewayOutput)
 	if !ok {
 		return nil, args.Error(1)
@@ -29,9 +30,9 @@ func (m *mockIGWService) CreateInternetGateway(
 	return val, args.Error(1)
 }
 
-func (m *mockIGWService) CreateTags(
-	input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error) {
-	args := m.Called(input)
+func (m *mockIGWService) CreateTagsWithContext(ctx aws.Context,
+	input *ec2.CreateTagsInput, opts ...request.Option) (*ec2.CreateTagsOutput, error) {
+	args := m.Called(ctx, input, opts)
 	val, ok := args.Get(0).(*ec2.CreateTagsOutput)
 	if !ok {
 		return nil, args.Error(1)
@@ -39,9 +40,9 @@ func (m *mockIGWService) CreateTags(
 	return val, args.Error(1)
 }
 
-func (m *mockIGWService) AttachInternetGateway(
-	input *ec2.AttachInternetGatewayInput) (*ec2.AttachInternetGatewayOutput, error) {
-	args := m.Called(input)
+func (m *mockIGWService) AttachInternetGatewayWithContext(ctx aws.Context,
+	input *ec2.AttachInternetGatewayInput, opts ...request.Option) (*ec2.AttachInternetGatewayOutput, error) {
+	args := m.Called(ctx, input, opts)
 	val, ok := args.Get(0).(*ec2.AttachInternetGatewayOutput)
 	if !ok {
 		return nil, args.Error(1)
@@ -111,11 +112,11 @@ func TestCreateInternetGatewayStep_Run(t *testing.T) {
 
 	for _, testCase := range testCases {
 		svc := &mockIGWService{}
-		svc.On("CreateInternetGateway", mock.Anything).
+		svc.On("CreateInternetGatewayWithContext", mock.Anything, mock.Anything, mock.Anything).
 			Return(testCase.createIGWOut, testCase.createIGWErr)
-		svc.On("CreateTags", mock.Anything).
+		svc.On("CreateTagsWithContext", mock.Anything, mock.Anything, mock.Anything).
 			Return(mock.Anything, testCase.createTagserr)
-		svc.On("AttachInternetGateway", mock.Anything).
+		svc.On("AttachInternetGatewayWithContext", mock.Anything, mock.Anything, mock.Anything).
 			Return(mock.Anything, testCase.attachErr)
 
 		step := &CreateInternetGatewayStep{
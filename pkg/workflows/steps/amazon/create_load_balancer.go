@@ -63,6 +63,13 @@ func NewCreateLoadBalancerStep(getELBFn GetELBFn) *CreateLoadBalancerStep {
 }
 
 func (s *CreateLoadBalancerStep) Run(ctx context.Context, out io.Writer, cfg *steps.Config) error {
+	// Masters are registered to target group of user instead
+	if cfg.AWSConfig.TargetGroupARN != "" {
+		logrus.Infof("Skip creating load balancers, target group %s is used",
+			cfg.AWSConfig.TargetGroupARN)
+		return nil
+	}
+
 	svc, err := s.getLoadBalancerService(cfg.AWSConfig)
 
 	if err != nil {
@@ -78,7 +85,8 @@ func (s *CreateLoadBalancerStep) Run(ctx context.Context, out io.Writer, cfg *st
 		subnetsSlice = append(subnetsSlice, aws.String(subnet))
 	}
 
-	if cfg.AWSConfig.ExternalLoadBalancerName == "" {
+	// API of private kube is reachable only inside of VPC
+	if cfg.AWSConfig.ExternalLoadBalancerName == "" && !cfg.Kube.PrivateNetworking {
 		externalLoadBalancerName := aws.String(util.CreateLBName(cfg.Kube.ID, true))
 		output, err := svc.CreateLoadBalancerWithContext(ctx, &elb.CreateLoadBalancerInput{
 			Listeners: []*elb.Listener{
@@ -178,6 +186,10 @@ func (s *CreateLoadBalancerStep) Run(ctx context.Context, out io.Writer, cfg *st
 		cfg.AWSConfig.InternalLoadBalancerName = *internalLoadBalancerName
 	}
 
+	if cfg.Kube.PrivateNetworking {
+		cfg.Kube.ExternalDNSName = cfg.Kube.InternalDNSName
+	}
+
 	for i := 0; i < s.attemptCount; i++ {
 		select {
 		case <-ctx.Done():
@@ -200,24 +212,26 @@ func (s *CreateLoadBalancerStep) Run(ctx context.Context, out io.Writer, cfg *st
 		return errors.Wrap(err, "error waiting for load balancer to come up")
 	}
 
-	logrus.Debugf("Configure health check for %s", cfg.AWSConfig.ExternalLoadBalancerName)
-	healthCheckInput := &elb.ConfigureHealthCheckInput{
-		LoadBalancerName: aws.String(cfg.AWSConfig.ExternalLoadBalancerName),
-		HealthCheck: &elb.HealthCheck{
-			HealthyThreshold:   &healthyThreshold,
-			UnhealthyThreshold: &unhealthyThreshold,
-			Interval:           &checkInternal,
-			Timeout:            &checkTimeout,
-			Target:             aws.String(fmt.Sprintf("HTTPS:%d/healthz", cfg.Kube.APIServerPort)),
-		},
-	}
+	if cfg.AWSConfig.ExternalLoadBalancerName != "" {
+		logrus.Debugf("Configure health check for %s", cfg.AWSConfig.ExternalLoadBalancerName)
+		healthCheckInput := &elb.ConfigureHealthCheckInput{
+			LoadBalancerName: aws.String(cfg.AWSConfig.ExternalLoadBalancerName),
+			HealthCheck: &elb.HealthCheck{
+				HealthyThreshold:   &healthyThreshold,
+				UnhealthyThreshold: &unhealthyThreshold,
+				Interval:           &checkInternal,
+				Timeout:            &checkTimeout,
+				Target:             aws.String(fmt.Sprintf("HTTPS:%d/healthz", cfg.Kube.APIServerPort)),
+			},
+		}
 
-	if _, err := svc.ConfigureHealthCheck(healthCheckInput); err != nil {
-		logrus.Errorf("error configuring health check for %v  %s", err, cfg.AWSConfig.ExternalLoadBalancerName)
+		if _, err := svc.ConfigureHealthCheck(healthCheckInput); err != nil {
+			logrus.Errorf("error configuring health check for %v  %s", err, cfg.AWSConfig.ExternalLoadBalancerName)
+		}
 	}
 
 	logrus.Debugf("Configure health check for %s", cfg.AWSConfig.InternalLoadBalancerName)
-	healthCheckInput = &elb.ConfigureHealthCheckInput{
+	healthCheckInput := &elb.ConfigureHealthCheckInput{
 		LoadBalancerName: aws.String(cfg.AWSConfig.InternalLoadBalancerName),
 		HealthCheck: &elb.HealthCheck{
 			HealthyThreshold:   &healthyThreshold,
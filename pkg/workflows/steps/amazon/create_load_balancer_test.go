@@ -199,6 +199,70 @@ func TestCreateLoadBalancerStep_Run(t *testing.T) {
 	}
 }
 
+func TestCreateLoadBalancerStepPrivate(t *testing.T) {
+	svc := new(mockELBService)
+	svc.On("CreateLoadBalancerWithContext", mock.Anything,
+		mock.MatchedBy(func(input *elb.CreateLoadBalancerInput) bool {
+			return aws.StringValue(input.Scheme) == "internal"
+		}), mock.Anything).Return(&elb.CreateLoadBalancerOutput{
+		DNSName: aws.String("localhost"),
+	}, nil).Once()
+	svc.On("ConfigureHealthCheck", mock.Anything).Return(nil, nil).Once()
+
+	step := &CreateLoadBalancerStep{
+		attemptCount: 1,
+		getLoadBalancerService: func(cfg steps.AWSConfig) (LoadBalancerCreater, error) {
+			return svc, nil
+		},
+	}
+
+	config := &steps.Config{
+		Kube: model.Kube{
+			ID:                "1234",
+			PrivateNetworking: true,
+		},
+	}
+
+	if err := step.Run(context.Background(), &bytes.Buffer{}, config); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	if config.AWSConfig.ExternalLoadBalancerName != "" {
+		t.Errorf("External load balancer %s must not be created",
+			config.AWSConfig.ExternalLoadBalancerName)
+	}
+
+	if config.Kube.ExternalDNSName != "localhost" {
+		t.Errorf("Wrong external dns name %s", config.Kube.ExternalDNSName)
+	}
+
+	svc.AssertExpectations(t)
+}
+
+func TestCreateLoadBalancerStepTargetGroup(t *testing.T) {
+	svc := new(mockELBService)
+
+	step := &CreateLoadBalancerStep{
+		attemptCount: 1,
+		getLoadBalancerService: func(cfg steps.AWSConfig) (LoadBalancerCreater, error) {
+			return svc, nil
+		},
+	}
+
+	config := &steps.Config{
+		AWSConfig: steps.AWSConfig{
+			TargetGroupARN: "arn:tg",
+		},
+	}
+
+	if err := step.Run(context.Background(), &bytes.Buffer{}, config); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	svc.AssertNotCalled(t, "CreateLoadBalancerWithContext", mock.Anything,
+		mock.Anything, mock.Anything)
+}
+
 func TestCreateLoadBalancerStep_Rollback(t *testing.T) {
 	step := &CreateLoadBalancerStep{}
 
@@ -2,9 +2,10 @@ package amazon
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"io"
-	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
@@ -20,6 +21,9 @@ import (
 
 const (
 	StepNameCreateEC2Instance = "aws_create_instance"
+
+	instanceRunningTimeout = time.Minute * 10
+	instanceWaiterDelay    = time.Second * 15
 )
 
 type instanceService interface {
@@ -61,10 +65,7 @@ func (s *StepCreateInstance) Run(ctx context.Context, w io.Writer, cfg *steps.Co
 		return errors.Wrap(ErrAuthorization, err.Error())
 	}
 
-	role := model.RoleMaster
-	if !cfg.IsMaster {
-		role = model.RoleNode
-	}
+	role := cfg.Role()
 
 	nodeName := util.MakeNodeName(cfg.Kube.Name, cfg.TaskID, cfg.IsMaster)
 
@@ -76,6 +77,7 @@ func (s *StepCreateInstance) Run(ctx context.Context, w io.Writer, cfg *steps.Co
 		Size:     cfg.AWSConfig.InstanceType,
 		Provider: clouds.AWS,
 		State:    model.MachineStatePlanned,
+		Pool:     cfg.Pool,
 	}
 
 	// Update node state in cluster
@@ -94,17 +96,48 @@ func (s *StepCreateInstance) Run(ctx context.Context, w io.Writer, cfg *steps.Co
 	}
 
 	isEbs := false
-	volumeSize, err := strconv.Atoi(cfg.AWSConfig.VolumeSize)
+	ebs, err := ebsBlockDevice(cfg.AWSConfig, true)
+
+	if err != nil {
+		cfg.Node.State = model.MachineStateError
+		cfg.NodeChan() <- cfg.Node
+
+		return errors.Wrap(err, "root volume settings")
+	}
+
+	metadataOpts, err := newMetadataOptions(cfg.AWSConfig)
+
+	if err != nil {
+		cfg.Node.State = model.MachineStateError
+		cfg.NodeChan() <- cfg.Node
+
+		return errors.Wrap(err, "instance metadata options")
+	}
+
+	tags := ec2Tags(cfg.Tags,
+		&ec2.Tag{
+			Key:   aws.String("KubernetesCluster"),
+			Value: aws.String(cfg.Kube.Name),
+		},
+		&ec2.Tag{
+			Key:   aws.String(clouds.TagNodeName),
+			Value: aws.String(nodeName),
+		},
+		&ec2.Tag{
+			Key:   aws.String(clouds.TagRole),
+			Value: aws.String(util.MakeRole(cfg.IsMaster)),
+		},
+		&ec2.Tag{
+			Key:   aws.String(clouds.TagClusterID),
+			Value: aws.String(cfg.Kube.ID),
+		},
+	)
 
 	runInstanceInput := &ec2.RunInstancesInput{
 		BlockDeviceMappings: []*ec2.BlockDeviceMapping{
 			{
 				DeviceName: aws.String(cfg.AWSConfig.DeviceName),
-				Ebs: &ec2.EbsBlockDevice{
-					DeleteOnTermination: aws.Bool(true),
-					VolumeType:          aws.String("gp2"),
-					VolumeSize:          aws.Int64(int64(volumeSize)),
-				},
+				Ebs:        ebs,
 			},
 		},
 		Placement: &ec2.Placement{
@@ -120,43 +153,35 @@ func (s *StepCreateInstance) Run(ctx context.Context, w io.Writer, cfg *steps.Co
 		MaxCount:     aws.Int64(1),
 		MinCount:     aws.Int64(1),
 
-		//TODO add custom TAGS
 		TagSpecifications: []*ec2.TagSpecification{
 			{
-				ResourceType: aws.String("instance"),
-				Tags: []*ec2.Tag{
-					{
-						Key:   aws.String("KubernetesCluster"),
-						Value: aws.String(cfg.Kube.Name),
-					},
-					{
-						Key:   aws.String(clouds.TagNodeName),
-						Value: aws.String(nodeName),
-					},
-					{
-						Key:   aws.String("Role"),
-						Value: aws.String(util.MakeRole(cfg.IsMaster)),
-					},
-					{
-						Key:   aws.String(clouds.TagClusterID),
-						Value: aws.String(cfg.Kube.ID),
-					},
-				},
+				ResourceType: aws.String(ec2.ResourceTypeInstance),
+				Tags:         tags,
+			},
+			{
+				ResourceType: aws.String(ec2.ResourceTypeVolume),
+				Tags:         tags,
 			},
 		},
 	}
 
+	if cfg.AWSConfig.UserData != "" {
+		runInstanceInput.UserData = aws.String(base64.StdEncoding.EncodeToString(
+			[]byte(cfg.AWSConfig.UserData)))
+	}
+
 	runInstanceInput.NetworkInterfaces = []*ec2.InstanceNetworkInterfaceSpecification{
 		{
 			DeviceIndex:              aws.Int64(0),
-			AssociatePublicIpAddress: aws.Bool(true),
+			AssociatePublicIpAddress: aws.Bool(!cfg.Kube.PrivateNetworking),
 			DeleteOnTermination:      aws.Bool(true),
 			SubnetId:                 aws.String(cfg.AWSConfig.Subnets[cfg.AWSConfig.AvailabilityZone]),
 			Groups:                   []*string{secGroupID},
 		},
 	}
 
-	res, err := ec2Svc.RunInstancesWithContext(ctx, runInstanceInput)
+	res, err := ec2Svc.RunInstancesWithContext(ctx, runInstanceInput,
+		withMetadataOptions(metadataOpts, "MetadataOptions"))
 	if err != nil {
 		cfg.Node.State = model.MachineStateError
 		cfg.NodeChan() <- cfg.Node
@@ -173,6 +198,7 @@ func (s *StepCreateInstance) Run(ctx context.Context, w io.Writer, cfg *steps.Co
 		Provider: clouds.AWS,
 		Size:     cfg.AWSConfig.InstanceType,
 		State:    model.MachineStateBuilding,
+		Pool:     cfg.Pool,
 	}
 
 	// Update node state in cluster
@@ -186,7 +212,7 @@ func (s *StepCreateInstance) Run(ctx context.Context, w io.Writer, cfg *steps.Co
 	}
 
 	instance := res.Instances[0]
-	log.Infof("[%s] - waiting to obtain public IP...", s.Name())
+	log.Infof("[%s] - waiting to obtain IP address...", s.Name())
 
 	lookup := &ec2.DescribeInstancesInput{
 		Filters: []*ec2.Filter{
@@ -201,7 +227,10 @@ func (s *StepCreateInstance) Run(ctx context.Context, w io.Writer, cfg *steps.Co
 		},
 	}
 	logrus.Debugf("Wait until instance %s running", nodeName)
-	err = ec2Svc.WaitUntilInstanceRunningWithContext(ctx, lookup)
+	timeout := steps.WaitTimeout(ctx, instanceRunningTimeout)
+	err = ec2Svc.WaitUntilInstanceRunningWithContext(ctx, lookup,
+		request.WithWaiterDelay(request.ConstantWaiterDelay(instanceWaiterDelay)),
+		request.WithWaiterMaxAttempts(int(timeout/instanceWaiterDelay)+1))
 
 	if err != nil {
 		logrus.Errorf("Error waiting instance %s cluster %s running %v",
@@ -221,10 +250,13 @@ func (s *StepCreateInstance) Run(ctx context.Context, w io.Writer, cfg *steps.Co
 		return errors.Wrap(ErrNoPublicIP, err.Error())
 	}
 
-	if i := findInstanceWithPublicAddr(out.Reservations); i != nil {
-		cfg.Node.PublicIp = *i.PublicIpAddress
-		cfg.Node.PrivateIp = *i.PrivateIpAddress
-		log.Infof("[%s] - found public ip - %s for node %s", s.Name(), cfg.Node.PublicIp, nodeName)
+	// Machines of private kube have no public IPs, they are reached
+	// through the bastion by private IP.
+	if i := findInstanceWithAddr(out.Reservations, !cfg.Kube.PrivateNetworking); i != nil {
+		cfg.Node.PublicIp = aws.StringValue(i.PublicIpAddress)
+		cfg.Node.PrivateIp = aws.StringValue(i.PrivateIpAddress)
+		log.Infof("[%s] - found public ip - %s private ip - %s for node %s",
+			s.Name(), cfg.Node.PublicIp, cfg.Node.PrivateIp, nodeName)
 	} else {
 		log.Errorf("[%s] - failed to find public IP address", s.Name())
 		cfg.Node.State = model.MachineStateError
@@ -255,10 +287,14 @@ func (s *StepCreateInstance) Rollback(ctx context.Context, w io.Writer, cfg *ste
 	return nil
 }
 
-func findInstanceWithPublicAddr(reservations []*ec2.Reservation) *ec2.Instance {
+func findInstanceWithAddr(reservations []*ec2.Reservation, public bool) *ec2.Instance {
 	for _, r := range reservations {
 		for _, i := range r.Instances {
-			if i.PublicIpAddress != nil {
+			if public && i.PublicIpAddress != nil {
+				return i
+			}
+
+			if !public && i.PrivateIpAddress != nil {
 				return i
 			}
 		}
@@ -266,10 +302,20 @@ func findInstanceWithPublicAddr(reservations []*ec2.Reservation) *ec2.Instance {
 	return nil
 }
 
+// RetryPolicy opts out of retries, instance would be launched twice.
+func (*StepCreateInstance) RetryPolicy() steps.RetryPolicy {
+	return steps.NoRetry
+}
+
 func (*StepCreateInstance) Name() string {
 	return StepNameCreateEC2Instance
 }
 
+// DefaultTimeout is how long instance is waited to be running.
+func (*StepCreateInstance) DefaultTimeout() time.Duration {
+	return instanceRunningTimeout
+}
+
 func (*StepCreateInstance) Description() string {
 	return "Create EC2 Instance"
 }
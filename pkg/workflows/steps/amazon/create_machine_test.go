@@ -239,6 +239,79 @@ func TestStepCreateInstance_Run(t *testing.T) {
 	}
 }
 
+func TestStepCreateInstancePrivate(t *testing.T) {
+	config, err := steps.NewConfig("test", "", profile.Profile{
+		PrivateNetworking: true,
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	config.TaskID = uuid.New()
+	config.Kube.ID = uuid.New()
+
+	ec2Svc := &mockEC2{}
+	ec2Svc.On("RunInstancesWithContext", mock.Anything,
+		mock.MatchedBy(func(input *ec2.RunInstancesInput) bool {
+			return !aws.BoolValue(input.NetworkInterfaces[0].AssociatePublicIpAddress)
+		}), mock.Anything).
+		Return(&ec2.Reservation{
+			Instances: []*ec2.Instance{
+				{
+					InstanceId: aws.String("1234"),
+					LaunchTime: &time.Time{},
+				},
+			},
+		}, nil)
+	ec2Svc.On("DescribeInstancesWithContext",
+		mock.Anything, mock.Anything, mock.Anything).
+		Return(&ec2.DescribeInstancesOutput{
+			Reservations: []*ec2.Reservation{
+				{
+					Instances: []*ec2.Instance{
+						{
+							InstanceId:       aws.String("1234"),
+							PrivateIpAddress: aws.String("172.16.0.1"),
+							LaunchTime:       &time.Time{},
+						},
+					},
+				},
+			},
+		}, nil)
+	ec2Svc.On("WaitUntilInstanceRunningWithContext",
+		mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	step := &StepCreateInstance{
+		getSvc: func(steps.AWSConfig) (instanceService, error) {
+			return ec2Svc, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for {
+			select {
+			case <-config.NodeChan():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	if err := step.Run(ctx, &bytes.Buffer{}, config); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if config.Node.PublicIp != "" || config.Node.PrivateIp != "172.16.0.1" {
+		t.Errorf("Wrong node ips %s %s", config.Node.PublicIp,
+			config.Node.PrivateIp)
+	}
+
+	ec2Svc.AssertExpectations(t)
+}
+
 func TestCreateInstanceStepName(t *testing.T) {
 	s := StepCreateInstance{}
 
@@ -6,6 +6,7 @@ import (
 	"io"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -17,9 +18,9 @@ import (
 const StepCreateRouteTable = "create_route_table"
 
 type Service interface {
-	CreateRouteTable(*ec2.CreateRouteTableInput) (*ec2.CreateRouteTableOutput, error)
-	CreateTags(*ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error)
-	CreateRoute(*ec2.CreateRouteInput) (*ec2.CreateRouteOutput, error)
+	CreateRouteTableWithContext(aws.Context, *ec2.CreateRouteTableInput, ...request.Option) (*ec2.CreateRouteTableOutput, error)
+	CreateTagsWithContext(aws.Context, *ec2.CreateTagsInput, ...request.Option) (*ec2.CreateTagsOutput, error)
+	CreateRouteWithContext(aws.Context, *ec2.CreateRouteInput, ...request.Option) (*ec2.CreateRouteOutput, error)
 }
 
 type CreateRouteTableStep struct {
@@ -52,7 +53,7 @@ func (s *CreateRouteTableStep) Run(ctx context.Context, w io.Writer, cfg *steps.
 	logrus.Debugf(StepCreateRouteTable)
 
 	//  route table already exists
-	if cfg.AWSConfig.RouteTableID != "" {
+	if cfg.AWSConfig.RouteTableID != "" || hasUserSubnets(cfg) {
 		return nil
 	}
 
@@ -65,7 +66,7 @@ func (s *CreateRouteTableStep) Run(ctx context.Context, w io.Writer, cfg *steps.
 			StepCreateRouteTable)
 	}
 
-	createResp, err := svc.CreateRouteTable(&ec2.CreateRouteTableInput{
+	createResp, err := svc.CreateRouteTableWithContext(ctx, &ec2.CreateRouteTableInput{
 		VpcId: aws.String(cfg.AWSConfig.VPCID),
 	})
 
@@ -78,7 +79,7 @@ func (s *CreateRouteTableStep) Run(ctx context.Context, w io.Writer, cfg *steps.
 	logrus.Infof("Create route table %s", cfg.AWSConfig.RouteTableID)
 
 	// Tag route table
-	ec2Tags := []*ec2.Tag{
+	tags := []*ec2.Tag{
 		{
 			Key:   aws.String("KubernetesCluster"),
 			Value: aws.String(cfg.Kube.Name),
@@ -96,9 +97,9 @@ func (s *CreateRouteTableStep) Run(ctx context.Context, w io.Writer, cfg *steps.
 
 	input := &ec2.CreateTagsInput{
 		Resources: []*string{aws.String(cfg.AWSConfig.RouteTableID)},
-		Tags:      ec2Tags,
+		Tags:      ec2Tags(cfg.Tags, tags...),
 	}
-	_, err = svc.CreateTags(input)
+	_, err = svc.CreateTagsWithContext(ctx, input)
 
 	if err != nil {
 		logrus.Errorf("Error tagging route table %s %v",
@@ -107,7 +108,7 @@ func (s *CreateRouteTableStep) Run(ctx context.Context, w io.Writer, cfg *steps.
 	}
 
 	// Create route for external connectivity
-	_, err = svc.CreateRoute(&ec2.CreateRouteInput{
+	_, err = svc.CreateRouteWithContext(ctx, &ec2.CreateRouteInput{
 		DestinationCidrBlock: aws.String("0.0.0.0/0"),
 		RouteTableId:         aws.String(cfg.AWSConfig.RouteTableID),
 		GatewayId:            aws.String(cfg.AWSConfig.InternetGatewayID),
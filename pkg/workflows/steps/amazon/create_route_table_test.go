@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/pkg/errors"
@@ -19,8 +20,9 @@ type mockService struct {
 	mock.Mock
 }
 
-func (m *mockService) CreateRouteTable(input *ec2.CreateRouteTableInput) (*ec2.CreateRouteTableOutput, error) {
-	args := m.Called(input)
+func (m *mockService) CreateRouteTableWithContext(ctx aws.Context,
+	input *ec2.CreateRouteTableInput, opts ...request.Option) (*ec2.CreateRouteTableOutput, error) {
+	args := m.Called(ctx, input, opts)
 	val, ok := args.Get(0).(*ec2.CreateRouteTableOutput)
 	if !ok {
 		return nil, args.Error(1)
@@ -28,8 +30,9 @@ func (m *mockService) CreateRouteTable(input *ec2.CreateRouteTableInput) (*ec2.C
 	return val, args.Error(1)
 }
 
-func (m *mockService) CreateTags(input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error) {
-	args := m.Called(input)
+func (m *mockService) CreateTagsWithContext(ctx aws.Context,
+	input *ec2.CreateTagsInput, opts ...request.Option) (*ec2.CreateTagsOutput, error) {
+	args := m.Called(ctx, input, opts)
 	val, ok := args.Get(0).(*ec2.CreateTagsOutput)
 	if !ok {
 		return nil, args.Error(1)
@@ -37,8 +40,9 @@ func (m *mockService) CreateTags(input *ec2.CreateTagsInput) (*ec2.CreateTagsOut
 	return val, args.Error(1)
 }
 
-func (m *mockService) CreateRoute(input *ec2.CreateRouteInput) (*ec2.CreateRouteOutput, error) {
-	args := m.Called(input)
+func (m *mockService) CreateRouteWithContext(ctx aws.Context,
+	input *ec2.CreateRouteInput, opts ...request.Option) (*ec2.CreateRouteOutput, error) {
+	args := m.Called(ctx, input, opts)
 	val, ok := args.Get(0).(*ec2.CreateRouteOutput)
 	if !ok {
 		return nil, args.Error(1)
@@ -104,11 +108,11 @@ func TestCreateRouteTableStep_Run(t *testing.T) {
 	for _, testCase := range testCases {
 		t.Log(testCase.description)
 		svc := &mockService{}
-		svc.On("CreateRouteTable", mock.Anything).
+		svc.On("CreateRouteTableWithContext", mock.Anything, mock.Anything, mock.Anything).
 			Return(testCase.createOut, testCase.createRouteTableErr)
-		svc.On("CreateTags", mock.Anything).
+		svc.On("CreateTagsWithContext", mock.Anything, mock.Anything, mock.Anything).
 			Return(mock.Anything, testCase.tagErr)
-		svc.On("CreateRoute", mock.Anything).
+		svc.On("CreateRouteWithContext", mock.Anything, mock.Anything, mock.Anything).
 			Return(mock.Anything, testCase.createRouteErr)
 
 		step := &CreateRouteTableStep{
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
@@ -12,6 +13,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/sgerrors"
 	"github.com/supergiant/control/pkg/util"
 	"github.com/supergiant/control/pkg/workflows/steps"
 )
@@ -19,8 +21,10 @@ import (
 const StepCreateSecurityGroups = "create_security_groups_step"
 
 type secGroupService interface {
+	DescribeSecurityGroupsWithContext(aws.Context, *ec2.DescribeSecurityGroupsInput, ...request.Option) (*ec2.DescribeSecurityGroupsOutput, error)
 	CreateSecurityGroupWithContext(aws.Context, *ec2.CreateSecurityGroupInput, ...request.Option) (*ec2.CreateSecurityGroupOutput, error)
 	AuthorizeSecurityGroupIngressWithContext(aws.Context, *ec2.AuthorizeSecurityGroupIngressInput, ...request.Option) (*ec2.AuthorizeSecurityGroupIngressOutput, error)
+	CreateTagsWithContext(aws.Context, *ec2.CreateTagsInput, ...request.Option) (*ec2.CreateTagsOutput, error)
 }
 
 type CreateSecurityGroupsStep struct {
@@ -57,38 +61,34 @@ func (s *CreateSecurityGroupsStep) Run(ctx context.Context, w io.Writer, cfg *st
 		return errors.Wrapf(err, "%s get service", StepCreateSecurityGroups)
 	}
 
+	// Security groups of user are validated and kept as is
+	if cfg.Kube.IsUserResource(cfg.AWSConfig.MastersSecurityGroupID) ||
+		cfg.Kube.IsUserResource(cfg.AWSConfig.NodesSecurityGroupID) {
+		log.Infof("[%s] - validate security groups %s %s", s.Name(),
+			cfg.AWSConfig.MastersSecurityGroupID, cfg.AWSConfig.NodesSecurityGroupID)
+		return s.validateGroups(ctx, svc, cfg)
+	}
+
 	logrus.Debugf("Create security groups for VPC %s",
 		cfg.AWSConfig.VPCID)
 	if cfg.AWSConfig.MastersSecurityGroupID == "" {
-		groupName := fmt.Sprintf("%s-masters-secgroup", cfg.Kube.ID)
-
 		log.Infof("[%s] - masters security groups not specified, will create a new one...", s.Name())
-		out, err := svc.CreateSecurityGroupWithContext(ctx, &ec2.CreateSecurityGroupInput{
-			Description: aws.String("Security group for Kubernetes masters for cluster " + cfg.Kube.ID),
-			VpcId:       aws.String(cfg.AWSConfig.VPCID),
-			GroupName:   aws.String(groupName),
-		})
+		groupID, err := s.ensureGroup(ctx, svc, cfg, resourceRoleMastersSecGroup,
+			"Security group for Kubernetes masters for cluster "+cfg.Kube.ID)
 		if err != nil {
 			return errors.Wrapf(err, "create master security group")
-		} else {
-			cfg.AWSConfig.MastersSecurityGroupID = *out.GroupId
 		}
+		cfg.AWSConfig.MastersSecurityGroupID = groupID
 	}
 	//If there is no security group, create it
 	if cfg.AWSConfig.NodesSecurityGroupID == "" {
-		groupName := fmt.Sprintf("%s-nodes-secgroup", cfg.Kube.ID)
-
 		log.Infof("[%s] - node security groups not specified, will create a new one...", s.Name())
-		out, err := svc.CreateSecurityGroupWithContext(ctx, &ec2.CreateSecurityGroupInput{
-			Description: aws.String("Security group for Kubernetes nodes for cluster " + cfg.Kube.ID),
-			VpcId:       aws.String(cfg.AWSConfig.VPCID),
-			GroupName:   aws.String(groupName),
-		})
+		groupID, err := s.ensureGroup(ctx, svc, cfg, resourceRoleNodesSecGroup,
+			"Security group for Kubernetes nodes for cluster "+cfg.Kube.ID)
 		if err != nil {
 			return errors.Wrapf(err, "create node security group")
-		} else {
-			cfg.AWSConfig.NodesSecurityGroupID = *out.GroupId
 		}
+		cfg.AWSConfig.NodesSecurityGroupID = groupID
 	}
 
 	logrus.Debugf("Security groups %s %s has been created",
@@ -124,6 +124,103 @@ func (s *CreateSecurityGroupsStep) Run(ctx context.Context, w io.Writer, cfg *st
 	return nil
 }
 
+// ensureGroup returns the group of the cluster with the role created by
+// the previous run of the step or creates a new one.
+func (s *CreateSecurityGroupsStep) ensureGroup(ctx context.Context, svc secGroupService,
+	cfg *steps.Config, role, description string) (string, error) {
+	filters := append(clusterFilters(cfg.Kube.ID, role), &ec2.Filter{
+		Name:   aws.String("vpc-id"),
+		Values: aws.StringSlice([]string{cfg.AWSConfig.VPCID}),
+	})
+
+	found, err := svc.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: filters,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "describe security groups")
+	}
+
+	if found != nil && len(found.SecurityGroups) > 0 {
+		return aws.StringValue(found.SecurityGroups[0].GroupId), nil
+	}
+
+	out, err := svc.CreateSecurityGroupWithContext(ctx, &ec2.CreateSecurityGroupInput{
+		Description: aws.String(description),
+		VpcId:       aws.String(cfg.AWSConfig.VPCID),
+		GroupName:   aws.String(fmt.Sprintf("%s-%s", cfg.Kube.ID, role)),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	groupID := aws.StringValue(out.GroupId)
+
+	if err := tagResource(ctx, svc, cfg, groupID, role); err != nil {
+		return "", err
+	}
+
+	return groupID, nil
+}
+
+// validateGroups checks that security groups of user are in the VPC of
+// the cluster and allow traffic required by the cluster.
+func (s *CreateSecurityGroupsStep) validateGroups(ctx context.Context, svc secGroupService, cfg *steps.Config) error {
+	groupIDs := []string{cfg.AWSConfig.MastersSecurityGroupID, cfg.AWSConfig.NodesSecurityGroupID}
+
+	out, err := svc.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{
+		GroupIds: aws.StringSlice(groupIDs),
+	})
+	if err != nil {
+		return errors.Wrap(err, "describe security groups")
+	}
+
+	vpcIDs := make(map[string]string)
+
+	for _, group := range out.SecurityGroups {
+		vpcIDs[aws.StringValue(group.GroupId)] = aws.StringValue(group.VpcId)
+	}
+
+	for _, groupID := range groupIDs {
+		vpcID, ok := vpcIDs[groupID]
+
+		if !ok {
+			return errors.Wrapf(sgerrors.ErrNotFound, "security group %s", groupID)
+		}
+
+		if vpcID != cfg.AWSConfig.VPCID {
+			return errors.Wrapf(sgerrors.ErrValidationFailed,
+				"security group %s is in vpc %s not in %s", groupID, vpcID,
+				cfg.AWSConfig.VPCID)
+		}
+	}
+
+	missing, err := MissingSecurityGroupRules(ctx, svc, RequiredSecurityGroupRules(
+		cfg.AWSConfig, cfg.Kube.ExposedAddresses, cfg.Kube.APIServerPort))
+	if err != nil {
+		return errors.Wrap(err, "check security group rules")
+	}
+
+	if len(missing) > 0 {
+		rules := make([]string, 0, len(missing))
+
+		for _, rule := range missing {
+			source := rule.CIDR
+
+			if rule.SourceGroupID != "" {
+				source = rule.SourceGroupID
+			}
+
+			rules = append(rules, fmt.Sprintf("%s %s %d-%d from %s", rule.GroupID,
+				rule.Protocol, rule.FromPort, rule.ToPort, source))
+		}
+
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"security groups miss rules: %s", strings.Join(rules, ", "))
+	}
+
+	return nil
+}
+
 func (s *CreateSecurityGroupsStep) authorizeSSH(ctx context.Context, EC2 secGroupService, groupID string) error {
 	_, err := EC2.AuthorizeSecurityGroupIngressWithContext(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
 		GroupId:    aws.String(groupID),
@@ -133,7 +230,7 @@ func (s *CreateSecurityGroupsStep) authorizeSSH(ctx context.Context, EC2 secGrou
 		IpProtocol: aws.String("tcp"),
 	})
 
-	return err
+	return ignoreDuplicatePermission(err)
 }
 
 func (s *CreateSecurityGroupsStep) allowAllTraffic(ctx context.Context, EC2 secGroupService, cfg *steps.Config) error {
@@ -163,7 +260,7 @@ func (s *CreateSecurityGroupsStep) allowAllTraffic(ctx context.Context, EC2 secG
 		},
 	})
 
-	if err != nil {
+	if err := ignoreDuplicatePermission(err); err != nil {
 		return err
 	}
 
@@ -193,7 +290,7 @@ func (s *CreateSecurityGroupsStep) allowAllTraffic(ctx context.Context, EC2 secG
 		},
 	})
 
-	return err
+	return ignoreDuplicatePermission(err)
 }
 
 func (s *CreateSecurityGroupsStep) whiteListAddresses(ctx context.Context, EC2 secGroupService, groupID string, addrs []profile.Addresses, port int64) error {
@@ -219,7 +316,7 @@ func (s *CreateSecurityGroupsStep) whiteListAddresses(ctx context.Context, EC2 s
 		},
 	})
 
-	return err
+	return ignoreDuplicatePermission(err)
 }
 
 func (*CreateSecurityGroupsStep) Name() string {
@@ -14,6 +14,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/workflows/steps"
 )
 
@@ -41,6 +42,26 @@ func (m *mockSecurityGroupSvc) AuthorizeSecurityGroupIngressWithContext(ctx aws.
 	return val, args.Error(1)
 }
 
+func (m *mockSecurityGroupSvc) DescribeSecurityGroupsWithContext(ctx aws.Context,
+	req *ec2.DescribeSecurityGroupsInput, opts ...request.Option) (*ec2.DescribeSecurityGroupsOutput, error) {
+	args := m.Called(ctx, req, opts)
+	val, ok := args.Get(0).(*ec2.DescribeSecurityGroupsOutput)
+	if !ok {
+		return nil, args.Error(1)
+	}
+	return val, args.Error(1)
+}
+
+func (m *mockSecurityGroupSvc) CreateTagsWithContext(ctx aws.Context,
+	req *ec2.CreateTagsInput, opts ...request.Option) (*ec2.CreateTagsOutput, error) {
+	args := m.Called(ctx, req, opts)
+	val, ok := args.Get(0).(*ec2.CreateTagsOutput)
+	if !ok {
+		return nil, args.Error(1)
+	}
+	return val, args.Error(1)
+}
+
 func TestCreateSecurityGroupsStep_Run(t *testing.T) {
 	testCases := []struct {
 		description string
@@ -174,6 +195,13 @@ func TestCreateSecurityGroupsStep_Run(t *testing.T) {
 
 	for _, testCase := range testCases {
 		svc := &mockSecurityGroupSvc{}
+		svc.On("DescribeSecurityGroupsWithContext",
+			mock.Anything, mock.Anything, mock.Anything).
+			Return(&ec2.DescribeSecurityGroupsOutput{}, nil)
+		svc.On("CreateTagsWithContext",
+			mock.Anything, mock.Anything, mock.Anything).
+			Return(&ec2.CreateTagsOutput{}, nil)
+
 		svc.On("CreateSecurityGroupWithContext",
 			mock.Anything, mock.Anything, mock.Anything).
 			Return(testCase.createMasterGroupOutput,
@@ -239,6 +267,106 @@ func TestCreateSecurityGroupsStep_Run(t *testing.T) {
 	}
 }
 
+func TestCreateSecurityGroupsStep_RunUserGroups(t *testing.T) {
+	group := func(groupID, vpcID string, permissions ...*ec2.IpPermission) *ec2.SecurityGroup {
+		return &ec2.SecurityGroup{
+			GroupId:       aws.String(groupID),
+			VpcId:         aws.String(vpcID),
+			IpPermissions: permissions,
+		}
+	}
+
+	permissions := []*ec2.IpPermission{
+		{
+			IpProtocol: aws.String("tcp"),
+			FromPort:   aws.Int64(22),
+			ToPort:     aws.Int64(22),
+			IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+		},
+		{
+			IpProtocol: aws.String("-1"),
+			UserIdGroupPairs: []*ec2.UserIdGroupPair{
+				{GroupId: aws.String("sg-masters")},
+				{GroupId: aws.String("sg-nodes")},
+			},
+		},
+	}
+
+	testCases := []struct {
+		description string
+		groups      []*ec2.SecurityGroup
+		errMsg      string
+	}{
+		{
+			description: "group not found",
+			groups:      []*ec2.SecurityGroup{group("sg-masters", "vpc-1")},
+			errMsg:      "sg-nodes",
+		},
+		{
+			description: "group of other vpc",
+			groups: []*ec2.SecurityGroup{
+				group("sg-masters", "vpc-1", permissions...),
+				group("sg-nodes", "vpc-2", permissions...),
+			},
+			errMsg: "vpc-2",
+		},
+		{
+			description: "missing rules",
+			groups: []*ec2.SecurityGroup{
+				group("sg-masters", "vpc-1", permissions...),
+				group("sg-nodes", "vpc-1"),
+			},
+			errMsg: "sg-nodes tcp 22-22",
+		},
+		{
+			description: "success",
+			groups: []*ec2.SecurityGroup{
+				group("sg-masters", "vpc-1", permissions...),
+				group("sg-nodes", "vpc-1", permissions...),
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		svc := &mockSecurityGroupSvc{}
+		svc.On("DescribeSecurityGroupsWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(&ec2.DescribeSecurityGroupsOutput{
+			SecurityGroups: testCase.groups,
+		}, nil)
+
+		config := &steps.Config{
+			AWSConfig: steps.AWSConfig{
+				VPCID:                  "vpc-1",
+				MastersSecurityGroupID: "sg-masters",
+				NodesSecurityGroupID:   "sg-nodes",
+			},
+		}
+		config.Kube.SetOwner("sg-masters", model.OwnerUser)
+		config.Kube.SetOwner("sg-nodes", model.OwnerUser)
+
+		step := &CreateSecurityGroupsStep{
+			getSvc: func(config steps.AWSConfig) (secGroupService, error) {
+				return svc, nil
+			},
+		}
+
+		err := step.Run(context.Background(), &bytes.Buffer{}, config)
+
+		if err == nil && testCase.errMsg != "" {
+			t.Errorf("Error must not be nil")
+		}
+
+		if err != nil && (testCase.errMsg == "" ||
+			!strings.Contains(err.Error(), testCase.errMsg)) {
+			t.Errorf("Wrong error %v expected %s", err, testCase.errMsg)
+		}
+
+		svc.AssertNotCalled(t, "AuthorizeSecurityGroupIngressWithContext",
+			mock.Anything, mock.Anything, mock.Anything)
+	}
+}
+
 func TestInitCreateSecurityGroups(t *testing.T) {
 	InitCreateSecurityGroups(GetEC2)
 
@@ -0,0 +1,287 @@
+package amazon
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const (
+	CreateSpotFleetStepName = "aws_create_spot_fleet"
+
+	AllocationStrategyLowestPrice       = "lowestPrice"
+	AllocationStrategyCapacityOptimized = "capacityOptimized"
+
+	// Vendored sdk does not know capacity optimized strategy yet
+	spotAllocationStrategyCapacityOptimized = "capacity-optimized"
+)
+
+type launchTemplateCreator interface {
+	CreateLaunchTemplateWithContext(aws.Context, *ec2.CreateLaunchTemplateInput, ...request.Option) (*ec2.CreateLaunchTemplateOutput, error)
+}
+
+type spotFleetCreator interface {
+	launchTemplateCreator
+	DeleteLaunchTemplateWithContext(aws.Context, *ec2.DeleteLaunchTemplateInput, ...request.Option) (*ec2.DeleteLaunchTemplateOutput, error)
+	CreateFleetWithContext(aws.Context, *ec2.CreateFleetInput, ...request.Option) (*ec2.CreateFleetOutput, error)
+}
+
+// CreateSpotFleetStep launches spot instances of several instance
+// types with instant EC2 fleet, so they are not outbid as a unit.
+type CreateSpotFleetStep struct {
+	getSvc func(steps.AWSConfig) (spotFleetCreator, error)
+}
+
+func InitCreateSpotFleet(fn GetEC2Fn) {
+	steps.RegisterStep(CreateSpotFleetStepName, NewCreateSpotFleet(fn))
+}
+
+func NewCreateSpotFleet(fn GetEC2Fn) *CreateSpotFleetStep {
+	return &CreateSpotFleetStep{
+		getSvc: func(cfg steps.AWSConfig) (spotFleetCreator, error) {
+			EC2, err := fn(cfg)
+
+			if err != nil {
+				return nil, errors.Wrap(ErrAuthorization, err.Error())
+			}
+
+			return EC2, nil
+		},
+	}
+}
+
+func (s *CreateSpotFleetStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config) error {
+	log := util.GetLogger(w)
+	spotCfg := &cfg.SpotConfig
+
+	// Fleet has been created before restart
+	if spotCfg.FleetID != "" {
+		log.Infof("[%s] - spot fleet %s already exists", s.Name(), spotCfg.FleetID)
+		return nil
+	}
+
+	allocationStrategy, err := spotAllocationStrategy(spotCfg.AllocationStrategy)
+
+	if err != nil {
+		return err
+	}
+
+	svc, err := s.getSvc(cfg.AWSConfig)
+
+	if err != nil {
+		logrus.Errorf("[%s] - error getting service %v", s.Name(), err)
+		return errors.Wrapf(err, "%s error getting service", s.Name())
+	}
+
+	templateID, err := createLaunchTemplate(ctx, svc, cfg,
+		fmt.Sprintf("%s-%s", cfg.Kube.Name, cfg.TaskID), "")
+
+	if err != nil {
+		return errors.Wrap(err, "create launch template")
+	}
+
+	// Instant fleet does not need launch template after it is created
+	defer func() {
+		_, err := svc.DeleteLaunchTemplateWithContext(ctx, &ec2.DeleteLaunchTemplateInput{
+			LaunchTemplateId: aws.String(templateID),
+		})
+
+		if err != nil {
+			logrus.Warnf("[%s] - delete launch template %s %v", s.Name(), templateID, err)
+		}
+	}()
+
+	zones := make([]string, 0, len(spotCfg.Zones))
+
+	for zone := range spotCfg.Zones {
+		zones = append(zones, zone)
+	}
+
+	sort.Strings(zones)
+	overrides := make([]*ec2.FleetLaunchTemplateOverridesRequest, 0,
+		len(zones)*len(spotCfg.InstanceTypes))
+
+	for _, zone := range zones {
+		for _, instanceType := range spotCfg.InstanceTypes {
+			override := &ec2.FleetLaunchTemplateOverridesRequest{
+				InstanceType: aws.String(instanceType.InstanceType),
+				SubnetId:     aws.String(cfg.AWSConfig.Subnets[zone]),
+			}
+
+			if instanceType.Weight > 0 {
+				override.WeightedCapacity = aws.Float64(instanceType.Weight)
+			}
+
+			if spotCfg.SpotPrice != "" {
+				override.MaxPrice = aws.String(spotCfg.SpotPrice)
+			}
+
+			overrides = append(overrides, override)
+		}
+	}
+
+	out, err := svc.CreateFleetWithContext(ctx, &ec2.CreateFleetInput{
+		Type:        aws.String(ec2.FleetTypeInstant),
+		ClientToken: aws.String(cfg.TaskID),
+		DryRun:      aws.Bool(cfg.DryRun),
+		LaunchTemplateConfigs: []*ec2.FleetLaunchTemplateConfigRequest{
+			{
+				LaunchTemplateSpecification: &ec2.FleetLaunchTemplateSpecificationRequest{
+					LaunchTemplateId: aws.String(templateID),
+					Version:          aws.String("$Latest"),
+				},
+				Overrides: overrides,
+			},
+		},
+		SpotOptions: &ec2.SpotOptionsRequest{
+			AllocationStrategy: aws.String(allocationStrategy),
+		},
+		TargetCapacitySpecification: &ec2.TargetCapacitySpecificationRequest{
+			DefaultTargetCapacityType: aws.String(ec2.DefaultTargetCapacityTypeSpot),
+			TotalTargetCapacity:       aws.Int64(spotCfg.MachineCount),
+		},
+		ValidUntil: spotCfg.ValidUntil,
+	})
+
+	if err != nil {
+		logrus.Errorf("[%s] - create spot fleet %v", s.Name(), err)
+		spotCfg.State = model.SpotRequestFailed
+		return errors.Wrap(err, "create spot fleet")
+	}
+
+	spotCfg.FleetID = aws.StringValue(out.FleetId)
+
+	for _, instance := range out.Instances {
+		spotCfg.FleetInstances = append(spotCfg.FleetInstances,
+			aws.StringValueSlice(instance.InstanceIds)...)
+	}
+
+	for _, fleetErr := range out.Errors {
+		log.Infof("[%s] - spot fleet %s error %s: %s", s.Name(), spotCfg.FleetID,
+			aws.StringValue(fleetErr.ErrorCode), aws.StringValue(fleetErr.ErrorMessage))
+	}
+
+	if len(spotCfg.FleetInstances) == 0 {
+		spotCfg.State = model.SpotRequestFailed
+		return errors.Errorf("spot fleet %s has no instances", spotCfg.FleetID)
+	}
+
+	spotCfg.State = model.SpotRequestFulfilled
+	log.Infof("[%s] - spot fleet %s has launched instances %v", s.Name(),
+		spotCfg.FleetID, spotCfg.FleetInstances)
+
+	return nil
+}
+
+// createLaunchTemplate creates template of node instances of the kube,
+// instance type is set by its users when it is empty.
+func createLaunchTemplate(ctx context.Context, svc launchTemplateCreator,
+	cfg *steps.Config, name, instanceType string) (string, error) {
+	ebs, err := ebsBlockDevice(cfg.AWSConfig, true)
+
+	if err != nil {
+		return "", errors.Wrap(err, "root volume settings")
+	}
+
+	metadataOpts, err := newMetadataOptions(cfg.AWSConfig)
+
+	if err != nil {
+		return "", errors.Wrap(err, "instance metadata options")
+	}
+
+	data := &ec2.RequestLaunchTemplateData{
+		IamInstanceProfile: &ec2.LaunchTemplateIamInstanceProfileSpecificationRequest{
+			Name: aws.String(cfg.AWSConfig.NodesInstanceProfile),
+		},
+		SecurityGroupIds: []*string{aws.String(cfg.AWSConfig.NodesSecurityGroupID)},
+		ImageId:          aws.String(cfg.AWSConfig.ImageID),
+		KeyName:          aws.String(cfg.AWSConfig.KeyPairName),
+		UserData: aws.String(base64.StdEncoding.EncodeToString(
+			[]byte(cfg.AWSConfig.UserData))),
+	}
+
+	if instanceType != "" {
+		data.InstanceType = aws.String(instanceType)
+	}
+
+	if ebs != nil {
+		data.BlockDeviceMappings = []*ec2.LaunchTemplateBlockDeviceMappingRequest{
+			{
+				DeviceName: aws.String("/dev/sda1"),
+				Ebs: &ec2.LaunchTemplateEbsBlockDeviceRequest{
+					DeleteOnTermination: ebs.DeleteOnTermination,
+					Encrypted:           ebs.Encrypted,
+					Iops:                ebs.Iops,
+					KmsKeyId:            ebs.KmsKeyId,
+					VolumeSize:          ebs.VolumeSize,
+					VolumeType:          ebs.VolumeType,
+				},
+			},
+		}
+	}
+
+	out, err := svc.CreateLaunchTemplateWithContext(ctx, &ec2.CreateLaunchTemplateInput{
+		ClientToken:        aws.String(cfg.TaskID),
+		DryRun:             aws.Bool(cfg.DryRun),
+		LaunchTemplateName: aws.String(name),
+		LaunchTemplateData: data,
+	}, withMetadataOptions(metadataOpts, "LaunchTemplateData.MetadataOptions"))
+
+	if err != nil {
+		return "", err
+	}
+
+	if out.LaunchTemplate == nil {
+		return "", errors.Wrap(sgerrors.ErrNotFound, "launch template")
+	}
+
+	return aws.StringValue(out.LaunchTemplate.LaunchTemplateId), nil
+}
+
+// spotAllocationStrategy converts allocation strategy of spot request
+// to EC2 one, lowest price is used by default.
+func spotAllocationStrategy(strategy string) (string, error) {
+	switch strategy {
+	case "", AllocationStrategyLowestPrice:
+		return ec2.SpotAllocationStrategyLowestPrice, nil
+	case AllocationStrategyCapacityOptimized:
+		return spotAllocationStrategyCapacityOptimized, nil
+	default:
+		return "", errors.Wrapf(sgerrors.ErrValidationFailed,
+			"unknown allocation strategy %s", strategy)
+	}
+}
+
+// RetryPolicy opts out of retries, spot fleet would be requested twice.
+func (*CreateSpotFleetStep) RetryPolicy() steps.RetryPolicy {
+	return steps.NoRetry
+}
+
+func (*CreateSpotFleetStep) Name() string {
+	return CreateSpotFleetStepName
+}
+
+func (*CreateSpotFleetStep) Depends() []string {
+	return nil
+}
+
+func (*CreateSpotFleetStep) Description() string {
+	return "Create spot fleet of several instance types"
+}
+
+func (*CreateSpotFleetStep) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
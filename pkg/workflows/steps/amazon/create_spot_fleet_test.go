@@ -0,0 +1,285 @@
+package amazon
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type mockSpotFleetCreator struct {
+	mock.Mock
+}
+
+func (m *mockSpotFleetCreator) CreateLaunchTemplateWithContext(ctx aws.Context,
+	req *ec2.CreateLaunchTemplateInput, opts ...request.Option) (*ec2.CreateLaunchTemplateOutput, error) {
+	args := m.Called(ctx, req, opts)
+	val, ok := args.Get(0).(*ec2.CreateLaunchTemplateOutput)
+	if !ok {
+		return nil, args.Error(1)
+	}
+	return val, args.Error(1)
+}
+
+func (m *mockSpotFleetCreator) DeleteLaunchTemplateWithContext(ctx aws.Context,
+	req *ec2.DeleteLaunchTemplateInput, opts ...request.Option) (*ec2.DeleteLaunchTemplateOutput, error) {
+	args := m.Called(ctx, req, opts)
+	val, ok := args.Get(0).(*ec2.DeleteLaunchTemplateOutput)
+	if !ok {
+		return nil, args.Error(1)
+	}
+	return val, args.Error(1)
+}
+
+func (m *mockSpotFleetCreator) CreateFleetWithContext(ctx aws.Context,
+	req *ec2.CreateFleetInput, opts ...request.Option) (*ec2.CreateFleetOutput, error) {
+	args := m.Called(ctx, req, opts)
+	val, ok := args.Get(0).(*ec2.CreateFleetOutput)
+	if !ok {
+		return nil, args.Error(1)
+	}
+	return val, args.Error(1)
+}
+
+func TestCreateSpotFleetStep_Run(t *testing.T) {
+	template := &ec2.CreateLaunchTemplateOutput{
+		LaunchTemplate: &ec2.LaunchTemplate{
+			LaunchTemplateId: aws.String("lt-1"),
+		},
+	}
+
+	testCases := []struct {
+		description string
+
+		fleetID            string
+		allocationStrategy string
+		getSvcErr          error
+		templateOutput     *ec2.CreateLaunchTemplateOutput
+		templateErr        error
+		fleetOutput        *ec2.CreateFleetOutput
+		fleetErr           error
+
+		fleetCalls        int
+		expectedInstances []string
+		expectedState     model.SpotRequestState
+		errMsg            string
+	}{
+		{
+			description: "already created",
+			fleetID:     "fleet-1",
+		},
+		{
+			description:        "unknown allocation strategy",
+			allocationStrategy: "diversified",
+			errMsg:             "allocation strategy",
+		},
+		{
+			description: "get service error",
+			getSvcErr:   errors.New("message1"),
+			errMsg:      "message1",
+		},
+		{
+			description: "launch template error",
+			templateErr: errors.New("message2"),
+			errMsg:      "message2",
+		},
+		{
+			description:    "fleet error",
+			templateOutput: template,
+			fleetErr:       errors.New("message3"),
+			fleetCalls:     1,
+			expectedState:  model.SpotRequestFailed,
+			errMsg:         "message3",
+		},
+		{
+			description:    "no instances",
+			templateOutput: template,
+			fleetOutput: &ec2.CreateFleetOutput{
+				FleetId: aws.String("fleet-1"),
+				Errors: []*ec2.CreateFleetError{
+					{
+						ErrorCode: aws.String("InsufficientInstanceCapacity"),
+					},
+				},
+			},
+			fleetCalls:    1,
+			expectedState: model.SpotRequestFailed,
+			errMsg:        "no instances",
+		},
+		{
+			description:        "success",
+			allocationStrategy: AllocationStrategyCapacityOptimized,
+			templateOutput:     template,
+			fleetOutput: &ec2.CreateFleetOutput{
+				FleetId: aws.String("fleet-1"),
+				Instances: []*ec2.CreateFleetInstance{
+					{
+						InstanceIds: aws.StringSlice([]string{"i-1", "i-2"}),
+					},
+					{
+						InstanceIds: aws.StringSlice([]string{"i-3"}),
+					},
+				},
+			},
+			fleetCalls:        1,
+			expectedInstances: []string{"i-1", "i-2", "i-3"},
+			expectedState:     model.SpotRequestFulfilled,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		svc := &mockSpotFleetCreator{}
+		svc.On("CreateLaunchTemplateWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(testCase.templateOutput,
+			testCase.templateErr)
+		svc.On("DeleteLaunchTemplateWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(nil, nil)
+		svc.On("CreateFleetWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(testCase.fleetOutput,
+			testCase.fleetErr)
+
+		config := &steps.Config{
+			TaskID: "task-id",
+			AWSConfig: steps.AWSConfig{
+				Subnets: map[string]string{
+					"us-east-1a": "subnet-a",
+					"us-east-1b": "subnet-b",
+				},
+			},
+			SpotConfig: steps.SpotConfig{
+				MachineCount: 3,
+				Zones: map[string]int64{
+					"us-east-1a": 2,
+					"us-east-1b": 1,
+				},
+				InstanceTypes: []steps.SpotInstanceType{
+					{InstanceType: "m4.large", Weight: 1},
+					{InstanceType: "m5.large"},
+				},
+				AllocationStrategy: testCase.allocationStrategy,
+				FleetID:            testCase.fleetID,
+			},
+		}
+		step := CreateSpotFleetStep{
+			getSvc: func(steps.AWSConfig) (spotFleetCreator, error) {
+				return svc, testCase.getSvcErr
+			},
+		}
+
+		err := step.Run(context.Background(), &bytes.Buffer{}, config)
+
+		if err == nil && testCase.errMsg != "" {
+			t.Errorf("Error must not be nil")
+		}
+
+		if err != nil && !strings.Contains(err.Error(), testCase.errMsg) {
+			t.Errorf("Error message %s does not contain %s",
+				err.Error(), testCase.errMsg)
+		}
+
+		svc.AssertNumberOfCalls(t, "CreateFleetWithContext", testCase.fleetCalls)
+
+		if testCase.fleetCalls > 0 {
+			svc.AssertNumberOfCalls(t, "DeleteLaunchTemplateWithContext", 1)
+			input := svc.Calls[1].Arguments.Get(1).(*ec2.CreateFleetInput)
+
+			// Instance type overrides in each zone
+			if overrides := input.LaunchTemplateConfigs[0].Overrides; len(overrides) != 4 {
+				t.Errorf("Wrong count of overrides expected 4 actual %d", len(overrides))
+			}
+		}
+
+		if len(config.SpotConfig.FleetInstances) != len(testCase.expectedInstances) {
+			t.Errorf("Wrong fleet instances expected %v actual %v",
+				testCase.expectedInstances, config.SpotConfig.FleetInstances)
+		}
+
+		if config.SpotConfig.State != testCase.expectedState {
+			t.Errorf("Wrong state expected %s actual %s",
+				testCase.expectedState, config.SpotConfig.State)
+		}
+	}
+}
+
+func TestSpotAllocationStrategy(t *testing.T) {
+	testCases := []struct {
+		strategy string
+		expected string
+		isErr    bool
+	}{
+		{
+			expected: ec2.SpotAllocationStrategyLowestPrice,
+		},
+		{
+			strategy: AllocationStrategyLowestPrice,
+			expected: ec2.SpotAllocationStrategyLowestPrice,
+		},
+		{
+			strategy: AllocationStrategyCapacityOptimized,
+			expected: spotAllocationStrategyCapacityOptimized,
+		},
+		{
+			strategy: "diversified",
+			isErr:    true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := spotAllocationStrategy(testCase.strategy)
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("Unexpected error %v for %s", err, testCase.strategy)
+		}
+
+		if actual != testCase.expected {
+			t.Errorf("Wrong strategy expected %s actual %s",
+				testCase.expected, actual)
+		}
+	}
+}
+
+func TestNewCreateSpotFleetErr(t *testing.T) {
+	fn := func(steps.AWSConfig) (ec2iface.EC2API, error) {
+		return nil, errors.New("errorMessage")
+	}
+
+	s := NewCreateSpotFleet(fn)
+
+	if s == nil {
+		t.Error("Step must not be nil")
+	}
+
+	if api, err := s.getSvc(steps.AWSConfig{}); err == nil || api != nil {
+		t.Errorf("Unexpected values %v %v", api, err)
+	}
+}
+
+func TestInitCreateSpotFleet(t *testing.T) {
+	InitCreateSpotFleet(GetEC2)
+
+	s := steps.GetStep(CreateSpotFleetStepName)
+
+	if s == nil {
+		t.Errorf("Step must not be nil")
+	}
+}
+
+func TestCreateSpotFleetStep_Name(t *testing.T) {
+	s := &CreateSpotFleetStep{}
+
+	if name := s.Name(); name != CreateSpotFleetStepName {
+		t.Errorf("Wrong name expected %s actual %s",
+			CreateSpotFleetStepName, name)
+	}
+}
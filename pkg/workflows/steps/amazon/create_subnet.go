@@ -5,6 +5,8 @@ import (
 	"io"
 	"math/rand"
 	"net"
+	"sort"
+	"strings"
 
 	"github.com/apparentlymart/go-cidr/cidr"
 	"github.com/aws/aws-sdk-go/aws"
@@ -15,6 +17,7 @@ import (
 
 	"github.com/supergiant/control/pkg/account"
 	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
 	"github.com/supergiant/control/pkg/workflows/steps"
 )
 
@@ -29,6 +32,12 @@ type subnetSvc interface {
 		...request.Option) (*ec2.CreateSubnetOutput, error)
 	ModifySubnetAttributeWithContext(aws.Context, *ec2.ModifySubnetAttributeInput,
 		...request.Option) (*ec2.ModifySubnetAttributeOutput, error)
+	DescribeSubnetsWithContext(aws.Context, *ec2.DescribeSubnetsInput,
+		...request.Option) (*ec2.DescribeSubnetsOutput, error)
+	CreateTagsWithContext(aws.Context, *ec2.CreateTagsInput,
+		...request.Option) (*ec2.CreateTagsOutput, error)
+	DescribeRouteTablesWithContext(aws.Context, *ec2.DescribeRouteTablesInput,
+		...request.Option) (*ec2.DescribeRouteTablesOutput, error)
 }
 
 type CreateSubnetsStep struct {
@@ -80,6 +89,10 @@ func (s *CreateSubnetsStep) Run(ctx context.Context, w io.Writer, cfg *steps.Con
 			StepCreateSubnets)
 	}
 
+	if hasUserSubnets(cfg) {
+		return s.validateSubnets(ctx, svc, cfg)
+	}
+
 	zoneGetter, err := s.zoneGetterFactory(ctx, s.accountGetter, cfg)
 
 	if err != nil {
@@ -105,8 +118,22 @@ func (s *CreateSubnetsStep) Run(ctx context.Context, w io.Writer, cfg *steps.Con
 			cfg.AWSConfig.Region)
 	}
 
+	// Subnets created by the previous run of the step are reused
+	existing, err := s.findSubnets(ctx, svc, cfg)
+
+	if err != nil {
+		logrus.Errorf("Find subnets of VPC %s caused %v", cfg.AWSConfig.VPCID, err)
+		return errors.Wrap(ErrCreateSubnet, err.Error())
+	}
+
 	// Create subnet for each availability zone
 	for _, zone := range zones {
+		if subnetID, ok := existing[zone]; ok {
+			logrus.Debugf("Reuse subnet %s in zone %s", subnetID, zone)
+			cfg.AWSConfig.Subnets[zone] = subnetID
+			continue
+		}
+
 		_, cidrIP, err := net.ParseCIDR(cfg.AWSConfig.VPCCIDR)
 
 		if err != nil {
@@ -137,6 +164,13 @@ func (s *CreateSubnetsStep) Run(ctx context.Context, w io.Writer, cfg *steps.Con
 			return errors.Wrap(ErrCreateSubnet, err.Error())
 		}
 
+		err = tagResource(ctx, svc, cfg, aws.StringValue(out.Subnet.SubnetId), resourceRoleSubnet)
+
+		if err != nil {
+			logrus.Debugf("Tag subnet cause error %s", err.Error())
+			return errors.Wrap(ErrCreateSubnet, err.Error())
+		}
+
 		modifyReq := &ec2.ModifySubnetAttributeInput{
 			MapPublicIpOnLaunch: &ec2.AttributeBooleanValue{
 				Value: aws.Bool(true),
@@ -158,6 +192,148 @@ func (s *CreateSubnetsStep) Run(ctx context.Context, w io.Writer, cfg *steps.Con
 	return nil
 }
 
+// findSubnets returns subnets of the cluster in the VPC by zone.
+func (s *CreateSubnetsStep) findSubnets(ctx context.Context, svc subnetSvc, cfg *steps.Config) (map[string]string, error) {
+	filters := append(clusterFilters(cfg.Kube.ID, resourceRoleSubnet), &ec2.Filter{
+		Name:   aws.String("vpc-id"),
+		Values: aws.StringSlice([]string{cfg.AWSConfig.VPCID}),
+	})
+
+	out, err := svc.DescribeSubnetsWithContext(ctx, &ec2.DescribeSubnetsInput{
+		Filters: filters,
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "describe subnets")
+	}
+
+	subnets := make(map[string]string)
+
+	if out == nil {
+		return subnets, nil
+	}
+
+	for _, subnet := range out.Subnets {
+		subnets[aws.StringValue(subnet.AvailabilityZone)] = aws.StringValue(subnet.SubnetId)
+	}
+
+	return subnets, nil
+}
+
+// validateSubnets checks that subnets of user are in the VPC and zones
+// they are given for and route traffic to the internet gateway, or to
+// NAT gateway when machines have no public IPs.
+func (s *CreateSubnetsStep) validateSubnets(ctx context.Context, svc subnetSvc, cfg *steps.Config) error {
+	zones := make(map[string]string, len(cfg.AWSConfig.Subnets))
+	subnetIDs := make([]string, 0, len(cfg.AWSConfig.Subnets))
+
+	for az, subnetID := range cfg.AWSConfig.Subnets {
+		zones[subnetID] = az
+		subnetIDs = append(subnetIDs, subnetID)
+	}
+
+	sort.Strings(subnetIDs)
+
+	out, err := svc.DescribeSubnetsWithContext(ctx, &ec2.DescribeSubnetsInput{
+		SubnetIds: aws.StringSlice(subnetIDs),
+	})
+
+	if err != nil {
+		return errors.Wrap(ErrCreateSubnet, err.Error())
+	}
+
+	found := make(map[string]*ec2.Subnet)
+
+	for _, subnet := range out.Subnets {
+		found[aws.StringValue(subnet.SubnetId)] = subnet
+	}
+
+	for _, subnetID := range subnetIDs {
+		subnet := found[subnetID]
+
+		if subnet == nil {
+			return errors.Wrapf(sgerrors.ErrNotFound, "subnet %s", subnetID)
+		}
+
+		if vpcID := aws.StringValue(subnet.VpcId); vpcID != cfg.AWSConfig.VPCID {
+			return errors.Wrapf(sgerrors.ErrValidationFailed,
+				"subnet %s is in vpc %s not in %s", subnetID, vpcID,
+				cfg.AWSConfig.VPCID)
+		}
+
+		if az := aws.StringValue(subnet.AvailabilityZone); az != zones[subnetID] {
+			return errors.Wrapf(sgerrors.ErrValidationFailed,
+				"subnet %s is in zone %s not in %s", subnetID, az,
+				zones[subnetID])
+		}
+	}
+
+	tables, err := svc.DescribeRouteTablesWithContext(ctx, &ec2.DescribeRouteTablesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: aws.StringSlice([]string{cfg.AWSConfig.VPCID}),
+			},
+		},
+	})
+
+	if err != nil {
+		return errors.Wrap(ErrCreateSubnet, err.Error())
+	}
+
+	for _, subnetID := range subnetIDs {
+		table := subnetRouteTable(tables.RouteTables, subnetID)
+
+		if table == nil || !hasDefaultRoute(table, cfg.Kube.PrivateNetworking) {
+			return errors.Wrapf(sgerrors.ErrValidationFailed,
+				"subnet %s has no route to the internet", subnetID)
+		}
+	}
+
+	return nil
+}
+
+// subnetRouteTable returns route table associated with the subnet or
+// the main route table of VPC that routes subnets without association.
+func subnetRouteTable(tables []*ec2.RouteTable, subnetID string) *ec2.RouteTable {
+	var main *ec2.RouteTable
+
+	for _, table := range tables {
+		for _, association := range table.Associations {
+			if aws.StringValue(association.SubnetId) == subnetID {
+				return table
+			}
+
+			if aws.BoolValue(association.Main) {
+				main = table
+			}
+		}
+	}
+
+	return main
+}
+
+// hasDefaultRoute returns true when the table routes all traffic to the
+// internet gateway, NAT gateway is allowed for private machines.
+func hasDefaultRoute(table *ec2.RouteTable, private bool) bool {
+	for _, route := range table.Routes {
+		if aws.StringValue(route.DestinationCidrBlock) != "0.0.0.0/0" ||
+			aws.StringValue(route.State) == ec2.RouteStateBlackhole {
+			continue
+		}
+
+		if strings.HasPrefix(aws.StringValue(route.GatewayId), "igw-") {
+			return true
+		}
+
+		if private && route.NatGatewayId != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (*CreateSubnetsStep) Name() string {
 	return StepCreateSubnets
 }
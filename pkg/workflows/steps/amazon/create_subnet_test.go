@@ -46,6 +46,39 @@ func (m *mockSubnetSvc) ModifySubnetAttributeWithContext(ctx aws.Context, req *e
 	return val, args.Error(1)
 }
 
+func (m *mockSubnetSvc) DescribeSubnetsWithContext(ctx aws.Context, req *ec2.DescribeSubnetsInput,
+	opts ...request.Option) (*ec2.DescribeSubnetsOutput, error) {
+	args := m.Called(ctx, req, opts)
+	val, ok := args.Get(0).(*ec2.DescribeSubnetsOutput)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return val, args.Error(1)
+}
+
+func (m *mockSubnetSvc) CreateTagsWithContext(ctx aws.Context, req *ec2.CreateTagsInput,
+	opts ...request.Option) (*ec2.CreateTagsOutput, error) {
+	args := m.Called(ctx, req, opts)
+	val, ok := args.Get(0).(*ec2.CreateTagsOutput)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return val, args.Error(1)
+}
+
+func (m *mockSubnetSvc) DescribeRouteTablesWithContext(ctx aws.Context, req *ec2.DescribeRouteTablesInput,
+	opts ...request.Option) (*ec2.DescribeRouteTablesOutput, error) {
+	args := m.Called(ctx, req, opts)
+	val, ok := args.Get(0).(*ec2.DescribeRouteTablesOutput)
+	if !ok {
+		return nil, args.Error(1)
+	}
+
+	return val, args.Error(1)
+}
+
 type mockAccountGetter struct {
 	mock.Mock
 }
@@ -141,6 +174,10 @@ func TestCreateSubnetStep_Run(t *testing.T) {
 			Return(testCase.createSubnet, testCase.createSubnetErr)
 		svc.On("ModifySubnetAttributeWithContext", mock.Anything, mock.Anything,
 			mock.Anything).Return(nil, nil)
+		svc.On("DescribeSubnetsWithContext", mock.Anything, mock.Anything,
+			mock.Anything).Return(&ec2.DescribeSubnetsOutput{}, nil)
+		svc.On("CreateTagsWithContext", mock.Anything, mock.Anything,
+			mock.Anything).Return(&ec2.CreateTagsOutput{}, nil)
 
 		zoneGetter := &mockZoneGetter{
 			zones: testCase.getZoneResp,
@@ -323,3 +360,144 @@ func TestCreateSubnetsStep_Rollback(t *testing.T) {
 		t.Errorf("Unexpected error %v when rollback", err)
 	}
 }
+
+func TestCreateSubnetStep_RunUserSubnets(t *testing.T) {
+	subnet := &ec2.Subnet{
+		SubnetId:         aws.String("subnet-1"),
+		VpcId:            aws.String("vpc-1"),
+		AvailabilityZone: aws.String("us-west-1a"),
+	}
+
+	mainTable := func(gatewayID, natID string) *ec2.RouteTable {
+		route := &ec2.Route{
+			DestinationCidrBlock: aws.String("0.0.0.0/0"),
+			State:                aws.String(ec2.RouteStateActive),
+		}
+
+		if gatewayID != "" {
+			route.GatewayId = aws.String(gatewayID)
+		}
+
+		if natID != "" {
+			route.NatGatewayId = aws.String(natID)
+		}
+
+		return &ec2.RouteTable{
+			Associations: []*ec2.RouteTableAssociation{
+				{Main: aws.Bool(true)},
+			},
+			Routes: []*ec2.Route{route},
+		}
+	}
+
+	testCases := []struct {
+		description string
+
+		private bool
+		subnets []*ec2.Subnet
+		tables  []*ec2.RouteTable
+
+		isErr bool
+	}{
+		{
+			description: "subnet not found",
+			isErr:       true,
+		},
+		{
+			description: "subnet of other vpc",
+			subnets: []*ec2.Subnet{
+				{
+					SubnetId:         aws.String("subnet-1"),
+					VpcId:            aws.String("vpc-2"),
+					AvailabilityZone: aws.String("us-west-1a"),
+				},
+			},
+			isErr: true,
+		},
+		{
+			description: "subnet of other zone",
+			subnets: []*ec2.Subnet{
+				{
+					SubnetId:         aws.String("subnet-1"),
+					VpcId:            aws.String("vpc-1"),
+					AvailabilityZone: aws.String("us-west-1b"),
+				},
+			},
+			isErr: true,
+		},
+		{
+			description: "no route to the internet",
+			subnets:     []*ec2.Subnet{subnet},
+			tables:      []*ec2.RouteTable{mainTable("local", "")},
+			isErr:       true,
+		},
+		{
+			description: "nat gateway of public machines",
+			subnets:     []*ec2.Subnet{subnet},
+			tables:      []*ec2.RouteTable{mainTable("", "nat-1")},
+			isErr:       true,
+		},
+		{
+			description: "nat gateway of private machines",
+			private:     true,
+			subnets:     []*ec2.Subnet{subnet},
+			tables:      []*ec2.RouteTable{mainTable("", "nat-1")},
+		},
+		{
+			description: "internet gateway",
+			subnets:     []*ec2.Subnet{subnet},
+			tables: []*ec2.RouteTable{
+				mainTable("", "nat-1"),
+				{
+					Associations: []*ec2.RouteTableAssociation{
+						{SubnetId: aws.String("subnet-1")},
+					},
+					Routes: mainTable("igw-1", "").Routes,
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		svc := &mockSubnetSvc{}
+		svc.On("DescribeSubnetsWithContext", mock.Anything, mock.Anything,
+			mock.Anything).Return(&ec2.DescribeSubnetsOutput{
+			Subnets: testCase.subnets,
+		}, nil)
+		svc.On("DescribeRouteTablesWithContext", mock.Anything, mock.Anything,
+			mock.Anything).Return(&ec2.DescribeRouteTablesOutput{
+			RouteTables: testCase.tables,
+		}, nil)
+
+		step := &CreateSubnetsStep{
+			getSvc: func(steps.AWSConfig) (subnetSvc, error) {
+				return svc, nil
+			},
+		}
+
+		config, err := steps.NewConfig("clusterName", "", profile.Profile{
+			Provider:          clouds.AWS,
+			PrivateNetworking: testCase.private,
+			Subnets: map[string]string{
+				"us-west-1a": "subnet-1",
+			},
+			CloudSpecificSettings: profile.CloudSpecificSettings{
+				clouds.AwsVpcID: "vpc-1",
+			},
+		})
+
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+		}
+
+		err = step.Run(context.Background(), &bytes.Buffer{}, config)
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("Wrong error %v", err)
+		}
+
+		svc.AssertNotCalled(t, "CreateSubnetWithContext", mock.Anything,
+			mock.Anything, mock.Anything)
+	}
+}
@@ -71,16 +71,16 @@ func (s *CreateTagsStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config
 
 	input := &ec2.CreateTagsInput{
 		Resources: resourceIds,
-		Tags: []*ec2.Tag{
-			{
+		Tags: ec2Tags(cfg.Tags,
+			&ec2.Tag{
 				Key:   aws.String("KubernetesCluster"),
 				Value: aws.String(cfg.Kube.Name),
 			},
-			{
+			&ec2.Tag{
 				Key:   aws.String(clouds.TagClusterID),
 				Value: aws.String(cfg.Kube.ID),
 			},
-		},
+		),
 	}
 
 	_, err = svc.CreateTags(input)
@@ -6,9 +6,12 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
 	"github.com/supergiant/control/pkg/util"
 	"github.com/supergiant/control/pkg/workflows/steps"
 )
@@ -41,6 +44,26 @@ func (c *CreateVPCStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config)
 	//A user doesn't specified that she wants to use preexisting VPC
 	//creating a new one for a cluster
 	if cfg.AWSConfig.VPCID == "" {
+		// VPC may have been created by the previous run of the step
+		vpc, err := findClusterVPC(ctx, EC2, cfg.Kube.ID)
+		if err != nil {
+			return errors.Wrap(ErrCreateVPC, err.Error())
+		}
+
+		if vpc != nil {
+			cfg.AWSConfig.VPCID = aws.StringValue(vpc.VpcId)
+			cfg.AWSConfig.VPCCIDR = aws.StringValue(vpc.CidrBlock)
+			cfg.AWSConfig.CreatedVPCID = cfg.AWSConfig.VPCID
+			log.Infof("[%s] - reuse VPC %s of the cluster", c.Name(), cfg.AWSConfig.VPCID)
+
+			// Previous run may have failed before DNS hostnames were enabled
+			if err := enableDNSHostnames(ctx, EC2, cfg.AWSConfig.VPCID); err != nil {
+				return errors.Wrap(ErrCreateVPC, err.Error())
+			}
+
+			return nil
+		}
+
 		log.Infof("[%s] - no VPC id specified, creating now...", c.Name())
 
 		input := &ec2.CreateVpcInput{
@@ -51,15 +74,13 @@ func (c *CreateVPCStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config)
 			return errors.Wrap(ErrCreateVPC, err.Error())
 		}
 		cfg.AWSConfig.VPCID = *out.Vpc.VpcId
+		cfg.AWSConfig.CreatedVPCID = cfg.AWSConfig.VPCID
 
-		vpcattr := &ec2.ModifyVpcAttributeInput{
-			EnableDnsHostnames: &ec2.AttributeBooleanValue{
-				Value: aws.Bool(true),
-			},
-			VpcId: &cfg.AWSConfig.VPCID,
+		if err := tagResource(ctx, EC2, cfg, cfg.AWSConfig.VPCID, resourceRoleVPC); err != nil {
+			return errors.Wrap(ErrCreateVPC, err.Error())
 		}
-		_, err = EC2.ModifyVpcAttributeWithContext(ctx, vpcattr)
-		if err != nil {
+
+		if err := enableDNSHostnames(ctx, EC2, cfg.AWSConfig.VPCID); err != nil {
 			return errors.Wrap(ErrCreateVPC, err.Error())
 		}
 
@@ -75,7 +96,7 @@ func (c *CreateVPCStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config)
 		}
 		log.Infof("[%s] - created a VPC with ID %s and CIDR %s",
 			c.Name(), cfg.AWSConfig.VPCID, cfg.AWSConfig.VPCCIDR)
-	} else {
+	} else if cfg.AWSConfig.VPCID == steps.DefaultVPCID {
 		out, err := EC2.DescribeVpcsWithContext(ctx, &ec2.DescribeVpcsInput{
 			Filters: []*ec2.Filter{
 				{
@@ -103,11 +124,82 @@ func (c *CreateVPCStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config)
 
 		cfg.AWSConfig.VPCID = defaultVPCID
 		cfg.AWSConfig.VPCCIDR = defaultVPCCIDR
+		// Default VPC is never deleted with the cluster
+		cfg.Kube.SetOwner(defaultVPCID, model.OwnerUser)
+	} else {
+		vpc, err := validateVPC(ctx, EC2, cfg.AWSConfig.VPCID)
+		if err != nil {
+			log.Errorf("[%s] - VPC %s can't be used: %v", c.Name(), cfg.AWSConfig.VPCID, err)
+			return err
+		}
+
+		cfg.AWSConfig.VPCCIDR = aws.StringValue(vpc.CidrBlock)
+		log.Infof("[%s] - use VPC %s with CIDR %s", c.Name(),
+			cfg.AWSConfig.VPCID, cfg.AWSConfig.VPCCIDR)
 	}
 
 	return nil
 }
 
+// validateVPC returns existing VPC, DNS hostnames must be enabled in
+// it for instances to be registered as nodes by their private DNS names.
+func validateVPC(ctx context.Context, EC2 ec2iface.EC2API, vpcID string) (*ec2.Vpc, error) {
+	out, err := EC2.DescribeVpcsWithContext(ctx, &ec2.DescribeVpcsInput{
+		VpcIds: aws.StringSlice([]string{vpcID}),
+	})
+	if err != nil {
+		return nil, errors.Wrap(ErrReadVPC, err.Error())
+	}
+
+	if out == nil || len(out.Vpcs) == 0 {
+		return nil, errors.Wrapf(sgerrors.ErrNotFound, "vpc %s", vpcID)
+	}
+
+	attr, err := EC2.DescribeVpcAttributeWithContext(ctx, &ec2.DescribeVpcAttributeInput{
+		Attribute: aws.String(ec2.VpcAttributeNameEnableDnsHostnames),
+		VpcId:     aws.String(vpcID),
+	})
+	if err != nil {
+		return nil, errors.Wrap(ErrReadVPC, err.Error())
+	}
+
+	if attr.EnableDnsHostnames == nil || !aws.BoolValue(attr.EnableDnsHostnames.Value) {
+		return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"vpc %s must have DNS hostnames enabled", vpcID)
+	}
+
+	return out.Vpcs[0], nil
+}
+
+// enableDNSHostnames makes instances of the vpc get DNS hostnames.
+func enableDNSHostnames(ctx context.Context, EC2 ec2iface.EC2API, vpcID string) error {
+	_, err := EC2.ModifyVpcAttributeWithContext(ctx, &ec2.ModifyVpcAttributeInput{
+		EnableDnsHostnames: &ec2.AttributeBooleanValue{
+			Value: aws.Bool(true),
+		},
+		VpcId: aws.String(vpcID),
+	})
+
+	return errors.Wrapf(err, "enable dns hostnames of vpc %s", vpcID)
+}
+
+// findClusterVPC returns VPC created for the cluster or nil.
+func findClusterVPC(ctx context.Context, EC2 ec2iface.EC2API, clusterID string) (*ec2.Vpc, error) {
+	out, err := EC2.DescribeVpcsWithContext(ctx, &ec2.DescribeVpcsInput{
+		Filters: clusterFilters(clusterID, resourceRoleVPC),
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "describe vpcs")
+	}
+
+	if out == nil || len(out.Vpcs) == 0 {
+		return nil, nil
+	}
+
+	return out.Vpcs[0], nil
+}
+
 func (*CreateVPCStep) Name() string {
 	return StepCreateVPC
 }
@@ -120,6 +212,18 @@ func (*CreateVPCStep) Depends() []string {
 	return nil
 }
 
-func (*CreateVPCStep) Rollback(context.Context, io.Writer, *steps.Config) error {
+// Rollback deletes VPC created by the step, existing VPC is kept.
+func (c *CreateVPCStep) Rollback(ctx context.Context, w io.Writer, cfg *steps.Config) error {
+	if cfg.AWSConfig.CreatedVPCID == "" || cfg.AWSConfig.CreatedVPCID != cfg.AWSConfig.VPCID {
+		return nil
+	}
+
+	if err := NewDeleteVPC(c.GetEC2).Run(ctx, w, cfg); err != nil {
+		return errors.Wrapf(err, "delete vpc %s", cfg.AWSConfig.VPCID)
+	}
+
+	cfg.AWSConfig.VPCID = ""
+	cfg.AWSConfig.CreatedVPCID = ""
+
 	return nil
 }
@@ -15,6 +15,7 @@ import (
 
 	"github.com/supergiant/control/pkg/clouds"
 	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/sgerrors"
 	"github.com/supergiant/control/pkg/workflows/steps"
 )
 
@@ -23,6 +24,8 @@ type fakeEC2VPC struct {
 	createVPCOutput   *ec2.CreateVpcOutput
 	describeVPCOutput *ec2.DescribeVpcsOutput
 	modifyVPCOut      *ec2.ModifyVpcAttributeOutput
+	vpcAttrOutput     *ec2.DescribeVpcAttributeOutput
+	modifiedVPCIDs    []string
 	err               error
 }
 
@@ -34,10 +37,19 @@ func (f *fakeEC2VPC) DescribeVpcsWithContext(aws.Context, *ec2.DescribeVpcsInput
 	return f.describeVPCOutput, f.err
 }
 
-func (f *fakeEC2VPC) ModifyVpcAttributeWithContext(aws.Context, *ec2.ModifyVpcAttributeInput, ...request.Option) (*ec2.ModifyVpcAttributeOutput, error) {
+func (f *fakeEC2VPC) ModifyVpcAttributeWithContext(ctx aws.Context, input *ec2.ModifyVpcAttributeInput, opts ...request.Option) (*ec2.ModifyVpcAttributeOutput, error) {
+	f.modifiedVPCIDs = append(f.modifiedVPCIDs, aws.StringValue(input.VpcId))
 	return f.modifyVPCOut, f.err
 }
 
+func (f *fakeEC2VPC) DescribeVpcAttributeWithContext(aws.Context, *ec2.DescribeVpcAttributeInput, ...request.Option) (*ec2.DescribeVpcAttributeOutput, error) {
+	return f.vpcAttrOutput, f.err
+}
+
+func (f *fakeEC2VPC) CreateTagsWithContext(aws.Context, *ec2.CreateTagsInput, ...request.Option) (*ec2.CreateTagsOutput, error) {
+	return &ec2.CreateTagsOutput{}, f.err
+}
+
 func (f *fakeEC2VPC) WaitUntilVpcExistsWithContext(aws.Context,
 	*ec2.DescribeVpcsInput, ...request.WaiterOption) error {
 	return nil
@@ -123,11 +135,15 @@ func TestCreateVPCStep_Run(t *testing.T) {
 						Vpcs: []*ec2.Vpc{
 							{
 								VpcId:     aws.String("1"),
-								IsDefault: aws.Bool(true),
 								CidrBlock: aws.String("10.20.30.40/16"),
 							},
 						},
 					},
+					vpcAttrOutput: &ec2.DescribeVpcAttributeOutput{
+						EnableDnsHostnames: &ec2.AttributeBooleanValue{
+							Value: aws.Bool(true),
+						},
+					},
 				}, nil
 			},
 			nil,
@@ -135,6 +151,42 @@ func TestCreateVPCStep_Run(t *testing.T) {
 				VPCID: "1",
 			},
 		},
+		{
+			//error, vpc of user has DNS hostnames disabled
+			func(config steps.AWSConfig) (ec2iface.EC2API, error) {
+				return &fakeEC2VPC{
+					describeVPCOutput: &ec2.DescribeVpcsOutput{
+						Vpcs: []*ec2.Vpc{
+							{
+								VpcId:     aws.String("1"),
+								CidrBlock: aws.String("10.20.30.40/16"),
+							},
+						},
+					},
+					vpcAttrOutput: &ec2.DescribeVpcAttributeOutput{
+						EnableDnsHostnames: &ec2.AttributeBooleanValue{
+							Value: aws.Bool(false),
+						},
+					},
+				}, nil
+			},
+			sgerrors.ErrValidationFailed,
+			steps.AWSConfig{
+				VPCID: "1",
+			},
+		},
+		{
+			//error, vpc of user doesn't exist
+			func(config steps.AWSConfig) (ec2iface.EC2API, error) {
+				return &fakeEC2VPC{
+					describeVPCOutput: &ec2.DescribeVpcsOutput{},
+				}, nil
+			},
+			sgerrors.ErrNotFound,
+			steps.AWSConfig{
+				VPCID: "1",
+			},
+		},
 		{
 			func(config steps.AWSConfig) (ec2iface.EC2API, error) {
 				return &fakeEC2VPC{
@@ -179,6 +231,34 @@ func TestCreateVPCStep_Run(t *testing.T) {
 	}
 }
 
+func TestCreateVPCStep_RunReuse(t *testing.T) {
+	fake := &fakeEC2VPC{
+		describeVPCOutput: &ec2.DescribeVpcsOutput{
+			Vpcs: []*ec2.Vpc{
+				{
+					VpcId:     aws.String("created"),
+					CidrBlock: aws.String("10.0.0.0/16"),
+				},
+			},
+		},
+	}
+
+	cfg, err := steps.NewConfig("TEST", "TEST", profile.Profile{
+		Region:   "us-east-1",
+		Provider: clouds.AWS,
+	})
+	require.NoError(t, err)
+
+	step := NewCreateVPCStep(func(steps.AWSConfig) (ec2iface.EC2API, error) {
+		return fake, nil
+	})
+
+	require.NoError(t, step.Run(context.Background(), os.Stdout, cfg))
+	require.Equal(t, "created", cfg.AWSConfig.CreatedVPCID)
+	// DNS hostnames of the reused VPC are enabled as well
+	require.Equal(t, []string{"created"}, fake.modifiedVPCIDs)
+}
+
 func TestInitCreateVPC(t *testing.T) {
 	InitCreateVPC(GetEC2)
 
@@ -0,0 +1,164 @@
+package amazon
+
+import (
+	"context"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const (
+	DeleteAutoScalingGroupsStepName = "aws_delete_auto_scaling_groups"
+
+	autoScalingGroupPollInterval = 10 * time.Second
+)
+
+type launchTemplateDeleter interface {
+	DeleteLaunchTemplateWithContext(aws.Context, *ec2.DeleteLaunchTemplateInput, ...request.Option) (*ec2.DeleteLaunchTemplateOutput, error)
+}
+
+// DeleteAutoScalingGroupsStep deletes auto scaling groups of autoscaled
+// node pools along with their instances and launch templates. Only the
+// group of the pool of config is deleted when the pool is set.
+type DeleteAutoScalingGroupsStep struct {
+	getEC2         func(steps.AWSConfig) (launchTemplateDeleter, error)
+	getAutoScaling GetAutoScalingFn
+	pollInterval   time.Duration
+}
+
+func InitDeleteAutoScalingGroups(ec2Fn GetEC2Fn, autoScalingFn GetAutoScalingFn) {
+	steps.RegisterStep(DeleteAutoScalingGroupsStepName,
+		NewDeleteAutoScalingGroups(ec2Fn, autoScalingFn))
+}
+
+func NewDeleteAutoScalingGroups(ec2Fn GetEC2Fn, autoScalingFn GetAutoScalingFn) *DeleteAutoScalingGroupsStep {
+	return &DeleteAutoScalingGroupsStep{
+		getEC2: func(cfg steps.AWSConfig) (launchTemplateDeleter, error) {
+			EC2, err := ec2Fn(cfg)
+
+			if err != nil {
+				return nil, errors.Wrap(ErrAuthorization, err.Error())
+			}
+
+			return EC2, nil
+		},
+		getAutoScaling: autoScalingFn,
+		pollInterval:   autoScalingGroupPollInterval,
+	}
+}
+
+func (s *DeleteAutoScalingGroupsStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config) error {
+	log := util.GetLogger(w)
+	names := autoScalingGroupNames(cfg)
+
+	if len(names) == 0 {
+		log.Infof("[%s] - no auto scaling groups in kube %s", s.Name(), cfg.Kube.Name)
+		return nil
+	}
+
+	svc, err := s.getAutoScaling(cfg.AWSConfig)
+
+	if err != nil {
+		logrus.Errorf("[%s] - error getting service %v", s.Name(), err)
+		return errors.Wrapf(err, "%s error getting service", s.Name())
+	}
+
+	for _, name := range names {
+		if err := svc.DeleteAutoScalingGroup(ctx, name); err != nil {
+			return err
+		}
+
+		log.Infof("[%s] - auto scaling group %s is being deleted", s.Name(), name)
+	}
+
+	// Groups are gone once their instances are terminated
+	for {
+		groups, err := svc.DescribeAutoScalingGroups(ctx, names)
+
+		if err != nil {
+			return err
+		}
+
+		if len(groups) == 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "wait for deletion of auto scaling groups %v", names)
+		case <-time.After(s.pollInterval):
+		}
+	}
+
+	EC2, err := s.getEC2(cfg.AWSConfig)
+
+	if err != nil {
+		logrus.Errorf("[%s] - error getting service %v", s.Name(), err)
+		return errors.Wrapf(err, "%s error getting service", s.Name())
+	}
+
+	// Launch templates are named after their groups
+	for _, name := range names {
+		_, err := EC2.DeleteLaunchTemplateWithContext(ctx, &ec2.DeleteLaunchTemplateInput{
+			LaunchTemplateName: aws.String(name),
+		})
+
+		if aerr, ok := err.(awserr.Error); ok &&
+			aerr.Code() == "InvalidLaunchTemplateName.NotFoundException" {
+			continue
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "delete launch template %s", name)
+		}
+	}
+
+	log.Infof("[%s] - auto scaling groups %v have been deleted", s.Name(), names)
+	return nil
+}
+
+// autoScalingGroupNames returns groups of the pool of config or of all
+// pools of the kube when the pool is not set.
+func autoScalingGroupNames(cfg *steps.Config) []string {
+	names := make([]string, 0)
+
+	for name, pool := range cfg.Kube.NodePools {
+		if pool == nil || pool.AutoScalingGroup == "" {
+			continue
+		}
+
+		if cfg.Pool == "" || cfg.Pool == name {
+			names = append(names, pool.AutoScalingGroup)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func (*DeleteAutoScalingGroupsStep) Name() string {
+	return DeleteAutoScalingGroupsStepName
+}
+
+func (*DeleteAutoScalingGroupsStep) Depends() []string {
+	return nil
+}
+
+func (*DeleteAutoScalingGroupsStep) Description() string {
+	return "Delete auto scaling groups of node pools"
+}
+
+func (*DeleteAutoScalingGroupsStep) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
@@ -0,0 +1,159 @@
+package amazon
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/clouds/awssdk"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+func TestDeleteAutoScalingGroupsStep_Run(t *testing.T) {
+	testCases := []struct {
+		description string
+
+		pool          string
+		deleteErr     error
+		templateErr   error
+		describeCalls int
+
+		expectedDeleted []string
+		templateCalls   int
+		errMsg          string
+	}{
+		{
+			description:     "all groups",
+			describeCalls:   3,
+			expectedDeleted: []string{"kube-a", "kube-b"},
+			templateCalls:   2,
+		},
+		{
+			description:     "group of pool",
+			pool:            "b",
+			expectedDeleted: []string{"kube-b"},
+			templateCalls:   1,
+		},
+		{
+			description:     "pool without group",
+			pool:            "c",
+			expectedDeleted: []string{},
+		},
+		{
+			description:     "delete error",
+			deleteErr:       errors.New("message1"),
+			expectedDeleted: []string{"kube-a"},
+			errMsg:          "message1",
+		},
+		{
+			description:     "template not found",
+			pool:            "a",
+			templateErr:     awserr.New("InvalidLaunchTemplateName.NotFoundException", "", nil),
+			expectedDeleted: []string{"kube-a"},
+			templateCalls:   1,
+		},
+		{
+			description:     "template error",
+			pool:            "a",
+			templateErr:     errors.New("message2"),
+			expectedDeleted: []string{"kube-a"},
+			templateCalls:   1,
+			errMsg:          "message2",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		EC2 := &mockSpotFleetCreator{}
+		EC2.On("DeleteLaunchTemplateWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(&ec2.DeleteLaunchTemplateOutput{},
+			testCase.templateErr)
+		svc := &fakeAutoScaling{
+			deleteErr:     testCase.deleteErr,
+			describeCalls: testCase.describeCalls,
+		}
+
+		config := &steps.Config{
+			Pool: testCase.pool,
+			Kube: model.Kube{
+				Name: "test",
+				NodePools: map[string]*model.NodePool{
+					"b": {AutoScalingGroup: "kube-b"},
+					"a": {AutoScalingGroup: "kube-a"},
+					"c": {},
+				},
+			},
+		}
+		step := DeleteAutoScalingGroupsStep{
+			getEC2: func(steps.AWSConfig) (launchTemplateDeleter, error) {
+				return EC2, nil
+			},
+			getAutoScaling: func(steps.AWSConfig) (awssdk.AutoScalingService, error) {
+				return svc, nil
+			},
+		}
+
+		err := step.Run(context.Background(), &bytes.Buffer{}, config)
+
+		if err == nil && testCase.errMsg != "" {
+			t.Errorf("Error must not be nil")
+		}
+
+		if err != nil && !strings.Contains(err.Error(), testCase.errMsg) {
+			t.Errorf("Error message %s does not contain %s",
+				err.Error(), testCase.errMsg)
+		}
+
+		if strings.Join(svc.deleted, ",") != strings.Join(testCase.expectedDeleted, ",") {
+			t.Errorf("Expected deleted %v actual %v", testCase.expectedDeleted, svc.deleted)
+		}
+
+		if testCase.describeCalls > 0 && svc.described != testCase.describeCalls {
+			t.Errorf("Deletion must be waited for, described %d", svc.described)
+		}
+
+		EC2.AssertNumberOfCalls(t, "DeleteLaunchTemplateWithContext", testCase.templateCalls)
+
+		for i, call := range EC2.Calls {
+			input := call.Arguments.Get(1).(*ec2.DeleteLaunchTemplateInput)
+
+			if aws.StringValue(input.LaunchTemplateName) != testCase.expectedDeleted[i] {
+				t.Errorf("Wrong launch template %v", input)
+			}
+		}
+	}
+}
+
+func TestDeleteAutoScalingGroupsStep_Cancel(t *testing.T) {
+	svc := &fakeAutoScaling{describeCalls: 100}
+	step := DeleteAutoScalingGroupsStep{
+		getAutoScaling: func(steps.AWSConfig) (awssdk.AutoScalingService, error) {
+			return svc, nil
+		},
+		pollInterval: time.Hour,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := step.Run(ctx, &bytes.Buffer{}, &steps.Config{
+		Kube: model.Kube{
+			NodePools: map[string]*model.NodePool{
+				"a": {AutoScalingGroup: "kube-a"},
+			},
+		},
+	})
+
+	if errors.Cause(err) != context.Canceled {
+		t.Errorf("Expected cancel error actual %v", err)
+	}
+}
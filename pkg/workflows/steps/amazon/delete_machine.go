@@ -12,6 +12,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/util"
 	"github.com/supergiant/control/pkg/workflows/steps"
 )
@@ -25,15 +26,17 @@ type instanceDeleter interface {
 }
 
 type DeleteNodeStep struct {
-	getSvc func(steps.AWSConfig) (instanceDeleter, error)
+	getSvc                func(steps.AWSConfig) (instanceDeleter, error)
+	getTargetGroupService GetELBv2Fn
 }
 
-func InitDeleteNode(fn GetEC2Fn) {
-	steps.RegisterStep(DeleteNodeStepName, NewDeleteNode(fn))
+func InitDeleteNode(fn GetEC2Fn, elbv2fn GetELBv2Fn) {
+	steps.RegisterStep(DeleteNodeStepName, NewDeleteNode(fn, elbv2fn))
 }
 
-func NewDeleteNode(fn GetEC2Fn) *DeleteNodeStep {
+func NewDeleteNode(fn GetEC2Fn, elbv2fn GetELBv2Fn) *DeleteNodeStep {
 	return &DeleteNodeStep{
+		getTargetGroupService: elbv2fn,
 		getSvc: func(cfg steps.AWSConfig) (instanceDeleter, error) {
 			EC2, err := fn(cfg)
 
@@ -91,6 +94,12 @@ func (s *DeleteNodeStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config
 		return nil
 	}
 
+	if cfg.Node.Role == model.RoleMaster && cfg.AWSConfig.TargetGroupARN != "" {
+		if err := s.deregisterTargets(ctx, cfg, instanceIDS); err != nil {
+			return err
+		}
+	}
+
 	logrus.Debugf("Node to be deleted Name: %s AWS id: %v",
 		cfg.Node.Name, instanceIDS)
 	_, err = svc.TerminateInstancesWithContext(ctx,
@@ -116,6 +125,29 @@ func (s *DeleteNodeStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config
 	return nil
 }
 
+// deregisterTargets removes master from target group of network load
+// balancer of user, so that requests are drained before it terminates.
+func (s *DeleteNodeStep) deregisterTargets(ctx context.Context, cfg *steps.Config, instanceIDs []string) error {
+	svc, err := s.getTargetGroupService(cfg.AWSConfig)
+
+	if err != nil {
+		logrus.Errorf("Error getting ELBv2 service %v", err)
+		return errors.Wrap(ErrAuthorization, err.Error())
+	}
+
+	for _, instanceID := range instanceIDs {
+		logrus.Debugf("Deregister instance %s from target group %s",
+			instanceID, cfg.AWSConfig.TargetGroupARN)
+
+		if err := svc.DeregisterInstance(ctx, cfg.AWSConfig.TargetGroupARN, instanceID); err != nil {
+			return errors.Wrapf(err, "%s deregister instance %s from target group %s",
+				DeleteNodeStepName, instanceID, cfg.AWSConfig.TargetGroupARN)
+		}
+	}
+
+	return nil
+}
+
 func (*DeleteNodeStep) Name() string {
 	return DeleteNodeStepName
 }
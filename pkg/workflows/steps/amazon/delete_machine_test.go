@@ -13,6 +13,8 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/supergiant/control/pkg/clouds/awssdk"
+	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/workflows/steps"
 )
 
@@ -155,8 +157,75 @@ func TestDeleteNodeStep_Run(t *testing.T) {
 	}
 }
 
+func TestDeleteNodeStepTargetGroup(t *testing.T) {
+	testCases := []struct {
+		description  string
+		role         model.Role
+		deregistered int
+	}{
+		{
+			description:  "master",
+			role:         model.RoleMaster,
+			deregistered: 1,
+		},
+		{
+			description: "node",
+			role:        model.RoleNode,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		svc := &mockInstanceDeleter{}
+		svc.On("DescribeInstancesWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(&ec2.DescribeInstancesOutput{
+			Reservations: []*ec2.Reservation{
+				{
+					Instances: []*ec2.Instance{
+						{
+							InstanceId: aws.String("i-1234"),
+						},
+					},
+				},
+			},
+		}, nil)
+		svc.On("TerminateInstancesWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(&ec2.TerminateInstancesOutput{}, nil)
+		svc.On("CancelSpotInstanceRequestsWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(nil, nil)
+
+		targetGroupSvc := &fakeTargetGroupService{}
+		step := DeleteNodeStep{
+			getSvc: func(steps.AWSConfig) (instanceDeleter, error) {
+				return svc, nil
+			},
+			getTargetGroupService: func(steps.AWSConfig) (awssdk.TargetGroupService, error) {
+				return targetGroupSvc, nil
+			},
+		}
+
+		config := &steps.Config{
+			Node: model.Machine{
+				Name: "test",
+				Role: testCase.role,
+			},
+			AWSConfig: steps.AWSConfig{
+				TargetGroupARN: "arn:tg",
+			},
+		}
+
+		if err := step.Run(context.Background(), &bytes.Buffer{}, config); err != nil {
+			t.Errorf("Unexpected error %v", err)
+		}
+
+		if len(targetGroupSvc.deregistered) != testCase.deregistered {
+			t.Errorf("Wrong deregistered instances %v", targetGroupSvc.deregistered)
+		}
+	}
+}
+
 func TestInitDeleteNode(t *testing.T) {
-	InitDeleteNode(GetEC2)
+	InitDeleteNode(GetEC2, GetELBv2)
 
 	s := steps.GetStep(DeleteNodeStepName)
 
@@ -166,7 +235,7 @@ func TestInitDeleteNode(t *testing.T) {
 }
 
 func TestNewDeleteNode(t *testing.T) {
-	s := NewDeleteNode(GetEC2)
+	s := NewDeleteNode(GetEC2, GetELBv2)
 
 	if s == nil {
 		t.Error("Step must not be nil")
@@ -186,7 +255,7 @@ func TestNewDeleteNodeErr(t *testing.T) {
 		return nil, errors.New("errorMessage")
 	}
 
-	s := NewDeleteNode(fn)
+	s := NewDeleteNode(fn, GetELBv2)
 
 	if s == nil {
 		t.Error("Step must not be nil")
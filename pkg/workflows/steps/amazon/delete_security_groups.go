@@ -58,6 +58,12 @@ func (s *DeleteSecurityGroup) Run(ctx context.Context, w io.Writer, cfg *steps.C
 		return nil
 	}
 
+	if cfg.Kube.IsUserResource(cfg.AWSConfig.MastersSecurityGroupID) ||
+		cfg.Kube.IsUserResource(cfg.AWSConfig.NodesSecurityGroupID) {
+		logrus.Debug("Skip deleting security groups of user")
+		return nil
+	}
+
 	svc, err := s.getSvc(cfg.AWSConfig)
 
 	if err != nil {
@@ -11,6 +11,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/workflows/steps"
 )
 
@@ -34,6 +35,7 @@ func TestDeleteSubnets_Run(t *testing.T) {
 		subnets     map[string]string
 
 		existingID string
+		userSubnet string
 
 		getSvcErr error
 		deleteErr error
@@ -67,6 +69,14 @@ func TestDeleteSubnets_Run(t *testing.T) {
 			},
 			existingID: "1234",
 		},
+		{
+			description: "skip subnet of user",
+			subnets: map[string]string{
+				"az1": "subnet1",
+			},
+			existingID: "1234",
+			userSubnet: "subnet1",
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -87,6 +97,10 @@ func TestDeleteSubnets_Run(t *testing.T) {
 			},
 		}
 
+		if testCase.userSubnet != "" {
+			config.Kube.SetOwner(testCase.userSubnet, model.OwnerUser)
+		}
+
 		err := step.Run(context.Background(), &bytes.Buffer{}, config)
 
 		if err == nil && testCase.errMsg != "" {
@@ -97,6 +111,10 @@ func TestDeleteSubnets_Run(t *testing.T) {
 			t.Errorf("Error message %s does not have expected %s",
 				err.Error(), testCase.errMsg)
 		}
+
+		if testCase.userSubnet != "" {
+			svc.AssertNotCalled(t, "DeleteSubnet", mock.Anything)
+		}
 	}
 }
 
@@ -54,6 +54,11 @@ func (s *DeleteVPC) Run(ctx context.Context, w io.Writer, cfg *steps.Config) err
 		return nil
 	}
 
+	if cfg.Kube.IsUserResource(cfg.AWSConfig.VPCID) {
+		logrus.Debugf("Skip deleting VPC %s of user", cfg.AWSConfig.VPCID)
+		return nil
+	}
+
 	svc, err := s.getSvc(cfg.AWSConfig)
 
 	if err != nil {
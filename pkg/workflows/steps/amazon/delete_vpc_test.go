@@ -14,6 +14,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/workflows/steps"
 )
 
@@ -34,6 +35,7 @@ func TestDeleteVPC_Run(t *testing.T) {
 	testCases := []struct {
 		description string
 		existingID  string
+		userVPC     bool
 
 		getSvcErr error
 		deleteErr error
@@ -59,6 +61,11 @@ func TestDeleteVPC_Run(t *testing.T) {
 			description: "success",
 			existingID:  "1234",
 		},
+		{
+			description: "skip vpc of user",
+			existingID:  "1234",
+			userVPC:     true,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -79,6 +86,10 @@ func TestDeleteVPC_Run(t *testing.T) {
 			},
 		}
 
+		if testCase.userVPC {
+			config.Kube.SetOwner(testCase.existingID, model.OwnerUser)
+		}
+
 		deleteVPCAttemptCount = 1
 		deleteVPCTimeout = time.Nanosecond
 
@@ -92,6 +103,11 @@ func TestDeleteVPC_Run(t *testing.T) {
 			t.Errorf("Error message %v must contain %s",
 				err, testCase.errMsg)
 		}
+
+		if testCase.userVPC {
+			svc.AssertNotCalled(t, "DeleteVpcWithContext", mock.Anything,
+				mock.Anything, mock.Anything)
+		}
 	}
 }
 
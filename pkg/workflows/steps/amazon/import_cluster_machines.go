@@ -145,7 +145,7 @@ func (s *ImportClusterStep) importMachines(ctx context.Context, role model.Role,
 		machine.Region = azToRegion(*instance.Placement.AvailabilityZone)
 		machine.Provider = cfg.Provider
 		machine.PrivateIp = *instance.PrivateIpAddress
-		machine.PublicIp = *instance.PublicIpAddress
+		machine.PublicIp = aws.StringValue(instance.PublicIpAddress)
 		machine.State = instanceStateToMachineState(*instance.State.Name)
 
 		cfg.AWSConfig.ImageID = *instance.ImageId
@@ -172,7 +172,7 @@ func (s *ImportClusterStep) importMachines(ctx context.Context, role model.Role,
 					Value: aws.String(cfg.Kube.Name),
 				},
 				{
-					Key:   aws.String("Role"),
+					Key:   aws.String(clouds.TagRole),
 					Value: aws.String(string(role)),
 				},
 				{
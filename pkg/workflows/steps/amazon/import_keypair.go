@@ -20,6 +20,8 @@ const ImportKeyPairStepName = "aws_import_keypair_step"
 type keyImporter interface {
 	ImportKeyPairWithContext(aws.Context, *ec2.ImportKeyPairInput, ...request.Option) (*ec2.ImportKeyPairOutput, error)
 	WaitUntilKeyPairExists(*ec2.DescribeKeyPairsInput) error
+	DeleteKeyPairWithContext(aws.Context, *ec2.DeleteKeyPairInput, ...request.Option) (*ec2.DeleteKeyPairOutput, error)
+	DescribeKeyPairsWithContext(aws.Context, *ec2.DescribeKeyPairsInput, ...request.Option) (*ec2.DescribeKeyPairsOutput, error)
 }
 
 // KeyPairStep represents creation of keypair in aws
@@ -65,12 +67,29 @@ func (s *KeyPairStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config) e
 		return errors.New("Cluster ID is too short")
 	}
 
-	// NOTE(stgleb): Add unique part to key pair name that allows to
-	// create cluster with the same name and avoid name collision of key pairs.
-	bootstrapKeyPairName := util.MakeKeyName(fmt.Sprintf("%s-%s",
-		cfg.Kube.Name,
-		cfg.Kube.ID[:4]),
-		false)
+	bootstrapKeyPairName := bootstrapKeyPairName(cfg)
+
+	// Key pairs can't be tagged, key pair imported by the previous
+	// run of the step is found by its name unique for the cluster
+	found, err := svc.DescribeKeyPairsWithContext(ctx, &ec2.DescribeKeyPairsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("key-name"),
+				Values: aws.StringSlice([]string{bootstrapKeyPairName}),
+			},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(ErrImportKeyPair, err.Error())
+	}
+
+	if found != nil && len(found.KeyPairs) > 0 {
+		log.Infof("[%s] - reuse cluster bootstrap keypair %s",
+			s.Name(), bootstrapKeyPairName)
+		cfg.AWSConfig.KeyPairName = bootstrapKeyPairName
+		return nil
+	}
+
 	log.Infof("[%s] - importing cluster bootstrap key as keypair %s",
 		s.Name(), bootstrapKeyPairName)
 	req := &ec2.ImportKeyPairInput{
@@ -105,10 +124,43 @@ func (s *KeyPairStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config) e
 	return nil
 }
 
+// Rollback deletes key pair imported by the step, key pairs of
+// the profile are kept.
 func (s *KeyPairStep) Rollback(ctx context.Context, w io.Writer, cfg *steps.Config) error {
+	if len(cfg.Kube.ID) < 4 || cfg.AWSConfig.KeyPairName != bootstrapKeyPairName(cfg) {
+		return nil
+	}
+
+	svc, err := s.getSvc(cfg.AWSConfig)
+
+	if err != nil {
+		return errors.Wrapf(err, "%s caused error when getting service",
+			ImportKeyPairStepName)
+	}
+
+	_, err = svc.DeleteKeyPairWithContext(ctx, &ec2.DeleteKeyPairInput{
+		KeyName: aws.String(cfg.AWSConfig.KeyPairName),
+	})
+
+	if err != nil {
+		return errors.Wrapf(err, "delete key pair %s", cfg.AWSConfig.KeyPairName)
+	}
+
+	cfg.AWSConfig.KeyPairName = ""
+
 	return nil
 }
 
+// bootstrapKeyPairName is the name of key pair imported for the cluster.
+func bootstrapKeyPairName(cfg *steps.Config) string {
+	// NOTE(stgleb): Add unique part to key pair name that allows to
+	// create cluster with the same name and avoid name collision of key pairs.
+	return util.MakeKeyName(fmt.Sprintf("%s-%s",
+		cfg.Kube.Name,
+		cfg.Kube.ID[:4]),
+		false)
+}
+
 func (*KeyPairStep) Name() string {
 	return ImportKeyPairStepName
 }
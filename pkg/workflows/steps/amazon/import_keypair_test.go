@@ -41,6 +41,26 @@ func (m *mockKeyPairSvc) ImportKeyPairWithContext(ctx aws.Context,
 	return val, args.Error(1)
 }
 
+func (m *mockKeyPairSvc) DeleteKeyPairWithContext(ctx aws.Context,
+	req *ec2.DeleteKeyPairInput, opts ...request.Option) (*ec2.DeleteKeyPairOutput, error) {
+	args := m.Called(ctx, req, opts)
+	val, ok := args.Get(0).(*ec2.DeleteKeyPairOutput)
+	if !ok {
+		return nil, args.Error(1)
+	}
+	return val, args.Error(1)
+}
+
+func (m *mockKeyPairSvc) DescribeKeyPairsWithContext(ctx aws.Context,
+	req *ec2.DescribeKeyPairsInput, opts ...request.Option) (*ec2.DescribeKeyPairsOutput, error) {
+	args := m.Called(ctx, req, opts)
+	val, ok := args.Get(0).(*ec2.DescribeKeyPairsOutput)
+	if !ok {
+		return nil, args.Error(1)
+	}
+	return val, args.Error(1)
+}
+
 func (m *mockKeyPairSvc) WaitUntilKeyPairExists(req *ec2.DescribeKeyPairsInput) error {
 	args := m.Called(req)
 	val, ok := args.Get(0).(error)
@@ -106,6 +126,9 @@ func TestImportKeyPair_Run(t *testing.T) {
 		svc.On("WaitUntilKeyPairExists",
 			mock.Anything).
 			Return(testCase.waitErr)
+		svc.On("DescribeKeyPairsWithContext",
+			mock.Anything, mock.Anything, mock.Anything).
+			Return(&ec2.DescribeKeyPairsOutput{}, nil)
 
 		config := &steps.Config{
 			Kube: model.Kube{
@@ -206,6 +229,45 @@ func TestKeyPairStep_Rollback(t *testing.T) {
 	}
 }
 
+func TestKeyPairStep_RollbackImported(t *testing.T) {
+	config := &steps.Config{
+		Kube: model.Kube{
+			Name: "test",
+			ID:   "1234abcd",
+		},
+	}
+	config.AWSConfig.KeyPairName = bootstrapKeyPairName(config)
+
+	svc := &mockKeyPairSvc{}
+	svc.On("DeleteKeyPairWithContext", mock.Anything, mock.Anything, mock.Anything).
+		Return(&ec2.DeleteKeyPairOutput{}, nil)
+
+	s := &KeyPairStep{
+		getSvc: func(steps.AWSConfig) (keyImporter, error) {
+			return svc, nil
+		},
+	}
+
+	if err := s.Rollback(context.Background(), &bytes.Buffer{}, config); err != nil {
+		t.Errorf("Unexpected error when rollback %v", err)
+	}
+
+	svc.AssertNumberOfCalls(t, "DeleteKeyPairWithContext", 1)
+
+	if config.AWSConfig.KeyPairName != "" {
+		t.Errorf("Key pair name must be cleared %s", config.AWSConfig.KeyPairName)
+	}
+
+	// Key pair of the user is not deleted
+	config.AWSConfig.KeyPairName = "user-key"
+
+	if err := s.Rollback(context.Background(), &bytes.Buffer{}, config); err != nil {
+		t.Errorf("Unexpected error when rollback %v", err)
+	}
+
+	svc.AssertNumberOfCalls(t, "DeleteKeyPairWithContext", 1)
+}
+
 func TestKeyPairStep_Name(t *testing.T) {
 	s := &KeyPairStep{}
 
@@ -0,0 +1,90 @@
+package amazon
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+// Roles of resources created by steps, resources are tagged with the
+// cluster id and the role to be reused when the step is run again.
+const (
+	resourceRoleVPC             = "vpc"
+	resourceRoleMastersSecGroup = "masters-secgroup"
+	resourceRoleNodesSecGroup   = "nodes-secgroup"
+	resourceRoleSubnet          = "subnet"
+)
+
+type tagCreator interface {
+	CreateTagsWithContext(aws.Context, *ec2.CreateTagsInput, ...request.Option) (*ec2.CreateTagsOutput, error)
+}
+
+// clusterFilters match resources of the cluster with the role.
+func clusterFilters(clusterID, role string) []*ec2.Filter {
+	return []*ec2.Filter{
+		{
+			Name:   aws.String("tag:" + clouds.TagClusterID),
+			Values: aws.StringSlice([]string{clusterID}),
+		},
+		{
+			Name:   aws.String("tag:" + clouds.TagRole),
+			Values: aws.StringSlice([]string{role}),
+		},
+	}
+}
+
+// tagResource tags the resource created by the step for the cluster.
+func tagResource(ctx context.Context, svc tagCreator, cfg *steps.Config, resourceID, role string) error {
+	_, err := svc.CreateTagsWithContext(ctx, &ec2.CreateTagsInput{
+		Resources: []*string{aws.String(resourceID)},
+		Tags: ec2Tags(cfg.Tags,
+			&ec2.Tag{
+				Key:   aws.String(clouds.TagKubernetesCluster),
+				Value: aws.String(cfg.Kube.Name),
+			},
+			&ec2.Tag{
+				Key:   aws.String(clouds.TagClusterID),
+				Value: aws.String(cfg.Kube.ID),
+			},
+			&ec2.Tag{
+				Key:   aws.String(clouds.TagRole),
+				Value: aws.String(role),
+			},
+		),
+	})
+
+	return errors.Wrapf(err, "tag %s %s", role, resourceID)
+}
+
+// ignoreDuplicatePermission skips rules that have been authorized
+// by the previous run of the step.
+func ignoreDuplicatePermission(err error) error {
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == errCodeDuplicatePermission {
+		return nil
+	}
+
+	return err
+}
+
+// hasUserSubnets returns true when subnets of the cluster are provided
+// by user, routing of them is managed by user too.
+func hasUserSubnets(cfg *steps.Config) bool {
+	if len(cfg.AWSConfig.Subnets) == 0 {
+		return false
+	}
+
+	for _, subnetID := range cfg.AWSConfig.Subnets {
+		if !cfg.Kube.IsUserResource(subnetID) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,309 @@
+package amazon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+
+	"github.com/supergiant/control/pkg/account"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+// fakeEC2 keeps resources created by steps and finds them by filters.
+type fakeEC2 struct {
+	ec2iface.EC2API
+
+	// Attributes of resources by id, tags are prefixed with tag:
+	resources map[string]map[string]string
+	kinds     map[string]string
+	rules     map[string]bool
+	created   map[string]int
+}
+
+func newFakeEC2() *fakeEC2 {
+	return &fakeEC2{
+		resources: make(map[string]map[string]string),
+		kinds:     make(map[string]string),
+		rules:     make(map[string]bool),
+		created:   make(map[string]int),
+	}
+}
+
+func (f *fakeEC2) create(kind string, attrs map[string]string) string {
+	f.created[kind]++
+	id := fmt.Sprintf("%s-%d", kind, len(f.resources))
+	f.resources[id] = attrs
+	f.kinds[id] = kind
+
+	return id
+}
+
+func (f *fakeEC2) find(kind string, filters []*ec2.Filter) []string {
+	var ids []string
+
+	for id, attrs := range f.resources {
+		if f.kinds[id] != kind {
+			continue
+		}
+
+		matches := true
+
+		for _, filter := range filters {
+			if attrs[aws.StringValue(filter.Name)] != aws.StringValue(filter.Values[0]) {
+				matches = false
+			}
+		}
+
+		if matches {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+func (f *fakeEC2) CreateTagsWithContext(ctx aws.Context, req *ec2.CreateTagsInput,
+	opts ...request.Option) (*ec2.CreateTagsOutput, error) {
+	for _, id := range req.Resources {
+		for _, tag := range req.Tags {
+			f.resources[aws.StringValue(id)]["tag:"+aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+	}
+
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+func (f *fakeEC2) DescribeVpcsWithContext(ctx aws.Context, req *ec2.DescribeVpcsInput,
+	opts ...request.Option) (*ec2.DescribeVpcsOutput, error) {
+	out := &ec2.DescribeVpcsOutput{}
+
+	for _, id := range f.find("vpc", req.Filters) {
+		out.Vpcs = append(out.Vpcs, &ec2.Vpc{
+			VpcId:     aws.String(id),
+			CidrBlock: aws.String(f.resources[id]["cidr"]),
+		})
+	}
+
+	return out, nil
+}
+
+func (f *fakeEC2) CreateVpcWithContext(ctx aws.Context, req *ec2.CreateVpcInput,
+	opts ...request.Option) (*ec2.CreateVpcOutput, error) {
+	id := f.create("vpc", map[string]string{
+		"cidr": aws.StringValue(req.CidrBlock),
+	})
+
+	return &ec2.CreateVpcOutput{Vpc: &ec2.Vpc{VpcId: aws.String(id)}}, nil
+}
+
+func (f *fakeEC2) ModifyVpcAttributeWithContext(aws.Context, *ec2.ModifyVpcAttributeInput,
+	...request.Option) (*ec2.ModifyVpcAttributeOutput, error) {
+	return &ec2.ModifyVpcAttributeOutput{}, nil
+}
+
+func (f *fakeEC2) WaitUntilVpcExistsWithContext(aws.Context, *ec2.DescribeVpcsInput,
+	...request.WaiterOption) error {
+	return nil
+}
+
+func (f *fakeEC2) DescribeSecurityGroupsWithContext(ctx aws.Context, req *ec2.DescribeSecurityGroupsInput,
+	opts ...request.Option) (*ec2.DescribeSecurityGroupsOutput, error) {
+	out := &ec2.DescribeSecurityGroupsOutput{}
+
+	for _, id := range f.find("sg", req.Filters) {
+		out.SecurityGroups = append(out.SecurityGroups, &ec2.SecurityGroup{
+			GroupId: aws.String(id),
+		})
+	}
+
+	return out, nil
+}
+
+func (f *fakeEC2) CreateSecurityGroupWithContext(ctx aws.Context, req *ec2.CreateSecurityGroupInput,
+	opts ...request.Option) (*ec2.CreateSecurityGroupOutput, error) {
+	if len(f.find("sg", []*ec2.Filter{{
+		Name:   aws.String("group-name"),
+		Values: []*string{req.GroupName},
+	}})) > 0 {
+		return nil, awserr.New("InvalidGroup.Duplicate", "group exists", nil)
+	}
+
+	id := f.create("sg", map[string]string{
+		"vpc-id":     aws.StringValue(req.VpcId),
+		"group-name": aws.StringValue(req.GroupName),
+	})
+
+	return &ec2.CreateSecurityGroupOutput{GroupId: aws.String(id)}, nil
+}
+
+func (f *fakeEC2) AuthorizeSecurityGroupIngressWithContext(ctx aws.Context, req *ec2.AuthorizeSecurityGroupIngressInput,
+	opts ...request.Option) (*ec2.AuthorizeSecurityGroupIngressOutput, error) {
+	rule := req.String()
+
+	if f.rules[rule] {
+		return nil, awserr.New(errCodeDuplicatePermission, "rule exists", nil)
+	}
+
+	f.rules[rule] = true
+
+	return &ec2.AuthorizeSecurityGroupIngressOutput{}, nil
+}
+
+func (f *fakeEC2) DescribeSubnetsWithContext(ctx aws.Context, req *ec2.DescribeSubnetsInput,
+	opts ...request.Option) (*ec2.DescribeSubnetsOutput, error) {
+	out := &ec2.DescribeSubnetsOutput{}
+
+	for _, id := range f.find("subnet", req.Filters) {
+		out.Subnets = append(out.Subnets, &ec2.Subnet{
+			SubnetId:         aws.String(id),
+			AvailabilityZone: aws.String(f.resources[id]["az"]),
+		})
+	}
+
+	return out, nil
+}
+
+func (f *fakeEC2) CreateSubnetWithContext(ctx aws.Context, req *ec2.CreateSubnetInput,
+	opts ...request.Option) (*ec2.CreateSubnetOutput, error) {
+	id := f.create("subnet", map[string]string{
+		"vpc-id": aws.StringValue(req.VpcId),
+		"az":     aws.StringValue(req.AvailabilityZone),
+	})
+
+	return &ec2.CreateSubnetOutput{Subnet: &ec2.Subnet{SubnetId: aws.String(id)}}, nil
+}
+
+func (f *fakeEC2) ModifySubnetAttributeWithContext(aws.Context, *ec2.ModifySubnetAttributeInput,
+	...request.Option) (*ec2.ModifySubnetAttributeOutput, error) {
+	return &ec2.ModifySubnetAttributeOutput{}, nil
+}
+
+func (f *fakeEC2) DescribeKeyPairsWithContext(ctx aws.Context, req *ec2.DescribeKeyPairsInput,
+	opts ...request.Option) (*ec2.DescribeKeyPairsOutput, error) {
+	out := &ec2.DescribeKeyPairsOutput{}
+
+	for _, id := range f.find("key", req.Filters) {
+		out.KeyPairs = append(out.KeyPairs, &ec2.KeyPairInfo{
+			KeyName: aws.String(f.resources[id]["key-name"]),
+		})
+	}
+
+	return out, nil
+}
+
+func (f *fakeEC2) ImportKeyPairWithContext(ctx aws.Context, req *ec2.ImportKeyPairInput,
+	opts ...request.Option) (*ec2.ImportKeyPairOutput, error) {
+	if len(f.find("key", []*ec2.Filter{{
+		Name:   aws.String("key-name"),
+		Values: []*string{req.KeyName},
+	}})) > 0 {
+		return nil, awserr.New("InvalidKeyPair.Duplicate", "key pair exists", nil)
+	}
+
+	f.create("key", map[string]string{
+		"key-name": aws.StringValue(req.KeyName),
+	})
+
+	return &ec2.ImportKeyPairOutput{
+		KeyName:        req.KeyName,
+		KeyFingerprint: aws.String("fingerprint"),
+	}, nil
+}
+
+func (f *fakeEC2) WaitUntilKeyPairExists(*ec2.DescribeKeyPairsInput) error {
+	return nil
+}
+
+func TestCreateStepsReuseResources(t *testing.T) {
+	fake := newFakeEC2()
+	zones := []string{"us-east-1a", "us-east-1b"}
+
+	getEC2 := func(steps.AWSConfig) (ec2iface.EC2API, error) {
+		return fake, nil
+	}
+
+	secGroupsStep := NewCreateSecurityGroupsStep(getEC2)
+	secGroupsStep.findOutboundIP = func() (string, error) {
+		return "10.20.30.40", nil
+	}
+
+	subnetsStep := NewCreateSubnetStep(getEC2, nil)
+	subnetsStep.zoneGetterFactory = func(context.Context, accountGetter, *steps.Config) (account.ZonesGetter, error) {
+		return &mockZoneGetter{zones: zones}, nil
+	}
+
+	workflow := []steps.Step{
+		NewCreateVPCStep(getEC2),
+		secGroupsStep,
+		subnetsStep,
+		NewImportKeyPairStep(getEC2),
+	}
+
+	// Each run starts with the config that has not been saved
+	// after resources had been created
+	run := func() *steps.Config {
+		cfg := &steps.Config{
+			Kube: model.Kube{
+				ID:   "abcdef",
+				Name: "test",
+			},
+			AWSConfig: steps.AWSConfig{
+				VPCCIDR: "10.0.0.0/16",
+			},
+		}
+
+		for _, step := range workflow {
+			if err := step.Run(context.Background(), &bytes.Buffer{}, cfg); err != nil {
+				t.Fatalf("%s: unexpected error %v", step.Name(), err)
+			}
+		}
+
+		return cfg
+	}
+
+	first := run()
+	second := run()
+
+	expected := map[string]int{
+		"vpc":    1,
+		"sg":     2,
+		"subnet": len(zones),
+		"key":    1,
+	}
+
+	for kind, count := range expected {
+		if fake.created[kind] != count {
+			t.Errorf("Expected %d %s created actual %d", count, kind, fake.created[kind])
+		}
+	}
+
+	if first.AWSConfig.VPCID != second.AWSConfig.VPCID ||
+		first.AWSConfig.CreatedVPCID != second.AWSConfig.CreatedVPCID {
+		t.Errorf("VPC must be reused %s %s", first.AWSConfig.VPCID, second.AWSConfig.VPCID)
+	}
+
+	if first.AWSConfig.MastersSecurityGroupID != second.AWSConfig.MastersSecurityGroupID ||
+		first.AWSConfig.NodesSecurityGroupID != second.AWSConfig.NodesSecurityGroupID {
+		t.Errorf("Security groups must be reused %+v %+v", first.AWSConfig, second.AWSConfig)
+	}
+
+	for _, zone := range zones {
+		if first.AWSConfig.Subnets[zone] != second.AWSConfig.Subnets[zone] {
+			t.Errorf("Subnet in %s must be reused %v %v", zone,
+				first.AWSConfig.Subnets, second.AWSConfig.Subnets)
+		}
+	}
+
+	if second.AWSConfig.KeyPairName == "" || !strings.HasPrefix(second.AWSConfig.KeyPairName, "test") {
+		t.Errorf("Key pair must be reused %s", second.AWSConfig.KeyPairName)
+	}
+}
@@ -0,0 +1,149 @@
+package amazon
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+// Instance metadata options are not known to the vendored SDK, they
+// are added to EC2 query parameters of requests instead.
+
+const (
+	HTTPTokensOptional = "optional"
+	HTTPTokensRequired = "required"
+
+	// Metadata responses must pass one more hop to reach containers,
+	// e.g. kubelet running in a container.
+	DefaultHTTPPutResponseHopLimit = 2
+	maxHTTPPutResponseHopLimit     = 64
+)
+
+type metadataOptions struct {
+	HTTPTokens              string
+	HTTPPutResponseHopLimit int64
+}
+
+// newMetadataOptions validates metadata options of config, whether
+// tokens are required is left to AWS when they are not set.
+func newMetadataOptions(cfg steps.AWSConfig) (*metadataOptions, error) {
+	opts := &metadataOptions{
+		HTTPTokens:              cfg.HttpTokens,
+		HTTPPutResponseHopLimit: DefaultHTTPPutResponseHopLimit,
+	}
+
+	switch opts.HTTPTokens {
+	case "", HTTPTokensOptional, HTTPTokensRequired:
+	default:
+		return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"http tokens must be %s or %s, got %s", HTTPTokensOptional,
+			HTTPTokensRequired, opts.HTTPTokens)
+	}
+
+	if cfg.HttpPutResponseHopLimit != "" {
+		hopLimit, err := strconv.ParseInt(cfg.HttpPutResponseHopLimit, 10, 64)
+
+		if err != nil || hopLimit < 1 || hopLimit > maxHTTPPutResponseHopLimit {
+			return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"wrong http put response hop limit %s", cfg.HttpPutResponseHopLimit)
+		}
+
+		opts.HTTPPutResponseHopLimit = hopLimit
+	}
+
+	return opts, nil
+}
+
+// values returns query parameters of options prefixed with the name
+// of the request field, e.g. MetadataOptions of RunInstances.
+func (o *metadataOptions) values(prefix string) url.Values {
+	values := url.Values{}
+	values.Set(prefix+".HttpPutResponseHopLimit",
+		strconv.FormatInt(o.HTTPPutResponseHopLimit, 10))
+
+	if o.HTTPTokens != "" {
+		values.Set(prefix+".HttpTokens", o.HTTPTokens)
+	}
+
+	return values
+}
+
+// withMetadataOptions adds options to the request body once it has
+// been built from the input.
+func withMetadataOptions(opts *metadataOptions, prefix string) request.Option {
+	return func(r *request.Request) {
+		r.Handlers.Build.PushBack(func(r *request.Request) {
+			if r.Error != nil || r.Body == nil {
+				return
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+
+			if err != nil {
+				r.Error = awserr.New(request.ErrCodeSerialization, "read request body", err)
+				return
+			}
+
+			values, err := url.ParseQuery(string(body))
+
+			if err != nil {
+				r.Error = awserr.New(request.ErrCodeSerialization, "parse request body", err)
+				return
+			}
+
+			for key, value := range opts.values(prefix) {
+				values[key] = value
+			}
+
+			r.SetBufferBody([]byte(values.Encode()))
+		})
+	}
+}
+
+type modifyInstanceMetadataOptionsInput struct {
+	InstanceId              *string `type:"string"`
+	HttpTokens              *string `type:"string"`
+	HttpPutResponseHopLimit *int64  `type:"integer"`
+
+	_ struct{} `type:"structure"`
+}
+
+type modifyInstanceMetadataOptionsOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+// requestBuilder is implemented by EC2 client of the SDK.
+type requestBuilder interface {
+	NewRequest(*request.Operation, interface{}, interface{}) *request.Request
+}
+
+// modifyInstanceMetadataOptions sets options of a running instance.
+func modifyInstanceMetadataOptions(ctx context.Context, svc requestBuilder,
+	instanceID string, opts *metadataOptions) error {
+	input := &modifyInstanceMetadataOptionsInput{
+		InstanceId:              aws.String(instanceID),
+		HttpPutResponseHopLimit: aws.Int64(opts.HTTPPutResponseHopLimit),
+	}
+
+	if opts.HTTPTokens != "" {
+		input.HttpTokens = aws.String(opts.HTTPTokens)
+	}
+
+	req := svc.NewRequest(&request.Operation{
+		Name:       "ModifyInstanceMetadataOptions",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}, input, &modifyInstanceMetadataOptionsOutput{})
+	req.SetContext(ctx)
+
+	return req.Send()
+}
@@ -0,0 +1,252 @@
+package amazon
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+func testEC2(t *testing.T, endpoint string) *ec2.EC2 {
+	sess, err := NewSession(steps.AWSConfig{
+		KeyID:  "key",
+		Secret: "secret",
+		Region: "us-east-1",
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	return ec2.New(sess, &aws.Config{
+		Endpoint:   aws.String(endpoint),
+		MaxRetries: aws.Int(0),
+	})
+}
+
+func requestBody(t *testing.T, body []byte) url.Values {
+	values, err := url.ParseQuery(string(body))
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	return values
+}
+
+func TestNewMetadataOptions(t *testing.T) {
+	testCases := []struct {
+		description string
+		cfg         steps.AWSConfig
+
+		expectedTokens   string
+		expectedHopLimit int64
+		isErr            bool
+	}{
+		{
+			description:      "defaults",
+			expectedHopLimit: DefaultHTTPPutResponseHopLimit,
+		},
+		{
+			description: "imds v2",
+			cfg: steps.AWSConfig{
+				HttpTokens:              HTTPTokensRequired,
+				HttpPutResponseHopLimit: "3",
+			},
+			expectedTokens:   HTTPTokensRequired,
+			expectedHopLimit: 3,
+		},
+		{
+			description: "wrong tokens",
+			cfg:         steps.AWSConfig{HttpTokens: "always"},
+			isErr:       true,
+		},
+		{
+			description: "wrong hop limit",
+			cfg:         steps.AWSConfig{HttpPutResponseHopLimit: "0"},
+			isErr:       true,
+		},
+		{
+			description: "hop limit is not a number",
+			cfg:         steps.AWSConfig{HttpPutResponseHopLimit: "two"},
+			isErr:       true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		opts, err := newMetadataOptions(testCase.cfg)
+
+		if testCase.isErr {
+			if !sgerrors.IsValidationFailed(err) {
+				t.Errorf("Expected validation error actual %v", err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if opts.HTTPTokens != testCase.expectedTokens ||
+			opts.HTTPPutResponseHopLimit != testCase.expectedHopLimit {
+			t.Errorf("Wrong options %v", opts)
+		}
+	}
+}
+
+func TestWithMetadataOptions(t *testing.T) {
+	svc := testEC2(t, "http://127.0.0.1")
+	opts := &metadataOptions{
+		HTTPTokens:              HTTPTokensRequired,
+		HTTPPutResponseHopLimit: 2,
+	}
+
+	runReq, _ := svc.RunInstancesRequest(&ec2.RunInstancesInput{
+		ImageId:  aws.String("ami-1"),
+		MinCount: aws.Int64(1),
+		MaxCount: aws.Int64(1),
+	})
+	runReq.ApplyOptions(withMetadataOptions(opts, "MetadataOptions"))
+
+	templateReq, _ := svc.CreateLaunchTemplateRequest(&ec2.CreateLaunchTemplateInput{
+		LaunchTemplateName: aws.String("template"),
+		LaunchTemplateData: &ec2.RequestLaunchTemplateData{
+			ImageId: aws.String("ami-1"),
+		},
+	})
+	templateReq.ApplyOptions(withMetadataOptions(opts, "LaunchTemplateData.MetadataOptions"))
+
+	testCases := []struct {
+		description string
+		build       func() ([]byte, error)
+		expected    map[string]string
+	}{
+		{
+			description: "run instances",
+			build: func() ([]byte, error) {
+				if err := runReq.Build(); err != nil {
+					return nil, err
+				}
+				return ioutil.ReadAll(runReq.Body)
+			},
+			expected: map[string]string{
+				"Action":                     "RunInstances",
+				"ImageId":                    "ami-1",
+				"MetadataOptions.HttpTokens": HTTPTokensRequired,
+				"MetadataOptions.HttpPutResponseHopLimit": "2",
+			},
+		},
+		{
+			description: "launch template",
+			build: func() ([]byte, error) {
+				if err := templateReq.Build(); err != nil {
+					return nil, err
+				}
+				return ioutil.ReadAll(templateReq.Body)
+			},
+			expected: map[string]string{
+				"Action":                     "CreateLaunchTemplate",
+				"LaunchTemplateData.ImageId": "ami-1",
+				"LaunchTemplateData.MetadataOptions.HttpTokens":              HTTPTokensRequired,
+				"LaunchTemplateData.MetadataOptions.HttpPutResponseHopLimit": "2",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		body, err := testCase.build()
+
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		values := requestBody(t, body)
+
+		for key, value := range testCase.expected {
+			if actual := values.Get(key); actual != value {
+				t.Errorf("Wrong %s expected %s actual %s", key, value, actual)
+			}
+		}
+	}
+}
+
+func TestSetSpotMetadataOptionsStep_Run(t *testing.T) {
+	var (
+		m        sync.Mutex
+		modified = make(map[string]url.Values)
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		values := requestBody(t, body)
+
+		m.Lock()
+		modified[values.Get("InstanceId")] = values
+		m.Unlock()
+
+		w.Write([]byte(`<ModifyInstanceMetadataOptionsResponse>` +
+			`<requestId>1</requestId></ModifyInstanceMetadataOptionsResponse>`))
+	}))
+	defer server.Close()
+
+	step := NewSetSpotMetadataOptions(func(steps.AWSConfig) (ec2iface.EC2API, error) {
+		return testEC2(t, server.URL), nil
+	})
+
+	cfg := &steps.Config{
+		AWSConfig: steps.AWSConfig{
+			HttpTokens: HTTPTokensRequired,
+		},
+		SpotConfig: steps.SpotConfig{
+			Instances: map[string]string{
+				"sir-1": "i-1",
+				"sir-2": "i-2",
+			},
+		},
+	}
+
+	if err := step.Run(context.Background(), &bytes.Buffer{}, cfg); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	for _, instanceID := range []string{"i-1", "i-2"} {
+		values, ok := modified[instanceID]
+
+		if !ok {
+			t.Errorf("Metadata options of %s must be set", instanceID)
+			continue
+		}
+
+		if values.Get("Action") != "ModifyInstanceMetadataOptions" ||
+			values.Get("HttpTokens") != HTTPTokensRequired ||
+			values.Get("HttpPutResponseHopLimit") != "2" {
+			t.Errorf("Wrong request of %s %v", instanceID, values)
+		}
+	}
+}
+
+func TestSetSpotMetadataOptionsStep_RunNoInstances(t *testing.T) {
+	step := NewSetSpotMetadataOptions(func(steps.AWSConfig) (ec2iface.EC2API, error) {
+		t.Errorf("Service must not be created")
+		return nil, nil
+	})
+
+	if err := step.Run(context.Background(), &bytes.Buffer{}, &steps.Config{}); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
@@ -21,15 +21,17 @@ type LoadBalancerRegister interface {
 
 type RegisterInstanceStep struct {
 	getLoadBalancerService func(cfg steps.AWSConfig) (LoadBalancerRegister, error)
+	getTargetGroupService  GetELBv2Fn
 }
 
 //InitCreateMachine adds the step to the registry
-func InitRegisterInstance(getELBFn GetELBFn) {
-	steps.RegisterStep(RegisterInstanceStepName, NewRegisterInstanceStep(getELBFn))
+func InitRegisterInstance(getELBFn GetELBFn, getELBv2Fn GetELBv2Fn) {
+	steps.RegisterStep(RegisterInstanceStepName, NewRegisterInstanceStep(getELBFn, getELBv2Fn))
 }
 
-func NewRegisterInstanceStep(getELBFn GetELBFn) *RegisterInstanceStep {
+func NewRegisterInstanceStep(getELBFn GetELBFn, getELBv2Fn GetELBv2Fn) *RegisterInstanceStep {
 	return &RegisterInstanceStep{
+		getTargetGroupService: getELBv2Fn,
 		getLoadBalancerService: func(cfg steps.AWSConfig) (LoadBalancerRegister, error) {
 
 			elbInstance, err := getELBFn(cfg)
@@ -46,6 +48,10 @@ func NewRegisterInstanceStep(getELBFn GetELBFn) *RegisterInstanceStep {
 }
 
 func (s *RegisterInstanceStep) Run(ctx context.Context, out io.Writer, cfg *steps.Config) error {
+	if cfg.AWSConfig.TargetGroupARN != "" {
+		return s.registerTarget(ctx, cfg)
+	}
+
 	svc, err := s.getLoadBalancerService(cfg.AWSConfig)
 
 	if err != nil {
@@ -54,22 +60,25 @@ func (s *RegisterInstanceStep) Run(ctx context.Context, out io.Writer, cfg *step
 			RegisterInstanceStepName)
 	}
 
-	logrus.Infof("Register instance Name: %s ID: %s to external load balancer: %s",
-		cfg.Node.Name, cfg.Node.ID, cfg.AWSConfig.ExternalLoadBalancerName)
-	_, err = svc.RegisterInstancesWithLoadBalancerWithContext(ctx, &elb.RegisterInstancesWithLoadBalancerInput{
-		LoadBalancerName: aws.String(cfg.AWSConfig.ExternalLoadBalancerName),
-		Instances: []*elb.Instance{
-			{
-				InstanceId: aws.String(cfg.Node.ID),
+	// Private kube has no external load balancer
+	if cfg.AWSConfig.ExternalLoadBalancerName != "" {
+		logrus.Infof("Register instance Name: %s ID: %s to external load balancer: %s",
+			cfg.Node.Name, cfg.Node.ID, cfg.AWSConfig.ExternalLoadBalancerName)
+		_, err := svc.RegisterInstancesWithLoadBalancerWithContext(ctx, &elb.RegisterInstancesWithLoadBalancerInput{
+			LoadBalancerName: aws.String(cfg.AWSConfig.ExternalLoadBalancerName),
+			Instances: []*elb.Instance{
+				{
+					InstanceId: aws.String(cfg.Node.ID),
+				},
 			},
-		},
-	})
-
-	if err != nil {
-		logrus.Errorf("error registering instance %s to external loadbalancer %s %v", cfg.Node.ID, cfg.AWSConfig.ExternalLoadBalancerName, err)
-		return errors.Wrapf(err, "registering instance %s to load balancer Load balancer %s %s",
-			cfg.Node.ID, cfg.AWSConfig.ExternalLoadBalancerName,
-			DeleteLoadBalancerStepName)
+		})
+
+		if err != nil {
+			logrus.Errorf("error registering instance %s to external loadbalancer %s %v", cfg.Node.ID, cfg.AWSConfig.ExternalLoadBalancerName, err)
+			return errors.Wrapf(err, "registering instance %s to load balancer Load balancer %s %s",
+				cfg.Node.ID, cfg.AWSConfig.ExternalLoadBalancerName,
+				DeleteLoadBalancerStepName)
+		}
 	}
 
 	logrus.Infof("Register instance Name: %s ID: %s to internal load balancer: %s",
@@ -93,6 +102,30 @@ func (s *RegisterInstanceStep) Run(ctx context.Context, out io.Writer, cfg *step
 	return nil
 }
 
+// registerTarget adds instance to target group of network load balancer
+// of user that replaces load balancers of the cluster.
+func (s *RegisterInstanceStep) registerTarget(ctx context.Context, cfg *steps.Config) error {
+	svc, err := s.getTargetGroupService(cfg.AWSConfig)
+
+	if err != nil {
+		logrus.Errorf("error getting ELBv2 service %v", err)
+		return errors.Wrapf(ErrAuthorization, "%s error getting ELBv2 service %v",
+			RegisterInstanceStepName, err)
+	}
+
+	logrus.Infof("Register instance Name: %s ID: %s to target group: %s",
+		cfg.Node.Name, cfg.Node.ID, cfg.AWSConfig.TargetGroupARN)
+
+	if err := svc.RegisterInstance(ctx, cfg.AWSConfig.TargetGroupARN, cfg.Node.ID); err != nil {
+		logrus.Errorf("error registering instance %s to target group %s %v",
+			cfg.Node.ID, cfg.AWSConfig.TargetGroupARN, err)
+		return errors.Wrapf(err, "registering instance %s to target group %s",
+			cfg.Node.ID, cfg.AWSConfig.TargetGroupARN)
+	}
+
+	return nil
+}
+
 func (s *RegisterInstanceStep) Name() string {
 	return RegisterInstanceStepName
 }
@@ -7,15 +7,17 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/elb"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/supergiant/control/pkg/clouds/awssdk"
 	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/workflows/steps"
 )
 
 func TestInitRegisterInstance(t *testing.T) {
-	InitRegisterInstance(GetELB)
+	InitRegisterInstance(GetELB, GetELBv2)
 
 	s := steps.GetStep(RegisterInstanceStepName)
 
@@ -25,7 +27,7 @@ func TestInitRegisterInstance(t *testing.T) {
 }
 
 func TestNewRegisterInstanceStep(t *testing.T) {
-	step := NewRegisterInstanceStep(GetELB)
+	step := NewRegisterInstanceStep(GetELB, GetELBv2)
 
 	if step == nil {
 		t.Errorf("Step must not be nil")
@@ -46,7 +48,7 @@ func TestNewRegisterInstanceStepErr(t *testing.T) {
 		return nil, errors.New("errorMessage")
 	}
 
-	step := NewRegisterInstanceStep(fn)
+	step := NewRegisterInstanceStep(fn, GetELBv2)
 
 	if step == nil {
 		t.Errorf("Step must not be nil")
@@ -119,7 +121,10 @@ func TestRegisterInstanceStep_Run(t *testing.T) {
 			Kube: model.Kube{
 				ID: "1234",
 			},
-			AWSConfig: steps.AWSConfig{},
+			AWSConfig: steps.AWSConfig{
+				ExternalLoadBalancerName: "external",
+				InternalLoadBalancerName: "internal",
+			},
 		}
 
 		err := step.Run(context.Background(), &bytes.Buffer{}, config)
@@ -137,6 +142,70 @@ func TestRegisterInstanceStep_Run(t *testing.T) {
 	}
 }
 
+func TestRegisterInstanceStepPrivate(t *testing.T) {
+	svc := new(mockELBService)
+	svc.On("RegisterInstancesWithLoadBalancerWithContext", mock.Anything,
+		mock.MatchedBy(func(input *elb.RegisterInstancesWithLoadBalancerInput) bool {
+			return aws.StringValue(input.LoadBalancerName) == "internal"
+		}), mock.Anything).Return(&elb.RegisterInstancesWithLoadBalancerOutput{}, nil).Once()
+
+	step := &RegisterInstanceStep{
+		getLoadBalancerService: func(cfg steps.AWSConfig) (LoadBalancerRegister, error) {
+			return svc, nil
+		},
+	}
+
+	config := &steps.Config{
+		Kube: model.Kube{
+			ID:                "1234",
+			PrivateNetworking: true,
+		},
+		AWSConfig: steps.AWSConfig{
+			InternalLoadBalancerName: "internal",
+		},
+	}
+
+	if err := step.Run(context.Background(), &bytes.Buffer{}, config); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	svc.AssertExpectations(t)
+}
+
+func TestRegisterInstanceStepTargetGroup(t *testing.T) {
+	svc := new(mockELBService)
+	targetGroupSvc := &fakeTargetGroupService{}
+
+	step := &RegisterInstanceStep{
+		getLoadBalancerService: func(cfg steps.AWSConfig) (LoadBalancerRegister, error) {
+			return svc, nil
+		},
+		getTargetGroupService: func(steps.AWSConfig) (awssdk.TargetGroupService, error) {
+			return targetGroupSvc, nil
+		},
+	}
+
+	config := &steps.Config{
+		Node: model.Machine{
+			ID: "i-1234",
+		},
+		AWSConfig: steps.AWSConfig{
+			TargetGroupARN: "arn:tg",
+		},
+	}
+
+	if err := step.Run(context.Background(), &bytes.Buffer{}, config); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	if len(targetGroupSvc.registered) != 1 || targetGroupSvc.registered[0] != "i-1234" {
+		t.Errorf("Wrong registered instances %v", targetGroupSvc.registered)
+	}
+
+	svc.AssertNotCalled(t, "RegisterInstancesWithLoadBalancerWithContext",
+		mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestRegisterInstanceStep_Rollback(t *testing.T) {
 	step := &RegisterInstanceStep{}
 
@@ -0,0 +1,202 @@
+package amazon
+
+import (
+	"context"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const (
+	RegisterSpotMachinesStepName = "aws_register_spot_machines"
+
+	// SpotInterruptionReason is state reason of spot instance
+	// terminated by EC2.
+	SpotInterruptionReason = "Server.SpotInstanceTermination"
+)
+
+type spotInstanceDescriber interface {
+	WaitUntilInstanceRunningWithContext(aws.Context, *ec2.DescribeInstancesInput, ...request.WaiterOption) error
+	DescribeInstancesWithContext(aws.Context, *ec2.DescribeInstancesInput, ...request.Option) (*ec2.DescribeInstancesOutput, error)
+}
+
+// RegisterSpotMachinesStep adds machines of spot requests to the
+// config nodes and reports them over node channel.
+type RegisterSpotMachinesStep struct {
+	getSvc func(steps.AWSConfig) (spotInstanceDescriber, error)
+}
+
+func InitRegisterSpotMachines(fn GetEC2Fn) {
+	steps.RegisterStep(RegisterSpotMachinesStepName, NewRegisterSpotMachines(fn))
+}
+
+func NewRegisterSpotMachines(fn GetEC2Fn) *RegisterSpotMachinesStep {
+	return &RegisterSpotMachinesStep{
+		getSvc: func(cfg steps.AWSConfig) (spotInstanceDescriber, error) {
+			EC2, err := fn(cfg)
+
+			if err != nil {
+				return nil, errors.Wrap(ErrAuthorization, err.Error())
+			}
+
+			return EC2, nil
+		},
+	}
+}
+
+func (s *RegisterSpotMachinesStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config) error {
+	log := util.GetLogger(w)
+	spotCfg := &cfg.SpotConfig
+
+	instanceIDs := make([]string, 0, len(spotCfg.Instances)+
+		len(spotCfg.OnDemandInstances)+len(spotCfg.FleetInstances))
+
+	for _, instanceID := range spotCfg.Instances {
+		instanceIDs = append(instanceIDs, instanceID)
+	}
+
+	for _, instanceID := range spotCfg.OnDemandInstances {
+		instanceIDs = append(instanceIDs, instanceID)
+	}
+
+	instanceIDs = append(instanceIDs, spotCfg.FleetInstances...)
+
+	if len(instanceIDs) == 0 {
+		log.Infof("[%s] - no spot instances to register", s.Name())
+		return nil
+	}
+
+	svc, err := s.getSvc(cfg.AWSConfig)
+
+	if err != nil {
+		logrus.Errorf("[%s] - error getting service %v", s.Name(), err)
+		return errors.Wrapf(err, "%s error getting service", s.Name())
+	}
+
+	input := &ec2.DescribeInstancesInput{
+		InstanceIds: aws.StringSlice(instanceIDs),
+	}
+
+	if err := svc.WaitUntilInstanceRunningWithContext(ctx, input); err != nil {
+		return errors.Wrapf(err, "wait instances %v running", instanceIDs)
+	}
+
+	out, err := svc.DescribeInstancesWithContext(ctx, input)
+
+	if err != nil {
+		return errors.Wrapf(err, "describe instances %v", instanceIDs)
+	}
+
+	for _, reservation := range out.Reservations {
+		for _, instance := range reservation.Instances {
+			machine := s.machineFromInstance(cfg, instance)
+
+			// Nodes are keyed by instance id, so re-run replaces the same machine
+			cfg.AddNode(machine)
+
+			if nodeChan := cfg.NodeChan(); nodeChan != nil {
+				nodeChan <- *machine
+			}
+
+			log.Infof("[%s] - machine %s has been registered", s.Name(), machine.Name)
+		}
+	}
+
+	return nil
+}
+
+func (s *RegisterSpotMachinesStep) machineFromInstance(cfg *steps.Config, instance *ec2.Instance) *model.Machine {
+	instanceID := aws.StringValue(instance.InstanceId)
+	machine := &model.Machine{
+		ID:        instanceID,
+		Name:      cfg.SpotConfig.Names[instanceID],
+		TaskID:    cfg.TaskID,
+		Role:      model.RoleNode,
+		Size:      aws.StringValue(instance.InstanceType),
+		Region:    cfg.AWSConfig.Region,
+		Provider:  clouds.AWS,
+		State:     model.MachineStateActive,
+		PublicIp:  aws.StringValue(instance.PublicIpAddress),
+		PrivateIp: aws.StringValue(instance.PrivateIpAddress),
+		Pool:      cfg.Pool,
+	}
+
+	if instance.LaunchTime != nil {
+		machine.CreatedAt = instance.LaunchTime.Unix()
+	}
+
+	if instance.Placement != nil {
+		machine.AvailabilityZone = aws.StringValue(instance.Placement.AvailabilityZone)
+	}
+
+	machine.SpotInfo = SpotInfo(instance, cfg.SpotConfig.SpotPrice)
+
+	// On-demand instances are named by create instance step
+	for _, tag := range instance.Tags {
+		if aws.StringValue(tag.Key) == clouds.TagNodeName && machine.Name == "" {
+			machine.Name = aws.StringValue(tag.Value)
+		}
+	}
+
+	if machine.Name == "" {
+		machine.Name = instanceID
+	}
+
+	return machine
+}
+
+// SpotInfo returns spot request details of instance, it is nil
+// for on-demand instances.
+func SpotInfo(instance *ec2.Instance, bidPrice string) *model.SpotInfo {
+	if aws.StringValue(instance.InstanceLifecycle) != ec2.InstanceLifecycleTypeSpot {
+		return nil
+	}
+
+	info := &model.SpotInfo{
+		RequestID:   aws.StringValue(instance.SpotInstanceRequestId),
+		BidPrice:    bidPrice,
+		Interrupted: IsSpotInterrupted(instance),
+	}
+
+	if instance.Placement != nil {
+		info.AvailabilityZone = aws.StringValue(instance.Placement.AvailabilityZone)
+	}
+
+	if instance.LaunchTime != nil {
+		info.FulfilledAt = instance.LaunchTime.Unix()
+	}
+
+	return info
+}
+
+// IsSpotInterrupted returns true for spot instance that was
+// reclaimed by EC2.
+func IsSpotInterrupted(instance *ec2.Instance) bool {
+	return instance.StateReason != nil &&
+		aws.StringValue(instance.StateReason.Code) == SpotInterruptionReason
+}
+
+func (*RegisterSpotMachinesStep) Name() string {
+	return RegisterSpotMachinesStepName
+}
+
+func (*RegisterSpotMachinesStep) Depends() []string {
+	return nil
+}
+
+func (*RegisterSpotMachinesStep) Description() string {
+	return "Register spot machines in the cluster"
+}
+
+func (*RegisterSpotMachinesStep) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
@@ -0,0 +1,251 @@
+package amazon
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type mockSpotInstanceDescriber struct {
+	mock.Mock
+}
+
+func (m *mockSpotInstanceDescriber) WaitUntilInstanceRunningWithContext(ctx aws.Context,
+	req *ec2.DescribeInstancesInput, opts ...request.WaiterOption) error {
+	args := m.Called(ctx, req, opts)
+	return args.Error(0)
+}
+
+func (m *mockSpotInstanceDescriber) DescribeInstancesWithContext(ctx aws.Context,
+	req *ec2.DescribeInstancesInput, opts ...request.Option) (*ec2.DescribeInstancesOutput, error) {
+	args := m.Called(ctx, req, opts)
+	val, ok := args.Get(0).(*ec2.DescribeInstancesOutput)
+	if !ok {
+		return nil, args.Error(1)
+	}
+	return val, args.Error(1)
+}
+
+func TestRegisterSpotMachinesStep_Run(t *testing.T) {
+	output := &ec2.DescribeInstancesOutput{
+		Reservations: []*ec2.Reservation{
+			{
+				Instances: []*ec2.Instance{
+					{
+						InstanceId:            aws.String("i-1"),
+						InstanceType:          aws.String("m4.large"),
+						PrivateIpAddress:      aws.String("10.0.0.1"),
+						PublicIpAddress:       aws.String("54.0.0.1"),
+						InstanceLifecycle:     aws.String(ec2.InstanceLifecycleTypeSpot),
+						SpotInstanceRequestId: aws.String("sir-1"),
+						Placement: &ec2.Placement{
+							AvailabilityZone: aws.String("us-east-1a"),
+						},
+					},
+					{
+						InstanceId:       aws.String("i-2"),
+						InstanceType:     aws.String("m4.large"),
+						PrivateIpAddress: aws.String("10.0.0.2"),
+						Tags: []*ec2.Tag{
+							{
+								Key:   aws.String(clouds.TagNodeName),
+								Value: aws.String("test-node-2222"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		description string
+
+		instances         map[string]string
+		onDemandInstances map[string]string
+		fleetInstances    []string
+		getSvcErr         error
+		waitErr           error
+		describeOutput    *ec2.DescribeInstancesOutput
+		describeErr       error
+
+		expectedNodes []string
+		errMsg        string
+	}{
+		{
+			description: "no instances",
+		},
+		{
+			description: "get service error",
+			instances:   map[string]string{"sir-1": "i-1"},
+			getSvcErr:   errors.New("message1"),
+			errMsg:      "message1",
+		},
+		{
+			description: "wait error",
+			instances:   map[string]string{"sir-1": "i-1"},
+			waitErr:     errors.New("message2"),
+			errMsg:      "message2",
+		},
+		{
+			description: "describe error",
+			instances:   map[string]string{"sir-1": "i-1"},
+			describeErr: errors.New("message3"),
+			errMsg:      "message3",
+		},
+		{
+			description:       "success",
+			instances:         map[string]string{"sir-1": "i-1"},
+			onDemandInstances: map[string]string{"sir-2": "i-2"},
+			describeOutput:    output,
+			expectedNodes:     []string{"test-node-1111", "test-node-2222"},
+		},
+		{
+			description:    "fleet instances",
+			fleetInstances: []string{"i-1", "i-2"},
+			describeOutput: output,
+			expectedNodes:  []string{"test-node-1111", "test-node-2222"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		svc := &mockSpotInstanceDescriber{}
+		svc.On("WaitUntilInstanceRunningWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(testCase.waitErr)
+		svc.On("DescribeInstancesWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(testCase.describeOutput,
+			testCase.describeErr)
+
+		nodeChan := make(chan model.Machine, 2)
+		config := &steps.Config{
+			Nodes: steps.NewMap(make(map[string]*model.Machine)),
+			SpotConfig: steps.SpotConfig{
+				Instances:         testCase.instances,
+				OnDemandInstances: testCase.onDemandInstances,
+				FleetInstances:    testCase.fleetInstances,
+				Names:             map[string]string{"i-1": "test-node-1111"},
+				SpotPrice:         "0.05",
+			},
+		}
+		config.SetNodeChan(nodeChan)
+
+		step := RegisterSpotMachinesStep{
+			getSvc: func(steps.AWSConfig) (spotInstanceDescriber, error) {
+				return svc, testCase.getSvcErr
+			},
+		}
+
+		err := step.Run(context.Background(), &bytes.Buffer{}, config)
+
+		if err == nil && testCase.errMsg != "" {
+			t.Errorf("Error must not be nil")
+		}
+
+		if err != nil && !strings.Contains(err.Error(), testCase.errMsg) {
+			t.Errorf("Error message %s does not contain %s",
+				err.Error(), testCase.errMsg)
+		}
+
+		nodes := config.GetNodes()
+
+		if len(nodes) != len(testCase.expectedNodes) {
+			t.Errorf("Wrong node count expected %d actual %d",
+				len(testCase.expectedNodes), len(nodes))
+		}
+
+		if len(nodeChan) != len(testCase.expectedNodes) {
+			t.Errorf("Wrong count of machine updates expected %d actual %d",
+				len(testCase.expectedNodes), len(nodeChan))
+		}
+
+		for _, name := range testCase.expectedNodes {
+			if nodes[name] == nil {
+				t.Errorf("Node %s not found in %v", name, nodes)
+			}
+		}
+
+		if len(testCase.expectedNodes) == 0 {
+			continue
+		}
+
+		spotInfo := nodes["test-node-1111"].SpotInfo
+
+		if spotInfo == nil || spotInfo.RequestID != "sir-1" || spotInfo.BidPrice != "0.05" ||
+			spotInfo.AvailabilityZone != "us-east-1a" {
+			t.Errorf("Wrong spot info %v", spotInfo)
+		}
+
+		if nodes["test-node-2222"].SpotInfo != nil {
+			t.Errorf("On-demand machine must not have spot info")
+		}
+	}
+}
+
+func TestIsSpotInterrupted(t *testing.T) {
+	instance := &ec2.Instance{
+		InstanceLifecycle: aws.String(ec2.InstanceLifecycleTypeSpot),
+		StateReason: &ec2.StateReason{
+			Code: aws.String(SpotInterruptionReason),
+		},
+	}
+
+	if !IsSpotInterrupted(instance) {
+		t.Errorf("Instance must be interrupted")
+	}
+
+	if info := SpotInfo(instance, ""); info == nil || !info.Interrupted {
+		t.Errorf("Wrong spot info %v", info)
+	}
+
+	if IsSpotInterrupted(&ec2.Instance{}) {
+		t.Errorf("Instance without state reason must not be interrupted")
+	}
+}
+
+func TestNewRegisterSpotMachinesErr(t *testing.T) {
+	fn := func(steps.AWSConfig) (ec2iface.EC2API, error) {
+		return nil, errors.New("errorMessage")
+	}
+
+	s := NewRegisterSpotMachines(fn)
+
+	if s == nil {
+		t.Error("Step must not be nil")
+	}
+
+	if api, err := s.getSvc(steps.AWSConfig{}); err == nil || api != nil {
+		t.Errorf("Unexpected values %v %v", api, err)
+	}
+}
+
+func TestInitRegisterSpotMachines(t *testing.T) {
+	InitRegisterSpotMachines(GetEC2)
+
+	s := steps.GetStep(RegisterSpotMachinesStepName)
+
+	if s == nil {
+		t.Errorf("Step must not be nil")
+	}
+}
+
+func TestRegisterSpotMachinesStep_Name(t *testing.T) {
+	s := &RegisterSpotMachinesStep{}
+
+	if name := s.Name(); name != RegisterSpotMachinesStepName {
+		t.Errorf("Wrong name expected %s actual %s",
+			RegisterSpotMachinesStepName, name)
+	}
+}
@@ -0,0 +1,211 @@
+package amazon
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const (
+	RequestSpotInstancesStepName = "aws_request_spot_instances"
+
+	// ErrCodeDryRunOperation is returned by AWS when dry run request
+	// would have succeeded.
+	ErrCodeDryRunOperation = "DryRunOperation"
+)
+
+type spotRequester interface {
+	RequestSpotInstancesWithContext(aws.Context, *ec2.RequestSpotInstancesInput, ...request.Option) (*ec2.RequestSpotInstancesOutput, error)
+}
+
+// RequestSpotInstancesStep submits persistent spot instance request
+// for the machines described by spot config.
+type RequestSpotInstancesStep struct {
+	getSvc func(steps.AWSConfig) (spotRequester, error)
+}
+
+func InitRequestSpotInstances(fn GetEC2Fn) {
+	steps.RegisterStep(RequestSpotInstancesStepName, NewRequestSpotInstances(fn))
+}
+
+func NewRequestSpotInstances(fn GetEC2Fn) *RequestSpotInstancesStep {
+	return &RequestSpotInstancesStep{
+		getSvc: func(cfg steps.AWSConfig) (spotRequester, error) {
+			EC2, err := fn(cfg)
+
+			if err != nil {
+				return nil, errors.Wrap(ErrAuthorization, err.Error())
+			}
+
+			return EC2, nil
+		},
+	}
+}
+
+func (s *RequestSpotInstancesStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config) error {
+	log := util.GetLogger(w)
+
+	spotCfg := &cfg.SpotConfig
+
+	if spotCfg.ZoneRequests == nil {
+		spotCfg.ZoneRequests = make(map[string][]string)
+	}
+
+	zones := make([]string, 0, len(spotCfg.Zones))
+
+	for zone := range spotCfg.Zones {
+		// Requests have been submitted before restart
+		if _, ok := spotCfg.ZoneRequests[zone]; ok {
+			log.Infof("[%s] - spot requests %v in %s already exist", s.Name(),
+				spotCfg.ZoneRequests[zone], zone)
+			continue
+		}
+
+		zones = append(zones, zone)
+	}
+
+	if len(zones) == 0 {
+		return nil
+	}
+
+	sort.Strings(zones)
+
+	svc, err := s.getSvc(cfg.AWSConfig)
+
+	if err != nil {
+		logrus.Errorf("[%s] - error getting service %v", s.Name(), err)
+		return errors.Wrapf(err, "%s error getting service", s.Name())
+	}
+
+	ebs, err := ebsBlockDevice(cfg.AWSConfig, false)
+
+	if err != nil {
+		return errors.Wrap(err, "root volume settings")
+	}
+
+	for _, zone := range zones {
+		input := &ec2.RequestSpotInstancesInput{
+			Type: aws.String(ec2.SpotInstanceTypePersistent),
+			LaunchSpecification: &ec2.RequestSpotLaunchSpecification{
+				IamInstanceProfile: &ec2.IamInstanceProfileSpecification{
+					Name: aws.String(cfg.AWSConfig.NodesInstanceProfile),
+				},
+				SubnetId:         aws.String(cfg.AWSConfig.Subnets[zone]),
+				SecurityGroupIds: []*string{aws.String(cfg.AWSConfig.NodesSecurityGroupID)},
+				ImageId:          aws.String(cfg.AWSConfig.ImageID),
+				InstanceType:     aws.String(cfg.AWSConfig.InstanceType),
+				KeyName:          aws.String(cfg.AWSConfig.KeyPairName),
+				BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+					{
+						DeviceName: aws.String("/dev/sda1"),
+						Ebs:        ebs,
+					},
+				},
+				UserData: aws.String(base64.StdEncoding.EncodeToString(
+					[]byte(cfg.AWSConfig.UserData))),
+			},
+			SpotPrice: aws.String(spotCfg.SpotPrice),
+			// Client token makes request idempotent, so AWS returns the same
+			// requests if the step is re-run before ids were saved.
+			ClientToken:   aws.String(fmt.Sprintf("%s-%s", cfg.TaskID, zone)),
+			InstanceCount: aws.Int64(spotCfg.Zones[zone]),
+			DryRun:        aws.Bool(cfg.DryRun),
+			ValidFrom:     aws.Time(time.Now().Add(time.Second * 10)),
+			ValidUntil:    spotCfg.ValidUntil,
+		}
+
+		var result *ec2.RequestSpotInstancesOutput
+		err := util.Retry(ctx, RetryPolicy, IsRetryableErr, "request spot instances", func() error {
+			var err error
+			result, err = svc.RequestSpotInstancesWithContext(ctx, input)
+			return err
+		})
+
+		if isDryRunErr(err) {
+			spotCfg.DryRunResults = append(spotCfg.DryRunResults, steps.SpotDryRunResult{
+				AvailabilityZone: zone,
+				InstanceType:     cfg.AWSConfig.InstanceType,
+				SubnetID:         cfg.AWSConfig.Subnets[zone],
+				ImageID:          cfg.AWSConfig.ImageID,
+				SpotPrice:        spotCfg.SpotPrice,
+				InstanceCount:    spotCfg.Zones[zone],
+			})
+			log.Infof("[%s] - dry run of spot request for %d %s in %s succeeded",
+				s.Name(), spotCfg.Zones[zone], cfg.AWSConfig.InstanceType, zone)
+			continue
+		}
+
+		if err != nil {
+			logrus.Errorf("[%s] - request spot instances in %s %v", s.Name(), zone, err)
+			return errors.Wrapf(err, "request spot instances in %s", zone)
+		}
+
+		requestIDs := make([]string, 0, len(result.SpotInstanceRequests))
+
+		for _, spotRequest := range result.SpotInstanceRequests {
+			requestIDs = append(requestIDs, aws.StringValue(spotRequest.SpotInstanceRequestId))
+		}
+
+		spotCfg.ZoneRequests[zone] = requestIDs
+		spotCfg.RequestIDs = append(spotCfg.RequestIDs, requestIDs...)
+
+		// Requests are saved to the kube before anything else can fail,
+		// so they are cancelled with the kube.
+		if spotRequestsChan := cfg.SpotRequestsChan(); spotRequestsChan != nil {
+			spotRequestsChan <- requestIDs
+		}
+		log.Infof("[%s] - spot requests %v have been created in %s", s.Name(),
+			requestIDs, zone)
+	}
+
+	// Nothing has been requested, so the request is not open
+	if cfg.DryRun {
+		return nil
+	}
+
+	spotCfg.State = model.SpotRequestOpen
+
+	return nil
+}
+
+// isDryRunErr reports whether AWS would have succeeded the request
+// if it was not a dry run.
+func isDryRunErr(err error) bool {
+	awsErr, ok := errors.Cause(err).(awserr.Error)
+	return ok && awsErr.Code() == ErrCodeDryRunOperation
+}
+
+// RetryPolicy opts out of retries, spot requests would be submitted twice.
+func (*RequestSpotInstancesStep) RetryPolicy() steps.RetryPolicy {
+	return steps.NoRetry
+}
+
+func (*RequestSpotInstancesStep) Name() string {
+	return RequestSpotInstancesStepName
+}
+
+func (*RequestSpotInstancesStep) Depends() []string {
+	return nil
+}
+
+func (*RequestSpotInstancesStep) Description() string {
+	return "Request spot instances"
+}
+
+func (*RequestSpotInstancesStep) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
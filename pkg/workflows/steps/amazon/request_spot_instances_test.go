@@ -0,0 +1,257 @@
+package amazon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type mockSpotRequester struct {
+	mock.Mock
+}
+
+func (m *mockSpotRequester) RequestSpotInstancesWithContext(ctx aws.Context,
+	req *ec2.RequestSpotInstancesInput, opts ...request.Option) (*ec2.RequestSpotInstancesOutput, error) {
+	args := m.Called(ctx, req, opts)
+	val, ok := args.Get(0).(*ec2.RequestSpotInstancesOutput)
+	if !ok {
+		return nil, args.Error(1)
+	}
+	return val, args.Error(1)
+}
+
+func TestRequestSpotInstancesStep_Run(t *testing.T) {
+	testCases := []struct {
+		description string
+
+		zones        map[string]int64
+		zoneRequests map[string][]string
+		volumeSize   string
+		dryRun       bool
+		getSvcErr    error
+
+		requestOutput *ec2.RequestSpotInstancesOutput
+		requestErr    error
+
+		requestCalls  int
+		expectedIDs   []string
+		dryRunResults int
+		errMsg        string
+	}{
+		{
+			description:  "already requested",
+			zones:        map[string]int64{"us-east-1a": 1},
+			zoneRequests: map[string][]string{"us-east-1a": {"sir-1"}},
+			requestCalls: 0,
+		},
+		{
+			description: "get service error",
+			zones:       map[string]int64{"us-east-1a": 1},
+			getSvcErr:   errors.New("message1"),
+			errMsg:      "message1",
+		},
+		{
+			description: "wrong volume size",
+			zones:       map[string]int64{"us-east-1a": 1},
+			volumeSize:  "size",
+			errMsg:      "volume size",
+		},
+		{
+			description:  "request error",
+			zones:        map[string]int64{"us-east-1a": 1},
+			volumeSize:   "20",
+			requestErr:   errors.New("message2"),
+			requestCalls: 1,
+			errMsg:       "message2",
+		},
+		{
+			description: "success",
+			zones:       map[string]int64{"us-east-1a": 2},
+			volumeSize:  "20",
+			requestOutput: &ec2.RequestSpotInstancesOutput{
+				SpotInstanceRequests: []*ec2.SpotInstanceRequest{
+					{
+						SpotInstanceRequestId: aws.String("sir-1"),
+					},
+					{
+						SpotInstanceRequestId: aws.String("sir-2"),
+					},
+				},
+			},
+			requestCalls: 1,
+			expectedIDs:  []string{"sir-1", "sir-2"},
+		},
+		{
+			description: "request per zone",
+			zones: map[string]int64{
+				"us-east-1a": 1,
+				"us-east-1b": 1,
+			},
+			zoneRequests: map[string][]string{"us-east-1b": {"sir-0"}},
+			volumeSize:   "20",
+			requestOutput: &ec2.RequestSpotInstancesOutput{
+				SpotInstanceRequests: []*ec2.SpotInstanceRequest{
+					{
+						SpotInstanceRequestId: aws.String("sir-1"),
+					},
+				},
+			},
+			requestCalls: 1,
+			expectedIDs:  []string{"sir-1"},
+		},
+		{
+			description:   "dry run",
+			zones:         map[string]int64{"us-east-1a": 2},
+			volumeSize:    "20",
+			dryRun:        true,
+			requestErr:    awserr.New(ErrCodeDryRunOperation, "Request would have succeeded", nil),
+			requestCalls:  1,
+			dryRunResults: 1,
+		},
+		{
+			description:  "dry run unauthorized",
+			zones:        map[string]int64{"us-east-1a": 2},
+			volumeSize:   "20",
+			dryRun:       true,
+			requestErr:   awserr.New("UnauthorizedOperation", "not authorized", nil),
+			requestCalls: 1,
+			errMsg:       "not authorized",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		svc := &mockSpotRequester{}
+		svc.On("RequestSpotInstancesWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(testCase.requestOutput,
+			testCase.requestErr)
+
+		config := &steps.Config{
+			TaskID: "task-id",
+			DryRun: testCase.dryRun,
+			AWSConfig: steps.AWSConfig{
+				VolumeSize: testCase.volumeSize,
+				Subnets: map[string]string{
+					"us-east-1a": "subnet-a",
+					"us-east-1b": "subnet-b",
+				},
+			},
+			SpotConfig: steps.SpotConfig{
+				Zones:        testCase.zones,
+				ZoneRequests: testCase.zoneRequests,
+			},
+		}
+		spotRequests := make(chan []string, len(testCase.zones))
+		config.SetSpotRequestsChan(spotRequests)
+		step := RequestSpotInstancesStep{
+			getSvc: func(steps.AWSConfig) (spotRequester, error) {
+				return svc, testCase.getSvcErr
+			},
+		}
+
+		err := step.Run(context.Background(), &bytes.Buffer{}, config)
+		close(spotRequests)
+
+		if err == nil && testCase.errMsg != "" {
+			t.Errorf("Error must not be nil")
+		}
+
+		if err != nil && !strings.Contains(err.Error(), testCase.errMsg) {
+			t.Errorf("Error message %s does not contain %s",
+				err.Error(), testCase.errMsg)
+		}
+
+		svc.AssertNumberOfCalls(t, "RequestSpotInstancesWithContext",
+			testCase.requestCalls)
+
+		if len(config.SpotConfig.RequestIDs) != len(testCase.expectedIDs) {
+			t.Errorf("Wrong request ids expected %v actual %v",
+				testCase.expectedIDs, config.SpotConfig.RequestIDs)
+		}
+
+		// Requests are sent as soon as they are submitted
+		sent := make([]string, 0, len(testCase.expectedIDs))
+		for requestIDs := range spotRequests {
+			sent = append(sent, requestIDs...)
+		}
+
+		if fmt.Sprint(sent) != fmt.Sprint(testCase.expectedIDs) {
+			t.Errorf("Wrong sent request ids expected %v actual %v",
+				testCase.expectedIDs, sent)
+		}
+
+		if len(config.SpotConfig.DryRunResults) != testCase.dryRunResults {
+			t.Errorf("Wrong count of dry run results expected %d actual %d",
+				testCase.dryRunResults, len(config.SpotConfig.DryRunResults))
+		}
+
+		if testCase.dryRun && config.SpotConfig.State != "" {
+			t.Errorf("Dry run must not open spot request %s", config.SpotConfig.State)
+		}
+
+		for _, result := range config.SpotConfig.DryRunResults {
+			if result.SubnetID != "subnet-a" || result.InstanceCount != 2 {
+				t.Errorf("Wrong dry run result %v", result)
+			}
+		}
+
+		for _, call := range svc.Calls {
+			input := call.Arguments.Get(1).(*ec2.RequestSpotInstancesInput)
+
+			if token := aws.StringValue(input.ClientToken); token != "task-id-us-east-1a" {
+				t.Errorf("Wrong client token expected task-id-us-east-1a actual %s", token)
+			}
+
+			if subnet := aws.StringValue(input.LaunchSpecification.SubnetId); subnet != "subnet-a" {
+				t.Errorf("Wrong subnet expected subnet-a actual %s", subnet)
+			}
+		}
+	}
+}
+
+func TestNewRequestSpotInstancesErr(t *testing.T) {
+	fn := func(steps.AWSConfig) (ec2iface.EC2API, error) {
+		return nil, errors.New("errorMessage")
+	}
+
+	s := NewRequestSpotInstances(fn)
+
+	if s == nil {
+		t.Error("Step must not be nil")
+	}
+
+	if api, err := s.getSvc(steps.AWSConfig{}); err == nil || api != nil {
+		t.Errorf("Unexpected values %v %v", api, err)
+	}
+}
+
+func TestInitRequestSpotInstances(t *testing.T) {
+	InitRequestSpotInstances(GetEC2)
+
+	s := steps.GetStep(RequestSpotInstancesStepName)
+
+	if s == nil {
+		t.Errorf("Step must not be nil")
+	}
+}
+
+func TestRequestSpotInstancesStep_Name(t *testing.T) {
+	s := &RequestSpotInstancesStep{}
+
+	if name := s.Name(); name != RequestSpotInstancesStepName {
+		t.Errorf("Wrong name expected %s actual %s",
+			RequestSpotInstancesStepName, name)
+	}
+}
@@ -0,0 +1,180 @@
+package amazon
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const (
+	protocolAll = "-1"
+
+	errCodeDuplicatePermission = "InvalidPermission.Duplicate"
+)
+
+// SecurityGroupService checks and authorizes security group rules.
+type SecurityGroupService interface {
+	DescribeSecurityGroupsWithContext(aws.Context, *ec2.DescribeSecurityGroupsInput, ...request.Option) (*ec2.DescribeSecurityGroupsOutput, error)
+	AuthorizeSecurityGroupIngressWithContext(aws.Context, *ec2.AuthorizeSecurityGroupIngressInput, ...request.Option) (*ec2.AuthorizeSecurityGroupIngressOutput, error)
+}
+
+// RequiredSecurityGroupRules returns rules authorized by CreateSecurityGroupsStep.
+// Address of control whitelisted for API server port is not included,
+// since it is discovered at the time of provisioning.
+func RequiredSecurityGroupRules(cfg steps.AWSConfig, exposed []profile.Addresses,
+	apiServerPort int64) []model.SecurityGroupRule {
+	groups := []string{cfg.MastersSecurityGroupID, cfg.NodesSecurityGroupID}
+	rules := make([]model.SecurityGroupRule, 0)
+
+	for _, groupID := range groups {
+		rules = append(rules, model.SecurityGroupRule{
+			GroupID:  groupID,
+			Protocol: "tcp",
+			FromPort: 22,
+			ToPort:   22,
+			CIDR:     "0.0.0.0/0",
+		})
+
+		for _, sourceGroupID := range groups {
+			rules = append(rules, model.SecurityGroupRule{
+				GroupID:       groupID,
+				Protocol:      protocolAll,
+				SourceGroupID: sourceGroupID,
+			})
+		}
+	}
+
+	for _, addr := range exposed {
+		rules = append(rules, model.SecurityGroupRule{
+			GroupID:  cfg.MastersSecurityGroupID,
+			Protocol: "tcp",
+			FromPort: apiServerPort,
+			ToPort:   apiServerPort,
+			CIDR:     addr.CIDR,
+		})
+	}
+
+	return rules
+}
+
+// MissingSecurityGroupRules returns required rules which are not covered by
+// permissions of security groups, other permissions are ignored.
+func MissingSecurityGroupRules(ctx context.Context, svc SecurityGroupService,
+	required []model.SecurityGroupRule) ([]model.SecurityGroupRule, error) {
+	groupIDs := make([]*string, 0)
+	seen := make(map[string]bool)
+
+	for _, rule := range required {
+		if !seen[rule.GroupID] {
+			seen[rule.GroupID] = true
+			groupIDs = append(groupIDs, aws.String(rule.GroupID))
+		}
+	}
+
+	missing := make([]model.SecurityGroupRule, 0)
+
+	if len(groupIDs) == 0 {
+		return missing, nil
+	}
+
+	out, err := svc.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{
+		GroupIds: groupIDs,
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "describe security groups")
+	}
+
+	permissions := make(map[string][]*ec2.IpPermission)
+
+	for _, group := range out.SecurityGroups {
+		permissions[aws.StringValue(group.GroupId)] = group.IpPermissions
+	}
+
+	for _, rule := range required {
+		if !isRuleCovered(rule, permissions[rule.GroupID]) {
+			missing = append(missing, rule)
+		}
+	}
+
+	return missing, nil
+}
+
+// AuthorizeSecurityGroupRules adds ingress rules to security groups.
+func AuthorizeSecurityGroupRules(ctx context.Context, svc SecurityGroupService,
+	rules []model.SecurityGroupRule) error {
+	for _, rule := range rules {
+		permission := &ec2.IpPermission{
+			IpProtocol: aws.String(rule.Protocol),
+			FromPort:   aws.Int64(rule.FromPort),
+			ToPort:     aws.Int64(rule.ToPort),
+		}
+
+		if rule.SourceGroupID != "" {
+			permission.UserIdGroupPairs = []*ec2.UserIdGroupPair{
+				{GroupId: aws.String(rule.SourceGroupID)},
+			}
+		} else {
+			permission.IpRanges = []*ec2.IpRange{
+				{CidrIp: aws.String(rule.CIDR)},
+			}
+		}
+
+		_, err := svc.AuthorizeSecurityGroupIngressWithContext(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+			GroupId:       aws.String(rule.GroupID),
+			IpPermissions: []*ec2.IpPermission{permission},
+		})
+
+		if err != nil {
+			if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == errCodeDuplicatePermission {
+				continue
+			}
+
+			return errors.Wrapf(err, "authorize %s %d-%d of security group %s",
+				rule.Protocol, rule.FromPort, rule.ToPort, rule.GroupID)
+		}
+	}
+
+	return nil
+}
+
+// isRuleCovered returns true when any permission allows traffic of the rule,
+// e.g. user has widened the port range.
+func isRuleCovered(rule model.SecurityGroupRule, permissions []*ec2.IpPermission) bool {
+	for _, permission := range permissions {
+		protocol := aws.StringValue(permission.IpProtocol)
+
+		if protocol != protocolAll {
+			if protocol != rule.Protocol || rule.Protocol == protocolAll ||
+				aws.Int64Value(permission.FromPort) > rule.FromPort ||
+				aws.Int64Value(permission.ToPort) < rule.ToPort {
+				continue
+			}
+		}
+
+		if rule.SourceGroupID != "" {
+			for _, pair := range permission.UserIdGroupPairs {
+				if aws.StringValue(pair.GroupId) == rule.SourceGroupID {
+					return true
+				}
+			}
+			continue
+		}
+
+		for _, ipRange := range permission.IpRanges {
+			if aws.StringValue(ipRange.CidrIp) == rule.CIDR {
+				return true
+			}
+		}
+	}
+
+	return false
+}
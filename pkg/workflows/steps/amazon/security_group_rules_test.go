@@ -0,0 +1,229 @@
+package amazon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type fakeSecurityGroupService struct {
+	describeOut *ec2.DescribeSecurityGroupsOutput
+	describeErr error
+
+	authorized   []*ec2.AuthorizeSecurityGroupIngressInput
+	authorizeErr error
+}
+
+func (f *fakeSecurityGroupService) DescribeSecurityGroupsWithContext(aws.Context,
+	*ec2.DescribeSecurityGroupsInput, ...request.Option) (*ec2.DescribeSecurityGroupsOutput, error) {
+	return f.describeOut, f.describeErr
+}
+
+func (f *fakeSecurityGroupService) AuthorizeSecurityGroupIngressWithContext(ctx aws.Context,
+	input *ec2.AuthorizeSecurityGroupIngressInput, opts ...request.Option) (*ec2.AuthorizeSecurityGroupIngressOutput, error) {
+	f.authorized = append(f.authorized, input)
+	return &ec2.AuthorizeSecurityGroupIngressOutput{}, f.authorizeErr
+}
+
+func groupPermissions(groupID string, permissions ...*ec2.IpPermission) *ec2.SecurityGroup {
+	return &ec2.SecurityGroup{
+		GroupId:       aws.String(groupID),
+		IpPermissions: permissions,
+	}
+}
+
+func cidrPermission(protocol string, from, to int64, cidr string) *ec2.IpPermission {
+	return &ec2.IpPermission{
+		IpProtocol: aws.String(protocol),
+		FromPort:   aws.Int64(from),
+		ToPort:     aws.Int64(to),
+		IpRanges:   []*ec2.IpRange{{CidrIp: aws.String(cidr)}},
+	}
+}
+
+func groupPermission(sourceGroupIDs ...string) *ec2.IpPermission {
+	permission := &ec2.IpPermission{
+		IpProtocol: aws.String("-1"),
+	}
+
+	for _, groupID := range sourceGroupIDs {
+		permission.UserIdGroupPairs = append(permission.UserIdGroupPairs,
+			&ec2.UserIdGroupPair{GroupId: aws.String(groupID)})
+	}
+
+	return permission
+}
+
+func TestRequiredSecurityGroupRules(t *testing.T) {
+	rules := RequiredSecurityGroupRules(steps.AWSConfig{
+		MastersSecurityGroupID: "sg-masters",
+		NodesSecurityGroupID:   "sg-nodes",
+	}, []profile.Addresses{{CIDR: "10.0.0.0/8"}}, 443)
+
+	// ssh and traffic from both groups to each group and api server port
+	if len(rules) != 7 {
+		t.Fatalf("Wrong count of rules %d", len(rules))
+	}
+
+	api := rules[len(rules)-1]
+
+	if api.GroupID != "sg-masters" || api.FromPort != 443 || api.CIDR != "10.0.0.0/8" {
+		t.Errorf("Wrong api server rule %v", api)
+	}
+}
+
+func TestMissingSecurityGroupRules(t *testing.T) {
+	required := RequiredSecurityGroupRules(steps.AWSConfig{
+		MastersSecurityGroupID: "sg-masters",
+		NodesSecurityGroupID:   "sg-nodes",
+	}, nil, 443)
+
+	testCases := []struct {
+		description string
+		groups      []*ec2.SecurityGroup
+		describeErr error
+
+		expectedMissing []model.SecurityGroupRule
+		isErr           bool
+	}{
+		{
+			description: "no drift",
+			groups: []*ec2.SecurityGroup{
+				groupPermissions("sg-masters",
+					cidrPermission("tcp", 22, 22, "0.0.0.0/0"),
+					groupPermission("sg-masters", "sg-nodes")),
+				groupPermissions("sg-nodes",
+					cidrPermission("tcp", 22, 22, "0.0.0.0/0"),
+					groupPermission("sg-masters", "sg-nodes")),
+			},
+			expectedMissing: []model.SecurityGroupRule{},
+		},
+		{
+			description: "rules are removed, user rules are ignored",
+			groups: []*ec2.SecurityGroup{
+				groupPermissions("sg-masters",
+					cidrPermission("tcp", 22, 22, "0.0.0.0/0"),
+					cidrPermission("tcp", 8080, 8080, "1.2.3.4/32"),
+					groupPermission("sg-masters", "sg-nodes")),
+				groupPermissions("sg-nodes",
+					cidrPermission("tcp", 22, 22, "10.0.0.0/8"),
+					groupPermission("sg-nodes")),
+			},
+			expectedMissing: []model.SecurityGroupRule{
+				{GroupID: "sg-nodes", Protocol: "tcp", FromPort: 22, ToPort: 22, CIDR: "0.0.0.0/0"},
+				{GroupID: "sg-nodes", Protocol: "-1", SourceGroupID: "sg-masters"},
+			},
+		},
+		{
+			description: "wider rules cover required",
+			groups: []*ec2.SecurityGroup{
+				groupPermissions("sg-masters",
+					cidrPermission("tcp", 0, 65535, "0.0.0.0/0"),
+					groupPermission("sg-masters", "sg-nodes")),
+				groupPermissions("sg-nodes",
+					cidrPermission("-1", 0, 0, "0.0.0.0/0"),
+					groupPermission("sg-masters", "sg-nodes")),
+			},
+			expectedMissing: []model.SecurityGroupRule{},
+		},
+		{
+			description: "describe error",
+			describeErr: errors.New("error"),
+			isErr:       true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		svc := &fakeSecurityGroupService{
+			describeOut: &ec2.DescribeSecurityGroupsOutput{
+				SecurityGroups: testCase.groups,
+			},
+			describeErr: testCase.describeErr,
+		}
+
+		missing, err := MissingSecurityGroupRules(context.Background(), svc, required)
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if len(missing) != len(testCase.expectedMissing) {
+			t.Errorf("Wrong missing rules expected %v actual %v",
+				testCase.expectedMissing, missing)
+			continue
+		}
+
+		for i := range missing {
+			if missing[i] != testCase.expectedMissing[i] {
+				t.Errorf("Wrong missing rule expected %v actual %v",
+					testCase.expectedMissing[i], missing[i])
+			}
+		}
+	}
+}
+
+func TestAuthorizeSecurityGroupRules(t *testing.T) {
+	rules := []model.SecurityGroupRule{
+		{GroupID: "sg-nodes", Protocol: "tcp", FromPort: 22, ToPort: 22, CIDR: "0.0.0.0/0"},
+		{GroupID: "sg-nodes", Protocol: "-1", SourceGroupID: "sg-masters"},
+	}
+
+	testCases := []struct {
+		description  string
+		authorizeErr error
+		isErr        bool
+	}{
+		{
+			description: "success",
+		},
+		{
+			description:  "duplicate rule",
+			authorizeErr: awserr.New(errCodeDuplicatePermission, "duplicate", nil),
+		},
+		{
+			description:  "error",
+			authorizeErr: errors.New("error"),
+			isErr:        true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		svc := &fakeSecurityGroupService{
+			authorizeErr: testCase.authorizeErr,
+		}
+
+		err := AuthorizeSecurityGroupRules(context.Background(), svc, rules)
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if len(svc.authorized) != 2 {
+			t.Errorf("Wrong count of authorized rules %d", len(svc.authorized))
+			continue
+		}
+
+		pairs := svc.authorized[1].IpPermissions[0].UserIdGroupPairs
+
+		if len(pairs) != 1 || aws.StringValue(pairs[0].GroupId) != "sg-masters" {
+			t.Errorf("Wrong source group %v", svc.authorized[1])
+		}
+	}
+}
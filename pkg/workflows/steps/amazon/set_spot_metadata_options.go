@@ -0,0 +1,97 @@
+package amazon
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const SetSpotMetadataOptionsStepName = "aws_set_spot_metadata_options"
+
+// SetSpotMetadataOptionsStep sets instance metadata options of spot
+// instances once requests are fulfilled, since launch specification
+// of spot requests can not have them.
+type SetSpotMetadataOptionsStep struct {
+	getSvc func(steps.AWSConfig) (requestBuilder, error)
+}
+
+func InitSetSpotMetadataOptions(fn GetEC2Fn) {
+	steps.RegisterStep(SetSpotMetadataOptionsStepName, NewSetSpotMetadataOptions(fn))
+}
+
+func NewSetSpotMetadataOptions(fn GetEC2Fn) *SetSpotMetadataOptionsStep {
+	return &SetSpotMetadataOptionsStep{
+		getSvc: func(cfg steps.AWSConfig) (requestBuilder, error) {
+			EC2, err := fn(cfg)
+
+			if err != nil {
+				return nil, errors.Wrap(ErrAuthorization, err.Error())
+			}
+
+			svc, ok := EC2.(requestBuilder)
+
+			if !ok {
+				return nil, errors.New("EC2 client can not build requests")
+			}
+
+			return svc, nil
+		},
+	}
+}
+
+func (s *SetSpotMetadataOptionsStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config) error {
+	log := util.GetLogger(w)
+
+	if len(cfg.SpotConfig.Instances) == 0 {
+		log.Infof("[%s] - no spot instances", s.Name())
+		return nil
+	}
+
+	opts, err := newMetadataOptions(cfg.AWSConfig)
+
+	if err != nil {
+		return errors.Wrap(err, "instance metadata options")
+	}
+
+	svc, err := s.getSvc(cfg.AWSConfig)
+
+	if err != nil {
+		logrus.Errorf("[%s] - error getting service %v", s.Name(), err)
+		return errors.Wrapf(err, "%s error getting service", s.Name())
+	}
+
+	for _, instanceID := range cfg.SpotConfig.Instances {
+		err := util.Retry(ctx, RetryPolicy, IsRetryableErr, "modify instance metadata options", func() error {
+			return modifyInstanceMetadataOptions(ctx, svc, instanceID, opts)
+		})
+
+		if err != nil {
+			return errors.Wrapf(err, "set metadata options of spot instance %s", instanceID)
+		}
+
+		log.Infof("[%s] - metadata options of spot instance %s have been set", s.Name(), instanceID)
+	}
+
+	return nil
+}
+
+func (*SetSpotMetadataOptionsStep) Name() string {
+	return SetSpotMetadataOptionsStepName
+}
+
+func (*SetSpotMetadataOptionsStep) Depends() []string {
+	return nil
+}
+
+func (*SetSpotMetadataOptionsStep) Description() string {
+	return "Set instance metadata options of spot instances"
+}
+
+func (*SetSpotMetadataOptionsStep) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
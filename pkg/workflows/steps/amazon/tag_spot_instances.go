@@ -0,0 +1,181 @@
+package amazon
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const (
+	TagSpotInstancesStepName = "aws_tag_spot_instances"
+
+	tagRetryAttempts = 5
+	tagRetryDelay    = time.Second
+)
+
+type spotInstanceTagger interface {
+	CreateTagsWithContext(aws.Context, *ec2.CreateTagsInput, ...request.Option) (*ec2.CreateTagsOutput, error)
+}
+
+// TagSpotInstancesStep tags spot instances and their requests with
+// cluster tags, so they can be found by cluster id.
+type TagSpotInstancesStep struct {
+	getSvc func(steps.AWSConfig) (spotInstanceTagger, error)
+
+	// CreateTags is retried with exponential backoff when it is throttled
+	attempts   int
+	retryDelay time.Duration
+}
+
+func InitTagSpotInstances(fn GetEC2Fn) {
+	steps.RegisterStep(TagSpotInstancesStepName, NewTagSpotInstances(fn))
+}
+
+func NewTagSpotInstances(fn GetEC2Fn) *TagSpotInstancesStep {
+	return &TagSpotInstancesStep{
+		getSvc: func(cfg steps.AWSConfig) (spotInstanceTagger, error) {
+			EC2, err := fn(cfg)
+
+			if err != nil {
+				return nil, errors.Wrap(ErrAuthorization, err.Error())
+			}
+
+			return EC2, nil
+		},
+		attempts:   tagRetryAttempts,
+		retryDelay: tagRetryDelay,
+	}
+}
+
+func (s *TagSpotInstancesStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config) error {
+	log := util.GetLogger(w)
+	spotCfg := &cfg.SpotConfig
+
+	// Map of instance id to resources tagged along with it
+	resources := make(map[string][]string)
+
+	for requestID, instanceID := range spotCfg.Instances {
+		resources[instanceID] = []string{instanceID, requestID}
+	}
+
+	for _, instanceID := range spotCfg.FleetInstances {
+		resources[instanceID] = []string{instanceID}
+	}
+
+	if len(resources) == 0 {
+		log.Infof("[%s] - no spot instances to tag", s.Name())
+		return nil
+	}
+
+	svc, err := s.getSvc(cfg.AWSConfig)
+
+	if err != nil {
+		logrus.Errorf("[%s] - error getting service %v", s.Name(), err)
+		return errors.Wrapf(err, "%s error getting service", s.Name())
+	}
+
+	if spotCfg.Names == nil {
+		spotCfg.Names = make(map[string]string)
+	}
+
+	resourceIDs := make([]string, 0, len(resources)*2)
+
+	for _, ids := range resources {
+		resourceIDs = append(resourceIDs, ids...)
+	}
+
+	// Cluster tags are the same for all resources, instances are
+	// found by them in sync, so they are created in one call.
+	err = s.createTags(ctx, svc, &ec2.CreateTagsInput{
+		Resources: aws.StringSlice(resourceIDs),
+		Tags: ec2Tags(cfg.Tags,
+			&ec2.Tag{
+				Key:   aws.String("KubernetesCluster"),
+				Value: aws.String(cfg.Kube.Name),
+			},
+			&ec2.Tag{
+				Key:   aws.String(clouds.TagClusterID),
+				Value: aws.String(cfg.Kube.ID),
+			},
+			&ec2.Tag{
+				Key:   aws.String(clouds.TagRole),
+				Value: aws.String(util.MakeRole(cfg.IsMaster)),
+			},
+		),
+	})
+
+	if err != nil {
+		return errors.Wrapf(err, "tag spot resources %v", resourceIDs)
+	}
+
+	for instanceID, ids := range resources {
+		// Names are saved with the task, so re-run overwrites
+		// the tags with the same values.
+		name, ok := spotCfg.Names[instanceID]
+
+		if !ok {
+			name = util.MakeNodeName(cfg.Kube.Name, uuid.New(), cfg.IsMaster)
+			spotCfg.Names[instanceID] = name
+		}
+
+		err := s.createTags(ctx, svc, &ec2.CreateTagsInput{
+			Resources: aws.StringSlice(ids),
+			Tags: []*ec2.Tag{
+				{
+					Key:   aws.String(clouds.TagNodeName),
+					Value: aws.String(name),
+				},
+			},
+		})
+
+		if err != nil {
+			return errors.Wrapf(err, "tag spot instance %s", instanceID)
+		}
+
+		log.Infof("[%s] - spot instance %s has been tagged as %s",
+			s.Name(), instanceID, name)
+	}
+
+	return nil
+}
+
+// createTags retries CreateTags with exponential backoff while
+// AWS throttles requests.
+func (s *TagSpotInstancesStep) createTags(ctx context.Context, svc spotInstanceTagger,
+	input *ec2.CreateTagsInput) error {
+	policy := RetryPolicy
+	policy.Attempts = s.attempts
+	policy.Delay = s.retryDelay
+
+	return util.Retry(ctx, policy, IsRetryableErr, "create tags", func() error {
+		_, err := svc.CreateTagsWithContext(ctx, input)
+		return err
+	})
+}
+
+func (*TagSpotInstancesStep) Name() string {
+	return TagSpotInstancesStepName
+}
+
+func (*TagSpotInstancesStep) Depends() []string {
+	return nil
+}
+
+func (*TagSpotInstancesStep) Description() string {
+	return "Tag spot instances with cluster tags"
+}
+
+func (*TagSpotInstancesStep) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
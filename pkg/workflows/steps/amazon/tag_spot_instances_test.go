@@ -0,0 +1,241 @@
+package amazon
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type mockSpotInstanceTagger struct {
+	mock.Mock
+}
+
+func (m *mockSpotInstanceTagger) CreateTagsWithContext(ctx aws.Context,
+	req *ec2.CreateTagsInput, opts ...request.Option) (*ec2.CreateTagsOutput, error) {
+	args := m.Called(ctx, req, opts)
+	val, ok := args.Get(0).(*ec2.CreateTagsOutput)
+	if !ok {
+		return nil, args.Error(1)
+	}
+	return val, args.Error(1)
+}
+
+func TestTagSpotInstancesStep_Run(t *testing.T) {
+	testCases := []struct {
+		description string
+
+		instances      map[string]string
+		fleetInstances []string
+		names          map[string]string
+		getSvcErr      error
+		tagErr         error
+
+		tagCalls int
+		errMsg   string
+	}{
+		{
+			description: "no instances",
+		},
+		{
+			description: "get service error",
+			instances:   map[string]string{"sir-1": "i-1"},
+			getSvcErr:   errors.New("message1"),
+			errMsg:      "message1",
+		},
+		{
+			description: "tag error",
+			instances:   map[string]string{"sir-1": "i-1"},
+			tagErr:      errors.New("message2"),
+			tagCalls:    1,
+			errMsg:      "message2",
+		},
+		{
+			description: "success",
+			instances:   map[string]string{"sir-1": "i-1", "sir-2": "i-2"},
+			tagCalls:    3,
+		},
+		{
+			description: "name is kept on re-run",
+			instances:   map[string]string{"sir-1": "i-1"},
+			names:       map[string]string{"i-1": "test-node-abcd"},
+			tagCalls:    2,
+		},
+		{
+			description:    "fleet instances",
+			instances:      map[string]string{"sir-1": "i-1"},
+			fleetInstances: []string{"i-2", "i-3"},
+			tagCalls:       4,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		svc := &mockSpotInstanceTagger{}
+		svc.On("CreateTagsWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(nil, testCase.tagErr)
+
+		config := &steps.Config{
+			Kube: model.Kube{
+				ID:   "kube-id",
+				Name: "test",
+			},
+			SpotConfig: steps.SpotConfig{
+				Instances:      testCase.instances,
+				FleetInstances: testCase.fleetInstances,
+				Names:          testCase.names,
+			},
+		}
+		step := TagSpotInstancesStep{
+			getSvc: func(steps.AWSConfig) (spotInstanceTagger, error) {
+				return svc, testCase.getSvcErr
+			},
+		}
+
+		err := step.Run(context.Background(), &bytes.Buffer{}, config)
+
+		if err == nil && testCase.errMsg != "" {
+			t.Errorf("Error must not be nil")
+		}
+
+		if err != nil && !strings.Contains(err.Error(), testCase.errMsg) {
+			t.Errorf("Error message %s does not contain %s",
+				err.Error(), testCase.errMsg)
+		}
+
+		svc.AssertNumberOfCalls(t, "CreateTagsWithContext", testCase.tagCalls)
+
+		for _, call := range svc.Calls {
+			input := call.Arguments.Get(1).(*ec2.CreateTagsInput)
+			instanceID := aws.StringValue(input.Resources[0])
+
+			for _, tag := range input.Tags {
+				if aws.StringValue(tag.Key) == clouds.TagNodeName &&
+					aws.StringValue(tag.Value) != config.SpotConfig.Names[instanceID] {
+					t.Errorf("Wrong name of instance %s expected %s actual %s",
+						instanceID, config.SpotConfig.Names[instanceID],
+						aws.StringValue(tag.Value))
+				}
+			}
+		}
+
+		if testCase.names != nil && config.SpotConfig.Names["i-1"] != testCase.names["i-1"] {
+			t.Errorf("Name must not be changed expected %s actual %s",
+				testCase.names["i-1"], config.SpotConfig.Names["i-1"])
+		}
+	}
+}
+
+func TestTagSpotInstancesStep_Retry(t *testing.T) {
+	throttled := awserr.New("RequestLimitExceeded", "limit exceeded", nil)
+
+	testCases := []struct {
+		description string
+
+		errs     []error
+		tagCalls int
+		errMsg   string
+	}{
+		{
+			description: "retry throttled",
+			errs:        []error{throttled, throttled, nil, nil},
+			tagCalls:    4,
+		},
+		{
+			description: "retries exceeded",
+			errs:        []error{throttled, throttled, throttled},
+			tagCalls:    3,
+			errMsg:      "RequestLimitExceeded",
+		},
+		{
+			description: "not throttled",
+			errs:        []error{awserr.New("InvalidID", "invalid id", nil)},
+			tagCalls:    1,
+			errMsg:      "InvalidID",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		svc := &mockSpotInstanceTagger{}
+
+		for _, err := range testCase.errs {
+			svc.On("CreateTagsWithContext", mock.Anything,
+				mock.Anything, mock.Anything).Return(nil, err).Once()
+		}
+
+		config := &steps.Config{
+			SpotConfig: steps.SpotConfig{
+				Instances: map[string]string{"sir-1": "i-1"},
+			},
+		}
+		step := TagSpotInstancesStep{
+			getSvc: func(steps.AWSConfig) (spotInstanceTagger, error) {
+				return svc, nil
+			},
+			attempts:   3,
+			retryDelay: time.Millisecond,
+		}
+
+		err := step.Run(context.Background(), &bytes.Buffer{}, config)
+
+		if err == nil && testCase.errMsg != "" {
+			t.Errorf("Error must not be nil")
+		}
+
+		if err != nil && !strings.Contains(err.Error(), testCase.errMsg) {
+			t.Errorf("Error message %s does not contain %s",
+				err.Error(), testCase.errMsg)
+		}
+
+		svc.AssertNumberOfCalls(t, "CreateTagsWithContext", testCase.tagCalls)
+	}
+}
+
+func TestNewTagSpotInstancesErr(t *testing.T) {
+	fn := func(steps.AWSConfig) (ec2iface.EC2API, error) {
+		return nil, errors.New("errorMessage")
+	}
+
+	s := NewTagSpotInstances(fn)
+
+	if s == nil {
+		t.Error("Step must not be nil")
+	}
+
+	if api, err := s.getSvc(steps.AWSConfig{}); err == nil || api != nil {
+		t.Errorf("Unexpected values %v %v", api, err)
+	}
+}
+
+func TestInitTagSpotInstances(t *testing.T) {
+	InitTagSpotInstances(GetEC2)
+
+	s := steps.GetStep(TagSpotInstancesStepName)
+
+	if s == nil {
+		t.Errorf("Step must not be nil")
+	}
+}
+
+func TestTagSpotInstancesStep_Name(t *testing.T) {
+	s := &TagSpotInstancesStep{}
+
+	if name := s.Name(); name != TagSpotInstancesStepName {
+		t.Errorf("Wrong name expected %s actual %s",
+			TagSpotInstancesStepName, name)
+	}
+}
@@ -4,10 +4,24 @@ import (
 	"context"
 	"io/ioutil"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+// Volume types unknown to the vendored SDK
+const (
+	VolumeTypeGp3 = "gp3"
+	VolumeTypeIo2 = "io2"
 )
 
 var (
@@ -81,3 +95,125 @@ func FindExternalIP() (string, error) {
 
 	return publicIP, err
 }
+
+// ebsBlockDevice builds root volume settings from config, deleteOnTermination
+// is used when config does not specify it.
+func ebsBlockDevice(cfg steps.AWSConfig, deleteOnTermination bool) (*ec2.EbsBlockDevice, error) {
+	volumeType := cfg.VolumeType
+
+	if volumeType == "" {
+		volumeType = ec2.VolumeTypeGp2
+	}
+
+	device := &ec2.EbsBlockDevice{
+		DeleteOnTermination: aws.Bool(deleteOnTermination),
+		VolumeType:          aws.String(volumeType),
+	}
+
+	// Size of the image snapshot is used when it is empty
+	if cfg.VolumeSize != "" {
+		volumeSize, err := strconv.ParseInt(cfg.VolumeSize, 10, 64)
+
+		if err != nil {
+			return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"parse volume size %s", cfg.VolumeSize)
+		}
+
+		device.VolumeSize = aws.Int64(volumeSize)
+	}
+
+	switch volumeType {
+	case ec2.VolumeTypeStandard, ec2.VolumeTypeGp2, ec2.VolumeTypeSt1, ec2.VolumeTypeSc1:
+		if cfg.Iops != "" {
+			return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"iops are not allowed for %s volumes", volumeType)
+		}
+	case ec2.VolumeTypeIo1, VolumeTypeIo2:
+		if cfg.Iops == "" {
+			return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"iops are required for %s volumes", volumeType)
+		}
+	case VolumeTypeGp3:
+	default:
+		return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"unknown volume type %s", volumeType)
+	}
+
+	if cfg.Iops != "" {
+		iops, err := strconv.ParseInt(cfg.Iops, 10, 64)
+
+		if err != nil || iops <= 0 {
+			return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"wrong iops %s", cfg.Iops)
+		}
+
+		device.Iops = aws.Int64(iops)
+	}
+
+	if cfg.DeleteOnTermination != "" {
+		deleteOnTermination, err := strconv.ParseBool(cfg.DeleteOnTermination)
+
+		if err != nil {
+			return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"parse delete on termination %s", cfg.DeleteOnTermination)
+		}
+
+		device.DeleteOnTermination = aws.Bool(deleteOnTermination)
+	}
+
+	if cfg.EbsEncrypted != "" {
+		encrypted, err := strconv.ParseBool(cfg.EbsEncrypted)
+
+		if err != nil {
+			return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"parse ebs encrypted %s", cfg.EbsEncrypted)
+		}
+
+		if encrypted {
+			device.Encrypted = aws.Bool(true)
+		}
+	}
+
+	if cfg.KmsKeyID != "" {
+		if !aws.BoolValue(device.Encrypted) {
+			return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+				"kms key %s is set for unencrypted volume", cfg.KmsKeyID)
+		}
+
+		device.KmsKeyId = aws.String(cfg.KmsKeyID)
+	}
+
+	return device, nil
+}
+
+// ec2Tags appends custom tags of the cluster to tags set by control,
+// custom tags never override them.
+func ec2Tags(custom map[string]string, tags ...*ec2.Tag) []*ec2.Tag {
+	keys := make([]string, 0, len(custom))
+
+	for key := range custom {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		reserved := false
+
+		for _, tag := range tags {
+			if aws.StringValue(tag.Key) == key {
+				reserved = true
+				break
+			}
+		}
+
+		if !reserved {
+			tags = append(tags, &ec2.Tag{
+				Key:   aws.String(key),
+				Value: aws.String(custom[key]),
+			})
+		}
+	}
+
+	return tags
+}
@@ -7,10 +7,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/jarcoal/httpmock"
 	"github.com/pkg/errors"
 
+	"github.com/supergiant/control/pkg/clouds"
 	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows/steps"
 )
 
 func TestFindOutboundIPCancelled(t *testing.T) {
@@ -147,3 +151,154 @@ func TestFindOutboundIP(t *testing.T) {
 		}
 	}
 }
+
+func TestEbsBlockDevice(t *testing.T) {
+	testCases := []struct {
+		description string
+		cfg         steps.AWSConfig
+
+		volumeType          string
+		iops                int64
+		deleteOnTermination bool
+		encrypted           bool
+		kmsKeyID            string
+		hasErr              bool
+	}{
+		{
+			description:         "defaults",
+			cfg:                 steps.AWSConfig{VolumeSize: "20"},
+			volumeType:          "gp2",
+			deleteOnTermination: true,
+		},
+		{
+			description: "wrong volume size",
+			cfg:         steps.AWSConfig{VolumeSize: "size"},
+			hasErr:      true,
+		},
+		{
+			description: "unknown volume type",
+			cfg:         steps.AWSConfig{VolumeType: "gp4"},
+			hasErr:      true,
+		},
+		{
+			description: "iops for gp2",
+			cfg:         steps.AWSConfig{VolumeType: "gp2", Iops: "3000"},
+			hasErr:      true,
+		},
+		{
+			description: "io1 without iops",
+			cfg:         steps.AWSConfig{VolumeType: "io1"},
+			hasErr:      true,
+		},
+		{
+			description: "wrong iops",
+			cfg:         steps.AWSConfig{VolumeType: "io2", Iops: "-1"},
+			hasErr:      true,
+		},
+		{
+			description: "wrong delete on termination",
+			cfg:         steps.AWSConfig{DeleteOnTermination: "maybe"},
+			hasErr:      true,
+		},
+		{
+			description: "gp3 with iops",
+			cfg: steps.AWSConfig{
+				VolumeSize:          "100",
+				VolumeType:          "gp3",
+				Iops:                "6000",
+				DeleteOnTermination: "false",
+			},
+			volumeType: "gp3",
+			iops:       6000,
+		},
+		{
+			description:         "encrypted with default key",
+			cfg:                 steps.AWSConfig{EbsEncrypted: "true"},
+			volumeType:          "gp2",
+			deleteOnTermination: true,
+			encrypted:           true,
+		},
+		{
+			description: "encrypted with kms key",
+			cfg: steps.AWSConfig{
+				EbsEncrypted: "true",
+				KmsKeyID:     "arn:aws:kms:us-east-1:1:key/1234",
+			},
+			volumeType:          "gp2",
+			deleteOnTermination: true,
+			encrypted:           true,
+			kmsKeyID:            "arn:aws:kms:us-east-1:1:key/1234",
+		},
+		{
+			description: "wrong ebs encrypted",
+			cfg:         steps.AWSConfig{EbsEncrypted: "yes"},
+			hasErr:      true,
+		},
+		{
+			description: "kms key of unencrypted volume",
+			cfg:         steps.AWSConfig{EbsEncrypted: "false", KmsKeyID: "alias/ebs"},
+			hasErr:      true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		device, err := ebsBlockDevice(testCase.cfg, true)
+
+		if testCase.hasErr {
+			if !sgerrors.IsValidationFailed(err) {
+				t.Errorf("Expected validation error actual %v", err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		if aws.StringValue(device.VolumeType) != testCase.volumeType {
+			t.Errorf("Wrong volume type expected %s actual %s",
+				testCase.volumeType, aws.StringValue(device.VolumeType))
+		}
+
+		if aws.Int64Value(device.Iops) != testCase.iops {
+			t.Errorf("Wrong iops expected %d actual %d",
+				testCase.iops, aws.Int64Value(device.Iops))
+		}
+
+		if aws.BoolValue(device.DeleteOnTermination) != testCase.deleteOnTermination {
+			t.Errorf("Wrong delete on termination expected %v actual %v",
+				testCase.deleteOnTermination, aws.BoolValue(device.DeleteOnTermination))
+		}
+
+		if aws.BoolValue(device.Encrypted) != testCase.encrypted ||
+			aws.StringValue(device.KmsKeyId) != testCase.kmsKeyID {
+			t.Errorf("Wrong encryption expected %v %s actual %v %s", testCase.encrypted,
+				testCase.kmsKeyID, aws.BoolValue(device.Encrypted), aws.StringValue(device.KmsKeyId))
+		}
+	}
+}
+
+func TestEc2Tags(t *testing.T) {
+	tags := ec2Tags(map[string]string{
+		"team":         "core",
+		"cost-center":  "42",
+		clouds.TagRole: "custom",
+	}, &ec2.Tag{
+		Key:   aws.String(clouds.TagRole),
+		Value: aws.String("node"),
+	})
+
+	expected := []string{"Role=node", "cost-center=42", "team=core"}
+
+	if len(tags) != len(expected) {
+		t.Fatalf("Wrong count of tags expected %d actual %d", len(expected), len(tags))
+	}
+
+	for i, tag := range tags {
+		if actual := aws.StringValue(tag.Key) + "=" + aws.StringValue(tag.Value); actual != expected[i] {
+			t.Errorf("Wrong tag #%d expected %s actual %s", i, expected[i], actual)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package amazon
+
+import (
+	"context"
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/clouds/awssdk"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const StepValidateKMSKey = "aws_validate_kms_key"
+
+// ValidateKMSKeyStep checks that KMS key of encrypted root volumes
+// can be used with account credentials before any resources are created.
+type ValidateKMSKeyStep struct {
+	getSvc GetKMSFn
+}
+
+func InitValidateKMSKey(fn GetKMSFn) {
+	steps.RegisterStep(StepValidateKMSKey, NewValidateKMSKeyStep(fn))
+}
+
+func NewValidateKMSKeyStep(fn GetKMSFn) *ValidateKMSKeyStep {
+	return &ValidateKMSKeyStep{
+		getSvc: fn,
+	}
+}
+
+func (s *ValidateKMSKeyStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config) error {
+	log := util.GetLogger(w)
+
+	if cfg.AWSConfig.KmsKeyID == "" {
+		log.Infof("[%s] - skip, kms key is not set", s.Name())
+		return nil
+	}
+
+	encrypted, err := strconv.ParseBool(cfg.AWSConfig.EbsEncrypted)
+
+	if err != nil || !encrypted {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"%s kms key %s is set for unencrypted volumes", s.Name(), cfg.AWSConfig.KmsKeyID)
+	}
+
+	svc, err := s.getSvc(cfg.AWSConfig)
+
+	if err != nil {
+		logrus.Errorf("[%s] - error getting service %v", s.Name(), err)
+		return errors.Wrapf(ErrAuthorization, "%s error getting service %v", s.Name(), err)
+	}
+
+	if err := awssdk.ValidateKMSKey(ctx, svc, cfg.AWSConfig.KmsKeyID); err != nil {
+		return errors.Wrap(err, s.Name())
+	}
+
+	log.Infof("[%s] - kms key %s is valid", s.Name(), cfg.AWSConfig.KmsKeyID)
+
+	return nil
+}
+
+func (*ValidateKMSKeyStep) Name() string {
+	return StepValidateKMSKey
+}
+
+func (*ValidateKMSKeyStep) Depends() []string {
+	return nil
+}
+
+func (*ValidateKMSKeyStep) Description() string {
+	return "Validate KMS key of root volumes"
+}
+
+func (*ValidateKMSKeyStep) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
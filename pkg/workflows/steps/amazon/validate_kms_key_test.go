@@ -0,0 +1,109 @@
+package amazon
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/clouds/awssdk"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type mockKeyDescriber struct {
+	keyID string
+	state string
+}
+
+func (m *mockKeyDescriber) DescribeKey(ctx context.Context, keyID string) (*awssdk.KMSKey, error) {
+	m.keyID = keyID
+
+	return &awssdk.KMSKey{
+		KeyID:    aws.String(keyID),
+		KeyState: aws.String(m.state),
+		Enabled:  aws.Bool(m.state == "Enabled"),
+	}, nil
+}
+
+func TestValidateKMSKeyStep_Run(t *testing.T) {
+	testCases := []struct {
+		description string
+		cfg         steps.AWSConfig
+		state       string
+		getSvcErr   error
+
+		described    bool
+		isErr        bool
+		isValidation bool
+	}{
+		{
+			description: "not encrypted",
+		},
+		{
+			description: "default key",
+			cfg:         steps.AWSConfig{EbsEncrypted: "true"},
+		},
+		{
+			description:  "key without encryption",
+			cfg:          steps.AWSConfig{KmsKeyID: "alias/ebs"},
+			isErr:        true,
+			isValidation: true,
+		},
+		{
+			description: "enabled key",
+			cfg:         steps.AWSConfig{EbsEncrypted: "true", KmsKeyID: "alias/ebs"},
+			state:       "Enabled",
+			described:   true,
+		},
+		{
+			description:  "disabled key",
+			cfg:          steps.AWSConfig{EbsEncrypted: "true", KmsKeyID: "alias/ebs"},
+			state:        "Disabled",
+			described:    true,
+			isErr:        true,
+			isValidation: true,
+		},
+		{
+			description: "get service error",
+			cfg:         steps.AWSConfig{EbsEncrypted: "true", KmsKeyID: "alias/ebs"},
+			getSvcErr:   errors.New("error"),
+			isErr:       true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		svc := &mockKeyDescriber{state: testCase.state}
+
+		step := NewValidateKMSKeyStep(func(steps.AWSConfig) (awssdk.KeyDescriber, error) {
+			return svc, testCase.getSvcErr
+		})
+
+		err := step.Run(context.Background(), &bytes.Buffer{}, &steps.Config{
+			AWSConfig: testCase.cfg,
+		})
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("Wrong error %v", err)
+		}
+
+		if testCase.isValidation != sgerrors.IsValidationFailed(err) {
+			t.Errorf("Wrong error type %v", err)
+		}
+
+		if testCase.described != (svc.keyID == testCase.cfg.KmsKeyID && svc.keyID != "") {
+			t.Errorf("Wrong described key %s", svc.keyID)
+		}
+	}
+}
+
+func TestInitValidateKMSKey(t *testing.T) {
+	InitValidateKMSKey(GetKMS)
+
+	if s := steps.GetStep(StepValidateKMSKey); s == nil {
+		t.Errorf("Step must not be nil")
+	}
+}
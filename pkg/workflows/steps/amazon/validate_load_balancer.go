@@ -0,0 +1,148 @@
+package amazon
+
+import (
+	"context"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/clouds/awssdk"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const (
+	StepValidateLoadBalancer = "aws_validate_load_balancer"
+
+	loadBalancerTypeNetwork = "network"
+	targetGroupProtocolTCP  = "TCP"
+)
+
+// ValidateLoadBalancerStep checks that target group of network load
+// balancer of user forwards to API server of masters before any resources
+// are created, load balancer replaces ELBs of the cluster then.
+type ValidateLoadBalancerStep struct {
+	getSvc GetELBv2Fn
+}
+
+func InitValidateLoadBalancer(fn GetELBv2Fn) {
+	steps.RegisterStep(StepValidateLoadBalancer, NewValidateLoadBalancerStep(fn))
+}
+
+func NewValidateLoadBalancerStep(fn GetELBv2Fn) *ValidateLoadBalancerStep {
+	return &ValidateLoadBalancerStep{
+		getSvc: fn,
+	}
+}
+
+func (s *ValidateLoadBalancerStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config) error {
+	log := util.GetLogger(w)
+
+	if cfg.AWSConfig.TargetGroup == "" {
+		log.Infof("[%s] - skip, load balancer is not set", s.Name())
+		return nil
+	}
+
+	svc, err := s.getSvc(cfg.AWSConfig)
+
+	if err != nil {
+		logrus.Errorf("[%s] - error getting service %v", s.Name(), err)
+		return errors.Wrapf(ErrAuthorization, "%s error getting service %v", s.Name(), err)
+	}
+
+	lb, err := svc.DescribeLoadBalancer(ctx, cfg.AWSConfig.NetworkLoadBalancer)
+
+	if err != nil {
+		return errors.Wrap(err, s.Name())
+	}
+
+	targetGroup, err := svc.DescribeTargetGroup(ctx, cfg.AWSConfig.TargetGroup)
+
+	if err != nil {
+		return errors.Wrap(err, s.Name())
+	}
+
+	if err := validateTargetGroup(lb, targetGroup, cfg); err != nil {
+		return errors.Wrap(err, s.Name())
+	}
+
+	cfg.AWSConfig.TargetGroupARN = aws.StringValue(targetGroup.TargetGroupArn)
+	// Masters and nodes reach API server through the same load balancer
+	cfg.Kube.ExternalDNSName = aws.StringValue(lb.DNSName)
+	cfg.Kube.InternalDNSName = aws.StringValue(lb.DNSName)
+
+	log.Infof("[%s] - target group %s of load balancer %s is valid", s.Name(),
+		cfg.AWSConfig.TargetGroupARN, cfg.Kube.ExternalDNSName)
+
+	return nil
+}
+
+// validateTargetGroup checks that target group belongs to the network
+// load balancer and forwards TCP to API server port of instances.
+func validateTargetGroup(lb *awssdk.NetworkLoadBalancer, targetGroup *awssdk.TargetGroup,
+	cfg *steps.Config) error {
+	if lbType := aws.StringValue(lb.Type); lbType != loadBalancerTypeNetwork {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"load balancer %s is %s not network", aws.StringValue(lb.LoadBalancerName), lbType)
+	}
+
+	attached := false
+
+	for _, lbARN := range targetGroup.LoadBalancerArns {
+		if aws.StringValue(lbARN) == aws.StringValue(lb.LoadBalancerArn) {
+			attached = true
+			break
+		}
+	}
+
+	if !attached {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"target group %s is not attached to load balancer %s",
+			aws.StringValue(targetGroup.TargetGroupName), aws.StringValue(lb.LoadBalancerName))
+	}
+
+	if port := aws.Int64Value(targetGroup.Port); port != cfg.Kube.APIServerPort {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"target group port %d differs from API server port %d", port,
+			cfg.Kube.APIServerPort)
+	}
+
+	// API server terminates TLS itself to authenticate clients by certificates
+	if protocol := aws.StringValue(targetGroup.Protocol); protocol != targetGroupProtocolTCP {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"target group protocol %s must be %s", protocol, targetGroupProtocolTCP)
+	}
+
+	if targetType := aws.StringValue(targetGroup.TargetType); targetType != awssdk.TargetTypeInstance {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"target group target type %s must be %s", targetType, awssdk.TargetTypeInstance)
+	}
+
+	vpcID := cfg.AWSConfig.VPCID
+
+	if vpcID != "" && vpcID != steps.DefaultVPCID && aws.StringValue(targetGroup.VpcId) != vpcID {
+		return errors.Wrapf(sgerrors.ErrValidationFailed,
+			"target group is in vpc %s not in %s", aws.StringValue(targetGroup.VpcId), vpcID)
+	}
+
+	return nil
+}
+
+func (*ValidateLoadBalancerStep) Name() string {
+	return StepValidateLoadBalancer
+}
+
+func (*ValidateLoadBalancerStep) Depends() []string {
+	return nil
+}
+
+func (*ValidateLoadBalancerStep) Description() string {
+	return "Validate target group of network load balancer"
+}
+
+func (*ValidateLoadBalancerStep) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
@@ -0,0 +1,166 @@
+package amazon
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/clouds/awssdk"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type fakeTargetGroupService struct {
+	lb          *awssdk.NetworkLoadBalancer
+	targetGroup *awssdk.TargetGroup
+	err         error
+
+	registered   []string
+	deregistered []string
+}
+
+func (f *fakeTargetGroupService) DescribeLoadBalancer(context.Context, string) (*awssdk.NetworkLoadBalancer, error) {
+	return f.lb, f.err
+}
+
+func (f *fakeTargetGroupService) DescribeTargetGroup(context.Context, string) (*awssdk.TargetGroup, error) {
+	return f.targetGroup, f.err
+}
+
+func (f *fakeTargetGroupService) RegisterInstance(_ context.Context, _, instanceID string) error {
+	f.registered = append(f.registered, instanceID)
+	return f.err
+}
+
+func (f *fakeTargetGroupService) DeregisterInstance(_ context.Context, _, instanceID string) error {
+	f.deregistered = append(f.deregistered, instanceID)
+	return f.err
+}
+
+func TestValidateLoadBalancerStep_Run(t *testing.T) {
+	lb := &awssdk.NetworkLoadBalancer{
+		LoadBalancerArn:  aws.String("arn:lb"),
+		LoadBalancerName: aws.String("central"),
+		DNSName:          aws.String("central.elb.amazonaws.com"),
+		Type:             aws.String("network"),
+	}
+
+	targetGroup := func(port int64, protocol string) *awssdk.TargetGroup {
+		return &awssdk.TargetGroup{
+			TargetGroupArn:   aws.String("arn:tg"),
+			TargetGroupName:  aws.String("api"),
+			Port:             aws.Int64(port),
+			Protocol:         aws.String(protocol),
+			TargetType:       aws.String(awssdk.TargetTypeInstance),
+			VpcId:            aws.String("vpc-1"),
+			LoadBalancerArns: aws.StringSlice([]string{"arn:lb"}),
+		}
+	}
+
+	testCases := []struct {
+		description string
+
+		targetGroup string
+		svc         *fakeTargetGroupService
+
+		isErr        bool
+		isValidation bool
+	}{
+		{
+			description: "skip",
+		},
+		{
+			description: "describe error",
+			targetGroup: "api",
+			svc:         &fakeTargetGroupService{err: errors.New("describe")},
+			isErr:       true,
+		},
+		{
+			description: "wrong port",
+			targetGroup: "api",
+			svc: &fakeTargetGroupService{
+				lb:          lb,
+				targetGroup: targetGroup(443, "TCP"),
+			},
+			isErr:        true,
+			isValidation: true,
+		},
+		{
+			description: "wrong protocol",
+			targetGroup: "api",
+			svc: &fakeTargetGroupService{
+				lb:          lb,
+				targetGroup: targetGroup(6443, "TLS"),
+			},
+			isErr:        true,
+			isValidation: true,
+		},
+		{
+			description: "target group of other load balancer",
+			targetGroup: "api",
+			svc: &fakeTargetGroupService{
+				lb: lb,
+				targetGroup: &awssdk.TargetGroup{
+					Port:     aws.Int64(6443),
+					Protocol: aws.String("TCP"),
+				},
+			},
+			isErr:        true,
+			isValidation: true,
+		},
+		{
+			description: "valid",
+			targetGroup: "api",
+			svc: &fakeTargetGroupService{
+				lb:          lb,
+				targetGroup: targetGroup(6443, "TCP"),
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		step := NewValidateLoadBalancerStep(func(steps.AWSConfig) (awssdk.TargetGroupService, error) {
+			return testCase.svc, nil
+		})
+
+		config := &steps.Config{
+			Kube: model.Kube{
+				APIServerPort: 6443,
+			},
+			AWSConfig: steps.AWSConfig{
+				VPCID:               "vpc-1",
+				NetworkLoadBalancer: "central",
+				TargetGroup:         testCase.targetGroup,
+			},
+		}
+
+		err := step.Run(context.Background(), &bytes.Buffer{}, config)
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("Wrong error %v", err)
+			continue
+		}
+
+		if testCase.isValidation != sgerrors.IsValidationFailed(err) {
+			t.Errorf("Wrong error type %v", err)
+		}
+
+		if err == nil && testCase.targetGroup != "" {
+			if config.AWSConfig.TargetGroupARN != "arn:tg" {
+				t.Errorf("Wrong target group arn %s", config.AWSConfig.TargetGroupARN)
+			}
+
+			if config.Kube.ExternalDNSName != "central.elb.amazonaws.com" ||
+				config.Kube.InternalDNSName != "central.elb.amazonaws.com" {
+				t.Errorf("Wrong dns names %s %s", config.Kube.ExternalDNSName,
+					config.Kube.InternalDNSName)
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package amazon
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/clouds/awssdk"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const StepValidateRegion = "aws_validate_region"
+
+// ValidateRegionStep checks region and availability zone of the config
+// before any resources are created.
+type ValidateRegionStep struct {
+	getSvc func(steps.AWSConfig) (awssdk.RegionDescriber, error)
+}
+
+func InitValidateRegion(fn GetEC2Fn) {
+	steps.RegisterStep(StepValidateRegion, NewValidateRegionStep(fn))
+}
+
+func NewValidateRegionStep(fn GetEC2Fn) *ValidateRegionStep {
+	return &ValidateRegionStep{
+		getSvc: func(cfg steps.AWSConfig) (awssdk.RegionDescriber, error) {
+			EC2, err := fn(cfg)
+
+			if err != nil {
+				return nil, errors.Wrap(ErrAuthorization, err.Error())
+			}
+
+			return EC2, nil
+		},
+	}
+}
+
+func (s *ValidateRegionStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config) error {
+	log := util.GetLogger(w)
+
+	awsCfg := cfg.AWSConfig
+	awsCfg.Region = awssdk.DefaultRegion
+	svc, err := s.getSvc(awsCfg)
+
+	if err != nil {
+		logrus.Errorf("[%s] - error getting service %v", s.Name(), err)
+		return errors.Wrapf(err, "%s error getting service", s.Name())
+	}
+
+	if err := awssdk.ValidateRegion(ctx, svc, cfg.AWSConfig.Region); err != nil {
+		return errors.Wrap(err, s.Name())
+	}
+
+	if cfg.AWSConfig.AvailabilityZone != "" {
+		svc, err = s.getSvc(cfg.AWSConfig)
+
+		if err != nil {
+			logrus.Errorf("[%s] - error getting service %v", s.Name(), err)
+			return errors.Wrapf(err, "%s error getting service", s.Name())
+		}
+
+		err = awssdk.ValidateZone(ctx, svc, cfg.AWSConfig.Region,
+			cfg.AWSConfig.AvailabilityZone)
+
+		if err != nil {
+			return errors.Wrap(err, s.Name())
+		}
+	}
+
+	log.Infof("[%s] - region %s and availability zone %s are valid", s.Name(),
+		cfg.AWSConfig.Region, cfg.AWSConfig.AvailabilityZone)
+
+	return nil
+}
+
+func (*ValidateRegionStep) Name() string {
+	return StepValidateRegion
+}
+
+func (*ValidateRegionStep) Depends() []string {
+	return nil
+}
+
+func (*ValidateRegionStep) Description() string {
+	return "Validate region and availability zone"
+}
+
+func (*ValidateRegionStep) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
@@ -0,0 +1,165 @@
+package amazon
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/clouds/awssdk"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type mockRegionDescriber struct {
+	zones []string
+}
+
+func (m *mockRegionDescriber) DescribeRegionsWithContext(aws.Context, *ec2.DescribeRegionsInput,
+	...request.Option) (*ec2.DescribeRegionsOutput, error) {
+	return &ec2.DescribeRegionsOutput{
+		Regions: []*ec2.Region{
+			{RegionName: aws.String("us-east-1")},
+			{RegionName: aws.String("eu-west-1")},
+		},
+	}, nil
+}
+
+func (m *mockRegionDescriber) DescribeAvailabilityZonesWithContext(aws.Context, *ec2.DescribeAvailabilityZonesInput,
+	...request.Option) (*ec2.DescribeAvailabilityZonesOutput, error) {
+	out := &ec2.DescribeAvailabilityZonesOutput{}
+
+	for _, zone := range m.zones {
+		out.AvailabilityZones = append(out.AvailabilityZones,
+			&ec2.AvailabilityZone{ZoneName: aws.String(zone)})
+	}
+
+	return out, nil
+}
+
+func TestValidateRegionStep_Run(t *testing.T) {
+	testCases := []struct {
+		description string
+
+		region    string
+		zone      string
+		getSvcErr error
+
+		regions    []string
+		isNotFound bool
+		errMsg     string
+	}{
+		{
+			description: "get service error",
+			region:      "eu-west-1",
+			getSvcErr:   errors.New("message1"),
+			errMsg:      "message1",
+		},
+		{
+			description: "wrong region",
+			region:      "eu-wset-1",
+			regions:     []string{awssdk.DefaultRegion},
+			isNotFound:  true,
+			errMsg:      "eu-west-1, us-east-1",
+		},
+		{
+			description: "wrong zone",
+			region:      "eu-west-1",
+			zone:        "eu-west-1z",
+			regions:     []string{awssdk.DefaultRegion, "eu-west-1"},
+			isNotFound:  true,
+			errMsg:      "eu-west-1a",
+		},
+		{
+			description: "without zone",
+			region:      "eu-west-1",
+			regions:     []string{awssdk.DefaultRegion},
+		},
+		{
+			description: "success",
+			region:      "eu-west-1",
+			zone:        "eu-west-1a",
+			regions:     []string{awssdk.DefaultRegion, "eu-west-1"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		var regions []string
+
+		step := &ValidateRegionStep{
+			getSvc: func(cfg steps.AWSConfig) (awssdk.RegionDescriber, error) {
+				regions = append(regions, cfg.Region)
+				return &mockRegionDescriber{
+					zones: []string{"eu-west-1a"},
+				}, testCase.getSvcErr
+			},
+		}
+
+		config := &steps.Config{
+			AWSConfig: steps.AWSConfig{
+				Region:           testCase.region,
+				AvailabilityZone: testCase.zone,
+			},
+		}
+
+		err := step.Run(context.Background(), &bytes.Buffer{}, config)
+
+		if testCase.errMsg == "" && err != nil {
+			t.Errorf("Unexpected error %v", err)
+		}
+
+		if testCase.errMsg != "" && (err == nil || !strings.Contains(err.Error(), testCase.errMsg)) {
+			t.Errorf("Error %v does not contain %s", err, testCase.errMsg)
+		}
+
+		if testCase.isNotFound != sgerrors.IsNotFound(err) {
+			t.Errorf("Wrong not found error %v", err)
+		}
+
+		if testCase.regions != nil && strings.Join(regions, ",") != strings.Join(testCase.regions, ",") {
+			t.Errorf("Wrong regions of services expected %v actual %v",
+				testCase.regions, regions)
+		}
+	}
+}
+
+func TestNewValidateRegionStepErr(t *testing.T) {
+	fn := func(steps.AWSConfig) (ec2iface.EC2API, error) {
+		return nil, errors.New("errorMessage")
+	}
+
+	s := NewValidateRegionStep(fn)
+
+	if s == nil {
+		t.Error("Step must not be nil")
+	}
+
+	if api, err := s.getSvc(steps.AWSConfig{}); err == nil || api != nil {
+		t.Errorf("Unexpected values %v %v", api, err)
+	}
+}
+
+func TestInitValidateRegion(t *testing.T) {
+	InitValidateRegion(GetEC2)
+
+	s := steps.GetStep(StepValidateRegion)
+
+	if s == nil {
+		t.Errorf("Step must not be nil")
+	}
+}
+
+func TestValidateRegionStep_Name(t *testing.T) {
+	s := &ValidateRegionStep{}
+
+	if name := s.Name(); name != StepValidateRegion {
+		t.Errorf("Wrong name expected %s actual %s", StepValidateRegion, name)
+	}
+}
@@ -0,0 +1,252 @@
+package amazon
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/util"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const (
+	WaitSpotRequestsStepName = "aws_wait_spot_requests"
+
+	defaultSpotFulfillmentTimeout = time.Minute * 10
+	spotWaiterDelay               = time.Second * 15
+	onDemandCreateTimeout         = time.Minute * 10
+)
+
+type spotRequestWaiter interface {
+	WaitUntilSpotInstanceRequestFulfilledWithContext(aws.Context, *ec2.DescribeSpotInstanceRequestsInput, ...request.WaiterOption) error
+	DescribeSpotInstanceRequestsWithContext(aws.Context, *ec2.DescribeSpotInstanceRequestsInput, ...request.Option) (*ec2.DescribeSpotInstanceRequestsOutput, error)
+	CancelSpotInstanceRequestsWithContext(aws.Context, *ec2.CancelSpotInstanceRequestsInput, ...request.Option) (*ec2.CancelSpotInstanceRequestsOutput, error)
+}
+
+// WaitSpotRequestsStep waits until spot requests are fulfilled and
+// launches on-demand instances instead of the rest of them if
+// fallback was requested.
+type WaitSpotRequestsStep struct {
+	getSvc func(steps.AWSConfig) (spotRequestWaiter, error)
+}
+
+func InitWaitSpotRequests(fn GetEC2Fn) {
+	steps.RegisterStep(WaitSpotRequestsStepName, NewWaitSpotRequests(fn))
+}
+
+func NewWaitSpotRequests(fn GetEC2Fn) *WaitSpotRequestsStep {
+	return &WaitSpotRequestsStep{
+		getSvc: func(cfg steps.AWSConfig) (spotRequestWaiter, error) {
+			EC2, err := fn(cfg)
+
+			if err != nil {
+				return nil, errors.Wrap(ErrAuthorization, err.Error())
+			}
+
+			return EC2, nil
+		},
+	}
+}
+
+func (s *WaitSpotRequestsStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config) error {
+	log := util.GetLogger(w)
+	spotCfg := &cfg.SpotConfig
+
+	if spotCfg.Instances == nil {
+		spotCfg.Instances = make(map[string]string)
+	}
+
+	if spotCfg.OnDemandInstances == nil {
+		spotCfg.OnDemandInstances = make(map[string]string)
+	}
+
+	// Skip requests that have got their instances before restart
+	requestIDs := make([]string, 0, len(spotCfg.RequestIDs))
+
+	for _, requestID := range spotCfg.RequestIDs {
+		if spotCfg.Instances[requestID] != "" || spotCfg.OnDemandInstances[requestID] != "" {
+			continue
+		}
+
+		requestIDs = append(requestIDs, requestID)
+	}
+
+	if len(requestIDs) == 0 {
+		log.Infof("[%s] - all spot requests have instances", s.Name())
+		return nil
+	}
+
+	svc, err := s.getSvc(cfg.AWSConfig)
+
+	if err != nil {
+		logrus.Errorf("[%s] - error getting service %v", s.Name(), err)
+		return errors.Wrapf(err, "%s error getting service", s.Name())
+	}
+
+	timeout := defaultSpotFulfillmentTimeout
+
+	if spotCfg.FulfillmentTimeout > 0 {
+		timeout = time.Duration(spotCfg.FulfillmentTimeout) * time.Second
+	}
+
+	describeReq := &ec2.DescribeSpotInstanceRequestsInput{
+		SpotInstanceRequestIds: aws.StringSlice(requestIDs),
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	waitErr := svc.WaitUntilSpotInstanceRequestFulfilledWithContext(waitCtx, describeReq,
+		request.WithWaiterDelay(request.ConstantWaiterDelay(spotWaiterDelay)),
+		request.WithWaiterMaxAttempts(int(timeout/spotWaiterDelay)+1))
+	cancel()
+
+	if waitErr != nil {
+		log.Infof("[%s] - wait spot requests %v: %v", s.Name(), requestIDs, waitErr)
+	}
+
+	out, err := svc.DescribeSpotInstanceRequestsWithContext(ctx, describeReq)
+
+	if err != nil {
+		return errors.Wrap(err, "describe spot instance requests")
+	}
+
+	pending := make([]string, 0)
+
+	for _, spotRequest := range out.SpotInstanceRequests {
+		requestID := aws.StringValue(spotRequest.SpotInstanceRequestId)
+
+		if spotRequest.InstanceId == nil {
+			pending = append(pending, requestID)
+			continue
+		}
+
+		spotCfg.Instances[requestID] = *spotRequest.InstanceId
+		log.Infof("[%s] - spot request %s has been fulfilled with instance %s",
+			s.Name(), requestID, *spotRequest.InstanceId)
+	}
+
+	if len(pending) == 0 {
+		spotCfg.State = model.SpotRequestFulfilled
+		return nil
+	}
+
+	if !spotCfg.FallbackOnDemand {
+		spotCfg.State = model.SpotRequestFailed
+		return errors.Errorf("spot requests %v have not been fulfilled", pending)
+	}
+
+	if err := s.fallbackOnDemand(ctx, svc, w, cfg, pending); err != nil {
+		spotCfg.State = model.SpotRequestFailed
+		return errors.Wrap(err, "fall back to on-demand instances")
+	}
+
+	spotCfg.State = model.SpotRequestFallback
+	return nil
+}
+
+// fallbackOnDemand cancels spot requests that were not fulfilled and
+// launches on-demand instance for each of them.
+func (s *WaitSpotRequestsStep) fallbackOnDemand(ctx context.Context, svc spotRequestWaiter,
+	w io.Writer, cfg *steps.Config, pending []string) error {
+	_, err := svc.CancelSpotInstanceRequestsWithContext(ctx, &ec2.CancelSpotInstanceRequestsInput{
+		SpotInstanceRequestIds: aws.StringSlice(pending),
+	})
+
+	if err != nil {
+		return errors.Wrapf(err, "cancel spot requests %v", pending)
+	}
+
+	step := steps.GetStep(StepNameCreateEC2Instance)
+
+	if step == nil {
+		return errors.Wrapf(sgerrors.ErrNotFound, "step %s", StepNameCreateEC2Instance)
+	}
+
+	// Machine updates go nowhere when task was restarted
+	// and nobody listens for them.
+	if cfg.NodeChan() == nil {
+		nodeChan := make(chan model.Machine)
+		cfg.SetNodeChan(nodeChan)
+
+		defer func() {
+			cfg.SetNodeChan(nil)
+			close(nodeChan)
+		}()
+
+		go func() {
+			for range nodeChan {
+			}
+		}()
+	}
+
+	// Instance names are made of task id
+	taskID, zone := cfg.TaskID, cfg.AWSConfig.AvailabilityZone
+	defer func() {
+		cfg.TaskID = taskID
+		cfg.AWSConfig.AvailabilityZone = zone
+	}()
+
+	for _, requestID := range pending {
+		cfg.TaskID = uuid.New()
+		// Launch on-demand instance in the zone of spot request
+		if requestZone := spotRequestZone(cfg.SpotConfig, requestID); requestZone != "" {
+			cfg.AWSConfig.AvailabilityZone = requestZone
+		}
+
+		createCtx, cancel := context.WithTimeout(ctx, onDemandCreateTimeout)
+		err := step.Run(createCtx, w, cfg)
+		cancel()
+
+		if err != nil {
+			return errors.Wrapf(err, "create on-demand instance for spot request %s", requestID)
+		}
+
+		cfg.SpotConfig.OnDemandInstances[requestID] = cfg.Node.ID
+		logrus.Infof("On-demand instance %s has been created instead of spot request %s",
+			cfg.Node.Name, requestID)
+	}
+
+	return nil
+}
+
+func spotRequestZone(spotCfg steps.SpotConfig, requestID string) string {
+	for zone, requestIDs := range spotCfg.ZoneRequests {
+		for _, id := range requestIDs {
+			if id == requestID {
+				return zone
+			}
+		}
+	}
+
+	return ""
+}
+
+// RetryPolicy opts out of retries, on-demand instances of unfulfilled
+// requests would be launched twice.
+func (*WaitSpotRequestsStep) RetryPolicy() steps.RetryPolicy {
+	return steps.NoRetry
+}
+
+func (*WaitSpotRequestsStep) Name() string {
+	return WaitSpotRequestsStepName
+}
+
+func (*WaitSpotRequestsStep) Depends() []string {
+	return nil
+}
+
+func (*WaitSpotRequestsStep) Description() string {
+	return "Wait until spot requests are fulfilled"
+}
+
+func (*WaitSpotRequestsStep) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
@@ -0,0 +1,281 @@
+package amazon
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type mockSpotRequestWaiter struct {
+	mock.Mock
+}
+
+func (m *mockSpotRequestWaiter) WaitUntilSpotInstanceRequestFulfilledWithContext(ctx aws.Context,
+	req *ec2.DescribeSpotInstanceRequestsInput, opts ...request.WaiterOption) error {
+	args := m.Called(ctx, req, opts)
+	return args.Error(0)
+}
+
+func (m *mockSpotRequestWaiter) DescribeSpotInstanceRequestsWithContext(ctx aws.Context,
+	req *ec2.DescribeSpotInstanceRequestsInput, opts ...request.Option) (*ec2.DescribeSpotInstanceRequestsOutput, error) {
+	args := m.Called(ctx, req, opts)
+	val, ok := args.Get(0).(*ec2.DescribeSpotInstanceRequestsOutput)
+	if !ok {
+		return nil, args.Error(1)
+	}
+	return val, args.Error(1)
+}
+
+func (m *mockSpotRequestWaiter) CancelSpotInstanceRequestsWithContext(ctx aws.Context,
+	req *ec2.CancelSpotInstanceRequestsInput, opts ...request.Option) (*ec2.CancelSpotInstanceRequestsOutput, error) {
+	args := m.Called(ctx, req, opts)
+	val, ok := args.Get(0).(*ec2.CancelSpotInstanceRequestsOutput)
+	if !ok {
+		return nil, args.Error(1)
+	}
+	return val, args.Error(1)
+}
+
+type fakeCreateInstanceStep struct {
+	StepCreateInstance
+	calls int
+	err   error
+}
+
+func (s *fakeCreateInstanceStep) Run(ctx context.Context, w io.Writer, cfg *steps.Config) error {
+	s.calls++
+	cfg.Node = model.Machine{
+		ID:   "i-" + cfg.TaskID,
+		Name: cfg.TaskID,
+	}
+	cfg.NodeChan() <- cfg.Node
+	return s.err
+}
+
+func TestWaitSpotRequestsStep_Run(t *testing.T) {
+	fulfilled := &ec2.SpotInstanceRequest{
+		SpotInstanceRequestId: aws.String("sir-1"),
+		InstanceId:            aws.String("i-1"),
+	}
+	pending := &ec2.SpotInstanceRequest{
+		SpotInstanceRequestId: aws.String("sir-2"),
+	}
+
+	testCases := []struct {
+		description string
+
+		requestIDs []string
+		instances  map[string]string
+		fallback   bool
+		getSvcErr  error
+
+		waitErr        error
+		describeOutput *ec2.DescribeSpotInstanceRequestsOutput
+		describeErr    error
+		cancelErr      error
+		createErr      error
+
+		waitCalls   int
+		cancelCalls int
+		createCalls int
+		state       model.SpotRequestState
+		errMsg      string
+	}{
+		{
+			description: "all requests have instances",
+			requestIDs:  []string{"sir-1"},
+			instances:   map[string]string{"sir-1": "i-1"},
+		},
+		{
+			description: "get service error",
+			requestIDs:  []string{"sir-1"},
+			getSvcErr:   errors.New("message1"),
+			errMsg:      "message1",
+		},
+		{
+			description: "describe error",
+			requestIDs:  []string{"sir-1"},
+			describeErr: errors.New("message2"),
+			waitCalls:   1,
+			errMsg:      "message2",
+		},
+		{
+			description: "fulfilled",
+			requestIDs:  []string{"sir-1"},
+			describeOutput: &ec2.DescribeSpotInstanceRequestsOutput{
+				SpotInstanceRequests: []*ec2.SpotInstanceRequest{fulfilled},
+			},
+			waitCalls: 1,
+			state:     model.SpotRequestFulfilled,
+		},
+		{
+			description: "not fulfilled",
+			requestIDs:  []string{"sir-1", "sir-2"},
+			waitErr:     errors.New("timeout"),
+			describeOutput: &ec2.DescribeSpotInstanceRequestsOutput{
+				SpotInstanceRequests: []*ec2.SpotInstanceRequest{fulfilled, pending},
+			},
+			waitCalls: 1,
+			state:     model.SpotRequestFailed,
+			errMsg:    "sir-2",
+		},
+		{
+			description: "cancel error",
+			requestIDs:  []string{"sir-1", "sir-2"},
+			fallback:    true,
+			waitErr:     errors.New("timeout"),
+			describeOutput: &ec2.DescribeSpotInstanceRequestsOutput{
+				SpotInstanceRequests: []*ec2.SpotInstanceRequest{fulfilled, pending},
+			},
+			cancelErr:   errors.New("message3"),
+			waitCalls:   1,
+			cancelCalls: 1,
+			state:       model.SpotRequestFailed,
+			errMsg:      "message3",
+		},
+		{
+			description: "create error",
+			requestIDs:  []string{"sir-1", "sir-2"},
+			fallback:    true,
+			waitErr:     errors.New("timeout"),
+			describeOutput: &ec2.DescribeSpotInstanceRequestsOutput{
+				SpotInstanceRequests: []*ec2.SpotInstanceRequest{fulfilled, pending},
+			},
+			createErr:   errors.New("message4"),
+			waitCalls:   1,
+			cancelCalls: 1,
+			createCalls: 1,
+			state:       model.SpotRequestFailed,
+			errMsg:      "message4",
+		},
+		{
+			description: "fallback",
+			requestIDs:  []string{"sir-1", "sir-2"},
+			fallback:    true,
+			waitErr:     errors.New("timeout"),
+			describeOutput: &ec2.DescribeSpotInstanceRequestsOutput{
+				SpotInstanceRequests: []*ec2.SpotInstanceRequest{fulfilled, pending},
+			},
+			waitCalls:   1,
+			cancelCalls: 1,
+			createCalls: 1,
+			state:       model.SpotRequestFallback,
+		},
+	}
+
+	original := steps.GetStep(StepNameCreateEC2Instance)
+	defer steps.RegisterStep(StepNameCreateEC2Instance, original)
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+		svc := &mockSpotRequestWaiter{}
+		svc.On("WaitUntilSpotInstanceRequestFulfilledWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(testCase.waitErr)
+		svc.On("DescribeSpotInstanceRequestsWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(testCase.describeOutput,
+			testCase.describeErr)
+		svc.On("CancelSpotInstanceRequestsWithContext", mock.Anything,
+			mock.Anything, mock.Anything).Return(nil, testCase.cancelErr)
+
+		createStep := &fakeCreateInstanceStep{
+			err: testCase.createErr,
+		}
+		steps.RegisterStep(StepNameCreateEC2Instance, createStep)
+
+		config := &steps.Config{
+			TaskID: "task-id",
+			SpotConfig: steps.SpotConfig{
+				RequestIDs:       testCase.requestIDs,
+				Instances:        testCase.instances,
+				FallbackOnDemand: testCase.fallback,
+			},
+		}
+		step := WaitSpotRequestsStep{
+			getSvc: func(steps.AWSConfig) (spotRequestWaiter, error) {
+				return svc, testCase.getSvcErr
+			},
+		}
+
+		err := step.Run(context.Background(), &bytes.Buffer{}, config)
+
+		if err == nil && testCase.errMsg != "" {
+			t.Errorf("Error must not be nil")
+		}
+
+		if err != nil && !strings.Contains(err.Error(), testCase.errMsg) {
+			t.Errorf("Error message %s does not contain %s",
+				err.Error(), testCase.errMsg)
+		}
+
+		svc.AssertNumberOfCalls(t, "WaitUntilSpotInstanceRequestFulfilledWithContext",
+			testCase.waitCalls)
+		svc.AssertNumberOfCalls(t, "CancelSpotInstanceRequestsWithContext",
+			testCase.cancelCalls)
+
+		if createStep.calls != testCase.createCalls {
+			t.Errorf("Wrong create calls expected %d actual %d",
+				testCase.createCalls, createStep.calls)
+		}
+
+		if config.SpotConfig.State != testCase.state {
+			t.Errorf("Wrong state expected %s actual %s",
+				testCase.state, config.SpotConfig.State)
+		}
+
+		if config.TaskID != "task-id" {
+			t.Errorf("Task id must be restored, actual %s", config.TaskID)
+		}
+
+		if testCase.state == model.SpotRequestFallback &&
+			config.SpotConfig.OnDemandInstances["sir-2"] == "" {
+			t.Errorf("On-demand instance must be saved for request sir-2")
+		}
+	}
+}
+
+func TestNewWaitSpotRequestsErr(t *testing.T) {
+	fn := func(steps.AWSConfig) (ec2iface.EC2API, error) {
+		return nil, errors.New("errorMessage")
+	}
+
+	s := NewWaitSpotRequests(fn)
+
+	if s == nil {
+		t.Error("Step must not be nil")
+	}
+
+	if api, err := s.getSvc(steps.AWSConfig{}); err == nil || api != nil {
+		t.Errorf("Unexpected values %v %v", api, err)
+	}
+}
+
+func TestInitWaitSpotRequests(t *testing.T) {
+	InitWaitSpotRequests(GetEC2)
+
+	s := steps.GetStep(WaitSpotRequestsStepName)
+
+	if s == nil {
+		t.Errorf("Step must not be nil")
+	}
+}
+
+func TestWaitSpotRequestsStep_Name(t *testing.T) {
+	s := &WaitSpotRequestsStep{}
+
+	if name := s.Name(); name != WaitSpotRequestsStepName {
+		t.Errorf("Wrong name expected %s actual %s",
+			WaitSpotRequestsStepName, name)
+	}
+}
@@ -59,6 +59,11 @@ func ensureAuthorizer(sdk SDKInterface, config *steps.Config) error {
 	return nil
 }
 
+// ResourceGroupName returns name of resource group of the cluster
+func ResourceGroupName(clusterID, clusterName string) string {
+	return toResourceGroupName(clusterID, clusterName)
+}
+
 func toResourceGroupName(clusterID, clusterName string) string {
 	return fmt.Sprintf("sg-%s-%s", clusterName, clusterID)
 }
@@ -62,10 +62,11 @@ func (s *CreateVMStep) Run(ctx context.Context, output io.Writer, config *steps.
 		Name:     vmName,
 		TaskID:   config.TaskID,
 		Region:   config.AzureConfig.Location,
-		Role:     model.ToRole(config.IsMaster),
+		Role:     config.Role(),
 		Size:     config.AzureConfig.VMSize,
 		Provider: clouds.Azure,
 		State:    model.MachineStatePlanned,
+		Pool:     config.Pool,
 	}
 
 	// Update node state in cluster
@@ -152,6 +153,19 @@ func (s *CreateVMStep) setupVM(ctx context.Context, config *steps.Config, vmName
 	}
 
 	volumeSize32 := int32(volumeSize)
+	// Tags are used to find machines of the cluster on sync
+	tags := map[string]*string{
+		clouds.LabelClusterID: to.StringPtr(config.Kube.ID),
+		clouds.TagRole:        to.StringPtr(util.MakeRole(config.IsMaster)),
+		clouds.TagNodeName:    to.StringPtr(vmName),
+	}
+
+	for key, value := range config.Tags {
+		if _, ok := tags[key]; !ok {
+			tags[key] = to.StringPtr(value)
+		}
+	}
+
 	vmClient := s.sdk.VMClient(config.GetAzureAuthorizer(), config.AzureConfig.SubscriptionID)
 	f, err := vmClient.CreateOrUpdate(
 		ctx,
@@ -159,6 +173,7 @@ func (s *CreateVMStep) setupVM(ctx context.Context, config *steps.Config, vmName
 		vmName,
 		compute.VirtualMachine{
 			Location: to.StringPtr(config.AzureConfig.Location),
+			Tags:     tags,
 			VirtualMachineProperties: &compute.VirtualMachineProperties{
 				AvailabilitySet: &compute.SubResource{
 					ID: as.ID,
@@ -16,8 +16,13 @@ const StepName = "certificates"
 
 type Config struct {
 	IsBootstrap bool
+	IsMaster    bool
 	CACert      string
 	CAKey       string
+
+	EtcdCACert     string
+	EtcdClientCert string
+	EtcdClientKey  string
 }
 
 type Step struct {
@@ -67,7 +72,12 @@ func (s *Step) Depends() []string {
 func toStepCfg(c *steps.Config) Config {
 	return Config{
 		IsBootstrap: c.IsBootstrap,
+		IsMaster:    c.IsMaster,
 		CACert:      c.Kube.Auth.CACert,
 		CAKey:       c.Kube.Auth.CAKey,
+
+		EtcdCACert:     c.Kube.Auth.EtcdCACert,
+		EtcdClientCert: c.Kube.Auth.EtcdClientCert,
+		EtcdClientKey:  c.Kube.Auth.EtcdClientKey,
 	}
 }
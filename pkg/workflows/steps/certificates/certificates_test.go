@@ -114,6 +114,59 @@ func TestWriteCertificates(t *testing.T) {
 	output.Reset()
 }
 
+func TestWriteEtcdCertificates(t *testing.T) {
+	if err := templatemanager.Init("../../../../templates"); err != nil {
+		t.Fatal(err)
+	}
+
+	tpl, _ := templatemanager.GetTemplate(StepName)
+
+	testCases := []struct {
+		description string
+		isMaster    bool
+		etcdCACert  string
+		expected    bool
+	}{
+		{
+			description: "stacked etcd",
+			isMaster:    true,
+		},
+		{
+			description: "master of dedicated etcd",
+			isMaster:    true,
+			etcdCACert:  "etcd-ca-cert",
+			expected:    true,
+		},
+		{
+			description: "node of dedicated etcd",
+			etcdCACert:  "etcd-ca-cert",
+		},
+	}
+
+	for _, testCase := range testCases {
+		output := new(bytes.Buffer)
+		cfg := &steps.Config{
+			IsMaster: testCase.isMaster,
+			Runner:   &fakeRunner{},
+		}
+		cfg.Kube.Auth.EtcdCACert = testCase.etcdCACert
+		cfg.Kube.Auth.EtcdClientCert = "etcd-client-cert"
+
+		if err := New(tpl).Run(context.Background(), output, cfg); err != nil {
+			t.Errorf("%s: unexpected error %v", testCase.description, err)
+			continue
+		}
+
+		written := strings.Contains(output.String(), "etcd-ca-cert") &&
+			strings.Contains(output.String(), "etcd-client-cert")
+
+		if written != testCase.expected {
+			t.Errorf("%s: etcd certificates expected %v actual %v", testCase.description,
+				testCase.expected, written)
+		}
+	}
+}
+
 func TestWriteCertificatesError(t *testing.T) {
 	errMsg := "error has occurred"
 
@@ -42,7 +42,7 @@ func Init() {
 }
 
 func (s *Step) Run(ctx context.Context, out io.Writer, config *steps.Config) error {
-	err := steps.RunTemplate(context.Background(), s.script, config.Runner, out, toStepCfg(config))
+	err := steps.RunTemplate(ctx, s.script, config.Runner, out, toStepCfg(config))
 
 	if err != nil {
 		return errors.Wrap(err, "install cloud-controller-manager")
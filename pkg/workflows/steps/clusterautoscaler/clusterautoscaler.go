@@ -0,0 +1,118 @@
+package clusterautoscaler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/clouds"
+	tm "github.com/supergiant/control/pkg/templatemanager"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const (
+	StepName = "cluster_autoscaler"
+
+	imageRepository = "k8s.gcr.io/cluster-autoscaler"
+	// defaultVersion is used for k8s versions missing from versions
+	defaultVersion = "v1.15.7"
+)
+
+// Cluster autoscaler versions by k8s minor version they are released for
+var versions = map[string]string{
+	"1.11": "v1.3.9",
+	"1.12": "v1.12.8",
+	"1.13": "v1.13.9",
+	"1.14": "v1.14.8",
+	"1.15": "v1.15.7",
+}
+
+type Config struct {
+	Image      string
+	Region     string
+	EnabledTag string
+	ClusterTag string
+}
+
+// Step deploys cluster autoscaler to masters of the kube when it is
+// enabled, autoscaler resizes auto scaling groups tagged for the kube.
+type Step struct {
+	script *template.Template
+}
+
+func Init() {
+	tpl, err := tm.GetTemplate(StepName)
+
+	if err != nil {
+		panic(fmt.Sprintf("template %s not found", StepName))
+	}
+
+	steps.RegisterStep(StepName, New(tpl))
+}
+
+func New(script *template.Template) *Step {
+	return &Step{
+		script: script,
+	}
+}
+
+func (s *Step) Run(ctx context.Context, out io.Writer, config *steps.Config) error {
+	if !config.Kube.ClusterAutoscaler {
+		return nil
+	}
+
+	if config.Kube.Provider != clouds.AWS {
+		return errors.Errorf("cluster autoscaler is not supported on %s", config.Kube.Provider)
+	}
+
+	err := steps.RunTemplate(ctx, s.script, config.Runner, out, toStepCfg(config))
+
+	if err != nil {
+		return errors.Wrap(err, "install cluster autoscaler")
+	}
+
+	return nil
+}
+
+func (s *Step) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
+
+func (s *Step) Name() string {
+	return StepName
+}
+
+func (s *Step) Description() string {
+	return "Install cluster autoscaler"
+}
+
+func (s *Step) Depends() []string {
+	return nil
+}
+
+func toStepCfg(c *steps.Config) Config {
+	return Config{
+		Image:      Image(c.Kube.K8SVersion),
+		Region:     c.AWSConfig.Region,
+		EnabledTag: clouds.TagAutoscalerEnabled,
+		ClusterTag: clouds.TagAutoscalerPrefix + c.Kube.ID,
+	}
+}
+
+// Image returns cluster autoscaler image released for the k8s version.
+func Image(k8sVersion string) string {
+	version := defaultVersion
+	parts := strings.Split(strings.TrimPrefix(k8sVersion, "v"), ".")
+
+	if len(parts) >= 2 {
+		if v, ok := versions[parts[0]+"."+parts[1]]; ok {
+			version = v
+		}
+	}
+
+	return fmt.Sprintf("%s:%s", imageRepository, version)
+}
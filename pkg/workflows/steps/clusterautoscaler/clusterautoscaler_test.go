@@ -0,0 +1,135 @@
+package clusterautoscaler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/runner"
+	"github.com/supergiant/control/pkg/templatemanager"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type fakeRunner struct {
+	errMsg string
+}
+
+func (f *fakeRunner) Run(command *runner.Command) error {
+	if len(f.errMsg) > 0 {
+		return errors.New(f.errMsg)
+	}
+
+	_, err := io.Copy(command.Out, strings.NewReader(command.Script))
+	return err
+}
+
+func TestStep_Run(t *testing.T) {
+	err := templatemanager.Init("../../../../templates")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl, _ := templatemanager.GetTemplate(StepName)
+
+	if tpl == nil {
+		t.Fatal("template not found")
+	}
+
+	testCases := []struct {
+		description string
+		enabled     bool
+		provider    clouds.Name
+		runErr      string
+
+		expectedErr    bool
+		expectedOutput []string
+	}{
+		{
+			description: "disabled",
+			provider:    clouds.AWS,
+		},
+		{
+			description: "enabled",
+			enabled:     true,
+			provider:    clouds.AWS,
+			expectedOutput: []string{
+				"k8s.gcr.io/cluster-autoscaler:v1.14.8",
+				"asg:tag=k8s.io/cluster-autoscaler/enabled,k8s.io/cluster-autoscaler/kube-id",
+				"value: us-west-2",
+			},
+		},
+		{
+			description: "other provider",
+			enabled:     true,
+			provider:    clouds.GCE,
+			expectedErr: true,
+		},
+		{
+			description: "runner error",
+			enabled:     true,
+			provider:    clouds.AWS,
+			runErr:      "error",
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		output := new(bytes.Buffer)
+		config := &steps.Config{
+			Kube: model.Kube{
+				ID:                "kube-id",
+				Provider:          testCase.provider,
+				K8SVersion:        "1.14.3",
+				ClusterAutoscaler: testCase.enabled,
+			},
+			AWSConfig: steps.AWSConfig{
+				Region: "us-west-2",
+			},
+			Runner: &fakeRunner{errMsg: testCase.runErr},
+		}
+
+		err := New(tpl).Run(context.Background(), output, config)
+
+		if (err != nil) != testCase.expectedErr {
+			t.Errorf("Expected error %v actual %v", testCase.expectedErr, err)
+		}
+
+		if !testCase.enabled && output.Len() > 0 {
+			t.Errorf("Autoscaler must not be installed %s", output.String())
+		}
+
+		for _, expected := range testCase.expectedOutput {
+			if !strings.Contains(output.String(), expected) {
+				t.Errorf("%s not found in %s", expected, output.String())
+			}
+		}
+	}
+}
+
+func TestImage(t *testing.T) {
+	testCases := []struct {
+		version  string
+		expected string
+	}{
+		{"1.11.5", "k8s.gcr.io/cluster-autoscaler:v1.3.9"},
+		{"v1.15.1", "k8s.gcr.io/cluster-autoscaler:v1.15.7"},
+		{"1.20.0", "k8s.gcr.io/cluster-autoscaler:v1.15.7"},
+		{"", "k8s.gcr.io/cluster-autoscaler:v1.15.7"},
+	}
+
+	for _, testCase := range testCases {
+		if image := Image(testCase.version); image != testCase.expected {
+			t.Errorf("Expected image %s for %s actual %s", testCase.expected,
+				testCase.version, image)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package steps
 
 import (
 	"encoding/json"
-	"fmt"
 	"sync"
 	"time"
 
@@ -12,6 +11,7 @@ import (
 	"github.com/supergiant/control/pkg/clouds"
 	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/provisioner/addons"
 	"github.com/supergiant/control/pkg/runner"
 	"github.com/supergiant/control/pkg/sgerrors"
 	"github.com/supergiant/control/pkg/storage"
@@ -62,6 +62,8 @@ type GCEConfig struct {
 	Region           string `json:"region"`
 	AvailabilityZone string `json:"availabilityZone"`
 	Size             string `json:"size"`
+	// Preemptible instances are cheaper, but GCE may stop them at any time
+	Preemptible string `json:"preemptible"`
 
 	NetworkName string `json:"networkName"`
 	NetworkLink string `json:"networkLink"`
@@ -89,7 +91,7 @@ type GCEConfig struct {
 	HealthCheckName string `json:"healthCheckName"`
 
 	ExternalForwardingRuleName string `json:"externalForwardingRuleName"`
-	InternalForwardingRuleName string `json:"externalForwardingRuleName"`
+	InternalForwardingRuleName string `json:"internalForwardingRuleName"`
 }
 
 type AzureConfig struct {
@@ -110,9 +112,15 @@ type PacketConfig struct{}
 
 type OSConfig struct{}
 
+// DefaultVPCID makes provisioning use default VPC of the region
+const DefaultVPCID = "default"
+
 type AWSConfig struct {
 	KeyID                  string `json:"access_key"`
 	Secret                 string `json:"secret_key"`
+	SessionToken           string `json:"session_token"`
+	RoleARN                string `json:"role_arn"`
+	ExternalID             string `json:"external_id"`
 	Region                 string `json:"region"`
 	AvailabilityZone       string `json:"availabilityZone"`
 	KeyPairName            string `json:"keyPairName"`
@@ -130,17 +138,139 @@ type AWSConfig struct {
 	ImageID                string `json:"image"`
 	InstanceType           string `json:"size"`
 
+	// EBS volume type, gp2 is used when it is empty
+	VolumeType string `json:"volumeType"`
+	// Provisioned IOPS, allowed only for io1, io2 and gp3 volumes
+	Iops                string `json:"iops"`
+	DeleteOnTermination string `json:"deleteOnTermination"`
+	// Root volumes are encrypted with KmsKeyID or with the default
+	// EBS key of the account when it is empty.
+	EbsEncrypted string `json:"ebsEncrypted"`
+	KmsKeyID     string `json:"kmsKeyId"`
+	// Instance metadata options, IMDSv2 is enforced when http tokens
+	// are required, hop limit is 2 when it is empty.
+	HttpTokens              string `json:"httpTokens"`
+	HttpPutResponseHopLimit string `json:"httpPutResponseHopLimit"`
+
 	ExternalLoadBalancerName string `json:"externalLoadBalancerName"`
 	InternalLoadBalancerName string `json:"internalLoadBalancerName"`
+	// Network load balancer and target group of user by name or ARN,
+	// masters are registered to the target group by TargetGroupARN
+	// instead of load balancers created for the cluster.
+	NetworkLoadBalancer string `json:"networkLoadBalancer,omitempty"`
+	TargetGroup         string `json:"targetGroup,omitempty"`
+	TargetGroupARN      string `json:"targetGroupArn,omitempty"`
+
+	// CreatedVPCID is set when VPC has been created for the cluster
+	CreatedVPCID string `json:"createdVpcId,omitempty"`
 
 	// Map of availability zone to subnet
 	Subnets map[string]string `json:"subnets"`
 	// Map az to route table association
 	RouteTableAssociationIDs map[string]string `json:"routeTableAssociationIds"`
+	// Script passed to instances as user data, it is empty for instances
+	// that are provisioned over ssh.
+	UserData string `json:"userData,omitempty"`
+	// Credentials are resolved from environment, shared config
+	// or instance profile when it is true.
+	UseDefaultCredentialChain string `json:"use_default_credential_chain"`
+}
+
+type SpotConfig struct {
+	SpotPrice        string     `json:"spotPrice"`
+	MachineCount     int64      `json:"machineCount"`
+	ValidUntil       *time.Time `json:"validUntil,omitempty"`
+	FallbackOnDemand bool       `json:"fallbackOnDemand"`
+	// FulfillmentTimeout in seconds
+	FulfillmentTimeout int64 `json:"fulfillmentTimeout"`
+	// Map of availability zone to count of machines requested in it
+	Zones map[string]int64 `json:"zones"`
+	// InstanceTypes of spot fleet, machines are requested with a single
+	// machine type when it is empty.
+	InstanceTypes      []SpotInstanceType `json:"instanceTypes,omitempty"`
+	AllocationStrategy string             `json:"allocationStrategy,omitempty"`
+
+	// These are filled by spot workflow steps and persisted with
+	// the task, so steps can be re-run after restart.
+	RequestIDs []string `json:"requestIds,omitempty"`
+	// Map of availability zone to spot requests made in it
+	ZoneRequests map[string][]string `json:"zoneRequests,omitempty"`
+	// Map of spot request id to instance id
+	Instances map[string]string `json:"instances,omitempty"`
+	// Map of spot request id to on-demand instance created instead
+	OnDemandInstances map[string]string `json:"onDemandInstances,omitempty"`
+	// Fleet and its instances created for spot fleet request
+	FleetID        string   `json:"fleetId,omitempty"`
+	FleetInstances []string `json:"fleetInstances,omitempty"`
+	// Map of instance id to machine name
+	Names map[string]string      `json:"names,omitempty"`
+	State model.SpotRequestState `json:"state,omitempty"`
+	// GroupID of the spot group machines are added to
+	GroupID string `json:"groupId,omitempty"`
+	// Launch specifications validated by AWS when request is a dry run
+	DryRunResults []SpotDryRunResult `json:"dryRunResults,omitempty"`
+}
+
+// SpotDryRunResult describes spot request that would be submitted
+// in availability zone if it was not a dry run.
+type SpotDryRunResult struct {
+	AvailabilityZone string `json:"availabilityZone"`
+	InstanceType     string `json:"instanceType"`
+	SubnetID         string `json:"subnetId"`
+	ImageID          string `json:"imageId"`
+	SpotPrice        string `json:"spotPrice"`
+	InstanceCount    int64  `json:"instanceCount"`
+}
+
+// SpotInstanceType is an instance type of spot fleet, weight is
+// the count of capacity units instance of the type provides.
+type SpotInstanceType struct {
+	InstanceType string  `json:"instanceType"`
+	Weight       float64 `json:"weight,omitempty"`
+}
+
+// AutoScalingConfig is AWS auto scaling group of the autoscaled node pool.
+type AutoScalingConfig struct {
+	// GroupName is also the name of launch template of the group
+	GroupName       string `json:"groupName"`
+	MinSize         int64  `json:"minSize"`
+	MaxSize         int64  `json:"maxSize"`
+	DesiredCapacity int64  `json:"desiredCapacity"`
+	// LaunchTemplateID is filled by the step that creates the group
+	LaunchTemplateID string `json:"launchTemplateId,omitempty"`
 }
 
 type DrainConfig struct {
 	PrivateIP string `json:"privateIp"`
+	// NodeName in kubernetes, node is found by private ip when it is not set.
+	NodeName string `json:"nodeName,omitempty"`
+	// GracePeriod of evicted pods in seconds, pods use their own when it is zero.
+	GracePeriod int64 `json:"gracePeriod,omitempty"`
+	// Timeout of the drain in seconds, default timeout is used when it is zero.
+	Timeout int64 `json:"timeout,omitempty"`
+	// Force deletion of the node when drain times out instead of aborting it.
+	Force bool `json:"force"`
+}
+
+// ReplaceMasterConfig describes the master replaced by the node of the task.
+type ReplaceMasterConfig struct {
+	Name      string `json:"name"`
+	PrivateIP string `json:"privateIp"`
+	// HealthyMasterIP is public ip of the master that prepares the cluster
+	// for the join of the new master and removes the old one from etcd.
+	HealthyMasterIP string `json:"healthyMasterIp"`
+}
+
+// EtcdConfig of the etcd dedicated to the kube.
+type EtcdConfig struct {
+	// Endpoints are client urls of provisioned etcd members, etcd is
+	// stacked on masters when there are none.
+	Endpoints []string `json:"endpoints,omitempty"`
+}
+
+type UpgradeConfig struct {
+	// BatchSize is the count of workers upgraded at the same time.
+	BatchSize int `json:"batchSize"`
 }
 
 type ApplyConfig struct {
@@ -155,6 +285,10 @@ type InstallAppConfig struct {
 	RepoName     string `json:"repoName" valid:"required"`
 	ChartRef     string `json:"chartRef"`
 	Values       string `json:"values"`
+	// Values documents merged in order before values and overrides
+	ValuesFiles     []string              `json:"valuesFiles"`
+	Set             []model.ValueOverride `json:"set"`
+	CreateNamespace bool                  `json:"createNamespace"`
 }
 
 type Map struct {
@@ -162,7 +296,16 @@ type Map struct {
 }
 
 func (m *Map) UnmarshalJSON(b []byte) error {
-	return json.Unmarshal(b, &m.internal)
+	if err := json.Unmarshal(b, &m.internal); err != nil {
+		return err
+	}
+
+	// Machines are added to config restored from null
+	if m.internal == nil {
+		m.internal = make(map[string]*model.Machine)
+	}
+
+	return nil
 }
 
 func (m *Map) MarshalJSON() ([]byte, error) {
@@ -183,6 +326,7 @@ type Config struct {
 	IsMaster           bool         `json:"isMaster"`
 	IsBootstrap        bool         `json:"IsBootstrap"`
 	IsImport           bool         `json:"isImport"`
+	IsEtcd             bool         `json:"isEtcd"`
 	DigitalOceanConfig DOConfig     `json:"digitalOceanConfig"`
 	AWSConfig          AWSConfig    `json:"awsConfig"`
 	GCEConfig          GCEConfig    `json:"gceConfig"`
@@ -190,18 +334,38 @@ type Config struct {
 	OSConfig           OSConfig     `json:"osConfig"`
 	PacketConfig       PacketConfig `json:"packetConfig"`
 
-	DrainConfig DrainConfig `json:"drainConfig"`
-	ConfigMap   ConfigMap   `json:"configMap"`
-	ApplyConfig ApplyConfig `json:"applyConfig"`
-	InstallAppConfig   InstallAppConfig   `json:"installAppConfig"`
+	DrainConfig         DrainConfig         `json:"drainConfig"`
+	ReplaceMasterConfig ReplaceMasterConfig `json:"replaceMasterConfig"`
+	UpgradeConfig       UpgradeConfig       `json:"upgradeConfig"`
+	EtcdConfig          EtcdConfig          `json:"etcdConfig"`
+	ConfigMap           ConfigMap           `json:"configMap"`
+	ApplyConfig         ApplyConfig         `json:"applyConfig"`
+	InstallAppConfig    InstallAppConfig    `json:"installAppConfig"`
+	SpotConfig          SpotConfig          `json:"spotConfig"`
+	AutoScalingConfig   AutoScalingConfig   `json:"autoScalingConfig"`
 
 	Provider clouds.Name `json:"provider"`
+	// Tags of the profile added to all cloud resources of the cluster
+	Tags map[string]string `json:"tags,omitempty"`
+	// Pool of the node being provisioned, taints and labels it registers with
+	Pool   string `json:"pool,omitempty"`
+	Taints string `json:"taints,omitempty"`
+	Labels string `json:"labels,omitempty"`
+	// GPU node is labeled and tainted for GPU workloads after it joins
+	GPU bool `json:"gpu,omitempty"`
 
 	Node             model.Machine `json:"node"`
 	CloudAccountID   string        `json:"cloudAccountId" valid:"required, length(1|32)"`
 	CloudAccountName string        `json:"cloudAccountName" valid:"required, length(1|32)"`
 	Timeout          time.Duration `json:"timeout"`
-	Runner           runner.Runner `json:"-"`
+	// Roll back completed steps when the task fails
+	RollbackOnFailure bool `json:"rollbackOnFailure"`
+	// Timeouts of steps by step name, they override default timeouts of steps
+	Timeouts map[string]time.Duration `json:"timeouts,omitempty"`
+	Runner   runner.Runner            `json:"-"`
+	// Hook scripts of masters and nodes
+	MasterHooks profile.Hooks `json:"masterHooks"`
+	NodeHooks   profile.Hooks `json:"nodeHooks"`
 
 	repository storage.Interface `json:"-"`
 
@@ -217,6 +381,8 @@ type Config struct {
 	nodeChan      chan model.Machine
 	kubeStateChan chan model.KubeState
 	configChan    chan *Config
+	// Spot requests are sent here as soon as they are submitted
+	spotRequestsChan chan []string
 }
 
 type ConfigMap struct {
@@ -225,8 +391,8 @@ type ConfigMap struct {
 
 // NewConfig builds instance of config for provisioning
 func NewConfig(clusterName, cloudAccountName string, profile profile.Profile) (*Config, error) {
-	if err := validateAddons(profile.Addons); err != nil {
-		return nil, err
+	if err := addons.Validate(profile.Addons, profile.K8SVersion); err != nil {
+		return nil, errors.Wrap(err, "validate addons")
 	}
 
 	var user = "root"
@@ -240,15 +406,16 @@ func NewConfig(clusterName, cloudAccountName string, profile profile.Profile) (*
 		user = clouds.OSUser
 	}
 
-	return &Config{
+	cfg := &Config{
 		Kube: model.Kube{
 			Name:       clusterName,
 			K8SVersion: profile.K8SVersion,
 			SSHConfig: model.SSHConfig{
-				Port:      "22",
-				User:      user,
-				Timeout:   30,
-				PublicKey: profile.PublicKey,
+				Port:        "22",
+				User:        user,
+				Timeout:     30,
+				PublicKey:   profile.PublicKey,
+				BastionHost: profile.BastionHost,
 			},
 			Auth: model.Auth{
 				Username:   profile.User,
@@ -261,29 +428,38 @@ func NewConfig(clusterName, cloudAccountName string, profile profile.Profile) (*
 				Type:     profile.NetworkType,
 				CIDR:     profile.CIDR,
 			},
-			Arch:             profile.Arch,
-			OperatingSystem:  profile.OperatingSystem,
-			DockerVersion:    profile.DockerVersion,
-			HelmVersion:      profile.HelmVersion,
-			ExposedAddresses: profile.ExposedAddresses,
-			APIServerPort:    ensurePort(profile.K8SAPIPort),
-			Provider:         profile.Provider,
-			RBACEnabled:      profile.RBACEnabled,
-			ServicesCIDR:     profile.K8SServicesCIDR,
-			Addons:           profile.Addons,
+			Arch:               profile.Arch,
+			OperatingSystem:    profile.OperatingSystem,
+			DockerVersion:      profile.DockerVersion,
+			HelmVersion:        profile.HelmVersion,
+			ExposedAddresses:   profile.ExposedAddresses,
+			APIServerPort:      ensurePort(profile.K8SAPIPort),
+			Provider:           profile.Provider,
+			RBACEnabled:        profile.RBACEnabled,
+			ServicesCIDR:       profile.K8SServicesCIDR,
+			Addons:             profile.Addons,
+			PrivateNetworking:  profile.PrivateNetworking,
+			KubeletExtraArgs:   profile.KubeletExtraArgs,
+			APIServerExtraArgs: profile.APIServerExtraArgs,
+			ClusterAutoscaler:  profile.ClusterAutoscaler,
 		},
 		Provider: profile.Provider,
+		Tags:     profile.Tags,
 		DigitalOceanConfig: DOConfig{
 			Region: profile.Region,
 		},
 		AWSConfig: AWSConfig{
-			Region:                 profile.Region,
-			AvailabilityZone:       profile.CloudSpecificSettings[clouds.AwsAZ],
-			VPCCIDR:                profile.CloudSpecificSettings[clouds.AwsVpcCIDR],
-			VPCID:                  profile.CloudSpecificSettings[clouds.AwsVpcID],
-			KeyPairName:            profile.CloudSpecificSettings[clouds.AwsKeyPairName],
-			MastersSecurityGroupID: profile.CloudSpecificSettings[clouds.AwsMastersSecGroupID],
-			NodesSecurityGroupID:   profile.CloudSpecificSettings[clouds.AwsNodesSecgroupID],
+			Region:                  profile.Region,
+			AvailabilityZone:        profile.CloudSpecificSettings[clouds.AwsAZ],
+			VPCCIDR:                 profile.CloudSpecificSettings[clouds.AwsVpcCIDR],
+			VPCID:                   profile.CloudSpecificSettings[clouds.AwsVpcID],
+			KeyPairName:             profile.CloudSpecificSettings[clouds.AwsKeyPairName],
+			MastersSecurityGroupID:  profile.CloudSpecificSettings[clouds.AwsMastersSecGroupID],
+			NodesSecurityGroupID:    profile.CloudSpecificSettings[clouds.AwsNodesSecgroupID],
+			EbsEncrypted:            profile.CloudSpecificSettings[clouds.AwsEbsEncrypted],
+			KmsKeyID:                profile.CloudSpecificSettings[clouds.AwsKmsKeyID],
+			HttpTokens:              profile.CloudSpecificSettings[clouds.AwsHttpTokens],
+			HttpPutResponseHopLimit: profile.CloudSpecificSettings[clouds.AwsHttpPutResponseHopLimit],
 			// TODO(stgleb): Passs this from UI or figure out any better way
 			DeviceName: "/dev/sda1",
 		},
@@ -309,12 +485,90 @@ func NewConfig(clusterName, cloudAccountName string, profile profile.Profile) (*
 			internal: make(map[string]*model.Machine, len(profile.NodesProfiles)),
 		},
 		Timeout:          time.Minute * 60,
+		Timeouts:         stepTimeouts(profile.StepTimeouts),
+		MasterHooks:      profile.MasterHooks,
+		NodeHooks:        profile.NodeHooks,
 		CloudAccountName: cloudAccountName,
 
 		nodeChan:      make(chan model.Machine, len(profile.MasterProfiles)+len(profile.NodesProfiles)),
 		kubeStateChan: make(chan model.KubeState, 2),
 		configChan:    make(chan *Config),
-	}, nil
+	}
+
+	if profile.Provider == clouds.AWS {
+		setAWSResources(cfg, profile)
+	}
+
+	return cfg, nil
+}
+
+// setAWSResources records VPC, subnets, security groups and load balancer
+// provided by user, provisioning validates them instead of creating new ones.
+func setAWSResources(cfg *Config, p profile.Profile) {
+	// Default VPC is looked up by provisioning
+	if vpcID := cfg.AWSConfig.VPCID; vpcID != "" && vpcID != DefaultVPCID {
+		cfg.Kube.SetOwner(vpcID, model.OwnerUser)
+	}
+
+	if len(p.Subnets) > 0 {
+		cfg.AWSConfig.Subnets = make(map[string]string, len(p.Subnets))
+	}
+
+	for az, subnetID := range p.Subnets {
+		cfg.AWSConfig.Subnets[az] = subnetID
+		cfg.Kube.SetOwner(subnetID, model.OwnerUser)
+	}
+
+	if lb := p.ExternalLoadBalancer; lb != nil {
+		cfg.AWSConfig.NetworkLoadBalancer = lb.LoadBalancer
+		cfg.AWSConfig.TargetGroup = lb.TargetGroup
+	}
+
+	for _, groupID := range []string{cfg.AWSConfig.MastersSecurityGroupID,
+		cfg.AWSConfig.NodesSecurityGroupID} {
+		if groupID != "" {
+			cfg.Kube.SetOwner(groupID, model.OwnerUser)
+		}
+	}
+}
+
+// stepTimeouts converts timeouts of profile in seconds.
+func stepTimeouts(timeouts map[string]int64) map[string]time.Duration {
+	if len(timeouts) == 0 {
+		return nil
+	}
+
+	result := make(map[string]time.Duration, len(timeouts))
+
+	for stepName, seconds := range timeouts {
+		result[stepName] = time.Duration(seconds) * time.Second
+	}
+
+	return result
+}
+
+// checkNetworking rejects profile which networking differs from the one
+// kube has been provisioned with, it can't be changed on existing kube.
+func checkNetworking(p *profile.Profile, k *model.Kube) error {
+	changes := []struct {
+		name    string
+		current string
+		next    string
+	}{
+		{"pod CIDR", k.Networking.CIDR, p.CIDR},
+		{"service CIDR", k.ServicesCIDR, p.K8SServicesCIDR},
+		{"network provider", k.Networking.Provider, p.NetworkProvider},
+	}
+
+	for _, change := range changes {
+		if change.current != "" && change.next != "" && change.current != change.next {
+			return errors.Wrapf(sgerrors.ErrValidationFailed,
+				"%s of existing kube %s can't be changed from %s to %s",
+				change.name, k.ID, change.current, change.next)
+		}
+	}
+
+	return nil
 }
 
 // TODO(stgleb): Compare that to LoadCloudSpecificDataFromKube
@@ -323,6 +577,10 @@ func NewConfigFromKube(profile *profile.Profile, k *model.Kube) (*Config, error)
 		return nil, errors.Wrapf(sgerrors.ErrNilEntity, "kube must not be nil")
 	}
 
+	if err := checkNetworking(profile, k); err != nil {
+		return nil, err
+	}
+
 	var user string
 
 	if profile.Provider == clouds.AWS {
@@ -336,6 +594,7 @@ func NewConfigFromKube(profile *profile.Profile, k *model.Kube) (*Config, error)
 
 	cfg := &Config{
 		Provider: profile.Provider,
+		Tags:     profile.Tags,
 		DigitalOceanConfig: DOConfig{
 			Region: profile.Region,
 		},
@@ -352,6 +611,7 @@ func NewConfigFromKube(profile *profile.Profile, k *model.Kube) (*Config, error)
 			ImageID:                  k.CloudSpec[clouds.AwsImageID],
 			ExternalLoadBalancerName: k.CloudSpec[clouds.AwsExternalLoadBalancerName],
 			InternalLoadBalancerName: k.CloudSpec[clouds.AwsInternalLoadBalancerName],
+			TargetGroupARN:           k.CloudSpec[clouds.AwsTargetGroupARN],
 			// TODO(stgleb): Passs this from UI or figure out any better way
 			DeviceName: "/dev/sda1",
 		},
@@ -370,6 +630,9 @@ func NewConfigFromKube(profile *profile.Profile, k *model.Kube) (*Config, error)
 			internal: make(map[string]*model.Machine, len(profile.NodesProfiles)),
 		},
 		Timeout:          time.Minute * 60,
+		Timeouts:         stepTimeouts(profile.StepTimeouts),
+		MasterHooks:      profile.MasterHooks,
+		NodeHooks:        profile.NodeHooks,
 		CloudAccountName: k.AccountName,
 		nodeChan:         make(chan model.Machine, len(profile.MasterProfiles)+len(profile.NodesProfiles)),
 		kubeStateChan:    make(chan model.KubeState, 5),
@@ -388,10 +651,11 @@ func NewConfigFromKube(profile *profile.Profile, k *model.Kube) (*Config, error)
 	cfg.Kube = *k
 
 	cfg.Kube.SSHConfig = model.SSHConfig{
-		Port:      "22",
-		User:      user,
-		Timeout:   10,
-		PublicKey: profile.PublicKey,
+		Port:        "22",
+		User:        user,
+		Timeout:     10,
+		PublicKey:   profile.PublicKey,
+		BastionHost: k.SSHConfig.BastionHost,
 	}
 
 	return cfg, nil
@@ -405,13 +669,109 @@ func (c *Config) AddMaster(n *model.Machine) {
 	c.Masters.internal[n.ID] = n
 }
 
-// AddNode to map of nodes in cluster
+// AddNode to map of nodes in cluster, etcd members are not nodes of the cluster
 func (c *Config) AddNode(n *model.Machine) {
+	if n.Role == model.RoleEtcd {
+		return
+	}
+
 	c.m2.Lock()
 	defer c.m2.Unlock()
 	c.Nodes.internal[n.ID] = n
 }
 
+// Clone returns a copy of the config that a task can change without
+// affecting the config it has been made of, machines of the cluster are
+// copied as well.
+func (c *Config) Clone() *Config {
+	return &Config{
+		Kube:                c.Kube,
+		DryRun:              c.DryRun,
+		TaskID:              c.TaskID,
+		IsMaster:            c.IsMaster,
+		IsBootstrap:         c.IsBootstrap,
+		IsImport:            c.IsImport,
+		IsEtcd:              c.IsEtcd,
+		DigitalOceanConfig:  c.DigitalOceanConfig,
+		AWSConfig:           c.AWSConfig,
+		GCEConfig:           c.GCEConfig,
+		AzureConfig:         c.AzureConfig,
+		OSConfig:            c.OSConfig,
+		PacketConfig:        c.PacketConfig,
+		DrainConfig:         c.DrainConfig,
+		ReplaceMasterConfig: c.ReplaceMasterConfig,
+		UpgradeConfig:       c.UpgradeConfig,
+		EtcdConfig:          c.EtcdConfig,
+		ConfigMap:           c.ConfigMap,
+		ApplyConfig:         c.ApplyConfig,
+		InstallAppConfig:    c.InstallAppConfig,
+		SpotConfig:          c.SpotConfig,
+		AutoScalingConfig:   c.AutoScalingConfig,
+		Provider:            c.Provider,
+		Tags:                c.Tags,
+		Pool:                c.Pool,
+		Taints:              c.Taints,
+		Labels:              c.Labels,
+		GPU:                 c.GPU,
+		Node:                c.Node,
+		CloudAccountID:      c.CloudAccountID,
+		CloudAccountName:    c.CloudAccountName,
+		Timeout:             c.Timeout,
+		RollbackOnFailure:   c.RollbackOnFailure,
+		Timeouts:            c.Timeouts,
+		Runner:              c.Runner,
+		MasterHooks:         c.MasterHooks,
+		NodeHooks:           c.NodeHooks,
+		repository:          c.repository,
+		Masters: Map{
+			internal: c.copyMasters(),
+		},
+		Nodes: Map{
+			internal: c.copyNodes(),
+		},
+		azureAthorizer:   c.GetAzureAuthorizer(),
+		nodeChan:         c.nodeChan,
+		kubeStateChan:    c.kubeStateChan,
+		configChan:       c.configChan,
+		spotRequestsChan: c.spotRequestsChan,
+	}
+}
+
+func (c *Config) copyMasters() map[string]*model.Machine {
+	c.m1.RLock()
+	defer c.m1.RUnlock()
+
+	m := make(map[string]*model.Machine, len(c.Masters.internal))
+
+	for key, machine := range c.Masters.internal {
+		m[key] = machine
+	}
+
+	return m
+}
+
+func (c *Config) copyNodes() map[string]*model.Machine {
+	c.m2.RLock()
+	defer c.m2.RUnlock()
+
+	m := make(map[string]*model.Machine, len(c.Nodes.internal))
+
+	for key, machine := range c.Nodes.internal {
+		m[key] = machine
+	}
+
+	return m
+}
+
+// Role of the machine provisioned by the config
+func (c *Config) Role() model.Role {
+	if c.IsEtcd {
+		return model.RoleEtcd
+	}
+
+	return model.ToRole(c.IsMaster)
+}
+
 // GetMaster returns first master in master map or nil
 func (c *Config) GetMaster() *model.Machine {
 	// non-blocking fast path for master nodes
@@ -505,6 +865,14 @@ func (c *Config) SetConfigChan(configChan chan *Config) {
 	c.configChan = configChan
 }
 
+func (c *Config) SpotRequestsChan() chan []string {
+	return c.spotRequestsChan
+}
+
+func (c *Config) SetSpotRequestsChan(spotRequestsChan chan []string) {
+	c.spotRequestsChan = spotRequestsChan
+}
+
 func (c *Config) SetAzureAuthorizer(a autorest.Authorizer) {
 	c.authorizerMux.Lock()
 	defer c.authorizerMux.Unlock()
@@ -525,18 +893,3 @@ func ensurePort(p int64) int64 {
 	}
 	return p
 }
-
-func validateAddons(in []string) error {
-	invalid := make([]string, 0)
-	for _, addon := range in {
-		for _, registered := range []string{"dashboard"} {
-			if addon != registered {
-				invalid = append(invalid, addon)
-			}
-		}
-	}
-	if len(invalid) > 0 {
-		return fmt.Errorf("validate addons: unknown: %v", invalid)
-	}
-	return nil
-}
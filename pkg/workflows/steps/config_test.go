@@ -2,11 +2,14 @@ package steps
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/supergiant/control/pkg/clouds"
 	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/sgerrors"
 )
 
 func TestMarshalConfig(t *testing.T) {
@@ -45,6 +48,114 @@ func TestMarshalConfig(t *testing.T) {
 	}
 }
 
+// Spot task is resumed with config of the task restored from storage,
+// outputs of completed steps must survive the round trip.
+func TestConfigRoundTripSpot(t *testing.T) {
+	validUntil := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	cfg := &Config{
+		TaskID:   "task-1",
+		Provider: clouds.AWS,
+		Tags:     map[string]string{"team": "infra"},
+		Pool:     "spot",
+		Taints:   "spot=true:NoSchedule",
+		Kube: model.Kube{
+			ID:   "kube-1",
+			Name: "test",
+		},
+		AWSConfig: AWSConfig{
+			Region:                  "us-west-1",
+			KeyPairName:             "test-key",
+			VPCID:                   "vpc-1",
+			NodesSecurityGroupID:    "sg-1",
+			NodesInstanceProfile:    "nodes-profile",
+			ImageID:                 "ami-1",
+			InstanceType:            "m5.large",
+			VolumeSize:              "80",
+			VolumeType:              "gp3",
+			EbsOptimized:            "true",
+			HttpTokens:              "required",
+			HttpPutResponseHopLimit: "2",
+			Subnets: map[string]string{
+				"us-west-1a": "subnet-1",
+				"us-west-1b": "subnet-2",
+			},
+			UserData: "#!/bin/bash",
+		},
+		SpotConfig: SpotConfig{
+			SpotPrice:          "0.1",
+			MachineCount:       3,
+			ValidUntil:         &validUntil,
+			FallbackOnDemand:   true,
+			FulfillmentTimeout: 600,
+			Zones:              map[string]int64{"us-west-1a": 2, "us-west-1b": 1},
+			InstanceTypes: []SpotInstanceType{
+				{InstanceType: "m5.large", Weight: 2},
+			},
+			AllocationStrategy: "lowestPrice",
+			RequestIDs:         []string{"sir-1", "sir-2"},
+			ZoneRequests: map[string][]string{
+				"us-west-1a": {"sir-1"},
+				"us-west-1b": {"sir-2"},
+			},
+			Instances:         map[string]string{"sir-1": "i-1"},
+			OnDemandInstances: map[string]string{"sir-2": "i-2"},
+			FleetID:           "sfr-1",
+			FleetInstances:    []string{"i-3"},
+			Names:             map[string]string{"i-1": "test-node-1"},
+			State:             model.SpotRequestFallback,
+		},
+		Nodes: Map{
+			internal: map[string]*model.Machine{
+				"i-1": {ID: "i-1", Name: "test-node-1", State: model.MachineStateProvisioning},
+			},
+		},
+	}
+
+	data, err := json.Marshal(cfg)
+
+	if err != nil {
+		t.Fatalf("Marshall json %v", err)
+	}
+
+	restored := &Config{}
+
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("Unmarshall json %v", err)
+	}
+
+	if restored.TaskID != cfg.TaskID || restored.Provider != cfg.Provider ||
+		restored.Pool != cfg.Pool || restored.Taints != cfg.Taints ||
+		restored.Kube.ID != cfg.Kube.ID || !reflect.DeepEqual(restored.Tags, cfg.Tags) {
+		t.Errorf("Wrong config restored %+v", restored)
+	}
+
+	if !reflect.DeepEqual(restored.AWSConfig, cfg.AWSConfig) {
+		t.Errorf("Wrong aws config expected %+v actual %+v", cfg.AWSConfig, restored.AWSConfig)
+	}
+
+	if restored.SpotConfig.ValidUntil == nil || !restored.SpotConfig.ValidUntil.Equal(validUntil) {
+		t.Errorf("Wrong valid until expected %v actual %v", validUntil, restored.SpotConfig.ValidUntil)
+	}
+
+	restored.SpotConfig.ValidUntil = cfg.SpotConfig.ValidUntil
+
+	if !reflect.DeepEqual(restored.SpotConfig, cfg.SpotConfig) {
+		t.Errorf("Wrong spot config expected %+v actual %+v", cfg.SpotConfig, restored.SpotConfig)
+	}
+
+	if !reflect.DeepEqual(restored.GetNodes(), cfg.GetNodes()) {
+		t.Errorf("Wrong nodes expected %v actual %v", cfg.GetNodes(), restored.GetNodes())
+	}
+
+	// Machines are added to restored config without masters
+	restored.AddMaster(&model.Machine{ID: "i-4"})
+
+	if len(restored.GetMasters()) != 1 {
+		t.Errorf("Master must be added to restored config")
+	}
+}
+
 func TestNewConfig(t *testing.T) {
 	clusterName := "testCluster"
 	cloudAccountName := "cloudAccountName"
@@ -77,6 +188,51 @@ func TestNewConfig(t *testing.T) {
 	}
 }
 
+func TestNewConfigAWSResources(t *testing.T) {
+	cfg, err := NewConfig("test", "", profile.Profile{
+		Provider: clouds.AWS,
+		Subnets: map[string]string{
+			"us-west-1a": "subnet-1",
+		},
+		CloudSpecificSettings: profile.CloudSpecificSettings{
+			clouds.AwsVpcID:             "vpc-1",
+			clouds.AwsMastersSecGroupID: "sg-masters",
+			clouds.AwsNodesSecgroupID:   "sg-nodes",
+		},
+	})
+
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+		return
+	}
+
+	if cfg.AWSConfig.Subnets["us-west-1a"] != "subnet-1" {
+		t.Errorf("Wrong subnets %v", cfg.AWSConfig.Subnets)
+	}
+
+	for _, resourceID := range []string{"vpc-1", "subnet-1", "sg-masters", "sg-nodes"} {
+		if !cfg.Kube.IsUserResource(resourceID) {
+			t.Errorf("Resource %s must be owned by user", resourceID)
+		}
+	}
+
+	cfg, err = NewConfig("test", "", profile.Profile{
+		Provider: clouds.AWS,
+		CloudSpecificSettings: profile.CloudSpecificSettings{
+			clouds.AwsVpcID: DefaultVPCID,
+		},
+	})
+
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+		return
+	}
+
+	if len(cfg.Kube.ResourceOwners) != 0 {
+		t.Errorf("Unexpected resource owners %v", cfg.Kube.ResourceOwners)
+	}
+}
+
 func TestAddMaster(t *testing.T) {
 	n := &model.Machine{
 		Role: model.RoleMaster,
@@ -115,6 +271,41 @@ func TestAddNode(t *testing.T) {
 	}
 }
 
+func TestConfigClone(t *testing.T) {
+	cfg := &Config{
+		TaskID:   "task",
+		Provider: clouds.AWS,
+		Masters: Map{
+			internal: map[string]*model.Machine{
+				"master": {Name: "master"},
+			},
+		},
+		Nodes: Map{
+			internal: make(map[string]*model.Machine),
+		},
+		nodeChan: make(chan model.Machine),
+	}
+
+	clone := cfg.Clone()
+
+	if clone.TaskID != cfg.TaskID || clone.Provider != cfg.Provider ||
+		clone.NodeChan() != cfg.NodeChan() {
+		t.Errorf("Wrong clone %+v", clone)
+	}
+
+	if len(clone.GetMasters()) != 1 {
+		t.Errorf("Wrong masters count of clone expected %d actual %d",
+			1, len(clone.GetMasters()))
+	}
+
+	clone.TaskID = "clone"
+	clone.AddNode(&model.Machine{ID: "node"})
+
+	if cfg.TaskID != "task" || len(cfg.GetNodes()) != 0 {
+		t.Errorf("Config must not be changed by its clone")
+	}
+}
+
 func TestConfigGetMaster(t *testing.T) {
 	n := &model.Machine{
 		Name:  "master-1",
@@ -318,3 +509,59 @@ func TestNewConfigFromKube(t *testing.T) {
 			expectedNodeCount+expectedMasterCount, len(cfg.Nodes.internal)+len(cfg.Masters.internal))
 	}
 }
+
+func TestNewConfigFromKubeNetworkingChanged(t *testing.T) {
+	k := &model.Kube{
+		ID: "kube",
+		Networking: model.Networking{
+			Provider: profile.Calico,
+			CIDR:     "10.0.0.0/16",
+		},
+		ServicesCIDR: "10.3.0.0/16",
+	}
+
+	testCases := []struct {
+		description string
+		profile     profile.Profile
+		isErr       bool
+	}{
+		{
+			description: "same",
+			profile: profile.Profile{
+				NetworkProvider: profile.Calico,
+				CIDR:            "10.0.0.0/16",
+				K8SServicesCIDR: "10.3.0.0/16",
+			},
+		},
+		{
+			description: "not set",
+		},
+		{
+			description: "pod cidr",
+			profile:     profile.Profile{CIDR: "192.168.0.0/16"},
+			isErr:       true,
+		},
+		{
+			description: "service cidr",
+			profile:     profile.Profile{K8SServicesCIDR: "10.96.0.0/12"},
+			isErr:       true,
+		},
+		{
+			description: "network provider",
+			profile:     profile.Profile{NetworkProvider: profile.Cilium},
+			isErr:       true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		_, err := NewConfigFromKube(&testCase.profile, k)
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("%s: wrong error %v", testCase.description, err)
+		}
+
+		if err != nil && !sgerrors.IsValidationFailed(err) {
+			t.Errorf("%s: wrong error type %v", testCase.description, err)
+		}
+	}
+}
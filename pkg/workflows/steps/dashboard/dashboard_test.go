@@ -1,100 +0,0 @@
-package dashboard
-
-import (
-	"context"
-	"io"
-	"io/ioutil"
-	"strings"
-	"testing"
-	"text/template"
-
-	"github.com/pkg/errors"
-
-	"github.com/supergiant/control/pkg/runner"
-	"github.com/supergiant/control/pkg/templatemanager"
-	"github.com/supergiant/control/pkg/workflows/steps"
-)
-
-type fakeRunner struct {
-	errMsg string
-}
-
-func (f *fakeRunner) Run(command *runner.Command) error {
-	if len(f.errMsg) > 0 {
-		return errors.New(f.errMsg)
-	}
-
-	_, err := io.Copy(command.Out, strings.NewReader(command.Script))
-	return err
-}
-
-func TestStepName(t *testing.T) {
-	s := Step{}
-
-	if s.Name() != StepName {
-		t.Errorf("Unexpected step name expected %s actual %s", StepName, s.Name())
-	}
-}
-
-func TestDepends(t *testing.T) {
-	s := Step{}
-
-	if len(s.Depends()) != 0 {
-		t.Errorf("Wrong dependency list %v expected %v", s.Depends(), []string{})
-	}
-}
-
-func TestStep_Rollback(t *testing.T) {
-	s := Step{}
-	err := s.Rollback(context.Background(), ioutil.Discard, &steps.Config{})
-
-	if err != nil {
-		t.Errorf("unexpected error while rollback %v", err)
-	}
-}
-
-func TestNew(t *testing.T) {
-	tpl := template.New("test")
-	s := New(tpl)
-
-	if s.script != tpl {
-		t.Errorf("Wrong template expected %v actual %v", tpl, s.script)
-	}
-}
-
-func TestInit(t *testing.T) {
-	templatemanager.SetTemplate(StepName, &template.Template{})
-	Init()
-	templatemanager.DeleteTemplate(StepName)
-
-	s := steps.GetStep(StepName)
-
-	if s == nil {
-		t.Error("Step not found")
-	}
-}
-
-func TestInitPanic(t *testing.T) {
-	defer func() {
-		if r := recover(); r == nil {
-			t.Errorf("recover output must not be nil")
-		}
-	}()
-
-	Init()
-
-	s := steps.GetStep("not_found.sh.tpl")
-
-	if s == nil {
-		t.Error("Step not found")
-	}
-}
-
-func TestStep_Description(t *testing.T) {
-	s := &Step{}
-
-	if desc := s.Description(); desc != "Install kubernetes dashboard" {
-		t.Errorf("Wrong desription expected %s actual %s",
-			"Install kubernetes dashboard", desc)
-	}
-}
@@ -14,6 +14,7 @@ import (
 	"github.com/supergiant/control/pkg/clouds"
 	"github.com/supergiant/control/pkg/clouds/digitaloceansdk"
 	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/profile"
 	"github.com/supergiant/control/pkg/sgerrors"
 	"github.com/supergiant/control/pkg/util"
 	"github.com/supergiant/control/pkg/workflows/steps"
@@ -62,6 +63,10 @@ func (s *CreateInstanceStep) Run(ctx context.Context, output io.Writer, config *
 		tags = append(tags, fmt.Sprintf("master-%s", config.Kube.ID))
 	}
 
+	for key, value := range config.Tags {
+		tags = append(tags, profile.DOTag(key, value))
+	}
+
 	dropletRequest := &godo.DropletCreateRequest{
 		Name:              config.DigitalOceanConfig.Name,
 		Region:            config.DigitalOceanConfig.Region,
@@ -74,10 +79,7 @@ func (s *CreateInstanceStep) Run(ctx context.Context, output io.Writer, config *
 		Tags: tags,
 	}
 
-	role := model.RoleMaster
-	if !config.IsMaster {
-		role = model.RoleNode
-	}
+	role := config.Role()
 
 	config.Node = model.Machine{
 		TaskID:   config.TaskID,
@@ -87,6 +89,7 @@ func (s *CreateInstanceStep) Run(ctx context.Context, output io.Writer, config *
 		Region:   config.DigitalOceanConfig.Region,
 		State:    model.MachineStateBuilding,
 		Name:     config.DigitalOceanConfig.Name,
+		Pool:     config.Pool,
 	}
 
 	// Update node state in cluster
@@ -99,7 +102,7 @@ func (s *CreateInstanceStep) Run(ctx context.Context, output io.Writer, config *
 		return errors.Wrap(err, "dropletService has returned an error in Run job")
 	}
 
-	after := time.After(s.DropletTimeout)
+	after := time.After(steps.WaitTimeout(ctx, s.DropletTimeout))
 	ticker := time.NewTicker(s.CheckPeriod)
 
 	for {
@@ -148,10 +151,20 @@ func (s *CreateInstanceStep) Rollback(context.Context, io.Writer, *steps.Config)
 	return nil
 }
 
+// RetryPolicy opts out of retries, droplet would be created twice.
+func (s *CreateInstanceStep) RetryPolicy() steps.RetryPolicy {
+	return steps.NoRetry
+}
+
 func (s *CreateInstanceStep) Name() string {
 	return CreateMachineStepName
 }
 
+// DefaultTimeout is how long droplet is waited to become active.
+func (s *CreateInstanceStep) DefaultTimeout() time.Duration {
+	return s.DropletTimeout
+}
+
 func (s *CreateInstanceStep) Depends() []string {
 	return nil
 }
@@ -41,7 +41,7 @@ func New(tpl *template.Template) *Step {
 }
 
 func (s *Step) Run(ctx context.Context, out io.Writer, config *steps.Config) error {
-	err := steps.RunTemplate(context.Background(), s.script, config.Runner, out, toStepCfg(config))
+	err := steps.RunTemplate(ctx, s.script, config.Runner, out, toStepCfg(config))
 	if err != nil {
 		return errors.Wrap(err, "download k8s binary step")
 	}
@@ -4,87 +4,239 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"text/template"
+	"time"
 
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 
-	"github.com/supergiant/control/pkg/clouds"
-	"github.com/supergiant/control/pkg/runner"
-	"github.com/supergiant/control/pkg/runner/ssh"
+	"github.com/supergiant/control/pkg/kubeconfig"
+	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/sgerrors"
-	tm "github.com/supergiant/control/pkg/templatemanager"
 	"github.com/supergiant/control/pkg/workflows/steps"
 )
 
-const StepName = "drain"
+const (
+	StepName = "drain"
 
+	// DefaultTimeout of eviction of node pods
+	DefaultTimeout = 5 * time.Minute
+	// PollInterval between attempts to evict pods protected
+	// by disruption budgets and checks of evicted pods
+	PollInterval = 5 * time.Second
+
+	// Static pods are managed by kubelet and can't be evicted
+	mirrorPodAnnotation = "kubernetes.io/config.mirror"
+)
+
+// Step cordons the node, evicts its pods respecting pod disruption
+// budgets and deletes the node object from kubernetes.
 type Step struct {
-	script    *template.Template
-	getRunner func(string, *steps.Config) (runner.Runner, error)
+	getClient    func(*model.Kube) (kubernetes.Interface, error)
+	pollInterval time.Duration
 }
 
-func (s *Step) Rollback(context.Context, io.Writer, *steps.Config) error {
-	return nil
+func Init() {
+	steps.RegisterStep(StepName, New())
 }
 
-func Init() {
-	tpl, err := tm.GetTemplate(StepName)
+func New() *Step {
+	return &Step{
+		getClient: func(k *model.Kube) (kubernetes.Interface, error) {
+			cfg, err := kubeconfig.NewConfigFor(k)
+
+			if err != nil {
+				return nil, errors.Wrap(err, "build kubernetes rest config")
+			}
+
+			return kubernetes.NewForConfig(cfg)
+		},
+		pollInterval: PollInterval,
+	}
+}
+
+func (s *Step) Run(ctx context.Context, out io.Writer, config *steps.Config) error {
+	client, err := s.getClient(&config.Kube)
+
+	if err != nil {
+		return errors.Wrap(err, "build kubernetes client")
+	}
+
+	node, err := findNode(client, config.DrainConfig)
+
+	// Node that has not joined the cluster has nothing to drain
+	if sgerrors.IsNotFound(err) {
+		fmt.Fprintf(out, "node %s is not found in kubernetes, skip drain\n",
+			config.DrainConfig.PrivateIP)
+		return nil
+	}
 
 	if err != nil {
-		panic(fmt.Sprintf("template %s not found", StepName))
+		return err
+	}
+
+	if err := setUnschedulable(client, node.Name, true); err != nil {
+		return errors.Wrapf(err, "cordon node %s", node.Name)
 	}
 
-	steps.RegisterStep(StepName, New(tpl))
+	fmt.Fprintf(out, "node %s cordoned\n", node.Name)
+
+	timeout := DefaultTimeout
+
+	if config.DrainConfig.Timeout > 0 {
+		timeout = time.Duration(config.DrainConfig.Timeout) * time.Second
+	}
+
+	drainCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := s.drain(drainCtx, out, client, node.Name, config.DrainConfig); err != nil {
+		// Only expiry of the drain timeout may be ignored
+		if drainCtx.Err() == nil || ctx.Err() != nil {
+			return err
+		}
+
+		if !config.DrainConfig.Force {
+			return errors.Wrapf(sgerrors.ErrTimeoutExceeded, "drain node %s: %v", node.Name, err)
+		}
+
+		fmt.Fprintf(out, "drain node %s timed out, deletion is forced: %v\n", node.Name, err)
+	}
+
+	err = client.CoreV1().Nodes().Delete(node.Name, &metav1.DeleteOptions{})
+
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "delete node %s", node.Name)
+	}
+
+	fmt.Fprintf(out, "node %s deleted from kubernetes\n", node.Name)
+
+	return nil
 }
 
-func New(script *template.Template) *Step {
-	t := &Step{
-		script: script,
-		getRunner: func(masterIp string, config *steps.Config) (runner.Runner, error) {
-			if config.Provider == clouds.AWS {
-				//on aws default user name on ubuntu images are not root but ubuntu
-				//https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/AccessingInstancesLinux.html
-				config.Kube.SSHConfig.User = "ubuntu"
-			}
+// drain evicts pods of the node and waits until they are gone, evictions
+// refused by disruption budgets are retried until context is done.
+func (s *Step) drain(ctx context.Context, out io.Writer, client kubernetes.Interface,
+	nodeName string, cfg steps.DrainConfig) error {
+	pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
 
-			cfg := ssh.Config{
-				Host:    masterIp,
-				Port:    config.Kube.SSHConfig.Port,
-				User:    config.Kube.SSHConfig.User,
-				Timeout: 10,
-				Key:     []byte(config.Kube.SSHConfig.BootstrapPrivateKey),
-			}
+	if err != nil {
+		return errors.Wrapf(err, "list pods of node %s", nodeName)
+	}
 
-			sshRunner, err := ssh.NewRunner(cfg)
+	var gracePeriod *int64
 
-			if err != nil {
-				return nil, errors.Wrapf(err, "create ssh runner")
+	if cfg.GracePeriod > 0 {
+		gracePeriod = &cfg.GracePeriod
+	}
+
+	evicted := make(map[types.NamespacedName]types.UID)
+	pending := evictable(pods.Items)
+
+	for len(pending) > 0 {
+		var refused []corev1.Pod
+
+		for _, pod := range pending {
+			err := client.PolicyV1beta1().Evictions(pod.Namespace).Evict(&policy.Eviction{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      pod.Name,
+					Namespace: pod.Namespace,
+				},
+				DeleteOptions: &metav1.DeleteOptions{
+					GracePeriodSeconds: gracePeriod,
+				},
+			})
+
+			switch {
+			case err == nil:
+				fmt.Fprintf(out, "evicting pod %s/%s\n", pod.Namespace, pod.Name)
+				evicted[types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}] = pod.UID
+			case apierrors.IsNotFound(err):
+			case apierrors.IsTooManyRequests(err):
+				refused = append(refused, pod)
+			default:
+				return errors.Wrapf(err, "evict pod %s/%s", pod.Namespace, pod.Name)
 			}
+		}
 
-			return sshRunner, err
-		},
+		if pending = refused; len(pending) == 0 {
+			break
+		}
+
+		fmt.Fprintf(out, "eviction of %d pods is refused by disruption budgets, retry in %s\n",
+			len(pending), s.pollInterval)
+
+		select {
+		case <-ctx.Done():
+			return errors.Errorf("%d pods of node %s are not evicted", len(pending), nodeName)
+		case <-time.After(s.pollInterval):
+		}
 	}
 
-	return t
+	return s.waitDeleted(ctx, client, evicted)
 }
 
-func (s *Step) Run(ctx context.Context, out io.Writer, config *steps.Config) error {
-	masterNode := config.GetMaster()
+// waitDeleted waits until evicted pods are gone, pod recreated with
+// the same name by a stateful set is a new one.
+func (s *Step) waitDeleted(ctx context.Context, client kubernetes.Interface,
+	evicted map[types.NamespacedName]types.UID) error {
+	for {
+		for key, uid := range evicted {
+			pod, err := client.CoreV1().Pods(key.Namespace).Get(key.Name, metav1.GetOptions{})
+
+			if apierrors.IsNotFound(err) || (err == nil && pod.UID != uid) {
+				delete(evicted, key)
+				continue
+			}
+
+			if err != nil {
+				return errors.Wrapf(err, "get pod %s", key)
+			}
+		}
 
-	if masterNode == nil {
-		return errors.Wrapf(sgerrors.ErrNotFound, "master node not found")
+		if len(evicted) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Errorf("%d evicted pods have not terminated", len(evicted))
+		case <-time.After(s.pollInterval):
+		}
+	}
+}
+
+// Rollback makes node schedulable again when drain has failed.
+func (s *Step) Rollback(ctx context.Context, out io.Writer, config *steps.Config) error {
+	if s.getClient == nil {
+		return nil
 	}
 
-	r, err := s.getRunner(masterNode.PublicIp, config)
+	client, err := s.getClient(&config.Kube)
 
 	if err != nil {
-		return errors.Wrapf(err, "get runner")
+		return errors.Wrap(err, "build kubernetes client")
 	}
 
-	err = steps.RunTemplate(ctx, s.script, r, out, config.DrainConfig)
+	node, err := findNode(client, config.DrainConfig)
+
+	if sgerrors.IsNotFound(err) {
+		return nil
+	}
 
 	if err != nil {
-		return errors.Wrap(err, "drain step")
+		return err
+	}
+
+	if err := setUnschedulable(client, node.Name, false); err != nil {
+		return errors.Wrapf(err, "uncordon node %s", node.Name)
 	}
 
 	return nil
@@ -101,3 +253,84 @@ func (s *Step) Description() string {
 func (s *Step) Depends() []string {
 	return nil
 }
+
+// findNode finds the node by name or by its internal ip.
+func findNode(client kubernetes.Interface, cfg steps.DrainConfig) (*corev1.Node, error) {
+	if cfg.NodeName != "" {
+		node, err := client.CoreV1().Nodes().Get(cfg.NodeName, metav1.GetOptions{})
+
+		if err == nil {
+			return node, nil
+		}
+
+		if !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "get node %s", cfg.NodeName)
+		}
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "list nodes")
+	}
+
+	for i := range nodes.Items {
+		for _, addr := range nodes.Items[i].Status.Addresses {
+			if addr.Type == corev1.NodeInternalIP && addr.Address == cfg.PrivateIP {
+				return &nodes.Items[i], nil
+			}
+		}
+	}
+
+	return nil, errors.Wrapf(sgerrors.ErrNotFound, "node %s %s", cfg.NodeName, cfg.PrivateIP)
+}
+
+func setUnschedulable(client kubernetes.Interface, nodeName string, unschedulable bool) error {
+	node, err := client.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+
+	if err != nil {
+		return err
+	}
+
+	if node.Spec.Unschedulable == unschedulable {
+		return nil
+	}
+
+	node.Spec.Unschedulable = unschedulable
+	_, err = client.CoreV1().Nodes().Update(node)
+
+	return err
+}
+
+// evictable skips pods that are recreated on the node or have finished.
+func evictable(pods []corev1.Pod) []corev1.Pod {
+	result := make([]corev1.Pod, 0, len(pods))
+
+	for _, pod := range pods {
+		if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+			continue
+		}
+
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		if isDaemonSetPod(pod) {
+			continue
+		}
+
+		result = append(result, pod)
+	}
+
+	return result
+}
+
+func isDaemonSetPod(pod corev1.Pod) bool {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "DaemonSet" {
+			return true
+		}
+	}
+
+	return false
+}
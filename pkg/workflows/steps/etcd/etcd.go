@@ -0,0 +1,104 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/pki"
+	"github.com/supergiant/control/pkg/sgerrors"
+	tm "github.com/supergiant/control/pkg/templatemanager"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const (
+	StepName = "etcd"
+	// Version of etcd run on dedicated machines
+	Version = "3.3.10"
+)
+
+// Step runs etcd member on the machine, the member starts a new etcd
+// cluster or joins the members provisioned before it.
+type Step struct {
+	script *template.Template
+}
+
+func Init() {
+	tpl, err := tm.GetTemplate(StepName)
+
+	if err != nil {
+		panic(fmt.Sprintf("template %s not found", StepName))
+	}
+
+	steps.RegisterStep(StepName, New(tpl))
+}
+
+func New(script *template.Template) *Step {
+	return &Step{
+		script: script,
+	}
+}
+
+func (s *Step) Run(ctx context.Context, out io.Writer, config *steps.Config) error {
+	if config.Kube.Auth.EtcdCACert == "" {
+		return errors.Wrap(sgerrors.ErrNotFound, "etcd CA")
+	}
+
+	member, err := pki.NewEtcdMemberPair(config.Node.Name, config.Node.PrivateIp, &pki.PairPEM{
+		Cert: []byte(config.Kube.Auth.EtcdCACert),
+		Key:  []byte(config.Kube.Auth.EtcdCAKey),
+	})
+
+	if err != nil {
+		return errors.Wrapf(err, "create certificates of etcd member %s", config.Node.Name)
+	}
+
+	err = steps.RunTemplate(ctx, s.script, config.Runner, out, struct {
+		Name      string
+		PrivateIP string
+		Version   string
+		CACert    string
+		Cert      string
+		Key       string
+		Endpoints string
+	}{
+		Name:      config.Node.Name,
+		PrivateIP: config.Node.PrivateIp,
+		Version:   Version,
+		CACert:    config.Kube.Auth.EtcdCACert,
+		Cert:      string(member.Cert),
+		Key:       string(member.Key),
+		Endpoints: strings.Join(config.EtcdConfig.Endpoints, ","),
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "etcd step has failed")
+	}
+
+	return nil
+}
+
+func (s *Step) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
+
+func (s *Step) Name() string {
+	return StepName
+}
+
+func (s *Step) Description() string {
+	return "Run etcd member"
+}
+
+func (s *Step) Depends() []string {
+	return nil
+}
+
+// ClientURL of the etcd member with the ip.
+func ClientURL(ip string) string {
+	return fmt.Sprintf("https://%s:2379", ip)
+}
@@ -0,0 +1,121 @@
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/pki"
+	"github.com/supergiant/control/pkg/runner"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/templatemanager"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type fakeRunner struct{}
+
+func (f *fakeRunner) Run(command *runner.Command) error {
+	_, err := io.Copy(command.Out, strings.NewReader(command.Script))
+	return err
+}
+
+func TestStepRun(t *testing.T) {
+	if err := templatemanager.Init("../../../../templates"); err != nil {
+		t.Fatal(err)
+	}
+
+	tpl, err := templatemanager.GetTemplate(StepName)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca, err := pki.NewCAPair(nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		description string
+		noCA        bool
+		endpoints   []string
+		expected    []string
+	}{
+		{
+			description: "no etcd CA",
+			noCA:        true,
+		},
+		{
+			description: "first member",
+			expected: []string{
+				"INITIAL_CLUSTER=etcd-1=https://10.0.0.1:2380",
+				"INITIAL_CLUSTER_STATE=new",
+				"--listen-client-urls https://10.0.0.1:2379",
+			},
+		},
+		{
+			description: "joining member",
+			endpoints:   []string{ClientURL("10.0.0.2"), ClientURL("10.0.0.3")},
+			expected: []string{
+				"--endpoints https://10.0.0.2:2379,https://10.0.0.3:2379",
+				"member add etcd-1 --peer-urls https://10.0.0.1:2380",
+				"INITIAL_CLUSTER_STATE=existing",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		config := &steps.Config{
+			Runner: &fakeRunner{},
+			Node: model.Machine{
+				Name:      "etcd-1",
+				PrivateIp: "10.0.0.1",
+			},
+			EtcdConfig: steps.EtcdConfig{
+				Endpoints: testCase.endpoints,
+			},
+		}
+
+		if !testCase.noCA {
+			config.Kube.Auth.EtcdCACert = string(ca.Cert)
+			config.Kube.Auth.EtcdCAKey = string(ca.Key)
+		}
+
+		out := &bytes.Buffer{}
+		err := New(tpl).Run(context.Background(), out, config)
+
+		if testCase.noCA {
+			if !sgerrors.IsNotFound(err) {
+				t.Errorf("%s: expected not found error actual %v", testCase.description, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", testCase.description, err)
+			continue
+		}
+
+		for _, expected := range testCase.expected {
+			if !strings.Contains(out.String(), expected) {
+				t.Errorf("%s: %q not found in %s", testCase.description, expected, out.String())
+			}
+		}
+	}
+}
+
+func TestInit(t *testing.T) {
+	if err := templatemanager.Init("../../../../templates"); err != nil {
+		t.Fatal(err)
+	}
+
+	Init()
+
+	if s := steps.GetStep(StepName); s == nil || s.Name() != StepName {
+		t.Errorf("Step %s not found", StepName)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"time"
 
@@ -104,6 +105,12 @@ func (s *CreateInstanceStep) Run(ctx context.Context, output io.Writer,
 		return errors.Wrapf(err, "error gettting machine types")
 	}
 
+	scheduling, err := instanceScheduling(config.GCEConfig)
+
+	if err != nil {
+		return errors.Wrapf(err, "%s scheduling", CreateInstanceStepName)
+	}
+
 	role := "master"
 
 	if !config.IsMaster {
@@ -128,11 +135,24 @@ func (s *CreateInstanceStep) Run(ctx context.Context, output io.Writer,
 		},
 	}
 
+	labels := map[string]string{
+		clouds.LabelClusterID: config.Kube.ID,
+	}
+
+	// Custom labels of the profile never override cluster id
+	for key, value := range config.Tags {
+		if _, ok := labels[key]; !ok {
+			labels[key] = value
+		}
+	}
+
 	instance := &compute.Instance{
 		Name:         name,
 		Description:  "Kubernetes master node for cluster:" + config.Kube.Name,
 		MachineType:  instType.SelfLink,
 		CanIpForward: true,
+		Scheduling:   scheduling,
+		Labels:       labels,
 		Tags: &compute.Tags{
 			Items: []string{"https-server", "kubernetes"},
 		},
@@ -208,11 +228,7 @@ func (s *CreateInstanceStep) Run(ctx context.Context, output io.Writer,
 			CreateInstanceStepName)
 	}
 
-	nodeRole := model.RoleMaster
-
-	if !config.IsMaster {
-		nodeRole = model.RoleNode
-	}
+	nodeRole := config.Role()
 
 	config.Node = model.Machine{
 		ID:        string(resp.Id),
@@ -226,13 +242,14 @@ func (s *CreateInstanceStep) Run(ctx context.Context, output io.Writer,
 		// cluster wide and we need az to delete instance.
 		// TODO(stgleb): consider adding AZ to node struct
 		Region: config.GCEConfig.AvailabilityZone,
+		Pool:   config.Pool,
 	}
 
 	// Update node state in cluster
 	config.NodeChan() <- config.Node
 
 	ticker := time.NewTicker(s.checkPeriod)
-	after := time.After(s.instanceTimeout)
+	after := time.After(steps.WaitTimeout(ctx, s.instanceTimeout))
 
 	for {
 		select {
@@ -312,6 +329,45 @@ func (s *CreateInstanceStep) Run(ctx context.Context, output io.Writer,
 	}
 }
 
+// instanceScheduling returns scheduling options of preemptible
+// instance, nil means the default ones.
+func instanceScheduling(config steps.GCEConfig) (*compute.Scheduling, error) {
+	if config.Preemptible == "" {
+		return nil, nil
+	}
+
+	preemptible, err := strconv.ParseBool(config.Preemptible)
+
+	if err != nil {
+		return nil, errors.Wrapf(sgerrors.ErrValidationFailed,
+			"preemptible %s %v", config.Preemptible, err)
+	}
+
+	if !preemptible {
+		return nil, nil
+	}
+
+	// Preemptible instances can not be restarted automatically
+	// and must be terminated on host maintenance.
+	automaticRestart := false
+
+	return &compute.Scheduling{
+		Preemptible:       true,
+		AutomaticRestart:  &automaticRestart,
+		OnHostMaintenance: "TERMINATE",
+	}, nil
+}
+
+// DefaultTimeout is how long instance is waited to be running.
+func (s *CreateInstanceStep) DefaultTimeout() time.Duration {
+	return s.instanceTimeout
+}
+
+// RetryPolicy opts out of retries, instance would be created twice.
+func (s *CreateInstanceStep) RetryPolicy() steps.RetryPolicy {
+	return steps.NoRetry
+}
+
 func (s *CreateInstanceStep) Name() string {
 	return CreateInstanceStepName
 }
@@ -261,3 +261,50 @@ func TestCreateInstanceStep_Description(t *testing.T) {
 			"Google compute engine step for creating instance", desc)
 	}
 }
+
+func TestInstanceScheduling(t *testing.T) {
+	testCases := []struct {
+		preemptible string
+		expected    bool
+		errMsg      string
+	}{
+		{},
+		{
+			preemptible: "false",
+		},
+		{
+			preemptible: "true",
+			expected:    true,
+		},
+		{
+			preemptible: "yes",
+			errMsg:      "preemptible",
+		},
+	}
+
+	for _, testCase := range testCases {
+		scheduling, err := instanceScheduling(steps.GCEConfig{
+			Preemptible: testCase.preemptible,
+		})
+
+		if err == nil && testCase.errMsg != "" {
+			t.Errorf("Error must not be nil")
+		}
+
+		if err != nil && !sgerrors.IsValidationFailed(err) {
+			t.Errorf("Expected validation error actual %v", err)
+		}
+
+		if !testCase.expected {
+			if scheduling != nil {
+				t.Errorf("Scheduling must be nil for %s", testCase.preemptible)
+			}
+			continue
+		}
+
+		if scheduling == nil || !scheduling.Preemptible ||
+			scheduling.AutomaticRestart == nil || *scheduling.AutomaticRestart {
+			t.Errorf("Wrong preemptible scheduling %v", scheduling)
+		}
+	}
+}
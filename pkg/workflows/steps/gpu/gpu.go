@@ -0,0 +1,328 @@
+package gpu
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/supergiant/control/pkg/kubeconfig"
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const (
+	StepName = "gpu"
+
+	// ResourceName is extended resource of GPUs advertised by device plugin
+	ResourceName corev1.ResourceName = "nvidia.com/gpu"
+	// LabelKey selects nodes device plugin is run on
+	LabelKey = "nvidia.com/gpu.present"
+	// TaintKey keeps pods that don't tolerate GPUs off GPU nodes
+	TaintKey = "nvidia.com/gpu"
+
+	DevicePluginName  = "nvidia-device-plugin-daemonset"
+	DevicePluginImage = "nvcr.io/nvidia/k8s-device-plugin:v0.14.5"
+
+	// DefaultTimeout of waiting for the node to join the cluster
+	DefaultTimeout = 5 * time.Minute
+	// PollInterval between checks of joined node
+	PollInterval = 5 * time.Second
+
+	devicePluginsPath = "/var/lib/kubelet/device-plugins"
+)
+
+// Step labels and taints GPU node once it has joined the cluster and
+// installs NVIDIA device plugin that advertises GPUs of the nodes.
+// NVIDIA drivers and container runtime must be provided by the image.
+type Step struct {
+	getClient    func(*model.Kube) (kubernetes.Interface, error)
+	pollInterval time.Duration
+}
+
+func Init() {
+	steps.RegisterStep(StepName, New())
+}
+
+func New() *Step {
+	return &Step{
+		getClient: func(k *model.Kube) (kubernetes.Interface, error) {
+			cfg, err := kubeconfig.NewConfigFor(k)
+
+			if err != nil {
+				return nil, errors.Wrap(err, "build kubernetes rest config")
+			}
+
+			return kubernetes.NewForConfig(cfg)
+		},
+		pollInterval: PollInterval,
+	}
+}
+
+func (s *Step) Run(ctx context.Context, out io.Writer, config *steps.Config) error {
+	if !config.GPU {
+		return nil
+	}
+
+	client, err := s.getClient(&config.Kube)
+
+	if err != nil {
+		return errors.Wrap(err, "build kubernetes client")
+	}
+
+	node, err := s.waitNode(ctx, client, config.Node.PrivateIp)
+
+	if err != nil {
+		return err
+	}
+
+	if err := setGPU(client, node); err != nil {
+		return errors.Wrapf(err, "label GPU node %s", node.Name)
+	}
+
+	fmt.Fprintf(out, "node %s labeled %s and tainted %s\n", node.Name, LabelKey, TaintKey)
+
+	created, err := ensureDevicePlugin(client)
+
+	if err != nil {
+		return errors.Wrap(err, "install NVIDIA device plugin")
+	}
+
+	if created {
+		fmt.Fprintf(out, "daemonset %s/%s created\n", metav1.NamespaceSystem, DevicePluginName)
+	}
+
+	config.Node.GPU = true
+
+	// Kube reports allocatable GPUs when it has GPU nodes
+	if !config.DryRun {
+		if config.IsMaster {
+			config.AddMaster(&config.Node)
+		} else {
+			config.AddNode(&config.Node)
+		}
+
+		config.NodeChan() <- config.Node
+	}
+
+	return nil
+}
+
+// waitNode waits until node with the private IP is registered by kubelet.
+func (s *Step) waitNode(ctx context.Context, client kubernetes.Interface, privateIP string) (*corev1.Node, error) {
+	for {
+		node, err := findNode(client, privateIP)
+
+		if err == nil {
+			return node, nil
+		}
+
+		if !sgerrors.IsNotFound(err) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(sgerrors.ErrTimeoutExceeded, "wait for node %s", privateIP)
+		case <-time.After(s.pollInterval):
+		}
+	}
+}
+
+func (s *Step) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
+
+func (s *Step) Name() string {
+	return StepName
+}
+
+func (s *Step) Description() string {
+	return "label GPU node and install NVIDIA device plugin"
+}
+
+func (s *Step) Depends() []string {
+	return nil
+}
+
+// DefaultTimeout is how long the node is waited to join.
+func (s *Step) DefaultTimeout() time.Duration {
+	return DefaultTimeout
+}
+
+// TotalAllocatable returns count of GPUs that nodes may allocate to pods.
+func TotalAllocatable(nodes []corev1.Node) int64 {
+	var total int64
+
+	for _, node := range nodes {
+		if quantity, ok := node.Status.Allocatable[ResourceName]; ok {
+			total += quantity.Value()
+		}
+	}
+
+	return total
+}
+
+func findNode(client kubernetes.Interface, privateIP string) (*corev1.Node, error) {
+	nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "list nodes")
+	}
+
+	for i := range nodes.Items {
+		for _, addr := range nodes.Items[i].Status.Addresses {
+			if addr.Type == corev1.NodeInternalIP && addr.Address == privateIP {
+				return &nodes.Items[i], nil
+			}
+		}
+	}
+
+	return nil, errors.Wrapf(sgerrors.ErrNotFound, "node %s", privateIP)
+}
+
+// setGPU labels the node and taints it unless it has been done before.
+func setGPU(client kubernetes.Interface, node *corev1.Node) error {
+	changed := false
+
+	if node.Labels[LabelKey] != "true" {
+		if node.Labels == nil {
+			node.Labels = make(map[string]string)
+		}
+
+		node.Labels[LabelKey] = "true"
+		changed = true
+	}
+
+	taint := corev1.Taint{
+		Key:    TaintKey,
+		Value:  "present",
+		Effect: corev1.TaintEffectNoSchedule,
+	}
+
+	if !hasTaint(node.Spec.Taints, taint) {
+		node.Spec.Taints = append(node.Spec.Taints, taint)
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	_, err := client.CoreV1().Nodes().Update(node)
+
+	return err
+}
+
+func hasTaint(taints []corev1.Taint, taint corev1.Taint) bool {
+	for _, t := range taints {
+		if t.Key == taint.Key && t.Effect == taint.Effect {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ensureDevicePlugin creates device plugin daemonset shared by GPU nodes
+// of the cluster, it returns true when the daemonset has been created.
+func ensureDevicePlugin(client kubernetes.Interface) (bool, error) {
+	daemonSets := client.AppsV1().DaemonSets(metav1.NamespaceSystem)
+	_, err := daemonSets.Get(DevicePluginName, metav1.GetOptions{})
+
+	if err == nil {
+		return false, nil
+	}
+
+	if !apierrors.IsNotFound(err) {
+		return false, err
+	}
+
+	_, err = daemonSets.Create(devicePlugin())
+
+	// Nodes of the pool are provisioned concurrently
+	if apierrors.IsAlreadyExists(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func devicePlugin() *appsv1.DaemonSet {
+	labels := map[string]string{
+		"name": DevicePluginName,
+	}
+	allowEscalation := false
+
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      DevicePluginName,
+			Namespace: metav1.NamespaceSystem,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					NodeSelector: map[string]string{
+						LabelKey: "true",
+					},
+					Tolerations: []corev1.Toleration{
+						{
+							Key:      TaintKey,
+							Operator: corev1.TolerationOpExists,
+							Effect:   corev1.TaintEffectNoSchedule,
+						},
+					},
+					PriorityClassName: "system-node-critical",
+					Containers: []corev1.Container{
+						{
+							Name:  "nvidia-device-plugin-ctr",
+							Image: DevicePluginImage,
+							Env: []corev1.EnvVar{
+								{Name: "FAIL_ON_INIT_ERROR", Value: "false"},
+							},
+							SecurityContext: &corev1.SecurityContext{
+								AllowPrivilegeEscalation: &allowEscalation,
+								Capabilities: &corev1.Capabilities{
+									Drop: []corev1.Capability{"ALL"},
+								},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "device-plugin",
+									MountPath: devicePluginsPath,
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "device-plugin",
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Path: devicePluginsPath,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,173 @@
+package gpu
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+func newNode(name, privateIP string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: privateIP},
+			},
+		},
+	}
+}
+
+func TestStep_Run(t *testing.T) {
+	testCases := []struct {
+		description string
+		gpu         bool
+		privateIP   string
+		expectedErr error
+		expectedGPU bool
+	}{
+		{
+			description: "node without gpu",
+			privateIP:   "10.0.0.1",
+		},
+		{
+			description: "gpu node",
+			gpu:         true,
+			privateIP:   "10.0.0.1",
+			expectedGPU: true,
+		},
+		{
+			description: "gpu node has not joined",
+			gpu:         true,
+			privateIP:   "10.0.0.2",
+			expectedErr: sgerrors.ErrTimeoutExceeded,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		client := fake.NewSimpleClientset(newNode("node-1", "10.0.0.1"))
+		step := &Step{
+			getClient: func(*model.Kube) (kubernetes.Interface, error) {
+				return client, nil
+			},
+			pollInterval: time.Millisecond,
+		}
+
+		config, err := steps.NewConfig("test", "test", profile.Profile{
+			NodesProfiles: []profile.NodeProfile{{}},
+		})
+
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		config.GPU = testCase.gpu
+		config.Node = model.Machine{
+			ID:        "node-1",
+			PrivateIp: testCase.privateIP,
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		err = step.Run(ctx, &bytes.Buffer{}, config)
+		cancel()
+
+		if testCase.expectedErr != nil {
+			if err == nil || !sgerrors.IsTimeoutExceeded(err) {
+				t.Errorf("Wrong error expected %v actual %v", testCase.expectedErr, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		node, _ := client.CoreV1().Nodes().Get("node-1", metav1.GetOptions{})
+
+		if isGPU := node.Labels[LabelKey] == "true"; isGPU != testCase.expectedGPU {
+			t.Errorf("Wrong gpu label %v", node.Labels)
+		}
+
+		if tainted := len(node.Spec.Taints) == 1; tainted != testCase.expectedGPU {
+			t.Errorf("Wrong taints %v", node.Spec.Taints)
+		}
+
+		_, err = client.AppsV1().DaemonSets(metav1.NamespaceSystem).
+			Get(DevicePluginName, metav1.GetOptions{})
+
+		if installed := err == nil; installed != testCase.expectedGPU {
+			t.Errorf("Device plugin installed %v expected %v", installed, testCase.expectedGPU)
+		}
+
+		select {
+		case node := <-config.NodeChan():
+			if !node.GPU || !testCase.expectedGPU {
+				t.Errorf("Wrong node update %v", node)
+			}
+		default:
+			if testCase.expectedGPU {
+				t.Errorf("GPU node has not been updated")
+			}
+		}
+	}
+}
+
+func TestStep_RunTwice(t *testing.T) {
+	client := fake.NewSimpleClientset(newNode("node-1", "10.0.0.1"))
+	step := &Step{
+		getClient: func(*model.Kube) (kubernetes.Interface, error) {
+			return client, nil
+		},
+		pollInterval: time.Millisecond,
+	}
+
+	config := &steps.Config{
+		GPU:    true,
+		DryRun: true,
+		Node: model.Machine{
+			PrivateIp: "10.0.0.1",
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := step.Run(context.Background(), &bytes.Buffer{}, config); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	node, _ := client.CoreV1().Nodes().Get("node-1", metav1.GetOptions{})
+
+	if len(node.Spec.Taints) != 1 {
+		t.Errorf("Node must be tainted once %v", node.Spec.Taints)
+	}
+}
+
+func TestTotalAllocatable(t *testing.T) {
+	gpuNode := newNode("gpu", "10.0.0.1")
+	gpuNode.Status.Allocatable = corev1.ResourceList{
+		ResourceName:       resource.MustParse("4"),
+		corev1.ResourceCPU: resource.MustParse("8"),
+	}
+
+	nodes := []corev1.Node{*gpuNode, *newNode("cpu", "10.0.0.2"), *gpuNode}
+
+	if total := TotalAllocatable(nodes); total != 8 {
+		t.Errorf("Wrong allocatable GPUs %d", total)
+	}
+}
@@ -46,7 +46,7 @@ func New(script *template.Template) *Step {
 }
 
 func (j *Step) Run(ctx context.Context, out io.Writer, config *steps.Config) error {
-	err := steps.RunTemplate(context.Background(), j.script, config.Runner, out, toStepCfg(config))
+	err := steps.RunTemplate(ctx, j.script, config.Runner, out, toStepCfg(config))
 
 	if err != nil {
 		return errors.Wrap(err, "install helm step")
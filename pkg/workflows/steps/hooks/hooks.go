@@ -0,0 +1,151 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/runner"
+	"github.com/supergiant/control/pkg/workflows/steps"
+	"github.com/supergiant/control/pkg/workflows/steps/kubelet"
+	"github.com/supergiant/control/pkg/workflows/steps/ssh"
+)
+
+const (
+	PreProvisionStepName  = "pre_provision_hook"
+	PostProvisionStepName = "post_provision_hook"
+
+	// Names of hooks in results of machines
+	PreProvision  = "preProvision"
+	PostProvision = "postProvision"
+
+	DefaultTimeout = time.Minute * 10
+)
+
+// Step runs the hook script of the profile on the machine.
+type Step struct {
+	name    string
+	hook    string
+	depends []string
+}
+
+func Init() {
+	steps.RegisterStep(PreProvisionStepName, NewPreProvision())
+	steps.RegisterStep(PostProvisionStepName, NewPostProvision())
+}
+
+// NewPreProvision runs the script once the machine is reachable over ssh.
+func NewPreProvision() *Step {
+	return &Step{
+		name:    PreProvisionStepName,
+		hook:    PreProvision,
+		depends: []string{ssh.StepName},
+	}
+}
+
+// NewPostProvision runs the script after kubelet has started.
+func NewPostProvision() *Step {
+	return &Step{
+		name:    PostProvisionStepName,
+		hook:    PostProvision,
+		depends: []string{kubelet.StepName},
+	}
+}
+
+func (s *Step) Run(ctx context.Context, out io.Writer, config *steps.Config) error {
+	hooks := config.NodeHooks
+
+	if config.IsMaster {
+		hooks = config.MasterHooks
+	}
+
+	script := script(hooks, s.hook)
+
+	if script == "" {
+		fmt.Fprintf(out, "No %s hook for %s\n", s.hook, config.Node.Name)
+		return nil
+	}
+
+	err := runScript(ctx, config.Runner, out, script)
+	result := model.HookSuccess
+
+	if err != nil {
+		result = model.HookFailed
+
+		if hooks.WarnOnly {
+			result = model.HookWarning
+			fmt.Fprintf(out, "WARNING: %s hook has failed on %s: %v\n", s.hook, config.Node.Name, err)
+		}
+	}
+
+	if config.Node.Hooks == nil {
+		config.Node.Hooks = make(map[string]model.HookResult)
+	}
+
+	config.Node.Hooks[s.hook] = result
+
+	// Update results of hooks visible in the kube
+	if !config.DryRun {
+		if config.IsMaster {
+			config.AddMaster(&config.Node)
+		} else {
+			config.AddNode(&config.Node)
+		}
+
+		config.NodeChan() <- config.Node
+	}
+
+	if result == model.HookFailed {
+		return errors.Wrapf(err, "run %s hook", s.hook)
+	}
+
+	return nil
+}
+
+func (s *Step) Name() string {
+	return s.name
+}
+
+func (s *Step) Description() string {
+	return fmt.Sprintf("Run %s hook script of the profile", s.hook)
+}
+
+func (s *Step) Depends() []string {
+	return s.depends
+}
+
+func (s *Step) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
+
+func (s *Step) DefaultTimeout() time.Duration {
+	return DefaultTimeout
+}
+
+// RetryPolicy disables retries, scripts are not known to be idempotent.
+func (s *Step) RetryPolicy() steps.RetryPolicy {
+	return steps.NoRetry
+}
+
+func script(hooks profile.Hooks, hook string) string {
+	if hook == PreProvision {
+		return hooks.PreProvision
+	}
+
+	return hooks.PostProvision
+}
+
+func runScript(ctx context.Context, r runner.Runner, out io.Writer, script string) error {
+	cmd, err := runner.NewCommand(ctx, script, out, out)
+
+	if err != nil {
+		return err
+	}
+
+	return r.Run(cmd)
+}
@@ -0,0 +1,141 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/profile"
+	"github.com/supergiant/control/pkg/runner"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type fakeRunner struct {
+	script string
+	err    error
+}
+
+func (f *fakeRunner) Run(command *runner.Command) error {
+	f.script = command.Script
+	io.Copy(command.Out, strings.NewReader(command.Script))
+
+	return f.err
+}
+
+func TestStepRun(t *testing.T) {
+	testCases := []struct {
+		description    string
+		step           *Step
+		isMaster       bool
+		hooks          profile.Hooks
+		runErr         error
+		expectedScript string
+		expectedResult model.HookResult
+		isErr          bool
+	}{
+		{
+			description: "no hook",
+			step:        NewPreProvision(),
+		},
+		{
+			description: "pre provision of node",
+			step:        NewPreProvision(),
+			hooks: profile.Hooks{
+				PreProvision:  "echo pre",
+				PostProvision: "echo post",
+			},
+			expectedScript: "echo pre",
+			expectedResult: model.HookSuccess,
+		},
+		{
+			description:    "post provision of master",
+			step:           NewPostProvision(),
+			isMaster:       true,
+			hooks:          profile.Hooks{PostProvision: "echo post"},
+			expectedScript: "echo post",
+			expectedResult: model.HookSuccess,
+		},
+		{
+			description:    "script fails",
+			step:           NewPostProvision(),
+			hooks:          profile.Hooks{PostProvision: "exit 1"},
+			runErr:         errors.New("exit status 1"),
+			expectedScript: "exit 1",
+			expectedResult: model.HookFailed,
+			isErr:          true,
+		},
+		{
+			description: "warn only script fails",
+			step:        NewPreProvision(),
+			hooks: profile.Hooks{
+				PreProvision: "exit 1",
+				WarnOnly:     true,
+			},
+			runErr:         errors.New("exit status 1"),
+			expectedScript: "exit 1",
+			expectedResult: model.HookWarning,
+		},
+	}
+
+	for _, testCase := range testCases {
+		r := &fakeRunner{
+			err: testCase.runErr,
+		}
+
+		cfg, err := steps.NewConfig("test", "test", profile.Profile{
+			NodesProfiles: []profile.NodeProfile{{}},
+		})
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", testCase.description, err)
+			continue
+		}
+
+		cfg.Runner = r
+		cfg.IsMaster = testCase.isMaster
+		cfg.Node = model.Machine{
+			ID:   "node-1",
+			Name: "node-1",
+		}
+
+		if testCase.isMaster {
+			cfg.MasterHooks = testCase.hooks
+		} else {
+			cfg.NodeHooks = testCase.hooks
+		}
+
+		err = testCase.step.Run(context.Background(), &bytes.Buffer{}, cfg)
+
+		if testCase.isErr != (err != nil) {
+			t.Errorf("%s: unexpected error %v", testCase.description, err)
+		}
+
+		if r.script != testCase.expectedScript {
+			t.Errorf("%s: expected script %q actual %q", testCase.description,
+				testCase.expectedScript, r.script)
+		}
+
+		if result := cfg.Node.Hooks[testCase.step.hook]; result != testCase.expectedResult {
+			t.Errorf("%s: expected result %q actual %q", testCase.description,
+				testCase.expectedResult, result)
+		}
+
+		if testCase.expectedResult == "" {
+			continue
+		}
+
+		select {
+		case node := <-cfg.NodeChan():
+			if node.Hooks[testCase.step.hook] != testCase.expectedResult {
+				t.Errorf("%s: result must be sent with the node %v", testCase.description, node)
+			}
+		default:
+			t.Errorf("%s: node has not been updated", testCase.description)
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 	tm "github.com/supergiant/control/pkg/templatemanager"
 	"github.com/supergiant/control/pkg/workflows/steps"
 	"github.com/supergiant/control/pkg/workflows/steps/docker"
+	"github.com/supergiant/control/pkg/workflows/steps/kubelet"
 )
 
 const (
@@ -36,6 +37,12 @@ type Config struct {
 	APIServerPort   int64
 	NodeIp          string
 	ProviderID      string
+	NodeLabels      string
+	Taints          string
+	// EtcdEndpoints of dedicated etcd, etcd is stacked on masters when empty
+	EtcdEndpoints []string
+	// APIServerExtraArgs are added to API server flags set by control
+	APIServerExtraArgs map[string]string
 }
 
 type Step struct {
@@ -130,5 +137,22 @@ func toStepCfg(c *steps.Config) Config {
 		APIServerPort:   c.Kube.APIServerPort,
 		NodeIp:          c.Node.PrivateIp,
 		ProviderID:      toProviderID(c.Kube.Provider, c.Node.ID),
+		NodeLabels:      toNodeLabels(c.Pool, c.Labels),
+		Taints:          c.Taints,
+		EtcdEndpoints:   c.EtcdConfig.Endpoints,
+
+		APIServerExtraArgs: c.Kube.APIServerExtraArgs,
+	}
+}
+
+func toNodeLabels(pool, labels string) string {
+	if pool == "" {
+		return labels
 	}
+
+	if labels == "" {
+		return fmt.Sprintf("%s=%s", kubelet.LabelNodePool, pool)
+	}
+
+	return fmt.Sprintf("%s=%s,%s", kubelet.LabelNodePool, pool, labels)
 }
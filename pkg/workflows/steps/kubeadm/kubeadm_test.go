@@ -61,6 +61,9 @@ func TestKubeadm(t *testing.T) {
 			BootstrapToken:  "1234",
 			ExternalDNSName: "external.dns.name",
 			InternalDNSName: "internal.dns.name",
+			APIServerExtraArgs: map[string]string{
+				"max-requests-inflight": "800",
+			},
 		},
 		Runner: r,
 		Node: model.Machine{
@@ -82,6 +85,76 @@ func TestKubeadm(t *testing.T) {
 	if !strings.Contains(output.String(), cfg.Kube.InternalDNSName) {
 		t.Errorf("LoadBalancerHost %s not found in %s", cfg.Kube.InternalDNSName, output.String())
 	}
+
+	if !strings.Contains(output.String(), "    max-requests-inflight: '800'\n") {
+		t.Errorf("API server extra args not found in %s", output.String())
+	}
+}
+
+func TestKubeadmEtcd(t *testing.T) {
+	if err := templatemanager.Init("../../../../templates"); err != nil {
+		t.Fatal(err)
+	}
+
+	tpl, _ := templatemanager.GetTemplate(StepName)
+
+	testCases := []struct {
+		description string
+		isBootstrap bool
+		endpoints   []string
+		expected    []string
+		unexpected  string
+	}{
+		{
+			description: "stacked etcd",
+			isBootstrap: true,
+			expected:    []string{"  local:\n    dataDir: /var/lib/etcd"},
+			unexpected:  "external:",
+		},
+		{
+			description: "dedicated etcd",
+			isBootstrap: true,
+			endpoints:   []string{"https://10.0.0.1:2379", "https://10.0.0.2:2379"},
+			expected: []string{
+				"  external:\n    endpoints:\n    - https://10.0.0.1:2379\n    - https://10.0.0.2:2379\n",
+				"certFile: /etc/kubernetes/pki/apiserver-etcd-client.crt",
+			},
+			unexpected: "local:",
+		},
+		{
+			description: "joining master",
+			endpoints:   []string{"https://10.0.0.1:2379"},
+			expected:    []string{"    endpoints:\n    - https://10.0.0.1:2379\n"},
+			unexpected:  "local:",
+		},
+	}
+
+	for _, testCase := range testCases {
+		output := new(bytes.Buffer)
+		cfg := &steps.Config{
+			IsMaster:    true,
+			IsBootstrap: testCase.isBootstrap,
+			Runner:      &fakeRunner{},
+			EtcdConfig: steps.EtcdConfig{
+				Endpoints: testCase.endpoints,
+			},
+		}
+
+		if err := New(tpl).Run(context.Background(), output, cfg); err != nil {
+			t.Errorf("%s: unexpected error %v", testCase.description, err)
+			continue
+		}
+
+		for _, expected := range testCase.expected {
+			if !strings.Contains(output.String(), expected) {
+				t.Errorf("%s: %q not found in %s", testCase.description, expected, output.String())
+			}
+		}
+
+		if strings.Contains(output.String(), testCase.unexpected) {
+			t.Errorf("%s: unexpected %q in %s", testCase.description, testCase.unexpected, output.String())
+		}
+	}
 }
 
 func TestStartKubeadmError(t *testing.T) {
@@ -200,3 +273,20 @@ func TestToCloudProviderOpt(t *testing.T) {
 		}
 	}
 }
+
+func TestToNodeLabels(t *testing.T) {
+	for _, tc := range []struct {
+		pool   string
+		labels string
+		out    string
+	}{
+		{"", "", ""},
+		{"gpu", "", "supergiant.io/pool=gpu"},
+		{"", "team=ml", "team=ml"},
+		{"gpu", "accelerator=nvidia,team=ml", "supergiant.io/pool=gpu,accelerator=nvidia,team=ml"},
+	} {
+		if out := toNodeLabels(tc.pool, tc.labels); out != tc.out {
+			t.Errorf("toNodeLabels(%s, %s) expected %s actual %s", tc.pool, tc.labels, tc.out, out)
+		}
+	}
+}
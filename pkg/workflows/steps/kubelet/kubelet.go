@@ -20,6 +20,8 @@ const (
 
 	// LabelNodeRole specifies the role of a node
 	LabelNodeRole = "kubernetes.io/role"
+	// LabelNodePool specifies the node pool of a node
+	LabelNodePool = "supergiant.io/pool"
 )
 
 type Config struct {
@@ -41,6 +43,9 @@ type Config struct {
 	AdminKey  string `json:"adminKey"`
 	CACert    string `json:"caCert"`
 	CAKey     string `json:"caKey"`
+
+	// ExtraArgs are added to kubelet flags set by control
+	ExtraArgs map[string]string `json:"extraArgs"`
 }
 
 type Step struct {
@@ -119,5 +124,6 @@ func toStepCfg(c *steps.Config) (Config, error) {
 		UserName:         c.Kube.SSHConfig.User,
 		ServicesCIDR:     c.Kube.ServicesCIDR,
 		KubernetesSvcIP:  svcIP.String(),
+		ExtraArgs:        c.Kube.KubeletExtraArgs,
 	}, nil
 }
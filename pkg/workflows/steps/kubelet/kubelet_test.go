@@ -11,6 +11,7 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/supergiant/control/pkg/model"
 	"github.com/supergiant/control/pkg/runner"
 	"github.com/supergiant/control/pkg/templatemanager"
 	"github.com/supergiant/control/pkg/workflows/steps"
@@ -48,6 +49,11 @@ func TestStartKubelet(t *testing.T) {
 
 	cfg := &steps.Config{
 		Runner: r,
+		Kube: model.Kube{
+			KubeletExtraArgs: map[string]string{
+				"max-pods": "200",
+			},
+		},
 	}
 
 	task := &Step{
@@ -59,6 +65,10 @@ func TestStartKubelet(t *testing.T) {
 	if err != nil {
 		t.Errorf("Unexpected error %v", err)
 	}
+
+	if !strings.Contains(output.String(), "--max-pods=200") {
+		t.Errorf("Kubelet extra args not found in %s", output.String())
+	}
 }
 
 func TestStartKubeletError(t *testing.T) {
@@ -1,10 +1,11 @@
-package prometheus
+package kubeletconfig
 
 import (
 	"context"
 	"fmt"
 	"io"
 	"text/template"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -12,20 +13,23 @@ import (
 	"github.com/supergiant/control/pkg/workflows/steps"
 )
 
-const StepName = "prometheus"
+const (
+	StepName = "kubelet_config"
+
+	// DefaultTimeout of kubelet restart with new flags
+	DefaultTimeout = 5 * time.Minute
+)
 
 type Config struct {
-	RBACEnabled bool
+	ExtraArgs map[string]string
 }
 
+// Step rewrites kubelet flags of the provisioned machine and restarts
+// kubelet, it waits until kubelet is running with the new flags.
 type Step struct {
 	script *template.Template
 }
 
-func (s *Step) Rollback(context.Context, io.Writer, *steps.Config) error {
-	return nil
-}
-
 func Init() {
 	tpl, err := tm.GetTemplate(StepName)
 
@@ -37,37 +41,40 @@ func Init() {
 }
 
 func New(script *template.Template) *Step {
-	t := &Step{
+	return &Step{
 		script: script,
 	}
-
-	return t
 }
 
 func (s *Step) Run(ctx context.Context, out io.Writer, config *steps.Config) error {
-	err := steps.RunTemplate(ctx, s.script, config.Runner, out, toStepCfg(config))
+	err := steps.RunTemplate(ctx, s.script, config.Runner, out, Config{
+		ExtraArgs: config.Kube.KubeletExtraArgs,
+	})
 
 	if err != nil {
-		return errors.Wrap(err, "install prometheus step")
+		return errors.Wrap(err, "reconfigure kubelet")
 	}
 
 	return nil
 }
 
+func (s *Step) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
+
 func (s *Step) Name() string {
 	return StepName
 }
 
 func (s *Step) Description() string {
-	return "Install prometheus"
+	return "Reconfigure kubelet flags"
 }
 
 func (s *Step) Depends() []string {
 	return nil
 }
 
-func toStepCfg(c *steps.Config) Config {
-	return Config{
-		RBACEnabled: c.Kube.RBACEnabled,
-	}
+// DefaultTimeout is how long kubelet is waited to restart.
+func (s *Step) DefaultTimeout() time.Duration {
+	return DefaultTimeout
 }
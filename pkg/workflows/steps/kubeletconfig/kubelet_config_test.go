@@ -0,0 +1,101 @@
+package kubeletconfig
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/runner"
+	"github.com/supergiant/control/pkg/templatemanager"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type fakeRunner struct {
+	err error
+}
+
+func (f *fakeRunner) Run(command *runner.Command) error {
+	if f.err != nil {
+		return f.err
+	}
+
+	_, err := io.Copy(command.Out, strings.NewReader(command.Script))
+	return err
+}
+
+func TestStep_Run(t *testing.T) {
+	if err := templatemanager.Init("../../../../templates"); err != nil {
+		t.Fatal(err)
+	}
+
+	tpl, _ := templatemanager.GetTemplate(StepName)
+
+	if tpl == nil {
+		t.Fatal("template not found")
+	}
+
+	testCases := []struct {
+		description string
+		args        map[string]string
+		runErr      error
+		expected    []string
+	}{
+		{
+			description: "default flags",
+			expected:    []string{"--feature-gates=RotateKubeletClientCertificate=true\nEOF"},
+		},
+		{
+			description: "extra flags",
+			args: map[string]string{
+				"max-pods":      "200",
+				"feature-gates": "CPUManager=true",
+			},
+			expected: []string{
+				"--feature-gates=CPUManager=true \\\n--max-pods=200\nEOF",
+				"systemctl restart kubelet",
+			},
+		},
+		{
+			description: "run error",
+			runErr:      errors.New("connection refused"),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.description)
+
+		output := new(bytes.Buffer)
+		cfg := &steps.Config{
+			Kube: model.Kube{
+				KubeletExtraArgs: testCase.args,
+			},
+			Runner: &fakeRunner{err: testCase.runErr},
+		}
+
+		err := New(tpl).Run(context.Background(), output, cfg)
+
+		if testCase.runErr != nil {
+			if errors.Cause(err) != testCase.runErr {
+				t.Errorf("Wrong error expected %v actual %v", testCase.runErr, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			continue
+		}
+
+		for _, expected := range testCase.expected {
+			if !strings.Contains(output.String(), expected) {
+				t.Errorf("%q not found in %s", expected, output.String())
+			}
+		}
+	}
+}
@@ -46,7 +46,7 @@ func (t *Step) Run(ctx context.Context, out io.Writer, config *steps.Config) err
 		return nil
 	}
 
-	err := steps.RunTemplate(context.Background(), t.script, config.Runner, out, toStepCfg(config))
+	err := steps.RunTemplate(ctx, t.script, config.Runner, out, toStepCfg(config))
 	if err != nil {
 		return errors.Wrap(err, "configure network step")
 	}
@@ -67,6 +67,11 @@ func TestNetworkConfig(t *testing.T) {
 			"weave",
 			nil,
 		},
+		{
+			"Cilium",
+			"cilium",
+			nil,
+		},
 		{
 			"",
 			"",
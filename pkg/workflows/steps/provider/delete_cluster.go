@@ -61,6 +61,8 @@ func cleanUpStepsFor(provider clouds.Name) ([]steps.Step, error) {
 	switch provider {
 	case clouds.AWS:
 		return []steps.Step{
+			steps.GetStep(amazon.CancelSpotRequestsStepName),
+			steps.GetStep(amazon.DeleteAutoScalingGroupsStepName),
 			steps.GetStep(amazon.DeleteClusterMachinesStepName),
 			steps.GetStep(amazon.DeleteLoadBalancerStepName),
 			steps.GetStep(amazon.DeleteSecurityGroupsStepName),
@@ -0,0 +1,122 @@
+package replacemaster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/control/pkg/clouds"
+	"github.com/supergiant/control/pkg/runner"
+	"github.com/supergiant/control/pkg/runner/ssh"
+	"github.com/supergiant/control/pkg/sgerrors"
+	tm "github.com/supergiant/control/pkg/templatemanager"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+const (
+	UploadCertsStepName  = "upload_certs"
+	RemoveMemberStepName = "remove_etcd_member"
+)
+
+// Step runs its script on the healthy master of the replacement.
+type Step struct {
+	name        string
+	description string
+	script      *template.Template
+	getRunner   func(string, *steps.Config) (runner.Runner, error)
+}
+
+func Init() {
+	for name, description := range map[string]string{
+		UploadCertsStepName:  "upload control plane certificates for the new master",
+		RemoveMemberStepName: "remove replaced master from etcd cluster",
+	} {
+		tpl, err := tm.GetTemplate(name)
+
+		if err != nil {
+			panic(fmt.Sprintf("template %s not found", name))
+		}
+
+		steps.RegisterStep(name, New(name, description, tpl))
+	}
+}
+
+func New(name, description string, script *template.Template) *Step {
+	return &Step{
+		name:        name,
+		description: description,
+		script:      script,
+		getRunner: func(masterIP string, config *steps.Config) (runner.Runner, error) {
+			user := config.Kube.SSHConfig.User
+
+			if config.Provider == clouds.AWS {
+				// Default user of ubuntu images on aws is ubuntu
+				user = "ubuntu"
+			}
+
+			sshRunner, err := ssh.NewRunner(ssh.Config{
+				Host:        masterIP,
+				Port:        config.Kube.SSHConfig.Port,
+				User:        user,
+				Timeout:     config.Kube.SSHConfig.Timeout,
+				Key:         []byte(config.Kube.SSHConfig.BootstrapPrivateKey),
+				BastionHost: config.Kube.SSHConfig.BastionHost,
+			})
+
+			if err != nil {
+				return nil, errors.Wrap(err, "create ssh runner")
+			}
+
+			return sshRunner, nil
+		},
+	}
+}
+
+func (s *Step) Run(ctx context.Context, out io.Writer, config *steps.Config) error {
+	masterIP := config.ReplaceMasterConfig.HealthyMasterIP
+
+	if masterIP == "" {
+		return errors.Wrap(sgerrors.ErrNotFound, "healthy master")
+	}
+
+	r, err := s.getRunner(masterIP, config)
+
+	if err != nil {
+		return errors.Wrapf(err, "get runner of master %s", masterIP)
+	}
+
+	err = steps.RunTemplate(ctx, s.script, r, out, struct {
+		Provider       clouds.Name
+		CertificateKey string
+		PrivateIP      string
+	}{
+		Provider:       config.Provider,
+		CertificateKey: config.Kube.Auth.CertificateKey,
+		PrivateIP:      config.ReplaceMasterConfig.PrivateIP,
+	})
+
+	if err != nil {
+		return errors.Wrap(err, s.name)
+	}
+
+	return nil
+}
+
+func (s *Step) Rollback(context.Context, io.Writer, *steps.Config) error {
+	return nil
+}
+
+func (s *Step) Name() string {
+	return s.name
+}
+
+func (s *Step) Description() string {
+	return s.description
+}
+
+func (s *Step) Depends() []string {
+	return nil
+}
@@ -0,0 +1,116 @@
+package replacemaster
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/supergiant/control/pkg/model"
+	"github.com/supergiant/control/pkg/runner"
+	"github.com/supergiant/control/pkg/sgerrors"
+	"github.com/supergiant/control/pkg/templatemanager"
+	"github.com/supergiant/control/pkg/workflows/steps"
+)
+
+type fakeRunner struct{}
+
+func (f *fakeRunner) Run(command *runner.Command) error {
+	_, err := io.Copy(command.Out, strings.NewReader(command.Script))
+	return err
+}
+
+func TestStepRun(t *testing.T) {
+	if err := templatemanager.Init("../../../../templates"); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		stepName  string
+		healthyIP string
+		expected  string
+		errNil    bool
+	}{
+		{
+			stepName: UploadCertsStepName,
+		},
+		{
+			stepName:  UploadCertsStepName,
+			healthyIP: "1.2.3.4",
+			expected:  "--certificate-key certkey",
+			errNil:    true,
+		},
+		{
+			stepName:  RemoveMemberStepName,
+			healthyIP: "1.2.3.4",
+			expected:  "https://10.0.0.1:2380",
+			errNil:    true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tpl, err := templatemanager.GetTemplate(testCase.stepName)
+
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", testCase.stepName, err)
+		}
+
+		var runnerIP string
+		step := New(testCase.stepName, "", tpl)
+		step.getRunner = func(ip string, config *steps.Config) (runner.Runner, error) {
+			runnerIP = ip
+			return &fakeRunner{}, nil
+		}
+
+		out := &bytes.Buffer{}
+		err = step.Run(context.Background(), out, &steps.Config{
+			Kube: model.Kube{
+				Auth: model.Auth{
+					CertificateKey: "certkey",
+				},
+			},
+			ReplaceMasterConfig: steps.ReplaceMasterConfig{
+				Name:            "master-1",
+				PrivateIP:       "10.0.0.1",
+				HealthyMasterIP: testCase.healthyIP,
+			},
+		})
+
+		if !testCase.errNil {
+			if !sgerrors.IsNotFound(err) {
+				t.Errorf("%s: expected not found error actual %v", testCase.stepName, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", testCase.stepName, err)
+			continue
+		}
+
+		if runnerIP != testCase.healthyIP {
+			t.Errorf("%s: script must be run on healthy master %s actual %s",
+				testCase.stepName, testCase.healthyIP, runnerIP)
+		}
+
+		if !strings.Contains(out.String(), testCase.expected) {
+			t.Errorf("%s: script %s must contain %s", testCase.stepName,
+				out.String(), testCase.expected)
+		}
+	}
+}
+
+func TestInit(t *testing.T) {
+	if err := templatemanager.Init("../../../../templates"); err != nil {
+		t.Fatal(err)
+	}
+
+	Init()
+
+	for _, name := range []string{UploadCertsStepName, RemoveMemberStepName} {
+		if s := steps.GetStep(name); s == nil || s.Name() != name {
+			t.Errorf("Step %s not found", name)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package steps
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/pkg/errors"
+)
+
+// RetryPolicy tells how a failed step is run again.
+type RetryPolicy struct {
+	// MaxAttempts of the step including the first one, one means no retries
+	MaxAttempts int
+	// Backoff is the delay before the second attempt, it is doubled
+	// for every next attempt.
+	Backoff time.Duration
+	// Retryable matches errors the step is retried on, transient cloud
+	// errors are matched when it is nil.
+	Retryable func(error) bool
+}
+
+// RetryStep is implemented by steps with their own retry policy, steps
+// that are not idempotent opt out of retries with NoRetry.
+type RetryStep interface {
+	RetryPolicy() RetryPolicy
+}
+
+var (
+	// DefaultRetryPolicy retries steps on transient cloud errors.
+	DefaultRetryPolicy = RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     time.Second * 5,
+	}
+
+	NoRetry = RetryPolicy{
+		MaxAttempts: 1,
+	}
+)
+
+// transientErrorCodes are aws errors of eventual consistency of
+// resources that have just been created.
+var transientErrorCodes = []string{
+	"InvalidGroup.NotFound",
+	"InvalidInstanceID.NotFound",
+	"InvalidSubnetID.NotFound",
+	"InvalidVpcID.NotFound",
+	"InvalidRouteTableID.NotFound",
+	"InvalidInternetGatewayID.NotFound",
+	"IncorrectState",
+	"DependencyViolation",
+}
+
+// GetRetryPolicy returns retry policy of the step.
+func GetRetryPolicy(step Step) RetryPolicy {
+	policy := DefaultRetryPolicy
+
+	if s, ok := step.(RetryStep); ok {
+		policy = s.RetryPolicy()
+	}
+
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	if policy.Retryable == nil {
+		policy.Retryable = IsTransient
+	}
+
+	return policy
+}
+
+// Delay returns backoff before the attempt.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	if attempt < 2 {
+		return 0
+	}
+
+	return p.Backoff * time.Duration(1<<uint(attempt-2))
+}
+
+// IsTransient returns true for rate limits, server errors and errors of
+// eventual consistency of cloud APIs.
+func IsTransient(err error) bool {
+	err = errors.Cause(err)
+
+	if request.IsErrorThrottle(err) || request.IsErrorRetryable(err) {
+		return true
+	}
+
+	if reqErr, ok := err.(awserr.RequestFailure); ok && reqErr.StatusCode() >= http.StatusInternalServerError {
+		return true
+	}
+
+	aerr, ok := err.(awserr.Error)
+
+	if !ok {
+		return false
+	}
+
+	for _, code := range transientErrorCodes {
+		if strings.EqualFold(aerr.Code(), code) {
+			return true
+		}
+	}
+
+	return false
+}
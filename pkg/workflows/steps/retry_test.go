@@ -0,0 +1,98 @@
+package steps
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	pkgerrors "github.com/pkg/errors"
+)
+
+type retryStep struct {
+	Step
+	policy RetryPolicy
+}
+
+func (s *retryStep) RetryPolicy() RetryPolicy {
+	return s.policy
+}
+
+func TestIsTransient(t *testing.T) {
+	testCases := []struct {
+		description string
+		err         error
+		expected    bool
+	}{
+		{
+			description: "nil",
+		},
+		{
+			description: "plain error",
+			err:         errors.New("ssh: handshake failed"),
+		},
+		{
+			description: "throttling",
+			err:         awserr.New("Throttling", "rate exceeded", nil),
+			expected:    true,
+		},
+		{
+			description: "wrapped request limit",
+			err: pkgerrors.Wrap(awserr.New("RequestLimitExceeded",
+				"request limit exceeded", nil), "create security group"),
+			expected: true,
+		},
+		{
+			description: "security group is not consistent yet",
+			err:         awserr.New("InvalidGroup.NotFound", "group does not exist", nil),
+			expected:    true,
+		},
+		{
+			description: "server error",
+			err: awserr.NewRequestFailure(awserr.New("InternalError",
+				"internal error", nil), 503, "id"),
+			expected: true,
+		},
+		{
+			description: "unauthorized",
+			err: awserr.NewRequestFailure(awserr.New("UnauthorizedOperation",
+				"not authorized", nil), 403, "id"),
+		},
+	}
+
+	for _, testCase := range testCases {
+		if actual := IsTransient(testCase.err); actual != testCase.expected {
+			t.Errorf("%s: expected %v actual %v", testCase.description, testCase.expected, actual)
+		}
+	}
+}
+
+func TestGetRetryPolicy(t *testing.T) {
+	policy := GetRetryPolicy(&timeoutStep{})
+
+	if policy.MaxAttempts != DefaultRetryPolicy.MaxAttempts || policy.Retryable == nil {
+		t.Errorf("Default policy must be used %+v", policy)
+	}
+
+	policy = GetRetryPolicy(&retryStep{policy: NoRetry})
+
+	if policy.MaxAttempts != 1 {
+		t.Errorf("Step must opt out of retries %+v", policy)
+	}
+
+	policy = GetRetryPolicy(&retryStep{})
+
+	if policy.MaxAttempts != 1 || policy.Retryable == nil {
+		t.Errorf("Empty policy must run step once %+v", policy)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{Backoff: time.Second}
+
+	for attempt, expected := range []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second} {
+		if actual := policy.Delay(attempt); actual != expected {
+			t.Errorf("Attempt %d: expected delay %v actual %v", attempt, expected, actual)
+		}
+	}
+}
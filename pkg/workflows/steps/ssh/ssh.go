@@ -30,12 +30,13 @@ func (s *Step) Run(ctx context.Context, writer io.Writer, config *steps.Config)
 	}
 
 	cfg := ssh.Config{
-		Host:    config.Node.PublicIp,
+		Host:    config.Kube.SSHConfig.Host(config.Node),
 		Port:    config.Kube.SSHConfig.Port,
 		User:    config.Kube.SSHConfig.User,
 		Timeout: config.Kube.SSHConfig.Timeout,
 		// TODO(stgleb): Use secure storage for private keys instead carrying them in plain text
-		Key: []byte(config.Kube.SSHConfig.BootstrapPrivateKey),
+		Key:         []byte(config.Kube.SSHConfig.BootstrapPrivateKey),
+		BastionHost: config.Kube.SSHConfig.BastionHost,
 	}
 
 	config.Runner, err = ssh.NewRunner(cfg)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"sync"
+	"time"
 )
 
 type Step interface {
@@ -14,6 +15,12 @@ type Step interface {
 	Rollback(context.Context, io.Writer, *Config) error
 }
 
+// TimeoutStep is implemented by steps that limit their run time by
+// default, e.g. waiting for instances of a slow cloud.
+type TimeoutStep interface {
+	DefaultTimeout() time.Duration
+}
+
 var (
 	m       sync.RWMutex
 	stepMap map[string]Step
@@ -34,3 +41,29 @@ func GetStep(stepName string) Step {
 	defer m.RUnlock()
 	return stepMap[stepName]
 }
+
+// StepTimeout returns timeout of the step set by the config or its
+// default, zero means the step is limited by the task only.
+func StepTimeout(step Step, config *Config) time.Duration {
+	if config != nil {
+		if timeout, ok := config.Timeouts[step.Name()]; ok && timeout > 0 {
+			return timeout
+		}
+	}
+
+	if s, ok := step.(TimeoutStep); ok {
+		return s.DefaultTimeout()
+	}
+
+	return 0
+}
+
+// WaitTimeout returns time left to deadline of the step context, steps
+// that are run outside of a task wait for the default.
+func WaitTimeout(ctx context.Context, defaultTimeout time.Duration) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		return time.Until(deadline)
+	}
+
+	return defaultTimeout
+}
@@ -1,6 +1,10 @@
 package steps
 
-import "testing"
+import (
+	"context"
+	"testing"
+	"time"
+)
 
 func TestRegisterStep(t *testing.T) {
 	var (
@@ -26,3 +30,73 @@ func TestGetStepNotFound(t *testing.T) {
 		t.Errorf("Step must be nil")
 	}
 }
+
+type timeoutStep struct {
+	Step
+	timeout time.Duration
+}
+
+func (s *timeoutStep) Name() string {
+	return "timeout"
+}
+
+func (s *timeoutStep) DefaultTimeout() time.Duration {
+	return s.timeout
+}
+
+func TestStepTimeout(t *testing.T) {
+	testCases := []struct {
+		description string
+		step        Step
+		config      *Config
+		expected    time.Duration
+	}{
+		{
+			description: "no timeout",
+			step:        &timeoutStep{},
+			expected:    0,
+		},
+		{
+			description: "default",
+			step:        &timeoutStep{timeout: time.Minute},
+			config:      &Config{},
+			expected:    time.Minute,
+		},
+		{
+			description: "configured",
+			step:        &timeoutStep{timeout: time.Minute},
+			config: &Config{
+				Timeouts: map[string]time.Duration{"timeout": time.Hour},
+			},
+			expected: time.Hour,
+		},
+		{
+			description: "other step configured",
+			step:        &timeoutStep{timeout: time.Minute},
+			config: &Config{
+				Timeouts: map[string]time.Duration{"other": time.Hour},
+			},
+			expected: time.Minute,
+		},
+	}
+
+	for _, testCase := range testCases {
+		if actual := StepTimeout(testCase.step, testCase.config); actual != testCase.expected {
+			t.Errorf("%s: expected timeout %v actual %v", testCase.description,
+				testCase.expected, actual)
+		}
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	if actual := WaitTimeout(context.Background(), time.Minute); actual != time.Minute {
+		t.Errorf("Expected default timeout %v actual %v", time.Minute, actual)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	if actual := WaitTimeout(ctx, time.Minute); actual <= time.Minute || actual > time.Hour {
+		t.Errorf("Timeout must be limited by deadline of context %v", actual)
+	}
+}
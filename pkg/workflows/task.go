@@ -6,11 +6,15 @@ import (
 	"encoding/json"
 	"io"
 	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/pborman/uuid"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
+	"github.com/supergiant/control/pkg/metrics"
 	"github.com/supergiant/control/pkg/sgerrors"
 	"github.com/supergiant/control/pkg/storage"
 	"github.com/supergiant/control/pkg/util"
@@ -20,6 +24,21 @@ import (
 
 type TaskType string
 
+// RollbackTimeout limits rollback of steps of the failed task.
+const RollbackTimeout = 30 * time.Minute
+
+var (
+	runningMux sync.Mutex
+	// Runs of tasks running in this process by task id
+	running = make(map[string]*taskRun)
+)
+
+// taskRun cancels the run of the task, the task may be run again
+// while the previous run is finishing.
+type taskRun struct {
+	cancel context.CancelFunc
+}
+
 const (
 	MasterTask       = "master"
 	NodeTask         = "node"
@@ -27,6 +46,10 @@ const (
 	PreProvisionTask = "preprovision"
 	DeleteTask       = "delete_task"
 	ImportTask       = "import"
+	SpotTask         = "spot"
+	NodePoolTask     = "pool"
+	EtcdTask         = "etcd"
+	ReconfigureTask  = "reconfigure"
 )
 
 // Task is an entity that has it own state that can be tracked
@@ -38,9 +61,19 @@ type Task struct {
 	Config       *steps.Config   `json:"config"`
 	Status       statuses.Status `json:"status"`
 	StepStatuses []StepStatus    `json:"stepsStatuses"`
+	// Statuses of tasks run on behalf of the task by task id,
+	// e.g. node tasks of the cluster task
+	Subtasks map[string]statuses.Status `json:"subtasks,omitempty"`
+	// KubeID is the kube the task is run against
+	KubeID string `json:"kubeId,omitempty"`
+	// Unix time the task has been created and saved last time
+	CreatedAt int64 `json:"createdAt,omitempty"`
+	UpdatedAt int64 `json:"updatedAt,omitempty"`
 
 	workflow   Workflow
 	repository storage.Interface
+	// Task has been added to the index of the kube
+	indexed bool
 }
 
 func NewTask(config *steps.Config, taskType string, repository storage.Interface) (*Task, error) {
@@ -78,6 +111,7 @@ func newTask(workflowType string, workflow Workflow, repository storage.Interfac
 		Type:         workflowType,
 		Status:       statuses.Todo,
 		StepStatuses: make([]StepStatus, 0, 0),
+		CreatedAt:    time.Now().Unix(),
 
 		workflow:   workflow,
 		repository: repository,
@@ -85,7 +119,7 @@ func newTask(workflowType string, workflow Workflow, repository storage.Interfac
 }
 
 // Run executes all steps of workflow and tracks the progress in persistent storage
-func (t *Task) Run(ctx context.Context, config steps.Config, out io.WriteCloser) chan error {
+func (t *Task) Run(ctx context.Context, config *steps.Config, out io.WriteCloser) chan error {
 	errChan := make(chan error, 1)
 
 	if t.Status == statuses.Success {
@@ -98,75 +132,187 @@ func (t *Task) Run(ctx context.Context, config steps.Config, out io.WriteCloser)
 		return errChan
 	}
 
+	// Statuses of restored task must match steps of its workflow
+	if len(t.StepStatuses) != len(t.workflow) {
+		errChan <- errors.Errorf("task %s has %d step statuses, workflow %s has %d steps",
+			t.ID, len(t.StepStatuses), t.Type, len(t.workflow))
+		return errChan
+	}
+
+	metrics.TaskStarted()
+
+	ctx, cancel := context.WithCancel(ctx)
+	run := setRunning(t.ID, cancel)
+	id := t.ID
+
 	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				t.Status = statuses.Error
-				if err := t.sync(ctx); err != nil {
-					logrus.Errorf("sync error %v for task %s", err, t.ID)
-				}
-				debug.PrintStack()
-				errChan <- errors.Errorf("provisioning failed, unexpected panic: %v ", r)
-			}
-		}()
+		defer metrics.TaskFinished()
 
-		t.Config = &config
+		err := t.execute(ctx, config, out)
 
-		// Save task state before first step
-		if err := t.sync(ctx); err != nil {
-			logrus.Errorf("Error saving task state %v", err)
+		// Task is not running anymore when its result is received,
+		// so it may be restarted at once
+		unsetRunning(id, run)
+		cancel()
+
+		if err != nil {
+			errChan <- err
+			return
 		}
 
-		startIndex := 0
-		// Skip successfully finished steps in case of restart
-		for index, stepStatus := range t.StepStatuses {
-			if stepStatus.Status != statuses.Success {
-				startIndex = index
-				break
+		close(errChan)
+	}()
+
+	return errChan
+}
+
+// execute runs steps of the task from the first one that has not
+// succeeded and saves the task state.
+func (t *Task) execute(ctx context.Context, config *steps.Config, out io.WriteCloser) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Status = statuses.Error
+			if err := t.sync(ctx); err != nil {
+				logrus.Errorf("sync error %v for task %s", err, t.ID)
 			}
+			debug.PrintStack()
+			err = errors.Errorf("provisioning failed, unexpected panic: %v ", r)
 		}
+	}()
 
-		logrus.Debugf("start task from step #%d startIndex %s",
-			startIndex, t.StepStatuses[startIndex].StepName)
+	t.Config = config
 
-		// Start from the first step
-		err := t.startFrom(ctx, t.ID, out, startIndex)
+	// Save task state before first step
+	if err := t.sync(ctx); err != nil {
+		logrus.Errorf("Error saving task state %v", err)
+	}
 
-		if err != nil {
-			if ctx.Err() == context.Canceled {
-				t.Status = statuses.Cancelled
-				// Save task in cancelled state
-				if err := t.sync(context.Background()); err != nil {
-					logrus.Errorf("failed to sync task %s to db: %v", t.ID, err)
-				}
-				errChan <- ctx.Err()
-			} else {
-				t.Status = statuses.Error
-				if err := t.sync(ctx); err != nil {
-					logrus.Errorf("failed to sync task %s to db: %v", t.ID, err)
-				}
-				errChan <- err
+	// Skip successfully finished steps in case of restart
+	startIndex := t.resumeIndex()
+	logrus.Debugf("start task %s from step #%d", t.ID, startIndex)
+
+	// Start from the first step
+	if err := t.startFrom(ctx, t.ID, out, startIndex); err != nil {
+		if ctx.Err() == context.Canceled {
+			t.Status = statuses.Cancelled
+			// Save task in cancelled state
+			if err := t.sync(context.Background()); err != nil {
+				logrus.Errorf("failed to sync task %s to db: %v", t.ID, err)
 			}
-
-			return
+			return ctx.Err()
 		}
 
-		// Set task state to success and save this state
-		t.Status = statuses.Success
-
+		t.Status = statuses.Error
 		if err := t.sync(ctx); err != nil {
 			logrus.Errorf("failed to sync task %s to db: %v", t.ID, err)
 		}
 
-		logrus.Infof("Task %s has finished successfully", t.ID)
-		// Notify provisioner that task output closed with error
-		if err := out.Close(); err != nil {
-			errChan <- err
+		return err
+	}
+
+	// Set task state to success and save this state
+	t.Status = statuses.Success
+
+	if err := t.sync(ctx); err != nil {
+		logrus.Errorf("failed to sync task %s to db: %v", t.ID, err)
+	}
+
+	logrus.Infof("Task %s has finished successfully", t.ID)
+
+	// Notify provisioner that task output closed with error
+	return out.Close()
+}
+
+// CancelTask cancels context of the task running in this process,
+// ErrNotFound is returned when the task is not running.
+func CancelTask(id string) error {
+	runningMux.Lock()
+	defer runningMux.Unlock()
+
+	run, ok := running[id]
+
+	if !ok {
+		return errors.Wrapf(sgerrors.ErrNotFound, "task %s is not running", id)
+	}
+
+	run.cancel()
+
+	return nil
+}
+
+// cancelled saves the task that is not running as cancelled.
+func (t *Task) cancelled(ctx context.Context) error {
+	for index := range t.StepStatuses {
+		if t.StepStatuses[index].Status == statuses.Executing {
+			t.StepStatuses[index].Status = statuses.Cancelled
 		}
-		close(errChan)
-	}()
+	}
 
-	return errChan
+	t.Status = statuses.Cancelled
+
+	return t.sync(ctx)
+}
+
+func setRunning(id string, cancel context.CancelFunc) *taskRun {
+	runningMux.Lock()
+	defer runningMux.Unlock()
+
+	run := &taskRun{cancel: cancel}
+	running[id] = run
+
+	return run
+}
+
+// unsetRunning removes the run of the task unless the task
+// has been run again meanwhile.
+func unsetRunning(id string, run *taskRun) {
+	runningMux.Lock()
+	defer runningMux.Unlock()
+
+	if running[id] == run {
+		delete(running, id)
+	}
+}
+
+func isRunning(id string) bool {
+	runningMux.Lock()
+	defer runningMux.Unlock()
+
+	_, ok := running[id]
+
+	return ok
+}
+
+// SetStatus saves status of the task that tracks its subtasks
+// instead of running steps.
+func (t *Task) SetStatus(ctx context.Context, status statuses.Status) error {
+	t.Status = status
+
+	return t.sync(ctx)
+}
+
+// SetSubtaskStatus saves status of the task run on behalf of this task,
+// it is not safe for concurrent use.
+func (t *Task) SetSubtaskStatus(ctx context.Context, id string, status statuses.Status) error {
+	if t.Subtasks == nil {
+		t.Subtasks = make(map[string]statuses.Status)
+	}
+
+	t.Subtasks[id] = status
+
+	return t.sync(ctx)
+}
+
+// resumeIndex returns index of the first step that has not finished
+// successfully, outputs of finished steps are restored from the config.
+func (t *Task) resumeIndex() int {
+	for index, stepStatus := range t.StepStatuses {
+		if stepStatus.Status != statuses.Success {
+			return index
+		}
+	}
+
+	return len(t.StepStatuses)
 }
 
 // start task execution from particular step
@@ -187,11 +333,22 @@ func (w *Task) startFrom(ctx context.Context, id string, out io.Writer, i int) e
 			logrus.Errorf("sync error %v", err)
 		}
 
-		if err := step.Run(ctx, out, w.Config); err != nil {
-			// Mark step status as error
+		start := time.Now()
+		err := w.runWithRetries(ctx, index, step, out)
+		metrics.ObserveStep(step.Name(), string(w.Config.Provider), start, err)
+
+		if err != nil {
+			// Mark step status as error or cancelled one that has been interrupted
 			w.StepStatuses[index].Status = statuses.Error
 			w.Status = statuses.Error
+
+			if ctx.Err() == context.Canceled {
+				w.StepStatuses[index].Status = statuses.Cancelled
+				w.Status = statuses.Cancelled
+			}
+
 			w.StepStatuses[index].ErrMsg = err.Error()
+			w.StepStatuses[index].TimedOut = IsStepTimeout(err)
 			if err := w.sync(ctx); err != nil {
 				logrus.Errorf("error syncing %v", err)
 			}
@@ -201,7 +358,9 @@ func (w *Task) startFrom(ctx context.Context, id string, out io.Writer, i int) e
 				logrus.Errorf("sync error %v for step %s", err2, step.Name())
 			}
 
-			if err3 := step.Rollback(ctx, out, w.Config); err3 != nil {
+			if w.Config.RollbackOnFailure && ctx.Err() != context.Canceled {
+				w.rollback(out, index)
+			} else if err3 := step.Rollback(ctx, out, w.Config); err3 != nil {
 				logrus.Errorf("rollback: step %s : %v", step.Name(), err3)
 			}
 
@@ -211,6 +370,7 @@ func (w *Task) startFrom(ctx context.Context, id string, out io.Writer, i int) e
 			// Mark step as success
 			w.StepStatuses[index].Status = statuses.Success
 			w.StepStatuses[index].ErrMsg = ""
+			w.StepStatuses[index].TimedOut = false
 			w.Status = statuses.Success
 			if err := w.sync(ctx); err != nil {
 				logrus.Errorf("sync error %v for step %s", err, step.Name())
@@ -221,8 +381,127 @@ func (w *Task) startFrom(ctx context.Context, id string, out io.Writer, i int) e
 	return nil
 }
 
+// rollback undoes the failed step and steps completed before it in
+// reverse order, failed rollbacks do not stop rollback of other steps.
+func (w *Task) rollback(out io.Writer, failedIndex int) {
+	wsLog := util.GetLogger(out)
+
+	// Task context may be expired by now
+	ctx, cancel := context.WithTimeout(context.Background(), RollbackTimeout)
+	defer cancel()
+
+	var rolledBack, leftBehind []string
+
+	for index := failedIndex; index >= 0; index-- {
+		step := w.workflow[index]
+		status := &w.StepStatuses[index]
+
+		if index != failedIndex && status.Status != statuses.Success {
+			continue
+		}
+
+		wsLog.Infof("[%s] - rolling back", step.Name())
+
+		if err := step.Rollback(ctx, out, w.Config); err != nil {
+			status.RollbackErr = err.Error()
+			leftBehind = append(leftBehind, step.Name())
+			wsLog.Infof("[%s] - rollback failed, resources are left behind: %v", step.Name(), err)
+			continue
+		}
+
+		status.RollbackErr = ""
+		rolledBack = append(rolledBack, step.Name())
+		wsLog.Infof("[%s] - rolled back", step.Name())
+
+		if index != failedIndex {
+			status.Status = statuses.RolledBack
+		}
+	}
+
+	wsLog.Infof("rollback finished, rolled back: [%s], left behind: [%s]",
+		strings.Join(rolledBack, ", "), strings.Join(leftBehind, ", "))
+
+	if err := w.sync(ctx); err != nil {
+		logrus.Errorf("sync error %v for task %s", err, w.ID)
+	}
+}
+
+// runWithRetries runs the step until it succeeds, fails with an error
+// that is not retryable or runs out of attempts of its retry policy.
+func (w *Task) runWithRetries(ctx context.Context, index int, step steps.Step, out io.Writer) error {
+	policy := steps.GetRetryPolicy(step)
+	wsLog := util.GetLogger(out)
+
+	w.StepStatuses[index].MaxAttempts = policy.MaxAttempts
+
+	for attempt := 1; ; attempt++ {
+		w.StepStatuses[index].Attempt = attempt
+
+		err := w.runStep(ctx, step, out)
+
+		if err == nil || attempt >= policy.MaxAttempts || ctx.Err() != nil || !policy.Retryable(err) {
+			return err
+		}
+
+		delay := policy.Delay(attempt + 1)
+		wsLog.Infof("[%s] - attempt %d/%d failed: %v, retry in %s",
+			step.Name(), attempt, policy.MaxAttempts, err, delay)
+
+		// Error of the attempt is visible while the step is retried
+		w.StepStatuses[index].ErrMsg = err.Error()
+		if err := w.sync(ctx); err != nil {
+			logrus.Errorf("sync error %v for step %s", err, step.Name())
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// runStep runs the step limited by its timeout, expiry of the timeout
+// is reported as StepTimeoutError.
+func (w *Task) runStep(ctx context.Context, step steps.Step, out io.Writer) error {
+	timeout := steps.StepTimeout(step, w.Config)
+
+	if timeout <= 0 {
+		return step.Run(ctx, out, w.Config)
+	}
+
+	stepCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	deadline, _ := stepCtx.Deadline()
+	err := step.Run(stepCtx, out, w.Config)
+
+	// Deadline of the task or cancellation is not a timeout of the step
+	if err != nil && ctx.Err() == nil && !time.Now().Before(deadline) {
+		return &StepTimeoutError{
+			Step:    step.Name(),
+			Timeout: timeout,
+		}
+	}
+
+	return err
+}
+
 // synchronize state of workflow to storage
 func (w *Task) sync(ctx context.Context) error {
+	if w.KubeID == "" && w.Config != nil {
+		w.KubeID = w.Config.Kube.ID
+	}
+
+	if w.KubeID != "" && !w.indexed {
+		if err := w.repository.Put(ctx, kubeIndexPrefix(w.KubeID), w.ID, []byte(w.ID)); err != nil {
+			return errors.Wrapf(err, "index task %s of kube %s", w.ID, w.KubeID)
+		}
+
+		w.indexed = true
+	}
+
+	w.UpdatedAt = time.Now().Unix()
 	data, err := json.Marshal(w)
 	buf := &bytes.Buffer{}
 
@@ -9,7 +9,9 @@ import (
 	"io"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/stretchr/testify/require"
 
 	"github.com/supergiant/control/pkg/sgerrors"
@@ -147,7 +149,7 @@ func TestTaskRunError(t *testing.T) {
 	task, err := NewTask(&steps.Config{}, "mock", s)
 
 	buffer := &bufferCloser{}
-	errChan := task.Run(context.Background(), steps.Config{}, buffer)
+	errChan := task.Run(context.Background(), &steps.Config{}, buffer)
 
 	err = <-errChan
 
@@ -195,7 +197,7 @@ func TestTaskRunSuccess(t *testing.T) {
 	task, err := NewTask(&steps.Config{}, "mock", s)
 
 	buffer := &bufferCloser{}
-	errChan := task.Run(context.Background(), steps.Config{}, buffer)
+	errChan := task.Run(context.Background(), &steps.Config{}, buffer)
 
 	err = <-errChan
 
@@ -243,7 +245,7 @@ func TestWorkflowRestart(t *testing.T) {
 	}
 
 	buffer := &bufferCloser{}
-	errChan := task.Run(context.Background(), steps.Config{}, buffer)
+	errChan := task.Run(context.Background(), &steps.Config{}, buffer)
 	err = <-errChan
 
 	if err == nil {
@@ -267,7 +269,7 @@ func TestWorkflowRestart(t *testing.T) {
 	}
 
 	buffer.Reset()
-	errChan = task.Run(context.Background(), steps.Config{}, buffer)
+	errChan = task.Run(context.Background(), &steps.Config{}, buffer)
 	err = <-errChan
 
 	if err != nil {
@@ -291,7 +293,7 @@ func TestRollback(t *testing.T) {
 	require.False(t, mockStep.rollback)
 
 	buffer := &bufferCloser{}
-	errChan := task.Run(context.Background(), steps.Config{}, buffer)
+	errChan := task.Run(context.Background(), &steps.Config{}, buffer)
 	err = <-errChan
 	require.Error(t, err)
 
@@ -334,8 +336,359 @@ func TestPanicHandler(t *testing.T) {
 			step,
 		},
 	}
-	errChan := task.Run(context.Background(), steps.Config{}, &bufferCloser{})
+	errChan := task.Run(context.Background(), &steps.Config{}, &bufferCloser{})
 
 	err := <-errChan
 	require.Error(t, err)
 }
+
+func TestTaskRunStepStatusesMismatch(t *testing.T) {
+	s := &MockRepository{
+		storage: make(map[string][]byte),
+	}
+
+	step := &MockStep{
+		name: "step",
+	}
+	task := &Task{
+		ID: "XYZ",
+		StepStatuses: []StepStatus{
+			{
+				StepName: step.Name(),
+				Status:   statuses.Success,
+			},
+			{
+				StepName: "removed",
+				Status:   statuses.Todo,
+			},
+		},
+		repository: s,
+		workflow: []steps.Step{
+			step,
+		},
+	}
+
+	err := <-task.Run(context.Background(), &steps.Config{}, &bufferCloser{})
+
+	if err == nil {
+		t.Fatal("Error expected for mismatched step statuses")
+	}
+
+	if step.counter != 0 {
+		t.Errorf("Task with mismatched step statuses must not be run")
+	}
+}
+
+type SlowStep struct {
+	MockStep
+}
+
+func (s *SlowStep) Run(ctx context.Context, out io.Writer, config *steps.Config) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestTaskRunStepTimeout(t *testing.T) {
+	s := &MockRepository{
+		storage: make(map[string][]byte),
+	}
+
+	wf := []steps.Step{
+		&MockStep{name: "step1", errs: nil},
+		&SlowStep{MockStep{name: "slow"}},
+	}
+
+	workflowMap = make(map[string]Workflow)
+	RegisterWorkFlow("mock", wf)
+	task, err := NewTask(&steps.Config{}, "mock", s)
+	require.NoError(t, err)
+
+	buffer := &bufferCloser{}
+	errChan := task.Run(context.Background(), &steps.Config{
+		Timeouts: map[string]time.Duration{"slow": time.Millisecond * 10},
+	}, buffer)
+
+	err = <-errChan
+
+	if !IsStepTimeout(err) {
+		t.Errorf("Expected step timeout error actual %v", err)
+	}
+
+	w := &Task{}
+	require.NoError(t, json.Unmarshal(s.storage[Prefix+task.ID], w))
+
+	status := w.StepStatuses[1]
+
+	if !status.TimedOut || status.ErrMsg != "step slow timed out after 10ms" {
+		t.Errorf("Wrong status of timed out step %+v", status)
+	}
+
+	if w.StepStatuses[0].TimedOut {
+		t.Errorf("Step %s must not time out", w.StepStatuses[0].StepName)
+	}
+}
+
+func TestTaskRunCancelNotTimeout(t *testing.T) {
+	s := &MockRepository{
+		storage: make(map[string][]byte),
+	}
+
+	workflowMap = make(map[string]Workflow)
+	RegisterWorkFlow("mock", []steps.Step{&SlowStep{MockStep{name: "slow"}}})
+	task, err := NewTask(&steps.Config{}, "mock", s)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errChan := task.Run(ctx, &steps.Config{
+		Timeouts: map[string]time.Duration{"slow": time.Minute},
+	}, &bufferCloser{})
+	cancel()
+
+	if err := <-errChan; err != context.Canceled {
+		t.Errorf("Expected error %v actual %v", context.Canceled, err)
+	}
+}
+
+type FlakyStep struct {
+	MockStep
+	failures int
+	err      error
+	policy   steps.RetryPolicy
+}
+
+func (s *FlakyStep) Run(ctx context.Context, out io.Writer, config *steps.Config) error {
+	s.counter++
+
+	if s.counter <= s.failures {
+		return s.err
+	}
+
+	return nil
+}
+
+func (s *FlakyStep) RetryPolicy() steps.RetryPolicy {
+	return s.policy
+}
+
+func TestTaskRunRetries(t *testing.T) {
+	throttled := awserr.New("RequestLimitExceeded", "request limit exceeded", nil)
+	policy := steps.RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     time.Millisecond,
+	}
+
+	testCases := []struct {
+		description      string
+		step             *FlakyStep
+		expectedErr      bool
+		expectedAttempts int
+		expectedMax      int
+	}{
+		{
+			description: "success after retries",
+			step: &FlakyStep{
+				MockStep: MockStep{name: "flaky"},
+				failures: 2,
+				err:      errors.New("wrapped: " + throttled.Error()),
+				policy: steps.RetryPolicy{
+					MaxAttempts: 5,
+					Backoff:     time.Millisecond,
+					Retryable: func(error) bool {
+						return true
+					},
+				},
+			},
+			expectedAttempts: 3,
+			expectedMax:      5,
+		},
+		{
+			description: "transient error",
+			step: &FlakyStep{
+				MockStep: MockStep{name: "flaky"},
+				failures: 4,
+				err:      throttled,
+				policy:   policy,
+			},
+			expectedAttempts: 5,
+			expectedMax:      5,
+		},
+		{
+			description: "out of attempts",
+			step: &FlakyStep{
+				MockStep: MockStep{name: "flaky"},
+				failures: 5,
+				err:      throttled,
+				policy:   policy,
+			},
+			expectedErr:      true,
+			expectedAttempts: 5,
+			expectedMax:      5,
+		},
+		{
+			description: "error is not retryable",
+			step: &FlakyStep{
+				MockStep: MockStep{name: "flaky"},
+				failures: 1,
+				err:      errors.New("permission denied"),
+				policy:   policy,
+			},
+			expectedErr:      true,
+			expectedAttempts: 1,
+			expectedMax:      5,
+		},
+		{
+			description: "opt out",
+			step: &FlakyStep{
+				MockStep: MockStep{name: "flaky"},
+				failures: 1,
+				err:      throttled,
+				policy:   steps.NoRetry,
+			},
+			expectedErr:      true,
+			expectedAttempts: 1,
+			expectedMax:      1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		s := &MockRepository{
+			storage: make(map[string][]byte),
+		}
+
+		workflowMap = make(map[string]Workflow)
+		RegisterWorkFlow("mock", []steps.Step{testCase.step})
+		task, err := NewTask(&steps.Config{}, "mock", s)
+		require.NoError(t, err)
+
+		err = <-task.Run(context.Background(), &steps.Config{}, &bufferCloser{})
+
+		if testCase.expectedErr != (err != nil) {
+			t.Errorf("%s: unexpected error %v", testCase.description, err)
+		}
+
+		w := &Task{}
+		require.NoError(t, json.Unmarshal(s.storage[Prefix+task.ID], w))
+
+		status := w.StepStatuses[0]
+
+		if status.Attempt != testCase.expectedAttempts || status.MaxAttempts != testCase.expectedMax {
+			t.Errorf("%s: expected attempt %d/%d actual %d/%d", testCase.description,
+				testCase.expectedAttempts, testCase.expectedMax, status.Attempt, status.MaxAttempts)
+		}
+
+		if testCase.step.counter != testCase.expectedAttempts {
+			t.Errorf("%s: expected runs %d actual %d", testCase.description,
+				testCase.expectedAttempts, testCase.step.counter)
+		}
+	}
+}
+
+type RollbackStep struct {
+	MockStep
+	err   error
+	order *[]string
+}
+
+func (s *RollbackStep) Rollback(context.Context, io.Writer, *steps.Config) error {
+	*s.order = append(*s.order, s.name)
+	return s.err
+}
+
+func TestTaskRunRollbackOnFailure(t *testing.T) {
+	var order []string
+	wf := []steps.Step{
+		&RollbackStep{MockStep: MockStep{name: "step1"}, order: &order},
+		&RollbackStep{MockStep: MockStep{name: "step2"}, err: errors.New("access denied"), order: &order},
+		&RollbackStep{MockStep: MockStep{name: "step3"}, order: &order},
+		&RollbackStep{MockStep: MockStep{name: "step4", errs: []error{errors.New("error")}}, order: &order},
+		&RollbackStep{MockStep: MockStep{name: "step5"}, order: &order},
+	}
+
+	s := &MockRepository{
+		storage: make(map[string][]byte),
+	}
+
+	workflowMap = make(map[string]Workflow)
+	RegisterWorkFlow("mock", wf)
+	task, err := NewTask(&steps.Config{}, "mock", s)
+	require.NoError(t, err)
+
+	buffer := &bufferCloser{}
+	err = <-task.Run(context.Background(), &steps.Config{RollbackOnFailure: true}, buffer)
+	require.Error(t, err)
+
+	require.Equal(t, []string{"step4", "step3", "step2", "step1"}, order)
+
+	w := &Task{}
+	require.NoError(t, json.Unmarshal(s.storage[Prefix+task.ID], w))
+
+	expected := []statuses.Status{
+		statuses.RolledBack,
+		statuses.Success,
+		statuses.RolledBack,
+		statuses.Error,
+		statuses.Todo,
+	}
+
+	for i, status := range expected {
+		if w.StepStatuses[i].Status != status {
+			t.Errorf("step %s: expected status %s actual %s",
+				w.StepStatuses[i].StepName, status, w.StepStatuses[i].Status)
+		}
+	}
+
+	require.Equal(t, "access denied", w.StepStatuses[1].RollbackErr)
+	require.Contains(t, buffer.String(), "rolled back: [step4, step3, step1], left behind: [step2]")
+}
+
+type BlockingStep struct {
+	MockStep
+	started chan struct{}
+}
+
+func (s *BlockingStep) Run(ctx context.Context, out io.Writer, config *steps.Config) error {
+	close(s.started)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s *BlockingStep) RetryPolicy() steps.RetryPolicy {
+	return steps.NoRetry
+}
+
+func TestCancelTask(t *testing.T) {
+	s := &MockRepository{
+		storage: make(map[string][]byte),
+	}
+
+	blocking := &BlockingStep{
+		MockStep: MockStep{name: "blocking"},
+		started:  make(chan struct{}),
+	}
+
+	workflowMap = make(map[string]Workflow)
+	RegisterWorkFlow("mock", []steps.Step{
+		&MockStep{name: "step1"},
+		blocking,
+		&MockStep{name: "step3"},
+	})
+	task, err := NewTask(&steps.Config{}, "mock", s)
+	require.NoError(t, err)
+
+	errChan := task.Run(context.Background(), &steps.Config{}, &bufferCloser{})
+
+	<-blocking.started
+	require.NoError(t, CancelTask(task.ID))
+
+	require.Equal(t, context.Canceled, <-errChan)
+
+	w := &Task{}
+	require.NoError(t, json.Unmarshal(s.storage[Prefix+task.ID], w))
+
+	require.Equal(t, statuses.Cancelled, w.Status)
+	require.Equal(t, statuses.Cancelled, w.StepStatuses[1].Status)
+	require.Equal(t, statuses.Todo, w.StepStatuses[2].Status)
+
+	err = CancelTask(task.ID)
+	require.True(t, sgerrors.IsNotFound(err), "finished task must not be found %v", err)
+}
@@ -18,15 +18,16 @@ func DeserializeTask(data []byte, repository storage.Interface) (*Task, error) {
 	task.repository = repository
 	task.workflow = GetWorkflow(task.Type)
 	// NOTE(stgleb): If step has failed on machine creation state
-	// public ip will be blank and lead to error when restart
+	// ip will be blank and lead to error when restart
 	// TODO(stgleb): Move ssh runner creation to task Restart method
-	if task.Config != nil && task.Config.Node.PublicIp != "" {
+	if task.Config != nil && task.Config.Kube.SSHConfig.Host(task.Config.Node) != "" {
 		cfg := ssh.Config{
-			Host:    task.Config.Node.PublicIp,
-			Port:    task.Config.Kube.SSHConfig.Port,
-			User:    task.Config.Kube.SSHConfig.User,
-			Timeout: task.Config.Kube.SSHConfig.Timeout,
-			Key:     []byte(task.Config.Kube.SSHConfig.BootstrapPrivateKey),
+			Host:        task.Config.Kube.SSHConfig.Host(task.Config.Node),
+			Port:        task.Config.Kube.SSHConfig.Port,
+			User:        task.Config.Kube.SSHConfig.User,
+			Timeout:     task.Config.Kube.SSHConfig.Timeout,
+			Key:         []byte(task.Config.Kube.SSHConfig.BootstrapPrivateKey),
+			BastionHost: task.Config.Kube.SSHConfig.BastionHost,
 		}
 
 		task.Config.Runner, err = ssh.NewRunner(cfg)
@@ -1,11 +1,14 @@
 package workflows
 
 import (
+	"fmt"
 	"sync"
+	"time"
+
+	"github.com/pkg/errors"
 
 	"github.com/supergiant/control/pkg/workflows/statuses"
 	"github.com/supergiant/control/pkg/workflows/steps"
-	"github.com/supergiant/control/pkg/workflows/steps/addons"
 	"github.com/supergiant/control/pkg/workflows/steps/amazon"
 	"github.com/supergiant/control/pkg/workflows/steps/apply"
 	"github.com/supergiant/control/pkg/workflows/steps/authorizedkeys"
@@ -13,22 +16,27 @@ import (
 	"github.com/supergiant/control/pkg/workflows/steps/bootstraptoken"
 	"github.com/supergiant/control/pkg/workflows/steps/certificates"
 	"github.com/supergiant/control/pkg/workflows/steps/cloudcontroller"
+	"github.com/supergiant/control/pkg/workflows/steps/clusterautoscaler"
 	"github.com/supergiant/control/pkg/workflows/steps/clustercheck"
 	"github.com/supergiant/control/pkg/workflows/steps/configmap"
 	"github.com/supergiant/control/pkg/workflows/steps/digitalocean"
 	"github.com/supergiant/control/pkg/workflows/steps/docker"
 	"github.com/supergiant/control/pkg/workflows/steps/downloadk8sbinary"
 	"github.com/supergiant/control/pkg/workflows/steps/drain"
+	"github.com/supergiant/control/pkg/workflows/steps/etcd"
 	"github.com/supergiant/control/pkg/workflows/steps/evacuate"
 	"github.com/supergiant/control/pkg/workflows/steps/gce"
+	"github.com/supergiant/control/pkg/workflows/steps/gpu"
 	"github.com/supergiant/control/pkg/workflows/steps/helm"
+	"github.com/supergiant/control/pkg/workflows/steps/hooks"
 	"github.com/supergiant/control/pkg/workflows/steps/install_app"
 	"github.com/supergiant/control/pkg/workflows/steps/kubeadm"
 	"github.com/supergiant/control/pkg/workflows/steps/kubelet"
+	"github.com/supergiant/control/pkg/workflows/steps/kubeletconfig"
 	"github.com/supergiant/control/pkg/workflows/steps/network"
 	"github.com/supergiant/control/pkg/workflows/steps/poststart"
-	"github.com/supergiant/control/pkg/workflows/steps/prometheus"
 	"github.com/supergiant/control/pkg/workflows/steps/provider"
+	"github.com/supergiant/control/pkg/workflows/steps/replacemaster"
 	"github.com/supergiant/control/pkg/workflows/steps/ssh"
 	"github.com/supergiant/control/pkg/workflows/steps/storageclass"
 	"github.com/supergiant/control/pkg/workflows/steps/tiller"
@@ -42,6 +50,30 @@ type StepStatus struct {
 	Status   statuses.Status `json:"status"`
 	StepName string          `json:"stepName"`
 	ErrMsg   string          `json:"errorMessage"`
+	// TimedOut is set when the step has failed by its timeout
+	TimedOut bool `json:"timedOut,omitempty"`
+	// Attempt of the step out of max attempts of its retry policy
+	Attempt     int `json:"attempt,omitempty"`
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+	// RollbackErr is set when rollback of the step has failed and
+	// its resources are left behind.
+	RollbackErr string `json:"rollbackError,omitempty"`
+}
+
+// StepTimeoutError is returned when the step does not finish in time.
+type StepTimeoutError struct {
+	Step    string
+	Timeout time.Duration
+}
+
+func (e *StepTimeoutError) Error() string {
+	return fmt.Sprintf("step %s timed out after %s", e.Step, e.Timeout)
+}
+
+// IsStepTimeout returns true when the step has failed by its timeout.
+func IsStepTimeout(err error) bool {
+	_, ok := errors.Cause(err).(*StepTimeoutError)
+	return ok
 }
 
 // Workflow is a template for doing some actions
@@ -61,10 +93,25 @@ const (
 	ProvisionMaster = "ProvisionMaster"
 	ProvisionNode   = "ProvisionNode"
 	DeleteNode      = "DeleteNode"
+	DrainNode       = "DrainNode"
+	ReplaceMaster   = "ReplaceMaster"
+	ProvisionEtcd   = "ProvisionEtcd"
 	DeleteCluster   = "DeleteCluster"
 	ImportCluster   = "ImportCluster"
 	Upgrade         = "Upgrade"
 	ApplyYaml       = "ApplyYaml"
+	SpotInstance    = "SpotInstance"
+	SpotFleet       = "SpotFleet"
+	// Auto scaling group of autoscaled node pool is created and deleted
+	// as a whole, its nodes are not provisioned by control.
+	AutoScalingGroup       = "AutoScalingGroup"
+	DeleteAutoScalingGroup = "DeleteAutoScalingGroup"
+	// NodePool task has no steps, it tracks node tasks of the pool
+	NodePool = "NodePool"
+	// ReconfigureNodes task has no steps, it tracks ReconfigureNode
+	// tasks that are run one machine at a time.
+	ReconfigureNodes = "ReconfigureNodes"
+	ReconfigureNode  = "ReconfigureNode"
 )
 
 type WorkflowSet struct {
@@ -84,6 +131,9 @@ func Init() {
 	workflowMap = make(map[string]Workflow)
 
 	awsInfra := []steps.Step{
+		steps.GetStep(amazon.StepValidateRegion),
+		steps.GetStep(amazon.StepValidateKMSKey),
+		steps.GetStep(amazon.StepValidateLoadBalancer),
 		steps.GetStep(amazon.StepFindAMI),
 		steps.GetStep(amazon.StepCreateVPC),
 		steps.GetStep(amazon.StepCreateSecurityGroups),
@@ -93,6 +143,7 @@ func Init() {
 		steps.GetStep(amazon.StepCreateSubnets),
 		steps.GetStep(amazon.StepCreateRouteTable),
 		steps.GetStep(amazon.StepAssociateRouteTable),
+		steps.GetStep(amazon.StepCreateBastion),
 		steps.GetStep(amazon.StepCreateLoadBalancer),
 	}
 
@@ -124,6 +175,7 @@ func Init() {
 		&provider.RegisterInstanceToLoadBalancer{},
 		steps.GetStep(ssh.StepName),
 		steps.GetStep(authorizedkeys.StepName),
+		steps.GetStep(hooks.PreProvisionStepName),
 		steps.GetStep(downloadk8sbinary.StepName),
 		steps.GetStep(docker.StepName),
 		steps.GetStep(certificates.StepName),
@@ -131,22 +183,53 @@ func Init() {
 		steps.GetStep(bootstraptoken.StepName),
 		steps.GetStep(kubelet.StepName),
 		steps.GetStep(poststart.StepName),
+		steps.GetStep(hooks.PostProvisionStepName),
 		steps.GetStep(network.StepName),
 		steps.GetStep(clustercheck.StepName),
 		steps.GetStep(helm.StepName),
 	}
 
+	// New master joins the cluster with certs uploaded by a healthy one,
+	// that removes the replaced master from etcd afterwards.
+	replaceMasterWorkflow := []steps.Step{
+		provider.StepCreateMachine{},
+		&provider.RegisterInstanceToLoadBalancer{},
+		steps.GetStep(ssh.StepName),
+		steps.GetStep(authorizedkeys.StepName),
+		steps.GetStep(hooks.PreProvisionStepName),
+		steps.GetStep(downloadk8sbinary.StepName),
+		steps.GetStep(docker.StepName),
+		steps.GetStep(certificates.StepName),
+		steps.GetStep(replacemaster.UploadCertsStepName),
+		steps.GetStep(kubeadm.StepName),
+		steps.GetStep(kubelet.StepName),
+		steps.GetStep(poststart.StepName),
+		steps.GetStep(hooks.PostProvisionStepName),
+		steps.GetStep(replacemaster.RemoveMemberStepName),
+	}
+
+	// Dedicated etcd members are provisioned before masters
+	etcdWorkflow := []steps.Step{
+		provider.StepCreateMachine{},
+		steps.GetStep(ssh.StepName),
+		steps.GetStep(authorizedkeys.StepName),
+		steps.GetStep(etcd.StepName),
+	}
+
 	nodeWorkflow := []steps.Step{
 		// TODO(stgleb): Provider steps should also register theirself it step map
 		provider.StepCreateMachine{},
 		steps.GetStep(ssh.StepName),
 		steps.GetStep(authorizedkeys.StepName),
+		steps.GetStep(hooks.PreProvisionStepName),
 		steps.GetStep(downloadk8sbinary.StepName),
 		steps.GetStep(docker.StepName),
 		steps.GetStep(certificates.StepName),
 		steps.GetStep(kubeadm.StepName),
 		steps.GetStep(kubelet.StepName),
 		steps.GetStep(poststart.StepName),
+		steps.GetStep(gpu.StepName),
+		steps.GetStep(hooks.PostProvisionStepName),
 	}
 
 	postProvision := []steps.Step{
@@ -154,9 +237,8 @@ func Init() {
 		steps.GetStep(cloudcontroller.StepName),
 		steps.GetStep(storageclass.StepName),
 		steps.GetStep(tiller.StepName),
-		steps.GetStep(prometheus.StepName),
+		steps.GetStep(clusterautoscaler.StepName),
 		steps.GetStep(configmap.StepName),
-		addons.Step{},
 		provider.StepPostStartCluster{},
 	}
 
@@ -169,6 +251,11 @@ func Init() {
 		provider.StepDeleteMachine{},
 	}
 
+	// Nodes of kubes without cloud account are only removed from k8s
+	drainNodeWorkflow := []steps.Step{
+		steps.GetStep(drain.StepName),
+	}
+
 	deleteClusterWorkflow := []steps.Step{
 		provider.DeleteCluster{},
 	}
@@ -180,6 +267,11 @@ func Init() {
 		steps.GetStep(uncordon.StepName),
 	}
 
+	reconfigureNode := []steps.Step{
+		steps.GetStep(ssh.StepName),
+		steps.GetStep(kubeletconfig.StepName),
+	}
+
 	apply := []steps.Step{
 		steps.GetStep(ssh.StepName),
 		steps.GetStep(apply.StepName),
@@ -190,6 +282,28 @@ func Init() {
 		steps.GetStep(install_app.StepName),
 	}
 
+	spotInstance := []steps.Step{
+		steps.GetStep(amazon.RequestSpotInstancesStepName),
+		steps.GetStep(amazon.WaitSpotRequestsStepName),
+		steps.GetStep(amazon.SetSpotMetadataOptionsStepName),
+		steps.GetStep(amazon.TagSpotInstancesStepName),
+		steps.GetStep(amazon.RegisterSpotMachinesStepName),
+	}
+
+	spotFleet := []steps.Step{
+		steps.GetStep(amazon.CreateSpotFleetStepName),
+		steps.GetStep(amazon.TagSpotInstancesStepName),
+		steps.GetStep(amazon.RegisterSpotMachinesStepName),
+	}
+
+	autoScalingGroup := []steps.Step{
+		steps.GetStep(amazon.CreateAutoScalingGroupStepName),
+	}
+
+	deleteAutoScalingGroup := []steps.Step{
+		steps.GetStep(amazon.DeleteAutoScalingGroupsStepName),
+	}
+
 	m.Lock()
 	defer m.Unlock()
 
@@ -201,12 +315,22 @@ func Init() {
 	workflowMap[ProvisionMaster] = masterWorkflow
 	workflowMap[ProvisionNode] = nodeWorkflow
 	workflowMap[DeleteNode] = deleteMachineWorkflow
+	workflowMap[DrainNode] = drainNodeWorkflow
 	workflowMap[DeleteCluster] = deleteClusterWorkflow
 	workflowMap[PostProvision] = postProvision
 	workflowMap[ImportCluster] = importClusterWorkflow
 	workflowMap[Upgrade] = upgradeNode
 	workflowMap[ApplyYaml] = apply
 	workflowMap[InstallApp] = installApp
+	workflowMap[SpotInstance] = spotInstance
+	workflowMap[SpotFleet] = spotFleet
+	workflowMap[AutoScalingGroup] = autoScalingGroup
+	workflowMap[DeleteAutoScalingGroup] = deleteAutoScalingGroup
+	workflowMap[NodePool] = Workflow{}
+	workflowMap[ReconfigureNodes] = Workflow{}
+	workflowMap[ReconfigureNode] = reconfigureNode
+	workflowMap[ReplaceMaster] = replaceMasterWorkflow
+	workflowMap[ProvisionEtcd] = etcdWorkflow
 }
 
 func RegisterWorkFlow(workflowName string, workflow Workflow) {
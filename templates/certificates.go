@@ -14,5 +14,21 @@ sudo bash -c "cat > /etc/kubernetes/pki/ca.crt <<EOF
 sudo bash -c "cat > /etc/kubernetes/pki/ca.key <<EOF
 {{ .CAKey }}EOF"
 
+{{ end }}
+
+{{ if and .IsMaster .EtcdCACert }}
+
+sudo mkdir -p /etc/kubernetes/pki/etcd
+
+# API server accesses dedicated etcd with client certificate generated on control side
+sudo bash -c "cat > /etc/kubernetes/pki/etcd/ca.crt <<EOF
+{{ .EtcdCACert }}EOF"
+
+sudo bash -c "cat > /etc/kubernetes/pki/apiserver-etcd-client.crt <<EOF
+{{ .EtcdClientCert }}EOF"
+
+sudo bash -c "cat > /etc/kubernetes/pki/apiserver-etcd-client.key <<EOF
+{{ .EtcdClientKey }}EOF"
+
 {{ end }}
 `
@@ -0,0 +1,88 @@
+package templates
+
+const etcdTpl = `
+set -e
+
+ETCD_VERSION=v{{ .Version }}
+ETCD_PKI=/etc/etcd/pki
+
+curl -sSL https://github.com/etcd-io/etcd/releases/download/${ETCD_VERSION}/etcd-${ETCD_VERSION}-linux-amd64.tar.gz -o /tmp/etcd.tar.gz
+sudo tar -xzf /tmp/etcd.tar.gz -C /usr/local/bin --strip-components=1 \
+etcd-${ETCD_VERSION}-linux-amd64/etcd etcd-${ETCD_VERSION}-linux-amd64/etcdctl
+rm /tmp/etcd.tar.gz
+
+sudo mkdir -p ${ETCD_PKI} /var/lib/etcd
+
+sudo bash -c "cat > ${ETCD_PKI}/ca.crt <<EOF
+{{ .CACert }}EOF"
+
+sudo bash -c "cat > ${ETCD_PKI}/member.crt <<EOF
+{{ .Cert }}EOF"
+
+sudo bash -c "cat > ${ETCD_PKI}/member.key <<EOF
+{{ .Key }}EOF"
+
+sudo chmod 600 ${ETCD_PKI}/member.key
+
+{{ if .Endpoints }}
+# Member joins etcd cluster of members provisioned before it
+INITIAL_CLUSTER=$(sudo ETCDCTL_API=3 etcdctl --endpoints {{ .Endpoints }} \
+--cacert ${ETCD_PKI}/ca.crt --cert ${ETCD_PKI}/member.crt --key ${ETCD_PKI}/member.key \
+member add {{ .Name }} --peer-urls https://{{ .PrivateIP }}:2380 | grep ETCD_INITIAL_CLUSTER= | cut -d '"' -f 2)
+INITIAL_CLUSTER_STATE=existing
+{{ else }}
+INITIAL_CLUSTER={{ .Name }}=https://{{ .PrivateIP }}:2380
+INITIAL_CLUSTER_STATE=new
+{{ end }}
+
+sudo bash -c "cat << EOF > /etc/systemd/system/etcd.service
+[Unit]
+Description=etcd
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+ExecStart=/usr/local/bin/etcd \
+--name {{ .Name }} \
+--data-dir /var/lib/etcd \
+--listen-client-urls https://{{ .PrivateIP }}:2379,https://127.0.0.1:2379 \
+--advertise-client-urls https://{{ .PrivateIP }}:2379 \
+--listen-peer-urls https://{{ .PrivateIP }}:2380 \
+--initial-advertise-peer-urls https://{{ .PrivateIP }}:2380 \
+--initial-cluster ${INITIAL_CLUSTER} \
+--initial-cluster-state ${INITIAL_CLUSTER_STATE} \
+--client-cert-auth \
+--trusted-ca-file ${ETCD_PKI}/ca.crt \
+--cert-file ${ETCD_PKI}/member.crt \
+--key-file ${ETCD_PKI}/member.key \
+--peer-client-cert-auth \
+--peer-trusted-ca-file ${ETCD_PKI}/ca.crt \
+--peer-cert-file ${ETCD_PKI}/member.crt \
+--peer-key-file ${ETCD_PKI}/member.key
+Restart=always
+RestartSec=5s
+
+[Install]
+WantedBy=multi-user.target
+EOF"
+
+sudo systemctl daemon-reload
+sudo systemctl enable etcd
+sudo systemctl restart etcd
+
+# Wait for the member to become healthy
+for i in $(seq 1 60)
+do
+	if sudo ETCDCTL_API=3 etcdctl --endpoints https://127.0.0.1:2379 \
+	--cacert ${ETCD_PKI}/ca.crt --cert ${ETCD_PKI}/member.crt --key ${ETCD_PKI}/member.key \
+	endpoint health
+	then
+		exit 0
+	fi
+
+	sleep 5
+done
+
+echo "etcd member {{ .Name }} is not healthy"
+exit 1
+`
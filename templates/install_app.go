@@ -6,5 +6,5 @@ sudo bash -c "cat > override.yaml <<EOF
 {{ .Values }}
 EOF"
 
-sudo helm install {{ .ChartRef }} {{ if .Name }}--name {{ .Name }}{{ end}} --namespace {{ .Namespace }} -f override.yaml --debug 
+sudo helm install {{ .ChartRef }} {{ if .Name }}--name {{ .Name }}{{ end}} {{ if .Namespace }}--namespace {{ .Namespace }}{{ end }} -f override.yaml --debug 
 `
@@ -54,6 +54,9 @@ apiServer:
     authorization-mode: Node,RBAC
     {{ if .Provider }}cloud-provider: {{ .Provider }}{{ end }}
     kubelet-preferred-address-types: InternalIP,Hostname,ExternalIP
+{{- range $name, $value := .APIServerExtraArgs }}
+    {{ $name }}: '{{ $value }}'
+{{- end }}
   timeoutForControlPlane: 8m0s
 controllerManager:
   extraArgs:
@@ -61,8 +64,17 @@ controllerManager:
 dns:
   type: CoreDNS
 etcd:
+{{- if .EtcdEndpoints }}
+  external:
+    endpoints:{{ range .EtcdEndpoints }}
+    - {{ . }}{{ end }}
+    caFile: /etc/kubernetes/pki/etcd/ca.crt
+    certFile: /etc/kubernetes/pki/apiserver-etcd-client.crt
+    keyFile: /etc/kubernetes/pki/apiserver-etcd-client.key
+{{- else }}
   local:
     dataDir: /var/lib/etcd
+{{- end }}
 networking:
   dnsDomain: cluster.local
   podSubnet: {{ .CIDR }}
@@ -106,6 +118,9 @@ apiServer:
   extraArgs:
     authorization-mode: Node,RBAC
     {{ if .Provider }}cloud-provider: {{ .Provider }}{{ end }}
+{{- range $name, $value := .APIServerExtraArgs }}
+    {{ $name }}: '{{ $value }}'
+{{- end }}
   timeoutForControlPlane: 8m0s
 controllerManager:
   extraArgs:
@@ -113,8 +128,17 @@ controllerManager:
 dns:
   type: CoreDNS
 etcd:
+{{- if .EtcdEndpoints }}
+  external:
+    endpoints:{{ range .EtcdEndpoints }}
+    - {{ . }}{{ end }}
+    caFile: /etc/kubernetes/pki/etcd/ca.crt
+    certFile: /etc/kubernetes/pki/apiserver-etcd-client.crt
+    keyFile: /etc/kubernetes/pki/apiserver-etcd-client.key
+{{- else }}
   local:
     dataDir: /var/lib/etcd
+{{- end }}
 networking:
   dnsDomain: cluster.local
   podSubnet: {{ .CIDR }}
@@ -144,6 +168,8 @@ nodeRegistration:
     node-ip: {{ .NodeIp }}
     {{ if .Provider }}cloud-provider: {{ .Provider }}{{ end }}
     {{ if .ProviderID }}provider-id: {{ .ProviderID }}{{ end }}
+    {{ if .NodeLabels }}node-labels: {{ .NodeLabels }}{{ end }}
+    {{ if .Taints }}register-with-taints: {{ .Taints }}{{ end }}
 discovery:
   bootstrapToken:
     token: {{ .Token }}
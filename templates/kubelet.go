@@ -16,8 +16,10 @@ DNS.2 = kubernetes.default
 DNS.3 = kubernetes.default.svc
 DNS.4 = kubernetes.default.svc.cluster
 DNS.5 = kubernetes.default.svc.cluster.local
-IP.1 = {{ .PublicIP }}
-IP.2 = {{ .PrivateIP }}
+IP.1 = {{ .PrivateIP }}
+{{if .PublicIP }}
+IP.2 = {{ .PublicIP }}
+{{ end }}
 {{if .KubernetesSvcIP }}
 IP.3 = {{ .KubernetesSvcIP }}
 {{ end }}
@@ -80,7 +82,8 @@ sudo rm /etc/kubernetes/pki/admin.crt
 sudo bash -c "cat > /etc/default/kubelet <<EOF
 KUBELET_EXTRA_ARGS=--tls-cert-file=/etc/kubernetes/pki/kubelet.crt \
 --tls-private-key-file=/etc/kubernetes/pki/kubelet.key \
---rotate-certificates  --feature-gates=RotateKubeletClientCertificate=true
+--rotate-certificates  --feature-gates=RotateKubeletClientCertificate=true{{ range $name, $value := .ExtraArgs }} \
+--{{ $name }}={{ $value }}{{ end }}
 EOF"
 
 sudo systemctl daemon-reload
@@ -0,0 +1,17 @@
+package templates
+
+const kubeletConfigTpl = `
+sudo bash -c "cat > /etc/default/kubelet <<EOF
+KUBELET_EXTRA_ARGS=--tls-cert-file=/etc/kubernetes/pki/kubelet.crt \
+--tls-private-key-file=/etc/kubernetes/pki/kubelet.key \
+--rotate-certificates  --feature-gates=RotateKubeletClientCertificate=true{{ range $name, $value := .ExtraArgs }} \
+--{{ $name }}={{ $value }}{{ end }}
+EOF"
+
+sudo systemctl daemon-reload
+sudo systemctl restart kubelet
+
+# Kubelet that fails on its flags is restarted by systemd
+sleep 10
+until sudo systemctl is-active --quiet kubelet; do printf '.'; sleep 5; done
+`
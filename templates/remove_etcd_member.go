@@ -0,0 +1,25 @@
+package templates
+
+const removeEtcdMemberTpl = `
+set -e
+
+HOSTNAME="$(hostname)"
+{{ if eq .Provider "aws" }}
+HOSTNAME="$(hostname -f)"
+{{ end }}
+
+ETCDCTL="sudo kubectl -n kube-system exec etcd-${HOSTNAME} -- env ETCDCTL_API=3 etcdctl \
+--endpoints https://127.0.0.1:2379 \
+--cacert /etc/kubernetes/pki/etcd/ca.crt \
+--cert /etc/kubernetes/pki/etcd/peer.crt \
+--key /etc/kubernetes/pki/etcd/peer.key"
+
+MEMBER_ID=$(${ETCDCTL} member list | grep "https://{{ .PrivateIP }}:2380" | cut -d',' -f1)
+
+if [ -z "${MEMBER_ID}" ]
+then
+	exit 0
+fi
+
+${ETCDCTL} member remove ${MEMBER_ID}
+`
@@ -5,17 +5,16 @@ var Default = map[string]string{
 	"bootstrap_token":            bootstrapTokenTpl,
 	"certificates":               certificatesTpl,
 	"cloudcontroller":            cloudcontrollerTpl,
+	"cluster_autoscaler":         clusterAutoscalerTpl,
 	"clustercheck":               clustercheckTpl,
 	"cni":                        cniTpl,
-	"dashboard":                  dashboardTpl,
 	"docker":                     dockerTpl,
 	"download_kubernetes_binary": downloadKubernetesBinaryTpl,
-	"drain":                      drainTpl,
 	"kubeadm":                    kubeadmTpl,
 	"kubelet":                    kubelet,
+	"kubelet_config":             kubeletConfigTpl,
 	"network":                    networkTpl,
 	"poststart":                  poststartTpl,
-	"prometheus":                 prometheusTpl,
 	"storageclass":               storageclassTpl,
 	"tiller":                     tillerTpl,
 	"upgrade":                    upgradeTpl,
@@ -24,4 +23,7 @@ var Default = map[string]string{
 	"apply":                      applyTpl,
 	"install_app":                installApp,
 	"helm":                       helmTpl,
+	"upload_certs":               uploadCertsTpl,
+	"remove_etcd_member":         removeEtcdMemberTpl,
+	"etcd":                       etcdTpl,
 }
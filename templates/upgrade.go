@@ -24,4 +24,24 @@ sudo apt-mark unhold kubelet kubectl && \
 sudo apt-get update && sudo apt-get install -y kubelet={{ .K8SVersion }}-00 kubectl={{ .K8SVersion }}-00 && \
 sudo apt-mark hold kubelet kubectl
 sudo systemctl restart kubelet
+
+KUBEADM_VERSION=$(kubeadm version -o short)
+KUBELET_VERSION=$(kubelet --version | awk '{ print $2 }')
+
+if [ "$KUBEADM_VERSION" != "v{{ .K8SVersion }}" ] || [ "$KUBELET_VERSION" != "v{{ .K8SVersion }}" ]
+then
+	echo "kubeadm $KUBEADM_VERSION and kubelet $KUBELET_VERSION are not upgraded to v{{ .K8SVersion }}"
+	exit 1
+fi
+
+{{ if .IsMaster }}
+for COMPONENT in kube-apiserver kube-controller-manager kube-scheduler
+do
+	if ! sudo grep -q "image: .*$COMPONENT.*:v{{ .K8SVersion }}" /etc/kubernetes/manifests/$COMPONENT.yaml
+	then
+		echo "$COMPONENT is not upgraded to v{{ .K8SVersion }}"
+		exit 1
+	fi
+done
+{{ end }}
 `
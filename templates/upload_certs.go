@@ -0,0 +1,8 @@
+package templates
+
+const uploadCertsTpl = `
+set -e
+
+# Uploaded certs expire, new control plane node needs them to join
+sudo kubeadm init phase upload-certs --upload-certs --certificate-key {{ .CertificateKey }}
+`